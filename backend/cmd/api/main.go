@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"crypto/rsa"
 	"flag"
 	"fmt"
 	"io"
@@ -29,12 +30,18 @@ import (
 	"github.com/bivex/paywall-iap/internal/infrastructure/cache"
 	"github.com/bivex/paywall-iap/internal/infrastructure/config"
 	iapext "github.com/bivex/paywall-iap/internal/infrastructure/external/iap"
+	"github.com/bivex/paywall-iap/internal/infrastructure/external/paypal"
+	"github.com/bivex/paywall-iap/internal/infrastructure/faultinject"
 	"github.com/bivex/paywall-iap/internal/infrastructure/logging"
+	"github.com/bivex/paywall-iap/internal/infrastructure/metrics"
+	"github.com/bivex/paywall-iap/internal/infrastructure/persistence/ingest"
 	"github.com/bivex/paywall-iap/internal/infrastructure/persistence/pool"
 	"github.com/bivex/paywall-iap/internal/infrastructure/persistence/repository"
 	"github.com/bivex/paywall-iap/internal/infrastructure/persistence/sqlc/generated"
+	"github.com/bivex/paywall-iap/internal/infrastructure/signing"
 	app_handler "github.com/bivex/paywall-iap/internal/interfaces/http/handlers"
 	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+	worker_tasks "github.com/bivex/paywall-iap/internal/worker/tasks"
 )
 
 func main() {
@@ -71,13 +78,37 @@ func main() {
 	defer asynqClient.Close()
 
 	deps := initDependencies(cfg, dbPool, redisClient, asynqClient)
+	ingestCtx, stopIngest := context.WithCancel(ctx)
+	ingestDone := make(chan struct{})
+	go func() {
+		deps.analyticsIngester.Run(ingestCtx)
+		close(ingestDone)
+	}()
+	go deps.killSwitch.Run(ingestCtx)
+	if cfg.WebhookSLO.EvaluationInterval > 0 {
+		go deps.webhookSLOService.Run(ingestCtx, cfg.WebhookSLO.EvaluationInterval)
+	}
+
 	router := setupRouter(cfg, deps, redisClient)
 	if *dumpRoutes {
 		printRoutes(os.Stdout, router)
 		return
 	}
 
+	// Load rates before the first reward conversion needs them
+	go func() {
+		warmCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := deps.currencyService.WarmRates(warmCtx); err != nil {
+			logging.Logger.Warn("Failed to warm currency rates", zap.Error(err))
+		}
+	}()
+
 	startServer(cfg, router)
+
+	// No request can submit events any more; flush what is buffered
+	stopIngest()
+	<-ingestDone
 }
 
 func dumpRoutesConfig() *config.Config {
@@ -97,6 +128,8 @@ func dumpRoutesDependencies() *dependencies {
 	return &dependencies{
 		jwtMiddleware: middleware.NewJWTMiddleware("dump-routes-secret-dump-routes-secret", nil, 15*time.Minute),
 		rateLimiter:   middleware.NewRateLimiter(redisClient, true),
+		requestLogger: mustInitRequestLogger(config.LoggingConfig{SampleRate: 1}),
+		loadShedder:   mustInitLoadShedder(config.LoadSheddingConfig{}),
 
 		authHandler:           (*app_handler.AuthHandler)(nil),
 		iapHandler:            (*app_handler.IAPHandler)(nil),
@@ -108,6 +141,7 @@ func dumpRoutesDependencies() *dependencies {
 		banditHandler:         (*app_handler.BanditHandler)(nil),
 		banditAdvancedHandler: (*app_handler.BanditAdvancedHandler)(nil),
 		paywallHandler:        (*app_handler.PaywallHandler)(nil),
+		offerHandler:          (*app_handler.OfferHandler)(nil),
 	}
 }
 
@@ -145,6 +179,92 @@ func mustInitLogger(sentryCfg *config.SentryConfig) {
 	}
 }
 
+// mustInitRequestLogger creates the HTTP request logger
+func mustInitRequestLogger(logCfg config.LoggingConfig) *logging.RequestLogger {
+	requestLogger, err := logging.NewRequestLogger(logging.Logger, logCfg)
+	if err != nil {
+		logging.Logger.Fatal("Invalid request logging configuration", zap.Error(err))
+	}
+	return requestLogger
+}
+
+// mustInitLoadShedder creates the overload protection middleware
+func mustInitLoadShedder(shedCfg config.LoadSheddingConfig) *middleware.LoadShedder {
+	loadShedder, err := middleware.NewLoadShedder(shedCfg)
+	if err != nil {
+		logging.Logger.Fatal("Invalid load shedding configuration", zap.Error(err))
+	}
+	return loadShedder
+}
+
+// mustInitSKANAttribution creates the SKAdNetwork attribution service,
+// verifying postback signatures when Apple's key is configured
+func mustInitSKANAttribution(dbPool *pgxpool.Pool, iapCfg config.IAPConfig) *service.SKANAttributionService {
+	skan := service.NewSKANAttributionService(dbPool, logging.Logger)
+	if iapCfg.SKAdNetworkPublicKey == "" {
+		logging.Logger.Warn("SKADNETWORK_PUBLIC_KEY not set; SKAdNetwork postbacks are stored unverified")
+		return skan
+	}
+	verifier, err := service.NewSKANSignatureVerifier(iapCfg.SKAdNetworkPublicKey)
+	if err != nil {
+		logging.Logger.Fatal("Invalid SKAdNetwork public key", zap.Error(err))
+	}
+	return skan.WithSignatureVerifier(verifier)
+}
+
+// mustInitJWTKeys loads the asymmetric keys auth tokens are signed with; no
+// keys keeps signing with JWT_SECRET
+func mustInitJWTKeys(jwtCfg config.JWTConfig) *signing.KeySet {
+	keys, err := signing.ParseKeySet(jwtCfg.SigningKeys)
+	if err != nil {
+		logging.Logger.Fatal("Invalid JWT_SIGNING_KEYS", zap.Error(err))
+	}
+	if !keys.Empty() {
+		logging.Logger.Info("Signing auth tokens with asymmetric keys",
+			zap.String("kid", keys.SigningKeyID()),
+			zap.Bool("accept_hs256", jwtCfg.AcceptHS256))
+	}
+	return keys
+}
+
+// mustInitEntitlementKeys loads the keys offline entitlement tokens are
+// signed with; no keys disables the tokens
+func mustInitEntitlementKeys(entitlementCfg config.EntitlementConfig) *signing.KeySet {
+	keys, err := signing.ParseKeySet(entitlementCfg.SigningKeys)
+	if err != nil {
+		logging.Logger.Fatal("Invalid ENTITLEMENT_SIGNING_KEYS", zap.Error(err))
+	}
+	if keys.Empty() {
+		logging.Logger.Warn("ENTITLEMENT_SIGNING_KEYS not set; access checks carry no offline entitlement token")
+	}
+	return keys
+}
+
+// mustInitHuaweiPublicKey parses the AppGallery IAP public key; nil when unset
+func mustInitHuaweiPublicKey(iapCfg config.IAPConfig) *rsa.PublicKey {
+	if iapCfg.HuaweiPublicKey == "" {
+		return nil
+	}
+	key, err := iapext.ParseHuaweiPublicKey(iapCfg.HuaweiPublicKey)
+	if err != nil {
+		logging.Logger.Fatal("Invalid HUAWEI_PUBLIC_KEY", zap.Error(err))
+	}
+	return key
+}
+
+// mustInitFaultInjector builds the fault injector for external clients; nil
+// when fault injection is disabled
+func mustInitFaultInjector(faultCfg config.FaultConfig) *faultinject.Injector {
+	faults, err := faultinject.NewFromConfig(faultCfg, logging.Logger)
+	if err != nil {
+		logging.Logger.Fatal("Invalid FAULT_INJECTION_SCENARIO", zap.Error(err))
+	}
+	if faults != nil {
+		logging.Logger.Warn("Fault injection enabled", zap.String("scenario", faults.Scenario().String()))
+	}
+	return faults
+}
+
 // mustInitDB creates and tests database connection
 func mustInitDB(ctx context.Context, dbCfg config.DatabaseConfig) *pgxpool.Pool {
 	dbPool, err := pool.NewPool(ctx, dbCfg)
@@ -188,15 +308,22 @@ type dependencies struct {
 	analyticsRepo    domainRepo.AnalyticsRepository
 	banditRepo       service.BanditRepository
 	adminCredRepo    domainRepo.AdminCredentialRepository
+	oauthClientRepo  domainRepo.OAuthClientRepository
 
-	analyticsService *service.AnalyticsService
-	auditService     *service.AuditService
-	banditService    *service.ThompsonSamplingBandit
-	advancedBandit   *service.AdvancedBanditEngine
-	currencyService  *service.CurrencyRateService
+	analyticsService  *service.AnalyticsService
+	auditService      *service.AuditService
+	banditService     *service.ThompsonSamplingBandit
+	advancedBandit    *service.AdvancedBanditEngine
+	currencyService   *service.CurrencyRateService
+	webhookSLOService *service.WebhookSLOService
 
 	jwtMiddleware *middleware.JWTMiddleware
 	rateLimiter   *middleware.RateLimiter
+	requestLogger *logging.RequestLogger
+	loadShedder   *middleware.LoadShedder
+
+	analyticsIngester *ingest.AnalyticsIngester
+	killSwitch        *cache.ExperimentKillSwitch
 
 	registerCmd   *command.RegisterCommand
 	cancelSubCmd  *command.CancelSubscriptionCommand
@@ -206,19 +333,71 @@ type dependencies struct {
 	getSubQuery      *query.GetSubscriptionQuery
 	checkAccessQuery *query.CheckAccessQuery
 
-	authHandler           *app_handler.AuthHandler
-	iapHandler            *app_handler.IAPHandler
-	subscriptionHandler   *app_handler.SubscriptionHandler
-	adminHandler          *app_handler.AdminHandler
-	appsHandler           *app_handler.AppsHandler
-	appSettingsHandler    *app_handler.AppSettingsHandler
-	webhookHandler        *app_handler.WebhookHandler
-	banditHandler         *app_handler.BanditHandler
-	banditAdvancedHandler *app_handler.BanditAdvancedHandler
-	paywallHandler        *app_handler.PaywallHandler
-	adminPaywallsHandler  *app_handler.AdminPaywallsHandler
-	winbackHandler        *app_handler.WinbackHandler
-	analyticsExtHandler   *app_handler.AnalyticsHandlersExtended
+	authHandler            *app_handler.AuthHandler
+	iapHandler             *app_handler.IAPHandler
+	subscriptionHandler    *app_handler.SubscriptionHandler
+	adminHandler           *app_handler.AdminHandler
+	appsHandler            *app_handler.AppsHandler
+	appSettingsHandler     *app_handler.AppSettingsHandler
+	webhookHandler         *app_handler.WebhookHandler
+	banditHandler          *app_handler.BanditHandler
+	banditAdvancedHandler  *app_handler.BanditAdvancedHandler
+	paywallHandler         *app_handler.PaywallHandler
+	offerHandler           *app_handler.OfferHandler
+	pendingPurchaseHandler *app_handler.PendingPurchaseHandler
+	lifetimeHandler        *app_handler.LifetimeHandler
+	creditsHandler         *app_handler.CreditsHandler
+	meteringHandler        *app_handler.MeteringHandler
+	adminPaywallsHandler   *app_handler.AdminPaywallsHandler
+	winbackHandler         *app_handler.WinbackHandler
+	eventsHandler          *app_handler.EventsHandler
+	consentHandler         *app_handler.ConsentHandler
+	notificationPrefs      *app_handler.NotificationPreferencesHandler
+	adminNotifications     *app_handler.AdminNotificationsHandler
+	analyticsExtHandler    *app_handler.AnalyticsHandlersExtended
+	paywallFunnelHandler   *app_handler.AdminPaywallFunnelHandler
+	funnelHealthHandler    *app_handler.AdminFunnelHealthHandler
+	webhookLatencyHandler  *app_handler.AdminWebhookLatencyHandler
+	webhookSLOHandler      *app_handler.AdminWebhookSLOHandler
+	priceIncreasesHandler  *app_handler.AdminPriceIncreasesHandler
+	dashboardAggregates    *app_handler.AdminDashboardAggregatesHandler
+	taskRunsHandler        *app_handler.AdminTaskRunsHandler
+	banditExpiryHandler    *app_handler.AdminBanditExpiryHandler
+	taxHandler             *app_handler.AdminTaxHandler
+	skanHandler            *app_handler.SKANHandler
+	adminSKANHandler       *app_handler.AdminSKANHandler
+	mmpHandler             *app_handler.MMPHandler
+	adminAcquisition       *app_handler.AdminAcquisitionHandler
+	adminOfferCodes        *app_handler.AdminOfferCodeHandler
+	adminWarehouse         *app_handler.AdminWarehouseHandler
+	adminRetention         *app_handler.AdminRetentionHandler
+	embedAnalyticsHandler  *app_handler.EmbedAnalyticsHandler
+	adminEmbedTokens       *app_handler.AdminEmbedTokensHandler
+	adminPayPalPlans       *app_handler.AdminPayPalPlansHandler
+	paywallRulesHandler    *app_handler.AdminPaywallRulesHandler
+	inAppMessagesHandler   *app_handler.InAppMessagesHandler
+	adminInAppMessages     *app_handler.AdminInAppMessagesHandler
+	segmentsHandler        *app_handler.AdminSegmentsHandler
+	sendTimeHandler        *app_handler.SendTimeHandler
+	reviewPromptHandler    *app_handler.ReviewPromptHandler
+	clientStatusHandler    *app_handler.ClientStatusHandler
+	experimentKillSwitch   *app_handler.AdminExperimentKillSwitchHandler
+	experimentAssignments  *app_handler.ExperimentAssignmentsHandler
+	priceRolloutsHandler   *app_handler.AdminPriceRolloutsHandler
+	snapshotsHandler       *app_handler.AdminSubscriptionSnapshotsHandler
+	lifecycleHandler       *app_handler.AdminLifecycleHandler
+	reactivationHandler    *app_handler.AdminReactivationHandler
+	impersonationHandler   *app_handler.AdminImpersonationHandler
+	oauthHandler           *app_handler.OAuthHandler
+	oauthClientsHandler    *app_handler.AdminOAuthClientsHandler
+	loggingHandler         *app_handler.AdminLoggingHandler
+	cacheHandler           *app_handler.AdminCacheHandler
+	dunningHandler         *app_handler.AdminDunningHandler
+	adjustmentHandler      *app_handler.AdminSubscriptionAdjustmentHandler
+	accountMergeHandler    *app_handler.AdminAccountMergeHandler
+	webhookQuarantine      *app_handler.AdminWebhookQuarantineHandler
+	entitlementKeys        *app_handler.JWKSHandler
+	authKeys               *app_handler.JWKSHandler
 }
 
 // initDependencies initializes all repositories, services, middleware, and handlers
@@ -230,7 +409,8 @@ func initDependencies(cfg *config.Config, dbPool *pgxpool.Pool, redisClient *red
 	userRepo := repository.NewUserRepository(queries)
 	subscriptionRepo := repository.NewSubscriptionRepository(queries)
 	transactionRepo := repository.NewTransactionRepository(queries)
-	analyticsRepo := repository.NewAnalyticsRepository(dbPool)
+	queryCache := cache.NewQueryCache(redisClient, logging.Logger)
+	analyticsRepo := cache.NewCachedAnalyticsRepository(repository.NewAnalyticsRepository(dbPool), queryCache)
 	banditRepo := repository.NewPostgresBanditRepository(dbPool, logging.Logger)
 	adminCredRepo := repository.NewAdminCredentialRepository(queries)
 
@@ -240,11 +420,35 @@ func initDependencies(cfg *config.Config, dbPool *pgxpool.Pool, redisClient *red
 	winbackRepo := repository.NewWinbackOfferRepository(dbPool)
 	winbackService := service.NewWinbackService(winbackRepo, userRepo, subscriptionRepo)
 
+	// Faults injected into external clients, for resilience testing
+	faults := mustInitFaultInjector(cfg.Faults)
+
 	// Bandit components
 	banditCache := cache.NewRedisBanditCache(redisClient, logging.Logger)
-	banditService := service.NewThompsonSamplingBandit(banditRepo, banditCache, logging.Logger)
-	currencyService := service.NewCurrencyRateService(redisClient, logging.Logger)
-
+	killSwitch := cache.NewExperimentKillSwitch(redisClient, logging.Logger)
+	banditService := service.NewThompsonSamplingBandit(banditRepo, banditCache, logging.Logger).
+		WithSimulationBudget(cfg.Bandit.SimulationBudget).
+		WithSimulationWorkers(cfg.Bandit.SimulationWorkers).
+		WithKillSwitch(killSwitch)
+	currencyRateHistory := repository.NewCurrencyRateHistoryRepository(dbPool)
+	currencyService := service.NewCurrencyRateService(redisClient, logging.Logger).
+		WithRateHistory(currencyRateHistory).
+		WithTransport(faults.Transport(faultinject.TargetCurrency, nil))
+	reportingCurrency := service.NewReportingCurrencyConverter(cfg.Revenue.ReportingCurrency, currencyRateHistory, currencyService)
+	analyticsService.WithReportingCurrency(reportingCurrency)
+
+	revenueBasis := service.ParseRevenueBasis(cfg.Revenue.Basis)
+	feeSchedule := service.StoreFeeSchedule{
+		AppleCommission:  cfg.Revenue.AppleCommission,
+		GoogleCommission: cfg.Revenue.GoogleCommission,
+		AmazonCommission: cfg.Revenue.AmazonCommission,
+		HuaweiCommission: cfg.Revenue.HuaweiCommission,
+		StripeFeePercent: cfg.Revenue.StripeFeePercent,
+		StripeFeeFixed:   cfg.Revenue.StripeFeeFixed,
+		PayPalFeePercent: cfg.Revenue.PayPalFeePercent,
+		PayPalFeeFixed:   cfg.Revenue.PayPalFeeFixed,
+	}
+	consentService := service.NewConsentService(repository.NewUserConsentRepository(dbPool), logging.Logger)
 	advancedBanditEngine := service.NewAdvancedBanditEngine(
 		banditService,
 		banditRepo,
@@ -260,46 +464,103 @@ func initDependencies(cfg *config.Config, dbPool *pgxpool.Pool, redisClient *red
 			EnableWindow:     true,
 			EnableHybrid:     true,
 		},
-	)
+	).WithRevenueBasis(revenueBasis, feeSchedule).
+		WithConsent(consentService)
 
 	// Initialize middleware
-	jwtMiddleware := middleware.NewJWTMiddleware(cfg.JWT.Secret, redisClient, cfg.JWT.AccessTTL)
+	jwtMiddleware := middleware.NewJWTMiddleware(cfg.JWT.Secret, redisClient, cfg.JWT.AccessTTL).
+		WithSigningKeys(mustInitJWTKeys(cfg.JWT), cfg.JWT.AcceptHS256).
+		WithImpersonationAudit(auditService)
 	rateLimiter := middleware.NewRateLimiter(redisClient, true)
+	requestLogger := mustInitRequestLogger(cfg.Logging)
+	loadShedder := mustInitLoadShedder(cfg.LoadShedding)
+	analyticsIngester := ingest.NewAnalyticsIngester(dbPool, cfg.Ingest, logging.Logger)
 
 	// Initialize IAP verifiers
 	// Dynamic verifiers resolve credentials per-app from app_credentials table at verify time.
 	// Static singleton verifiers (AppleVerifier/GoogleVerifier) are kept for webhook validation
 	// and as fallback when APP_CREDENTIALS_KEY is not set.
-	appleVerifier := iapext.NewAppleVerifier(cfg.IAP.AppleSharedSecret, cfg.IAP.IsProduction, cfg.IAP.AppleMockURL)
-	googleVerifier := iapext.NewGoogleVerifier(cfg.IAP.GoogleKeyJSON, cfg.IAP.IsProduction, cfg.IAP.GoogleIAPBaseURL)
+	appleTransport := faults.Transport(faultinject.TargetApple, nil)
+	googleTransport := faults.Transport(faultinject.TargetGoogle, nil)
+	appleVerifier := iapext.NewAppleVerifier(cfg.IAP.AppleSharedSecret, cfg.IAP.IsProduction, cfg.IAP.AppleMockURL).
+		WithTransport(appleTransport)
+	googleVerifier := iapext.NewGoogleVerifier(cfg.IAP.GoogleKeyJSON, cfg.IAP.IsProduction, cfg.IAP.GoogleIAPBaseURL).
+		WithTransport(googleTransport)
 	iapAdapter := iapext.NewIAPAdapter(appleVerifier, googleVerifier)
 	_ = iapAdapter // used by webhook handlers
 
 	credResolver := iapext.NewCredentialResolver(appRepo)
-	dynamicApple := iapext.NewDynamicAppleVerifier(credResolver, cfg.IAP.AppleMockURL)
-	dynamicGoogle := iapext.NewDynamicGoogleVerifier(credResolver, cfg.IAP.GoogleIAPBaseURL)
+	dynamicApple := iapext.NewDynamicAppleVerifier(credResolver, cfg.IAP.AppleMockURL).WithTransport(appleTransport)
+	dynamicGoogle := iapext.NewDynamicGoogleVerifier(credResolver, cfg.IAP.GoogleIAPBaseURL).WithTransport(googleTransport)
+
+	// Amazon Appstore and Huawei AppGallery use deployment-wide credentials
+	huaweiKey := mustInitHuaweiPublicKey(cfg.IAP)
 
 	// Initialize commands
-	registerCmd := command.NewRegisterCommand(userRepo, jwtMiddleware)
+	sessionCmd := command.NewUserSessionCommand(repository.NewUserSessionRepository(dbPool), jwtMiddleware)
+	registerCmd := command.NewRegisterCommand(userRepo, jwtMiddleware).WithSessions(sessionCmd)
 	cancelSubCmd := command.NewCancelSubscriptionCommand(subscriptionRepo)
+	offerRedemptionRepo := repository.NewOfferRedemptionRepository(dbPool)
+	offerService := service.NewOfferService(offerRedemptionRepo)
+	purchaseEvents := cache.NewPurchaseEventBroker(redisClient)
+	pendingPurchaseService := service.NewPendingPurchaseService(repository.NewPendingPurchaseRepository(dbPool), logging.Logger).
+		WithNotifier(purchaseEvents).
+		WithNotifier(worker_tasks.NewPurchaseResolutionPush(asynqClient))
 	verifyIAPCmd := command.NewVerifyIAPCommand(
 		userRepo,
 		subscriptionRepo,
 		transactionRepo,
 		dynamicApple,
 		dynamicGoogle,
-	)
+	).WithOfferService(offerService).
+		WithPendingPurchases(pendingPurchaseService).
+		WithAnalyticsInvalidation(queryCache)
+	// A store without credentials is not registered, so its purchases are rejected
+	if cfg.IAP.AmazonConfigured() {
+		verifyIAPCmd.WithStoreVerifier("amazon", iapext.NewStaticStoreVerifier(
+			iapext.NewAmazonVerifier(cfg.IAP.AmazonSharedSecret, cfg.IAP.AmazonRVSURL)))
+	} else {
+		logging.Logger.Warn("AMAZON_SHARED_SECRET not set; Amazon purchases are refused")
+	}
+	if cfg.IAP.HuaweiConfigured() {
+		verifyIAPCmd.WithStoreVerifier("huawei", iapext.NewStaticStoreVerifier(
+			iapext.NewHuaweiVerifier(cfg.IAP.HuaweiClientID, cfg.IAP.HuaweiClientSecret, cfg.IAP.HuaweiIAPBaseURL, huaweiKey)))
+	} else {
+		logging.Logger.Warn("HUAWEI_CLIENT_ID / HUAWEI_CLIENT_SECRET not set; Huawei purchases are refused")
+	}
+	creditService := service.NewCreditService(repository.NewCreditRepository(dbPool), logging.Logger).
+		WithLTV(userRepo).
+		WithConversions(advancedBanditEngine)
+	verifyConsumableCmd := command.NewVerifyConsumableCommand(appRepo, creditService, dynamicApple, dynamicGoogle)
+	lifetimeService := service.NewLifetimeEntitlementService(repository.NewLifetimeEntitlementRepository(dbPool), logging.Logger).
+		WithLTV(userRepo).
+		WithConversions(advancedBanditEngine)
+	verifyLifetimeCmd := command.NewVerifyLifetimeCommand(appRepo, lifetimeService, dynamicApple, dynamicGoogle)
 	adminLoginCmd := command.NewAdminLoginCommand(userRepo, adminCredRepo, jwtMiddleware)
+	oauthClientRepo := repository.NewOAuthClientRepository(dbPool)
+	clientCredentialsCmd := command.NewClientCredentialsCommand(oauthClientRepo, jwtMiddleware)
 
 	// Initialize queries
 	getSubQuery := query.NewGetSubscriptionQuery(subscriptionRepo)
-	checkAccessQuery := query.NewCheckAccessQuery(subscriptionRepo)
+	gracePeriodRepo := repository.NewGracePeriodRepository(dbPool)
+	checkAccessQuery := query.NewCheckAccessQuery(subscriptionRepo).
+		WithLifetimeEntitlements(lifetimeService).
+		WithGracePeriods(gracePeriodRepo)
+	entitlementKeys := mustInitEntitlementKeys(cfg.Entitlement)
+	if !entitlementKeys.Empty() {
+		checkAccessQuery.WithEntitlementTokens(service.NewEntitlementTokenService(entitlementKeys, cfg.Entitlement.TokenTTL, cfg.JWT.Issuer))
+	}
+	entitlementKeysHandler := app_handler.NewJWKSHandler(entitlementKeys)
 
 	// Initialize handlers
 	appsHandler := app_handler.NewAppsHandler(appRepo)
 	appSettingsHandler := app_handler.NewAppSettingsHandler(appRepo, credResolver)
-	authHandler := app_handler.NewAuthHandler(registerCmd, adminLoginCmd, jwtMiddleware)
-	iapHandler := app_handler.NewIAPHandler(verifyIAPCmd, jwtMiddleware, rateLimiter)
+	authHandler := app_handler.NewAuthHandler(registerCmd, adminLoginCmd, jwtMiddleware).WithSessions(sessionCmd)
+	oauthHandler := app_handler.NewOAuthHandler(clientCredentialsCmd)
+	oauthClientsHandler := app_handler.NewAdminOAuthClientsHandler(oauthClientRepo, clientCredentialsCmd)
+	loggingHandler := app_handler.NewAdminLoggingHandler(requestLogger)
+	verificationOutcomes := cache.NewVerificationOutcomes(redisClient)
+	iapHandler := app_handler.NewIAPHandler(verifyIAPCmd, jwtMiddleware, rateLimiter).WithVerificationOutcomes(verificationOutcomes)
 	subscriptionHandler := app_handler.NewSubscriptionHandler(getSubQuery, checkAccessQuery, cancelSubCmd, jwtMiddleware)
 	adminHandler := app_handler.NewAdminHandler(
 		subscriptionRepo,
@@ -310,11 +571,12 @@ func initDependencies(cfg *config.Config, dbPool *pgxpool.Pool, redisClient *red
 		analyticsService,
 		auditService,
 		service.NewRevenueOpsService(dbPool),
-		service.NewAnalyticsReportService(dbPool),
+		service.NewAnalyticsReportService(dbPool).WithReportingCurrency(reportingCurrency),
 		service.NewUserProfileService(dbPool),
 		winbackService,
 		asynqClient,
-	)
+	).WithReportingCurrency(reportingCurrency).WithKillSwitch(killSwitch)
+	experimentKillSwitchHandler := app_handler.NewAdminExperimentKillSwitchHandler(killSwitch, banditRepo, logging.Logger)
 	webhookHandler := app_handler.NewWebhookHandler(
 		cfg.IAP.StripeWebhookSecret,
 		cfg.IAP.AppleWebhookSecret,
@@ -322,6 +584,57 @@ func initDependencies(cfg *config.Config, dbPool *pgxpool.Pool, redisClient *red
 		queries,
 		asynqClient,
 	)
+	webhookQuarantineRepo := repository.NewWebhookQuarantineRepository(dbPool)
+	webhookHandler.WithStripeTolerance(cfg.IAP.StripeWebhookTolerance).
+		WithQuarantine(webhookQuarantineRepo).
+		WithAnalyticsEvents(analyticsIngester)
+	if cfg.IAP.AppleJWSVerificationDisabled {
+		logging.Logger.Warn("Apple notification signature verification is DISABLED (development mode)")
+		webhookHandler.WithAppleVerification(nil)
+	} else {
+		appleJWSVerifier, err := iapext.NewAppleJWSVerifier(cfg.IAP.AppleRootCAPath, cfg.IAP.AppleJWSCheckOCSP)
+		if err != nil {
+			logging.Logger.Fatal("Failed to initialize Apple JWS verifier", zap.Error(err))
+		}
+		webhookHandler.WithAppleVerification(appleJWSVerifier, cfg.IAP.AppleNotificationEnvironments()...)
+	}
+	if cfg.IAP.GooglePubSubAuthDisabled {
+		logging.Logger.Warn("Google Pub/Sub push authentication is DISABLED (development mode)")
+	} else {
+		googleOIDCVerifier, err := iapext.NewGoogleOIDCVerifier(iapext.GoogleOIDCConfig{
+			Audience:            cfg.IAP.GooglePubSubAudience,
+			Issuer:              cfg.IAP.GooglePubSubIssuer,
+			ServiceAccountEmail: cfg.IAP.GooglePubSubServiceAccount,
+			JWKSURL:             cfg.IAP.GooglePubSubJWKSURL,
+		})
+		if err != nil {
+			logging.Logger.Fatal("Failed to initialize Google Pub/Sub push authentication (set GOOGLE_PUBSUB_AUDIENCE)", zap.Error(err))
+		}
+		webhookHandler.WithGooglePushAuthentication(googleOIDCVerifier)
+	}
+	if cfg.IAP.AmazonSNSVerificationDisabled {
+		logging.Logger.Warn("Amazon SNS signature verification is DISABLED (development mode)")
+	} else {
+		webhookHandler.WithAmazonNotifications(iapext.NewAmazonSNSVerifier(cfg.IAP.AmazonTopicARNs()...))
+	}
+	switch {
+	case cfg.IAP.HuaweiSignatureVerificationDisabled:
+		logging.Logger.Warn("Huawei notification signature verification is DISABLED (development mode)")
+		webhookHandler.WithHuaweiNotifications(nil)
+	case huaweiKey != nil:
+		webhookHandler.WithHuaweiNotifications(huaweiKey)
+	default:
+		logging.Logger.Warn("HUAWEI_PUBLIC_KEY not set; Huawei notifications are refused")
+	}
+	switch {
+	case cfg.PayPal.VerificationDisabled:
+		logging.Logger.Warn("PayPal webhook signature verification is DISABLED (development mode)")
+		webhookHandler.WithPayPalVerification(nil)
+	case cfg.PayPal.WebhookID != "":
+		webhookHandler.WithPayPalVerification(paypal.NewWebhookVerifier(cfg.PayPal.WebhookID))
+	default:
+		logging.Logger.Info("PAYPAL_WEBHOOK_ID not set; PayPal webhooks are refused")
+	}
 	banditHandler := app_handler.NewBanditHandler(banditService)
 	banditAdvancedHandler := app_handler.NewBanditAdvancedHandler(advancedBanditEngine, currencyService, logging.Logger)
 
@@ -331,49 +644,217 @@ func initDependencies(cfg *config.Config, dbPool *pgxpool.Pool, redisClient *red
 	trackSessionCmd := command.NewTrackSessionCommand(userRepo)
 	paywallHandler := app_handler.NewPaywallHandler(getTriggerStatusQuery, captureEmailCmd, trackSessionCmd, jwtMiddleware)
 	adminPaywallsHandler := app_handler.NewAdminPaywallsHandler(dbPool)
+	offerHandler := app_handler.NewOfferHandler(offerService)
+	pendingPurchaseHandler := app_handler.NewPendingPurchaseHandler(pendingPurchaseService, purchaseEvents, logging.Logger)
+	lifetimeHandler := app_handler.NewLifetimeHandler(verifyLifetimeCmd, lifetimeService, logging.Logger)
+	creditsHandler := app_handler.NewCreditsHandler(verifyConsumableCmd, creditService, logging.Logger)
+	meteringService := service.NewMeteringService(cache.NewMeteringCounters(redisClient), repository.NewMeteringUsageRepository(dbPool), appRepo, logging.Logger)
+	meteringHandler := app_handler.NewMeteringHandler(meteringService, logging.Logger).WithAccessCheck(checkAccessQuery)
+	taskRunsHandler := app_handler.NewAdminTaskRunsHandler(repository.NewTaskRunRepository(dbPool))
+	banditExpiryHandler := app_handler.NewAdminBanditExpiryHandler(service.NewPendingRewardExpiryService(banditRepo, advancedBanditEngine), asynqClient)
+	taxHandler := app_handler.NewAdminTaxHandler(service.NewTaxReportService(dbPool))
+	skanService := mustInitSKANAttribution(dbPool, cfg.IAP)
+	skanHandler := app_handler.NewSKANHandler(skanService, logging.Logger)
+	adminSKANHandler := app_handler.NewAdminSKANHandler(skanService)
+	acquisitionService := service.NewAcquisitionService(dbPool)
+	mmpHandler := app_handler.NewMMPHandler(acquisitionService, appRepo, logging.Logger)
+	adminAcquisition := app_handler.NewAdminAcquisitionHandler(acquisitionService)
+	adminWarehouse := app_handler.NewAdminWarehouseHandler(service.NewWarehouseSyncService(dbPool, nil, logging.Logger), cfg.Warehouse.Provider)
+	retentionPolicies, err := service.ParseRetentionPolicies(cfg.Retention.Policies)
+	if err != nil {
+		logging.Logger.Fatal("Invalid RETENTION_POLICIES", zap.Error(err))
+	}
+	adminRetention := app_handler.NewAdminRetentionHandler(service.NewRetentionService(dbPool, retentionPolicies, logging.Logger))
+	embeddedAnalytics := service.NewEmbeddedAnalyticsService(dbPool, cfg.JWT.Secret)
+	embedAnalyticsHandler := app_handler.NewEmbedAnalyticsHandler(embeddedAnalytics, logging.Logger)
+	adminEmbedTokens := app_handler.NewAdminEmbedTokensHandler(embeddedAnalytics)
+	adminPayPalPlans := app_handler.NewAdminPayPalPlansHandler(repository.NewPayPalRepository(dbPool))
 
 	acceptWinbackCmd := command.NewAcceptWinbackOfferCommand(winbackService)
 	winbackHandler := app_handler.NewWinbackHandler(acceptWinbackCmd, winbackService, jwtMiddleware)
+	eventsHandler := app_handler.NewEventsHandler(analyticsIngester, logging.Logger).
+		WithConsent(consentService).
+		WithRewardShaping(advancedBanditEngine)
+	consentHandler := app_handler.NewConsentHandler(consentService, logging.Logger)
+	notificationPrefService := service.NewNotificationPreferenceService(repository.NewNotificationPreferenceRepository(dbPool), logging.Logger).
+		WithConsent(consentService)
+	notificationPrefs := app_handler.NewNotificationPreferencesHandler(notificationPrefService, logging.Logger)
+	adminNotifications := app_handler.NewAdminNotificationsHandler(notificationPrefService)
 
 	analyticsCache := cache.NewAnalyticsCache(redisClient, logging.Logger)
 	ltvService := service.NewLTVService(nil, nil, service.NewLTVSubscriptionAdapter(subscriptionRepo), transactionRepo, logging.Logger).
-		WithUserRepo(userRepo)
+		WithUserRepo(userRepo).
+		WithRevenueBasis(revenueBasis)
 	analyticsExtHandler := app_handler.NewAnalyticsHandlersExtended(ltvService, analyticsCache, logging.Logger)
+	paywallFunnelHandler := app_handler.NewAdminPaywallFunnelHandler(service.NewPaywallFunnelService(dbPool), analyticsCache, logging.Logger)
+	funnelHealthService := service.NewFunnelHealthService(dbPool).WithVerificationCounts(verificationOutcomes)
+	funnelHealthHandler := app_handler.NewAdminFunnelHealthHandler(funnelHealthService, logging.Logger)
+	clientStatusHandler := app_handler.NewClientStatusHandler(
+		service.NewClientStatusService(funnelHealthService, logging.Logger).WithAnalyticsBuffer(analyticsIngester),
+	)
+	webhookLatencyHandler := app_handler.NewAdminWebhookLatencyHandler(service.NewWebhookLatencyService(dbPool), logging.Logger)
+	webhookSLOService := service.NewWebhookSLOService(dbPool, []service.WebhookSLO{
+		service.RenewalWebhookSLO(cfg.WebhookSLO.RenewalThreshold, cfg.WebhookSLO.RenewalObjective),
+		service.ProcessingWebhookSLO(cfg.WebhookSLO.ProcessingThreshold, cfg.WebhookSLO.ProcessingObjective),
+	}, logging.Logger)
+	webhookSLOHandler := app_handler.NewAdminWebhookSLOHandler(webhookSLOService, logging.Logger)
+	priceIncreasesHandler := app_handler.NewAdminPriceIncreasesHandler(
+		service.NewPriceIncreaseConsentService(repository.NewPriceIncreaseConsentRepository(dbPool), logging.Logger),
+		asynqClient, logging.Logger)
+	dashboardAggregates := app_handler.NewAdminDashboardAggregatesHandler(service.NewDashboardViewsService(dbPool), logging.Logger)
+	cacheHandler := app_handler.NewAdminCacheHandler(analyticsCache, banditService, ltvService, auditService, logging.Logger)
+	dunningHandler := app_handler.NewAdminDunningHandler(repository.NewDunningCampaignRepository(dbPool), auditService, logging.Logger)
+	accountMergeService := service.NewAccountMergeService(repository.NewAccountMergeRepository(dbPool), logging.Logger).WithLTVCache(analyticsCache)
+	accountMergeHandler := app_handler.NewAdminAccountMergeHandler(accountMergeService, auditService, logging.Logger)
+	adjustmentHandler := app_handler.NewAdminSubscriptionAdjustmentHandler(service.NewSubscriptionAdjustmentService(subscriptionRepo, gracePeriodRepo), auditService, logging.Logger)
+	webhookQuarantineHandler := app_handler.NewAdminWebhookQuarantineHandler(webhookQuarantineRepo, webhookHandler, auditService, logging.Logger)
+
+	segmentRepo := repository.NewSegmentRepository(dbPool)
+	lifecycleService := service.NewLifecycleService(repository.NewLifecycleStageRepository(dbPool), logging.Logger)
+	segmentService := service.NewSegmentService(segmentRepo, userRepo, subscriptionRepo, logging.Logger).
+		WithChurnRisk(ltvService).
+		WithLifecycleStages(lifecycleService)
+	experimentAdminRepo := repository.NewExperimentAdminRepository(dbPool)
+	experimentEligibilityService := service.NewExperimentEligibilityService(experimentAdminRepo, userRepo)
+	banditHandler.WithSegmentGate(segmentService).
+		WithEligibility(experimentEligibilityService).
+		WithArmPayloads(experimentAdminRepo).
+		WithProductRewards(advancedBanditEngine)
+	sendTimeOptimizer := service.NewSendTimeOptimizer(
+		banditRepo,
+		banditService,
+		service.NewDelayedRewardStrategy(banditRepo, banditCache, logging.Logger),
+	).WithPreferences(notificationPrefService)
+	sendTimeHandler := app_handler.NewSendTimeHandler(sendTimeOptimizer, segmentRepo)
+	reviewPromptService := service.NewReviewPromptService(
+		banditRepo,
+		userRepo,
+		transactionRepo,
+		banditService,
+		service.NewDelayedRewardStrategy(banditRepo, banditCache, logging.Logger),
+	)
+	reviewPromptHandler := app_handler.NewReviewPromptHandler(reviewPromptService, logging.Logger)
+	segmentsHandler := app_handler.NewAdminSegmentsHandler(segmentRepo, segmentService, asynqClient).
+		WithSendTime(sendTimeOptimizer)
+
+	paywallRuleRepo := repository.NewPaywallRuleRepository(dbPool)
+	priceRolloutRepo := repository.NewPriceRolloutRepository(dbPool)
+	priceRolloutService := service.NewPriceRolloutService(cache.NewCachedPriceRolloutRepository(priceRolloutRepo, queryCache), banditRepo, logging.Logger)
+	priceRolloutsHandler := app_handler.NewAdminPriceRolloutsHandler(priceRolloutRepo, priceRolloutService)
+	snapshotsHandler := app_handler.NewAdminSubscriptionSnapshotsHandler(
+		service.NewSubscriptionSnapshotService(repository.NewSubscriptionSnapshotRepository(dbPool), logging.Logger),
+	)
+
+	paywallRuleService := service.NewPaywallRuleService(paywallRuleRepo, userRepo, logging.Logger).
+		WithChurnRisk(ltvService).
+		WithBandit(banditService).
+		WithSegments(segmentService).
+		WithEligibility(experimentEligibilityService).
+		WithPriceRollouts(priceRolloutService)
+	paywallHandler.WithPaywallRules(paywallRuleService)
+	paywallRulesHandler := app_handler.NewAdminPaywallRulesHandler(paywallRuleRepo, paywallRuleService)
+
+	inAppMessageRepo := repository.NewInAppMessageRepository(dbPool)
+	inAppMessageService := service.NewInAppMessageService(inAppMessageRepo, paywallRuleService, subscriptionRepo, offerRedemptionRepo, logging.Logger).
+		WithSegments(segmentService).
+		WithBandit(banditService)
+	inAppMessagesHandler := app_handler.NewInAppMessagesHandler(inAppMessageService, logging.Logger)
+	adminInAppMessages := app_handler.NewAdminInAppMessagesHandler(inAppMessageRepo, inAppMessageService)
 
 	return &dependencies{
-		queries:               queries,
-		userRepo:              userRepo,
-		subscriptionRepo:      subscriptionRepo,
-		transactionRepo:       transactionRepo,
-		analyticsRepo:         analyticsRepo,
-		banditRepo:            banditRepo,
-		adminCredRepo:         adminCredRepo,
-		analyticsService:      analyticsService,
-		auditService:          auditService,
-		banditService:         banditService,
-		advancedBandit:        advancedBanditEngine,
-		currencyService:       currencyService,
-		jwtMiddleware:         jwtMiddleware,
-		rateLimiter:           rateLimiter,
-		registerCmd:           registerCmd,
-		cancelSubCmd:          cancelSubCmd,
-		verifyIAPCmd:          verifyIAPCmd,
-		adminLoginCmd:         adminLoginCmd,
-		getSubQuery:           getSubQuery,
-		checkAccessQuery:      checkAccessQuery,
-		authHandler:           authHandler,
-		iapHandler:            iapHandler,
-		subscriptionHandler:   subscriptionHandler,
-		adminHandler:          adminHandler,
-		appsHandler:           appsHandler,
-		appSettingsHandler:    appSettingsHandler,
-		webhookHandler:        webhookHandler,
-		banditHandler:         banditHandler,
-		banditAdvancedHandler: banditAdvancedHandler,
-		paywallHandler:        paywallHandler,
-		adminPaywallsHandler:  adminPaywallsHandler,
-		winbackHandler:        winbackHandler,
-		analyticsExtHandler:   analyticsExtHandler,
+		queries:                queries,
+		userRepo:               userRepo,
+		subscriptionRepo:       subscriptionRepo,
+		transactionRepo:        transactionRepo,
+		analyticsRepo:          analyticsRepo,
+		banditRepo:             banditRepo,
+		adminCredRepo:          adminCredRepo,
+		oauthClientRepo:        oauthClientRepo,
+		analyticsService:       analyticsService,
+		auditService:           auditService,
+		banditService:          banditService,
+		advancedBandit:         advancedBanditEngine,
+		currencyService:        currencyService,
+		webhookSLOService:      webhookSLOService,
+		jwtMiddleware:          jwtMiddleware,
+		rateLimiter:            rateLimiter,
+		requestLogger:          requestLogger,
+		loadShedder:            loadShedder,
+		analyticsIngester:      analyticsIngester,
+		killSwitch:             killSwitch,
+		registerCmd:            registerCmd,
+		cancelSubCmd:           cancelSubCmd,
+		verifyIAPCmd:           verifyIAPCmd,
+		adminLoginCmd:          adminLoginCmd,
+		getSubQuery:            getSubQuery,
+		checkAccessQuery:       checkAccessQuery,
+		authHandler:            authHandler,
+		iapHandler:             iapHandler,
+		subscriptionHandler:    subscriptionHandler,
+		adminHandler:           adminHandler,
+		appsHandler:            appsHandler,
+		appSettingsHandler:     appSettingsHandler,
+		webhookHandler:         webhookHandler,
+		banditHandler:          banditHandler,
+		banditAdvancedHandler:  banditAdvancedHandler,
+		paywallHandler:         paywallHandler,
+		offerHandler:           offerHandler,
+		pendingPurchaseHandler: pendingPurchaseHandler,
+		lifetimeHandler:        lifetimeHandler,
+		creditsHandler:         creditsHandler,
+		meteringHandler:        meteringHandler,
+		adminPaywallsHandler:   adminPaywallsHandler,
+		winbackHandler:         winbackHandler,
+		eventsHandler:          eventsHandler,
+		consentHandler:         consentHandler,
+		notificationPrefs:      notificationPrefs,
+		adminNotifications:     adminNotifications,
+		analyticsExtHandler:    analyticsExtHandler,
+		paywallFunnelHandler:   paywallFunnelHandler,
+		funnelHealthHandler:    funnelHealthHandler,
+		webhookLatencyHandler:  webhookLatencyHandler,
+		webhookSLOHandler:      webhookSLOHandler,
+		priceIncreasesHandler:  priceIncreasesHandler,
+		dashboardAggregates:    dashboardAggregates,
+		taskRunsHandler:        taskRunsHandler,
+		banditExpiryHandler:    banditExpiryHandler,
+		taxHandler:             taxHandler,
+		skanHandler:            skanHandler,
+		adminSKANHandler:       adminSKANHandler,
+		mmpHandler:             mmpHandler,
+		adminAcquisition:       adminAcquisition,
+		adminOfferCodes:        app_handler.NewAdminOfferCodeHandler(offerService),
+		adminWarehouse:         adminWarehouse,
+		adminRetention:         adminRetention,
+		embedAnalyticsHandler:  embedAnalyticsHandler,
+		adminEmbedTokens:       adminEmbedTokens,
+		adminPayPalPlans:       adminPayPalPlans,
+		paywallRulesHandler:    paywallRulesHandler,
+		inAppMessagesHandler:   inAppMessagesHandler,
+		adminInAppMessages:     adminInAppMessages,
+		segmentsHandler:        segmentsHandler,
+		sendTimeHandler:        sendTimeHandler,
+		reviewPromptHandler:    reviewPromptHandler,
+		clientStatusHandler:    clientStatusHandler,
+		experimentKillSwitch:   experimentKillSwitchHandler,
+		experimentAssignments:  app_handler.NewExperimentAssignmentsHandler(experimentAdminRepo),
+		priceRolloutsHandler:   priceRolloutsHandler,
+		snapshotsHandler:       snapshotsHandler,
+		lifecycleHandler:       app_handler.NewAdminLifecycleHandler(lifecycleService),
+		impersonationHandler:   app_handler.NewAdminImpersonationHandler(jwtMiddleware, userRepo, auditService, logging.Logger),
+		reactivationHandler: app_handler.NewAdminReactivationHandler(
+			service.NewReactivationService(repository.NewReactivationRepository(dbPool), logging.Logger),
+		),
+		oauthHandler:        oauthHandler,
+		oauthClientsHandler: oauthClientsHandler,
+		loggingHandler:      loggingHandler,
+		cacheHandler:        cacheHandler,
+		dunningHandler:      dunningHandler,
+		adjustmentHandler:   adjustmentHandler,
+		accountMergeHandler: accountMergeHandler,
+		webhookQuarantine:   webhookQuarantineHandler,
+		entitlementKeys:     entitlementKeysHandler,
+		authKeys:            app_handler.NewJWKSHandler(jwtMiddleware),
 	}
 }
 
@@ -385,7 +866,7 @@ func setupRouter(cfg *config.Config, d *dependencies, redisClient *redis.Client)
 
 	router := gin.New()
 	router.HandleMethodNotAllowed = true
-	router.Use(gin.Recovery(), logging.RequestMiddleware(logging.Logger))
+	router.Use(gin.Recovery(), d.requestLogger.Middleware(), d.loadShedder.Middleware(), logging.SentryMiddleware())
 	router.GET("/openapi.yaml", openapi.ServeYAML)
 
 	// Health check
@@ -399,14 +880,37 @@ func setupRouter(cfg *config.Config, d *dependencies, redisClient *redis.Client)
 		webhooks.POST("/stripe", d.webhookHandler.StripeWebhook)
 		webhooks.POST("/apple", d.webhookHandler.AppleWebhook)
 		webhooks.POST("/google", d.webhookHandler.GoogleWebhook)
+		webhooks.POST("/amazon", d.webhookHandler.AmazonWebhook)
+		webhooks.POST("/huawei", d.webhookHandler.HuaweiWebhook)
+		webhooks.POST("/paypal", d.webhookHandler.PayPalWebhook)
+		webhooks.POST("/mmp/appsflyer/:app_id", d.mmpHandler.AppsFlyerCallback)
+		webhooks.GET("/mmp/adjust/:app_id", d.mmpHandler.AdjustCallback)
+		webhooks.POST("/mmp/adjust/:app_id", d.mmpHandler.AdjustCallback)
 	}
 
+	// Public keys for verifying auth tokens without the shared secret
+	router.GET("/.well-known/jwks.json", d.authKeys.GetJWKS)
+
+	// SKAdNetwork postbacks (no auth; Apple's standard endpoint path)
+	router.POST("/.well-known/skadnetwork/report-attribution/", d.skanHandler.ReceivePostback)
+
 	// API v1 routes
 	v1 := router.Group("/v1")
 	{
 		setupAuthRoutes(v1, d)
 		setupAdminAuthRoutes(v1, d)
+		setupOAuthRoutes(v1, d)
 		setupBanditRoutes(v1, d)
+		setupEmbedRoutes(v1, d)
+
+		// Public keys for verifying offline entitlement tokens (no auth)
+		v1.GET("/entitlements/jwks.json", d.entitlementKeys.GetJWKS)
+		// Degraded features and retry backoff for mobile clients (no auth)
+		v1.GET("/status",
+			httpmiddleware.RequireAppID(),
+			d.rateLimiter.Middleware(middleware.ByIP, middleware.StatusConfig),
+			d.clientStatusHandler.GetStatus,
+		)
 		setupProtectedRoutes(v1, d)
 		setupAdminRoutes(v1, d, cfg)
 	}
@@ -423,6 +927,8 @@ func setupAuthRoutes(v1 *gin.RouterGroup, d *dependencies) {
 			d.rateLimiter.Middleware(middleware.ByIP, middleware.DefaultConfig),
 			d.authHandler.RefreshToken,
 		)
+		auth.GET("/sessions", d.jwtMiddleware.Authenticate(), d.authHandler.ListSessions)
+		auth.DELETE("/sessions/:id", d.jwtMiddleware.Authenticate(), d.authHandler.RevokeSession)
 	}
 }
 
@@ -435,6 +941,16 @@ func setupAdminAuthRoutes(v1 *gin.RouterGroup, d *dependencies) {
 	}
 }
 
+// setupOAuthRoutes configures the OAuth2 client-credentials endpoints
+func setupOAuthRoutes(v1 *gin.RouterGroup, d *dependencies) {
+	oauth := v1.Group("/oauth")
+	oauth.Use(d.rateLimiter.Middleware(middleware.ByIP, middleware.DefaultConfig))
+	{
+		oauth.POST("/token", d.oauthHandler.Token)
+		oauth.POST("/introspect", d.oauthHandler.Introspect)
+	}
+}
+
 // setupBanditRoutes configures multi-armed bandit routes
 func setupBanditRoutes(v1 *gin.RouterGroup, d *dependencies) {
 	bandit := v1.Group("/bandit")
@@ -444,22 +960,49 @@ func setupBanditRoutes(v1 *gin.RouterGroup, d *dependencies) {
 		bandit.POST("/reward", d.banditHandler.Reward)
 		bandit.GET("/statistics", d.banditHandler.Statistics)
 		bandit.GET("/health", d.banditHandler.Health)
+	}
+
+	// Advanced bandit routes: currency lookups need a signed-in user, while
+	// everything that reads or changes experiment state is admin only
+	currency := bandit.Group("/currency", d.jwtMiddleware.Authenticate())
+	{
+		currency.GET("/rates", d.banditAdvancedHandler.GetCurrencyRates)
+		currency.POST("/convert", d.banditAdvancedHandler.ConvertCurrency)
+	}
 
-		// Advanced bandit routes
-		bandit.GET("/currency/rates", gin.WrapF(d.banditAdvancedHandler.GetCurrencyRates))
-		bandit.POST("/currency/update", gin.WrapF(d.banditAdvancedHandler.UpdateCurrencyRates))
-		bandit.POST("/currency/convert", gin.WrapF(d.banditAdvancedHandler.ConvertCurrency))
-		bandit.GET("/experiments/:id/objectives", gin.WrapF(d.banditAdvancedHandler.GetObjectiveScores))
-		bandit.GET("/experiments/:id/objectives/config", gin.WrapF(d.banditAdvancedHandler.GetObjectiveConfig))
-		bandit.PUT("/experiments/:id/objectives/config", gin.WrapF(d.banditAdvancedHandler.SetObjectiveConfig))
-		bandit.GET("/experiments/:id/window/info", gin.WrapF(d.banditAdvancedHandler.GetWindowInfo))
-		bandit.POST("/experiments/:id/window/trim", gin.WrapF(d.banditAdvancedHandler.TrimWindow))
-		bandit.GET("/experiments/:id/window/events", gin.WrapF(d.banditAdvancedHandler.ExportWindowEvents))
-		bandit.POST("/conversions", gin.WrapF(d.banditAdvancedHandler.ProcessConversion))
-		bandit.GET("/pending/:id", gin.WrapF(d.banditAdvancedHandler.GetPendingReward))
-		bandit.GET("/users/:id/pending", gin.WrapF(d.banditAdvancedHandler.GetUserPendingRewards))
-		bandit.GET("/experiments/:id/metrics", gin.WrapF(d.banditAdvancedHandler.GetMetrics))
-		bandit.POST("/maintenance", gin.WrapF(d.banditAdvancedHandler.RunMaintenance))
+	advanced := bandit.Group("")
+	advanced.Use(d.jwtMiddleware.Authenticate())
+	advanced.Use(middleware.OAuthClientMiddleware(d.oauthClientRepo, middleware.AdminScopeFor))
+	advanced.Use(middleware.AdminMiddleware(d.userRepo, d.jwtMiddleware.Keyfunc))
+	{
+		advanced.POST("/currency/update", d.banditAdvancedHandler.UpdateCurrencyRates)
+		advanced.GET("/experiments/:id/objectives", d.banditAdvancedHandler.GetObjectiveScores)
+		advanced.GET("/experiments/:id/objectives/config", d.banditAdvancedHandler.GetObjectiveConfig)
+		advanced.PUT("/experiments/:id/objectives/config", d.banditAdvancedHandler.SetObjectiveConfig)
+		advanced.GET("/experiments/:id/window/info", d.banditAdvancedHandler.GetWindowInfo)
+		advanced.POST("/experiments/:id/window/trim", d.banditAdvancedHandler.TrimWindow)
+		advanced.GET("/experiments/:id/window/events", d.banditAdvancedHandler.ExportWindowEvents)
+		advanced.POST("/conversions", d.banditAdvancedHandler.ProcessConversion)
+		advanced.GET("/experiments/:id/conversions", d.banditAdvancedHandler.ListConversions)
+		advanced.POST("/experiments/:id/conversions/reconvert", d.banditAdvancedHandler.ReconvertCurrency)
+		advanced.GET("/pending/:id", d.banditAdvancedHandler.GetPendingReward)
+		advanced.GET("/users/:id/pending", d.banditAdvancedHandler.GetUserPendingRewards)
+		advanced.GET("/experiments/:id/metrics", d.banditAdvancedHandler.GetMetrics)
+		advanced.POST("/maintenance", d.banditAdvancedHandler.RunMaintenance)
+	}
+}
+
+// setupEmbedRoutes configures the embedded analytics API, authenticated by
+// scoped embed tokens and rate limited per token
+func setupEmbedRoutes(v1 *gin.RouterGroup, d *dependencies) {
+	embed := v1.Group("/embed")
+	{
+		embed.OPTIONS("/analytics", d.embedAnalyticsHandler.Preflight)
+		embed.GET("/analytics",
+			d.embedAnalyticsHandler.RequireToken(),
+			d.rateLimiter.MiddlewareFunc(app_handler.ByEmbedToken, app_handler.EmbedTokenRateLimit),
+			d.embedAnalyticsHandler.Query,
+		)
 	}
 }
 
@@ -488,6 +1031,63 @@ func setupProtectedRoutes(v1 *gin.RouterGroup, d *dependencies) {
 			user.GET("/trigger-status", d.paywallHandler.GetTriggerStatus)
 			user.POST("/email", d.paywallHandler.CaptureEmail)
 			user.POST("/session", d.paywallHandler.TrackSession)
+			user.GET("/paywall", d.paywallHandler.GetPaywall)
+		}
+
+		users := protected.Group("/users")
+		{
+			users.GET("/me/consent", d.consentHandler.GetConsent)
+			users.PUT("/me/consent", d.consentHandler.UpdateConsent)
+			users.GET("/me/notification-preferences", d.notificationPrefs.GetNotificationPreferences)
+			users.PUT("/me/notification-preferences", d.notificationPrefs.PutNotificationPreferences)
+			users.GET("/me/experiments", d.experimentAssignments.ListMine)
+		}
+
+		protected.GET("/products/:id/eligibility", d.offerHandler.GetEligibility)
+		protected.POST("/notifications/deliveries/:id/open", d.sendTimeHandler.RecordOpen)
+
+		prompts := protected.Group("/prompts")
+		{
+			prompts.GET("/review-eligibility", d.reviewPromptHandler.GetEligibility)
+			prompts.POST("/review/:id/outcome", d.reviewPromptHandler.RecordOutcome)
+		}
+
+		purchases := protected.Group("/purchases")
+		{
+			purchases.POST("/pending", d.pendingPurchaseHandler.ReportPendingPurchase)
+			purchases.GET("/pending", d.pendingPurchaseHandler.ListPendingPurchases)
+			purchases.GET("/pending/events", d.pendingPurchaseHandler.StreamPurchaseEvents)
+			purchases.GET("/lifetime", d.lifetimeHandler.ListLifetimePurchases)
+			purchases.POST("/lifetime",
+				d.rateLimiter.Middleware(middleware.ByUserID, middleware.StrictConfig),
+				d.lifetimeHandler.VerifyLifetimePurchase,
+			)
+		}
+
+		credits := protected.Group("/credits")
+		{
+			credits.GET("", d.creditsHandler.GetBalance)
+			credits.POST("/purchases",
+				d.rateLimiter.Middleware(middleware.ByUserID, middleware.StrictConfig),
+				d.creditsHandler.VerifyConsumable,
+			)
+			credits.POST("/consume", d.creditsHandler.ConsumeCredits)
+		}
+
+		protected.GET("/attribution/skan/conversion-value", d.skanHandler.GetConversionValue)
+
+		metering := protected.Group("/metering")
+		{
+			metering.GET("/state", d.meteringHandler.GetMeterState)
+			metering.POST("/consume", d.meteringHandler.ConsumeMeter)
+		}
+
+		protected.POST("/events", d.eventsHandler.TrackEvents)
+
+		messages := protected.Group("/messages")
+		{
+			messages.GET("", d.inAppMessagesHandler.GetMessages)
+			messages.POST("/:id/events", d.inAppMessagesHandler.TrackMessageEvent)
 		}
 
 		winback := protected.Group("/winback")
@@ -502,7 +1102,8 @@ func setupProtectedRoutes(v1 *gin.RouterGroup, d *dependencies) {
 func setupAdminRoutes(v1 *gin.RouterGroup, d *dependencies, cfg *config.Config) {
 	admin := v1.Group("/admin")
 	admin.Use(d.jwtMiddleware.Authenticate())
-	admin.Use(middleware.AdminMiddleware(d.userRepo, cfg.JWT.Secret))
+	admin.Use(middleware.OAuthClientMiddleware(d.oauthClientRepo, middleware.AdminScopeFor))
+	admin.Use(middleware.AdminMiddleware(d.userRepo, d.jwtMiddleware.Keyfunc))
 	{
 		// Global admin routes — no X-App-ID required
 		admin.GET("/audit-log", d.adminHandler.GetAuditLog)
@@ -510,6 +1111,49 @@ func setupAdminRoutes(v1 *gin.RouterGroup, d *dependencies, cfg *config.Config)
 		admin.PUT("/settings", d.adminHandler.UpdatePlatformSettings)
 		admin.POST("/settings/password", d.adminHandler.ChangeAdminPassword)
 		admin.GET("/health", d.adminHandler.GetHealth)
+		admin.GET("/logging", d.loggingHandler.GetRequestLogSettings)
+		admin.PUT("/logging", d.loggingHandler.UpdateRequestLogSettings)
+
+		// Emergency switch stopping every experiment of every app
+		admin.GET("/experiments/kill-switch", d.experimentKillSwitch.GetKillSwitch)
+		admin.PUT("/experiments/kill-switch", d.experimentKillSwitch.UpdateKillSwitch)
+
+		// Data warehouse sync progress
+		admin.GET("/warehouse/sync-status", d.adminWarehouse.GetWarehouseSyncStatus)
+
+		// Data retention cleanup results
+		admin.GET("/retention", d.adminRetention.GetRetentionStatus)
+
+		// Redis cache inspection, namespace flushes and warming
+		admin.GET("/cache", d.cacheHandler.GetCacheStats)
+		admin.POST("/cache/flush", d.cacheHandler.FlushCache)
+		admin.POST("/cache/warm", d.cacheHandler.WarmCache)
+
+		// Dunning campaigns
+		admin.GET("/dunning/campaigns", d.dunningHandler.ListDunningCampaigns)
+		admin.POST("/dunning/campaigns", d.dunningHandler.CreateDunningCampaign)
+		admin.PUT("/dunning/campaigns/:id", d.dunningHandler.UpdateDunningCampaign)
+		admin.GET("/dunning/recovery", d.dunningHandler.GetDunningRecovery)
+
+		// Malformed webhook deliveries kept for inspection and re-parsing
+		admin.GET("/webhooks/quarantine", d.webhookQuarantine.ListQuarantinedWebhooks)
+		admin.GET("/webhooks/quarantine/:id", d.webhookQuarantine.GetQuarantinedWebhook)
+		admin.POST("/webhooks/quarantine/:id/reparse", d.webhookQuarantine.ReparseQuarantinedWebhook)
+		admin.POST("/webhooks/quarantine/:id/discard", d.webhookQuarantine.DiscardQuarantinedWebhook)
+
+		// OAuth clients for admin API automation
+		admin.GET("/oauth-clients", d.oauthClientsHandler.ListOAuthClients)
+		admin.POST("/oauth-clients", d.oauthClientsHandler.CreateOAuthClient)
+		admin.DELETE("/oauth-clients/:id", d.oauthClientsHandler.RevokeOAuthClient)
+
+		// Worker task execution history
+		admin.GET("/task-runs", d.taskRunsHandler.ListTaskRuns)
+		admin.GET("/task-runs/stats", d.taskRunsHandler.GetTaskRunStats)
+
+		// Sweeps of expired bandit pending rewards
+		admin.POST("/bandit/expiry-runs", d.banditExpiryHandler.StartExpiryRun)
+		admin.GET("/bandit/expiry-runs", d.banditExpiryHandler.ListExpiryRuns)
+		admin.GET("/bandit/expiry-runs/:id", d.banditExpiryHandler.GetExpiryRun)
 
 		// Apps management — global (CRUD for apps themselves)
 		admin.GET("/apps", d.appsHandler.ListApps)
@@ -535,12 +1179,21 @@ func setupAdminRoutes(v1 *gin.RouterGroup, d *dependencies, cfg *config.Config)
 			appScoped.POST("/users/:id/force-cancel", d.adminHandler.ForceCancel)
 			appScoped.POST("/users/:id/force-renew", d.adminHandler.ForceRenew)
 			appScoped.POST("/users/:id/grant-grace", d.adminHandler.GrantGracePeriod)
+			appScoped.POST("/users/:id/grace-period/extend", d.adjustmentHandler.ExtendGracePeriod)
+			appScoped.POST("/users/:id/subscription/extend", d.adjustmentHandler.ExtendSubscription)
+			appScoped.POST("/users/:id/impersonate", d.impersonationHandler.ImpersonateUser)
+			appScoped.GET("/users/:id/account-merges", d.accountMergeHandler.ListUserAccountMerges)
+			appScoped.POST("/account-merges", d.accountMergeHandler.MergeAccounts)
+			appScoped.GET("/account-merges/:id", d.accountMergeHandler.GetAccountMerge)
+			appScoped.POST("/account-merges/:id/undo", d.accountMergeHandler.UndoAccountMerge)
 			appScoped.GET("/users", d.adminHandler.ListUsers)
 			appScoped.GET("/users/search", d.adminHandler.SearchUsers)
 			appScoped.GET("/users/:id/profile", d.adminHandler.GetUserProfile)
+			appScoped.GET("/users/:id/experiments", d.experimentAssignments.ListForUser)
 
 			// Dashboard
 			appScoped.GET("/dashboard/metrics", d.adminHandler.GetDashboardMetrics)
+			appScoped.GET("/dashboard/aggregates", d.dashboardAggregates.GetDashboardAggregates)
 
 			// Subscriptions
 			appScoped.GET("/subscriptions", d.adminHandler.ListSubscriptions)
@@ -548,6 +1201,7 @@ func setupAdminRoutes(v1 *gin.RouterGroup, d *dependencies, cfg *config.Config)
 
 			// Transactions
 			appScoped.GET("/transactions", d.adminHandler.ListTransactions)
+			appScoped.GET("/transactions/export", d.adminHandler.ExportTransactions)
 			appScoped.GET("/transactions/:id", d.adminHandler.GetTransactionDetail)
 
 			// Webhooks
@@ -557,26 +1211,62 @@ func setupAdminRoutes(v1 *gin.RouterGroup, d *dependencies, cfg *config.Config)
 			// Analytics & revenue
 			appScoped.GET("/analytics/report", d.adminHandler.GetAnalyticsReport)
 			appScoped.GET("/revenue-ops", d.adminHandler.GetRevenueOps)
+			appScoped.GET("/analytics/tax-report", d.taxHandler.GetTaxReport)
+			appScoped.GET("/analytics/subscription-snapshots", d.snapshotsHandler.GetSnapshot)
+			appScoped.GET("/analytics/subscription-snapshots/diff", d.snapshotsHandler.GetSnapshotDiffs)
+			appScoped.GET("/analytics/subscription-snapshots/users/:user_id", d.snapshotsHandler.GetUserSnapshots)
+			appScoped.GET("/analytics/lifecycle-stages", d.lifecycleHandler.GetStageCounts)
+			appScoped.POST("/transactions/reconcile", d.taxHandler.ReconcileTransactions)
+
+			// SKAdNetwork attribution
+			appScoped.GET("/attribution/skan/schemas", d.adminSKANHandler.ListSKANSchemas)
+			appScoped.POST("/attribution/skan/schemas", d.adminSKANHandler.CreateSKANSchema)
+			appScoped.GET("/attribution/skan/campaigns", d.adminSKANHandler.GetSKANCampaignCohorts)
+			appScoped.GET("/analytics/acquisition", d.adminAcquisition.GetAcquisitionCohorts)
+			appScoped.GET("/analytics/offer-codes", d.adminOfferCodes.GetOfferCodeOutcomes)
+			appScoped.PUT("/offer-codes/campaign", d.adminOfferCodes.SetOfferCodeCampaign)
+			appScoped.GET("/notifications/suppressions", d.adminNotifications.GetSuppressions)
+			appScoped.GET("/analytics/embed-tokens", d.adminEmbedTokens.ListEmbedTokens)
+			appScoped.POST("/analytics/embed-tokens", d.adminEmbedTokens.CreateEmbedToken)
+			appScoped.DELETE("/analytics/embed-tokens/:id", d.adminEmbedTokens.RevokeEmbedToken)
 
 			// Extended analytics (LTV, cohort, churn)
 			appScoped.GET("/analytics/ltv", d.analyticsExtHandler.GetLTV)
 			appScoped.POST("/analytics/ltv", d.analyticsExtHandler.UpdateLTV)
 			appScoped.GET("/analytics/cohort-ltv", d.analyticsExtHandler.GetCohortLTV)
 			appScoped.GET("/analytics/churn-risk", d.analyticsExtHandler.GetChurnRisk)
+			appScoped.GET("/analytics/funnels/internal", d.paywallFunnelHandler.GetInternalFunnel)
+			appScoped.GET("/health/funnel", d.funnelHealthHandler.GetFunnelHealth)
+			appScoped.GET("/webhooks/latency", d.webhookLatencyHandler.GetWebhookLatency)
+			appScoped.GET("/webhooks/slo", d.webhookSLOHandler.GetWebhookSLOs)
+			appScoped.GET("/price-increases/pending", d.priceIncreasesHandler.ListPendingConsents)
+			appScoped.POST("/price-increases/prompt", d.priceIncreasesHandler.PromptPendingConsents)
 
 			// Experiments
 			appScoped.GET("/experiments", d.adminHandler.ListAdminExperiments)
 			appScoped.POST("/experiments", d.adminHandler.CreateAdminExperiment)
+			appScoped.GET("/experiments/meta", d.adminHandler.GetAdminExperimentMeta)
+			appScoped.GET("/experiment-templates", d.adminHandler.ListAdminExperimentTemplates)
+			appScoped.POST("/experiment-templates/:key/experiments", d.adminHandler.CreateAdminExperimentFromTemplate)
+			appScoped.POST("/experiments/:id/clone", d.adminHandler.CloneAdminExperiment)
 			appScoped.PUT("/experiments/:id", d.adminHandler.UpdateAdminExperiment)
 			appScoped.PUT("/experiments/:id/automation-policy", d.adminHandler.UpdateAdminExperimentAutomationPolicy)
 			appScoped.PUT("/experiments/:id/arms/pricing-tiers", d.adminHandler.UpdateAdminExperimentArmPricingTiers)
+			appScoped.POST("/experiments/:id/arms/:arm_id/archive", d.adminHandler.ArchiveAdminExperimentArm)
+			appScoped.POST("/experiments/:id/arms/:arm_id/pause", d.experimentKillSwitch.PauseExperimentArm)
+			appScoped.POST("/experiments/:id/arms/:arm_id/resume", d.experimentKillSwitch.ResumeExperimentArm)
 			appScoped.POST("/experiments/:id/confirm-winner", d.adminHandler.ConfirmAdminExperimentWinner)
 			appScoped.POST("/experiments/:id/hold-for-review", d.adminHandler.HoldAdminExperimentForReview)
 			appScoped.GET("/experiments/:id/lifecycle-audit", d.adminHandler.GetAdminExperimentLifecycleAuditHistory)
+			appScoped.GET("/experiments/:id/app-versions", d.adminHandler.GetAdminExperimentAppVersions)
+			appScoped.GET("/experiments/:id/timeline", d.adminHandler.GetAdminExperimentTimeline)
+			appScoped.GET("/experiments/:id/reactivations", d.reactivationHandler.GetExperimentReactivations)
 			appScoped.GET("/experiments/:id/winner-recommendation-audit", d.adminHandler.GetAdminExperimentWinnerRecommendationAuditHistory)
 			appScoped.POST("/experiments/:id/pause", d.adminHandler.PauseAdminExperiment)
 			appScoped.POST("/experiments/:id/resume", d.adminHandler.ResumeAdminExperiment)
 			appScoped.POST("/experiments/:id/complete", d.adminHandler.CompleteAdminExperiment)
+			appScoped.POST("/experiments/:id/archive", d.adminHandler.ArchiveAdminExperiment)
+			appScoped.POST("/experiments/:id/unarchive", d.adminHandler.UnarchiveAdminExperiment)
 			appScoped.POST("/experiments/:id/lock", d.adminHandler.LockAdminExperiment)
 			appScoped.POST("/experiments/:id/unlock", d.adminHandler.UnlockAdminExperiment)
 			appScoped.POST("/experiments/:id/repair", d.adminHandler.RepairAdminExperiment)
@@ -596,6 +1286,53 @@ func setupAdminRoutes(v1 *gin.RouterGroup, d *dependencies, cfg *config.Config)
 			appScoped.POST("/paywalls/:id/activate", d.adminPaywallsHandler.ActivatePaywall)
 			appScoped.DELETE("/paywalls/:id", d.adminPaywallsHandler.DeletePaywall)
 
+			// Paywall personalization rules
+			appScoped.GET("/paywall-rules", d.paywallRulesHandler.ListPaywallRules)
+			appScoped.POST("/paywall-rules", d.paywallRulesHandler.CreatePaywallRule)
+			appScoped.POST("/paywall-rules/dry-run", d.paywallRulesHandler.DryRunPaywallRules)
+			appScoped.GET("/paywall-rules/:id", d.paywallRulesHandler.GetPaywallRule)
+			appScoped.PUT("/paywall-rules/:id", d.paywallRulesHandler.UpdatePaywallRule)
+			appScoped.DELETE("/paywall-rules/:id", d.paywallRulesHandler.DeletePaywallRule)
+
+			// Scheduled in-app messages
+			appScoped.GET("/in-app-messages", d.adminInAppMessages.ListInAppMessages)
+			appScoped.POST("/in-app-messages", d.adminInAppMessages.CreateInAppMessage)
+			appScoped.GET("/in-app-messages/:id", d.adminInAppMessages.GetInAppMessage)
+			appScoped.PUT("/in-app-messages/:id", d.adminInAppMessages.UpdateInAppMessage)
+			appScoped.DELETE("/in-app-messages/:id", d.adminInAppMessages.DeleteInAppMessage)
+			appScoped.GET("/in-app-messages/:id/stats", d.adminInAppMessages.GetInAppMessageStats)
+
+			// Audience segments
+			appScoped.GET("/segments", d.segmentsHandler.ListSegments)
+			appScoped.POST("/segments", d.segmentsHandler.CreateSegment)
+			appScoped.GET("/segments/:id", d.segmentsHandler.GetSegment)
+			appScoped.PUT("/segments/:id", d.segmentsHandler.UpdateSegment)
+			appScoped.DELETE("/segments/:id", d.segmentsHandler.DeleteSegment)
+			appScoped.POST("/segments/:id/evaluate", d.segmentsHandler.EvaluateSegment)
+			appScoped.POST("/segments/:id/materialize", d.segmentsHandler.MaterializeSegment)
+			appScoped.POST("/segments/:id/notify", d.segmentsHandler.NotifySegment)
+			appScoped.PUT("/experiments/:id/segment", d.segmentsHandler.SetExperimentSegment)
+
+			// Notification send-time experiments
+			appScoped.POST("/send-time-experiments", d.sendTimeHandler.CreateExperiment)
+			appScoped.GET("/send-time-experiments/:id/results", d.sendTimeHandler.GetResults)
+			appScoped.POST("/review-prompt-experiments", d.reviewPromptHandler.CreateExperiment)
+
+			// Staged price rollouts
+			appScoped.GET("/price-rollouts", d.priceRolloutsHandler.ListPriceRollouts)
+			appScoped.POST("/price-rollouts", d.priceRolloutsHandler.CreatePriceRollout)
+			appScoped.GET("/price-rollouts/:id", d.priceRolloutsHandler.GetPriceRollout)
+			appScoped.GET("/price-rollouts/:id/results", d.priceRolloutsHandler.GetPriceRolloutResults)
+			appScoped.POST("/price-rollouts/:id/pause", d.priceRolloutsHandler.PausePriceRollout)
+			appScoped.POST("/price-rollouts/:id/resume", d.priceRolloutsHandler.ResumePriceRollout)
+			appScoped.POST("/price-rollouts/:id/complete", d.priceRolloutsHandler.CompletePriceRollout)
+			appScoped.POST("/price-rollouts/:id/rollback", d.priceRolloutsHandler.RollbackPriceRollout)
+
+			// PayPal plan mapping
+			appScoped.GET("/paypal/plans", d.adminPayPalPlans.ListPayPalPlans)
+			appScoped.PUT("/paypal/plans/:plan_id", d.adminPayPalPlans.PutPayPalPlan)
+			appScoped.DELETE("/paypal/plans/:plan_id", d.adminPayPalPlans.DeletePayPalPlan)
+
 			// Winback campaigns
 			appScoped.GET("/winback-campaigns", d.adminHandler.ListWinbackCampaigns)
 			appScoped.POST("/winback-campaigns", d.adminHandler.LaunchWinbackCampaign)
@@ -621,6 +1358,24 @@ func startServer(cfg *config.Config, router *gin.Engine) {
 		}
 	}()
 
+	// Metrics get their own listener so only the internal network can scrape them
+	var metricsSrv *http.Server
+	if cfg.Server.MetricsPort > 0 {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		metricsSrv = &http.Server{
+			Addr:        fmt.Sprintf(":%d", cfg.Server.MetricsPort),
+			Handler:     mux,
+			ReadTimeout: cfg.Server.ReadTimeout,
+		}
+		go func() {
+			logging.Logger.Info("Metrics listening", zap.String("addr", metricsSrv.Addr))
+			if err := metricsSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logging.Logger.Fatal("Failed to start metrics server", zap.Error(err))
+			}
+		}()
+	}
+
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -631,6 +1386,9 @@ func startServer(cfg *config.Config, router *gin.Engine) {
 	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer cancel()
 
+	if metricsSrv != nil {
+		_ = metricsSrv.Shutdown(ctx)
+	}
 	if err := srv.Shutdown(ctx); err != nil {
 		logging.Logger.Fatal("Server forced to shutdown", zap.Error(err))
 	}
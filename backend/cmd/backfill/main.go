@@ -0,0 +1,134 @@
+// cmd/backfill/main.go — recomputes historical analytics for a date range.
+//
+// Rebuilds daily aggregates, signup cohorts and user LTV after a bug fix or a
+// reporting time zone change. Dates are local days in each app's reporting
+// zone. Chunks commit one at a time with the run's progress, so an
+// interrupted run is continued with --resume and any chunk can be re-run.
+//
+// Usage:
+//
+//	go run ./cmd/backfill --from 2026-01-01 --to 2026-03-31 [flags]
+//	go run ./cmd/backfill --resume <run-id>
+//
+// Flags:
+//
+//	--database      PostgreSQL DSN (default: $DATABASE_URL)
+//	--app-id        Only this app (default: every active app)
+//	--from, --to    Inclusive local date range, YYYY-MM-DD
+//	--chunk-days    Days per chunk (default: 7)
+//	--steps         Comma-separated: aggregates,cohorts,ltv (default: all)
+//	--revenue-basis gross|net for LTV (default: $REVENUE_BASIS or gross)
+//	--resume        Continue a failed or interrupted run
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+)
+
+func main() {
+	var (
+		dbURL        string
+		appIDRaw     string
+		fromRaw      string
+		toRaw        string
+		chunkDays    int
+		stepsRaw     string
+		revenueBasis string
+		resumeRaw    string
+	)
+
+	flag.StringVar(&dbURL, "database", os.Getenv("DATABASE_URL"), "PostgreSQL connection string")
+	flag.StringVar(&appIDRaw, "app-id", "", "Only backfill this app (default: every active app)")
+	flag.StringVar(&fromRaw, "from", "", "First local day, YYYY-MM-DD")
+	flag.StringVar(&toRaw, "to", "", "Last local day, YYYY-MM-DD")
+	flag.IntVar(&chunkDays, "chunk-days", 7, "Days recomputed per chunk")
+	flag.StringVar(&stepsRaw, "steps", "aggregates,cohorts,ltv", "Comma-separated steps")
+	flag.StringVar(&revenueBasis, "revenue-basis", os.Getenv("REVENUE_BASIS"), "gross or net LTV")
+	flag.StringVar(&resumeRaw, "resume", "", "Run ID to resume")
+	flag.Parse()
+
+	if dbURL == "" {
+		log.Fatal("DATABASE_URL is required (flag --database or env var)")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	pool, err := pgxpool.New(ctx, dbURL)
+	if err != nil {
+		log.Fatalf("Cannot connect to database: %v", err)
+	}
+	defer pool.Close()
+
+	backfill := service.NewAnalyticsBackfillService(pool).
+		WithRevenueBasis(service.ParseRevenueBasis(revenueBasis))
+
+	var run *service.AnalyticsBackfillRun
+	if resumeRaw != "" {
+		runID, err := uuid.Parse(resumeRaw)
+		if err != nil {
+			log.Fatalf("--resume must be a run ID: %v", err)
+		}
+		if run, err = backfill.Resume(ctx, runID); err != nil {
+			log.Fatalf("Cannot resume: %v", err)
+		}
+	} else {
+		req, err := parseRequest(appIDRaw, fromRaw, toRaw, chunkDays, stepsRaw)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if run, err = backfill.Start(ctx, req); err != nil {
+			log.Fatalf("Cannot start backfill: %v", err)
+		}
+	}
+
+	fmt.Printf("Backfill %s: %s..%s, %d chunks of %d days, steps %v\n",
+		run.ID, run.From.Format("2006-01-02"), run.To.Format("2006-01-02"), run.ChunksTotal, run.ChunkDays, run.Steps)
+	started := time.Now()
+	err = backfill.Run(ctx, run, func(r *service.AnalyticsBackfillRun) {
+		fmt.Printf("  %d/%d chunks, through %s (%s)\n",
+			r.ChunksDone, r.ChunksTotal, r.CursorDate.Format("2006-01-02"), time.Since(started).Round(time.Second))
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Backfill failed: %v\nResume with: go run ./cmd/backfill --resume %s\n", err, run.ID)
+		os.Exit(1)
+	}
+	fmt.Printf("Backfill %s completed in %s\n", run.ID, time.Since(started).Round(time.Second))
+}
+
+func parseRequest(appIDRaw, fromRaw, toRaw string, chunkDays int, stepsRaw string) (service.AnalyticsBackfillRequest, error) {
+	req := service.AnalyticsBackfillRequest{ChunkDays: chunkDays}
+	if appIDRaw != "" {
+		appID, err := uuid.Parse(appIDRaw)
+		if err != nil {
+			return req, fmt.Errorf("--app-id must be a UUID: %w", err)
+		}
+		req.AppID = &appID
+	}
+	var err error
+	if req.From, err = time.Parse("2006-01-02", fromRaw); err != nil {
+		return req, fmt.Errorf("--from must be YYYY-MM-DD: %w", err)
+	}
+	if req.To, err = time.Parse("2006-01-02", toRaw); err != nil {
+		return req, fmt.Errorf("--to must be YYYY-MM-DD: %w", err)
+	}
+	for _, step := range strings.Split(stepsRaw, ",") {
+		if step = strings.TrimSpace(step); step != "" {
+			req.Steps = append(req.Steps, service.BackfillStep(step))
+		}
+	}
+	return req, req.Validate()
+}
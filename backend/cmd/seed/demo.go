@@ -0,0 +1,599 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// demoOptions shapes the generated dataset. The same seed, size and clock
+// produce the same data; only the row IDs differ between runs.
+type demoOptions struct {
+	AppID uuid.UUID
+	Users int
+	Days  int
+	Seed  int64
+	Now   time.Time
+}
+
+type demoPlan struct {
+	planType string
+	product  string
+	price    float64 // USD
+	months   int     // billing period, 0 for lifetime
+	// churn is the chance a subscriber cancels before a renewal
+	churn float64
+}
+
+var demoPlans = []weighted[demoPlan]{
+	{0.62, demoPlan{"monthly", "com.paywall.demo.premium.monthly", 9.99, 1, 0.12}},
+	{0.32, demoPlan{"annual", "com.paywall.demo.premium.annual", 59.99, 12, 0.35}},
+	{0.06, demoPlan{"lifetime", "com.paywall.demo.premium.lifetime", 149.99, 0, 0}},
+}
+
+type demoCountry struct {
+	code     string
+	currency string
+	// priceRatio turns a USD price into the local price point
+	priceRatio float64
+	// usdRate turns local revenue into USD for bandit rewards
+	usdRate float64
+	vat     float64
+}
+
+var demoCountries = []weighted[demoCountry]{
+	{0.44, demoCountry{"US", "USD", 1, 1, 0}},
+	{0.12, demoCountry{"GB", "GBP", 0.8, 1.27, 0.20}},
+	{0.12, demoCountry{"DE", "EUR", 0.92, 1.08, 0.19}},
+	{0.08, demoCountry{"FR", "EUR", 0.92, 1.08, 0.20}},
+	{0.10, demoCountry{"CA", "CAD", 1.35, 0.73, 0.05}},
+	{0.14, demoCountry{"BR", "BRL", 4.9, 0.2, 0}},
+}
+
+// demoProvider is where a purchase went through and what it charges
+type demoProvider struct {
+	source   string // subscriptions.source
+	provider string // transactions.provider
+	channel  string // users.purchase_channel
+	feeRate  float64
+	feeFixed float64
+}
+
+var (
+	appleProvider  = demoProvider{"iap", "apple", "iap", 0.15, 0}
+	googleProvider = demoProvider{"iap", "google", "iap", 0.15, 0}
+	amazonProvider = demoProvider{"iap", "amazon", "iap", 0.20, 0}
+	webProviders   = []weighted[demoProvider]{
+		{0.60, demoProvider{"stripe", "stripe", "stripe", 0.029, 0.30}},
+		{0.25, demoProvider{"paddle", "paddle", "web", 0.05, 0.50}},
+		{0.15, demoProvider{"paypal", "paypal", "web", 0.0349, 0.49}},
+	}
+)
+
+var demoPlatforms = []weighted[string]{{0.52, "ios"}, {0.41, "android"}, {0.07, "web"}}
+
+// demoBaseConversion is the share of users of a platform who ever subscribe
+var demoBaseConversion = map[string]float64{"ios": 0.11, "android": 0.07, "web": 0.09}
+
+var demoAppVersions = []string{"2.3.0", "2.4.0", "2.4.1", "2.5.0"}
+
+var demoPlacements = []string{"onboarding", "settings", "feature_gate"}
+
+const (
+	// demoRenewalFailure is the chance a renewal charge is declined; a
+	// declined charge is retried the next day and recovers demoRetryRecovery
+	// of the time
+	demoRenewalFailure = 0.05
+	demoRetryRecovery  = 0.7
+	demoRefundRate     = 0.02
+	demoGraceDays      = 7
+	demoMaxSessions    = 40
+)
+
+type demoArm struct {
+	name        string
+	description string
+	isControl   bool
+	// conversionLift scales the chance an assigned user subscribes
+	conversionLift float64
+	// priceRatio scales the monthly price shown to the arm
+	priceRatio float64
+}
+
+type demoExperiment struct {
+	name        string
+	description string
+	objective   string
+	// startShare is how far into the window the experiment starts
+	startShare float64
+	// coverage is the share of users signing up meanwhile who are enrolled
+	coverage float64
+	arms     []demoArm
+}
+
+var demoExperiments = []demoExperiment{
+	{
+		name:        "Demo: monthly price test",
+		description: "Thompson sampling across three monthly price points.",
+		objective:   "revenue",
+		startShare:  0,
+		coverage:    0.7,
+		arms: []demoArm{
+			{"Control $9.99", "Current monthly price", true, 1, 1},
+			{"Intro $7.99", "Lower monthly price", false, 1.25, 0.8},
+			{"Premium $12.99", "Higher monthly price", false, 0.8, 1.3},
+		},
+	},
+	{
+		name:        "Demo: paywall headline",
+		description: "Social proof headline against the current copy.",
+		objective:   "conversion",
+		startShare:  0.4,
+		coverage:    0.5,
+		arms: []demoArm{
+			{"Control", "Current headline", true, 1, 1},
+			{"Social proof", "\"Join 1M+ subscribers\" headline", false, 1.15, 1},
+		},
+	},
+}
+
+type weighted[T any] struct {
+	weight float64
+	value  T
+}
+
+func pick[T any](rng *rand.Rand, options []weighted[T]) T {
+	total := 0.0
+	for _, option := range options {
+		total += option.weight
+	}
+	roll := rng.Float64() * total
+	for _, option := range options {
+		if roll < option.weight {
+			return option.value
+		}
+		roll -= option.weight
+	}
+	return options[len(options)-1].value
+}
+
+// Rows, one struct per table, in the columns written by writeDemoData
+
+type demoUser struct {
+	ID              uuid.UUID
+	PlatformUserID  string
+	DeviceID        string
+	Platform        string
+	AppVersion      string
+	Email           string
+	PurchaseChannel *string
+	SessionCount    int
+	HasViewedAds    bool
+	CreatedAt       time.Time
+}
+
+type demoSubscription struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Status    string
+	Source    string
+	Platform  string
+	ProductID string
+	PlanType  string
+	ExpiresAt time.Time
+	AutoRenew bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type demoTransaction struct {
+	ID                    uuid.UUID
+	UserID                uuid.UUID
+	SubscriptionID        uuid.UUID
+	Amount                float64
+	Currency              string
+	Status                string
+	ProviderTxID          string
+	OriginalTransactionID string
+	CreatedAt             time.Time
+	StoreFee              float64
+	TaxAmount             float64
+	NetAmount             float64
+	CountryCode           string
+	Provider              string
+	ProductID             string
+	RefundedAt            *time.Time
+	RefundReference       *string
+}
+
+type demoExperimentRow struct {
+	ID          uuid.UUID
+	Name        string
+	Description string
+	Objective   string
+	StartAt     time.Time
+	Arms        []*demoArmRow
+}
+
+type demoArmRow struct {
+	ID          uuid.UUID
+	Name        string
+	Description string
+	IsControl   bool
+	Samples     int
+	Conversions int
+	RevenueUSD  float64
+}
+
+type demoAssignment struct {
+	ID           uuid.UUID
+	ExperimentID uuid.UUID
+	ArmID        uuid.UUID
+	UserID       uuid.UUID
+	AppVersion   string
+	AssignedAt   time.Time
+}
+
+// demoFunnelEvent is a bandit_impression_events row
+type demoFunnelEvent struct {
+	ExperimentID uuid.UUID
+	ArmID        uuid.UUID
+	UserID       uuid.UUID
+	EventType    string // impression or purchase_initiated
+	Placement    string
+	OccurredAt   time.Time
+}
+
+type demoConversion struct {
+	ExperimentID   uuid.UUID
+	ArmID          uuid.UUID
+	UserID         uuid.UUID
+	TransactionID  uuid.UUID
+	Amount         float64
+	Currency       string
+	ConversionRate float64
+	OccurredAt     time.Time
+}
+
+type demoEvent struct {
+	UserID     uuid.UUID
+	Name       string
+	Properties map[string]any
+	OccurredAt time.Time
+}
+
+type demoDataset struct {
+	Start         time.Time
+	End           time.Time
+	Users         []*demoUser
+	Subscriptions []*demoSubscription
+	Transactions  []*demoTransaction
+	Experiments   []*demoExperimentRow
+	Assignments   []*demoAssignment
+	FunnelEvents  []*demoFunnelEvent
+	Conversions   []*demoConversion
+	Events        []*demoEvent
+}
+
+// demoGenerator builds a dataset from one seeded source
+type demoGenerator struct {
+	opts demoOptions
+	rng  *rand.Rand
+	tag  string
+	data *demoDataset
+	txNo int
+}
+
+func generateDemoData(opts demoOptions) *demoDataset {
+	g := &demoGenerator{
+		opts: opts,
+		rng:  rand.New(rand.NewSource(opts.Seed)),
+		// tag keeps platform user IDs and emails unique across runs
+		tag:  fmt.Sprintf("%x", opts.Now.UnixNano()),
+		data: &demoDataset{Start: opts.Now.AddDate(0, 0, -opts.Days), End: opts.Now},
+	}
+	for _, spec := range demoExperiments {
+		g.addExperiment(spec)
+	}
+	for i := 0; i < opts.Users; i++ {
+		g.addUser(i)
+	}
+	return g.data
+}
+
+func (g *demoGenerator) addExperiment(spec demoExperiment) {
+	window := g.opts.Now.Sub(g.data.Start)
+	row := &demoExperimentRow{
+		ID:          uuid.New(),
+		Name:        spec.name,
+		Description: spec.description,
+		Objective:   spec.objective,
+		StartAt:     g.data.Start.Add(time.Duration(spec.startShare * float64(window))),
+	}
+	for _, arm := range spec.arms {
+		row.Arms = append(row.Arms, &demoArmRow{ID: uuid.New(), Name: arm.name, Description: arm.description, IsControl: arm.isControl})
+	}
+	g.data.Experiments = append(g.data.Experiments, row)
+}
+
+// between returns a time uniformly within [from, to)
+func (g *demoGenerator) between(from, to time.Time) time.Time {
+	if !to.After(from) {
+		return from
+	}
+	return from.Add(time.Duration(g.rng.Int63n(int64(to.Sub(from)))))
+}
+
+func (g *demoGenerator) addUser(i int) {
+	// Signups grow over the window: the density rises linearly towards now
+	window := g.opts.Now.Sub(g.data.Start)
+	createdAt := g.data.Start.Add(time.Duration(math.Sqrt(g.rng.Float64()) * float64(window)))
+	platform := pick(g.rng, demoPlatforms)
+	country := pick(g.rng, demoCountries)
+
+	user := &demoUser{
+		ID:             uuid.New(),
+		PlatformUserID: fmt.Sprintf("demo_%s_%05d", g.tag, i),
+		DeviceID:       fmt.Sprintf("demo-device-%s-%05d", g.tag, i),
+		Platform:       platform,
+		AppVersion:     demoAppVersions[g.rng.Intn(len(demoAppVersions))],
+		Email:          fmt.Sprintf("demo+%s.%05d@example.com", g.tag, i),
+		HasViewedAds:   g.rng.Float64() < 0.3,
+		CreatedAt:      createdAt,
+	}
+	g.data.Users = append(g.data.Users, user)
+
+	// Enrol in the experiments running at signup; each arm scales the chance
+	// to subscribe and may change the monthly price
+	conversion := demoBaseConversion[platform]
+	priceRatio := 1.0
+	type enrolment struct {
+		experiment *demoExperimentRow
+		arm        *demoArmRow
+	}
+	var enrolments []enrolment
+	for e, spec := range demoExperiments {
+		experiment := g.data.Experiments[e]
+		if createdAt.Before(experiment.StartAt) || g.rng.Float64() >= spec.coverage {
+			continue
+		}
+		a := g.rng.Intn(len(spec.arms))
+		conversion *= spec.arms[a].conversionLift
+		priceRatio *= spec.arms[a].priceRatio
+		arm := experiment.Arms[a]
+		arm.Samples++
+		enrolments = append(enrolments, enrolment{experiment, arm})
+		g.data.Assignments = append(g.data.Assignments, &demoAssignment{
+			ID: uuid.New(), ExperimentID: experiment.ID, ArmID: arm.ID, UserID: user.ID,
+			AppVersion: user.AppVersion, AssignedAt: createdAt,
+		})
+	}
+
+	converted := g.rng.Float64() < conversion
+	var subscribedAt time.Time
+	if converted {
+		// Most subscribers convert within a few days of signing up
+		subscribedAt = createdAt.Add(time.Duration(g.rng.ExpFloat64() * float64(36*time.Hour)))
+		converted = subscribedAt.Before(g.opts.Now)
+	}
+
+	sessionsEnd := g.opts.Now
+	var first *demoTransaction
+	if converted {
+		var sub *demoSubscription
+		var provider demoProvider
+		sub, first, provider = g.addSubscription(user, country, priceRatio, subscribedAt)
+		user.PurchaseChannel = &provider.channel
+		if sub.Status != "active" && sub.Status != "grace" {
+			sessionsEnd = sub.ExpiresAt
+		}
+	} else if g.rng.Float64() < 0.5 {
+		// Half of the users who never subscribe stop using the app early
+		sessionsEnd = g.between(createdAt, g.opts.Now)
+	}
+
+	g.addSessions(user, sessionsEnd)
+
+	for _, enrolled := range enrolments {
+		placement := demoPlacements[g.rng.Intn(len(demoPlacements))]
+		impressions := 1 + g.rng.Intn(3)
+		for n := 0; n < impressions; n++ {
+			g.data.FunnelEvents = append(g.data.FunnelEvents, &demoFunnelEvent{
+				ExperimentID: enrolled.experiment.ID, ArmID: enrolled.arm.ID, UserID: user.ID,
+				EventType: "impression", Placement: placement,
+				OccurredAt: g.between(createdAt, clampTime(createdAt.Add(72*time.Hour), g.opts.Now)),
+			})
+		}
+		if converted || g.rng.Float64() < 0.3 {
+			initiatedAt := g.between(createdAt, clampTime(createdAt.Add(72*time.Hour), g.opts.Now))
+			if converted {
+				initiatedAt = subscribedAt.Add(-time.Minute)
+			}
+			g.data.FunnelEvents = append(g.data.FunnelEvents, &demoFunnelEvent{
+				ExperimentID: enrolled.experiment.ID, ArmID: enrolled.arm.ID, UserID: user.ID,
+				EventType: "purchase_initiated", Placement: placement, OccurredAt: initiatedAt,
+			})
+		}
+		if first != nil {
+			rate := country.usdRate
+			enrolled.arm.Conversions++
+			enrolled.arm.RevenueUSD += round2(first.Amount * rate)
+			g.data.Conversions = append(g.data.Conversions, &demoConversion{
+				ExperimentID: enrolled.experiment.ID, ArmID: enrolled.arm.ID, UserID: user.ID,
+				TransactionID: first.ID, Amount: first.Amount, Currency: first.Currency,
+				ConversionRate: rate, OccurredAt: first.CreatedAt,
+			})
+		}
+	}
+}
+
+func clampTime(t, limit time.Time) time.Time {
+	if t.After(limit) {
+		return limit
+	}
+	return t
+}
+
+// addSessions records app opens between signup and end, some of them showing
+// the paywall
+func (g *demoGenerator) addSessions(user *demoUser, end time.Time) {
+	days := end.Sub(user.CreatedAt).Hours() / 24
+	sessions := 1 + int(days*g.rng.Float64()*0.8)
+	if sessions > demoMaxSessions {
+		sessions = demoMaxSessions
+	}
+	user.SessionCount = sessions
+	for n := 0; n < sessions; n++ {
+		at := user.CreatedAt
+		if n > 0 {
+			at = g.between(user.CreatedAt, end)
+		}
+		g.data.Events = append(g.data.Events, &demoEvent{
+			UserID: user.ID, Name: "app_open", OccurredAt: at,
+			Properties: map[string]any{"platform": user.Platform, "app_version": user.AppVersion},
+		})
+		if g.rng.Float64() < 0.4 {
+			g.data.Events = append(g.data.Events, &demoEvent{
+				UserID: user.ID, Name: "paywall_shown", OccurredAt: at.Add(30 * time.Second),
+				Properties: map[string]any{"placement": demoPlacements[g.rng.Intn(len(demoPlacements))]},
+			})
+		}
+	}
+}
+
+func (g *demoGenerator) providerFor(platform string) demoProvider {
+	switch platform {
+	case "ios":
+		return appleProvider
+	case "android":
+		if g.rng.Float64() < 0.05 {
+			return amazonProvider
+		}
+		return googleProvider
+	default:
+		return pick(g.rng, webProviders)
+	}
+}
+
+// addSubscription bills a subscription from start until it churns, is
+// refunded or reaches now, and returns it with its first charge
+func (g *demoGenerator) addSubscription(user *demoUser, country demoCountry, priceRatio float64, start time.Time) (*demoSubscription, *demoTransaction, demoProvider) {
+	plan := pick(g.rng, demoPlans)
+	provider := g.providerFor(user.Platform)
+	usdPrice := plan.price
+	if plan.planType == "monthly" {
+		usdPrice = math.Ceil(plan.price*priceRatio) - 0.01
+	}
+	amount := math.Ceil(usdPrice*country.priceRatio) - 0.01
+
+	sub := &demoSubscription{
+		ID: uuid.New(), UserID: user.ID, Source: provider.source, Platform: user.Platform,
+		ProductID: plan.product, PlanType: plan.planType, AutoRenew: plan.months > 0,
+		CreatedAt: start, UpdatedAt: start,
+	}
+	g.data.Subscriptions = append(g.data.Subscriptions, sub)
+
+	originalTxID := ""
+	var first *demoTransaction
+	charge := func(at time.Time, status string) *demoTransaction {
+		g.txNo++
+		tx := g.transaction(user, sub, provider, country, plan, amount, at, status)
+		if originalTxID == "" {
+			originalTxID = tx.ProviderTxID
+		}
+		tx.OriginalTransactionID = originalTxID
+		g.data.Transactions = append(g.data.Transactions, tx)
+		sub.UpdatedAt = at
+		return tx
+	}
+
+	first = charge(start, "success")
+	if plan.months == 0 {
+		sub.Status = "active"
+		sub.ExpiresAt = start.AddDate(100, 0, 0)
+		g.maybeRefund(first, sub)
+		return sub, first, provider
+	}
+
+	periodStart := start
+	for {
+		periodEnd := periodStart.AddDate(0, plan.months, 0)
+		sub.ExpiresAt = periodEnd
+		if g.maybeRefund(g.data.Transactions[len(g.data.Transactions)-1], sub) {
+			return sub, first, provider
+		}
+		if g.rng.Float64() < plan.churn {
+			// Cancelled: stays active until the paid period ends
+			sub.AutoRenew = false
+			sub.Status = "expired"
+			if periodEnd.After(g.opts.Now) {
+				sub.Status = "active"
+			}
+			return sub, first, provider
+		}
+		if periodEnd.After(g.opts.Now) {
+			sub.Status = "active"
+			return sub, first, provider
+		}
+		if g.rng.Float64() < demoRenewalFailure {
+			charge(periodEnd, "failed")
+			retryAt := periodEnd.Add(24 * time.Hour)
+			if retryAt.After(g.opts.Now) || g.rng.Float64() >= demoRetryRecovery {
+				// Billing retry still open or given up on
+				sub.Status = "expired"
+				if g.opts.Now.Sub(periodEnd) < demoGraceDays*24*time.Hour {
+					sub.Status = "grace"
+				}
+				return sub, first, provider
+			}
+			charge(retryAt, "success")
+		} else {
+			charge(periodEnd, "success")
+		}
+		periodStart = periodEnd
+	}
+}
+
+// maybeRefund refunds a successful charge a few days after it happened,
+// cancelling the subscription; it reports whether it did
+func (g *demoGenerator) maybeRefund(tx *demoTransaction, sub *demoSubscription) bool {
+	if tx.Status != "success" || g.rng.Float64() >= demoRefundRate {
+		return false
+	}
+	refundedAt := tx.CreatedAt.Add(time.Duration(1+g.rng.Intn(5)) * 24 * time.Hour)
+	if refundedAt.After(g.opts.Now) {
+		return false
+	}
+	reference := "demo-refund-" + tx.ProviderTxID
+	tx.Status = "refunded"
+	tx.RefundedAt = &refundedAt
+	tx.RefundReference = &reference
+	sub.Status = "cancelled"
+	sub.AutoRenew = false
+	sub.ExpiresAt = refundedAt
+	sub.UpdatedAt = refundedAt
+	return true
+}
+
+func (g *demoGenerator) transaction(user *demoUser, sub *demoSubscription, provider demoProvider, country demoCountry, plan demoPlan, amount float64, at time.Time, status string) *demoTransaction {
+	tx := &demoTransaction{
+		ID: uuid.New(), UserID: user.ID, SubscriptionID: sub.ID,
+		Amount: amount, Currency: country.currency, Status: status,
+		ProviderTxID: fmt.Sprintf("demo_%s_%s_%06d", provider.provider, g.tag, g.txNo),
+		CreatedAt:    at, CountryCode: country.code, Provider: provider.provider, ProductID: plan.product,
+	}
+	if status != "failed" {
+		// Prices are tax inclusive; the store takes its fee from the gross
+		tx.TaxAmount = round2(amount * country.vat / (1 + country.vat))
+		tx.StoreFee = round2(amount*provider.feeRate + provider.feeFixed*country.priceRatio)
+		tx.NetAmount = round2(amount - tx.TaxAmount - tx.StoreFee)
+	}
+	return tx
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
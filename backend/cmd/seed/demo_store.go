@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	demoAppName   = "com.paywall.demo"
+	demoAppTitle  = "Paywall Demo"
+	assignmentTTL = 24 * time.Hour
+)
+
+// ensureDemoApp returns the demo app, creating it on first use
+func ensureDemoApp(ctx context.Context, pool *pgxpool.Pool) (uuid.UUID, error) {
+	var appID uuid.UUID
+	err := pool.QueryRow(ctx, `
+		INSERT INTO apps (name, display_name, platform, bundle_id)
+		VALUES ($1, $2, 'both', $1)
+		ON CONFLICT (bundle_id) DO UPDATE SET updated_at = now()
+		RETURNING id
+	`, demoAppName, demoAppTitle).Scan(&appID)
+	return appID, err
+}
+
+// writeDemoData stores the dataset in one transaction, so a failed run
+// leaves nothing behind
+func writeDemoData(ctx context.Context, pool *pgxpool.Pool, appID uuid.UUID, data *demoDataset) error {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for month := monthStart(data.Start); !month.After(data.End); month = month.AddDate(0, 1, 0) {
+		if _, err := tx.Exec(ctx, `SELECT ensure_analytics_events_partition($1)`, month); err != nil {
+			return fmt.Errorf("failed to ensure partition for %s: %w", month.Format("2006-01"), err)
+		}
+	}
+
+	if err := copyRows(ctx, tx, "users",
+		[]string{"id", "app_id", "platform_user_id", "device_id", "platform", "app_version", "email",
+			"purchase_channel", "session_count", "has_viewed_ads", "created_at"},
+		len(data.Users), func(n int) []any {
+			u := data.Users[n]
+			return []any{u.ID, appID, u.PlatformUserID, u.DeviceID, u.Platform, u.AppVersion, u.Email,
+				u.PurchaseChannel, u.SessionCount, u.HasViewedAds, u.CreatedAt}
+		}); err != nil {
+		return err
+	}
+
+	if err := copyRows(ctx, tx, "subscriptions",
+		[]string{"id", "app_id", "user_id", "status", "source", "platform", "product_id", "plan_type",
+			"expires_at", "auto_renew", "created_at", "updated_at"},
+		len(data.Subscriptions), func(n int) []any {
+			s := data.Subscriptions[n]
+			return []any{s.ID, appID, s.UserID, s.Status, s.Source, s.Platform, s.ProductID, s.PlanType,
+				s.ExpiresAt, s.AutoRenew, s.CreatedAt, s.UpdatedAt}
+		}); err != nil {
+		return err
+	}
+
+	if err := copyRows(ctx, tx, "transactions",
+		[]string{"id", "app_id", "user_id", "subscription_id", "amount", "currency", "status",
+			"provider_tx_id", "original_transaction_id", "created_at", "store_fee", "tax_amount",
+			"net_amount", "country_code", "provider", "product_id", "environment", "refunded_at", "refund_reference"},
+		len(data.Transactions), func(n int) []any {
+			t := data.Transactions[n]
+			var net *float64
+			if t.Status != "failed" {
+				net = &t.NetAmount
+			}
+			return []any{t.ID, appID, t.UserID, t.SubscriptionID, t.Amount, t.Currency, t.Status,
+				t.ProviderTxID, t.OriginalTransactionID, t.CreatedAt, t.StoreFee, t.TaxAmount,
+				net, t.CountryCode, t.Provider, t.ProductID, "production", t.RefundedAt, t.RefundReference}
+		}); err != nil {
+		return err
+	}
+
+	for _, experiment := range data.Experiments {
+		if err := insertDemoExperiment(ctx, tx, appID, experiment); err != nil {
+			return err
+		}
+	}
+
+	if err := copyRows(ctx, tx, "ab_test_assignments",
+		[]string{"id", "experiment_id", "user_id", "arm_id", "assigned_at", "expires_at", "app_version"},
+		len(data.Assignments), func(n int) []any {
+			a := data.Assignments[n]
+			return []any{a.ID, a.ExperimentID, a.UserID, a.ArmID, a.AssignedAt, a.AssignedAt.Add(assignmentTTL), a.AppVersion}
+		}); err != nil {
+		return err
+	}
+
+	if err := copyRows(ctx, tx, "bandit_assignment_events",
+		[]string{"id", "assignment_id", "experiment_id", "user_id", "arm_id", "event_type", "occurred_at"},
+		len(data.Assignments), func(n int) []any {
+			a := data.Assignments[n]
+			return []any{uuid.New(), a.ID, a.ExperimentID, a.UserID, a.ArmID, "assigned", a.AssignedAt}
+		}); err != nil {
+		return err
+	}
+
+	if err := copyRows(ctx, tx, "bandit_impression_events",
+		[]string{"id", "experiment_id", "arm_id", "user_id", "event_type", "placement", "occurred_at"},
+		len(data.FunnelEvents), func(n int) []any {
+			e := data.FunnelEvents[n]
+			return []any{uuid.New(), e.ExperimentID, e.ArmID, e.UserID, e.EventType, e.Placement, e.OccurredAt}
+		}); err != nil {
+		return err
+	}
+
+	if err := copyRows(ctx, tx, "bandit_conversion_events",
+		[]string{"id", "experiment_id", "arm_id", "user_id", "transaction_id", "event_type",
+			"original_reward_value", "original_currency", "normalized_reward_value", "normalized_currency",
+			"conversion_rate", "rate_source", "occurred_at"},
+		len(data.Conversions), func(n int) []any {
+			c := data.Conversions[n]
+			return []any{uuid.New(), c.ExperimentID, c.ArmID, c.UserID, c.TransactionID, "direct_reward",
+				c.Amount, c.Currency, round2(c.Amount * c.ConversionRate), "USD",
+				c.ConversionRate, "demo", c.OccurredAt}
+		}); err != nil {
+		return err
+	}
+
+	if err := copyRows(ctx, tx, "bandit_conversion_transactions",
+		[]string{"transaction_id", "experiment_id", "arm_id", "source", "recorded_at"},
+		len(data.Conversions), func(n int) []any {
+			c := data.Conversions[n]
+			return []any{c.TransactionID, c.ExperimentID, c.ArmID, "direct_reward", c.OccurredAt}
+		}); err != nil {
+		return err
+	}
+
+	if err := copyRows(ctx, tx, "analytics_events",
+		[]string{"id", "app_id", "user_id", "source", "event_name", "properties", "occurred_at", "received_at"},
+		len(data.Events), func(n int) []any {
+			e := data.Events[n]
+			return []any{uuid.New(), appID, e.UserID, "client", e.Name, e.Properties, e.OccurredAt, e.OccurredAt}
+		}); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// insertDemoExperiment stores a running Thompson sampling experiment with its
+// arms and their Beta posteriors
+func insertDemoExperiment(ctx context.Context, tx pgx.Tx, appID uuid.UUID, experiment *demoExperimentRow) error {
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO ab_tests (id, app_id, name, description, status, start_at, algorithm_type, is_bandit,
+		                      min_sample_size, confidence_threshold, objective_type, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, 'running', $5, 'thompson_sampling', true, 100, 0.95, $6, $5, $5)
+	`, experiment.ID, appID, experiment.Name, experiment.Description, experiment.StartAt, experiment.Objective); err != nil {
+		return fmt.Errorf("failed to insert experiment %q: %w", experiment.Name, err)
+	}
+
+	for _, arm := range experiment.Arms {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO ab_test_arms (id, experiment_id, name, description, is_control, traffic_weight, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, 1.0, $6, $6)
+		`, arm.ID, experiment.ID, arm.Name, arm.Description, arm.IsControl, experiment.StartAt); err != nil {
+			return fmt.Errorf("failed to insert arm %q: %w", arm.Name, err)
+		}
+
+		var avgReward *float64
+		if arm.Samples > 0 {
+			avg := arm.RevenueUSD / float64(arm.Samples)
+			avgReward = &avg
+		}
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO ab_test_arm_stats (arm_id, alpha, beta, samples, conversions, revenue, revenue_usd, avg_reward)
+			VALUES ($1, $2, $3, $4, $5, $6, $6, $7)
+		`, arm.ID, float64(1+arm.Conversions), float64(1+arm.Samples-arm.Conversions),
+			arm.Samples, arm.Conversions, round2(arm.RevenueUSD), avgReward); err != nil {
+			return fmt.Errorf("failed to insert stats of arm %q: %w", arm.Name, err)
+		}
+	}
+	return nil
+}
+
+func copyRows(ctx context.Context, tx pgx.Tx, table string, columns []string, count int, row func(n int) []any) error {
+	if count == 0 {
+		return nil
+	}
+	_, err := tx.CopyFrom(ctx, pgx.Identifier{table}, columns, pgx.CopyFromSlice(count, func(n int) ([]any, error) {
+		return row(n), nil
+	}))
+	if err != nil {
+		return fmt.Errorf("failed to copy %d %s rows: %w", count, table, err)
+	}
+	return nil
+}
+
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
@@ -1,8 +1,19 @@
-// cmd/seed/main.go — creates or updates the first superadmin user.
+// cmd/seed/main.go — creates or updates the first superadmin user, or fills
+// a dev database with synthetic demo data.
 //
 // Usage:
 //
 //	go run ./cmd/seed --email admin@example.com --password secret123 [--name "Admin User"]
+//	go run ./cmd/seed --demo [--users 2000] [--days 90] [--seed 1] [--app-id <uuid>]
+//
+// --demo generates users across iOS, Android and web, subscriptions through
+// Apple, Google, Amazon, Stripe, Paddle and PayPal with renewals, failed
+// charges and refunds, running bandit experiments whose arm stats match
+// their assignments and conversions, and client analytics events over the
+// last --days days. It then backfills the daily aggregates, cohorts and LTV
+// and refreshes the dashboard views. Without --app-id the data goes to a
+// "com.paywall.demo" app. Each run adds a new batch; --seed makes the batch
+// reproducible.
 //
 // Environment variables (fallbacks):
 //
@@ -16,7 +27,9 @@ import (
 	"log"
 	"os"
 	"strings"
+	"time"
 
+	"github.com/bivex/paywall-iap/internal/domain/service"
 	"github.com/bivex/paywall-iap/internal/infrastructure/persistence/sqlc/generated"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -29,17 +42,36 @@ func main() {
 		email    string
 		password string
 		name     string
+
+		demo     bool
+		users    int
+		days     int
+		seed     int64
+		appIDRaw string
 	)
 
 	flag.StringVar(&dbURL, "database", os.Getenv("DATABASE_URL"), "PostgreSQL connection string")
 	flag.StringVar(&email, "email", "", "Admin email address (required)")
 	flag.StringVar(&password, "password", "", "Admin password (required, min 8 chars)")
 	flag.StringVar(&name, "name", "Admin", "Display name (stored as platform_user_id)")
+	flag.BoolVar(&demo, "demo", false, "Generate synthetic demo data instead of an admin user")
+	flag.IntVar(&users, "users", 2000, "Demo users to generate")
+	flag.IntVar(&days, "days", 90, "Days of demo history")
+	flag.Int64Var(&seed, "seed", 1, "Random seed of the demo data")
+	flag.StringVar(&appIDRaw, "app-id", "", "App receiving the demo data (default: the demo app)")
 	flag.Parse()
 
 	if dbURL == "" {
 		log.Fatal("DATABASE_URL is required (flag --database or env var)")
 	}
+
+	if demo {
+		if users < 1 || days < 1 || days > 400 {
+			log.Fatal("--users must be at least 1 and --days between 1 and 400")
+		}
+		seedDemo(dbURL, appIDRaw, demoOptions{Users: users, Days: days, Seed: seed, Now: time.Now().UTC()})
+		return
+	}
 	if email == "" || password == "" {
 		log.Fatal("--email and --password are required")
 	}
@@ -120,3 +152,48 @@ func maskPassword(p string) string {
 	}
 	return p[:2] + strings.Repeat("*", len(p)-2)
 }
+
+func seedDemo(dbURL, appIDRaw string, opts demoOptions) {
+	ctx := context.Background()
+
+	pool, err := pgxpool.New(ctx, dbURL)
+	if err != nil {
+		log.Fatalf("Cannot connect to database: %v", err)
+	}
+	defer pool.Close()
+
+	if appIDRaw != "" {
+		if opts.AppID, err = uuid.Parse(appIDRaw); err != nil {
+			log.Fatalf("--app-id must be a UUID: %v", err)
+		}
+	} else if opts.AppID, err = ensureDemoApp(ctx, pool); err != nil {
+		log.Fatalf("Failed to create demo app: %v", err)
+	}
+
+	data := generateDemoData(opts)
+	if err := writeDemoData(ctx, pool, opts.AppID, data); err != nil {
+		log.Fatalf("Failed to write demo data: %v", err)
+	}
+	fmt.Printf("✅ Seeded app %s: %d users, %d subscriptions, %d transactions, %d experiments, %d analytics events\n",
+		opts.AppID, len(data.Users), len(data.Subscriptions), len(data.Transactions), len(data.Experiments), len(data.Events))
+
+	backfill := service.NewAnalyticsBackfillService(pool)
+	run, err := backfill.Start(ctx, service.AnalyticsBackfillRequest{
+		AppID:     &opts.AppID,
+		From:      data.Start.Truncate(24 * time.Hour),
+		To:        data.End.Truncate(24 * time.Hour),
+		ChunkDays: 7,
+		Steps:     service.BackfillSteps,
+	})
+	if err != nil {
+		log.Fatalf("Cannot start analytics backfill: %v", err)
+	}
+	if err := backfill.Run(ctx, run, func(*service.AnalyticsBackfillRun) {}); err != nil {
+		log.Fatalf("Analytics backfill failed: %v\nResume with: go run ./cmd/backfill --resume %s", err, run.ID)
+	}
+	if _, err := service.NewDashboardViewsService(pool).RefreshAll(ctx); err != nil {
+		log.Fatalf("Failed to refresh dashboard views: %v", err)
+	}
+	fmt.Printf("✅ Backfilled analytics %s..%s and refreshed dashboard views\n",
+		run.From.Format("2006-01-02"), run.To.Format("2006-01-02"))
+}
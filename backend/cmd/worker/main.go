@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"crypto/rsa"
 	"log"
 	"os"
 	"os/signal"
@@ -15,6 +16,10 @@ import (
 	"github.com/bivex/paywall-iap/internal/domain/service"
 	"github.com/bivex/paywall-iap/internal/infrastructure/cache"
 	"github.com/bivex/paywall-iap/internal/infrastructure/config"
+	iapext "github.com/bivex/paywall-iap/internal/infrastructure/external/iap"
+	"github.com/bivex/paywall-iap/internal/infrastructure/external/paypal"
+	"github.com/bivex/paywall-iap/internal/infrastructure/external/warehouse"
+	"github.com/bivex/paywall-iap/internal/infrastructure/faultinject"
 	"github.com/bivex/paywall-iap/internal/infrastructure/logging"
 	"github.com/bivex/paywall-iap/internal/infrastructure/persistence/pool"
 	"github.com/bivex/paywall-iap/internal/infrastructure/persistence/repository"
@@ -60,35 +65,161 @@ func main() {
 	}
 
 	queries := generated.New(dbPool)
+	revenueBasis := service.ParseRevenueBasis(cfg.Revenue.Basis)
+	feeSchedule := service.StoreFeeSchedule{
+		AppleCommission:  cfg.Revenue.AppleCommission,
+		GoogleCommission: cfg.Revenue.GoogleCommission,
+		AmazonCommission: cfg.Revenue.AmazonCommission,
+		HuaweiCommission: cfg.Revenue.HuaweiCommission,
+		StripeFeePercent: cfg.Revenue.StripeFeePercent,
+		StripeFeeFixed:   cfg.Revenue.StripeFeeFixed,
+		PayPalFeePercent: cfg.Revenue.PayPalFeePercent,
+		PayPalFeeFixed:   cfg.Revenue.PayPalFeeFixed,
+	}
 	taskHandlers := worker_tasks.NewTaskHandlers(queries, redisClient).
 		WithLago(cfg.Lago.APIURL, cfg.Lago.APIKey).
-		WithFCM(cfg.Notification.FCMServerKey)
+		WithFCM(cfg.Notification.FCMServerKey).
+		WithRevenue(revenueBasis, feeSchedule)
+
+	// Amazon and Huawei notifications are re-verified with the store
+	var huaweiKey *rsa.PublicKey
+	if cfg.IAP.HuaweiPublicKey != "" {
+		if huaweiKey, err = iapext.ParseHuaweiPublicKey(cfg.IAP.HuaweiPublicKey); err != nil {
+			logging.Logger.Fatal("Invalid HUAWEI_PUBLIC_KEY", zap.Error(err))
+		}
+	}
+	if cfg.IAP.AmazonConfigured() {
+		taskHandlers.WithStoreVerifier("amazon", iapext.NewAmazonVerifier(cfg.IAP.AmazonSharedSecret, cfg.IAP.AmazonRVSURL))
+	}
+	if cfg.IAP.HuaweiConfigured() {
+		taskHandlers.WithStoreVerifier("huawei", iapext.NewHuaweiVerifier(cfg.IAP.HuaweiClientID, cfg.IAP.HuaweiClientSecret, cfg.IAP.HuaweiIAPBaseURL, huaweiKey))
+	}
+
+	// PayPal renewals take the next billing time from the REST API when a
+	// client is configured, else the mapped plan's period
+	payPalRepo := repository.NewPayPalRepository(dbPool)
+	if cfg.PayPal.ClientID != "" {
+		taskHandlers.WithPayPal(payPalRepo, paypal.NewClient(cfg.PayPal.APIBaseURL, cfg.PayPal.ClientID, cfg.PayPal.ClientSecret))
+	} else {
+		taskHandlers.WithPayPal(payPalRepo, nil)
+	}
 
 	// Initialize dunning service and handler
 	dunningRepo := repository.NewDunningRepository(dbPool)
 	subscriptionRepo := repository.NewSubscriptionRepository(queries)
 	userRepo := repository.NewUserRepository(queries)
+	consentService := service.NewConsentService(repository.NewUserConsentRepository(dbPool), logging.Logger)
+	notificationPrefs := service.NewNotificationPreferenceService(repository.NewNotificationPreferenceRepository(dbPool), logging.Logger).
+		WithConsent(consentService)
 	notificationSvc := service.NewNotificationService().
 		WithSendGrid(cfg.Notification.SendGridAPIKey, cfg.Notification.FromEmail).
-		WithFCM(cfg.Notification.FCMServerKey)
-	dunningService := service.NewDunningService(dunningRepo, subscriptionRepo, userRepo, notificationSvc)
+		WithFCM(cfg.Notification.FCMServerKey).
+		WithPreferences(notificationPrefs)
+	taskHandlers.WithNotificationPreferences(notificationPrefs)
+	dunningService := service.NewDunningService(dunningRepo, subscriptionRepo, userRepo, notificationSvc).
+		WithCampaigns(repository.NewDunningCampaignRepository(dbPool)).
+		WithApps(repository.NewAppRepository(dbPool))
+	taskHandlers.WithDunning(dunningService)
+	priceIncreaseService := service.NewPriceIncreaseConsentService(repository.NewPriceIncreaseConsentRepository(dbPool), logging.Logger).
+		WithPrompts(userRepo, notificationSvc)
+	taskHandlers.WithPriceIncreaseConsents(priceIncreaseService)
+	taskHandlers.WithOfferRedemptions(service.NewOfferService(repository.NewOfferRedemptionRepository(dbPool)))
+	priceIncreaseJobHandler := worker_tasks.NewPriceIncreaseJobHandler(priceIncreaseService, logging.Logger)
 	asynqClient := asynq.NewClientFromRedisClient(redisClient)
 	defer asynqClient.Close()
+
+	pendingPurchaseService := service.NewPendingPurchaseService(repository.NewPendingPurchaseRepository(dbPool), logging.Logger).
+		WithNotifier(cache.NewPurchaseEventBroker(redisClient)).
+		WithNotifier(worker_tasks.NewPurchaseResolutionPush(asynqClient))
+	taskHandlers.WithPendingPurchases(pendingPurchaseService)
+	taskHandlers.WithLifetimeEntitlements(service.NewLifetimeEntitlementService(repository.NewLifetimeEntitlementRepository(dbPool), logging.Logger))
+	taskHandlers.WithAnalyticsInvalidation(cache.NewQueryCache(redisClient, logging.Logger))
+	taskHandlers.WithReportingApps(repository.NewAppRepository(dbPool))
+	pendingPurchaseJobHandler := worker_tasks.NewPendingPurchaseJobHandler(pendingPurchaseService, logging.Logger)
 	dunningJobHandler := worker_tasks.NewDunningJobHandler(dunningService, asynqClient)
+	meteringJobHandler := worker_tasks.NewMeteringJobHandler(
+		service.NewMeteringService(cache.NewMeteringCounters(redisClient), repository.NewMeteringUsageRepository(dbPool), repository.NewAppRepository(dbPool), logging.Logger),
+		logging.Logger,
+	)
+
+	segmentRepo := repository.NewSegmentRepository(dbPool)
+	churnRiskService := service.NewLTVService(nil, nil, service.NewLTVSubscriptionAdapter(subscriptionRepo), repository.NewTransactionRepository(queries), logging.Logger).
+		WithUserRepo(userRepo).
+		WithRevenueBasis(revenueBasis)
+	lifecycleService := service.NewLifecycleService(repository.NewLifecycleStageRepository(dbPool), logging.Logger)
+	segmentService := service.NewSegmentService(segmentRepo, userRepo, subscriptionRepo, logging.Logger).
+		WithChurnRisk(churnRiskService).
+		WithLifecycleStages(lifecycleService)
+	segmentJobHandler := worker_tasks.NewSegmentJobHandler(segmentService, segmentRepo, notificationSvc, logging.Logger)
+	sessionJobHandler := worker_tasks.NewSessionJobHandler(repository.NewUserSessionRepository(dbPool), logging.Logger)
+	snapshotJobHandler := worker_tasks.NewSubscriptionSnapshotJobHandler(
+		service.NewSubscriptionSnapshotService(repository.NewSubscriptionSnapshotRepository(dbPool), logging.Logger),
+	)
+	dashboardViewJobHandler := worker_tasks.NewDashboardViewJobHandler(service.NewDashboardViewsService(dbPool), logging.Logger)
+	skanJobHandler := worker_tasks.NewSKANJobHandler(service.NewSKANAttributionService(dbPool, logging.Logger), logging.Logger)
+	acquisitionJobHandler := worker_tasks.NewAcquisitionJobHandler(service.NewAcquisitionService(dbPool), logging.Logger)
+	lifecycleJobHandler := worker_tasks.NewLifecycleJobHandler(lifecycleService)
+	reactivationJobHandler := worker_tasks.NewReactivationJobHandler(
+		service.NewReactivationService(repository.NewReactivationRepository(dbPool), logging.Logger),
+	)
+	retentionPolicies, err := service.ParseRetentionPolicies(cfg.Retention.Policies)
+	if err != nil {
+		logging.Logger.Fatal("Invalid RETENTION_POLICIES", zap.Error(err))
+	}
+	retentionJobHandler := worker_tasks.NewRetentionJobHandler(
+		service.NewRetentionService(dbPool, retentionPolicies, logging.Logger).
+			WithBatchLimits(cfg.Retention.BatchSize, cfg.Retention.MaxBatches),
+		logging.Logger,
+	)
+	var warehouseJobHandler *worker_tasks.WarehouseJobHandler
+	if cfg.Warehouse.Provider != "" {
+		dest, err := newWarehouseDestination(ctx, cfg.Warehouse)
+		if err != nil {
+			logging.Logger.Fatal("Failed to initialize warehouse destination", zap.Error(err))
+		}
+		warehouseJobHandler = worker_tasks.NewWarehouseJobHandler(
+			service.NewWarehouseSyncService(dbPool, dest, logging.Logger).WithBatchSize(cfg.Warehouse.BatchSize),
+			logging.Logger,
+		)
+	}
 
 	// Initialize advanced bandit services for worker
 	banditRepo := repository.NewPostgresBanditRepository(dbPool, logging.Logger)
 	automationJobRunRepo := repository.NewAutomationJobRunRepository(dbPool)
 	experimentAdminRepo := repository.NewExperimentAdminRepository(dbPool)
-	experimentAdminService := service.NewExperimentAdminService(experimentAdminRepo)
+	// Automation pauses are broadcast to the API instances like manual ones
+	killSwitch := cache.NewExperimentKillSwitch(redisClient, logging.Logger)
+	go killSwitch.Run(ctx)
+	experimentAdminService := service.NewExperimentAdminService(experimentAdminRepo).WithKillSwitch(killSwitch)
 	experimentRepairService := service.NewExperimentRepairService(experimentAdminRepo, banditRepo)
-	experimentReconciler := service.NewExperimentAutomationReconciler(experimentAdminRepo, experimentAdminService)
+	experimentReconciler := service.NewExperimentAutomationReconciler(experimentAdminRepo, experimentAdminService).
+		WithRevenueGuardrails(experimentAdminRepo)
 	experimentRepairReconciler := service.NewExperimentRepairReconciler(experimentAdminRepo, experimentRepairService)
+	experimentMetaJobHandler := worker_tasks.NewExperimentMetaJobHandler(
+		service.NewExperimentMetaAnalyticsService(experimentAdminRepo, logging.Logger),
+	)
 	automationJobExecutor := service.NewAutomationJobExecutionService(automationJobRunRepo)
 	banditCache := cache.NewRedisBanditCache(redisClient, logging.Logger)
-	banditService := service.NewThompsonSamplingBandit(banditRepo, banditCache, logging.Logger)
-	currencyService := service.NewCurrencyRateService(redisClient, logging.Logger)
-	advancedBanditEngine := service.NewAdvancedBanditEngine(
+	banditService := service.NewThompsonSamplingBandit(banditRepo, banditCache, logging.Logger).
+		WithSimulationBudget(cfg.Bandit.SimulationBudget).
+		WithSimulationWorkers(cfg.Bandit.SimulationWorkers).
+		WithKillSwitch(killSwitch)
+	experimentTimelineJobHandler := worker_tasks.NewExperimentTimelineJobHandler(
+		service.NewExperimentTimelineService(experimentAdminRepo, logging.Logger).WithWinProbability(banditService),
+	)
+	faults, err := faultinject.NewFromConfig(cfg.Faults, logging.Logger)
+	if err != nil {
+		logging.Logger.Fatal("Invalid FAULT_INJECTION_SCENARIO", zap.Error(err))
+	}
+	if faults != nil {
+		logging.Logger.Warn("Fault injection enabled", zap.String("scenario", faults.Scenario().String()))
+	}
+	currencyService := service.NewCurrencyRateService(redisClient, logging.Logger).
+		WithRateHistory(repository.NewCurrencyRateHistoryRepository(dbPool)).
+		WithTransport(faults.Transport(faultinject.TargetCurrency, nil))
+	// Experiments get engines built from their own config; the shared one
+	// runs maintenance and sweeps
+	banditEngines := service.NewAdvancedBanditEngineRegistry(
 		banditService,
 		banditRepo,
 		banditCache,
@@ -96,12 +227,25 @@ func main() {
 		currencyService,
 		logging.Logger,
 		&service.EngineConfig{
-			EnableCurrency: true,
-			EnableDelayed:  true,
-			EnableWindow:   true,
-			EnableHybrid:   true,
+			EnableCurrency:   true,
+			EnableContextual: true,
+			EnableDelayed:    true,
+			EnableWindow:     true,
+			EnableHybrid:     true,
 		},
-	)
+	).WithRevenueBasis(revenueBasis, feeSchedule).
+		WithConsent(consentService)
+	advancedBanditEngine := banditEngines.Shared()
+	dunningService.WithBandit(banditEngines)
+	// Webhook purchases close the buyer's pending bandit rewards
+	taskHandlers.WithBanditConversions(advancedBanditEngine)
+	pendingRewardExpiry := service.NewPendingRewardExpiryService(banditRepo, advancedBanditEngine)
+	sendTimeOptimizer := service.NewSendTimeOptimizer(
+		banditRepo,
+		banditService,
+		service.NewDelayedRewardStrategy(banditRepo, banditCache, logging.Logger),
+	).WithPreferences(notificationPrefs)
+	segmentJobHandler.WithSendTime(sendTimeOptimizer, asynqClient)
 
 	// Initialize Asynq server
 	server := asynq.NewServerFromRedisClient(redisClient, asynq.Config{
@@ -119,14 +263,45 @@ func main() {
 
 	// Register task handlers
 	mux := asynq.NewServeMux()
+	mux.Use(worker_tasks.SentryMiddleware())
+	mux.Use(worker_tasks.TaskRunMiddleware(repository.NewTaskRunRepository(dbPool), logging.Logger))
 	worker_tasks.RegisterHandlers(mux, taskHandlers)
 	worker_tasks.RegisterDunningHandlers(mux, dunningJobHandler)
+	worker_tasks.RegisterSegmentTasks(mux, segmentJobHandler)
+	worker_tasks.RegisterSubscriptionSnapshotTasks(mux, snapshotJobHandler)
+	worker_tasks.RegisterExperimentMetaTasks(mux, experimentMetaJobHandler)
+	worker_tasks.RegisterExperimentTimelineTasks(mux, experimentTimelineJobHandler)
+	worker_tasks.RegisterSessionTasks(mux, sessionJobHandler)
+	worker_tasks.RegisterPendingPurchaseTasks(mux, pendingPurchaseJobHandler)
+	worker_tasks.RegisterPriceIncreaseTasks(mux, priceIncreaseJobHandler)
+	worker_tasks.RegisterMeteringTasks(mux, meteringJobHandler)
+	worker_tasks.RegisterDashboardViewTasks(mux, dashboardViewJobHandler)
+	worker_tasks.RegisterSKANTasks(mux, skanJobHandler)
+	worker_tasks.RegisterAcquisitionTasks(mux, acquisitionJobHandler)
+	worker_tasks.RegisterLifecycleTasks(mux, lifecycleJobHandler)
+	worker_tasks.RegisterReactivationTasks(mux, reactivationJobHandler)
+	worker_tasks.RegisterRetentionTasks(mux, retentionJobHandler)
+	if warehouseJobHandler != nil {
+		worker_tasks.RegisterWarehouseTasks(mux, warehouseJobHandler)
+	}
+
+	// Load rates before the first reward conversion needs them
+	go func() {
+		warmCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := currencyService.WarmRates(warmCtx); err != nil {
+			logging.Logger.Warn("Failed to warm currency rates", zap.Error(err))
+		}
+	}()
 
 	// Register advanced bandit worker handlers
 	worker_tasks.RegisterCurrencyTasks(mux, currencyService, automationJobExecutor, logging.Logger)
 	worker_tasks.RegisterBanditMaintenanceTasks(mux, advancedBanditEngine, automationJobExecutor, logging.Logger)
 	worker_tasks.RegisterExperimentAutomationTasks(mux, experimentReconciler, automationJobExecutor, logging.Logger)
 	worker_tasks.RegisterExperimentRepairTasks(mux, experimentRepairReconciler, automationJobExecutor, logging.Logger)
+	worker_tasks.RegisterArmMigrationTasks(mux, banditService, automationJobExecutor, logging.Logger)
+	worker_tasks.RegisterPendingRewardExpiryTasks(mux, pendingRewardExpiry, logging.Logger)
+	worker_tasks.RegisterSendTimeTasks(mux, sendTimeOptimizer, notificationSvc, logging.Logger)
 
 	// Start server in background
 	if err := server.Start(mux); err != nil {
@@ -136,12 +311,63 @@ func main() {
 	// Register scheduled tasks
 	scheduler := asynq.NewSchedulerFromRedisClient(redisClient, nil)
 	worker_tasks.RegisterScheduledTasks(scheduler)
+	if err := worker_tasks.RegisterSegmentScheduledTasks(scheduler); err != nil {
+		logging.Logger.Error("Failed to schedule segment materialization", zap.Error(err))
+	}
+	if err := worker_tasks.RegisterSubscriptionSnapshotScheduledTasks(scheduler); err != nil {
+		logging.Logger.Error("Failed to schedule subscription snapshots", zap.Error(err))
+	}
+	if err := worker_tasks.RegisterExperimentMetaScheduledTasks(scheduler); err != nil {
+		logging.Logger.Error("Failed to schedule experiment meta-analytics", zap.Error(err))
+	}
+	if err := worker_tasks.RegisterExperimentTimelineScheduledTasks(scheduler); err != nil {
+		logging.Logger.Error("Failed to schedule experiment timelines", zap.Error(err))
+	}
+	if err := worker_tasks.RegisterSessionScheduledTasks(scheduler); err != nil {
+		logging.Logger.Error("Failed to schedule session cleanup", zap.Error(err))
+	}
+	if err := worker_tasks.RegisterPendingPurchaseScheduledTasks(scheduler); err != nil {
+		logging.Logger.Error("Failed to schedule pending purchase expiry", zap.Error(err))
+	}
+	if err := worker_tasks.RegisterPriceIncreaseScheduledTasks(scheduler); err != nil {
+		logging.Logger.Error("Failed to schedule price increase consent prompts", zap.Error(err))
+	}
+	if err := worker_tasks.RegisterMeteringScheduledTasks(scheduler); err != nil {
+		logging.Logger.Error("Failed to schedule metering flush", zap.Error(err))
+	}
+	if err := worker_tasks.RegisterDashboardViewScheduledTasks(scheduler); err != nil {
+		logging.Logger.Error("Failed to schedule dashboard view refresh", zap.Error(err))
+	}
+	if err := worker_tasks.RegisterSKANScheduledTasks(scheduler); err != nil {
+		logging.Logger.Error("Failed to schedule SKAdNetwork attribution", zap.Error(err))
+	}
+	if err := worker_tasks.RegisterAcquisitionScheduledTasks(scheduler); err != nil {
+		logging.Logger.Error("Failed to schedule acquisition user resolution", zap.Error(err))
+	}
+	if err := worker_tasks.RegisterLifecycleScheduledTasks(scheduler); err != nil {
+		logging.Logger.Error("Failed to schedule lifecycle stage recompute", zap.Error(err))
+	}
+	if err := worker_tasks.RegisterReactivationScheduledTasks(scheduler); err != nil {
+		logging.Logger.Error("Failed to schedule reactivation detection", zap.Error(err))
+	}
+	if err := worker_tasks.RegisterRetentionScheduledTasks(scheduler); err != nil {
+		logging.Logger.Error("Failed to schedule retention cleanup", zap.Error(err))
+	}
+	if warehouseJobHandler != nil {
+		if err := worker_tasks.RegisterWarehouseScheduledTasks(scheduler); err != nil {
+			logging.Logger.Error("Failed to schedule warehouse sync", zap.Error(err))
+		}
+	}
 
 	// Register advanced bandit scheduled tasks
 	worker_tasks.RegisterCurrencyScheduledTasks(scheduler)
 	worker_tasks.RegisterBanditMaintenanceScheduledTasks(scheduler)
 	worker_tasks.RegisterExperimentAutomationScheduledTasks(scheduler)
 	worker_tasks.RegisterExperimentRepairScheduledTasks(scheduler)
+	worker_tasks.RegisterArmMigrationScheduledTasks(scheduler)
+	if err := worker_tasks.RegisterPendingRewardExpiryScheduledTasks(scheduler); err != nil {
+		logging.Logger.Error("Failed to schedule pending reward expiry", zap.Error(err))
+	}
 
 	// Start scheduler
 	if err := scheduler.Start(); err != nil {
@@ -162,3 +388,24 @@ func main() {
 
 	logging.Logger.Info("Worker exited")
 }
+
+// newWarehouseDestination connects to the configured warehouse
+func newWarehouseDestination(ctx context.Context, cfg config.WarehouseConfig) (service.WarehouseDestination, error) {
+	if cfg.Provider == "snowflake" {
+		return warehouse.NewSnowflake(warehouse.SnowflakeConfig{
+			Account:    cfg.SnowflakeAccount,
+			User:       cfg.SnowflakeUser,
+			PrivateKey: cfg.SnowflakePrivateKey,
+			Database:   cfg.SnowflakeDatabase,
+			Schema:     cfg.SnowflakeSchema,
+			Warehouse:  cfg.SnowflakeWarehouse,
+			Role:       cfg.SnowflakeRole,
+		})
+	}
+	return warehouse.NewBigQuery(ctx, warehouse.BigQueryConfig{
+		ProjectID:       cfg.BigQueryProjectID,
+		Dataset:         cfg.BigQueryDataset,
+		Location:        cfg.BigQueryLocation,
+		CredentialsJSON: cfg.BigQueryCredentialsJSON,
+	})
+}
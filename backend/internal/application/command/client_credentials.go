@@ -0,0 +1,168 @@
+package command
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/bivex/paywall-iap/internal/application/dto"
+	appMiddleware "github.com/bivex/paywall-iap/internal/application/middleware"
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+// ErrInvalidClient is returned for unknown, revoked or wrongly authenticated clients
+var ErrInvalidClient = errors.New("invalid client credentials")
+
+// dummySecretHash keeps the response time of unknown client IDs close to
+// that of a wrong secret
+var dummySecretHash, _ = bcrypt.GenerateFromPassword([]byte("unused-client-secret"), bcrypt.DefaultCost)
+
+// ClientCredentialsCommand issues and introspects OAuth2 client-credentials
+// tokens for the admin API.
+type ClientCredentialsCommand struct {
+	clientRepo    repository.OAuthClientRepository
+	jwtMiddleware *appMiddleware.JWTMiddleware
+}
+
+// NewClientCredentialsCommand creates a new ClientCredentialsCommand.
+func NewClientCredentialsCommand(
+	clientRepo repository.OAuthClientRepository,
+	jwtMiddleware *appMiddleware.JWTMiddleware,
+) *ClientCredentialsCommand {
+	return &ClientCredentialsCommand{
+		clientRepo:    clientRepo,
+		jwtMiddleware: jwtMiddleware,
+	}
+}
+
+// CreateClient registers a client and returns it with its plaintext secret,
+// which is not stored and cannot be retrieved later.
+func (c *ClientCredentialsCommand) CreateClient(ctx context.Context, name string, appID *uuid.UUID, scopes []string, createdBy uuid.UUID) (*entity.OAuthClient, string, error) {
+	clientID, err := randomToken(12, hex.EncodeToString)
+	if err != nil {
+		return nil, "", err
+	}
+	client := entity.NewOAuthClient("cli_"+clientID, name, appID, scopes, createdBy)
+	if err := client.Validate(); err != nil {
+		return nil, "", err
+	}
+
+	secret, err := randomToken(32, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return nil, "", err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to hash client secret: %w", err)
+	}
+	client.SecretHash = string(hash)
+
+	if err := c.clientRepo.Create(ctx, client); err != nil {
+		return nil, "", fmt.Errorf("failed to create oauth client: %w", err)
+	}
+	return client, secret, nil
+}
+
+// Authenticate verifies a client's credentials
+func (c *ClientCredentialsCommand) Authenticate(ctx context.Context, clientID, secret string) (*entity.OAuthClient, error) {
+	client, err := c.clientRepo.GetByClientID(ctx, clientID)
+	if err != nil {
+		_ = bcrypt.CompareHashAndPassword(dummySecretHash, []byte(secret))
+		return nil, ErrInvalidClient
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(client.SecretHash), []byte(secret)); err != nil {
+		return nil, ErrInvalidClient
+	}
+	if !client.IsActive() {
+		return nil, ErrInvalidClient
+	}
+	return client, nil
+}
+
+// IssueToken exchanges client credentials for an access token carrying the
+// requested scopes (all of the client's scopes when scope is empty).
+// Returns ErrInvalidClient or entity.ErrOAuthScope.
+func (c *ClientCredentialsCommand) IssueToken(ctx context.Context, clientID, secret, scope string) (*dto.ClientTokenResponse, error) {
+	client, err := c.Authenticate(ctx, clientID, secret)
+	if err != nil {
+		return nil, err
+	}
+	scopes, err := client.GrantScopes(entity.ParseOAuthScope(scope))
+	if err != nil {
+		return nil, err
+	}
+
+	appID := ""
+	if client.AppID != nil {
+		appID = client.AppID.String()
+	}
+	token, _, err := c.jwtMiddleware.GenerateClientToken(client.ClientID, appID, scopes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate access token: %w", err)
+	}
+	// Last-used is informational; a failed write must not fail the grant
+	_ = c.clientRepo.TouchLastUsed(ctx, client.ID, time.Now())
+
+	return &dto.ClientTokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(c.jwtMiddleware.AccessTTL().Seconds()),
+		Scope:       strings.Join(scopes, " "),
+	}, nil
+}
+
+// Introspect reports whether a client token is active (RFC 7662). Tokens
+// that are expired, revoked, not client tokens or belong to a revoked client
+// are inactive.
+func (c *ClientCredentialsCommand) Introspect(ctx context.Context, token string) (*dto.TokenIntrospectionResponse, error) {
+	inactive := &dto.TokenIntrospectionResponse{Active: false}
+
+	claims, err := c.jwtMiddleware.ParseToken(token)
+	if err != nil || claims.ClientID == "" {
+		return inactive, nil
+	}
+	revoked, err := c.jwtMiddleware.IsRevoked(ctx, claims.JTI)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+	if revoked {
+		return inactive, nil
+	}
+	client, err := c.clientRepo.GetByClientID(ctx, claims.ClientID)
+	if err != nil || !client.IsActive() {
+		return inactive, nil
+	}
+
+	resp := &dto.TokenIntrospectionResponse{
+		Active:    true,
+		Scope:     claims.Scope,
+		ClientID:  claims.ClientID,
+		Subject:   claims.UserID,
+		TokenType: "Bearer",
+		AppID:     claims.AppID,
+	}
+	if claims.ExpiresAt != nil {
+		resp.ExpiresAt = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		resp.IssuedAt = claims.IssuedAt.Unix()
+	}
+	return resp, nil
+}
+
+func randomToken(n int, encode func([]byte) string) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate random token: %w", err)
+	}
+	return encode(b), nil
+}
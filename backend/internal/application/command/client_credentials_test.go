@@ -0,0 +1,102 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	appMiddleware "github.com/bivex/paywall-iap/internal/application/middleware"
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+)
+
+type oauthClientRepoStub struct {
+	clients map[string]*entity.OAuthClient
+	touched int
+}
+
+func (r *oauthClientRepoStub) Create(_ context.Context, client *entity.OAuthClient) error {
+	r.clients[client.ClientID] = client
+	return nil
+}
+func (r *oauthClientRepoStub) List(context.Context) ([]*entity.OAuthClient, error) { return nil, nil }
+func (r *oauthClientRepoStub) GetByClientID(_ context.Context, clientID string) (*entity.OAuthClient, error) {
+	if client, ok := r.clients[clientID]; ok {
+		return client, nil
+	}
+	return nil, fmt.Errorf("oauth client %s: %w", clientID, domainErrors.ErrNotFound)
+}
+func (r *oauthClientRepoStub) Revoke(context.Context, uuid.UUID, time.Time) error { return nil }
+func (r *oauthClientRepoStub) TouchLastUsed(context.Context, uuid.UUID, time.Time) error {
+	r.touched++
+	return nil
+}
+
+func newClientCredentialsFixture(t *testing.T) (*ClientCredentialsCommand, *oauthClientRepoStub, *entity.OAuthClient, string) {
+	t.Helper()
+	repo := &oauthClientRepoStub{clients: map[string]*entity.OAuthClient{}}
+	jwt := appMiddleware.NewJWTMiddleware("test-secret-test-secret-test-secret", nil, time.Minute)
+	cmd := NewClientCredentialsCommand(repo, jwt)
+
+	appID := uuid.New()
+	client, secret, err := cmd.CreateClient(context.Background(), "CI", &appID,
+		[]string{entity.ScopeReadAnalytics, entity.ScopeWriteExperiments}, uuid.New())
+	require.NoError(t, err)
+	return cmd, repo, client, secret
+}
+
+func TestClientCredentialsCommand_IssueTokenWithRequestedScope(t *testing.T) {
+	cmd, repo, client, secret := newClientCredentialsFixture(t)
+
+	resp, err := cmd.IssueToken(context.Background(), client.ClientID, secret, "read:analytics")
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer", resp.TokenType)
+	assert.Equal(t, "read:analytics", resp.Scope)
+	assert.Equal(t, int64(60), resp.ExpiresIn)
+	assert.Equal(t, 1, repo.touched)
+
+	claims, err := cmd.jwtMiddleware.ParseToken(resp.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, client.ClientID, claims.ClientID)
+	assert.Equal(t, "client:"+client.ClientID, claims.UserID)
+	assert.Equal(t, appMiddleware.ClientTokenRole, claims.Role)
+	assert.Equal(t, client.AppID.String(), claims.AppID)
+}
+
+func TestClientCredentialsCommand_IssueTokenRejectsBadCredentials(t *testing.T) {
+	cmd, _, client, secret := newClientCredentialsFixture(t)
+
+	_, err := cmd.IssueToken(context.Background(), client.ClientID, "wrong", "")
+	assert.ErrorIs(t, err, ErrInvalidClient)
+
+	_, err = cmd.IssueToken(context.Background(), "cli_unknown", secret, "")
+	assert.ErrorIs(t, err, ErrInvalidClient)
+
+	now := time.Now()
+	client.RevokedAt = &now
+	_, err = cmd.IssueToken(context.Background(), client.ClientID, secret, "")
+	assert.ErrorIs(t, err, ErrInvalidClient)
+}
+
+func TestClientCredentialsCommand_IssueTokenRejectsUngrantedScope(t *testing.T) {
+	cmd, _, client, secret := newClientCredentialsFixture(t)
+
+	_, err := cmd.IssueToken(context.Background(), client.ClientID, secret, "read:analytics write:analytics")
+	assert.ErrorIs(t, err, entity.ErrOAuthScope)
+}
+
+func TestClientCredentialsCommand_IntrospectUserTokenIsInactive(t *testing.T) {
+	cmd, _, _, _ := newClientCredentialsFixture(t)
+	token, _, err := cmd.jwtMiddleware.GenerateAccessToken(uuid.New().String())
+	require.NoError(t, err)
+
+	resp, err := cmd.Introspect(context.Background(), token)
+	require.NoError(t, err)
+	assert.False(t, resp.Active)
+	assert.Empty(t, resp.ClientID)
+}
@@ -17,6 +17,7 @@ import (
 type RegisterCommand struct {
 	userRepo      repository.UserRepository
 	jwtMiddleware *appMiddleware.JWTMiddleware
+	sessions      *UserSessionCommand
 }
 
 // NewRegisterCommand creates a new register command
@@ -27,6 +28,12 @@ func NewRegisterCommand(userRepo repository.UserRepository, jwtMiddleware *appMi
 	}
 }
 
+// WithSessions records the registering device as a session
+func (c *RegisterCommand) WithSessions(sessions *UserSessionCommand) *RegisterCommand {
+	c.sessions = sessions
+	return c
+}
+
 // Execute executes the register command
 func (c *RegisterCommand) Execute(ctx context.Context, req *dto.RegisterRequest) (*dto.RegisterResponse, error) {
 	// Validate platform
@@ -91,9 +98,28 @@ func (c *RegisterCommand) Execute(ctx context.Context, req *dto.RegisterRequest)
 	}
 
 	// Generate JWT tokens (embed app_id when present)
-	accessToken, refreshToken, err := c.jwtMiddleware.GenerateTokenPair(user.ID.String(), req.AppID, "")
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate tokens: %w", err)
+	var accessToken, refreshToken string
+	if c.sessions != nil {
+		var sessionAppID *uuid.UUID
+		if appID != uuid.Nil {
+			sessionAppID = &appID
+		}
+		tokens, err := c.sessions.Start(ctx, user.ID, sessionAppID, "", entity.DeviceInfo{
+			DeviceID:   req.DeviceID,
+			Platform:   req.Platform,
+			AppVersion: req.AppVersion,
+			UserAgent:  req.UserAgent,
+			IPAddress:  req.IPAddress,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to start session: %w", err)
+		}
+		accessToken, refreshToken = tokens.AccessToken, tokens.RefreshToken
+	} else {
+		accessToken, refreshToken, err = c.jwtMiddleware.GenerateTokenPair(user.ID.String(), req.AppID, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate tokens: %w", err)
+		}
 	}
 
 	return &dto.RegisterResponse{
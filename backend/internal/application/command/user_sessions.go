@@ -0,0 +1,157 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	appMiddleware "github.com/bivex/paywall-iap/internal/application/middleware"
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+// ErrSessionRevoked is returned when a refresh token belongs to a session
+// that was revoked, expired or has moved on to a newer refresh token
+var ErrSessionRevoked = errors.New("session has been revoked")
+
+// ErrTokenNotRefreshable is returned for impersonation and client-credentials
+// tokens, which must never be exchanged for a user session
+var ErrTokenNotRefreshable = errors.New("token cannot be refreshed")
+
+// UserSessionCommand starts, rotates and revokes end-user sessions. Each
+// session is one signed-in device holding one usable refresh token.
+type UserSessionCommand struct {
+	sessionRepo   repository.UserSessionRepository
+	jwtMiddleware *appMiddleware.JWTMiddleware
+	now           func() time.Time
+}
+
+// NewUserSessionCommand creates a new UserSessionCommand.
+func NewUserSessionCommand(
+	sessionRepo repository.UserSessionRepository,
+	jwtMiddleware *appMiddleware.JWTMiddleware,
+) *UserSessionCommand {
+	return &UserSessionCommand{
+		sessionRepo:   sessionRepo,
+		jwtMiddleware: jwtMiddleware,
+		now:           time.Now,
+	}
+}
+
+// Start signs a device in and returns the session's first token pair
+func (c *UserSessionCommand) Start(ctx context.Context, userID uuid.UUID, appID *uuid.UUID, role string, device entity.DeviceInfo) (*appMiddleware.SessionTokens, error) {
+	session := entity.NewUserSession(userID, appID, device, c.now())
+	appIDClaim := ""
+	if appID != nil {
+		appIDClaim = appID.String()
+	}
+	tokens, err := c.jwtMiddleware.GenerateSessionTokens(userID.String(), appIDClaim, role, session.ID.String())
+	if err != nil {
+		return nil, err
+	}
+	session.RefreshJTI = tokens.RefreshJTI
+	session.AccessJTI = tokens.AccessJTI
+	session.ExpiresAt = tokens.RefreshExpiresAt
+
+	if err := c.sessionRepo.Create(ctx, session); err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	return tokens, nil
+}
+
+// Refresh rotates the session of a verified refresh token. Tokens issued
+// before sessions existed start a new session. Presenting a refresh token
+// the session has already rotated past revokes the session, since it means
+// the token was copied.
+func (c *UserSessionCommand) Refresh(ctx context.Context, claims *appMiddleware.JWTClaims, device entity.DeviceInfo) (*appMiddleware.SessionTokens, error) {
+	if claims.ImpersonatedBy != "" || claims.ClientID != "" {
+		return nil, ErrTokenNotRefreshable
+	}
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token subject: %w", err)
+	}
+	if claims.SessionID == "" {
+		var appID *uuid.UUID
+		if parsed, err := uuid.Parse(claims.AppID); err == nil {
+			appID = &parsed
+		}
+		return c.Start(ctx, userID, appID, claims.Role, device)
+	}
+
+	sessionID, err := uuid.Parse(claims.SessionID)
+	if err != nil {
+		return nil, ErrSessionRevoked
+	}
+	session, err := c.sessionRepo.GetByID(ctx, sessionID)
+	if errors.Is(err, domainErrors.ErrNotFound) {
+		return nil, ErrSessionRevoked
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	now := c.now()
+	if session.UserID != userID || !session.IsActive(now) {
+		return nil, ErrSessionRevoked
+	}
+	if session.RefreshJTI != claims.JTI {
+		if err := c.revoke(ctx, session); err != nil {
+			return nil, err
+		}
+		return nil, ErrSessionRevoked
+	}
+
+	tokens, err := c.jwtMiddleware.GenerateSessionTokens(claims.UserID, claims.AppID, claims.Role, claims.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	session.Rotate(tokens.RefreshJTI, tokens.AccessJTI, tokens.RefreshExpiresAt, device, now)
+	err = c.sessionRepo.Rotate(ctx, session, claims.JTI)
+	if errors.Is(err, domainErrors.ErrNotFound) {
+		// A concurrent refresh with the same token won
+		return nil, ErrSessionRevoked
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate session: %w", err)
+	}
+	return tokens, nil
+}
+
+// List returns the user's active sessions, most recently used first
+func (c *UserSessionCommand) List(ctx context.Context, userID uuid.UUID) ([]*entity.UserSession, error) {
+	return c.sessionRepo.ListActive(ctx, userID, c.now())
+}
+
+// Revoke signs a user's session out, blocklisting its current tokens.
+// Returns ErrNotFound when the user has no such active session.
+func (c *UserSessionCommand) Revoke(ctx context.Context, userID, sessionID uuid.UUID) error {
+	session, err := c.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if session.UserID != userID || !session.IsActive(c.now()) {
+		return fmt.Errorf("user session %s: %w", sessionID, domainErrors.ErrNotFound)
+	}
+	return c.revoke(ctx, session)
+}
+
+func (c *UserSessionCommand) revoke(ctx context.Context, session *entity.UserSession) error {
+	now := c.now()
+	if err := c.sessionRepo.Revoke(ctx, session.UserID, session.ID, now); err != nil && !errors.Is(err, domainErrors.ErrNotFound) {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	if ttl := session.ExpiresAt.Sub(now); ttl > 0 {
+		if err := c.jwtMiddleware.RevokeToken(ctx, session.RefreshJTI, ttl); err != nil {
+			return fmt.Errorf("failed to revoke refresh token: %w", err)
+		}
+	}
+	// The access token expires within the access TTL of the last rotation
+	if err := c.jwtMiddleware.RevokeToken(ctx, session.AccessJTI, c.jwtMiddleware.AccessTTL()); err != nil {
+		return fmt.Errorf("failed to revoke access token: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	appMiddleware "github.com/bivex/paywall-iap/internal/application/middleware"
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+)
+
+type userSessionRepoStub struct {
+	sessions map[uuid.UUID]*entity.UserSession
+}
+
+func (r *userSessionRepoStub) Create(_ context.Context, session *entity.UserSession) error {
+	copied := *session
+	r.sessions[session.ID] = &copied
+	return nil
+}
+func (r *userSessionRepoStub) GetByID(_ context.Context, id uuid.UUID) (*entity.UserSession, error) {
+	if session, ok := r.sessions[id]; ok {
+		copied := *session
+		return &copied, nil
+	}
+	return nil, fmt.Errorf("user session %s: %w", id, domainErrors.ErrNotFound)
+}
+func (r *userSessionRepoStub) ListActive(context.Context, uuid.UUID, time.Time) ([]*entity.UserSession, error) {
+	return nil, nil
+}
+func (r *userSessionRepoStub) Rotate(_ context.Context, session *entity.UserSession, previousRefreshJTI string) error {
+	stored, ok := r.sessions[session.ID]
+	if !ok || stored.RefreshJTI != previousRefreshJTI || stored.RevokedAt != nil {
+		return fmt.Errorf("user session %s: %w", session.ID, domainErrors.ErrNotFound)
+	}
+	copied := *session
+	r.sessions[session.ID] = &copied
+	return nil
+}
+func (r *userSessionRepoStub) Revoke(_ context.Context, _ uuid.UUID, id uuid.UUID, at time.Time) error {
+	r.sessions[id].RevokedAt = &at
+	return nil
+}
+func (r *userSessionRepoStub) DeleteStale(context.Context, time.Time) (int64, error) { return 0, nil }
+
+func newUserSessionFixture() (*UserSessionCommand, *userSessionRepoStub) {
+	repo := &userSessionRepoStub{sessions: map[uuid.UUID]*entity.UserSession{}}
+	jwt := appMiddleware.NewJWTMiddleware("test-secret-test-secret-test-secret", nil, time.Minute)
+	return NewUserSessionCommand(repo, jwt), repo
+}
+
+func TestUserSessionCommand_StartAndRefreshRotates(t *testing.T) {
+	cmd, repo := newUserSessionFixture()
+	userID := uuid.New()
+
+	tokens, err := cmd.Start(context.Background(), userID, nil, "user", entity.DeviceInfo{DeviceID: "dev-1", Platform: "ios"})
+	require.NoError(t, err)
+	claims, err := cmd.jwtMiddleware.ParseToken(tokens.RefreshToken)
+	require.NoError(t, err)
+	require.NotEmpty(t, claims.SessionID)
+	assert.Len(t, repo.sessions, 1)
+
+	rotated, err := cmd.Refresh(context.Background(), claims, entity.DeviceInfo{IPAddress: "10.0.0.9"})
+	require.NoError(t, err)
+	assert.NotEqual(t, tokens.RefreshJTI, rotated.RefreshJTI)
+
+	session := repo.sessions[uuid.MustParse(claims.SessionID)]
+	assert.Equal(t, rotated.RefreshJTI, session.RefreshJTI)
+	assert.Equal(t, "10.0.0.9", session.Device.IPAddress)
+	assert.Equal(t, "dev-1", session.Device.DeviceID)
+}
+
+func TestUserSessionCommand_RefreshLegacyTokenStartsSession(t *testing.T) {
+	cmd, repo := newUserSessionFixture()
+	refresh, _, err := cmd.jwtMiddleware.GenerateRefreshToken(uuid.New().String())
+	require.NoError(t, err)
+	claims, err := cmd.jwtMiddleware.ParseToken(refresh)
+	require.NoError(t, err)
+
+	_, err = cmd.Refresh(context.Background(), claims, entity.DeviceInfo{})
+	require.NoError(t, err)
+	assert.Len(t, repo.sessions, 1)
+}
+
+func TestUserSessionCommand_RefreshRejectsImpersonationToken(t *testing.T) {
+	cmd, repo := newUserSessionFixture()
+	token, _, _, err := cmd.jwtMiddleware.GenerateImpersonationToken(uuid.New().String(), "", uuid.New().String(), 0)
+	require.NoError(t, err)
+	claims, err := cmd.jwtMiddleware.ParseToken(token)
+	require.NoError(t, err)
+
+	_, err = cmd.Refresh(context.Background(), claims, entity.DeviceInfo{})
+	assert.ErrorIs(t, err, ErrTokenNotRefreshable)
+	assert.Empty(t, repo.sessions)
+}
+
+func TestUserSessionCommand_RefreshRevokedSession(t *testing.T) {
+	cmd, repo := newUserSessionFixture()
+	tokens, err := cmd.Start(context.Background(), uuid.New(), nil, "user", entity.DeviceInfo{})
+	require.NoError(t, err)
+	claims, err := cmd.jwtMiddleware.ParseToken(tokens.RefreshToken)
+	require.NoError(t, err)
+
+	now := time.Now()
+	repo.sessions[uuid.MustParse(claims.SessionID)].RevokedAt = &now
+	_, err = cmd.Refresh(context.Background(), claims, entity.DeviceInfo{})
+	assert.ErrorIs(t, err, ErrSessionRevoked)
+}
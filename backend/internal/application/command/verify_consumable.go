@@ -0,0 +1,114 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/application/dto"
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+)
+
+// consumableCatalog loads the app's consumable product configuration
+type consumableCatalog interface {
+	GetSettings(ctx context.Context, id uuid.UUID) (*entity.AppSettings, error)
+}
+
+// CreditPurchaser credits verified consumable purchases (see service.CreditService)
+type CreditPurchaser interface {
+	CreditPurchase(ctx context.Context, entry *entity.CreditEntry) (*entity.CreditEntry, bool, error)
+}
+
+// VerifyConsumableCommand verifies a consumable store receipt and credits the
+// product's credits to the user's balance, once per store transaction.
+type VerifyConsumableCommand struct {
+	catalog         consumableCatalog
+	credits         CreditPurchaser
+	iosVerifier     DynamicIAPVerifier
+	androidVerifier DynamicIAPVerifier
+}
+
+// NewVerifyConsumableCommand creates a new verify consumable command
+func NewVerifyConsumableCommand(catalog consumableCatalog, credits CreditPurchaser, iosVerifier, androidVerifier DynamicIAPVerifier) *VerifyConsumableCommand {
+	return &VerifyConsumableCommand{
+		catalog:         catalog,
+		credits:         credits,
+		iosVerifier:     iosVerifier,
+		androidVerifier: androidVerifier,
+	}
+}
+
+// Execute executes the verify consumable command
+func (c *VerifyConsumableCommand) Execute(ctx context.Context, userID string, appID uuid.UUID, req *dto.VerifyIAPRequest) (*dto.CreditPurchaseResponse, error) {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid user ID", domainErrors.ErrInvalidInput)
+	}
+	if err := validateConsumableRequest(req); err != nil {
+		return nil, err
+	}
+
+	settings, err := c.catalog.GetSettings(ctx, appID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load app settings: %w", err)
+	}
+	product, ok := settings.Consumables[req.ProductID]
+	if !ok {
+		return nil, domainErrors.NewValidationError("product_id", "is not a consumable product of this app")
+	}
+
+	verifier := c.androidVerifier
+	if req.Platform == "ios" {
+		verifier = c.iosVerifier
+	}
+	result, err := verifier.VerifyReceipt(ctx, appID, req.ReceiptData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify receipt: %w", err)
+	}
+	if result.Pending {
+		return nil, fmt.Errorf("%w: purchase is pending", domainErrors.ErrReceiptInvalid)
+	}
+	if !result.Valid || result.TransactionID == "" {
+		return nil, fmt.Errorf("%w: receipt is invalid", domainErrors.ErrReceiptInvalid)
+	}
+	if result.ProductID != "" && !strings.EqualFold(result.ProductID, req.ProductID) {
+		return nil, fmt.Errorf("%w: receipt is for product %q", domainErrors.ErrReceiptInvalid, result.ProductID)
+	}
+
+	entry := entity.NewCreditPurchase(appID, userUUID, req.Platform, req.ProductID, result.TransactionID, product)
+	stored, created, err := c.credits.CreditPurchase(ctx, entry)
+	if err != nil {
+		return nil, err
+	}
+	if stored.UserID != userUUID {
+		return nil, fmt.Errorf("%w: receipt was credited to another user", domainErrors.ErrReceiptAlreadyProcessed)
+	}
+
+	return &dto.CreditPurchaseResponse{
+		EntryID:   stored.ID.String(),
+		ProductID: stored.ProductID,
+		Credits:   stored.Delta,
+		Balance:   stored.BalanceAfter,
+		IsNew:     created,
+	}, nil
+}
+
+// validateConsumableRequest applies the receipt checks of a subscription
+// purchase; Google Play receipts must be for an in-app product.
+func validateConsumableRequest(req *dto.VerifyIAPRequest) error {
+	if err := validateIAPRequest(req); err != nil {
+		return err
+	}
+	if req.Platform == "android" {
+		var payload androidReceiptPayload
+		_ = json.Unmarshal([]byte(req.ReceiptData), &payload)
+		if payload.Type != "inapp" {
+			return domainErrors.NewValidationError("receipt_data", `field "type" must be "inapp" for consumable products`)
+		}
+	}
+	return nil
+}
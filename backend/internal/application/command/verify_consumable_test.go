@@ -0,0 +1,81 @@
+package command
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bivex/paywall-iap/internal/application/dto"
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+)
+
+type consumableCatalogStub struct{ settings *entity.AppSettings }
+
+func (s consumableCatalogStub) GetSettings(context.Context, uuid.UUID) (*entity.AppSettings, error) {
+	return s.settings, nil
+}
+
+type creditPurchaserStub struct{ credited []*entity.CreditEntry }
+
+func (s *creditPurchaserStub) CreditPurchase(_ context.Context, e *entity.CreditEntry) (*entity.CreditEntry, bool, error) {
+	s.credited = append(s.credited, e)
+	e.BalanceAfter = e.Delta
+	return e, true, nil
+}
+
+var coinsCatalog = consumableCatalogStub{&entity.AppSettings{
+	Consumables: map[string]entity.ConsumableProduct{"com.app.coins.100": {Credits: 100, Price: 0.99}},
+}}
+
+func TestVerifyConsumable_CreditsConfiguredProduct(t *testing.T) {
+	credits := &creditPurchaserStub{}
+	ios := &staticVerifierAdapter{verifierStub{&IAPVerificationResult{Valid: true, TransactionID: "3000", ProductID: "com.app.coins.100"}}}
+	cmd := NewVerifyConsumableCommand(coinsCatalog, credits, ios, nil)
+
+	resp, err := cmd.Execute(context.Background(), uuid.NewString(), uuid.New(), &dto.VerifyIAPRequest{
+		Platform: "ios", ReceiptData: "receipt", ProductID: "com.app.coins.100",
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), resp.Credits)
+	assert.True(t, resp.IsNew)
+	require.Len(t, credits.credited, 1)
+	assert.Equal(t, "receipt:ios:3000", credits.credited[0].IdempotencyKey)
+	assert.Equal(t, 0.99, credits.credited[0].Amount)
+}
+
+func TestVerifyConsumable_RejectsUnconfiguredAndMismatchedProducts(t *testing.T) {
+	credits := &creditPurchaserStub{}
+	ios := &staticVerifierAdapter{verifierStub{&IAPVerificationResult{Valid: true, TransactionID: "3000", ProductID: "com.app.coins.500"}}}
+	cmd := NewVerifyConsumableCommand(coinsCatalog, credits, ios, nil)
+
+	_, err := cmd.Execute(context.Background(), uuid.NewString(), uuid.New(), &dto.VerifyIAPRequest{
+		Platform: "ios", ReceiptData: "receipt", ProductID: "com.app.premium.monthly",
+	})
+	var validationErr *domainErrors.ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+
+	_, err = cmd.Execute(context.Background(), uuid.NewString(), uuid.New(), &dto.VerifyIAPRequest{
+		Platform: "ios", ReceiptData: "receipt", ProductID: "com.app.coins.100",
+	})
+	assert.ErrorIs(t, err, domainErrors.ErrReceiptInvalid)
+	assert.Empty(t, credits.credited)
+}
+
+func TestVerifyConsumable_RequiresInAppAndroidReceipt(t *testing.T) {
+	cmd := NewVerifyConsumableCommand(coinsCatalog, &creditPurchaserStub{}, nil, nil)
+
+	_, err := cmd.Execute(context.Background(), uuid.NewString(), uuid.New(), &dto.VerifyIAPRequest{
+		Platform:    "android",
+		ReceiptData: `{"packageName":"com.app","productId":"com.app.coins.100","purchaseToken":"t","type":"subscription"}`,
+		ProductID:   "com.app.coins.100",
+	})
+
+	var validationErr *domainErrors.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "receipt_data", validationErr.Field)
+}
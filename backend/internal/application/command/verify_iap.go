@@ -1,6 +1,7 @@
 package command
 
 import (
+	"cmp"
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
@@ -13,6 +14,7 @@ import (
 	"github.com/bivex/paywall-iap/internal/domain/entity"
 	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
 	"github.com/bivex/paywall-iap/internal/domain/repository"
+	"github.com/bivex/paywall-iap/internal/domain/service"
 	"github.com/google/uuid"
 )
 
@@ -35,6 +37,25 @@ type IAPVerificationResult struct {
 	ExpiresAt     time.Time
 	IsRenewable   bool
 	OriginalTxID  string
+	// OfferType is set when the store reports an intro price, free trial,
+	// offer code or promotional offer on this transaction.
+	OfferType entity.OfferType
+	OfferCode string
+	// Environment is the store environment ("Production", "Sandbox")
+	Environment string
+	// Pending is set when the store deferred the payment; Valid is false
+	Pending bool
+}
+
+// PendingPurchaseRecorder tracks deferred purchases (see service.PendingPurchaseService)
+type PendingPurchaseRecorder interface {
+	RecordPending(ctx context.Context, purchase *entity.PendingPurchase) (*entity.PendingPurchase, error)
+	Resolve(ctx context.Context, match repository.PendingPurchaseMatch, status entity.PendingPurchaseStatus, providerTxID string) (*entity.PendingPurchase, error)
+}
+
+// AnalyticsInvalidator drops an app's cached analytics results (see cache.QueryCache)
+type AnalyticsInvalidator interface {
+	Invalidate(ctx context.Context, appID uuid.UUID) error
 }
 
 // staticVerifierAdapter wraps a legacy IAPVerifier as a DynamicIAPVerifier,
@@ -52,6 +73,11 @@ type VerifyIAPCommand struct {
 	transactionRepo  repository.TransactionRepository
 	iosVerifier      DynamicIAPVerifier
 	androidVerifier  DynamicIAPVerifier
+	// storeVerifiers verify purchases from Android stores other than Google Play
+	storeVerifiers   map[string]DynamicIAPVerifier
+	offerService     *service.OfferService
+	pendingPurchases PendingPurchaseRecorder
+	analytics        AnalyticsInvalidator
 }
 
 // NewVerifyIAPCommand creates a new verify IAP command with dynamic (per-app) verifiers.
@@ -86,6 +112,36 @@ func NewVerifyIAPCommandLegacy(
 	)
 }
 
+// WithStoreVerifier verifies Android purchases made in store ("amazon",
+// "huawei") with verifier instead of Google Play.
+func (c *VerifyIAPCommand) WithStoreVerifier(store string, verifier DynamicIAPVerifier) *VerifyIAPCommand {
+	if c.storeVerifiers == nil {
+		c.storeVerifiers = make(map[string]DynamicIAPVerifier)
+	}
+	c.storeVerifiers[store] = verifier
+	return c
+}
+
+// WithOfferService enables recording of intro / trial / offer-code redemptions.
+func (c *VerifyIAPCommand) WithOfferService(offerService *service.OfferService) *VerifyIAPCommand {
+	c.offerService = offerService
+	return c
+}
+
+// WithPendingPurchases records receipts the store reports as pending instead
+// of rejecting them, and completes the pending purchase once verified.
+func (c *VerifyIAPCommand) WithPendingPurchases(recorder PendingPurchaseRecorder) *VerifyIAPCommand {
+	c.pendingPurchases = recorder
+	return c
+}
+
+// WithAnalyticsInvalidation invalidates the app's cached revenue analytics
+// after each recorded purchase.
+func (c *VerifyIAPCommand) WithAnalyticsInvalidation(inv AnalyticsInvalidator) *VerifyIAPCommand {
+	c.analytics = inv
+	return c
+}
+
 // Execute executes the verify IAP command.
 // appID is the app the user belongs to — used to select per-app store credentials.
 func (c *VerifyIAPCommand) Execute(ctx context.Context, userID string, appID uuid.UUID, req *dto.VerifyIAPRequest) (*dto.VerifyIAPResponse, error) {
@@ -104,12 +160,18 @@ func (c *VerifyIAPCommand) Execute(ctx context.Context, userID string, appID uui
 		return nil, err
 	}
 
-	// Select verifier based on platform
+	// Select verifier based on platform and Android store
 	var verifier DynamicIAPVerifier
-	if req.Platform == "ios" {
+	switch {
+	case req.Platform == "ios":
 		verifier = c.iosVerifier
-	} else {
+	case isGooglePlay(req):
 		verifier = c.androidVerifier
+	default:
+		verifier = c.storeVerifiers[req.Store]
+		if verifier == nil {
+			return nil, domainErrors.NewValidationError("store", fmt.Sprintf("%s purchases are not supported", req.Store))
+		}
 	}
 
 	// Verify receipt — uses per-app credentials from app_credentials table
@@ -118,19 +180,36 @@ func (c *VerifyIAPCommand) Execute(ctx context.Context, userID string, appID uui
 		return nil, fmt.Errorf("failed to verify receipt: %w", err)
 	}
 
+	// A deferred purchase is not a conversion: nothing is granted or counted
+	// until the store finalizes it
+	if result.Pending && c.pendingPurchases != nil {
+		return c.recordPending(ctx, userUUID, appID, req)
+	}
+
 	if !result.Valid {
 		return nil, fmt.Errorf("%w: receipt is invalid", domainErrors.ErrReceiptInvalid)
 	}
 
+	// Amazon and Huawei report the purchased product; the subscription must
+	// be for that product, not whatever the client asked for
+	if req.Platform == "android" && !isGooglePlay(req) && !strings.EqualFold(result.ProductID, req.ProductID) {
+		return nil, domainErrors.NewValidationError("product_id",
+			fmt.Sprintf("mismatch: request has %q but the %s purchase is for %q", req.ProductID, req.Store, result.ProductID))
+	}
+
 	// Check for duplicate receipt (idempotency)
 	receiptHash := hashReceipt(req.ReceiptData)
 	isDuplicate, err := c.transactionRepo.CheckDuplicateReceipt(ctx, receiptHash)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check duplicate receipt: %w", err)
 	}
+	userSubs, err := c.subscriptionRepo.GetByUserID(ctx, userUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscriptions: %w", err)
+	}
 	if isDuplicate {
-		sub, err := c.subscriptionRepo.GetActiveByUserID(ctx, userUUID)
-		if err != nil {
+		sub := service.ActiveSubscriptionForStore(userSubs, entity.SourceIAP, req.Platform)
+		if sub == nil {
 			// Subscription may have been cancelled after the receipt was processed.
 			// Still idempotent — return a clear error rather than an internal failure.
 			return nil, fmt.Errorf("%w: receipt already processed", domainErrors.ErrReceiptAlreadyProcessed)
@@ -141,12 +220,14 @@ func (c *VerifyIAPCommand) Execute(ctx context.Context, userID string, appID uui
 	// Determine plan type from product ID
 	planType := c.determinePlanType(req.ProductID)
 
-	// Check for existing active subscription
+	// Only this store's subscription is extended: an active subscription bought
+	// on another platform stays separate and both count towards the user's
+	// entitlement (see service.ResolveEntitlement).
 	var sub *entity.Subscription
-	existingSub, err := c.subscriptionRepo.GetActiveByUserID(ctx, userUUID)
+	existingSub := service.ActiveSubscriptionForStore(userSubs, entity.SourceIAP, req.Platform)
 	isNew := false
 
-	if err == nil && existingSub != nil {
+	if existingSub != nil {
 		existingSub.ExpiresAt = result.ExpiresAt
 		if err := c.subscriptionRepo.Update(ctx, existingSub); err != nil {
 			return nil, fmt.Errorf("failed to update subscription: %w", err)
@@ -172,16 +253,69 @@ func (c *VerifyIAPCommand) Execute(ctx context.Context, userID string, appID uui
 	txn := entity.NewTransaction(appID, userUUID, sub.ID, 0, "USD")
 	txn.ReceiptHash = receiptHash
 	txn.ProviderTxID = result.TransactionID
+	txn.Provider = service.RevenueProviderForPlatform(cmp.Or(req.Store, req.Platform))
+	txn.OriginalTransactionID = result.OriginalTxID
+	txn.ProductID = req.ProductID
+	txn.Environment = entity.NormalizeTransactionEnvironment(result.Environment)
 	if err := c.transactionRepo.Create(ctx, txn); err != nil {
 		return nil, fmt.Errorf("failed to create transaction: %w", err)
 	}
 
+	// Drop cached revenue analytics — best-effort, entries also expire
+	if c.analytics != nil {
+		_ = c.analytics.Invalidate(ctx, appID)
+	}
+
+	// Record consumed offer — best-effort, eligibility is advisory
+	if c.offerService != nil && result.OfferType != "" {
+		redemption := entity.NewOfferRedemption(appID, userUUID, req.ProductID, result.OfferType, req.Platform)
+		redemption.OfferCode = result.OfferCode
+		redemption.TransactionID = &txn.ID
+		redemption.ProviderTxID = result.TransactionID
+		_ = c.offerService.RecordRedemption(ctx, redemption)
+	}
+
 	// Update LTV — best-effort, don't fail the whole request
 	_ = c.userRepo.IncrementLTV(ctx, userUUID, priceFromPlanType(planType))
 
+	// Complete a purchase reported pending earlier — best-effort
+	if c.pendingPurchases != nil {
+		_, _ = c.pendingPurchases.Resolve(ctx, repository.PendingPurchaseMatch{
+			UserID:    userUUID,
+			ProductID: req.ProductID,
+			Platform:  req.Platform,
+		}, entity.PendingPurchaseStatusCompleted, result.TransactionID)
+	}
+
 	return c.toSubscriptionResponse(sub, isNew), nil
 }
 
+func (c *VerifyIAPCommand) recordPending(ctx context.Context, userID, appID uuid.UUID, req *dto.VerifyIAPRequest) (*dto.VerifyIAPResponse, error) {
+	purchase := entity.NewPendingPurchase(appID, userID, req.Platform, req.ProductID, entity.PendingReasonPaymentPending, time.Now())
+	purchase.PurchaseToken = androidPurchaseToken(req)
+	purchase, err := c.pendingPurchases.RecordPending(ctx, purchase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record pending purchase: %w", err)
+	}
+	return &dto.VerifyIAPResponse{
+		Status:            string(entity.PendingPurchaseStatusPending),
+		ExpiresAt:         purchase.ExpiresAt.Format(time.RFC3339),
+		PlanType:          string(c.determinePlanType(req.ProductID)),
+		PendingPurchaseID: purchase.ID.String(),
+	}, nil
+}
+
+// androidPurchaseToken returns the purchase token of a Google Play receipt,
+// empty for iOS and other stores' receipts
+func androidPurchaseToken(req *dto.VerifyIAPRequest) string {
+	if req.Platform != "android" || !isGooglePlay(req) {
+		return ""
+	}
+	var payload androidReceiptPayload
+	_ = json.Unmarshal([]byte(req.ReceiptData), &payload)
+	return payload.PurchaseToken
+}
+
 func (c *VerifyIAPCommand) determinePlanType(productID string) entity.PlanType {
 	if len(productID) > 0 {
 		if containsIgnoreCase(productID, "annual") || containsIgnoreCase(productID, "year") {
@@ -234,14 +368,70 @@ func validateIAPRequest(req *dto.VerifyIAPRequest) error {
 	if len(req.ReceiptData) > 65536 {
 		return domainErrors.NewValidationError("receipt_data", "exceeds maximum allowed size (64 KB)")
 	}
+	if req.Store != "" && req.Platform != "android" {
+		return domainErrors.NewValidationError("store", "is only supported for the android platform")
+	}
 	if req.Platform == "android" {
-		if err := validateAndroidReceipt(req.ReceiptData, req.ProductID); err != nil {
+		var err error
+		switch req.Store {
+		case "amazon":
+			err = validateAmazonReceipt(req.ReceiptData)
+		case "huawei":
+			err = validateHuaweiReceipt(req.ReceiptData)
+		default:
+			err = validateAndroidReceipt(req.ReceiptData, req.ProductID)
+		}
+		if err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// isGooglePlay reports whether an Android purchase was made in Google Play
+func isGooglePlay(req *dto.VerifyIAPRequest) bool {
+	return req.Store == "" || req.Store == "google"
+}
+
+// validateAmazonReceipt checks receipt_data is {"userId", "receiptId"} as
+// returned by the Appstore SDK
+func validateAmazonReceipt(receiptData string) error {
+	var payload struct {
+		UserID    string `json:"userId"`
+		ReceiptID string `json:"receiptId"`
+	}
+	if err := json.Unmarshal([]byte(receiptData), &payload); err != nil {
+		return domainErrors.NewValidationError("receipt_data", "must be valid JSON for Amazon purchases")
+	}
+	if payload.UserID == "" {
+		return domainErrors.NewValidationError("receipt_data", "missing required field: userId")
+	}
+	if payload.ReceiptID == "" {
+		return domainErrors.NewValidationError("receipt_data", "missing required field: receiptId")
+	}
+	return nil
+}
+
+// validateHuaweiReceipt checks receipt_data carries the purchase token and,
+// for subscriptions, the subscription ID
+func validateHuaweiReceipt(receiptData string) error {
+	var payload struct {
+		PurchaseToken  string `json:"purchaseToken"`
+		SubscriptionID string `json:"subscriptionId"`
+		Type           string `json:"type"`
+	}
+	if err := json.Unmarshal([]byte(receiptData), &payload); err != nil {
+		return domainErrors.NewValidationError("receipt_data", "must be valid JSON for Huawei purchases")
+	}
+	if payload.PurchaseToken == "" {
+		return domainErrors.NewValidationError("receipt_data", "missing required field: purchaseToken")
+	}
+	if payload.Type == "subscription" && payload.SubscriptionID == "" {
+		return domainErrors.NewValidationError("receipt_data", "missing required field: subscriptionId")
+	}
+	return nil
+}
+
 type androidReceiptPayload struct {
 	PackageName   string `json:"packageName"`
 	ProductID     string `json:"productId"`
@@ -0,0 +1,225 @@
+package command
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bivex/paywall-iap/internal/application/dto"
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+type verifyUserRepoStub struct{ repository.UserRepository }
+
+func (verifyUserRepoStub) GetByID(context.Context, uuid.UUID) (*entity.User, error) {
+	return &entity.User{}, nil
+}
+func (verifyUserRepoStub) UpdatePurchaseChannel(context.Context, uuid.UUID, string) error { return nil }
+func (verifyUserRepoStub) IncrementLTV(context.Context, uuid.UUID, float64) error         { return nil }
+
+type verifySubRepoStub struct {
+	repository.SubscriptionRepository
+	subs    []*entity.Subscription
+	updated []*entity.Subscription
+}
+
+func (r *verifySubRepoStub) GetByUserID(context.Context, uuid.UUID) ([]*entity.Subscription, error) {
+	return r.subs, nil
+}
+func (r *verifySubRepoStub) Create(_ context.Context, sub *entity.Subscription) error {
+	r.subs = append(r.subs, sub)
+	return nil
+}
+func (r *verifySubRepoStub) Update(_ context.Context, sub *entity.Subscription) error {
+	r.updated = append(r.updated, sub)
+	return nil
+}
+
+type verifyTxRepoStub struct {
+	repository.TransactionRepository
+	created []*entity.Transaction
+}
+
+func (r *verifyTxRepoStub) CheckDuplicateReceipt(context.Context, string) (bool, error) {
+	return false, nil
+}
+func (r *verifyTxRepoStub) Create(_ context.Context, txn *entity.Transaction) error {
+	r.created = append(r.created, txn)
+	return nil
+}
+
+type verifierStub struct{ result *IAPVerificationResult }
+
+func (v verifierStub) VerifyReceipt(context.Context, string) (*IAPVerificationResult, error) {
+	return v.result, nil
+}
+
+func TestVerifyIAP_KeepsOtherPlatformSubscriptionSeparate(t *testing.T) {
+	userID := uuid.New()
+	iosSub := entity.NewSubscription(userID, entity.SourceIAP, "ios", "com.app.premium.monthly", entity.PlanMonthly, time.Now().Add(10*24*time.Hour))
+	subs := &verifySubRepoStub{subs: []*entity.Subscription{iosSub}}
+	txns := &verifyTxRepoStub{}
+	androidExpiry := time.Now().Add(30 * 24 * time.Hour)
+	android := verifierStub{&IAPVerificationResult{Valid: true, TransactionID: "gpa.1", OriginalTxID: "token-1", ExpiresAt: androidExpiry, Environment: "Production"}}
+	cmd := NewVerifyIAPCommandLegacy(verifyUserRepoStub{}, subs, txns, verifierStub{}, android)
+
+	resp, err := cmd.Execute(context.Background(), userID.String(), uuid.New(), &dto.VerifyIAPRequest{
+		Platform:    "android",
+		ReceiptData: `{"packageName":"com.app","productId":"com.app.premium.monthly","purchaseToken":"token-1","type":"subscription"}`,
+		ProductID:   "com.app.premium.monthly",
+	})
+
+	require.NoError(t, err)
+	assert.True(t, resp.IsNew)
+	assert.Empty(t, subs.updated, "the iOS subscription is not extended by an Android purchase")
+	require.Len(t, subs.subs, 2)
+	assert.Equal(t, "android", subs.subs[1].Platform)
+	assert.Equal(t, subs.subs[1].ID, txns.created[0].SubscriptionID)
+	assert.Equal(t, entity.TransactionProviderGoogle, txns.created[0].Provider)
+}
+
+func TestVerifyIAP_ExtendsSamePlatformSubscription(t *testing.T) {
+	userID := uuid.New()
+	iosSub := entity.NewSubscription(userID, entity.SourceIAP, "ios", "com.app.premium.monthly", entity.PlanMonthly, time.Now().Add(time.Hour))
+	subs := &verifySubRepoStub{subs: []*entity.Subscription{iosSub}}
+	renewed := time.Now().Add(30 * 24 * time.Hour)
+	ios := verifierStub{&IAPVerificationResult{Valid: true, TransactionID: "2000", OriginalTxID: "1000", ExpiresAt: renewed}}
+	cmd := NewVerifyIAPCommandLegacy(verifyUserRepoStub{}, subs, &verifyTxRepoStub{}, ios, verifierStub{})
+
+	resp, err := cmd.Execute(context.Background(), userID.String(), uuid.New(), &dto.VerifyIAPRequest{
+		Platform:    "ios",
+		ReceiptData: "receipt",
+		ProductID:   "com.app.premium.monthly",
+	})
+
+	require.NoError(t, err)
+	assert.False(t, resp.IsNew)
+	require.Len(t, subs.updated, 1)
+	assert.Same(t, iosSub, subs.updated[0])
+	assert.Equal(t, renewed, iosSub.ExpiresAt)
+}
+
+type pendingRecorderStub struct {
+	recorded []*entity.PendingPurchase
+	resolved []repository.PendingPurchaseMatch
+}
+
+func (r *pendingRecorderStub) RecordPending(_ context.Context, p *entity.PendingPurchase) (*entity.PendingPurchase, error) {
+	r.recorded = append(r.recorded, p)
+	return p, nil
+}
+func (r *pendingRecorderStub) Resolve(_ context.Context, match repository.PendingPurchaseMatch, _ entity.PendingPurchaseStatus, _ string) (*entity.PendingPurchase, error) {
+	r.resolved = append(r.resolved, match)
+	return nil, nil
+}
+
+func TestVerifyIAP_PendingPurchaseIsNotAConversion(t *testing.T) {
+	userID := uuid.New()
+	subs := &verifySubRepoStub{}
+	txns := &verifyTxRepoStub{}
+	pending := &pendingRecorderStub{}
+	android := verifierStub{&IAPVerificationResult{Pending: true, TransactionID: "token-1", OriginalTxID: "token-1"}}
+	cmd := NewVerifyIAPCommandLegacy(verifyUserRepoStub{}, subs, txns, verifierStub{}, android).WithPendingPurchases(pending)
+
+	resp, err := cmd.Execute(context.Background(), userID.String(), uuid.New(), &dto.VerifyIAPRequest{
+		Platform:    "android",
+		ReceiptData: `{"packageName":"com.app","productId":"com.app.premium.monthly","purchaseToken":"token-1","type":"subscription"}`,
+		ProductID:   "com.app.premium.monthly",
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "pending", resp.Status)
+	assert.Empty(t, resp.SubscriptionID)
+	require.Len(t, pending.recorded, 1)
+	assert.Equal(t, resp.PendingPurchaseID, pending.recorded[0].ID.String())
+	assert.Equal(t, "token-1", pending.recorded[0].PurchaseToken)
+	assert.Equal(t, entity.PendingReasonPaymentPending, pending.recorded[0].Reason)
+	assert.Empty(t, subs.subs)
+	assert.Empty(t, txns.created)
+}
+
+func TestVerifyIAP_PendingWithoutTrackingIsRejected(t *testing.T) {
+	ios := verifierStub{&IAPVerificationResult{Pending: true}}
+	cmd := NewVerifyIAPCommandLegacy(verifyUserRepoStub{}, &verifySubRepoStub{}, &verifyTxRepoStub{}, ios, verifierStub{})
+
+	_, err := cmd.Execute(context.Background(), uuid.New().String(), uuid.New(), &dto.VerifyIAPRequest{
+		Platform:    "ios",
+		ReceiptData: "receipt",
+		ProductID:   "com.app.premium.monthly",
+	})
+
+	assert.ErrorIs(t, err, domainErrors.ErrReceiptInvalid)
+}
+
+func TestVerifyIAP_CompletesPendingPurchase(t *testing.T) {
+	userID := uuid.New()
+	pending := &pendingRecorderStub{}
+	ios := verifierStub{&IAPVerificationResult{Valid: true, TransactionID: "2000", OriginalTxID: "1000", ExpiresAt: time.Now().Add(time.Hour)}}
+	cmd := NewVerifyIAPCommandLegacy(verifyUserRepoStub{}, &verifySubRepoStub{}, &verifyTxRepoStub{}, ios, verifierStub{}).WithPendingPurchases(pending)
+
+	_, err := cmd.Execute(context.Background(), userID.String(), uuid.New(), &dto.VerifyIAPRequest{
+		Platform:    "ios",
+		ReceiptData: "receipt",
+		ProductID:   "com.app.premium.monthly",
+	})
+
+	require.NoError(t, err)
+	require.Len(t, pending.resolved, 1)
+	assert.Equal(t, repository.PendingPurchaseMatch{UserID: userID, ProductID: "com.app.premium.monthly", Platform: "ios"}, pending.resolved[0])
+}
+
+func TestVerifyIAP_AmazonStore(t *testing.T) {
+	txns := &verifyTxRepoStub{}
+	amazon := &staticVerifierAdapter{verifierStub{&IAPVerificationResult{Valid: true, TransactionID: "receipt-1", OriginalTxID: "receipt-1", ProductID: "com.app.premium.monthly", ExpiresAt: time.Now().Add(30 * 24 * time.Hour)}}}
+	cmd := NewVerifyIAPCommandLegacy(verifyUserRepoStub{}, &verifySubRepoStub{}, txns, verifierStub{}, verifierStub{}).
+		WithStoreVerifier("amazon", amazon)
+	req := &dto.VerifyIAPRequest{
+		Platform:    "android",
+		Store:       "amazon",
+		ReceiptData: `{"userId":"amzn-user","receiptId":"receipt-1"}`,
+		ProductID:   "com.app.premium.monthly",
+	}
+
+	_, err := cmd.Execute(context.Background(), uuid.New().String(), uuid.New(), req)
+	require.NoError(t, err)
+	require.Len(t, txns.created, 1)
+	assert.Equal(t, entity.TransactionProviderAmazon, txns.created[0].Provider)
+	assert.Equal(t, "receipt-1", txns.created[0].ProviderTxID)
+
+	req.Store = "huawei"
+	req.ReceiptData = `{"purchaseToken":"tok"}`
+	_, err = cmd.Execute(context.Background(), uuid.New().String(), uuid.New(), req)
+	var validationErr *domainErrors.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "store", validationErr.Field, "no verifier configured for huawei")
+
+	req.Store = "amazon"
+	req.ReceiptData = `{"receiptId":"receipt-1"}`
+	_, err = cmd.Execute(context.Background(), uuid.New().String(), uuid.New(), req)
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "receipt_data", validationErr.Field)
+}
+
+func TestVerifyIAP_StoreProductMismatch(t *testing.T) {
+	txns := &verifyTxRepoStub{}
+	amazon := &staticVerifierAdapter{verifierStub{&IAPVerificationResult{Valid: true, TransactionID: "receipt-1", OriginalTxID: "receipt-1", ProductID: "com.app.premium.weekly", ExpiresAt: time.Now().Add(30 * 24 * time.Hour)}}}
+	cmd := NewVerifyIAPCommandLegacy(verifyUserRepoStub{}, &verifySubRepoStub{}, txns, verifierStub{}, verifierStub{}).
+		WithStoreVerifier("amazon", amazon)
+
+	_, err := cmd.Execute(context.Background(), uuid.New().String(), uuid.New(), &dto.VerifyIAPRequest{
+		Platform:    "android",
+		Store:       "amazon",
+		ReceiptData: `{"userId":"amzn-user","receiptId":"receipt-1"}`,
+		ProductID:   "com.app.premium.yearly",
+	})
+	var validationErr *domainErrors.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "product_id", validationErr.Field)
+	assert.Empty(t, txns.created)
+}
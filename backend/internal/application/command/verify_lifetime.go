@@ -0,0 +1,120 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/application/dto"
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+)
+
+// LifetimeGranter grants verified lifetime purchases (see service.LifetimeEntitlementService)
+type LifetimeGranter interface {
+	Grant(ctx context.Context, e *entity.LifetimeEntitlement) (*entity.LifetimeEntitlement, bool, error)
+}
+
+// VerifyLifetimeCommand verifies a non-consumable store receipt and unlocks
+// the product permanently, once per store transaction.
+type VerifyLifetimeCommand struct {
+	catalog         consumableCatalog
+	lifetime        LifetimeGranter
+	iosVerifier     DynamicIAPVerifier
+	androidVerifier DynamicIAPVerifier
+}
+
+// NewVerifyLifetimeCommand creates a new verify lifetime command
+func NewVerifyLifetimeCommand(catalog consumableCatalog, lifetime LifetimeGranter, iosVerifier, androidVerifier DynamicIAPVerifier) *VerifyLifetimeCommand {
+	return &VerifyLifetimeCommand{
+		catalog:         catalog,
+		lifetime:        lifetime,
+		iosVerifier:     iosVerifier,
+		androidVerifier: androidVerifier,
+	}
+}
+
+// Execute executes the verify lifetime command
+func (c *VerifyLifetimeCommand) Execute(ctx context.Context, userID string, appID uuid.UUID, req *dto.VerifyIAPRequest) (*dto.LifetimeEntitlementResponse, error) {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid user ID", domainErrors.ErrInvalidInput)
+	}
+	if err := validateLifetimeRequest(req); err != nil {
+		return nil, err
+	}
+
+	settings, err := c.catalog.GetSettings(ctx, appID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load app settings: %w", err)
+	}
+	product, ok := settings.LifetimeProducts[req.ProductID]
+	if !ok {
+		return nil, domainErrors.NewValidationError("product_id", "is not a lifetime product of this app")
+	}
+
+	verifier := c.androidVerifier
+	if req.Platform == "ios" {
+		verifier = c.iosVerifier
+	}
+	result, err := verifier.VerifyReceipt(ctx, appID, req.ReceiptData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify receipt: %w", err)
+	}
+	if result.Pending {
+		return nil, fmt.Errorf("%w: purchase is pending", domainErrors.ErrReceiptInvalid)
+	}
+	if !result.Valid || result.TransactionID == "" {
+		return nil, fmt.Errorf("%w: receipt is invalid", domainErrors.ErrReceiptInvalid)
+	}
+	if result.ProductID != "" && !strings.EqualFold(result.ProductID, req.ProductID) {
+		return nil, fmt.Errorf("%w: receipt is for product %q", domainErrors.ErrReceiptInvalid, result.ProductID)
+	}
+
+	// Restoring a purchase on iOS yields a new transaction ID; the original
+	// one identifies the purchase across restores
+	txID := result.TransactionID
+	if result.OriginalTxID != "" {
+		txID = result.OriginalTxID
+	}
+	unlock := entity.NewLifetimeEntitlement(appID, userUUID, req.Platform, req.ProductID, txID, result.OriginalTxID, product, time.Now())
+	stored, created, err := c.lifetime.Grant(ctx, unlock)
+	if err != nil {
+		return nil, err
+	}
+	if stored.UserID != userUUID {
+		return nil, fmt.Errorf("%w: purchase was unlocked for another user", domainErrors.ErrReceiptAlreadyProcessed)
+	}
+	if !stored.IsActive() {
+		return nil, fmt.Errorf("%w: purchase was refunded", domainErrors.ErrReceiptInvalid)
+	}
+
+	return &dto.LifetimeEntitlementResponse{
+		ID:          stored.ID.String(),
+		Platform:    stored.Platform,
+		ProductID:   stored.ProductID,
+		Status:      string(stored.Status),
+		PurchasedAt: stored.PurchasedAt.Format(time.RFC3339),
+		IsNew:       created,
+	}, nil
+}
+
+// validateLifetimeRequest applies the receipt checks of a subscription
+// purchase; Google Play receipts must be for an in-app product.
+func validateLifetimeRequest(req *dto.VerifyIAPRequest) error {
+	if err := validateIAPRequest(req); err != nil {
+		return err
+	}
+	if req.Platform == "android" {
+		var payload androidReceiptPayload
+		_ = json.Unmarshal([]byte(req.ReceiptData), &payload)
+		if payload.Type != "inapp" {
+			return domainErrors.NewValidationError("receipt_data", `field "type" must be "inapp" for lifetime products`)
+		}
+	}
+	return nil
+}
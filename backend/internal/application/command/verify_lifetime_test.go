@@ -0,0 +1,75 @@
+package command
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bivex/paywall-iap/internal/application/dto"
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+)
+
+type lifetimeGranterStub struct{ existing *entity.LifetimeEntitlement }
+
+func (s *lifetimeGranterStub) Grant(_ context.Context, e *entity.LifetimeEntitlement) (*entity.LifetimeEntitlement, bool, error) {
+	if s.existing != nil {
+		return s.existing, false, nil
+	}
+	s.existing = e
+	return e, true, nil
+}
+
+var lifetimeCatalog = consumableCatalogStub{&entity.AppSettings{
+	LifetimeProducts: map[string]entity.LifetimeProduct{"com.app.lifetime": {Price: 49.99}},
+}}
+
+func TestVerifyLifetime_UnlocksByOriginalTransaction(t *testing.T) {
+	granter := &lifetimeGranterStub{}
+	ios := &staticVerifierAdapter{verifierStub{&IAPVerificationResult{Valid: true, TransactionID: "4001", OriginalTxID: "4000", ProductID: "com.app.lifetime"}}}
+	cmd := NewVerifyLifetimeCommand(lifetimeCatalog, granter, ios, nil)
+
+	resp, err := cmd.Execute(context.Background(), uuid.NewString(), uuid.New(), &dto.VerifyIAPRequest{
+		Platform: "ios", ReceiptData: "receipt", ProductID: "com.app.lifetime",
+	})
+
+	require.NoError(t, err)
+	assert.True(t, resp.IsNew)
+	assert.Equal(t, "active", resp.Status)
+	require.NotNil(t, granter.existing)
+	assert.Equal(t, "4000", granter.existing.ProviderTxID, "restores reuse the original transaction")
+	assert.Equal(t, 49.99, granter.existing.Amount)
+}
+
+func TestVerifyLifetime_RejectsPurchaseOfAnotherUserOrRefunded(t *testing.T) {
+	ios := &staticVerifierAdapter{verifierStub{&IAPVerificationResult{Valid: true, TransactionID: "4000", ProductID: "com.app.lifetime"}}}
+	req := &dto.VerifyIAPRequest{Platform: "ios", ReceiptData: "receipt", ProductID: "com.app.lifetime"}
+	owner := uuid.New()
+
+	other := entity.NewLifetimeEntitlement(uuid.New(), uuid.New(), "ios", "com.app.lifetime", "4000", "", entity.LifetimeProduct{}, time.Now())
+	_, err := NewVerifyLifetimeCommand(lifetimeCatalog, &lifetimeGranterStub{existing: other}, ios, nil).
+		Execute(context.Background(), owner.String(), uuid.New(), req)
+	assert.ErrorIs(t, err, domainErrors.ErrReceiptAlreadyProcessed)
+
+	refunded := entity.NewLifetimeEntitlement(uuid.New(), owner, "ios", "com.app.lifetime", "4000", "", entity.LifetimeProduct{}, time.Now())
+	require.NoError(t, refunded.Revoke("refund", time.Now()))
+	_, err = NewVerifyLifetimeCommand(lifetimeCatalog, &lifetimeGranterStub{existing: refunded}, ios, nil).
+		Execute(context.Background(), owner.String(), uuid.New(), req)
+	assert.ErrorIs(t, err, domainErrors.ErrReceiptInvalid)
+}
+
+func TestVerifyLifetime_RejectsUnconfiguredProduct(t *testing.T) {
+	cmd := NewVerifyLifetimeCommand(lifetimeCatalog, &lifetimeGranterStub{}, nil, nil)
+
+	_, err := cmd.Execute(context.Background(), uuid.NewString(), uuid.New(), &dto.VerifyIAPRequest{
+		Platform: "ios", ReceiptData: "receipt", ProductID: "com.app.coins.100",
+	})
+
+	var validationErr *domainErrors.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "product_id", validationErr.Field)
+}
@@ -23,6 +23,27 @@ type AdminLogoutRequest struct {
 	RefreshToken string `json:"refresh_token"`
 }
 
+// ClientTokenResponse is the RFC 6749 §5.1 client-credentials token response
+type ClientTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
+
+// TokenIntrospectionResponse is the RFC 7662 introspection response.
+// Inactive tokens only carry Active.
+type TokenIntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	AppID     string `json:"app_id,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+}
+
 // RegisterRequest represents a user registration request
 type RegisterRequest struct {
 	PlatformUserID string `json:"platform_user_id" binding:"required"`
@@ -31,6 +52,9 @@ type RegisterRequest struct {
 	AppVersion     string `json:"app_version" binding:"required"`
 	Email          string `json:"email" binding:"omitempty,email"`
 	AppID          string `json:"app_id" binding:"omitempty,uuid"`
+	// UserAgent and IPAddress are taken from the request, not the body
+	UserAgent string `json:"-"`
+	IPAddress string `json:"-"`
 }
 
 // RegisterResponse represents a registration response
@@ -46,6 +70,21 @@ type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
+// SessionResponse is a signed-in device of the current user
+type SessionResponse struct {
+	ID         string `json:"id"`
+	DeviceID   string `json:"device_id"`
+	Platform   string `json:"platform"`
+	AppVersion string `json:"app_version"`
+	UserAgent  string `json:"user_agent"`
+	IPAddress  string `json:"ip_address"`
+	CreatedAt  string `json:"created_at"`
+	LastSeenAt string `json:"last_seen_at"`
+	ExpiresAt  string `json:"expires_at"`
+	// Current marks the session of the access token making the request
+	Current bool `json:"current"`
+}
+
 // RefreshTokenResponse represents a refresh token response
 type RefreshTokenResponse struct {
 	AccessToken  string `json:"access_token"`
@@ -61,6 +100,8 @@ type VerifyIAPRequest struct {
 	ReceiptData   string `json:"receipt_data" binding:"required"`
 	ProductID     string `json:"product_id" binding:"required"`
 	TransactionID string `json:"transaction_id,omitempty"`
+	// Store is the Android store the purchase was made in; empty is Google Play
+	Store string `json:"store,omitempty" binding:"omitempty,oneof=google amazon huawei"`
 }
 
 // VerifyIAPResponse represents an IAP verification response
@@ -71,6 +112,58 @@ type VerifyIAPResponse struct {
 	AutoRenew      bool   `json:"auto_renew"`
 	PlanType       string `json:"plan_type"`
 	IsNew          bool   `json:"is_new"`
+	// PendingPurchaseID is set with status "pending": the store deferred the
+	// payment and no subscription exists yet
+	PendingPurchaseID string `json:"pending_purchase_id,omitempty"`
+}
+
+// ========== CREDIT DTOs ==========
+
+// CreditPurchaseResponse is returned after verifying a consumable receipt
+type CreditPurchaseResponse struct {
+	EntryID   string `json:"entry_id"`
+	ProductID string `json:"product_id"`
+	Credits   int64  `json:"credits"`
+	Balance   int64  `json:"balance"`
+	// IsNew is false when the receipt had already been credited
+	IsNew bool `json:"is_new"`
+}
+
+// ConsumeCreditsRequest debits credits from the user's balance
+type ConsumeCreditsRequest struct {
+	Amount         int64  `json:"amount" binding:"required,gt=0"`
+	IdempotencyKey string `json:"idempotency_key" binding:"required,max=200"`
+	Description    string `json:"description" binding:"max=500"`
+}
+
+// CreditEntryResponse is one credit ledger entry
+type CreditEntryResponse struct {
+	ID           string `json:"id"`
+	Kind         string `json:"kind"`
+	Delta        int64  `json:"delta"`
+	BalanceAfter int64  `json:"balance_after"`
+	ProductID    string `json:"product_id,omitempty"`
+	Description  string `json:"description,omitempty"`
+	CreatedAt    string `json:"created_at"`
+}
+
+// CreditBalanceResponse is the user's balance with recent ledger entries
+type CreditBalanceResponse struct {
+	Balance int64                 `json:"balance"`
+	Entries []CreditEntryResponse `json:"entries"`
+}
+
+// ========== LIFETIME DTOs ==========
+
+// LifetimeEntitlementResponse is a lifetime unlock as returned to the app
+type LifetimeEntitlementResponse struct {
+	ID          string `json:"id"`
+	Platform    string `json:"platform"`
+	ProductID   string `json:"product_id"`
+	Status      string `json:"status"`
+	PurchasedAt string `json:"purchased_at"`
+	// IsNew is false when the receipt had already been verified
+	IsNew bool `json:"is_new,omitempty"`
 }
 
 // ========== SUBSCRIPTION DTOs ==========
@@ -87,13 +180,29 @@ type SubscriptionResponse struct {
 	AutoRenew bool   `json:"auto_renew"`
 	CreatedAt string `json:"created_at"`
 	UpdatedAt string `json:"updated_at"`
+	// Platforms with an active subscription; the fields above describe the
+	// one that expires last
+	Platforms      []string `json:"platforms,omitempty"`
+	MultipleActive bool     `json:"multiple_active,omitempty"`
 }
 
 // AccessCheckResponse represents an access check response
 type AccessCheckResponse struct {
-	HasAccess bool   `json:"has_access"`
-	ExpiresAt string `json:"expires_at,omitempty"`
-	Reason    string `json:"reason,omitempty"`
+	HasAccess        bool     `json:"has_access"`
+	ExpiresAt        string   `json:"expires_at,omitempty"`
+	Reason           string   `json:"reason,omitempty"`
+	Platforms        []string `json:"platforms,omitempty"`
+	MultipleActive   bool     `json:"multiple_active,omitempty"`
+	// Lifetime is set when a non-consumable unlock grants permanent access
+	Lifetime         bool     `json:"lifetime,omitempty"`
+	LifetimeProducts []string `json:"lifetime_products,omitempty"`
+	// GracePeriod is set when access comes from an active grace period;
+	// expires_at is then the end of the grace period
+	GracePeriod      bool     `json:"grace_period,omitempty"`
+	// EntitlementToken is a signed JWT of this response for offline checks,
+	// verifiable with GET /v1/entitlements/jwks.json
+	EntitlementToken          string `json:"entitlement_token,omitempty"`
+	EntitlementTokenExpiresAt string `json:"entitlement_token_expires_at,omitempty"`
 }
 
 // CancelSubscriptionRequest represents a cancel subscription request
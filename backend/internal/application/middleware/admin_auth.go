@@ -1,7 +1,6 @@
 package middleware
 
 import (
-	"fmt"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -12,9 +11,16 @@ import (
 	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
 )
 
-// AdminMiddleware ensures the user is an admin
-func AdminMiddleware(userRepo repository.UserRepository, jwtSecret string) gin.HandlerFunc {
+// AdminMiddleware ensures the user is an admin. keyfunc resolves token
+// verification keys, normally JWTMiddleware.Keyfunc.
+func AdminMiddleware(userRepo repository.UserRepository, keyfunc jwt.Keyfunc) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// Client tokens were already authorized by OAuthClientMiddleware
+		if _, ok := c.Get(OAuthClientKey); ok {
+			c.Next()
+			return
+		}
+
 		// 1. Get token from header
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -31,12 +37,7 @@ func AdminMiddleware(userRepo repository.UserRepository, jwtSecret string) gin.H
 		}
 
 		// 2. Parse and verify JWT
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte(jwtSecret), nil
-		})
+		token, err := jwt.Parse(tokenString, keyfunc)
 
 		if err != nil || !token.Valid {
 			response.Unauthorized(c, "Invalid or expired token")
@@ -51,6 +52,13 @@ func AdminMiddleware(userRepo repository.UserRepository, jwtSecret string) gin.H
 			return
 		}
 
+		// Support sees what a user sees, never what an admin can do
+		if _, impersonated := claims["imp"]; impersonated {
+			response.Forbidden(c, "Impersonation tokens cannot access admin routes")
+			c.Abort()
+			return
+		}
+
 		// 3. Resolve user and check role
 		userIDStr, ok := claims["sub"].(string)
 		if !ok {
@@ -4,12 +4,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"strings"
 	"time"
 
 	"github.com/bivex/paywall-iap/internal/appctx"
 	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
 	"github.com/bivex/paywall-iap/internal/infrastructure/logging"
+	"github.com/bivex/paywall-iap/internal/infrastructure/signing"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
@@ -23,22 +25,51 @@ type JWTClaims struct {
 	JTI    string `json:"jti"` // JWT ID for revocation
 	Role   string `json:"role,omitempty"`
 	AppID  string `json:"app_id,omitempty"`
+	// SessionID ties user tokens to a signed-in device (user_sessions)
+	SessionID string `json:"sid,omitempty"`
+	// ClientID and Scope are only set on client-credentials tokens
+	ClientID string `json:"client_id,omitempty"`
+	Scope    string `json:"scope,omitempty"`
+	// ImpersonatedBy is the admin a support impersonation token was issued to
+	ImpersonatedBy string `json:"imp,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// ClientTokenRole is the role claim of client-credentials tokens
+const ClientTokenRole = "service"
+
+const (
+	// ImpersonatedByKey holds the impersonating admin's ID on impersonated requests
+	ImpersonatedByKey = "impersonated_by"
+	// DefaultImpersonationTTL and MaxImpersonationTTL bound impersonation tokens
+	DefaultImpersonationTTL = 15 * time.Minute
+	MaxImpersonationTTL     = time.Hour
+)
+
+// impersonationAuditor records every request made with an impersonation token
+type impersonationAuditor interface {
+	LogAction(ctx context.Context, adminID uuid.UUID, action, targetType string, targetUserID *uuid.UUID, details map[string]interface{}) error
+}
+
 // JWTMiddleware handles JWT validation and revocation checking
 type JWTMiddleware struct {
-	secret          []byte
+	secret []byte
+	// keys sign tokens when set; acceptHMAC keeps secret-signed tokens valid
+	// while services migrate to verifying against the JWKS
+	keys            *signing.KeySet
+	acceptHMAC      bool
 	refreshCache    *redis.Client
 	accessTTL       time.Duration
 	blocklistPrefix string
 	logger          *zap.Logger
+	audit           impersonationAuditor
 }
 
 // NewJWTMiddleware creates a new JWT middleware
 func NewJWTMiddleware(secret string, redisClient *redis.Client, accessTTL time.Duration) *JWTMiddleware {
 	return &JWTMiddleware{
 		secret:          []byte(secret),
+		acceptHMAC:      true,
 		refreshCache:    redisClient,
 		accessTTL:       accessTTL,
 		blocklistPrefix: "jwt:blocked:",
@@ -46,6 +77,52 @@ func NewJWTMiddleware(secret string, redisClient *redis.Client, accessTTL time.D
 	}
 }
 
+// WithSigningKeys signs new tokens with the key set's current key instead of
+// the shared secret. acceptHMAC keeps verifying secret-signed tokens, so
+// tokens issued before the switch stay valid until they expire.
+func (j *JWTMiddleware) WithSigningKeys(keys *signing.KeySet, acceptHMAC bool) *JWTMiddleware {
+	if keys.Empty() {
+		return j
+	}
+	j.keys = keys
+	j.acceptHMAC = acceptHMAC
+	return j
+}
+
+// WithImpersonationAudit audits every request made with an impersonation token
+func (j *JWTMiddleware) WithImpersonationAudit(audit impersonationAuditor) *JWTMiddleware {
+	j.audit = audit
+	return j
+}
+
+// JWKS returns the public keys tokens are signed with; empty while tokens
+// are signed with the shared secret
+func (j *JWTMiddleware) JWKS() signing.JWKS {
+	return j.keys.JWKS()
+}
+
+// Keyfunc resolves the verification key of a token: asymmetric tokens by
+// their kid, HMAC tokens by the shared secret while it is accepted
+func (j *JWTMiddleware) Keyfunc(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		if !j.acceptHMAC {
+			return nil, errors.New("HMAC-signed tokens are no longer accepted")
+		}
+		return j.secret, nil
+	}
+	if j.keys.Empty() {
+		return nil, errors.New("unexpected signing method")
+	}
+	return j.keys.Keyfunc(token)
+}
+
+func (j *JWTMiddleware) sign(claims jwt.Claims) (string, error) {
+	if !j.keys.Empty() {
+		return j.keys.Sign(claims)
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(j.secret)
+}
+
 // Authenticate validates the JWT token and sets user context
 func (j *JWTMiddleware) Authenticate() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -68,12 +145,7 @@ func (j *JWTMiddleware) Authenticate() gin.HandlerFunc {
 
 		// Parse and validate token
 		claims := &JWTClaims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, errors.New("unexpected signing method")
-			}
-			return j.secret, nil
-		})
+		token, err := jwt.ParseWithClaims(tokenString, claims, j.Keyfunc)
 
 		if err != nil || !token.Valid {
 			response.Unauthorized(c, "Invalid token")
@@ -104,6 +176,13 @@ func (j *JWTMiddleware) Authenticate() gin.HandlerFunc {
 		if claims.Role != "" {
 			c.Set("role", claims.Role)
 		}
+		if claims.SessionID != "" {
+			c.Set("session_id", claims.SessionID)
+		}
+		if claims.ClientID != "" {
+			c.Set("client_id", claims.ClientID)
+			c.Set("scope", claims.Scope)
+		}
 		if claims.AppID != "" {
 			c.Set("app_id", claims.AppID)
 			// Also inject into request context so repositories can access it
@@ -113,10 +192,56 @@ func (j *JWTMiddleware) Authenticate() gin.HandlerFunc {
 			}
 		}
 
+		if claims.ImpersonatedBy != "" {
+			j.serveImpersonated(c, claims)
+			return
+		}
+
 		c.Next()
 	}
 }
 
+// serveImpersonated lets support see what the user sees without acting for
+// them: only reads are allowed, responses carry the impersonation flag and
+// every request is audited against the admin
+func (j *JWTMiddleware) serveImpersonated(c *gin.Context, claims *JWTClaims) {
+	c.Set(ImpersonatedByKey, claims.ImpersonatedBy)
+	c.Header("X-Impersonated-By", claims.ImpersonatedBy)
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+	default:
+		response.Error(c, http.StatusForbidden, "IMPERSONATION_READ_ONLY", "Impersonation tokens are read-only")
+		c.Abort()
+		j.auditImpersonated(c, claims)
+		return
+	}
+	c.Next()
+	j.auditImpersonated(c, claims)
+}
+
+func (j *JWTMiddleware) auditImpersonated(c *gin.Context, claims *JWTClaims) {
+	if j.audit == nil {
+		return
+	}
+	adminID, err := uuid.Parse(claims.ImpersonatedBy)
+	if err != nil {
+		return
+	}
+	var userID *uuid.UUID
+	if id, err := uuid.Parse(claims.UserID); err == nil {
+		userID = &id
+	}
+	details := map[string]interface{}{
+		"jti":    claims.JTI,
+		"method": c.Request.Method,
+		"path":   c.Request.URL.Path,
+		"status": c.Writer.Status(),
+	}
+	if err := j.audit.LogAction(c.Request.Context(), adminID, "impersonated_request", "user", userID, details); err != nil {
+		j.logger.Warn("failed to audit impersonated request", zap.String("jti", claims.JTI), zap.Error(err))
+	}
+}
+
 // GenerateAccessTokenWithRole creates a new access token that includes the user's role.
 func (j *JWTMiddleware) GenerateAccessTokenWithRole(userID, role string) (string, string, error) {
 	jti := uuid.New().String()
@@ -133,8 +258,7 @@ func (j *JWTMiddleware) GenerateAccessTokenWithRole(userID, role string) (string
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(j.secret)
+	tokenString, err := j.sign(claims)
 	if err != nil {
 		return "", "", err
 	}
@@ -157,8 +281,7 @@ func (j *JWTMiddleware) GenerateAccessToken(userID string) (string, string, erro
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(j.secret)
+	tokenString, err := j.sign(claims)
 	if err != nil {
 		return "", "", err
 	}
@@ -181,8 +304,7 @@ func (j *JWTMiddleware) GenerateRefreshToken(userID string) (string, string, err
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(j.secret)
+	tokenString, err := j.sign(claims)
 	if err != nil {
 		return "", "", err
 	}
@@ -218,8 +340,7 @@ func (j *JWTMiddleware) generateAccessInternal(userID, appID, role string) (stri
 			Issuer:    "iap-system",
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(j.secret)
+	tokenString, err := j.sign(claims)
 	if err != nil {
 		return "", "", err
 	}
@@ -239,24 +360,116 @@ func (j *JWTMiddleware) generateRefreshInternal(userID, appID string) (string, s
 			Issuer:    "iap-system",
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(j.secret)
+	tokenString, err := j.sign(claims)
 	if err != nil {
 		return "", "", err
 	}
 	return tokenString, jti, nil
 }
 
+// SessionTokens is an access and refresh token pair issued for a session
+type SessionTokens struct {
+	AccessToken      string
+	AccessJTI        string
+	RefreshToken     string
+	RefreshJTI       string
+	RefreshExpiresAt time.Time
+}
+
+// GenerateSessionTokens creates an access and refresh token pair carrying
+// the session ID so the session can be rotated and revoked as a whole.
+func (j *JWTMiddleware) GenerateSessionTokens(userID, appID, role, sessionID string) (*SessionTokens, error) {
+	now := time.Now()
+	out := &SessionTokens{
+		AccessJTI:        uuid.New().String(),
+		RefreshJTI:       uuid.New().String(),
+		RefreshExpiresAt: now.Add(30 * 24 * time.Hour), // 30 days
+	}
+	sign := func(jti, tokenRole string, expiresAt time.Time) (string, error) {
+		claims := &JWTClaims{
+			UserID:    userID,
+			JTI:       jti,
+			Role:      tokenRole,
+			AppID:     appID,
+			SessionID: sessionID,
+			RegisteredClaims: jwt.RegisteredClaims{
+				IssuedAt:  jwt.NewNumericDate(now),
+				ExpiresAt: jwt.NewNumericDate(expiresAt),
+				Issuer:    "iap-system",
+			},
+		}
+		return j.sign(claims)
+	}
+
+	var err error
+	if out.AccessToken, err = sign(out.AccessJTI, role, now.Add(j.accessTTL)); err != nil {
+		return nil, fmt.Errorf("failed to generate access token: %w", err)
+	}
+	if out.RefreshToken, err = sign(out.RefreshJTI, "", out.RefreshExpiresAt); err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	return out, nil
+}
+
+// GenerateClientToken creates an access token for an OAuth client. The
+// subject is prefixed with "client:" so it never parses as a user ID.
+func (j *JWTMiddleware) GenerateClientToken(clientID, appID string, scopes []string) (string, string, error) {
+	jti := uuid.New().String()
+	now := time.Now()
+	claims := &JWTClaims{
+		UserID:   "client:" + clientID,
+		JTI:      jti,
+		Role:     ClientTokenRole,
+		AppID:    appID,
+		ClientID: clientID,
+		Scope:    strings.Join(scopes, " "),
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(j.accessTTL)),
+			Issuer:    "iap-system",
+		},
+	}
+	tokenString, err := j.sign(claims)
+	if err != nil {
+		return "", "", err
+	}
+	return tokenString, jti, nil
+}
+
+// GenerateImpersonationToken creates a short-lived, read-only access token
+// for userID on behalf of adminID. ttl is capped at MaxImpersonationTTL; 0
+// uses DefaultImpersonationTTL. No refresh token is issued.
+func (j *JWTMiddleware) GenerateImpersonationToken(userID, appID, adminID string, ttl time.Duration) (string, string, time.Time, error) {
+	if ttl <= 0 {
+		ttl = DefaultImpersonationTTL
+	}
+	ttl = min(ttl, MaxImpersonationTTL)
+	jti := uuid.New().String()
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	claims := &JWTClaims{
+		UserID:         userID,
+		JTI:            jti,
+		AppID:          appID,
+		ImpersonatedBy: adminID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			Issuer:    "iap-system",
+		},
+	}
+	tokenString, err := j.sign(claims)
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+	return tokenString, jti, expiresAt, nil
+}
+
 // ParseToken parses a token string and returns the claims without checking the Redis blocklist.
 // Useful for testing and internal token inspection.
 func (j *JWTMiddleware) ParseToken(tokenString string) (*JWTClaims, error) {
 	claims := &JWTClaims{}
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return j.secret, nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, claims, j.Keyfunc)
 	if err != nil || !token.Valid {
 		return nil, errors.New("invalid token")
 	}
@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bivex/paywall-iap/internal/infrastructure/signing"
+)
+
+const testJWTSecret = "test-secret-test-secret-test-secret"
+
+func testKeySet(t *testing.T) *signing.KeySet {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	keys := &signing.KeySet{}
+	require.NoError(t, keys.Add("k1", priv))
+	return keys
+}
+
+func TestJWTMiddleware_SigningKeyMigration(t *testing.T) {
+	legacy := NewJWTMiddleware(testJWTSecret, nil, time.Minute)
+	hmacToken, _, err := legacy.GenerateAccessToken("user-1")
+	require.NoError(t, err)
+
+	keys := testKeySet(t)
+	migrating := NewJWTMiddleware(testJWTSecret, nil, time.Minute).WithSigningKeys(keys, true)
+	signed, _, err := migrating.GenerateAccessToken("user-1")
+	require.NoError(t, err)
+
+	parsed, _, err := jwt.NewParser().ParseUnverified(signed, &JWTClaims{})
+	require.NoError(t, err)
+	assert.Equal(t, "EdDSA", parsed.Method.Alg())
+	assert.Equal(t, "k1", parsed.Header["kid"])
+
+	for _, raw := range []string{hmacToken, signed} {
+		claims, err := migrating.ParseToken(raw)
+		require.NoError(t, err)
+		assert.Equal(t, "user-1", claims.UserID)
+	}
+	assert.Len(t, migrating.JWKS().Keys, 1)
+
+	// Once migrated, secret-signed tokens are refused
+	migrated := NewJWTMiddleware(testJWTSecret, nil, time.Minute).WithSigningKeys(keys, false)
+	_, err = migrated.ParseToken(hmacToken)
+	assert.Error(t, err)
+	_, err = migrated.ParseToken(signed)
+	assert.NoError(t, err)
+
+	// Tokens from the new keys are refused by a secret-only deployment
+	_, err = legacy.ParseToken(signed)
+	assert.Error(t, err)
+	assert.Empty(t, legacy.JWKS().Keys)
+}
+
+type recordedAudit struct {
+	adminID uuid.UUID
+	action  string
+	details map[string]interface{}
+}
+
+type fakeImpersonationAuditor struct {
+	entries []recordedAudit
+}
+
+func (f *fakeImpersonationAuditor) LogAction(_ context.Context, adminID uuid.UUID, action, _ string, _ *uuid.UUID, details map[string]interface{}) error {
+	f.entries = append(f.entries, recordedAudit{adminID: adminID, action: action, details: details})
+	return nil
+}
+
+func TestJWTMiddleware_GenerateImpersonationToken(t *testing.T) {
+	j := NewJWTMiddleware(testJWTSecret, nil, time.Minute)
+	adminID := uuid.NewString()
+
+	token, jti, expiresAt, err := j.GenerateImpersonationToken("user-1", "app-1", adminID, 3*time.Hour)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(MaxImpersonationTTL), expiresAt, 5*time.Second)
+
+	claims, err := j.ParseToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", claims.UserID)
+	assert.Equal(t, adminID, claims.ImpersonatedBy)
+	assert.Equal(t, jti, claims.JTI)
+	assert.Empty(t, claims.Role)
+
+	_, _, expiresAt, err = j.GenerateImpersonationToken("user-1", "app-1", adminID, 0)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(DefaultImpersonationTTL), expiresAt, 5*time.Second)
+}
+
+func TestJWTMiddleware_ImpersonatedRequestsAreReadOnlyAndAudited(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	auditor := &fakeImpersonationAuditor{}
+	j := NewJWTMiddleware(testJWTSecret, nil, time.Minute).WithImpersonationAudit(auditor)
+	adminID := uuid.New()
+	claims := &JWTClaims{UserID: uuid.NewString(), JTI: "jti-1", ImpersonatedBy: adminID.String()}
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) { j.serveImpersonated(c, claims) })
+	r.GET("/v1/user/paywall", func(c *gin.Context) {
+		assert.Equal(t, adminID.String(), c.GetString(ImpersonatedByKey))
+		c.Status(http.StatusOK)
+	})
+	r.POST("/v1/verify/iap", func(c *gin.Context) { t.Fatal("write reached the handler") })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/user/paywall", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, adminID.String(), w.Header().Get("X-Impersonated-By"))
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/verify/iap", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "IMPERSONATION_READ_ONLY")
+	assert.Contains(t, w.Body.String(), `"impersonated":true`)
+
+	require.Len(t, auditor.entries, 2)
+	assert.Equal(t, adminID, auditor.entries[0].adminID)
+	assert.Equal(t, "impersonated_request", auditor.entries[0].action)
+	assert.Equal(t, "/v1/user/paywall", auditor.entries[0].details["path"])
+	assert.Equal(t, http.StatusForbidden, auditor.entries[1].details["status"])
+}
@@ -0,0 +1,269 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/infrastructure/config"
+	"github.com/bivex/paywall-iap/internal/infrastructure/logging"
+	"github.com/bivex/paywall-iap/internal/infrastructure/metrics"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+// Priority is the load shedding class of a request
+type Priority string
+
+const (
+	// PriorityCritical is purchase verification and provider webhooks
+	PriorityCritical Priority = "critical"
+	PriorityNormal   Priority = "normal"
+	// PriorityLow is analytics reads and access checks past the polling rate
+	PriorityLow Priority = "low"
+
+	// priorityAccessCheck is resolved to normal or low by the polling rate
+	priorityAccessCheck Priority = "access_check"
+	// priorityExempt is never shed (health checks, metrics scrapes and the
+	// client status clients back off by)
+	priorityExempt Priority = "exempt"
+)
+
+// ShedBudget bounds a priority class. A request of the class is admitted
+// while fewer than MaxInFlight requests of any class are running (0 means no
+// limit); otherwise it waits for a slot if fewer than MaxQueue requests of the
+// class are already waiting, and is shed if none frees up in time.
+type ShedBudget struct {
+	MaxInFlight int
+	MaxQueue    int
+}
+
+var loadShedRequests = metrics.NewCounterVec(
+	"load_shed_requests_total",
+	"Requests rejected with 503 because the server was over its budget for their priority class",
+	"class",
+)
+
+var loadShedQueued = metrics.NewCounterVec(
+	"load_shed_queued_total",
+	"Requests that waited for an in-flight slot before being admitted or shed",
+	"class",
+)
+
+// ParseShedBudgets parses a comma-separated list of class=max_in_flight/max_queue
+// budgets, e.g. "critical=0/0,normal=512/256,low=64/0"
+func ParseShedBudgets(spec string) (map[Priority]ShedBudget, error) {
+	budgets := map[Priority]ShedBudget{}
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		class, limits, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid shed budget %q: want class=max_in_flight/max_queue", pair)
+		}
+		p := Priority(strings.TrimSpace(class))
+		if p != PriorityCritical && p != PriorityNormal && p != PriorityLow {
+			return nil, fmt.Errorf("invalid shed budget %q: unknown class %q", pair, p)
+		}
+		inFlight, queue, ok := strings.Cut(limits, "/")
+		if !ok {
+			return nil, fmt.Errorf("invalid shed budget %q: want class=max_in_flight/max_queue", pair)
+		}
+		var b ShedBudget
+		var err error
+		if b.MaxInFlight, err = strconv.Atoi(strings.TrimSpace(inFlight)); err != nil || b.MaxInFlight < 0 {
+			return nil, fmt.Errorf("invalid shed budget %q: max_in_flight must be a non-negative integer", pair)
+		}
+		if b.MaxQueue, err = strconv.Atoi(strings.TrimSpace(queue)); err != nil || b.MaxQueue < 0 {
+			return nil, fmt.Errorf("invalid shed budget %q: max_queue must be a non-negative integer", pair)
+		}
+		budgets[p] = b
+	}
+	return budgets, nil
+}
+
+// LoadShedder rejects lower-priority requests with 503 and Retry-After once
+// the server has too many requests in flight, so receipt verification and
+// webhooks keep their capacity under overload. Budgets are per process.
+type LoadShedder struct {
+	enabled      bool
+	budgets      map[Priority]ShedBudget
+	queueTimeout time.Duration
+	retryAfter   time.Duration
+	accessChecks *tokenBucket
+	logger       *zap.Logger
+
+	mu       sync.Mutex
+	inFlight int
+	queued   map[Priority]int
+	released chan struct{} // closed and replaced whenever a slot frees up
+}
+
+// NewLoadShedder creates a load shedder from the load shedding configuration
+func NewLoadShedder(cfg config.LoadSheddingConfig) (*LoadShedder, error) {
+	budgets, err := ParseShedBudgets(cfg.Classes)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.AccessCheckRate < 0 {
+		return nil, fmt.Errorf("access check rate must not be negative")
+	}
+	s := &LoadShedder{
+		enabled:      cfg.Enabled,
+		budgets:      budgets,
+		queueTimeout: cfg.QueueTimeout,
+		retryAfter:   cfg.RetryAfter,
+		logger:       logging.Logger,
+		queued:       map[Priority]int{},
+		released:     make(chan struct{}),
+	}
+	if cfg.AccessCheckRate > 0 {
+		s.accessChecks = newTokenBucket(float64(cfg.AccessCheckRate), time.Now)
+	}
+	return s, nil
+}
+
+// Middleware returns a Gin middleware that sheds requests over budget
+func (s *LoadShedder) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !s.enabled {
+			c.Next()
+			return
+		}
+		class := classifyRequest(c)
+		if class == priorityExempt {
+			c.Next()
+			return
+		}
+		if class == priorityAccessCheck {
+			class = PriorityNormal
+			if s.accessChecks != nil && !s.accessChecks.take() {
+				class = PriorityLow
+			}
+		}
+
+		if !s.acquire(c, class) {
+			loadShedRequests.Inc(string(class))
+			retryAfter := int(s.retryAfter.Seconds())
+			if retryAfter < 1 {
+				retryAfter = 1
+			}
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			response.ServiceUnavailable(c, "Server is overloaded, retry later")
+			c.Abort()
+			return
+		}
+		defer s.release()
+		c.Next()
+	}
+}
+
+func (s *LoadShedder) acquire(c *gin.Context, class Priority) bool {
+	budget, ok := s.budgets[class]
+	if !ok {
+		budget = s.budgets[PriorityNormal]
+	}
+	fits := func() bool { return budget.MaxInFlight == 0 || s.inFlight < budget.MaxInFlight }
+
+	s.mu.Lock()
+	if fits() {
+		s.inFlight++
+		s.mu.Unlock()
+		return true
+	}
+	if s.queued[class] >= budget.MaxQueue || s.queueTimeout <= 0 {
+		s.mu.Unlock()
+		return false
+	}
+	s.queued[class]++
+	loadShedQueued.Inc(string(class))
+	defer func() {
+		s.queued[class]--
+		s.mu.Unlock()
+	}()
+
+	timer := time.NewTimer(s.queueTimeout)
+	defer timer.Stop()
+	for {
+		released := s.released
+		s.mu.Unlock()
+		select {
+		case <-released:
+			s.mu.Lock()
+			if fits() {
+				s.inFlight++
+				return true
+			}
+		case <-timer.C:
+			s.mu.Lock()
+			return false
+		case <-c.Request.Context().Done():
+			s.mu.Lock()
+			return false
+		}
+	}
+}
+
+func (s *LoadShedder) release() {
+	s.mu.Lock()
+	s.inFlight--
+	close(s.released)
+	s.released = make(chan struct{})
+	s.mu.Unlock()
+}
+
+// classifyRequest maps a request to its priority class by matched route
+func classifyRequest(c *gin.Context) Priority {
+	path := c.FullPath()
+	if path == "" {
+		path = c.Request.URL.Path
+	}
+	switch {
+	case path == "/health" || path == "/metrics" || path == "/v1/status":
+		return priorityExempt
+	case strings.HasPrefix(path, "/webhook/") || path == "/v1/verify/iap":
+		return PriorityCritical
+	case path == "/v1/subscription/access":
+		return priorityAccessCheck
+	case c.Request.Method == http.MethodGet && strings.Contains(path, "/analytics"):
+		return PriorityLow
+	}
+	return PriorityNormal
+}
+
+// tokenBucket allows rate events per second with a burst of one second's worth
+type tokenBucket struct {
+	rate float64
+	now  func() time.Time
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, now func() time.Time) *tokenBucket {
+	return &tokenBucket{rate: rate, now: now, tokens: rate, last: now()}
+}
+
+func (b *tokenBucket) take() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.rate {
+		b.tokens = b.rate
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bivex/paywall-iap/internal/infrastructure/config"
+)
+
+// newBlockingRouter serves the routes under test from a handler that waits on hold
+func newBlockingRouter(t *testing.T, cfg config.LoadSheddingConfig, hold chan struct{}, started *sync.WaitGroup) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	shedder, err := NewLoadShedder(cfg)
+	require.NoError(t, err)
+
+	r := gin.New()
+	r.Use(shedder.Middleware())
+	handler := func(c *gin.Context) {
+		if started != nil {
+			started.Done()
+		}
+		<-hold
+		c.Status(http.StatusOK)
+	}
+	r.POST("/v1/verify/iap", handler)
+	r.GET("/v1/admin/analytics/ltv", handler)
+	r.GET("/v1/subscription", handler)
+	r.GET("/health", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/v1/status", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return r
+}
+
+func serve(r *gin.Engine, method, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+	return w
+}
+
+func TestLoadShedder_ShedsLowPriorityBeforeCritical(t *testing.T) {
+	hold := make(chan struct{})
+	var started sync.WaitGroup
+	r := newBlockingRouter(t, config.LoadSheddingConfig{
+		Enabled:    true,
+		Classes:    "critical=0/0,normal=4/0,low=1/0",
+		RetryAfter: 3 * time.Second,
+	}, hold, &started)
+
+	// One normal request in flight fills the low budget
+	started.Add(1)
+	done := make(chan int, 2)
+	go func() { done <- serve(r, http.MethodGet, "/v1/subscription").Code }()
+	started.Wait()
+
+	shedBefore := loadShedRequests.Value("low")
+	w := serve(r, http.MethodGet, "/v1/admin/analytics/ltv")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "3", w.Header().Get("Retry-After"))
+	assert.Equal(t, shedBefore+1, loadShedRequests.Value("low"))
+
+	// Verification is never capped, and health checks and client status are exempt
+	started.Add(1)
+	go func() { done <- serve(r, http.MethodPost, "/v1/verify/iap").Code }()
+	started.Wait()
+	assert.Equal(t, http.StatusOK, serve(r, http.MethodGet, "/health").Code)
+	assert.Equal(t, http.StatusOK, serve(r, http.MethodGet, "/v1/status").Code)
+
+	close(hold)
+	assert.Equal(t, http.StatusOK, <-done)
+	assert.Equal(t, http.StatusOK, <-done)
+}
+
+func TestLoadShedder_QueuedRequestTakesFreedSlot(t *testing.T) {
+	hold := make(chan struct{})
+	var started sync.WaitGroup
+	r := newBlockingRouter(t, config.LoadSheddingConfig{
+		Enabled:      true,
+		Classes:      "normal=1/1",
+		QueueTimeout: 5 * time.Second,
+	}, hold, &started)
+
+	started.Add(1)
+	first := make(chan int, 1)
+	go func() { first <- serve(r, http.MethodGet, "/v1/subscription").Code }()
+	started.Wait()
+
+	queuedBefore := loadShedQueued.Value("normal")
+	started.Add(1)
+	second := make(chan int, 1)
+	go func() { second <- serve(r, http.MethodGet, "/v1/subscription").Code }()
+	require.Eventually(t, func() bool { return loadShedQueued.Value("normal") > queuedBefore }, time.Second, 5*time.Millisecond)
+
+	// The queue is full, so a third request is shed at once
+	assert.Equal(t, http.StatusServiceUnavailable, serve(r, http.MethodGet, "/v1/subscription").Code)
+
+	close(hold)
+	assert.Equal(t, http.StatusOK, <-first)
+	assert.Equal(t, http.StatusOK, <-second)
+}
+
+func TestLoadShedder_DisabledPassesThrough(t *testing.T) {
+	hold := make(chan struct{})
+	close(hold)
+	r := newBlockingRouter(t, config.LoadSheddingConfig{Classes: "low=1/0"}, hold, nil)
+
+	assert.Equal(t, http.StatusOK, serve(r, http.MethodGet, "/v1/admin/analytics/ltv").Code)
+}
+
+func TestParseShedBudgets(t *testing.T) {
+	budgets, err := ParseShedBudgets("critical=0/0, normal=512/256,low=64/0")
+	require.NoError(t, err)
+	assert.Equal(t, ShedBudget{MaxInFlight: 512, MaxQueue: 256}, budgets[PriorityNormal])
+	assert.Equal(t, ShedBudget{MaxInFlight: 64}, budgets[PriorityLow])
+
+	for _, spec := range []string{"batch=1/1", "low=1", "low=-1/0", "low=1/x"} {
+		_, err := ParseShedBudgets(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestTokenBucket_RefillsAtRate(t *testing.T) {
+	now := time.Now()
+	b := newTokenBucket(2, func() time.Time { return now })
+
+	assert.True(t, b.take())
+	assert.True(t, b.take())
+	assert.False(t, b.take(), "burst is one second of tokens")
+
+	now = now.Add(500 * time.Millisecond)
+	assert.True(t, b.take())
+	assert.False(t, b.take())
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+// OAuthClientKey holds the *entity.OAuthClient of a verified client token.
+// AdminMiddleware lets such requests through without a user lookup.
+const OAuthClientKey = "oauth_client"
+
+// OAuthClientMiddleware authorizes client-credentials tokens on admin routes.
+// It must run after Authenticate and before AdminMiddleware. Requests with a
+// user token pass through untouched. For client tokens the client must still
+// be active, the X-App-ID header must match the client's app when it is bound
+// to one, and the token must carry the scope scopeFor returns for the route;
+// routes without a scope are closed to clients.
+func OAuthClientMiddleware(clientRepo repository.OAuthClientRepository, scopeFor func(method, path string) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clientID := c.GetString("client_id")
+		if clientID == "" {
+			c.Next()
+			return
+		}
+
+		client, err := clientRepo.GetByClientID(c.Request.Context(), clientID)
+		if err != nil || !client.IsActive() {
+			response.Unauthorized(c, "Client revoked or unknown")
+			c.Abort()
+			return
+		}
+		if client.AppID != nil {
+			if raw := c.GetHeader("X-App-ID"); raw != "" && !strings.EqualFold(raw, client.AppID.String()) {
+				response.Forbidden(c, "Client is not allowed to access this app")
+				c.Abort()
+				return
+			}
+		}
+
+		required := scopeFor(c.Request.Method, c.FullPath())
+		if required == "" || !entity.HasOAuthScope(entity.ParseOAuthScope(c.GetString("scope")), required) {
+			response.Forbidden(c, "Token lacks the required scope")
+			c.Abort()
+			return
+		}
+
+		c.Set(OAuthClientKey, client)
+		// Audit entries are attributed to the admin who created the client
+		c.Set("admin_id", client.CreatedBy)
+		c.Next()
+	}
+}
+
+// AdminScopeFor maps admin routes to the OAuth scope a client token needs
+func AdminScopeFor(method, path string) string {
+	path = strings.TrimPrefix(path, "/v1/admin")
+	switch {
+	case strings.HasPrefix(path, "/analytics/"),
+		path == "/dashboard/metrics",
+		path == "/revenue-ops":
+		if method == "GET" {
+			return entity.ScopeReadAnalytics
+		}
+	case path == "/experiments/kill-switch":
+		// Spans every app, so app-bound clients never get it
+		return ""
+	case path == "/experiments", strings.HasPrefix(path, "/experiments/"):
+		if method == "GET" {
+			return entity.ScopeReadExperiments
+		}
+		return entity.ScopeWriteExperiments
+	}
+	return ""
+}
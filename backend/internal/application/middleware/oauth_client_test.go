@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+func TestAdminScopeFor(t *testing.T) {
+	assert.Equal(t, entity.ScopeReadExperiments, AdminScopeFor("GET", "/v1/admin/experiments/:id"))
+	assert.Equal(t, entity.ScopeWriteExperiments, AdminScopeFor("PUT", "/v1/admin/experiments/:id"))
+	assert.Equal(t, entity.ScopeReadAnalytics, AdminScopeFor("GET", "/v1/admin/analytics/ltv"))
+
+	// The kill switch spans every app, so no client scope grants it
+	assert.Empty(t, AdminScopeFor("GET", "/v1/admin/experiments/kill-switch"))
+	assert.Empty(t, AdminScopeFor("PUT", "/v1/admin/experiments/kill-switch"))
+}
@@ -14,8 +14,9 @@ import (
 
 // RateLimitConfig defines rate limiting parameters
 type RateLimitConfig struct {
-	Rate  int // requests per second
-	Burst int // maximum burst size
+	Rate   int           // requests per period
+	Burst  int           // maximum burst size
+	Period time.Duration // defaults to one second
 }
 
 // RateLimiter manages rate limiting using Redis
@@ -41,19 +42,30 @@ func NewRateLimiter(redisClient *redis.Client, failOpen bool) *RateLimiter {
 
 // Middleware returns a Gin middleware for rate limiting
 func (r *RateLimiter) Middleware(keyFunc func(*gin.Context) string, config RateLimitConfig) gin.HandlerFunc {
+	return r.MiddlewareFunc(keyFunc, func(*gin.Context) RateLimitConfig { return config })
+}
+
+// MiddlewareFunc is Middleware with the limit chosen per request, for keys
+// that carry their own limit
+func (r *RateLimiter) MiddlewareFunc(keyFunc func(*gin.Context) string, configFunc func(*gin.Context) RateLimitConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		key := keyFunc(c)
 		if key == "" {
 			c.Next()
 			return
 		}
+		config := configFunc(c)
+		period := config.Period
+		if period == 0 {
+			period = time.Second
+		}
 
 		// Create rate limiter for this key
 		limiterKey := r.prefix + key
 		limit := redis_rate.Limit{
 			Rate:   config.Rate,
 			Burst:  config.Burst,
-			Period: time.Second,
+			Period: period,
 		}
 		res, err := r.limiter.Allow(c.Request.Context(), limiterKey, limit)
 		if err != nil {
@@ -143,4 +155,11 @@ var (
 		Rate:  100,
 		Burst: 500,
 	}
+
+	// Client status: 30 requests per minute; the status changes every 15s
+	StatusConfig = RateLimitConfig{
+		Rate:   30,
+		Burst:  10,
+		Period: time.Minute,
+	}
 )
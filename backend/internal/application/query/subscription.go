@@ -3,11 +3,14 @@ package query
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/bivex/paywall-iap/internal/appctx"
 	"github.com/bivex/paywall-iap/internal/application/dto"
 	"github.com/bivex/paywall-iap/internal/domain/entity"
 	"github.com/bivex/paywall-iap/internal/domain/repository"
+	"github.com/bivex/paywall-iap/internal/domain/service"
 	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
 )
 
@@ -30,12 +33,28 @@ func (q *GetSubscriptionQuery) Execute(ctx context.Context, userID string) (*dto
 		return nil, fmt.Errorf("%w: invalid user ID", domainErrors.ErrInvalidInput)
 	}
 
-	sub, err := q.subscriptionRepo.GetActiveByUserID(ctx, userUUID)
+	ent, err := resolveEntitlement(ctx, q.subscriptionRepo, userUUID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get subscription: %w", err)
+		return nil, err
 	}
+	if !ent.HasAccess() {
+		return nil, fmt.Errorf("failed to get subscription: %w", domainErrors.ErrSubscriptionNotActive)
+	}
+
+	resp := q.toResponse(ent.Primary)
+	resp.Platforms = ent.Platforms()
+	resp.MultipleActive = ent.HasConflict()
+	return resp, nil
+}
 
-	return q.toResponse(sub), nil
+// resolveEntitlement merges all of the user's subscriptions, whichever store
+// they were bought on
+func resolveEntitlement(ctx context.Context, repo repository.SubscriptionRepository, userID uuid.UUID) (*service.Entitlement, error) {
+	subs, err := repo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscriptions: %w", err)
+	}
+	return service.ResolveEntitlement(subs, time.Now()), nil
 }
 
 func (q *GetSubscriptionQuery) toResponse(sub *entity.Subscription) *dto.SubscriptionResponse {
@@ -53,9 +72,40 @@ func (q *GetSubscriptionQuery) toResponse(sub *entity.Subscription) *dto.Subscri
 	}
 }
 
+// activeGracePeriod returns the user's active grace period, nil when there is
+// none; the repository reports none as an error
+func (q *CheckAccessQuery) activeGracePeriod(ctx context.Context, userID uuid.UUID) *entity.GracePeriod {
+	if q.gracePeriods == nil {
+		return nil
+	}
+	grace, err := q.gracePeriods.GetActiveByUserID(ctx, userID)
+	if err != nil || grace == nil || !grace.IsActive() {
+		return nil
+	}
+	return grace
+}
+
+// activeGracePeriodFinder loads a user's active grace period
+type activeGracePeriodFinder interface {
+	GetActiveByUserID(ctx context.Context, userID uuid.UUID) (*entity.GracePeriod, error)
+}
+
+// lifetimeEntitlementLister loads a user's active lifetime unlocks
+type lifetimeEntitlementLister interface {
+	ListActive(ctx context.Context, userID uuid.UUID) ([]*entity.LifetimeEntitlement, error)
+}
+
+// entitlementTokenIssuer signs the access state for offline checks
+type entitlementTokenIssuer interface {
+	Issue(userID, appID uuid.UUID, snapshot service.EntitlementSnapshot) (string, time.Time, error)
+}
+
 // CheckAccessQuery handles checking user access
 type CheckAccessQuery struct {
-	subscriptionRepo repository.SubscriptionRepository
+	subscriptionRepo  repository.SubscriptionRepository
+	lifetime          lifetimeEntitlementLister
+	gracePeriods      activeGracePeriodFinder
+	entitlementTokens entitlementTokenIssuer
 }
 
 // NewCheckAccessQuery creates a new check access query
@@ -65,6 +115,26 @@ func NewCheckAccessQuery(subscriptionRepo repository.SubscriptionRepository) *Ch
 	}
 }
 
+// WithLifetimeEntitlements grants access from non-consumable unlocks as well
+func (q *CheckAccessQuery) WithLifetimeEntitlements(lifetime lifetimeEntitlementLister) *CheckAccessQuery {
+	q.lifetime = lifetime
+	return q
+}
+
+// WithGracePeriods grants access while a billing-retry or support-granted
+// grace period is active
+func (q *CheckAccessQuery) WithGracePeriods(gracePeriods activeGracePeriodFinder) *CheckAccessQuery {
+	q.gracePeriods = gracePeriods
+	return q
+}
+
+// WithEntitlementTokens attaches a signed entitlement token to every
+// response so clients can check access while offline
+func (q *CheckAccessQuery) WithEntitlementTokens(issuer entitlementTokenIssuer) *CheckAccessQuery {
+	q.entitlementTokens = issuer
+	return q
+}
+
 // Execute executes the access check query
 func (q *CheckAccessQuery) Execute(ctx context.Context, userID string) (*dto.AccessCheckResponse, error) {
 	userUUID, err := uuid.Parse(userID)
@@ -72,23 +142,56 @@ func (q *CheckAccessQuery) Execute(ctx context.Context, userID string) (*dto.Acc
 		return nil, fmt.Errorf("%w: invalid user ID", domainErrors.ErrInvalidInput)
 	}
 
-	hasAccess, err := q.subscriptionRepo.CanAccess(ctx, userUUID)
+	ent, err := resolveEntitlement(ctx, q.subscriptionRepo, userUUID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check access: %w", err)
 	}
+	if q.lifetime != nil {
+		unlocks, err := q.lifetime.ListActive(ctx, userUUID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check access: %w", err)
+		}
+		ent.WithLifetime(unlocks)
+	}
 
 	resp := &dto.AccessCheckResponse{
-		HasAccess: hasAccess,
+		HasAccess: ent.HasAccess(),
 	}
+	snapshot := service.EntitlementSnapshot{HasAccess: ent.HasAccess(), Lifetime: ent.IsLifetime()}
 
-	if hasAccess {
-		sub, err := q.subscriptionRepo.GetActiveByUserID(ctx, userUUID)
-		if err == nil {
-			resp.ExpiresAt = sub.ExpiresAt.Format("2006-01-02T15:04:05Z07:00")
+	if ent.HasAccess() {
+		// Lifetime-only access has no expiry to report
+		if ent.Primary != nil {
+			expiresAt := ent.ExpiresAt()
+			resp.ExpiresAt = expiresAt.Format("2006-01-02T15:04:05Z07:00")
+			snapshot.ExpiresAt = &expiresAt
+		}
+		resp.Platforms = ent.Platforms()
+		resp.MultipleActive = ent.HasConflict()
+		resp.Lifetime = ent.IsLifetime()
+		resp.LifetimeProducts = ent.LifetimeProducts()
+		for _, sub := range ent.Active {
+			snapshot.Products = append(snapshot.Products, sub.ProductID)
 		}
+		snapshot.Products = append(snapshot.Products, ent.LifetimeProducts()...)
+	} else if grace := q.activeGracePeriod(ctx, userUUID); grace != nil {
+		resp.HasAccess = true
+		resp.GracePeriod = true
+		resp.ExpiresAt = grace.ExpiresAt.Format("2006-01-02T15:04:05Z07:00")
+		snapshot.HasAccess = true
+		snapshot.GraceExpiresAt = &grace.ExpiresAt
 	} else {
 		resp.Reason = "no_active_subscription"
 	}
 
+	if q.entitlementTokens != nil {
+		token, expiresAt, err := q.entitlementTokens.Issue(userUUID, appctx.MustAppIDFromCtx(ctx), snapshot)
+		if err != nil {
+			return nil, fmt.Errorf("failed to issue entitlement token: %w", err)
+		}
+		resp.EntitlementToken = token
+		resp.EntitlementTokenExpiresAt = expiresAt.Format("2006-01-02T15:04:05Z07:00")
+	}
+
 	return resp, nil
 }
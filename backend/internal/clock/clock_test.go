@@ -0,0 +1,43 @@
+package clock
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFake(t *testing.T) {
+	start := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	fake := NewFake(start)
+	assert.Equal(t, start, fake.Now())
+
+	assert.Equal(t, start.Add(time.Hour), fake.Advance(time.Hour))
+	assert.Equal(t, start.Add(time.Hour), fake.Now())
+
+	fake.Set(start.Add(-time.Hour))
+	assert.Equal(t, start.Add(-time.Hour), fake.Now())
+}
+
+func TestFake_ConcurrentAdvance(t *testing.T) {
+	start := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	fake := NewFake(start)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fake.Advance(time.Second)
+			_ = fake.Now()
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, start.Add(50*time.Second), fake.Now())
+}
+
+func TestReal(t *testing.T) {
+	before := time.Now()
+	assert.False(t, Real.Now().Before(before))
+}
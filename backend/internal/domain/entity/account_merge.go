@@ -0,0 +1,127 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AccountMergeUndoWindow is how long a merge can be undone
+const AccountMergeUndoWindow = 7 * 24 * time.Hour
+
+// MergeConflictPolicy decides which account wins where both hold the same thing
+type MergeConflictPolicy string
+
+const (
+	MergeKeepTarget MergeConflictPolicy = "keep_target"
+	MergeKeepSource MergeConflictPolicy = "keep_source"
+)
+
+// AccountMergeStatus is the lifecycle of a merge
+type AccountMergeStatus string
+
+const (
+	AccountMergeStatusMerged AccountMergeStatus = "merged"
+	AccountMergeStatusUndone AccountMergeStatus = "undone"
+)
+
+// Merge conflict kinds
+const (
+	MergeConflictActiveSubscription   = "active_subscription"
+	MergeConflictExperimentAssignment = "experiment_assignment"
+)
+
+var (
+	// ErrInvalidAccountMerge is returned for a merge that cannot be applied
+	ErrInvalidAccountMerge = errors.New("invalid account merge")
+	// ErrAccountMergeNotUndoable is returned for a merge that was undone or
+	// whose undo window has closed
+	ErrAccountMergeNotUndoable = errors.New("account merge cannot be undone")
+)
+
+// AccountMergeConflict is something both accounts held and the outcome
+type AccountMergeConflict struct {
+	Kind string `json:"kind"`
+	// SourceID and TargetID are the conflicting rows of each account
+	SourceID uuid.UUID `json:"source_id"`
+	TargetID uuid.UUID `json:"target_id"`
+	// Resolution is the policy applied: kept_target or kept_source
+	Resolution string `json:"resolution"`
+}
+
+// AccountMerge folds a duplicate user (the source) into the account the
+// person keeps (the target). The source's rows are moved to the target and
+// the source is soft-deleted; each moved row is journaled so the merge can be
+// undone within AccountMergeUndoWindow.
+type AccountMerge struct {
+	ID           uuid.UUID
+	AppID        uuid.UUID
+	SourceUserID uuid.UUID
+	TargetUserID uuid.UUID
+	Policy       MergeConflictPolicy
+	Status       AccountMergeStatus
+	Reason       string
+	MergedBy     *uuid.UUID
+	// SourceLTV is the lifetime value added to the target
+	SourceLTV float64
+	// Moved counts the rows moved per table
+	Moved     map[string]int64
+	Conflicts []AccountMergeConflict
+	CreatedAt time.Time
+	UndoUntil time.Time
+	UndoneAt  *time.Time
+}
+
+// NewAccountMerge creates a merge of source into target
+func NewAccountMerge(appID, sourceUserID, targetUserID uuid.UUID, policy MergeConflictPolicy, reason string, mergedBy *uuid.UUID, now time.Time) (*AccountMerge, error) {
+	if policy == "" {
+		policy = MergeKeepTarget
+	}
+	m := &AccountMerge{
+		ID:           uuid.New(),
+		AppID:        appID,
+		SourceUserID: sourceUserID,
+		TargetUserID: targetUserID,
+		Policy:       policy,
+		Status:       AccountMergeStatusMerged,
+		Reason:       reason,
+		MergedBy:     mergedBy,
+		Moved:        map[string]int64{},
+		CreatedAt:    now,
+		UndoUntil:    now.Add(AccountMergeUndoWindow),
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Validate checks the merge can be applied
+func (m *AccountMerge) Validate() error {
+	switch {
+	case m.SourceUserID == uuid.Nil || m.TargetUserID == uuid.Nil:
+		return fmt.Errorf("%w: source and target users are required", ErrInvalidAccountMerge)
+	case m.SourceUserID == m.TargetUserID:
+		return fmt.Errorf("%w: cannot merge a user into itself", ErrInvalidAccountMerge)
+	case m.Policy != MergeKeepTarget && m.Policy != MergeKeepSource:
+		return fmt.Errorf("%w: unknown conflict policy %q", ErrInvalidAccountMerge, m.Policy)
+	case m.Reason == "":
+		return fmt.Errorf("%w: a reason is required", ErrInvalidAccountMerge)
+	}
+	return nil
+}
+
+// Undo marks the merge undone; the repository reverts the moved rows
+func (m *AccountMerge) Undo(now time.Time) error {
+	if m.Status != AccountMergeStatusMerged {
+		return fmt.Errorf("%w: merge is %s", ErrAccountMergeNotUndoable, m.Status)
+	}
+	if now.After(m.UndoUntil) {
+		return fmt.Errorf("%w: undo window closed at %s", ErrAccountMergeNotUndoable, m.UndoUntil.Format(time.RFC3339))
+	}
+	m.Status = AccountMergeStatusUndone
+	m.UndoneAt = &now
+	return nil
+}
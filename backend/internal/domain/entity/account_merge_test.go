@@ -0,0 +1,43 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAccountMerge_Validates(t *testing.T) {
+	now := time.Now()
+	user := uuid.New()
+
+	_, err := NewAccountMerge(uuid.New(), user, user, MergeKeepTarget, "duplicate", nil, now)
+	assert.ErrorIs(t, err, ErrInvalidAccountMerge, "self merge")
+
+	_, err = NewAccountMerge(uuid.New(), user, uuid.New(), "newest", "duplicate", nil, now)
+	assert.ErrorIs(t, err, ErrInvalidAccountMerge, "unknown policy")
+
+	_, err = NewAccountMerge(uuid.New(), user, uuid.New(), MergeKeepTarget, "", nil, now)
+	assert.ErrorIs(t, err, ErrInvalidAccountMerge, "reason required")
+
+	m, err := NewAccountMerge(uuid.New(), user, uuid.New(), "", "duplicate", nil, now)
+	require.NoError(t, err)
+	assert.Equal(t, MergeKeepTarget, m.Policy)
+	assert.Equal(t, now.Add(AccountMergeUndoWindow), m.UndoUntil)
+}
+
+func TestAccountMerge_UndoWindow(t *testing.T) {
+	now := time.Now()
+	m, err := NewAccountMerge(uuid.New(), uuid.New(), uuid.New(), MergeKeepSource, "duplicate", nil, now)
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, m.Undo(now.Add(AccountMergeUndoWindow+time.Minute)), ErrAccountMergeNotUndoable)
+	assert.Equal(t, AccountMergeStatusMerged, m.Status)
+
+	require.NoError(t, m.Undo(now.Add(time.Hour)))
+	assert.Equal(t, AccountMergeStatusUndone, m.Status)
+	require.NotNil(t, m.UndoneAt)
+	assert.ErrorIs(t, m.Undo(now.Add(2*time.Hour)), ErrAccountMergeNotUndoable, "already undone")
+}
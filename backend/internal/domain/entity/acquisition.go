@@ -0,0 +1,82 @@
+package entity
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MMPProvider is a mobile measurement partner that reports attribution
+type MMPProvider string
+
+const (
+	MMPAdjust    MMPProvider = "adjust"
+	MMPAppsFlyer MMPProvider = "appsflyer"
+)
+
+// AcquisitionEvent is the kind of attribution an MMP reported
+type AcquisitionEvent string
+
+const (
+	AcquisitionInstall       AcquisitionEvent = "install"
+	AcquisitionReattribution AcquisitionEvent = "reattribution"
+)
+
+// AcquisitionChannel groups media sources for reporting and bandit features
+type AcquisitionChannel string
+
+const (
+	AcquisitionOrganic    AcquisitionChannel = "organic"
+	AcquisitionPaidSocial AcquisitionChannel = "paid_social"
+	AcquisitionPaidSearch AcquisitionChannel = "paid_search"
+	AcquisitionPaidOther  AcquisitionChannel = "paid_other"
+)
+
+// AcquisitionChannels are every channel, in bandit feature order
+var AcquisitionChannels = []AcquisitionChannel{AcquisitionOrganic, AcquisitionPaidSocial, AcquisitionPaidSearch, AcquisitionPaidOther}
+
+var (
+	socialMediaSources = []string{"facebook", "instagram", "meta", "tiktok", "snapchat", "twitter", "pinterest", "reddit", "linkedin"}
+	searchMediaSources = []string{"google", "adwords", "apple search ads", "apple_search_ads", "searchads", "bing"}
+)
+
+// ClassifyAcquisitionChannel maps an MMP media source (AppsFlyer
+// media_source, Adjust network_name) to a channel
+func ClassifyAcquisitionChannel(mediaSource string) AcquisitionChannel {
+	source := strings.ToLower(strings.TrimSpace(mediaSource))
+	switch source {
+	case "", "organic", "organic_install":
+		return AcquisitionOrganic
+	}
+	for _, s := range socialMediaSources {
+		if strings.Contains(source, s) {
+			return AcquisitionPaidSocial
+		}
+	}
+	for _, s := range searchMediaSources {
+		if strings.Contains(source, s) {
+			return AcquisitionPaidSearch
+		}
+	}
+	return AcquisitionPaidOther
+}
+
+// UserAcquisition is the campaign an MMP attributed a user to
+type UserAcquisition struct {
+	AppID          uuid.UUID
+	CustomerUserID string
+	// UserID is nil until a user with CustomerUserID signs up
+	UserID       *uuid.UUID
+	Provider     MMPProvider
+	Event        AcquisitionEvent
+	Channel      AcquisitionChannel
+	MediaSource  string
+	Campaign     string
+	CampaignID   string
+	Adset        string
+	Ad           string
+	InstalledAt  *time.Time
+	AttributedAt time.Time
+	UpdatedAt    time.Time
+}
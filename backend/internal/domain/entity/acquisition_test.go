@@ -0,0 +1,22 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyAcquisitionChannel(t *testing.T) {
+	cases := map[string]AcquisitionChannel{
+		"":                  AcquisitionOrganic,
+		"Organic":           AcquisitionOrganic,
+		"Facebook Ads":      AcquisitionPaidSocial,
+		"tiktokglobal_int":  AcquisitionPaidSocial,
+		"googleadwords_int": AcquisitionPaidSearch,
+		"Apple Search Ads":  AcquisitionPaidSearch,
+		"unityads_int":      AcquisitionPaidOther,
+	}
+	for source, want := range cases {
+		assert.Equal(t, want, ClassifyAcquisitionChannel(source), source)
+	}
+}
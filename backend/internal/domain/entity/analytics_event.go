@@ -0,0 +1,55 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Analytics event sources
+const (
+	AnalyticsSourceClient  = "client"
+	AnalyticsSourceWebhook = "webhook"
+)
+
+// Analytics event limits
+const (
+	MaxAnalyticsEventNameLength = 128
+	// MaxAnalyticsEventSkew bounds how far from receipt a client may date an event
+	MaxAnalyticsEventSkew = 7 * 24 * time.Hour
+)
+
+var ErrInvalidAnalyticsEvent = errors.New("invalid analytics event")
+
+// AnalyticsEvent is one client or webhook event bound for the partitioned
+// analytics_events table. AppID is nil for webhooks received before their app
+// is resolved.
+type AnalyticsEvent struct {
+	ID         uuid.UUID
+	AppID      *uuid.UUID
+	UserID     *uuid.UUID
+	Source     string
+	Name       string
+	Properties map[string]any
+	OccurredAt time.Time
+	ReceivedAt time.Time
+}
+
+// Validate checks the event against the ingestion limits at the given receipt time
+func (e AnalyticsEvent) Validate(now time.Time) error {
+	if e.Source != AnalyticsSourceClient && e.Source != AnalyticsSourceWebhook {
+		return fmt.Errorf("%w: unknown source %q", ErrInvalidAnalyticsEvent, e.Source)
+	}
+	if e.Name == "" || len(e.Name) > MaxAnalyticsEventNameLength {
+		return fmt.Errorf("%w: name must be 1 to %d characters", ErrInvalidAnalyticsEvent, MaxAnalyticsEventNameLength)
+	}
+	if e.OccurredAt.IsZero() {
+		return fmt.Errorf("%w: occurred_at is required", ErrInvalidAnalyticsEvent)
+	}
+	if e.OccurredAt.Before(now.Add(-MaxAnalyticsEventSkew)) || e.OccurredAt.After(now.Add(MaxAnalyticsEventSkew)) {
+		return fmt.Errorf("%w: occurred_at must be within %s of now", ErrInvalidAnalyticsEvent, MaxAnalyticsEventSkew)
+	}
+	return nil
+}
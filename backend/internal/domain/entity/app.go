@@ -29,6 +29,12 @@ type AppSettings struct {
 	StoreEnvironment         string            `json:"store_environment"` // "production" | "sandbox"
 	Entitlements             map[string][]string `json:"entitlements"`     // product_id → []feature_key
 	SubscriptionRequiredFor  []string          `json:"subscription_required_for"`
+	Consumables              map[string]ConsumableProduct `json:"consumables"` // product_id → credits granted
+	LifetimeProducts         map[string]LifetimeProduct   `json:"lifetime_products"` // non-consumable unlocks
+	Metering                 map[string]MeteringRule      `json:"metering"` // feature key → free uses before the hard paywall
+	ReportingTimezone        string                       `json:"reporting_timezone"` // IANA zone of the business day; empty is UTC
+	AppStoreID               string                       `json:"app_store_id"` // numeric App Store ID; routes SKAdNetwork postbacks
+	MMPCallbackToken         string                       `json:"mmp_callback_token"` // shared with Adjust/AppsFlyer in their callback URLs
 }
 
 // AppCredentials holds store keys for one provider. Sensitive fields are encrypted at rest.
@@ -0,0 +1,50 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ConsentPurpose is a use of a user's data that they can opt out of
+type ConsentPurpose string
+
+const (
+	// ConsentAnalytics covers identified event tracking and Matomo forwarding
+	ConsentAnalytics ConsentPurpose = "analytics"
+	// ConsentPersonalization covers contextual (per-user feature) targeting
+	ConsentPersonalization ConsentPurpose = "personalization"
+	// ConsentMarketing covers promotional pushes and emails
+	ConsentMarketing ConsentPurpose = "marketing"
+)
+
+// UserConsent is what a user allows their data to be used for
+type UserConsent struct {
+	UserID          uuid.UUID
+	AppID           uuid.UUID
+	Analytics       bool
+	Personalization bool
+	Marketing       bool
+	// UpdatedAt is zero until the user first sets their consent
+	UpdatedAt time.Time
+}
+
+// DefaultUserConsent is the consent of a user who never set one. Consent is
+// enforced as an opt-out, so every purpose is allowed.
+func DefaultUserConsent(appID, userID uuid.UUID) *UserConsent {
+	return &UserConsent{UserID: userID, AppID: appID, Analytics: true, Personalization: true, Marketing: true}
+}
+
+// Allows reports whether the user consented to purpose
+func (c *UserConsent) Allows(purpose ConsentPurpose) bool {
+	switch purpose {
+	case ConsentAnalytics:
+		return c.Analytics
+	case ConsentPersonalization:
+		return c.Personalization
+	case ConsentMarketing:
+		return c.Marketing
+	default:
+		return false
+	}
+}
@@ -0,0 +1,97 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CreditEntryKind is the cause of a credit balance change
+type CreditEntryKind string
+
+const (
+	CreditEntryPurchase CreditEntryKind = "purchase"
+	CreditEntryConsume  CreditEntryKind = "consume"
+)
+
+// ConsumableProduct configures a consumable store product (coins, credits)
+type ConsumableProduct struct {
+	// Credits added to the balance per purchase
+	Credits int64 `json:"credits"`
+	// Price in USD, counted towards LTV and bandit revenue rewards
+	Price float64 `json:"price"`
+}
+
+// ErrInsufficientCredits is returned when a debit exceeds the balance
+var ErrInsufficientCredits = errors.New("insufficient credits")
+
+// ErrIdempotencyKeyReused is returned when an idempotency key is replayed
+// with a different amount or kind than its first use
+var ErrIdempotencyKeyReused = errors.New("idempotency key already used for a different request")
+
+// CreditEntry is one change of a user's credit balance. Delta is positive for
+// purchases and negative for consumption.
+type CreditEntry struct {
+	ID             uuid.UUID
+	AppID          uuid.UUID
+	UserID         uuid.UUID
+	Kind           CreditEntryKind
+	Delta          int64
+	BalanceAfter   int64
+	IdempotencyKey string
+	ProductID      string
+	Platform       string
+	ProviderTxID   string
+	// Amount is the USD revenue of a purchase entry
+	Amount      float64
+	Currency    string
+	Description string
+	CreatedAt   time.Time
+}
+
+// NewCreditPurchase credits a verified consumable purchase. The store
+// transaction is the idempotency key, so a receipt is credited at most once.
+func NewCreditPurchase(appID, userID uuid.UUID, platform, productID, providerTxID string, product ConsumableProduct) *CreditEntry {
+	return &CreditEntry{
+		ID:             uuid.New(),
+		AppID:          appID,
+		UserID:         userID,
+		Kind:           CreditEntryPurchase,
+		Delta:          product.Credits,
+		IdempotencyKey: "receipt:" + platform + ":" + providerTxID,
+		ProductID:      productID,
+		Platform:       platform,
+		ProviderTxID:   providerTxID,
+		Amount:         product.Price,
+		Currency:       "USD",
+		CreatedAt:      time.Now(),
+	}
+}
+
+// NewCreditConsumption debits amount credits under a client-supplied key
+func NewCreditConsumption(appID, userID uuid.UUID, amount int64, idempotencyKey, description string) (*CreditEntry, error) {
+	if amount <= 0 {
+		return nil, errors.New("amount must be positive")
+	}
+	if idempotencyKey == "" {
+		return nil, errors.New("idempotency key is required")
+	}
+	return &CreditEntry{
+		ID:             uuid.New(),
+		AppID:          appID,
+		UserID:         userID,
+		Kind:           CreditEntryConsume,
+		Delta:          -amount,
+		IdempotencyKey: "consume:" + idempotencyKey,
+		Currency:       "USD",
+		Description:    description,
+		CreatedAt:      time.Now(),
+	}, nil
+}
+
+// SameRequest reports whether other, found under the same idempotency key,
+// records the same change for the same user as e
+func (e *CreditEntry) SameRequest(other *CreditEntry) bool {
+	return e.UserID == other.UserID && e.Kind == other.Kind && e.Delta == other.Delta
+}
@@ -0,0 +1,43 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCreditPurchase_KeyedByStoreTransaction(t *testing.T) {
+	product := ConsumableProduct{Credits: 500, Price: 4.99}
+
+	entry := NewCreditPurchase(uuid.New(), uuid.New(), "ios", "com.app.coins.500", "2000000123", product)
+
+	assert.Equal(t, CreditEntryPurchase, entry.Kind)
+	assert.Equal(t, int64(500), entry.Delta)
+	assert.Equal(t, "receipt:ios:2000000123", entry.IdempotencyKey)
+	assert.Equal(t, 4.99, entry.Amount)
+}
+
+func TestNewCreditConsumption(t *testing.T) {
+	entry, err := NewCreditConsumption(uuid.New(), uuid.New(), 30, "order-1", "hint")
+	require.NoError(t, err)
+	assert.Equal(t, int64(-30), entry.Delta)
+	assert.Equal(t, "consume:order-1", entry.IdempotencyKey)
+
+	_, err = NewCreditConsumption(uuid.New(), uuid.New(), 0, "order-2", "")
+	assert.Error(t, err)
+	_, err = NewCreditConsumption(uuid.New(), uuid.New(), 10, "", "")
+	assert.Error(t, err)
+}
+
+func TestCreditEntry_SameRequest(t *testing.T) {
+	first, _ := NewCreditConsumption(uuid.New(), uuid.New(), 30, "order-1", "")
+	replay, _ := NewCreditConsumption(first.AppID, first.UserID, 30, "order-1", "")
+	different, _ := NewCreditConsumption(first.AppID, first.UserID, 40, "order-1", "")
+	otherUser, _ := NewCreditConsumption(first.AppID, uuid.New(), 30, "order-1", "")
+
+	assert.True(t, first.SameRequest(replay))
+	assert.False(t, first.SameRequest(different))
+	assert.False(t, first.SameRequest(otherUser))
+}
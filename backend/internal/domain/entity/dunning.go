@@ -30,6 +30,13 @@ type Dunning struct {
 	FailedAt       *time.Time
 	CreatedAt      time.Time
 	UpdatedAt      time.Time
+
+	// CampaignID and Variant are set when a campaign schedules the reminders
+	CampaignID    *uuid.UUID
+	Variant       string
+	Reason        DunningReason
+	Platform      string
+	PaymentFixURL string
 }
 
 // NewDunning creates a new dunning process
@@ -87,6 +94,14 @@ func (d *Dunning) MarkFailed() {
 	d.UpdatedAt = now
 }
 
+// ApplyCampaign schedules the dunning by campaign c
+func (d *Dunning) ApplyCampaign(c *DunningCampaign) {
+	d.CampaignID = &c.ID
+	d.Variant = c.Variant
+	d.MaxAttempts = len(c.ReminderOffsets)
+	d.NextAttemptAt = c.NextReminderAt(d.CreatedAt, 0)
+}
+
 // GetRetryDelay returns the delay before next retry based on attempt count
 func (d *Dunning) GetRetryDelay() time.Duration {
 	// Exponential backoff: 1 day, 3 days, 7 days, 14 days, 30 days
@@ -0,0 +1,135 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// maxDunningReminders caps the follow-up reminders of a campaign
+const maxDunningReminders = 10
+
+// DunningReason is the store state that started a dunning process
+type DunningReason string
+
+const (
+	DunningReasonGrace  DunningReason = "grace"
+	DunningReasonOnHold DunningReason = "on_hold"
+)
+
+// ErrInvalidDunningCampaign is returned for a campaign that cannot be scheduled
+var ErrInvalidDunningCampaign = errors.New("invalid dunning campaign")
+
+// DunningCampaign is one variant of the dunning sequence. The first reminder
+// is sent when the payment fails; ReminderOffsets schedule the follow-ups
+// after the failure, and the last offset ends the campaign.
+type DunningCampaign struct {
+	ID              uuid.UUID
+	Name            string
+	Variant         string
+	ReminderOffsets []time.Duration
+	// Weight is the share of users assigned when no bandit arm decides
+	Weight int
+	// BanditExperimentID and BanditArmID link the campaign to a bandit arm;
+	// both nil or both set
+	BanditExperimentID *uuid.UUID
+	BanditArmID        *uuid.UUID
+	IsActive           bool
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+}
+
+// NewDunningCampaign creates an active campaign
+func NewDunningCampaign(name, variant string, offsets []time.Duration, weight int) (*DunningCampaign, error) {
+	now := time.Now()
+	c := &DunningCampaign{
+		ID:              uuid.New(),
+		Name:            name,
+		Variant:         variant,
+		ReminderOffsets: offsets,
+		Weight:          weight,
+		IsActive:        true,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Validate checks the campaign can be scheduled: a name and variant, 1 to 10
+// strictly increasing whole-hour offsets, a positive weight and a complete
+// bandit link
+func (c *DunningCampaign) Validate() error {
+	if c.Name == "" || c.Variant == "" {
+		return fmt.Errorf("%w: name and variant are required", ErrInvalidDunningCampaign)
+	}
+	if len(c.ReminderOffsets) == 0 || len(c.ReminderOffsets) > maxDunningReminders {
+		return fmt.Errorf("%w: between 1 and %d reminder offsets are required", ErrInvalidDunningCampaign, maxDunningReminders)
+	}
+	var prev time.Duration
+	for _, offset := range c.ReminderOffsets {
+		if offset <= prev || offset%time.Hour != 0 {
+			return fmt.Errorf("%w: reminder offsets must be increasing whole hours", ErrInvalidDunningCampaign)
+		}
+		prev = offset
+	}
+	if c.Weight <= 0 {
+		return fmt.Errorf("%w: weight must be positive", ErrInvalidDunningCampaign)
+	}
+	if (c.BanditExperimentID == nil) != (c.BanditArmID == nil) {
+		return fmt.Errorf("%w: bandit experiment and arm must be set together", ErrInvalidDunningCampaign)
+	}
+	return nil
+}
+
+// NextReminderAt returns when the follow-up after attempt reminders is due
+func (c *DunningCampaign) NextReminderAt(startedAt time.Time, attempt int) time.Time {
+	if attempt >= len(c.ReminderOffsets) {
+		attempt = len(c.ReminderOffsets) - 1
+	}
+	return startedAt.Add(c.ReminderOffsets[attempt])
+}
+
+// DunningCampaignStats is the recovery outcome of one campaign variant.
+// CampaignID is nil for dunning run on the default schedule.
+type DunningCampaignStats struct {
+	CampaignID         *uuid.UUID
+	Name               string
+	Variant            string
+	Started            int64
+	Recovered          int64
+	Failed             int64
+	InProgress         int64
+	AvgHoursToRecovery float64
+}
+
+// RecoveryRate is the share of started dunning processes that recovered
+func (s *DunningCampaignStats) RecoveryRate() float64 {
+	if s.Started == 0 {
+		return 0
+	}
+	return float64(s.Recovered) / float64(s.Started)
+}
+
+// PaymentFixURL returns the store page where the user updates the payment
+// method of a subscription. packageName is only used on Android; Stripe
+// subscriptions have no store page and return "".
+func PaymentFixURL(platform, productID, packageName string) string {
+	switch platform {
+	case "ios":
+		return "https://apps.apple.com/account/billing"
+	case "android":
+		if productID == "" || packageName == "" {
+			return "https://play.google.com/store/account/subscriptions"
+		}
+		q := url.Values{"sku": {productID}, "package": {packageName}}
+		return "https://play.google.com/store/account/subscriptions?" + q.Encode()
+	default:
+		return ""
+	}
+}
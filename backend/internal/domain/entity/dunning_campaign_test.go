@@ -0,0 +1,45 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewDunningCampaign_Validates(t *testing.T) {
+	_, err := NewDunningCampaign("default", "control", []time.Duration{72 * time.Hour, 24 * time.Hour}, 1)
+	assert.ErrorIs(t, err, ErrInvalidDunningCampaign, "offsets must increase")
+
+	_, err = NewDunningCampaign("default", "control", []time.Duration{90 * time.Minute}, 1)
+	assert.ErrorIs(t, err, ErrInvalidDunningCampaign, "offsets are whole hours")
+
+	c, err := NewDunningCampaign("default", "control", []time.Duration{24 * time.Hour}, 1)
+	require.NoError(t, err)
+	arm := uuid.New()
+	c.BanditArmID = &arm
+	assert.ErrorIs(t, c.Validate(), ErrInvalidDunningCampaign, "arm needs its experiment")
+}
+
+func TestDunning_ApplyCampaignSchedulesReminders(t *testing.T) {
+	c, err := NewDunningCampaign("fast", "b", []time.Duration{12 * time.Hour, 48 * time.Hour, 96 * time.Hour}, 1)
+	require.NoError(t, err)
+	d := NewDunning(uuid.New(), uuid.New(), time.Now())
+
+	d.ApplyCampaign(c)
+
+	assert.Equal(t, 3, d.MaxAttempts)
+	assert.Equal(t, "b", d.Variant)
+	assert.Equal(t, d.CreatedAt.Add(12*time.Hour), d.NextAttemptAt)
+	assert.Equal(t, d.CreatedAt.Add(96*time.Hour), c.NextReminderAt(d.CreatedAt, 5), "past the last offset stays on it")
+}
+
+func TestPaymentFixURL(t *testing.T) {
+	assert.Equal(t, "https://apps.apple.com/account/billing", PaymentFixURL("ios", "com.app.pro", ""))
+	assert.Equal(t, "https://play.google.com/store/account/subscriptions?package=com.app&sku=com.app.pro",
+		PaymentFixURL("android", "com.app.pro", "com.app"))
+	assert.Equal(t, "https://play.google.com/store/account/subscriptions", PaymentFixURL("android", "com.app.pro", ""))
+	assert.Empty(t, PaymentFixURL("web", "price_1", ""))
+}
@@ -0,0 +1,87 @@
+package entity
+
+import (
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EmbedMetric is a metric the embedded analytics API can chart
+type EmbedMetric string
+
+const (
+	EmbedRevenue        EmbedMetric = "revenue"
+	EmbedRefunds        EmbedMetric = "refunds"
+	EmbedTransactions   EmbedMetric = "transactions"
+	EmbedSignups        EmbedMetric = "signups"
+	EmbedConversions    EmbedMetric = "conversions"
+	EmbedConversionRate EmbedMetric = "conversion_rate"
+)
+
+// EmbedDimension is what an embedded metric is broken down by
+type EmbedDimension string
+
+const (
+	EmbedByDay      EmbedDimension = "day"
+	EmbedByWeek     EmbedDimension = "week"
+	EmbedByMonth    EmbedDimension = "month"
+	EmbedByCurrency EmbedDimension = "currency"
+	EmbedByPlatform EmbedDimension = "platform"
+)
+
+// embedMetricDimensions are the dimensions each metric supports
+var embedMetricDimensions = map[EmbedMetric][]EmbedDimension{
+	EmbedRevenue:        {EmbedByDay, EmbedByWeek, EmbedByMonth, EmbedByCurrency},
+	EmbedRefunds:        {EmbedByDay, EmbedByWeek, EmbedByMonth, EmbedByCurrency},
+	EmbedTransactions:   {EmbedByDay, EmbedByWeek, EmbedByMonth, EmbedByCurrency},
+	EmbedSignups:        {EmbedByDay, EmbedByWeek, EmbedByMonth, EmbedByPlatform},
+	EmbedConversions:    {EmbedByDay, EmbedByWeek, EmbedByMonth, EmbedByPlatform},
+	EmbedConversionRate: {EmbedByDay, EmbedByWeek, EmbedByMonth, EmbedByPlatform},
+}
+
+// IsValid reports whether m is a known metric
+func (m EmbedMetric) IsValid() bool {
+	_, ok := embedMetricDimensions[m]
+	return ok
+}
+
+// IsValid reports whether d is a known dimension
+func (d EmbedDimension) IsValid() bool {
+	switch d {
+	case EmbedByDay, EmbedByWeek, EmbedByMonth, EmbedByCurrency, EmbedByPlatform:
+		return true
+	}
+	return false
+}
+
+// Supports reports whether m can be broken down by d
+func (m EmbedMetric) Supports(d EmbedDimension) bool {
+	return slices.Contains(embedMetricDimensions[m], d)
+}
+
+// AnalyticsEmbedToken scopes what a signed embed token may query. The signed
+// token only carries the ID, so revoking the row revokes the token.
+type AnalyticsEmbedToken struct {
+	ID                 uuid.UUID        `json:"id"`
+	AppID              uuid.UUID        `json:"app_id"`
+	Label              string           `json:"label"`
+	Metrics            []EmbedMetric    `json:"metrics"`
+	Dimensions         []EmbedDimension `json:"dimensions"`
+	MaxRangeDays       int              `json:"max_range_days"`
+	RateLimitPerMinute int              `json:"rate_limit_per_minute"`
+	CreatedBy          *uuid.UUID       `json:"created_by,omitempty"`
+	CreatedAt          time.Time        `json:"created_at"`
+	ExpiresAt          time.Time        `json:"expires_at"`
+	RevokedAt          *time.Time       `json:"revoked_at,omitempty"`
+}
+
+// Allows reports whether the token may query metric by dimension
+func (t *AnalyticsEmbedToken) Allows(metric EmbedMetric, dimension EmbedDimension) bool {
+	return slices.Contains(t.Metrics, metric) && slices.Contains(t.Dimensions, dimension) && metric.Supports(dimension)
+}
+
+// IsActive reports whether the token is neither revoked nor expired at now
+func (t *AnalyticsEmbedToken) IsActive(now time.Time) bool {
+	return t.RevokedAt == nil && now.Before(t.ExpiresAt)
+}
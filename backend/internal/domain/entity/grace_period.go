@@ -30,7 +30,11 @@ type GracePeriod struct {
 
 // NewGracePeriod creates a new active grace period
 func NewGracePeriod(userID, subscriptionID uuid.UUID, expiresAt time.Time) *GracePeriod {
-	now := time.Now()
+	return NewGracePeriodAt(userID, subscriptionID, expiresAt, time.Now())
+}
+
+// NewGracePeriodAt creates a new active grace period created at now
+func NewGracePeriodAt(userID, subscriptionID uuid.UUID, expiresAt, now time.Time) *GracePeriod {
 	return &GracePeriod{
 		ID:             uuid.New(),
 		UserID:         userID,
@@ -44,12 +48,22 @@ func NewGracePeriod(userID, subscriptionID uuid.UUID, expiresAt time.Time) *Grac
 
 // IsActive returns true if the grace period is currently active
 func (gp *GracePeriod) IsActive() bool {
-	return gp.Status == GraceStatusActive && gp.ExpiresAt.After(time.Now())
+	return gp.IsActiveAt(time.Now())
+}
+
+// IsActiveAt returns true if the grace period is active at now
+func (gp *GracePeriod) IsActiveAt(now time.Time) bool {
+	return gp.Status == GraceStatusActive && gp.ExpiresAt.After(now)
 }
 
 // IsExpired returns true if the grace period has expired
 func (gp *GracePeriod) IsExpired() bool {
-	return gp.Status == GraceStatusExpired || gp.ExpiresAt.Before(time.Now())
+	return gp.IsExpiredAt(time.Now())
+}
+
+// IsExpiredAt returns true if the grace period has expired by now
+func (gp *GracePeriod) IsExpiredAt(now time.Time) bool {
+	return gp.Status == GraceStatusExpired || gp.ExpiresAt.Before(now)
 }
 
 // IsResolved returns true if the grace period has been resolved
@@ -64,11 +78,15 @@ func (gp *GracePeriod) CanAccessContent() bool {
 
 // Resolve marks the grace period as resolved (user renewed successfully)
 func (gp *GracePeriod) Resolve() error {
+	return gp.ResolveAt(time.Now())
+}
+
+// ResolveAt marks the grace period as resolved at now
+func (gp *GracePeriod) ResolveAt(now time.Time) error {
 	if gp.Status == GraceStatusExpired {
 		return errors.New("cannot resolve expired grace period")
 	}
 
-	now := time.Now()
 	gp.Status = GraceStatusResolved
 	gp.ResolvedAt = &now
 	gp.UpdatedAt = now
@@ -77,22 +95,48 @@ func (gp *GracePeriod) Resolve() error {
 
 // Expire marks the grace period as expired
 func (gp *GracePeriod) Expire() error {
+	return gp.ExpireAt(time.Now())
+}
+
+// ExpireAt marks the grace period as expired at now
+func (gp *GracePeriod) ExpireAt(now time.Time) error {
 	if gp.Status == GraceStatusResolved {
 		return errors.New("cannot expire already resolved grace period")
 	}
 
 	gp.Status = GraceStatusExpired
-	gp.UpdatedAt = time.Now()
+	gp.UpdatedAt = now
+	return nil
+}
+
+// Extend moves the expiry by days, which may be negative to correct an
+// earlier extension. Extending a grace period that already ran out counts
+// from now.
+func (gp *GracePeriod) Extend(days int, now time.Time) error {
+	if gp.Status != GraceStatusActive {
+		return errors.New("cannot extend a grace period that is not active")
+	}
+	base := gp.ExpiresAt
+	if days > 0 && base.Before(now) {
+		base = now
+	}
+	gp.ExpiresAt = base.AddDate(0, 0, days)
+	gp.UpdatedAt = now
 	return nil
 }
 
 // DaysRemaining returns the number of days remaining in the grace period
 func (gp *GracePeriod) DaysRemaining() int {
-	if gp.IsExpired() {
+	return gp.DaysRemainingAt(time.Now())
+}
+
+// DaysRemainingAt returns the number of days remaining in the grace period as of now
+func (gp *GracePeriod) DaysRemainingAt(now time.Time) int {
+	if gp.IsExpiredAt(now) {
 		return 0
 	}
 
-	duration := time.Until(gp.ExpiresAt)
+	duration := gp.ExpiresAt.Sub(now)
 	if duration <= 0 {
 		return 0
 	}
@@ -103,11 +147,16 @@ func (gp *GracePeriod) DaysRemaining() int {
 
 // HoursRemaining returns the number of hours remaining in the grace period
 func (gp *GracePeriod) HoursRemaining() int {
-	if gp.IsExpired() {
+	return gp.HoursRemainingAt(time.Now())
+}
+
+// HoursRemainingAt returns the number of hours remaining in the grace period as of now
+func (gp *GracePeriod) HoursRemainingAt(now time.Time) int {
+	if gp.IsExpiredAt(now) {
 		return 0
 	}
 
-	duration := time.Until(gp.ExpiresAt)
+	duration := gp.ExpiresAt.Sub(now)
 	if duration < 0 {
 		return 0
 	}
@@ -84,3 +84,55 @@ func TestGracePeriodEntity(t *testing.T) {
 		assert.Contains(t, err.Error(), "cannot resolve expired grace period")
 	})
 }
+
+func TestGracePeriodExtend(t *testing.T) {
+	now := time.Now()
+
+	t.Run("Extend moves the expiry", func(t *testing.T) {
+		expiresAt := now.Add(48 * time.Hour)
+		gracePeriod := entity.NewGracePeriod(uuid.New(), uuid.New(), expiresAt)
+
+		assert.NoError(t, gracePeriod.Extend(3, now))
+		assert.Equal(t, expiresAt.AddDate(0, 0, 3), gracePeriod.ExpiresAt)
+
+		assert.NoError(t, gracePeriod.Extend(-1, now))
+		assert.Equal(t, expiresAt.AddDate(0, 0, 2), gracePeriod.ExpiresAt)
+	})
+
+	t.Run("Extend counts from now once the grace period ran out", func(t *testing.T) {
+		gracePeriod := entity.NewGracePeriod(uuid.New(), uuid.New(), now.Add(-time.Hour))
+
+		assert.NoError(t, gracePeriod.Extend(2, now))
+		assert.Equal(t, now.AddDate(0, 0, 2), gracePeriod.ExpiresAt)
+	})
+
+	t.Run("Extend rejects resolved grace periods", func(t *testing.T) {
+		gracePeriod := entity.NewGracePeriod(uuid.New(), uuid.New(), now.Add(time.Hour))
+		assert.NoError(t, gracePeriod.Resolve())
+
+		assert.Error(t, gracePeriod.Extend(1, now))
+	})
+}
+
+func TestGracePeriodAt(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	gracePeriod := entity.NewGracePeriodAt(uuid.New(), uuid.New(), now.Add(3*24*time.Hour), now)
+	assert.Equal(t, now, gracePeriod.CreatedAt)
+
+	assert.True(t, gracePeriod.IsActiveAt(now))
+	assert.Equal(t, 3, gracePeriod.DaysRemainingAt(now))
+	assert.Equal(t, 72, gracePeriod.HoursRemainingAt(now))
+
+	later := now.Add(71 * time.Hour)
+	assert.True(t, gracePeriod.IsActiveAt(later))
+	assert.Equal(t, 1, gracePeriod.HoursRemainingAt(later))
+
+	afterExpiry := now.Add(73 * time.Hour)
+	assert.False(t, gracePeriod.IsActiveAt(afterExpiry))
+	assert.True(t, gracePeriod.IsExpiredAt(afterExpiry))
+	assert.Zero(t, gracePeriod.DaysRemainingAt(afterExpiry))
+
+	assert.NoError(t, gracePeriod.ExpireAt(afterExpiry))
+	assert.Equal(t, afterExpiry, gracePeriod.UpdatedAt)
+	assert.Error(t, gracePeriod.ResolveAt(afterExpiry))
+}
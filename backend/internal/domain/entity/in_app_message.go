@@ -0,0 +1,258 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidInAppMessage is returned for malformed message definitions
+var ErrInvalidInAppMessage = errors.New("invalid in-app message")
+
+// InAppMessageFormat is how the client renders a message
+type InAppMessageFormat string
+
+const (
+	InAppMessageBanner InAppMessageFormat = "banner"
+	InAppMessageModal  InAppMessageFormat = "modal"
+)
+
+// InAppMessageTrigger ties a message to the user's subscription state
+type InAppMessageTrigger string
+
+const (
+	// TriggerAlways ignores subscription state
+	TriggerAlways InAppMessageTrigger = "always"
+	// TriggerTrialEnding fires while a free trial ends within the window
+	TriggerTrialEnding InAppMessageTrigger = "trial_ending"
+	// TriggerLapsed fires for former subscribers, within the window of
+	// their subscription ending when one is set
+	TriggerLapsed InAppMessageTrigger = "lapsed"
+)
+
+// InAppMessageEventType is what the client reports about a shown message
+type InAppMessageEventType string
+
+const (
+	InAppMessageImpression InAppMessageEventType = "impression"
+	InAppMessageClick      InAppMessageEventType = "click"
+	InAppMessageDismiss    InAppMessageEventType = "dismiss"
+)
+
+// IsValid reports whether t is a known event type
+func (t InAppMessageEventType) IsValid() bool {
+	switch t {
+	case InAppMessageImpression, InAppMessageClick, InAppMessageDismiss:
+		return true
+	}
+	return false
+}
+
+// InAppMessageContent is what the client displays; PaywallID or OfferCode
+// tell it what the call to action opens
+type InAppMessageContent struct {
+	Title     string     `json:"title"`
+	Body      string     `json:"body,omitempty"`
+	CTAText   string     `json:"cta_text,omitempty"`
+	CTAURL    string     `json:"cta_url,omitempty"`
+	PaywallID *uuid.UUID `json:"paywall_id,omitempty"`
+	OfferCode string     `json:"offer_code,omitempty"`
+}
+
+// InAppMessageVariant is the content shown for one arm of the experiment
+type InAppMessageVariant struct {
+	ArmID   uuid.UUID           `json:"arm_id"`
+	Content InAppMessageContent `json:"content"`
+}
+
+// SubscriberState is the subscription state triggers are evaluated against
+type SubscriberState struct {
+	Subscribed bool `json:"subscribed"`
+	InTrial    bool `json:"in_trial"`
+	// ExpiresAt is when the current or, for former subscribers, the last
+	// subscription ends
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// InAppMessageHistory is what a user has done with a message so far
+type InAppMessageHistory struct {
+	Impressions      int
+	LastImpressionAt *time.Time
+	// Closed is set once the user clicked or dismissed the message
+	Closed bool
+}
+
+// InAppMessage is a scheduled prompt (trial-ending banner, win-back modal)
+type InAppMessage struct {
+	ID                 uuid.UUID
+	AppID              uuid.UUID
+	Name               string
+	Placement          string // "" shows at every placement
+	Format             InAppMessageFormat
+	Trigger            InAppMessageTrigger
+	TriggerWindowHours int
+	Priority           int // higher wins within a format
+	Enabled            bool
+	StartsAt           *time.Time
+	EndsAt             *time.Time
+	Conditions         PaywallRuleConditions
+	Content            InAppMessageContent
+	// ExperimentID, when set, lets the bandit choose among Variants
+	ExperimentID     *uuid.UUID
+	Variants         []InAppMessageVariant
+	MaxImpressions   int // per user; 0 is unlimited
+	MinIntervalHours int // between impressions to the same user
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+// NewInAppMessage creates an enabled message shown whenever its conditions hold
+func NewInAppMessage(appID uuid.UUID, name string, format InAppMessageFormat) *InAppMessage {
+	now := time.Now()
+	return &InAppMessage{
+		ID:        uuid.New(),
+		AppID:     appID,
+		Name:      name,
+		Format:    format,
+		Trigger:   TriggerAlways,
+		Enabled:   true,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// Validate checks the format, trigger, schedule, caps and variants
+func (m *InAppMessage) Validate() error {
+	if strings.TrimSpace(m.Name) == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidInAppMessage)
+	}
+	switch m.Format {
+	case InAppMessageBanner, InAppMessageModal:
+	default:
+		return fmt.Errorf("%w: unknown format %q", ErrInvalidInAppMessage, m.Format)
+	}
+	switch m.Trigger {
+	case TriggerAlways, TriggerTrialEnding, TriggerLapsed:
+	default:
+		return fmt.Errorf("%w: unknown trigger %q", ErrInvalidInAppMessage, m.Trigger)
+	}
+	if m.Trigger == TriggerTrialEnding && m.TriggerWindowHours <= 0 {
+		return fmt.Errorf("%w: trial_ending needs a positive trigger_window_hours", ErrInvalidInAppMessage)
+	}
+	if m.TriggerWindowHours < 0 || m.MaxImpressions < 0 || m.MinIntervalHours < 0 {
+		return fmt.Errorf("%w: trigger window and caps must not be negative", ErrInvalidInAppMessage)
+	}
+	if m.StartsAt != nil && m.EndsAt != nil && !m.EndsAt.After(*m.StartsAt) {
+		return fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidInAppMessage)
+	}
+	if err := m.Conditions.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidInAppMessage, err)
+	}
+	if len(m.Variants) > 0 && m.ExperimentID == nil {
+		return fmt.Errorf("%w: variants require experiment_id", ErrInvalidInAppMessage)
+	}
+	if m.ExperimentID != nil && len(m.Variants) == 0 {
+		return fmt.Errorf("%w: experiment_id requires variants", ErrInvalidInAppMessage)
+	}
+	seen := make(map[uuid.UUID]bool, len(m.Variants))
+	for _, v := range m.Variants {
+		if v.ArmID == uuid.Nil || seen[v.ArmID] {
+			return fmt.Errorf("%w: each variant needs a distinct arm_id", ErrInvalidInAppMessage)
+		}
+		seen[v.ArmID] = true
+	}
+	return nil
+}
+
+// AppliesTo reports whether the message shows at placement
+func (m *InAppMessage) AppliesTo(placement string) bool {
+	return m.Placement == "" || strings.EqualFold(m.Placement, placement)
+}
+
+// Live reports whether the message is enabled and scheduled at now
+func (m *InAppMessage) Live(now time.Time) bool {
+	if !m.Enabled {
+		return false
+	}
+	if m.StartsAt != nil && now.Before(*m.StartsAt) {
+		return false
+	}
+	return m.EndsAt == nil || now.Before(*m.EndsAt)
+}
+
+// Triggered reports whether the user's subscription state fires the trigger
+func (m *InAppMessage) Triggered(state SubscriberState, now time.Time) bool {
+	window := time.Duration(m.TriggerWindowHours) * time.Hour
+	switch m.Trigger {
+	case TriggerTrialEnding:
+		return state.Subscribed && state.InTrial && state.ExpiresAt != nil &&
+			state.ExpiresAt.Sub(now) <= window
+	case TriggerLapsed:
+		if state.Subscribed || state.ExpiresAt == nil {
+			return false
+		}
+		return window == 0 || now.Sub(*state.ExpiresAt) <= window
+	default:
+		return true
+	}
+}
+
+// Capped returns why the user must not see the message again now, or ""
+func (m *InAppMessage) Capped(h InAppMessageHistory, now time.Time) string {
+	if h.Closed {
+		return "closed"
+	}
+	if m.MaxImpressions > 0 && h.Impressions >= m.MaxImpressions {
+		return "max_impressions"
+	}
+	if m.MinIntervalHours > 0 && h.LastImpressionAt != nil &&
+		now.Sub(*h.LastImpressionAt) < time.Duration(m.MinIntervalHours)*time.Hour {
+		return "min_interval"
+	}
+	return ""
+}
+
+// ArmIDs returns the arms of the message's variants
+func (m *InAppMessage) ArmIDs() []uuid.UUID {
+	ids := make([]uuid.UUID, len(m.Variants))
+	for i, v := range m.Variants {
+		ids[i] = v.ArmID
+	}
+	return ids
+}
+
+// ContentFor returns the content of armID's variant, the default content
+// when armID is nil or not a variant
+func (m *InAppMessage) ContentFor(armID *uuid.UUID) InAppMessageContent {
+	if armID != nil {
+		for _, v := range m.Variants {
+			if v.ArmID == *armID {
+				return v.Content
+			}
+		}
+	}
+	return m.Content
+}
+
+// InAppMessageEvent is one impression, click or dismissal by a user
+type InAppMessageEvent struct {
+	ID         uuid.UUID
+	MessageID  uuid.UUID
+	UserID     uuid.UUID
+	ArmID      *uuid.UUID
+	Type       InAppMessageEventType
+	OccurredAt time.Time
+}
+
+// InAppMessageStats are a message's event totals, per arm when it runs an
+// experiment
+type InAppMessageStats struct {
+	ArmID       *uuid.UUID `json:"arm_id,omitempty"`
+	Impressions int64      `json:"impressions"`
+	Clicks      int64      `json:"clicks"`
+	Dismissals  int64      `json:"dismissals"`
+	UniqueUsers int64      `json:"unique_users"`
+}
@@ -0,0 +1,58 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInAppMessage_Triggered(t *testing.T) {
+	now := time.Date(2026, 7, 1, 12, 0, 0, 0, time.UTC)
+	in := func(d time.Duration) *time.Time { at := now.Add(d); return &at }
+
+	trial := NewInAppMessage(uuid.New(), "Trial ending", InAppMessageBanner)
+	trial.Trigger, trial.TriggerWindowHours = TriggerTrialEnding, 48
+	assert.True(t, trial.Triggered(SubscriberState{Subscribed: true, InTrial: true, ExpiresAt: in(24 * time.Hour)}, now))
+	assert.False(t, trial.Triggered(SubscriberState{Subscribed: true, InTrial: true, ExpiresAt: in(72 * time.Hour)}, now))
+	assert.False(t, trial.Triggered(SubscriberState{Subscribed: true, ExpiresAt: in(24 * time.Hour)}, now), "paid period, not a trial")
+
+	winback := NewInAppMessage(uuid.New(), "Come back", InAppMessageModal)
+	winback.Trigger = TriggerLapsed
+	assert.True(t, winback.Triggered(SubscriberState{ExpiresAt: in(-90 * 24 * time.Hour)}, now))
+	assert.False(t, winback.Triggered(SubscriberState{}, now), "never subscribed")
+	winback.TriggerWindowHours = 24 * 30
+	assert.False(t, winback.Triggered(SubscriberState{ExpiresAt: in(-90 * 24 * time.Hour)}, now))
+}
+
+func TestInAppMessage_Capped(t *testing.T) {
+	now := time.Date(2026, 7, 1, 12, 0, 0, 0, time.UTC)
+	last := now.Add(-2 * time.Hour)
+	m := NewInAppMessage(uuid.New(), "Sale", InAppMessageBanner)
+	m.MaxImpressions, m.MinIntervalHours = 3, 24
+
+	assert.Empty(t, m.Capped(InAppMessageHistory{}, now))
+	assert.Equal(t, "min_interval", m.Capped(InAppMessageHistory{Impressions: 1, LastImpressionAt: &last}, now))
+	assert.Equal(t, "max_impressions", m.Capped(InAppMessageHistory{Impressions: 3}, now))
+	assert.Equal(t, "closed", m.Capped(InAppMessageHistory{Closed: true}, now))
+}
+
+func TestInAppMessage_Validate(t *testing.T) {
+	m := NewInAppMessage(uuid.New(), "Sale", InAppMessageBanner)
+	assert.NoError(t, m.Validate())
+
+	m.Trigger = TriggerTrialEnding
+	assert.ErrorIs(t, m.Validate(), ErrInvalidInAppMessage, "trial_ending needs a window")
+
+	m.Trigger = TriggerAlways
+	arm := uuid.New()
+	m.Variants = []InAppMessageVariant{{ArmID: arm}}
+	assert.ErrorIs(t, m.Validate(), ErrInvalidInAppMessage, "variants need an experiment")
+
+	experiment := uuid.New()
+	m.ExperimentID = &experiment
+	assert.NoError(t, m.Validate())
+	m.Variants = append(m.Variants, InAppMessageVariant{ArmID: arm})
+	assert.ErrorIs(t, m.Validate(), ErrInvalidInAppMessage, "duplicate arm")
+}
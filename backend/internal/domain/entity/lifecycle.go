@@ -0,0 +1,129 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LifecycleStage is where a user is in their relationship with the app
+type LifecycleStage string
+
+const (
+	// LifecycleNew users have never subscribed and are still active
+	LifecycleNew LifecycleStage = "new"
+	// LifecycleTrialing users are in a free trial
+	LifecycleTrialing LifecycleStage = "trialing"
+	// LifecycleEngagedPayer users pay, renew and keep using the app
+	LifecycleEngagedPayer LifecycleStage = "engaged_payer"
+	// LifecycleAtRisk users pay but turned auto-renew off, are in a billing
+	// grace period or stopped using the app
+	LifecycleAtRisk LifecycleStage = "at_risk"
+	// LifecycleChurned users lost access, or never subscribed and went quiet
+	LifecycleChurned LifecycleStage = "churned"
+	// LifecycleReactivated users subscribed again after a lapse
+	LifecycleReactivated LifecycleStage = "reactivated"
+)
+
+// LifecycleStages are every stage, in bandit feature order
+var LifecycleStages = []LifecycleStage{
+	LifecycleNew, LifecycleTrialing, LifecycleEngagedPayer, LifecycleAtRisk, LifecycleChurned, LifecycleReactivated,
+}
+
+const (
+	// LifecyclePayerInactivity is how long a paying user may go without a
+	// session before they count as at risk
+	LifecyclePayerInactivity = 14 * 24 * time.Hour
+	// LifecycleFreeInactivity is how long a user who never subscribed may go
+	// without a session before they count as churned
+	LifecycleFreeInactivity = 30 * 24 * time.Hour
+	// LifecycleReactivationWindow is how long a resubscribed user stays
+	// reactivated before counting as an engaged payer
+	LifecycleReactivationWindow = 30 * 24 * time.Hour
+)
+
+// IsValid reports whether s is a known stage
+func (s LifecycleStage) IsValid() bool {
+	for _, stage := range LifecycleStages {
+		if s == stage {
+			return true
+		}
+	}
+	return false
+}
+
+// LifecycleSignals is what a user's lifecycle stage is classified from
+type LifecycleSignals struct {
+	UserID   uuid.UUID
+	AppID    uuid.UUID
+	SignupAt time.Time
+	// LastSeenAt is the user's latest session activity, nil without sessions
+	LastSeenAt *time.Time
+
+	// Current is the user's subscription that ends last, nil when they
+	// never subscribed
+	Current *LifecycleSubscription
+	// LapsedAt is when an earlier subscription ended before Current started
+	LapsedAt *time.Time
+	// InTrial is set while a free trial of Current's product was redeemed in
+	// its current billing period
+	InTrial bool
+
+	// PreviousStage is the stage from the last run, empty for new users, and
+	// PreviousEnteredAt when the user moved into it
+	PreviousStage     LifecycleStage
+	PreviousEnteredAt time.Time
+}
+
+// LifecycleSubscription is the part of a subscription classification reads
+type LifecycleSubscription struct {
+	Status    SubscriptionStatus
+	PlanType  PlanType
+	AutoRenew bool
+	StartedAt time.Time
+	ExpiresAt time.Time
+}
+
+// UserLifecycleStage is a user's classified stage
+type UserLifecycleStage struct {
+	UserID     uuid.UUID      `json:"user_id"`
+	AppID      uuid.UUID      `json:"app_id"`
+	Stage      LifecycleStage `json:"stage"`
+	EnteredAt  time.Time      `json:"entered_at"`
+	ComputedAt time.Time      `json:"computed_at"`
+}
+
+// ClassifyLifecycleStage places a user in a lifecycle stage at now
+func ClassifyLifecycleStage(s LifecycleSignals, now time.Time) LifecycleStage {
+	lastActive := s.SignupAt
+	if s.LastSeenAt != nil && s.LastSeenAt.After(lastActive) {
+		lastActive = *s.LastSeenAt
+	}
+	inactive := now.Sub(lastActive)
+
+	sub := s.Current
+	if sub == nil {
+		if inactive >= LifecycleFreeInactivity {
+			return LifecycleChurned
+		}
+		return LifecycleNew
+	}
+	subscribed := (sub.Status == StatusActive || sub.Status == StatusGrace) && sub.ExpiresAt.After(now)
+	if !subscribed {
+		return LifecycleChurned
+	}
+	if s.InTrial {
+		return LifecycleTrialing
+	}
+	if sub.Status == StatusGrace || (!sub.AutoRenew && sub.PlanType != PlanLifetime) || inactive >= LifecyclePayerInactivity {
+		return LifecycleAtRisk
+	}
+	// A lapse is seen either as an earlier subscription or, when the same
+	// subscription came back, as the churned stage of the last run
+	if s.PreviousStage == LifecycleChurned ||
+		(s.PreviousStage == LifecycleReactivated && now.Sub(s.PreviousEnteredAt) < LifecycleReactivationWindow) ||
+		(s.LapsedAt != nil && now.Sub(sub.StartedAt) < LifecycleReactivationWindow) {
+		return LifecycleReactivated
+	}
+	return LifecycleEngagedPayer
+}
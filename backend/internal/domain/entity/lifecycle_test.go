@@ -0,0 +1,48 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyLifecycleStage(t *testing.T) {
+	now := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	daysAgo := func(d int) *time.Time {
+		at := now.AddDate(0, 0, -d)
+		return &at
+	}
+	paying := func() *LifecycleSubscription {
+		return &LifecycleSubscription{Status: StatusActive, PlanType: PlanMonthly, AutoRenew: true, StartedAt: now.AddDate(0, -6, 0), ExpiresAt: now.AddDate(0, 0, 10)}
+	}
+	withSub := func(mutate func(*LifecycleSignals)) LifecycleSignals {
+		s := LifecycleSignals{SignupAt: now.AddDate(-1, 0, 0), LastSeenAt: daysAgo(1), Current: paying()}
+		mutate(&s)
+		return s
+	}
+
+	cases := map[string]struct {
+		signals LifecycleSignals
+		want    LifecycleStage
+	}{
+		"fresh signup":           {LifecycleSignals{SignupAt: now.AddDate(0, 0, -3)}, LifecycleNew},
+		"free user gone quiet":   {LifecycleSignals{SignupAt: now.AddDate(0, -3, 0), LastSeenAt: daysAgo(45)}, LifecycleChurned},
+		"engaged payer":          {withSub(func(*LifecycleSignals) {}), LifecycleEngagedPayer},
+		"in free trial":          {withSub(func(s *LifecycleSignals) { s.InTrial = true }), LifecycleTrialing},
+		"auto-renew off":         {withSub(func(s *LifecycleSignals) { s.Current.AutoRenew = false }), LifecycleAtRisk},
+		"lifetime never renews":  {withSub(func(s *LifecycleSignals) { s.Current.AutoRenew, s.Current.PlanType = false, PlanLifetime }), LifecycleEngagedPayer},
+		"billing grace":          {withSub(func(s *LifecycleSignals) { s.Current.Status = StatusGrace }), LifecycleAtRisk},
+		"payer stopped opening":  {withSub(func(s *LifecycleSignals) { s.LastSeenAt = daysAgo(20) }), LifecycleAtRisk},
+		"subscription ended":     {withSub(func(s *LifecycleSignals) { s.Current.ExpiresAt = now.AddDate(0, 0, -2) }), LifecycleChurned},
+		"came back after lapse":  {withSub(func(s *LifecycleSignals) { s.LapsedAt, s.Current.StartedAt = daysAgo(40), *daysAgo(5) }), LifecycleReactivated},
+		"long since came back":   {withSub(func(s *LifecycleSignals) { s.LapsedAt, s.Current.StartedAt = daysAgo(90), *daysAgo(60) }), LifecycleEngagedPayer},
+		"same subscription back": {withSub(func(s *LifecycleSignals) { s.PreviousStage = LifecycleChurned }), LifecycleReactivated},
+		"still reactivated": {withSub(func(s *LifecycleSignals) {
+			s.PreviousStage, s.PreviousEnteredAt = LifecycleReactivated, *daysAgo(10)
+		}), LifecycleReactivated},
+	}
+	for name, tc := range cases {
+		assert.Equal(t, tc.want, ClassifyLifecycleStage(tc.signals, now), name)
+	}
+}
@@ -0,0 +1,77 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LifetimeEntitlementStatus is the state of a lifetime unlock
+type LifetimeEntitlementStatus string
+
+const (
+	LifetimeEntitlementActive  LifetimeEntitlementStatus = "active"
+	LifetimeEntitlementRevoked LifetimeEntitlementStatus = "revoked"
+)
+
+// LifetimeProduct configures a non-consumable store product that unlocks
+// access permanently
+type LifetimeProduct struct {
+	// Price in USD, counted towards LTV
+	Price float64 `json:"price"`
+}
+
+// ErrLifetimeEntitlementRevoked is returned when revoking an unlock twice
+var ErrLifetimeEntitlementRevoked = errors.New("lifetime entitlement already revoked")
+
+// LifetimeEntitlement is permanent access granted by a one-time purchase.
+// Unlike a subscription it never expires; only a refund or store revocation
+// ends it.
+type LifetimeEntitlement struct {
+	ID           uuid.UUID
+	AppID        uuid.UUID
+	UserID       uuid.UUID
+	Platform     string
+	ProductID    string
+	ProviderTxID string
+	OriginalTxID string
+	// Amount is the purchase price in USD
+	Amount       float64
+	Status       LifetimeEntitlementStatus
+	RevokeReason string
+	PurchasedAt  time.Time
+	RevokedAt    *time.Time
+}
+
+// NewLifetimeEntitlement creates an active unlock for a verified purchase
+func NewLifetimeEntitlement(appID, userID uuid.UUID, platform, productID, providerTxID, originalTxID string, product LifetimeProduct, purchasedAt time.Time) *LifetimeEntitlement {
+	return &LifetimeEntitlement{
+		ID:           uuid.New(),
+		AppID:        appID,
+		UserID:       userID,
+		Platform:     platform,
+		ProductID:    productID,
+		ProviderTxID: providerTxID,
+		OriginalTxID: originalTxID,
+		Amount:       product.Price,
+		Status:       LifetimeEntitlementActive,
+		PurchasedAt:  purchasedAt,
+	}
+}
+
+// IsActive returns true while the unlock grants access
+func (e *LifetimeEntitlement) IsActive() bool {
+	return e.Status == LifetimeEntitlementActive
+}
+
+// Revoke ends the unlock, e.g. after a refund
+func (e *LifetimeEntitlement) Revoke(reason string, at time.Time) error {
+	if !e.IsActive() {
+		return ErrLifetimeEntitlementRevoked
+	}
+	e.Status = LifetimeEntitlementRevoked
+	e.RevokeReason = reason
+	e.RevokedAt = &at
+	return nil
+}
@@ -0,0 +1,24 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLifetimeEntitlement_Revoke(t *testing.T) {
+	now := time.Now()
+	e := NewLifetimeEntitlement(uuid.New(), uuid.New(), "ios", "com.app.lifetime", "tx-1", "tx-1", LifetimeProduct{Price: 49.99}, now)
+	require.True(t, e.IsActive())
+	assert.Equal(t, 49.99, e.Amount)
+
+	require.NoError(t, e.Revoke("refund", now))
+	assert.False(t, e.IsActive())
+	assert.Equal(t, "refund", e.RevokeReason)
+	require.NotNil(t, e.RevokedAt)
+
+	assert.ErrorIs(t, e.Revoke("refund", now), ErrLifetimeEntitlementRevoked)
+}
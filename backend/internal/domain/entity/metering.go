@@ -0,0 +1,139 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrFeatureNotMetered is returned for features without a metering rule
+var ErrFeatureNotMetered = errors.New("feature is not metered")
+
+// MeteringPeriod is the window free uses are counted over. Windows are UTC;
+// weeks start on Monday.
+type MeteringPeriod string
+
+const (
+	MeteringPeriodDay      MeteringPeriod = "day"
+	MeteringPeriodWeek     MeteringPeriod = "week"
+	MeteringPeriodMonth    MeteringPeriod = "month"
+	MeteringPeriodLifetime MeteringPeriod = "lifetime"
+)
+
+// meteringEpoch is the period start of lifetime meters
+var meteringEpoch = time.Unix(0, 0).UTC()
+
+// PaywallMode is the paywall a metered feature calls for
+type PaywallMode string
+
+const (
+	PaywallModeNone PaywallMode = "none"
+	// PaywallModeSoft is dismissible, shown while the last free uses remain
+	PaywallModeSoft PaywallMode = "soft"
+	// PaywallModeHard blocks the feature until the user subscribes
+	PaywallModeHard PaywallMode = "hard"
+)
+
+// MeteringRule grants FreeUses of a feature per Period before the hard paywall
+type MeteringRule struct {
+	FreeUses int64          `json:"free_uses"`
+	Period   MeteringPeriod `json:"period"`
+	// SoftPaywallRemaining shows the soft paywall once this many free uses or
+	// fewer remain; 0 goes straight to the hard paywall
+	SoftPaywallRemaining int64 `json:"soft_paywall_remaining,omitempty"`
+}
+
+// Validate checks the rule's period and bounds
+func (r MeteringRule) Validate() error {
+	switch r.Period {
+	case MeteringPeriodDay, MeteringPeriodWeek, MeteringPeriodMonth, MeteringPeriodLifetime:
+	default:
+		return fmt.Errorf("unknown metering period %q", r.Period)
+	}
+	if r.FreeUses < 0 {
+		return fmt.Errorf("free_uses must not be negative")
+	}
+	if r.SoftPaywallRemaining < 0 || r.SoftPaywallRemaining > r.FreeUses {
+		return fmt.Errorf("soft_paywall_remaining must be between 0 and free_uses")
+	}
+	return nil
+}
+
+// PeriodStart returns the start of the window containing now
+func (r MeteringRule) PeriodStart(now time.Time) time.Time {
+	now = now.UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	switch r.Period {
+	case MeteringPeriodDay:
+		return day
+	case MeteringPeriodWeek:
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case MeteringPeriodMonth:
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return meteringEpoch
+	}
+}
+
+// PeriodEnd returns when the window starting at start resets; zero for lifetime
+func (r MeteringRule) PeriodEnd(start time.Time) time.Time {
+	switch r.Period {
+	case MeteringPeriodDay:
+		return start.AddDate(0, 0, 1)
+	case MeteringPeriodWeek:
+		return start.AddDate(0, 0, 7)
+	case MeteringPeriodMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		return time.Time{}
+	}
+}
+
+// MeterUsage is how many free uses of a feature a user spent in one window
+type MeterUsage struct {
+	AppID       uuid.UUID
+	UserID      uuid.UUID
+	Feature     string
+	PeriodStart time.Time
+	Used        int64
+	UpdatedAt   time.Time
+}
+
+// MeterState is what the client needs to decide which paywall to show
+type MeterState struct {
+	Feature     string
+	Used        int64
+	FreeUses    int64
+	Remaining   int64
+	Paywall     PaywallMode
+	PeriodStart time.Time
+	// ResetsAt is nil for lifetime meters
+	ResetsAt *time.Time
+	// Unlimited is set for subscribers, who are never metered
+	Unlimited bool
+}
+
+// NewMeterState derives the paywall mode from the uses spent in the window
+func NewMeterState(feature string, rule MeteringRule, periodStart time.Time, used int64) MeterState {
+	state := MeterState{
+		Feature:     feature,
+		Used:        used,
+		FreeUses:    rule.FreeUses,
+		Remaining:   max(rule.FreeUses-used, 0),
+		PeriodStart: periodStart,
+	}
+	if end := rule.PeriodEnd(periodStart); !end.IsZero() {
+		state.ResetsAt = &end
+	}
+	switch {
+	case state.Remaining == 0:
+		state.Paywall = PaywallModeHard
+	case state.Remaining <= rule.SoftPaywallRemaining:
+		state.Paywall = PaywallModeSoft
+	default:
+		state.Paywall = PaywallModeNone
+	}
+	return state
+}
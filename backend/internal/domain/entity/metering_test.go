@@ -0,0 +1,30 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMeteringRule_PeriodStart(t *testing.T) {
+	// A Wednesday afternoon
+	now := time.Date(2026, 10, 14, 15, 30, 0, 0, time.UTC)
+
+	cases := map[MeteringPeriod]time.Time{
+		MeteringPeriodDay:      time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC),
+		MeteringPeriodWeek:     time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC),
+		MeteringPeriodMonth:    time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+		MeteringPeriodLifetime: time.Unix(0, 0).UTC(),
+	}
+	for period, want := range cases {
+		assert.Equal(t, want, MeteringRule{Period: period}.PeriodStart(now), period)
+	}
+	assert.True(t, MeteringRule{Period: MeteringPeriodLifetime}.PeriodEnd(meteringEpoch).IsZero())
+}
+
+func TestMeteringRule_Validate(t *testing.T) {
+	assert.NoError(t, MeteringRule{FreeUses: 5, Period: MeteringPeriodWeek, SoftPaywallRemaining: 2}.Validate())
+	assert.Error(t, MeteringRule{FreeUses: 5, Period: "year"}.Validate())
+	assert.Error(t, MeteringRule{FreeUses: 1, Period: MeteringPeriodDay, SoftPaywallRemaining: 2}.Validate())
+}
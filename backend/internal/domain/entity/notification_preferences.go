@@ -0,0 +1,139 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidNotificationPreferences is returned for unknown categories,
+// quiet hours out of range or unknown timezones
+var ErrInvalidNotificationPreferences = errors.New("invalid notification preferences")
+
+// NotificationChannel is how a notification reaches the user
+type NotificationChannel string
+
+const (
+	NotificationPush  NotificationChannel = "push"
+	NotificationEmail NotificationChannel = "email"
+)
+
+// NotificationCategory groups notifications users can opt out of together
+type NotificationCategory string
+
+const (
+	// NotificationBilling covers payment failures, grace periods and expiry
+	NotificationBilling NotificationCategory = "billing"
+	// NotificationPurchase covers the outcome of purchases the user made
+	NotificationPurchase NotificationCategory = "purchase"
+	// NotificationMarketing covers win-back offers and segment campaigns
+	NotificationMarketing NotificationCategory = "marketing"
+)
+
+// NotificationCategories are every category, in settings screen order
+var NotificationCategories = []NotificationCategory{NotificationBilling, NotificationPurchase, NotificationMarketing}
+
+// IsValid reports whether c is a known category
+func (c NotificationCategory) IsValid() bool {
+	return slices.Contains(NotificationCategories, c)
+}
+
+// SuppressionReason is why a notification was not sent
+type SuppressionReason string
+
+const (
+	SuppressedChannelDisabled  SuppressionReason = "channel_disabled"
+	SuppressedCategoryDisabled SuppressionReason = "category_disabled"
+	SuppressedQuietHours       SuppressionReason = "quiet_hours"
+	SuppressedNoConsent        SuppressionReason = "no_consent"
+)
+
+// QuietHours is a daily window, in minutes of the day in Timezone, during
+// which notifications are held back. End before Start wraps past midnight.
+type QuietHours struct {
+	StartMinute int
+	EndMinute   int
+	Timezone    string
+}
+
+// Contains reports whether now falls inside the window
+func (q *QuietHours) Contains(now time.Time) bool {
+	loc, err := time.LoadLocation(q.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	local := now.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	if q.StartMinute <= q.EndMinute {
+		return minute >= q.StartMinute && minute < q.EndMinute
+	}
+	return minute >= q.StartMinute || minute < q.EndMinute
+}
+
+// NotificationPreferences is what a user wants to be notified about and when
+type NotificationPreferences struct {
+	UserID             uuid.UUID
+	AppID              uuid.UUID
+	PushEnabled        bool
+	EmailEnabled       bool
+	DisabledCategories []NotificationCategory
+	QuietHours         *QuietHours
+	// UpdatedAt is zero until the user first sets their preferences
+	UpdatedAt time.Time
+}
+
+// DefaultNotificationPreferences are the preferences of a user who never set
+// any: every channel and category, no quiet hours
+func DefaultNotificationPreferences(appID, userID uuid.UUID) *NotificationPreferences {
+	return &NotificationPreferences{UserID: userID, AppID: appID, PushEnabled: true, EmailEnabled: true}
+}
+
+// Validate checks categories, quiet hours and timezone
+func (p *NotificationPreferences) Validate() error {
+	for _, c := range p.DisabledCategories {
+		if !c.IsValid() {
+			return fmt.Errorf("%w: unknown category %q", ErrInvalidNotificationPreferences, c)
+		}
+	}
+	if q := p.QuietHours; q != nil {
+		if q.StartMinute < 0 || q.StartMinute >= 24*60 || q.EndMinute < 0 || q.EndMinute >= 24*60 {
+			return fmt.Errorf("%w: quiet hours must be times of day", ErrInvalidNotificationPreferences)
+		}
+		if q.StartMinute == q.EndMinute {
+			return fmt.Errorf("%w: quiet hours must not start and end at the same time", ErrInvalidNotificationPreferences)
+		}
+		if _, err := time.LoadLocation(q.Timezone); err != nil || q.Timezone == "" {
+			return fmt.Errorf("%w: unknown timezone %q", ErrInvalidNotificationPreferences, q.Timezone)
+		}
+	}
+	return nil
+}
+
+// Suppression is why a notification of category on channel must not be sent
+// at now, or "" when it may. Purchase outcomes answer something the user just
+// did, so quiet hours do not hold them back.
+func (p *NotificationPreferences) Suppression(category NotificationCategory, channel NotificationChannel, now time.Time) SuppressionReason {
+	if (channel == NotificationPush && !p.PushEnabled) || (channel == NotificationEmail && !p.EmailEnabled) {
+		return SuppressedChannelDisabled
+	}
+	if slices.Contains(p.DisabledCategories, category) {
+		return SuppressedCategoryDisabled
+	}
+	if p.QuietHours != nil && category != NotificationPurchase && p.QuietHours.Contains(now) {
+		return SuppressedQuietHours
+	}
+	return ""
+}
+
+// NotificationSuppressionCount is how many notifications were suppressed on
+// a day for one reason
+type NotificationSuppressionCount struct {
+	Day      string               `json:"day"`
+	Category NotificationCategory `json:"category"`
+	Channel  NotificationChannel  `json:"channel"`
+	Reason   SuppressionReason    `json:"reason"`
+	Count    int64                `json:"count"`
+}
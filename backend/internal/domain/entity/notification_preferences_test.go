@@ -0,0 +1,49 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuietHours_WrapPastMidnight(t *testing.T) {
+	q := &QuietHours{StartMinute: 22 * 60, EndMinute: 7 * 60, Timezone: "Europe/Berlin"}
+
+	// 21:30 UTC is 23:30 in Berlin (CEST)
+	assert.True(t, q.Contains(time.Date(2026, 7, 1, 21, 30, 0, 0, time.UTC)))
+	// 05:00 UTC is 07:00 in Berlin, the end is exclusive
+	assert.False(t, q.Contains(time.Date(2026, 7, 1, 5, 0, 0, 0, time.UTC)))
+	assert.False(t, q.Contains(time.Date(2026, 7, 1, 12, 0, 0, 0, time.UTC)))
+}
+
+func TestNotificationPreferences_Suppression(t *testing.T) {
+	p := DefaultNotificationPreferences(uuid.New(), uuid.New())
+	p.EmailEnabled = false
+	p.DisabledCategories = []NotificationCategory{NotificationMarketing}
+	p.QuietHours = &QuietHours{StartMinute: 0, EndMinute: 6 * 60, Timezone: "UTC"}
+	night := time.Date(2026, 7, 1, 2, 0, 0, 0, time.UTC)
+	day := time.Date(2026, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, SuppressedChannelDisabled, p.Suppression(NotificationBilling, NotificationEmail, day))
+	assert.Equal(t, SuppressedCategoryDisabled, p.Suppression(NotificationMarketing, NotificationPush, day))
+	assert.Equal(t, SuppressedQuietHours, p.Suppression(NotificationBilling, NotificationPush, night))
+	assert.Empty(t, p.Suppression(NotificationPurchase, NotificationPush, night), "purchase outcomes ignore quiet hours")
+	assert.Empty(t, p.Suppression(NotificationBilling, NotificationPush, day))
+}
+
+func TestNotificationPreferences_Validate(t *testing.T) {
+	p := DefaultNotificationPreferences(uuid.New(), uuid.New())
+	assert.NoError(t, p.Validate())
+
+	p.DisabledCategories = []NotificationCategory{"newsletter"}
+	assert.ErrorIs(t, p.Validate(), ErrInvalidNotificationPreferences)
+
+	p.DisabledCategories = nil
+	p.QuietHours = &QuietHours{StartMinute: 60, EndMinute: 60, Timezone: "UTC"}
+	assert.ErrorIs(t, p.Validate(), ErrInvalidNotificationPreferences)
+
+	p.QuietHours = &QuietHours{StartMinute: 60, EndMinute: 120, Timezone: "Mars/Olympus"}
+	assert.ErrorIs(t, p.Validate(), ErrInvalidNotificationPreferences)
+}
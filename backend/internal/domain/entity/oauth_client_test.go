@@ -0,0 +1,44 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOAuthClient_Validate(t *testing.T) {
+	client := NewOAuthClient("cli_1", " CI ", nil, []string{"READ:analytics", "read:analytics"}, uuid.New())
+	require.NoError(t, client.Validate())
+	assert.Equal(t, "CI", client.Name)
+	assert.Equal(t, []string{ScopeReadAnalytics}, client.Scopes)
+
+	client.Scopes = []string{"admin:*"}
+	assert.Error(t, client.Validate())
+
+	client.Scopes = nil
+	assert.Error(t, client.Validate())
+}
+
+func TestOAuthClient_GrantScopes(t *testing.T) {
+	client := NewOAuthClient("cli_1", "Dashboards", nil, []string{ScopeReadAnalytics, ScopeWriteExperiments}, uuid.New())
+
+	all, err := client.GrantScopes(nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{ScopeReadAnalytics, ScopeWriteExperiments}, all)
+
+	// write:experiments implies read:experiments
+	narrowed, err := client.GrantScopes(ParseOAuthScope("read:experiments"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{ScopeReadExperiments}, narrowed)
+
+	_, err = client.GrantScopes([]string{"write:analytics"})
+	assert.ErrorIs(t, err, ErrOAuthScope)
+}
+
+func TestHasOAuthScope(t *testing.T) {
+	assert.True(t, HasOAuthScope([]string{ScopeWriteExperiments}, ScopeReadExperiments))
+	assert.False(t, HasOAuthScope([]string{ScopeReadExperiments}, ScopeWriteExperiments))
+	assert.False(t, HasOAuthScope([]string{ScopeReadAnalytics}, ScopeReadExperiments))
+}
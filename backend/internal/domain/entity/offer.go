@@ -0,0 +1,61 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OfferType identifies the kind of store-side discount applied to a purchase
+type OfferType string
+
+const (
+	OfferTypeIntroductory OfferType = "introductory"
+	OfferTypeFreeTrial    OfferType = "free_trial"
+	OfferTypeOfferCode    OfferType = "offer_code"
+	OfferTypePromotional  OfferType = "promotional"
+	OfferTypeWinBack      OfferType = "win_back"
+)
+
+// IsIntro returns true for offers the stores grant only once per user and product
+func (t OfferType) IsIntro() bool {
+	return t == OfferTypeIntroductory || t == OfferTypeFreeTrial
+}
+
+// IsValid returns true if the offer type is one of the known values
+func (t OfferType) IsValid() bool {
+	switch t {
+	case OfferTypeIntroductory, OfferTypeFreeTrial, OfferTypeOfferCode, OfferTypePromotional, OfferTypeWinBack:
+		return true
+	}
+	return false
+}
+
+// OfferRedemption records that a user bought a product with an intro price,
+// free trial, offer code, promotional or win-back offer. OfferCode is the
+// offer code reference name, or the promotional or win-back offer ID.
+type OfferRedemption struct {
+	ID            uuid.UUID
+	AppID         uuid.UUID
+	UserID        uuid.UUID
+	ProductID     string
+	OfferType     OfferType
+	OfferCode     string
+	TransactionID *uuid.UUID
+	ProviderTxID  string
+	Platform      string
+	RedeemedAt    time.Time
+}
+
+// NewOfferRedemption creates a new offer redemption
+func NewOfferRedemption(appID, userID uuid.UUID, productID string, offerType OfferType, platform string) *OfferRedemption {
+	return &OfferRedemption{
+		ID:         uuid.New(),
+		AppID:      appID,
+		UserID:     userID,
+		ProductID:  productID,
+		OfferType:  offerType,
+		Platform:   platform,
+		RedeemedAt: time.Now(),
+	}
+}
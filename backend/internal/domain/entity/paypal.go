@@ -0,0 +1,65 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PayPalPlan maps a PayPal billing plan to the product it sells
+type PayPalPlan struct {
+	PlanID    string    `json:"plan_id"` // PayPal plan ID (P-...)
+	AppID     uuid.UUID `json:"app_id"`
+	ProductID string    `json:"product_id"`
+	PlanType  PlanType  `json:"plan_type"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ErrPayPalPlanType is returned for plan types PayPal subscriptions cannot have
+var ErrPayPalPlanType = errors.New("paypal plans must be monthly or annual")
+
+// ErrPayPalPlanTaken is returned when a plan is already mapped by another app
+var ErrPayPalPlanTaken = errors.New("paypal plan is mapped by another app")
+
+// Validate checks the mapping can back a renewing subscription
+func (p *PayPalPlan) Validate() error {
+	if p.PlanID == "" || p.ProductID == "" {
+		return errors.New("plan_id and product_id are required")
+	}
+	if p.PlanType != PlanMonthly && p.PlanType != PlanAnnual {
+		return ErrPayPalPlanType
+	}
+	return nil
+}
+
+// PeriodEnd returns when a billing period starting at from ends. Like
+// PayPal, a period starting on a day the next month lacks ends on that
+// month's last day.
+func (p *PayPalPlan) PeriodEnd(from time.Time) time.Time {
+	return p.PlanType.PeriodEnd(from)
+}
+
+// PayPal subscription statuses
+const (
+	PayPalStatusApprovalPending = "APPROVAL_PENDING"
+	PayPalStatusApproved        = "APPROVED"
+	PayPalStatusActive          = "ACTIVE"
+	PayPalStatusSuspended       = "SUSPENDED"
+	PayPalStatusCancelled       = "CANCELLED"
+	PayPalStatusExpired         = "EXPIRED"
+)
+
+// PayPalSubscription links a PayPal subscription (I-...) to the subscription
+// it provisioned
+type PayPalSubscription struct {
+	ID             string    `json:"id"`
+	AppID          uuid.UUID `json:"app_id"`
+	UserID         uuid.UUID `json:"user_id"`
+	SubscriptionID uuid.UUID `json:"subscription_id"`
+	PlanID         string    `json:"plan_id"`
+	Status         string    `json:"status"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
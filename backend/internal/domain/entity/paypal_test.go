@@ -0,0 +1,23 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPayPalPlan_Validate(t *testing.T) {
+	assert.NoError(t, (&PayPalPlan{PlanID: "P-1", ProductID: "pro_monthly", PlanType: PlanMonthly}).Validate())
+	assert.ErrorIs(t, (&PayPalPlan{PlanID: "P-1", ProductID: "pro_lifetime", PlanType: PlanLifetime}).Validate(), ErrPayPalPlanType)
+	assert.Error(t, (&PayPalPlan{PlanID: "P-1", PlanType: PlanAnnual}).Validate())
+}
+
+func TestPayPalPlan_PeriodEnd(t *testing.T) {
+	from := time.Date(2026, 1, 31, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2026, 2, 28, 12, 0, 0, 0, time.UTC), (&PayPalPlan{PlanType: PlanMonthly}).PeriodEnd(from))
+	assert.Equal(t, time.Date(2027, 1, 31, 12, 0, 0, 0, time.UTC), (&PayPalPlan{PlanType: PlanAnnual}).PeriodEnd(from))
+
+	leapDay := time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2029, 2, 28, 0, 0, 0, 0, time.UTC), (&PayPalPlan{PlanType: PlanAnnual}).PeriodEnd(leapDay))
+}
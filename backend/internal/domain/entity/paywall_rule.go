@@ -0,0 +1,169 @@
+package entity
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/valueobject"
+)
+
+// LTV buckets used by paywall rule predicates
+const (
+	LTVBucketNone   = "none"   // never paid
+	LTVBucketLow    = "low"    // under 20
+	LTVBucketMedium = "medium" // 20 to under 100
+	LTVBucketHigh   = "high"   // 100 and above
+)
+
+// LTVBucketFor maps a lifetime value onto its bucket
+func LTVBucketFor(ltv float64) string {
+	switch {
+	case ltv <= 0:
+		return LTVBucketNone
+	case ltv < 20:
+		return LTVBucketLow
+	case ltv < 100:
+		return LTVBucketMedium
+	default:
+		return LTVBucketHigh
+	}
+}
+
+// PaywallRuleConditions are the predicates a user must satisfy for a rule to
+// apply. Empty lists and nil bounds match everyone; all set predicates must hold.
+type PaywallRuleConditions struct {
+	Countries           []string `json:"countries,omitempty"`
+	Platforms           []string `json:"platforms,omitempty"`
+	LTVBuckets          []string `json:"ltv_buckets,omitempty"`
+	MinChurnRisk        *float64 `json:"min_churn_risk,omitempty"`
+	MaxChurnRisk        *float64 `json:"max_churn_risk,omitempty"`
+	MinDaysSinceInstall *int     `json:"min_days_since_install,omitempty"`
+	MaxDaysSinceInstall *int     `json:"max_days_since_install,omitempty"`
+	// AppVersionRange is a semver constraint such as ">=3.2.0"; users on
+	// an unknown version do not match
+	AppVersionRange string `json:"app_version_range,omitempty"`
+	// SegmentID restricts the rule to members of a segment
+	SegmentID *uuid.UUID `json:"segment_id,omitempty"`
+}
+
+// PaywallAudience is what a rule is evaluated against
+type PaywallAudience struct {
+	Country          string  `json:"country"`
+	Platform         string  `json:"platform"`
+	LTV              float64 `json:"ltv"`
+	ChurnRisk        float64 `json:"churn_risk"`
+	DaysSinceInstall int     `json:"days_since_install"`
+	AppVersion       string  `json:"app_version,omitempty"`
+	// Segments the user belongs to, among those referenced by the rules
+	Segments []uuid.UUID `json:"segments,omitempty"`
+}
+
+// Mismatch returns the first predicate the audience fails, or "" when every
+// predicate holds
+func (c PaywallRuleConditions) Mismatch(a PaywallAudience) string {
+	if len(c.Countries) > 0 && !containsFold(c.Countries, a.Country) {
+		return "country"
+	}
+	if len(c.Platforms) > 0 && !containsFold(c.Platforms, a.Platform) {
+		return "platform"
+	}
+	if len(c.LTVBuckets) > 0 && !containsFold(c.LTVBuckets, LTVBucketFor(a.LTV)) {
+		return "ltv_bucket"
+	}
+	if c.MinChurnRisk != nil && a.ChurnRisk < *c.MinChurnRisk {
+		return "min_churn_risk"
+	}
+	if c.MaxChurnRisk != nil && a.ChurnRisk > *c.MaxChurnRisk {
+		return "max_churn_risk"
+	}
+	if c.MinDaysSinceInstall != nil && a.DaysSinceInstall < *c.MinDaysSinceInstall {
+		return "min_days_since_install"
+	}
+	if c.MaxDaysSinceInstall != nil && a.DaysSinceInstall > *c.MaxDaysSinceInstall {
+		return "max_days_since_install"
+	}
+	if c.AppVersionRange != "" {
+		constraint, err := valueobject.ParseVersionConstraint(c.AppVersionRange)
+		if err != nil || !constraint.Allows(a.AppVersion) {
+			return "app_version"
+		}
+	}
+	if c.SegmentID != nil && !slices.Contains(a.Segments, *c.SegmentID) {
+		return "segment"
+	}
+	return ""
+}
+
+// Validate checks that bounds and buckets are well formed
+func (c PaywallRuleConditions) Validate() error {
+	for _, b := range c.LTVBuckets {
+		switch strings.ToLower(b) {
+		case LTVBucketNone, LTVBucketLow, LTVBucketMedium, LTVBucketHigh:
+		default:
+			return fmt.Errorf("unknown ltv bucket %q", b)
+		}
+	}
+	for _, r := range []*float64{c.MinChurnRisk, c.MaxChurnRisk} {
+		if r != nil && (*r < 0 || *r > 1) {
+			return fmt.Errorf("churn risk bounds must be between 0 and 1")
+		}
+	}
+	if c.MinChurnRisk != nil && c.MaxChurnRisk != nil && *c.MinChurnRisk > *c.MaxChurnRisk {
+		return fmt.Errorf("min_churn_risk exceeds max_churn_risk")
+	}
+	if c.MinDaysSinceInstall != nil && c.MaxDaysSinceInstall != nil && *c.MinDaysSinceInstall > *c.MaxDaysSinceInstall {
+		return fmt.Errorf("min_days_since_install exceeds max_days_since_install")
+	}
+	if c.AppVersionRange != "" {
+		if _, err := valueobject.ParseVersionConstraint(c.AppVersionRange); err != nil {
+			return fmt.Errorf("app_version_range: %w", err)
+		}
+	}
+	return nil
+}
+
+// PaywallRule selects what to show an audience at a placement. ExperimentID,
+// when set, hands the final choice to the bandit restricted to ArmIDs.
+type PaywallRule struct {
+	ID           uuid.UUID
+	AppID        uuid.UUID
+	Name         string
+	Placement    string // "" applies to every placement
+	Priority     int    // higher is evaluated first
+	Enabled      bool
+	Conditions   PaywallRuleConditions
+	PaywallID    *uuid.UUID
+	OfferCode    string
+	ExperimentID *uuid.UUID
+	ArmIDs       []uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// NewPaywallRule creates an enabled rule
+func NewPaywallRule(appID uuid.UUID, name, placement string, priority int) *PaywallRule {
+	now := time.Now()
+	return &PaywallRule{
+		ID:        uuid.New(),
+		AppID:     appID,
+		Name:      name,
+		Placement: placement,
+		Priority:  priority,
+		Enabled:   true,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// AppliesTo reports whether the rule covers placement
+func (r *PaywallRule) AppliesTo(placement string) bool {
+	return r.Placement == "" || strings.EqualFold(r.Placement, placement)
+}
+
+func containsFold(values []string, v string) bool {
+	return slices.ContainsFunc(values, func(s string) bool { return strings.EqualFold(s, v) })
+}
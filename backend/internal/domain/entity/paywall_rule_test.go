@@ -0,0 +1,55 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaywallRuleConditions_Mismatch(t *testing.T) {
+	minRisk, maxDays := 0.5, 7
+	conditions := PaywallRuleConditions{
+		Countries:           []string{"US", "ca"},
+		Platforms:           []string{"ios"},
+		LTVBuckets:          []string{LTVBucketNone},
+		MinChurnRisk:        &minRisk,
+		MaxDaysSinceInstall: &maxDays,
+		AppVersionRange:     ">=3.2.0 <4",
+	}
+	match := PaywallAudience{Country: "CA", Platform: "ios", ChurnRisk: 0.7, DaysSinceInstall: 3, AppVersion: "3.4.1"}
+
+	tests := []struct {
+		name   string
+		mutate func(a *PaywallAudience)
+		want   string
+	}{
+		{name: "matches", mutate: func(a *PaywallAudience) {}, want: ""},
+		{name: "country", mutate: func(a *PaywallAudience) { a.Country = "DE" }, want: "country"},
+		{name: "platform", mutate: func(a *PaywallAudience) { a.Platform = "android" }, want: "platform"},
+		{name: "ltv bucket", mutate: func(a *PaywallAudience) { a.LTV = 45 }, want: "ltv_bucket"},
+		{name: "churn risk", mutate: func(a *PaywallAudience) { a.ChurnRisk = 0.2 }, want: "min_churn_risk"},
+		{name: "days since install", mutate: func(a *PaywallAudience) { a.DaysSinceInstall = 30 }, want: "max_days_since_install"},
+		{name: "app version", mutate: func(a *PaywallAudience) { a.AppVersion = "3.1.9" }, want: "app_version"},
+		{name: "unknown app version", mutate: func(a *PaywallAudience) { a.AppVersion = "" }, want: "app_version"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := match
+			tt.mutate(&a)
+			assert.Equal(t, tt.want, conditions.Mismatch(a))
+		})
+	}
+
+	assert.Empty(t, PaywallRuleConditions{}.Mismatch(PaywallAudience{}), "empty conditions match everyone")
+}
+
+func TestPaywallRuleConditions_Validate(t *testing.T) {
+	low, high := 0.2, 0.8
+	tooHigh := 1.5
+	assert.NoError(t, PaywallRuleConditions{MinChurnRisk: &low, MaxChurnRisk: &high, LTVBuckets: []string{"High"}}.Validate())
+	assert.Error(t, PaywallRuleConditions{MinChurnRisk: &high, MaxChurnRisk: &low}.Validate())
+	assert.Error(t, PaywallRuleConditions{MaxChurnRisk: &tooHigh}.Validate())
+	assert.Error(t, PaywallRuleConditions{LTVBuckets: []string{"whale"}}.Validate())
+	assert.NoError(t, PaywallRuleConditions{AppVersionRange: "^3.2 || ~4.1.0"}.Validate())
+	assert.Error(t, PaywallRuleConditions{AppVersionRange: "latest"}.Validate())
+}
@@ -0,0 +1,104 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PendingPurchaseStatus is the lifecycle state of a deferred purchase
+type PendingPurchaseStatus string
+
+const (
+	PendingPurchaseStatusPending   PendingPurchaseStatus = "pending"
+	PendingPurchaseStatusCompleted PendingPurchaseStatus = "completed"
+	PendingPurchaseStatusDeclined  PendingPurchaseStatus = "declined"
+	PendingPurchaseStatusExpired   PendingPurchaseStatus = "expired"
+)
+
+// PendingPurchaseReason says why the store deferred the purchase
+type PendingPurchaseReason string
+
+const (
+	// PendingReasonAskToBuy is a Family Sharing purchase awaiting a parent
+	PendingReasonAskToBuy PendingPurchaseReason = "ask_to_buy"
+	// PendingReasonPaymentPending covers SCA and slow payment methods (Google
+	// Play cash / bank transfer, StoreKit deferred transactions)
+	PendingReasonPaymentPending PendingPurchaseReason = "payment_pending"
+)
+
+// How long a deferred purchase stays open. Apple expires Ask to Buy requests
+// after 24 hours; Google lets pending payments settle for up to a few days.
+const (
+	PendingPurchaseTTLApple  = 24 * time.Hour
+	PendingPurchaseTTLGoogle = 72 * time.Hour
+)
+
+// ErrPendingPurchaseOpen is returned when the user already has an open
+// pending purchase for the product on that platform
+var ErrPendingPurchaseOpen = errors.New("pending purchase already open")
+
+// ErrPendingPurchaseResolved is returned when resolving a purchase that is no
+// longer pending
+var ErrPendingPurchaseResolved = errors.New("pending purchase already resolved")
+
+// PendingPurchase is a store purchase started by the user but not yet
+// finalized. It grants nothing and is not a conversion until it completes.
+type PendingPurchase struct {
+	ID            uuid.UUID
+	AppID         uuid.UUID
+	UserID        uuid.UUID
+	Platform      string
+	ProductID     string
+	Reason        PendingPurchaseReason
+	Status        PendingPurchaseStatus
+	PurchaseToken string
+	DeviceToken   string
+	ProviderTxID  string
+	CreatedAt     time.Time
+	ExpiresAt     time.Time
+	ResolvedAt    *time.Time
+}
+
+// NewPendingPurchase creates an open pending purchase expiring after the
+// platform's deferral window
+func NewPendingPurchase(appID, userID uuid.UUID, platform, productID string, reason PendingPurchaseReason, now time.Time) *PendingPurchase {
+	ttl := PendingPurchaseTTLApple
+	if platform == "android" {
+		ttl = PendingPurchaseTTLGoogle
+	}
+	return &PendingPurchase{
+		ID:        uuid.New(),
+		AppID:     appID,
+		UserID:    userID,
+		Platform:  platform,
+		ProductID: productID,
+		Reason:    reason,
+		Status:    PendingPurchaseStatusPending,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+}
+
+// IsPending returns true while the purchase awaits approval or payment
+func (p *PendingPurchase) IsPending() bool {
+	return p.Status == PendingPurchaseStatusPending
+}
+
+// Resolve moves a pending purchase to a final status. providerTxID is the
+// store transaction of a completed purchase.
+func (p *PendingPurchase) Resolve(status PendingPurchaseStatus, providerTxID string, at time.Time) error {
+	if !p.IsPending() {
+		return ErrPendingPurchaseResolved
+	}
+	switch status {
+	case PendingPurchaseStatusCompleted, PendingPurchaseStatusDeclined, PendingPurchaseStatusExpired:
+	default:
+		return errors.New("pending purchase can only resolve to completed, declined or expired")
+	}
+	p.Status = status
+	p.ProviderTxID = providerTxID
+	p.ResolvedAt = &at
+	return nil
+}
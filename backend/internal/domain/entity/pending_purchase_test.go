@@ -0,0 +1,34 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPendingPurchase_ExpiresAfterPlatformWindow(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	ios := NewPendingPurchase(uuid.New(), uuid.New(), "ios", "com.app.annual", PendingReasonAskToBuy, now)
+	android := NewPendingPurchase(uuid.New(), uuid.New(), "android", "com.app.annual", PendingReasonPaymentPending, now)
+
+	assert.Equal(t, now.Add(24*time.Hour), ios.ExpiresAt)
+	assert.Equal(t, now.Add(72*time.Hour), android.ExpiresAt)
+	assert.True(t, ios.IsPending())
+}
+
+func TestPendingPurchase_ResolveOnlyOnce(t *testing.T) {
+	now := time.Now()
+	p := NewPendingPurchase(uuid.New(), uuid.New(), "ios", "com.app.monthly", PendingReasonAskToBuy, now)
+
+	require.Error(t, p.Resolve(PendingPurchaseStatusPending, "", now))
+	require.NoError(t, p.Resolve(PendingPurchaseStatusCompleted, "1000000123", now))
+	assert.Equal(t, PendingPurchaseStatusCompleted, p.Status)
+	assert.Equal(t, "1000000123", p.ProviderTxID)
+	require.NotNil(t, p.ResolvedAt)
+
+	assert.ErrorIs(t, p.Resolve(PendingPurchaseStatusDeclined, "", now), ErrPendingPurchaseResolved)
+}
@@ -0,0 +1,115 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PriceIncreaseConsentStatus is where a subscriber stands on a store price increase
+type PriceIncreaseConsentStatus string
+
+const (
+	// PriceIncreasePending awaits the subscriber's consent; the subscription
+	// expires at the end of the period without it
+	PriceIncreasePending PriceIncreaseConsentStatus = "pending"
+	// PriceIncreaseAccepted renews at the new price
+	PriceIncreaseAccepted PriceIncreaseConsentStatus = "accepted"
+	// PriceIncreaseDeclined expired without the subscriber consenting
+	PriceIncreaseDeclined PriceIncreaseConsentStatus = "declined"
+)
+
+// IsValid reports whether s is a known status
+func (s PriceIncreaseConsentStatus) IsValid() bool {
+	switch s {
+	case PriceIncreasePending, PriceIncreaseAccepted, PriceIncreaseDeclined:
+		return true
+	}
+	return false
+}
+
+// ErrPriceIncreaseOpen is returned when the subscription already has a
+// pending price increase
+var ErrPriceIncreaseOpen = errors.New("price increase already pending")
+
+// ErrPriceIncreaseAnswered is returned when answering a price increase that
+// is no longer pending
+var ErrPriceIncreaseAnswered = errors.New("price increase already answered")
+
+// PriceIncreaseConsent tracks one store price increase of a subscription from
+// the first store notice until the subscriber consents or expires
+type PriceIncreaseConsent struct {
+	ID             uuid.UUID
+	AppID          uuid.UUID
+	SubscriptionID uuid.UUID
+	UserID         uuid.UUID
+	Platform       string
+	ProductID      string
+	Status         PriceIncreaseConsentStatus
+	// NewPrice and Currency are the renewal price, when the store reports it
+	NewPrice       *float64
+	Currency       string
+	NotifiedAt     time.Time
+	RespondedAt    *time.Time
+	PromptCount    int
+	LastPromptedAt *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// NewPriceIncreaseConsent creates a price increase the store reported at now
+// with the given status
+func NewPriceIncreaseConsent(appID, subscriptionID, userID uuid.UUID, platform, productID string, status PriceIncreaseConsentStatus, now time.Time) *PriceIncreaseConsent {
+	c := &PriceIncreaseConsent{
+		ID:             uuid.New(),
+		AppID:          appID,
+		SubscriptionID: subscriptionID,
+		UserID:         userID,
+		Platform:       platform,
+		ProductID:      productID,
+		Status:         PriceIncreasePending,
+		NotifiedAt:     now,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if status != PriceIncreasePending {
+		_ = c.Respond(status, now)
+	}
+	return c
+}
+
+// IsPending returns true while the subscriber has not answered
+func (c *PriceIncreaseConsent) IsPending() bool {
+	return c.Status == PriceIncreasePending
+}
+
+// Respond records the subscriber consenting or expiring without consent
+func (c *PriceIncreaseConsent) Respond(status PriceIncreaseConsentStatus, at time.Time) error {
+	if !c.IsPending() {
+		return ErrPriceIncreaseAnswered
+	}
+	if status != PriceIncreaseAccepted && status != PriceIncreaseDeclined {
+		return errors.New("price increase can only be accepted or declined")
+	}
+	c.Status = status
+	c.RespondedAt = &at
+	c.UpdatedAt = at
+	return nil
+}
+
+// RecordPrompt counts a consent prompt sent at now
+func (c *PriceIncreaseConsent) RecordPrompt(now time.Time) {
+	c.PromptCount++
+	c.LastPromptedAt = &now
+	c.UpdatedAt = now
+}
+
+// PromptDue reports whether a pending subscriber should be prompted again:
+// fewer than maxPrompts were sent and the last one is at least interval old
+func (c *PriceIncreaseConsent) PromptDue(now time.Time, interval time.Duration, maxPrompts int) bool {
+	if !c.IsPending() || c.PromptCount >= maxPrompts {
+		return false
+	}
+	return c.LastPromptedAt == nil || !now.Before(c.LastPromptedAt.Add(interval))
+}
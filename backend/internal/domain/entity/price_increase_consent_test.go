@@ -0,0 +1,53 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPriceIncreaseConsent_AnsweredOnArrival(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	pending := NewPriceIncreaseConsent(uuid.New(), uuid.New(), uuid.New(), "ios", "com.app.annual", PriceIncreasePending, now)
+	accepted := NewPriceIncreaseConsent(uuid.New(), uuid.New(), uuid.New(), "ios", "com.app.annual", PriceIncreaseAccepted, now)
+
+	assert.True(t, pending.IsPending())
+	assert.Nil(t, pending.RespondedAt)
+	assert.Equal(t, PriceIncreaseAccepted, accepted.Status)
+	require.NotNil(t, accepted.RespondedAt)
+	assert.Equal(t, now, *accepted.RespondedAt)
+}
+
+func TestPriceIncreaseConsent_RespondOnlyOnce(t *testing.T) {
+	now := time.Now()
+	c := NewPriceIncreaseConsent(uuid.New(), uuid.New(), uuid.New(), "ios", "com.app.monthly", PriceIncreasePending, now)
+
+	require.Error(t, c.Respond(PriceIncreasePending, now))
+	require.NoError(t, c.Respond(PriceIncreaseDeclined, now))
+	assert.Equal(t, PriceIncreaseDeclined, c.Status)
+
+	assert.ErrorIs(t, c.Respond(PriceIncreaseAccepted, now), ErrPriceIncreaseAnswered)
+}
+
+func TestPriceIncreaseConsent_PromptDue(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	c := NewPriceIncreaseConsent(uuid.New(), uuid.New(), uuid.New(), "ios", "com.app.monthly", PriceIncreasePending, now)
+	interval := 72 * time.Hour
+
+	assert.True(t, c.PromptDue(now, interval, 2), "never prompted")
+
+	c.RecordPrompt(now)
+	assert.Equal(t, 1, c.PromptCount)
+	assert.False(t, c.PromptDue(now.Add(71*time.Hour), interval, 2))
+	assert.True(t, c.PromptDue(now.Add(interval), interval, 2))
+
+	c.RecordPrompt(now.Add(interval))
+	assert.False(t, c.PromptDue(now.Add(10*interval), interval, 2), "out of prompts")
+
+	answered := NewPriceIncreaseConsent(uuid.New(), uuid.New(), uuid.New(), "ios", "com.app.monthly", PriceIncreaseAccepted, now)
+	assert.False(t, answered.PromptDue(now, interval, 2))
+}
@@ -0,0 +1,202 @@
+package entity
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PriceRolloutStatus is the lifecycle state of a price rollout
+type PriceRolloutStatus string
+
+const (
+	// PriceRolloutActive ramps according to the stage schedule
+	PriceRolloutActive PriceRolloutStatus = "active"
+	// PriceRolloutPaused keeps the cohort that already sees the new price but
+	// stops the ramp
+	PriceRolloutPaused PriceRolloutStatus = "paused"
+	// PriceRolloutCompleted has applied the new prices to the pricing tier
+	PriceRolloutCompleted PriceRolloutStatus = "completed"
+	// PriceRolloutRolledBack returned everyone to the old prices
+	PriceRolloutRolledBack PriceRolloutStatus = "rolled_back"
+)
+
+// PriceRolloutStage raises the share of users on the new price at a point in time
+type PriceRolloutStage struct {
+	At      time.Time `json:"at"`
+	Percent int       `json:"percent"`
+}
+
+// PriceRollout schedules new prices for a pricing tier, ramped up in stages
+type PriceRollout struct {
+	ID            uuid.UUID
+	AppID         uuid.UUID
+	PricingTierID uuid.UUID
+	// Region limits the rollout to one country; empty applies everywhere
+	Region        string
+	Currency      string
+	MonthlyPrice  *float64
+	AnnualPrice   *float64
+	LifetimePrice *float64
+	Stages        []PriceRolloutStage
+	Status        PriceRolloutStatus
+	PausedAt      *time.Time
+	ExperimentID  uuid.UUID
+	HoldoutArmID  uuid.UUID
+	RampArmID     uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// NewPriceRollout creates an active rollout. The experiment and arms are
+// allocated up front so they can be created with the rollout.
+func NewPriceRollout(appID, pricingTierID uuid.UUID, region, currency string, stages []PriceRolloutStage) *PriceRollout {
+	now := time.Now()
+	return &PriceRollout{
+		ID:            uuid.New(),
+		AppID:         appID,
+		PricingTierID: pricingTierID,
+		Region:        strings.ToUpper(strings.TrimSpace(region)),
+		Currency:      strings.ToUpper(strings.TrimSpace(currency)),
+		Stages:        stages,
+		Status:        PriceRolloutActive,
+		ExperimentID:  uuid.New(),
+		HoldoutArmID:  uuid.New(),
+		RampArmID:     uuid.New(),
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+}
+
+// Validate checks prices and that stages are in time order with a
+// non-decreasing percentage in 1..100
+func (r *PriceRollout) Validate() error {
+	if len(r.Currency) != 3 {
+		return errors.New("currency must be a 3-letter ISO code")
+	}
+	if r.Region != "" && len(r.Region) != 2 {
+		return errors.New("region must be an ISO 3166-1 alpha-2 country code")
+	}
+	if r.MonthlyPrice == nil && r.AnnualPrice == nil && r.LifetimePrice == nil {
+		return errors.New("at least one price is required")
+	}
+	for _, p := range []*float64{r.MonthlyPrice, r.AnnualPrice, r.LifetimePrice} {
+		if p != nil && *p <= 0 {
+			return errors.New("prices must be greater than zero")
+		}
+	}
+	if len(r.Stages) == 0 {
+		return errors.New("at least one stage is required")
+	}
+	for i, stage := range r.Stages {
+		if stage.Percent < 1 || stage.Percent > 100 {
+			return fmt.Errorf("stage %d: percent must be between 1 and 100", i+1)
+		}
+		if i == 0 {
+			continue
+		}
+		prev := r.Stages[i-1]
+		if !stage.At.After(prev.At) {
+			return fmt.Errorf("stage %d: stages must be in time order", i+1)
+		}
+		if stage.Percent < prev.Percent {
+			return fmt.Errorf("stage %d: percent cannot decrease", i+1)
+		}
+	}
+	return nil
+}
+
+// StartsAt is when the first stage takes effect
+func (r *PriceRollout) StartsAt() time.Time {
+	return r.Stages[0].At
+}
+
+// PercentAt is the share of users on the new price at now. A paused rollout
+// holds the percentage it had when it was paused.
+func (r *PriceRollout) PercentAt(now time.Time) int {
+	switch r.Status {
+	case PriceRolloutCompleted:
+		return 100
+	case PriceRolloutRolledBack:
+		return 0
+	case PriceRolloutPaused:
+		if r.PausedAt != nil {
+			now = *r.PausedAt
+		}
+	}
+	percent := 0
+	for _, stage := range r.Stages {
+		if stage.At.After(now) {
+			break
+		}
+		percent = stage.Percent
+	}
+	return percent
+}
+
+// Pause stops the ramp at its current percentage
+func (r *PriceRollout) Pause(now time.Time) error {
+	if r.Status != PriceRolloutActive {
+		return fmt.Errorf("cannot pause a %s rollout", r.Status)
+	}
+	r.Status = PriceRolloutPaused
+	r.PausedAt = &now
+	r.UpdatedAt = now
+	return nil
+}
+
+// Resume continues the ramp; stages not yet reached when it was paused are
+// pushed back by the length of the pause
+func (r *PriceRollout) Resume(now time.Time) error {
+	if r.Status != PriceRolloutPaused {
+		return fmt.Errorf("cannot resume a %s rollout", r.Status)
+	}
+	if r.PausedAt != nil {
+		shift := now.Sub(*r.PausedAt)
+		for i := range r.Stages {
+			if r.Stages[i].At.After(*r.PausedAt) {
+				r.Stages[i].At = r.Stages[i].At.Add(shift)
+			}
+		}
+	}
+	r.Status = PriceRolloutActive
+	r.PausedAt = nil
+	r.UpdatedAt = now
+	return nil
+}
+
+// Finish ends an active or paused rollout as completed or rolled back
+func (r *PriceRollout) Finish(status PriceRolloutStatus, now time.Time) error {
+	if status != PriceRolloutCompleted && status != PriceRolloutRolledBack {
+		return fmt.Errorf("cannot finish a rollout as %s", status)
+	}
+	if r.Status != PriceRolloutActive && r.Status != PriceRolloutPaused {
+		return fmt.Errorf("rollout is already %s", r.Status)
+	}
+	r.Status = status
+	r.PausedAt = nil
+	r.UpdatedAt = now
+	return nil
+}
+
+// CoversRegion reports whether the rollout applies to a request country
+func (r *PriceRollout) CoversRegion(country string) bool {
+	return r.Region == "" || strings.EqualFold(r.Region, country)
+}
+
+// Bucket places a user in 0..99, stable per rollout, so raising the
+// percentage only ever moves users from the holdout to the ramp
+func (r *PriceRollout) Bucket(userID uuid.UUID) int {
+	sum := sha256.Sum256([]byte(r.ID.String() + ":" + userID.String()))
+	return int(binary.BigEndian.Uint64(sum[:8]) % 100)
+}
+
+// InRamp reports whether the user sees the new price at now
+func (r *PriceRollout) InRamp(userID uuid.UUID, now time.Time) bool {
+	return r.Bucket(userID) < r.PercentAt(now)
+}
@@ -0,0 +1,86 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPriceRollout(start time.Time) *PriceRollout {
+	rollout := NewPriceRollout(uuid.New(), uuid.New(), "de", "eur", []PriceRolloutStage{
+		{At: start, Percent: 10},
+		{At: start.Add(48 * time.Hour), Percent: 50},
+		{At: start.Add(96 * time.Hour), Percent: 100},
+	})
+	price := 9.99
+	rollout.MonthlyPrice = &price
+	return rollout
+}
+
+func TestPriceRollout_Validate(t *testing.T) {
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	rollout := newTestPriceRollout(start)
+	require.NoError(t, rollout.Validate())
+	assert.Equal(t, "DE", rollout.Region)
+	assert.Equal(t, "EUR", rollout.Currency)
+
+	rollout.Stages[2].Percent = 40
+	assert.ErrorContains(t, rollout.Validate(), "cannot decrease")
+
+	rollout = newTestPriceRollout(start)
+	rollout.Stages[1].At = start
+	assert.ErrorContains(t, rollout.Validate(), "time order")
+
+	rollout = newTestPriceRollout(start)
+	rollout.MonthlyPrice = nil
+	assert.ErrorContains(t, rollout.Validate(), "at least one price")
+}
+
+func TestPriceRollout_PercentFollowsStages(t *testing.T) {
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	rollout := newTestPriceRollout(start)
+
+	assert.Equal(t, 0, rollout.PercentAt(start.Add(-time.Minute)))
+	assert.Equal(t, 10, rollout.PercentAt(start))
+	assert.Equal(t, 50, rollout.PercentAt(start.Add(72*time.Hour)))
+	assert.Equal(t, 100, rollout.PercentAt(start.Add(200*time.Hour)))
+
+	require.NoError(t, rollout.Finish(PriceRolloutRolledBack, start.Add(time.Hour)))
+	assert.Equal(t, 0, rollout.PercentAt(start.Add(200*time.Hour)))
+	assert.Error(t, rollout.Finish(PriceRolloutCompleted, start.Add(2*time.Hour)))
+}
+
+func TestPriceRollout_PauseHoldsAndResumeShiftsSchedule(t *testing.T) {
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	rollout := newTestPriceRollout(start)
+
+	pausedAt := start.Add(24 * time.Hour)
+	require.NoError(t, rollout.Pause(pausedAt))
+	assert.Equal(t, 10, rollout.PercentAt(start.Add(200*time.Hour)))
+
+	require.NoError(t, rollout.Resume(pausedAt.Add(72*time.Hour)))
+	assert.Equal(t, start, rollout.Stages[0].At)
+	assert.Equal(t, start.Add(120*time.Hour), rollout.Stages[1].At)
+	assert.Equal(t, 10, rollout.PercentAt(pausedAt.Add(72*time.Hour)))
+}
+
+func TestPriceRollout_RampOnlyGrows(t *testing.T) {
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	rollout := newTestPriceRollout(start)
+
+	inRamp := 0
+	for i := 0; i < 1000; i++ {
+		user := uuid.New()
+		early := rollout.InRamp(user, start)
+		late := rollout.InRamp(user, start.Add(72*time.Hour))
+		assert.Equal(t, rollout.Bucket(user), rollout.Bucket(user))
+		if early {
+			assert.True(t, late, "users in the ramp stay there as it grows")
+			inRamp++
+		}
+	}
+	assert.InDelta(t, 100, inRamp, 40)
+}
@@ -0,0 +1,135 @@
+package entity
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReactivationSource is what a reactivation is credited to
+type ReactivationSource string
+
+const (
+	// ReactivationSourceWinback reactivations followed an accepted win-back offer
+	ReactivationSourceWinback ReactivationSource = "winback_offer"
+	// ReactivationSourceOfferCode reactivations were bought with an offer code
+	// or promotional offer
+	ReactivationSourceOfferCode ReactivationSource = "offer_code"
+	// ReactivationSourceOrganic reactivations had no campaign or offer behind them
+	ReactivationSourceOrganic ReactivationSource = "organic"
+)
+
+// ReactivationSources are every source, in report order
+var ReactivationSources = []ReactivationSource{
+	ReactivationSourceWinback, ReactivationSourceOfferCode, ReactivationSourceOrganic,
+}
+
+const (
+	// ReactivationAttributionWindow is how long before resubscribing a
+	// win-back offer may have been accepted and still be credited
+	ReactivationAttributionWindow = 30 * 24 * time.Hour
+	// ReactivationRedemptionSlack is how long after the subscription started
+	// its offer redemption may be recorded; verification stores it afterwards
+	ReactivationRedemptionSlack = time.Hour
+)
+
+// ReactivationTouch is a win-back offer, an offer redemption or an experiment
+// assignment that may have driven a reactivation
+type ReactivationTouch struct {
+	ID uuid.UUID
+	// Key is the win-back campaign ID or the offer code
+	Key string
+	// ArmID is set for experiment assignments
+	ArmID uuid.UUID
+	At    time.Time
+}
+
+// ReactivationCandidate is a subscription started after the user's previous
+// one lapsed, with the latest touch of each kind since the lapse
+type ReactivationCandidate struct {
+	SubscriptionID uuid.UUID
+	UserID         uuid.UUID
+	AppID          uuid.UUID
+	PlanType       PlanType
+	LapsedAt       time.Time
+	ReactivatedAt  time.Time
+	WinbackOffer   *ReactivationTouch
+	Redemption     *ReactivationTouch
+	Assignment     *ReactivationTouch
+}
+
+// SubscriptionReactivation is a churned subscriber's resubscription and what
+// it is credited to
+type SubscriptionReactivation struct {
+	SubscriptionID uuid.UUID
+	UserID         uuid.UUID
+	AppID          uuid.UUID
+	PlanType       PlanType
+	// MRR is the list-price monthly value in USD
+	MRR               float64
+	LapsedAt          time.Time
+	ReactivatedAt     time.Time
+	Source            ReactivationSource
+	WinbackOfferID    *uuid.UUID
+	CampaignID        string
+	OfferRedemptionID *uuid.UUID
+	OfferCode         string
+	ExperimentID      *uuid.UUID
+	ArmID             *uuid.UUID
+}
+
+// ListPriceMRR is the plan's list-price monthly recurring revenue in USD,
+// the same prices the analytics report uses
+func ListPriceMRR(plan PlanType) float64 {
+	switch plan {
+	case PlanMonthly:
+		return 9.99
+	case PlanAnnual:
+		return math.Round(99.99/12*100) / 100
+	}
+	return 0
+}
+
+// AttributeReactivation credits a reactivation to the win-back offer accepted
+// within ReactivationAttributionWindow before it, else to the offer code it
+// was bought with, else to nobody. An experiment assignment made after the
+// lapse is recorded whatever the source, so win-back experiments can compare
+// their arms.
+func AttributeReactivation(c ReactivationCandidate) SubscriptionReactivation {
+	r := SubscriptionReactivation{
+		SubscriptionID: c.SubscriptionID,
+		UserID:         c.UserID,
+		AppID:          c.AppID,
+		PlanType:       c.PlanType,
+		MRR:            ListPriceMRR(c.PlanType),
+		LapsedAt:       c.LapsedAt,
+		ReactivatedAt:  c.ReactivatedAt,
+		Source:         ReactivationSourceOrganic,
+	}
+	switch {
+	case c.touchedBy(c.WinbackOffer, ReactivationAttributionWindow, 0):
+		r.Source = ReactivationSourceWinback
+		id := c.WinbackOffer.ID
+		r.WinbackOfferID, r.CampaignID = &id, c.WinbackOffer.Key
+	case c.touchedBy(c.Redemption, 0, ReactivationRedemptionSlack):
+		r.Source = ReactivationSourceOfferCode
+		id := c.Redemption.ID
+		r.OfferRedemptionID, r.OfferCode = &id, c.Redemption.Key
+	}
+	if c.touchedBy(c.Assignment, 0, 0) {
+		experimentID, armID := c.Assignment.ID, c.Assignment.ArmID
+		r.ExperimentID, r.ArmID = &experimentID, &armID
+	}
+	return r
+}
+
+// touchedBy reports whether t happened after the lapse, no earlier than
+// window before the reactivation (0 for any time since the lapse) and no
+// later than slack after it
+func (c ReactivationCandidate) touchedBy(t *ReactivationTouch, window, slack time.Duration) bool {
+	if t == nil || !t.At.After(c.LapsedAt) || t.At.After(c.ReactivatedAt.Add(slack)) {
+		return false
+	}
+	return window == 0 || !t.At.Before(c.ReactivatedAt.Add(-window))
+}
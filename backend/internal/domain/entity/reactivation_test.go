@@ -0,0 +1,54 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttributeReactivation(t *testing.T) {
+	lapsed := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	back := lapsed.Add(60 * 24 * time.Hour)
+	offer := &ReactivationTouch{ID: uuid.New(), Key: "spring-winback", At: back.Add(-2 * 24 * time.Hour)}
+	code := &ReactivationTouch{ID: uuid.New(), Key: "COMEBACK50", At: back.Add(time.Minute)}
+	assignment := &ReactivationTouch{ID: uuid.New(), ArmID: uuid.New(), At: back.Add(-3 * 24 * time.Hour)}
+	base := ReactivationCandidate{SubscriptionID: uuid.New(), PlanType: PlanMonthly, LapsedAt: lapsed, ReactivatedAt: back}
+
+	t.Run("win-back offer wins over the offer code", func(t *testing.T) {
+		c := base
+		c.WinbackOffer, c.Redemption, c.Assignment = offer, code, assignment
+		r := AttributeReactivation(c)
+		assert.Equal(t, ReactivationSourceWinback, r.Source)
+		require.NotNil(t, r.WinbackOfferID)
+		assert.Equal(t, offer.ID, *r.WinbackOfferID)
+		assert.Equal(t, "spring-winback", r.CampaignID)
+		assert.Nil(t, r.OfferRedemptionID)
+		require.NotNil(t, r.ArmID)
+		assert.Equal(t, assignment.ArmID, *r.ArmID)
+		assert.Equal(t, 9.99, r.MRR)
+	})
+
+	t.Run("stale offer falls back to the offer code", func(t *testing.T) {
+		c := base
+		c.WinbackOffer = &ReactivationTouch{ID: uuid.New(), Key: "old", At: back.Add(-ReactivationAttributionWindow - time.Hour)}
+		c.Redemption = code
+		r := AttributeReactivation(c)
+		assert.Equal(t, ReactivationSourceOfferCode, r.Source)
+		assert.Equal(t, "COMEBACK50", r.OfferCode)
+		assert.Empty(t, r.CampaignID)
+	})
+
+	t.Run("touches before the lapse are organic", func(t *testing.T) {
+		c := base
+		c.PlanType = PlanAnnual
+		c.WinbackOffer = &ReactivationTouch{ID: uuid.New(), Key: "early", At: lapsed.Add(-time.Hour)}
+		c.Assignment = &ReactivationTouch{ID: uuid.New(), ArmID: uuid.New(), At: lapsed}
+		r := AttributeReactivation(c)
+		assert.Equal(t, ReactivationSourceOrganic, r.Source)
+		assert.Nil(t, r.ExperimentID)
+		assert.Equal(t, 8.33, r.MRR)
+	})
+}
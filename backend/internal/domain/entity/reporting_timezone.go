@@ -0,0 +1,54 @@
+package entity
+
+import (
+	"fmt"
+	"time"
+	// Reporting zones must resolve in minimal images without /usr/share/zoneinfo
+	_ "time/tzdata"
+)
+
+// DefaultReportingTimezone is used by apps that have not configured one
+const DefaultReportingTimezone = "UTC"
+
+// ValidateReportingTimezone checks that name is an IANA time zone such as
+// "America/New_York"
+func ValidateReportingTimezone(name string) error {
+	if name == "" || name == "Local" {
+		return fmt.Errorf("reporting_timezone must be an IANA time zone name")
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return fmt.Errorf("unknown reporting_timezone %q", name)
+	}
+	return nil
+}
+
+// ReportingTimezoneName is the app's reporting time zone, UTC when unset
+func (s *AppSettings) ReportingTimezoneName() string {
+	if s == nil || s.ReportingTimezone == "" {
+		return DefaultReportingTimezone
+	}
+	return s.ReportingTimezone
+}
+
+// ReportingLocation is the zone the app's business day is reported in.
+// An invalid stored name falls back to UTC.
+func (s *AppSettings) ReportingLocation() *time.Location {
+	loc, err := time.LoadLocation(s.ReportingTimezoneName())
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// ReportingDay returns the bounds of the local day in loc containing t. Days
+// on which DST changes are 23 or 25 hours long.
+func ReportingDay(t time.Time, loc *time.Location) (start, end time.Time) {
+	local := t.In(loc)
+	start = time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	return start, start.AddDate(0, 0, 1)
+}
+
+// ParseReportingDate parses a YYYY-MM-DD date as the start of that day in loc
+func ParseReportingDate(date string, loc *time.Location) (time.Time, error) {
+	return time.ParseInLocation("2006-01-02", date, loc)
+}
@@ -0,0 +1,40 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportingDay_UsesLocalMidnight(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	// 02:00 UTC on the 14th is still the evening of the 13th in New York
+	start, end := ReportingDay(time.Date(2026, 10, 14, 2, 0, 0, 0, time.UTC), ny)
+
+	assert.Equal(t, time.Date(2026, 10, 13, 4, 0, 0, 0, time.UTC), start.UTC())
+	assert.Equal(t, time.Date(2026, 10, 14, 4, 0, 0, 0, time.UTC), end.UTC())
+}
+
+func TestReportingDay_DSTChangeIs25Hours(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	start, end := ReportingDay(time.Date(2026, 11, 1, 12, 0, 0, 0, ny), ny)
+
+	assert.Equal(t, 25*time.Hour, end.Sub(start))
+}
+
+func TestAppSettings_ReportingLocation(t *testing.T) {
+	var unset *AppSettings
+	assert.Equal(t, time.UTC, unset.ReportingLocation())
+	assert.Equal(t, time.UTC, (&AppSettings{ReportingTimezone: "Mars/Olympus"}).ReportingLocation())
+	assert.Equal(t, "Asia/Tokyo", (&AppSettings{ReportingTimezone: "Asia/Tokyo"}).ReportingLocation().String())
+
+	assert.NoError(t, ValidateReportingTimezone("Europe/Berlin"))
+	assert.Error(t, ValidateReportingTimezone("Local"))
+	assert.Error(t, ValidateReportingTimezone("Mars/Olympus"))
+}
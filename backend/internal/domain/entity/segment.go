@@ -0,0 +1,332 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Segment attribute names usable in predicates
+const (
+	SegmentFieldCountry               = "country" // only known at request time
+	SegmentFieldPlatform              = "platform"
+	SegmentFieldAppVersion            = "app_version"
+	SegmentFieldPurchaseChannel       = "purchase_channel"
+	SegmentFieldLTV                   = "ltv"
+	SegmentFieldLTVBucket             = "ltv_bucket"
+	SegmentFieldChurnRisk             = "churn_risk"
+	SegmentFieldDaysSinceInstall      = "days_since_install"
+	SegmentFieldSessionCount          = "session_count"
+	SegmentFieldHasActiveSubscription = "has_active_subscription"
+	SegmentFieldSubscriptionStatus    = "subscription_status"
+	SegmentFieldSubscriptionPlan      = "subscription_plan"
+	SegmentFieldSubscriptionProductID = "subscription_product_id"
+	SegmentFieldAutoRenew             = "auto_renew"
+	SegmentFieldLifecycleStage        = "lifecycle_stage"
+)
+
+type segmentFieldKind int
+
+const (
+	segmentString segmentFieldKind = iota
+	segmentNumber
+	segmentBool
+)
+
+var segmentFields = map[string]segmentFieldKind{
+	SegmentFieldCountry:               segmentString,
+	SegmentFieldPlatform:              segmentString,
+	SegmentFieldAppVersion:            segmentString,
+	SegmentFieldPurchaseChannel:       segmentString,
+	SegmentFieldLTV:                   segmentNumber,
+	SegmentFieldLTVBucket:             segmentString,
+	SegmentFieldChurnRisk:             segmentNumber,
+	SegmentFieldDaysSinceInstall:      segmentNumber,
+	SegmentFieldSessionCount:          segmentNumber,
+	SegmentFieldHasActiveSubscription: segmentBool,
+	SegmentFieldSubscriptionStatus:    segmentString,
+	SegmentFieldSubscriptionPlan:      segmentString,
+	SegmentFieldSubscriptionProductID: segmentString,
+	SegmentFieldAutoRenew:             segmentBool,
+	SegmentFieldLifecycleStage:        segmentString,
+}
+
+// maxSegmentDepth bounds nesting so definitions stay cheap to evaluate
+const maxSegmentDepth = 8
+
+// SegmentAttributes are the values a predicate is evaluated against, keyed by
+// field name. A missing key means the value is unknown for the user.
+type SegmentAttributes map[string]any
+
+// SegmentPredicate is a node of the segment DSL. Exactly one of All, Any, Not
+// or Field is set:
+//
+//	{"all": [...]}                                    every child matches
+//	{"any": [...]}                                    at least one child matches
+//	{"not": {...}}                                    the child does not match
+//	{"field": "ltv", "op": "gte", "value": 20}       compare an attribute
+//
+// Ops are eq, neq, in, not_in, gt, gte, lt, lte and exists. String comparisons
+// ignore case. A comparison on an unknown attribute never matches.
+type SegmentPredicate struct {
+	All   []SegmentPredicate `json:"all,omitempty"`
+	Any   []SegmentPredicate `json:"any,omitempty"`
+	Not   *SegmentPredicate  `json:"not,omitempty"`
+	Field string             `json:"field,omitempty"`
+	Op    string             `json:"op,omitempty"`
+	Value any                `json:"value,omitempty"`
+}
+
+// Matches evaluates the predicate
+func (p SegmentPredicate) Matches(attrs SegmentAttributes) bool {
+	switch {
+	case p.All != nil:
+		for _, child := range p.All {
+			if !child.Matches(attrs) {
+				return false
+			}
+		}
+		return true
+	case p.Any != nil:
+		for _, child := range p.Any {
+			if child.Matches(attrs) {
+				return true
+			}
+		}
+		return false
+	case p.Not != nil:
+		return !p.Not.Matches(attrs)
+	}
+
+	actual, ok := attrs[p.Field]
+	if p.Op == "exists" {
+		return ok
+	}
+	if !ok {
+		return false
+	}
+	switch p.Op {
+	case "eq":
+		return segmentEqual(actual, p.Value)
+	case "neq":
+		return !segmentEqual(actual, p.Value)
+	case "in", "not_in":
+		values, _ := p.Value.([]any)
+		found := false
+		for _, v := range values {
+			if segmentEqual(actual, v) {
+				found = true
+				break
+			}
+		}
+		return found == (p.Op == "in")
+	case "gt", "gte", "lt", "lte":
+		a, aok := segmentNumberValue(actual)
+		b, bok := segmentNumberValue(p.Value)
+		if !aok || !bok {
+			return false
+		}
+		switch p.Op {
+		case "gt":
+			return a > b
+		case "gte":
+			return a >= b
+		case "lt":
+			return a < b
+		default:
+			return a <= b
+		}
+	}
+	return false
+}
+
+// Fields lists the attributes the predicate reads
+func (p SegmentPredicate) Fields() []string {
+	var fields []string
+	p.walk(func(n SegmentPredicate) {
+		if n.Field != "" {
+			fields = append(fields, n.Field)
+		}
+	})
+	return fields
+}
+
+func (p SegmentPredicate) walk(visit func(SegmentPredicate)) {
+	visit(p)
+	for _, child := range p.All {
+		child.walk(visit)
+	}
+	for _, child := range p.Any {
+		child.walk(visit)
+	}
+	if p.Not != nil {
+		p.Not.walk(visit)
+	}
+}
+
+// Validate checks the predicate is well formed: one node kind per level,
+// known fields and ops, and values of the field's type
+func (p SegmentPredicate) Validate() error {
+	return p.validate(1)
+}
+
+func (p SegmentPredicate) validate(depth int) error {
+	if depth > maxSegmentDepth {
+		return fmt.Errorf("segment definition nests deeper than %d levels", maxSegmentDepth)
+	}
+	kinds := 0
+	for _, set := range []bool{p.All != nil, p.Any != nil, p.Not != nil, p.Field != ""} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return errors.New("each segment node needs exactly one of all, any, not or field")
+	}
+
+	switch {
+	case p.All != nil || p.Any != nil:
+		children := p.All
+		if p.Any != nil {
+			children = p.Any
+		}
+		if len(children) == 0 {
+			return errors.New("all / any need at least one condition")
+		}
+		for _, child := range children {
+			if err := child.validate(depth + 1); err != nil {
+				return err
+			}
+		}
+		return nil
+	case p.Not != nil:
+		return p.Not.validate(depth + 1)
+	}
+
+	kind, ok := segmentFields[p.Field]
+	if !ok {
+		return fmt.Errorf("unknown segment field %q", p.Field)
+	}
+	switch p.Op {
+	case "exists":
+		return nil
+	case "eq", "neq":
+		if !segmentValueOfKind(p.Value, kind) {
+			return fmt.Errorf("%s %s needs a %s value", p.Field, p.Op, kindName(kind))
+		}
+	case "in", "not_in":
+		values, ok := p.Value.([]any)
+		if !ok || len(values) == 0 {
+			return fmt.Errorf("%s %s needs a non-empty list", p.Field, p.Op)
+		}
+		for _, v := range values {
+			if !segmentValueOfKind(v, kind) {
+				return fmt.Errorf("%s %s needs %s values", p.Field, p.Op, kindName(kind))
+			}
+		}
+	case "gt", "gte", "lt", "lte":
+		if kind != segmentNumber || !segmentValueOfKind(p.Value, segmentNumber) {
+			return fmt.Errorf("%s only applies to numeric fields with a numeric value", p.Op)
+		}
+	default:
+		return fmt.Errorf("unknown segment op %q", p.Op)
+	}
+	return nil
+}
+
+// Segment is a reusable audience definition
+type Segment struct {
+	ID          uuid.UUID
+	AppID       uuid.UUID
+	Name        string
+	Description string
+	Definition  SegmentPredicate
+	// Materialized segments read membership from the last worker run
+	// instead of evaluating the definition per request
+	Materialized   bool
+	MemberCount    int
+	MaterializedAt *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// NewSegment creates a real-time segment
+func NewSegment(appID uuid.UUID, name string, definition SegmentPredicate) *Segment {
+	now := time.Now()
+	return &Segment{
+		ID:         uuid.New(),
+		AppID:      appID,
+		Name:       name,
+		Definition: definition,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+}
+
+// Validate checks the definition and that materialized segments only use
+// attributes known outside a request
+func (s *Segment) Validate() error {
+	if err := s.Definition.Validate(); err != nil {
+		return err
+	}
+	if s.Materialized {
+		for _, f := range s.Definition.Fields() {
+			if f == SegmentFieldCountry {
+				return errors.New("country is only known at request time; use a real-time segment")
+			}
+		}
+	}
+	return nil
+}
+
+func segmentEqual(actual, expected any) bool {
+	switch a := actual.(type) {
+	case string:
+		e, ok := expected.(string)
+		return ok && strings.EqualFold(a, e)
+	case bool:
+		e, ok := expected.(bool)
+		return ok && a == e
+	}
+	a, aok := segmentNumberValue(actual)
+	e, eok := segmentNumberValue(expected)
+	return aok && eok && a == e
+}
+
+func segmentNumberValue(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+func segmentValueOfKind(v any, kind segmentFieldKind) bool {
+	switch kind {
+	case segmentString:
+		_, ok := v.(string)
+		return ok
+	case segmentBool:
+		_, ok := v.(bool)
+		return ok
+	default:
+		_, ok := segmentNumberValue(v)
+		return ok
+	}
+}
+
+func kindName(kind segmentFieldKind) string {
+	switch kind {
+	case segmentString:
+		return "string"
+	case segmentBool:
+		return "boolean"
+	default:
+		return "numeric"
+	}
+}
@@ -0,0 +1,70 @@
+package entity
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeSegment(t *testing.T, raw string) SegmentPredicate {
+	t.Helper()
+	var p SegmentPredicate
+	require.NoError(t, json.Unmarshal([]byte(raw), &p))
+	return p
+}
+
+func TestSegmentPredicate_Matches(t *testing.T) {
+	p := decodeSegment(t, `{"all":[
+		{"field":"platform","op":"in","value":["iOS","android"]},
+		{"any":[
+			{"field":"ltv","op":"gte","value":20},
+			{"field":"has_active_subscription","op":"eq","value":true}
+		]},
+		{"not":{"field":"subscription_status","op":"eq","value":"grace"}}
+	]}`)
+	require.NoError(t, p.Validate())
+
+	tests := []struct {
+		name  string
+		attrs SegmentAttributes
+		want  bool
+	}{
+		{name: "high ltv", attrs: SegmentAttributes{"platform": "ios", "ltv": 25.0, "has_active_subscription": false}, want: true},
+		{name: "subscriber", attrs: SegmentAttributes{"platform": "android", "ltv": 0.0, "has_active_subscription": true}, want: true},
+		{name: "wrong platform", attrs: SegmentAttributes{"platform": "web", "ltv": 25.0}, want: false},
+		{name: "in grace", attrs: SegmentAttributes{"platform": "ios", "ltv": 25.0, "subscription_status": "grace"}, want: false},
+		{name: "unknown attribute never compares", attrs: SegmentAttributes{"platform": "ios"}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, p.Matches(tt.attrs))
+		})
+	}
+}
+
+func TestSegmentPredicate_Validate(t *testing.T) {
+	invalid := map[string]string{
+		"two kinds in one node": `{"field":"ltv","op":"gt","value":1,"all":[{"field":"ltv","op":"gt","value":1}]}`,
+		"unknown field":         `{"field":"favourite_colour","op":"eq","value":"red"}`,
+		"unknown op":            `{"field":"ltv","op":"between","value":1}`,
+		"wrong value type":      `{"field":"ltv","op":"eq","value":"high"}`,
+		"range on a string":     `{"field":"platform","op":"gt","value":1}`,
+		"empty list":            `{"field":"country","op":"in","value":[]}`,
+		"empty all":             `{"all":[]}`,
+	}
+	for name, raw := range invalid {
+		t.Run(name, func(t *testing.T) {
+			assert.Error(t, decodeSegment(t, raw).Validate())
+		})
+	}
+}
+
+func TestSegment_MaterializedCannotUseCountry(t *testing.T) {
+	s := &Segment{Definition: decodeSegment(t, `{"field":"country","op":"eq","value":"US"}`)}
+	assert.NoError(t, s.Validate())
+
+	s.Materialized = true
+	assert.Error(t, s.Validate())
+}
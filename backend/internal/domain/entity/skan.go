@@ -0,0 +1,137 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SKANMaxConversionValue is the largest fine conversion value SKAdNetwork
+// accepts (6 bits)
+const SKANMaxConversionValue = 63
+
+// SKANCoarseValue is the coarse conversion value of SKAdNetwork 4 postbacks
+// with low crowd anonymity
+type SKANCoarseValue string
+
+const (
+	SKANCoarseLow    SKANCoarseValue = "low"
+	SKANCoarseMedium SKANCoarseValue = "medium"
+	SKANCoarseHigh   SKANCoarseValue = "high"
+)
+
+// ErrInvalidSKANSchema is returned for conversion value schemas that cannot
+// be decoded unambiguously
+var ErrInvalidSKANSchema = errors.New("invalid SKAdNetwork conversion schema")
+
+// SKANValueBucket maps users who paid at least MinRevenue in their first 24
+// hours to a conversion value
+type SKANValueBucket struct {
+	Value      int             `json:"value"`
+	MinRevenue float64         `json:"min_revenue"`
+	Coarse     SKANCoarseValue `json:"coarse"`
+}
+
+// SKANConversionSchema is one version of an app's conversion value mapping.
+// Apps set the value of the highest bucket they reach; postbacks are decoded
+// with the version that was current at install.
+type SKANConversionSchema struct {
+	ID        uuid.UUID         `json:"id"`
+	AppID     uuid.UUID         `json:"app_id"`
+	Version   int               `json:"version"`
+	Buckets   []SKANValueBucket `json:"buckets"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// Validate checks the buckets and sorts them by value. Values must be unique
+// and revenue thresholds must rise with the value, starting at 0.
+func (s *SKANConversionSchema) Validate() error {
+	if len(s.Buckets) == 0 {
+		return fmt.Errorf("%w: at least one bucket is required", ErrInvalidSKANSchema)
+	}
+	sort.Slice(s.Buckets, func(i, j int) bool { return s.Buckets[i].Value < s.Buckets[j].Value })
+	for i, b := range s.Buckets {
+		if b.Value < 0 || b.Value > SKANMaxConversionValue {
+			return fmt.Errorf("%w: value %d is outside 0-%d", ErrInvalidSKANSchema, b.Value, SKANMaxConversionValue)
+		}
+		switch b.Coarse {
+		case SKANCoarseLow, SKANCoarseMedium, SKANCoarseHigh:
+		default:
+			return fmt.Errorf("%w: value %d has coarse value %q, want low, medium or high", ErrInvalidSKANSchema, b.Value, b.Coarse)
+		}
+		if i == 0 {
+			if b.MinRevenue != 0 {
+				return fmt.Errorf("%w: the lowest bucket must start at 0 revenue", ErrInvalidSKANSchema)
+			}
+			continue
+		}
+		prev := s.Buckets[i-1]
+		if b.Value == prev.Value {
+			return fmt.Errorf("%w: value %d is used twice", ErrInvalidSKANSchema, b.Value)
+		}
+		if b.MinRevenue <= prev.MinRevenue {
+			return fmt.Errorf("%w: min_revenue must rise with the value (value %d)", ErrInvalidSKANSchema, b.Value)
+		}
+	}
+	return nil
+}
+
+// Bucket returns the highest bucket reached with revenue. Buckets must be
+// validated.
+func (s *SKANConversionSchema) Bucket(revenue float64) SKANValueBucket {
+	bucket := s.Buckets[0]
+	for _, b := range s.Buckets[1:] {
+		if revenue >= b.MinRevenue {
+			bucket = b
+		}
+	}
+	return bucket
+}
+
+// SKANPostback is an install postback from Apple or an ad network
+type SKANPostback struct {
+	ID                    uuid.UUID
+	AppID                 uuid.UUID
+	TransactionID         string
+	Version               string
+	AdNetworkID           string
+	SourceIdentifier      string
+	SourceAppID           string
+	SourceDomain          string
+	FidelityType          *int
+	Redownload            bool
+	DidWin                bool
+	PostbackSequenceIndex int
+	ConversionValue       *int
+	CoarseConversionValue SKANCoarseValue
+	SignatureVerified     bool
+	ReceivedAt            time.Time
+	ProcessedAt           *time.Time
+}
+
+// InstallWindow is when the install behind a first postback happened. The
+// postback follows the install by its conversion window plus Apple's random
+// delay: 24h + up to 24h before SKAdNetwork 4, and up to 2 days + up to 48h
+// from 4 on. Apps that keep updating the value under SKAN 3 restart the
+// timer and fall outside the window.
+func (p *SKANPostback) InstallWindow() (from, to time.Time) {
+	latest := 72 * time.Hour
+	if p.Version >= "4" {
+		latest = 96 * time.Hour
+	}
+	return p.ReceivedAt.Add(-latest), p.ReceivedAt.Add(-24 * time.Hour)
+}
+
+// InstallAttribution is a signup matched to the campaign of a postback
+type InstallAttribution struct {
+	UserID           uuid.UUID
+	AppID            uuid.UUID
+	PostbackID       uuid.UUID
+	AdNetworkID      string
+	SourceIdentifier string
+	Confidence       float64
+	AttributedAt     time.Time
+}
@@ -0,0 +1,37 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSKANConversionSchema_ValidateSortsAndChecksThresholds(t *testing.T) {
+	schema := &SKANConversionSchema{Buckets: []SKANValueBucket{
+		{Value: 20, MinRevenue: 9.99, Coarse: SKANCoarseHigh},
+		{Value: 0, MinRevenue: 0, Coarse: SKANCoarseLow},
+		{Value: 10, MinRevenue: 0.99, Coarse: SKANCoarseMedium},
+	}}
+	require.NoError(t, schema.Validate())
+	assert.Equal(t, []int{0, 10, 20}, []int{schema.Buckets[0].Value, schema.Buckets[1].Value, schema.Buckets[2].Value})
+
+	assert.Equal(t, 0, schema.Bucket(0.5).Value)
+	assert.Equal(t, 10, schema.Bucket(0.99).Value)
+	assert.Equal(t, SKANCoarseHigh, schema.Bucket(50).Coarse)
+}
+
+func TestSKANConversionSchema_ValidateRejectsAmbiguousBuckets(t *testing.T) {
+	cases := map[string][]SKANValueBucket{
+		"empty":              nil,
+		"out of range":       {{Value: 64, Coarse: SKANCoarseLow}},
+		"no zero bucket":     {{Value: 0, MinRevenue: 1, Coarse: SKANCoarseLow}},
+		"falling thresholds": {{Value: 0, Coarse: SKANCoarseLow}, {Value: 1, MinRevenue: 5, Coarse: SKANCoarseLow}, {Value: 2, MinRevenue: 5, Coarse: SKANCoarseHigh}},
+		"duplicate value":    {{Value: 0, Coarse: SKANCoarseLow}, {Value: 0, MinRevenue: 1, Coarse: SKANCoarseLow}},
+		"unknown coarse":     {{Value: 0, Coarse: "huge"}},
+	}
+	for name, buckets := range cases {
+		schema := &SKANConversionSchema{Buckets: buckets}
+		assert.ErrorIs(t, schema.Validate(), ErrInvalidSKANSchema, name)
+	}
+}
@@ -1,11 +1,16 @@
 package entity
 
 import (
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// ErrInvalidSubscriptionTransition is returned for a status change the
+// subscription lifecycle does not allow
+var ErrInvalidSubscriptionTransition = errors.New("invalid subscription status transition")
+
 type SubscriptionStatus string
 
 const (
@@ -21,6 +26,7 @@ const (
 	SourceIAP    SubscriptionSource = "iap"
 	SourceStripe SubscriptionSource = "stripe"
 	SourcePaddle SubscriptionSource = "paddle"
+	SourcePayPal SubscriptionSource = "paypal"
 )
 
 type PlanType string
@@ -31,8 +37,32 @@ const (
 	PlanLifetime PlanType = "lifetime"
 )
 
+// PeriodEnd returns when a billing period of the plan starting at from ends.
+// A period starting on a day the closing month lacks ends on that month's
+// last day. Lifetime plans have no period; from is returned.
+func (p PlanType) PeriodEnd(from time.Time) time.Time {
+	months := 0
+	switch p {
+	case PlanMonthly:
+		months = 1
+	case PlanAnnual:
+		months = 12
+	default:
+		return from
+	}
+	end := from.AddDate(0, months, 0)
+	if end.Day() != from.Day() {
+		// Overflowed into the following month; step back to its last day
+		end = end.AddDate(0, 0, -end.Day())
+	}
+	return end
+}
+
+// Subscription is the subscription aggregate. Store notifications change it
+// through Apply, which enforces the lifecycle rules.
 type Subscription struct {
 	ID        uuid.UUID
+	AppID     uuid.UUID
 	UserID    uuid.UUID
 	Status    SubscriptionStatus
 	Source    SubscriptionSource
@@ -65,18 +95,28 @@ func NewSubscription(userID uuid.UUID, source SubscriptionSource, platform, prod
 
 // IsActive returns true if the subscription is currently active
 func (s *Subscription) IsActive() bool {
+	return s.IsActiveAt(time.Now())
+}
+
+// IsActiveAt returns true if the subscription is active at now
+func (s *Subscription) IsActiveAt(now time.Time) bool {
 	if s.DeletedAt != nil {
 		return false
 	}
-	return s.Status == StatusActive && s.ExpiresAt.After(time.Now())
+	return s.Status == StatusActive && s.ExpiresAt.After(now)
 }
 
 // IsExpired returns true if the subscription has expired
 func (s *Subscription) IsExpired() bool {
+	return s.IsExpiredAt(time.Now())
+}
+
+// IsExpiredAt returns true if the subscription has expired at now
+func (s *Subscription) IsExpiredAt(now time.Time) bool {
 	if s.Status == StatusGrace {
 		return false
 	}
-	return s.Status == StatusExpired || s.ExpiresAt.Before(time.Now())
+	return s.Status == StatusExpired || s.ExpiresAt.Before(now)
 }
 
 // CanAccessContent returns true if user can access premium content
@@ -91,3 +131,45 @@ func (s *Subscription) CanAccessContent() bool {
 func (s *Subscription) HasGracePeriod() bool {
 	return s.Status == StatusGrace
 }
+
+// CanTransitionTo reports whether the subscription may move to status. Any
+// status may return to active, but an expired subscription cannot enter
+// grace or be cancelled, so a late billing retry or cancellation cannot
+// revive it. Deleted subscriptions never change.
+func (s *Subscription) CanTransitionTo(status SubscriptionStatus) bool {
+	if s.DeletedAt != nil {
+		return false
+	}
+	switch status {
+	case StatusActive, StatusExpired:
+		return true
+	case StatusGrace, StatusCancelled:
+		return s.Status != StatusExpired
+	default:
+		return false
+	}
+}
+
+// TransitionTo moves the subscription to status
+func (s *Subscription) TransitionTo(status SubscriptionStatus, now time.Time) error {
+	if !s.CanTransitionTo(status) {
+		return ErrInvalidSubscriptionTransition
+	}
+	if s.Status != status {
+		s.Status = status
+		s.UpdatedAt = now
+	}
+	return nil
+}
+
+// ExtendExpiry moves the expiry to expiresAt when that is later. Expiry
+// never moves backwards, so a replayed or out-of-order notification cannot
+// shorten an entitlement. It reports whether the expiry moved.
+func (s *Subscription) ExtendExpiry(expiresAt, now time.Time) bool {
+	if !expiresAt.After(s.ExpiresAt) {
+		return false
+	}
+	s.ExpiresAt = expiresAt
+	s.UpdatedAt = now
+	return true
+}
@@ -0,0 +1,108 @@
+package entity
+
+import "time"
+
+// SubscriptionEventType is a lifecycle change reported by a store, whatever
+// the store calls it
+type SubscriptionEventType string
+
+const (
+	// SubscriptionEventPurchased starts the subscription
+	SubscriptionEventPurchased SubscriptionEventType = "purchased"
+	// SubscriptionEventRenewed bills a new period, including recoveries from
+	// billing retry and restarts after a pause
+	SubscriptionEventRenewed SubscriptionEventType = "renewed"
+	// SubscriptionEventResumed makes the subscription active again without
+	// billing a new period
+	SubscriptionEventResumed SubscriptionEventType = "resumed"
+	// SubscriptionEventBillingRetry reports a failed renewal the store keeps
+	// retrying; access continues in grace
+	SubscriptionEventBillingRetry SubscriptionEventType = "billing_retry"
+	// SubscriptionEventAutoRenewOff reports the subscriber cancelled; access
+	// continues until expiry
+	SubscriptionEventAutoRenewOff SubscriptionEventType = "auto_renew_off"
+	// SubscriptionEventOnHold reports billing retry ran out and access is
+	// suspended until payment recovers
+	SubscriptionEventOnHold SubscriptionEventType = "on_hold"
+	// SubscriptionEventPaused reports the subscriber paused billing
+	SubscriptionEventPaused SubscriptionEventType = "paused"
+	// SubscriptionEventExpired ends the subscription
+	SubscriptionEventExpired SubscriptionEventType = "expired"
+	// SubscriptionEventRevoked ends the subscription early, after a refund or
+	// a revoked family share
+	SubscriptionEventRevoked SubscriptionEventType = "revoked"
+)
+
+// Status returns the status a subscription moves to on the event
+func (t SubscriptionEventType) Status() SubscriptionStatus {
+	switch t {
+	case SubscriptionEventPurchased, SubscriptionEventRenewed, SubscriptionEventResumed:
+		return StatusActive
+	case SubscriptionEventBillingRetry:
+		return StatusGrace
+	case SubscriptionEventAutoRenewOff, SubscriptionEventOnHold, SubscriptionEventPaused:
+		return StatusCancelled
+	default:
+		return StatusExpired
+	}
+}
+
+// DunningReason returns the billing retry state the event starts, or ""
+func (t SubscriptionEventType) DunningReason() DunningReason {
+	switch t {
+	case SubscriptionEventBillingRetry:
+		return DunningReasonGrace
+	case SubscriptionEventOnHold:
+		return DunningReasonOnHold
+	default:
+		return ""
+	}
+}
+
+// SubscriptionEvent is a store notification translated by its provider's
+// adapter
+type SubscriptionEvent struct {
+	Type SubscriptionEventType
+	// Provider and ProviderType name the store and its notification type,
+	// for logs
+	Provider     string
+	ProviderType string
+	// ExpiresAt is the expiry the store reported, zero when it reported none.
+	// A renewal without one extends the subscription by its plan's period.
+	ExpiresAt time.Time
+}
+
+// SubscriptionChange is what applying an event changed
+type SubscriptionChange struct {
+	PreviousStatus    SubscriptionStatus
+	StatusChanged     bool
+	ExpiryExtended    bool
+	AutoRenewDisabled bool
+	// Rejected is set when the lifecycle does not allow the event's status,
+	// e.g. a billing retry reported after the subscription expired; nothing
+	// changes then
+	Rejected bool
+}
+
+// Apply moves the subscription through the lifecycle on a store event
+func (s *Subscription) Apply(event SubscriptionEvent, now time.Time) SubscriptionChange {
+	change := SubscriptionChange{PreviousStatus: s.Status}
+	if err := s.TransitionTo(event.Type.Status(), now); err != nil {
+		change.Rejected = true
+		return change
+	}
+	change.StatusChanged = s.Status != change.PreviousStatus
+
+	expiresAt := event.ExpiresAt
+	if expiresAt.IsZero() && event.Type == SubscriptionEventRenewed {
+		expiresAt = s.PlanType.PeriodEnd(now)
+	}
+	change.ExpiryExtended = s.ExtendExpiry(expiresAt, now)
+
+	if event.Type == SubscriptionEventAutoRenewOff && s.AutoRenew {
+		s.AutoRenew = false
+		s.UpdatedAt = now
+		change.AutoRenewDisabled = true
+	}
+	return change
+}
@@ -0,0 +1,117 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanType_PeriodEnd(t *testing.T) {
+	from := time.Date(2026, 1, 31, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2026, 2, 28, 12, 0, 0, 0, time.UTC), PlanMonthly.PeriodEnd(from))
+	assert.Equal(t, time.Date(2027, 1, 31, 12, 0, 0, 0, time.UTC), PlanAnnual.PeriodEnd(from))
+	assert.Equal(t, from, PlanLifetime.PeriodEnd(from))
+}
+
+func TestSubscription_CanTransitionTo(t *testing.T) {
+	tests := []struct {
+		from, to SubscriptionStatus
+		want     bool
+	}{
+		{from: StatusActive, to: StatusGrace, want: true},
+		{from: StatusGrace, to: StatusActive, want: true},
+		{from: StatusCancelled, to: StatusActive, want: true},
+		{from: StatusExpired, to: StatusActive, want: true},
+		{from: StatusExpired, to: StatusGrace, want: false},
+		{from: StatusExpired, to: StatusCancelled, want: false},
+		{from: StatusActive, to: "refunded", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"->"+string(tt.to), func(t *testing.T) {
+			sub := &Subscription{Status: tt.from}
+			assert.Equal(t, tt.want, sub.CanTransitionTo(tt.to))
+		})
+	}
+
+	deleted := time.Now()
+	assert.False(t, (&Subscription{Status: StatusExpired, DeletedAt: &deleted}).CanTransitionTo(StatusActive))
+}
+
+func TestSubscription_Apply(t *testing.T) {
+	now := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	expiresAt := now.Add(10 * 24 * time.Hour)
+	newSub := func(status SubscriptionStatus) *Subscription {
+		return &Subscription{ID: uuid.New(), Status: status, PlanType: PlanAnnual, ExpiresAt: expiresAt, AutoRenew: true}
+	}
+
+	t.Run("renewal takes the store's expiry", func(t *testing.T) {
+		sub := newSub(StatusGrace)
+		storeExpiry := expiresAt.AddDate(1, 0, 0)
+
+		change := sub.Apply(SubscriptionEvent{Type: SubscriptionEventRenewed, ExpiresAt: storeExpiry}, now)
+
+		assert.Equal(t, SubscriptionChange{PreviousStatus: StatusGrace, StatusChanged: true, ExpiryExtended: true}, change)
+		assert.Equal(t, StatusActive, sub.Status)
+		assert.Equal(t, storeExpiry, sub.ExpiresAt)
+	})
+
+	t.Run("renewal without an expiry extends by the plan period", func(t *testing.T) {
+		sub := newSub(StatusActive)
+
+		change := sub.Apply(SubscriptionEvent{Type: SubscriptionEventRenewed}, now)
+
+		assert.True(t, change.ExpiryExtended)
+		assert.False(t, change.StatusChanged)
+		assert.Equal(t, now.AddDate(1, 0, 0), sub.ExpiresAt)
+	})
+
+	t.Run("replayed notification never shortens expiry", func(t *testing.T) {
+		sub := newSub(StatusActive)
+
+		change := sub.Apply(SubscriptionEvent{Type: SubscriptionEventPurchased, ExpiresAt: now}, now)
+
+		assert.False(t, change.ExpiryExtended)
+		assert.Equal(t, expiresAt, sub.ExpiresAt)
+	})
+
+	t.Run("cancellation turns auto-renew off", func(t *testing.T) {
+		sub := newSub(StatusActive)
+
+		change := sub.Apply(SubscriptionEvent{Type: SubscriptionEventAutoRenewOff}, now)
+
+		assert.True(t, change.StatusChanged)
+		assert.True(t, change.AutoRenewDisabled)
+		assert.Equal(t, StatusCancelled, sub.Status)
+		assert.False(t, sub.AutoRenew)
+		assert.Equal(t, expiresAt, sub.ExpiresAt, "access continues until expiry")
+	})
+
+	t.Run("late billing retry cannot revive an expired subscription", func(t *testing.T) {
+		sub := newSub(StatusExpired)
+
+		change := sub.Apply(SubscriptionEvent{Type: SubscriptionEventBillingRetry, ExpiresAt: expiresAt.Add(time.Hour)}, now)
+
+		assert.True(t, change.Rejected)
+		assert.Equal(t, StatusExpired, sub.Status)
+		assert.Equal(t, expiresAt, sub.ExpiresAt)
+	})
+
+	t.Run("revocation expires the subscription", func(t *testing.T) {
+		sub := newSub(StatusActive)
+
+		change := sub.Apply(SubscriptionEvent{Type: SubscriptionEventRevoked}, now)
+
+		require.True(t, change.StatusChanged)
+		assert.Equal(t, StatusExpired, sub.Status)
+		assert.False(t, sub.IsActiveAt(now))
+	})
+}
+
+func TestSubscriptionEventType_DunningReason(t *testing.T) {
+	assert.Equal(t, DunningReasonGrace, SubscriptionEventBillingRetry.DunningReason())
+	assert.Equal(t, DunningReasonOnHold, SubscriptionEventOnHold.DunningReason())
+	assert.Empty(t, SubscriptionEventPaused.DunningReason())
+}
@@ -0,0 +1,42 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	TaskRunStatusRunning   = "running"
+	TaskRunStatusSucceeded = "succeeded"
+	TaskRunStatusFailed    = "failed"
+)
+
+// TaskRun is a single recorded execution of an asynq task handler.
+type TaskRun struct {
+	ID         uuid.UUID  `json:"id"`
+	TaskType   string     `json:"task_type"`
+	TaskID     string     `json:"task_id"`
+	Queue      string     `json:"queue"`
+	RetryCount int        `json:"retry_count"`
+	Status     string     `json:"status"`
+	Error      *string    `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	DurationMs *int64     `json:"duration_ms,omitempty"`
+}
+
+// TaskRunStats aggregates task runs of one task type over a time window.
+type TaskRunStats struct {
+	TaskType        string     `json:"task_type"`
+	Total           int64      `json:"total"`
+	Succeeded       int64      `json:"succeeded"`
+	Failed          int64      `json:"failed"`
+	Running         int64      `json:"running"`
+	FailureRate     float64    `json:"failure_rate"`
+	AvgDurationMs   float64    `json:"avg_duration_ms"`
+	MaxDurationMs   int64      `json:"max_duration_ms"`
+	LastStartedAt   *time.Time `json:"last_started_at,omitempty"`
+	LastSucceededAt *time.Time `json:"last_succeeded_at,omitempty"`
+	LastFailedAt    *time.Time `json:"last_failed_at,omitempty"`
+}
@@ -1,6 +1,7 @@
 package entity
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,6 +15,22 @@ const (
 	TransactionStatusRefunded TransactionStatus = "refunded"
 )
 
+// Transaction providers
+const (
+	TransactionProviderApple  = "apple"
+	TransactionProviderGoogle = "google"
+	TransactionProviderAmazon = "amazon"
+	TransactionProviderHuawei = "huawei"
+	TransactionProviderStripe = "stripe"
+	TransactionProviderPayPal = "paypal"
+)
+
+// Transaction environments
+const (
+	TransactionEnvironmentProduction = "production"
+	TransactionEnvironmentSandbox    = "sandbox"
+)
+
 type Transaction struct {
 	ID             uuid.UUID
 	AppID          uuid.UUID
@@ -25,6 +42,32 @@ type Transaction struct {
 	ReceiptHash    string
 	ProviderTxID   string
 	CreatedAt      time.Time
+
+	// Revenue breakdown. Amount is the gross charged to the customer; NetAmount
+	// is nil until the store fee and tax are known.
+	StoreFee     float64
+	TaxAmount    float64
+	NetAmount    *float64
+	CountryCode  string
+	ReconciledAt *time.Time
+
+	// Ledger fields. OriginalTransactionID is the store id of the first
+	// purchase in the chain; ProviderTxID identifies this charge.
+	Provider              string
+	OriginalTransactionID string
+	ProductID             string
+	Environment           string
+	RefundedAt            *time.Time
+	RefundReference       string
+}
+
+// RevenueBreakdown splits a gross amount into store fee, tax and net proceeds
+type RevenueBreakdown struct {
+	Gross       float64 `json:"gross"`
+	StoreFee    float64 `json:"store_fee"`
+	Tax         float64 `json:"tax"`
+	Net         float64 `json:"net"`
+	CountryCode string  `json:"country_code,omitempty"`
 }
 
 // NewTransaction creates a new transaction entity
@@ -37,10 +80,20 @@ func NewTransaction(appID, userID, subscriptionID uuid.UUID, amount float64, cur
 		Amount:         amount,
 		Currency:       currency,
 		Status:         TransactionStatusSuccess,
+		Environment:    TransactionEnvironmentProduction,
 		CreatedAt:      time.Now(),
 	}
 }
 
+// NormalizeTransactionEnvironment maps store environment names ("Sandbox",
+// "Production", Stripe livemode) onto the ledger values
+func NormalizeTransactionEnvironment(env string) string {
+	if strings.EqualFold(env, "sandbox") || strings.EqualFold(env, "xcode") || strings.EqualFold(env, "test") {
+		return TransactionEnvironmentSandbox
+	}
+	return TransactionEnvironmentProduction
+}
+
 // IsSuccessful returns true if the transaction was successful
 func (t *Transaction) IsSuccessful() bool {
 	return t.Status == TransactionStatusSuccess
@@ -50,3 +103,22 @@ func (t *Transaction) IsSuccessful() bool {
 func (t *Transaction) IsFailed() bool {
 	return t.Status == TransactionStatusFailed
 }
+
+// ApplyBreakdown records the gross / fee / tax / net split on the transaction
+func (t *Transaction) ApplyBreakdown(b RevenueBreakdown) {
+	net := b.Net
+	t.Amount = b.Gross
+	t.StoreFee = b.StoreFee
+	t.TaxAmount = b.Tax
+	t.NetAmount = &net
+	t.CountryCode = b.CountryCode
+}
+
+// NetRevenue returns the net proceeds, falling back to the gross amount when
+// the transaction has not been broken down yet
+func (t *Transaction) NetRevenue() float64 {
+	if t.NetAmount != nil {
+		return *t.NetAmount
+	}
+	return t.Amount
+}
@@ -0,0 +1,20 @@
+package entity_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+func TestNormalizeTransactionEnvironment(t *testing.T) {
+	for env, want := range map[string]string{
+		"Sandbox":    entity.TransactionEnvironmentSandbox,
+		"Xcode":      entity.TransactionEnvironmentSandbox,
+		"Production": entity.TransactionEnvironmentProduction,
+		"":           entity.TransactionEnvironmentProduction,
+	} {
+		assert.Equal(t, want, entity.NormalizeTransactionEnvironment(env), env)
+	}
+}
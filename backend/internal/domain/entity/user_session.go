@@ -0,0 +1,74 @@
+package entity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DeviceInfo describes the device a session was signed in from
+type DeviceInfo struct {
+	DeviceID   string
+	Platform   string
+	AppVersion string
+	UserAgent  string
+	IPAddress  string
+}
+
+// Fingerprint identifies the device across sign-ins. The IP address and app
+// version are left out since they change without the device changing.
+func (d DeviceInfo) Fingerprint() string {
+	sum := sha256.Sum256([]byte(d.DeviceID + "\x00" + d.Platform + "\x00" + d.UserAgent))
+	return hex.EncodeToString(sum[:])
+}
+
+// UserSession is a signed-in device of a user. It survives refresh-token
+// rotation; RefreshJTI is the only refresh token of the session still usable.
+type UserSession struct {
+	ID                uuid.UUID
+	UserID            uuid.UUID
+	AppID             *uuid.UUID
+	RefreshJTI        string
+	AccessJTI         string
+	DeviceFingerprint string
+	Device            DeviceInfo
+	CreatedAt         time.Time
+	LastSeenAt        time.Time
+	ExpiresAt         time.Time
+	RevokedAt         *time.Time
+}
+
+// NewUserSession creates a session for a device signing in now
+func NewUserSession(userID uuid.UUID, appID *uuid.UUID, device DeviceInfo, now time.Time) *UserSession {
+	return &UserSession{
+		ID:                uuid.New(),
+		UserID:            userID,
+		AppID:             appID,
+		DeviceFingerprint: device.Fingerprint(),
+		Device:            device,
+		CreatedAt:         now,
+		LastSeenAt:        now,
+	}
+}
+
+// IsActive returns true while the session is neither revoked nor expired
+func (s *UserSession) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// Rotate records the tokens issued by a refresh. The last known IP address
+// and app version replace the previous ones.
+func (s *UserSession) Rotate(refreshJTI, accessJTI string, expiresAt time.Time, device DeviceInfo, now time.Time) {
+	s.RefreshJTI = refreshJTI
+	s.AccessJTI = accessJTI
+	s.ExpiresAt = expiresAt
+	s.LastSeenAt = now
+	if device.IPAddress != "" {
+		s.Device.IPAddress = device.IPAddress
+	}
+	if device.AppVersion != "" {
+		s.Device.AppVersion = device.AppVersion
+	}
+}
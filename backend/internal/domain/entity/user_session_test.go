@@ -0,0 +1,38 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeviceInfo_FingerprintIgnoresNetwork(t *testing.T) {
+	device := DeviceInfo{DeviceID: "dev-1", Platform: "ios", UserAgent: "App/1.0", IPAddress: "10.0.0.1", AppVersion: "1.0"}
+	moved := device
+	moved.IPAddress = "10.0.0.2"
+	moved.AppVersion = "1.1"
+
+	assert.Equal(t, device.Fingerprint(), moved.Fingerprint())
+	assert.NotEqual(t, device.Fingerprint(), DeviceInfo{DeviceID: "dev-2", Platform: "ios", UserAgent: "App/1.0"}.Fingerprint())
+}
+
+func TestUserSession_RotateAndIsActive(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	session := NewUserSession(uuid.New(), nil, DeviceInfo{DeviceID: "dev-1", IPAddress: "10.0.0.1", AppVersion: "1.0"}, now)
+	session.ExpiresAt = now.Add(time.Hour)
+	assert.True(t, session.IsActive(now))
+	assert.False(t, session.IsActive(now.Add(time.Hour)))
+
+	later := now.Add(30 * time.Minute)
+	session.Rotate("r2", "a2", later.Add(time.Hour), DeviceInfo{IPAddress: "10.0.0.2"}, later)
+	assert.Equal(t, "r2", session.RefreshJTI)
+	assert.Equal(t, later, session.LastSeenAt)
+	assert.Equal(t, "10.0.0.2", session.Device.IPAddress)
+	assert.Equal(t, "1.0", session.Device.AppVersion)
+	assert.True(t, session.IsActive(now.Add(time.Hour)))
+
+	session.RevokedAt = &later
+	assert.False(t, session.IsActive(later))
+}
@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+// AccountMergeRepository defines the interface for merging duplicate users
+type AccountMergeRepository interface {
+	// Merge moves the source user's rows to the target in one transaction,
+	// filling the merge's moved counts, conflicts and source LTV. With dryRun
+	// the transaction is rolled back after the counts are taken.
+	// entity.ErrInvalidAccountMerge when a user is missing, deleted, in
+	// another app, or the source is already merged away.
+	Merge(ctx context.Context, merge *entity.AccountMerge, dryRun bool) error
+
+	// Undo moves the journaled rows back and restores the source user; the
+	// merge must already be marked undone. entity.ErrAccountMergeNotUndoable
+	// when it was undone concurrently or the rows cannot be moved back.
+	Undo(ctx context.Context, merge *entity.AccountMerge) error
+
+	// GetByID retrieves a merge; domain ErrNotFound when it does not exist
+	GetByID(ctx context.Context, id uuid.UUID) (*entity.AccountMerge, error)
+
+	// ListByUser retrieves the merges a user took part in, newest first
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]*entity.AccountMerge, error)
+}
@@ -56,6 +56,9 @@ type AnalyticsRepository interface {
 	GetMRR(ctx context.Context) (float64, error)
 	GetActiveSubscriptionCountAt(ctx context.Context, timestamp time.Time) (int, error)
 	GetChurnedCountBetween(ctx context.Context, start, end time.Time) (int, error)
+	// GetPriceIncreaseChurnedCountBetween counts subscriptions that expired
+	// without consenting to a store price increase
+	GetPriceIncreaseChurnedCountBetween(ctx context.Context, start, end time.Time) (int, error)
 
 	// Dashboard extras
 	GetMRRTrend(ctx context.Context, months int) ([]MonthlyMRR, error)
@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+// CreditRepository defines the interface for consumable credit balances
+type CreditRepository interface {
+	// Apply records entry and moves the balance by entry.Delta atomically,
+	// setting entry.BalanceAfter. When the idempotency key was used before it
+	// changes nothing and returns the earlier entry with created false.
+	// entity.ErrInsufficientCredits when the balance would go negative.
+	Apply(ctx context.Context, entry *entity.CreditEntry) (stored *entity.CreditEntry, created bool, err error)
+
+	// GetBalance returns the user's balance; 0 when nothing was ever credited
+	GetBalance(ctx context.Context, appID, userID uuid.UUID) (int64, error)
+
+	// ListEntries retrieves the user's most recent ledger entries, newest first
+	ListEntries(ctx context.Context, appID, userID uuid.UUID, limit int) ([]*entity.CreditEntry, error)
+}
@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+// DunningCampaignRepository defines the interface for dunning campaign variants
+type DunningCampaignRepository interface {
+	// Create creates a campaign; entity.ErrInvalidDunningCampaign when the name is taken
+	Create(ctx context.Context, c *entity.DunningCampaign) error
+
+	// Update saves a campaign's configuration; domain ErrNotFound when it
+	// does not exist, entity.ErrInvalidDunningCampaign when the name is taken
+	Update(ctx context.Context, c *entity.DunningCampaign) error
+
+	// GetByID retrieves a campaign; domain ErrNotFound when it does not exist
+	GetByID(ctx context.Context, id uuid.UUID) (*entity.DunningCampaign, error)
+
+	// List retrieves campaigns by name, only active ones when activeOnly is set
+	List(ctx context.Context, activeOnly bool) ([]*entity.DunningCampaign, error)
+
+	// RecoveryStats aggregates the outcome of dunning started since since,
+	// one row per campaign plus one for the default schedule
+	RecoveryStats(ctx context.Context, since time.Time) ([]*entity.DunningCampaignStats, error)
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+// InAppMessageRepository persists in-app messages and the events users
+// report about them
+type InAppMessageRepository interface {
+	// List retrieves all messages of an app, highest priority first
+	List(ctx context.Context, appID uuid.UUID) ([]*entity.InAppMessage, error)
+
+	// GetByID retrieves a message of an app
+	GetByID(ctx context.Context, appID, id uuid.UUID) (*entity.InAppMessage, error)
+
+	// Create creates a new message
+	Create(ctx context.Context, message *entity.InAppMessage) error
+
+	// Update replaces an existing message
+	Update(ctx context.Context, message *entity.InAppMessage) error
+
+	// Delete removes a message and its events
+	Delete(ctx context.Context, appID, id uuid.UUID) error
+
+	// RecordEvent stores an impression, click or dismissal
+	RecordEvent(ctx context.Context, event *entity.InAppMessageEvent) error
+
+	// History returns what the user did with each message they ever saw
+	History(ctx context.Context, userID uuid.UUID) (map[uuid.UUID]entity.InAppMessageHistory, error)
+
+	// Stats totals a message's events per arm
+	Stats(ctx context.Context, messageID uuid.UUID) ([]entity.InAppMessageStats, error)
+}
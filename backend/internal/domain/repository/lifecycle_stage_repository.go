@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+// LifecycleStageRepository reads the signals lifecycle stages are classified
+// from and stores the stages
+type LifecycleStageRepository interface {
+	// ListSignals returns up to limit users with IDs after afterUserID, in ID
+	// order, with their signals at now
+	ListSignals(ctx context.Context, afterUserID uuid.UUID, limit int, now time.Time) ([]entity.LifecycleSignals, error)
+
+	// SaveStages upserts stages. EnteredAt is only replaced when the stage changed.
+	SaveStages(ctx context.Context, stages []entity.UserLifecycleStage) error
+
+	// GetStage returns the user's stage, nil before it was first computed
+	GetStage(ctx context.Context, userID uuid.UUID) (*entity.UserLifecycleStage, error)
+
+	// CountByStage returns how many of the app's users are in each stage
+	CountByStage(ctx context.Context, appID uuid.UUID) (map[entity.LifecycleStage]int64, error)
+}
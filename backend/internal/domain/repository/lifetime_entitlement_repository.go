@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+// LifetimeEntitlementRepository defines the interface for non-consumable unlocks
+type LifetimeEntitlementRepository interface {
+	// Create stores the unlock. When the store transaction was recorded
+	// before it changes nothing and returns the earlier unlock with created false.
+	Create(ctx context.Context, e *entity.LifetimeEntitlement) (stored *entity.LifetimeEntitlement, created bool, err error)
+
+	// ListActiveByUser retrieves the user's unrevoked unlocks, oldest first
+	ListActiveByUser(ctx context.Context, userID uuid.UUID) ([]*entity.LifetimeEntitlement, error)
+
+	// RevokeByTransaction revokes the active unlocks whose transaction or
+	// original transaction is txID and returns them; none revoked is not an error
+	RevokeByTransaction(ctx context.Context, platform, txID, reason string, at time.Time) ([]*entity.LifetimeEntitlement, error)
+}
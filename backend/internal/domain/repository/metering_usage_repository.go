@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+// MeteringUsageRepository persists free-use counters of metered features
+type MeteringUsageRepository interface {
+	// GetUsed returns the uses spent in the window, 0 when none were recorded
+	GetUsed(ctx context.Context, appID, userID uuid.UUID, feature string, periodStart time.Time) (int64, error)
+
+	// Upsert saves the counters; a stored count is never lowered
+	Upsert(ctx context.Context, usages []*entity.MeterUsage) error
+}
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+// NotificationPreferenceRepository persists users' notification preferences
+// and counts the notifications they suppressed
+type NotificationPreferenceRepository interface {
+	// Get returns the user's preferences, nil when they never set any
+	Get(ctx context.Context, userID uuid.UUID) (*entity.NotificationPreferences, error)
+
+	// Upsert saves the user's preferences
+	Upsert(ctx context.Context, prefs *entity.NotificationPreferences) error
+
+	// RecordSuppression counts one suppressed notification against the
+	// user's app on day
+	RecordSuppression(ctx context.Context, userID uuid.UUID, day time.Time, category entity.NotificationCategory, channel entity.NotificationChannel, reason entity.SuppressionReason) error
+
+	// SuppressionCounts returns the app's counts for days in [from, to]
+	SuppressionCounts(ctx context.Context, appID uuid.UUID, from, to time.Time) ([]entity.NotificationSuppressionCount, error)
+}
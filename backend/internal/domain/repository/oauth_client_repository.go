@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+// OAuthClientRepository defines the interface for OAuth client data access
+type OAuthClientRepository interface {
+	// Create stores a new client; a duplicate client_id is a ConflictError
+	Create(ctx context.Context, client *entity.OAuthClient) error
+
+	// List retrieves all clients, newest first
+	List(ctx context.Context) ([]*entity.OAuthClient, error)
+
+	// GetByClientID retrieves a client by its public client_id, revoked or not
+	GetByClientID(ctx context.Context, clientID string) (*entity.OAuthClient, error)
+
+	// Revoke marks a client revoked; tokens already issued stop working
+	Revoke(ctx context.Context, id uuid.UUID, at time.Time) error
+
+	// TouchLastUsed records a successful token issuance
+	TouchLastUsed(ctx context.Context, id uuid.UUID, at time.Time) error
+}
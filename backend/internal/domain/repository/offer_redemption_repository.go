@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+// OfferCodeOutcome is what became of the users who redeemed one offer code
+// batch or win-back offer
+type OfferCodeOutcome struct {
+	OfferType entity.OfferType
+	OfferCode string
+	Campaign  string
+	// Users counts each redeeming user once, however many intro periods they
+	// paid for at the offer price
+	Users int
+	// Renewed users paid for the product after redeeming
+	Renewed int
+	// Retained users still have an active or grace subscription to it
+	Retained int
+}
+
+// OfferRedemptionRepository defines the interface for offer redemption data access
+type OfferRedemptionRepository interface {
+	// Create records a redemption; a repeated provider transaction is ignored
+	Create(ctx context.Context, redemption *entity.OfferRedemption) error
+
+	// GetByUserID retrieves all redemptions of a user within an app, newest first
+	GetByUserID(ctx context.Context, appID, userID uuid.UUID) ([]*entity.OfferRedemption, error)
+
+	// SetCampaign attributes an offer code batch or win-back offer to a
+	// campaign; an empty campaign removes the attribution
+	SetCampaign(ctx context.Context, appID uuid.UUID, offerCode, campaign string) error
+
+	// ListCodeOutcomes returns the outcome of each offer code batch and
+	// win-back offer first redeemed by its users in [from, to), most redeemed
+	// first
+	ListCodeOutcomes(ctx context.Context, appID uuid.UUID, from, to time.Time) ([]OfferCodeOutcome, error)
+}
@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+// PayPalRepository stores the PayPal plan mapping and the PayPal
+// subscriptions provisioned from it
+type PayPalRepository interface {
+	// UpsertPlan creates or replaces the mapping of a PayPal plan;
+	// entity.ErrPayPalPlanTaken when another app mapped it
+	UpsertPlan(ctx context.Context, plan *entity.PayPalPlan) error
+
+	// GetPlan retrieves the mapping of a PayPal plan; ErrNotFound when unmapped
+	GetPlan(ctx context.Context, planID string) (*entity.PayPalPlan, error)
+
+	// ListPlans retrieves an app's plan mappings
+	ListPlans(ctx context.Context, appID uuid.UUID) ([]*entity.PayPalPlan, error)
+
+	// DeletePlan removes a plan mapping; ErrNotFound when unmapped
+	DeletePlan(ctx context.Context, appID uuid.UUID, planID string) error
+
+	// GetSubscription retrieves a PayPal subscription; ErrNotFound when it
+	// provisioned nothing yet
+	GetSubscription(ctx context.Context, id string) (*entity.PayPalSubscription, error)
+
+	// SaveSubscription creates or updates a PayPal subscription link
+	SaveSubscription(ctx context.Context, sub *entity.PayPalSubscription) error
+}
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+// PaywallRuleRepository defines the interface for paywall rule data access
+type PaywallRuleRepository interface {
+	// List retrieves all rules of an app in evaluation order (priority desc)
+	List(ctx context.Context, appID uuid.UUID) ([]*entity.PaywallRule, error)
+
+	// GetByID retrieves a rule of an app
+	GetByID(ctx context.Context, appID, id uuid.UUID) (*entity.PaywallRule, error)
+
+	// Create creates a new rule
+	Create(ctx context.Context, rule *entity.PaywallRule) error
+
+	// Update replaces an existing rule
+	Update(ctx context.Context, rule *entity.PaywallRule) error
+
+	// Delete removes a rule
+	Delete(ctx context.Context, appID, id uuid.UUID) error
+}
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+// PendingPurchaseMatch selects the open pending purchase a store event
+// resolves: by Google purchase token when set, otherwise by user, product and
+// platform
+type PendingPurchaseMatch struct {
+	PurchaseToken string
+	UserID        uuid.UUID
+	ProductID     string
+	Platform      string
+}
+
+// PendingPurchaseRepository defines the interface for deferred purchase data access
+type PendingPurchaseRepository interface {
+	// Create stores a new pending purchase; entity.ErrPendingPurchaseOpen when
+	// the user already has an open one for the product on that platform
+	Create(ctx context.Context, purchase *entity.PendingPurchase) error
+
+	// GetOpen retrieves the open pending purchase matching match; ErrNotFound when none
+	GetOpen(ctx context.Context, match PendingPurchaseMatch) (*entity.PendingPurchase, error)
+
+	// ListByUser retrieves a user's pending purchases created since, newest first
+	ListByUser(ctx context.Context, userID uuid.UUID, since time.Time) ([]*entity.PendingPurchase, error)
+
+	// Resolve stores the final status of a purchase that is still pending;
+	// entity.ErrPendingPurchaseResolved when it was resolved concurrently
+	Resolve(ctx context.Context, purchase *entity.PendingPurchase) error
+
+	// ListExpired retrieves open pending purchases whose window closed before now
+	ListExpired(ctx context.Context, now time.Time, limit int) ([]*entity.PendingPurchase, error)
+}
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+// PriceIncreaseConsentRepository defines the interface for price increase consent data access
+type PriceIncreaseConsentRepository interface {
+	// Create stores a new price increase; entity.ErrPriceIncreaseOpen when
+	// the subscription already has a pending one
+	Create(ctx context.Context, consent *entity.PriceIncreaseConsent) error
+
+	// GetPending retrieves the pending price increase of a subscription; ErrNotFound when none
+	GetPending(ctx context.Context, subscriptionID uuid.UUID) (*entity.PriceIncreaseConsent, error)
+
+	// GetLatest retrieves the most recently notified price increase of a
+	// subscription, whatever its status; ErrNotFound when none
+	GetLatest(ctx context.Context, subscriptionID uuid.UUID) (*entity.PriceIncreaseConsent, error)
+
+	// Update stores the status, price and prompt counters of a price increase
+	Update(ctx context.Context, consent *entity.PriceIncreaseConsent) error
+
+	// ListPending retrieves an app's pending price increases, oldest notice
+	// first, with the total number pending
+	ListPending(ctx context.Context, appID uuid.UUID, limit, offset int) ([]*entity.PriceIncreaseConsent, int, error)
+
+	// ListPromptable retrieves pending price increases of an app, of every app
+	// when appID is uuid.Nil, prompted fewer than maxPrompts times and not
+	// since promptedBefore
+	ListPromptable(ctx context.Context, appID uuid.UUID, promptedBefore time.Time, maxPrompts, limit int) ([]*entity.PriceIncreaseConsent, error)
+}
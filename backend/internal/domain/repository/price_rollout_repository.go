@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+// PriceRolloutCohortStats holds the outcome of one rollout arm: users assigned
+// to it and their successful purchases of any product since assignment
+type PriceRolloutCohortStats struct {
+	ArmID      uuid.UUID
+	Users      int
+	Converters int
+	Revenue    float64
+}
+
+// PriceRolloutRepository defines the interface for price rollout data access
+type PriceRolloutRepository interface {
+	// List retrieves all rollouts of an app, newest first
+	List(ctx context.Context, appID uuid.UUID) ([]*entity.PriceRollout, error)
+
+	// ListOpen retrieves the active and paused rollouts of an app
+	ListOpen(ctx context.Context, appID uuid.UUID) ([]*entity.PriceRollout, error)
+
+	// GetByID retrieves a rollout of an app
+	GetByID(ctx context.Context, appID, id uuid.UUID) (*entity.PriceRollout, error)
+
+	// Create creates the rollout together with its running holdout experiment
+	Create(ctx context.Context, rollout *entity.PriceRollout, experimentName string) error
+
+	// Update persists status and schedule. Finishing a rollout completes its
+	// experiment; completing it also writes the new prices to the pricing tier.
+	Update(ctx context.Context, rollout *entity.PriceRollout) error
+
+	// CohortStats returns per-arm outcomes of the rollout experiment
+	CohortStats(ctx context.Context, rollout *entity.PriceRollout, until time.Time) ([]PriceRolloutCohortStats, error)
+}
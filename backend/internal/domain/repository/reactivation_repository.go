@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+// ReactivationArmStats is one experiment arm's reactivations
+type ReactivationArmStats struct {
+	ArmID         uuid.UUID
+	ArmName       string
+	IsControl     bool
+	Users         int
+	Reactivations int
+	MRR           float64
+}
+
+// ReactivationRepository finds churned subscribers who resubscribed and
+// stores what their reactivation is credited to
+type ReactivationRepository interface {
+	// ListCandidates returns up to limit subscriptions started since since,
+	// oldest first, that follow a lapsed subscription of the same user and are
+	// not recorded yet
+	ListCandidates(ctx context.Context, since time.Time, limit int) ([]entity.ReactivationCandidate, error)
+
+	// SaveReactivations records reactivations; already recorded ones are kept
+	SaveReactivations(ctx context.Context, reactivations []entity.SubscriptionReactivation) error
+
+	// ListArmStats returns the reactivations credited to each arm of the
+	// app's experiment, nil for an unknown experiment
+	ListArmStats(ctx context.Context, appID, experimentID uuid.UUID) ([]ReactivationArmStats, error)
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+// SegmentRepository defines the interface for segment data access
+type SegmentRepository interface {
+	// List retrieves all segments of an app
+	List(ctx context.Context, appID uuid.UUID) ([]*entity.Segment, error)
+
+	// ListMaterialized retrieves the materialized segments of every app
+	ListMaterialized(ctx context.Context) ([]*entity.Segment, error)
+
+	// GetByID retrieves a segment of an app
+	GetByID(ctx context.Context, appID, id uuid.UUID) (*entity.Segment, error)
+
+	// Create creates a new segment
+	Create(ctx context.Context, segment *entity.Segment) error
+
+	// Update replaces an existing segment's definition and settings
+	Update(ctx context.Context, segment *entity.Segment) error
+
+	// Delete removes a segment and its members
+	Delete(ctx context.Context, appID, id uuid.UUID) error
+
+	// ReplaceMembers atomically replaces the materialized membership
+	ReplaceMembers(ctx context.Context, segmentID uuid.UUID, userIDs []uuid.UUID, materializedAt time.Time) error
+
+	// IsMember reports whether a user is in a materialized segment
+	IsMember(ctx context.Context, segmentID, userID uuid.UUID) (bool, error)
+
+	// ListMemberIDs pages through a materialized segment ordered by user ID
+	ListMemberIDs(ctx context.Context, segmentID, after uuid.UUID, limit int) ([]uuid.UUID, error)
+
+	// ListAppUserIDs pages through an app's users ordered by ID
+	ListAppUserIDs(ctx context.Context, appID, after uuid.UUID, limit int) ([]uuid.UUID, error)
+
+	// GetExperimentSegment returns the segment an experiment targets, or nil
+	GetExperimentSegment(ctx context.Context, experimentID uuid.UUID) (*entity.Segment, error)
+
+	// SetExperimentSegment targets an experiment of an app at a segment (nil clears it)
+	SetExperimentSegment(ctx context.Context, appID, experimentID uuid.UUID, segmentID *uuid.UUID) error
+}
@@ -41,4 +41,7 @@ type SubscriptionRepository interface {
 
 	// GetTotalRevenue returns the total revenue for a user across all transactions
 	GetTotalRevenue(ctx context.Context, userID uuid.UUID) (float64, error)
+
+	// GetNetRevenue returns the total revenue for a user net of store fees and tax
+	GetNetRevenue(ctx context.Context, userID uuid.UUID) (float64, error)
 }
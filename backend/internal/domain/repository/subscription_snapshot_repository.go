@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SubscriptionSnapshotSummary counts an app's subscriptions on a snapshot date
+type SubscriptionSnapshotSummary struct {
+	Date        time.Time
+	CapturedAt  time.Time
+	Total       int
+	Active      int
+	ActiveUsers int
+	ByStatus    map[string]int
+	// ActiveByPlan and ActiveByPlatform only count active subscriptions
+	ActiveByPlan     map[string]int
+	ActiveByPlatform map[string]int
+}
+
+// SubscriptionSnapshotDiff compares each user's subscriptions on two dates.
+// A user is active when any of their subscriptions is; plans are ranked
+// monthly < annual < lifetime using the best active plan.
+type SubscriptionSnapshotDiff struct {
+	From       time.Time
+	To         time.Time
+	New        int
+	Churned    int
+	Upgraded   int
+	Downgraded int
+	// Crossgraded users stayed on the same plan rank but changed product
+	Crossgraded int
+	Retained    int
+}
+
+// SubscriptionSnapshotRow is one subscription as recorded on a date
+type SubscriptionSnapshotRow struct {
+	Date           time.Time
+	SubscriptionID uuid.UUID
+	UserID         uuid.UUID
+	Status         string
+	PlanType       string
+	ProductID      string
+	Platform       string
+	Source         string
+	ExpiresAt      time.Time
+	AutoRenew      bool
+	Active         bool
+}
+
+// SubscriptionSnapshotRepository defines the interface for subscription snapshot data access
+type SubscriptionSnapshotRepository interface {
+	// Capture replaces the snapshot of date with the current state of every
+	// subscription, evaluated at capturedAt. Returns the number of rows.
+	Capture(ctx context.Context, date, capturedAt time.Time) (int64, error)
+
+	// Summary counts an app's snapshot of date; ErrNotFound when none was taken
+	Summary(ctx context.Context, appID uuid.UUID, date time.Time) (*SubscriptionSnapshotSummary, error)
+
+	// Diff compares an app's snapshots of two dates
+	Diff(ctx context.Context, appID uuid.UUID, from, to time.Time) (*SubscriptionSnapshotDiff, error)
+
+	// ListDates returns the captured snapshot dates within [from, to]
+	ListDates(ctx context.Context, from, to time.Time) ([]time.Time, error)
+
+	// ListUserRows returns a user's snapshot rows within [from, to], oldest first
+	ListUserRows(ctx context.Context, appID, userID uuid.UUID, from, to time.Time) ([]SubscriptionSnapshotRow, error)
+}
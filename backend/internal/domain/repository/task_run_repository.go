@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+// TaskRunStartInput describes the task execution being started
+type TaskRunStartInput struct {
+	TaskType   string
+	TaskID     string
+	Queue      string
+	RetryCount int
+}
+
+// TaskRunFilter narrows a task run listing; empty fields match every run
+type TaskRunFilter struct {
+	TaskType string
+	Status   string
+	Limit    int
+}
+
+// TaskRunRepository defines the interface for asynq task run data access
+type TaskRunRepository interface {
+	// StartTaskRun records a running task and returns the run ID
+	StartTaskRun(ctx context.Context, input TaskRunStartInput) (uuid.UUID, error)
+
+	// FinishTaskRun stores the final status, error and duration of a run
+	FinishTaskRun(ctx context.Context, runID uuid.UUID, status string, errMsg string, duration time.Duration) error
+
+	// ListTaskRuns retrieves runs matching filter, most recently started first
+	ListTaskRuns(ctx context.Context, filter TaskRunFilter) ([]entity.TaskRun, error)
+
+	// GetTaskRunStats aggregates runs started since, per task type
+	GetTaskRunStats(ctx context.Context, since time.Time) ([]entity.TaskRunStats, error)
+}
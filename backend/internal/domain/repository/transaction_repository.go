@@ -24,6 +24,10 @@ type TransactionRepository interface {
 	// CheckDuplicateReceipt checks if a receipt has already been processed
 	CheckDuplicateReceipt(ctx context.Context, receiptHash string) (bool, error)
 
+	// ReconcileRevenue stores the gross / fee / tax / net breakdown on the transaction
+	// identified by its store transaction ID; returns false when no transaction matched
+	ReconcileRevenue(ctx context.Context, appID uuid.UUID, providerTxID, currency string, breakdown entity.RevenueBreakdown) (bool, error)
+
 	// GetSegmentedLTV returns LTV grouped by platform for the given period in days
 	GetSegmentedLTV(ctx context.Context, periodDays int) (map[string]float64, error)
 }
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+// UserConsentRepository persists users' consent choices
+type UserConsentRepository interface {
+	// Get returns the user's consent, nil when they never set one
+	Get(ctx context.Context, userID uuid.UUID) (*entity.UserConsent, error)
+
+	// Upsert saves the user's consent
+	Upsert(ctx context.Context, consent *entity.UserConsent) error
+}
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+// UserSessionRepository defines the interface for user session data access
+type UserSessionRepository interface {
+	// Create stores a new session
+	Create(ctx context.Context, session *entity.UserSession) error
+
+	// GetByID retrieves a session, revoked or not
+	GetByID(ctx context.Context, id uuid.UUID) (*entity.UserSession, error)
+
+	// ListActive retrieves a user's unrevoked, unexpired sessions, most recently used first
+	ListActive(ctx context.Context, userID uuid.UUID, now time.Time) ([]*entity.UserSession, error)
+
+	// Rotate stores the tokens and device details of a refresh, provided the
+	// session is still unrevoked and previousRefreshJTI is its current token.
+	// Returns ErrNotFound otherwise, so concurrent refreshes with the same
+	// token cannot both succeed.
+	Rotate(ctx context.Context, session *entity.UserSession, previousRefreshJTI string) error
+
+	// Revoke marks a user's session revoked; ErrNotFound when the user has no
+	// such unrevoked session
+	Revoke(ctx context.Context, userID, id uuid.UUID, at time.Time) error
+
+	// DeleteStale removes sessions that expired or were revoked before cutoff
+	DeleteStale(ctx context.Context, cutoff time.Time) (int64, error)
+}
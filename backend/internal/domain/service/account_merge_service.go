@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+// ltvCacheInvalidator drops a user's cached LTV estimate
+type ltvCacheInvalidator interface {
+	InvalidateLTV(ctx context.Context, userID string) error
+}
+
+// AccountMergeRequest asks to fold the source user into the target
+type AccountMergeRequest struct {
+	AppID        uuid.UUID
+	SourceUserID uuid.UUID
+	TargetUserID uuid.UUID
+	Policy       entity.MergeConflictPolicy
+	Reason       string
+	MergedBy     *uuid.UUID
+	// DryRun reports what would move and conflict without changing anything
+	DryRun bool
+}
+
+// AccountMergeService merges duplicate accounts of one person, e.g. an email
+// login and an Apple SSO login, and undoes merges made by mistake
+type AccountMergeService struct {
+	repo     repository.AccountMergeRepository
+	ltvCache ltvCacheInvalidator
+	logger   *zap.Logger
+	now      func() time.Time
+}
+
+func NewAccountMergeService(repo repository.AccountMergeRepository, logger *zap.Logger) *AccountMergeService {
+	return &AccountMergeService{repo: repo, logger: logger, now: time.Now}
+}
+
+// WithLTVCache drops cached LTV estimates of both users after a merge or undo
+func (s *AccountMergeService) WithLTVCache(cache ltvCacheInvalidator) *AccountMergeService {
+	s.ltvCache = cache
+	return s
+}
+
+// Merge moves the source user's subscriptions, transactions, experiment
+// assignments, LTV and analytics events to the target
+func (s *AccountMergeService) Merge(ctx context.Context, req AccountMergeRequest) (*entity.AccountMerge, error) {
+	merge, err := entity.NewAccountMerge(req.AppID, req.SourceUserID, req.TargetUserID, req.Policy, req.Reason, req.MergedBy, s.now())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Merge(ctx, merge, req.DryRun); err != nil {
+		return nil, fmt.Errorf("failed to merge accounts: %w", err)
+	}
+	if !req.DryRun {
+		s.invalidateLTV(ctx, merge)
+	}
+	return merge, nil
+}
+
+// Undo reverts a merge of the app while its undo window is open
+func (s *AccountMergeService) Undo(ctx context.Context, appID, mergeID uuid.UUID) (*entity.AccountMerge, error) {
+	merge, err := s.Get(ctx, appID, mergeID)
+	if err != nil {
+		return nil, err
+	}
+	if err := merge.Undo(s.now()); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Undo(ctx, merge); err != nil {
+		return nil, fmt.Errorf("failed to undo account merge: %w", err)
+	}
+	s.invalidateLTV(ctx, merge)
+	return merge, nil
+}
+
+// Get returns a merge of the app; domain ErrNotFound for another app's merge
+func (s *AccountMergeService) Get(ctx context.Context, appID, mergeID uuid.UUID) (*entity.AccountMerge, error) {
+	merge, err := s.repo.GetByID(ctx, mergeID)
+	if err != nil {
+		return nil, err
+	}
+	if merge.AppID != appID {
+		return nil, fmt.Errorf("account merge %s: %w", mergeID, domainErrors.ErrNotFound)
+	}
+	return merge, nil
+}
+
+// ListForUser returns the merges the user took part in, newest first
+func (s *AccountMergeService) ListForUser(ctx context.Context, userID uuid.UUID) ([]*entity.AccountMerge, error) {
+	merges, err := s.repo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list account merges: %w", err)
+	}
+	return merges, nil
+}
+
+func (s *AccountMergeService) invalidateLTV(ctx context.Context, merge *entity.AccountMerge) {
+	if s.ltvCache == nil {
+		return
+	}
+	for _, userID := range []uuid.UUID{merge.SourceUserID, merge.TargetUserID} {
+		if err := s.ltvCache.InvalidateLTV(ctx, userID.String()); err != nil {
+			s.logger.Warn("Failed to invalidate cached LTV after account merge",
+				zap.String("merge_id", merge.ID.String()),
+				zap.String("user_id", userID.String()),
+				zap.Error(err),
+			)
+		}
+	}
+}
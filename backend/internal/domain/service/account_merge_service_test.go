@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+)
+
+type fakeAccountMergeRepo struct {
+	merges map[uuid.UUID]*entity.AccountMerge
+	undone []uuid.UUID
+}
+
+func (r *fakeAccountMergeRepo) Merge(_ context.Context, m *entity.AccountMerge, dryRun bool) error {
+	m.Moved["subscriptions"] = 1
+	if !dryRun {
+		r.merges[m.ID] = m
+	}
+	return nil
+}
+
+func (r *fakeAccountMergeRepo) Undo(_ context.Context, m *entity.AccountMerge) error {
+	r.undone = append(r.undone, m.ID)
+	return nil
+}
+
+func (r *fakeAccountMergeRepo) GetByID(_ context.Context, id uuid.UUID) (*entity.AccountMerge, error) {
+	m, ok := r.merges[id]
+	if !ok {
+		return nil, fmt.Errorf("account merge: %w", domainErrors.ErrNotFound)
+	}
+	copied := *m
+	return &copied, nil
+}
+
+func (r *fakeAccountMergeRepo) ListByUser(context.Context, uuid.UUID) ([]*entity.AccountMerge, error) {
+	return nil, nil
+}
+
+type recordingLTVCache struct {
+	invalidated []string
+}
+
+func (c *recordingLTVCache) InvalidateLTV(_ context.Context, userID string) error {
+	c.invalidated = append(c.invalidated, userID)
+	return nil
+}
+
+func TestAccountMergeService_DryRunChangesNothing(t *testing.T) {
+	repo := &fakeAccountMergeRepo{merges: map[uuid.UUID]*entity.AccountMerge{}}
+	cache := &recordingLTVCache{}
+	svc := NewAccountMergeService(repo, zap.NewNop()).WithLTVCache(cache)
+
+	merge, err := svc.Merge(context.Background(), AccountMergeRequest{
+		AppID: uuid.New(), SourceUserID: uuid.New(), TargetUserID: uuid.New(), Reason: "duplicate", DryRun: true,
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), merge.Moved["subscriptions"])
+	assert.Empty(t, repo.merges)
+	assert.Empty(t, cache.invalidated)
+}
+
+func TestAccountMergeService_UndoWithinWindow(t *testing.T) {
+	repo := &fakeAccountMergeRepo{merges: map[uuid.UUID]*entity.AccountMerge{}}
+	cache := &recordingLTVCache{}
+	svc := NewAccountMergeService(repo, zap.NewNop()).WithLTVCache(cache)
+	appID := uuid.New()
+
+	merge, err := svc.Merge(context.Background(), AccountMergeRequest{
+		AppID: appID, SourceUserID: uuid.New(), TargetUserID: uuid.New(), Reason: "email and Apple SSO",
+	})
+	require.NoError(t, err)
+	assert.Len(t, cache.invalidated, 2)
+
+	_, err = svc.Undo(context.Background(), uuid.New(), merge.ID)
+	assert.ErrorIs(t, err, domainErrors.ErrNotFound, "another app's merge")
+
+	svc.now = func() time.Time { return merge.CreatedAt.Add(entity.AccountMergeUndoWindow + time.Hour) }
+	_, err = svc.Undo(context.Background(), appID, merge.ID)
+	assert.ErrorIs(t, err, entity.ErrAccountMergeNotUndoable)
+	assert.Empty(t, repo.undone)
+
+	svc.now = func() time.Time { return merge.CreatedAt.Add(time.Hour) }
+	undone, err := svc.Undo(context.Background(), appID, merge.ID)
+	require.NoError(t, err)
+	assert.Equal(t, entity.AccountMergeStatusUndone, undone.Status)
+	assert.Equal(t, []uuid.UUID{merge.ID}, repo.undone)
+}
@@ -0,0 +1,275 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+var (
+	// ErrInvalidMMPCallback is returned for callbacks missing required fields
+	ErrInvalidMMPCallback = errors.New("invalid MMP callback")
+	// ErrMMPEventIgnored is returned for callback events that do not change
+	// attribution (sessions, in-app events, re-engagements)
+	ErrMMPEventIgnored = errors.New("MMP event does not change attribution")
+)
+
+// AcquisitionDimension is what acquisition cohorts are broken down by
+type AcquisitionDimension string
+
+const (
+	AcquisitionByChannel     AcquisitionDimension = "channel"
+	AcquisitionByMediaSource AcquisitionDimension = "media_source"
+	AcquisitionByCampaign    AcquisitionDimension = "campaign"
+	AcquisitionByAdset       AcquisitionDimension = "adset"
+)
+
+// acquisitionDimensionColumns are the SQL expressions of each dimension; users
+// without a callback count as organic
+var acquisitionDimensionColumns = map[AcquisitionDimension]string{
+	AcquisitionByChannel:     "COALESCE(ua.channel, 'organic')",
+	AcquisitionByMediaSource: "COALESCE(NULLIF(ua.media_source, ''), 'organic')",
+	AcquisitionByCampaign:    "COALESCE(ua.campaign, '')",
+	AcquisitionByAdset:       "COALESCE(ua.adset, '')",
+}
+
+// IsValid reports whether cohorts can be broken down by d
+func (d AcquisitionDimension) IsValid() bool {
+	_, ok := acquisitionDimensionColumns[d]
+	return ok
+}
+
+// AcquisitionCohort is the signups of one acquisition breakdown value
+type AcquisitionCohort struct {
+	Value          string  `json:"value"`
+	Users          int64   `json:"users"`
+	Converted      int64   `json:"converted"`
+	ConversionRate float64 `json:"conversion_rate"`
+	TotalLTV       float64 `json:"total_ltv"`
+	AvgLTV         float64 `json:"avg_ltv"`
+}
+
+// appsFlyerCallback is the subset of an AppsFlyer Push API payload we keep
+type appsFlyerCallback struct {
+	EventName      string `json:"event_name"`
+	CustomerUserID string `json:"customer_user_id"`
+	MediaSource    string `json:"media_source"`
+	Campaign       string `json:"campaign"`
+	CampaignID     string `json:"af_c_id"`
+	Adset          string `json:"af_adset"`
+	Ad             string `json:"af_ad"`
+	InstallTime    string `json:"install_time"`
+	EventTime      string `json:"event_time"`
+}
+
+var appsFlyerTimeLayouts = []string{"2006-01-02 15:04:05.000", "2006-01-02 15:04:05", time.RFC3339}
+
+// ParseAppsFlyerCallback reads an AppsFlyer Push API install or
+// re-attribution payload
+func ParseAppsFlyerCallback(body []byte) (*entity.UserAcquisition, error) {
+	var cb appsFlyerCallback
+	if err := json.Unmarshal(body, &cb); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMMPCallback, err)
+	}
+	a := &entity.UserAcquisition{
+		Provider:       entity.MMPAppsFlyer,
+		CustomerUserID: strings.TrimSpace(cb.CustomerUserID),
+		MediaSource:    cb.MediaSource,
+		Campaign:       cb.Campaign,
+		CampaignID:     cb.CampaignID,
+		Adset:          cb.Adset,
+		Ad:             cb.Ad,
+	}
+	switch strings.ToLower(cb.EventName) {
+	case "install":
+		a.Event = entity.AcquisitionInstall
+	case "re-attribution", "reattribution":
+		a.Event = entity.AcquisitionReattribution
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrMMPEventIgnored, cb.EventName)
+	}
+	if t, ok := parseAppsFlyerTime(cb.InstallTime); ok {
+		a.InstalledAt = &t
+	}
+	eventTime, ok := parseAppsFlyerTime(cb.EventTime)
+	if !ok && a.InstalledAt != nil {
+		eventTime, ok = *a.InstalledAt, true
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: event_time is required", ErrInvalidMMPCallback)
+	}
+	a.AttributedAt = eventTime
+	return a, finishAcquisition(a)
+}
+
+func parseAppsFlyerTime(raw string) (time.Time, bool) {
+	for _, layout := range appsFlyerTimeLayouts {
+		if t, err := time.ParseInLocation(layout, raw, time.UTC); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// ParseAdjustCallback reads an Adjust install or reattribution callback. The
+// callback URL must carry the {activity_kind}, {network_name},
+// {campaign_name}, {adgroup_name}, {creative_name}, {installed_at} and
+// {reattributed_at} placeholders, and the app sets user_id as a callback
+// parameter.
+func ParseAdjustCallback(q url.Values) (*entity.UserAcquisition, error) {
+	a := &entity.UserAcquisition{
+		Provider:       entity.MMPAdjust,
+		CustomerUserID: strings.TrimSpace(q.Get("user_id")),
+		MediaSource:    q.Get("network_name"),
+		Campaign:       q.Get("campaign_name"),
+		Adset:          q.Get("adgroup_name"),
+		Ad:             q.Get("creative_name"),
+	}
+	installedAt, installOK := parseUnixParam(q.Get("installed_at"))
+	if installOK {
+		a.InstalledAt = &installedAt
+	}
+	switch q.Get("activity_kind") {
+	case "install":
+		a.Event = entity.AcquisitionInstall
+		if !installOK {
+			return nil, fmt.Errorf("%w: installed_at is required", ErrInvalidMMPCallback)
+		}
+		a.AttributedAt = installedAt
+	case "reattribution":
+		a.Event = entity.AcquisitionReattribution
+		reattributedAt, ok := parseUnixParam(q.Get("reattributed_at"))
+		if !ok {
+			return nil, fmt.Errorf("%w: reattributed_at is required", ErrInvalidMMPCallback)
+		}
+		a.AttributedAt = reattributedAt
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrMMPEventIgnored, q.Get("activity_kind"))
+	}
+	return a, finishAcquisition(a)
+}
+
+func parseUnixParam(raw string) (time.Time, bool) {
+	secs, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || secs <= 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(secs, 0).UTC(), true
+}
+
+func finishAcquisition(a *entity.UserAcquisition) error {
+	if a.CustomerUserID == "" {
+		return fmt.Errorf("%w: a customer user ID is required", ErrInvalidMMPCallback)
+	}
+	a.Channel = entity.ClassifyAcquisitionChannel(a.MediaSource)
+	return nil
+}
+
+// AcquisitionService records MMP attribution and reports signup cohorts by
+// acquisition source
+type AcquisitionService struct {
+	pool *pgxpool.Pool
+}
+
+// NewAcquisitionService creates a new acquisition service
+func NewAcquisitionService(pool *pgxpool.Pool) *AcquisitionService {
+	return &AcquisitionService{pool: pool}
+}
+
+// Record stores an attribution for the app. Installs never replace an
+// existing attribution and older callbacks never replace newer ones, so
+// retried and out-of-order callbacks are harmless; applied is false for them.
+func (s *AcquisitionService) Record(ctx context.Context, appID uuid.UUID, a *entity.UserAcquisition) (bool, error) {
+	a.AppID = appID
+	tag, err := s.pool.Exec(ctx, `
+		INSERT INTO user_acquisitions (app_id, customer_user_id, user_id, provider, event, channel,
+		    media_source, campaign, campaign_id, adset, ad, installed_at, attributed_at)
+		VALUES ($1, $2,
+		    (SELECT u.id FROM users u
+		     WHERE u.app_id = $1 AND u.deleted_at IS NULL AND (u.id::text = $2 OR u.platform_user_id = $2)
+		     LIMIT 1),
+		    $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (app_id, customer_user_id) DO UPDATE
+		SET provider = EXCLUDED.provider,
+		    event = EXCLUDED.event,
+		    channel = EXCLUDED.channel,
+		    media_source = EXCLUDED.media_source,
+		    campaign = EXCLUDED.campaign,
+		    campaign_id = EXCLUDED.campaign_id,
+		    adset = EXCLUDED.adset,
+		    ad = EXCLUDED.ad,
+		    installed_at = COALESCE(user_acquisitions.installed_at, EXCLUDED.installed_at),
+		    attributed_at = EXCLUDED.attributed_at,
+		    updated_at = now()
+		WHERE EXCLUDED.event = 'reattribution' AND EXCLUDED.attributed_at > user_acquisitions.attributed_at
+	`, appID, a.CustomerUserID, string(a.Provider), string(a.Event), string(a.Channel),
+		a.MediaSource, a.Campaign, a.CampaignID, a.Adset, a.Ad, a.InstalledAt, a.AttributedAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to record acquisition: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// ResolveUsers links attributions that arrived before their user signed up
+// and returns how many were linked
+func (s *AcquisitionService) ResolveUsers(ctx context.Context) (int64, error) {
+	tag, err := s.pool.Exec(ctx, `
+		UPDATE user_acquisitions ua
+		SET user_id = u.id, updated_at = now()
+		FROM users u
+		WHERE ua.user_id IS NULL AND u.app_id = ua.app_id AND u.deleted_at IS NULL
+		  AND (u.id::text = ua.customer_user_id OR u.platform_user_id = ua.customer_user_id)
+		  AND NOT EXISTS (SELECT 1 FROM user_acquisitions o WHERE o.user_id = u.id)
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve acquisition users: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// Cohorts breaks down the app's signups in [from, to) by acquisition
+// dimension with their conversion and LTV
+func (s *AcquisitionService) Cohorts(ctx context.Context, appID uuid.UUID, from, to time.Time, dimension AcquisitionDimension) ([]AcquisitionCohort, error) {
+	column, ok := acquisitionDimensionColumns[dimension]
+	if !ok {
+		return nil, fmt.Errorf("unknown acquisition dimension %q", dimension)
+	}
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+column+` AS value,
+		       COUNT(*),
+		       COUNT(*) FILTER (WHERE EXISTS (SELECT 1 FROM subscriptions s WHERE s.user_id = u.id)),
+		       COALESCE(SUM(u.ltv), 0)::float8
+		FROM users u
+		LEFT JOIN user_acquisitions ua ON ua.user_id = u.id
+		WHERE u.app_id = $1 AND u.deleted_at IS NULL AND u.created_at >= $2 AND u.created_at < $3
+		GROUP BY 1
+		ORDER BY 2 DESC, 1
+	`, appID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read acquisition cohorts: %w", err)
+	}
+	defer rows.Close()
+	cohorts := []AcquisitionCohort{}
+	for rows.Next() {
+		var c AcquisitionCohort
+		if err := rows.Scan(&c.Value, &c.Users, &c.Converted, &c.TotalLTV); err != nil {
+			return nil, fmt.Errorf("failed to scan acquisition cohort: %w", err)
+		}
+		if c.Users > 0 {
+			c.ConversionRate = float64(c.Converted) / float64(c.Users)
+			c.AvgLTV = c.TotalLTV / float64(c.Users)
+		}
+		cohorts = append(cohorts, c)
+	}
+	return cohorts, rows.Err()
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+func TestParseAppsFlyerCallback(t *testing.T) {
+	a, err := ParseAppsFlyerCallback([]byte(`{
+		"event_name": "re-attribution",
+		"customer_user_id": " user-1 ",
+		"media_source": "googleadwords_int",
+		"campaign": "retarget",
+		"af_c_id": "123",
+		"af_adset": "lapsed",
+		"install_time": "2026-01-01 08:00:00.000",
+		"event_time": "2026-03-01 10:00:00.000"
+	}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, entity.AcquisitionReattribution, a.Event)
+	assert.Equal(t, "user-1", a.CustomerUserID)
+	assert.Equal(t, entity.AcquisitionPaidSearch, a.Channel)
+	assert.Equal(t, "lapsed", a.Adset)
+	assert.Equal(t, time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC), a.AttributedAt)
+	require.NotNil(t, a.InstalledAt)
+	assert.Equal(t, time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC), *a.InstalledAt)
+}
+
+func TestParseAppsFlyerCallback_Rejects(t *testing.T) {
+	_, err := ParseAppsFlyerCallback([]byte(`{"event_name":"af_purchase","customer_user_id":"u"}`))
+	assert.True(t, errors.Is(err, ErrMMPEventIgnored))
+
+	_, err = ParseAppsFlyerCallback([]byte(`{"event_name":"install","event_time":"2026-03-01 10:00:00"}`))
+	assert.True(t, errors.Is(err, ErrInvalidMMPCallback))
+}
+
+func TestParseAdjustCallback(t *testing.T) {
+	q := url.Values{
+		"activity_kind": {"install"},
+		"user_id":       {"user-2"},
+		"network_name":  {"Organic"},
+		"installed_at":  {"1772359200"},
+	}
+	a, err := ParseAdjustCallback(q)
+	require.NoError(t, err)
+	assert.Equal(t, entity.AcquisitionInstall, a.Event)
+	assert.Equal(t, entity.AcquisitionOrganic, a.Channel)
+	assert.Equal(t, time.Unix(1772359200, 0).UTC(), a.AttributedAt)
+
+	q.Set("activity_kind", "reattribution")
+	_, err = ParseAdjustCallback(q)
+	assert.True(t, errors.Is(err, ErrInvalidMMPCallback), "reattributions need reattributed_at")
+}
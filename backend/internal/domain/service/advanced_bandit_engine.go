@@ -2,13 +2,19 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
 	"sort"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/clock"
+	"github.com/bivex/paywall-iap/internal/domain/entity"
 )
 
 // AdvancedBanditEngine orchestrates all advanced bandit features
@@ -18,6 +24,7 @@ type AdvancedBanditEngine struct {
 	rewardStrategy    RewardStrategy
 	selectionStrategy SelectionStrategy
 	windowStrategy    WindowStrategy
+	windowStrategies  *WindowStrategyRegistry
 	delayedStrategy   *DelayedRewardStrategy
 	hybridStrategy    *HybridObjectiveStrategy
 	currencyService   *CurrencyRateService
@@ -30,6 +37,21 @@ type AdvancedBanditEngine struct {
 	enableDelayed     bool
 	enableWindow      bool
 	enableHybrid      bool
+	revenueBasis      RevenueBasis
+	feeSchedule       StoreFeeSchedule
+	consent           ConsentChecker
+	clock             clock.Clock
+
+	configMu  sync.Mutex
+	configs   map[uuid.UUID]cachedExperimentConfig
+	configTTL time.Duration
+}
+
+// cachedExperimentConfig is an experiment's config as last read from the
+// repository
+type cachedExperimentConfig struct {
+	config    ExperimentConfig
+	expiresAt time.Time
 }
 
 const (
@@ -37,6 +59,9 @@ const (
 	defaultBanditMaintenanceScanLimit       = 100
 	defaultBanditContextRetentionWindow     = 90 * 24 * time.Hour
 	defaultBanditExpiredAssignmentRetention = 24 * time.Hour
+	// defaultExperimentConfigTTL bounds how long a config changed outside
+	// this engine, e.g. by another instance, can be served stale
+	defaultExperimentConfigTTL = 30 * time.Second
 )
 
 type banditMaintenanceRepository interface {
@@ -47,8 +72,8 @@ type banditMaintenanceRepository interface {
 }
 
 // BanditMaintenanceSummary reports what periodic maintenance actually did.
+// Expired pending rewards are swept separately by PendingRewardExpiryService.
 type BanditMaintenanceSummary struct {
-	ExpiredPendingRewards       int   `json:"expired_pending_rewards"`
 	CurrencyRatesUpdated        bool  `json:"currency_rates_updated"`
 	WindowExperimentsScanned    int   `json:"window_experiments_scanned"`
 	WindowsTrimmed              int   `json:"windows_trimmed"`
@@ -79,12 +104,18 @@ func NewAdvancedBanditEngine(
 	config *EngineConfig,
 ) *AdvancedBanditEngine {
 	engine := &AdvancedBanditEngine{
-		base:            base,
-		repo:            repo,
-		cache:           cache,
-		redisClient:     redisClient,
-		currencyService: currencyService,
-		logger:          logger,
+		base:             base,
+		repo:             repo,
+		cache:            cache,
+		redisClient:      redisClient,
+		currencyService:  currencyService,
+		logger:           logger,
+		revenueBasis:     RevenueBasisGross,
+		feeSchedule:      DefaultStoreFeeSchedule(),
+		windowStrategies: NewWindowStrategyRegistry(),
+		configs:          make(map[uuid.UUID]cachedExperimentConfig),
+		configTTL:        defaultExperimentConfigTTL,
+		clock:            clock.Real,
 	}
 
 	if config != nil {
@@ -108,7 +139,7 @@ func NewAdvancedBanditEngine(
 		if config.EnableContextual && config.ExperimentConfig.EnableContextual {
 			alpha := config.ExperimentConfig.ExplorationAlpha
 			engine.selectionStrategy = NewLinUCBSelectionStrategy(
-				repo, cache, logger, alpha, 20, // 20 dimension features
+				repo, cache, logger, alpha, 30, // 20 base features + 4 acquisition channels + 6 lifecycle stages
 			)
 		}
 
@@ -139,13 +170,24 @@ func NewAdvancedBanditEngine(
 	return engine
 }
 
+// getExperimentConfig returns the experiment's config, read from the
+// repository at most once per configTTL. Callers get their own copy.
 func (e *AdvancedBanditEngine) getExperimentConfig(
 	ctx context.Context,
 	experimentID uuid.UUID,
 ) (*ExperimentConfig, error) {
+	e.configMu.Lock()
+	cached, ok := e.configs[experimentID]
+	e.configMu.Unlock()
+	if ok && e.clock.Now().Before(cached.expiresAt) {
+		config := cached.config
+		return &config, nil
+	}
+
 	config, err := e.repo.GetExperimentConfig(ctx, experimentID)
 	if err != nil || config == nil {
-		return &ExperimentConfig{ID: experimentID, ObjectiveType: ObjectiveConversion}, nil
+		// Without the experiment's own settings, record rewards as they came in
+		return &ExperimentConfig{ID: experimentID, ObjectiveType: ObjectiveConversion, RewardBasis: RevenueBasisGross}, nil
 	}
 
 	if config.ID == uuid.Nil {
@@ -154,7 +196,57 @@ func (e *AdvancedBanditEngine) getExperimentConfig(
 	if config.ObjectiveType == "" {
 		config.ObjectiveType = ObjectiveConversion
 	}
+	if config.RewardBasis == "" {
+		config.RewardBasis = e.revenueBasis
+	}
+
+	if e.configTTL > 0 {
+		e.configMu.Lock()
+		e.configs[experimentID] = cachedExperimentConfig{config: *config, expiresAt: e.clock.Now().Add(e.configTTL)}
+		e.configMu.Unlock()
+	}
+	return config, nil
+}
+
+// WithClock makes the engine, and the delayed and window strategies it
+// builds, read the current time from c. The base bandit has its own clock.
+func (e *AdvancedBanditEngine) WithClock(c clock.Clock) *AdvancedBanditEngine {
+	e.clock = c
+	if e.delayedStrategy != nil {
+		e.delayedStrategy.WithClock(c)
+	}
+	return e
+}
+
+// WithExperimentConfigTTL sets how long experiment configs are served from
+// memory; zero reads the repository on every call
+func (e *AdvancedBanditEngine) WithExperimentConfigTTL(ttl time.Duration) *AdvancedBanditEngine {
+	e.configTTL = ttl
+	return e
+}
 
+// InvalidateExperimentConfig drops the experiment's cached config so the
+// next call reads it from the repository. Writers that update ab_tests
+// without going through the engine call it.
+func (e *AdvancedBanditEngine) InvalidateExperimentConfig(experimentID uuid.UUID) {
+	e.configMu.Lock()
+	delete(e.configs, experimentID)
+	e.configMu.Unlock()
+}
+
+// refreshExperimentConfig re-reads the experiment's config after a write and
+// hands it to the engine's configured hybrid strategy when that serves the
+// experiment
+func (e *AdvancedBanditEngine) refreshExperimentConfig(ctx context.Context, experimentID uuid.UUID) (*ExperimentConfig, error) {
+	e.InvalidateExperimentConfig(experimentID)
+	config, err := e.getExperimentConfig(ctx, experimentID)
+	if err != nil {
+		return nil, err
+	}
+	if e.hybridStrategy != nil && e.hybridStrategy.GetConfig().ID == experimentID {
+		strategyConfig := *config
+		e.hybridStrategy.UpdateConfig(&strategyConfig)
+	}
 	return config, nil
 }
 
@@ -166,7 +258,7 @@ func (e *AdvancedBanditEngine) getDelayedStrategy() (*DelayedRewardStrategy, err
 		return e.delayedStrategy, nil
 	}
 
-	e.delayedStrategy = NewDelayedRewardStrategy(e.repo, e.cache, e.logger)
+	e.delayedStrategy = NewDelayedRewardStrategy(e.repo, e.cache, e.logger).WithClock(e.clock)
 	return e.delayedStrategy, nil
 }
 
@@ -186,10 +278,16 @@ func (e *AdvancedBanditEngine) getHybridStrategy(
 	return NewHybridObjectiveStrategy(e.repo, e.cache, e.logger, config, e.base), nil
 }
 
+// WithWindowStrategy registers the strategy experiments with windowType use
+func (e *AdvancedBanditEngine) WithWindowStrategy(windowType WindowType, factory WindowStrategyFactory) *AdvancedBanditEngine {
+	e.windowStrategies.Register(windowType, factory)
+	return e
+}
+
 func (e *AdvancedBanditEngine) getWindowStrategy(
 	ctx context.Context,
 	experimentID uuid.UUID,
-) (*SlidingWindowStrategy, error) {
+) (WindowStrategy, error) {
 	if !e.enableWindow {
 		return nil, fmt.Errorf("sliding window not enabled")
 	}
@@ -202,7 +300,25 @@ func (e *AdvancedBanditEngine) getWindowStrategy(
 		return nil, err
 	}
 
-	return NewSlidingWindowStrategy(e.repo, e.redisClient, e.logger, experimentID, config.WindowConfig), nil
+	deps := WindowStrategyDeps{Repo: e.repo, Redis: e.redisClient, Logger: e.logger, Clock: e.clock}
+	return e.windowStrategies.New(deps, experimentID, config.WindowConfig)
+}
+
+// getWindowManager returns the experiment's window strategy when it keeps an
+// event log that can be inspected and trimmed
+func (e *AdvancedBanditEngine) getWindowManager(
+	ctx context.Context,
+	experimentID uuid.UUID,
+) (windowManager, error) {
+	strategy, err := e.getWindowStrategy(ctx, experimentID)
+	if err != nil {
+		return nil, err
+	}
+	manager, ok := strategy.(windowManager)
+	if !ok {
+		return nil, fmt.Errorf("window strategy %s does not keep an event log", strategy.GetType())
+	}
+	return manager, nil
 }
 
 // SelectArm selects an arm using the configured strategies
@@ -216,12 +332,17 @@ func (e *AdvancedBanditEngine) SelectArm(
 	if err != nil {
 		return uuid.Nil, fmt.Errorf("failed to get arms: %w", err)
 	}
+	if e.base.killSwitch != nil && e.base.killSwitch.ExperimentKilled(experimentID) {
+		return uuid.Nil, fmt.Errorf("%w: %s", ErrExperimentPaused, experimentID)
+	}
 
 	var selectedArm *Arm
 
-	// Use selection strategy if configured
-	if e.selectionStrategy != nil {
-		arm, err := e.selectionStrategy.SelectArm(ctx, arms, userContext)
+	// Use selection strategy if configured; archived and paused arms are
+	// never drawn
+	// Contextual selection personalizes on the user, so it needs their consent
+	if e.selectionStrategy != nil && e.personalizes(ctx, userID) {
+		arm, err := e.selectionStrategy.SelectArm(ctx, e.base.liveArms(activeArms(arms)), userContext)
 		if err != nil {
 			e.logger.Warn("Selection strategy failed, falling back to base", zap.Error(err))
 		} else {
@@ -237,7 +358,7 @@ func (e *AdvancedBanditEngine) SelectArm(
 			return uuid.Nil, err
 		}
 
-		// Find the arm object
+		// Find the arm object; a kept user may still be on an archived arm
 		for _, arm := range arms {
 			if arm.ID == armID {
 				selectedArm = &arm
@@ -252,7 +373,8 @@ func (e *AdvancedBanditEngine) SelectArm(
 
 	// Record pending reward if delayed feedback is enabled
 	if delayedStrategy, err := e.getDelayedStrategy(); err == nil {
-		_, err := delayedStrategy.RecordPendingReward(ctx, experimentID, selectedArm.ID, userID)
+		config, _ := e.getExperimentConfig(ctx, experimentID)
+		_, err := delayedStrategy.RecordPendingReward(ctx, experimentID, selectedArm.ID, userID, config.ConversionWindow)
 		if err != nil {
 			e.logger.Warn("Failed to record pending reward", zap.Error(err))
 		}
@@ -275,21 +397,83 @@ func (e *AdvancedBanditEngine) RecordReward(
 	currency string,
 	userContext UserContext,
 ) error {
-	// Convert currency if enabled
+	return e.RecordProductReward(ctx, experimentID, armID, userID, nil, "", reward, currency, userContext)
+}
+
+// RecordProductReward records a reward for a purchase of productID. The
+// product is only needed by the margin basis, which subtracts the
+// experiment's per-product cost. A non-nil transactionID makes the reward
+// idempotent like any other conversion of that transaction.
+func (e *AdvancedBanditEngine) RecordProductReward(
+	ctx context.Context,
+	experimentID, armID, userID uuid.UUID,
+	transactionID *uuid.UUID,
+	productID string,
+	reward float64,
+	currency string,
+	userContext UserContext,
+) error {
+	return e.recordReward(ctx, experimentID, armID, userID, transactionID, productID, reward, currency, userContext, true)
+}
+
+// RecordConversion records a yes/no outcome, such as a recovered payment, as a
+// success. It carries no amount, so neither currency conversion nor the
+// experiment's reward basis applies.
+func (e *AdvancedBanditEngine) RecordConversion(
+	ctx context.Context,
+	experimentID, armID, userID uuid.UUID,
+	userContext UserContext,
+) error {
+	return e.recordReward(ctx, experimentID, armID, userID, nil, "", 1, "", userContext, false)
+}
+
+// recordReward records reward with all applicable strategies. Monetary
+// rewards are converted to USD and into the experiment's reward basis.
+func (e *AdvancedBanditEngine) recordReward(
+	ctx context.Context,
+	experimentID, armID, userID uuid.UUID,
+	transactionID *uuid.UUID,
+	productID string,
+	reward float64,
+	currency string,
+	userContext UserContext,
+	monetary bool,
+) error {
 	finalReward := reward
 	finalCurrency := currency
-	recordedAt := time.Now().UTC()
+	var rate CurrencyRate
+	recordedAt := e.clock.Now().UTC()
+	config, _ := e.getExperimentConfig(ctx, experimentID)
 
-	if currency != "" && currency != "USD" && e.currencyService != nil && e.enableCurrency {
-		config, err := e.getExperimentConfig(ctx, experimentID)
-		if err == nil && (config == nil || config.EnableCurrency) {
-			converted, err := e.currencyService.ConvertToUSD(ctx, reward, currency)
-			if err != nil {
-				e.logger.Warn("Currency conversion failed", zap.Error(err))
-			} else {
-				finalReward = converted
-				finalCurrency = "USD"
-			}
+	if monetary {
+		finalReward, finalCurrency, rate = e.convertCurrency(ctx, config, reward, currency)
+	}
+
+	grossReward := finalReward
+	rewardFor := func(objectiveType ObjectiveType) float64 {
+		if !monetary {
+			return grossReward
+		}
+		return e.applyRewardBasis(config, objectiveType, productID, grossReward, userContext)
+	}
+	finalReward = rewardFor(config.ObjectiveType)
+	rewardBasis := RevenueBasisGross
+	if monetary {
+		rewardBasis = rewardBasisOf(config)
+	}
+
+	metadata := map[string]interface{}{
+		"source":       "advanced_bandit_engine",
+		"reward_basis": string(rewardBasis),
+		"gross_reward": grossReward,
+	}
+	if productID != "" {
+		metadata["product_id"] = productID
+	}
+	// Re-conversion rescales the pre-cost reward, so note the cost taken off
+	if rewardBasis == RevenueBasisMargin && grossReward > 0 {
+		if cost, ok := config.ProductCosts[productID]; ok {
+			metadata["product_cost"] = cost
 		}
 	}
 
@@ -298,21 +482,22 @@ func (e *AdvancedBanditEngine) RecordReward(
 		ExperimentID:          experimentID,
 		ArmID:                 armID,
 		UserID:                &userID,
+		TransactionID:         transactionID,
 		EventType:             ConversionEventTypeDirectReward,
 		OriginalRewardValue:   reward,
 		OriginalCurrency:      currency,
 		NormalizedRewardValue: finalReward,
 		NormalizedCurrency:    finalCurrency,
-		Metadata: map[string]interface{}{
-			"source": "advanced_bandit_engine",
-		},
-		OccurredAt: recordedAt,
+		ConversionRate:        rate.Rate,
+		RateSource:            rate.Source,
+		Metadata:              metadata,
+		OccurredAt:            recordedAt,
 	}); err != nil {
 		return fmt.Errorf("failed to update base reward: %w", err)
 	}
 
 	// Update LinUCB model if contextual is enabled
-	if linucbStrategy, ok := e.selectionStrategy.(*LinUCBSelectionStrategy); ok {
+	if linucbStrategy, ok := e.selectionStrategy.(*LinUCBSelectionStrategy); ok && e.personalizes(ctx, userID) {
 		if err := linucbStrategy.UpdateModel(ctx, armID, userContext, finalReward); err != nil {
 			e.logger.Warn("Failed to update LinUCB model", zap.Error(err))
 		}
@@ -338,10 +523,10 @@ func (e *AdvancedBanditEngine) RecordReward(
 		objectiveType := hybridStrategy.GetConfig().ObjectiveType
 
 		if objectiveType == ObjectiveHybrid {
-			// Update all objectives
+			// Update all objectives, each in the basis its stats are kept under
 			for objType := range hybridStrategy.GetConfig().ObjectiveWeights {
 				if err := hybridStrategy.RecordObjectiveReward(
-					ctx, armID, ObjectiveType(objType), finalReward, 0,
+					ctx, armID, ObjectiveType(objType), rewardFor(ObjectiveType(objType)), 0, reward, currency,
 				); err != nil {
 					e.logger.Warn("Failed to record objective reward",
 						zap.String("objective", objType),
@@ -351,7 +536,7 @@ func (e *AdvancedBanditEngine) RecordReward(
 			}
 		} else {
 			if err := hybridStrategy.RecordObjectiveReward(
-				ctx, armID, objectiveType, finalReward, 0,
+				ctx, armID, objectiveType, rewardFor(objectiveType), 0, reward, currency,
 			); err != nil {
 				e.logger.Warn("Failed to record objective reward", zap.Error(err))
 			}
@@ -361,7 +546,175 @@ func (e *AdvancedBanditEngine) RecordReward(
 	return nil
 }
 
-// ProcessConversion processes a delayed conversion
+// WithRevenueBasis sets the default reward basis for experiments without their
+// own and the fee schedule used to derive net revenue
+func (e *AdvancedBanditEngine) WithRevenueBasis(basis RevenueBasis, schedule StoreFeeSchedule) *AdvancedBanditEngine {
+	e.revenueBasis = basis
+	e.feeSchedule = schedule
+	return e
+}
+
+// WithConsent makes contextual selection and model updates skip users who have
+// not consented to personalization
+func (e *AdvancedBanditEngine) WithConsent(consent ConsentChecker) *AdvancedBanditEngine {
+	e.consent = consent
+	return e
+}
+
+// personalizes reports whether the user's context may be used
+func (e *AdvancedBanditEngine) personalizes(ctx context.Context, userID uuid.UUID) bool {
+	return e.consent == nil || e.consent.Allows(ctx, userID, entity.ConsentPersonalization)
+}
+
+// convertCurrency converts a reward to USD when the engine and experiment
+// enable it, keeping the original currency when no usable rate is known
+func (e *AdvancedBanditEngine) convertCurrency(ctx context.Context, config *ExperimentConfig, reward float64, currency string) (float64, string, CurrencyRate) {
+	if currency == "" || currency == "USD" || e.currencyService == nil || !e.enableCurrency {
+		return reward, currency, CurrencyRate{}
+	}
+	if config != nil && !config.EnableCurrency {
+		return reward, currency, CurrencyRate{}
+	}
+	converted, quote, err := e.currencyService.ConvertToUSDQuote(ctx, reward, currency)
+	if errors.Is(err, ErrCurrencyRateStale) {
+		e.logger.Warn("Currency rate too stale, recording original currency",
+			zap.String("currency", currency),
+		)
+		return reward, currency, CurrencyRate{}
+	}
+	if err != nil {
+		e.logger.Warn("Currency conversion failed", zap.Error(err))
+		return reward, currency, CurrencyRate{}
+	}
+	return converted, "USD", quote
+}
+
+// normalizeConversion values a delayed conversion for the experiment of the
+// pending reward it matched, as RecordProductReward would a direct one. The
+// purchased product is not known, so a margin basis subtracts no cost.
+func (e *AdvancedBanditEngine) normalizeConversion(ctx context.Context, pending *PendingReward, value float64, currency string) NormalizedConversion {
+	config, _ := e.getExperimentConfig(ctx, pending.ExperimentID)
+	gross, finalCurrency, rate := e.convertCurrency(ctx, config, value, currency)
+
+	// The stored context says which store the user pays through
+	userContext := UserContext{UserID: pending.UserID}
+	if stored, err := e.repo.GetUserContext(ctx, pending.UserID); err == nil && stored != nil {
+		userContext = *stored
+	}
+
+	return NormalizedConversion{
+		Value:    e.applyRewardBasis(config, config.ObjectiveType, "", gross, userContext),
+		Currency: finalCurrency,
+		Rate:     rate,
+		Metadata: map[string]interface{}{
+			"reward_basis": string(rewardBasisOf(config)),
+			"gross_reward": gross,
+		},
+	}
+}
+
+// rewardBasisOf returns the basis rewards of the experiment's objective are
+// recorded in
+func rewardBasisOf(config *ExperimentConfig) RevenueBasis {
+	if !RewardBasisApplies(config.ObjectiveType) || !config.RewardBasis.IsValid() {
+		return RevenueBasisGross
+	}
+	return config.RewardBasis
+}
+
+// RewardBasisApplies reports whether rewards for objectiveType are converted
+// into the experiment's reward basis. Only revenue-valued objectives are; a
+// conversion objective counts purchases, whatever they netted.
+func RewardBasisApplies(objectiveType ObjectiveType) bool {
+	return objectiveType == ObjectiveRevenue || objectiveType == ObjectiveLTV
+}
+
+// applyRewardBasis converts a gross USD reward for objectiveType into the
+// experiment's basis. Net deducts the store fee of the platform the user paid
+// on (tax is not known at this point); margin additionally deducts the
+// product's unit cost and may go negative, which counts as a failure for the
+// conversion posterior.
+func (e *AdvancedBanditEngine) applyRewardBasis(config *ExperimentConfig, objectiveType ObjectiveType, productID string, reward float64, userContext UserContext) float64 {
+	if reward <= 0 || !RewardBasisApplies(objectiveType) || config.RewardBasis == RevenueBasisGross || !config.RewardBasis.IsValid() {
+		return reward
+	}
+
+	net := e.feeSchedule.Net(RevenueProviderForPlatform(userContext.Device), reward, 0)
+	if config.RewardBasis == RevenueBasisMargin {
+		return roundCents(net - config.ProductCosts[productID])
+	}
+	return net
+}
+
+type rewardBasisRepository interface {
+	UpdateRewardBasisConfig(ctx context.Context, experimentID uuid.UUID, basis RevenueBasis, productCosts map[string]float64) error
+}
+
+// SetRewardBasis persists the reward basis and, for margin, per-product costs
+// of an experiment. Objective stats collected under another basis are kept
+// but no longer read.
+func (e *AdvancedBanditEngine) SetRewardBasis(
+	ctx context.Context,
+	experimentID uuid.UUID,
+	basis RevenueBasis,
+	productCosts map[string]float64,
+) (*ExperimentConfig, error) {
+	if !basis.IsValid() {
+		return nil, fmt.Errorf("invalid reward basis: %s", basis)
+	}
+	if basis != RevenueBasisMargin {
+		productCosts = nil
+	}
+	for productID, cost := range productCosts {
+		if productID == "" || cost < 0 || math.IsNaN(cost) || math.IsInf(cost, 0) {
+			return nil, fmt.Errorf("invalid cost for product %q", productID)
+		}
+	}
+
+	repo, ok := e.repo.(rewardBasisRepository)
+	if !ok {
+		return nil, fmt.Errorf("repository does not support reward basis config")
+	}
+	if err := repo.UpdateRewardBasisConfig(ctx, experimentID, basis, productCosts); err != nil {
+		return nil, err
+	}
+
+	return e.refreshExperimentConfig(ctx, experimentID)
+}
+
+type conversionWindowRepository interface {
+	UpdateConversionWindow(ctx context.Context, experimentID uuid.UUID, window, effective time.Duration) error
+}
+
+// SetConversionWindow sets how long the experiment's pending rewards wait for
+// a conversion; zero restores the default. Open pending rewards are re-based
+// on the new window so the expiry job honours it right away.
+func (e *AdvancedBanditEngine) SetConversionWindow(
+	ctx context.Context,
+	experimentID uuid.UUID,
+	window time.Duration,
+) (*ExperimentConfig, error) {
+	delayedStrategy, err := e.getDelayedStrategy()
+	if err != nil {
+		return nil, err
+	}
+	if window < 0 || window%time.Hour != 0 || window > delayedStrategy.MaxConversionWindow() {
+		return nil, fmt.Errorf("invalid conversion window %s: must be whole hours up to %s", window, delayedStrategy.MaxConversionWindow())
+	}
+
+	repo, ok := e.repo.(conversionWindowRepository)
+	if !ok {
+		return nil, fmt.Errorf("repository does not support conversion window config")
+	}
+	if err := repo.UpdateConversionWindow(ctx, experimentID, window, delayedStrategy.ConversionWindow(window)); err != nil {
+		return nil, err
+	}
+
+	return e.refreshExperimentConfig(ctx, experimentID)
+}
+
+// ProcessConversion processes a delayed conversion, crediting it in the
+// matched experiment's currency and reward basis
 func (e *AdvancedBanditEngine) ProcessConversion(
 	ctx context.Context,
 	transactionID uuid.UUID,
@@ -377,7 +730,7 @@ func (e *AdvancedBanditEngine) ProcessConversion(
 	// Process through delayed strategy
 	if err := delayedStrategy.ProcessConversion(
 		ctx, transactionID, userID, conversionValue, currency,
-		e.base,
+		e.normalizeConversion, e.base,
 	); err != nil {
 		return err
 	}
@@ -424,7 +777,8 @@ func (e *AdvancedBanditEngine) GetObjectiveScores(
 	return result, nil
 }
 
-// SetObjectiveConfig persists optimization objective settings for an experiment.
+// SetObjectiveConfig validates and persists the experiment's objective and
+// returns its refreshed config, which selection uses from then on.
 func (e *AdvancedBanditEngine) SetObjectiveConfig(
 	ctx context.Context,
 	experimentID uuid.UUID,
@@ -454,10 +808,10 @@ func (e *AdvancedBanditEngine) SetObjectiveConfig(
 	}
 
 	if err := e.repo.UpdateObjectiveConfig(ctx, experimentID, config.ObjectiveType, config.ObjectiveWeights); err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to persist objective config: %w", err)
 	}
 
-	return config, nil
+	return e.refreshExperimentConfig(ctx, experimentID)
 }
 
 // GetMetrics returns production metrics for the engine
@@ -480,7 +834,7 @@ func (e *AdvancedBanditEngine) GetMetrics(ctx context.Context, experimentID uuid
 		}
 	}
 
-	if windowStrategy, err := e.getWindowStrategy(ctx, experimentID); err == nil {
+	if windowStrategy, err := e.getWindowManager(ctx, experimentID); err == nil {
 		arms, armsErr := e.repo.GetArms(ctx, experimentID)
 		if armsErr == nil && len(arms) > 0 {
 			totalUtilization := 0.0
@@ -506,7 +860,7 @@ func (e *AdvancedBanditEngine) GetWindowInfo(
 	ctx context.Context,
 	experimentID uuid.UUID,
 ) (map[uuid.UUID]*WindowStats, error) {
-	windowStrategy, err := e.getWindowStrategy(ctx, experimentID)
+	windowStrategy, err := e.getWindowManager(ctx, experimentID)
 	if err != nil {
 		return nil, err
 	}
@@ -529,10 +883,15 @@ func (e *AdvancedBanditEngine) GetWindowInfo(
 }
 
 func (e *AdvancedBanditEngine) TrimWindow(ctx context.Context, experimentID uuid.UUID) error {
-	windowStrategy, err := e.getWindowStrategy(ctx, experimentID)
+	strategy, err := e.getWindowStrategy(ctx, experimentID)
 	if err != nil {
 		return err
 	}
+	windowStrategy, ok := strategy.(windowManager)
+	if !ok {
+		// Nothing to trim
+		return nil
+	}
 
 	arms, err := e.repo.GetArms(ctx, experimentID)
 	if err != nil {
@@ -568,6 +927,26 @@ func (e *AdvancedBanditEngine) ProcessExpiredPendingRewards(ctx context.Context,
 	return processed, nil
 }
 
+// ProcessExpiredPendingRewardsPage records one page of expired pending
+// rewards as non-conversions. It does nothing when delayed rewards are off.
+func (e *AdvancedBanditEngine) ProcessExpiredPendingRewardsPage(
+	ctx context.Context,
+	after *ExpiredRewardCursor,
+	expiredBefore time.Time,
+	limit int,
+	pace func(context.Context) error,
+) (ExpiredRewardPage, error) {
+	if limit <= 0 {
+		limit = defaultBanditMaintenanceBatchSize
+	}
+
+	delayedStrategy, err := e.getDelayedStrategy()
+	if err != nil {
+		return ExpiredRewardPage{}, nil
+	}
+	return delayedStrategy.ProcessExpiredRewardsPage(ctx, e.base, after, expiredBefore, limit, pace)
+}
+
 func (e *AdvancedBanditEngine) TrimConfiguredWindows(ctx context.Context, limit int) (int, error) {
 	if !e.enableWindow {
 		return 0, nil
@@ -656,6 +1035,7 @@ func (e *AdvancedBanditEngine) SyncObjectiveStats(ctx context.Context, limit int
 					Conversions:   stats.Conversions,
 					TotalRevenue:  stats.Revenue,
 					AvgLTV:        stats.AvgReward,
+					RewardBasis:   ObjectiveStatsBasis(objectiveType, config.RewardBasis),
 				}); err != nil {
 					return synced, fmt.Errorf("failed to sync objective stats: %w", err)
 				}
@@ -693,31 +1073,121 @@ func (e *AdvancedBanditEngine) CleanupExpiredAssignments(ctx context.Context, ol
 	return maintenanceRepo.CleanupExpiredAssignments(ctx, olderThan)
 }
 
+// ExportWindowEvents returns a page of the experiment's window events, up to
+// query.Limit per arm. A cursor from a previous page continues only the arms
+// that had events left.
 func (e *AdvancedBanditEngine) ExportWindowEvents(
 	ctx context.Context,
 	experimentID uuid.UUID,
-	limit int64,
-) (map[uuid.UUID][]RewardEvent, error) {
-	windowStrategy, err := e.getWindowStrategy(ctx, experimentID)
+	query WindowExportQuery,
+) (*WindowExportPage, error) {
+	windowStrategy, armIDs, err := e.windowExportArms(ctx, experimentID, query.ArmIDs)
 	if err != nil {
 		return nil, err
 	}
 
-	arms, err := e.repo.GetArms(ctx, experimentID)
-	if err != nil {
-		return nil, err
+	var cursors map[uuid.UUID]*WindowCursor
+	if query.Cursor != "" {
+		if cursors, err = decodeWindowExportCursor(query.Cursor); err != nil {
+			return nil, err
+		}
 	}
 
-	result := make(map[uuid.UUID][]RewardEvent, len(arms))
-	for _, arm := range arms {
-		events, exportErr := windowStrategy.ExportEvents(ctx, arm.ID, limit)
+	page := &WindowExportPage{Events: make(map[uuid.UUID][]RewardEvent, len(armIDs))}
+	next := make(map[uuid.UUID]*WindowCursor)
+	for _, armID := range armIDs {
+		armQuery := WindowEventQuery{Since: query.Since, Until: query.Until, Limit: query.Limit}
+		if cursors != nil {
+			cursor, ok := cursors[armID]
+			if !ok {
+				continue
+			}
+			armQuery.Cursor = cursor
+		}
+		events, cursor, exportErr := windowStrategy.ExportEvents(ctx, armID, armQuery)
 		if exportErr != nil {
 			return nil, exportErr
 		}
-		result[arm.ID] = events
+		page.Events[armID] = events
+		if cursor != nil {
+			next[armID] = cursor
+		}
 	}
 
-	return result, nil
+	page.NextCursor, err = encodeWindowExportCursor(next)
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// StreamWindowEvents pages through every selected arm's window events,
+// ignoring query.Cursor, and hands each page to emit
+func (e *AdvancedBanditEngine) StreamWindowEvents(
+	ctx context.Context,
+	experimentID uuid.UUID,
+	query WindowExportQuery,
+	emit func(armID uuid.UUID, events []RewardEvent) error,
+) error {
+	windowStrategy, armIDs, err := e.windowExportArms(ctx, experimentID, query.ArmIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, armID := range armIDs {
+		armQuery := WindowEventQuery{Since: query.Since, Until: query.Until, Limit: MaxWindowExportLimit}
+		for {
+			events, cursor, exportErr := windowStrategy.ExportEvents(ctx, armID, armQuery)
+			if exportErr != nil {
+				return exportErr
+			}
+			if len(events) > 0 {
+				if err := emit(armID, events); err != nil {
+					return err
+				}
+			}
+			if cursor == nil {
+				break
+			}
+			armQuery.Cursor = cursor
+		}
+	}
+	return nil
+}
+
+// windowExportArms returns the experiment's window manager and the arms to
+// export: filter, if set, else every arm. Arms not in the experiment are
+// rejected with ErrBanditArmNotFound.
+func (e *AdvancedBanditEngine) windowExportArms(
+	ctx context.Context,
+	experimentID uuid.UUID,
+	filter []uuid.UUID,
+) (windowManager, []uuid.UUID, error) {
+	windowStrategy, err := e.getWindowManager(ctx, experimentID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	arms, err := e.repo.GetArms(ctx, experimentID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	known := make(map[uuid.UUID]bool, len(arms))
+	armIDs := make([]uuid.UUID, 0, len(arms))
+	for _, arm := range arms {
+		known[arm.ID] = true
+		armIDs = append(armIDs, arm.ID)
+	}
+	if len(filter) == 0 {
+		return windowStrategy, armIDs, nil
+	}
+	for _, armID := range filter {
+		if !known[armID] {
+			return nil, nil, fmt.Errorf("%w: %s", ErrBanditArmNotFound, armID)
+		}
+	}
+	return windowStrategy, filter, nil
 }
 
 func (e *AdvancedBanditEngine) GetObjectiveConfig(ctx context.Context, experimentID uuid.UUID) (*ExperimentConfig, error) {
@@ -736,13 +1206,14 @@ func (e *AdvancedBanditEngine) GetPendingReward(ctx context.Context, pendingID u
 func (e *AdvancedBanditEngine) GetUserPendingRewards(
 	ctx context.Context,
 	userID uuid.UUID,
+	experimentID *uuid.UUID,
 ) ([]*PendingReward, error) {
 	delayedStrategy, err := e.getDelayedStrategy()
 	if err != nil {
 		return nil, err
 	}
 
-	return delayedStrategy.GetPendingRewardsByUser(ctx, userID, uuid.Nil)
+	return delayedStrategy.GetPendingRewardsByUser(ctx, userID, experimentID)
 }
 
 // calculateBalanceIndex measures how evenly users are distributed
@@ -794,12 +1265,6 @@ type BanditMetrics struct {
 func (e *AdvancedBanditEngine) RunMaintenanceDetailed(ctx context.Context) (*BanditMaintenanceSummary, error) {
 	summary := &BanditMaintenanceSummary{}
 
-	processed, err := e.ProcessExpiredPendingRewards(ctx, defaultBanditMaintenanceBatchSize)
-	if err != nil {
-		return summary, err
-	}
-	summary.ExpiredPendingRewards = processed
-
 	if e.currencyService != nil {
 		if err := e.currencyService.UpdateRates(ctx); err != nil {
 			return summary, err
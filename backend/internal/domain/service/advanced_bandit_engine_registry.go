@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// defaultEngineRegistryTTL is how long an experiment's engine is used before
+// its config is re-read, which bounds how late changes made by another
+// process are picked up
+const defaultEngineRegistryTTL = time.Minute
+
+// AdvancedBanditEngineRegistry hands out one AdvancedBanditEngine per
+// experiment, built from the experiment's own contextual, window, delayed
+// and currency settings. Features disabled in the registry's EngineConfig
+// stay off for every experiment.
+type AdvancedBanditEngineRegistry struct {
+	base            *ThompsonSamplingBandit
+	repo            BanditRepository
+	cache           BanditCache
+	redisClient     *redis.Client
+	currencyService *CurrencyRateService
+	logger          *zap.Logger
+	features        EngineConfig
+	options         []func(*AdvancedBanditEngine)
+	ttl             time.Duration
+	now             func() time.Time
+	shared          *AdvancedBanditEngine
+
+	mu      sync.Mutex
+	engines map[uuid.UUID]*experimentEngine
+}
+
+// experimentEngine is an experiment's engine and the config it was built from
+type experimentEngine struct {
+	engine    *AdvancedBanditEngine
+	config    *ExperimentConfig
+	checkedAt time.Time
+}
+
+// NewAdvancedBanditEngineRegistry creates a registry. features selects the
+// enabled features; its ExperimentConfig is ignored, each experiment's comes
+// from the repository.
+func NewAdvancedBanditEngineRegistry(
+	base *ThompsonSamplingBandit,
+	repo BanditRepository,
+	cache BanditCache,
+	redisClient *redis.Client,
+	currencyService *CurrencyRateService,
+	logger *zap.Logger,
+	features *EngineConfig,
+) *AdvancedBanditEngineRegistry {
+	r := &AdvancedBanditEngineRegistry{
+		base:            base,
+		repo:            repo,
+		cache:           cache,
+		redisClient:     redisClient,
+		currencyService: currencyService,
+		logger:          logger,
+		ttl:             defaultEngineRegistryTTL,
+		now:             time.Now,
+		engines:         make(map[uuid.UUID]*experimentEngine),
+	}
+	if features != nil {
+		r.features = *features
+		r.features.ExperimentConfig = nil
+	}
+	r.shared = r.build(nil)
+	return r
+}
+
+// WithTTL sets how long an engine is used before its experiment's config is
+// checked for changes
+func (r *AdvancedBanditEngineRegistry) WithTTL(ttl time.Duration) *AdvancedBanditEngineRegistry {
+	r.ttl = ttl
+	return r
+}
+
+// WithRevenueBasis applies AdvancedBanditEngine.WithRevenueBasis to every engine
+func (r *AdvancedBanditEngineRegistry) WithRevenueBasis(basis RevenueBasis, schedule StoreFeeSchedule) *AdvancedBanditEngineRegistry {
+	return r.with(func(e *AdvancedBanditEngine) { e.WithRevenueBasis(basis, schedule) })
+}
+
+// WithConsent applies AdvancedBanditEngine.WithConsent to every engine
+func (r *AdvancedBanditEngineRegistry) WithConsent(consent ConsentChecker) *AdvancedBanditEngineRegistry {
+	return r.with(func(e *AdvancedBanditEngine) { e.WithConsent(consent) })
+}
+
+func (r *AdvancedBanditEngineRegistry) with(option func(*AdvancedBanditEngine)) *AdvancedBanditEngineRegistry {
+	r.options = append(r.options, option)
+	option(r.shared)
+	r.mu.Lock()
+	clear(r.engines)
+	r.mu.Unlock()
+	return r
+}
+
+// Shared returns the engine for work that isn't tied to one experiment's
+// config, such as maintenance and expired reward sweeps
+func (r *AdvancedBanditEngineRegistry) Shared() *AdvancedBanditEngine {
+	return r.shared
+}
+
+// For returns the experiment's engine. Once the TTL passed the config is
+// re-read and the engine rebuilt if it changed; if the re-read fails the
+// current engine stays in use.
+func (r *AdvancedBanditEngineRegistry) For(ctx context.Context, experimentID uuid.UUID) (*AdvancedBanditEngine, error) {
+	now := r.now()
+	r.mu.Lock()
+	current, ok := r.engines[experimentID]
+	r.mu.Unlock()
+	if ok && now.Sub(current.checkedAt) < r.ttl {
+		return current.engine, nil
+	}
+
+	config, err := r.repo.GetExperimentConfig(ctx, experimentID)
+	if err != nil {
+		if ok {
+			r.logger.Warn("Failed to reload experiment config, keeping current engine",
+				zap.String("experiment_id", experimentID.String()), zap.Error(err))
+			return current.engine, nil
+		}
+		return nil, fmt.Errorf("failed to load experiment config: %w", err)
+	}
+
+	next := &experimentEngine{config: config, checkedAt: now}
+	if ok && reflect.DeepEqual(current.config, config) {
+		next.engine = current.engine
+	} else {
+		next.engine = r.build(config)
+		if ok {
+			r.logger.Info("Experiment config changed, rebuilt bandit engine",
+				zap.String("experiment_id", experimentID.String()))
+		}
+	}
+
+	r.mu.Lock()
+	r.engines[experimentID] = next
+	r.mu.Unlock()
+	return next.engine, nil
+}
+
+// Invalidate makes the next For rebuild the experiment's engine from its
+// current config. Call it after updating the experiment's config.
+func (r *AdvancedBanditEngineRegistry) Invalidate(experimentID uuid.UUID) {
+	r.mu.Lock()
+	delete(r.engines, experimentID)
+	r.mu.Unlock()
+	r.shared.InvalidateExperimentConfig(experimentID)
+}
+
+// SelectArm selects an arm with the experiment's engine
+func (r *AdvancedBanditEngineRegistry) SelectArm(ctx context.Context, experimentID, userID uuid.UUID, userContext UserContext) (uuid.UUID, error) {
+	engine, err := r.For(ctx, experimentID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return engine.SelectArm(ctx, experimentID, userID, userContext)
+}
+
+// RecordReward records a reward with the experiment's engine
+func (r *AdvancedBanditEngineRegistry) RecordReward(
+	ctx context.Context,
+	experimentID, armID, userID uuid.UUID,
+	reward float64,
+	currency string,
+	userContext UserContext,
+) error {
+	engine, err := r.For(ctx, experimentID)
+	if err != nil {
+		return err
+	}
+	return engine.RecordReward(ctx, experimentID, armID, userID, reward, currency, userContext)
+}
+
+// RecordProductReward records a reward for a purchase of productID with the
+// experiment's engine, so a margin basis can subtract the product's cost
+func (r *AdvancedBanditEngineRegistry) RecordProductReward(
+	ctx context.Context,
+	experimentID, armID, userID uuid.UUID,
+	transactionID *uuid.UUID,
+	productID string,
+	reward float64,
+	currency string,
+	userContext UserContext,
+) error {
+	engine, err := r.For(ctx, experimentID)
+	if err != nil {
+		return err
+	}
+	return engine.RecordProductReward(ctx, experimentID, armID, userID, transactionID, productID, reward, currency, userContext)
+}
+
+// RecordConversion records a yes/no outcome as a success with the
+// experiment's engine
+func (r *AdvancedBanditEngineRegistry) RecordConversion(
+	ctx context.Context,
+	experimentID, armID, userID uuid.UUID,
+	userContext UserContext,
+) error {
+	engine, err := r.For(ctx, experimentID)
+	if err != nil {
+		return err
+	}
+	return engine.RecordConversion(ctx, experimentID, armID, userID, userContext)
+}
+
+// build creates an engine for config, which may be nil
+func (r *AdvancedBanditEngineRegistry) build(config *ExperimentConfig) *AdvancedBanditEngine {
+	features := r.features
+	if config != nil {
+		// The engine's strategies may fill in defaults; keep the original
+		// for change detection
+		engineConfig := *config
+		features.ExperimentConfig = &engineConfig
+	}
+	engine := NewAdvancedBanditEngine(r.base, r.repo, r.cache, r.redisClient, r.currencyService, r.logger, &features)
+	for _, option := range r.options {
+		option(engine)
+	}
+	return engine
+}
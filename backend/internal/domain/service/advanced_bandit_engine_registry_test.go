@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// flakyConfigRepo fails config reads while err is set
+type flakyConfigRepo struct {
+	advancedEngineTestRepo
+	err error
+}
+
+func (r *flakyConfigRepo) GetExperimentConfig(ctx context.Context, experimentID uuid.UUID) (*ExperimentConfig, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	return r.advancedEngineTestRepo.GetExperimentConfig(ctx, experimentID)
+}
+
+func newTestEngineRegistry(repo BanditRepository) (*AdvancedBanditEngineRegistry, *time.Time) {
+	cache := &advancedEngineTestCache{}
+	registry := NewAdvancedBanditEngineRegistry(NewThompsonSamplingBandit(repo, cache, zap.NewNop()), repo, cache, nil, nil, zap.NewNop(),
+		&EngineConfig{EnableContextual: true, EnableDelayed: true, EnableHybrid: true})
+	now := time.Now()
+	registry.now = func() time.Time { return now }
+	return registry, &now
+}
+
+func TestAdvancedBanditEngineRegistry_BuildsEnginePerExperimentConfig(t *testing.T) {
+	experimentID := uuid.New()
+	repo := &advancedEngineTestRepo{experimentConfig: &ExperimentConfig{ID: experimentID, EnableContextual: true, ExplorationAlpha: 0.7}}
+	registry, _ := newTestEngineRegistry(repo)
+
+	engine, err := registry.For(context.Background(), experimentID)
+	require.NoError(t, err)
+	linucb, ok := engine.selectionStrategy.(*LinUCBSelectionStrategy)
+	require.True(t, ok, "contextual experiments select with LinUCB")
+	assert.Equal(t, 0.7, linucb.alpha)
+	assert.Nil(t, registry.Shared().selectionStrategy)
+
+	again, err := registry.For(context.Background(), experimentID)
+	require.NoError(t, err)
+	assert.Same(t, engine, again)
+	assert.Equal(t, 1, repo.configReads)
+}
+
+func TestAdvancedBanditEngineRegistry_RebuildsOnlyWhenConfigChanged(t *testing.T) {
+	experimentID := uuid.New()
+	repo := &advancedEngineTestRepo{experimentConfig: &ExperimentConfig{ID: experimentID, ObjectiveType: ObjectiveConversion}}
+	registry, now := newTestEngineRegistry(repo)
+
+	first, err := registry.For(context.Background(), experimentID)
+	require.NoError(t, err)
+
+	*now = now.Add(defaultEngineRegistryTTL)
+	unchanged, err := registry.For(context.Background(), experimentID)
+	require.NoError(t, err)
+	assert.Same(t, first, unchanged)
+	assert.Equal(t, 2, repo.configReads)
+
+	repo.experimentConfig.EnableContextual = true
+	cached, err := registry.For(context.Background(), experimentID)
+	require.NoError(t, err)
+	assert.Same(t, first, cached, "changes are picked up once the TTL passes")
+
+	*now = now.Add(defaultEngineRegistryTTL)
+	rebuilt, err := registry.For(context.Background(), experimentID)
+	require.NoError(t, err)
+	assert.NotSame(t, first, rebuilt)
+	assert.NotNil(t, rebuilt.selectionStrategy)
+}
+
+func TestAdvancedBanditEngineRegistry_Invalidate(t *testing.T) {
+	experimentID := uuid.New()
+	repo := &advancedEngineTestRepo{experimentConfig: &ExperimentConfig{ID: experimentID}}
+	registry, _ := newTestEngineRegistry(repo)
+
+	first, err := registry.For(context.Background(), experimentID)
+	require.NoError(t, err)
+	repo.experimentConfig.EnableContextual = true
+	registry.Invalidate(experimentID)
+
+	rebuilt, err := registry.For(context.Background(), experimentID)
+	require.NoError(t, err)
+	assert.NotSame(t, first, rebuilt)
+	assert.NotNil(t, rebuilt.selectionStrategy)
+}
+
+func TestAdvancedBanditEngineRegistry_KeepsEngineWhenReloadFails(t *testing.T) {
+	experimentID := uuid.New()
+	repo := &flakyConfigRepo{advancedEngineTestRepo: advancedEngineTestRepo{experimentConfig: &ExperimentConfig{ID: experimentID}}}
+	registry, now := newTestEngineRegistry(repo)
+
+	repo.err = errors.New("connection refused")
+	_, err := registry.For(context.Background(), experimentID)
+	require.ErrorContains(t, err, "connection refused")
+
+	repo.err = nil
+	engine, err := registry.For(context.Background(), experimentID)
+	require.NoError(t, err)
+
+	repo.err = errors.New("connection refused")
+	*now = now.Add(defaultEngineRegistryTTL)
+	kept, err := registry.For(context.Background(), experimentID)
+	require.NoError(t, err)
+	assert.Same(t, engine, kept)
+}
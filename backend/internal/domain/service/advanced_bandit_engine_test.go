@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"net/http"
 	"sort"
 	"testing"
 	"time"
@@ -10,20 +11,26 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/clock"
 )
 
 type advancedEngineTestRepo struct {
 	experimentConfig      *ExperimentConfig
+	configReads           int
 	updatedConfig         *ExperimentConfig
 	arms                  []Arm
 	armStats              map[uuid.UUID]*ArmStats
 	pendingReward         *PendingReward
 	userRewards           []*PendingReward
+	userRewardsFilters    []*uuid.UUID
+	createdPending        []*PendingReward
 	windowExperiments     []uuid.UUID
 	objectiveExperiments  []uuid.UUID
 	updatedObjectiveStats []*ArmObjectiveStats
 	deletedContexts       int64
 	deletedAssignments    int64
+	device                string
 }
 
 func (r *advancedEngineTestRepo) GetArms(ctx context.Context, experimentID uuid.UUID) ([]Arm, error) {
@@ -47,19 +54,37 @@ func (r *advancedEngineTestRepo) GetActiveAssignment(ctx context.Context, experi
 	return nil, nil
 }
 func (r *advancedEngineTestRepo) GetExperimentConfig(ctx context.Context, experimentID uuid.UUID) (*ExperimentConfig, error) {
-	return r.experimentConfig, nil
+	r.configReads++
+	if r.experimentConfig == nil {
+		return nil, nil
+	}
+	config := *r.experimentConfig
+	return &config, nil
 }
 func (r *advancedEngineTestRepo) UpdateObjectiveConfig(ctx context.Context, experimentID uuid.UUID, objectiveType ObjectiveType, objectiveWeights map[string]float64) error {
 	r.updatedConfig = &ExperimentConfig{ID: experimentID, ObjectiveType: objectiveType, ObjectiveWeights: objectiveWeights}
+	if r.experimentConfig == nil {
+		r.experimentConfig = &ExperimentConfig{ID: experimentID}
+	}
+	r.experimentConfig.ObjectiveType = objectiveType
+	r.experimentConfig.ObjectiveWeights = objectiveWeights
+	return nil
+}
+func (r *advancedEngineTestRepo) UpdateRewardBasisConfig(ctx context.Context, experimentID uuid.UUID, basis RevenueBasis, productCosts map[string]float64) error {
+	if r.experimentConfig == nil {
+		r.experimentConfig = &ExperimentConfig{ID: experimentID}
+	}
+	r.experimentConfig.RewardBasis = basis
+	r.experimentConfig.ProductCosts = productCosts
 	return nil
 }
 func (r *advancedEngineTestRepo) GetUserContext(ctx context.Context, userID uuid.UUID) (*UserContext, error) {
-	return &UserContext{UserID: userID}, nil
+	return &UserContext{UserID: userID, Device: r.device}, nil
 }
 func (r *advancedEngineTestRepo) SetUserContext(ctx context.Context, uctx *UserContext) error {
 	return nil
 }
-func (r *advancedEngineTestRepo) GetObjectiveStats(ctx context.Context, armID uuid.UUID, objectiveType ObjectiveType) (*ArmObjectiveStats, error) {
+func (r *advancedEngineTestRepo) GetObjectiveStats(ctx context.Context, armID uuid.UUID, objectiveType ObjectiveType, basis RevenueBasis) (*ArmObjectiveStats, error) {
 	stats := r.armStats[armID]
 	return &ArmObjectiveStats{ArmID: armID, ObjectiveType: objectiveType, AvgLTV: stats.AvgReward}, nil
 }
@@ -67,7 +92,7 @@ func (r *advancedEngineTestRepo) UpdateObjectiveStats(ctx context.Context, stats
 	r.updatedObjectiveStats = append(r.updatedObjectiveStats, stats)
 	return nil
 }
-func (r *advancedEngineTestRepo) GetAllObjectiveStats(ctx context.Context, armID uuid.UUID) (map[ObjectiveType]*ArmObjectiveStats, error) {
+func (r *advancedEngineTestRepo) GetAllObjectiveStats(ctx context.Context, armID uuid.UUID, basis RevenueBasis) (map[ObjectiveType]*ArmObjectiveStats, error) {
 	stats := r.armStats[armID]
 	return map[ObjectiveType]*ArmObjectiveStats{
 		ObjectiveConversion: {ArmID: armID, ObjectiveType: ObjectiveConversion, Alpha: stats.Alpha, Beta: stats.Beta, Samples: stats.Samples, Conversions: stats.Conversions, TotalRevenue: stats.Revenue, AvgLTV: stats.AvgReward},
@@ -77,13 +102,24 @@ func (r *advancedEngineTestRepo) GetAllObjectiveStats(ctx context.Context, armID
 	}, nil
 }
 func (r *advancedEngineTestRepo) CreatePendingReward(ctx context.Context, reward *PendingReward) error {
+	r.createdPending = append(r.createdPending, reward)
 	return nil
 }
 func (r *advancedEngineTestRepo) GetPendingReward(ctx context.Context, id uuid.UUID) (*PendingReward, error) {
 	return r.pendingReward, nil
 }
-func (r *advancedEngineTestRepo) GetPendingRewardsByUser(ctx context.Context, userID, experimentID uuid.UUID) ([]*PendingReward, error) {
-	return r.userRewards, nil
+func (r *advancedEngineTestRepo) GetPendingRewardsByUser(ctx context.Context, userID uuid.UUID, experimentID *uuid.UUID) ([]*PendingReward, error) {
+	r.userRewardsFilters = append(r.userRewardsFilters, experimentID)
+	if experimentID == nil {
+		return r.userRewards, nil
+	}
+	var filtered []*PendingReward
+	for _, reward := range r.userRewards {
+		if reward.ExperimentID == *experimentID {
+			filtered = append(filtered, reward)
+		}
+	}
+	return filtered, nil
 }
 func (r *advancedEngineTestRepo) GetExpiredPendingRewards(ctx context.Context, limit int) ([]*PendingReward, error) {
 	return nil, nil
@@ -124,6 +160,15 @@ func (c *advancedEngineTestCache) GetAssignment(ctx context.Context, key string)
 func (c *advancedEngineTestCache) SetAssignment(ctx context.Context, key string, armID uuid.UUID, ttl time.Duration) error {
 	return nil
 }
+func (c *advancedEngineTestCache) DeleteKey(ctx context.Context, key string) error {
+	return nil
+}
+func (c *advancedEngineTestCache) SetBytes(ctx context.Context, key string, data []byte, ttl time.Duration) error {
+	return nil
+}
+func (c *advancedEngineTestCache) GetBytes(ctx context.Context, key string) ([]byte, error) {
+	return nil, nil
+}
 
 func TestAdvancedBanditEngine_GetObjectiveScores_LazilyLoadsExperimentConfig(t *testing.T) {
 	experimentID := uuid.New()
@@ -185,7 +230,7 @@ func TestAdvancedBanditEngine_GetPendingReward_UsesLazyDelayedStrategy(t *testin
 	engine := NewAdvancedBanditEngine(base, repo, cache, nil, nil, zap.NewNop(), &EngineConfig{EnableDelayed: true})
 
 	pendingReward, err := engine.GetPendingReward(context.Background(), pendingID)
-	rewards, rewardsErr := engine.GetUserPendingRewards(context.Background(), userID)
+	rewards, rewardsErr := engine.GetUserPendingRewards(context.Background(), userID, nil)
 
 	require.NoError(t, err)
 	require.NoError(t, rewardsErr)
@@ -194,6 +239,28 @@ func TestAdvancedBanditEngine_GetPendingReward_UsesLazyDelayedStrategy(t *testin
 	require.Equal(t, pendingID, rewards[0].ID)
 }
 
+func TestAdvancedBanditEngine_GetUserPendingRewards_FiltersByExperiment(t *testing.T) {
+	userID, experimentID, otherID := uuid.New(), uuid.New(), uuid.New()
+	repo := &advancedEngineTestRepo{userRewards: []*PendingReward{
+		{ID: uuid.New(), UserID: userID, ExperimentID: experimentID},
+		{ID: uuid.New(), UserID: userID, ExperimentID: otherID},
+	}}
+	cache := &advancedEngineTestCache{}
+	base := NewThompsonSamplingBandit(repo, cache, zap.NewNop())
+	engine := NewAdvancedBanditEngine(base, repo, cache, nil, nil, zap.NewNop(), &EngineConfig{EnableDelayed: true})
+
+	all, err := engine.GetUserPendingRewards(context.Background(), userID, nil)
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+
+	filtered, err := engine.GetUserPendingRewards(context.Background(), userID, &experimentID)
+	require.NoError(t, err)
+	require.Len(t, filtered, 1)
+	require.Equal(t, experimentID, filtered[0].ExperimentID)
+
+	require.Equal(t, []*uuid.UUID{nil, &experimentID}, repo.userRewardsFilters)
+}
+
 func TestAdvancedBanditEngine_SetObjectiveConfig_NormalizesHybridWeights(t *testing.T) {
 	experimentID := uuid.New()
 	repo := &advancedEngineTestRepo{}
@@ -215,6 +282,174 @@ func TestAdvancedBanditEngine_SetObjectiveConfig_NormalizesHybridWeights(t *test
 	require.InDelta(t, 0.2, config.ObjectiveWeights["revenue"], 0.0001)
 }
 
+func TestAdvancedBanditEngine_SetObjectiveConfig_RefreshesCachedConfig(t *testing.T) {
+	experimentID := uuid.New()
+	repo := &advancedEngineTestRepo{experimentConfig: &ExperimentConfig{ID: experimentID, ObjectiveType: ObjectiveConversion}}
+	cache := &advancedEngineTestCache{}
+	base := NewThompsonSamplingBandit(repo, cache, zap.NewNop())
+	engine := NewAdvancedBanditEngine(base, repo, cache, nil, nil, zap.NewNop(), &EngineConfig{EnableHybrid: true})
+
+	for i := 0; i < 3; i++ {
+		config, err := engine.GetObjectiveConfig(context.Background(), experimentID)
+		require.NoError(t, err)
+		require.Equal(t, ObjectiveConversion, config.ObjectiveType)
+	}
+	require.Equal(t, 1, repo.configReads, "config is served from memory within its TTL")
+
+	_, err := engine.SetObjectiveConfig(context.Background(), experimentID, ObjectiveRevenue, nil)
+	require.NoError(t, err)
+
+	config, err := engine.GetObjectiveConfig(context.Background(), experimentID)
+	require.NoError(t, err)
+	require.Equal(t, ObjectiveRevenue, config.ObjectiveType)
+	require.Equal(t, 2, repo.configReads)
+
+	// Changes made elsewhere show up once invalidated
+	repo.experimentConfig.ObjectiveType = ObjectiveLTV
+	engine.InvalidateExperimentConfig(experimentID)
+	config, err = engine.GetObjectiveConfig(context.Background(), experimentID)
+	require.NoError(t, err)
+	require.Equal(t, ObjectiveLTV, config.ObjectiveType)
+}
+
+func TestAdvancedBanditEngine_SetObjectiveConfig_RejectsInvalidWeights(t *testing.T) {
+	experimentID := uuid.New()
+	repo := &advancedEngineTestRepo{}
+	cache := &advancedEngineTestCache{}
+	base := NewThompsonSamplingBandit(repo, cache, zap.NewNop())
+	engine := NewAdvancedBanditEngine(base, repo, cache, nil, nil, zap.NewNop(), &EngineConfig{EnableHybrid: true})
+
+	for _, weights := range []map[string]float64{
+		{"conversion": -1, "revenue": 2},
+		{"conversion": 0, "ltv": 0},
+	} {
+		_, err := engine.SetObjectiveConfig(context.Background(), experimentID, ObjectiveHybrid, weights)
+		require.Error(t, err, "%v", weights)
+	}
+	_, err := engine.SetObjectiveConfig(context.Background(), experimentID, "engagement", nil)
+	require.Error(t, err)
+	require.Nil(t, repo.updatedConfig, "nothing is persisted")
+}
+
+func TestAdvancedBanditEngine_ApplyRewardBasis(t *testing.T) {
+	engine := NewAdvancedBanditEngine(nil, &advancedEngineTestRepo{}, nil, nil, nil, zap.NewNop(), nil)
+	ios := UserContext{Device: "ios"}
+
+	gross := &ExperimentConfig{RewardBasis: RevenueBasisGross}
+	net := &ExperimentConfig{RewardBasis: RevenueBasisNet}
+	margin := &ExperimentConfig{RewardBasis: RevenueBasisMargin, ProductCosts: map[string]float64{"pro_monthly": 1.50}}
+
+	require.InDelta(t, 10.0, engine.applyRewardBasis(gross, ObjectiveRevenue, "pro_monthly", 10, ios), 0.0001)
+	require.InDelta(t, 7.0, engine.applyRewardBasis(net, ObjectiveRevenue, "pro_monthly", 10, ios), 0.0001)
+	require.InDelta(t, 5.5, engine.applyRewardBasis(margin, ObjectiveLTV, "pro_monthly", 10, ios), 0.0001)
+	require.InDelta(t, 7.0, engine.applyRewardBasis(margin, ObjectiveRevenue, "unknown_product", 10, ios), 0.0001)
+	require.InDelta(t, 8.5, engine.applyRewardBasis(net, ObjectiveRevenue, "", 10, UserContext{Device: "android"}), 0.0001)
+
+	// Conversion and hybrid rewards count purchases, so fees never apply
+	require.InDelta(t, 10.0, engine.applyRewardBasis(margin, ObjectiveConversion, "pro_monthly", 10, ios), 0.0001)
+	require.InDelta(t, 10.0, engine.applyRewardBasis(net, ObjectiveHybrid, "pro_monthly", 10, ios), 0.0001)
+}
+
+func TestAdvancedBanditEngine_FallbackConfigIsGross(t *testing.T) {
+	experimentID := uuid.New()
+	engine := NewAdvancedBanditEngine(nil, &advancedEngineTestRepo{}, nil, nil, nil, zap.NewNop(), nil).
+		WithRevenueBasis(RevenueBasisNet, DefaultStoreFeeSchedule())
+
+	config, err := engine.getExperimentConfig(context.Background(), experimentID)
+
+	require.NoError(t, err)
+	require.Equal(t, RevenueBasisGross, config.RewardBasis, "an unknown experiment does not inherit the global basis")
+}
+
+func TestAdvancedBanditEngine_RecordConversion_IgnoresMarginBasis(t *testing.T) {
+	experimentID := uuid.New()
+	armID := uuid.New()
+	stats := &ArmStats{ArmID: armID, Alpha: 1, Beta: 1}
+	repo := &advancedEngineTestRepo{
+		experimentConfig: &ExperimentConfig{
+			ID:            experimentID,
+			ObjectiveType: ObjectiveConversion,
+			RewardBasis:   RevenueBasisMargin,
+			ProductCosts:  map[string]float64{"pro_monthly": 5},
+		},
+		armStats: map[uuid.UUID]*ArmStats{armID: stats},
+	}
+	cache := &advancedEngineTestCache{}
+	base := NewThompsonSamplingBandit(repo, cache, zap.NewNop())
+	engine := NewAdvancedBanditEngine(base, repo, cache, nil, nil, zap.NewNop(), nil)
+
+	err := engine.RecordConversion(context.Background(), experimentID, armID, uuid.New(), UserContext{Device: "ios"})
+
+	require.NoError(t, err)
+	assert.Equal(t, 2.0, stats.Alpha, "a conversion is a success whatever the reward basis")
+	assert.Equal(t, 1.0, stats.Beta)
+	assert.InDelta(t, 1.0, stats.Revenue, 0.0001)
+}
+
+func TestAdvancedBanditEngine_ProcessConversion_AppliesCurrencyAndRewardBasis(t *testing.T) {
+	experimentID := uuid.New()
+	armID := uuid.New()
+	userID := uuid.New()
+	stats := &ArmStats{ArmID: armID, Alpha: 1, Beta: 1}
+	repo := &advancedEngineTestRepo{
+		experimentConfig: &ExperimentConfig{
+			ID:             experimentID,
+			ObjectiveType:  ObjectiveRevenue,
+			RewardBasis:    RevenueBasisNet,
+			EnableCurrency: true,
+		},
+		armStats: map[uuid.UUID]*ArmStats{armID: stats},
+		userRewards: []*PendingReward{{
+			ID: uuid.New(), ExperimentID: experimentID, ArmID: armID, UserID: userID,
+			AssignedAt: time.Now().Add(-time.Hour), ExpiresAt: time.Now().Add(time.Hour),
+		}},
+		device: "ios",
+	}
+	currencies := newTestCurrencyService(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(testECBResponse))
+	})
+	require.NoError(t, currencies.WarmRates(context.Background()))
+	cache := &advancedEngineTestCache{}
+	base := NewThompsonSamplingBandit(repo, cache, zap.NewNop())
+	engine := NewAdvancedBanditEngine(base, repo, cache, nil, currencies, zap.NewNop(), &EngineConfig{EnableDelayed: true, EnableCurrency: true})
+
+	// 8 GBP is 10 USD, and the App Store keeps 30%
+	err := engine.ProcessConversion(context.Background(), uuid.New(), userID, 8, "GBP")
+
+	require.NoError(t, err)
+	assert.Equal(t, 2.0, stats.Alpha)
+	assert.InDelta(t, 7.0, stats.Revenue, 0.0001)
+}
+
+func TestAdvancedBanditEngine_SetRewardBasis(t *testing.T) {
+	experimentID := uuid.New()
+	repo := &advancedEngineTestRepo{}
+	engine := NewAdvancedBanditEngine(nil, repo, nil, nil, nil, zap.NewNop(), nil).
+		WithRevenueBasis(RevenueBasisNet, DefaultStoreFeeSchedule())
+
+	_, err := engine.SetRewardBasis(context.Background(), experimentID, "profit", nil)
+	require.Error(t, err)
+
+	_, err = engine.SetRewardBasis(context.Background(), experimentID, RevenueBasisMargin, map[string]float64{"pro": -1})
+	require.Error(t, err)
+
+	config, err := engine.SetRewardBasis(context.Background(), experimentID, RevenueBasisNet, map[string]float64{"pro": 2})
+	require.NoError(t, err)
+	require.Equal(t, RevenueBasisNet, config.RewardBasis)
+	require.Nil(t, config.ProductCosts, "product costs only apply to the margin basis")
+
+	repo.experimentConfig.RewardBasis = ""
+	config, err = engine.GetObjectiveConfig(context.Background(), experimentID)
+	require.NoError(t, err)
+	require.Equal(t, RevenueBasisNet, config.RewardBasis, "engine default applies when the experiment has none")
+}
+
+func TestObjectiveStatsBasis_ConversionAlwaysGross(t *testing.T) {
+	require.Equal(t, RevenueBasisGross, ObjectiveStatsBasis(ObjectiveConversion, RevenueBasisMargin))
+	require.Equal(t, RevenueBasisMargin, ObjectiveStatsBasis(ObjectiveRevenue, RevenueBasisMargin))
+	require.Equal(t, RevenueBasisGross, ObjectiveStatsBasis(ObjectiveLTV, ""))
+}
+
 func TestAdvancedBanditEngine_SyncObjectiveStats_UsesConfiguredHybridObjectives(t *testing.T) {
 	experimentID := uuid.New()
 	firstArmID := uuid.New()
@@ -249,12 +484,14 @@ func TestAdvancedBanditEngine_SyncObjectiveStats_UsesConfiguredHybridObjectives(
 			seen = append(seen, stat.ArmID.String()+":"+string(stat.ObjectiveType))
 		}
 		sort.Strings(seen)
-		assert.Equal(t, []string{
+		expected := []string{
 			firstArmID.String() + ":conversion",
 			firstArmID.String() + ":ltv",
 			secondArmID.String() + ":conversion",
 			secondArmID.String() + ":ltv",
-		}, seen)
+		}
+		sort.Strings(expected)
+		assert.Equal(t, expected, seen)
 	}
 }
 
@@ -286,3 +523,130 @@ func TestAdvancedBanditEngine_RunMaintenanceDetailed_ReturnsRepositoryBackedSumm
 	assert.EqualValues(t, 3, summary.StaleContextsDeleted)
 	assert.EqualValues(t, 2, summary.ExpiredAssignmentsDeleted)
 }
+
+// conversionWindowTestRepo records conversion window updates
+type conversionWindowTestRepo struct {
+	advancedEngineTestRepo
+
+	window, effective time.Duration
+}
+
+func (r *conversionWindowTestRepo) UpdateConversionWindow(ctx context.Context, experimentID uuid.UUID, window, effective time.Duration) error {
+	r.window, r.effective = window, effective
+	r.experimentConfig = &ExperimentConfig{ID: experimentID, ConversionWindow: window}
+	return nil
+}
+
+func TestDelayedRewardStrategy_RecordPendingRewardUsesExperimentWindow(t *testing.T) {
+	repo := &advancedEngineTestRepo{}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	strategy := NewDelayedRewardStrategy(repo, &advancedEngineTestCache{}, zap.NewNop()).WithClock(clock.NewFake(now))
+
+	for _, tc := range []struct {
+		window, want time.Duration
+	}{
+		{window: 6 * time.Hour, want: 6 * time.Hour},
+		{window: 0, want: 7 * 24 * time.Hour},
+		{window: 90 * 24 * time.Hour, want: 30 * 24 * time.Hour},
+	} {
+		pending, err := strategy.RecordPendingReward(context.Background(), uuid.New(), uuid.New(), uuid.New(), tc.window)
+		require.NoError(t, err)
+		require.Equal(t, now, pending.AssignedAt)
+		require.Equal(t, now.Add(tc.want), pending.ExpiresAt)
+	}
+	require.Len(t, repo.createdPending, 3)
+}
+
+func TestAdvancedBanditEngine_SetConversionWindow(t *testing.T) {
+	experimentID := uuid.New()
+	repo := &conversionWindowTestRepo{}
+	cache := &advancedEngineTestCache{}
+	base := NewThompsonSamplingBandit(repo, cache, zap.NewNop())
+	engine := NewAdvancedBanditEngine(base, repo, cache, nil, nil, zap.NewNop(), &EngineConfig{EnableDelayed: true})
+
+	config, err := engine.SetConversionWindow(context.Background(), experimentID, 48*time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, 48*time.Hour, config.ConversionWindow)
+	require.Equal(t, 48*time.Hour, repo.effective)
+
+	// Zero restores the default, which open pending rewards are re-based on
+	_, err = engine.SetConversionWindow(context.Background(), experimentID, 0)
+	require.NoError(t, err)
+	require.Zero(t, repo.window)
+	require.Equal(t, 7*24*time.Hour, repo.effective)
+
+	for _, window := range []time.Duration{-time.Hour, 90 * time.Minute, 31 * 24 * time.Hour} {
+		_, err := engine.SetConversionWindow(context.Background(), experimentID, window)
+		require.Error(t, err, "window %s", window)
+	}
+}
+
+// expiredPageTestRepo serves expired rewards by cursor and records them
+type expiredPageTestRepo struct {
+	advancedEngineTestRepo
+
+	expired   []*PendingReward
+	skip, bad uuid.UUID
+	recorded  []uuid.UUID
+}
+
+func (r *expiredPageTestRepo) GetExpiredPendingRewardsPage(ctx context.Context, after *ExpiredRewardCursor, expiredBefore time.Time, limit int) ([]*PendingReward, error) {
+	var page []*PendingReward
+	for _, reward := range r.expired {
+		if after != nil && !reward.ExpiresAt.After(after.ExpiresAt) {
+			continue
+		}
+		if len(page) == limit {
+			break
+		}
+		page = append(page, reward)
+	}
+	return page, nil
+}
+
+func (r *expiredPageTestRepo) ProcessExpiredPendingReward(ctx context.Context, pendingID uuid.UUID, processedAt time.Time) (bool, error) {
+	switch pendingID {
+	case r.skip:
+		return false, nil
+	case r.bad:
+		return false, assert.AnError
+	}
+	r.recorded = append(r.recorded, pendingID)
+	return true, nil
+}
+
+func TestAdvancedBanditEngine_ProcessExpiredPendingRewardsPage(t *testing.T) {
+	start := time.Now().Add(-time.Hour)
+	repo := &expiredPageTestRepo{}
+	for i := 0; i < 5; i++ {
+		repo.expired = append(repo.expired, &PendingReward{ID: uuid.New(), ExpiresAt: start.Add(time.Duration(i) * time.Minute)})
+	}
+	repo.skip, repo.bad = repo.expired[1].ID, repo.expired[2].ID
+	cache := &advancedEngineTestCache{}
+	base := NewThompsonSamplingBandit(repo, cache, zap.NewNop())
+	engine := NewAdvancedBanditEngine(base, repo, cache, nil, nil, zap.NewNop(), &EngineConfig{EnableDelayed: true})
+
+	paced := 0
+	pace := func(context.Context) error {
+		paced++
+		return nil
+	}
+	page, err := engine.ProcessExpiredPendingRewardsPage(context.Background(), nil, time.Now(), 3, pace)
+	require.NoError(t, err)
+	assert.Equal(t, ExpiredRewardPage{Fetched: 3, Processed: 1, Skipped: 1, Failed: 1, Next: &ExpiredRewardCursor{ExpiresAt: repo.expired[2].ExpiresAt, ID: repo.expired[2].ID}}, page)
+	assert.Equal(t, 3, paced)
+
+	// A pacer that gives up stops the page after what it already recorded
+	stopAfter := 1
+	page, err = engine.ProcessExpiredPendingRewardsPage(context.Background(), page.Next, time.Now(), 3, func(ctx context.Context) error {
+		if stopAfter == 0 {
+			return context.Canceled
+		}
+		stopAfter--
+		return nil
+	})
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, page.Processed)
+	assert.Equal(t, repo.expired[3].ID, page.Next.ID)
+	assert.Equal(t, []uuid.UUID{repo.expired[0].ID, repo.expired[3].ID}, repo.recorded)
+}
@@ -0,0 +1,360 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// BackfillStep is one kind of historical data a backfill recomputes
+type BackfillStep string
+
+const (
+	// BackfillStepAggregates recomputes daily_revenue and active_subscriptions
+	BackfillStepAggregates BackfillStep = "aggregates"
+	// BackfillStepCohorts recomputes signups and conversions per signup day and platform
+	BackfillStepCohorts BackfillStep = "cohorts"
+	// BackfillStepLTV recomputes the LTV of users who signed up in the range
+	BackfillStepLTV BackfillStep = "ltv"
+)
+
+// BackfillSteps are every step, in the order they run within a chunk
+var BackfillSteps = []BackfillStep{BackfillStepAggregates, BackfillStepCohorts, BackfillStepLTV}
+
+// Backfill run statuses
+const (
+	BackfillStatusRunning   = "running"
+	BackfillStatusCompleted = "completed"
+	BackfillStatusFailed    = "failed"
+)
+
+// MaxBackfillChunkDays bounds how many days one chunk's transaction covers
+const MaxBackfillChunkDays = 366
+
+// ErrInvalidBackfill is returned for a malformed backfill request
+var ErrInvalidBackfill = errors.New("invalid backfill")
+
+// AnalyticsBackfillRequest describes a recompute over local days From..To,
+// inclusive. A nil AppID covers every active app.
+type AnalyticsBackfillRequest struct {
+	AppID     *uuid.UUID
+	From      time.Time
+	To        time.Time
+	ChunkDays int
+	Steps     []BackfillStep
+}
+
+// Validate checks the range, chunk size and steps
+func (r AnalyticsBackfillRequest) Validate() error {
+	if r.From.IsZero() || r.To.IsZero() || r.To.Before(r.From) {
+		return fmt.Errorf("%w: from must not be after to", ErrInvalidBackfill)
+	}
+	if r.ChunkDays < 1 || r.ChunkDays > MaxBackfillChunkDays {
+		return fmt.Errorf("%w: chunk days must be 1-%d", ErrInvalidBackfill, MaxBackfillChunkDays)
+	}
+	if len(r.Steps) == 0 {
+		return fmt.Errorf("%w: at least one step is required", ErrInvalidBackfill)
+	}
+	for _, step := range r.Steps {
+		switch step {
+		case BackfillStepAggregates, BackfillStepCohorts, BackfillStepLTV:
+		default:
+			return fmt.Errorf("%w: unknown step %q", ErrInvalidBackfill, step)
+		}
+	}
+	return nil
+}
+
+// AnalyticsBackfillRun is a persisted backfill and how far it got
+type AnalyticsBackfillRun struct {
+	ID          uuid.UUID
+	AppID       *uuid.UUID
+	From        time.Time
+	To          time.Time
+	ChunkDays   int
+	Steps       []BackfillStep
+	Status      string
+	CursorDate  *time.Time
+	ChunksDone  int
+	ChunksTotal int
+	LastError   *string
+	StartedAt   time.Time
+	UpdatedAt   time.Time
+	CompletedAt *time.Time
+}
+
+// backfillChunk is an inclusive range of local days
+type backfillChunk struct {
+	From, To time.Time
+}
+
+// remainingChunks splits the days after the run's cursor into chunks
+func (r *AnalyticsBackfillRun) remainingChunks() []backfillChunk {
+	from := r.From
+	if r.CursorDate != nil {
+		from = r.CursorDate.AddDate(0, 0, 1)
+	}
+	return backfillChunks(from, r.To, r.ChunkDays)
+}
+
+func backfillChunks(from, to time.Time, chunkDays int) []backfillChunk {
+	var chunks []backfillChunk
+	for start := from; !start.After(to); start = start.AddDate(0, 0, chunkDays) {
+		end := start.AddDate(0, 0, chunkDays-1)
+		if end.After(to) {
+			end = to
+		}
+		chunks = append(chunks, backfillChunk{From: start, To: end})
+	}
+	return chunks
+}
+
+// AnalyticsBackfillService recomputes historical analytics. Days are local to
+// each app's reporting time zone, and every write is an upsert or a
+// delete-and-insert of the chunk's own rows, so chunks can be re-run.
+type AnalyticsBackfillService struct {
+	dbPool       *pgxpool.Pool
+	revenueBasis RevenueBasis
+}
+
+// NewAnalyticsBackfillService creates a backfill service computing gross LTV
+func NewAnalyticsBackfillService(dbPool *pgxpool.Pool) *AnalyticsBackfillService {
+	return &AnalyticsBackfillService{dbPool: dbPool, revenueBasis: RevenueBasisGross}
+}
+
+// WithRevenueBasis sets whether recomputed LTV sums gross or net amounts
+func (s *AnalyticsBackfillService) WithRevenueBasis(basis RevenueBasis) *AnalyticsBackfillService {
+	s.revenueBasis = basis
+	return s
+}
+
+const backfillRunColumns = `id, app_id, from_date, to_date, chunk_days, steps, status, cursor_date,
+	chunks_done, chunks_total, last_error, started_at, updated_at, completed_at`
+
+// Start records a new run; call Run to execute it
+func (s *AnalyticsBackfillService) Start(ctx context.Context, req AnalyticsBackfillRequest) (*AnalyticsBackfillRun, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	steps := make([]string, len(req.Steps))
+	for i, step := range req.Steps {
+		steps[i] = string(step)
+	}
+	total := len(backfillChunks(req.From, req.To, req.ChunkDays))
+	row := s.dbPool.QueryRow(ctx, `
+		INSERT INTO analytics_backfill_runs (app_id, from_date, to_date, chunk_days, steps, chunks_total)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+backfillRunColumns,
+		req.AppID, backfillDate(req.From), backfillDate(req.To), req.ChunkDays, steps, total)
+	run, err := scanBackfillRun(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start backfill: %w", err)
+	}
+	return run, nil
+}
+
+// Resume loads a run so Run continues after its last finished chunk
+func (s *AnalyticsBackfillService) Resume(ctx context.Context, runID uuid.UUID) (*AnalyticsBackfillRun, error) {
+	row := s.dbPool.QueryRow(ctx, `SELECT `+backfillRunColumns+` FROM analytics_backfill_runs WHERE id = $1`, runID)
+	run, err := scanBackfillRun(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: backfill run %s not found", ErrInvalidBackfill, runID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load backfill run: %w", err)
+	}
+	if run.Status == BackfillStatusCompleted {
+		return nil, fmt.Errorf("%w: backfill run %s already completed", ErrInvalidBackfill, runID)
+	}
+	return run, nil
+}
+
+// Run executes the run's remaining chunks in order. progress is called after
+// each committed chunk. A failed chunk marks the run failed and is retried
+// first when the run is resumed.
+func (s *AnalyticsBackfillService) Run(ctx context.Context, run *AnalyticsBackfillRun, progress func(*AnalyticsBackfillRun)) error {
+	if _, err := s.dbPool.Exec(ctx, `
+		UPDATE analytics_backfill_runs SET status = 'running', last_error = NULL, updated_at = now() WHERE id = $1
+	`, run.ID); err != nil {
+		return fmt.Errorf("failed to mark backfill running: %w", err)
+	}
+	run.Status, run.LastError = BackfillStatusRunning, nil
+
+	for _, chunk := range run.remainingChunks() {
+		if err := s.runChunk(ctx, run, chunk); err != nil {
+			msg := err.Error()
+			run.Status, run.LastError = BackfillStatusFailed, &msg
+			// The run's own context may be what failed
+			if _, markErr := s.dbPool.Exec(context.WithoutCancel(ctx), `
+				UPDATE analytics_backfill_runs SET status = 'failed', last_error = $2, updated_at = now() WHERE id = $1
+			`, run.ID, msg); markErr != nil {
+				return fmt.Errorf("%w (and failed to mark run failed: %v)", err, markErr)
+			}
+			return err
+		}
+		cursor := chunk.To
+		run.CursorDate = &cursor
+		run.ChunksDone++
+		if progress != nil {
+			progress(run)
+		}
+	}
+
+	now := time.Now()
+	if _, err := s.dbPool.Exec(ctx, `
+		UPDATE analytics_backfill_runs SET status = 'completed', completed_at = $2, updated_at = $2 WHERE id = $1
+	`, run.ID, now); err != nil {
+		return fmt.Errorf("failed to mark backfill completed: %w", err)
+	}
+	run.Status, run.CompletedAt = BackfillStatusCompleted, &now
+	return nil
+}
+
+// runChunk recomputes every step for one chunk and advances the cursor in
+// the same transaction
+func (s *AnalyticsBackfillService) runChunk(ctx context.Context, run *AnalyticsBackfillRun, chunk backfillChunk) error {
+	tx, err := s.dbPool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin backfill chunk: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	from, to := backfillDate(chunk.From), backfillDate(chunk.To)
+	for _, step := range run.Steps {
+		var sql string
+		switch step {
+		case BackfillStepAggregates:
+			sql = backfillAggregatesSQL
+		case BackfillStepCohorts:
+			if _, err := tx.Exec(ctx, backfillClearCohortsSQL, run.AppID, from, to); err != nil {
+				return fmt.Errorf("failed to clear cohorts %s..%s: %w", from, to, err)
+			}
+			sql = backfillCohortsSQL
+		case BackfillStepLTV:
+			sql = backfillGrossLTVSQL
+			if s.revenueBasis == RevenueBasisNet {
+				sql = backfillNetLTVSQL
+			}
+		default:
+			return fmt.Errorf("%w: unknown step %q", ErrInvalidBackfill, step)
+		}
+		if _, err := tx.Exec(ctx, sql, run.AppID, from, to); err != nil {
+			return fmt.Errorf("failed to backfill %s %s..%s: %w", step, from, to, err)
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE analytics_backfill_runs
+		SET cursor_date = $2, chunks_done = chunks_done + 1, updated_at = now()
+		WHERE id = $1
+	`, run.ID, to); err != nil {
+		return fmt.Errorf("failed to record backfill progress: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit backfill chunk %s..%s: %w", from, to, err)
+	}
+	return nil
+}
+
+func scanBackfillRun(row pgx.Row) (*AnalyticsBackfillRun, error) {
+	var run AnalyticsBackfillRun
+	var steps []string
+	if err := row.Scan(&run.ID, &run.AppID, &run.From, &run.To, &run.ChunkDays, &steps, &run.Status, &run.CursorDate,
+		&run.ChunksDone, &run.ChunksTotal, &run.LastError, &run.StartedAt, &run.UpdatedAt, &run.CompletedAt); err != nil {
+		return nil, err
+	}
+	run.Steps = make([]BackfillStep, len(steps))
+	for i, step := range steps {
+		run.Steps[i] = BackfillStep(step)
+	}
+	return &run, nil
+}
+
+// backfillDate formats a local day for the DATE parameters below
+func backfillDate(day time.Time) string {
+	return day.Format("2006-01-02")
+}
+
+// backfillDaysCTE expands $2..$3 into each targeted app's local days and
+// their UTC bounds ($1 is the app, NULL for every active app)
+const backfillDaysCTE = `
+	WITH days AS (
+		SELECT a.id AS app_id,
+		       tz.name AS tz,
+		       d.day::date AS day,
+		       d.day::date::timestamp AT TIME ZONE tz.name AS day_start,
+		       (d.day::date + 1)::timestamp AT TIME ZONE tz.name AS day_end
+		FROM apps a
+		CROSS JOIN LATERAL (SELECT app_reporting_timezone(a.id) AS name) tz
+		CROSS JOIN generate_series($2::date, $3::date, INTERVAL '1 day') AS d(day)
+		WHERE a.is_active AND ($1::uuid IS NULL OR a.id = $1)
+	)`
+
+// Active subscriptions are reconstructed from subscription periods as of
+// the end of each day
+const backfillAggregatesSQL = backfillDaysCTE + `
+	INSERT INTO analytics_aggregates (app_id, metric_name, metric_date, metric_value, reporting_timezone)
+	SELECT d.app_id, 'daily_revenue', d.day,
+	       (SELECT COALESCE(SUM(t.amount), 0) FROM transactions t
+	        WHERE t.app_id = d.app_id AND t.status = 'success'
+	          AND t.created_at >= d.day_start AND t.created_at < d.day_end),
+	       d.tz
+	FROM days d
+	UNION ALL
+	SELECT d.app_id, 'active_subscriptions', d.day,
+	       (SELECT COUNT(*) FROM subscriptions s
+	        WHERE s.app_id = d.app_id AND s.created_at < d.day_end AND s.expires_at > d.day_end
+	          AND (s.deleted_at IS NULL OR s.deleted_at > d.day_end)),
+	       d.tz
+	FROM days d
+	ON CONFLICT (app_id, metric_name, metric_date, dimensions) DO UPDATE
+	    SET metric_value = EXCLUDED.metric_value,
+	        reporting_timezone = EXCLUDED.reporting_timezone,
+	        updated_at = now()`
+
+// Cohort rows are replaced wholesale so platforms without signups drop out
+const backfillClearCohortsSQL = `
+	DELETE FROM analytics_aggregates
+	WHERE metric_name IN ('cohort_signups', 'cohort_conversions')
+	  AND metric_date BETWEEN $2::date AND $3::date
+	  AND ($1::uuid IS NULL OR app_id = $1)`
+
+const backfillCohortsSQL = backfillDaysCTE + `,
+	cohorts AS (
+		SELECT d.app_id, d.day, d.tz, u.platform,
+		       COUNT(*) AS users,
+		       COUNT(*) FILTER (WHERE EXISTS (SELECT 1 FROM subscriptions s WHERE s.user_id = u.id)) AS converted
+		FROM days d
+		JOIN users u ON u.app_id = d.app_id AND u.created_at >= d.day_start AND u.created_at < d.day_end
+		WHERE u.deleted_at IS NULL
+		GROUP BY d.app_id, d.day, d.tz, u.platform
+	)
+	INSERT INTO analytics_aggregates (app_id, metric_name, metric_date, metric_value, dimensions, reporting_timezone)
+	SELECT app_id, 'cohort_signups', day, users, jsonb_build_object('platform', platform), tz FROM cohorts
+	UNION ALL
+	SELECT app_id, 'cohort_conversions', day, converted, jsonb_build_object('platform', platform), tz FROM cohorts
+	ON CONFLICT (app_id, metric_name, metric_date, dimensions) DO UPDATE
+	    SET metric_value = EXCLUDED.metric_value,
+	        reporting_timezone = EXCLUDED.reporting_timezone,
+	        updated_at = now()`
+
+const backfillGrossLTVSQL = backfillDaysCTE + `
+	UPDATE users u
+	SET ltv = (SELECT COALESCE(SUM(t.amount), 0) FROM transactions t
+	           WHERE t.app_id = u.app_id AND t.user_id = u.id AND t.status = 'success'),
+	    ltv_updated_at = now()
+	FROM days d
+	WHERE u.app_id = d.app_id AND u.created_at >= d.day_start AND u.created_at < d.day_end`
+
+const backfillNetLTVSQL = backfillDaysCTE + `
+	UPDATE users u
+	SET ltv = (SELECT COALESCE(SUM(COALESCE(t.net_amount, t.amount)), 0) FROM transactions t
+	           WHERE t.app_id = u.app_id AND t.user_id = u.id AND t.status = 'success'),
+	    ltv_updated_at = now()
+	FROM days d
+	WHERE u.app_id = d.app_id AND u.created_at >= d.day_start AND u.created_at < d.day_end`
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func backfillDay(s string) time.Time {
+	t, _ := time.Parse("2006-01-02", s)
+	return t
+}
+
+func TestBackfillChunks_CoverRangeInclusive(t *testing.T) {
+	chunks := backfillChunks(backfillDay("2026-01-30"), backfillDay("2026-02-10"), 5)
+
+	require.Len(t, chunks, 3)
+	assert.Equal(t, backfillChunk{From: backfillDay("2026-01-30"), To: backfillDay("2026-02-03")}, chunks[0])
+	assert.Equal(t, backfillChunk{From: backfillDay("2026-02-04"), To: backfillDay("2026-02-08")}, chunks[1])
+	assert.Equal(t, backfillChunk{From: backfillDay("2026-02-09"), To: backfillDay("2026-02-10")}, chunks[2])
+}
+
+func TestBackfillRun_ResumesAfterCursor(t *testing.T) {
+	cursor := backfillDay("2026-02-03")
+	run := &AnalyticsBackfillRun{From: backfillDay("2026-01-30"), To: backfillDay("2026-02-10"), ChunkDays: 5, CursorDate: &cursor}
+
+	chunks := run.remainingChunks()
+
+	require.Len(t, chunks, 2)
+	assert.Equal(t, backfillDay("2026-02-04"), chunks[0].From)
+
+	done := backfillDay("2026-02-10")
+	run.CursorDate = &done
+	assert.Empty(t, run.remainingChunks())
+}
+
+func TestAnalyticsBackfillRequest_Validate(t *testing.T) {
+	valid := AnalyticsBackfillRequest{From: backfillDay("2026-01-01"), To: backfillDay("2026-01-31"), ChunkDays: 7, Steps: BackfillSteps}
+	require.NoError(t, valid.Validate())
+
+	reversed := valid
+	reversed.From, reversed.To = valid.To, valid.From
+	assert.ErrorIs(t, reversed.Validate(), ErrInvalidBackfill)
+
+	noChunk := valid
+	noChunk.ChunkDays = 0
+	assert.ErrorIs(t, noChunk.Validate(), ErrInvalidBackfill)
+
+	unknown := valid
+	unknown.Steps = []BackfillStep{"mrr"}
+	assert.ErrorIs(t, unknown.Validate(), ErrInvalidBackfill)
+}
@@ -4,19 +4,38 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // AnalyticsReportService handles analytics report generation
 type AnalyticsReportService struct {
-	dbPool *pgxpool.Pool
+	dbPool    *pgxpool.Pool
+	converter *ReportingCurrencyConverter
 }
 
 // NewAnalyticsReportService creates a new analytics report service
 func NewAnalyticsReportService(dbPool *pgxpool.Pool) *AnalyticsReportService {
-	return &AnalyticsReportService{dbPool: dbPool}
+	return &AnalyticsReportService{
+		dbPool:    dbPool,
+		converter: NewReportingCurrencyConverter(DefaultReportingCurrency, nil, nil),
+	}
+}
+
+// WithReportingCurrency sets the converter used to express the report in one currency
+func (s *AnalyticsReportService) WithReportingCurrency(converter *ReportingCurrencyConverter) *AnalyticsReportService {
+	if converter != nil {
+		s.converter = converter
+	}
+	return s
+}
+
+// Converter returns the reporting-currency converter
+func (s *AnalyticsReportService) Converter() *ReportingCurrencyConverter {
+	return s.converter
 }
 
 // TrendPoint represents a monthly trend data point
@@ -41,6 +60,25 @@ type PlanRow struct {
 	MRR      float64 `json:"mrr"`
 }
 
+// OfferRevenueRow splits successful transaction revenue by the store offer it
+// was charged under; "regular" covers transactions without a recorded offer.
+type OfferRevenueRow struct {
+	OfferType    string  `json:"offer_type"`
+	Transactions int     `json:"transactions"`
+	Revenue      float64 `json:"revenue"`
+	Currency     string  `json:"currency"`
+}
+
+// ReactivationRevenueRow is the list-price MRR churned subscribers came back
+// with this month, by what their reactivation is credited to. Campaign is the
+// win-back campaign ID or offer code.
+type ReactivationRevenueRow struct {
+	Source        string  `json:"source"`
+	Campaign      string  `json:"campaign,omitempty"`
+	Reactivations int     `json:"reactivations"`
+	MRR           float64 `json:"mrr"`
+}
+
 // StatusCounts represents subscription status breakdown
 type StatusCounts struct {
 	Active    int `json:"active"`
@@ -49,28 +87,52 @@ type StatusCounts struct {
 	Expired   int `json:"expired"`
 }
 
-// Report contains the complete analytics report
+// Report contains the complete analytics report. Every monetary field is in
+// Currency; RevenueByCurrency breaks total_revenue out by charge currency.
 type Report struct {
-	MRR          float64       `json:"mrr"`
-	ARR          float64       `json:"arr"`
-	LTV          float64       `json:"ltv"`
-	TotalRevenue float64       `json:"total_revenue"`
-	ChurnRate    float64       `json:"churn_rate"`
-	NewSubsMonth int           `json:"new_subs_month"`
-	Trend        []TrendPoint  `json:"trend"`
-	ByPlatform   []PlatformRow `json:"by_platform"`
-	ByPlan       []PlanRow     `json:"by_plan"`
-	StatusCounts StatusCounts  `json:"status_counts"`
+	Currency     string            `json:"currency"`
+	MRR          float64           `json:"mrr"`
+	ARR          float64           `json:"arr"`
+	LTV          float64           `json:"ltv"`
+	TotalRevenue float64           `json:"total_revenue"`
+	ChurnRate    float64           `json:"churn_rate"`
+	NewSubsMonth int               `json:"new_subs_month"`
+	Trend        []TrendPoint      `json:"trend"`
+	ByPlatform   []PlatformRow     `json:"by_platform"`
+	ByPlan       []PlanRow         `json:"by_plan"`
+	StatusCounts StatusCounts      `json:"status_counts"`
+	ByOffer      []OfferRevenueRow `json:"by_offer"`
+
+	// ResurrectedMRR is the part of MRR won back from churned subscribers this month
+	ResurrectedMRR     float64                  `json:"resurrected_mrr"`
+	ReactivationsMonth int                      `json:"reactivations_month"`
+	ByReactivation     []ReactivationRevenueRow `json:"by_reactivation"`
+
+	RevenueByCurrency     []CurrencyBreakout `json:"revenue_by_currency"`
+	UnconvertedCurrencies []string           `json:"unconverted_currencies,omitempty"`
 }
 
-// GetReport fetches the complete analytics report scoped to the given app.
-func (s *AnalyticsReportService) GetReport(ctx context.Context, appID uuid.UUID) (*Report, error) {
+// GetReport fetches the complete analytics report scoped to the given app,
+// expressed in currency (empty uses the configured reporting currency).
+// List-price MRR figures are converted at today's rate; transaction revenue
+// at the rate of the day it was charged.
+func (s *AnalyticsReportService) GetReport(ctx context.Context, appID uuid.UUID, currency string) (*Report, error) {
+	currency, err := s.converter.ResolveCurrency(currency)
+	if err != nil {
+		return nil, err
+	}
+	listPriceRate, err := s.converter.Convert(ctx, 1, "USD", time.Now(), currency)
+	if err != nil {
+		return nil, fmt.Errorf("convert list prices to %s: %w", currency, err)
+	}
+
 	mrr, err := s.fetchMRR(ctx, appID)
 	if err != nil {
 		return nil, fmt.Errorf("fetch mrr: %w", err)
 	}
+	mrr = roundCents(mrr * listPriceRate)
 
-	ltv, totalRevenue, err := s.fetchLTV(ctx, appID)
+	revenue, ltv, err := s.fetchLTV(ctx, appID, currency)
 	if err != nil {
 		return nil, fmt.Errorf("fetch ltv: %w", err)
 	}
@@ -105,17 +167,53 @@ func (s *AnalyticsReportService) GetReport(ctx context.Context, appID uuid.UUID)
 		return nil, fmt.Errorf("fetch status counts: %w", err)
 	}
 
+	byOffer, err := s.fetchByOffer(ctx, appID, currency)
+	if err != nil {
+		return nil, fmt.Errorf("fetch by offer: %w", err)
+	}
+
+	byReactivation, err := s.fetchByReactivation(ctx, appID)
+	if err != nil {
+		return nil, fmt.Errorf("fetch reactivations: %w", err)
+	}
+
+	for i := range trend {
+		trend[i].MRR = roundCents(trend[i].MRR * listPriceRate)
+	}
+	for i := range byPlatform {
+		byPlatform[i].MRR = roundCents(byPlatform[i].MRR * listPriceRate)
+	}
+	for i := range byPlan {
+		byPlan[i].MRR = roundCents(byPlan[i].MRR * listPriceRate)
+	}
+	var resurrectedMRR float64
+	var reactivations int
+	for i := range byReactivation {
+		byReactivation[i].MRR = roundCents(byReactivation[i].MRR * listPriceRate)
+		resurrectedMRR += byReactivation[i].MRR
+		reactivations += byReactivation[i].Reactivations
+	}
+
 	return &Report{
+		Currency:     currency,
 		MRR:          mrr,
 		ARR:          math.Round(mrr * 12 * 100 / 100),
 		LTV:          ltv,
-		TotalRevenue: totalRevenue,
+		TotalRevenue: revenue.Amount,
 		ChurnRate:    churnRate,
 		NewSubsMonth: newSubsMonth,
 		Trend:        trend,
 		ByPlatform:   byPlatform,
 		ByPlan:       byPlan,
 		StatusCounts: statusCounts,
+		ByOffer:      byOffer,
+
+		ResurrectedMRR:     roundCents(resurrectedMRR),
+		ReactivationsMonth: reactivations,
+		ByReactivation:     byReactivation,
+
+		RevenueByCurrency:     revenue.ByCurrency,
+		UnconvertedCurrencies: revenue.Unconverted,
 	}, nil
 }
 
@@ -134,13 +232,38 @@ func (s *AnalyticsReportService) fetchMRR(ctx context.Context, appID uuid.UUID)
 	return mrr, err
 }
 
-// fetchLTV retrieves lifetime value and total revenue scoped to appID.
-func (s *AnalyticsReportService) fetchLTV(ctx context.Context, appID uuid.UUID) (ltv, totalRevenue float64, err error) {
-	err = s.dbPool.QueryRow(ctx, `
-		SELECT COALESCE(SUM(amount),0),
-		       COALESCE(ROUND(SUM(amount)/NULLIF(COUNT(DISTINCT user_id),0),2),0)
-		FROM transactions WHERE status='success' AND app_id = $1`, appID).Scan(&totalRevenue, &ltv)
-	return
+// fetchLTV retrieves total revenue converted into currency and lifetime value
+// (converted revenue per paying user) scoped to appID.
+func (s *AnalyticsReportService) fetchLTV(ctx context.Context, appID uuid.UUID, currency string) (*ConvertedTotal, float64, error) {
+	rows, err := s.dbPool.Query(ctx, `
+		SELECT currency, date_trunc('day', created_at AT TIME ZONE 'UTC'), COALESCE(SUM(amount), 0)::float8, COUNT(*)
+		FROM transactions WHERE status='success' AND app_id = $1
+		GROUP BY 1, 2`, appID)
+	if err != nil {
+		return nil, 0, err
+	}
+	amounts, err := scanCurrencyAmounts(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	revenue, err := s.converter.Aggregate(ctx, currency, amounts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var payingUsers int
+	if err := s.dbPool.QueryRow(ctx, `
+		SELECT COUNT(DISTINCT user_id) FROM transactions WHERE status='success' AND app_id = $1`,
+		appID).Scan(&payingUsers); err != nil {
+		return nil, 0, err
+	}
+
+	ltv := 0.0
+	if payingUsers > 0 {
+		ltv = roundCents(revenue.Amount / float64(payingUsers))
+	}
+	return revenue, ltv, nil
 }
 
 // fetchNewSubsMonth retrieves count of new subscriptions this month scoped to appID.
@@ -288,3 +411,91 @@ func (s *AnalyticsReportService) fetchStatusCounts(ctx context.Context, appID uu
 		&counts.Active, &counts.Grace, &counts.Cancelled, &counts.Expired)
 	return counts, err
 }
+
+// fetchByOffer retrieves intro / trial / offer-code vs regular price revenue
+// converted into currency, scoped to appID.
+func (s *AnalyticsReportService) fetchByOffer(ctx context.Context, appID uuid.UUID, currency string) ([]OfferRevenueRow, error) {
+	rows, err := s.dbPool.Query(ctx, `
+		SELECT COALESCE(o.offer_type, 'regular'), t.currency, date_trunc('day', t.created_at AT TIME ZONE 'UTC'),
+		       COALESCE(SUM(t.amount), 0)::float8, COUNT(*)
+		FROM transactions t
+		LEFT JOIN offer_redemptions o ON o.transaction_id = t.id
+		WHERE t.status = 'success' AND t.app_id = $1
+		GROUP BY 1, 2, 3 ORDER BY 1`, appID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var offerTypes []string
+	amountsByOffer := make(map[string][]CurrencyAmount)
+	for rows.Next() {
+		var offerType string
+		var a CurrencyAmount
+		if err := rows.Scan(&offerType, &a.Currency, &a.Day, &a.Amount, &a.Count); err != nil {
+			return nil, err
+		}
+		if _, ok := amountsByOffer[offerType]; !ok {
+			offerTypes = append(offerTypes, offerType)
+		}
+		amountsByOffer[offerType] = append(amountsByOffer[offerType], a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	stats := make([]OfferRevenueRow, 0, len(offerTypes))
+	for _, offerType := range offerTypes {
+		total, err := s.converter.Aggregate(ctx, currency, amountsByOffer[offerType])
+		if err != nil {
+			return nil, err
+		}
+		row := OfferRevenueRow{OfferType: offerType, Revenue: total.Amount, Currency: currency}
+		for _, b := range total.ByCurrency {
+			row.Transactions += b.Transactions
+		}
+		stats = append(stats, row)
+	}
+
+	return stats, nil
+}
+
+// fetchByReactivation retrieves this month's reactivations by source and
+// campaign, in USD list prices, scoped to appID.
+func (s *AnalyticsReportService) fetchByReactivation(ctx context.Context, appID uuid.UUID) ([]ReactivationRevenueRow, error) {
+	rows, err := s.dbPool.Query(ctx, `
+		SELECT source, COALESCE(campaign_id, offer_code, ''), COUNT(*)::int, COALESCE(SUM(mrr), 0)::float8
+		FROM subscription_reactivations
+		WHERE app_id = $1
+		  AND date_trunc('month', reactivated_at) = date_trunc('month', now())
+		GROUP BY 1, 2 ORDER BY 4 DESC, 1, 2`, appID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := make([]ReactivationRevenueRow, 0)
+	for rows.Next() {
+		var r ReactivationRevenueRow
+		if err := rows.Scan(&r.Source, &r.Campaign, &r.Reactivations, &r.MRR); err != nil {
+			return nil, err
+		}
+		stats = append(stats, r)
+	}
+	return stats, rows.Err()
+}
+
+// scanCurrencyAmounts reads (currency, day, amount, count) rows and closes them
+func scanCurrencyAmounts(rows pgx.Rows) ([]CurrencyAmount, error) {
+	defer rows.Close()
+
+	amounts := make([]CurrencyAmount, 0)
+	for rows.Next() {
+		var a CurrencyAmount
+		if err := rows.Scan(&a.Currency, &a.Day, &a.Amount, &a.Count); err != nil {
+			return nil, err
+		}
+		amounts = append(amounts, a)
+	}
+	return amounts, rows.Err()
+}
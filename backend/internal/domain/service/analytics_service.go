@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"sort"
 	"time"
 
 	"github.com/bivex/paywall-iap/internal/domain/repository"
@@ -11,6 +12,15 @@ import (
 type AnalyticsService struct {
 	repo             repository.AnalyticsRepository
 	subscriptionRepo repository.SubscriptionRepository
+	converter        *ReportingCurrencyConverter
+}
+
+// currencyRevenueReader is implemented by analytics repositories that can
+// split revenue aggregates by charge currency
+type currencyRevenueReader interface {
+	GetRevenueByCurrencyBetween(ctx context.Context, start, end time.Time) ([]CurrencyAmount, error)
+	GetMRRByCurrency(ctx context.Context) ([]CurrencyAmount, error)
+	GetMRRTrendByCurrency(ctx context.Context, months int) ([]CurrencyAmount, error)
 }
 
 // NewAnalyticsService creates a new analytics service
@@ -21,25 +31,54 @@ func NewAnalyticsService(repo repository.AnalyticsRepository, subscriptionRepo r
 	}
 }
 
+// WithReportingCurrency converts revenue metrics into the converter's
+// reporting currency instead of summing raw amounts across currencies
+func (s *AnalyticsService) WithReportingCurrency(converter *ReportingCurrencyConverter) *AnalyticsService {
+	s.converter = converter
+	return s
+}
+
+// Currency returns the currency revenue metrics are reported in
+func (s *AnalyticsService) Currency() string {
+	if s.converter == nil {
+		return DefaultReportingCurrency
+	}
+	return s.converter.Currency()
+}
+
 // ChurnMetrics represents churn-related statistics
 type ChurnMetrics struct {
 	TotalSubscriptions int
 	ChurnedCount       int
 	ChurnRate          float64
-	Period             string
+	// PriceIncreaseChurnedCount are the churned subscriptions that expired
+	// without consenting to a store price increase, rather than cancelling
+	PriceIncreaseChurnedCount int
+	PriceIncreaseChurnRate    float64
+	Period                    string
 }
 
 // RevenueMetrics represents revenue-related statistics
 type RevenueMetrics struct {
+	Currency       string
 	DailyRevenue   float64
 	WeeklyRevenue  float64
 	MonthlyRevenue float64
 	MRR            float64
 	ARR            float64
+
+	// Per-currency breakouts; nil when the repository can't split by currency
+	DailyByCurrency       []CurrencyBreakout
+	MRRByCurrency         []CurrencyBreakout
+	UnconvertedCurrencies []string
 }
 
 // CalculateRevenueMetrics calculates revenue metrics for a period
 func (s *AnalyticsService) CalculateRevenueMetrics(ctx context.Context, start, end time.Time) (*RevenueMetrics, error) {
+	if reader, ok := s.repo.(currencyRevenueReader); ok && s.converter != nil {
+		return s.calculateConvertedRevenueMetrics(ctx, reader, s.converter.Currency())
+	}
+
 	// Daily revenue
 	daily, err := s.repo.GetRevenueBetween(ctx, time.Now().Truncate(24*time.Hour), time.Now())
 	if err != nil {
@@ -53,12 +92,53 @@ func (s *AnalyticsService) CalculateRevenueMetrics(ctx context.Context, start, e
 	}
 
 	return &RevenueMetrics{
+		Currency:     s.Currency(),
 		DailyRevenue: daily,
 		MRR:          mrr,
 		ARR:          mrr * 12.0,
 	}, nil
 }
 
+// CalculateRevenueMetricsIn is CalculateRevenueMetrics in an explicit currency
+func (s *AnalyticsService) CalculateRevenueMetricsIn(ctx context.Context, start, end time.Time, currency string) (*RevenueMetrics, error) {
+	reader, ok := s.repo.(currencyRevenueReader)
+	if !ok || s.converter == nil {
+		return s.CalculateRevenueMetrics(ctx, start, end)
+	}
+	return s.calculateConvertedRevenueMetrics(ctx, reader, currency)
+}
+
+func (s *AnalyticsService) calculateConvertedRevenueMetrics(ctx context.Context, reader currencyRevenueReader, currency string) (*RevenueMetrics, error) {
+	now := time.Now()
+	dailyAmounts, err := reader.GetRevenueByCurrencyBetween(ctx, now.Truncate(24*time.Hour), now)
+	if err != nil {
+		return nil, err
+	}
+	daily, err := s.converter.Aggregate(ctx, currency, dailyAmounts)
+	if err != nil {
+		return nil, err
+	}
+
+	mrrAmounts, err := reader.GetMRRByCurrency(ctx)
+	if err != nil {
+		return nil, err
+	}
+	mrr, err := s.converter.Aggregate(ctx, currency, mrrAmounts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RevenueMetrics{
+		Currency:              currency,
+		DailyRevenue:          daily.Amount,
+		MRR:                   mrr.Amount,
+		ARR:                   roundCents(mrr.Amount * 12.0),
+		DailyByCurrency:       daily.ByCurrency,
+		MRRByCurrency:         mrr.ByCurrency,
+		UnconvertedCurrencies: mergeCurrencyLists(daily.Unconverted, mrr.Unconverted),
+	}, nil
+}
+
 // CalculateChurnMetrics calculates churn metrics for a period
 func (s *AnalyticsService) CalculateChurnMetrics(ctx context.Context, start, end time.Time) (*ChurnMetrics, error) {
 	// Total active at start
@@ -73,16 +153,25 @@ func (s *AnalyticsService) CalculateChurnMetrics(ctx context.Context, start, end
 		return nil, err
 	}
 
-	rate := 0.0
+	// Of which lost to a price increase they did not consent to
+	priceIncreaseChurned, err := s.repo.GetPriceIncreaseChurnedCountBetween(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	rate, priceIncreaseRate := 0.0, 0.0
 	if total > 0 {
 		rate = float64(churned) / float64(total) * 100
+		priceIncreaseRate = float64(priceIncreaseChurned) / float64(total) * 100
 	}
 
 	return &ChurnMetrics{
-		TotalSubscriptions: total,
-		ChurnedCount:       churned,
-		ChurnRate:          rate,
-		Period:             start.Format("2006-01-02") + " to " + end.Format("2006-01-02"),
+		TotalSubscriptions:        total,
+		ChurnedCount:              churned,
+		ChurnRate:                 rate,
+		PriceIncreaseChurnedCount: priceIncreaseChurned,
+		PriceIncreaseChurnRate:    priceIncreaseRate,
+		Period:                    start.Format("2006-01-02") + " to " + end.Format("2006-01-02"),
 	}, nil
 }
 
@@ -91,6 +180,53 @@ func (s *AnalyticsService) GetMRRTrend(ctx context.Context, months int) ([]repos
 	return s.repo.GetMRRTrend(ctx, months)
 }
 
+// GetMRRTrendIn returns the MRR trend with each month converted into currency
+// at that month's rate. Falls back to GetMRRTrend without a converter.
+func (s *AnalyticsService) GetMRRTrendIn(ctx context.Context, months int, currency string) ([]repository.MonthlyMRR, error) {
+	reader, ok := s.repo.(currencyRevenueReader)
+	if !ok || s.converter == nil {
+		return s.GetMRRTrend(ctx, months)
+	}
+
+	trend, err := s.repo.GetMRRTrend(ctx, months)
+	if err != nil {
+		return nil, err
+	}
+	amounts, err := reader.GetMRRTrendByCurrency(ctx, months)
+	if err != nil {
+		return nil, err
+	}
+
+	byMonth := make(map[string][]CurrencyAmount)
+	for _, a := range amounts {
+		month := a.Day.Format("2006-01")
+		byMonth[month] = append(byMonth[month], a)
+	}
+	for i := range trend {
+		total, err := s.converter.Aggregate(ctx, currency, byMonth[trend[i].Month])
+		if err != nil {
+			return nil, err
+		}
+		trend[i].MRR = total.Amount
+	}
+	return trend, nil
+}
+
+func mergeCurrencyLists(lists ...[]string) []string {
+	seen := make(map[string]bool)
+	var merged []string
+	for _, list := range lists {
+		for _, currency := range list {
+			if !seen[currency] {
+				seen[currency] = true
+				merged = append(merged, currency)
+			}
+		}
+	}
+	sort.Strings(merged)
+	return merged
+}
+
 // GetSubscriptionStatusCounts delegates to the repository.
 func (s *AnalyticsService) GetSubscriptionStatusCounts(ctx context.Context) (*repository.SubscriptionStatusCounts, error) {
 	return s.repo.GetSubscriptionStatusCounts(ctx)
@@ -39,12 +39,55 @@ func TestAnalyticsService(t *testing.T) {
 
 		repo.On("GetActiveSubscriptionCountAt", mock.Anything, mock.Anything).Return(100, nil).Once()
 		repo.On("GetChurnedCountBetween", mock.Anything, mock.Anything, mock.Anything).Return(5, nil).Once()
+		repo.On("GetPriceIncreaseChurnedCountBetween", mock.Anything, mock.Anything, mock.Anything).Return(2, nil).Once()
 
 		metrics, err := service.CalculateChurnMetrics(ctx, start, end)
 		assert.NoError(t, err)
 		assert.Equal(t, 100, metrics.TotalSubscriptions)
 		assert.Equal(t, 5, metrics.ChurnedCount)
 		assert.Equal(t, 5.0, metrics.ChurnRate)
+		assert.Equal(t, 2, metrics.PriceIncreaseChurnedCount)
+		assert.Equal(t, 2.0, metrics.PriceIncreaseChurnRate)
 		repo.AssertExpectations(t)
 	})
 }
+
+type currencyAnalyticsRepo struct {
+	*mocks.AnalyticsRepositoryMock
+	daily []CurrencyAmount
+	mrr   []CurrencyAmount
+}
+
+func (r *currencyAnalyticsRepo) GetRevenueByCurrencyBetween(ctx context.Context, start, end time.Time) ([]CurrencyAmount, error) {
+	return r.daily, nil
+}
+
+func (r *currencyAnalyticsRepo) GetMRRByCurrency(ctx context.Context) ([]CurrencyAmount, error) {
+	return r.mrr, nil
+}
+
+func (r *currencyAnalyticsRepo) GetMRRTrendByCurrency(ctx context.Context, months int) ([]CurrencyAmount, error) {
+	return nil, nil
+}
+
+func TestAnalyticsService_CalculateRevenueMetricsConvertsToReportingCurrency(t *testing.T) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	repo := &currencyAnalyticsRepo{
+		AnalyticsRepositoryMock: new(mocks.AnalyticsRepositoryMock),
+		daily:                   []CurrencyAmount{{Currency: "USD", Day: today, Amount: 10, Count: 1}, {Currency: "EUR", Day: today, Amount: 10, Count: 1}},
+		mrr:                     []CurrencyAmount{{Currency: "EUR", Day: today, Amount: 100, Count: 10}, {Currency: "JPY", Day: today, Amount: 1000, Count: 1}},
+	}
+	converter := NewReportingCurrencyConverter("USD", nil, fakeLiveRates{"EUR": 1.10})
+	svc := NewAnalyticsService(repo, new(mocks.MockSubscriptionRepository)).WithReportingCurrency(converter)
+
+	metrics, err := svc.CalculateRevenueMetrics(context.Background(), today.AddDate(0, -1, 0), today)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "USD", metrics.Currency)
+	assert.Equal(t, 21.0, metrics.DailyRevenue)
+	assert.Equal(t, 110.0, metrics.MRR)
+	assert.Equal(t, 1320.0, metrics.ARR)
+	assert.Equal(t, []string{"JPY"}, metrics.UnconvertedCurrencies)
+	assert.Len(t, metrics.MRRByCurrency, 2)
+	repo.AssertNotCalled(t, "GetMRR", mock.Anything)
+}
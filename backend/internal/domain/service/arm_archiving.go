@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// ErrUserExcluded is returned when the user's arm was archived under
+// ArmReassignmentExclude; they see no variant until the assignment lapses
+var ErrUserExcluded = errors.New("user excluded from experiment")
+
+// ErrInvalidArmReassignmentPolicy is returned for an unknown policy name
+var ErrInvalidArmReassignmentPolicy = errors.New("invalid arm reassignment policy")
+
+// ArmReassignmentPolicy decides what happens to users assigned to an arm
+// when it is archived
+type ArmReassignmentPolicy string
+
+const (
+	// ArmReassignmentKeep leaves users on the archived arm until their
+	// assignment lapses
+	ArmReassignmentKeep ArmReassignmentPolicy = "keep"
+	// ArmReassignmentReassign draws a live arm for them
+	ArmReassignmentReassign ArmReassignmentPolicy = "reassign"
+	// ArmReassignmentExclude drops them from the experiment until their
+	// assignment lapses
+	ArmReassignmentExclude ArmReassignmentPolicy = "exclude"
+)
+
+// ParseArmReassignmentPolicy validates a policy name; empty means reassign
+func ParseArmReassignmentPolicy(value string) (ArmReassignmentPolicy, error) {
+	switch policy := ArmReassignmentPolicy(value); policy {
+	case "":
+		return ArmReassignmentReassign, nil
+	case ArmReassignmentKeep, ArmReassignmentReassign, ArmReassignmentExclude:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrInvalidArmReassignmentPolicy, value)
+	}
+}
+
+// activeArms returns the arms that have not been archived
+func activeArms(arms []Arm) []Arm {
+	active := arms[:0:0]
+	for _, arm := range arms {
+		if arm.ArchivedAt == nil {
+			active = append(active, arm)
+		}
+	}
+	return active
+}
+
+// archivedArmAssignmentRepository is implemented by repositories that can
+// move users off archived arms
+type archivedArmAssignmentRepository interface {
+	// ListArchivedArmAssignments returns live assignments to archived arms
+	// whose policy is reassign or exclude, with ArchivedArmPolicy set.
+	// uuid.Nil lists every archived arm.
+	ListArchivedArmAssignments(ctx context.Context, armID uuid.UUID, limit int) ([]Assignment, error)
+	ExcludeAssignment(ctx context.Context, assignment *Assignment, excludedAt time.Time) error
+}
+
+// ArmMigrationResult summarizes one MigrateArchivedArmAssignments batch
+type ArmMigrationResult struct {
+	Scanned    int
+	Reassigned int
+	Excluded   int
+	Failed     int
+}
+
+// MigrateArchivedArmAssignments applies archived arms' reassignment policies
+// to up to limit assignments: reassigned users get a fresh draw among the
+// live arms, excluded users are marked so SelectArm returns ErrUserExcluded.
+// armID narrows the batch to one arm; uuid.Nil covers every archived arm.
+// Failures are counted and left for the next batch.
+func (b *ThompsonSamplingBandit) MigrateArchivedArmAssignments(ctx context.Context, armID uuid.UUID, limit int) (ArmMigrationResult, error) {
+	var result ArmMigrationResult
+	repo, ok := b.repo.(archivedArmAssignmentRepository)
+	if !ok {
+		return result, nil
+	}
+
+	assignments, err := repo.ListArchivedArmAssignments(ctx, armID, limit)
+	if err != nil {
+		return result, fmt.Errorf("failed to list archived arm assignments: %w", err)
+	}
+
+	for i := range assignments {
+		assignment := &assignments[i]
+		result.Scanned++
+		if err := b.migrateAssignment(ctx, repo, assignment); err != nil {
+			result.Failed++
+			b.logger.Warn("Failed to migrate assignment off archived arm",
+				zap.String("experiment_id", assignment.ExperimentID.String()),
+				zap.String("user_id", assignment.UserID.String()),
+				zap.String("arm_id", assignment.ArmID.String()),
+				zap.Error(err),
+			)
+			continue
+		}
+		if assignment.ArchivedArmPolicy == ArmReassignmentExclude {
+			result.Excluded++
+		} else {
+			result.Reassigned++
+		}
+	}
+	return result, nil
+}
+
+func (b *ThompsonSamplingBandit) migrateAssignment(ctx context.Context, repo archivedArmAssignmentRepository, assignment *Assignment) error {
+	cacheKey := assignmentCacheKey(assignment.ExperimentID, assignment.UserID)
+	switch assignment.ArchivedArmPolicy {
+	case ArmReassignmentExclude:
+		if err := repo.ExcludeAssignment(ctx, assignment, time.Now().UTC()); err != nil {
+			return err
+		}
+		// The cached arm would otherwise outlive the exclusion
+		if err := b.cache.DeleteKey(ctx, cacheKey); err != nil {
+			b.logger.Warn("Failed to evict excluded assignment", zap.Error(err))
+		}
+		return nil
+	case ArmReassignmentReassign:
+		// Shares the flight key with selectArm so a concurrent request for
+		// the same user does not draw a second arm
+		_, err, _ := b.flights.Do("assign:"+cacheKey, func() (interface{}, error) {
+			return b.assignArm(ctx, assignment.ExperimentID, assignment.UserID, nil, assignment)
+		})
+		return err
+	default:
+		return fmt.Errorf("%w: %s", ErrInvalidArmReassignmentPolicy, assignment.ArchivedArmPolicy)
+	}
+}
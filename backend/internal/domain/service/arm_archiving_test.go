@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// armMigrationTestRepo serves archived-arm assignments to the migration
+type armMigrationTestRepo struct {
+	assignmentTestRepo
+
+	pending  []Assignment
+	excluded []uuid.UUID
+}
+
+func (r *armMigrationTestRepo) ListArchivedArmAssignments(ctx context.Context, armID uuid.UUID, limit int) ([]Assignment, error) {
+	var assignments []Assignment
+	for _, assignment := range r.pending {
+		if (armID == uuid.Nil || assignment.ArmID == armID) && len(assignments) < limit {
+			assignments = append(assignments, assignment)
+		}
+	}
+	return assignments, nil
+}
+
+func (r *armMigrationTestRepo) ExcludeAssignment(ctx context.Context, assignment *Assignment, excludedAt time.Time) error {
+	r.excluded = append(r.excluded, assignment.ID)
+	return nil
+}
+
+func archivedArms() (archived, live Arm) {
+	archivedAt := time.Now().Add(-time.Hour)
+	archived = Arm{ID: uuid.New(), IsControl: true, ArchivedAt: &archivedAt, ReassignmentPolicy: ArmReassignmentReassign}
+	live = Arm{ID: uuid.New()}
+	return archived, live
+}
+
+func TestThompsonSamplingBandit_SelectArmSkipsArchivedArms(t *testing.T) {
+	archived, live := archivedArms()
+	repo := &assignmentTestRepo{advancedEngineTestRepo: advancedEngineTestRepo{arms: []Arm{archived, live}}}
+	bandit := NewThompsonSamplingBandit(repo, newAssignmentTestCache(), zap.NewNop()).WithSeed(7)
+
+	for i := 0; i < 20; i++ {
+		armID, err := bandit.SelectArm(context.Background(), uuid.New(), uuid.New())
+		require.NoError(t, err)
+		assert.Equal(t, live.ID, armID)
+	}
+
+	_, err := bandit.SelectArmFrom(context.Background(), uuid.New(), uuid.New(), []uuid.UUID{archived.ID})
+	assert.ErrorIs(t, err, ErrExperimentArmsNotFound)
+}
+
+func TestThompsonSamplingBandit_SelectArmAppliesArchivedArmPolicy(t *testing.T) {
+	archived, live := archivedArms()
+	cases := []struct {
+		policy  ArmReassignmentPolicy
+		wantArm uuid.UUID
+		wantNew bool
+		wantErr error
+	}{
+		{policy: ArmReassignmentKeep, wantArm: archived.ID},
+		{policy: ArmReassignmentReassign, wantArm: live.ID, wantNew: true},
+		{policy: ArmReassignmentExclude, wantErr: ErrUserExcluded},
+	}
+	for _, tc := range cases {
+		t.Run(string(tc.policy), func(t *testing.T) {
+			repo := &assignmentTestRepo{
+				advancedEngineTestRepo: advancedEngineTestRepo{arms: []Arm{archived, live}},
+				active: &Assignment{
+					ArmID:             archived.ID,
+					ExpiresAt:         time.Now().Add(time.Hour),
+					ArchivedArmPolicy: tc.policy,
+				},
+			}
+			bandit := NewThompsonSamplingBandit(repo, newAssignmentTestCache(), zap.NewNop())
+
+			armID, isNew, err := bandit.SelectArmWithMeta(context.Background(), uuid.New(), uuid.New())
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				assert.Empty(t, repo.created)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantArm, armID)
+			assert.Equal(t, tc.wantNew, isNew)
+		})
+	}
+}
+
+func TestThompsonSamplingBandit_MigrateArchivedArmAssignments(t *testing.T) {
+	archived, live := archivedArms()
+	experimentID := uuid.New()
+	moved := Assignment{ID: uuid.New(), ExperimentID: experimentID, UserID: uuid.New(), ArmID: archived.ID, ArchivedArmPolicy: ArmReassignmentReassign}
+	dropped := Assignment{ID: uuid.New(), ExperimentID: experimentID, UserID: uuid.New(), ArmID: archived.ID, ArchivedArmPolicy: ArmReassignmentExclude}
+	repo := &armMigrationTestRepo{
+		assignmentTestRepo: assignmentTestRepo{advancedEngineTestRepo: advancedEngineTestRepo{arms: []Arm{archived, live}}},
+		pending:            []Assignment{moved, dropped},
+	}
+	cache := newAssignmentTestCache()
+	// Both users were cached on the archived arm before it was retired
+	cache.assignments[assignmentCacheKey(experimentID, moved.UserID)] = archived.ID
+	cache.assignments[assignmentCacheKey(experimentID, dropped.UserID)] = archived.ID
+	bandit := NewThompsonSamplingBandit(repo, cache, zap.NewNop())
+
+	result, err := bandit.MigrateArchivedArmAssignments(context.Background(), archived.ID, 10)
+	require.NoError(t, err)
+	assert.Equal(t, ArmMigrationResult{Scanned: 2, Reassigned: 1, Excluded: 1}, result)
+
+	require.Len(t, repo.created, 1)
+	assert.Equal(t, live.ID, repo.created[0].ArmID)
+	assert.Equal(t, AssignmentEventTypeReassigned, repo.created[0].EventType)
+	assert.Equal(t, archived.ID, repo.created[0].Metadata["reassigned_from_arm_id"])
+	assert.Equal(t, live.ID, cache.assignments[assignmentCacheKey(experimentID, moved.UserID)])
+
+	assert.Equal(t, []uuid.UUID{dropped.ID}, repo.excluded)
+	assert.NotContains(t, cache.assignments, assignmentCacheKey(experimentID, dropped.UserID))
+}
+
+func TestParseArmReassignmentPolicy(t *testing.T) {
+	policy, err := ParseArmReassignmentPolicy("")
+	require.NoError(t, err)
+	assert.Equal(t, ArmReassignmentReassign, policy)
+
+	policy, err = ParseArmReassignmentPolicy("exclude")
+	require.NoError(t, err)
+	assert.Equal(t, ArmReassignmentExclude, policy)
+
+	_, err = ParseArmReassignmentPolicy("delete")
+	assert.ErrorIs(t, err, ErrInvalidArmReassignmentPolicy)
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// lockedSource serializes a rand.Source so a single *rand.Rand can be shared
+// by concurrent requests. rand.Rand itself keeps no state outside its source
+// for the methods the bandit uses.
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Int63()
+}
+
+func (s *lockedSource) Uint64() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if src64, ok := s.src.(rand.Source64); ok {
+		return src64.Uint64()
+	}
+	return uint64(s.src.Int63())>>31 | uint64(s.src.Int63())<<32
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.src.Seed(seed)
+}
+
+// newLockedRand returns a concurrency-safe *rand.Rand over src, seeded from
+// the clock when src is nil
+func newLockedRand(src rand.Source) *rand.Rand {
+	if src == nil {
+		src = rand.NewSource(time.Now().UnixNano())
+	}
+	return rand.New(&lockedSource{src: src})
+}
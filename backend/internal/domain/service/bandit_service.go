@@ -6,10 +6,15 @@ import (
 	"fmt"
 	"math"
 	"math/rand"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/bivex/paywall-iap/internal/clock"
 )
 
 // ErrAssignmentNotFound is returned when no active assignment is found for a user
@@ -21,6 +26,11 @@ var ErrExperimentArmsNotFound = errors.New("experiment arms not found")
 // ErrBanditArmNotFound is returned when a reward references a non-existent arm.
 var ErrBanditArmNotFound = errors.New("bandit arm not found")
 
+// ErrDuplicateConversion is returned when a transaction's conversion was
+// already counted, e.g. by a webhook retry after the client reported it.
+// Callers should treat it as success.
+var ErrDuplicateConversion = errors.New("conversion already recorded for transaction")
+
 // BanditRepository defines the interface for bandit data persistence
 type BanditRepository interface {
 	GetArms(ctx context.Context, experimentID uuid.UUID) ([]Arm, error)
@@ -40,6 +50,8 @@ type BanditRepository interface {
 type BanditCache interface {
 	GetArmStats(ctx context.Context, key string) (*ArmStats, error)
 	SetArmStats(ctx context.Context, key string, stats *ArmStats, ttl time.Duration) error
+	// GetAssignment returns an error on a cache miss; uuid.Nil is a cached
+	// "no assignment" answer
 	GetAssignment(ctx context.Context, key string) (uuid.UUID, error)
 	SetAssignment(ctx context.Context, key string, armID uuid.UUID, ttl time.Duration) error
 	// Generic JSON cache for arbitrary structs (pending rewards, etc.)
@@ -56,6 +68,10 @@ type Arm struct {
 	Description   string
 	IsControl     bool
 	TrafficWeight float64
+	// ArchivedAt is set once the arm is retired; archived arms are never
+	// drawn and ReassignmentPolicy says what happens to their users
+	ArchivedAt         *time.Time
+	ReassignmentPolicy ArmReassignmentPolicy
 }
 
 // ArmStats represents the statistics for an arm
@@ -67,7 +83,11 @@ type ArmStats struct {
 	Conversions int
 	Revenue     float64
 	AvgReward   float64
-	UpdatedAt   time.Time
+	// Sums of log(revenue) and its square over conversions, the sufficient
+	// statistics of the revenue posterior (see revenue_posterior.go)
+	LogRevenueSum   float64
+	LogRevenueSqSum float64
+	UpdatedAt       time.Time
 }
 
 // Assignment represents a user's assignment to an arm
@@ -79,18 +99,30 @@ type Assignment struct {
 	AssignedAt   time.Time
 	ExpiresAt    time.Time
 	Metadata     map[string]interface{}
+	// EventType labels the history event written with the assignment;
+	// empty means AssignmentEventTypeAssigned
+	EventType AssignmentEventType
+	// ExcludedAt is set when the user was dropped from the experiment
+	ExcludedAt *time.Time
+	// ArchivedArmPolicy is the arm's reassignment policy when ArmID is archived
+	ArchivedArmPolicy ArmReassignmentPolicy
 }
 
 type AssignmentEventType string
 
 const (
-	AssignmentEventTypeAssigned AssignmentEventType = "assigned"
+	AssignmentEventTypeAssigned   AssignmentEventType = "assigned"
+	AssignmentEventTypeReassigned AssignmentEventType = "reassigned"
+	AssignmentEventTypeExcluded   AssignmentEventType = "excluded"
 )
 
 type ImpressionEventType string
 
 const (
 	ImpressionEventTypeImpression ImpressionEventType = "impression"
+	// ImpressionEventTypePurchaseInitiated marks the user starting checkout
+	// from the paywall they were shown
+	ImpressionEventTypePurchaseInitiated ImpressionEventType = "purchase_initiated"
 )
 
 // =====================================================
@@ -125,7 +157,14 @@ type UserContext struct {
 	DaysSinceInstall int
 	TotalSpent       float64
 	LastPurchaseAt   *time.Time
-	CustomFeatures   map[string]interface{}
+	// AcquisitionChannel and Campaign come from MMP attribution; empty for
+	// users without a callback
+	AcquisitionChannel string
+	Campaign           string
+	// LifecycleStage is the nightly lifecycle classification; empty before
+	// the user was first classified
+	LifecycleStage string
+	CustomFeatures map[string]interface{}
 }
 
 // RewardEvent represents a reward event with metadata
@@ -145,6 +184,9 @@ const (
 	ConversionEventTypeDirectReward         ConversionEventType = "direct_reward"
 	ConversionEventTypeDelayedConversion    ConversionEventType = "delayed_conversion"
 	ConversionEventTypeExpiredPendingReward ConversionEventType = "expired_pending_reward"
+	ConversionEventTypeNotificationOpen     ConversionEventType = "notification_open"
+	ConversionEventTypeCurrencyCorrection   ConversionEventType = "currency_correction"
+	ConversionEventTypeReviewPromptAccepted ConversionEventType = "review_prompt_accepted"
 )
 
 type ConversionEvent struct {
@@ -158,19 +200,33 @@ type ConversionEvent struct {
 	OriginalCurrency      string
 	NormalizedRewardValue float64
 	NormalizedCurrency    string
-	Metadata              map[string]interface{}
-	OccurredAt            time.Time
+	// ConversionRate is the rate that took the original amount to the
+	// normalized currency, zero when no conversion happened
+	ConversionRate float64
+	RateSource     string
+	// CorrectsEventID is set on currency corrections to the event they adjust
+	CorrectsEventID *uuid.UUID
+	Metadata        map[string]interface{}
+	OccurredAt      time.Time
 }
 
 type conversionEventAppender interface {
 	AppendConversionEvent(ctx context.Context, event *ConversionEvent) error
 }
 
+// conversionClaimer reserves a transaction's conversion so replays of it are
+// not counted twice. ClaimConversion reports false when it is already taken.
+type conversionClaimer interface {
+	ClaimConversion(ctx context.Context, transactionID, experimentID, armID uuid.UUID, source ConversionEventType) (bool, error)
+	ReleaseConversion(ctx context.Context, transactionID uuid.UUID) error
+}
+
 type ImpressionEvent struct {
 	ExperimentID uuid.UUID
 	ArmID        uuid.UUID
 	UserID       uuid.UUID
 	EventType    ImpressionEventType
+	Placement    string
 	Metadata     map[string]interface{}
 	OccurredAt   time.Time
 }
@@ -195,6 +251,7 @@ type WindowType string
 const (
 	WindowTypeEvents WindowType = "events"
 	WindowTypeTime   WindowType = "time"
+	WindowTypeDecay  WindowType = "decay" // Exponentially discounted; Size is the half-life in seconds
 	WindowTypeNone   WindowType = "none"
 )
 
@@ -202,27 +259,55 @@ const (
 type WindowConfig struct {
 	Type       WindowType
 	Size       int // Number of events or seconds
-	MinSamples int // Minimum samples before using window
+	MinSamples int // Below this, window stats are topped up with lifetime stats
+	// ArmOverrides replaces the window for individual arms
+	ArmOverrides map[uuid.UUID]*WindowConfig
 }
 
 // ExperimentConfig defines per-experiment configuration for advanced features
 type ExperimentConfig struct {
-	ID               uuid.UUID
-	ObjectiveType    ObjectiveType
-	ObjectiveWeights map[string]float64 // For hybrid: {"conversion": 0.5, "ltv": 0.3, "revenue": 0.2}
-	WindowConfig     *WindowConfig
-	EnableContextual bool
-	EnableDelayed    bool
-	EnableCurrency   bool
-	ExplorationAlpha float64 // For LinUCB: exploration parameter
+	ID                uuid.UUID
+	ObjectiveType     ObjectiveType
+	ObjectiveWeights  map[string]float64 // For hybrid: {"conversion": 0.5, "ltv": 0.3, "revenue": 0.2}
+	WindowConfig      *WindowConfig
+	EnableContextual  bool
+	EnableDelayed     bool
+	EnableCurrency    bool
+	ExplorationAlpha  float64            // For LinUCB: exploration parameter
+	RewardBasis       RevenueBasis       // gross, net or margin; empty uses the engine default
+	ProductCosts      map[string]float64 // For margin: unit cost per product_id in USD
+	EndAt             *time.Time         // Scheduled end; assignments never outlive it
+	ConversionWindow  time.Duration      // How long a pending reward waits for a conversion; zero uses the engine default
+	RewardDefinitions []RewardDefinition // Event rewards for non-monetary conversions (see reward_shaping.go)
 }
 
+const (
+	// assignmentTTL is how long a user stays on the arm they were assigned
+	assignmentTTL = 24 * time.Hour
+	// noAssignmentTTL bounds how long a cached "not assigned" answer is trusted
+	noAssignmentTTL = 30 * time.Second
+	// armStatsTTL is how long cached arm statistics are kept
+	armStatsTTL = 24 * time.Hour
+)
+
 // ThompsonSamplingBandit implements the Thompson Sampling algorithm
 type ThompsonSamplingBandit struct {
 	repo   BanditRepository
 	cache  BanditCache
 	logger *zap.Logger
-	rng    *rand.Rand
+	rng    *rand.Rand // safe for concurrent use, see newLockedRand
+
+	// simulationBudget caps the Beta draws of one win probability simulation
+	// and simulationWorkers the goroutines running it, 0 being GOMAXPROCS
+	simulationBudget  int
+	simulationWorkers int
+
+	// flights collapses concurrent lookups and assignments for the same user
+	flights singleflight.Group
+
+	killSwitch ExperimentKillSwitch
+
+	now func() time.Time
 }
 
 // NewThompsonSamplingBandit creates a new Thompson Sampling bandit service
@@ -231,37 +316,187 @@ func NewThompsonSamplingBandit(
 	cache BanditCache,
 	logger *zap.Logger,
 ) *ThompsonSamplingBandit {
-	source := rand.NewSource(time.Now().UnixNano())
 	return &ThompsonSamplingBandit{
 		repo:   repo,
 		cache:  cache,
 		logger: logger,
-		rng:    rand.New(source),
+		rng:    newLockedRand(nil),
+
+		simulationBudget: DefaultSimulationBudget,
+		now:              time.Now,
 	}
 }
 
+// WithClock makes assignment times and expiries, and event times left
+// empty by the caller, come from c
+func (b *ThompsonSamplingBandit) WithClock(c clock.Clock) *ThompsonSamplingBandit {
+	b.now = c.Now
+	return b
+}
+
+// WithKillSwitch stops selection for experiments and arms paused through ks
+// without waiting for cached assignments to expire
+func (b *ThompsonSamplingBandit) WithKillSwitch(ks ExperimentKillSwitch) *ThompsonSamplingBandit {
+	b.killSwitch = ks
+	return b
+}
+
+// WithRandSource draws every sample from src instead of a clock-seeded
+// source. The bandit serializes access, so src need not be goroutine safe.
+func (b *ThompsonSamplingBandit) WithRandSource(src rand.Source) *ThompsonSamplingBandit {
+	b.rng = newLockedRand(src)
+	return b
+}
+
+// WithSeed makes arm selection and win probabilities reproducible: two
+// bandits with the same seed and the same calls return the same results.
+// Meant for tests and simulations.
+func (b *ThompsonSamplingBandit) WithSeed(seed int64) *ThompsonSamplingBandit {
+	return b.WithRandSource(rand.NewSource(seed))
+}
+
 // SelectArm selects the best arm using Thompson Sampling
 // Returns the arm ID that maximizes the sampled Beta distribution
 func (b *ThompsonSamplingBandit) SelectArm(ctx context.Context, experimentID, userID uuid.UUID) (uuid.UUID, error) {
-	// First, check if user has an active assignment (sticky assignment)
-	if assignment, err := b.repo.GetActiveAssignment(ctx, experimentID, userID); err == nil && assignment != nil {
+	armID, _, err := b.selectArm(ctx, experimentID, userID, nil)
+	return armID, err
+}
+
+// SelectArmFrom selects an arm like SelectArm but only among candidates, so a
+// paywall rule can narrow the experiment before the bandit chooses. An empty
+// candidate list allows every arm. A sticky assignment outside the candidate
+// set is ignored and a new one is made.
+func (b *ThompsonSamplingBandit) SelectArmFrom(ctx context.Context, experimentID, userID uuid.UUID, candidates []uuid.UUID) (uuid.UUID, error) {
+	var allowed map[uuid.UUID]bool
+	if len(candidates) > 0 {
+		allowed = make(map[uuid.UUID]bool, len(candidates))
+		for _, id := range candidates {
+			allowed[id] = true
+		}
+	}
+	armID, _, err := b.selectArm(ctx, experimentID, userID, allowed)
+	return armID, err
+}
+
+// selectArm returns the user's sticky arm or runs Thompson Sampling over the
+// experiment's arms, restricted to allowed when it is non-nil. The bool
+// reports whether a new assignment was made. A sticky arm paused by the kill
+// switch is ignored like one outside allowed.
+func (b *ThompsonSamplingBandit) selectArm(ctx context.Context, experimentID, userID uuid.UUID, allowed map[uuid.UUID]bool) (uuid.UUID, bool, error) {
+	if b.killSwitch != nil && b.killSwitch.ExperimentKilled(experimentID) {
+		return uuid.Nil, false, fmt.Errorf("%w: %s", ErrExperimentPaused, experimentID)
+	}
+	armID, err := b.activeAssignment(ctx, experimentID, userID)
+	if err != nil {
+		return uuid.Nil, false, err
+	}
+	if armID != uuid.Nil && (allowed == nil || allowed[armID]) && !b.armKilled(armID) {
 		b.logger.Debug("Using existing assignment",
 			zap.String("experiment_id", experimentID.String()),
 			zap.String("user_id", userID.String()),
-			zap.String("arm_id", assignment.ArmID.String()),
+			zap.String("arm_id", armID.String()),
 		)
+		return armID, false, nil
+	}
+
+	// Concurrent first requests for a user must not each draw an arm
+	flightKey := "assign:" + assignmentCacheKey(experimentID, userID) + candidateSetKey(allowed)
+	result, err, _ := b.flights.Do(flightKey, func() (interface{}, error) {
+		return b.assignArm(ctx, experimentID, userID, allowed, nil)
+	})
+	if err != nil {
+		return uuid.Nil, false, err
+	}
+	return result.(uuid.UUID), true, nil
+}
+
+// activeAssignment returns the user's sticky arm, or uuid.Nil when they have
+// none, consulting the cache before Postgres. A database miss is cached as
+// uuid.Nil for noAssignmentTTL, and concurrent lookups for the same user
+// share one query. An assignment to an archived arm is honoured only under
+// ArmReassignmentKeep; under ArmReassignmentExclude it yields ErrUserExcluded.
+func (b *ThompsonSamplingBandit) activeAssignment(ctx context.Context, experimentID, userID uuid.UUID) (uuid.UUID, error) {
+	cacheKey := assignmentCacheKey(experimentID, userID)
+	if armID, err := b.cache.GetAssignment(ctx, cacheKey); err == nil {
+		return armID, nil
+	}
+
+	result, err, _ := b.flights.Do("lookup:"+cacheKey, func() (interface{}, error) {
+		assignment, err := b.repo.GetActiveAssignment(ctx, experimentID, userID)
+		if err != nil && !errors.Is(err, ErrAssignmentNotFound) {
+			// Don't remember transient failures as "not assigned"
+			b.logger.Warn("Failed to load assignment", zap.Error(err))
+			return uuid.Nil, nil
+		}
+		if assignment == nil {
+			if err := b.cache.SetAssignment(ctx, cacheKey, uuid.Nil, noAssignmentTTL); err != nil {
+				b.logger.Warn("Failed to cache missing assignment", zap.Error(err))
+			}
+			return uuid.Nil, nil
+		}
+		// Archived-arm answers aren't cached; the migration task settles
+		// these users shortly
+		if assignment.ExcludedAt != nil || assignment.ArchivedArmPolicy == ArmReassignmentExclude {
+			return uuid.Nil, ErrUserExcluded
+		}
+		if assignment.ArchivedArmPolicy == ArmReassignmentReassign {
+			return uuid.Nil, nil
+		}
+		if ttl := assignment.ExpiresAt.Sub(b.now()); ttl > 0 {
+			if err := b.cache.SetAssignment(ctx, cacheKey, assignment.ArmID, ttl); err != nil {
+				b.logger.Warn("Failed to cache assignment", zap.Error(err))
+			}
+		}
 		return assignment.ArmID, nil
+	})
+	if err != nil {
+		return uuid.Nil, err
 	}
+	return result.(uuid.UUID), nil
+}
 
+// assignArm draws an arm for the user among the experiment's live arms,
+// persists the assignment and caches it. previous is the assignment being
+// replaced when the user is moved off an archived arm.
+func (b *ThompsonSamplingBandit) assignArm(ctx context.Context, experimentID, userID uuid.UUID, allowed map[uuid.UUID]bool, previous *Assignment) (uuid.UUID, error) {
 	// Get all arms for this experiment
-	arms, err := b.repo.GetArms(ctx, experimentID)
+	allArms, err := b.repo.GetArms(ctx, experimentID)
 	if err != nil {
 		return uuid.Nil, fmt.Errorf("failed to get arms: %w", err)
 	}
 
+	arms := allArms[:0:0]
+	for _, arm := range activeArms(allArms) {
+		if allowed == nil || allowed[arm.ID] {
+			arms = append(arms, arm)
+		}
+	}
+
 	if len(arms) == 0 {
 		return uuid.Nil, fmt.Errorf("%w: %s", ErrExperimentArmsNotFound, experimentID)
 	}
+	arms = b.liveArms(arms)
+	if len(arms) == 0 {
+		return uuid.Nil, fmt.Errorf("%w: every arm of %s is paused", ErrExperimentPaused, experimentID)
+	}
+
+	cacheKey := assignmentCacheKey(experimentID, userID)
+	// A flight that finished just before this one started may have assigned
+	// the user already
+	if armID, err := b.cache.GetAssignment(ctx, cacheKey); err == nil && previous == nil {
+		for _, arm := range arms {
+			if arm.ID == armID {
+				return armID, nil
+			}
+		}
+	}
+
+	config, err := b.repo.GetExperimentConfig(ctx, experimentID)
+	if err != nil {
+		b.logger.Warn("Failed to load experiment config, using defaults", zap.Error(err))
+		config = nil
+	}
+	optimizeRevenue := config != nil && config.ObjectiveType == ObjectiveRevenue
 
 	var bestArm *Arm
 	maxSample := -1.0
@@ -292,8 +527,14 @@ func (b *ThompsonSamplingBandit) SelectArm(ctx context.Context, experimentID, us
 			}
 		}
 
-		// Sample from Beta(alpha, beta)
-		sample := b.SampleBeta(stats.Alpha, stats.Beta)
+		// Sample from Beta(alpha, beta), or from the revenue-per-visitor
+		// posterior when the experiment optimizes revenue
+		var sample float64
+		if optimizeRevenue {
+			sample = b.SampleRevenuePerVisitor(stats)
+		} else {
+			sample = b.SampleBeta(stats.Alpha, stats.Beta)
+		}
 
 		b.logger.Debug("Arm sample",
 			zap.String("arm_id", arm.ID.String()),
@@ -327,43 +568,88 @@ func (b *ThompsonSamplingBandit) SelectArm(ctx context.Context, experimentID, us
 		bestArm = &arms[b.rng.Intn(len(arms))]
 	}
 
-	assignedAt := time.Now().UTC()
+	assignedAt := b.now().UTC()
 	assignment := &Assignment{
 		ID:           uuid.New(),
 		ExperimentID: experimentID,
 		UserID:       userID,
 		ArmID:        bestArm.ID,
 		AssignedAt:   assignedAt,
-		ExpiresAt:    assignedAt.Add(24 * time.Hour),
+		ExpiresAt:    assignmentExpiry(config, assignedAt),
 		Metadata: map[string]interface{}{
 			"selection_strategy": "thompson_sampling",
+			"revenue_objective":  optimizeRevenue,
 			"arms_considered":    len(arms),
+			"candidate_set":      allowed != nil,
 			"selected_arm_name":  bestArm.Name,
 			"selected_sample":    maxSample,
 			"arm_scores":         armScores,
 		},
 	}
+	if previous != nil {
+		assignment.EventType = AssignmentEventTypeReassigned
+		assignment.Metadata["reassigned_from_arm_id"] = previous.ArmID
+	}
 	if err := b.repo.CreateAssignment(ctx, assignment); err != nil {
 		return uuid.Nil, fmt.Errorf("failed to persist assignment: %w", err)
 	}
 
 	// Create sticky assignment in cache
-	cacheKey := fmt.Sprintf("ab:assign:%s:%s", experimentID.String(), userID.String())
-	if err := b.cache.SetAssignment(ctx, cacheKey, bestArm.ID, 24*time.Hour); err != nil {
+	if err := b.cache.SetAssignment(ctx, cacheKey, bestArm.ID, assignment.ExpiresAt.Sub(assignedAt)); err != nil {
 		b.logger.Warn("Failed to cache assignment", zap.Error(err))
 	}
 
 	return bestArm.ID, nil
 }
 
+// assignmentExpiry returns when an assignment made at assignedAt lapses:
+// after assignmentTTL, or at the experiment's scheduled end if that is sooner
+func assignmentExpiry(config *ExperimentConfig, assignedAt time.Time) time.Time {
+	expiresAt := assignedAt.Add(assignmentTTL)
+	if config != nil && config.EndAt != nil && config.EndAt.After(assignedAt) && config.EndAt.Before(expiresAt) {
+		return *config.EndAt
+	}
+	return expiresAt
+}
+
 // SelectArmWithMeta returns the assigned arm ID and whether it was a new assignment
 func (b *ThompsonSamplingBandit) SelectArmWithMeta(ctx context.Context, experimentID, userID uuid.UUID) (uuid.UUID, bool, error) {
-	// Check for existing assignment first
-	if assignment, err := b.repo.GetActiveAssignment(ctx, experimentID, userID); err == nil && assignment != nil {
-		return assignment.ArmID, false, nil
+	return b.selectArm(ctx, experimentID, userID, nil)
+}
+
+func (b *ThompsonSamplingBandit) armKilled(armID uuid.UUID) bool {
+	return b.killSwitch != nil && b.killSwitch.ArmKilled(armID)
+}
+
+// liveArms drops the arms paused by the kill switch
+func (b *ThompsonSamplingBandit) liveArms(arms []Arm) []Arm {
+	if b.killSwitch == nil {
+		return arms
+	}
+	live := arms[:0:0]
+	for _, arm := range arms {
+		if !b.killSwitch.ArmKilled(arm.ID) {
+			live = append(live, arm)
+		}
+	}
+	return live
+}
+
+func assignmentCacheKey(experimentID, userID uuid.UUID) string {
+	return fmt.Sprintf("ab:assign:%s:%s", experimentID.String(), userID.String())
+}
+
+// candidateSetKey identifies a candidate restriction for single-flight keys
+func candidateSetKey(allowed map[uuid.UUID]bool) string {
+	if allowed == nil {
+		return ""
+	}
+	ids := make([]string, 0, len(allowed))
+	for id := range allowed {
+		ids = append(ids, id.String())
 	}
-	armID, err := b.SelectArm(ctx, experimentID, userID)
-	return armID, err == nil, err
+	sort.Strings(ids)
+	return ":" + strings.Join(ids, ",")
 }
 
 // UpdateReward updates the alpha/beta parameters for the selected arm
@@ -407,7 +693,7 @@ func (b *ThompsonSamplingBandit) TrackImpression(
 		ArmID:        armID,
 		UserID:       userID,
 		EventType:    ImpressionEventTypeImpression,
-		OccurredAt:   time.Now().UTC(),
+		OccurredAt:   b.now().UTC(),
 	}
 	if event != nil {
 		normalizedEvent = *event
@@ -424,7 +710,7 @@ func (b *ThompsonSamplingBandit) TrackImpression(
 			normalizedEvent.EventType = ImpressionEventTypeImpression
 		}
 		if normalizedEvent.OccurredAt.IsZero() {
-			normalizedEvent.OccurredAt = time.Now().UTC()
+			normalizedEvent.OccurredAt = b.now().UTC()
 		}
 	}
 
@@ -435,39 +721,82 @@ func (b *ThompsonSamplingBandit) TrackImpression(
 	return nil
 }
 
+// UpdateRewardWithEvent applies reward to the arm and logs event. An event
+// with a transaction ID is counted once: replays return ErrDuplicateConversion
+// without touching the arm.
 func (b *ThompsonSamplingBandit) UpdateRewardWithEvent(
 	ctx context.Context,
 	experimentID, armID uuid.UUID,
 	reward float64,
 	event *ConversionEvent,
 ) error {
+	claimed, err := b.claimConversion(ctx, experimentID, armID, event)
+	if err != nil {
+		return err
+	}
+	if applied, err := b.applyReward(ctx, experimentID, armID, reward, event); err != nil {
+		if claimed && !applied {
+			// The arm is untouched, so let a retry of the transaction count it
+			if releaseErr := b.repo.(conversionClaimer).ReleaseConversion(ctx, *event.TransactionID); releaseErr != nil {
+				b.logger.Warn("Failed to release conversion claim", zap.String("transaction_id", event.TransactionID.String()), zap.Error(releaseErr))
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// claimConversion reserves the event's transaction, reporting whether a claim
+// was taken. It returns ErrDuplicateConversion for a replay.
+func (b *ThompsonSamplingBandit) claimConversion(ctx context.Context, experimentID, armID uuid.UUID, event *ConversionEvent) (bool, error) {
+	if event == nil || event.TransactionID == nil || *event.TransactionID == uuid.Nil {
+		return false, nil
+	}
+	claimer, ok := b.repo.(conversionClaimer)
+	if !ok {
+		return false, nil
+	}
+	source := event.EventType
+	if source == "" {
+		source = ConversionEventTypeDirectReward
+	}
+	claimed, err := claimer.ClaimConversion(ctx, *event.TransactionID, experimentID, armID, source)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim conversion: %w", err)
+	}
+	if !claimed {
+		b.logger.Info("Ignoring duplicate conversion",
+			zap.String("transaction_id", event.TransactionID.String()),
+			zap.String("arm_id", armID.String()),
+		)
+		return false, fmt.Errorf("%w: %s", ErrDuplicateConversion, event.TransactionID)
+	}
+	return true, nil
+}
+
+// applyReward reports whether the arm stats were saved, even when logging the
+// event failed afterwards
+func (b *ThompsonSamplingBandit) applyReward(
+	ctx context.Context,
+	experimentID, armID uuid.UUID,
+	reward float64,
+	event *ConversionEvent,
+) (bool, error) {
 	// Get current stats
 	stats, err := b.repo.GetArmStats(ctx, armID)
 	if err != nil {
-		return fmt.Errorf("failed to get arm stats: %w", err)
+		return false, fmt.Errorf("failed to get arm stats: %w", err)
 	}
 
 	// Update alpha/beta based on reward
 	// In Thompson Sampling for conversion rate:
 	// - Success (conversion): increment alpha
 	// - Failure (no conversion): increment beta
-	if reward > 0 {
-		stats.Alpha += 1.0
-		stats.Conversions++
-		stats.Revenue += reward
-	} else {
-		stats.Beta += 1.0
-	}
-	stats.Samples++
-
-	// Calculate average reward
-	if stats.Samples > 0 {
-		stats.AvgReward = stats.Revenue / float64(stats.Samples)
-	}
+	stats.AddReward(reward)
 
 	// Save to database
 	if err := b.repo.UpdateArmStats(ctx, stats); err != nil {
-		return fmt.Errorf("failed to update arm stats: %w", err)
+		return false, fmt.Errorf("failed to update arm stats: %w", err)
 	}
 
 	if event != nil {
@@ -483,7 +812,7 @@ func (b *ThompsonSamplingBandit) UpdateRewardWithEvent(
 				normalizedEvent.EventType = ConversionEventTypeDirectReward
 			}
 			if normalizedEvent.OccurredAt.IsZero() {
-				normalizedEvent.OccurredAt = time.Now().UTC()
+				normalizedEvent.OccurredAt = b.now().UTC()
 			}
 			if normalizedEvent.NormalizedRewardValue == 0 {
 				normalizedEvent.NormalizedRewardValue = reward
@@ -496,14 +825,14 @@ func (b *ThompsonSamplingBandit) UpdateRewardWithEvent(
 			}
 
 			if err := appender.AppendConversionEvent(ctx, &normalizedEvent); err != nil {
-				return fmt.Errorf("failed to append conversion event: %w", err)
+				return true, fmt.Errorf("failed to append conversion event: %w", err)
 			}
 		}
 	}
 
 	// Update cache
 	cacheKey := fmt.Sprintf("ab:arm:%s", armID.String())
-	if err := b.cache.SetArmStats(ctx, cacheKey, stats, 24*time.Hour); err != nil {
+	if err := b.cache.SetArmStats(ctx, cacheKey, stats, armStatsTTL); err != nil {
 		b.logger.Warn("Failed to update cache", zap.Error(err))
 	}
 
@@ -515,124 +844,60 @@ func (b *ThompsonSamplingBandit) UpdateRewardWithEvent(
 		zap.Int("samples", stats.Samples),
 	)
 
-	return nil
+	return true, nil
 }
 
-// SampleBeta generates a random sample from Beta(α, β)
-// Uses Marsaglia and Tsang's method for alpha,beta >= 1
-// Falls back to simple uniform for small parameters
+// SampleBeta generates a random sample from Beta(α, β) as X/(X+Y), where
+// X ~ Gamma(α, 1) and Y ~ Gamma(β, 1). Non-positive parameters fall back to
+// the uniform prior.
 func (b *ThompsonSamplingBandit) SampleBeta(alpha, beta float64) float64 {
-	// Handle edge cases
-	if alpha <= 0 || beta <= 0 {
-		return b.rng.Float64()
-	}
-
-	// For small parameters, use simple approximation
-	if alpha < 1 && beta < 1 {
-		return b.sampleBetaJohnk(alpha, beta)
-	}
-
-	if alpha < 1 {
-		// For alpha < 1, beta >= 1
-		return b.SampleBeta(alpha+1, beta) * math.Pow(b.rng.Float64(), 1/alpha)
-	}
-
-	if beta < 1 {
-		// For beta < 1, alpha >= 1
-		return b.SampleBeta(alpha, beta+1) * math.Pow(b.rng.Float64(), 1/beta)
-	}
-
-	// Try Marsaglia-Tsang method for alpha,beta >= 1
-	if sample := b.sampleBetaMarsagliaTsang(alpha, beta); sample >= 0 {
-		return sample
-	}
-
-	// Fallback: Cheng's method
-	return b.sampleBetaCheng(alpha, beta)
+	return sampleBeta(b.rng, alpha, beta)
 }
 
-// sampleBetaJohnk implements Johnk's method for alpha,beta < 1
-func (b *ThompsonSamplingBandit) sampleBetaJohnk(alpha, beta float64) float64 {
-	for {
-		u1 := b.rng.Float64()
-		u2 := b.rng.Float64()
-		if u1 == 0 || u2 == 0 {
-			continue
-		}
-		x := math.Pow(u1, 1/alpha)
-		y := math.Pow(u2, 1/beta)
-		if x+y <= 1 {
-			return x / (x + y)
-		}
+// sampleBeta draws from Beta(α, β) with rng, see SampleBeta
+func sampleBeta(rng *rand.Rand, alpha, beta float64) float64 {
+	if alpha <= 0 || beta <= 0 {
+		return rng.Float64()
 	}
-}
 
-// sampleBetaMarsagliaTsang implements Marsaglia-Tsang method for alpha,beta >= 1
-// Returns -1 if sampling fails
-func (b *ThompsonSamplingBandit) sampleBetaMarsagliaTsang(alpha, beta float64) float64 {
-	const iterations = 3
-
-	for i := 0; i < iterations; i++ {
-		u := b.rng.Float64()
-		v := b.rng.Float64()
-
-		gamma := b.sampleGamma(alpha, u)
-		gamma2 := b.sampleGamma(beta, v)
-
-		if gamma+gamma2 > 0 {
-			return gamma / (gamma + gamma2)
+	x := sampleGamma(rng, alpha)
+	y := sampleGamma(rng, beta)
+	if x+y == 0 {
+		// Both variates underflowed, which only happens for tiny shapes where
+		// Beta(α, β) is close to Bernoulli(α/(α+β))
+		if rng.Float64() < alpha/(alpha+beta) {
+			return 1
 		}
+		return 0
 	}
-
-	return -1 // Indicate failure
-}
-
-// sampleGamma generates a sample from Gamma(shape, 1) using logarithm
-func (b *ThompsonSamplingBandit) sampleGamma(shape, u float64) float64 {
-	gamma := -math.Log(u)
-	if gamma > 0 {
-		gamma = math.Pow(gamma, 1/shape)
-	}
-	return gamma
+	return x / (x + y)
 }
 
-// sampleBetaCheng implements Cheng's method as a fallback
-func (b *ThompsonSamplingBandit) sampleBetaCheng(alpha, beta float64) float64 {
-	a := alpha - 1
-	bParam := beta - 1
-
-	// Initial theta value
-	theta := 1.0
-	if a <= bParam {
-		theta = a / (a + bParam)
+// sampleGamma draws from Gamma(shape, 1) with Marsaglia and Tsang's squeeze
+// method ("A Simple Method for Generating Gamma Variables", 2000). Shapes
+// below 1 are boosted: Gamma(a) = Gamma(a+1) * U^(1/a).
+func sampleGamma(rng *rand.Rand, shape float64) float64 {
+	if shape < 1 {
+		return sampleGamma(rng, shape+1) * math.Pow(rng.Float64(), 1/shape)
 	}
 
-	x := theta
+	d := shape - 1.0/3.0
+	c := 1 / math.Sqrt(9*d)
 	for {
-		u := b.rng.Float64()
-		v := b.rng.Float64()
-
-		if u == 0 || v == 0 {
+		x := rng.NormFloat64()
+		v := 1 + c*x
+		if v <= 0 {
 			continue
 		}
-
-		w := math.Pow(v, 1/beta)
-		x = math.Pow(w/(1+w), 1/alpha)
-
-		if x <= 0 || x >= 1 {
-			continue
+		v = v * v * v
+		u := rng.Float64()
+		if u < 1-0.0331*(x*x)*(x*x) {
+			return d * v
 		}
-
-		// Acceptance-rejection
-		lhs := math.Pow(1-x, bParam)
-		rhs := math.Pow(x, a-1)
-
-		if u <= lhs*rhs {
-			break
+		if math.Log(u) < 0.5*x*x+d*(1-v+math.Log(v)) {
+			return d * v
 		}
 	}
-
-	return x
 }
 
 // GetArmStatistics returns the current statistics for all arms in an experiment
@@ -655,53 +920,44 @@ func (b *ThompsonSamplingBandit) GetArmStatistics(ctx context.Context, experimen
 	return stats, nil
 }
 
-// CalculateWinProbability calculates the probability that each arm is the best
-// using Monte Carlo simulation of Beta distributions
-func (b *ThompsonSamplingBandit) CalculateWinProbability(ctx context.Context, experimentID uuid.UUID, simulations int) (map[uuid.UUID]float64, error) {
+// WarmArmStats loads the statistics of every arm in the experiment into the
+// cache and returns how many arms were cached
+func (b *ThompsonSamplingBandit) WarmArmStats(ctx context.Context, experimentID uuid.UUID) (int, error) {
 	arms, err := b.repo.GetArms(ctx, experimentID)
 	if err != nil {
-		return nil, err
+		return 0, err
+	}
+	if len(arms) == 0 {
+		return 0, ErrExperimentArmsNotFound
 	}
 
-	// Get stats for all arms
-	armStats := make([]*ArmStats, 0, len(arms))
+	warmed := 0
 	for _, arm := range arms {
 		stats, err := b.repo.GetArmStats(ctx, arm.ID)
 		if err != nil {
-			return nil, err
-		}
-		stats.ArmID = arm.ID
-		armStats = append(armStats, stats)
-	}
-
-	// Monte Carlo simulation
-	winCounts := make(map[uuid.UUID]int)
-	for _, stats := range armStats {
-		winCounts[stats.ArmID] = 0
-	}
-
-	for i := 0; i < simulations; i++ {
-		var bestArmID uuid.UUID
-		maxSample := -1.0
-
-		for _, stats := range armStats {
-			sample := b.SampleBeta(stats.Alpha, stats.Beta)
-			if sample > maxSample {
-				maxSample = sample
-				bestArmID = stats.ArmID
-			}
+			return warmed, fmt.Errorf("failed to load stats for arm %s: %w", arm.ID, err)
 		}
-
-		if bestArmID != uuid.Nil {
-			winCounts[bestArmID]++
+		cacheKey := fmt.Sprintf("ab:arm:%s", arm.ID.String())
+		if err := b.cache.SetArmStats(ctx, cacheKey, stats, armStatsTTL); err != nil {
+			return warmed, fmt.Errorf("failed to cache stats for arm %s: %w", arm.ID, err)
 		}
+		warmed++
 	}
+	return warmed, nil
+}
 
-	// Convert to probabilities
-	winProbs := make(map[uuid.UUID]float64)
-	for armID, count := range winCounts {
-		winProbs[armID] = float64(count) / float64(simulations)
-	}
+// WarmAssignment caches the user's live assignment, or the negative answer
+// when they have none, and returns the assigned arm (uuid.Nil if none)
+func (b *ThompsonSamplingBandit) WarmAssignment(ctx context.Context, experimentID, userID uuid.UUID) (uuid.UUID, error) {
+	return b.activeAssignment(ctx, experimentID, userID)
+}
 
-	return winProbs, nil
+// CalculateWinProbability calculates the probability that each arm is the best
+// using Monte Carlo simulation of Beta distributions
+func (b *ThompsonSamplingBandit) CalculateWinProbability(ctx context.Context, experimentID uuid.UUID, simulations int) (map[uuid.UUID]float64, error) {
+	simulation, err := b.SimulateArms(ctx, experimentID, simulations)
+	if err != nil {
+		return nil, err
+	}
+	return simulation.WinProbabilities, nil
 }
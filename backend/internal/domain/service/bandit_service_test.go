@@ -0,0 +1,521 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/clock"
+)
+
+func TestThompsonSamplingBandit_WithSeedIsReproducible(t *testing.T) {
+	newBandit := func(seed int64) *ThompsonSamplingBandit {
+		return NewThompsonSamplingBandit(&advancedEngineTestRepo{}, &advancedEngineTestCache{}, zap.NewNop()).WithSeed(seed)
+	}
+	first, second, other := newBandit(42), newBandit(42), newBandit(7)
+
+	var differs bool
+	for i := 0; i < 100; i++ {
+		a := first.SampleBeta(3, 5)
+		assert.Equal(t, a, second.SampleBeta(3, 5))
+		if a != other.SampleBeta(3, 5) {
+			differs = true
+		}
+	}
+	assert.True(t, differs, "a different seed should give different samples")
+}
+
+func TestThompsonSamplingBandit_WinProbabilityIsReproducibleWithSeed(t *testing.T) {
+	control, treatment := uuid.New(), uuid.New()
+	repo := &advancedEngineTestRepo{
+		arms: []Arm{{ID: control, IsControl: true}, {ID: treatment}},
+		armStats: map[uuid.UUID]*ArmStats{
+			control:   {ArmID: control, Alpha: 11, Beta: 91},
+			treatment: {ArmID: treatment, Alpha: 14, Beta: 88},
+		},
+	}
+
+	run := func() map[uuid.UUID]float64 {
+		bandit := NewThompsonSamplingBandit(repo, &advancedEngineTestCache{}, zap.NewNop()).WithSeed(1)
+		probs, err := bandit.CalculateWinProbability(context.Background(), uuid.New(), 2000)
+		require.NoError(t, err)
+		return probs
+	}
+	assert.Equal(t, run(), run())
+}
+
+func TestThompsonSamplingBandit_SampleBetaIsSafeForConcurrentUse(t *testing.T) {
+	bandit := NewThompsonSamplingBandit(&advancedEngineTestRepo{}, &advancedEngineTestCache{}, zap.NewNop()).WithSeed(3)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				sample := bandit.SampleBeta(2, 2)
+				if sample < 0 || sample > 1 {
+					t.Errorf("sample %v outside [0, 1]", sample)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// ksStatistic is the Kolmogorov-Smirnov distance between samples and cdf
+func ksStatistic(samples []float64, cdf func(float64) float64) float64 {
+	sorted := append([]float64(nil), samples...)
+	sort.Float64s(sorted)
+	n := float64(len(sorted))
+	var d float64
+	for i, x := range sorted {
+		f := cdf(x)
+		d = math.Max(d, math.Max(float64(i+1)/n-f, f-float64(i)/n))
+	}
+	return d
+}
+
+func TestThompsonSamplingBandit_SampleBetaMatchesDistribution(t *testing.T) {
+	const n = 20000
+	// 1.63/sqrt(n) is the Kolmogorov-Smirnov critical value at the 1% level
+	critical := 1.63 / math.Sqrt(n)
+
+	tests := []struct {
+		name        string
+		alpha, beta float64
+		cdf         func(float64) float64
+	}{
+		{"uniform", 1, 1, func(x float64) float64 { return x }},
+		{"alpha 3, beta 1", 3, 1, func(x float64) float64 { return math.Pow(x, 3) }},
+		{"alpha 1, beta 4", 1, 4, func(x float64) float64 { return 1 - math.Pow(1-x, 4) }},
+		{"alpha 2, beta 2", 2, 2, func(x float64) float64 { return 3*x*x - 2*x*x*x }},
+		{"alpha 0.5, beta 1", 0.5, 1, math.Sqrt},
+		{"alpha 1, beta 0.5", 1, 0.5, func(x float64) float64 { return 1 - math.Sqrt(1-x) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bandit := NewThompsonSamplingBandit(&advancedEngineTestRepo{}, &advancedEngineTestCache{}, zap.NewNop()).WithSeed(11)
+			samples := make([]float64, n)
+			for i := range samples {
+				samples[i] = bandit.SampleBeta(tt.alpha, tt.beta)
+			}
+			assert.Less(t, ksStatistic(samples, tt.cdf), critical)
+		})
+	}
+}
+
+func TestThompsonSamplingBandit_SampleBetaMoments(t *testing.T) {
+	const n = 20000
+	for _, params := range [][2]float64{{0.3, 0.7}, {11, 91}, {150, 850}, {1e4, 3e4}} {
+		alpha, beta := params[0], params[1]
+		bandit := NewThompsonSamplingBandit(&advancedEngineTestRepo{}, &advancedEngineTestCache{}, zap.NewNop()).WithSeed(5)
+
+		var sum, sumSq float64
+		for i := 0; i < n; i++ {
+			x := bandit.SampleBeta(alpha, beta)
+			require.True(t, x >= 0 && x <= 1, "sample %v outside [0, 1]", x)
+			sum += x
+			sumSq += x * x
+		}
+		mean := sum / n
+		variance := sumSq/n - mean*mean
+
+		wantMean := alpha / (alpha + beta)
+		wantVariance := alpha * beta / ((alpha + beta) * (alpha + beta) * (alpha + beta + 1))
+		// Five standard errors of the mean; variance within 10%
+		assert.InDelta(t, wantMean, mean, 5*math.Sqrt(wantVariance/n), "mean for Beta(%v, %v)", alpha, beta)
+		assert.InEpsilon(t, wantVariance, variance, 0.1, "variance for Beta(%v, %v)", alpha, beta)
+	}
+}
+
+func BenchmarkThompsonSamplingBandit_SampleBeta(b *testing.B) {
+	bandit := NewThompsonSamplingBandit(&advancedEngineTestRepo{}, &advancedEngineTestCache{}, zap.NewNop()).WithSeed(1)
+	for _, params := range [][2]float64{{0.5, 0.5}, {2, 8}, {120, 880}} {
+		b.Run(fmt.Sprintf("alpha=%v,beta=%v", params[0], params[1]), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				bandit.SampleBeta(params[0], params[1])
+			}
+		})
+	}
+}
+
+// assignmentTestRepo records assignment reads and writes
+type assignmentTestRepo struct {
+	advancedEngineTestRepo
+
+	mu       sync.Mutex
+	active   *Assignment
+	lookups  int
+	created  []*Assignment
+	release  chan struct{} // when set, GetArms blocks until it is closed
+	armCalls int
+}
+
+func (r *assignmentTestRepo) GetActiveAssignment(ctx context.Context, experimentID, userID uuid.UUID) (*Assignment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lookups++
+	if r.active == nil {
+		return nil, ErrAssignmentNotFound
+	}
+	return r.active, nil
+}
+
+func (r *assignmentTestRepo) CreateAssignment(ctx context.Context, assignment *Assignment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.created = append(r.created, assignment)
+	return nil
+}
+
+func (r *assignmentTestRepo) GetArms(ctx context.Context, experimentID uuid.UUID) ([]Arm, error) {
+	if r.release != nil {
+		<-r.release
+	}
+	r.mu.Lock()
+	r.armCalls++
+	r.mu.Unlock()
+	return r.arms, nil
+}
+
+// assignmentTestCache is an in-memory assignment cache that misses with an
+// error, like Redis
+type assignmentTestCache struct {
+	advancedEngineTestCache
+
+	mu          sync.Mutex
+	assignments map[string]uuid.UUID
+	ttls        map[string]time.Duration
+}
+
+func newAssignmentTestCache() *assignmentTestCache {
+	return &assignmentTestCache{assignments: map[string]uuid.UUID{}, ttls: map[string]time.Duration{}}
+}
+
+func (c *assignmentTestCache) GetArmStats(ctx context.Context, key string) (*ArmStats, error) {
+	return nil, errors.New("cache miss")
+}
+
+func (c *assignmentTestCache) GetAssignment(ctx context.Context, key string) (uuid.UUID, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if armID, ok := c.assignments[key]; ok {
+		return armID, nil
+	}
+	return uuid.Nil, errors.New("cache miss")
+}
+
+func (c *assignmentTestCache) SetAssignment(ctx context.Context, key string, armID uuid.UUID, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.assignments[key] = armID
+	c.ttls[key] = ttl
+	return nil
+}
+
+func (c *assignmentTestCache) DeleteKey(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.assignments, key)
+	delete(c.ttls, key)
+	return nil
+}
+
+func TestThompsonSamplingBandit_SelectArmUsesCachedAssignment(t *testing.T) {
+	experimentID, userID, armID := uuid.New(), uuid.New(), uuid.New()
+	repo := &assignmentTestRepo{}
+	cache := newAssignmentTestCache()
+	cache.assignments[assignmentCacheKey(experimentID, userID)] = armID
+
+	got, isNew, err := NewThompsonSamplingBandit(repo, cache, zap.NewNop()).SelectArmWithMeta(context.Background(), experimentID, userID)
+	require.NoError(t, err)
+	assert.Equal(t, armID, got)
+	assert.False(t, isNew)
+	assert.Zero(t, repo.lookups, "a cache hit must not query Postgres")
+}
+
+func TestThompsonSamplingBandit_SelectArmWarmsCacheFromDatabase(t *testing.T) {
+	experimentID, userID, armID := uuid.New(), uuid.New(), uuid.New()
+	repo := &assignmentTestRepo{active: &Assignment{ArmID: armID, ExpiresAt: time.Now().Add(time.Hour)}}
+	cache := newAssignmentTestCache()
+	bandit := NewThompsonSamplingBandit(repo, cache, zap.NewNop())
+
+	for i := 0; i < 3; i++ {
+		got, err := bandit.SelectArm(context.Background(), experimentID, userID)
+		require.NoError(t, err)
+		assert.Equal(t, armID, got)
+	}
+	assert.Equal(t, 1, repo.lookups)
+	assert.Empty(t, repo.created)
+
+	key := assignmentCacheKey(experimentID, userID)
+	assert.Equal(t, armID, cache.assignments[key])
+	assert.InDelta(t, time.Hour, cache.ttls[key], float64(time.Minute), "cache entry should expire with the assignment")
+}
+
+func TestThompsonSamplingBandit_SelectArmPersistsNewAssignment(t *testing.T) {
+	experimentID, userID := uuid.New(), uuid.New()
+	arms := []Arm{{ID: uuid.New(), IsControl: true}, {ID: uuid.New()}}
+	repo := &assignmentTestRepo{advancedEngineTestRepo: advancedEngineTestRepo{arms: arms}}
+	cache := newAssignmentTestCache()
+	bandit := NewThompsonSamplingBandit(repo, cache, zap.NewNop()).WithSeed(1)
+
+	armID, isNew, err := bandit.SelectArmWithMeta(context.Background(), experimentID, userID)
+	require.NoError(t, err)
+	assert.True(t, isNew)
+	require.Len(t, repo.created, 1)
+	assert.Equal(t, armID, repo.created[0].ArmID)
+	assert.Equal(t, armID, cache.assignments[assignmentCacheKey(experimentID, userID)])
+
+	// Stickiness survives cache eviction through the stored assignment
+	repo.active = repo.created[0]
+	delete(cache.assignments, assignmentCacheKey(experimentID, userID))
+	again, isNew, err := bandit.SelectArmWithMeta(context.Background(), experimentID, userID)
+	require.NoError(t, err)
+	assert.Equal(t, armID, again)
+	assert.False(t, isNew)
+	assert.Len(t, repo.created, 1)
+}
+
+func TestThompsonSamplingBandit_AssignmentExpiresWithExperiment(t *testing.T) {
+	experimentID, userID := uuid.New(), uuid.New()
+	endAt := time.Now().Add(2 * time.Hour).UTC()
+	repo := &assignmentTestRepo{advancedEngineTestRepo: advancedEngineTestRepo{
+		arms:             []Arm{{ID: uuid.New(), IsControl: true}},
+		experimentConfig: &ExperimentConfig{ID: experimentID, EndAt: &endAt},
+	}}
+	cache := newAssignmentTestCache()
+
+	_, err := NewThompsonSamplingBandit(repo, cache, zap.NewNop()).SelectArm(context.Background(), experimentID, userID)
+	require.NoError(t, err)
+	require.Len(t, repo.created, 1)
+	assert.Equal(t, endAt, repo.created[0].ExpiresAt)
+	assert.InDelta(t, 2*time.Hour, cache.ttls[assignmentCacheKey(experimentID, userID)], float64(time.Minute))
+
+	// An end date beyond the default TTL does not extend the assignment
+	farEnd := time.Now().Add(30 * 24 * time.Hour)
+	repo.experimentConfig.EndAt = &farEnd
+	_, err = NewThompsonSamplingBandit(repo, newAssignmentTestCache(), zap.NewNop()).SelectArm(context.Background(), experimentID, uuid.New())
+	require.NoError(t, err)
+	require.Len(t, repo.created, 2)
+	assert.Equal(t, assignmentTTL, repo.created[1].ExpiresAt.Sub(repo.created[1].AssignedAt))
+}
+
+func TestThompsonSamplingBandit_AssignmentExpiryFollowsClock(t *testing.T) {
+	experimentID, userID := uuid.New(), uuid.New()
+	repo := &assignmentTestRepo{advancedEngineTestRepo: advancedEngineTestRepo{arms: []Arm{{ID: uuid.New(), IsControl: true}}}}
+	fakeClock := clock.NewFake(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	bandit := NewThompsonSamplingBandit(repo, newAssignmentTestCache(), zap.NewNop()).WithClock(fakeClock)
+
+	_, err := bandit.SelectArm(context.Background(), experimentID, userID)
+	require.NoError(t, err)
+	require.Len(t, repo.created, 1)
+	assert.Equal(t, fakeClock.Now(), repo.created[0].AssignedAt)
+	assert.Equal(t, fakeClock.Now().Add(assignmentTTL), repo.created[0].ExpiresAt)
+
+	// Warming the cache later only keeps the assignment for what is left of it
+	repo.active = repo.created[0]
+	fakeClock.Advance(assignmentTTL - time.Hour)
+	cache := newAssignmentTestCache()
+	_, err = NewThompsonSamplingBandit(repo, cache, zap.NewNop()).WithClock(fakeClock).SelectArm(context.Background(), experimentID, userID)
+	require.NoError(t, err)
+	assert.Equal(t, time.Hour, cache.ttls[assignmentCacheKey(experimentID, userID)])
+}
+
+func TestThompsonSamplingBandit_ActiveAssignmentCachesMiss(t *testing.T) {
+	experimentID, userID := uuid.New(), uuid.New()
+	repo := &assignmentTestRepo{}
+	cache := newAssignmentTestCache()
+	bandit := NewThompsonSamplingBandit(repo, cache, zap.NewNop())
+
+	for i := 0; i < 3; i++ {
+		armID, err := bandit.activeAssignment(context.Background(), experimentID, userID)
+		require.NoError(t, err)
+		assert.Equal(t, uuid.Nil, armID)
+	}
+	assert.Equal(t, 1, repo.lookups)
+
+	key := assignmentCacheKey(experimentID, userID)
+	assert.Equal(t, uuid.Nil, cache.assignments[key])
+	assert.Equal(t, noAssignmentTTL, cache.ttls[key])
+}
+
+func TestThompsonSamplingBandit_ConcurrentSelectArmAssignsOnce(t *testing.T) {
+	experimentID, userID := uuid.New(), uuid.New()
+	arms := []Arm{{ID: uuid.New(), IsControl: true}, {ID: uuid.New()}, {ID: uuid.New()}}
+	repo := &assignmentTestRepo{advancedEngineTestRepo: advancedEngineTestRepo{arms: arms}, release: make(chan struct{})}
+	bandit := NewThompsonSamplingBandit(repo, newAssignmentTestCache(), zap.NewNop())
+
+	const callers = 16
+	results := make([]uuid.UUID, callers)
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			armID, err := bandit.SelectArm(context.Background(), experimentID, userID)
+			assert.NoError(t, err)
+			results[i] = armID
+		}(i)
+	}
+	// Give every caller time to join the in-flight assignment
+	time.Sleep(50 * time.Millisecond)
+	close(repo.release)
+	wg.Wait()
+
+	for _, armID := range results {
+		assert.Equal(t, results[0], armID)
+	}
+	assert.Len(t, repo.created, 1)
+}
+
+// killSwitchTestState is an in-memory ExperimentKillSwitch
+type killSwitchTestState struct {
+	experiments map[uuid.UUID]bool
+	arms        map[uuid.UUID]bool
+}
+
+func (k killSwitchTestState) ExperimentKilled(experimentID uuid.UUID) bool {
+	return k.experiments[experimentID]
+}
+
+func (k killSwitchTestState) ArmKilled(armID uuid.UUID) bool { return k.arms[armID] }
+
+func TestThompsonSamplingBandit_KillSwitchStopsCachedAssignments(t *testing.T) {
+	experimentID, userID, armID := uuid.New(), uuid.New(), uuid.New()
+	cache := newAssignmentTestCache()
+	cache.assignments[assignmentCacheKey(experimentID, userID)] = armID
+	ks := killSwitchTestState{experiments: map[uuid.UUID]bool{experimentID: true}}
+
+	_, err := NewThompsonSamplingBandit(&assignmentTestRepo{}, cache, zap.NewNop()).WithKillSwitch(ks).
+		SelectArm(context.Background(), experimentID, userID)
+
+	require.ErrorIs(t, err, ErrExperimentPaused)
+}
+
+func TestThompsonSamplingBandit_KillSwitchMovesUsersOffPausedArms(t *testing.T) {
+	experimentID, userID := uuid.New(), uuid.New()
+	paused, live := uuid.New(), uuid.New()
+	repo := &assignmentTestRepo{advancedEngineTestRepo: advancedEngineTestRepo{arms: []Arm{{ID: paused, IsControl: true}, {ID: live}}}}
+	cache := newAssignmentTestCache()
+	cache.assignments[assignmentCacheKey(experimentID, userID)] = paused
+	ks := killSwitchTestState{arms: map[uuid.UUID]bool{paused: true}}
+	bandit := NewThompsonSamplingBandit(repo, cache, zap.NewNop()).WithKillSwitch(ks)
+
+	armID, isNew, err := bandit.SelectArmWithMeta(context.Background(), experimentID, userID)
+	require.NoError(t, err)
+	assert.Equal(t, live, armID)
+	assert.True(t, isNew)
+
+	ks.arms[live] = true
+	delete(cache.assignments, assignmentCacheKey(experimentID, userID))
+	_, err = bandit.SelectArm(context.Background(), experimentID, userID)
+	require.ErrorIs(t, err, ErrExperimentPaused, "pausing every arm pauses the experiment")
+}
+
+type armStatsTestCache struct {
+	advancedEngineTestCache
+	stats map[string]*ArmStats
+	ttls  map[string]time.Duration
+}
+
+func (c *armStatsTestCache) SetArmStats(ctx context.Context, key string, stats *ArmStats, ttl time.Duration) error {
+	c.stats[key] = stats
+	c.ttls[key] = ttl
+	return nil
+}
+
+func TestThompsonSamplingBandit_WarmArmStatsCachesEveryArm(t *testing.T) {
+	control, variant := uuid.New(), uuid.New()
+	repo := &advancedEngineTestRepo{
+		arms:     []Arm{{ID: control, IsControl: true}, {ID: variant}},
+		armStats: map[uuid.UUID]*ArmStats{variant: {ArmID: variant, Alpha: 12, Beta: 40}},
+	}
+	cache := &armStatsTestCache{stats: map[string]*ArmStats{}, ttls: map[string]time.Duration{}}
+	bandit := NewThompsonSamplingBandit(repo, cache, zap.NewNop())
+
+	warmed, err := bandit.WarmArmStats(context.Background(), uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, 2, warmed)
+	assert.Equal(t, 12.0, cache.stats["ab:arm:"+variant.String()].Alpha)
+	assert.Equal(t, armStatsTTL, cache.ttls["ab:arm:"+control.String()])
+
+	_, err = NewThompsonSamplingBandit(&advancedEngineTestRepo{}, cache, zap.NewNop()).WarmArmStats(context.Background(), uuid.New())
+	assert.ErrorIs(t, err, ErrExperimentArmsNotFound)
+}
+
+// conversionClaimTestRepo claims transactions in memory and counts stat writes
+type conversionClaimTestRepo struct {
+	advancedEngineTestRepo
+
+	claimed  map[uuid.UUID]bool
+	updates  int
+	failSave bool
+}
+
+func (r *conversionClaimTestRepo) ClaimConversion(ctx context.Context, transactionID, experimentID, armID uuid.UUID, source ConversionEventType) (bool, error) {
+	if r.claimed[transactionID] {
+		return false, nil
+	}
+	r.claimed[transactionID] = true
+	return true, nil
+}
+
+func (r *conversionClaimTestRepo) ReleaseConversion(ctx context.Context, transactionID uuid.UUID) error {
+	delete(r.claimed, transactionID)
+	return nil
+}
+
+func (r *conversionClaimTestRepo) UpdateArmStats(ctx context.Context, stats *ArmStats) error {
+	if r.failSave {
+		return errors.New("database unavailable")
+	}
+	r.updates++
+	return nil
+}
+
+func TestThompsonSamplingBandit_UpdateRewardWithEventCountsTransactionOnce(t *testing.T) {
+	experimentID, armID, transactionID := uuid.New(), uuid.New(), uuid.New()
+	repo := &conversionClaimTestRepo{claimed: map[uuid.UUID]bool{}}
+	bandit := NewThompsonSamplingBandit(repo, &advancedEngineTestCache{}, zap.NewNop())
+	event := func() *ConversionEvent {
+		return &ConversionEvent{TransactionID: &transactionID, EventType: ConversionEventTypeDirectReward}
+	}
+
+	require.NoError(t, bandit.UpdateRewardWithEvent(context.Background(), experimentID, armID, 9.99, event()))
+	err := bandit.UpdateRewardWithEvent(context.Background(), experimentID, armID, 9.99, event())
+	assert.ErrorIs(t, err, ErrDuplicateConversion)
+	assert.Equal(t, 1, repo.updates, "a replayed transaction must not update the arm again")
+
+	// Rewards without a transaction are not deduplicated
+	require.NoError(t, bandit.UpdateRewardWithEvent(context.Background(), experimentID, armID, 1, &ConversionEvent{}))
+	require.NoError(t, bandit.UpdateRewardWithEvent(context.Background(), experimentID, armID, 1, &ConversionEvent{}))
+	assert.Equal(t, 3, repo.updates)
+}
+
+func TestThompsonSamplingBandit_UpdateRewardWithEventReleasesClaimOnFailure(t *testing.T) {
+	experimentID, armID, transactionID := uuid.New(), uuid.New(), uuid.New()
+	repo := &conversionClaimTestRepo{claimed: map[uuid.UUID]bool{}, failSave: true}
+	bandit := NewThompsonSamplingBandit(repo, &advancedEngineTestCache{}, zap.NewNop())
+	event := &ConversionEvent{TransactionID: &transactionID}
+
+	err := bandit.UpdateRewardWithEvent(context.Background(), experimentID, armID, 1, event)
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrDuplicateConversion)
+
+	repo.failSave = false
+	require.NoError(t, bandit.UpdateRewardWithEvent(context.Background(), experimentID, armID, 1, event), "a retry after a failed write must be counted")
+	assert.Equal(t, 1, repo.updates)
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/clock"
+)
+
+// ClientStatusTTL is how long a status is served before health is checked
+// again, and how long clients may cache it
+const ClientStatusTTL = 15 * time.Second
+
+// analyticsDelayedFill is the ingest buffer fill at which analytics count as
+// delayed
+const analyticsDelayedFill = 0.5
+
+// Client status values
+const (
+	ClientStatusOK       = "ok"
+	ClientStatusDegraded = "degraded"
+)
+
+// ClientDegradedFeatures flag features clients should expect to misbehave
+type ClientDegradedFeatures struct {
+	// Verification is set when receipt verification is failing more than
+	// usual; clients should keep receipts and retry rather than report errors
+	Verification bool `json:"verification"`
+	// PurchaseSync is set when store notifications are processed late, so
+	// renewals and cancellations show up with a delay
+	PurchaseSync bool `json:"purchase_sync"`
+	// Analytics is set when events are accepted but land late
+	Analytics bool `json:"analytics"`
+}
+
+// ClientBackoff is the exponential backoff clients should retry failed
+// requests with: InitialDelayMs, multiplied by Multiplier per attempt up to
+// MaxDelayMs, each delay randomized by ±Jitter
+type ClientBackoff struct {
+	InitialDelayMs int     `json:"initial_delay_ms"`
+	MaxDelayMs     int     `json:"max_delay_ms"`
+	Multiplier     float64 `json:"multiplier"`
+	Jitter         float64 `json:"jitter"`
+	// PollIntervalSeconds is how soon to check the status again
+	PollIntervalSeconds int `json:"poll_interval_seconds"`
+}
+
+// Backoff per funnel health status; unknown is treated as green
+var clientBackoffs = map[HealthStatus]ClientBackoff{
+	HealthGreen:  {InitialDelayMs: 500, MaxDelayMs: 30_000, Multiplier: 2, Jitter: 0.2, PollIntervalSeconds: 300},
+	HealthYellow: {InitialDelayMs: 2_000, MaxDelayMs: 120_000, Multiplier: 2, Jitter: 0.3, PollIntervalSeconds: 60},
+	HealthRed:    {InitialDelayMs: 5_000, MaxDelayMs: 600_000, Multiplier: 3, Jitter: 0.5, PollIntervalSeconds: 30},
+}
+
+// ClientStatus tells mobile clients which features are degraded and how to
+// back off while they are
+type ClientStatus struct {
+	Status    string                 `json:"status"`
+	Degraded  ClientDegradedFeatures `json:"degraded"`
+	Backoff   ClientBackoff          `json:"backoff"`
+	CheckedAt time.Time              `json:"checked_at"`
+}
+
+type funnelHealthChecker interface {
+	Check(ctx context.Context, appID uuid.UUID) (*FunnelHealth, error)
+}
+
+// analyticsBuffer is the API's in-memory analytics ingest buffer
+type analyticsBuffer interface {
+	Buffered() int
+	Capacity() int
+}
+
+type cachedClientStatus struct {
+	status    *ClientStatus
+	expiresAt time.Time
+}
+
+// ClientStatusService derives client-facing status from funnel health. Each
+// app's status is kept in memory for ClientStatusTTL, so clients polling it
+// during an outage do not add load to the database being checked.
+type ClientStatusService struct {
+	health    funnelHealthChecker
+	analytics analyticsBuffer
+	logger    *zap.Logger
+	now       func() time.Time
+
+	mu     sync.Mutex
+	cached map[uuid.UUID]cachedClientStatus
+}
+
+func NewClientStatusService(health funnelHealthChecker, logger *zap.Logger) *ClientStatusService {
+	return &ClientStatusService{health: health, logger: logger, now: time.Now, cached: map[uuid.UUID]cachedClientStatus{}}
+}
+
+// WithAnalyticsBuffer also reports analytics delayed while the ingest buffer
+// is filling up
+func (s *ClientStatusService) WithAnalyticsBuffer(buffer analyticsBuffer) *ClientStatusService {
+	s.analytics = buffer
+	return s
+}
+
+func (s *ClientStatusService) WithClock(c clock.Clock) *ClientStatusService {
+	s.now = c.Now
+	return s
+}
+
+// Status returns the app's client status. A failed health check is itself
+// an outage: everything is reported degraded with the longest backoff.
+func (s *ClientStatusService) Status(ctx context.Context, appID uuid.UUID) *ClientStatus {
+	now := s.now()
+	s.mu.Lock()
+	if entry, ok := s.cached[appID]; ok && now.Before(entry.expiresAt) {
+		s.mu.Unlock()
+		return entry.status
+	}
+	s.mu.Unlock()
+
+	status := s.check(ctx, appID, now)
+
+	s.mu.Lock()
+	for id, entry := range s.cached {
+		if !now.Before(entry.expiresAt) {
+			delete(s.cached, id)
+		}
+	}
+	s.cached[appID] = cachedClientStatus{status: status, expiresAt: now.Add(ClientStatusTTL)}
+	s.mu.Unlock()
+	return status
+}
+
+func (s *ClientStatusService) check(ctx context.Context, appID uuid.UUID, now time.Time) *ClientStatus {
+	health, err := s.health.Check(ctx, appID)
+	if err != nil {
+		s.logger.Warn("Funnel health check failed, reporting degraded status", zap.String("app_id", appID.String()), zap.Error(err))
+		return &ClientStatus{
+			Status:    ClientStatusDegraded,
+			Degraded:  ClientDegradedFeatures{Verification: true, PurchaseSync: true, Analytics: true},
+			Backoff:   clientBackoffs[HealthRed],
+			CheckedAt: now,
+		}
+	}
+
+	status := &ClientStatus{Status: ClientStatusOK, CheckedAt: now}
+	worst := HealthGreen
+	for _, f := range health.Factors {
+		if f.Status != HealthYellow && f.Status != HealthRed {
+			continue
+		}
+		switch f.Name {
+		case "verification_error_rate":
+			status.Degraded.Verification = true
+		case "webhook_lag_seconds":
+			status.Degraded.PurchaseSync = true
+		case "staged_event_backlog_seconds":
+			status.Degraded.Analytics = true
+		default:
+			// Conversion dips are a business signal, not a client concern
+			continue
+		}
+		if healthSeverity[f.Status] > healthSeverity[worst] {
+			worst = f.Status
+		}
+	}
+	if s.analytics != nil && s.analytics.Capacity() > 0 &&
+		float64(s.analytics.Buffered())/float64(s.analytics.Capacity()) >= analyticsDelayedFill {
+		status.Degraded.Analytics = true
+		if worst == HealthGreen {
+			worst = HealthYellow
+		}
+	}
+
+	if status.Degraded != (ClientDegradedFeatures{}) {
+		status.Status = ClientStatusDegraded
+	}
+	status.Backoff = clientBackoffs[worst]
+	return status
+}
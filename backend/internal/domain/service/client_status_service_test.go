@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/clock"
+)
+
+type stubFunnelHealth struct {
+	factors []FunnelHealthFactor
+	err     error
+	checks  int
+}
+
+func (s *stubFunnelHealth) Check(ctx context.Context, appID uuid.UUID) (*FunnelHealth, error) {
+	s.checks++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &FunnelHealth{Factors: s.factors}, nil
+}
+
+type stubAnalyticsBuffer struct{ buffered, capacity int }
+
+func (b stubAnalyticsBuffer) Buffered() int { return b.buffered }
+func (b stubAnalyticsBuffer) Capacity() int { return b.capacity }
+
+func TestClientStatusService_Status(t *testing.T) {
+	green := []FunnelHealthFactor{
+		{Name: "verification_error_rate", Status: HealthGreen},
+		{Name: "webhook_lag_seconds", Status: HealthGreen},
+		{Name: "conversion_rate_vs_baseline", Status: HealthRed},
+		{Name: "staged_event_backlog_seconds", Status: HealthUnknown},
+	}
+	withFactor := func(name string, status HealthStatus) []FunnelHealthFactor {
+		factors := append([]FunnelHealthFactor(nil), green...)
+		for i := range factors {
+			if factors[i].Name == name {
+				factors[i].Status = status
+			}
+		}
+		return factors
+	}
+
+	tests := []struct {
+		name     string
+		factors  []FunnelHealthFactor
+		buffer   *stubAnalyticsBuffer
+		status   string
+		degraded ClientDegradedFeatures
+		backoff  HealthStatus
+	}{
+		{name: "healthy, conversion dips are ignored", factors: green, status: ClientStatusOK, backoff: HealthGreen},
+		{name: "verification failing", factors: withFactor("verification_error_rate", HealthRed),
+			status: ClientStatusDegraded, degraded: ClientDegradedFeatures{Verification: true}, backoff: HealthRed},
+		{name: "webhooks lagging", factors: withFactor("webhook_lag_seconds", HealthYellow),
+			status: ClientStatusDegraded, degraded: ClientDegradedFeatures{PurchaseSync: true}, backoff: HealthYellow},
+		{name: "staged events backing up", factors: withFactor("staged_event_backlog_seconds", HealthYellow),
+			status: ClientStatusDegraded, degraded: ClientDegradedFeatures{Analytics: true}, backoff: HealthYellow},
+		{name: "ingest buffer filling", factors: green, buffer: &stubAnalyticsBuffer{buffered: 600, capacity: 1000},
+			status: ClientStatusDegraded, degraded: ClientDegradedFeatures{Analytics: true}, backoff: HealthYellow},
+		{name: "ingest buffer mostly empty", factors: green, buffer: &stubAnalyticsBuffer{buffered: 10, capacity: 1000},
+			status: ClientStatusOK, backoff: HealthGreen},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewClientStatusService(&stubFunnelHealth{factors: tt.factors}, zap.NewNop())
+			if tt.buffer != nil {
+				svc.WithAnalyticsBuffer(*tt.buffer)
+			}
+
+			status := svc.Status(context.Background(), uuid.New())
+
+			assert.Equal(t, tt.status, status.Status)
+			assert.Equal(t, tt.degraded, status.Degraded)
+			assert.Equal(t, clientBackoffs[tt.backoff], status.Backoff)
+		})
+	}
+}
+
+func TestClientStatusService_FailedCheckIsDegraded(t *testing.T) {
+	svc := NewClientStatusService(&stubFunnelHealth{err: errors.New("connection refused")}, zap.NewNop())
+
+	status := svc.Status(context.Background(), uuid.New())
+
+	assert.Equal(t, ClientStatusDegraded, status.Status)
+	assert.Equal(t, ClientDegradedFeatures{Verification: true, PurchaseSync: true, Analytics: true}, status.Degraded)
+	assert.Equal(t, clientBackoffs[HealthRed], status.Backoff)
+}
+
+func TestClientStatusService_CachesPerApp(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC))
+	health := &stubFunnelHealth{}
+	svc := NewClientStatusService(health, zap.NewNop()).WithClock(clk)
+	appA, appB := uuid.New(), uuid.New()
+
+	svc.Status(context.Background(), appA)
+	svc.Status(context.Background(), appA)
+	svc.Status(context.Background(), appB)
+	assert.Equal(t, 2, health.checks)
+
+	clk.Advance(ClientStatusTTL)
+	status := svc.Status(context.Background(), appA)
+	assert.Equal(t, 3, health.checks)
+	assert.Equal(t, clk.Now(), status.CheckedAt)
+}
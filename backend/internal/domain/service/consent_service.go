@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+// consentCacheTTL bounds how long another instance may act on a consent
+// the user has since withdrawn
+const consentCacheTTL = time.Minute
+
+// ConsentChecker reports whether a user allows a use of their data
+type ConsentChecker interface {
+	Allows(ctx context.Context, userID uuid.UUID, purpose entity.ConsentPurpose) bool
+}
+
+// ConsentUpdate changes the purposes that are set; nil leaves one unchanged
+type ConsentUpdate struct {
+	Analytics       *bool
+	Personalization *bool
+	Marketing       *bool
+}
+
+type cachedConsent struct {
+	consent *entity.UserConsent
+	expires time.Time
+}
+
+// ConsentService reads and records users' consent, and answers the
+// enforcement checks of ingestion, Matomo forwarding and bandit targeting
+type ConsentService struct {
+	repo   repository.UserConsentRepository
+	logger *zap.Logger
+	now    func() time.Time
+
+	mu    sync.Mutex
+	cache map[uuid.UUID]cachedConsent
+}
+
+// NewConsentService creates a consent service
+func NewConsentService(repo repository.UserConsentRepository, logger *zap.Logger) *ConsentService {
+	return &ConsentService{repo: repo, logger: logger, now: time.Now, cache: map[uuid.UUID]cachedConsent{}}
+}
+
+// Get returns the user's consent, the opted-in default if they never set one
+func (s *ConsentService) Get(ctx context.Context, appID, userID uuid.UUID) (*entity.UserConsent, error) {
+	consent, err := s.repo.Get(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get consent: %w", err)
+	}
+	if consent == nil {
+		consent = entity.DefaultUserConsent(appID, userID)
+	}
+	return consent, nil
+}
+
+// Update applies the changed purposes and takes effect on this instance
+// immediately
+func (s *ConsentService) Update(ctx context.Context, appID, userID uuid.UUID, update ConsentUpdate) (*entity.UserConsent, error) {
+	consent, err := s.Get(ctx, appID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if update.Analytics != nil {
+		consent.Analytics = *update.Analytics
+	}
+	if update.Personalization != nil {
+		consent.Personalization = *update.Personalization
+	}
+	if update.Marketing != nil {
+		consent.Marketing = *update.Marketing
+	}
+	consent.UpdatedAt = s.now()
+	if err := s.repo.Upsert(ctx, consent); err != nil {
+		return nil, fmt.Errorf("failed to update consent: %w", err)
+	}
+
+	s.mu.Lock()
+	s.cache[userID] = cachedConsent{consent: consent, expires: s.now().Add(consentCacheTTL)}
+	s.mu.Unlock()
+	return consent, nil
+}
+
+// Allows reports whether the user consented to purpose. When consent cannot
+// be read the purpose is denied.
+func (s *ConsentService) Allows(ctx context.Context, userID uuid.UUID, purpose entity.ConsentPurpose) bool {
+	now := s.now()
+	s.mu.Lock()
+	cached, ok := s.cache[userID]
+	s.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.consent.Allows(purpose)
+	}
+
+	consent, err := s.repo.Get(ctx, userID)
+	if err != nil {
+		s.logger.Warn("Failed to read consent, denying", zap.String("user_id", userID.String()), zap.String("purpose", string(purpose)), zap.Error(err))
+		return false
+	}
+	if consent == nil {
+		consent = entity.DefaultUserConsent(uuid.Nil, userID)
+	}
+
+	s.mu.Lock()
+	if len(s.cache) > 100_000 {
+		// Bounded memory; entries are cheap to reload
+		s.cache = map[uuid.UUID]cachedConsent{}
+	}
+	s.cache[userID] = cachedConsent{consent: consent, expires: now.Add(consentCacheTTL)}
+	s.mu.Unlock()
+	return consent.Allows(purpose)
+}
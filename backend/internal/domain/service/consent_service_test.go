@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+type fakeConsentRepo struct {
+	consents map[uuid.UUID]*entity.UserConsent
+	err      error
+	reads    int
+}
+
+func (r *fakeConsentRepo) Get(_ context.Context, userID uuid.UUID) (*entity.UserConsent, error) {
+	r.reads++
+	if r.err != nil {
+		return nil, r.err
+	}
+	if c, ok := r.consents[userID]; ok {
+		copied := *c
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+func (r *fakeConsentRepo) Upsert(_ context.Context, c *entity.UserConsent) error {
+	copied := *c
+	r.consents[c.UserID] = &copied
+	return nil
+}
+
+func TestConsentService_DefaultsToOptedIn(t *testing.T) {
+	svc := NewConsentService(&fakeConsentRepo{consents: map[uuid.UUID]*entity.UserConsent{}}, zap.NewNop())
+	userID := uuid.New()
+
+	assert.True(t, svc.Allows(context.Background(), userID, entity.ConsentAnalytics))
+	assert.True(t, svc.Allows(context.Background(), userID, entity.ConsentPersonalization))
+}
+
+func TestConsentService_UpdateAppliesImmediately(t *testing.T) {
+	repo := &fakeConsentRepo{consents: map[uuid.UUID]*entity.UserConsent{}}
+	svc := NewConsentService(repo, zap.NewNop())
+	appID, userID := uuid.New(), uuid.New()
+	require.True(t, svc.Allows(context.Background(), userID, entity.ConsentAnalytics))
+
+	off := false
+	consent, err := svc.Update(context.Background(), appID, userID, ConsentUpdate{Analytics: &off})
+	require.NoError(t, err)
+
+	assert.False(t, consent.Analytics)
+	assert.True(t, consent.Marketing, "unset purposes keep their value")
+	assert.False(t, svc.Allows(context.Background(), userID, entity.ConsentAnalytics))
+	assert.Equal(t, appID, repo.consents[userID].AppID)
+}
+
+func TestConsentService_DeniesWhenUnreadable(t *testing.T) {
+	svc := NewConsentService(&fakeConsentRepo{err: errors.New("db down")}, zap.NewNop())
+
+	assert.False(t, svc.Allows(context.Background(), uuid.New(), entity.ConsentAnalytics))
+}
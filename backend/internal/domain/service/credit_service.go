@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+// creditHistoryLimit caps the ledger entries returned with a balance
+const creditHistoryLimit = 50
+
+// ltvIncrementer adds purchase revenue to a user's lifetime value
+type ltvIncrementer interface {
+	IncrementLTV(ctx context.Context, id uuid.UUID, amount float64) error
+}
+
+// ConversionRecorder attributes purchase revenue to the bandit arm the user
+// was assigned (see AdvancedBanditEngine.ProcessConversion)
+type ConversionRecorder interface {
+	ProcessConversion(ctx context.Context, transactionID, userID uuid.UUID, conversionValue float64, currency string) error
+}
+
+// CreditService manages consumable credit balances: crediting verified store
+// purchases and debiting them as the app spends credits
+type CreditService struct {
+	repo        repository.CreditRepository
+	ltv         ltvIncrementer
+	conversions ConversionRecorder
+	logger      *zap.Logger
+}
+
+func NewCreditService(repo repository.CreditRepository, logger *zap.Logger) *CreditService {
+	return &CreditService{repo: repo, logger: logger}
+}
+
+// WithLTV counts consumable revenue towards the user's lifetime value
+func (s *CreditService) WithLTV(ltv ltvIncrementer) *CreditService {
+	s.ltv = ltv
+	return s
+}
+
+// WithConversions feeds consumable revenue to bandit experiments as delayed conversions
+func (s *CreditService) WithConversions(recorder ConversionRecorder) *CreditService {
+	s.conversions = recorder
+	return s
+}
+
+// CreditPurchase credits a verified consumable purchase. A receipt that was
+// already credited returns its entry with created false and records no
+// revenue again.
+func (s *CreditService) CreditPurchase(ctx context.Context, entry *entity.CreditEntry) (*entity.CreditEntry, bool, error) {
+	stored, created, err := s.repo.Apply(ctx, entry)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to credit purchase: %w", err)
+	}
+	if !created {
+		return stored, false, nil
+	}
+
+	// Revenue attribution is best-effort: the credits are already granted
+	if stored.Amount > 0 {
+		if s.ltv != nil {
+			if err := s.ltv.IncrementLTV(ctx, stored.UserID, stored.Amount); err != nil {
+				s.logger.Warn("Failed to add consumable revenue to LTV", zap.String("entry_id", stored.ID.String()), zap.Error(err))
+			}
+		}
+		if s.conversions != nil {
+			if err := s.conversions.ProcessConversion(ctx, stored.ID, stored.UserID, stored.Amount, stored.Currency); err != nil && !errors.Is(err, ErrDuplicateConversion) {
+				s.logger.Warn("Failed to record consumable conversion", zap.String("entry_id", stored.ID.String()), zap.Error(err))
+			}
+		}
+	}
+	return stored, true, nil
+}
+
+// Consume debits credits. Replaying the same idempotency key returns the
+// original entry without debiting twice; entity.ErrIdempotencyKeyReused when
+// the replay asks for a different amount.
+func (s *CreditService) Consume(ctx context.Context, entry *entity.CreditEntry) (*entity.CreditEntry, error) {
+	stored, created, err := s.repo.Apply(ctx, entry)
+	if err != nil {
+		if errors.Is(err, entity.ErrInsufficientCredits) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to consume credits: %w", err)
+	}
+	if !created && !stored.SameRequest(entry) {
+		return nil, entity.ErrIdempotencyKeyReused
+	}
+	return stored, nil
+}
+
+// Balance returns the user's balance and most recent ledger entries
+func (s *CreditService) Balance(ctx context.Context, appID, userID uuid.UUID) (int64, []*entity.CreditEntry, error) {
+	balance, err := s.repo.GetBalance(ctx, appID, userID)
+	if err != nil {
+		return 0, nil, err
+	}
+	entries, err := s.repo.ListEntries(ctx, appID, userID, creditHistoryLimit)
+	if err != nil {
+		return 0, nil, err
+	}
+	return balance, entries, nil
+}
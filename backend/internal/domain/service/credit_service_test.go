@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+type fakeCreditRepo struct {
+	balance int64
+	entries map[string]*entity.CreditEntry
+}
+
+func newFakeCreditRepo(balance int64) *fakeCreditRepo {
+	return &fakeCreditRepo{balance: balance, entries: map[string]*entity.CreditEntry{}}
+}
+
+func (r *fakeCreditRepo) Apply(_ context.Context, e *entity.CreditEntry) (*entity.CreditEntry, bool, error) {
+	if existing, ok := r.entries[e.IdempotencyKey]; ok {
+		return existing, false, nil
+	}
+	if r.balance+e.Delta < 0 {
+		return nil, false, entity.ErrInsufficientCredits
+	}
+	r.balance += e.Delta
+	e.BalanceAfter = r.balance
+	r.entries[e.IdempotencyKey] = e
+	return e, true, nil
+}
+
+func (r *fakeCreditRepo) GetBalance(context.Context, uuid.UUID, uuid.UUID) (int64, error) {
+	return r.balance, nil
+}
+
+func (r *fakeCreditRepo) ListEntries(context.Context, uuid.UUID, uuid.UUID, int) ([]*entity.CreditEntry, error) {
+	return nil, nil
+}
+
+type recordingLTV struct{ amounts []float64 }
+
+func (l *recordingLTV) IncrementLTV(_ context.Context, _ uuid.UUID, amount float64) error {
+	l.amounts = append(l.amounts, amount)
+	return nil
+}
+
+type recordingConversions struct {
+	values []float64
+	err    error
+}
+
+func (r *recordingConversions) ProcessConversion(_ context.Context, _, _ uuid.UUID, value float64, _ string) error {
+	r.values = append(r.values, value)
+	return r.err
+}
+
+func TestCreditService_CreditPurchaseCountsRevenueOnce(t *testing.T) {
+	repo := newFakeCreditRepo(0)
+	ltv := &recordingLTV{}
+	conversions := &recordingConversions{err: errors.New("no pending reward table")}
+	svc := NewCreditService(repo, zap.NewNop()).WithLTV(ltv).WithConversions(conversions)
+	appID, userID := uuid.New(), uuid.New()
+	product := entity.ConsumableProduct{Credits: 100, Price: 1.99}
+
+	first, created, err := svc.CreditPurchase(context.Background(), entity.NewCreditPurchase(appID, userID, "ios", "com.app.coins", "tx-1", product))
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, int64(100), first.BalanceAfter)
+
+	replay, created, err := svc.CreditPurchase(context.Background(), entity.NewCreditPurchase(appID, userID, "ios", "com.app.coins", "tx-1", product))
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, first.ID, replay.ID)
+
+	assert.Equal(t, int64(100), repo.balance)
+	assert.Equal(t, []float64{1.99}, ltv.amounts)
+	assert.Equal(t, []float64{1.99}, conversions.values, "a failing conversion does not undo the credit")
+}
+
+func TestCreditService_Consume(t *testing.T) {
+	repo := newFakeCreditRepo(50)
+	svc := NewCreditService(repo, zap.NewNop())
+	appID, userID := uuid.New(), uuid.New()
+	debit := func(amount int64, key string) (*entity.CreditEntry, error) {
+		entry, err := entity.NewCreditConsumption(appID, userID, amount, key, "")
+		require.NoError(t, err)
+		return svc.Consume(context.Background(), entry)
+	}
+
+	entry, err := debit(30, "order-1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(20), entry.BalanceAfter)
+
+	replay, err := debit(30, "order-1")
+	require.NoError(t, err)
+	assert.Equal(t, entry.ID, replay.ID)
+	assert.Equal(t, int64(20), repo.balance, "a replayed key debits once")
+
+	_, err = debit(10, "order-1")
+	assert.ErrorIs(t, err, entity.ErrIdempotencyKeyReused)
+
+	_, err = debit(25, "order-2")
+	assert.ErrorIs(t, err, entity.ErrInsufficientCredits)
+	assert.Equal(t, int64(20), repo.balance)
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultConversionAuditLimit is the page size when none is requested
+	DefaultConversionAuditLimit = 100
+	// MaxConversionAuditLimit caps a single conversion audit page
+	MaxConversionAuditLimit = 1000
+)
+
+// ConversionAuditQuery filters the conversion events of an experiment.
+// Zero bounds leave that side of the time range open.
+type ConversionAuditQuery struct {
+	Currency string
+	Since    time.Time
+	Until    time.Time
+	Limit    int
+}
+
+// ConversionAuditRecord is a reward as received next to the amount the bandit
+// was credited with. NormalizedAmount and ConversionRate include any
+// currency corrections since.
+type ConversionAuditRecord struct {
+	ID                 uuid.UUID           `json:"id"`
+	ArmID              uuid.UUID           `json:"arm_id"`
+	EventType          ConversionEventType `json:"event_type"`
+	OriginalAmount     float64             `json:"original_amount"`
+	OriginalCurrency   string              `json:"original_currency"`
+	NormalizedAmount   float64             `json:"normalized_amount"`
+	NormalizedCurrency string              `json:"normalized_currency"`
+	ConversionRate     float64             `json:"conversion_rate,omitempty"`
+	RateSource         string              `json:"rate_source,omitempty"`
+	Corrected          bool                `json:"corrected"`
+	OccurredAt         time.Time           `json:"occurred_at"`
+}
+
+// CurrencyReconversion re-converts an experiment's rewards in Currency that
+// occurred within [Since, Until) at Rate, replacing whatever rate they were
+// converted at
+type CurrencyReconversion struct {
+	Currency string
+	Rate     float64
+	Source   string
+	Since    time.Time
+	Until    time.Time
+}
+
+// CurrencyReconversionResult reports what a re-conversion changed. Events
+// whose reward would change sign are skipped, since the arm stats only hold
+// positive revenue.
+type CurrencyReconversionResult struct {
+	EventsCorrected int         `json:"events_corrected"`
+	EventsSkipped   int         `json:"events_skipped"`
+	RevenueDelta    float64     `json:"revenue_delta"`
+	ArmIDs          []uuid.UUID `json:"arm_ids"`
+}
+
+// conversionAuditRepository lists conversion events and applies currency
+// corrections to them and their arm stats in one transaction
+type conversionAuditRepository interface {
+	ListConversionAudit(ctx context.Context, experimentID uuid.UUID, query ConversionAuditQuery) ([]ConversionAuditRecord, error)
+	ReconvertCurrency(ctx context.Context, experimentID uuid.UUID, req CurrencyReconversion, basis RevenueBasis) (*CurrencyReconversionResult, error)
+}
+
+// CorrectedConversionReward returns a normalized reward as if its original
+// amount had been converted at correctedRate instead of previousRate. The
+// product cost a margin reward had taken off is not rescaled.
+func CorrectedConversionReward(normalized, productCost, previousRate, correctedRate float64) float64 {
+	if previousRate <= 0 || correctedRate <= 0 {
+		return normalized
+	}
+	return (normalized+productCost)*correctedRate/previousRate - productCost
+}
+
+// ListConversionAudit returns the experiment's conversion events with their
+// original and normalized amounts, newest first
+func (e *AdvancedBanditEngine) ListConversionAudit(
+	ctx context.Context,
+	experimentID uuid.UUID,
+	query ConversionAuditQuery,
+) ([]ConversionAuditRecord, error) {
+	repo, ok := e.repo.(conversionAuditRepository)
+	if !ok {
+		return nil, fmt.Errorf("repository does not support conversion audit")
+	}
+	if query.Limit <= 0 {
+		query.Limit = DefaultConversionAuditLimit
+	}
+	return repo.ListConversionAudit(ctx, experimentID, query)
+}
+
+// ReconvertCurrency corrects the rate applied to an experiment's rewards in
+// one currency. Each corrected reward is logged as a currency_correction
+// event and its arm's revenue is adjusted by the difference.
+func (e *AdvancedBanditEngine) ReconvertCurrency(
+	ctx context.Context,
+	experimentID uuid.UUID,
+	req CurrencyReconversion,
+) (*CurrencyReconversionResult, error) {
+	if req.Currency == "" || req.Currency == "USD" {
+		return nil, fmt.Errorf("invalid reconversion currency: %q", req.Currency)
+	}
+	if req.Rate <= 0 || math.IsNaN(req.Rate) || math.IsInf(req.Rate, 0) {
+		return nil, fmt.Errorf("invalid reconversion rate: %v", req.Rate)
+	}
+	if req.Source == "" {
+		req.Source = "manual"
+	}
+
+	repo, ok := e.repo.(conversionAuditRepository)
+	if !ok {
+		return nil, fmt.Errorf("repository does not support currency reconversion")
+	}
+
+	config, err := e.getExperimentConfig(ctx, experimentID)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := repo.ReconvertCurrency(ctx, experimentID, req, config.RewardBasis)
+	if err != nil {
+		return nil, err
+	}
+
+	// Cached arm stats still hold the old revenue
+	for _, armID := range result.ArmIDs {
+		if err := e.cache.DeleteKey(ctx, fmt.Sprintf("ab:arm:%s", armID.String())); err != nil {
+			e.logger.Warn("Failed to invalidate reconverted arm stats", zap.String("arm_id", armID.String()), zap.Error(err))
+		}
+	}
+
+	e.logger.Info("Rewards reconverted",
+		zap.String("experiment_id", experimentID.String()),
+		zap.String("currency", req.Currency),
+		zap.Float64("rate", req.Rate),
+		zap.Int("events_corrected", result.EventsCorrected),
+		zap.Int("events_skipped", result.EventsSkipped),
+		zap.Float64("revenue_delta", result.RevenueDelta),
+	)
+	return result, nil
+}
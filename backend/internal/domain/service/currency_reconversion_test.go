@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type reconversionTestRepo struct {
+	advancedEngineTestRepo
+	basis  RevenueBasis
+	req    CurrencyReconversion
+	result *CurrencyReconversionResult
+}
+
+func (r *reconversionTestRepo) ListConversionAudit(ctx context.Context, experimentID uuid.UUID, query ConversionAuditQuery) ([]ConversionAuditRecord, error) {
+	return nil, nil
+}
+
+func (r *reconversionTestRepo) ReconvertCurrency(ctx context.Context, experimentID uuid.UUID, req CurrencyReconversion, basis RevenueBasis) (*CurrencyReconversionResult, error) {
+	r.req, r.basis = req, basis
+	return r.result, nil
+}
+
+type deletedKeysTestCache struct {
+	advancedEngineTestCache
+	deleted []string
+}
+
+func (c *deletedKeysTestCache) DeleteKey(ctx context.Context, key string) error {
+	c.deleted = append(c.deleted, key)
+	return nil
+}
+
+func TestCorrectedConversionReward(t *testing.T) {
+	// Gross and net rewards scale with the rate
+	require.InDelta(t, 12.0, CorrectedConversionReward(10, 0, 1.0, 1.2), 1e-9)
+	// A margin reward rescales only the part before the product cost
+	require.InDelta(t, 10.5, CorrectedConversionReward(8.5, 1.5, 1.0, 1.2), 1e-9)
+	// Without a known previous rate there is nothing to correct from
+	require.Equal(t, 10.0, CorrectedConversionReward(10, 0, 0, 1.2))
+}
+
+func TestAdvancedBanditEngine_ReconvertCurrency_UsesExperimentBasisAndClearsArmCache(t *testing.T) {
+	experimentID, armID := uuid.New(), uuid.New()
+	repo := &reconversionTestRepo{
+		advancedEngineTestRepo: advancedEngineTestRepo{
+			experimentConfig: &ExperimentConfig{ID: experimentID, ObjectiveType: ObjectiveRevenue, RewardBasis: RevenueBasisNet},
+		},
+		result: &CurrencyReconversionResult{EventsCorrected: 2, ArmIDs: []uuid.UUID{armID}},
+	}
+	cache := &deletedKeysTestCache{}
+	engine := NewAdvancedBanditEngine(nil, repo, cache, nil, nil, zap.NewNop(), nil)
+
+	result, err := engine.ReconvertCurrency(context.Background(), experimentID, CurrencyReconversion{Currency: "GBP", Rate: 1.27})
+
+	require.NoError(t, err)
+	require.Equal(t, 2, result.EventsCorrected)
+	require.Equal(t, RevenueBasisNet, repo.basis)
+	require.Equal(t, "manual", repo.req.Source)
+	require.Equal(t, []string{"ab:arm:" + armID.String()}, cache.deleted)
+}
+
+func TestAdvancedBanditEngine_ReconvertCurrency_RejectsInvalidRequests(t *testing.T) {
+	engine := NewAdvancedBanditEngine(nil, &reconversionTestRepo{}, &advancedEngineTestCache{}, nil, nil, zap.NewNop(), nil)
+
+	for _, req := range []CurrencyReconversion{
+		{Currency: "USD", Rate: 1},
+		{Currency: "", Rate: 1},
+		{Currency: "EUR", Rate: 0},
+		{Currency: "EUR", Rate: -1},
+	} {
+		_, err := engine.ReconvertCurrency(context.Background(), uuid.New(), req)
+		require.Error(t, err, "%+v", req)
+	}
+}
+
+func TestHybridObjectiveStrategy_RecordObjectiveReward_SumsOriginalAmounts(t *testing.T) {
+	armID := uuid.New()
+	repo := &advancedEngineTestRepo{armStats: map[uuid.UUID]*ArmStats{armID: {ArmID: armID}}}
+	strategy := NewHybridObjectiveStrategy(repo, &advancedEngineTestCache{}, zap.NewNop(), &ExperimentConfig{ObjectiveType: ObjectiveRevenue}, nil)
+
+	require.NoError(t, strategy.RecordObjectiveReward(context.Background(), armID, ObjectiveRevenue, 10.8, 0, 10, "EUR"))
+	require.NoError(t, strategy.RecordObjectiveReward(context.Background(), armID, ObjectiveRevenue, 0, 0, 0, "EUR"))
+
+	require.Len(t, repo.updatedObjectiveStats, 2)
+	require.Equal(t, map[string]float64{"EUR": 10}, repo.updatedObjectiveStats[0].OriginalRevenue)
+	// A non-converting sample leaves the stored amounts untouched
+	require.Nil(t, repo.updatedObjectiveStats[1].OriginalRevenue)
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"encoding/xml"
 	"errors"
 	"fmt"
@@ -10,6 +11,8 @@ import (
 	"sync"
 	"time"
 
+	"golang.org/x/sync/singleflight"
+
 	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
@@ -18,6 +21,19 @@ import (
 // ErrCurrencyRateNotFound is returned when a currency rate is not available
 var ErrCurrencyRateNotFound = errors.New("currency rate not found")
 
+// ErrCurrencyRateStale is returned when the only known rate is older than the
+// max staleness and could not be refreshed
+var ErrCurrencyRateStale = errors.New("currency rate too stale")
+
+const (
+	// defaultCurrencyRateFreshTTL is how long a fetched rate is served as current
+	defaultCurrencyRateFreshTTL = time.Hour
+	// defaultCurrencyRateMaxStaleness is the oldest rate conversions will use
+	defaultCurrencyRateMaxStaleness = 24 * time.Hour
+
+	currencyRefreshFlight = "ecb"
+)
+
 // CurrencyRateService manages currency exchange rates with caching
 type CurrencyRateService struct {
 	redisClient *redis.Client
@@ -30,6 +46,17 @@ type CurrencyRateService struct {
 
 	// ECB API endpoint
 	ecbAPIURL string
+
+	// Optional daily rate history for reporting-currency conversion
+	history CurrencyRateHistory
+
+	// lastRates is the most recent ECB snapshot, guarded by rateMutex
+	lastRates    map[string]CurrencyRate
+	freshTTL     time.Duration
+	maxStaleness time.Duration
+
+	// flights collapses concurrent ECB refreshes into one request
+	flights singleflight.Group
 }
 
 // ECBCurrencyRates represents the ECB daily exchange rate XML structure
@@ -50,11 +77,13 @@ type ECBCurrencyRates struct {
 
 // CurrencyRate represents a currency exchange rate
 type CurrencyRate struct {
-	BaseCurrency   string
-	TargetCurrency string
-	Rate           float64
-	Source         string
-	UpdatedAt      time.Time
+	BaseCurrency   string    `json:"base_currency"`
+	TargetCurrency string    `json:"target_currency"`
+	Rate           float64   `json:"rate"`
+	Source         string    `json:"source"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	// Stale is set when the rate is past the fresh TTL and a refresh is pending
+	Stale bool `json:"-"`
 }
 
 // NewCurrencyRateService creates a new currency rate service
@@ -65,7 +94,9 @@ func NewCurrencyRateService(redisClient *redis.Client, logger *zap.Logger) *Curr
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
-		ecbAPIURL: "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-daily.xml",
+		ecbAPIURL:    "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-daily.xml",
+		freshTTL:     defaultCurrencyRateFreshTTL,
+		maxStaleness: defaultCurrencyRateMaxStaleness,
 		fallbackRates: map[string]float64{
 			"EUR": 0.92,   // Euro to USD
 			"GBP": 0.79,   // British Pound to USD
@@ -81,109 +112,210 @@ func NewCurrencyRateService(redisClient *redis.Client, logger *zap.Logger) *Curr
 	}
 }
 
+// WithTransport sends ECB requests through rt, e.g. a fault injecting one
+func (s *CurrencyRateService) WithTransport(rt http.RoundTripper) *CurrencyRateService {
+	s.httpClient.Transport = rt
+	return s
+}
+
+// WithRateHistory records every ECB refresh into history
+func (s *CurrencyRateService) WithRateHistory(history CurrencyRateHistory) *CurrencyRateService {
+	s.history = history
+	return s
+}
+
+// WithRateStaleness overrides how long rates are served as fresh and the oldest
+// rate still used for conversion
+func (s *CurrencyRateService) WithRateStaleness(freshTTL, maxStaleness time.Duration) *CurrencyRateService {
+	if freshTTL > 0 {
+		s.freshTTL = freshTTL
+	}
+	if maxStaleness > 0 {
+		s.maxStaleness = maxStaleness
+	}
+	if s.maxStaleness < s.freshTTL {
+		s.maxStaleness = s.freshTTL
+	}
+	return s
+}
+
 // ConvertToUSD converts an amount from the given currency to USD
 func (s *CurrencyRateService) ConvertToUSD(ctx context.Context, amount float64, currency string) (float64, error) {
+	converted, _, err := s.ConvertToUSDQuote(ctx, amount, currency)
+	return converted, err
+}
+
+// ConvertToUSDQuote converts an amount to USD and returns the rate it used,
+// so callers can record how the amount was normalized. USD amounts come back
+// unchanged with a zero quote.
+func (s *CurrencyRateService) ConvertToUSDQuote(ctx context.Context, amount float64, currency string) (float64, CurrencyRate, error) {
 	if currency == "USD" || currency == "" {
-		return amount, nil
+		return amount, CurrencyRate{}, nil
 	}
 
-	rate, err := s.GetRate(ctx, currency)
+	quote, err := s.GetRateQuote(ctx, currency)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get rate for %s: %w", currency, err)
+		return 0, CurrencyRate{}, fmt.Errorf("failed to get rate for %s: %w", currency, err)
 	}
 
 	// If rate is already USD-based, multiply
 	// ECB rates are EUR-based, so we need to convert
 	// For simplicity, we store all rates as USD-based in cache
-	convertedAmount := amount * rate
+	convertedAmount := amount * quote.Rate
 
 	s.logger.Debug("Currency conversion",
 		zap.Float64("original_amount", amount),
 		zap.String("original_currency", currency),
-		zap.Float64("rate", rate),
+		zap.Float64("rate", quote.Rate),
 		zap.Float64("converted_amount", convertedAmount),
 	)
 
-	return convertedAmount, nil
+	return convertedAmount, quote, nil
 }
 
 // GetRate retrieves the exchange rate for a currency (to USD)
 func (s *CurrencyRateService) GetRate(ctx context.Context, currency string) (float64, error) {
-	// Check Redis cache first
-	cacheKey := fmt.Sprintf("currency:rate:%s:USD", currency)
-	cachedRate, err := s.redisClient.Get(ctx, cacheKey).Float64()
-	if err == nil {
-		return cachedRate, nil
-	}
-
-	if err != redis.Nil {
-		s.logger.Warn("Redis error when fetching rate", zap.Error(err))
+	quote, err := s.GetRateQuote(ctx, currency)
+	if err != nil {
+		return 0, err
 	}
+	return quote.Rate, nil
+}
 
-	// Try to fetch from ECB API
-	rate, source, err := s.fetchRateFromECB(ctx, currency)
-	if err != nil {
-		s.logger.Warn("Failed to fetch from ECB API, using fallback",
-			zap.String("currency", currency),
-			zap.Error(err),
-		)
+// GetRateQuote retrieves the exchange rate for a currency (to USD) along with
+// when it was fetched. A rate older than the fresh TTL is still served, marked
+// Stale, while one shared refresh runs in the background; past the max
+// staleness it is refused with ErrCurrencyRateStale so callers keep the
+// original currency instead of converting at an outdated rate.
+func (s *CurrencyRateService) GetRateQuote(ctx context.Context, currency string) (CurrencyRate, error) {
+	if quote, ok := s.cachedQuote(ctx, currency); ok {
+		age := time.Since(quote.UpdatedAt)
+		switch {
+		case age <= s.freshTTL:
+			return quote, nil
+		case age <= s.maxStaleness:
+			quote.Stale = true
+			s.refreshInBackground(ctx)
+			return quote, nil
+		}
 
-		// Use fallback rate
-		var ok bool
-		rate, ok = s.getFallbackRate(currency)
-		if !ok {
-			return 0, ErrCurrencyRateNotFound
+		// Too old to trust, so only a successful refresh may answer
+		rates, err := s.refreshRates(ctx)
+		if err != nil {
+			s.logger.Warn("Currency rate exceeds max staleness and refresh failed",
+				zap.String("currency", currency),
+				zap.Duration("age", age),
+				zap.Error(err),
+			)
+			return CurrencyRate{}, ErrCurrencyRateStale
 		}
-		source = "fallback"
+		if fresh, ok := rates[currency]; ok {
+			return fresh, nil
+		}
+		return CurrencyRate{}, ErrCurrencyRateNotFound
 	}
 
-	// Cache the rate for 1 hour
-	if err := s.redisClient.Set(ctx, cacheKey, rate, 1*time.Hour).Err(); err != nil {
-		s.logger.Warn("Failed to cache currency rate", zap.Error(err))
+	// Nothing cached; concurrent misses share a single ECB fetch
+	rates, err := s.refreshRates(ctx)
+	if err == nil {
+		if quote, ok := rates[currency]; ok {
+			s.logger.Info("Currency rate retrieved",
+				zap.String("currency", currency),
+				zap.Float64("rate", quote.Rate),
+				zap.String("source", quote.Source),
+			)
+			return quote, nil
+		}
+		err = ErrCurrencyRateNotFound
 	}
 
-	s.logger.Info("Currency rate retrieved",
+	s.logger.Warn("Failed to fetch from ECB API, using fallback",
 		zap.String("currency", currency),
-		zap.Float64("rate", rate),
-		zap.String("source", source),
+		zap.Error(err),
 	)
 
-	return rate, nil
+	rate, ok := s.getFallbackRate(currency)
+	if !ok {
+		return CurrencyRate{}, ErrCurrencyRateNotFound
+	}
+	return CurrencyRate{
+		BaseCurrency:   currency,
+		TargetCurrency: "USD",
+		Rate:           rate,
+		Source:         "fallback",
+		UpdatedAt:      time.Now().UTC(),
+	}, nil
+}
+
+// cachedQuote returns the last known rate for currency from Redis, falling
+// back to the in-process snapshot so a Redis flush does not force a refetch
+func (s *CurrencyRateService) cachedQuote(ctx context.Context, currency string) (CurrencyRate, bool) {
+	raw, err := s.redisClient.Get(ctx, currencyRateCacheKey(currency)).Bytes()
+	if err == nil {
+		var quote CurrencyRate
+		if err := json.Unmarshal(raw, &quote); err == nil && quote.Rate > 0 {
+			return quote, true
+		}
+	} else if err != redis.Nil {
+		s.logger.Warn("Redis error when fetching rate", zap.Error(err))
+	}
+
+	s.rateMutex.RLock()
+	defer s.rateMutex.RUnlock()
+	quote, ok := s.lastRates[currency]
+	return quote, ok
+}
+
+// refreshInBackground starts a shared refresh without waiting for it
+func (s *CurrencyRateService) refreshInBackground(ctx context.Context) {
+	s.flights.DoChan(currencyRefreshFlight, func() (interface{}, error) {
+		return s.fetchRates(context.WithoutCancel(ctx))
+	})
 }
 
-// fetchRateFromECB fetches exchange rates from the European Central Bank API
-func (s *CurrencyRateService) fetchRateFromECB(ctx context.Context, currency string) (float64, string, error) {
+// refreshRates fetches all rates from ECB, collapsing concurrent callers into
+// one request. The fetch outlives a cancelled caller so joined callers are
+// not failed by someone else's deadline.
+func (s *CurrencyRateService) refreshRates(ctx context.Context) (map[string]CurrencyRate, error) {
+	result, err, _ := s.flights.Do(currencyRefreshFlight, func() (interface{}, error) {
+		return s.fetchRates(context.WithoutCancel(ctx))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(map[string]CurrencyRate), nil
+}
+
+// fetchRates downloads the ECB daily rates, converts them to USD-based quotes
+// and stores them in Redis, the in-process snapshot and the rate history
+func (s *CurrencyRateService) fetchRates(ctx context.Context) (map[string]CurrencyRate, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.ecbAPIURL, nil)
 	if err != nil {
-		return 0, "", fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	resp, err := s.httpClient.Do(req)
 	if err != nil {
-		return 0, "", fmt.Errorf("failed to fetch rates: %w", err)
+		return nil, fmt.Errorf("%w: failed to fetch rates: %v", domainErrors.ErrExternalServiceUnavailable, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return 0, "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, fmt.Errorf("%w: unexpected status code: %d", domainErrors.ErrExternalServiceUnavailable, resp.StatusCode)
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return 0, "", fmt.Errorf("failed to read response: %w", err)
+		return nil, fmt.Errorf("%w: failed to read response: %v", domainErrors.ErrExternalServiceUnavailable, err)
 	}
 
 	var ecbRates ECBCurrencyRates
 	if err := xml.Unmarshal(body, &ecbRates); err != nil {
-		return 0, "", fmt.Errorf("failed to parse XML: %w", err)
+		return nil, fmt.Errorf("%w: failed to parse XML: %v", domainErrors.ErrExternalServiceUnavailable, err)
 	}
 
-	// ECB provides EUR-based rates
-	// We need to convert to USD-based
-	// EUR/USD rate from ECB (inverse)
-	var eurToUsdRate float64 = 1.08 // Approximate, will be calculated
-
-	// Find the target currency rate
+	// ECB provides EUR-based rates; find EUR to USD to rebase them
+	var eurToUsdRate float64
 	for _, cube := range ecbRates.Cube.Cube.Cube {
 		if cube.Currency == "USD" {
 			eurToUsdRate = cube.Rate
@@ -191,32 +323,53 @@ func (s *CurrencyRateService) fetchRateFromECB(ctx context.Context, currency str
 		}
 	}
 
-	// Calculate target currency rate to USD
-	for _, cube := range ecbRates.Cube.Cube.Cube {
-		if cube.Currency == currency {
-			// ECB rate is EUR to Currency
-			// We want Currency to USD
-			// Currency/USD = (EUR/USD) / (EUR/Currency)
-			rate := eurToUsdRate / cube.Rate
-
-			// Cache all fetched rates
-			s.cacheFetchedRates(ctx, ecbRates, eurToUsdRate)
+	if eurToUsdRate == 0 {
+		return nil, fmt.Errorf("%w: EUR to USD rate not found in ECB response", domainErrors.ErrExternalServiceUnavailable)
+	}
 
-			return rate, "ecb", nil
+	// Currency/USD = (EUR/USD) / (EUR/Currency)
+	now := time.Now().UTC()
+	rates := map[string]CurrencyRate{
+		"EUR": {BaseCurrency: "EUR", TargetCurrency: "USD", Rate: eurToUsdRate, Source: "ecb", UpdatedAt: now},
+	}
+	for _, cube := range ecbRates.Cube.Cube.Cube {
+		if cube.Currency == "USD" || cube.Rate <= 0 {
+			continue
+		}
+		rates[cube.Currency] = CurrencyRate{
+			BaseCurrency:   cube.Currency,
+			TargetCurrency: "USD",
+			Rate:           eurToUsdRate / cube.Rate,
+			Source:         "ecb",
+			UpdatedAt:      now,
 		}
 	}
 
-	return 0, "", ErrCurrencyRateNotFound
+	s.rateMutex.Lock()
+	s.lastRates = rates
+	s.rateMutex.Unlock()
+
+	s.cacheFetchedRates(ctx, rates)
+	s.recordRateHistory(ctx, ecbRates, eurToUsdRate)
+
+	s.logger.Info("Currency rates updated from ECB",
+		zap.String("date", ecbRates.Cube.Cube.Time),
+		zap.Int("currencies", len(rates)),
+	)
+
+	return rates, nil
 }
 
-// cacheFetchedRates caches all rates from an ECB response
-func (s *CurrencyRateService) cacheFetchedRates(ctx context.Context, ecbRates ECBCurrencyRates, eurToUsdRate float64) {
+// cacheFetchedRates caches all fetched quotes, kept until they pass max staleness
+func (s *CurrencyRateService) cacheFetchedRates(ctx context.Context, rates map[string]CurrencyRate) {
 	pipe := s.redisClient.Pipeline()
 
-	for _, cube := range ecbRates.Cube.Cube.Cube {
-		cacheKey := fmt.Sprintf("currency:rate:%s:USD", cube.Currency)
-		rate := eurToUsdRate / cube.Rate
-		pipe.Set(ctx, cacheKey, rate, 1*time.Hour)
+	for currency, quote := range rates {
+		payload, err := json.Marshal(quote)
+		if err != nil {
+			continue
+		}
+		pipe.Set(ctx, currencyRateCacheKey(currency), payload, s.maxStaleness)
 	}
 
 	if _, err := pipe.Exec(ctx); err != nil {
@@ -224,6 +377,34 @@ func (s *CurrencyRateService) cacheFetchedRates(ctx context.Context, ecbRates EC
 	}
 }
 
+func currencyRateCacheKey(currency string) string {
+	return fmt.Sprintf("currency:rate:%s:USD", currency)
+}
+
+// recordRateHistory stores the USD rates of an ECB response under its reference date
+func (s *CurrencyRateService) recordRateHistory(ctx context.Context, ecbRates ECBCurrencyRates, eurToUsdRate float64) {
+	if s.history == nil {
+		return
+	}
+
+	day, err := time.Parse("2006-01-02", ecbRates.Cube.Cube.Time)
+	if err != nil {
+		day = time.Now().UTC().Truncate(24 * time.Hour)
+	}
+
+	rates := map[string]float64{"EUR": eurToUsdRate}
+	for _, cube := range ecbRates.Cube.Cube.Cube {
+		if cube.Currency == "USD" || cube.Rate <= 0 {
+			continue
+		}
+		rates[cube.Currency] = eurToUsdRate / cube.Rate
+	}
+
+	if err := s.history.SaveRates(ctx, day, rates, "ecb"); err != nil {
+		s.logger.Warn("Failed to record currency rate history", zap.Error(err))
+	}
+}
+
 // getFallbackRate retrieves a fallback exchange rate
 func (s *CurrencyRateService) getFallbackRate(currency string) (float64, bool) {
 	s.rateMutex.RLock()
@@ -245,54 +426,32 @@ func (s *CurrencyRateService) SetFallbackRate(currency string, rate float64) {
 	)
 }
 
-// UpdateRates triggers an update of all exchange rates from the ECB API
+// UpdateRates triggers an update of all exchange rates from the ECB API.
+// Concurrent calls share one request.
 func (s *CurrencyRateService) UpdateRates(ctx context.Context) error {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.ecbAPIURL, nil)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	resp, err := s.httpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("%w: failed to fetch rates: %v", domainErrors.ErrExternalServiceUnavailable, err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("%w: unexpected status code: %d", domainErrors.ErrExternalServiceUnavailable, resp.StatusCode)
-	}
+	_, err := s.refreshRates(ctx)
+	return err
+}
 
-	body, err := io.ReadAll(resp.Body)
+// WarmRates loads every rate ahead of the first conversion so a cold or
+// flushed cache does not send reward traffic to ECB
+func (s *CurrencyRateService) WarmRates(ctx context.Context) error {
+	rates, err := s.refreshRates(ctx)
 	if err != nil {
-		return fmt.Errorf("%w: failed to read response: %v", domainErrors.ErrExternalServiceUnavailable, err)
-	}
-
-	var ecbRates ECBCurrencyRates
-	if err := xml.Unmarshal(body, &ecbRates); err != nil {
-		return fmt.Errorf("%w: failed to parse XML: %v", domainErrors.ErrExternalServiceUnavailable, err)
+		return err
 	}
 
-	// Find EUR to USD rate
-	var eurToUsdRate float64
-	for _, cube := range ecbRates.Cube.Cube.Cube {
-		if cube.Currency == "USD" {
-			eurToUsdRate = cube.Rate
-			break
+	var missing []string
+	for _, currency := range s.GetSupportedCurrencies() {
+		if _, ok := rates[currency]; !ok && currency != "USD" {
+			missing = append(missing, currency)
 		}
 	}
-
-	if eurToUsdRate == 0 {
-		return fmt.Errorf("%w: EUR to USD rate not found in ECB response", domainErrors.ErrExternalServiceUnavailable)
+	if len(missing) > 0 {
+		s.logger.Warn("ECB rates missing supported currencies; fallback rates will be used",
+			zap.Strings("currencies", missing),
+		)
 	}
-
-	// Cache all rates
-	s.cacheFetchedRates(ctx, ecbRates, eurToUsdRate)
-
-	s.logger.Info("Currency rates updated from ECB",
-		zap.String("date", ecbRates.Cube.Cube.Time),
-		zap.Int("currencies", len(ecbRates.Cube.Cube.Cube)),
-	)
-
 	return nil
 }
 
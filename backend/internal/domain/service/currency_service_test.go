@@ -5,9 +5,13 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 )
@@ -38,3 +42,100 @@ func TestCurrencyRateService_UpdateRates_ReturnsExternalServiceUnavailableOnTran
 	require.Error(t, err)
 	require.True(t, errors.Is(err, domainErrors.ErrExternalServiceUnavailable))
 }
+
+const testECBResponse = `<?xml version="1.0" encoding="UTF-8"?>
+<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">
+<Cube><Cube time="2026-10-14"><Cube currency="USD" rate="1.10"/><Cube currency="GBP" rate="0.88"/></Cube></Cube>
+</gesmes:Envelope>`
+
+func newTestCurrencyService(t *testing.T, handler http.HandlerFunc) *CurrencyRateService {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	// Unreachable Redis: every lookup misses and only the in-process snapshot answers
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", DialTimeout: 50 * time.Millisecond, MaxRetries: -1})
+	t.Cleanup(func() { _ = client.Close() })
+
+	svc := NewCurrencyRateService(client, zap.NewNop())
+	svc.ecbAPIURL = server.URL
+	svc.httpClient = server.Client()
+	return svc
+}
+
+func TestCurrencyRateService_UpdateRates_CollapsesConcurrentRefreshes(t *testing.T) {
+	var hits atomic.Int32
+	release := make(chan struct{})
+	svc := newTestCurrencyService(t, func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		<-release
+		_, _ = w.Write([]byte(testECBResponse))
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.NoError(t, svc.UpdateRates(context.Background()))
+		}()
+	}
+	require.Eventually(t, func() bool { return hits.Load() == 1 }, time.Second, 5*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	require.Equal(t, int32(1), hits.Load())
+}
+
+func TestCurrencyRateService_GetRateQuote_ServesSnapshotAfterCacheLoss(t *testing.T) {
+	var hits atomic.Int32
+	svc := newTestCurrencyService(t, func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		_, _ = w.Write([]byte(testECBResponse))
+	})
+	require.NoError(t, svc.WarmRates(context.Background()))
+
+	quote, err := svc.GetRateQuote(context.Background(), "GBP")
+
+	require.NoError(t, err)
+	require.False(t, quote.Stale)
+	require.Equal(t, "ecb", quote.Source)
+	require.InDelta(t, 1.10/0.88, quote.Rate, 1e-9)
+	require.Equal(t, int32(1), hits.Load())
+}
+
+func TestCurrencyRateService_GetRateQuote_ServesStaleWhileRefreshing(t *testing.T) {
+	refreshed := make(chan struct{}, 1)
+	svc := newTestCurrencyService(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(testECBResponse))
+		refreshed <- struct{}{}
+	})
+	svc.lastRates = map[string]CurrencyRate{
+		"GBP": {BaseCurrency: "GBP", TargetCurrency: "USD", Rate: 1.2, Source: "ecb", UpdatedAt: time.Now().Add(-2 * time.Hour)},
+	}
+
+	quote, err := svc.GetRateQuote(context.Background(), "GBP")
+
+	require.NoError(t, err)
+	require.True(t, quote.Stale)
+	require.Equal(t, 1.2, quote.Rate)
+	select {
+	case <-refreshed:
+	case <-time.After(time.Second):
+		t.Fatal("expected a background refresh")
+	}
+}
+
+func TestCurrencyRateService_GetRateQuote_RefusesRatePastMaxStaleness(t *testing.T) {
+	svc := newTestCurrencyService(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	svc.lastRates = map[string]CurrencyRate{
+		"GBP": {BaseCurrency: "GBP", TargetCurrency: "USD", Rate: 1.2, Source: "ecb", UpdatedAt: time.Now().Add(-48 * time.Hour)},
+	}
+
+	_, err := svc.ConvertToUSD(context.Background(), 10, "GBP")
+
+	require.ErrorIs(t, err, ErrCurrencyRateStale)
+}
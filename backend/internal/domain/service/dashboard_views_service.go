@@ -0,0 +1,243 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+// DashboardViewStaleAfter is how old a view may get before it is reported
+// stale; the worker refreshes every 15 minutes
+const DashboardViewStaleAfter = 45 * time.Minute
+
+// DashboardViews are the materialized views behind the dashboard aggregates,
+// in refresh order
+var DashboardViews = []string{
+	"mv_daily_revenue",
+	"mv_active_subscriptions_by_plan",
+	"mv_conversion_by_platform",
+}
+
+// ViewFreshness is when a materialized view was last refreshed
+type ViewFreshness struct {
+	View        string     `json:"view"`
+	RefreshedAt *time.Time `json:"refreshed_at"`
+	AgeSeconds  float64    `json:"age_seconds"`
+	Stale       bool       `json:"stale"`
+}
+
+// DailyRevenuePoint is one reporting day's production revenue in one currency
+type DailyRevenuePoint struct {
+	Day          string  `json:"day"`
+	Currency     string  `json:"currency"`
+	Revenue      float64 `json:"revenue"`
+	Refunded     float64 `json:"refunded"`
+	Transactions int64   `json:"transactions"`
+}
+
+// PlanSubscriptions counts active and in-grace subscriptions of a product
+type PlanSubscriptions struct {
+	PlanType     string `json:"plan_type"`
+	ProductID    string `json:"product_id"`
+	Active       int64  `json:"active"`
+	InGrace      int64  `json:"in_grace"`
+	AutoRenewing int64  `json:"auto_renewing"`
+}
+
+// PlatformConversion is the share of users who signed up in the window and
+// have subscribed, per platform
+type PlatformConversion struct {
+	Platform       string  `json:"platform"`
+	Users          int64   `json:"users"`
+	Converted      int64   `json:"converted"`
+	ConversionRate float64 `json:"conversion_rate"`
+}
+
+// DashboardAggregates are the precomputed dashboard metrics of an app. Days
+// are in the app's reporting time zone. Stale is true when any view is older
+// than DashboardViewStaleAfter.
+type DashboardAggregates struct {
+	From                 time.Time            `json:"from"`
+	To                   time.Time            `json:"to"`
+	Timezone             string               `json:"timezone"`
+	DailyRevenue         []DailyRevenuePoint  `json:"daily_revenue"`
+	ActiveSubsByPlan     []PlanSubscriptions  `json:"active_subs_by_plan"`
+	ConversionByPlatform []PlatformConversion `json:"conversion_by_platform"`
+	Freshness            []ViewFreshness      `json:"freshness"`
+	Stale                bool                 `json:"stale"`
+}
+
+// ViewRefresh is the outcome of refreshing one view
+type ViewRefresh struct {
+	View     string
+	Duration time.Duration
+}
+
+// DashboardViewsService reads and refreshes the dashboard materialized views
+type DashboardViewsService struct {
+	dbPool *pgxpool.Pool
+	now    func() time.Time
+}
+
+// NewDashboardViewsService creates a new dashboard views service
+func NewDashboardViewsService(dbPool *pgxpool.Pool) *DashboardViewsService {
+	return &DashboardViewsService{dbPool: dbPool, now: time.Now}
+}
+
+// RefreshAll refreshes every view concurrently with readers, so dashboards
+// keep reading the previous contents meanwhile, and records the refresh time
+func (s *DashboardViewsService) RefreshAll(ctx context.Context) ([]ViewRefresh, error) {
+	refreshes := make([]ViewRefresh, 0, len(DashboardViews))
+	for _, view := range DashboardViews {
+		started := s.now()
+		if _, err := s.dbPool.Exec(ctx, "REFRESH MATERIALIZED VIEW CONCURRENTLY "+pgx.Identifier{view}.Sanitize()); err != nil {
+			return refreshes, fmt.Errorf("failed to refresh %s: %w", view, err)
+		}
+		elapsed := s.now().Sub(started)
+		if _, err := s.dbPool.Exec(ctx, `
+			INSERT INTO dashboard_view_refreshes (view_name, refreshed_at, duration_ms)
+			VALUES ($1, now(), $2)
+			ON CONFLICT (view_name) DO UPDATE SET refreshed_at = EXCLUDED.refreshed_at, duration_ms = EXCLUDED.duration_ms
+		`, view, elapsed.Milliseconds()); err != nil {
+			return refreshes, fmt.Errorf("failed to record refresh of %s: %w", view, err)
+		}
+		refreshes = append(refreshes, ViewRefresh{View: view, Duration: elapsed})
+	}
+	return refreshes, nil
+}
+
+// Aggregates reads the app's dashboard metrics for the last days local days
+func (s *DashboardViewsService) Aggregates(ctx context.Context, appID uuid.UUID, days int) (*DashboardAggregates, error) {
+	var tz string
+	if err := s.dbPool.QueryRow(ctx, `SELECT COALESCE(app_reporting_timezone($1), 'UTC')`, appID).Scan(&tz); err != nil {
+		return nil, fmt.Errorf("failed to read reporting timezone: %w", err)
+	}
+	loc := (&entity.AppSettings{ReportingTimezone: tz}).ReportingLocation()
+
+	now := s.now()
+	today, _ := entity.ReportingDay(now, loc)
+	from := today.AddDate(0, 0, -(days - 1))
+	agg := &DashboardAggregates{
+		From:                 from,
+		To:                   now.In(loc),
+		Timezone:             loc.String(),
+		DailyRevenue:         []DailyRevenuePoint{},
+		ActiveSubsByPlan:     []PlanSubscriptions{},
+		ConversionByPlatform: []PlatformConversion{},
+	}
+
+	rows, err := s.dbPool.Query(ctx, `
+		SELECT to_char(day, 'YYYY-MM-DD'), currency, revenue::float8, refunded::float8, transactions
+		FROM mv_daily_revenue
+		WHERE app_id = $1 AND day >= $2::date
+		ORDER BY day, currency
+	`, appID, from.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read daily revenue: %w", err)
+	}
+	for rows.Next() {
+		var p DailyRevenuePoint
+		if err := rows.Scan(&p.Day, &p.Currency, &p.Revenue, &p.Refunded, &p.Transactions); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan daily revenue: %w", err)
+		}
+		agg.DailyRevenue = append(agg.DailyRevenue, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read daily revenue: %w", err)
+	}
+
+	rows, err = s.dbPool.Query(ctx, `
+		SELECT plan_type, product_id, active, in_grace, auto_renewing
+		FROM mv_active_subscriptions_by_plan
+		WHERE app_id = $1
+		ORDER BY active DESC, product_id
+	`, appID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read active subscriptions by plan: %w", err)
+	}
+	for rows.Next() {
+		var p PlanSubscriptions
+		if err := rows.Scan(&p.PlanType, &p.ProductID, &p.Active, &p.InGrace, &p.AutoRenewing); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan active subscriptions by plan: %w", err)
+		}
+		agg.ActiveSubsByPlan = append(agg.ActiveSubsByPlan, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read active subscriptions by plan: %w", err)
+	}
+
+	rows, err = s.dbPool.Query(ctx, `
+		SELECT platform, SUM(users)::bigint, SUM(converted)::bigint
+		FROM mv_conversion_by_platform
+		WHERE app_id = $1 AND cohort_day >= $2::date
+		GROUP BY platform
+		ORDER BY platform
+	`, appID, from.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read conversion by platform: %w", err)
+	}
+	for rows.Next() {
+		var p PlatformConversion
+		if err := rows.Scan(&p.Platform, &p.Users, &p.Converted); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan conversion by platform: %w", err)
+		}
+		if p.Users > 0 {
+			p.ConversionRate = float64(p.Converted) / float64(p.Users)
+		}
+		agg.ConversionByPlatform = append(agg.ConversionByPlatform, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read conversion by platform: %w", err)
+	}
+
+	refreshed := map[string]time.Time{}
+	rows, err = s.dbPool.Query(ctx, `SELECT view_name, refreshed_at FROM dashboard_view_refreshes`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read view refreshes: %w", err)
+	}
+	for rows.Next() {
+		var view string
+		var at time.Time
+		if err := rows.Scan(&view, &at); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan view refreshes: %w", err)
+		}
+		refreshed[view] = at
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read view refreshes: %w", err)
+	}
+	agg.Freshness, agg.Stale = viewFreshness(refreshed, now)
+	return agg, nil
+}
+
+// viewFreshness reports the age of every dashboard view; a view never
+// refreshed is stale
+func viewFreshness(refreshed map[string]time.Time, now time.Time) ([]ViewFreshness, bool) {
+	freshness := make([]ViewFreshness, 0, len(DashboardViews))
+	anyStale := false
+	for _, view := range DashboardViews {
+		f := ViewFreshness{View: view, Stale: true}
+		if at, ok := refreshed[view]; ok {
+			f.RefreshedAt = &at
+			f.AgeSeconds = max(now.Sub(at).Seconds(), 0)
+			f.Stale = now.Sub(at) > DashboardViewStaleAfter
+		}
+		anyStale = anyStale || f.Stale
+		freshness = append(freshness, f)
+	}
+	return freshness, anyStale
+}
@@ -0,0 +1,33 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestViewFreshness_FlagsOldAndMissingViews(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	freshness, stale := viewFreshness(map[string]time.Time{
+		"mv_daily_revenue":                now.Add(-10 * time.Minute),
+		"mv_active_subscriptions_by_plan": now.Add(-time.Hour),
+	}, now)
+
+	require.Len(t, freshness, len(DashboardViews))
+	assert.True(t, stale)
+
+	assert.False(t, freshness[0].Stale)
+	assert.Equal(t, 600.0, freshness[0].AgeSeconds)
+	assert.True(t, freshness[1].Stale, "older than the stale threshold")
+	assert.True(t, freshness[2].Stale, "never refreshed")
+	assert.Nil(t, freshness[2].RefreshedAt)
+
+	_, stale = viewFreshness(map[string]time.Time{
+		"mv_daily_revenue":                now,
+		"mv_active_subscriptions_by_plan": now,
+		"mv_conversion_by_platform":       now,
+	}, now)
+	assert.False(t, stale)
+}
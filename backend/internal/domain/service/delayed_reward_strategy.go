@@ -8,6 +8,8 @@ import (
 
 	"github.com/google/uuid"
 	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/clock"
 )
 
 // DelayedRewardStrategy handles delayed feedback for conversions
@@ -18,6 +20,7 @@ type DelayedRewardStrategy struct {
 	logger     *zap.Logger
 	defaultTTL time.Duration // How long to wait for conversions
 	maxTTL     time.Duration // Maximum time to track pending rewards
+	now        func() time.Time
 }
 
 // PendingReward represents a pending conversion reward
@@ -46,21 +49,68 @@ type ConversionLink struct {
 type DelayedRewardRepository interface {
 	CreatePendingReward(ctx context.Context, reward *PendingReward) error
 	GetPendingReward(ctx context.Context, id uuid.UUID) (*PendingReward, error)
-	GetPendingRewardsByUser(ctx context.Context, userID, experimentID uuid.UUID) ([]*PendingReward, error)
+	// GetPendingRewardsByUser lists the user's pending rewards, limited to one
+	// experiment when experimentID is not nil
+	GetPendingRewardsByUser(ctx context.Context, userID uuid.UUID, experimentID *uuid.UUID) ([]*PendingReward, error)
 	GetExpiredPendingRewards(ctx context.Context, limit int) ([]*PendingReward, error)
 	UpdatePendingReward(ctx context.Context, reward *PendingReward) error
 	LinkConversion(ctx context.Context, link *ConversionLink) error
 	GetByTransactionID(ctx context.Context, transactionID uuid.UUID) ([]*ConversionLink, error)
 }
 
+// NormalizedConversion is a conversion valued for the experiment it is
+// credited to: in the reporting currency and the experiment's reward basis
+type NormalizedConversion struct {
+	Value    float64
+	Currency string
+	Rate     CurrencyRate
+	Metadata map[string]interface{}
+}
+
+// ConversionNormalizer values a conversion for the experiment of the pending
+// reward it matched, which is only known once the match is made
+type ConversionNormalizer func(ctx context.Context, pending *PendingReward, value float64, currency string) NormalizedConversion
+
+// Normalize values a conversion for pending; a nil normalizer credits the
+// conversion as reported
+func (n ConversionNormalizer) Normalize(ctx context.Context, pending *PendingReward, value float64, currency string) NormalizedConversion {
+	if n == nil {
+		return NormalizedConversion{Value: value, Currency: currency}
+	}
+	return n(ctx, pending, value, currency)
+}
+
 type DelayedConversionProcessor interface {
-	ProcessPendingConversion(ctx context.Context, transactionID, userID uuid.UUID, conversionValue float64, currency string, processedAt time.Time) (*PendingReward, bool, error)
+	ProcessPendingConversion(ctx context.Context, transactionID, userID uuid.UUID, conversionValue float64, currency string, normalize ConversionNormalizer, processedAt time.Time) (*PendingReward, bool, error)
 }
 
 type ExpiredPendingRewardProcessor interface {
 	ProcessExpiredPendingReward(ctx context.Context, pendingID uuid.UUID, processedAt time.Time) (bool, error)
 }
 
+// ExpiredRewardCursor is the position of the last expired reward a page
+// reached, in (ExpiresAt, ID) order
+type ExpiredRewardCursor struct {
+	ExpiresAt time.Time `json:"expires_at"`
+	ID        uuid.UUID `json:"id"`
+}
+
+// ExpiredRewardPage counts what one page of expired rewards came to
+type ExpiredRewardPage struct {
+	Fetched   int
+	Processed int
+	Skipped   int
+	Failed    int
+	// Next is where the following page starts; nil when nothing was handled
+	Next *ExpiredRewardCursor
+}
+
+type ExpiredPendingRewardPager interface {
+	// GetExpiredPendingRewardsPage lists unprocessed rewards that expired
+	// before expiredBefore, after the cursor, in (expires_at, id) order
+	GetExpiredPendingRewardsPage(ctx context.Context, after *ExpiredRewardCursor, expiredBefore time.Time, limit int) ([]*PendingReward, error)
+}
+
 // NewDelayedRewardStrategy creates a new delayed reward strategy
 func NewDelayedRewardStrategy(
 	repo BanditRepository,
@@ -73,22 +123,50 @@ func NewDelayedRewardStrategy(
 		logger:     logger,
 		defaultTTL: 7 * 24 * time.Hour,  // 7 days default
 		maxTTL:     30 * 24 * time.Hour, // 30 days maximum
+		now:        time.Now,
+	}
+}
+
+// WithClock makes pending reward expiries and conversion times come from c
+func (s *DelayedRewardStrategy) WithClock(c clock.Clock) *DelayedRewardStrategy {
+	s.now = c.Now
+	return s
+}
+
+// ConversionWindow resolves an experiment's conversion window, falling back
+// to the default TTL when the experiment has none
+func (s *DelayedRewardStrategy) ConversionWindow(window time.Duration) time.Duration {
+	if window <= 0 {
+		return s.defaultTTL
+	}
+	if window > s.maxTTL {
+		return s.maxTTL
 	}
+	return window
+}
+
+// MaxConversionWindow is the longest conversion window an experiment may use
+func (s *DelayedRewardStrategy) MaxConversionWindow() time.Duration {
+	return s.maxTTL
 }
 
-// RecordPendingReward records a pending reward that will be credited upon conversion
+// RecordPendingReward records a pending reward that will be credited upon
+// conversion within the experiment's window, or the default TTL when window
+// is zero
 func (s *DelayedRewardStrategy) RecordPendingReward(
 	ctx context.Context,
 	experimentID, armID, userID uuid.UUID,
+	window time.Duration,
 ) (*PendingReward, error) {
-	expiresAt := time.Now().Add(s.defaultTTL)
+	assignedAt := s.now()
+	expiresAt := assignedAt.Add(s.ConversionWindow(window))
 
 	pending := &PendingReward{
 		ID:           uuid.New(),
 		ExperimentID: experimentID,
 		ArmID:        armID,
 		UserID:       userID,
-		AssignedAt:   time.Now(),
+		AssignedAt:   assignedAt,
 		ExpiresAt:    expiresAt,
 		Converted:    false,
 	}
@@ -120,23 +198,25 @@ func (s *DelayedRewardStrategy) RecordPendingReward(
 	return pending, nil
 }
 
-// ProcessConversion processes a conversion and applies the reward to the pending arm
+// ProcessConversion processes a conversion and applies the reward, valued by
+// normalize, to the pending arm
 func (s *DelayedRewardStrategy) ProcessConversion(
 	ctx context.Context,
 	transactionID uuid.UUID,
 	userID uuid.UUID,
 	conversionValue float64,
 	currency string,
+	normalize ConversionNormalizer,
 	baseBandit *ThompsonSamplingBandit,
 ) error {
 	delayedRepo, ok := s.repo.(DelayedRewardRepository)
 	if !ok {
 		return fmt.Errorf("repository does not support delayed rewards")
 	}
-	now := time.Now().UTC()
+	now := s.now().UTC()
 
 	if processor, ok := s.repo.(DelayedConversionProcessor); ok {
-		matchedPending, processed, err := processor.ProcessPendingConversion(ctx, transactionID, userID, conversionValue, currency, now)
+		matchedPending, processed, err := processor.ProcessPendingConversion(ctx, transactionID, userID, conversionValue, currency, normalize, now)
 		if err != nil {
 			return fmt.Errorf("failed to process pending conversion: %w", err)
 		}
@@ -165,7 +245,7 @@ func (s *DelayedRewardStrategy) ProcessConversion(
 
 	// Find pending rewards for this user
 	// For now, we'll link to the most recent unconverted pending reward
-	pendingRewards, err := delayedRepo.GetPendingRewardsByUser(ctx, userID, uuid.Nil)
+	pendingRewards, err := delayedRepo.GetPendingRewardsByUser(ctx, userID, nil)
 	if err != nil {
 		return fmt.Errorf("failed to get pending rewards: %w", err)
 	}
@@ -189,7 +269,14 @@ func (s *DelayedRewardStrategy) ProcessConversion(
 		return nil // Not an error, just no match
 	}
 
-	if err := baseBandit.UpdateRewardWithEvent(ctx, matchedPending.ExperimentID, matchedPending.ArmID, conversionValue, &ConversionEvent{
+	normalized := normalize.Normalize(ctx, matchedPending, conversionValue, currency)
+	metadata := map[string]interface{}{
+		"source": "delayed_reward_strategy_fallback",
+	}
+	for key, value := range normalized.Metadata {
+		metadata[key] = value
+	}
+	if err := baseBandit.UpdateRewardWithEvent(ctx, matchedPending.ExperimentID, matchedPending.ArmID, normalized.Value, &ConversionEvent{
 		ExperimentID:          matchedPending.ExperimentID,
 		ArmID:                 matchedPending.ArmID,
 		UserID:                &matchedPending.UserID,
@@ -198,12 +285,12 @@ func (s *DelayedRewardStrategy) ProcessConversion(
 		EventType:             ConversionEventTypeDelayedConversion,
 		OriginalRewardValue:   conversionValue,
 		OriginalCurrency:      currency,
-		NormalizedRewardValue: conversionValue,
-		NormalizedCurrency:    currency,
-		Metadata: map[string]interface{}{
-			"source": "delayed_reward_strategy_fallback",
-		},
-		OccurredAt: now,
+		NormalizedRewardValue: normalized.Value,
+		NormalizedCurrency:    normalized.Currency,
+		ConversionRate:        normalized.Rate.Rate,
+		RateSource:            normalized.Rate.Source,
+		Metadata:              metadata,
+		OccurredAt:            now,
 	}); err != nil {
 		return fmt.Errorf("failed to apply delayed reward: %w", err)
 	}
@@ -267,79 +354,146 @@ func (s *DelayedRewardStrategy) ProcessExpiredRewards(
 
 	processed := 0
 	for _, pending := range expired {
-		if pending.Converted {
-			continue // Already processed
+		if s.expirePendingReward(ctx, baseBandit, delayedRepo, pending) == expiredRewardProcessed {
+			processed++
 		}
+	}
 
-		now := time.Now().UTC()
-		if processor, ok := s.repo.(ExpiredPendingRewardProcessor); ok {
-			applied, err := processor.ProcessExpiredPendingReward(ctx, pending.ID, now)
-			if err != nil {
-				s.logger.Error("Failed to record expired reward",
-					zap.String("pending_id", pending.ID.String()),
-					zap.Error(err),
-				)
-				continue
-			}
-			if !applied {
-				continue
-			}
+	if processed > 0 {
+		s.logger.Info("Processed expired pending rewards",
+			zap.Int("count", processed),
+		)
+	}
+
+	return processed, nil
+}
+
+// ProcessExpiredRewardsPage records the next page of rewards that expired
+// before expiredBefore, after the cursor, as non-conversions. pace is called
+// before each reward; when it fails the page stops there and Next points at
+// the last reward handled.
+func (s *DelayedRewardStrategy) ProcessExpiredRewardsPage(
+	ctx context.Context,
+	baseBandit *ThompsonSamplingBandit,
+	after *ExpiredRewardCursor,
+	expiredBefore time.Time,
+	limit int,
+	pace func(context.Context) error,
+) (ExpiredRewardPage, error) {
+	var page ExpiredRewardPage
+	delayedRepo, ok := s.repo.(DelayedRewardRepository)
+	if !ok {
+		return page, fmt.Errorf("repository does not support delayed rewards")
+	}
+	pager, ok := s.repo.(ExpiredPendingRewardPager)
+	if !ok {
+		return page, fmt.Errorf("repository does not support paging expired rewards")
+	}
 
-			cacheKey := s.getPendingCacheKey(pending.ID)
-			if err := s.invalidatePendingCache(ctx, cacheKey); err != nil {
-				s.logger.Warn("Failed to invalidate cache", zap.Error(err))
+	expired, err := pager.GetExpiredPendingRewardsPage(ctx, after, expiredBefore, limit)
+	if err != nil {
+		return page, fmt.Errorf("failed to get expired rewards page: %w", err)
+	}
+	page.Fetched = len(expired)
+
+	for _, pending := range expired {
+		if pace != nil {
+			if err := pace(ctx); err != nil {
+				return page, err
 			}
-			processed++
-			continue
 		}
+		switch s.expirePendingReward(ctx, baseBandit, delayedRepo, pending) {
+		case expiredRewardProcessed:
+			page.Processed++
+		case expiredRewardSkipped:
+			page.Skipped++
+		default:
+			page.Failed++
+		}
+		page.Next = &ExpiredRewardCursor{ExpiresAt: pending.ExpiresAt, ID: pending.ID}
+	}
+	return page, nil
+}
+
+type expiredRewardOutcome int
+
+const (
+	expiredRewardProcessed expiredRewardOutcome = iota
+	// expiredRewardSkipped is a reward that converted or was recorded meanwhile
+	expiredRewardSkipped
+	expiredRewardFailed
+)
 
-		// Record as non-conversion (reward = 0)
-		if err := baseBandit.UpdateRewardWithEvent(ctx, pending.ExperimentID, pending.ArmID, 0, &ConversionEvent{
-			ExperimentID:          pending.ExperimentID,
-			ArmID:                 pending.ArmID,
-			UserID:                &pending.UserID,
-			PendingRewardID:       &pending.ID,
-			EventType:             ConversionEventTypeExpiredPendingReward,
-			OriginalRewardValue:   0,
-			NormalizedRewardValue: 0,
-			Metadata: map[string]interface{}{
-				"source": "delayed_reward_strategy_fallback",
-			},
-			OccurredAt: now,
-		}); err != nil {
+// expirePendingReward records one expired reward as a non-conversion. Errors
+// are logged so one bad reward does not stop the rest of its batch.
+func (s *DelayedRewardStrategy) expirePendingReward(
+	ctx context.Context,
+	baseBandit *ThompsonSamplingBandit,
+	delayedRepo DelayedRewardRepository,
+	pending *PendingReward,
+) expiredRewardOutcome {
+	if pending.Converted {
+		return expiredRewardSkipped
+	}
+
+	now := s.now().UTC()
+	if processor, ok := s.repo.(ExpiredPendingRewardProcessor); ok {
+		applied, err := processor.ProcessExpiredPendingReward(ctx, pending.ID, now)
+		if err != nil {
 			s.logger.Error("Failed to record expired reward",
 				zap.String("pending_id", pending.ID.String()),
 				zap.Error(err),
 			)
-			continue
+			return expiredRewardFailed
 		}
-
-		// Mark as processed
-		pending.ProcessedAt = &now
-		if err := delayedRepo.UpdatePendingReward(ctx, pending); err != nil {
-			s.logger.Error("Failed to update expired pending reward",
-				zap.String("pending_id", pending.ID.String()),
-				zap.Error(err),
-			)
-			continue
+		if !applied {
+			return expiredRewardSkipped
 		}
 
-		// Invalidate cache
 		cacheKey := s.getPendingCacheKey(pending.ID)
 		if err := s.invalidatePendingCache(ctx, cacheKey); err != nil {
 			s.logger.Warn("Failed to invalidate cache", zap.Error(err))
 		}
-
-		processed++
+		return expiredRewardProcessed
+	}
+
+	// Record as non-conversion (reward = 0)
+	if err := baseBandit.UpdateRewardWithEvent(ctx, pending.ExperimentID, pending.ArmID, 0, &ConversionEvent{
+		ExperimentID:          pending.ExperimentID,
+		ArmID:                 pending.ArmID,
+		UserID:                &pending.UserID,
+		PendingRewardID:       &pending.ID,
+		EventType:             ConversionEventTypeExpiredPendingReward,
+		OriginalRewardValue:   0,
+		NormalizedRewardValue: 0,
+		Metadata: map[string]interface{}{
+			"source": "delayed_reward_strategy_fallback",
+		},
+		OccurredAt: now,
+	}); err != nil {
+		s.logger.Error("Failed to record expired reward",
+			zap.String("pending_id", pending.ID.String()),
+			zap.Error(err),
+		)
+		return expiredRewardFailed
 	}
 
-	if processed > 0 {
-		s.logger.Info("Processed expired pending rewards",
-			zap.Int("count", processed),
+	// Mark as processed
+	pending.ProcessedAt = &now
+	if err := delayedRepo.UpdatePendingReward(ctx, pending); err != nil {
+		s.logger.Error("Failed to update expired pending reward",
+			zap.String("pending_id", pending.ID.String()),
+			zap.Error(err),
 		)
+		return expiredRewardFailed
 	}
 
-	return processed, nil
+	// Invalidate cache
+	cacheKey := s.getPendingCacheKey(pending.ID)
+	if err := s.invalidatePendingCache(ctx, cacheKey); err != nil {
+		s.logger.Warn("Failed to invalidate cache", zap.Error(err))
+	}
+	return expiredRewardProcessed
 }
 
 // GetPendingReward retrieves a pending reward by ID
@@ -368,10 +522,12 @@ func (s *DelayedRewardStrategy) GetPendingReward(ctx context.Context, id uuid.UU
 	return pending, nil
 }
 
-// GetPendingRewardsByUser retrieves all pending rewards for a user
+// GetPendingRewardsByUser retrieves a user's pending rewards, in every
+// experiment when experimentID is nil
 func (s *DelayedRewardStrategy) GetPendingRewardsByUser(
 	ctx context.Context,
-	userID, experimentID uuid.UUID,
+	userID uuid.UUID,
+	experimentID *uuid.UUID,
 ) ([]*PendingReward, error) {
 	delayedRepo, ok := s.repo.(DelayedRewardRepository)
 	if !ok {
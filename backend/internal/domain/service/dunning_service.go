@@ -4,20 +4,48 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"hash/fnv"
 	"time"
 
 	"github.com/google/uuid"
+	"go.uber.org/zap"
 
 	"github.com/bivex/paywall-iap/internal/domain/entity"
 	"github.com/bivex/paywall-iap/internal/domain/repository"
+	"github.com/bivex/paywall-iap/internal/infrastructure/logging"
 )
 
+// DunningStart describes the renewal failure that opens a dunning process
+type DunningStart struct {
+	AppID          uuid.UUID
+	SubscriptionID uuid.UUID
+	UserID         uuid.UUID
+	Platform       string
+	ProductID      string
+	Reason         entity.DunningReason
+}
+
+// dunningBandit assigns campaign variants through a bandit experiment and
+// rewards the arm whose campaign recovered the payment (see AdvancedBanditEngine)
+type dunningBandit interface {
+	SelectArm(ctx context.Context, experimentID, userID uuid.UUID, userContext UserContext) (uuid.UUID, error)
+	RecordConversion(ctx context.Context, experimentID, armID, userID uuid.UUID, userContext UserContext) error
+}
+
+// dunningAppLookup resolves the Google Play package name for payment-fix links
+type dunningAppLookup interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*entity.App, error)
+}
+
 // DunningService handles dunning management
 type DunningService struct {
 	dunningRepo      repository.DunningRepository
 	subscriptionRepo repository.SubscriptionRepository
 	userRepo         repository.UserRepository
 	notificationSvc  *NotificationService
+	campaigns        repository.DunningCampaignRepository
+	bandit           dunningBandit
+	apps             dunningAppLookup
 }
 
 // NewDunningService creates a new dunning service
@@ -35,17 +63,50 @@ func NewDunningService(
 	}
 }
 
+// WithCampaigns schedules reminders by the active campaign variants instead
+// of the default backoff
+func (s *DunningService) WithCampaigns(campaigns repository.DunningCampaignRepository) *DunningService {
+	s.campaigns = campaigns
+	return s
+}
+
+// WithBandit lets bandit experiments assign campaigns linked to their arms
+// and rewards the arm when the payment recovers
+func (s *DunningService) WithBandit(bandit dunningBandit) *DunningService {
+	s.bandit = bandit
+	return s
+}
+
+// WithApps adds the Google Play package to payment-fix links
+func (s *DunningService) WithApps(apps dunningAppLookup) *DunningService {
+	s.apps = apps
+	return s
+}
+
 // StartDunning starts a dunning process for a failed subscription renewal
 func (s *DunningService) StartDunning(ctx context.Context, subscriptionID, userID uuid.UUID) (*entity.Dunning, error) {
+	return s.StartCampaign(ctx, DunningStart{SubscriptionID: subscriptionID, UserID: userID})
+}
+
+// StartCampaign starts dunning for a store renewal failure, assigning a
+// campaign variant and sending the first reminder. A subscription already in
+// dunning keeps its process.
+func (s *DunningService) StartCampaign(ctx context.Context, start DunningStart) (*entity.Dunning, error) {
 	// Check if active dunning already exists
-	existing, err := s.dunningRepo.GetActiveBySubscriptionID(ctx, subscriptionID)
+	existing, err := s.dunningRepo.GetActiveBySubscriptionID(ctx, start.SubscriptionID)
 	if err == nil && existing != nil && existing.CanRetry() {
 		return existing, nil
 	}
 
 	// Create new dunning
 	nextAttemptAt := time.Now().Add(24 * time.Hour)
-	dunning := entity.NewDunning(subscriptionID, userID, nextAttemptAt)
+	dunning := entity.NewDunning(start.SubscriptionID, start.UserID, nextAttemptAt)
+	dunning.Reason = start.Reason
+	dunning.Platform = start.Platform
+	dunning.PaymentFixURL = entity.PaymentFixURL(start.Platform, start.ProductID, s.packageName(ctx, start))
+	if campaign := s.assignCampaign(ctx, start.UserID); campaign != nil {
+		dunning.ApplyCampaign(campaign)
+	}
 
 	// Save dunning
 	err = s.dunningRepo.Create(ctx, dunning)
@@ -54,11 +115,39 @@ func (s *DunningService) StartDunning(ctx context.Context, subscriptionID, userI
 	}
 
 	// Send first retry notification
-	_ = s.notificationSvc.SendPaymentRetryNotification(ctx, userID, 1)
+	_ = s.notificationSvc.SendPaymentFixReminder(ctx, start.UserID, 1, dunning.PaymentFixURL)
 
 	return dunning, nil
 }
 
+// RecordOutcome closes the subscription's active dunning when the store
+// reports the payment recovered or the subscription lost. Returns nil
+// without error when the subscription is not in dunning.
+func (s *DunningService) RecordOutcome(ctx context.Context, subscriptionID uuid.UUID, recovered bool) (*entity.Dunning, error) {
+	dunning, err := s.dunningRepo.GetActiveBySubscriptionID(ctx, subscriptionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load dunning: %w", err)
+	}
+	if dunning == nil || !dunning.CanRetry() {
+		return nil, nil
+	}
+
+	if recovered {
+		dunning.MarkRecovered()
+	} else {
+		dunning.MarkFailed()
+	}
+	if err := s.dunningRepo.Update(ctx, dunning); err != nil {
+		return nil, fmt.Errorf("failed to update dunning: %w", err)
+	}
+
+	if recovered {
+		s.rewardRecovery(ctx, dunning)
+		s.notificationSvc.SendPaymentSuccessNotification(ctx, dunning.UserID)
+	}
+	return dunning, nil
+}
+
 // ProcessDunningAttempt processes a dunning retry attempt
 func (s *DunningService) ProcessDunningAttempt(ctx context.Context, dunningID uuid.UUID, paymentSuccess bool) error {
 	// Get dunning
@@ -87,6 +176,7 @@ func (s *DunningService) ProcessDunningAttempt(ctx context.Context, dunningID uu
 		if err != nil {
 			return err
 		}
+		s.rewardRecovery(ctx, dunning)
 
 		// Send success notification
 		s.notificationSvc.SendPaymentSuccessNotification(ctx, dunning.UserID)
@@ -114,14 +204,14 @@ func (s *DunningService) ProcessDunningAttempt(ctx context.Context, dunningID uu
 	}
 
 	// Schedule next attempt
-	dunning.NextAttemptAt = time.Now().Add(dunning.GetRetryDelay())
+	dunning.NextAttemptAt = s.nextAttemptAt(ctx, dunning)
 	err = s.dunningRepo.Update(ctx, dunning)
 	if err != nil {
 		return err
 	}
 
 	// Send retry notification
-	s.notificationSvc.SendPaymentRetryNotification(ctx, dunning.UserID, dunning.AttemptCount+1)
+	s.notificationSvc.SendPaymentFixReminder(ctx, dunning.UserID, dunning.AttemptCount+1, dunning.PaymentFixURL)
 	return nil
 }
 
@@ -129,3 +219,105 @@ func (s *DunningService) ProcessDunningAttempt(ctx context.Context, dunningID uu
 func (s *DunningService) GetPendingDunningAttempts(ctx context.Context, limit int) ([]*entity.Dunning, error) {
 	return s.dunningRepo.GetPendingAttempts(ctx, limit)
 }
+
+// assignCampaign picks the user's campaign variant: the one linked to the arm
+// a bandit experiment selects, else a weighted choice that is stable per user.
+// Nil without campaigns, which keeps the default backoff.
+func (s *DunningService) assignCampaign(ctx context.Context, userID uuid.UUID) *entity.DunningCampaign {
+	if s.campaigns == nil {
+		return nil
+	}
+	campaigns, err := s.campaigns.List(ctx, true)
+	if err != nil {
+		logging.Logger.Warn("Failed to load dunning campaigns, using default schedule", zap.Error(err))
+		return nil
+	}
+	if len(campaigns) == 0 {
+		return nil
+	}
+
+	if s.bandit != nil {
+		for _, c := range campaigns {
+			if c.BanditExperimentID == nil {
+				continue
+			}
+			armID, err := s.bandit.SelectArm(ctx, *c.BanditExperimentID, userID, UserContext{UserID: userID})
+			if err != nil {
+				logging.Logger.Warn("Dunning bandit selection failed, using weights",
+					zap.String("experiment_id", c.BanditExperimentID.String()),
+					zap.Error(err),
+				)
+				break
+			}
+			for _, candidate := range campaigns {
+				if candidate.BanditArmID != nil && *candidate.BanditArmID == armID {
+					return candidate
+				}
+			}
+			break
+		}
+	}
+
+	total := 0
+	for _, c := range campaigns {
+		total += c.Weight
+	}
+	h := fnv.New32a()
+	h.Write([]byte(userID.String()))
+	pick := int(h.Sum32() % uint32(total))
+	for _, c := range campaigns {
+		if pick < c.Weight {
+			return c
+		}
+		pick -= c.Weight
+	}
+	return campaigns[len(campaigns)-1]
+}
+
+// nextAttemptAt schedules the next reminder by the dunning's campaign, or by
+// the default backoff when it has none
+func (s *DunningService) nextAttemptAt(ctx context.Context, dunning *entity.Dunning) time.Time {
+	if dunning.CampaignID != nil && s.campaigns != nil {
+		campaign, err := s.campaigns.GetByID(ctx, *dunning.CampaignID)
+		if err == nil {
+			return campaign.NextReminderAt(dunning.CreatedAt, dunning.AttemptCount)
+		}
+		logging.Logger.Warn("Failed to load dunning campaign, using default backoff",
+			zap.String("dunning_id", dunning.ID.String()),
+			zap.Error(err),
+		)
+	}
+	return time.Now().Add(dunning.GetRetryDelay())
+}
+
+// rewardRecovery credits the bandit arm of the campaign that recovered the
+// payment. Best-effort: the recovery is already recorded.
+func (s *DunningService) rewardRecovery(ctx context.Context, dunning *entity.Dunning) {
+	if s.bandit == nil || s.campaigns == nil || dunning.CampaignID == nil {
+		return
+	}
+	campaign, err := s.campaigns.GetByID(ctx, *dunning.CampaignID)
+	if err != nil || campaign.BanditExperimentID == nil {
+		return
+	}
+	// A recovery is a yes/no outcome, so the experiment's revenue basis must
+	// not change whether it counts as a success
+	if err := s.bandit.RecordConversion(ctx, *campaign.BanditExperimentID, *campaign.BanditArmID, dunning.UserID,
+		UserContext{UserID: dunning.UserID}); err != nil {
+		logging.Logger.Warn("Failed to reward dunning campaign arm",
+			zap.String("dunning_id", dunning.ID.String()),
+			zap.Error(err),
+		)
+	}
+}
+
+func (s *DunningService) packageName(ctx context.Context, start DunningStart) string {
+	if s.apps == nil || start.Platform != "android" || start.AppID == uuid.Nil {
+		return ""
+	}
+	app, err := s.apps.GetByID(ctx, start.AppID)
+	if err != nil {
+		return ""
+	}
+	return app.BundleID
+}
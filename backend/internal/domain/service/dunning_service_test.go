@@ -10,14 +10,17 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 
 	"github.com/bivex/paywall-iap/internal/domain/entity"
 	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/infrastructure/logging"
 	"github.com/bivex/paywall-iap/tests/mocks"
 )
 
 func TestDunningService(t *testing.T) {
 	ctx := context.Background()
+	logging.Logger = zap.NewNop()
 
 	// Setup mocks
 	dunningRepo := mocks.NewMockDunningRepository()
@@ -77,3 +80,96 @@ func TestDunningService(t *testing.T) {
 		assert.NotNil(t, dunning.FailedAt)
 	})
 }
+
+type fakeDunningCampaigns struct {
+	campaigns []*entity.DunningCampaign
+}
+
+func (f *fakeDunningCampaigns) Create(context.Context, *entity.DunningCampaign) error { return nil }
+func (f *fakeDunningCampaigns) Update(context.Context, *entity.DunningCampaign) error { return nil }
+
+func (f *fakeDunningCampaigns) GetByID(_ context.Context, id uuid.UUID) (*entity.DunningCampaign, error) {
+	for _, c := range f.campaigns {
+		if c.ID == id {
+			return c, nil
+		}
+	}
+	return nil, errors.New("not found")
+}
+
+func (f *fakeDunningCampaigns) List(context.Context, bool) ([]*entity.DunningCampaign, error) {
+	return f.campaigns, nil
+}
+
+func (f *fakeDunningCampaigns) RecoveryStats(context.Context, time.Time) ([]*entity.DunningCampaignStats, error) {
+	return nil, nil
+}
+
+type fakeDunningBandit struct {
+	arm      uuid.UUID
+	rewarded []uuid.UUID
+}
+
+func (f *fakeDunningBandit) SelectArm(context.Context, uuid.UUID, uuid.UUID, service.UserContext) (uuid.UUID, error) {
+	return f.arm, nil
+}
+
+func (f *fakeDunningBandit) RecordConversion(_ context.Context, _, armID, _ uuid.UUID, _ service.UserContext) error {
+	f.rewarded = append(f.rewarded, armID)
+	return nil
+}
+
+func TestDunningService_BanditAssignsCampaignAndRewardsRecovery(t *testing.T) {
+	ctx := context.Background()
+	logging.Logger = zap.NewNop()
+
+	experimentID, armA, armB := uuid.New(), uuid.New(), uuid.New()
+	control, err := entity.NewDunningCampaign("control", "a", []time.Duration{24 * time.Hour, 72 * time.Hour}, 1)
+	require.NoError(t, err)
+	control.BanditExperimentID, control.BanditArmID = &experimentID, &armA
+	fast, err := entity.NewDunningCampaign("fast", "b", []time.Duration{6 * time.Hour, 24 * time.Hour, 48 * time.Hour}, 1)
+	require.NoError(t, err)
+	fast.BanditExperimentID, fast.BanditArmID = &experimentID, &armB
+
+	dunningRepo := mocks.NewMockDunningRepository()
+	subscriptionRepo := mocks.NewMockSubscriptionRepository()
+	bandit := &fakeDunningBandit{arm: armB}
+	svc := service.NewDunningService(dunningRepo, subscriptionRepo, mocks.NewMockUserRepository(), service.NewNotificationService()).
+		WithCampaigns(&fakeDunningCampaigns{campaigns: []*entity.DunningCampaign{control, fast}}).
+		WithBandit(bandit)
+
+	subscriptionID, userID := uuid.New(), uuid.New()
+	dunningRepo.On("GetActiveBySubscriptionID", ctx, subscriptionID).Return(nil, nil).Once()
+	dunningRepo.On("Create", ctx, mock.Anything).Return(nil).Once()
+
+	d, err := svc.StartCampaign(ctx, service.DunningStart{
+		SubscriptionID: subscriptionID, UserID: userID, Platform: "ios", Reason: entity.DunningReasonGrace,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, d.CampaignID)
+	assert.Equal(t, fast.ID, *d.CampaignID)
+	assert.Equal(t, 3, d.MaxAttempts)
+	assert.Equal(t, "https://apps.apple.com/account/billing", d.PaymentFixURL)
+
+	dunningRepo.On("GetActiveBySubscriptionID", ctx, subscriptionID).Return(d, nil).Once()
+	dunningRepo.On("Update", ctx, d).Return(nil).Once()
+
+	closed, err := svc.RecordOutcome(ctx, subscriptionID, true)
+	require.NoError(t, err)
+	require.NotNil(t, closed)
+	assert.True(t, closed.IsRecovered())
+	assert.Equal(t, []uuid.UUID{armB}, bandit.rewarded)
+}
+
+func TestDunningService_RecordOutcomeWithoutDunning(t *testing.T) {
+	ctx := context.Background()
+	dunningRepo := mocks.NewMockDunningRepository()
+	svc := service.NewDunningService(dunningRepo, mocks.NewMockSubscriptionRepository(), mocks.NewMockUserRepository(), service.NewNotificationService())
+
+	subscriptionID := uuid.New()
+	dunningRepo.On("GetActiveBySubscriptionID", ctx, subscriptionID).Return(nil, nil).Once()
+
+	closed, err := svc.RecordOutcome(ctx, subscriptionID, false)
+	require.NoError(t, err)
+	assert.Nil(t, closed)
+}
@@ -0,0 +1,394 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+const (
+	// embedTokenAudience keeps embed tokens from passing as any other JWT
+	embedTokenAudience = "analytics-embed"
+
+	DefaultEmbedTokenTTL       = 15 * time.Minute
+	MaxEmbedTokenTTL           = 24 * time.Hour
+	DefaultEmbedMaxRangeDays   = 90
+	MaxEmbedRangeDays          = 366
+	DefaultEmbedRatePerMinute  = 60
+	MaxEmbedRatePerMinute      = 600
+	defaultEmbedQueryRangeDays = 30
+)
+
+var (
+	// ErrInvalidEmbedTokenSpec is returned when minting with an unknown or
+	// unsupported metric/dimension, or limits out of range
+	ErrInvalidEmbedTokenSpec = errors.New("invalid embed token spec")
+	// ErrEmbedTokenInvalid is returned for tokens that are malformed, signed
+	// with another key, expired or revoked
+	ErrEmbedTokenInvalid = errors.New("invalid or expired embed token")
+	// ErrEmbedTokenNotFound is returned when revoking an unknown token
+	ErrEmbedTokenNotFound = errors.New("embed token not found")
+	// ErrEmbedQueryNotAllowed is returned for queries outside the token scope
+	ErrEmbedQueryNotAllowed = errors.New("query not allowed by embed token")
+)
+
+// EmbedTokenSpec is what a new embed token may query; zero limits take the
+// defaults
+type EmbedTokenSpec struct {
+	Label              string
+	Metrics            []entity.EmbedMetric
+	Dimensions         []entity.EmbedDimension
+	MaxRangeDays       int
+	RateLimitPerMinute int
+	TTL                time.Duration
+}
+
+// EmbedQuery is one chart request; From and To are inclusive reporting days
+type EmbedQuery struct {
+	Metric    entity.EmbedMetric
+	Dimension entity.EmbedDimension
+	From      time.Time
+	To        time.Time
+}
+
+// EmbedPoint is one value of a chart. Money metrics are never summed across
+// currencies, so they carry the currency of the value.
+type EmbedPoint struct {
+	Key      string  `json:"key"`
+	Currency string  `json:"currency,omitempty"`
+	Value    float64 `json:"value"`
+}
+
+// EmbedQueryResult is a chart series
+type EmbedQueryResult struct {
+	Metric    entity.EmbedMetric    `json:"metric"`
+	Dimension entity.EmbedDimension `json:"dimension"`
+	From      string                `json:"from"`
+	To        string                `json:"to"`
+	Points    []EmbedPoint          `json:"points"`
+}
+
+type embedTokenClaims struct {
+	jwt.RegisteredClaims
+}
+
+// EmbeddedAnalyticsService mints embed tokens and answers the predefined
+// chart queries they allow from the dashboard materialized views
+type EmbeddedAnalyticsService struct {
+	dbPool *pgxpool.Pool
+	key    []byte
+	now    func() time.Time
+}
+
+// NewEmbeddedAnalyticsService signs tokens with a key derived from the JWT
+// secret, so an embed token never verifies as a user or admin token
+func NewEmbeddedAnalyticsService(dbPool *pgxpool.Pool, jwtSecret string) *EmbeddedAnalyticsService {
+	return &EmbeddedAnalyticsService{dbPool: dbPool, key: deriveEmbedKey(jwtSecret), now: time.Now}
+}
+
+func deriveEmbedKey(secret string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(embedTokenAudience))
+	return mac.Sum(nil)
+}
+
+// normalizeEmbedTokenSpec applies defaults and checks the spec
+func normalizeEmbedTokenSpec(spec EmbedTokenSpec) (EmbedTokenSpec, error) {
+	if len(spec.Metrics) == 0 || len(spec.Dimensions) == 0 {
+		return spec, fmt.Errorf("%w: at least one metric and dimension are required", ErrInvalidEmbedTokenSpec)
+	}
+	for _, m := range spec.Metrics {
+		if !m.IsValid() {
+			return spec, fmt.Errorf("%w: unknown metric %q", ErrInvalidEmbedTokenSpec, m)
+		}
+	}
+	for _, d := range spec.Dimensions {
+		if !d.IsValid() {
+			return spec, fmt.Errorf("%w: unknown dimension %q", ErrInvalidEmbedTokenSpec, d)
+		}
+		supported := false
+		for _, m := range spec.Metrics {
+			supported = supported || m.Supports(d)
+		}
+		if !supported {
+			return spec, fmt.Errorf("%w: no metric supports dimension %q", ErrInvalidEmbedTokenSpec, d)
+		}
+	}
+	if spec.MaxRangeDays == 0 {
+		spec.MaxRangeDays = DefaultEmbedMaxRangeDays
+	}
+	if spec.RateLimitPerMinute == 0 {
+		spec.RateLimitPerMinute = DefaultEmbedRatePerMinute
+	}
+	if spec.TTL == 0 {
+		spec.TTL = DefaultEmbedTokenTTL
+	}
+	if spec.MaxRangeDays < 1 || spec.MaxRangeDays > MaxEmbedRangeDays {
+		return spec, fmt.Errorf("%w: max_range_days must be between 1 and %d", ErrInvalidEmbedTokenSpec, MaxEmbedRangeDays)
+	}
+	if spec.RateLimitPerMinute < 1 || spec.RateLimitPerMinute > MaxEmbedRatePerMinute {
+		return spec, fmt.Errorf("%w: rate_limit_per_minute must be between 1 and %d", ErrInvalidEmbedTokenSpec, MaxEmbedRatePerMinute)
+	}
+	if spec.TTL < time.Minute || spec.TTL > MaxEmbedTokenTTL {
+		return spec, fmt.Errorf("%w: ttl must be between 1 minute and 24 hours", ErrInvalidEmbedTokenSpec)
+	}
+	return spec, nil
+}
+
+// Issue stores a token scoped to spec and returns it with its signed form
+func (s *EmbeddedAnalyticsService) Issue(ctx context.Context, appID uuid.UUID, spec EmbedTokenSpec, createdBy *uuid.UUID) (*entity.AnalyticsEmbedToken, string, error) {
+	spec, err := normalizeEmbedTokenSpec(spec)
+	if err != nil {
+		return nil, "", err
+	}
+	now := s.now()
+	t := &entity.AnalyticsEmbedToken{
+		ID:                 uuid.New(),
+		AppID:              appID,
+		Label:              spec.Label,
+		Metrics:            spec.Metrics,
+		Dimensions:         spec.Dimensions,
+		MaxRangeDays:       spec.MaxRangeDays,
+		RateLimitPerMinute: spec.RateLimitPerMinute,
+		CreatedBy:          createdBy,
+		ExpiresAt:          now.Add(spec.TTL).Truncate(time.Second),
+	}
+	err = s.dbPool.QueryRow(ctx, `
+		INSERT INTO analytics_embed_tokens
+			(id, app_id, label, metrics, dimensions, max_range_days, rate_limit_per_minute, created_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING created_at
+	`, t.ID, appID, t.Label, embedStrings(t.Metrics), embedStrings(t.Dimensions),
+		t.MaxRangeDays, t.RateLimitPerMinute, createdBy, t.ExpiresAt).Scan(&t.CreatedAt)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to store embed token: %w", err)
+	}
+	signed, err := s.sign(t, now)
+	if err != nil {
+		return nil, "", err
+	}
+	return t, signed, nil
+}
+
+func (s *EmbeddedAnalyticsService) sign(t *entity.AnalyticsEmbedToken, now time.Time) (string, error) {
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, embedTokenClaims{jwt.RegisteredClaims{
+		ID:        t.ID.String(),
+		Subject:   t.AppID.String(),
+		Audience:  jwt.ClaimStrings{embedTokenAudience},
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(t.ExpiresAt),
+	}}).SignedString(s.key)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign embed token: %w", err)
+	}
+	return signed, nil
+}
+
+// parse verifies the signature, audience and expiry and returns the token ID
+func (s *EmbeddedAnalyticsService) parse(raw string) (uuid.UUID, error) {
+	var claims embedTokenClaims
+	_, err := jwt.ParseWithClaims(raw, &claims, func(*jwt.Token) (any, error) { return s.key, nil },
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithAudience(embedTokenAudience),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(s.now))
+	if err != nil {
+		return uuid.Nil, ErrEmbedTokenInvalid
+	}
+	id, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return uuid.Nil, ErrEmbedTokenInvalid
+	}
+	return id, nil
+}
+
+// Authenticate resolves a signed token to its active scope
+func (s *EmbeddedAnalyticsService) Authenticate(ctx context.Context, raw string) (*entity.AnalyticsEmbedToken, error) {
+	id, err := s.parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	t, err := scanEmbedToken(s.dbPool.QueryRow(ctx, embedTokenSelect+` WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrEmbedTokenInvalid
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load embed token: %w", err)
+	}
+	if !t.IsActive(s.now()) {
+		return nil, ErrEmbedTokenInvalid
+	}
+	return t, nil
+}
+
+// List returns the app's tokens that have not expired, newest first
+func (s *EmbeddedAnalyticsService) List(ctx context.Context, appID uuid.UUID) ([]*entity.AnalyticsEmbedToken, error) {
+	rows, err := s.dbPool.Query(ctx, embedTokenSelect+`
+		WHERE app_id = $1 AND expires_at > $2
+		ORDER BY created_at DESC
+	`, appID, s.now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list embed tokens: %w", err)
+	}
+	defer rows.Close()
+	tokens := []*entity.AnalyticsEmbedToken{}
+	for rows.Next() {
+		t, err := scanEmbedToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan embed token: %w", err)
+		}
+		tokens = append(tokens, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list embed tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+// Revoke stops a token from authenticating before it expires
+func (s *EmbeddedAnalyticsService) Revoke(ctx context.Context, appID, id uuid.UUID) error {
+	tag, err := s.dbPool.Exec(ctx, `
+		UPDATE analytics_embed_tokens SET revoked_at = COALESCE(revoked_at, $3)
+		WHERE app_id = $1 AND id = $2
+	`, appID, id, s.now())
+	if err != nil {
+		return fmt.Errorf("failed to revoke embed token: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrEmbedTokenNotFound
+	}
+	return nil
+}
+
+const embedTokenSelect = `
+	SELECT id, app_id, label, metrics, dimensions, max_range_days, rate_limit_per_minute,
+	       created_by, created_at, expires_at, revoked_at
+	FROM analytics_embed_tokens`
+
+func scanEmbedToken(row pgx.Row) (*entity.AnalyticsEmbedToken, error) {
+	var t entity.AnalyticsEmbedToken
+	var metrics, dimensions []string
+	if err := row.Scan(&t.ID, &t.AppID, &t.Label, &metrics, &dimensions, &t.MaxRangeDays, &t.RateLimitPerMinute,
+		&t.CreatedBy, &t.CreatedAt, &t.ExpiresAt, &t.RevokedAt); err != nil {
+		return nil, err
+	}
+	for _, m := range metrics {
+		t.Metrics = append(t.Metrics, entity.EmbedMetric(m))
+	}
+	for _, d := range dimensions {
+		t.Dimensions = append(t.Dimensions, entity.EmbedDimension(d))
+	}
+	return &t, nil
+}
+
+func embedStrings[T ~string](values []T) []string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = string(v)
+	}
+	return out
+}
+
+// DefaultEmbedQueryRange is the last 30 reporting days up to today, shortened
+// to the token's range limit
+func DefaultEmbedQueryRange(t *entity.AnalyticsEmbedToken, now time.Time) (from, to time.Time) {
+	to = now.UTC().Truncate(24 * time.Hour)
+	days := min(defaultEmbedQueryRangeDays, t.MaxRangeDays)
+	return to.AddDate(0, 0, -(days - 1)), to
+}
+
+// CheckEmbedQuery reports whether the token may run q
+func CheckEmbedQuery(t *entity.AnalyticsEmbedToken, q EmbedQuery) error {
+	if !t.Allows(q.Metric, q.Dimension) {
+		return fmt.Errorf("%w: %s by %s", ErrEmbedQueryNotAllowed, q.Metric, q.Dimension)
+	}
+	if q.To.Before(q.From) {
+		return fmt.Errorf("%w: from must not be after to", ErrEmbedQueryNotAllowed)
+	}
+	if days := int(q.To.Sub(q.From).Hours()/24) + 1; days > t.MaxRangeDays {
+		return fmt.Errorf("%w: range is limited to %d days", ErrEmbedQueryNotAllowed, t.MaxRangeDays)
+	}
+	return nil
+}
+
+// Query runs q for the token's app
+func (s *EmbeddedAnalyticsService) Query(ctx context.Context, t *entity.AnalyticsEmbedToken, q EmbedQuery) (*EmbedQueryResult, error) {
+	if err := CheckEmbedQuery(t, q); err != nil {
+		return nil, err
+	}
+	result := &EmbedQueryResult{
+		Metric:    q.Metric,
+		Dimension: q.Dimension,
+		From:      q.From.Format("2006-01-02"),
+		To:        q.To.Format("2006-01-02"),
+		Points:    []EmbedPoint{},
+	}
+	rows, err := s.dbPool.Query(ctx, embedQuerySQL(q.Metric, q.Dimension), t.AppID, result.From, result.To)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s: %w", q.Metric, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p EmbedPoint
+		if err := rows.Scan(&p.Key, &p.Currency, &p.Value); err != nil {
+			return nil, fmt.Errorf("failed to scan %s: %w", q.Metric, err)
+		}
+		result.Points = append(result.Points, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query %s: %w", q.Metric, err)
+	}
+	return result, nil
+}
+
+// embedMetricSources are the view and aggregate behind each metric
+var embedMetricSources = map[entity.EmbedMetric]struct {
+	view, day, value string
+	money            bool
+}{
+	entity.EmbedRevenue:        {"mv_daily_revenue", "day", "SUM(revenue)::float8", true},
+	entity.EmbedRefunds:        {"mv_daily_revenue", "day", "SUM(refunded)::float8", true},
+	entity.EmbedTransactions:   {"mv_daily_revenue", "day", "SUM(transactions)::float8", false},
+	entity.EmbedSignups:        {"mv_conversion_by_platform", "cohort_day", "SUM(users)::float8", false},
+	entity.EmbedConversions:    {"mv_conversion_by_platform", "cohort_day", "SUM(converted)::float8", false},
+	entity.EmbedConversionRate: {"mv_conversion_by_platform", "cohort_day", "COALESCE(SUM(converted)::float8 / NULLIF(SUM(users), 0), 0)", false},
+}
+
+// embedQuerySQL builds the query for a checked metric and dimension; $1 is
+// the app, $2 and $3 the inclusive day range
+func embedQuerySQL(metric entity.EmbedMetric, dimension entity.EmbedDimension) string {
+	src := embedMetricSources[metric]
+	var key string
+	switch dimension {
+	case entity.EmbedByWeek:
+		key = "to_char(date_trunc('week', " + src.day + "), 'YYYY-MM-DD')"
+	case entity.EmbedByMonth:
+		key = "to_char(date_trunc('month', " + src.day + "), 'YYYY-MM')"
+	case entity.EmbedByCurrency:
+		key = "currency"
+	case entity.EmbedByPlatform:
+		key = "platform"
+	default:
+		key = "to_char(" + src.day + ", 'YYYY-MM-DD')"
+	}
+	currency, groupBy := "''", "1"
+	if src.money {
+		currency, groupBy = "currency", "1, 2"
+	}
+	return fmt.Sprintf(`
+		SELECT %s, %s, %s
+		FROM %s
+		WHERE app_id = $1 AND %s BETWEEN $2::date AND $3::date
+		GROUP BY %s
+		ORDER BY 1, 2`, key, currency, src.value, src.view, src.day, groupBy)
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+func TestEmbedToken_SignedWithDerivedKey(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	s := &EmbeddedAnalyticsService{key: deriveEmbedKey("jwt-secret"), now: func() time.Time { return now }}
+	tok := &entity.AnalyticsEmbedToken{ID: uuid.New(), AppID: uuid.New(), ExpiresAt: now.Add(15 * time.Minute)}
+
+	signed, err := s.sign(tok, now)
+	require.NoError(t, err)
+	id, err := s.parse(signed)
+	require.NoError(t, err)
+	assert.Equal(t, tok.ID, id)
+
+	// The JWT secret itself must not verify it, nor it a token signed with the secret
+	_, err = jwt.Parse(signed, func(*jwt.Token) (any, error) { return []byte("jwt-secret"), nil })
+	assert.Error(t, err)
+	userToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		ID:        tok.ID.String(),
+		Audience:  jwt.ClaimStrings{embedTokenAudience},
+		ExpiresAt: jwt.NewNumericDate(tok.ExpiresAt),
+	}).SignedString([]byte("jwt-secret"))
+	require.NoError(t, err)
+	_, err = s.parse(userToken)
+	assert.ErrorIs(t, err, ErrEmbedTokenInvalid)
+
+	s.now = func() time.Time { return now.Add(time.Hour) }
+	_, err = s.parse(signed)
+	assert.ErrorIs(t, err, ErrEmbedTokenInvalid)
+}
+
+func TestNormalizeEmbedTokenSpec(t *testing.T) {
+	spec, err := normalizeEmbedTokenSpec(EmbedTokenSpec{
+		Metrics:    []entity.EmbedMetric{entity.EmbedRevenue},
+		Dimensions: []entity.EmbedDimension{entity.EmbedByDay, entity.EmbedByCurrency},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, DefaultEmbedTokenTTL, spec.TTL)
+	assert.Equal(t, DefaultEmbedMaxRangeDays, spec.MaxRangeDays)
+	assert.Equal(t, DefaultEmbedRatePerMinute, spec.RateLimitPerMinute)
+
+	for _, bad := range []EmbedTokenSpec{
+		{Metrics: []entity.EmbedMetric{"ltv"}, Dimensions: []entity.EmbedDimension{entity.EmbedByDay}},
+		{Metrics: []entity.EmbedMetric{entity.EmbedRevenue}, Dimensions: []entity.EmbedDimension{entity.EmbedByPlatform}},
+		{Metrics: []entity.EmbedMetric{entity.EmbedSignups}, Dimensions: []entity.EmbedDimension{entity.EmbedByDay}, TTL: 48 * time.Hour},
+		{Metrics: []entity.EmbedMetric{entity.EmbedSignups}, Dimensions: []entity.EmbedDimension{entity.EmbedByDay}, RateLimitPerMinute: 10000},
+	} {
+		_, err := normalizeEmbedTokenSpec(bad)
+		assert.ErrorIs(t, err, ErrInvalidEmbedTokenSpec)
+	}
+}
+
+func TestCheckEmbedQuery_EnforcesScopeAndRange(t *testing.T) {
+	tok := &entity.AnalyticsEmbedToken{
+		Metrics:      []entity.EmbedMetric{entity.EmbedRevenue, entity.EmbedSignups},
+		Dimensions:   []entity.EmbedDimension{entity.EmbedByDay, entity.EmbedByPlatform},
+		MaxRangeDays: 7,
+	}
+	day := func(d int) time.Time { return time.Date(2026, 10, d, 0, 0, 0, 0, time.UTC) }
+
+	assert.NoError(t, CheckEmbedQuery(tok, EmbedQuery{Metric: entity.EmbedRevenue, Dimension: entity.EmbedByDay, From: day(1), To: day(7)}))
+	assert.NoError(t, CheckEmbedQuery(tok, EmbedQuery{Metric: entity.EmbedSignups, Dimension: entity.EmbedByPlatform, From: day(1), To: day(1)}))
+
+	for _, q := range []EmbedQuery{
+		{Metric: entity.EmbedRevenue, Dimension: entity.EmbedByDay, From: day(1), To: day(8)},
+		{Metric: entity.EmbedRevenue, Dimension: entity.EmbedByPlatform, From: day(1), To: day(2)},
+		{Metric: entity.EmbedRefunds, Dimension: entity.EmbedByDay, From: day(1), To: day(2)},
+		{Metric: entity.EmbedRevenue, Dimension: entity.EmbedByDay, From: day(3), To: day(2)},
+	} {
+		assert.ErrorIs(t, CheckEmbedQuery(tok, q), ErrEmbedQueryNotAllowed)
+	}
+}
+
+func TestEmbedQuerySQL_KeepsMoneyPerCurrency(t *testing.T) {
+	revenue := embedQuerySQL(entity.EmbedRevenue, entity.EmbedByWeek)
+	assert.Contains(t, revenue, "date_trunc('week', day)")
+	assert.Contains(t, revenue, "GROUP BY 1, 2")
+	assert.Contains(t, revenue, "FROM mv_daily_revenue")
+
+	rate := embedQuerySQL(entity.EmbedConversionRate, entity.EmbedByPlatform)
+	assert.Contains(t, rate, "SELECT platform, ''")
+	assert.Contains(t, rate, "cohort_day BETWEEN $2::date AND $3::date")
+	assert.Contains(t, rate, "GROUP BY 1\n")
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"sort"
+	"time"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+// Entitlement is a user's premium access merged across every store they
+// bought on. Access belongs to the user, so an iOS purchase restores on
+// Android (and on the web) once the same account signs in there.
+type Entitlement struct {
+	// Primary is the subscription that currently defines the entitlement;
+	// nil when the user has no access
+	Primary *entity.Subscription
+	// Active holds every subscription granting access, Primary first
+	Active []*entity.Subscription
+	// Lifetime holds the active non-consumable unlocks, which never expire
+	Lifetime []*entity.LifetimeEntitlement
+}
+
+// HasAccess reports whether any subscription or lifetime unlock grants access
+func (e *Entitlement) HasAccess() bool {
+	return e.Primary != nil || len(e.Lifetime) > 0
+}
+
+// IsLifetime reports whether access is permanent
+func (e *Entitlement) IsLifetime() bool {
+	return len(e.Lifetime) > 0
+}
+
+// WithLifetime adds the user's active lifetime unlocks to the entitlement
+func (e *Entitlement) WithLifetime(unlocks []*entity.LifetimeEntitlement) *Entitlement {
+	for _, unlock := range unlocks {
+		if unlock.IsActive() {
+			e.Lifetime = append(e.Lifetime, unlock)
+		}
+	}
+	return e
+}
+
+// ExpiresAt is when access ends if no store renews: the latest expiry among
+// the active subscriptions. Zero when there is no subscription, including
+// lifetime-only access.
+func (e *Entitlement) ExpiresAt() time.Time {
+	if e.Primary == nil {
+		return time.Time{}
+	}
+	return e.Primary.ExpiresAt
+}
+
+// Platforms lists the distinct platforms with an active subscription
+func (e *Entitlement) Platforms() []string {
+	var platforms []string
+	seen := map[string]bool{}
+	for _, sub := range e.Active {
+		if !seen[sub.Platform] {
+			seen[sub.Platform] = true
+			platforms = append(platforms, sub.Platform)
+		}
+	}
+	return platforms
+}
+
+// HasConflict reports whether the user is billed by more than one store at
+// once. Neither store can be cancelled on the user's behalf, so clients
+// should point the user at the store they no longer need. Lifetime unlocks
+// are paid once and never conflict.
+func (e *Entitlement) HasConflict() bool {
+	return len(e.Platforms()) > 1
+}
+
+// LifetimeProducts lists the product IDs of the active lifetime unlocks
+func (e *Entitlement) LifetimeProducts() []string {
+	var products []string
+	for _, unlock := range e.Lifetime {
+		products = append(products, unlock.ProductID)
+	}
+	return products
+}
+
+// ResolveEntitlement merges a user's subscriptions into one entitlement.
+//
+// A subscription grants access while it is active, not deleted and not past
+// its expiry. When several do, the primary is chosen by:
+//  1. the latest expiry, since access lasts until the last store stops;
+//  2. then auto-renewing over not renewing, as that one keeps access going;
+//  3. then the most recently created.
+func ResolveEntitlement(subs []*entity.Subscription, now time.Time) *Entitlement {
+	ent := &Entitlement{}
+	for _, sub := range subs {
+		if sub.DeletedAt == nil && sub.Status == entity.StatusActive && sub.ExpiresAt.After(now) {
+			ent.Active = append(ent.Active, sub)
+		}
+	}
+	sort.SliceStable(ent.Active, func(i, j int) bool {
+		a, b := ent.Active[i], ent.Active[j]
+		if !a.ExpiresAt.Equal(b.ExpiresAt) {
+			return a.ExpiresAt.After(b.ExpiresAt)
+		}
+		if a.AutoRenew != b.AutoRenew {
+			return a.AutoRenew
+		}
+		return a.CreatedAt.After(b.CreatedAt)
+	})
+	if len(ent.Active) > 0 {
+		ent.Primary = ent.Active[0]
+	}
+	return ent
+}
+
+// ActiveSubscriptionForStore returns the active subscription bought through
+// source on platform, so a purchase extends its own store's subscription
+// instead of another store's
+func ActiveSubscriptionForStore(subs []*entity.Subscription, source entity.SubscriptionSource, platform string) *entity.Subscription {
+	for _, sub := range subs {
+		if sub.DeletedAt == nil && sub.Status == entity.StatusActive && sub.Source == source && sub.Platform == platform {
+			return sub
+		}
+	}
+	return nil
+}
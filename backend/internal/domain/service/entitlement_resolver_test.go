@@ -0,0 +1,89 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+func entitlementSub(platform string, source entity.SubscriptionSource, expiresIn time.Duration, autoRenew bool) *entity.Subscription {
+	s := entity.NewSubscription(uuid.New(), source, platform, "com.app.premium", entity.PlanMonthly, time.Now().Add(expiresIn))
+	s.AutoRenew = autoRenew
+	return s
+}
+
+func TestResolveEntitlement_ActiveOnTwoPlatforms(t *testing.T) {
+	ios := entitlementSub("ios", entity.SourceIAP, 10*24*time.Hour, true)
+	android := entitlementSub("android", entity.SourceIAP, 20*24*time.Hour, true)
+
+	ent := ResolveEntitlement([]*entity.Subscription{ios, android}, time.Now())
+
+	require.True(t, ent.HasAccess())
+	assert.Same(t, android, ent.Primary, "the later expiry defines access")
+	assert.Equal(t, android.ExpiresAt, ent.ExpiresAt())
+	assert.Equal(t, []string{"android", "ios"}, ent.Platforms())
+	assert.True(t, ent.HasConflict())
+}
+
+func TestResolveEntitlement_RestoresOnOtherPlatform(t *testing.T) {
+	ios := entitlementSub("ios", entity.SourceIAP, 10*24*time.Hour, true)
+	expiredAndroid := entitlementSub("android", entity.SourceIAP, -time.Hour, false)
+
+	ent := ResolveEntitlement([]*entity.Subscription{expiredAndroid, ios}, time.Now())
+
+	assert.Same(t, ios, ent.Primary)
+	assert.False(t, ent.HasConflict())
+}
+
+func TestResolveEntitlement_TieBreaks(t *testing.T) {
+	expiry := time.Now().Add(30 * 24 * time.Hour)
+	cancelling := entitlementSub("ios", entity.SourceIAP, 0, false)
+	renewing := entitlementSub("web", entity.SourceStripe, 0, true)
+	cancelling.ExpiresAt, renewing.ExpiresAt = expiry, expiry
+
+	ent := ResolveEntitlement([]*entity.Subscription{cancelling, renewing}, time.Now())
+
+	assert.Same(t, renewing, ent.Primary, "auto-renewing wins on equal expiry")
+}
+
+func TestResolveEntitlement_IgnoresInactive(t *testing.T) {
+	cancelled := entitlementSub("ios", entity.SourceIAP, 10*24*time.Hour, false)
+	cancelled.Status = entity.StatusCancelled
+	deleted := entitlementSub("android", entity.SourceIAP, 10*24*time.Hour, true)
+	deletedAt := time.Now()
+	deleted.DeletedAt = &deletedAt
+
+	ent := ResolveEntitlement([]*entity.Subscription{cancelled, deleted}, time.Now())
+
+	assert.False(t, ent.HasAccess())
+	assert.True(t, ent.ExpiresAt().IsZero())
+	assert.Empty(t, ent.Platforms())
+}
+
+func TestResolveEntitlement_LifetimeUnlockWithoutSubscription(t *testing.T) {
+	unlock := entity.NewLifetimeEntitlement(uuid.New(), uuid.New(), "ios", "com.app.lifetime", "tx-1", "tx-1", entity.LifetimeProduct{}, time.Now())
+	refunded := entity.NewLifetimeEntitlement(uuid.New(), uuid.New(), "ios", "com.app.extras", "tx-2", "tx-2", entity.LifetimeProduct{}, time.Now())
+	require.NoError(t, refunded.Revoke("refund", time.Now()))
+
+	ent := ResolveEntitlement(nil, time.Now()).WithLifetime([]*entity.LifetimeEntitlement{unlock, refunded})
+
+	assert.True(t, ent.HasAccess())
+	assert.True(t, ent.IsLifetime())
+	assert.Nil(t, ent.Primary)
+	assert.True(t, ent.ExpiresAt().IsZero())
+	assert.Equal(t, []string{"com.app.lifetime"}, ent.LifetimeProducts())
+}
+
+func TestActiveSubscriptionForStore(t *testing.T) {
+	ios := entitlementSub("ios", entity.SourceIAP, time.Hour, true)
+	web := entitlementSub("web", entity.SourceStripe, time.Hour, true)
+	subs := []*entity.Subscription{web, ios}
+
+	assert.Same(t, ios, ActiveSubscriptionForStore(subs, entity.SourceIAP, "ios"))
+	assert.Nil(t, ActiveSubscriptionForStore(subs, entity.SourceIAP, "android"))
+}
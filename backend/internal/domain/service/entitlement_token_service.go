@@ -0,0 +1,105 @@
+package service
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// EntitlementTokenAudience is the aud claim of offline entitlement tokens
+const EntitlementTokenAudience = "entitlement"
+
+// claimsSigner signs tokens with a key whose public half is published
+type claimsSigner interface {
+	Sign(claims jwt.Claims) (string, error)
+}
+
+// EntitlementSnapshot is the access state an entitlement token certifies
+type EntitlementSnapshot struct {
+	HasAccess bool
+	// Products are the subscription and lifetime products granting access
+	Products []string
+	// ExpiresAt is when access ends without a renewal; nil for lifetime
+	// access and no access
+	ExpiresAt *time.Time
+	Lifetime  bool
+	// GraceExpiresAt is set when access comes from a grace period
+	GraceExpiresAt *time.Time
+}
+
+// EntitlementGraceClaim describes access granted by a grace period
+type EntitlementGraceClaim struct {
+	ExpiresAt *jwt.NumericDate `json:"expires_at"`
+}
+
+// EntitlementClaims are the claims of an offline entitlement token
+type EntitlementClaims struct {
+	AppID        string                 `json:"app_id,omitempty"`
+	HasAccess    bool                   `json:"has_access"`
+	Entitlements []string               `json:"entitlements"`
+	AccessUntil  *jwt.NumericDate       `json:"access_until,omitempty"`
+	Lifetime     bool                   `json:"lifetime,omitempty"`
+	Grace        *EntitlementGraceClaim `json:"grace,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// EntitlementTokenService issues short-lived signed entitlement tokens that
+// clients verify offline against the published JWKS. A token never outlives
+// the access it certifies, so clients only need to check exp.
+type EntitlementTokenService struct {
+	signer claimsSigner
+	ttl    time.Duration
+	issuer string
+	now    func() time.Time
+}
+
+// NewEntitlementTokenService creates an entitlement token service
+func NewEntitlementTokenService(signer claimsSigner, ttl time.Duration, issuer string) *EntitlementTokenService {
+	return &EntitlementTokenService{signer: signer, ttl: ttl, issuer: issuer, now: time.Now}
+}
+
+// Issue signs the user's entitlement and returns the token and its expiry
+func (s *EntitlementTokenService) Issue(userID, appID uuid.UUID, snapshot EntitlementSnapshot) (string, time.Time, error) {
+	now := s.now()
+	expiresAt := now.Add(s.ttl)
+	end := snapshot.ExpiresAt
+	if snapshot.GraceExpiresAt != nil {
+		end = snapshot.GraceExpiresAt
+	}
+	if snapshot.HasAccess && !snapshot.Lifetime && end != nil && end.Before(expiresAt) {
+		expiresAt = *end
+	}
+
+	claims := EntitlementClaims{
+		HasAccess:    snapshot.HasAccess,
+		Entitlements: snapshot.Products,
+		Lifetime:     snapshot.Lifetime,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Subject:   userID.String(),
+			Issuer:    s.issuer,
+			Audience:  jwt.ClaimStrings{EntitlementTokenAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+	if claims.Entitlements == nil {
+		claims.Entitlements = []string{}
+	}
+	if appID != uuid.Nil {
+		claims.AppID = appID.String()
+	}
+	if snapshot.ExpiresAt != nil {
+		claims.AccessUntil = jwt.NewNumericDate(*snapshot.ExpiresAt)
+	}
+	if snapshot.GraceExpiresAt != nil {
+		claims.Grace = &EntitlementGraceClaim{ExpiresAt: jwt.NewNumericDate(*snapshot.GraceExpiresAt)}
+	}
+
+	token, err := s.signer.Sign(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
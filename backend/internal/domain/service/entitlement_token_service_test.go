@@ -0,0 +1,45 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bivex/paywall-iap/internal/infrastructure/signing"
+)
+
+func TestEntitlementTokenService_IssueVerifiesOffline(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	keys := &signing.KeySet{}
+	require.NoError(t, keys.Add("k1", priv))
+
+	now := time.Date(2026, 7, 1, 12, 0, 0, 0, time.UTC)
+	svc := NewEntitlementTokenService(keys, 24*time.Hour, "iap-system")
+	svc.now = func() time.Time { return now }
+	userID := uuid.New()
+
+	grace := now.Add(3 * time.Hour)
+	token, expiresAt, err := svc.Issue(userID, uuid.New(), EntitlementSnapshot{HasAccess: true, GraceExpiresAt: &grace})
+	require.NoError(t, err)
+	assert.Equal(t, grace, expiresAt, "a token never outlives the access it certifies")
+
+	claims := &EntitlementClaims{}
+	_, err = jwt.ParseWithClaims(token, claims, keys.Keyfunc,
+		jwt.WithAudience(EntitlementTokenAudience), jwt.WithTimeFunc(func() time.Time { return now }))
+	require.NoError(t, err)
+	assert.Equal(t, userID.String(), claims.Subject)
+	assert.True(t, claims.HasAccess)
+	require.NotNil(t, claims.Grace)
+	assert.Equal(t, grace.Unix(), claims.Grace.ExpiresAt.Unix())
+
+	_, expiresAt, err = svc.Issue(userID, uuid.Nil, EntitlementSnapshot{HasAccess: true, Lifetime: true, Products: []string{"pro_lifetime"}})
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(24*time.Hour), expiresAt)
+}
@@ -14,6 +14,10 @@ var (
 	ErrExperimentNotEditable                 = errors.New("only draft experiments can be edited")
 	ErrExperimentAutomationPolicyNotEditable = errors.New("completed experiments cannot update automation policy")
 	ErrExperimentArmNotFound                 = errors.New("experiment arm not found")
+	ErrExperimentArmNotArchivable            = errors.New("only running or paused experiments can archive arms")
+	ErrExperimentArchiveUnavailable          = errors.New("experiment archival is not configured")
+	ErrExperimentArmArchived                 = errors.New("experiment arm is already archived")
+	ErrLastExperimentArm                     = errors.New("cannot archive the last live arm")
 	ErrPricingTierNotFound                   = errors.New("pricing tier not found")
 	ErrInvalidStatusTransition               = errors.New("invalid experiment status transition")
 )
@@ -100,6 +104,9 @@ type UpdateExperimentInput struct {
 	StartAt             *time.Time
 	EndAt               *time.Time
 	AutomationPolicy    ExperimentAutomationPolicy
+	RevenueGuardrail    *RevenueGuardrail
+	Category            string
+	Eligibility         *ExperimentEligibility
 	Arms                []ExperimentArmInput
 }
 
@@ -119,6 +126,8 @@ type ExperimentArmInput struct {
 	IsControl     bool
 	TrafficWeight float64
 	PricingTierID *uuid.UUID
+	Discount      *ArmDiscount
+	Payload       *ArmPayload
 }
 
 type ExperimentStatusTransitionAudit struct {
@@ -136,6 +145,14 @@ type ExperimentMutationRepository interface {
 	UpdateExperimentStatusWithAudit(ctx context.Context, experimentID uuid.UUID, currentStatus, nextStatus string, startAt, endAt *time.Time, audit *ExperimentStatusTransitionAudit) error
 	UpdateExperimentAutomationPolicy(ctx context.Context, experimentID uuid.UUID, policy ExperimentAutomationPolicy) error
 	UpdateExperimentStatusAndAutomationPolicyWithAudit(ctx context.Context, experimentID uuid.UUID, currentStatus, nextStatus string, startAt, endAt *time.Time, policy ExperimentAutomationPolicy, audit *ExperimentStatusTransitionAudit) error
+	ArchiveExperimentArm(ctx context.Context, experimentID, armID uuid.UUID, policy ArmReassignmentPolicy, archivedAt time.Time) error
+}
+
+// ExperimentArchiveRepository moves a completed experiment's arm stats to and
+// from the archive along with its status
+type ExperimentArchiveRepository interface {
+	ArchiveExperiment(ctx context.Context, experimentID uuid.UUID, archivedAt time.Time, audit *ExperimentStatusTransitionAudit) error
+	UnarchiveExperiment(ctx context.Context, experimentID uuid.UUID, audit *ExperimentStatusTransitionAudit) error
 }
 
 type ExperimentLockInput struct {
@@ -158,8 +175,10 @@ func (e InvalidExperimentStatusTransitionError) Unwrap() error {
 }
 
 type ExperimentAdminService struct {
-	repo ExperimentMutationRepository
-	now  func() time.Time
+	repo       ExperimentMutationRepository
+	archive    ExperimentArchiveRepository
+	killSwitch ExperimentSwitchboard
+	now        func() time.Time
 }
 
 func NewExperimentAdminService(repo ExperimentMutationRepository) *ExperimentAdminService {
@@ -169,6 +188,20 @@ func NewExperimentAdminService(repo ExperimentMutationRepository) *ExperimentAdm
 	}
 }
 
+// WithArchive enables archiving completed experiments
+func (s *ExperimentAdminService) WithArchive(repo ExperimentArchiveRepository) *ExperimentAdminService {
+	s.archive = repo
+	return s
+}
+
+// WithKillSwitch broadcasts pauses and resumes, so arm selection on every
+// API instance follows within seconds instead of after cached assignments
+// expire
+func (s *ExperimentAdminService) WithKillSwitch(ks ExperimentSwitchboard) *ExperimentAdminService {
+	s.killSwitch = ks
+	return s
+}
+
 func (s *ExperimentAdminService) UpdateDraftExperiment(ctx context.Context, experimentID uuid.UUID, input UpdateExperimentInput) error {
 	experiment, err := s.repo.GetExperimentMutationState(ctx, experimentID)
 	if err != nil {
@@ -185,7 +218,7 @@ func (s *ExperimentAdminService) UpdateExperimentAutomationPolicy(ctx context.Co
 	if err != nil {
 		return err
 	}
-	if experiment.Status == "completed" {
+	if experiment.Status == "completed" || experiment.Status == "archived" {
 		return ErrExperimentAutomationPolicyNotEditable
 	}
 
@@ -201,6 +234,62 @@ func (s *ExperimentAdminService) UpdateExperimentAutomationPolicy(ctx context.Co
 	return s.repo.UpdateExperimentAutomationPolicy(ctx, experimentID, policy)
 }
 
+// ArchiveExperimentArm retires an arm of a running or paused experiment.
+// Users already on it are handled by policy once the assignment migration
+// task runs; draft experiments edit their arms directly instead.
+func (s *ExperimentAdminService) ArchiveExperimentArm(ctx context.Context, experimentID, armID uuid.UUID, policy ArmReassignmentPolicy) error {
+	experiment, err := s.repo.GetExperimentMutationState(ctx, experimentID)
+	if err != nil {
+		return err
+	}
+	if experiment.Status != "running" && experiment.Status != "paused" {
+		return ErrExperimentArmNotArchivable
+	}
+	policy, err = ParseArmReassignmentPolicy(string(policy))
+	if err != nil {
+		return err
+	}
+	return s.repo.ArchiveExperimentArm(ctx, experimentID, armID, policy, s.now())
+}
+
+// ArchiveExperiment archives a completed experiment: its arm stats leave the
+// live stats tables and arm selection no longer serves it. Results stay
+// recoverable with UnarchiveExperiment.
+func (s *ExperimentAdminService) ArchiveExperiment(ctx context.Context, experimentID uuid.UUID, audit *ExperimentStatusTransitionAudit) error {
+	return s.moveArchive(ctx, experimentID, "completed", "archived", func() error {
+		return s.archive.ArchiveExperiment(ctx, experimentID, s.now(), audit)
+	})
+}
+
+// UnarchiveExperiment restores an archived experiment's arm stats and
+// returns it to completed
+func (s *ExperimentAdminService) UnarchiveExperiment(ctx context.Context, experimentID uuid.UUID, audit *ExperimentStatusTransitionAudit) error {
+	return s.moveArchive(ctx, experimentID, "archived", "completed", func() error {
+		return s.archive.UnarchiveExperiment(ctx, experimentID, audit)
+	})
+}
+
+func (s *ExperimentAdminService) moveArchive(ctx context.Context, experimentID uuid.UUID, from, to string, move func() error) error {
+	if s.archive == nil {
+		return ErrExperimentArchiveUnavailable
+	}
+	experiment, err := s.repo.GetExperimentMutationState(ctx, experimentID)
+	if err != nil {
+		return err
+	}
+	invalid := InvalidExperimentStatusTransitionError{CurrentStatus: experiment.Status, NextStatus: to}
+	if experiment.Status != from {
+		return invalid
+	}
+	if err := move(); err != nil {
+		if errors.Is(err, ErrInvalidStatusTransition) {
+			return invalid
+		}
+		return err
+	}
+	return nil
+}
+
 func (s *ExperimentAdminService) TransitionExperimentStatus(ctx context.Context, experimentID uuid.UUID, nextStatus string) error {
 	return s.transitionExperimentStatus(ctx, experimentID, nextStatus, nil)
 }
@@ -275,6 +364,9 @@ func (s *ExperimentAdminService) HoldExperimentForReview(ctx context.Context, ex
 		policy.LockReason = &trimmed
 	}
 
+	if err := s.flipKillSwitch(ctx, experimentID, "paused"); err != nil {
+		return err
+	}
 	if experiment.Status == "paused" {
 		return s.repo.UpdateExperimentAutomationPolicy(ctx, experimentID, policy)
 	}
@@ -290,7 +382,7 @@ func (s *ExperimentAdminService) HoldExperimentForReview(ctx context.Context, ex
 		endAt = &value
 	}
 
-	return s.repo.UpdateExperimentStatusAndAutomationPolicyWithAudit(
+	err = s.repo.UpdateExperimentStatusAndAutomationPolicyWithAudit(
 		ctx,
 		experimentID,
 		experiment.Status,
@@ -300,6 +392,11 @@ func (s *ExperimentAdminService) HoldExperimentForReview(ctx context.Context, ex
 		policy,
 		audit,
 	)
+	if err != nil {
+		_ = s.flipKillSwitch(ctx, experimentID, experiment.Status)
+		return err
+	}
+	return nil
 }
 
 func (s *ExperimentAdminService) transitionExperimentStatus(ctx context.Context, experimentID uuid.UUID, nextStatus string, audit *ExperimentStatusTransitionAudit) error {
@@ -332,7 +429,31 @@ func (s *ExperimentAdminService) transitionExperimentStatus(ctx context.Context,
 		endAt = &value
 	}
 
-	return s.repo.UpdateExperimentStatusWithAudit(ctx, experimentID, experiment.Status, nextStatus, startAt, endAt, audit)
+	// The switch flips first so traffic follows at once, and flips back
+	// when the status cannot be saved
+	if err := s.flipKillSwitch(ctx, experimentID, nextStatus); err != nil {
+		return err
+	}
+	if err := s.repo.UpdateExperimentStatusWithAudit(ctx, experimentID, experiment.Status, nextStatus, startAt, endAt, audit); err != nil {
+		_ = s.flipKillSwitch(ctx, experimentID, experiment.Status)
+		return err
+	}
+	return nil
+}
+
+// flipKillSwitch kills a paused experiment and revives a running one. Other
+// statuses leave the switch as it is.
+func (s *ExperimentAdminService) flipKillSwitch(ctx context.Context, experimentID uuid.UUID, status string) error {
+	if s.killSwitch == nil {
+		return nil
+	}
+	switch status {
+	case "paused":
+		return s.killSwitch.KillExperiment(ctx, experimentID)
+	case "running":
+		return s.killSwitch.ReviveExperiment(ctx, experimentID)
+	}
+	return nil
 }
 
 func validateExperimentStatusTransition(currentStatus string, nextStatus string) error {
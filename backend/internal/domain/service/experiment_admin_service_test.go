@@ -19,6 +19,9 @@ type stubExperimentMutationRepository struct {
 	updatedStatusEnd   *time.Time
 	updatedStatusAudit *ExperimentStatusTransitionAudit
 	updatedPolicy      *ExperimentAutomationPolicy
+	archivedArmID      uuid.UUID
+	archivedPolicy     ArmReassignmentPolicy
+	statusErr          error
 }
 
 func (s *stubExperimentMutationRepository) GetExperimentMutationState(context.Context, uuid.UUID) (*ExperimentMutationState, error) {
@@ -41,6 +44,9 @@ func (s *stubExperimentMutationRepository) UpdateExperimentStatus(_ context.Cont
 }
 
 func (s *stubExperimentMutationRepository) UpdateExperimentStatusWithAudit(_ context.Context, _ uuid.UUID, _ string, nextStatus string, startAt, endAt *time.Time, audit *ExperimentStatusTransitionAudit) error {
+	if s.statusErr != nil {
+		return s.statusErr
+	}
 	s.updatedStatus = nextStatus
 	s.updatedStatusStart = startAt
 	s.updatedStatusEnd = endAt
@@ -62,6 +68,42 @@ func (s *stubExperimentMutationRepository) UpdateExperimentStatusAndAutomationPo
 	return nil
 }
 
+func (s *stubExperimentMutationRepository) ArchiveExperimentArm(_ context.Context, _ uuid.UUID, armID uuid.UUID, policy ArmReassignmentPolicy, _ time.Time) error {
+	s.archivedArmID = armID
+	s.archivedPolicy = policy
+	return nil
+}
+
+type stubExperimentArchiveRepository struct {
+	archived   []uuid.UUID
+	unarchived []uuid.UUID
+	err        error
+}
+
+func (s *stubExperimentArchiveRepository) ArchiveExperiment(_ context.Context, experimentID uuid.UUID, _ time.Time, _ *ExperimentStatusTransitionAudit) error {
+	s.archived = append(s.archived, experimentID)
+	return s.err
+}
+
+func (s *stubExperimentArchiveRepository) UnarchiveExperiment(_ context.Context, experimentID uuid.UUID, _ *ExperimentStatusTransitionAudit) error {
+	s.unarchived = append(s.unarchived, experimentID)
+	return s.err
+}
+
+type stubExperimentSwitchboard struct {
+	killed map[uuid.UUID]bool
+}
+
+func (s *stubExperimentSwitchboard) KillExperiment(_ context.Context, experimentID uuid.UUID) error {
+	s.killed[experimentID] = true
+	return nil
+}
+
+func (s *stubExperimentSwitchboard) ReviveExperiment(_ context.Context, experimentID uuid.UUID) error {
+	delete(s.killed, experimentID)
+	return nil
+}
+
 func TestExperimentAdminService(t *testing.T) {
 	ctx := context.Background()
 	experimentID := uuid.New()
@@ -288,6 +330,91 @@ func TestExperimentAdminService(t *testing.T) {
 		assert.Equal(t, auditKey, *repo.updatedStatusAudit.IdempotencyKey)
 	})
 
+	t.Run("ArchiveExperimentArm rejects draft experiments", func(t *testing.T) {
+		repo := &stubExperimentMutationRepository{state: &ExperimentMutationState{ID: experimentID, Status: "draft"}}
+		svc := NewExperimentAdminService(repo)
+
+		err := svc.ArchiveExperimentArm(ctx, experimentID, uuid.New(), ArmReassignmentKeep)
+
+		require.ErrorIs(t, err, ErrExperimentArmNotArchivable)
+		assert.Equal(t, uuid.Nil, repo.archivedArmID)
+	})
+
+	t.Run("ArchiveExperimentArm defaults to reassigning users", func(t *testing.T) {
+		armID := uuid.New()
+		repo := &stubExperimentMutationRepository{state: &ExperimentMutationState{ID: experimentID, Status: "running"}}
+		svc := NewExperimentAdminService(repo)
+
+		require.NoError(t, svc.ArchiveExperimentArm(ctx, experimentID, armID, ""))
+		assert.Equal(t, armID, repo.archivedArmID)
+		assert.Equal(t, ArmReassignmentReassign, repo.archivedPolicy)
+
+		err := svc.ArchiveExperimentArm(ctx, experimentID, armID, "delete")
+		require.ErrorIs(t, err, ErrInvalidArmReassignmentPolicy)
+	})
+
+	t.Run("ArchiveExperiment only archives completed experiments", func(t *testing.T) {
+		archive := &stubExperimentArchiveRepository{}
+		repo := &stubExperimentMutationRepository{state: &ExperimentMutationState{ID: experimentID, Status: "running"}}
+		svc := NewExperimentAdminService(repo).WithArchive(archive)
+
+		err := svc.ArchiveExperiment(ctx, experimentID, nil)
+		require.ErrorIs(t, err, ErrInvalidStatusTransition)
+		assert.Empty(t, archive.archived)
+
+		repo.state.Status = "completed"
+		require.NoError(t, svc.ArchiveExperiment(ctx, experimentID, nil))
+		assert.Equal(t, []uuid.UUID{experimentID}, archive.archived)
+	})
+
+	t.Run("UnarchiveExperiment reports a concurrent unarchive as an invalid transition", func(t *testing.T) {
+		archive := &stubExperimentArchiveRepository{err: ErrInvalidStatusTransition}
+		repo := &stubExperimentMutationRepository{state: &ExperimentMutationState{ID: experimentID, Status: "archived"}}
+		svc := NewExperimentAdminService(repo).WithArchive(archive)
+
+		err := svc.UnarchiveExperiment(ctx, experimentID, nil)
+
+		var invalid InvalidExperimentStatusTransitionError
+		require.ErrorAs(t, err, &invalid)
+		assert.Equal(t, "completed", invalid.NextStatus)
+		assert.Equal(t, []uuid.UUID{experimentID}, archive.unarchived)
+	})
+
+	t.Run("ArchiveExperiment requires an archive repository", func(t *testing.T) {
+		repo := &stubExperimentMutationRepository{state: &ExperimentMutationState{ID: experimentID, Status: "completed"}}
+
+		err := NewExperimentAdminService(repo).ArchiveExperiment(ctx, experimentID, nil)
+
+		require.ErrorIs(t, err, ErrExperimentArchiveUnavailable)
+	})
+
+	t.Run("TransitionExperimentStatus kills paused experiments and revives resumed ones", func(t *testing.T) {
+		repo := &stubExperimentMutationRepository{state: &ExperimentMutationState{ID: experimentID, Status: "running"}}
+		switchboard := &stubExperimentSwitchboard{killed: map[uuid.UUID]bool{}}
+		svc := NewExperimentAdminService(repo).WithKillSwitch(switchboard)
+
+		require.NoError(t, svc.TransitionExperimentStatus(ctx, experimentID, "paused"))
+		assert.True(t, switchboard.killed[experimentID])
+
+		repo.state.Status = "paused"
+		require.NoError(t, svc.TransitionExperimentStatus(ctx, experimentID, "running"))
+		assert.False(t, switchboard.killed[experimentID])
+	})
+
+	t.Run("TransitionExperimentStatus revives the experiment when the pause is not saved", func(t *testing.T) {
+		repo := &stubExperimentMutationRepository{
+			state:     &ExperimentMutationState{ID: experimentID, Status: "running"},
+			statusErr: assert.AnError,
+		}
+		switchboard := &stubExperimentSwitchboard{killed: map[uuid.UUID]bool{}}
+		svc := NewExperimentAdminService(repo).WithKillSwitch(switchboard)
+
+		err := svc.TransitionExperimentStatus(ctx, experimentID, "paused")
+
+		require.ErrorIs(t, err, assert.AnError)
+		assert.False(t, switchboard.killed[experimentID])
+	})
+
 	t.Run("NormalizeExperimentAutomationPolicy applies defaults and preserves explicit flags", func(t *testing.T) {
 		policy := NormalizeExperimentAutomationPolicy(&ExperimentAutomationPolicy{
 			Enabled:              true,
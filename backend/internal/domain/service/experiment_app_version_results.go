@@ -0,0 +1,244 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/valueobject"
+)
+
+const (
+	// AppVersionUnknown groups assignments made without a known app version
+	AppVersionUnknown = "unknown"
+	// DefaultAppVersionMinUsersPerArm is how many users control and a variant
+	// each need on a version before its lift can flag a regression
+	DefaultAppVersionMinUsersPerArm = 100
+)
+
+// ExperimentAppVersionArmStats are an arm's results among users assigned on
+// one app version
+type ExperimentAppVersionArmStats struct {
+	AppVersion  string
+	ArmID       uuid.UUID
+	IsControl   bool
+	Users       int
+	Conversions int
+	Revenue     float64
+}
+
+// ExperimentAppVersionInput is an experiment's results split by app version
+type ExperimentAppVersionInput struct {
+	ExperimentID  uuid.UUID
+	ObjectiveType ObjectiveType
+	Stats         []ExperimentAppVersionArmStats
+}
+
+type ExperimentAppVersionArm struct {
+	ArmID       uuid.UUID `json:"arm_id"`
+	IsControl   bool      `json:"is_control"`
+	Users       int       `json:"users"`
+	Conversions int       `json:"conversions"`
+	Revenue     float64   `json:"revenue"`
+	MetricValue float64   `json:"metric_value"`
+	// LiftPercent is the change in the metric over control on the same version
+	LiftPercent *float64 `json:"lift_percent,omitempty"`
+	// Regression marks a variant that wins overall but loses on this version
+	Regression bool `json:"regression,omitempty"`
+}
+
+type ExperimentAppVersionSegment struct {
+	AppVersion string                    `json:"app_version"`
+	Users      int                       `json:"users"`
+	Arms       []ExperimentAppVersionArm `json:"arms"`
+	Regression bool                      `json:"regression"`
+}
+
+// ExperimentAppVersionReport compares an experiment's arms within each app
+// version, newest version first
+type ExperimentAppVersionReport struct {
+	ExperimentID   uuid.UUID                     `json:"experiment_id"`
+	Metric         string                        `json:"metric"`
+	MinUsersPerArm int                           `json:"min_users_per_arm"`
+	Overall        []ExperimentAppVersionArm     `json:"overall"`
+	Versions       []ExperimentAppVersionSegment `json:"versions"`
+	// Regressions lists the versions with a regressing variant
+	Regressions []string `json:"regressions"`
+}
+
+// SegmentExperimentByAppVersion compares each variant against control within
+// every app version on the experiment's primary metric: conversion rate for
+// conversion objectives, revenue per user otherwise. A variant regresses on a
+// version when its overall lift is positive but its lift there is negative,
+// with at least minUsersPerArm users on both it and control.
+func SegmentExperimentByAppVersion(input ExperimentAppVersionInput, minUsersPerArm int) ExperimentAppVersionReport {
+	metric := ExperimentMetricRevenuePerUser
+	if input.ObjectiveType == ObjectiveConversion || input.ObjectiveType == "" {
+		metric = ExperimentMetricConversionRate
+	}
+	report := ExperimentAppVersionReport{
+		ExperimentID:   input.ExperimentID,
+		Metric:         metric,
+		MinUsersPerArm: minUsersPerArm,
+		Overall:        []ExperimentAppVersionArm{},
+		Versions:       []ExperimentAppVersionSegment{},
+		Regressions:    []string{},
+	}
+
+	overall := map[uuid.UUID]*ExperimentAppVersionArm{}
+	byVersion := map[string]map[uuid.UUID]*ExperimentAppVersionArm{}
+	var armOrder []uuid.UUID
+	for _, stat := range input.Stats {
+		version := stat.AppVersion
+		if version == "" {
+			version = AppVersionUnknown
+		}
+		total, ok := overall[stat.ArmID]
+		if !ok {
+			total = &ExperimentAppVersionArm{ArmID: stat.ArmID, IsControl: stat.IsControl}
+			overall[stat.ArmID] = total
+			armOrder = append(armOrder, stat.ArmID)
+		}
+		total.Users += stat.Users
+		total.Conversions += stat.Conversions
+		total.Revenue += stat.Revenue
+
+		if byVersion[version] == nil {
+			byVersion[version] = map[uuid.UUID]*ExperimentAppVersionArm{}
+		}
+		arm, ok := byVersion[version][stat.ArmID]
+		if !ok {
+			arm = &ExperimentAppVersionArm{ArmID: stat.ArmID, IsControl: stat.IsControl}
+			byVersion[version][stat.ArmID] = arm
+		}
+		arm.Users += stat.Users
+		arm.Conversions += stat.Conversions
+		arm.Revenue += stat.Revenue
+	}
+	// Control first, then variants in the order they were first seen
+	sort.SliceStable(armOrder, func(i, j int) bool {
+		return overall[armOrder[i]].IsControl && !overall[armOrder[j]].IsControl
+	})
+
+	report.Overall = compareAppVersionArms(metric, armOrder, overall)
+	overallLift := make(map[uuid.UUID]*float64, len(report.Overall))
+	for _, arm := range report.Overall {
+		overallLift[arm.ArmID] = arm.LiftPercent
+	}
+
+	for version, arms := range byVersion {
+		segment := ExperimentAppVersionSegment{AppVersion: version, Arms: compareAppVersionArms(metric, armOrder, arms)}
+		var controlUsers int
+		for _, arm := range segment.Arms {
+			segment.Users += arm.Users
+			if arm.IsControl {
+				controlUsers = arm.Users
+			}
+		}
+		for i := range segment.Arms {
+			arm := &segment.Arms[i]
+			wins := overallLift[arm.ArmID]
+			if arm.IsControl || arm.LiftPercent == nil || wins == nil {
+				continue
+			}
+			if *wins > 0 && *arm.LiftPercent < 0 && arm.Users >= minUsersPerArm && controlUsers >= minUsersPerArm {
+				arm.Regression = true
+				segment.Regression = true
+			}
+		}
+		report.Versions = append(report.Versions, segment)
+	}
+	sort.Slice(report.Versions, func(i, j int) bool {
+		return appVersionNewer(report.Versions[i].AppVersion, report.Versions[j].AppVersion)
+	})
+	for _, segment := range report.Versions {
+		if segment.Regression {
+			report.Regressions = append(report.Regressions, segment.AppVersion)
+		}
+	}
+	return report
+}
+
+// compareAppVersionArms fills in each arm's metric and lift over control
+func compareAppVersionArms(metric string, order []uuid.UUID, arms map[uuid.UUID]*ExperimentAppVersionArm) []ExperimentAppVersionArm {
+	compared := make([]ExperimentAppVersionArm, 0, len(arms))
+	var control *ExperimentAppVersionArm
+	for _, id := range order {
+		arm, ok := arms[id]
+		if !ok {
+			continue
+		}
+		if metric == ExperimentMetricConversionRate {
+			arm.MetricValue = perSample(float64(arm.Conversions), arm.Users)
+		} else {
+			arm.MetricValue = perSample(arm.Revenue, arm.Users)
+		}
+		if arm.IsControl && control == nil {
+			control = arm
+		}
+		compared = append(compared, *arm)
+	}
+	if control == nil || control.Users == 0 || control.MetricValue == 0 {
+		return compared
+	}
+	for i := range compared {
+		if compared[i].IsControl || compared[i].Users == 0 {
+			continue
+		}
+		lift := (compared[i].MetricValue - control.MetricValue) / control.MetricValue * 100
+		compared[i].LiftPercent = &lift
+	}
+	return compared
+}
+
+// appVersionNewer orders semantic versions newest first, then anything else
+// by name, with unknown last
+func appVersionNewer(a, b string) bool {
+	if a == AppVersionUnknown || b == AppVersionUnknown {
+		return b == AppVersionUnknown && a != AppVersionUnknown
+	}
+	va, errA := valueobject.ParseAppVersion(a)
+	vb, errB := valueobject.ParseAppVersion(b)
+	switch {
+	case errA == nil && errB == nil:
+		if c := va.Compare(vb); c != 0 {
+			return c > 0
+		}
+		return a < b
+	case errA == nil:
+		return true
+	case errB == nil:
+		return false
+	}
+	return a < b
+}
+
+type ExperimentAppVersionRepository interface {
+	ListExperimentAppVersionStats(ctx context.Context, experimentID uuid.UUID) (*ExperimentAppVersionInput, error)
+}
+
+// ExperimentAppVersionService segments experiment results by the app version
+// users were assigned on, to catch version-specific regressions
+type ExperimentAppVersionService struct {
+	repo ExperimentAppVersionRepository
+}
+
+func NewExperimentAppVersionService(repo ExperimentAppVersionRepository) *ExperimentAppVersionService {
+	return &ExperimentAppVersionService{repo: repo}
+}
+
+// Report returns the experiment's results by app version. It returns
+// ErrExperimentNotFound for an unknown experiment.
+func (s *ExperimentAppVersionService) Report(ctx context.Context, experimentID uuid.UUID, minUsersPerArm int) (*ExperimentAppVersionReport, error) {
+	input, err := s.repo.ListExperimentAppVersionStats(ctx, experimentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list experiment app version stats: %w", err)
+	}
+	if input == nil {
+		return nil, ErrExperimentNotFound
+	}
+	report := SegmentExperimentByAppVersion(*input, minUsersPerArm)
+	return &report, nil
+}
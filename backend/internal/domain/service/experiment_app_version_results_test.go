@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSegmentExperimentByAppVersionFlagsRegressions(t *testing.T) {
+	control, variant := uuid.New(), uuid.New()
+	input := ExperimentAppVersionInput{
+		ExperimentID:  uuid.New(),
+		ObjectiveType: ObjectiveConversion,
+		Stats: []ExperimentAppVersionArmStats{
+			{AppVersion: "3.9.0", ArmID: variant, Users: 500, Conversions: 75},
+			{AppVersion: "3.9.0", ArmID: control, IsControl: true, Users: 500, Conversions: 50},
+			{AppVersion: "3.10.0", ArmID: control, IsControl: true, Users: 200, Conversions: 20},
+			{AppVersion: "3.10.0", ArmID: variant, Users: 200, Conversions: 12},
+			{AppVersion: "", ArmID: control, IsControl: true, Users: 10, Conversions: 2},
+			{AppVersion: "", ArmID: variant, Users: 10, Conversions: 0},
+		},
+	}
+
+	report := SegmentExperimentByAppVersion(input, DefaultAppVersionMinUsersPerArm)
+
+	assert.Equal(t, ExperimentMetricConversionRate, report.Metric)
+	require.Len(t, report.Overall, 2)
+	assert.Equal(t, control, report.Overall[0].ArmID, "control comes first")
+	require.NotNil(t, report.Overall[1].LiftPercent)
+	assert.Greater(t, *report.Overall[1].LiftPercent, 0.0)
+
+	require.Len(t, report.Versions, 3)
+	assert.Equal(t, []string{"3.10.0", "3.9.0", AppVersionUnknown}, []string{
+		report.Versions[0].AppVersion, report.Versions[1].AppVersion, report.Versions[2].AppVersion,
+	})
+	newest := report.Versions[0]
+	assert.True(t, newest.Regression)
+	assert.Equal(t, 400, newest.Users)
+	assert.InDelta(t, -40, *newest.Arms[1].LiftPercent, 1e-9)
+	assert.True(t, newest.Arms[1].Regression)
+	assert.False(t, report.Versions[1].Regression)
+	assert.False(t, report.Versions[2].Regression, "too few users to flag a regression")
+	assert.Equal(t, []string{"3.10.0"}, report.Regressions)
+}
+
+type stubExperimentAppVersionRepo struct {
+	input *ExperimentAppVersionInput
+}
+
+func (s stubExperimentAppVersionRepo) ListExperimentAppVersionStats(ctx context.Context, experimentID uuid.UUID) (*ExperimentAppVersionInput, error) {
+	return s.input, nil
+}
+
+func TestExperimentAppVersionServiceReport(t *testing.T) {
+	_, err := NewExperimentAppVersionService(stubExperimentAppVersionRepo{}).Report(context.Background(), uuid.New(), 1)
+	assert.ErrorIs(t, err, ErrExperimentNotFound)
+
+	experimentID := uuid.New()
+	report, err := NewExperimentAppVersionService(stubExperimentAppVersionRepo{input: &ExperimentAppVersionInput{
+		ExperimentID:  experimentID,
+		ObjectiveType: ObjectiveRevenue,
+	}}).Report(context.Background(), experimentID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, ExperimentMetricRevenuePerUser, report.Metric)
+	assert.Empty(t, report.Versions)
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidArmPayload = errors.New("invalid arm payload")
+
+const (
+	maxArmPayloadFeatures   = 10
+	maxArmPayloadProperties = 32
+)
+
+var (
+	armPayloadPriceIDPattern  = regexp.MustCompile(`^[A-Za-z0-9._:\-]{1,128}$`)
+	armPayloadLayoutPattern   = regexp.MustCompile(`^[a-z0-9][a-z0-9_\-]{0,63}$`)
+	armPayloadPropertyPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{0,63}$`)
+)
+
+// ArmPayloadCopy is the paywall copy an arm shows
+type ArmPayloadCopy struct {
+	Title    string   `json:"title,omitempty"`
+	Subtitle string   `json:"subtitle,omitempty"`
+	CTA      string   `json:"cta,omitempty"`
+	Features []string `json:"features,omitempty"`
+}
+
+// ArmPayload is the variant content a client renders for an arm. Properties
+// holds app-specific flat values: strings, numbers and booleans.
+type ArmPayload struct {
+	Copy       *ArmPayloadCopy        `json:"copy,omitempty"`
+	PriceID    string                 `json:"price_id,omitempty"`
+	ImageURL   string                 `json:"image_url,omitempty"`
+	LayoutKey  string                 `json:"layout_key,omitempty"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
+func (p ArmPayload) Validate() error {
+	if p.Copy != nil {
+		if err := validateArmPayloadText("copy.title", p.Copy.Title, 120); err != nil {
+			return err
+		}
+		if err := validateArmPayloadText("copy.subtitle", p.Copy.Subtitle, 240); err != nil {
+			return err
+		}
+		if err := validateArmPayloadText("copy.cta", p.Copy.CTA, 40); err != nil {
+			return err
+		}
+		if len(p.Copy.Features) > maxArmPayloadFeatures {
+			return fmt.Errorf("%w: copy.features allows at most %d entries", ErrInvalidArmPayload, maxArmPayloadFeatures)
+		}
+		for _, feature := range p.Copy.Features {
+			if err := validateArmPayloadText("copy.features", feature, 120); err != nil {
+				return err
+			}
+		}
+	}
+	if p.PriceID != "" && !armPayloadPriceIDPattern.MatchString(p.PriceID) {
+		return fmt.Errorf("%w: price_id must be up to 128 letters, digits or ._:-", ErrInvalidArmPayload)
+	}
+	if p.ImageURL != "" {
+		parsed, err := url.Parse(p.ImageURL)
+		if err != nil || parsed.Scheme != "https" || parsed.Host == "" || len(p.ImageURL) > 2048 {
+			return fmt.Errorf("%w: image_url must be an absolute https URL", ErrInvalidArmPayload)
+		}
+	}
+	if p.LayoutKey != "" && !armPayloadLayoutPattern.MatchString(p.LayoutKey) {
+		return fmt.Errorf("%w: layout_key must be up to 64 lowercase letters, digits, _ or -", ErrInvalidArmPayload)
+	}
+	if len(p.Properties) > maxArmPayloadProperties {
+		return fmt.Errorf("%w: properties allows at most %d keys", ErrInvalidArmPayload, maxArmPayloadProperties)
+	}
+	for key, value := range p.Properties {
+		if !armPayloadPropertyPattern.MatchString(key) {
+			return fmt.Errorf("%w: property key %q must start with a letter and use letters, digits or _", ErrInvalidArmPayload, key)
+		}
+		switch v := value.(type) {
+		case string:
+			if err := validateArmPayloadText("properties."+key, v, 1024); err != nil {
+				return err
+			}
+		case float64, bool:
+		default:
+			return fmt.Errorf("%w: property %q must be a string, number or boolean", ErrInvalidArmPayload, key)
+		}
+	}
+	return nil
+}
+
+func validateArmPayloadText(field, value string, max int) error {
+	if !utf8.ValidString(value) || utf8.RuneCountInString(value) > max {
+		return fmt.Errorf("%w: %s must be valid text of at most %d characters", ErrInvalidArmPayload, field, max)
+	}
+	if containsControlRune(value) {
+		return fmt.Errorf("%w: %s cannot contain control characters", ErrInvalidArmPayload, field)
+	}
+	return nil
+}
+
+func containsControlRune(value string) bool {
+	for _, r := range value {
+		if (r < 0x20 && r != '\n') || r == 0x7f {
+			return true
+		}
+	}
+	return false
+}
+
+// UserExperimentAssignment is a user's live assignment in a running
+// experiment, with the arm's variant content, so a client can bootstrap every
+// variant at once
+type UserExperimentAssignment struct {
+	ExperimentID   uuid.UUID   `json:"experiment_id"`
+	ExperimentName string      `json:"experiment_name"`
+	ArmID          uuid.UUID   `json:"arm_id"`
+	ArmName        string      `json:"arm_name"`
+	IsControl      bool        `json:"is_control"`
+	Payload        *ArmPayload `json:"payload,omitempty"`
+	AssignedAt     time.Time   `json:"assigned_at"`
+	ExpiresAt      time.Time   `json:"expires_at"`
+}
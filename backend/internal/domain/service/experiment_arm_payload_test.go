@@ -0,0 +1,33 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArmPayloadValidate(t *testing.T) {
+	valid := ArmPayload{
+		Copy:       &ArmPayloadCopy{Title: "Go Pro", CTA: "Start trial", Features: []string{"No ads", "Offline mode"}},
+		PriceID:    "com.app.pro.monthly",
+		ImageURL:   "https://cdn.example.com/paywall/hero.png",
+		LayoutKey:  "hero-v2",
+		Properties: map[string]interface{}{"show_close_after": 3.0, "dark": true, "badge": "Popular"},
+	}
+	assert.NoError(t, valid.Validate())
+
+	cases := map[string]ArmPayload{
+		"http image":    {ImageURL: "http://cdn.example.com/hero.png"},
+		"relative URL":  {ImageURL: "/hero.png"},
+		"layout case":   {LayoutKey: "Hero"},
+		"price spaces":  {PriceID: "pro monthly"},
+		"long cta":      {Copy: &ArmPayloadCopy{CTA: "Start your free trial today and never look back"}},
+		"control char":  {Copy: &ArmPayloadCopy{Title: "Go\x00Pro"}},
+		"nested value":  {Properties: map[string]interface{}{"colors": map[string]interface{}{"bg": "#000"}}},
+		"bad key":       {Properties: map[string]interface{}{"1st": "x"}},
+		"many features": {Copy: &ArmPayloadCopy{Features: make([]string, 11)}},
+	}
+	for name, payload := range cases {
+		assert.ErrorIs(t, payload.Validate(), ErrInvalidArmPayload, name)
+	}
+}
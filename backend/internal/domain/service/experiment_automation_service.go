@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -32,11 +33,15 @@ type ExperimentAutomationRunResult struct {
 	Started   []uuid.UUID
 	Completed []uuid.UUID
 	Skipped   int
+	// GuardrailStopped lists the discount arms archived for breaching their
+	// experiment's revenue guardrail
+	GuardrailStopped []uuid.UUID
 }
 
 type ExperimentAutomationReconciler struct {
 	repo        ExperimentAutomationRepository
 	transitions ExperimentStatusTransitioner
+	guardrails  ExperimentGuardrailRepository
 	now         func() time.Time
 }
 
@@ -48,6 +53,13 @@ func NewExperimentAutomationReconciler(repo ExperimentAutomationRepository, tran
 	}
 }
 
+// WithRevenueGuardrails makes each run archive running discount arms that
+// breach their experiment's revenue guardrail
+func (r *ExperimentAutomationReconciler) WithRevenueGuardrails(repo ExperimentGuardrailRepository) *ExperimentAutomationReconciler {
+	r.guardrails = repo
+	return r
+}
+
 func (r *ExperimentAutomationReconciler) Reconcile(ctx context.Context) (ExperimentAutomationRunResult, error) {
 	states, err := r.repo.ListExperimentAutomationStates(ctx)
 	if err != nil {
@@ -85,9 +97,57 @@ func (r *ExperimentAutomationReconciler) Reconcile(ctx context.Context) (Experim
 		}
 	}
 
+	if r.guardrails != nil {
+		stopped, err := r.enforceRevenueGuardrails(ctx, now)
+		result.GuardrailStopped = stopped
+		if err != nil {
+			return result, err
+		}
+	}
+
 	return result, nil
 }
 
+// enforceRevenueGuardrails archives breaching arms with the reassign policy,
+// so their users move to a live arm. Guardrails are a safety stop and apply
+// whether or not status automation is enabled.
+func (r *ExperimentAutomationReconciler) enforceRevenueGuardrails(ctx context.Context, now time.Time) ([]uuid.UUID, error) {
+	states, err := r.guardrails.ListExperimentDiscountGuardrailStates(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list experiment guardrail states: %w", err)
+	}
+
+	var stopped []uuid.UUID
+	for _, state := range states {
+		report := EvaluateDiscountGuardrail(&state.Guardrail, state.Arms)
+		if report == nil {
+			continue
+		}
+		for _, impact := range report.Arms {
+			if impact.GuardrailStatus != GuardrailStatusBreached {
+				continue
+			}
+			details := map[string]interface{}{
+				"reason":                         ArmArchiveReasonGuardrail,
+				"arm_id":                         impact.ArmID.String(),
+				"projected_revenue_loss_percent": impact.ProjectedRevenueLossPercent,
+				"max_revenue_loss_percent":       state.Guardrail.MaxRevenueLossPercent,
+				"samples":                        impact.Samples,
+				"revenue_per_user":               impact.RevenuePerUser,
+				"control_revenue_per_user":       impact.ControlRevenuePerUser,
+			}
+			if err := r.guardrails.StopExperimentArmForGuardrail(ctx, state.ID, impact.ArmID, now, details); err != nil {
+				if errors.Is(err, ErrLastExperimentArm) || errors.Is(err, ErrExperimentArmArchived) {
+					continue
+				}
+				return stopped, fmt.Errorf("failed to stop arm %s of experiment %s: %w", impact.ArmID, state.ID, err)
+			}
+			stopped = append(stopped, impact.ArmID)
+		}
+	}
+	return stopped, nil
+}
+
 type experimentAutomationDecision struct {
 	NextStatus string
 	Reason     string
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Discount types an experiment arm may offer
+const (
+	ArmDiscountPercent = "percent"
+	ArmDiscountFixed   = "fixed"
+)
+
+// Guardrail statuses reported for discount arms, and for the experiment as
+// the worst of its arms
+const (
+	GuardrailStatusNotConfigured    = "not_configured"
+	GuardrailStatusInsufficientData = "insufficient_data"
+	GuardrailStatusOK               = "ok"
+	GuardrailStatusBreached         = "breached"
+	GuardrailStatusStopped          = "stopped"
+)
+
+// ArmArchiveReasonGuardrail marks an arm the revenue guardrail archived
+const ArmArchiveReasonGuardrail = "revenue_guardrail"
+
+const defaultRevenueGuardrailMinSamples = 100
+
+var (
+	ErrInvalidArmDiscount      = errors.New("invalid arm discount")
+	ErrInvalidRevenueGuardrail = errors.New("invalid revenue guardrail")
+)
+
+// ArmDiscount is the discount an experiment arm offers. DurationPeriods is
+// how many billing periods it applies to; zero means the first only.
+type ArmDiscount struct {
+	Type            string  `json:"type"`
+	Value           float64 `json:"value"`
+	CouponCode      string  `json:"coupon_code,omitempty"`
+	DurationPeriods int     `json:"duration_periods,omitempty"`
+}
+
+func (d ArmDiscount) Validate() error {
+	switch d.Type {
+	case ArmDiscountPercent:
+		if d.Value <= 0 || d.Value > 100 {
+			return fmt.Errorf("%w: percent discount must be between 0 and 100", ErrInvalidArmDiscount)
+		}
+	case ArmDiscountFixed:
+		if d.Value <= 0 {
+			return fmt.Errorf("%w: fixed discount must be greater than zero", ErrInvalidArmDiscount)
+		}
+	default:
+		return fmt.Errorf("%w: type must be percent or fixed", ErrInvalidArmDiscount)
+	}
+	if d.DurationPeriods < 0 {
+		return fmt.Errorf("%w: duration_periods cannot be negative", ErrInvalidArmDiscount)
+	}
+	if len(d.CouponCode) > 64 || strings.ContainsAny(d.CouponCode, " \t\r\n") {
+		return fmt.Errorf("%w: coupon_code must be at most 64 characters without whitespace", ErrInvalidArmDiscount)
+	}
+	return nil
+}
+
+// RevenueGuardrail caps the revenue-per-user loss a discount arm may project
+// against control. Arms are judged once both they and control have
+// MinSamples samples.
+type RevenueGuardrail struct {
+	Enabled               bool    `json:"enabled"`
+	MaxRevenueLossPercent float64 `json:"max_revenue_loss_percent"`
+	MinSamples            int     `json:"min_samples"`
+}
+
+func (g RevenueGuardrail) Validate() error {
+	if g.MaxRevenueLossPercent <= 0 || g.MaxRevenueLossPercent > 100 {
+		return fmt.Errorf("%w: max_revenue_loss_percent must be between 0 and 100", ErrInvalidRevenueGuardrail)
+	}
+	if g.MinSamples < 0 {
+		return fmt.Errorf("%w: min_samples cannot be negative", ErrInvalidRevenueGuardrail)
+	}
+	return nil
+}
+
+func (g RevenueGuardrail) minSamples() int {
+	if g.MinSamples <= 0 {
+		return defaultRevenueGuardrailMinSamples
+	}
+	return g.MinSamples
+}
+
+// DiscountGuardrailArm is the observed performance of one experiment arm
+type DiscountGuardrailArm struct {
+	ArmID         uuid.UUID
+	IsControl     bool
+	Discount      *ArmDiscount
+	Samples       int
+	Conversions   int
+	Revenue       float64
+	Archived      bool
+	ArchiveReason string
+}
+
+// DiscountArmImpact compares a discount arm with control. Lift and impact
+// are relative to control and nil while control has no conversions or
+// revenue. ProjectedRevenueLossPercent is the revenue-per-user shortfall the
+// arm would cost if it took all traffic; ProjectedRevenueLoss is what its
+// own samples have cost so far.
+type DiscountArmImpact struct {
+	ArmID                       uuid.UUID   `json:"arm_id"`
+	Discount                    ArmDiscount `json:"discount"`
+	Samples                     int         `json:"samples"`
+	ConversionRate              float64     `json:"conversion_rate"`
+	ControlConversionRate       float64     `json:"control_conversion_rate"`
+	ConversionLiftPercent       *float64    `json:"conversion_lift_percent"`
+	RevenuePerUser              float64     `json:"revenue_per_user"`
+	ControlRevenuePerUser       float64     `json:"control_revenue_per_user"`
+	RevenuePerUserImpactPercent *float64    `json:"revenue_per_user_impact_percent"`
+	ProjectedRevenueLossPercent float64     `json:"projected_revenue_loss_percent"`
+	ProjectedRevenueLoss        float64     `json:"projected_revenue_loss"`
+	GuardrailStatus             string      `json:"guardrail_status"`
+}
+
+// DiscountGuardrailReport is the discount impact of an experiment
+type DiscountGuardrailReport struct {
+	Guardrail *RevenueGuardrail   `json:"guardrail,omitempty"`
+	Status    string              `json:"status"`
+	Arms      []DiscountArmImpact `json:"arms"`
+}
+
+var guardrailStatusSeverity = map[string]int{
+	GuardrailStatusNotConfigured:    0,
+	GuardrailStatusOK:               1,
+	GuardrailStatusInsufficientData: 2,
+	GuardrailStatusStopped:          3,
+	GuardrailStatusBreached:         4,
+}
+
+// EvaluateDiscountGuardrail measures every discount arm against control and
+// applies guardrail, which may be nil. It returns nil when the experiment
+// has no control or no discount arms. Arms archived for another reason are
+// left out.
+func EvaluateDiscountGuardrail(guardrail *RevenueGuardrail, arms []DiscountGuardrailArm) *DiscountGuardrailReport {
+	var control *DiscountGuardrailArm
+	for i := range arms {
+		if arms[i].IsControl {
+			control = &arms[i]
+			break
+		}
+	}
+	if control == nil {
+		return nil
+	}
+	active := guardrail != nil && guardrail.Enabled
+
+	report := &DiscountGuardrailReport{Status: GuardrailStatusNotConfigured}
+	if active {
+		report.Guardrail = guardrail
+		report.Status = GuardrailStatusOK
+	}
+	controlRate := perSample(float64(control.Conversions), control.Samples)
+	controlRPU := perSample(control.Revenue, control.Samples)
+	for _, arm := range arms {
+		if arm.IsControl || arm.Discount == nil {
+			continue
+		}
+		stopped := arm.Archived && arm.ArchiveReason == ArmArchiveReasonGuardrail
+		if arm.Archived && !stopped {
+			continue
+		}
+
+		impact := DiscountArmImpact{
+			ArmID:                 arm.ArmID,
+			Discount:              *arm.Discount,
+			Samples:               arm.Samples,
+			ConversionRate:        perSample(float64(arm.Conversions), arm.Samples),
+			ControlConversionRate: controlRate,
+			RevenuePerUser:        perSample(arm.Revenue, arm.Samples),
+			ControlRevenuePerUser: controlRPU,
+		}
+		if controlRate > 0 {
+			lift := (impact.ConversionRate/controlRate - 1) * 100
+			impact.ConversionLiftPercent = &lift
+		}
+		if controlRPU > 0 {
+			rpuImpact := (impact.RevenuePerUser/controlRPU - 1) * 100
+			impact.RevenuePerUserImpactPercent = &rpuImpact
+			if rpuImpact < 0 {
+				impact.ProjectedRevenueLossPercent = -rpuImpact
+				impact.ProjectedRevenueLoss = (controlRPU - impact.RevenuePerUser) * float64(arm.Samples)
+			}
+		}
+
+		switch {
+		case stopped:
+			impact.GuardrailStatus = GuardrailStatusStopped
+		case !active:
+			impact.GuardrailStatus = GuardrailStatusNotConfigured
+		case arm.Samples < guardrail.minSamples() || control.Samples < guardrail.minSamples() || controlRPU <= 0:
+			impact.GuardrailStatus = GuardrailStatusInsufficientData
+		case impact.ProjectedRevenueLossPercent > guardrail.MaxRevenueLossPercent:
+			impact.GuardrailStatus = GuardrailStatusBreached
+		default:
+			impact.GuardrailStatus = GuardrailStatusOK
+		}
+		if guardrailStatusSeverity[impact.GuardrailStatus] > guardrailStatusSeverity[report.Status] {
+			report.Status = impact.GuardrailStatus
+		}
+		report.Arms = append(report.Arms, impact)
+	}
+	if len(report.Arms) == 0 {
+		return nil
+	}
+	return report
+}
+
+func perSample(value float64, samples int) float64 {
+	if samples <= 0 {
+		return 0
+	}
+	return value / float64(samples)
+}
+
+// ExperimentDiscountGuardrailState is a running experiment with a revenue
+// guardrail, for the automation reconciler
+type ExperimentDiscountGuardrailState struct {
+	ID        uuid.UUID
+	Guardrail RevenueGuardrail
+	Arms      []DiscountGuardrailArm
+}
+
+type ExperimentGuardrailRepository interface {
+	ListExperimentDiscountGuardrailStates(ctx context.Context) ([]ExperimentDiscountGuardrailState, error)
+	StopExperimentArmForGuardrail(ctx context.Context, experimentID, armID uuid.UUID, stoppedAt time.Time, details map[string]interface{}) error
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func discountExperimentArms(discountRevenue float64) (control, discounted DiscountGuardrailArm) {
+	control = DiscountGuardrailArm{ArmID: uuid.New(), IsControl: true, Samples: 1000, Conversions: 50, Revenue: 500}
+	discounted = DiscountGuardrailArm{
+		ArmID:       uuid.New(),
+		Discount:    &ArmDiscount{Type: ArmDiscountPercent, Value: 30, CouponCode: "SPRING30"},
+		Samples:     1000,
+		Conversions: 70,
+		Revenue:     discountRevenue,
+	}
+	return control, discounted
+}
+
+func TestEvaluateDiscountGuardrailReportsLiftAndRevenueImpact(t *testing.T) {
+	control, discounted := discountExperimentArms(400)
+	guardrail := &RevenueGuardrail{Enabled: true, MaxRevenueLossPercent: 25, MinSamples: 500}
+
+	report := EvaluateDiscountGuardrail(guardrail, []DiscountGuardrailArm{control, discounted})
+
+	require.NotNil(t, report)
+	assert.Equal(t, GuardrailStatusOK, report.Status)
+	require.Len(t, report.Arms, 1)
+	impact := report.Arms[0]
+	assert.InDelta(t, 0.07, impact.ConversionRate, 1e-9)
+	require.NotNil(t, impact.ConversionLiftPercent)
+	assert.InDelta(t, 40, *impact.ConversionLiftPercent, 1e-9)
+	assert.InDelta(t, 0.4, impact.RevenuePerUser, 1e-9)
+	require.NotNil(t, impact.RevenuePerUserImpactPercent)
+	assert.InDelta(t, -20, *impact.RevenuePerUserImpactPercent, 1e-9)
+	assert.InDelta(t, 20, impact.ProjectedRevenueLossPercent, 1e-9)
+	assert.InDelta(t, 100, impact.ProjectedRevenueLoss, 1e-9)
+	assert.Equal(t, GuardrailStatusOK, impact.GuardrailStatus)
+}
+
+func TestEvaluateDiscountGuardrailStatuses(t *testing.T) {
+	guardrail := &RevenueGuardrail{Enabled: true, MaxRevenueLossPercent: 10, MinSamples: 500}
+
+	t.Run("breached", func(t *testing.T) {
+		control, discounted := discountExperimentArms(400)
+		report := EvaluateDiscountGuardrail(guardrail, []DiscountGuardrailArm{control, discounted})
+		assert.Equal(t, GuardrailStatusBreached, report.Status)
+		assert.Equal(t, GuardrailStatusBreached, report.Arms[0].GuardrailStatus)
+	})
+
+	t.Run("insufficient data", func(t *testing.T) {
+		control, discounted := discountExperimentArms(40)
+		discounted.Samples = 100
+		report := EvaluateDiscountGuardrail(guardrail, []DiscountGuardrailArm{control, discounted})
+		assert.Equal(t, GuardrailStatusInsufficientData, report.Arms[0].GuardrailStatus)
+	})
+
+	t.Run("stopped", func(t *testing.T) {
+		control, discounted := discountExperimentArms(400)
+		discounted.Archived, discounted.ArchiveReason = true, ArmArchiveReasonGuardrail
+		report := EvaluateDiscountGuardrail(guardrail, []DiscountGuardrailArm{control, discounted})
+		assert.Equal(t, GuardrailStatusStopped, report.Status)
+	})
+
+	t.Run("not configured", func(t *testing.T) {
+		control, discounted := discountExperimentArms(400)
+		report := EvaluateDiscountGuardrail(nil, []DiscountGuardrailArm{control, discounted})
+		assert.Equal(t, GuardrailStatusNotConfigured, report.Status)
+		assert.Nil(t, report.Guardrail)
+		assert.InDelta(t, 20, report.Arms[0].ProjectedRevenueLossPercent, 1e-9)
+	})
+
+	t.Run("no discount arms", func(t *testing.T) {
+		control, other := discountExperimentArms(400)
+		other.Discount = nil
+		assert.Nil(t, EvaluateDiscountGuardrail(guardrail, []DiscountGuardrailArm{control, other}))
+	})
+}
+
+func TestArmDiscountValidate(t *testing.T) {
+	assert.NoError(t, ArmDiscount{Type: ArmDiscountFixed, Value: 2.5, DurationPeriods: 3}.Validate())
+	assert.ErrorIs(t, ArmDiscount{Type: ArmDiscountPercent, Value: 120}.Validate(), ErrInvalidArmDiscount)
+	assert.ErrorIs(t, ArmDiscount{Type: "bogo", Value: 1}.Validate(), ErrInvalidArmDiscount)
+	assert.ErrorIs(t, ArmDiscount{Type: ArmDiscountPercent, Value: 10, CouponCode: "TWO WORDS"}.Validate(), ErrInvalidArmDiscount)
+	assert.ErrorIs(t, RevenueGuardrail{Enabled: true}.Validate(), ErrInvalidRevenueGuardrail)
+}
+
+type stubGuardrailRepository struct {
+	states  []ExperimentDiscountGuardrailState
+	stopped map[uuid.UUID]map[string]interface{}
+	err     error
+}
+
+func (s *stubGuardrailRepository) ListExperimentDiscountGuardrailStates(context.Context) ([]ExperimentDiscountGuardrailState, error) {
+	return s.states, nil
+}
+
+func (s *stubGuardrailRepository) StopExperimentArmForGuardrail(_ context.Context, _, armID uuid.UUID, _ time.Time, details map[string]interface{}) error {
+	if s.err != nil {
+		return s.err
+	}
+	if s.stopped == nil {
+		s.stopped = make(map[uuid.UUID]map[string]interface{})
+	}
+	s.stopped[armID] = details
+	return nil
+}
+
+type emptyAutomationRepository struct{}
+
+func (emptyAutomationRepository) ListExperimentAutomationStates(context.Context) ([]ExperimentAutomationState, error) {
+	return nil, nil
+}
+
+func TestExperimentAutomationReconcilerStopsBreachingDiscountArms(t *testing.T) {
+	control, breaching := discountExperimentArms(400)
+	_, healthy := discountExperimentArms(480)
+	repo := &stubGuardrailRepository{states: []ExperimentDiscountGuardrailState{{
+		ID:        uuid.New(),
+		Guardrail: RevenueGuardrail{Enabled: true, MaxRevenueLossPercent: 10, MinSamples: 500},
+		Arms:      []DiscountGuardrailArm{control, breaching, healthy},
+	}}}
+	reconciler := NewExperimentAutomationReconciler(emptyAutomationRepository{}, nil).WithRevenueGuardrails(repo)
+
+	result, err := reconciler.Reconcile(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{breaching.ArmID}, result.GuardrailStopped)
+	require.Contains(t, repo.stopped, breaching.ArmID)
+	assert.Equal(t, ArmArchiveReasonGuardrail, repo.stopped[breaching.ArmID]["reason"])
+	assert.NotContains(t, repo.stopped, healthy.ArmID)
+}
+
+func TestExperimentAutomationReconcilerSkipsLastLiveArm(t *testing.T) {
+	control, breaching := discountExperimentArms(400)
+	repo := &stubGuardrailRepository{
+		states: []ExperimentDiscountGuardrailState{{
+			ID:        uuid.New(),
+			Guardrail: RevenueGuardrail{Enabled: true, MaxRevenueLossPercent: 10},
+			Arms:      []DiscountGuardrailArm{control, breaching},
+		}},
+		err: ErrLastExperimentArm,
+	}
+	reconciler := NewExperimentAutomationReconciler(emptyAutomationRepository{}, nil).WithRevenueGuardrails(repo)
+
+	result, err := reconciler.Reconcile(context.Background())
+
+	require.NoError(t, err)
+	assert.Empty(t, result.GuardrailStopped)
+}
@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+	"github.com/bivex/paywall-iap/internal/domain/valueobject"
+)
+
+var ErrInvalidExperimentEligibility = errors.New("invalid experiment eligibility")
+
+// Reasons a user is not eligible for an experiment
+const (
+	EligibilityReasonCountry    = "country_not_allowed"
+	EligibilityReasonPlatform   = "platform_not_allowed"
+	EligibilityReasonAppVersion = "app_version_not_allowed"
+	EligibilityReasonOSVersion  = "os_version_too_old"
+)
+
+var (
+	countryCodePattern      = regexp.MustCompile(`^[A-Z]{2}$`)
+	appVersionPatternSyntax = regexp.MustCompile(`^[0-9A-Za-z.\-+]+(\.\*)?$`)
+	experimentPlatforms     = map[string]bool{"ios": true, "android": true, "web": true}
+)
+
+// ExperimentEligibility restricts who may enter an experiment. Empty lists
+// allow everyone. AppVersions holds exact versions or prefixes such as
+// "3.2.*"; AppVersionRange is a semver constraint such as ">=3.2.0 <4";
+// MinOSVersions is keyed by platform.
+type ExperimentEligibility struct {
+	Countries       []string          `json:"countries,omitempty"`
+	Platforms       []string          `json:"platforms,omitempty"`
+	AppVersions     []string          `json:"app_versions,omitempty"`
+	AppVersionRange string            `json:"app_version_range,omitempty"`
+	MinOSVersions   map[string]string `json:"min_os_versions,omitempty"`
+}
+
+// Normalize uppercases countries and lowercases platforms
+func (e ExperimentEligibility) Normalize() ExperimentEligibility {
+	normalized := e
+	normalized.AppVersionRange = strings.TrimSpace(e.AppVersionRange)
+	normalized.Countries = make([]string, 0, len(e.Countries))
+	for _, country := range e.Countries {
+		normalized.Countries = append(normalized.Countries, strings.ToUpper(strings.TrimSpace(country)))
+	}
+	normalized.Platforms = make([]string, 0, len(e.Platforms))
+	for _, platform := range e.Platforms {
+		normalized.Platforms = append(normalized.Platforms, strings.ToLower(strings.TrimSpace(platform)))
+	}
+	if len(e.MinOSVersions) > 0 {
+		normalized.MinOSVersions = make(map[string]string, len(e.MinOSVersions))
+		for platform, version := range e.MinOSVersions {
+			normalized.MinOSVersions[strings.ToLower(strings.TrimSpace(platform))] = strings.TrimSpace(version)
+		}
+	}
+	return normalized
+}
+
+func (e ExperimentEligibility) Validate() error {
+	for _, country := range e.Countries {
+		if !countryCodePattern.MatchString(country) {
+			return fmt.Errorf("%w: countries must be ISO 3166-1 alpha-2 codes", ErrInvalidExperimentEligibility)
+		}
+	}
+	for _, platform := range e.Platforms {
+		if !experimentPlatforms[platform] {
+			return fmt.Errorf("%w: platforms must be ios, android or web", ErrInvalidExperimentEligibility)
+		}
+	}
+	for _, version := range e.AppVersions {
+		if len(version) > 32 || !appVersionPatternSyntax.MatchString(version) {
+			return fmt.Errorf("%w: app_versions must be versions such as 3.2.0 or prefixes such as 3.2.*", ErrInvalidExperimentEligibility)
+		}
+	}
+	if e.AppVersionRange != "" {
+		if _, err := valueobject.ParseVersionConstraint(e.AppVersionRange); err != nil {
+			return fmt.Errorf("%w: app_version_range must be a semver constraint such as >=3.2.0", ErrInvalidExperimentEligibility)
+		}
+	}
+	for platform, version := range e.MinOSVersions {
+		if !experimentPlatforms[platform] {
+			return fmt.Errorf("%w: min_os_versions must be keyed by ios, android or web", ErrInvalidExperimentEligibility)
+		}
+		if _, ok := parseDottedVersion(version); !ok {
+			return fmt.Errorf("%w: min_os_versions must be numeric versions such as 16.4", ErrInvalidExperimentEligibility)
+		}
+	}
+	return nil
+}
+
+// EligibilityContext is what is known about the user at assignment time
+type EligibilityContext struct {
+	Country    string
+	Platform   string
+	AppVersion string
+	OSVersion  string
+}
+
+// Check returns an empty reason when the user is eligible. A constrained
+// attribute the context does not know excludes the user.
+func (e ExperimentEligibility) Check(ctx EligibilityContext) string {
+	if len(e.Countries) > 0 && !containsFold(e.Countries, ctx.Country) {
+		return EligibilityReasonCountry
+	}
+	if len(e.Platforms) > 0 && !containsFold(e.Platforms, ctx.Platform) {
+		return EligibilityReasonPlatform
+	}
+	if len(e.AppVersions) > 0 && !matchesAppVersion(e.AppVersions, ctx.AppVersion) {
+		return EligibilityReasonAppVersion
+	}
+	if e.AppVersionRange != "" {
+		constraint, err := valueobject.ParseVersionConstraint(e.AppVersionRange)
+		if err != nil || !constraint.Allows(ctx.AppVersion) {
+			return EligibilityReasonAppVersion
+		}
+	}
+	if minimum, ok := e.MinOSVersions[strings.ToLower(ctx.Platform)]; ok {
+		required, _ := parseDottedVersion(minimum)
+		actual, known := parseDottedVersion(ctx.OSVersion)
+		if !known || compareDottedVersions(actual, required) < 0 {
+			return EligibilityReasonOSVersion
+		}
+	}
+	return ""
+}
+
+func containsFold(values []string, value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, candidate := range values {
+		if strings.EqualFold(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAppVersion(patterns []string, version string) bool {
+	if version == "" {
+		return false
+	}
+	for _, pattern := range patterns {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(version, prefix) {
+				return true
+			}
+			continue
+		}
+		if pattern == version {
+			return true
+		}
+	}
+	return false
+}
+
+// parseDottedVersion parses numeric versions such as 16.4.1
+func parseDottedVersion(version string) ([]int, bool) {
+	if version == "" {
+		return nil, false
+	}
+	parts := strings.Split(version, ".")
+	numbers := make([]int, 0, len(parts))
+	for _, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, false
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers, true
+}
+
+func compareDottedVersions(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// ExperimentEligibilityRule is an experiment's eligibility and the arm its
+// ineligible users fall through to
+type ExperimentEligibilityRule struct {
+	Eligibility  *ExperimentEligibility
+	ControlArmID uuid.UUID
+}
+
+// EligibilityDecision is the outcome of an eligibility check. Ineligible users
+// are shown FallbackArmID without being assigned.
+type EligibilityDecision struct {
+	Eligible      bool
+	Reason        string
+	FallbackArmID uuid.UUID
+}
+
+type ExperimentEligibilityRepository interface {
+	GetExperimentEligibility(ctx context.Context, experimentID uuid.UUID) (*ExperimentEligibilityRule, error)
+}
+
+// ExperimentEligibilityService checks experiment eligibility against the
+// request context, enriched with the user's stored platform and app version
+type ExperimentEligibilityService struct {
+	repo     ExperimentEligibilityRepository
+	userRepo repository.UserRepository
+}
+
+func NewExperimentEligibilityService(repo ExperimentEligibilityRepository, userRepo repository.UserRepository) *ExperimentEligibilityService {
+	return &ExperimentEligibilityService{repo: repo, userRepo: userRepo}
+}
+
+// CheckEligibility decides whether the user may be assigned to the experiment
+func (s *ExperimentEligibilityService) CheckEligibility(ctx context.Context, experimentID, userID uuid.UUID, eligibilityCtx EligibilityContext) (*EligibilityDecision, error) {
+	rule, err := s.repo.GetExperimentEligibility(ctx, experimentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get experiment eligibility: %w", err)
+	}
+	if rule == nil || rule.Eligibility == nil {
+		return &EligibilityDecision{Eligible: true}, nil
+	}
+
+	if eligibilityCtx.Platform == "" || eligibilityCtx.AppVersion == "" {
+		user, err := s.userRepo.GetByID(ctx, userID)
+		switch {
+		case err == nil:
+			if eligibilityCtx.Platform == "" {
+				eligibilityCtx.Platform = string(user.Platform)
+			}
+			if eligibilityCtx.AppVersion == "" {
+				eligibilityCtx.AppVersion = user.AppVersion
+			}
+		case !errors.Is(err, domainErrors.ErrUserNotFound):
+			return nil, fmt.Errorf("failed to get user: %w", err)
+		}
+	}
+
+	if reason := rule.Eligibility.Check(eligibilityCtx); reason != "" {
+		return &EligibilityDecision{Reason: reason, FallbackArmID: rule.ControlArmID}, nil
+	}
+	return &EligibilityDecision{Eligible: true}, nil
+}
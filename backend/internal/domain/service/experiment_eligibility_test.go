@@ -0,0 +1,67 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExperimentEligibilityCheck(t *testing.T) {
+	eligibility := ExperimentEligibility{
+		Countries:       []string{"US", "CA"},
+		Platforms:       []string{"ios"},
+		AppVersions:     []string{"3.2.*", "3.3.0"},
+		AppVersionRange: ">=3.2.0",
+		MinOSVersions:   map[string]string{"ios": "16.4"},
+	}
+	eligible := EligibilityContext{Country: "us", Platform: "ios", AppVersion: "3.2.7", OSVersion: "17.0"}
+
+	tests := []struct {
+		name   string
+		modify func(*EligibilityContext)
+		reason string
+	}{
+		{name: "eligible", modify: func(*EligibilityContext) {}},
+		{name: "exact app version", modify: func(c *EligibilityContext) { c.AppVersion = "3.3.0" }},
+		{name: "equal os version", modify: func(c *EligibilityContext) { c.OSVersion = "16.4.0" }},
+		{name: "country", modify: func(c *EligibilityContext) { c.Country = "FR" }, reason: EligibilityReasonCountry},
+		{name: "unknown country", modify: func(c *EligibilityContext) { c.Country = "" }, reason: EligibilityReasonCountry},
+		{name: "platform", modify: func(c *EligibilityContext) { c.Platform = "android" }, reason: EligibilityReasonPlatform},
+		{name: "app version", modify: func(c *EligibilityContext) { c.AppVersion = "3.1.9" }, reason: EligibilityReasonAppVersion},
+		{name: "app version range", modify: func(c *EligibilityContext) { c.AppVersion = "3.2.0-beta.1" }, reason: EligibilityReasonAppVersion},
+		{name: "os version", modify: func(c *EligibilityContext) { c.OSVersion = "16.3.1" }, reason: EligibilityReasonOSVersion},
+		{name: "unknown os version", modify: func(c *EligibilityContext) { c.OSVersion = "" }, reason: EligibilityReasonOSVersion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := eligible
+			tt.modify(&ctx)
+			assert.Equal(t, tt.reason, eligibility.Check(ctx))
+		})
+	}
+
+	assert.Empty(t, ExperimentEligibility{}.Check(EligibilityContext{}), "no constraints admit everyone")
+}
+
+func TestExperimentEligibilityValidate(t *testing.T) {
+	valid := ExperimentEligibility{
+		Countries:     []string{" us "},
+		Platforms:     []string{"iOS"},
+		AppVersions:   []string{"3.2.*"},
+		MinOSVersions: map[string]string{"Android": "12"},
+	}.Normalize()
+	assert.NoError(t, valid.Validate())
+	assert.Equal(t, []string{"US"}, valid.Countries)
+	assert.Equal(t, "12", valid.MinOSVersions["android"])
+
+	for _, invalid := range []ExperimentEligibility{
+		{Countries: []string{"USA"}},
+		{Platforms: []string{"windows"}},
+		{AppVersions: []string{"3.*.1"}},
+		{MinOSVersions: map[string]string{"ios": "16.x"}},
+		{MinOSVersions: map[string]string{"tv": "1"}},
+		{AppVersionRange: ">=3.2.0 ||"},
+	} {
+		assert.ErrorIs(t, invalid.Normalize().Validate(), ErrInvalidExperimentEligibility)
+	}
+}
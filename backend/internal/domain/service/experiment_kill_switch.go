@@ -0,0 +1,27 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// ErrExperimentPaused is returned when arm selection is stopped by the kill
+// switch: the experiment is paused, every arm left is paused, or all
+// experiments are disabled. Callers serve their default experience.
+var ErrExperimentPaused = errors.New("experiment is paused")
+
+// ExperimentKillSwitch reports experiments and arms an operator stopped.
+// Checks are answered from memory on every assignment, so they must not
+// touch the network.
+type ExperimentKillSwitch interface {
+	ExperimentKilled(experimentID uuid.UUID) bool
+	ArmKilled(armID uuid.UUID) bool
+}
+
+// ExperimentSwitchboard broadcasts experiment pauses to every API instance
+type ExperimentSwitchboard interface {
+	KillExperiment(ctx context.Context, experimentID uuid.UUID) error
+	ReviveExperiment(ctx context.Context, experimentID uuid.UUID) error
+}
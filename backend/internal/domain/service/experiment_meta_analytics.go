@@ -0,0 +1,336 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var ErrInvalidExperimentCategory = errors.New("invalid experiment category")
+
+const (
+	// ExperimentCategoryUncategorized groups experiments without a category
+	ExperimentCategoryUncategorized = "uncategorized"
+	// ExperimentCategoryAll is the reserved category holding app-wide totals
+	ExperimentCategoryAll = "all"
+
+	ExperimentMetricConversionRate = "conversion_rate"
+	ExperimentMetricRevenuePerUser = "revenue_per_user"
+)
+
+var experimentCategoryPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_]{0,31}$`)
+
+// NormalizeExperimentCategory trims and lowercases a category. An empty
+// category stays empty.
+func NormalizeExperimentCategory(category string) (string, error) {
+	category = strings.ToLower(strings.TrimSpace(category))
+	if category == "" {
+		return "", nil
+	}
+	if category == ExperimentCategoryAll || category == ExperimentCategoryUncategorized {
+		return "", fmt.Errorf("%w: %q is reserved", ErrInvalidExperimentCategory, category)
+	}
+	if !experimentCategoryPattern.MatchString(category) {
+		return "", fmt.Errorf("%w: use up to 32 lowercase letters, digits and underscores", ErrInvalidExperimentCategory)
+	}
+	return category, nil
+}
+
+type ExperimentOutcomeArm struct {
+	ArmID       uuid.UUID
+	IsControl   bool
+	Samples     int
+	Conversions int
+	Revenue     float64
+}
+
+// ExperimentOutcomeInput is what a completed experiment left behind.
+// LaunchedArmID is the winner an admin confirmed, if any.
+type ExperimentOutcomeInput struct {
+	ExperimentID         uuid.UUID
+	AppID                uuid.UUID
+	Category             string
+	ObjectiveType        ObjectiveType
+	CompletedAt          time.Time
+	LaunchedArmID        *uuid.UUID
+	WinnerConfidence     *float64
+	ConfidenceThreshold  float64
+	UsersSinceCompletion int64
+	Arms                 []ExperimentOutcomeArm
+}
+
+// ExperimentOutcome compares the winner of a completed experiment, or its
+// best variant when inconclusive, against control on the primary metric
+type ExperimentOutcome struct {
+	ExperimentID         uuid.UUID     `json:"experiment_id"`
+	AppID                uuid.UUID     `json:"-"`
+	Category             string        `json:"category"`
+	ObjectiveType        ObjectiveType `json:"objective_type"`
+	Metric               string        `json:"metric"`
+	CompletedAt          time.Time     `json:"completed_at"`
+	WinnerArmID          *uuid.UUID    `json:"winner_arm_id,omitempty"`
+	WinnerIsControl      bool          `json:"winner_is_control"`
+	Launched             bool          `json:"launched"`
+	ControlMetric        float64       `json:"control_metric"`
+	VariantMetric        *float64      `json:"variant_metric,omitempty"`
+	LiftPercent          *float64      `json:"lift_percent,omitempty"`
+	RevenuePerUserUplift float64       `json:"revenue_per_user_uplift"`
+	UsersSinceCompletion int64         `json:"users_since_completion"`
+	RevenueGained        float64       `json:"revenue_gained"`
+}
+
+// Won reports whether a variant beat control
+func (o ExperimentOutcome) Won() bool {
+	return o.WinnerArmID != nil && !o.WinnerIsControl
+}
+
+// ComputeExperimentOutcome derives the outcome of a completed experiment. The
+// primary metric is conversion rate for conversion objectives and revenue per
+// user otherwise. A confirmed winner is taken as launched; without one the
+// best arm wins once winner confidence reaches the threshold. Revenue gained
+// is the launched winner's revenue-per-user uplift over control times the
+// users the app gained since completion. It returns nil without a sampled
+// control arm.
+func ComputeExperimentOutcome(input ExperimentOutcomeInput) *ExperimentOutcome {
+	metric := ExperimentMetricRevenuePerUser
+	if input.ObjectiveType == ObjectiveConversion || input.ObjectiveType == "" {
+		metric = ExperimentMetricConversionRate
+	}
+	value := func(arm ExperimentOutcomeArm) float64 {
+		if metric == ExperimentMetricConversionRate {
+			return perSample(float64(arm.Conversions), arm.Samples)
+		}
+		return perSample(arm.Revenue, arm.Samples)
+	}
+
+	var control, variant, launched *ExperimentOutcomeArm
+	for i := range input.Arms {
+		arm := &input.Arms[i]
+		if arm.IsControl {
+			control = arm
+			continue
+		}
+		if input.LaunchedArmID != nil && arm.ArmID == *input.LaunchedArmID {
+			launched = arm
+		}
+		if arm.Samples > 0 && (variant == nil || value(*arm) > value(*variant)) {
+			variant = arm
+		}
+	}
+	if control == nil || control.Samples == 0 {
+		return nil
+	}
+	if launched != nil {
+		variant = launched
+	}
+
+	outcome := &ExperimentOutcome{
+		ExperimentID:         input.ExperimentID,
+		AppID:                input.AppID,
+		Category:             input.Category,
+		ObjectiveType:        input.ObjectiveType,
+		Metric:               metric,
+		CompletedAt:          input.CompletedAt,
+		ControlMetric:        value(*control),
+		UsersSinceCompletion: input.UsersSinceCompletion,
+	}
+	if outcome.Category == "" {
+		outcome.Category = ExperimentCategoryUncategorized
+	}
+	if input.LaunchedArmID != nil && *input.LaunchedArmID == control.ArmID {
+		outcome.WinnerArmID, outcome.WinnerIsControl, outcome.Launched = &control.ArmID, true, true
+	}
+	if variant == nil {
+		return outcome
+	}
+
+	variantMetric := value(*variant)
+	outcome.VariantMetric = &variantMetric
+	if outcome.ControlMetric > 0 {
+		lift := (variantMetric - outcome.ControlMetric) / outcome.ControlMetric * 100
+		outcome.LiftPercent = &lift
+	}
+	outcome.RevenuePerUserUplift = perSample(variant.Revenue, variant.Samples) - perSample(control.Revenue, control.Samples)
+
+	switch {
+	case launched != nil:
+		outcome.WinnerArmID, outcome.Launched = &launched.ArmID, true
+	case outcome.Launched:
+	case input.WinnerConfidence != nil && input.ConfidenceThreshold > 0 && *input.WinnerConfidence >= input.ConfidenceThreshold:
+		if variantMetric > outcome.ControlMetric {
+			outcome.WinnerArmID = &variant.ArmID
+		} else {
+			outcome.WinnerArmID, outcome.WinnerIsControl = &control.ArmID, true
+		}
+	}
+	if outcome.Launched && outcome.Won() {
+		outcome.RevenueGained = outcome.RevenuePerUserUplift * float64(outcome.UsersSinceCompletion)
+	}
+	return outcome
+}
+
+// ExperimentMetaCategory aggregates the outcomes of one category. A win is a
+// variant beating control; AvgLiftPercent averages every outcome with a lift.
+type ExperimentMetaCategory struct {
+	Category       string   `json:"category"`
+	Experiments    int      `json:"experiments"`
+	Conclusive     int      `json:"conclusive"`
+	Wins           int      `json:"wins"`
+	Launched       int      `json:"launched"`
+	WinRate        float64  `json:"win_rate"`
+	AvgLiftPercent *float64 `json:"avg_lift_percent"`
+	RevenueGained  float64  `json:"revenue_gained"`
+}
+
+// AggregateExperimentMeta groups outcomes by category, sorted by name, and
+// returns the totals across all of them under ExperimentCategoryAll
+func AggregateExperimentMeta(outcomes []ExperimentOutcome) ([]ExperimentMetaCategory, ExperimentMetaCategory) {
+	type accumulator struct {
+		ExperimentMetaCategory
+		liftSum   float64
+		liftCount int
+	}
+	add := func(acc *accumulator, outcome ExperimentOutcome) {
+		acc.Experiments++
+		if outcome.WinnerArmID != nil {
+			acc.Conclusive++
+		}
+		if outcome.Won() {
+			acc.Wins++
+		}
+		if outcome.Launched {
+			acc.Launched++
+		}
+		if outcome.LiftPercent != nil {
+			acc.liftSum += *outcome.LiftPercent
+			acc.liftCount++
+		}
+		acc.RevenueGained += outcome.RevenueGained
+	}
+	finish := func(acc *accumulator) ExperimentMetaCategory {
+		result := acc.ExperimentMetaCategory
+		result.WinRate = perSample(float64(acc.Wins), acc.Experiments)
+		if acc.liftCount > 0 {
+			avg := acc.liftSum / float64(acc.liftCount)
+			result.AvgLiftPercent = &avg
+		}
+		return result
+	}
+
+	total := &accumulator{ExperimentMetaCategory: ExperimentMetaCategory{Category: ExperimentCategoryAll}}
+	byCategory := make(map[string]*accumulator)
+	for _, outcome := range outcomes {
+		acc, ok := byCategory[outcome.Category]
+		if !ok {
+			acc = &accumulator{ExperimentMetaCategory: ExperimentMetaCategory{Category: outcome.Category}}
+			byCategory[outcome.Category] = acc
+		}
+		add(acc, outcome)
+		add(total, outcome)
+	}
+
+	categories := make([]ExperimentMetaCategory, 0, len(byCategory))
+	for _, acc := range byCategory {
+		categories = append(categories, finish(acc))
+	}
+	sort.Slice(categories, func(i, j int) bool { return categories[i].Category < categories[j].Category })
+	return categories, finish(total)
+}
+
+// ExperimentMetaSnapshot is one day's recorded aggregate of a category
+type ExperimentMetaSnapshot struct {
+	Date time.Time `json:"date"`
+	ExperimentMetaCategory
+}
+
+// ExperimentMetaReport is the experimentation program's current standing
+type ExperimentMetaReport struct {
+	Total      ExperimentMetaCategory   `json:"total"`
+	Categories []ExperimentMetaCategory `json:"categories"`
+	Outcomes   []ExperimentOutcome      `json:"outcomes"`
+	History    []ExperimentMetaSnapshot `json:"history"`
+}
+
+type ExperimentMetaRepository interface {
+	ListExperimentOutcomeInputs(ctx context.Context) ([]ExperimentOutcomeInput, error)
+	SaveExperimentOutcomes(ctx context.Context, outcomes []ExperimentOutcome) error
+	SaveExperimentMetaSnapshot(ctx context.Context, appID uuid.UUID, date time.Time, rows []ExperimentMetaCategory) error
+	ListExperimentOutcomes(ctx context.Context, appID uuid.UUID) ([]ExperimentOutcome, error)
+	ListExperimentMetaSnapshots(ctx context.Context, appID uuid.UUID, from, to time.Time) ([]ExperimentMetaSnapshot, error)
+}
+
+// ExperimentMetaAnalyticsService records experiment outcomes and reports on
+// the experimentation program as a whole
+type ExperimentMetaAnalyticsService struct {
+	repo   ExperimentMetaRepository
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+func NewExperimentMetaAnalyticsService(repo ExperimentMetaRepository, logger *zap.Logger) *ExperimentMetaAnalyticsService {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &ExperimentMetaAnalyticsService{repo: repo, logger: logger, now: time.Now}
+}
+
+// RecordDaily recomputes the outcome of every completed experiment and
+// snapshots each app's aggregates under today's UTC date. Running it again
+// the same day replaces that day's snapshot.
+func (s *ExperimentMetaAnalyticsService) RecordDaily(ctx context.Context) (int, error) {
+	inputs, err := s.repo.ListExperimentOutcomeInputs(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list completed experiments: %w", err)
+	}
+
+	outcomes := make([]ExperimentOutcome, 0, len(inputs))
+	byApp := make(map[uuid.UUID][]ExperimentOutcome)
+	for _, input := range inputs {
+		outcome := ComputeExperimentOutcome(input)
+		if outcome == nil {
+			continue
+		}
+		outcomes = append(outcomes, *outcome)
+		byApp[outcome.AppID] = append(byApp[outcome.AppID], *outcome)
+	}
+	if err := s.repo.SaveExperimentOutcomes(ctx, outcomes); err != nil {
+		return 0, fmt.Errorf("failed to save experiment outcomes: %w", err)
+	}
+
+	date := s.now().UTC().Truncate(24 * time.Hour)
+	for appID, appOutcomes := range byApp {
+		categories, total := AggregateExperimentMeta(appOutcomes)
+		if err := s.repo.SaveExperimentMetaSnapshot(ctx, appID, date, append(categories, total)); err != nil {
+			return 0, fmt.Errorf("failed to save experiment meta snapshot: %w", err)
+		}
+	}
+	s.logger.Info("Experiment meta-analytics recorded",
+		zap.String("date", date.Format("2006-01-02")),
+		zap.Int("outcomes", len(outcomes)),
+		zap.Int("apps", len(byApp)),
+	)
+	return len(outcomes), nil
+}
+
+// Report aggregates the app's recorded outcomes and returns the snapshots
+// of the last days
+func (s *ExperimentMetaAnalyticsService) Report(ctx context.Context, appID uuid.UUID, days int) (*ExperimentMetaReport, error) {
+	outcomes, err := s.repo.ListExperimentOutcomes(ctx, appID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list experiment outcomes: %w", err)
+	}
+	to := s.now().UTC().Truncate(24 * time.Hour)
+	history, err := s.repo.ListExperimentMetaSnapshots(ctx, appID, to.AddDate(0, 0, -days), to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list experiment meta snapshots: %w", err)
+	}
+	categories, total := AggregateExperimentMeta(outcomes)
+	return &ExperimentMetaReport{Total: total, Categories: categories, Outcomes: outcomes, History: history}, nil
+}
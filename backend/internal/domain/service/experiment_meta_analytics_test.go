@@ -0,0 +1,131 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func metaOutcomeInput(objective ObjectiveType) (ExperimentOutcomeInput, ExperimentOutcomeArm, ExperimentOutcomeArm) {
+	control := ExperimentOutcomeArm{ArmID: uuid.New(), IsControl: true, Samples: 1000, Conversions: 50, Revenue: 500}
+	variant := ExperimentOutcomeArm{ArmID: uuid.New(), Samples: 1000, Conversions: 60, Revenue: 650}
+	return ExperimentOutcomeInput{
+		ExperimentID:         uuid.New(),
+		AppID:                uuid.New(),
+		Category:             "price_test",
+		ObjectiveType:        objective,
+		CompletedAt:          time.Now(),
+		ConfidenceThreshold:  0.95,
+		UsersSinceCompletion: 2000,
+		Arms:                 []ExperimentOutcomeArm{control, variant},
+	}, control, variant
+}
+
+func TestComputeExperimentOutcomeLaunchedWinner(t *testing.T) {
+	input, _, variant := metaOutcomeInput(ObjectiveRevenue)
+	input.LaunchedArmID = &variant.ArmID
+
+	outcome := ComputeExperimentOutcome(input)
+
+	require.NotNil(t, outcome)
+	assert.Equal(t, ExperimentMetricRevenuePerUser, outcome.Metric)
+	assert.True(t, outcome.Launched)
+	assert.True(t, outcome.Won())
+	require.NotNil(t, outcome.LiftPercent)
+	assert.InDelta(t, 30, *outcome.LiftPercent, 1e-9)
+	assert.InDelta(t, 0.15, outcome.RevenuePerUserUplift, 1e-9)
+	assert.InDelta(t, 300, outcome.RevenueGained, 1e-9)
+}
+
+func TestComputeExperimentOutcomeWithoutLaunch(t *testing.T) {
+	t.Run("confident", func(t *testing.T) {
+		input, _, variant := metaOutcomeInput(ObjectiveConversion)
+		confidence := 0.97
+		input.WinnerConfidence = &confidence
+
+		outcome := ComputeExperimentOutcome(input)
+
+		require.NotNil(t, outcome)
+		assert.Equal(t, ExperimentMetricConversionRate, outcome.Metric)
+		assert.Equal(t, &variant.ArmID, outcome.WinnerArmID)
+		assert.False(t, outcome.Launched)
+		assert.InDelta(t, 20, *outcome.LiftPercent, 1e-9)
+		assert.Zero(t, outcome.RevenueGained, "only launched winners gain revenue")
+	})
+
+	t.Run("inconclusive", func(t *testing.T) {
+		input, _, _ := metaOutcomeInput(ObjectiveConversion)
+		input.Category = ""
+
+		outcome := ComputeExperimentOutcome(input)
+
+		require.NotNil(t, outcome)
+		assert.Nil(t, outcome.WinnerArmID)
+		assert.Equal(t, ExperimentCategoryUncategorized, outcome.Category)
+		assert.NotNil(t, outcome.LiftPercent)
+	})
+
+	t.Run("control kept", func(t *testing.T) {
+		input, control, _ := metaOutcomeInput(ObjectiveRevenue)
+		input.LaunchedArmID = &control.ArmID
+
+		outcome := ComputeExperimentOutcome(input)
+
+		assert.True(t, outcome.Launched)
+		assert.True(t, outcome.WinnerIsControl)
+		assert.False(t, outcome.Won())
+		assert.Zero(t, outcome.RevenueGained)
+	})
+
+	t.Run("no control samples", func(t *testing.T) {
+		input, _, _ := metaOutcomeInput(ObjectiveRevenue)
+		input.Arms[0].Samples = 0
+		assert.Nil(t, ComputeExperimentOutcome(input))
+	})
+}
+
+func TestAggregateExperimentMeta(t *testing.T) {
+	lift := func(v float64) *float64 { return &v }
+	winner := uuid.New()
+	outcomes := []ExperimentOutcome{
+		{Category: "price_test", WinnerArmID: &winner, Launched: true, LiftPercent: lift(30), RevenueGained: 300},
+		{Category: "price_test", LiftPercent: lift(-10)},
+		{Category: "onboarding_test", WinnerArmID: &winner, WinnerIsControl: true, LiftPercent: lift(-5)},
+	}
+
+	categories, total := AggregateExperimentMeta(outcomes)
+
+	require.Len(t, categories, 2)
+	assert.Equal(t, "onboarding_test", categories[0].Category)
+	assert.Equal(t, 0, categories[0].Wins)
+	assert.Equal(t, 1, categories[0].Conclusive)
+	price := categories[1]
+	assert.Equal(t, 2, price.Experiments)
+	assert.Equal(t, 1, price.Wins)
+	assert.InDelta(t, 0.5, price.WinRate, 1e-9)
+	assert.InDelta(t, 10, *price.AvgLiftPercent, 1e-9)
+	assert.InDelta(t, 300, price.RevenueGained, 1e-9)
+
+	assert.Equal(t, ExperimentCategoryAll, total.Category)
+	assert.Equal(t, 3, total.Experiments)
+	assert.Equal(t, 1, total.Launched)
+	assert.InDelta(t, 5, *total.AvgLiftPercent, 1e-9)
+}
+
+func TestNormalizeExperimentCategory(t *testing.T) {
+	category, err := NormalizeExperimentCategory("  Price_Test ")
+	require.NoError(t, err)
+	assert.Equal(t, "price_test", category)
+
+	category, err = NormalizeExperimentCategory("")
+	require.NoError(t, err)
+	assert.Empty(t, category)
+
+	_, err = NormalizeExperimentCategory("all")
+	assert.ErrorIs(t, err, ErrInvalidExperimentCategory)
+	_, err = NormalizeExperimentCategory("price test")
+	assert.ErrorIs(t, err, ErrInvalidExperimentCategory)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math/rand"
 	"time"
 
 	"github.com/google/uuid"
@@ -64,12 +65,20 @@ type ExperimentRepairService struct {
 	repo        ExperimentRepairRepository
 	banditRepo  experimentRepairBanditRepository
 	simulations int
+	randSource  rand.Source
 }
 
 func NewExperimentRepairService(repo ExperimentRepairRepository, banditRepo experimentRepairBanditRepository) *ExperimentRepairService {
 	return &ExperimentRepairService{repo: repo, banditRepo: banditRepo, simulations: 2000}
 }
 
+// WithRandSource makes the winner confidence simulation draw from src, so a
+// seeded source gives reproducible repairs
+func (s *ExperimentRepairService) WithRandSource(src rand.Source) *ExperimentRepairService {
+	s.randSource = &lockedSource{src: src} // shared by concurrent repairs
+	return s
+}
+
 func (s *ExperimentRepairService) RepairExperiment(ctx context.Context, experimentID uuid.UUID) (*ExperimentRepairSummary, error) {
 	if _, err := s.repo.GetExperimentMutationState(ctx, experimentID); err != nil {
 		return nil, err
@@ -153,6 +162,7 @@ func (s *ExperimentRepairService) syncObjectiveStats(ctx context.Context, experi
 				Conversions:   stats.Conversions,
 				TotalRevenue:  stats.Revenue,
 				AvgLTV:        stats.AvgReward,
+				RewardBasis:   ObjectiveStatsBasis(objectiveType, config.RewardBasis),
 			}); err != nil {
 				return synced, fmt.Errorf("failed to sync objective stats during repair: %w", err)
 			}
@@ -165,6 +175,9 @@ func (s *ExperimentRepairService) syncObjectiveStats(ctx context.Context, experi
 
 func (s *ExperimentRepairService) recalculateWinnerConfidence(ctx context.Context, experimentID uuid.UUID) (*float64, error) {
 	bandit := NewThompsonSamplingBandit(s.banditRepo, noopBanditCache{}, zap.NewNop())
+	if s.randSource != nil {
+		bandit.WithRandSource(s.randSource)
+	}
 	winProbabilities, err := bandit.CalculateWinProbability(ctx, experimentID, s.simulations)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,278 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrExperimentTemplateNotFound = errors.New("experiment template not found")
+	ErrExperimentTemplateArm      = errors.New("unknown experiment template arm")
+)
+
+// ExperimentTemplateWindow is the sliding window a template configures
+type ExperimentTemplateWindow struct {
+	Type       WindowType `json:"type"`
+	Size       int        `json:"size"`
+	MinSamples int        `json:"min_samples"`
+}
+
+type ExperimentTemplateArm struct {
+	Name          string       `json:"name"`
+	Description   string       `json:"description"`
+	IsControl     bool         `json:"is_control"`
+	TrafficWeight float64      `json:"traffic_weight"`
+	Discount      *ArmDiscount `json:"discount,omitempty"`
+}
+
+// ExperimentTemplate pre-configures a recurring kind of experiment: its
+// arms, objective, window, guardrail and automation policy
+type ExperimentTemplate struct {
+	Key                 string                     `json:"key"`
+	Name                string                     `json:"name"`
+	Description         string                     `json:"description"`
+	IsBandit            bool                       `json:"is_bandit"`
+	AlgorithmType       string                     `json:"algorithm_type"`
+	MinSampleSize       int                        `json:"min_sample_size"`
+	ConfidenceThreshold float64                    `json:"confidence_threshold"`
+	ObjectiveType       ObjectiveType              `json:"objective_type"`
+	ObjectiveWeights    map[string]float64         `json:"objective_weights,omitempty"`
+	RewardBasis         RevenueBasis               `json:"reward_basis,omitempty"`
+	Window              *ExperimentTemplateWindow  `json:"window,omitempty"`
+	RevenueGuardrail    *RevenueGuardrail          `json:"revenue_guardrail,omitempty"`
+	AutomationPolicy    ExperimentAutomationPolicy `json:"automation_policy"`
+	Arms                []ExperimentTemplateArm    `json:"arms"`
+}
+
+var experimentTemplates = []ExperimentTemplate{
+	{
+		Key:                 "price_test",
+		Name:                "Price test",
+		Description:         "Current price against a test price, judged on revenue with a guardrail on revenue per user.",
+		AlgorithmType:       "thompson_sampling",
+		MinSampleSize:       1000,
+		ConfidenceThreshold: 0.95,
+		ObjectiveType:       ObjectiveRevenue,
+		RewardBasis:         RevenueBasisNet,
+		RevenueGuardrail:    &RevenueGuardrail{Enabled: true, MaxRevenueLossPercent: 15, MinSamples: 500},
+		AutomationPolicy: ExperimentAutomationPolicy{
+			Enabled:              true,
+			AutoComplete:         true,
+			CompleteOnEndTime:    true,
+			CompleteOnConfidence: true,
+		},
+		Arms: []ExperimentTemplateArm{
+			{Name: "current_price", Description: "Current price.", IsControl: true, TrafficWeight: 0.5},
+			{Name: "test_price", Description: "Test price.", TrafficWeight: 0.5},
+		},
+	},
+	{
+		Key:                 "paywall_design_test",
+		Name:                "Paywall design test",
+		Description:         "Bandit across paywall designs, optimising conversion over a recent-events window.",
+		IsBandit:            true,
+		AlgorithmType:       "thompson_sampling",
+		MinSampleSize:       500,
+		ConfidenceThreshold: 0.95,
+		ObjectiveType:       ObjectiveConversion,
+		Window:              &ExperimentTemplateWindow{Type: WindowTypeEvents, Size: 5000, MinSamples: 200},
+		AutomationPolicy: ExperimentAutomationPolicy{
+			Enabled:           true,
+			AutoComplete:      true,
+			CompleteOnEndTime: true,
+		},
+		Arms: []ExperimentTemplateArm{
+			{Name: "current_design", Description: "Current paywall design.", IsControl: true, TrafficWeight: 0.5},
+			{Name: "new_design", Description: "New paywall design.", TrafficWeight: 0.5},
+		},
+	},
+	{
+		Key:                 "onboarding_test",
+		Name:                "Onboarding test",
+		Description:         "Current onboarding flow against a new one, judged on lifetime value.",
+		AlgorithmType:       "thompson_sampling",
+		MinSampleSize:       500,
+		ConfidenceThreshold: 0.95,
+		ObjectiveType:       ObjectiveLTV,
+		AutomationPolicy: ExperimentAutomationPolicy{
+			Enabled:              true,
+			AutoComplete:         true,
+			CompleteOnEndTime:    true,
+			CompleteOnSampleSize: true,
+		},
+		Arms: []ExperimentTemplateArm{
+			{Name: "current_flow", Description: "Current onboarding flow.", IsControl: true, TrafficWeight: 0.5},
+			{Name: "new_flow", Description: "New onboarding flow.", TrafficWeight: 0.5},
+		},
+	},
+}
+
+// ExperimentTemplates returns the built-in experiment templates
+func ExperimentTemplates() []ExperimentTemplate {
+	return experimentTemplates
+}
+
+// ExperimentSpec is everything needed to create a draft experiment
+type ExperimentSpec struct {
+	Name                string
+	Description         string
+	Category            string
+	IsBandit            bool
+	AlgorithmType       *string
+	MinSampleSize       int
+	ConfidenceThreshold float64
+	StartAt             *time.Time
+	EndAt               *time.Time
+	ObjectiveType       ObjectiveType
+	ObjectiveWeights    map[string]float64
+	RewardBasis         RevenueBasis
+	Window              *ExperimentTemplateWindow
+	RevenueGuardrail    *RevenueGuardrail
+	Eligibility         *ExperimentEligibility
+	AutomationPolicy    ExperimentAutomationPolicy
+	Arms                []ExperimentArmInput
+}
+
+// ExperimentFromTemplateInput names the new experiment and fills in what a
+// template leaves open. PricingTiers, Discounts and Payloads are keyed by
+// arm name.
+type ExperimentFromTemplateInput struct {
+	Name         string
+	Description  string
+	StartAt      *time.Time
+	EndAt        *time.Time
+	Eligibility  *ExperimentEligibility
+	PricingTiers map[string]uuid.UUID
+	Discounts    map[string]ArmDiscount
+	Payloads     map[string]ArmPayload
+}
+
+type ExperimentTemplateRepository interface {
+	CreateExperimentFromSpec(ctx context.Context, appID uuid.UUID, spec ExperimentSpec) (uuid.UUID, error)
+	CloneExperiment(ctx context.Context, appID, experimentID uuid.UUID, name string) (uuid.UUID, error)
+}
+
+type ExperimentTemplateService struct {
+	repo ExperimentTemplateRepository
+}
+
+func NewExperimentTemplateService(repo ExperimentTemplateRepository) *ExperimentTemplateService {
+	return &ExperimentTemplateService{repo: repo}
+}
+
+// CreateFromTemplate creates a draft experiment from the template with key
+func (s *ExperimentTemplateService) CreateFromTemplate(ctx context.Context, appID uuid.UUID, key string, input ExperimentFromTemplateInput) (uuid.UUID, error) {
+	var template *ExperimentTemplate
+	for i := range experimentTemplates {
+		if experimentTemplates[i].Key == key {
+			template = &experimentTemplates[i]
+			break
+		}
+	}
+	if template == nil {
+		return uuid.Nil, ErrExperimentTemplateNotFound
+	}
+	spec, err := template.spec(input)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return s.repo.CreateExperimentFromSpec(ctx, appID, spec)
+}
+
+// CloneExperiment copies an experiment of the app, with its live arms and
+// configuration, into a new draft. An empty name appends " (copy)".
+func (s *ExperimentTemplateService) CloneExperiment(ctx context.Context, appID, experimentID uuid.UUID, name string) (uuid.UUID, error) {
+	return s.repo.CloneExperiment(ctx, appID, experimentID, strings.TrimSpace(name))
+}
+
+func (t ExperimentTemplate) spec(input ExperimentFromTemplateInput) (ExperimentSpec, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		name = t.Name
+	}
+	description := strings.TrimSpace(input.Description)
+	if description == "" {
+		description = t.Description
+	}
+	algorithm := t.AlgorithmType
+	spec := ExperimentSpec{
+		Name:                name,
+		Description:         description,
+		Category:            t.Key,
+		IsBandit:            t.IsBandit,
+		MinSampleSize:       t.MinSampleSize,
+		ConfidenceThreshold: t.ConfidenceThreshold,
+		StartAt:             input.StartAt,
+		EndAt:               input.EndAt,
+		ObjectiveType:       t.ObjectiveType,
+		ObjectiveWeights:    t.ObjectiveWeights,
+		RewardBasis:         t.RewardBasis,
+		Window:              t.Window,
+		RevenueGuardrail:    t.RevenueGuardrail,
+		AutomationPolicy:    NormalizeExperimentAutomationPolicy(&t.AutomationPolicy),
+	}
+	if t.IsBandit {
+		spec.AlgorithmType = &algorithm
+	}
+	if input.Eligibility != nil {
+		eligibility := input.Eligibility.Normalize()
+		if err := eligibility.Validate(); err != nil {
+			return ExperimentSpec{}, err
+		}
+		spec.Eligibility = &eligibility
+	}
+
+	known := make(map[string]bool, len(t.Arms))
+	for _, arm := range t.Arms {
+		known[arm.Name] = arm.IsControl
+		spec.Arms = append(spec.Arms, ExperimentArmInput{
+			Name:          arm.Name,
+			Description:   arm.Description,
+			IsControl:     arm.IsControl,
+			TrafficWeight: arm.TrafficWeight,
+			Discount:      arm.Discount,
+		})
+	}
+	for armName := range input.PricingTiers {
+		if _, ok := known[armName]; !ok {
+			return ExperimentSpec{}, fmt.Errorf("%w: %q", ErrExperimentTemplateArm, armName)
+		}
+	}
+	for armName, discount := range input.Discounts {
+		isControl, ok := known[armName]
+		if !ok {
+			return ExperimentSpec{}, fmt.Errorf("%w: %q", ErrExperimentTemplateArm, armName)
+		}
+		if isControl {
+			return ExperimentSpec{}, fmt.Errorf("%w: the control arm cannot carry a discount", ErrInvalidArmDiscount)
+		}
+		if err := discount.Validate(); err != nil {
+			return ExperimentSpec{}, err
+		}
+	}
+	for armName, payload := range input.Payloads {
+		if _, ok := known[armName]; !ok {
+			return ExperimentSpec{}, fmt.Errorf("%w: %q", ErrExperimentTemplateArm, armName)
+		}
+		if err := payload.Validate(); err != nil {
+			return ExperimentSpec{}, err
+		}
+	}
+	for i := range spec.Arms {
+		if tierID, ok := input.PricingTiers[spec.Arms[i].Name]; ok {
+			spec.Arms[i].PricingTierID = &tierID
+		}
+		if discount, ok := input.Discounts[spec.Arms[i].Name]; ok {
+			spec.Arms[i].Discount = &discount
+		}
+		if payload, ok := input.Payloads[spec.Arms[i].Name]; ok {
+			spec.Arms[i].Payload = &payload
+		}
+	}
+	return spec, nil
+}
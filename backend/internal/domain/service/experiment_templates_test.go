@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubExperimentTemplateRepository struct {
+	spec      ExperimentSpec
+	cloneName string
+}
+
+func (s *stubExperimentTemplateRepository) CreateExperimentFromSpec(_ context.Context, _ uuid.UUID, spec ExperimentSpec) (uuid.UUID, error) {
+	s.spec = spec
+	return uuid.New(), nil
+}
+
+func (s *stubExperimentTemplateRepository) CloneExperiment(_ context.Context, _, _ uuid.UUID, name string) (uuid.UUID, error) {
+	s.cloneName = name
+	return uuid.New(), nil
+}
+
+func TestExperimentTemplatesAreValid(t *testing.T) {
+	keys := make(map[string]bool)
+	for _, template := range ExperimentTemplates() {
+		assert.False(t, keys[template.Key], "duplicate key %s", template.Key)
+		keys[template.Key] = true
+
+		controls := 0
+		for _, arm := range template.Arms {
+			if arm.IsControl {
+				controls++
+			}
+		}
+		assert.Equal(t, 1, controls, template.Key)
+		assert.GreaterOrEqual(t, len(template.Arms), 2, template.Key)
+		if template.RevenueGuardrail != nil {
+			assert.NoError(t, template.RevenueGuardrail.Validate(), template.Key)
+		}
+	}
+	assert.True(t, keys["price_test"] && keys["paywall_design_test"] && keys["onboarding_test"])
+}
+
+func TestCreateFromTemplateFillsArms(t *testing.T) {
+	repo := &stubExperimentTemplateRepository{}
+	svc := NewExperimentTemplateService(repo)
+	tierID := uuid.New()
+
+	_, err := svc.CreateFromTemplate(context.Background(), uuid.New(), "price_test", ExperimentFromTemplateInput{
+		Name:         "  Spring price test ",
+		PricingTiers: map[string]uuid.UUID{"test_price": tierID},
+		Discounts:    map[string]ArmDiscount{"test_price": {Type: ArmDiscountPercent, Value: 20}},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "Spring price test", repo.spec.Name)
+	assert.Equal(t, "price_test", repo.spec.Category)
+	assert.Equal(t, ObjectiveRevenue, repo.spec.ObjectiveType)
+	require.NotNil(t, repo.spec.RevenueGuardrail)
+	assert.Nil(t, repo.spec.AlgorithmType, "classic A/B tests store no algorithm")
+	require.Len(t, repo.spec.Arms, 2)
+	assert.Nil(t, repo.spec.Arms[0].PricingTierID)
+	assert.Equal(t, &tierID, repo.spec.Arms[1].PricingTierID)
+	require.NotNil(t, repo.spec.Arms[1].Discount)
+	assert.Equal(t, 20.0, repo.spec.Arms[1].Discount.Value)
+}
+
+func TestCreateFromTemplateRejectsBadInput(t *testing.T) {
+	svc := NewExperimentTemplateService(&stubExperimentTemplateRepository{})
+	ctx := context.Background()
+
+	_, err := svc.CreateFromTemplate(ctx, uuid.New(), "checkout_test", ExperimentFromTemplateInput{})
+	assert.ErrorIs(t, err, ErrExperimentTemplateNotFound)
+
+	_, err = svc.CreateFromTemplate(ctx, uuid.New(), "price_test", ExperimentFromTemplateInput{
+		PricingTiers: map[string]uuid.UUID{"cheap_price": uuid.New()},
+	})
+	assert.ErrorIs(t, err, ErrExperimentTemplateArm)
+
+	_, err = svc.CreateFromTemplate(ctx, uuid.New(), "price_test", ExperimentFromTemplateInput{
+		Discounts: map[string]ArmDiscount{"current_price": {Type: ArmDiscountPercent, Value: 10}},
+	})
+	assert.ErrorIs(t, err, ErrInvalidArmDiscount)
+
+	_, err = svc.CreateFromTemplate(ctx, uuid.New(), "price_test", ExperimentFromTemplateInput{
+		Payloads: map[string]ArmPayload{"test_price": {ImageURL: "ftp://cdn.example.com/a.png"}},
+	})
+	assert.ErrorIs(t, err, ErrInvalidArmPayload)
+}
+
+func TestCloneExperimentTrimsName(t *testing.T) {
+	repo := &stubExperimentTemplateRepository{}
+	_, err := NewExperimentTemplateService(repo).CloneExperiment(context.Background(), uuid.New(), uuid.New(), "  Rerun ")
+	require.NoError(t, err)
+	assert.Equal(t, "Rerun", repo.cloneName)
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultExperimentTimelineDays is the timeline length when none is asked for
+	DefaultExperimentTimelineDays = 30
+	// timelineWinSimulations is the Monte Carlo draws per daily win probability
+	timelineWinSimulations = 10000
+)
+
+// ExperimentTimelineTarget is an experiment the daily timeline job records
+type ExperimentTimelineTarget struct {
+	ExperimentID uuid.UUID
+	AppID        uuid.UUID
+}
+
+// ExperimentTimelineDay is one arm's activity on one UTC day; WinProbability
+// is nil when it could not be computed
+type ExperimentTimelineDay struct {
+	ArmID          uuid.UUID
+	Date           time.Time
+	Exposures      int64
+	Conversions    int64
+	Revenue        float64
+	WinProbability *float64
+}
+
+// ExperimentTimelinePoint is a day of an arm's series
+type ExperimentTimelinePoint struct {
+	Date           time.Time `json:"date"`
+	Exposures      int64     `json:"exposures"`
+	Conversions    int64     `json:"conversions"`
+	Revenue        float64   `json:"revenue"`
+	WinProbability *float64  `json:"win_probability"`
+}
+
+// ExperimentTimelineArm is an arm and its daily series, oldest day first
+type ExperimentTimelineArm struct {
+	ArmID     uuid.UUID                 `json:"arm_id"`
+	Name      string                    `json:"name"`
+	IsControl bool                      `json:"is_control"`
+	Points    []ExperimentTimelinePoint `json:"points"`
+}
+
+// ExperimentTimeline is the materialized daily history of an experiment
+type ExperimentTimeline struct {
+	ExperimentID uuid.UUID               `json:"experiment_id"`
+	From         time.Time               `json:"from"`
+	To           time.Time               `json:"to"`
+	Arms         []ExperimentTimelineArm `json:"arms"`
+}
+
+type ExperimentTimelineRepository interface {
+	ListExperimentTimelineTargets(ctx context.Context) ([]ExperimentTimelineTarget, error)
+	// GetExperimentTimelineActivity totals each arm's exposures, conversions
+	// and revenue within [day, day+24h)
+	GetExperimentTimelineActivity(ctx context.Context, experimentID uuid.UUID, day time.Time) ([]ExperimentTimelineDay, error)
+	SaveExperimentTimelineDay(ctx context.Context, target ExperimentTimelineTarget, day time.Time, rows []ExperimentTimelineDay) error
+	// ListExperimentTimelineArms returns the app's experiment arms with empty
+	// series, nil when the experiment does not exist
+	ListExperimentTimelineArms(ctx context.Context, appID, experimentID uuid.UUID) ([]ExperimentTimelineArm, error)
+	ListExperimentTimelineDays(ctx context.Context, experimentID uuid.UUID, from, to time.Time) ([]ExperimentTimelineDay, error)
+}
+
+// winProbabilityCalculator estimates each arm's chance of being the best
+type winProbabilityCalculator interface {
+	CalculateWinProbability(ctx context.Context, experimentID uuid.UUID, simulations int) (map[uuid.UUID]float64, error)
+}
+
+// ExperimentTimelineService materializes daily per-arm series of running
+// experiments, so timeline charts are read rather than recomputed
+type ExperimentTimelineService struct {
+	repo        ExperimentTimelineRepository
+	winProbs    winProbabilityCalculator
+	logger      *zap.Logger
+	now         func() time.Time
+	simulations int
+}
+
+func NewExperimentTimelineService(repo ExperimentTimelineRepository, logger *zap.Logger) *ExperimentTimelineService {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &ExperimentTimelineService{repo: repo, logger: logger, now: time.Now, simulations: timelineWinSimulations}
+}
+
+// WithWinProbability records each day's win probability; without it the
+// timeline has none
+func (s *ExperimentTimelineService) WithWinProbability(calculator winProbabilityCalculator) *ExperimentTimelineService {
+	s.winProbs = calculator
+	return s
+}
+
+// RecordDay materializes the given UTC day for every running or paused
+// experiment. Win probabilities reflect the arm stats at the time of the run.
+// Recording a day again replaces it.
+func (s *ExperimentTimelineService) RecordDay(ctx context.Context, day time.Time) (int, error) {
+	day = day.UTC().Truncate(24 * time.Hour)
+	targets, err := s.repo.ListExperimentTimelineTargets(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list timeline experiments: %w", err)
+	}
+
+	recorded := 0
+	for _, target := range targets {
+		rows, err := s.repo.GetExperimentTimelineActivity(ctx, target.ExperimentID, day)
+		if err != nil {
+			return recorded, fmt.Errorf("failed to load experiment %s activity: %w", target.ExperimentID, err)
+		}
+		if s.winProbs != nil {
+			probs, err := s.winProbs.CalculateWinProbability(ctx, target.ExperimentID, s.simulations)
+			if err != nil {
+				s.logger.Warn("Failed to compute timeline win probability",
+					zap.String("experiment_id", target.ExperimentID.String()), zap.Error(err))
+			}
+			for i := range rows {
+				if p, ok := probs[rows[i].ArmID]; ok {
+					rows[i].WinProbability = &p
+				}
+			}
+		}
+		if err := s.repo.SaveExperimentTimelineDay(ctx, target, day, rows); err != nil {
+			return recorded, fmt.Errorf("failed to save experiment %s timeline: %w", target.ExperimentID, err)
+		}
+		recorded++
+	}
+	s.logger.Info("Experiment timelines recorded",
+		zap.String("date", day.Format("2006-01-02")),
+		zap.Int("experiments", recorded),
+	)
+	return recorded, nil
+}
+
+// RecordDaily materializes yesterday (UTC), the last complete day
+func (s *ExperimentTimelineService) RecordDaily(ctx context.Context) (int, error) {
+	return s.RecordDay(ctx, s.now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -1))
+}
+
+// Timeline returns the experiment's series for the last days complete days.
+// Days without a recorded row are left out of an arm's points.
+func (s *ExperimentTimelineService) Timeline(ctx context.Context, appID, experimentID uuid.UUID, days int) (*ExperimentTimeline, error) {
+	if days <= 0 {
+		days = DefaultExperimentTimelineDays
+	}
+	arms, err := s.repo.ListExperimentTimelineArms(ctx, appID, experimentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list experiment arms: %w", err)
+	}
+	if arms == nil {
+		return nil, ErrExperimentNotFound
+	}
+
+	to := s.now().UTC().Truncate(24 * time.Hour)
+	from := to.AddDate(0, 0, -days)
+	rows, err := s.repo.ListExperimentTimelineDays(ctx, experimentID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list experiment timeline: %w", err)
+	}
+
+	index := make(map[uuid.UUID]int, len(arms))
+	for i := range arms {
+		arms[i].Points = make([]ExperimentTimelinePoint, 0)
+		index[arms[i].ArmID] = i
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Date.Before(rows[j].Date) })
+	for _, row := range rows {
+		i, ok := index[row.ArmID]
+		if !ok {
+			continue
+		}
+		arms[i].Points = append(arms[i].Points, ExperimentTimelinePoint{
+			Date:           row.Date,
+			Exposures:      row.Exposures,
+			Conversions:    row.Conversions,
+			Revenue:        row.Revenue,
+			WinProbability: row.WinProbability,
+		})
+	}
+	return &ExperimentTimeline{ExperimentID: experimentID, From: from, To: to, Arms: arms}, nil
+}
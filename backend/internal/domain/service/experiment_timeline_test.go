@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// timelineTestRepo keeps saved timeline days in memory
+type timelineTestRepo struct {
+	targets  []ExperimentTimelineTarget
+	activity []ExperimentTimelineDay
+	arms     []ExperimentTimelineArm
+	saved    []ExperimentTimelineDay
+	savedDay time.Time
+}
+
+func (r *timelineTestRepo) ListExperimentTimelineTargets(ctx context.Context) ([]ExperimentTimelineTarget, error) {
+	return r.targets, nil
+}
+func (r *timelineTestRepo) GetExperimentTimelineActivity(ctx context.Context, experimentID uuid.UUID, day time.Time) ([]ExperimentTimelineDay, error) {
+	rows := make([]ExperimentTimelineDay, len(r.activity))
+	copy(rows, r.activity)
+	for i := range rows {
+		rows[i].Date = day
+	}
+	return rows, nil
+}
+func (r *timelineTestRepo) SaveExperimentTimelineDay(ctx context.Context, target ExperimentTimelineTarget, day time.Time, rows []ExperimentTimelineDay) error {
+	r.saved, r.savedDay = append(r.saved, rows...), day
+	return nil
+}
+func (r *timelineTestRepo) ListExperimentTimelineArms(ctx context.Context, appID, experimentID uuid.UUID) ([]ExperimentTimelineArm, error) {
+	return r.arms, nil
+}
+func (r *timelineTestRepo) ListExperimentTimelineDays(ctx context.Context, experimentID uuid.UUID, from, to time.Time) ([]ExperimentTimelineDay, error) {
+	return r.saved, nil
+}
+
+type timelineWinProbs map[uuid.UUID]float64
+
+func (p timelineWinProbs) CalculateWinProbability(ctx context.Context, experimentID uuid.UUID, simulations int) (map[uuid.UUID]float64, error) {
+	if p == nil {
+		return nil, errors.New("no stats")
+	}
+	return p, nil
+}
+
+func TestExperimentTimelineService_RecordDailyAndTimeline(t *testing.T) {
+	experimentID, control, variant := uuid.New(), uuid.New(), uuid.New()
+	repo := &timelineTestRepo{
+		targets: []ExperimentTimelineTarget{{ExperimentID: experimentID, AppID: uuid.New()}},
+		activity: []ExperimentTimelineDay{
+			{ArmID: control, Exposures: 100, Conversions: 5, Revenue: 49.95},
+			{ArmID: variant, Exposures: 98, Conversions: 8, Revenue: 79.92},
+		},
+		arms: []ExperimentTimelineArm{{ArmID: control, Name: "control", IsControl: true}, {ArmID: variant, Name: "variant"}},
+	}
+	now := time.Date(2024, 5, 10, 0, 25, 0, 0, time.UTC)
+	svc := NewExperimentTimelineService(repo, zap.NewNop()).WithWinProbability(timelineWinProbs{control: 0.2, variant: 0.8})
+	svc.now = func() time.Time { return now }
+
+	recorded, err := svc.RecordDaily(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, recorded)
+	assert.Equal(t, time.Date(2024, 5, 9, 0, 0, 0, 0, time.UTC), repo.savedDay, "yesterday is the last complete day")
+	require.Len(t, repo.saved, 2)
+	require.NotNil(t, repo.saved[1].WinProbability)
+	assert.Equal(t, 0.8, *repo.saved[1].WinProbability)
+
+	timeline, err := svc.Timeline(context.Background(), uuid.New(), experimentID, 7)
+	require.NoError(t, err)
+	assert.Equal(t, now.Truncate(24*time.Hour).AddDate(0, 0, -7), timeline.From)
+	require.Len(t, timeline.Arms, 2)
+	require.Len(t, timeline.Arms[1].Points, 1)
+	assert.Equal(t, int64(8), timeline.Arms[1].Points[0].Conversions)
+}
+
+func TestExperimentTimelineService_RecordsWithoutWinProbability(t *testing.T) {
+	armID := uuid.New()
+	repo := &timelineTestRepo{
+		targets:  []ExperimentTimelineTarget{{ExperimentID: uuid.New(), AppID: uuid.New()}},
+		activity: []ExperimentTimelineDay{{ArmID: armID, Exposures: 3}},
+	}
+	svc := NewExperimentTimelineService(repo, zap.NewNop()).WithWinProbability(timelineWinProbs(nil))
+
+	_, err := svc.RecordDay(context.Background(), time.Now())
+	require.NoError(t, err)
+	require.Len(t, repo.saved, 1)
+	assert.Nil(t, repo.saved[0].WinProbability)
+}
+
+func TestExperimentTimelineService_UnknownExperiment(t *testing.T) {
+	svc := NewExperimentTimelineService(&timelineTestRepo{}, zap.NewNop())
+	_, err := svc.Timeline(context.Background(), uuid.New(), uuid.New(), 0)
+	assert.ErrorIs(t, err, ErrExperimentNotFound)
+}
@@ -0,0 +1,258 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Funnel health windows
+const (
+	funnelHealthVerificationWindow = 15 * time.Minute
+	funnelHealthConversionWindow   = time.Hour
+	funnelHealthBaselineWindow     = 7 * 24 * time.Hour
+	// funnelHealthWebhookHorizon ignores events stuck for longer; they are
+	// left to quarantine and replay rather than paging on-call forever
+	funnelHealthWebhookHorizon = 24 * time.Hour
+)
+
+// Minimum samples before a rate is scored
+const (
+	funnelHealthMinVerifications = 20
+	funnelHealthMinImpressions   = 50
+)
+
+// HealthStatus is a traffic light; unknown means too little data to score
+type HealthStatus string
+
+const (
+	HealthGreen   HealthStatus = "green"
+	HealthYellow  HealthStatus = "yellow"
+	HealthRed     HealthStatus = "red"
+	HealthUnknown HealthStatus = "unknown"
+)
+
+var healthSeverity = map[HealthStatus]int{HealthUnknown: 0, HealthGreen: 1, HealthYellow: 2, HealthRed: 3}
+
+// FunnelHealthFactor is one signal contributing to the funnel status
+type FunnelHealthFactor struct {
+	Name   string       `json:"name"`
+	Status HealthStatus `json:"status"`
+	Value  float64      `json:"value"`
+	// Warn and Critical are the yellow and red thresholds; for conversion
+	// rate vs baseline lower values are worse
+	Warn     float64 `json:"warn"`
+	Critical float64 `json:"critical"`
+	Detail   string  `json:"detail"`
+}
+
+// FunnelHealth is the worst status among the factors
+type FunnelHealth struct {
+	Status    HealthStatus         `json:"status"`
+	CheckedAt time.Time            `json:"checked_at"`
+	Factors   []FunnelHealthFactor `json:"factors"`
+}
+
+// FunnelHealthSignals are the raw readings a funnel health check is scored from
+type FunnelHealthSignals struct {
+	// VerificationsMeasured is false when verification outcomes are not recorded
+	VerificationsMeasured bool
+	Verifications         int64
+	VerificationFailures  int64
+
+	UnprocessedWebhooks int64
+	OldestWebhookAge    time.Duration
+	FailedWebhooks      int64
+
+	ImpressionUsers         int64
+	ConvertedUsers          int64
+	BaselineImpressionUsers int64
+	BaselineConvertedUsers  int64
+
+	PendingStagedEvents int64
+	OldestStagedAge     time.Duration
+}
+
+// ScoreFunnelHealth turns readings into factors and an overall status
+func ScoreFunnelHealth(s FunnelHealthSignals, now time.Time) *FunnelHealth {
+	factors := []FunnelHealthFactor{
+		verificationFactor(s),
+		{
+			Name:     "webhook_lag_seconds",
+			Value:    s.OldestWebhookAge.Seconds(),
+			Warn:     (5 * time.Minute).Seconds(),
+			Critical: (30 * time.Minute).Seconds(),
+			Detail: fmt.Sprintf("%d webhooks waiting, %d failed in the last %s",
+				s.UnprocessedWebhooks, s.FailedWebhooks, funnelHealthConversionWindow),
+		},
+		conversionFactor(s),
+		{
+			Name:     "staged_event_backlog_seconds",
+			Value:    s.OldestStagedAge.Seconds(),
+			Warn:     (15 * time.Minute).Seconds(),
+			Critical: time.Hour.Seconds(),
+			Detail:   fmt.Sprintf("%d analytics events pending delivery", s.PendingStagedEvents),
+		},
+	}
+
+	health := &FunnelHealth{Status: HealthUnknown, CheckedAt: now, Factors: factors}
+	for i := range health.Factors {
+		f := &health.Factors[i]
+		if f.Status == "" {
+			f.Status = levelAbove(f.Value, f.Warn, f.Critical)
+		}
+		if healthSeverity[f.Status] > healthSeverity[health.Status] {
+			health.Status = f.Status
+		}
+	}
+	return health
+}
+
+func verificationFactor(s FunnelHealthSignals) FunnelHealthFactor {
+	f := FunnelHealthFactor{Name: "verification_error_rate", Warn: 0.05, Critical: 0.15}
+	switch {
+	case !s.VerificationsMeasured:
+		f.Status = HealthUnknown
+		f.Detail = "verification outcomes are not recorded"
+	case s.Verifications < funnelHealthMinVerifications:
+		f.Status = HealthUnknown
+		f.Detail = fmt.Sprintf("%d verifications in the last %s, too few to score", s.Verifications, funnelHealthVerificationWindow)
+	default:
+		f.Value = float64(s.VerificationFailures) / float64(s.Verifications)
+		f.Detail = fmt.Sprintf("%d of %d verifications failed in the last %s", s.VerificationFailures, s.Verifications, funnelHealthVerificationWindow)
+	}
+	return f
+}
+
+func conversionFactor(s FunnelHealthSignals) FunnelHealthFactor {
+	f := FunnelHealthFactor{Name: "conversion_rate_vs_baseline", Warn: 0.7, Critical: 0.4}
+	if s.ImpressionUsers < funnelHealthMinImpressions || s.BaselineImpressionUsers == 0 || s.BaselineConvertedUsers == 0 {
+		f.Status = HealthUnknown
+		f.Detail = fmt.Sprintf("%d paywall viewers in the last %s, too few to compare with the 7-day baseline", s.ImpressionUsers, funnelHealthConversionWindow)
+		return f
+	}
+	current := float64(s.ConvertedUsers) / float64(s.ImpressionUsers)
+	baseline := float64(s.BaselineConvertedUsers) / float64(s.BaselineImpressionUsers)
+	f.Value = current / baseline
+	f.Status = levelBelow(f.Value, f.Warn, f.Critical)
+	f.Detail = fmt.Sprintf("conversion %.2f%% in the last %s vs %.2f%% over 7 days", current*100, funnelHealthConversionWindow, baseline*100)
+	return f
+}
+
+func levelAbove(value, warn, critical float64) HealthStatus {
+	switch {
+	case value >= critical:
+		return HealthRed
+	case value >= warn:
+		return HealthYellow
+	default:
+		return HealthGreen
+	}
+}
+
+func levelBelow(value, warn, critical float64) HealthStatus {
+	switch {
+	case value <= critical:
+		return HealthRed
+	case value <= warn:
+		return HealthYellow
+	default:
+		return HealthGreen
+	}
+}
+
+// verificationCounter reads recent receipt verification outcomes
+type verificationCounter interface {
+	VerificationCounts(ctx context.Context, appID uuid.UUID, window time.Duration, now time.Time) (int64, int64, error)
+}
+
+// FunnelHealthService combines realtime signals of the purchase funnel into
+// one status for on-call triage
+type FunnelHealthService struct {
+	dbPool        *pgxpool.Pool
+	verifications verificationCounter
+	now           func() time.Time
+}
+
+// NewFunnelHealthService creates a new funnel health service
+func NewFunnelHealthService(dbPool *pgxpool.Pool) *FunnelHealthService {
+	return &FunnelHealthService{dbPool: dbPool, now: time.Now}
+}
+
+// WithVerificationCounts scores the receipt verification error rate
+func (s *FunnelHealthService) WithVerificationCounts(verifications verificationCounter) *FunnelHealthService {
+	s.verifications = verifications
+	return s
+}
+
+// Check reads the app's current signals and scores them
+func (s *FunnelHealthService) Check(ctx context.Context, appID uuid.UUID) (*FunnelHealth, error) {
+	now := s.now()
+	var signals FunnelHealthSignals
+
+	if s.verifications != nil {
+		total, failed, err := s.verifications.VerificationCounts(ctx, appID, funnelHealthVerificationWindow, now)
+		if err != nil {
+			return nil, err
+		}
+		signals.VerificationsMeasured = true
+		signals.Verifications, signals.VerificationFailures = total, failed
+	}
+
+	var oldestWebhook, oldestStaged float64
+	err := s.dbPool.QueryRow(ctx, `
+		SELECT
+			COUNT(*) FILTER (WHERE status IN ('received', 'processing')),
+			COALESCE(EXTRACT(EPOCH FROM $2 - MIN(created_at) FILTER (WHERE status IN ('received', 'processing'))), 0)::float8,
+			COUNT(*) FILTER (WHERE status = 'failed' AND created_at >= $4)
+		FROM webhook_events
+		WHERE app_id = $1 AND created_at >= $3
+	`, appID, now, now.Add(-funnelHealthWebhookHorizon), now.Add(-funnelHealthConversionWindow)).Scan(
+		&signals.UnprocessedWebhooks, &oldestWebhook, &signals.FailedWebhooks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhook backlog: %w", err)
+	}
+	signals.OldestWebhookAge = time.Duration(oldestWebhook * float64(time.Second))
+
+	err = s.dbPool.QueryRow(ctx, `
+		WITH shown AS (
+			SELECT e.user_id, e.occurred_at >= $3 AS recent
+			FROM bandit_impression_events e
+			JOIN ab_tests t ON t.id = e.experiment_id
+			WHERE t.app_id = $1 AND e.event_type = 'impression'
+			  AND e.occurred_at >= $2 AND e.occurred_at < $4
+		),
+		converted AS (
+			SELECT c.user_id, c.occurred_at >= $3 AS recent
+			FROM bandit_conversion_events c
+			JOIN ab_tests t ON t.id = c.experiment_id
+			WHERE t.app_id = $1 AND c.normalized_reward_value > 0
+			  AND c.occurred_at >= $2 AND c.occurred_at < $4
+		)
+		SELECT
+			(SELECT COUNT(DISTINCT user_id) FROM shown WHERE recent),
+			(SELECT COUNT(DISTINCT user_id) FROM converted WHERE recent),
+			(SELECT COUNT(DISTINCT user_id) FROM shown WHERE NOT recent),
+			(SELECT COUNT(DISTINCT user_id) FROM converted WHERE NOT recent)
+	`, appID, now.Add(-funnelHealthConversionWindow-funnelHealthBaselineWindow), now.Add(-funnelHealthConversionWindow), now).Scan(
+		&signals.ImpressionUsers, &signals.ConvertedUsers,
+		&signals.BaselineImpressionUsers, &signals.BaselineConvertedUsers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read paywall conversion: %w", err)
+	}
+
+	err = s.dbPool.QueryRow(ctx, `
+		SELECT COUNT(*), COALESCE(EXTRACT(EPOCH FROM $2 - MIN(created_at)), 0)::float8
+		FROM matomo_staged_events
+		WHERE app_id = $1 AND status IN ('pending', 'processing')
+	`, appID, now).Scan(&signals.PendingStagedEvents, &oldestStaged)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read staged event backlog: %w", err)
+	}
+	signals.OldestStagedAge = time.Duration(oldestStaged * float64(time.Second))
+
+	return ScoreFunnelHealth(signals, now), nil
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func funnelFactor(t *testing.T, h *FunnelHealth, name string) FunnelHealthFactor {
+	t.Helper()
+	for _, f := range h.Factors {
+		if f.Name == name {
+			return f
+		}
+	}
+	require.Failf(t, "missing factor", name)
+	return FunnelHealthFactor{}
+}
+
+func TestScoreFunnelHealth_HealthyFunnel(t *testing.T) {
+	h := ScoreFunnelHealth(FunnelHealthSignals{
+		VerificationsMeasured: true, Verifications: 200, VerificationFailures: 2,
+		OldestWebhookAge: 10 * time.Second,
+		ImpressionUsers:  500, ConvertedUsers: 20,
+		BaselineImpressionUsers: 50000, BaselineConvertedUsers: 2000,
+		OldestStagedAge: time.Minute,
+	}, time.Now())
+
+	assert.Equal(t, HealthGreen, h.Status)
+	assert.InDelta(t, 1.0, funnelFactor(t, h, "conversion_rate_vs_baseline").Value, 0.001)
+}
+
+func TestScoreFunnelHealth_WorstFactorWins(t *testing.T) {
+	h := ScoreFunnelHealth(FunnelHealthSignals{
+		VerificationsMeasured: true, Verifications: 100, VerificationFailures: 8,
+		OldestWebhookAge: 45 * time.Minute, UnprocessedWebhooks: 120,
+		ImpressionUsers: 500, ConvertedUsers: 6,
+		BaselineImpressionUsers: 50000, BaselineConvertedUsers: 2000,
+	}, time.Now())
+
+	assert.Equal(t, HealthRed, h.Status)
+	assert.Equal(t, HealthYellow, funnelFactor(t, h, "verification_error_rate").Status)
+	assert.Equal(t, HealthRed, funnelFactor(t, h, "webhook_lag_seconds").Status)
+	assert.Equal(t, HealthRed, funnelFactor(t, h, "conversion_rate_vs_baseline").Status, "0.3x baseline")
+	assert.Equal(t, HealthGreen, funnelFactor(t, h, "staged_event_backlog_seconds").Status)
+}
+
+func TestScoreFunnelHealth_TooLittleDataIsUnknown(t *testing.T) {
+	h := ScoreFunnelHealth(FunnelHealthSignals{
+		VerificationsMeasured: true, Verifications: 3, VerificationFailures: 3,
+		ImpressionUsers: 10,
+	}, time.Now())
+
+	assert.Equal(t, HealthUnknown, funnelFactor(t, h, "verification_error_rate").Status)
+	assert.Equal(t, HealthUnknown, funnelFactor(t, h, "conversion_rate_vs_baseline").Status)
+	assert.Equal(t, HealthGreen, h.Status, "unknown factors do not degrade the status")
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/google/uuid"
 
+	"github.com/bivex/paywall-iap/internal/clock"
 	"github.com/bivex/paywall-iap/internal/domain/entity"
 	"github.com/bivex/paywall-iap/internal/domain/repository"
 )
@@ -23,6 +24,7 @@ type GracePeriodService struct {
 	gracePeriodRepo  repository.GracePeriodRepository
 	subscriptionRepo repository.SubscriptionRepository
 	userRepo         repository.UserRepository
+	now              func() time.Time
 }
 
 // NewGracePeriodService creates a new grace period service
@@ -35,14 +37,22 @@ func NewGracePeriodService(
 		gracePeriodRepo:  gracePeriodRepo,
 		subscriptionRepo: subscriptionRepo,
 		userRepo:         userRepo,
+		now:              time.Now,
 	}
 }
 
+// WithClock makes the service read the current time from c
+func (s *GracePeriodService) WithClock(c clock.Clock) *GracePeriodService {
+	s.now = c.Now
+	return s
+}
+
 // CreateGracePeriod creates a new grace period for a subscription
 func (s *GracePeriodService) CreateGracePeriod(ctx context.Context, userID, subscriptionID uuid.UUID, durationDays int) (*entity.GracePeriod, error) {
 	// Check if active grace period already exists
+	now := s.now()
 	existing, err := s.gracePeriodRepo.GetActiveBySubscriptionID(ctx, subscriptionID)
-	if err == nil && existing != nil && existing.IsActive() {
+	if err == nil && existing != nil && existing.IsActiveAt(now) {
 		return nil, ErrGracePeriodAlreadyExists
 	}
 
@@ -57,8 +67,8 @@ func (s *GracePeriodService) CreateGracePeriod(ctx context.Context, userID, subs
 	}
 
 	// Create grace period
-	expiresAt := time.Now().Add(time.Duration(durationDays) * 24 * time.Hour)
-	gracePeriod := entity.NewGracePeriod(userID, subscriptionID, expiresAt)
+	expiresAt := now.Add(time.Duration(durationDays) * 24 * time.Hour)
+	gracePeriod := entity.NewGracePeriodAt(userID, subscriptionID, expiresAt, now)
 
 	// Update subscription status to grace
 	err = s.subscriptionRepo.UpdateStatus(ctx, subscriptionID, entity.StatusGrace)
@@ -83,12 +93,13 @@ func (s *GracePeriodService) ResolveGracePeriod(ctx context.Context, userID, sub
 		return ErrGracePeriodNotFound
 	}
 
-	if !gracePeriod.IsActive() {
+	now := s.now()
+	if !gracePeriod.IsActiveAt(now) {
 		return ErrGracePeriodNotActive
 	}
 
 	// Resolve grace period
-	err = gracePeriod.Resolve()
+	err = gracePeriod.ResolveAt(now)
 	if err != nil {
 		return err
 	}
@@ -121,7 +132,7 @@ func (s *GracePeriodService) ExpireGracePeriod(ctx context.Context, gracePeriodI
 	}
 
 	// Expire grace period
-	err = gracePeriod.Expire()
+	err = gracePeriod.ExpireAt(s.now())
 	if err != nil {
 		return err
 	}
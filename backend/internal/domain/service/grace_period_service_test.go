@@ -11,6 +11,7 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
+	"github.com/bivex/paywall-iap/internal/clock"
 	"github.com/bivex/paywall-iap/internal/domain/entity"
 	"github.com/bivex/paywall-iap/internal/domain/service"
 	"github.com/bivex/paywall-iap/tests/mocks"
@@ -95,4 +96,32 @@ func TestGracePeriodService(t *testing.T) {
 		require.NoError(t, err)
 		assert.Equal(t, entity.GraceStatusExpired, gracePeriod.Status)
 	})
+
+	t.Run("grace period runs out on the service clock", func(t *testing.T) {
+		gracePeriodRepo := mocks.NewMockGracePeriodRepository()
+		subscriptionRepo := mocks.NewMockSubscriptionRepository()
+		userRepo := mocks.NewMockUserRepository()
+		fakeClock := clock.NewFake(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+		graceService := service.NewGracePeriodService(gracePeriodRepo, subscriptionRepo, userRepo).WithClock(fakeClock)
+
+		userID := uuid.New()
+		subscriptionID := uuid.New()
+		gracePeriodRepo.On("GetActiveBySubscriptionID", ctx, subscriptionID).Return(nil, errors.New("not found")).Once()
+		subscriptionRepo.On("GetByID", ctx, subscriptionID).Return(&entity.Subscription{ID: subscriptionID, UserID: userID}, nil)
+		subscriptionRepo.On("UpdateStatus", ctx, subscriptionID, entity.StatusGrace).Return(nil)
+		gracePeriodRepo.On("Create", ctx, mock.Anything).Return(nil)
+
+		gracePeriod, err := graceService.CreateGracePeriod(ctx, userID, subscriptionID, 3)
+		require.NoError(t, err)
+		assert.Equal(t, fakeClock.Now(), gracePeriod.CreatedAt)
+		assert.Equal(t, fakeClock.Now().Add(3*24*time.Hour), gracePeriod.ExpiresAt)
+
+		// A payment arriving after the grace period can no longer resolve it
+		fakeClock.Advance(3*24*time.Hour + time.Minute)
+		gracePeriodRepo.On("GetActiveBySubscriptionID", ctx, subscriptionID).Return(gracePeriod, nil)
+
+		err = graceService.ResolveGracePeriod(ctx, userID, subscriptionID)
+		assert.ErrorIs(t, err, service.ErrGracePeriodNotActive)
+		assert.Equal(t, entity.GraceStatusActive, gracePeriod.Status)
+	})
 }
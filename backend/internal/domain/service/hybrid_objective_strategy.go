@@ -29,6 +29,9 @@ type ObjectiveScore struct {
 	Conversions   int
 	Revenue       float64
 	AvgLTV        float64
+	// OriginalRevenue sums the rewards behind the objective's stats in
+	// their original currencies
+	OriginalRevenue map[string]float64
 }
 
 // ArmObjectiveStats represents per-objective statistics for an arm
@@ -41,13 +44,28 @@ type ArmObjectiveStats struct {
 	Conversions   int
 	TotalRevenue  float64
 	AvgLTV        float64
+	RewardBasis   RevenueBasis
+	// OriginalRevenue sums converted rewards by the currency they arrived in.
+	// Nil leaves the stored amounts untouched on update.
+	OriginalRevenue map[string]float64
 }
 
-// ObjectiveRepository defines the repository interface for objective stats
+// ObjectiveRepository defines the repository interface for objective stats.
+// Stats are keyed by reward basis; see ObjectiveStatsBasis.
 type ObjectiveRepository interface {
-	GetObjectiveStats(ctx context.Context, armID uuid.UUID, objectiveType ObjectiveType) (*ArmObjectiveStats, error)
+	GetObjectiveStats(ctx context.Context, armID uuid.UUID, objectiveType ObjectiveType, basis RevenueBasis) (*ArmObjectiveStats, error)
 	UpdateObjectiveStats(ctx context.Context, stats *ArmObjectiveStats) error
-	GetAllObjectiveStats(ctx context.Context, armID uuid.UUID) (map[ObjectiveType]*ArmObjectiveStats, error)
+	GetAllObjectiveStats(ctx context.Context, armID uuid.UUID, basis RevenueBasis) (map[ObjectiveType]*ArmObjectiveStats, error)
+}
+
+// ObjectiveStatsBasis returns the basis objective stats are stored under.
+// Conversion counts don't depend on the amount, so they are always kept
+// under gross; revenue and LTV stats follow the experiment's basis.
+func ObjectiveStatsBasis(objectiveType ObjectiveType, basis RevenueBasis) RevenueBasis {
+	if objectiveType == ObjectiveConversion || basis == "" {
+		return RevenueBasisGross
+	}
+	return basis
 }
 
 // NewHybridObjectiveStrategy creates a new hybrid objective strategy
@@ -130,7 +148,7 @@ func (s *HybridObjectiveStrategy) calculateLVTScore(ctx context.Context, armID u
 		return 0, nil
 	}
 
-	objStats, err := objRepo.GetObjectiveStats(ctx, armID, ObjectiveLTV)
+	objStats, err := objRepo.GetObjectiveStats(ctx, armID, ObjectiveLTV, ObjectiveStatsBasis(ObjectiveLTV, s.config.RewardBasis))
 	if err != nil {
 		// Fall back to basic conversion probability
 		return s.baseBandit.SampleBeta(stats.Alpha, stats.Beta), nil
@@ -145,21 +163,16 @@ func (s *HybridObjectiveStrategy) calculateLVTScore(ctx context.Context, armID u
 	return expectedLTV, nil
 }
 
-// calculateRevenueScore uses Normalized Revenue = P(conv) × (Revenue / Price)
+// calculateRevenueScore draws revenue per visitor from the arm's
+// zero-inflated lognormal posterior, so arms with uncertain revenue are
+// explored instead of being ranked by their point estimate
 func (s *HybridObjectiveStrategy) calculateRevenueScore(ctx context.Context, armID uuid.UUID) (float64, error) {
 	stats, err := s.repo.GetArmStats(ctx, armID)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get arm stats: %w", err)
 	}
 
-	// P(conversion)
-	conversionProb := stats.Alpha / (stats.Alpha + stats.Beta)
-
-	// Average revenue per sample
-	avgRevenue := stats.AvgReward
-
-	// Score = conversion probability × average revenue
-	return conversionProb * avgRevenue, nil
+	return s.baseBandit.SampleRevenuePerVisitor(stats), nil
 }
 
 // calculateHybridScore combines multiple objectives with weights
@@ -254,13 +267,17 @@ func (s *HybridObjectiveStrategy) normalizeScores(scores map[string]float64) map
 	return normalized
 }
 
-// RecordObjectiveReward records a reward for a specific objective
+// RecordObjectiveReward records a reward for a specific objective.
+// originalAmount and originalCurrency are the reward as received, before
+// currency conversion and reward basis.
 func (s *HybridObjectiveStrategy) RecordObjectiveReward(
 	ctx context.Context,
 	armID uuid.UUID,
 	objectiveType ObjectiveType,
 	reward float64,
 	ltv float64,
+	originalAmount float64,
+	originalCurrency string,
 ) error {
 	objRepo, ok := s.repo.(ObjectiveRepository)
 	if !ok {
@@ -268,7 +285,8 @@ func (s *HybridObjectiveStrategy) RecordObjectiveReward(
 	}
 
 	// Get existing stats
-	stats, err := objRepo.GetObjectiveStats(ctx, armID, objectiveType)
+	basis := ObjectiveStatsBasis(objectiveType, s.config.RewardBasis)
+	stats, err := objRepo.GetObjectiveStats(ctx, armID, objectiveType, basis)
 	if err != nil {
 		// Initialize new stats
 		stats = &ArmObjectiveStats{
@@ -282,6 +300,7 @@ func (s *HybridObjectiveStrategy) RecordObjectiveReward(
 			AvgLTV:        0,
 		}
 	}
+	stats.RewardBasis = basis
 
 	// Update stats
 	stats.Samples++
@@ -289,6 +308,13 @@ func (s *HybridObjectiveStrategy) RecordObjectiveReward(
 		stats.Alpha += 1.0
 		stats.Conversions++
 		stats.TotalRevenue += reward
+		if originalCurrency == "" {
+			originalCurrency = "USD"
+		}
+		if stats.OriginalRevenue == nil {
+			stats.OriginalRevenue = make(map[string]float64)
+		}
+		stats.OriginalRevenue[originalCurrency] += originalAmount
 	} else {
 		stats.Beta += 1.0
 	}
@@ -368,7 +394,7 @@ func (s *HybridObjectiveStrategy) GetObjectiveScores(
 			}
 		}
 
-		objStats, err := objRepo.GetObjectiveStats(ctx, armID, objective)
+		objStats, err := objRepo.GetObjectiveStats(ctx, armID, objective, ObjectiveStatsBasis(objective, s.config.RewardBasis))
 		if err != nil || objStats == nil {
 			return &ArmObjectiveStats{
 				ArmID:         armID,
@@ -390,13 +416,14 @@ func (s *HybridObjectiveStrategy) GetObjectiveScores(
 		if err == nil {
 			objStat := getObjectiveStats(ObjectiveLTV)
 			scores[ObjectiveLTV] = &ObjectiveScore{
-				ObjectiveType: ObjectiveLTV,
-				Score:         ltvScore,
-				Alpha:         objStat.Alpha,
-				Beta:          objStat.Beta,
-				Samples:       objStat.Samples,
-				Conversions:   objStat.Conversions,
-				AvgLTV:        objStat.AvgLTV,
+				ObjectiveType:   ObjectiveLTV,
+				Score:           ltvScore,
+				Alpha:           objStat.Alpha,
+				Beta:            objStat.Beta,
+				Samples:         objStat.Samples,
+				Conversions:     objStat.Conversions,
+				AvgLTV:          objStat.AvgLTV,
+				OriginalRevenue: objStat.OriginalRevenue,
 			}
 		}
 	}
@@ -406,13 +433,14 @@ func (s *HybridObjectiveStrategy) GetObjectiveScores(
 		if err == nil {
 			objStat := getObjectiveStats(ObjectiveRevenue)
 			scores[ObjectiveRevenue] = &ObjectiveScore{
-				ObjectiveType: ObjectiveRevenue,
-				Score:         revenueScore,
-				Alpha:         objStat.Alpha,
-				Beta:          objStat.Beta,
-				Samples:       objStat.Samples,
-				Conversions:   objStat.Conversions,
-				Revenue:       objStat.TotalRevenue,
+				ObjectiveType:   ObjectiveRevenue,
+				Score:           revenueScore,
+				Alpha:           objStat.Alpha,
+				Beta:            objStat.Beta,
+				Samples:         objStat.Samples,
+				Conversions:     objStat.Conversions,
+				Revenue:         objStat.TotalRevenue,
+				OriginalRevenue: objStat.OriginalRevenue,
 			}
 		}
 	}
@@ -0,0 +1,308 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+// ErrInvalidInAppMessageEvent is returned for unknown event types and arms
+// that are not variants of the message
+var ErrInvalidInAppMessageEvent = errors.New("invalid in-app message event")
+
+// audienceBuilder builds the paywall rule audience of a user
+type audienceBuilder interface {
+	Audience(ctx context.Context, userID uuid.UUID, country string) (entity.PaywallAudience, error)
+}
+
+// messageBandit picks message variants and learns from clicks
+type messageBandit interface {
+	candidateArmSelector
+	TrackImpression(ctx context.Context, experimentID, armID, userID uuid.UUID, event *ImpressionEvent) error
+	UpdateRewardWithEvent(ctx context.Context, experimentID, armID uuid.UUID, reward float64, event *ConversionEvent) error
+}
+
+// InAppMessageDelivery is a message selected for a user
+type InAppMessageDelivery struct {
+	MessageID    uuid.UUID                  `json:"message_id"`
+	Name         string                     `json:"name"`
+	Format       entity.InAppMessageFormat  `json:"format"`
+	Content      entity.InAppMessageContent `json:"content"`
+	ExperimentID *uuid.UUID                 `json:"experiment_id,omitempty"`
+	ArmID        *uuid.UUID                 `json:"arm_id,omitempty"`
+}
+
+// InAppMessageService schedules in-app prompts. Messages are filtered by
+// schedule, placement, subscription trigger, rule conditions and per-user
+// caps; the highest priority message of each format is shown. Messages that
+// run an experiment let the bandit choose the variant, and the first click
+// or dismissal of a user scores it.
+type InAppMessageService struct {
+	repo        repository.InAppMessageRepository
+	audience    audienceBuilder
+	subRepo     repository.SubscriptionRepository
+	redemptions repository.OfferRedemptionRepository
+	segments    segmentMatcher
+	bandit      messageBandit
+	logger      *zap.Logger
+	now         func() time.Time
+}
+
+// NewInAppMessageService creates a new in-app message service
+func NewInAppMessageService(
+	repo repository.InAppMessageRepository,
+	audience audienceBuilder,
+	subRepo repository.SubscriptionRepository,
+	redemptions repository.OfferRedemptionRepository,
+	logger *zap.Logger,
+) *InAppMessageService {
+	return &InAppMessageService{
+		repo:        repo,
+		audience:    audience,
+		subRepo:     subRepo,
+		redemptions: redemptions,
+		logger:      logger,
+		now:         time.Now,
+	}
+}
+
+// WithSegments enables segment_id conditions and experiment segment targeting
+func (s *InAppMessageService) WithSegments(segments segmentMatcher) *InAppMessageService {
+	s.segments = segments
+	return s
+}
+
+// WithBandit lets messages with variants optimize them; without it the
+// default content is shown
+func (s *InAppMessageService) WithBandit(bandit messageBandit) *InAppMessageService {
+	s.bandit = bandit
+	return s
+}
+
+// Messages returns the messages to show the user at placement, at most one
+// per format
+func (s *InAppMessageService) Messages(ctx context.Context, appID, userID uuid.UUID, placement, country string) ([]InAppMessageDelivery, error) {
+	now := s.now()
+	messages, err := s.repo.List(ctx, appID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list in-app messages: %w", err)
+	}
+	var live []*entity.InAppMessage
+	for _, m := range messages {
+		if m.Live(now) && m.AppliesTo(placement) {
+			live = append(live, m)
+		}
+	}
+	deliveries := []InAppMessageDelivery{}
+	if len(live) == 0 {
+		return deliveries, nil
+	}
+
+	audience, err := s.audience.Audience(ctx, userID, country)
+	if err != nil {
+		return nil, err
+	}
+	state, err := s.subscriberState(ctx, appID, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	history, err := s.repo.History(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	shown := map[entity.InAppMessageFormat]bool{}
+	checked := map[uuid.UUID]bool{}
+	for _, m := range live {
+		if shown[m.Format] || !m.Triggered(state, now) || m.Capped(history[m.ID], now) != "" {
+			continue
+		}
+		if id := m.Conditions.SegmentID; id != nil && !checked[*id] {
+			checked[*id] = true
+			if s.isMember(ctx, appID, *id, userID, country) {
+				audience.Segments = append(audience.Segments, *id)
+			}
+		}
+		if m.Conditions.Mismatch(audience) != "" {
+			continue
+		}
+		delivery, err := s.deliver(ctx, m, userID, country)
+		if err != nil {
+			return nil, err
+		}
+		shown[m.Format] = true
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, nil
+}
+
+func (s *InAppMessageService) isMember(ctx context.Context, appID, segmentID, userID uuid.UUID, country string) bool {
+	if s.segments == nil {
+		return false
+	}
+	ok, err := s.segments.IsMember(ctx, appID, segmentID, userID, country)
+	if err != nil {
+		s.logger.Warn("segment unavailable for in-app messages", zap.String("segment_id", segmentID.String()), zap.Error(err))
+		return false
+	}
+	return ok
+}
+
+// deliver picks the variant of a message for the user
+func (s *InAppMessageService) deliver(ctx context.Context, m *entity.InAppMessage, userID uuid.UUID, country string) (InAppMessageDelivery, error) {
+	delivery := InAppMessageDelivery{MessageID: m.ID, Name: m.Name, Format: m.Format, Content: m.Content}
+	if m.ExperimentID == nil || s.bandit == nil {
+		return delivery, nil
+	}
+	if s.segments != nil {
+		allowed, err := s.segments.AllowsExperiment(ctx, *m.ExperimentID, userID, country)
+		if err != nil {
+			return delivery, err
+		}
+		if !allowed {
+			return delivery, nil
+		}
+	}
+	armID, err := s.bandit.SelectArmFrom(ctx, *m.ExperimentID, userID, m.ArmIDs())
+	if errors.Is(err, ErrUserExcluded) || errors.Is(err, ErrExperimentPaused) {
+		return delivery, nil
+	}
+	if err != nil {
+		return delivery, fmt.Errorf("failed to select message variant: %w", err)
+	}
+	delivery.ExperimentID = m.ExperimentID
+	delivery.ArmID = &armID
+	delivery.Content = m.ContentFor(&armID)
+	return delivery, nil
+}
+
+// subscriberState derives the trigger input from the user's subscriptions.
+// A subscription is in trial while a free trial of its product was redeemed
+// within its current billing period.
+func (s *InAppMessageService) subscriberState(ctx context.Context, appID, userID uuid.UUID, now time.Time) (entity.SubscriberState, error) {
+	var state entity.SubscriberState
+	subs, err := s.subRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return state, err
+	}
+	var latest *entity.Subscription
+	for _, sub := range subs {
+		if latest == nil || sub.ExpiresAt.After(latest.ExpiresAt) {
+			latest = sub
+		}
+	}
+	if latest == nil {
+		return state, nil
+	}
+	expiresAt := latest.ExpiresAt
+	state.ExpiresAt = &expiresAt
+	state.Subscribed = (latest.Status == entity.StatusActive || latest.Status == entity.StatusGrace) && expiresAt.After(now)
+	if !state.Subscribed {
+		return state, nil
+	}
+
+	var periodStart time.Time
+	switch latest.PlanType {
+	case entity.PlanMonthly:
+		periodStart = expiresAt.AddDate(0, -1, 0)
+	case entity.PlanAnnual:
+		periodStart = expiresAt.AddDate(-1, 0, 0)
+	default:
+		return state, nil
+	}
+	redemptions, err := s.redemptions.GetByUserID(ctx, appID, userID)
+	if err != nil {
+		return state, fmt.Errorf("failed to load offer redemptions: %w", err)
+	}
+	for _, r := range redemptions {
+		if r.OfferType == entity.OfferTypeFreeTrial && r.ProductID == latest.ProductID && r.RedeemedAt.After(periodStart) {
+			state.InTrial = true
+			break
+		}
+	}
+	return state, nil
+}
+
+// RecordEvent stores an event the client reported for a message. armID is
+// the variant it was shown, if any.
+func (s *InAppMessageService) RecordEvent(ctx context.Context, appID, userID, messageID uuid.UUID, eventType entity.InAppMessageEventType, armID *uuid.UUID, placement string) error {
+	if !eventType.IsValid() {
+		return fmt.Errorf("%w: unknown event %q", ErrInvalidInAppMessageEvent, eventType)
+	}
+	m, err := s.repo.GetByID(ctx, appID, messageID)
+	if err != nil {
+		return err
+	}
+	if armID != nil && !slices.Contains(m.ArmIDs(), *armID) {
+		return fmt.Errorf("%w: arm %s is not a variant of the message", ErrInvalidInAppMessageEvent, armID)
+	}
+
+	// Only the first click or dismissal scores the arm
+	closed := false
+	if eventType != entity.InAppMessageImpression && armID != nil {
+		history, err := s.repo.History(ctx, userID)
+		if err != nil {
+			return err
+		}
+		closed = history[messageID].Closed
+	}
+
+	now := s.now().UTC()
+	if err := s.repo.RecordEvent(ctx, &entity.InAppMessageEvent{
+		ID:         uuid.New(),
+		MessageID:  messageID,
+		UserID:     userID,
+		ArmID:      armID,
+		Type:       eventType,
+		OccurredAt: now,
+	}); err != nil {
+		return err
+	}
+	if armID == nil || m.ExperimentID == nil || s.bandit == nil || closed {
+		return nil
+	}
+
+	metadata := map[string]interface{}{"in_app_message_id": messageID.String()}
+	switch eventType {
+	case entity.InAppMessageImpression:
+		err = s.bandit.TrackImpression(ctx, *m.ExperimentID, *armID, userID, &ImpressionEvent{
+			EventType:  ImpressionEventTypeImpression,
+			Placement:  placement,
+			Metadata:   metadata,
+			OccurredAt: now,
+		})
+	default:
+		reward := 0.0
+		if eventType == entity.InAppMessageClick {
+			reward = 1
+		}
+		err = s.bandit.UpdateRewardWithEvent(ctx, *m.ExperimentID, *armID, reward, &ConversionEvent{
+			UserID:     &userID,
+			EventType:  ConversionEventTypeDirectReward,
+			Metadata:   metadata,
+			OccurredAt: now,
+		})
+	}
+	if err != nil {
+		// The event is stored; only the bandit misses it
+		s.logger.Warn("Failed to report in-app message event to bandit",
+			zap.String("message_id", messageID.String()), zap.String("event", string(eventType)), zap.Error(err))
+	}
+	return nil
+}
+
+// Stats totals a message's events per arm
+func (s *InAppMessageService) Stats(ctx context.Context, appID, messageID uuid.UUID) ([]entity.InAppMessageStats, error) {
+	if _, err := s.repo.GetByID(ctx, appID, messageID); err != nil {
+		return nil, err
+	}
+	return s.repo.Stats(ctx, messageID)
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+type stubInAppMessageRepo struct {
+	repository.InAppMessageRepository
+	messages []*entity.InAppMessage
+	history  map[uuid.UUID]entity.InAppMessageHistory
+	events   []*entity.InAppMessageEvent
+}
+
+func (s *stubInAppMessageRepo) List(ctx context.Context, appID uuid.UUID) ([]*entity.InAppMessage, error) {
+	return s.messages, nil
+}
+
+func (s *stubInAppMessageRepo) GetByID(ctx context.Context, appID, id uuid.UUID) (*entity.InAppMessage, error) {
+	return s.messages[0], nil
+}
+
+func (s *stubInAppMessageRepo) History(ctx context.Context, userID uuid.UUID) (map[uuid.UUID]entity.InAppMessageHistory, error) {
+	return s.history, nil
+}
+
+func (s *stubInAppMessageRepo) RecordEvent(ctx context.Context, event *entity.InAppMessageEvent) error {
+	s.events = append(s.events, event)
+	return nil
+}
+
+type stubAudience entity.PaywallAudience
+
+func (s stubAudience) Audience(ctx context.Context, userID uuid.UUID, country string) (entity.PaywallAudience, error) {
+	return entity.PaywallAudience(s), nil
+}
+
+type stubMessageSubRepo struct {
+	repository.SubscriptionRepository
+	subs []*entity.Subscription
+}
+
+func (s *stubMessageSubRepo) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*entity.Subscription, error) {
+	return s.subs, nil
+}
+
+type stubMessageRedemptions struct {
+	repository.OfferRedemptionRepository
+	redemptions []*entity.OfferRedemption
+}
+
+func (s *stubMessageRedemptions) GetByUserID(ctx context.Context, appID, userID uuid.UUID) ([]*entity.OfferRedemption, error) {
+	return s.redemptions, nil
+}
+
+type stubMessageBandit struct {
+	stubCandidateBandit
+	impressions int
+	rewards     []float64
+}
+
+func (s *stubMessageBandit) TrackImpression(ctx context.Context, experimentID, armID, userID uuid.UUID, event *ImpressionEvent) error {
+	s.impressions++
+	return nil
+}
+
+func (s *stubMessageBandit) UpdateRewardWithEvent(ctx context.Context, experimentID, armID uuid.UUID, reward float64, event *ConversionEvent) error {
+	s.rewards = append(s.rewards, reward)
+	return nil
+}
+
+func TestInAppMessageService_MessagesOnePerFormat(t *testing.T) {
+	appID, userID := uuid.New(), uuid.New()
+	now := time.Date(2026, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	trialEnding := entity.NewInAppMessage(appID, "trial ending", entity.InAppMessageBanner)
+	trialEnding.Trigger, trialEnding.TriggerWindowHours, trialEnding.Priority = entity.TriggerTrialEnding, 48, 10
+	generic := entity.NewInAppMessage(appID, "generic banner", entity.InAppMessageBanner)
+	capped := entity.NewInAppMessage(appID, "capped modal", entity.InAppMessageModal)
+	capped.MaxImpressions = 1
+	germany := entity.NewInAppMessage(appID, "germany modal", entity.InAppMessageModal)
+	germany.Conditions.Countries = []string{"DE"}
+
+	repo := &stubInAppMessageRepo{
+		messages: []*entity.InAppMessage{trialEnding, generic, capped, germany},
+		history:  map[uuid.UUID]entity.InAppMessageHistory{capped.ID: {Impressions: 1}},
+	}
+	subs := &stubMessageSubRepo{subs: []*entity.Subscription{{
+		ProductID: "pro_monthly", Status: entity.StatusActive, PlanType: entity.PlanMonthly, ExpiresAt: now.Add(24 * time.Hour),
+	}}}
+	redemptions := &stubMessageRedemptions{redemptions: []*entity.OfferRedemption{{
+		ProductID: "pro_monthly", OfferType: entity.OfferTypeFreeTrial, RedeemedAt: now.Add(-6 * 24 * time.Hour),
+	}}}
+	svc := NewInAppMessageService(repo, stubAudience{Country: "US"}, subs, redemptions, zap.NewNop())
+	svc.now = func() time.Time { return now }
+
+	got, err := svc.Messages(context.Background(), appID, userID, "home", "US")
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, trialEnding.ID, got[0].MessageID)
+
+	// After the trial converts the paid period no longer counts as a trial
+	redemptions.redemptions[0].RedeemedAt = now.Add(-40 * 24 * time.Hour)
+	got, err = svc.Messages(context.Background(), appID, userID, "home", "US")
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, generic.ID, got[0].MessageID)
+}
+
+func TestInAppMessageService_BanditVariantAndReward(t *testing.T) {
+	appID, userID := uuid.New(), uuid.New()
+	experimentID, armA, armB := uuid.New(), uuid.New(), uuid.New()
+	m := entity.NewInAppMessage(appID, "win-back", entity.InAppMessageModal)
+	m.ExperimentID = &experimentID
+	m.Variants = []entity.InAppMessageVariant{
+		{ArmID: armA, Content: entity.InAppMessageContent{Title: "A"}},
+		{ArmID: armB, Content: entity.InAppMessageContent{Title: "B"}},
+	}
+	repo := &stubInAppMessageRepo{messages: []*entity.InAppMessage{m}, history: map[uuid.UUID]entity.InAppMessageHistory{}}
+	bandit := &stubMessageBandit{}
+	svc := NewInAppMessageService(repo, stubAudience{}, &stubMessageSubRepo{}, &stubMessageRedemptions{}, zap.NewNop()).
+		WithBandit(bandit)
+
+	got, err := svc.Messages(context.Background(), appID, userID, "", "")
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, []uuid.UUID{armA, armB}, bandit.candidates)
+	assert.Equal(t, "B", got[0].Content.Title)
+
+	ctx := context.Background()
+	require.NoError(t, svc.RecordEvent(ctx, appID, userID, m.ID, entity.InAppMessageImpression, &armB, ""))
+	require.NoError(t, svc.RecordEvent(ctx, appID, userID, m.ID, entity.InAppMessageClick, &armB, ""))
+	repo.history[m.ID] = entity.InAppMessageHistory{Impressions: 1, Closed: true}
+	require.NoError(t, svc.RecordEvent(ctx, appID, userID, m.ID, entity.InAppMessageDismiss, &armB, ""))
+	assert.Equal(t, 1, bandit.impressions)
+	assert.Equal(t, []float64{1}, bandit.rewards, "only the first click or dismissal scores the arm")
+	assert.Len(t, repo.events, 3)
+
+	stranger := uuid.New()
+	assert.ErrorIs(t, svc.RecordEvent(ctx, appID, userID, m.ID, entity.InAppMessageClick, &stranger, ""), ErrInvalidInAppMessageEvent)
+}
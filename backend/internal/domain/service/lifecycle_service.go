@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+// lifecyclePageSize is how many users a recompute classifies per batch
+const lifecyclePageSize = 500
+
+// LifecycleStageCount is the number of an app's users in one stage
+type LifecycleStageCount struct {
+	Stage entity.LifecycleStage `json:"stage"`
+	Users int64                 `json:"users"`
+}
+
+// LifecycleService classifies users into lifecycle stages
+type LifecycleService struct {
+	repo   repository.LifecycleStageRepository
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+// NewLifecycleService creates a new lifecycle service
+func NewLifecycleService(repo repository.LifecycleStageRepository, logger *zap.Logger) *LifecycleService {
+	return &LifecycleService{repo: repo, logger: logger, now: time.Now}
+}
+
+// RecomputeAll classifies every user and stores their stage. Returns the
+// number of users classified and how many changed stage.
+func (s *LifecycleService) RecomputeAll(ctx context.Context) (int, int, error) {
+	now := s.now()
+	classified, changed := 0, 0
+	after := uuid.Nil
+	for {
+		signals, err := s.repo.ListSignals(ctx, after, lifecyclePageSize, now)
+		if err != nil {
+			return classified, changed, err
+		}
+		if len(signals) == 0 {
+			break
+		}
+		stages := make([]entity.UserLifecycleStage, len(signals))
+		for i, sig := range signals {
+			stage := entity.ClassifyLifecycleStage(sig, now)
+			if stage != sig.PreviousStage {
+				changed++
+			}
+			stages[i] = entity.UserLifecycleStage{UserID: sig.UserID, AppID: sig.AppID, Stage: stage, EnteredAt: now, ComputedAt: now}
+		}
+		if err := s.repo.SaveStages(ctx, stages); err != nil {
+			return classified, changed, err
+		}
+		classified += len(signals)
+		after = signals[len(signals)-1].UserID
+		if len(signals) < lifecyclePageSize {
+			break
+		}
+	}
+	s.logger.Info("Lifecycle stages recomputed",
+		zap.Int("users", classified),
+		zap.Int("changed", changed),
+		zap.Duration("took", s.now().Sub(now)),
+	)
+	return classified, changed, nil
+}
+
+// Stage returns the user's last computed stage, "" before the first run
+func (s *LifecycleService) Stage(ctx context.Context, userID uuid.UUID) (entity.LifecycleStage, error) {
+	stage, err := s.repo.GetStage(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if stage == nil {
+		return "", nil
+	}
+	return stage.Stage, nil
+}
+
+// StageCounts returns the app's users per stage, every stage included
+func (s *LifecycleService) StageCounts(ctx context.Context, appID uuid.UUID) ([]LifecycleStageCount, error) {
+	counts, err := s.repo.CountByStage(ctx, appID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count lifecycle stages: %w", err)
+	}
+	result := make([]LifecycleStageCount, len(entity.LifecycleStages))
+	for i, stage := range entity.LifecycleStages {
+		result[i] = LifecycleStageCount{Stage: stage, Users: counts[stage]}
+	}
+	return result, nil
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+type stubLifecycleRepo struct {
+	repository.LifecycleStageRepository
+	signals []entity.LifecycleSignals
+	saved   []entity.UserLifecycleStage
+	pages   int
+}
+
+func (s *stubLifecycleRepo) ListSignals(_ context.Context, after uuid.UUID, limit int, _ time.Time) ([]entity.LifecycleSignals, error) {
+	s.pages++
+	i := sort.Search(len(s.signals), func(i int) bool { return s.signals[i].UserID.String() > after.String() })
+	end := min(i+limit, len(s.signals))
+	return s.signals[i:end], nil
+}
+
+func (s *stubLifecycleRepo) SaveStages(_ context.Context, stages []entity.UserLifecycleStage) error {
+	s.saved = append(s.saved, stages...)
+	return nil
+}
+
+func (s *stubLifecycleRepo) CountByStage(context.Context, uuid.UUID) (map[entity.LifecycleStage]int64, error) {
+	return map[entity.LifecycleStage]int64{entity.LifecycleNew: 4, entity.LifecycleAtRisk: 1}, nil
+}
+
+func TestLifecycleService_RecomputeAllPagesThroughUsers(t *testing.T) {
+	now := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	repo := &stubLifecycleRepo{}
+	for i := 0; i < lifecyclePageSize+2; i++ {
+		repo.signals = append(repo.signals, entity.LifecycleSignals{UserID: uuid.New(), SignupAt: now.AddDate(0, 0, -1)})
+	}
+	sort.Slice(repo.signals, func(i, j int) bool { return repo.signals[i].UserID.String() < repo.signals[j].UserID.String() })
+	// Already classified as new, so it does not count as a change
+	repo.signals[0].PreviousStage = entity.LifecycleNew
+	svc := NewLifecycleService(repo, zap.NewNop())
+	svc.now = func() time.Time { return now }
+
+	classified, changed, err := svc.RecomputeAll(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, lifecyclePageSize+2, classified)
+	assert.Equal(t, lifecyclePageSize+1, changed)
+	assert.Equal(t, 2, repo.pages)
+	require.Len(t, repo.saved, lifecyclePageSize+2)
+	assert.Equal(t, entity.LifecycleNew, repo.saved[0].Stage)
+	assert.Equal(t, now, repo.saved[0].ComputedAt)
+}
+
+func TestLifecycleService_StageCountsIncludeEveryStage(t *testing.T) {
+	counts, err := NewLifecycleService(&stubLifecycleRepo{}, zap.NewNop()).StageCounts(context.Background(), uuid.New())
+	require.NoError(t, err)
+
+	require.Len(t, counts, len(entity.LifecycleStages))
+	assert.Equal(t, LifecycleStageCount{Stage: entity.LifecycleNew, Users: 4}, counts[0])
+	assert.Equal(t, LifecycleStageCount{Stage: entity.LifecycleTrialing}, counts[1])
+	assert.Equal(t, int64(1), counts[3].Users)
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+// LifetimeEntitlementService grants and revokes permanent unlocks bought as
+// non-consumable store products
+type LifetimeEntitlementService struct {
+	repo        repository.LifetimeEntitlementRepository
+	ltv         ltvIncrementer
+	conversions ConversionRecorder
+	logger      *zap.Logger
+	now         func() time.Time
+}
+
+func NewLifetimeEntitlementService(repo repository.LifetimeEntitlementRepository, logger *zap.Logger) *LifetimeEntitlementService {
+	return &LifetimeEntitlementService{repo: repo, logger: logger, now: time.Now}
+}
+
+// WithLTV counts lifetime purchase revenue towards the user's lifetime value
+func (s *LifetimeEntitlementService) WithLTV(ltv ltvIncrementer) *LifetimeEntitlementService {
+	s.ltv = ltv
+	return s
+}
+
+// WithConversions feeds lifetime purchase revenue to bandit experiments
+func (s *LifetimeEntitlementService) WithConversions(recorder ConversionRecorder) *LifetimeEntitlementService {
+	s.conversions = recorder
+	return s
+}
+
+// Grant records a verified lifetime purchase. A transaction that was already
+// granted returns its unlock with created false and records no revenue again.
+func (s *LifetimeEntitlementService) Grant(ctx context.Context, e *entity.LifetimeEntitlement) (*entity.LifetimeEntitlement, bool, error) {
+	stored, created, err := s.repo.Create(ctx, e)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to grant lifetime entitlement: %w", err)
+	}
+	if !created || stored.Amount <= 0 {
+		return stored, created, nil
+	}
+
+	// Revenue attribution is best-effort: access is already granted
+	if s.ltv != nil {
+		if err := s.ltv.IncrementLTV(ctx, stored.UserID, stored.Amount); err != nil {
+			s.logger.Warn("Failed to add lifetime purchase to LTV", zap.String("entitlement_id", stored.ID.String()), zap.Error(err))
+		}
+	}
+	if s.conversions != nil {
+		if err := s.conversions.ProcessConversion(ctx, stored.ID, stored.UserID, stored.Amount, "USD"); err != nil && !errors.Is(err, ErrDuplicateConversion) {
+			s.logger.Warn("Failed to record lifetime purchase conversion", zap.String("entitlement_id", stored.ID.String()), zap.Error(err))
+		}
+	}
+	return stored, true, nil
+}
+
+// ListActive returns the user's unrevoked lifetime unlocks
+func (s *LifetimeEntitlementService) ListActive(ctx context.Context, userID uuid.UUID) ([]*entity.LifetimeEntitlement, error) {
+	entitlements, err := s.repo.ListActiveByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list lifetime entitlements: %w", err)
+	}
+	return entitlements, nil
+}
+
+// RevokeByTransaction ends the unlocks bought with a refunded or revoked
+// store transaction. Returns how many were revoked; 0 for transactions that
+// are not lifetime purchases.
+func (s *LifetimeEntitlementService) RevokeByTransaction(ctx context.Context, platform, txID, reason string) (int, error) {
+	revoked, err := s.repo.RevokeByTransaction(ctx, platform, txID, reason, s.now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke lifetime entitlements: %w", err)
+	}
+	for _, e := range revoked {
+		s.logger.Info("Lifetime entitlement revoked",
+			zap.String("entitlement_id", e.ID.String()),
+			zap.String("user_id", e.UserID.String()),
+			zap.String("product_id", e.ProductID),
+			zap.String("reason", reason),
+		)
+	}
+	return len(revoked), nil
+}
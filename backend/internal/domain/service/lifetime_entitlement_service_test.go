@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+type fakeLifetimeRepo struct {
+	entitlements []*entity.LifetimeEntitlement
+}
+
+func (r *fakeLifetimeRepo) Create(_ context.Context, e *entity.LifetimeEntitlement) (*entity.LifetimeEntitlement, bool, error) {
+	for _, existing := range r.entitlements {
+		if existing.Platform == e.Platform && existing.ProviderTxID == e.ProviderTxID {
+			return existing, false, nil
+		}
+	}
+	r.entitlements = append(r.entitlements, e)
+	return e, true, nil
+}
+
+func (r *fakeLifetimeRepo) ListActiveByUser(_ context.Context, userID uuid.UUID) ([]*entity.LifetimeEntitlement, error) {
+	var active []*entity.LifetimeEntitlement
+	for _, e := range r.entitlements {
+		if e.UserID == userID && e.IsActive() {
+			active = append(active, e)
+		}
+	}
+	return active, nil
+}
+
+func (r *fakeLifetimeRepo) RevokeByTransaction(_ context.Context, platform, txID, reason string, at time.Time) ([]*entity.LifetimeEntitlement, error) {
+	var revoked []*entity.LifetimeEntitlement
+	for _, e := range r.entitlements {
+		if e.Platform == platform && (e.ProviderTxID == txID || e.OriginalTxID == txID) && e.Revoke(reason, at) == nil {
+			revoked = append(revoked, e)
+		}
+	}
+	return revoked, nil
+}
+
+func TestLifetimeEntitlementService_GrantRecordsRevenueOnce(t *testing.T) {
+	ltv := &recordingLTV{}
+	svc := NewLifetimeEntitlementService(&fakeLifetimeRepo{}, zap.NewNop()).WithLTV(ltv)
+	userID, appID := uuid.New(), uuid.New()
+	product := entity.LifetimeProduct{Price: 49.99}
+
+	first, created, err := svc.Grant(context.Background(), entity.NewLifetimeEntitlement(appID, userID, "ios", "com.app.lifetime", "tx-1", "tx-1", product, time.Now()))
+	require.NoError(t, err)
+	assert.True(t, created)
+
+	again, created, err := svc.Grant(context.Background(), entity.NewLifetimeEntitlement(appID, userID, "ios", "com.app.lifetime", "tx-1", "tx-1", product, time.Now()))
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, first.ID, again.ID)
+	assert.Equal(t, []float64{49.99}, ltv.amounts)
+}
+
+func TestLifetimeEntitlementService_RevokeByTransaction(t *testing.T) {
+	userID := uuid.New()
+	unlock := entity.NewLifetimeEntitlement(uuid.New(), userID, "android", "com.app.lifetime", "token-1", "token-1", entity.LifetimeProduct{}, time.Now())
+	repo := &fakeLifetimeRepo{entitlements: []*entity.LifetimeEntitlement{unlock}}
+	svc := NewLifetimeEntitlementService(repo, zap.NewNop())
+
+	revoked, err := svc.RevokeByTransaction(context.Background(), "android", "token-1", "refund")
+	require.NoError(t, err)
+	assert.Equal(t, 1, revoked)
+
+	active, err := svc.ListActive(context.Background(), userID)
+	require.NoError(t, err)
+	assert.Empty(t, active)
+
+	revoked, err = svc.RevokeByTransaction(context.Background(), "android", "token-1", "refund")
+	require.NoError(t, err)
+	assert.Zero(t, revoked, "revoking again is a no-op")
+}
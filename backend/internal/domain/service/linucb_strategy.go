@@ -8,6 +8,8 @@ import (
 
 	"github.com/google/uuid"
 	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
 )
 
 // LinUCBSelectionStrategy implements Linear Upper Confidence Bound for contextual bandits
@@ -274,6 +276,35 @@ func (s *LinUCBSelectionStrategy) contextToFeatureVector(ctx UserContext) ([]flo
 	// Index 19: Bias term
 	features[19] = 1.0
 
+	// Indices 20-23: Acquisition channel one-hot encoding; users without
+	// attribution count as organic
+	if d >= 20+len(entity.AcquisitionChannels) {
+		channel := entity.AcquisitionChannel(ctx.AcquisitionChannel)
+		if channel == "" {
+			channel = entity.AcquisitionOrganic
+		}
+		for i, c := range entity.AcquisitionChannels {
+			if c == channel {
+				features[20+i] = 1.0
+			}
+		}
+	}
+
+	// Indices 24-29: Lifecycle stage one-hot encoding; unclassified users
+	// count as new
+	base := 20 + len(entity.AcquisitionChannels)
+	if d >= base+len(entity.LifecycleStages) {
+		stage := entity.LifecycleStage(ctx.LifecycleStage)
+		if stage == "" {
+			stage = entity.LifecycleNew
+		}
+		for i, st := range entity.LifecycleStages {
+			if st == stage {
+				features[base+i] = 1.0
+			}
+		}
+	}
+
 	return features, nil
 }
 
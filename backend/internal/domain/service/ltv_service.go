@@ -3,11 +3,14 @@ package service
 import (
 	"context"
 	"fmt"
+	"math"
 	"time"
 
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 
+	"github.com/bivex/paywall-iap/internal/clock"
+	"github.com/bivex/paywall-iap/internal/domain/entity"
 	domainRepo "github.com/bivex/paywall-iap/internal/domain/repository"
 	"github.com/bivex/paywall-iap/internal/infrastructure/external/matomo"
 )
@@ -32,7 +35,15 @@ type LTVService struct {
 	subscriptionRepo SubscriptionRepository
 	transactionRepo  domainRepo.TransactionRepository
 	userRepo         domainRepo.UserRepository
+	revenueBasis     RevenueBasis
 	logger           *zap.Logger
+	now              func() time.Time
+}
+
+// netRevenueReader is implemented by subscription sources that can report
+// revenue net of store fees and tax
+type netRevenueReader interface {
+	GetNetRevenue(ctx context.Context, userID uuid.UUID) (float64, error)
 }
 
 // SubscriptionRepository defines the interface for subscription data access
@@ -41,14 +52,24 @@ type SubscriptionRepository interface {
 	GetTotalRevenue(ctx context.Context, userID uuid.UUID) (float64, error)
 }
 
-// Subscription represents a user subscription
+// Subscription represents a user subscription. Revenue is the plan price per
+// billing period and is only used when the subscription has no charges.
 type Subscription struct {
 	ID        uuid.UUID
 	UserID    uuid.UUID
 	Status    string
+	PlanType  string
 	Revenue   float64
 	CreatedAt time.Time
 	EndDate   *time.Time
+	Charges   []SubscriptionCharge
+}
+
+// SubscriptionCharge is one successful, unrefunded payment on a subscription
+type SubscriptionCharge struct {
+	Amount    float64
+	NetAmount *float64
+	ChargedAt time.Time
 }
 
 // NewLTVService creates a new LTV service
@@ -64,16 +85,31 @@ func NewLTVService(
 		cohortWorker:     cohortWorker,
 		subscriptionRepo: subscriptionRepo,
 		transactionRepo:  transactionRepo,
+		revenueBasis:     RevenueBasisGross,
 		logger:           logger,
+		now:              time.Now,
 	}
 }
 
+// WithClock makes subscription ages and revenue cutoffs come from c
+func (s *LTVService) WithClock(c clock.Clock) *LTVService {
+	s.now = c.Now
+	return s
+}
+
 // WithUserRepo sets the user repository for LTV updates (optional, enables DB-backed UpdateUserLTV)
 func (s *LTVService) WithUserRepo(userRepo domainRepo.UserRepository) *LTVService {
 	s.userRepo = userRepo
 	return s
 }
 
+// WithRevenueBasis selects gross or net revenue for LTV. Net requires the
+// subscription repository to implement GetNetRevenue; otherwise gross is used.
+func (s *LTVService) WithRevenueBasis(basis RevenueBasis) *LTVService {
+	s.revenueBasis = basis
+	return s
+}
+
 // LTVEstimates represents LTV predictions for different time horizons
 type LTVEstimates struct {
 	UserID        string            `json:"user_id"`
@@ -85,6 +121,43 @@ type LTVEstimates struct {
 	CalculatedAt  time.Time         `json:"calculated_at"`
 	Method        string            `json:"method"`
 	Factors       map[string]float64 `json:"factors"`
+
+	LTV30Interval  LTVInterval `json:"ltv30_interval"`
+	LTV90Interval  LTVInterval `json:"ltv90_interval"`
+	LTV365Interval LTVInterval `json:"ltv365_interval"`
+}
+
+// LTVInterval is the range an LTV estimate falls in at about 95% confidence.
+// Revenue already observed over the whole horizon has a zero-width interval.
+type LTVInterval struct {
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+}
+
+// ltvIntervalZ is the normal quantile for a two-sided 95% interval
+const ltvIntervalZ = 1.96
+
+// ltvExtrapolationError is the relative error of the cohort model's straight
+// extrapolation from 30-day revenue, on top of the cohort's own variance
+var ltvExtrapolationError = map[int]float64{30: 0, 90: 0.25, 365: 0.5}
+
+// pointInterval is the interval of revenue that has already been observed
+func pointInterval(value float64) LTVInterval {
+	return LTVInterval{Lower: value, Upper: value}
+}
+
+// predictionInterval combines the cohort's standard error with the model
+// error for the horizon. Bounds never go below zero.
+func predictionInterval(value, stderr float64, days int) LTVInterval {
+	modelErr := ltvExtrapolationError[days] * value
+	half := ltvIntervalZ * math.Sqrt(stderr*stderr+modelErr*modelErr)
+	return LTVInterval{Lower: math.Max(0, value-half), Upper: value + half}
+}
+
+// defaultLTVInterval covers the pricing fallback, which knows nothing about
+// the user: anywhere from no revenue to twice the default estimate
+func defaultLTVInterval(value float64) LTVInterval {
+	return LTVInterval{Lower: 0, Upper: 2 * value}
 }
 
 // CalculateLTV calculates LTV estimates for a user
@@ -96,7 +169,7 @@ func (s *LTVService) CalculateLTV(ctx context.Context, userID uuid.UUID) (*LTVEs
 	}
 
 	// Get total revenue to date
-	totalRevenue, err := s.subscriptionRepo.GetTotalRevenue(ctx, userID)
+	totalRevenue, err := s.totalRevenue(ctx, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get total revenue: %w", err)
 	}
@@ -105,56 +178,63 @@ func (s *LTVService) CalculateLTV(ctx context.Context, userID uuid.UUID) (*LTVEs
 	estimates := &LTVEstimates{
 		UserID:       userID.String(),
 		LTVLifetime:  totalRevenue,
-		CalculatedAt: time.Now(),
+		CalculatedAt: s.now(),
 		Method:       "cohort_based",
 		Factors:      make(map[string]float64),
 	}
 
 	// If user has enough history, use actual data
 	if len(subs) > 0 {
+		subs = s.withCharges(ctx, subs)
 		firstSub := subs[0]
-		daysSinceFirst := int(time.Since(firstSub.CreatedAt).Hours() / 24)
+		daysSinceFirst := int(s.now().Sub(firstSub.CreatedAt).Hours() / 24)
 
 		if daysSinceFirst >= 30 {
 			// Calculate 30-day LTV from actual data
 			estimates.LTV30 = s.getRevenueInPeriod(subs, 30)
+			estimates.LTV30Interval = pointInterval(estimates.LTV30)
 			estimates.Factors["actual_30day"] = estimates.LTV30
 		}
 
 		if daysSinceFirst >= 90 {
 			// Calculate 90-day LTV from actual data
 			estimates.LTV90 = s.getRevenueInPeriod(subs, 90)
+			estimates.LTV90Interval = pointInterval(estimates.LTV90)
 			estimates.Factors["actual_90day"] = estimates.LTV90
 		}
 
 		if daysSinceFirst >= 365 {
 			// Calculate 365-day LTV from actual data
 			estimates.LTV365 = s.getRevenueInPeriod(subs, 365)
+			estimates.LTV365Interval = pointInterval(estimates.LTV365)
 			estimates.Factors["actual_365day"] = estimates.LTV365
 		}
 	}
 
 	// For missing time horizons, use cohort-based predictions
 	if estimates.LTV30 == 0 {
-		ltv30, err := s.predictLTVFromCohorts(ctx, userID, 30)
+		ltv30, interval, err := s.predictLTVFromCohorts(ctx, userID, 30)
 		if err == nil {
 			estimates.LTV30 = ltv30
+			estimates.LTV30Interval = interval
 			estimates.Factors["predicted_30day"] = ltv30
 		}
 	}
 
 	if estimates.LTV90 == 0 {
-		ltv90, err := s.predictLTVFromCohorts(ctx, userID, 90)
+		ltv90, interval, err := s.predictLTVFromCohorts(ctx, userID, 90)
 		if err == nil {
 			estimates.LTV90 = ltv90
+			estimates.LTV90Interval = interval
 			estimates.Factors["predicted_90day"] = ltv90
 		}
 	}
 
 	if estimates.LTV365 == 0 {
-		ltv365, err := s.predictLTVFromCohorts(ctx, userID, 365)
+		ltv365, interval, err := s.predictLTVFromCohorts(ctx, userID, 365)
 		if err == nil {
 			estimates.LTV365 = ltv365
+			estimates.LTV365Interval = interval
 			estimates.Factors["predicted_365day"] = ltv365
 		}
 	}
@@ -174,13 +254,15 @@ func (s *LTVService) CalculateLTV(ctx context.Context, userID uuid.UUID) (*LTVEs
 	return estimates, nil
 }
 
-// predictLTVFromCohorts predicts LTV using cohort data
-func (s *LTVService) predictLTVFromCohorts(ctx context.Context, userID uuid.UUID, days int) (float64, error) {
+// predictLTVFromCohorts predicts LTV and its interval using cohort data
+func (s *LTVService) predictLTVFromCohorts(ctx context.Context, userID uuid.UUID, days int) (float64, LTVInterval, error) {
+	defaultLTV := s.getDefaultLTV(days)
+
 	// Get user's join date (first subscription)
 	subs, err := s.subscriptionRepo.GetUserSubscriptions(ctx, userID)
-	if err != nil || len(subs) == 0 {
+	if err != nil || len(subs) == 0 || s.cohortWorker == nil {
 		// Use default LTV based on product pricing
-		return s.getDefaultLTV(days), nil
+		return defaultLTV, defaultLTVInterval(defaultLTV), nil
 	}
 
 	// Get LTV from cohort worker
@@ -190,19 +272,17 @@ func (s *LTVService) predictLTVFromCohorts(ctx context.Context, userID uuid.UUID
 			zap.String("user_id", userID.String()),
 			zap.Error(err),
 		)
-		return s.getDefaultLTV(days), nil
+		return defaultLTV, defaultLTVInterval(defaultLTV), nil
 	}
 
 	// Extract the requested time horizon
 	switch days {
-	case 30:
-		return ltvMap["ltv30"], nil
-	case 90:
-		return ltvMap["ltv90"], nil
-	case 365:
-		return ltvMap["ltv365"], nil
+	case 30, 90, 365:
+		key := fmt.Sprintf("ltv%d", days)
+		value := ltvMap[key]
+		return value, predictionInterval(value, ltvMap[key+"_stderr"], days), nil
 	default:
-		return s.getDefaultLTV(days), nil
+		return defaultLTV, defaultLTVInterval(defaultLTV), nil
 	}
 }
 
@@ -233,7 +313,7 @@ func (s *LTVService) getRevenueInPeriod(subs []Subscription, days int) float64 {
 
 	firstSub := subs[0]
 	cutoffDate := firstSub.CreatedAt.AddDate(0, 0, days)
-	now := time.Now()
+	now := s.now()
 
 	// If cutoff is in the future, only count actual revenue so far
 	if cutoffDate.After(now) {
@@ -254,25 +334,87 @@ func (s *LTVService) getRevenueInPeriod(subs []Subscription, days int) float64 {
 	return totalRevenue
 }
 
-// calculateSubscriptionRevenue calculates revenue for a subscription within a date range
+// calculateSubscriptionRevenue calculates revenue for a subscription within a
+// date range from its charges, or from its plan price when it has none
 func (s *LTVService) calculateSubscriptionRevenue(sub Subscription, startDate, endDate time.Time) float64 {
-	// Simple calculation: revenue * number of billing cycles within period
-	// Assuming monthly billing for now
-	monthlyRevenue := sub.Revenue / 12 // Convert annual to monthly if needed
-
-	// Count months in period
-	months := 0
-	currentDate := startDate
-
-	for currentDate.Before(endDate) {
-		// Check if subscription was active in this month
-		if sub.Status == "active" || sub.Status == "grace" {
-			months++
+	if len(sub.Charges) > 0 {
+		var revenue float64
+		for _, charge := range sub.Charges {
+			if charge.ChargedAt.Before(startDate) || charge.ChargedAt.After(endDate) {
+				continue
+			}
+			if s.revenueBasis == RevenueBasisNet && charge.NetAmount != nil {
+				revenue += *charge.NetAmount
+			} else {
+				revenue += charge.Amount
+			}
 		}
-		currentDate = currentDate.AddDate(0, 1, 0)
+		return revenue
 	}
+	return estimateSubscriptionRevenue(sub, startDate, endDate)
+}
+
+// estimateSubscriptionRevenue prorates the plan price over the days the
+// subscription was active within the range. A lifetime purchase counts once,
+// when it was made; an unknown plan is priced as annual.
+func estimateSubscriptionRevenue(sub Subscription, startDate, endDate time.Time) float64 {
+	if sub.PlanType == string(entity.PlanLifetime) {
+		if sub.CreatedAt.Before(startDate) || sub.CreatedAt.After(endDate) {
+			return 0
+		}
+		return sub.Revenue
+	}
+
+	activeFrom, activeTo := sub.CreatedAt, endDate
+	if activeFrom.Before(startDate) {
+		activeFrom = startDate
+	}
+	if sub.EndDate != nil && sub.EndDate.Before(activeTo) {
+		activeTo = *sub.EndDate
+	}
+	if !activeTo.After(activeFrom) {
+		return 0
+	}
+
+	periodDays := 365.0
+	if sub.PlanType == string(entity.PlanMonthly) {
+		periodDays = 365.0 / 12
+	}
+	activeDays := activeTo.Sub(activeFrom).Hours() / 24
+	return sub.Revenue * activeDays / periodDays
+}
+
+// withCharges attaches each subscription's charges. Subscriptions whose
+// transactions cannot be read keep none and fall back to plan estimation.
+func (s *LTVService) withCharges(ctx context.Context, subs []Subscription) []Subscription {
+	if s.transactionRepo == nil {
+		return subs
+	}
+	result := make([]Subscription, len(subs))
+	for i, sub := range subs {
+		result[i] = sub
+		txs, err := s.transactionRepo.GetBySubscriptionID(ctx, sub.ID)
+		if err != nil {
+			s.logger.Warn("Failed to load subscription transactions, estimating revenue from plan",
+				zap.String("subscription_id", sub.ID.String()),
+				zap.Error(err),
+			)
+			continue
+		}
+		result[i].Charges = subscriptionCharges(txs)
+	}
+	return result
+}
 
-	return float64(months) * monthlyRevenue
+func subscriptionCharges(txs []*entity.Transaction) []SubscriptionCharge {
+	var charges []SubscriptionCharge
+	for _, tx := range txs {
+		if tx.Status != entity.TransactionStatusSuccess || tx.RefundedAt != nil {
+			continue
+		}
+		charges = append(charges, SubscriptionCharge{Amount: tx.Amount, NetAmount: tx.NetAmount, ChargedAt: tx.CreatedAt})
+	}
+	return charges
 }
 
 // calculateConfidence calculates confidence score based on data availability
@@ -428,7 +570,7 @@ func (s *LTVService) PredictChurnRisk(ctx context.Context, userID uuid.UUID) (fl
 	}
 
 	// Adjust based on subscription age
-	daysSinceSub := int(time.Since(latestSub.CreatedAt).Hours() / 24)
+	daysSinceSub := int(s.now().Sub(latestSub.CreatedAt).Hours() / 24)
 	if daysSinceSub < 7 {
 		risk += 0.1 // New users have slightly higher risk
 	} else if daysSinceSub > 90 {
@@ -445,3 +587,13 @@ func (s *LTVService) PredictChurnRisk(ctx context.Context, userID uuid.UUID) (fl
 
 	return risk, nil
 }
+
+// totalRevenue returns the user's revenue to date on the configured basis
+func (s *LTVService) totalRevenue(ctx context.Context, userID uuid.UUID) (float64, error) {
+	if s.revenueBasis == RevenueBasisNet {
+		if net, ok := s.subscriptionRepo.(netRevenueReader); ok {
+			return net.GetNetRevenue(ctx, userID)
+		}
+	}
+	return s.subscriptionRepo.GetTotalRevenue(ctx, userID)
+}
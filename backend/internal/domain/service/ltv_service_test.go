@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainRepo "github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+type ltvTestTransactions struct {
+	domainRepo.TransactionRepository
+	bySubscription map[uuid.UUID][]*entity.Transaction
+	failFor        uuid.UUID
+}
+
+func (r *ltvTestTransactions) GetBySubscriptionID(_ context.Context, subscriptionID uuid.UUID) ([]*entity.Transaction, error) {
+	if subscriptionID == r.failFor {
+		return nil, errors.New("connection reset")
+	}
+	return r.bySubscription[subscriptionID], nil
+}
+
+func ltvTestCharge(subscriptionID uuid.UUID, amount float64, at time.Time) *entity.Transaction {
+	return &entity.Transaction{SubscriptionID: subscriptionID, Amount: amount, Status: entity.TransactionStatusSuccess, CreatedAt: at}
+}
+
+func TestLTVService_RevenueInPeriodFromMixedCharges(t *testing.T) {
+	start := time.Now().AddDate(-2, 0, 0)
+	monthly, annual := uuid.New(), uuid.New()
+	refundedAt := start.AddDate(0, 2, 0)
+	txs := &ltvTestTransactions{bySubscription: map[uuid.UUID][]*entity.Transaction{
+		monthly: {
+			ltvTestCharge(monthly, 9.99, start),
+			ltvTestCharge(monthly, 9.99, start.AddDate(0, 1, 0)),
+			{SubscriptionID: monthly, Amount: 9.99, Status: entity.TransactionStatusSuccess, CreatedAt: start.AddDate(0, 2, 0), RefundedAt: &refundedAt},
+			{SubscriptionID: monthly, Amount: 9.99, Status: entity.TransactionStatusFailed, CreatedAt: start.AddDate(0, 2, 1)},
+		},
+		// Upgraded to annual after four months, renewed a year later
+		annual: {
+			ltvTestCharge(annual, 59.99, start.AddDate(0, 4, 0)),
+			ltvTestCharge(annual, 59.99, start.AddDate(1, 4, 0)),
+		},
+	}}
+	svc := NewLTVService(nil, nil, nil, txs, zap.NewNop())
+	subs := svc.withCharges(context.Background(), []Subscription{
+		{ID: monthly, PlanType: string(entity.PlanMonthly), Revenue: 9.99, CreatedAt: start},
+		{ID: annual, PlanType: string(entity.PlanAnnual), Revenue: 59.99, CreatedAt: start.AddDate(0, 4, 0)},
+	})
+
+	assert.InDelta(t, 19.98, svc.getRevenueInPeriod(subs, 90), 1e-9)
+	assert.InDelta(t, 79.97, svc.getRevenueInPeriod(subs, 365), 1e-9)
+	assert.InDelta(t, 139.96, svc.getRevenueInPeriod(subs, 600), 1e-9)
+}
+
+func TestLTVService_RevenueInPeriodNetBasis(t *testing.T) {
+	start := time.Now().AddDate(0, -2, 0)
+	subID := uuid.New()
+	net := 6.99
+	charge := ltvTestCharge(subID, 9.99, start)
+	charge.NetAmount = &net
+	txs := &ltvTestTransactions{bySubscription: map[uuid.UUID][]*entity.Transaction{
+		subID: {charge, ltvTestCharge(subID, 9.99, start.AddDate(0, 1, 0))},
+	}}
+	svc := NewLTVService(nil, nil, nil, txs, zap.NewNop()).WithRevenueBasis(RevenueBasisNet)
+	subs := svc.withCharges(context.Background(), []Subscription{{ID: subID, CreatedAt: start}})
+
+	// A charge not yet reconciled counts at gross
+	assert.InDelta(t, 16.98, svc.getRevenueInPeriod(subs, 90), 1e-9)
+}
+
+func TestLTVService_RevenueInPeriodFallsBackToPlan(t *testing.T) {
+	start := time.Now().AddDate(-1, -1, 0)
+	monthly, annual, lifetime := uuid.New(), uuid.New(), uuid.New()
+	monthlyEnd := start.AddDate(0, 0, 73)
+	svc := NewLTVService(nil, nil, nil, &ltvTestTransactions{failFor: monthly}, zap.NewNop())
+	subs := svc.withCharges(context.Background(), []Subscription{
+		{ID: monthly, PlanType: string(entity.PlanMonthly), Revenue: 10, CreatedAt: start, EndDate: &monthlyEnd},
+		{ID: annual, PlanType: string(entity.PlanAnnual), Revenue: 73, CreatedAt: start.AddDate(0, 0, 73)},
+		{ID: lifetime, PlanType: string(entity.PlanLifetime), Revenue: 100, CreatedAt: start.AddDate(0, 0, 200)},
+	})
+	for _, sub := range subs {
+		require.Empty(t, sub.Charges)
+	}
+
+	// 73 days of a monthly plan, then 73 days of annual at 0.2 a day
+	assert.InDelta(t, 10*73/(365.0/12)+14.6, svc.getRevenueInPeriod(subs, 146), 1e-6)
+	assert.InDelta(t, 10*73/(365.0/12)+73*219/365.0+100, svc.getRevenueInPeriod(subs, 292), 1e-6)
+}
+
+type ltvTestSubscriptions struct {
+	subs []Subscription
+}
+
+func (r *ltvTestSubscriptions) GetUserSubscriptions(context.Context, uuid.UUID) ([]Subscription, error) {
+	return r.subs, nil
+}
+
+func (r *ltvTestSubscriptions) GetTotalRevenue(context.Context, uuid.UUID) (float64, error) {
+	return 19.98, nil
+}
+
+type ltvTestCohorts struct {
+	ltv map[string]float64
+}
+
+func (c *ltvTestCohorts) CalculateLTVFromCohorts(context.Context, uuid.UUID) (map[string]float64, error) {
+	return c.ltv, nil
+}
+
+func (c *ltvTestCohorts) GetCohortMetrics(context.Context, time.Time, time.Time) ([]CohortMetrics, error) {
+	return nil, nil
+}
+
+func TestLTVService_CalculateLTVIntervals(t *testing.T) {
+	subID := uuid.New()
+	start := time.Now().AddDate(0, 0, -45)
+	subs := &ltvTestSubscriptions{subs: []Subscription{{ID: subID, PlanType: string(entity.PlanMonthly), CreatedAt: start}}}
+	txs := &ltvTestTransactions{bySubscription: map[uuid.UUID][]*entity.Transaction{
+		subID: {ltvTestCharge(subID, 9.99, start), ltvTestCharge(subID, 9.99, start.AddDate(0, 1, 0))},
+	}}
+	cohorts := &ltvTestCohorts{ltv: map[string]float64{
+		"ltv90": 30, "ltv90_stderr": 2,
+		"ltv365": 120, "ltv365_stderr": 8,
+	}}
+
+	estimates, err := NewLTVService(nil, cohorts, subs, txs, zap.NewNop()).CalculateLTV(context.Background(), uuid.New())
+	require.NoError(t, err)
+
+	// 30 days are observed, so there is nothing left to be uncertain about
+	assert.InDelta(t, 9.99, estimates.LTV30, 1e-9)
+	assert.Equal(t, LTVInterval{Lower: 9.99, Upper: 9.99}, estimates.LTV30Interval)
+
+	half90 := 1.96 * math.Sqrt(2*2+7.5*7.5)
+	assert.InDelta(t, 30-half90, estimates.LTV90Interval.Lower, 1e-9)
+	assert.InDelta(t, 30+half90, estimates.LTV90Interval.Upper, 1e-9)
+
+	// The year-long extrapolation is dominated by model error
+	half365 := 1.96 * math.Sqrt(8*8+60*60)
+	assert.InDelta(t, 120-half365, estimates.LTV365Interval.Lower, 1e-9)
+	assert.InDelta(t, 120+half365, estimates.LTV365Interval.Upper, 1e-9)
+}
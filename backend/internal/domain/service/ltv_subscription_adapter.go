@@ -30,6 +30,7 @@ func (a *ltvSubscriptionAdapter) GetUserSubscriptions(ctx context.Context, userI
 			ID:        s.ID,
 			UserID:    s.UserID,
 			Status:    string(s.Status),
+			PlanType:  string(s.PlanType),
 			CreatedAt: s.CreatedAt,
 		}
 		if !s.ExpiresAt.IsZero() {
@@ -45,5 +46,9 @@ func (a *ltvSubscriptionAdapter) GetTotalRevenue(ctx context.Context, userID uui
 	return a.repo.GetTotalRevenue(ctx, userID)
 }
 
+func (a *ltvSubscriptionAdapter) GetNetRevenue(ctx context.Context, userID uuid.UUID) (float64, error) {
+	return a.repo.GetNetRevenue(ctx, userID)
+}
+
 // ensure time import is used
 var _ = time.Time{}
@@ -8,6 +8,7 @@ import (
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 
+	"github.com/bivex/paywall-iap/internal/domain/entity"
 	matomoClient "github.com/bivex/paywall-iap/internal/infrastructure/external/matomo"
 )
 
@@ -21,6 +22,7 @@ type MatomoForwarder struct {
 	repo        MatomoEventRepository
 	logger      *zap.Logger
 	batchSize   int
+	consent     ConsentChecker
 }
 
 // MatomoEventRepository defines the interface for event persistence
@@ -62,6 +64,26 @@ func NewMatomoForwarder(
 	}
 }
 
+// WithConsent sends events of users without analytics consent anonymously:
+// no user ID and no custom variables
+func (f *MatomoForwarder) WithConsent(consent ConsentChecker) *MatomoForwarder {
+	f.consent = consent
+	return f
+}
+
+// matomoUser returns the user ID to send with event, or false when the event
+// must be sent anonymously. Consent is checked at send time so a withdrawal
+// also covers events still queued.
+func (f *MatomoForwarder) matomoUser(ctx context.Context, event *MatomoStagedEvent) (string, bool) {
+	if event.UserID == nil {
+		return "", true
+	}
+	if f.consent != nil && !f.consent.Allows(ctx, *event.UserID, entity.ConsentAnalytics) {
+		return "", false
+	}
+	return event.UserID.String(), true
+}
+
 // TrackEvent enqueues a standard event for delivery
 func (f *MatomoForwarder) TrackEvent(ctx context.Context, userID *uuid.UUID, category, action, name string, value float64, customVars map[string]string) error {
 	event := &MatomoStagedEvent{
@@ -209,9 +231,9 @@ func (f *MatomoForwarder) sendEvent(ctx context.Context, event *MatomoStagedEven
 		}
 	}
 
-	userID := ""
-	if event.UserID != nil {
-		userID = event.UserID.String()
+	userID, identified := f.matomoUser(ctx, event)
+	if !identified {
+		customVars = nil
 	}
 
 	req := matomoClient.TrackEventRequest{
@@ -268,9 +290,9 @@ func (f *MatomoForwarder) sendEcommerce(ctx context.Context, event *MatomoStaged
 		}
 	}
 
-	userID := ""
-	if event.UserID != nil {
-		userID = event.UserID.String()
+	userID, identified := f.matomoUser(ctx, event)
+	if !identified {
+		customVars = nil
 	}
 
 	req := matomoClient.TrackEcommerceRequest{
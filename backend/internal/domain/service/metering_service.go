@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+const (
+	// meteringIdleTTL is how long an untouched lifetime counter stays in
+	// Redis; it is reseeded from Postgres afterwards
+	meteringIdleTTL = 30 * 24 * time.Hour
+	// meteringTTLSlack keeps a closed window's counter until its final flush
+	meteringTTLSlack = 24 * time.Hour
+)
+
+// MeterCounters is the fast counter store of soft paywall meters (Redis)
+type MeterCounters interface {
+	GetUsed(ctx context.Context, u entity.MeterUsage) (used int64, found bool, err error)
+	Seed(ctx context.Context, u entity.MeterUsage, ttl time.Duration) error
+	Consume(ctx context.Context, u entity.MeterUsage, itemID string, amount, limit int64, ttl time.Duration) (used int64, allowed bool, err error)
+	PopDirty(ctx context.Context, n int) ([]*entity.MeterUsage, error)
+	MarkDirty(ctx context.Context, usages []*entity.MeterUsage) error
+}
+
+// appSettingsReader loads an app's paywall configuration
+type appSettingsReader interface {
+	GetSettings(ctx context.Context, id uuid.UUID) (*entity.AppSettings, error)
+}
+
+// MeteringService counts free uses of metered features (N free articles,
+// exports...) against the app's metering rules. Counters live in Redis and
+// are flushed to Postgres periodically.
+type MeteringService struct {
+	counters MeterCounters
+	usage    repository.MeteringUsageRepository
+	apps     appSettingsReader
+	logger   *zap.Logger
+	now      func() time.Time
+}
+
+func NewMeteringService(counters MeterCounters, usage repository.MeteringUsageRepository, apps appSettingsReader, logger *zap.Logger) *MeteringService {
+	return &MeteringService{counters: counters, usage: usage, apps: apps, logger: logger, now: time.Now}
+}
+
+// Consume spends amount free uses of feature. When fewer remain nothing is
+// spent and allowed is false: the client shows the hard paywall. Passing the
+// same itemID again within the window is allowed without spending a use.
+func (s *MeteringService) Consume(ctx context.Context, appID, userID uuid.UUID, feature, itemID string, amount int64) (entity.MeterState, bool, error) {
+	if amount <= 0 {
+		return entity.MeterState{}, false, fmt.Errorf("amount must be positive")
+	}
+	rule, err := s.rule(ctx, appID, feature)
+	if err != nil {
+		return entity.MeterState{}, false, err
+	}
+	usage, ttl := s.window(appID, userID, feature, rule)
+	if _, err := s.used(ctx, usage, ttl); err != nil {
+		return entity.MeterState{}, false, err
+	}
+
+	used, allowed, err := s.counters.Consume(ctx, usage, itemID, amount, rule.FreeUses, ttl)
+	if err != nil {
+		return entity.MeterState{}, false, err
+	}
+	return entity.NewMeterState(feature, rule, usage.PeriodStart, used), allowed, nil
+}
+
+// State returns the meter of one feature without spending a use
+func (s *MeteringService) State(ctx context.Context, appID, userID uuid.UUID, feature string) (entity.MeterState, error) {
+	rule, err := s.rule(ctx, appID, feature)
+	if err != nil {
+		return entity.MeterState{}, err
+	}
+	return s.state(ctx, appID, userID, feature, rule)
+}
+
+// States returns the meters of every metered feature, by feature key
+func (s *MeteringService) States(ctx context.Context, appID, userID uuid.UUID) ([]entity.MeterState, error) {
+	rules, err := s.rules(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+	features := make([]string, 0, len(rules))
+	for feature := range rules {
+		features = append(features, feature)
+	}
+	sort.Strings(features)
+
+	states := make([]entity.MeterState, 0, len(features))
+	for _, feature := range features {
+		state, err := s.state(ctx, appID, userID, feature, rules[feature])
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+// FlushUsage persists up to batch changed counters and returns how many were
+// saved. Counters that fail to save are put back for the next flush.
+func (s *MeteringService) FlushUsage(ctx context.Context, batch int) (int, error) {
+	usages, err := s.counters.PopDirty(ctx, batch)
+	if err != nil {
+		return 0, err
+	}
+	now := s.now()
+	for _, u := range usages {
+		u.UpdatedAt = now
+	}
+	if err := s.usage.Upsert(ctx, usages); err != nil {
+		if markErr := s.counters.MarkDirty(ctx, usages); markErr != nil {
+			s.logger.Error("Failed to requeue metering usage", zap.Int("count", len(usages)), zap.Error(markErr))
+		}
+		return 0, err
+	}
+	return len(usages), nil
+}
+
+func (s *MeteringService) state(ctx context.Context, appID, userID uuid.UUID, feature string, rule entity.MeteringRule) (entity.MeterState, error) {
+	usage, ttl := s.window(appID, userID, feature, rule)
+	used, err := s.used(ctx, usage, ttl)
+	if err != nil {
+		return entity.MeterState{}, err
+	}
+	return entity.NewMeterState(feature, rule, usage.PeriodStart, used), nil
+}
+
+// used reads the counter, seeding it from Postgres when Redis lost it
+func (s *MeteringService) used(ctx context.Context, usage entity.MeterUsage, ttl time.Duration) (int64, error) {
+	used, found, err := s.counters.GetUsed(ctx, usage)
+	if err != nil {
+		return 0, err
+	}
+	if found {
+		return used, nil
+	}
+	used, err = s.usage.GetUsed(ctx, usage.AppID, usage.UserID, usage.Feature, usage.PeriodStart)
+	if err != nil {
+		return 0, err
+	}
+	usage.Used = used
+	if err := s.counters.Seed(ctx, usage, ttl); err != nil {
+		return 0, err
+	}
+	return used, nil
+}
+
+// window identifies the counter of the current period and how long to keep it
+func (s *MeteringService) window(appID, userID uuid.UUID, feature string, rule entity.MeteringRule) (entity.MeterUsage, time.Duration) {
+	now := s.now()
+	start := rule.PeriodStart(now)
+	ttl := meteringIdleTTL
+	if end := rule.PeriodEnd(start); !end.IsZero() {
+		ttl = end.Sub(now) + meteringTTLSlack
+	}
+	return entity.MeterUsage{AppID: appID, UserID: userID, Feature: feature, PeriodStart: start}, ttl
+}
+
+func (s *MeteringService) rule(ctx context.Context, appID uuid.UUID, feature string) (entity.MeteringRule, error) {
+	rules, err := s.rules(ctx, appID)
+	if err != nil {
+		return entity.MeteringRule{}, err
+	}
+	rule, ok := rules[feature]
+	if !ok {
+		return entity.MeteringRule{}, fmt.Errorf("%w: %s", entity.ErrFeatureNotMetered, feature)
+	}
+	return rule, nil
+}
+
+func (s *MeteringService) rules(ctx context.Context, appID uuid.UUID) (map[string]entity.MeteringRule, error) {
+	settings, err := s.apps.GetSettings(ctx, appID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load app settings: %w", err)
+	}
+	return settings.Metering, nil
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+// fakeMeterCounters mimics the Redis script: counters keyed by window, items
+// counted once per window
+type fakeMeterCounters struct {
+	used  map[entity.MeterUsage]int64
+	items map[entity.MeterUsage]map[string]bool
+	dirty map[entity.MeterUsage]bool
+}
+
+func newFakeMeterCounters() *fakeMeterCounters {
+	return &fakeMeterCounters{
+		used:  map[entity.MeterUsage]int64{},
+		items: map[entity.MeterUsage]map[string]bool{},
+		dirty: map[entity.MeterUsage]bool{},
+	}
+}
+
+func (f *fakeMeterCounters) GetUsed(_ context.Context, u entity.MeterUsage) (int64, bool, error) {
+	used, ok := f.used[u]
+	return used, ok, nil
+}
+
+func (f *fakeMeterCounters) Seed(_ context.Context, u entity.MeterUsage, _ time.Duration) error {
+	used := u.Used
+	u.Used = 0
+	if _, ok := f.used[u]; !ok {
+		f.used[u] = used
+	}
+	return nil
+}
+
+func (f *fakeMeterCounters) Consume(_ context.Context, u entity.MeterUsage, itemID string, amount, limit int64, _ time.Duration) (int64, bool, error) {
+	if itemID != "" && f.items[u][itemID] {
+		return f.used[u], true, nil
+	}
+	if f.used[u]+amount > limit {
+		return f.used[u], false, nil
+	}
+	f.used[u] += amount
+	if itemID != "" {
+		if f.items[u] == nil {
+			f.items[u] = map[string]bool{}
+		}
+		f.items[u][itemID] = true
+	}
+	f.dirty[u] = true
+	return f.used[u], true, nil
+}
+
+func (f *fakeMeterCounters) PopDirty(_ context.Context, n int) ([]*entity.MeterUsage, error) {
+	var out []*entity.MeterUsage
+	for u := range f.dirty {
+		if len(out) == n {
+			break
+		}
+		delete(f.dirty, u)
+		popped := u
+		popped.Used = f.used[u]
+		out = append(out, &popped)
+	}
+	return out, nil
+}
+
+func (f *fakeMeterCounters) MarkDirty(_ context.Context, usages []*entity.MeterUsage) error {
+	for _, u := range usages {
+		key := *u
+		key.Used, key.UpdatedAt = 0, time.Time{}
+		f.dirty[key] = true
+	}
+	return nil
+}
+
+type fakeMeteringUsageRepo struct {
+	stored map[string]int64
+	err    error
+}
+
+func (r *fakeMeteringUsageRepo) GetUsed(_ context.Context, _, userID uuid.UUID, feature string, _ time.Time) (int64, error) {
+	return r.stored[userID.String()+feature], nil
+}
+
+func (r *fakeMeteringUsageRepo) Upsert(_ context.Context, usages []*entity.MeterUsage) error {
+	if r.err != nil {
+		return r.err
+	}
+	for _, u := range usages {
+		r.stored[u.UserID.String()+u.Feature] = u.Used
+	}
+	return nil
+}
+
+type fakeAppSettings struct {
+	settings *entity.AppSettings
+}
+
+func (f fakeAppSettings) GetSettings(context.Context, uuid.UUID) (*entity.AppSettings, error) {
+	return f.settings, nil
+}
+
+func newTestMeteringService(counters *fakeMeterCounters, repo *fakeMeteringUsageRepo) *MeteringService {
+	settings := &entity.AppSettings{Metering: map[string]entity.MeteringRule{
+		"articles": {FreeUses: 3, Period: entity.MeteringPeriodMonth, SoftPaywallRemaining: 1},
+	}}
+	svc := NewMeteringService(counters, repo, fakeAppSettings{settings}, zap.NewNop())
+	svc.now = func() time.Time { return time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC) }
+	return svc
+}
+
+func TestMeteringService_ConsumeUntilHardPaywall(t *testing.T) {
+	svc := newTestMeteringService(newFakeMeterCounters(), &fakeMeteringUsageRepo{stored: map[string]int64{}})
+	appID, userID := uuid.New(), uuid.New()
+
+	var modes []entity.PaywallMode
+	for _, article := range []string{"a1", "a2", "a2", "a3"} {
+		state, allowed, err := svc.Consume(context.Background(), appID, userID, "articles", article, 1)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+		modes = append(modes, state.Paywall)
+	}
+	assert.Equal(t, []entity.PaywallMode{entity.PaywallModeNone, entity.PaywallModeSoft, entity.PaywallModeSoft, entity.PaywallModeHard}, modes)
+
+	state, allowed, err := svc.Consume(context.Background(), appID, userID, "articles", "a4", 1)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, int64(3), state.Used)
+	assert.Equal(t, int64(0), state.Remaining)
+	require.NotNil(t, state.ResetsAt)
+	assert.Equal(t, time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC), *state.ResetsAt)
+}
+
+func TestMeteringService_SeedsLostCounterFromPostgres(t *testing.T) {
+	userID := uuid.New()
+	repo := &fakeMeteringUsageRepo{stored: map[string]int64{userID.String() + "articles": 3}}
+	svc := newTestMeteringService(newFakeMeterCounters(), repo)
+
+	state, allowed, err := svc.Consume(context.Background(), uuid.New(), userID, "articles", "", 1)
+
+	require.NoError(t, err)
+	assert.False(t, allowed, "uses persisted before Redis lost the counter still count")
+	assert.Equal(t, entity.PaywallModeHard, state.Paywall)
+}
+
+func TestMeteringService_UnknownFeature(t *testing.T) {
+	svc := newTestMeteringService(newFakeMeterCounters(), &fakeMeteringUsageRepo{stored: map[string]int64{}})
+
+	_, err := svc.State(context.Background(), uuid.New(), uuid.New(), "exports")
+
+	assert.ErrorIs(t, err, entity.ErrFeatureNotMetered)
+}
+
+func TestMeteringService_FlushRequeuesOnFailure(t *testing.T) {
+	counters := newFakeMeterCounters()
+	repo := &fakeMeteringUsageRepo{stored: map[string]int64{}, err: errors.New("db down")}
+	svc := newTestMeteringService(counters, repo)
+	userID := uuid.New()
+	_, _, err := svc.Consume(context.Background(), uuid.New(), userID, "articles", "", 2)
+	require.NoError(t, err)
+
+	_, err = svc.FlushUsage(context.Background(), 100)
+	require.Error(t, err)
+	assert.Len(t, counters.dirty, 1, "failed flush puts the counter back")
+
+	repo.err = nil
+	flushed, err := svc.FlushUsage(context.Background(), 100)
+	require.NoError(t, err)
+	assert.Equal(t, 1, flushed)
+	assert.Equal(t, int64(2), repo.stored[userID.String()+"articles"])
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+// NotificationGate decides whether a notification may be sent to a user
+type NotificationGate interface {
+	Permit(ctx context.Context, userID uuid.UUID, category entity.NotificationCategory, channel entity.NotificationChannel) (bool, error)
+}
+
+// NotificationSuppressionReport is what preferences held back over a range
+type NotificationSuppressionReport struct {
+	From     string                                `json:"from"`
+	To       string                                `json:"to"`
+	Total    int64                                 `json:"total"`
+	ByReason map[entity.SuppressionReason]int64    `json:"by_reason"`
+	Counts   []entity.NotificationSuppressionCount `json:"counts"`
+}
+
+// NotificationPreferenceService stores users' notification preferences and
+// enforces them, with marketing consent, for the notification dispatchers
+type NotificationPreferenceService struct {
+	repo    repository.NotificationPreferenceRepository
+	consent ConsentChecker
+	logger  *zap.Logger
+	now     func() time.Time
+}
+
+// NewNotificationPreferenceService creates a notification preference service
+func NewNotificationPreferenceService(repo repository.NotificationPreferenceRepository, logger *zap.Logger) *NotificationPreferenceService {
+	return &NotificationPreferenceService{repo: repo, logger: logger, now: time.Now}
+}
+
+// WithConsent suppresses marketing notifications to users who withdrew
+// marketing consent
+func (s *NotificationPreferenceService) WithConsent(consent ConsentChecker) *NotificationPreferenceService {
+	s.consent = consent
+	return s
+}
+
+// Get returns the user's preferences, the defaults if they never set any
+func (s *NotificationPreferenceService) Get(ctx context.Context, appID, userID uuid.UUID) (*entity.NotificationPreferences, error) {
+	prefs, err := s.repo.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if prefs == nil {
+		prefs = entity.DefaultNotificationPreferences(appID, userID)
+	}
+	return prefs, nil
+}
+
+// Put replaces the user's preferences
+func (s *NotificationPreferenceService) Put(ctx context.Context, prefs *entity.NotificationPreferences) error {
+	if err := prefs.Validate(); err != nil {
+		return err
+	}
+	prefs.UpdatedAt = s.now()
+	return s.repo.Upsert(ctx, prefs)
+}
+
+// Permit reports whether the notification may be sent now and counts it
+// when it may not
+func (s *NotificationPreferenceService) Permit(ctx context.Context, userID uuid.UUID, category entity.NotificationCategory, channel entity.NotificationChannel) (bool, error) {
+	now := s.now()
+	var reason entity.SuppressionReason
+	prefs, err := s.repo.Get(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if prefs != nil {
+		reason = prefs.Suppression(category, channel, now)
+	}
+	if reason == "" && category == entity.NotificationMarketing && s.consent != nil && !s.consent.Allows(ctx, userID, entity.ConsentMarketing) {
+		reason = entity.SuppressedNoConsent
+	}
+	if reason == "" {
+		return true, nil
+	}
+	if err := s.repo.RecordSuppression(ctx, userID, now.UTC(), category, channel, reason); err != nil {
+		// The notification stays suppressed; only the count is lost
+		s.logger.Warn("Failed to count suppressed notification", zap.String("user_id", userID.String()), zap.Error(err))
+	}
+	return false, nil
+}
+
+// Suppressions totals the app's suppressed notifications for days in
+// [from, to]
+func (s *NotificationPreferenceService) Suppressions(ctx context.Context, appID uuid.UUID, from, to time.Time) (*NotificationSuppressionReport, error) {
+	counts, err := s.repo.SuppressionCounts(ctx, appID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to report suppressions: %w", err)
+	}
+	report := &NotificationSuppressionReport{
+		From:     from.Format("2006-01-02"),
+		To:       to.Format("2006-01-02"),
+		ByReason: map[entity.SuppressionReason]int64{},
+		Counts:   counts,
+	}
+	for _, c := range counts {
+		report.Total += c.Count
+		report.ByReason[c.Reason] += c.Count
+	}
+	return report, nil
+}
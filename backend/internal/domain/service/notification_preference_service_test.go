@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+type fakeNotificationPrefRepo struct {
+	prefs      map[uuid.UUID]*entity.NotificationPreferences
+	suppressed []entity.SuppressionReason
+}
+
+func (r *fakeNotificationPrefRepo) Get(_ context.Context, userID uuid.UUID) (*entity.NotificationPreferences, error) {
+	return r.prefs[userID], nil
+}
+
+func (r *fakeNotificationPrefRepo) Upsert(_ context.Context, p *entity.NotificationPreferences) error {
+	r.prefs[p.UserID] = p
+	return nil
+}
+
+func (r *fakeNotificationPrefRepo) RecordSuppression(_ context.Context, _ uuid.UUID, _ time.Time, _ entity.NotificationCategory, _ entity.NotificationChannel, reason entity.SuppressionReason) error {
+	r.suppressed = append(r.suppressed, reason)
+	return nil
+}
+
+func (r *fakeNotificationPrefRepo) SuppressionCounts(context.Context, uuid.UUID, time.Time, time.Time) ([]entity.NotificationSuppressionCount, error) {
+	return nil, nil
+}
+
+type staticConsent bool
+
+func (c staticConsent) Allows(context.Context, uuid.UUID, entity.ConsentPurpose) bool { return bool(c) }
+
+func TestNotificationPreferenceService_PermitCountsSuppressions(t *testing.T) {
+	repo := &fakeNotificationPrefRepo{prefs: map[uuid.UUID]*entity.NotificationPreferences{}}
+	svc := NewNotificationPreferenceService(repo, zap.NewNop()).WithConsent(staticConsent(false))
+	svc.now = func() time.Time { return time.Date(2026, 7, 1, 23, 0, 0, 0, time.UTC) }
+	ctx := context.Background()
+	userID := uuid.New()
+
+	ok, err := svc.Permit(ctx, userID, entity.NotificationBilling, entity.NotificationPush)
+	require.NoError(t, err)
+	assert.True(t, ok, "users without preferences get everything but what consent rules out")
+
+	ok, err = svc.Permit(ctx, userID, entity.NotificationMarketing, entity.NotificationEmail)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	prefs := entity.DefaultNotificationPreferences(uuid.New(), userID)
+	prefs.QuietHours = &entity.QuietHours{StartMinute: 22 * 60, EndMinute: 6 * 60, Timezone: "UTC"}
+	require.NoError(t, svc.Put(ctx, prefs))
+	ok, err = svc.Permit(ctx, userID, entity.NotificationBilling, entity.NotificationPush)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.Equal(t, []entity.SuppressionReason{entity.SuppressedNoConsent, entity.SuppressedQuietHours}, repo.suppressed)
+}
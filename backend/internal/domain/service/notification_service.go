@@ -20,6 +20,7 @@ type NotificationService struct {
 	sendGridAPIKey string
 	fromEmail      string
 	fcmServerKey   string
+	gate           NotificationGate
 }
 
 // NewNotificationService creates a notification service without credentials (log-only mode).
@@ -40,8 +41,38 @@ func (s *NotificationService) WithFCM(serverKey string) *NotificationService {
 	return s
 }
 
+// WithPreferences holds back notifications the user's preferences or
+// consent do not allow.
+func (s *NotificationService) WithPreferences(gate NotificationGate) *NotificationService {
+	s.gate = gate
+	return s
+}
+
+// permitted reports whether the gate lets the notification through; without
+// a gate everything is sent.
+func (s *NotificationService) permitted(ctx context.Context, userID uuid.UUID, category entity.NotificationCategory, channel entity.NotificationChannel) (bool, error) {
+	if s.gate == nil {
+		return true, nil
+	}
+	ok, err := s.gate.Permit(ctx, userID, category, channel)
+	if err != nil {
+		return false, fmt.Errorf("notification preferences: %w", err)
+	}
+	if !ok {
+		logging.Logger.Info("[notification] suppressed by user preferences",
+			zap.String("user_id", userID.String()),
+			zap.String("category", string(category)),
+			zap.String("channel", string(channel)),
+		)
+	}
+	return ok, nil
+}
+
 // sendEmail sends a transactional email via SendGrid. Falls back to log if not configured.
-func (s *NotificationService) sendEmail(ctx context.Context, toEmail, subject, body string) error {
+func (s *NotificationService) sendEmail(ctx context.Context, userID uuid.UUID, category entity.NotificationCategory, toEmail, subject, body string) error {
+	if ok, err := s.permitted(ctx, userID, category, entity.NotificationEmail); !ok {
+		return err
+	}
 	if s.sendGridAPIKey == "" {
 		logging.Logger.Info("[notification] email (sendgrid not configured)",
 			zap.String("to", toEmail),
@@ -83,7 +114,15 @@ func (s *NotificationService) sendEmail(ctx context.Context, toEmail, subject, b
 }
 
 // sendPush sends an FCM push notification. Falls back to log if not configured or no token.
-func (s *NotificationService) sendPush(ctx context.Context, deviceToken, title, body string) error {
+func (s *NotificationService) sendPush(ctx context.Context, userID uuid.UUID, category entity.NotificationCategory, deviceToken, title, body string) error {
+	return s.sendPushWithData(ctx, userID, category, deviceToken, title, body, nil)
+}
+
+// sendPushWithData sends a push carrying data for the app to act on
+func (s *NotificationService) sendPushWithData(ctx context.Context, userID uuid.UUID, category entity.NotificationCategory, deviceToken, title, body string, data map[string]string) error {
+	if ok, err := s.permitted(ctx, userID, category, entity.NotificationPush); !ok {
+		return err
+	}
 	if s.fcmServerKey == "" || deviceToken == "" {
 		logging.Logger.Info("[notification] push (fcm not configured or no token)",
 			zap.String("title", title),
@@ -95,6 +134,9 @@ func (s *NotificationService) sendPush(ctx context.Context, deviceToken, title,
 		"to":           deviceToken,
 		"notification": map[string]string{"title": title, "body": body},
 	}
+	if len(data) > 0 {
+		payload["data"] = data
+	}
 	b, _ := json.Marshal(payload)
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
@@ -130,7 +172,7 @@ func (s *NotificationService) SendGracePeriodExpiringNotification(ctx context.Co
 	)
 	_ = subject
 	_ = body
-	return s.sendPush(ctx, "", subject, body)
+	return s.sendPush(ctx, userID, entity.NotificationBilling, "", subject, body)
 }
 
 // SendWinbackOfferNotification sends a winback offer to churned users.
@@ -142,7 +184,7 @@ func (s *NotificationService) SendWinbackOfferNotification(ctx context.Context,
 		zap.String("campaign_id", offer.CampaignID),
 		zap.Float64("discount", offer.DiscountValue),
 	)
-	return s.sendPush(ctx, "", title, body)
+	return s.sendPush(ctx, userID, entity.NotificationMarketing, "", title, body)
 }
 
 // SendSubscriptionExpiredNotification sends notification when subscription expires.
@@ -153,18 +195,28 @@ func (s *NotificationService) SendSubscriptionExpiredNotification(ctx context.Co
 		zap.String("user_id", userID.String()),
 		zap.String("subscription_id", subscriptionID.String()),
 	)
-	return s.sendPush(ctx, "", title, body)
+	return s.sendPush(ctx, userID, entity.NotificationBilling, "", title, body)
 }
 
 // SendPaymentRetryNotification sends a notification about failed payment and retry attempt.
 func (s *NotificationService) SendPaymentRetryNotification(ctx context.Context, userID uuid.UUID, retryCount int) error {
+	return s.SendPaymentFixReminder(ctx, userID, retryCount, "")
+}
+
+// SendPaymentFixReminder sends a dunning reminder linking to the store page
+// where the payment method is updated; fixURL may be empty.
+func (s *NotificationService) SendPaymentFixReminder(ctx context.Context, userID uuid.UUID, retryCount int, fixURL string) error {
 	title := "Payment failed"
 	body := fmt.Sprintf("We could not process your payment (attempt %d). Please update your payment method.", retryCount)
+	if fixURL != "" {
+		body += " " + fixURL
+	}
 	logging.Logger.Info("payment retry notification",
 		zap.String("user_id", userID.String()),
 		zap.Int("retry_count", retryCount),
+		zap.String("payment_fix_url", fixURL),
 	)
-	return s.sendPush(ctx, "", title, body)
+	return s.sendPush(ctx, userID, entity.NotificationBilling, "", title, body)
 }
 
 // SendPaymentSuccessNotification sends a notification when payment is recovered.
@@ -174,7 +226,7 @@ func (s *NotificationService) SendPaymentSuccessNotification(ctx context.Context
 	logging.Logger.Info("payment success notification",
 		zap.String("user_id", userID.String()),
 	)
-	_ = s.sendPush(ctx, "", title, body)
+	_ = s.sendPush(ctx, userID, entity.NotificationBilling, "", title, body)
 }
 
 // SendAllRetriesFailedNotification sends a notification when all payment retries fail.
@@ -184,10 +236,62 @@ func (s *NotificationService) SendAllRetriesFailedNotification(ctx context.Conte
 	logging.Logger.Info("all retries failed notification",
 		zap.String("user_id", userID.String()),
 	)
-	_ = s.sendPush(ctx, "", title, body)
+	_ = s.sendPush(ctx, userID, entity.NotificationBilling, "", title, body)
 }
 
 // SendPaymentFinalFailureNotification is an alias for SendAllRetriesFailedNotification.
 func (s *NotificationService) SendPaymentFinalFailureNotification(ctx context.Context, userID uuid.UUID) {
 	s.SendAllRetriesFailedNotification(ctx, userID)
 }
+
+// SendSegmentNotification sends an admin-authored message to a segment member.
+// Users with an email address receive it by email, others by push.
+func (s *NotificationService) SendSegmentNotification(ctx context.Context, user *entity.User, title, body string) error {
+	logging.Logger.Info("segment notification",
+		zap.String("user_id", user.ID.String()),
+		zap.String("title", title),
+	)
+	if user.Email != "" {
+		return s.sendEmail(ctx, user.ID, entity.NotificationMarketing, user.Email, title, body)
+	}
+	return s.sendPush(ctx, user.ID, entity.NotificationMarketing, "", title, body)
+}
+
+// SendSendTimeNotification pushes a segment message at the hour a send-time
+// experiment chose. The push carries delivery_id so the app can report the
+// open.
+func (s *NotificationService) SendSendTimeNotification(ctx context.Context, userID, deliveryID uuid.UUID, title, body string) error {
+	logging.Logger.Info("send-time notification",
+		zap.String("user_id", userID.String()),
+		zap.String("delivery_id", deliveryID.String()),
+	)
+	return s.sendPushWithData(ctx, userID, entity.NotificationMarketing, "", title, body, map[string]string{
+		"type":        "send_time",
+		"delivery_id": deliveryID.String(),
+	})
+}
+
+// appleSubscriptionsURL is where App Store subscribers review and accept a
+// price increase
+const appleSubscriptionsURL = "https://apps.apple.com/account/subscriptions"
+
+// SendPriceIncreaseConsentPrompt asks a subscriber to accept a store price
+// increase. Users with an email address receive it by email, others by a push
+// the app can open the subscription settings from.
+func (s *NotificationService) SendPriceIncreaseConsentPrompt(ctx context.Context, user *entity.User, consent *entity.PriceIncreaseConsent, title, body string) error {
+	if consent.Platform == "ios" {
+		body += " " + appleSubscriptionsURL
+	}
+	logging.Logger.Info("price increase consent prompt",
+		zap.String("user_id", user.ID.String()),
+		zap.String("subscription_id", consent.SubscriptionID.String()),
+		zap.Int("prompt", consent.PromptCount+1),
+	)
+	if user.Email != "" {
+		return s.sendEmail(ctx, user.ID, entity.NotificationBilling, user.Email, title, body)
+	}
+	return s.sendPushWithData(ctx, user.ID, entity.NotificationBilling, "", title, body, map[string]string{
+		"type":            "price_increase_consent",
+		"subscription_id": consent.SubscriptionID.String(),
+	})
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+// OfferEligibility describes which store offers a user can still be shown for a product
+type OfferEligibility struct {
+	ProductID      string   `json:"product_id"`
+	IntroEligible  bool     `json:"intro_eligible"`
+	ConsumedOffers []string `json:"consumed_offers"`
+	RedeemedCodes  []string `json:"redeemed_offer_codes"`
+}
+
+// ErrOfferCodeRequired is returned when attributing a campaign without an offer code
+var ErrOfferCodeRequired = errors.New("offer code is required")
+
+// OfferCodeReport is the redemption to retention outcome of one offer code
+// batch or win-back offer
+type OfferCodeReport struct {
+	OfferType     entity.OfferType `json:"offer_type"`
+	OfferCode     string           `json:"offer_code"`
+	Campaign      string           `json:"campaign"`
+	Redemptions   int              `json:"redemptions"`
+	Renewed       int              `json:"renewed"`
+	Retained      int              `json:"retained"`
+	RenewalRate   float64          `json:"renewal_rate"`
+	RetentionRate float64          `json:"retention_rate"`
+}
+
+// OfferService tracks intro / trial / offer-code redemptions and derives eligibility
+type OfferService struct {
+	redemptionRepo repository.OfferRedemptionRepository
+}
+
+func NewOfferService(redemptionRepo repository.OfferRedemptionRepository) *OfferService {
+	return &OfferService{redemptionRepo: redemptionRepo}
+}
+
+// CheckEligibility returns whether the user may still receive the intro price
+// or free trial of productID, plus the offers already consumed for it
+func (s *OfferService) CheckEligibility(ctx context.Context, appID, userID uuid.UUID, productID string) (*OfferEligibility, error) {
+	redemptions, err := s.redemptionRepo.GetByUserID(ctx, appID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load offer redemptions: %w", err)
+	}
+
+	eligibility := &OfferEligibility{
+		ProductID:      productID,
+		IntroEligible:  true,
+		ConsumedOffers: []string{},
+		RedeemedCodes:  []string{},
+	}
+	seen := make(map[entity.OfferType]bool)
+	for _, r := range redemptions {
+		if r.ProductID != productID {
+			continue
+		}
+		if r.OfferType.IsIntro() {
+			eligibility.IntroEligible = false
+		}
+		if !seen[r.OfferType] {
+			seen[r.OfferType] = true
+			eligibility.ConsumedOffers = append(eligibility.ConsumedOffers, string(r.OfferType))
+		}
+		if r.OfferCode != "" {
+			eligibility.RedeemedCodes = append(eligibility.RedeemedCodes, r.OfferCode)
+		}
+	}
+
+	return eligibility, nil
+}
+
+// RecordRedemption stores a redemption reported by store verification.
+// Unknown offer types are rejected; duplicates are ignored by the repository.
+func (s *OfferService) RecordRedemption(ctx context.Context, redemption *entity.OfferRedemption) error {
+	if !redemption.OfferType.IsValid() {
+		return fmt.Errorf("unknown offer type %q", redemption.OfferType)
+	}
+	return s.redemptionRepo.Create(ctx, redemption)
+}
+
+// SetCampaign attributes the redemptions of an offer code batch (its
+// reference name) or win-back offer ID to a campaign. An empty campaign
+// removes the attribution.
+func (s *OfferService) SetCampaign(ctx context.Context, appID uuid.UUID, offerCode, campaign string) error {
+	offerCode = strings.TrimSpace(offerCode)
+	if offerCode == "" {
+		return ErrOfferCodeRequired
+	}
+	if err := s.redemptionRepo.SetCampaign(ctx, appID, offerCode, strings.TrimSpace(campaign)); err != nil {
+		return fmt.Errorf("failed to set offer code campaign: %w", err)
+	}
+	return nil
+}
+
+// CodeReports returns the outcome of each offer code batch and win-back offer
+// redeemed in [from, to). Each user counts once per batch.
+func (s *OfferService) CodeReports(ctx context.Context, appID uuid.UUID, from, to time.Time) ([]OfferCodeReport, error) {
+	outcomes, err := s.redemptionRepo.ListCodeOutcomes(ctx, appID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load offer code outcomes: %w", err)
+	}
+	reports := make([]OfferCodeReport, 0, len(outcomes))
+	for _, o := range outcomes {
+		report := OfferCodeReport{
+			OfferType:   o.OfferType,
+			OfferCode:   o.OfferCode,
+			Campaign:    o.Campaign,
+			Redemptions: o.Users,
+			Renewed:     o.Renewed,
+			Retained:    o.Retained,
+		}
+		if o.Users > 0 {
+			report.RenewalRate = float64(o.Renewed) / float64(o.Users)
+			report.RetentionRate = float64(o.Retained) / float64(o.Users)
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+type fakeOfferRedemptionRepo struct {
+	redemptions []*entity.OfferRedemption
+	created     []*entity.OfferRedemption
+	campaigns   map[string]string
+	outcomes    []repository.OfferCodeOutcome
+}
+
+func (r *fakeOfferRedemptionRepo) Create(_ context.Context, redemption *entity.OfferRedemption) error {
+	r.created = append(r.created, redemption)
+	return nil
+}
+
+func (r *fakeOfferRedemptionRepo) GetByUserID(_ context.Context, _, _ uuid.UUID) ([]*entity.OfferRedemption, error) {
+	return r.redemptions, nil
+}
+
+func (r *fakeOfferRedemptionRepo) SetCampaign(_ context.Context, _ uuid.UUID, offerCode, campaign string) error {
+	r.campaigns[offerCode] = campaign
+	return nil
+}
+
+func (r *fakeOfferRedemptionRepo) ListCodeOutcomes(_ context.Context, _ uuid.UUID, _, _ time.Time) ([]repository.OfferCodeOutcome, error) {
+	return r.outcomes, nil
+}
+
+func TestOfferService_CheckEligibility(t *testing.T) {
+	appID, userID := uuid.New(), uuid.New()
+	code := entity.NewOfferRedemption(appID, userID, "com.app.annual", entity.OfferTypeOfferCode, "ios")
+	code.OfferCode = "SPRING50"
+	repo := &fakeOfferRedemptionRepo{redemptions: []*entity.OfferRedemption{
+		entity.NewOfferRedemption(appID, userID, "com.app.monthly", entity.OfferTypeFreeTrial, "ios"),
+		code,
+	}}
+	svc := NewOfferService(repo)
+
+	monthly, err := svc.CheckEligibility(context.Background(), appID, userID, "com.app.monthly")
+	require.NoError(t, err)
+	assert.False(t, monthly.IntroEligible)
+	assert.Equal(t, []string{"free_trial"}, monthly.ConsumedOffers)
+
+	// An offer code does not consume the intro price of the product.
+	annual, err := svc.CheckEligibility(context.Background(), appID, userID, "com.app.annual")
+	require.NoError(t, err)
+	assert.True(t, annual.IntroEligible)
+	assert.Equal(t, []string{"SPRING50"}, annual.RedeemedCodes)
+}
+
+func TestOfferService_RecordRedemptionRejectsUnknownType(t *testing.T) {
+	repo := &fakeOfferRedemptionRepo{}
+	svc := NewOfferService(repo)
+
+	err := svc.RecordRedemption(context.Background(), entity.NewOfferRedemption(uuid.New(), uuid.New(), "com.app.monthly", "bogus", "ios"))
+
+	assert.Error(t, err)
+	assert.Empty(t, repo.created)
+}
+
+func TestOfferService_SetCampaign(t *testing.T) {
+	repo := &fakeOfferRedemptionRepo{campaigns: map[string]string{}}
+	svc := NewOfferService(repo)
+
+	require.NoError(t, svc.SetCampaign(context.Background(), uuid.New(), " SPRING50 ", " spring-email "))
+	assert.Equal(t, map[string]string{"SPRING50": "spring-email"}, repo.campaigns)
+
+	assert.ErrorIs(t, svc.SetCampaign(context.Background(), uuid.New(), " ", "spring-email"), ErrOfferCodeRequired)
+}
+
+func TestOfferService_CodeReports(t *testing.T) {
+	repo := &fakeOfferRedemptionRepo{outcomes: []repository.OfferCodeOutcome{
+		{OfferType: entity.OfferTypeOfferCode, OfferCode: "SPRING50", Campaign: "spring-email", Users: 8, Renewed: 4, Retained: 2},
+		{OfferType: entity.OfferTypeWinBack, OfferCode: "winback.annual"},
+	}}
+	svc := NewOfferService(repo)
+
+	reports, err := svc.CodeReports(context.Background(), uuid.New(), time.Now().AddDate(0, -1, 0), time.Now())
+
+	require.NoError(t, err)
+	require.Len(t, reports, 2)
+	assert.Equal(t, 8, reports[0].Redemptions)
+	assert.InDelta(t, 0.5, reports[0].RenewalRate, 1e-9)
+	assert.InDelta(t, 0.25, reports[0].RetentionRate, 1e-9)
+	assert.Zero(t, reports[1].RetentionRate, "no redemptions must not divide by zero")
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PaywallFunnelService computes the paywall_shown -> purchase_initiated ->
+// purchase_completed funnel from ingested bandit events instead of Matomo.
+type PaywallFunnelService struct {
+	dbPool *pgxpool.Pool
+}
+
+// NewPaywallFunnelService creates a new paywall funnel service
+func NewPaywallFunnelService(dbPool *pgxpool.Pool) *PaywallFunnelService {
+	return &PaywallFunnelService{dbPool: dbPool}
+}
+
+// PaywallFunnelQuery selects the events a funnel is built from. From is
+// inclusive, To exclusive; a nil ExperimentID or empty Placement matches all.
+type PaywallFunnelQuery struct {
+	From         time.Time
+	To           time.Time
+	ExperimentID *uuid.UUID
+	Placement    string
+}
+
+// PaywallFunnelSegment counts distinct users reaching each step for one
+// experiment arm × placement. Events without a placement are grouped under "".
+type PaywallFunnelSegment struct {
+	ExperimentID uuid.UUID `json:"experiment_id"`
+	ArmID        uuid.UUID `json:"arm_id"`
+	ArmName      string    `json:"arm_name"`
+	Placement    string    `json:"placement"`
+	Shown        int       `json:"shown"`
+	Initiated    int       `json:"initiated"`
+	Completed    int       `json:"completed"`
+}
+
+// GetPaywallFunnel counts users by their first paywall impression in the
+// range. A user counts as initiated if a purchase_initiated event for the
+// same arm and placement follows that impression, and as completed if a
+// positive conversion on the arm follows the first purchase_initiated.
+// purchase_initiated events sent without a placement match any placement,
+// and conversions carry none.
+func (s *PaywallFunnelService) GetPaywallFunnel(ctx context.Context, appID uuid.UUID, query PaywallFunnelQuery) ([]PaywallFunnelSegment, error) {
+	rows, err := s.dbPool.Query(ctx, `
+		WITH shown AS (
+			SELECT e.experiment_id, e.arm_id, COALESCE(e.placement, '') AS placement, e.user_id,
+			       MIN(e.occurred_at) AS shown_at
+			FROM bandit_impression_events e
+			JOIN ab_tests t ON t.id = e.experiment_id
+			WHERE t.app_id = $1 AND e.event_type = 'impression'
+			  AND e.occurred_at >= $2 AND e.occurred_at < $3
+			  AND ($4::uuid IS NULL OR e.experiment_id = $4)
+			  AND ($5::text = '' OR COALESCE(e.placement, '') = $5)
+			GROUP BY 1, 2, 3, 4
+		),
+		initiated AS (
+			SELECT s.experiment_id, s.arm_id, s.placement, s.user_id, MIN(p.occurred_at) AS initiated_at
+			FROM shown s
+			JOIN bandit_impression_events p
+			  ON p.experiment_id = s.experiment_id AND p.arm_id = s.arm_id AND p.user_id = s.user_id
+			 AND COALESCE(p.placement, s.placement) = s.placement
+			WHERE p.event_type = 'purchase_initiated'
+			  AND p.occurred_at >= s.shown_at AND p.occurred_at < $3
+			GROUP BY 1, 2, 3, 4
+		)
+		SELECT s.experiment_id, s.arm_id, a.name, s.placement,
+		       COUNT(*)         AS shown,
+		       COUNT(i.user_id) AS initiated,
+		       COUNT(i.user_id) FILTER (WHERE EXISTS (
+		           SELECT 1 FROM bandit_conversion_events c
+		           WHERE c.experiment_id = i.experiment_id AND c.arm_id = i.arm_id AND c.user_id = i.user_id
+		             AND c.normalized_reward_value > 0
+		             AND c.occurred_at >= i.initiated_at AND c.occurred_at < $3
+		       ))               AS completed
+		FROM shown s
+		JOIN ab_test_arms a ON a.id = s.arm_id
+		LEFT JOIN initiated i
+		  ON i.experiment_id = s.experiment_id AND i.arm_id = s.arm_id
+		 AND i.placement = s.placement AND i.user_id = s.user_id
+		GROUP BY s.experiment_id, s.arm_id, a.name, s.placement
+		ORDER BY s.experiment_id, a.name, s.placement`,
+		appID, query.From, query.To, query.ExperimentID, query.Placement)
+	if err != nil {
+		return nil, fmt.Errorf("query paywall funnel: %w", err)
+	}
+	defer rows.Close()
+
+	segments := []PaywallFunnelSegment{}
+	for rows.Next() {
+		var segment PaywallFunnelSegment
+		if err := rows.Scan(&segment.ExperimentID, &segment.ArmID, &segment.ArmName, &segment.Placement,
+			&segment.Shown, &segment.Initiated, &segment.Completed); err != nil {
+			return nil, fmt.Errorf("scan paywall funnel row: %w", err)
+		}
+		segments = append(segments, segment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate paywall funnel rows: %w", err)
+	}
+
+	return segments, nil
+}
@@ -0,0 +1,311 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+// ErrNoPaywallRuleMatched is returned when no enabled rule covers the audience
+var ErrNoPaywallRuleMatched = errors.New("no paywall rule matched")
+
+// churnRiskPredictor scores how likely a user is to churn (0..1)
+type churnRiskPredictor interface {
+	PredictChurnRisk(ctx context.Context, userID uuid.UUID) (float64, error)
+}
+
+// candidateArmSelector lets the bandit pick an arm from a restricted set
+type candidateArmSelector interface {
+	SelectArmFrom(ctx context.Context, experimentID, userID uuid.UUID, candidates []uuid.UUID) (uuid.UUID, error)
+}
+
+// segmentMatcher resolves segment membership for rules and experiments
+type segmentMatcher interface {
+	IsMember(ctx context.Context, appID, segmentID, userID uuid.UUID, country string) (bool, error)
+	AllowsExperiment(ctx context.Context, experimentID, userID uuid.UUID, country string) (bool, error)
+}
+
+// experimentEligibilityChecker enforces an experiment's country, platform and
+// app version constraints
+type experimentEligibilityChecker interface {
+	CheckEligibility(ctx context.Context, experimentID, userID uuid.UUID, eligibilityCtx EligibilityContext) (*EligibilityDecision, error)
+}
+
+// priceQuoter returns staged rollout prices for a user
+type priceQuoter interface {
+	Quotes(ctx context.Context, appID, userID uuid.UUID, country string) ([]PriceQuote, error)
+}
+
+// PaywallRuleTrace explains why a rule did or did not match
+type PaywallRuleTrace struct {
+	RuleID   uuid.UUID `json:"rule_id"`
+	Name     string    `json:"name"`
+	Priority int       `json:"priority"`
+	Matched  bool      `json:"matched"`
+	// Reason is the first failed predicate, "disabled" or "placement"
+	Reason string `json:"reason,omitempty"`
+}
+
+// PaywallDecision is the paywall / offer selected for a user at a placement
+type PaywallDecision struct {
+	RuleID       uuid.UUID              `json:"rule_id"`
+	RuleName     string                 `json:"rule_name"`
+	Placement    string                 `json:"placement"`
+	PaywallID    *uuid.UUID             `json:"paywall_id,omitempty"`
+	OfferCode    string                 `json:"offer_code,omitempty"`
+	ExperimentID *uuid.UUID             `json:"experiment_id,omitempty"`
+	ArmID        *uuid.UUID             `json:"arm_id,omitempty"`
+	Candidates   []uuid.UUID            `json:"candidate_arm_ids,omitempty"`
+	Audience     entity.PaywallAudience `json:"audience"`
+	// Prices overrides pricing tiers under a staged price rollout
+	Prices []PriceQuote       `json:"prices,omitempty"`
+	Trace  []PaywallRuleTrace `json:"trace,omitempty"`
+}
+
+// MatchPaywallRule returns the first rule, in priority order, that is enabled,
+// covers placement and whose conditions hold for the audience, together with
+// the evaluation trace of every rule considered. rules must already be sorted
+// by priority (highest first).
+func MatchPaywallRule(rules []*entity.PaywallRule, placement string, audience entity.PaywallAudience) (*entity.PaywallRule, []PaywallRuleTrace) {
+	var matched *entity.PaywallRule
+	trace := make([]PaywallRuleTrace, 0, len(rules))
+	for _, rule := range rules {
+		t := PaywallRuleTrace{RuleID: rule.ID, Name: rule.Name, Priority: rule.Priority}
+		switch {
+		case matched != nil:
+			t.Reason = "lower_priority"
+		case !rule.Enabled:
+			t.Reason = "disabled"
+		case !rule.AppliesTo(placement):
+			t.Reason = "placement"
+		default:
+			t.Reason = rule.Conditions.Mismatch(audience)
+			if t.Reason == "" {
+				t.Matched = true
+				matched = rule
+			}
+		}
+		trace = append(trace, t)
+	}
+	return matched, trace
+}
+
+// PaywallRuleService selects paywalls and offers with personalization rules.
+// Rules decide the candidate set; when a rule points at an experiment the
+// bandit picks the arm within it.
+type PaywallRuleService struct {
+	ruleRepo    repository.PaywallRuleRepository
+	userRepo    repository.UserRepository
+	churnRisk   churnRiskPredictor
+	bandit      candidateArmSelector
+	segments    segmentMatcher
+	eligibility experimentEligibilityChecker
+	prices      priceQuoter
+	logger      *zap.Logger
+	now         func() time.Time
+}
+
+// NewPaywallRuleService creates a new paywall rule service
+func NewPaywallRuleService(ruleRepo repository.PaywallRuleRepository, userRepo repository.UserRepository, logger *zap.Logger) *PaywallRuleService {
+	return &PaywallRuleService{
+		ruleRepo: ruleRepo,
+		userRepo: userRepo,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// WithChurnRisk enables churn-risk predicates; without it every user scores 0
+func (s *PaywallRuleService) WithChurnRisk(predictor churnRiskPredictor) *PaywallRuleService {
+	s.churnRisk = predictor
+	return s
+}
+
+// WithBandit lets rules delegate the final choice to an experiment
+func (s *PaywallRuleService) WithBandit(bandit candidateArmSelector) *PaywallRuleService {
+	s.bandit = bandit
+	return s
+}
+
+// WithSegments enables segment_id conditions and experiment segment targeting
+func (s *PaywallRuleService) WithSegments(segments segmentMatcher) *PaywallRuleService {
+	s.segments = segments
+	return s
+}
+
+// WithEligibility leaves users outside an experiment's eligibility on the
+// rule's paywall without assigning them an arm
+func (s *PaywallRuleService) WithEligibility(eligibility experimentEligibilityChecker) *PaywallRuleService {
+	s.eligibility = eligibility
+	return s
+}
+
+// WithPriceRollouts attaches the user's staged rollout prices to decisions
+func (s *PaywallRuleService) WithPriceRollouts(prices priceQuoter) *PaywallRuleService {
+	s.prices = prices
+	return s
+}
+
+// Audience builds the rule input for a user. country comes from the client
+// (or edge geo header) since it is not stored on the user.
+func (s *PaywallRuleService) Audience(ctx context.Context, userID uuid.UUID, country string) (entity.PaywallAudience, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return entity.PaywallAudience{}, fmt.Errorf("failed to get user: %w", err)
+	}
+	audience := entity.PaywallAudience{
+		Country:          country,
+		Platform:         string(user.Platform),
+		LTV:              user.LTV,
+		DaysSinceInstall: int(s.now().Sub(user.CreatedAt).Hours() / 24),
+		AppVersion:       user.AppVersion,
+	}
+	if s.churnRisk != nil {
+		risk, err := s.churnRisk.PredictChurnRisk(ctx, userID)
+		if err != nil {
+			s.logger.Warn("churn risk unavailable for paywall rules", zap.String("user_id", userID.String()), zap.Error(err))
+		} else {
+			audience.ChurnRisk = risk
+		}
+	}
+	return audience, nil
+}
+
+// Decide selects the paywall for a user at placement and, when the matching
+// rule runs an experiment the user is eligible for, assigns an arm from the
+// rule's candidates
+func (s *PaywallRuleService) Decide(ctx context.Context, appID, userID uuid.UUID, placement, country string) (*PaywallDecision, error) {
+	decision, err := s.DryRunForUser(ctx, appID, userID, placement, country)
+	if err != nil {
+		return nil, err
+	}
+	decision.Trace = nil
+
+	if s.prices != nil {
+		quotes, err := s.prices.Quotes(ctx, appID, userID, country)
+		if err != nil {
+			// Fall back to the tiers' current prices
+			s.logger.Warn("price rollouts unavailable for paywall", zap.String("user_id", userID.String()), zap.Error(err))
+		}
+		decision.Prices = quotes
+	}
+
+	if decision.ExperimentID != nil && s.bandit != nil {
+		if s.segments != nil {
+			allowed, err := s.segments.AllowsExperiment(ctx, *decision.ExperimentID, userID, country)
+			if err != nil {
+				return nil, err
+			}
+			if !allowed {
+				return decision, nil
+			}
+		}
+		if s.eligibility != nil {
+			eligibility, err := s.eligibility.CheckEligibility(ctx, *decision.ExperimentID, userID, EligibilityContext{
+				Country:    country,
+				Platform:   decision.Audience.Platform,
+				AppVersion: decision.Audience.AppVersion,
+			})
+			if err != nil {
+				return nil, err
+			}
+			if !eligibility.Eligible {
+				return decision, nil
+			}
+		}
+		armID, err := s.bandit.SelectArmFrom(ctx, *decision.ExperimentID, userID, decision.Candidates)
+		if errors.Is(err, ErrUserExcluded) || errors.Is(err, ErrExperimentPaused) {
+			// Their arm was archived or the experiment is paused; show the
+			// paywall without a variant
+			return decision, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to select experiment arm: %w", err)
+		}
+		decision.ArmID = &armID
+	}
+	return decision, nil
+}
+
+// DryRunForUser evaluates the app's rules for a user, resolving the segments
+// the rules reference, without assigning an arm
+func (s *PaywallRuleService) DryRunForUser(ctx context.Context, appID, userID uuid.UUID, placement, country string) (*PaywallDecision, error) {
+	audience, err := s.Audience(ctx, userID, country)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := s.listRules(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+	audience.Segments = s.resolveSegments(ctx, appID, userID, country, rules)
+	return decide(rules, placement, audience)
+}
+
+// DryRun evaluates the app's rules for an audience without assigning an arm
+// and returns the per-rule trace. Segment membership is taken from
+// audience.Segments.
+func (s *PaywallRuleService) DryRun(ctx context.Context, appID uuid.UUID, placement string, audience entity.PaywallAudience) (*PaywallDecision, error) {
+	rules, err := s.listRules(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+	return decide(rules, placement, audience)
+}
+
+func (s *PaywallRuleService) listRules(ctx context.Context, appID uuid.UUID) ([]*entity.PaywallRule, error) {
+	rules, err := s.ruleRepo.List(ctx, appID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list paywall rules: %w", err)
+	}
+	return rules, nil
+}
+
+// resolveSegments returns the segments referenced by enabled rules that the
+// user belongs to. A segment that cannot be evaluated counts as not matching.
+func (s *PaywallRuleService) resolveSegments(ctx context.Context, appID, userID uuid.UUID, country string, rules []*entity.PaywallRule) []uuid.UUID {
+	if s.segments == nil {
+		return nil
+	}
+	var member []uuid.UUID
+	checked := map[uuid.UUID]bool{}
+	for _, rule := range rules {
+		id := rule.Conditions.SegmentID
+		if !rule.Enabled || id == nil || checked[*id] {
+			continue
+		}
+		checked[*id] = true
+		ok, err := s.segments.IsMember(ctx, appID, *id, userID, country)
+		if err != nil {
+			s.logger.Warn("segment unavailable for paywall rules", zap.String("segment_id", id.String()), zap.Error(err))
+			continue
+		}
+		if ok {
+			member = append(member, *id)
+		}
+	}
+	return member
+}
+
+func decide(rules []*entity.PaywallRule, placement string, audience entity.PaywallAudience) (*PaywallDecision, error) {
+	rule, trace := MatchPaywallRule(rules, placement, audience)
+	decision := &PaywallDecision{Placement: placement, Audience: audience, Trace: trace}
+	if rule == nil {
+		return decision, ErrNoPaywallRuleMatched
+	}
+	decision.RuleID = rule.ID
+	decision.RuleName = rule.Name
+	decision.PaywallID = rule.PaywallID
+	decision.OfferCode = rule.OfferCode
+	decision.ExperimentID = rule.ExperimentID
+	decision.Candidates = slices.Clone(rule.ArmIDs)
+	return decision, nil
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+type stubPaywallRuleRepo struct {
+	repository.PaywallRuleRepository
+	rules []*entity.PaywallRule
+}
+
+func (s *stubPaywallRuleRepo) List(ctx context.Context, appID uuid.UUID) ([]*entity.PaywallRule, error) {
+	return s.rules, nil
+}
+
+type stubPaywallUserRepo struct {
+	repository.UserRepository
+	user *entity.User
+}
+
+func (s *stubPaywallUserRepo) GetByID(ctx context.Context, id uuid.UUID) (*entity.User, error) {
+	return s.user, nil
+}
+
+type stubChurnRisk float64
+
+func (s stubChurnRisk) PredictChurnRisk(ctx context.Context, userID uuid.UUID) (float64, error) {
+	return float64(s), nil
+}
+
+type stubCandidateBandit struct {
+	candidates []uuid.UUID
+}
+
+func (s *stubCandidateBandit) SelectArmFrom(ctx context.Context, experimentID, userID uuid.UUID, candidates []uuid.UUID) (uuid.UUID, error) {
+	s.candidates = candidates
+	return candidates[len(candidates)-1], nil
+}
+
+func TestMatchPaywallRule_PriorityAndTrace(t *testing.T) {
+	appID := uuid.New()
+	disabled := entity.NewPaywallRule(appID, "disabled", "", 100)
+	disabled.Enabled = false
+	onboarding := entity.NewPaywallRule(appID, "onboarding only", "onboarding", 90)
+	germany := entity.NewPaywallRule(appID, "germany", "", 50)
+	germany.Conditions.Countries = []string{"DE"}
+	fallback := entity.NewPaywallRule(appID, "fallback", "", 0)
+	lowest := entity.NewPaywallRule(appID, "lowest", "", -1)
+
+	rule, trace := MatchPaywallRule([]*entity.PaywallRule{disabled, onboarding, germany, fallback, lowest}, "settings", entity.PaywallAudience{Country: "US"})
+
+	require.NotNil(t, rule)
+	assert.Same(t, fallback, rule)
+	reasons := make([]string, 0, len(trace))
+	for _, tr := range trace {
+		reasons = append(reasons, tr.Reason)
+	}
+	assert.Equal(t, []string{"disabled", "placement", "country", "", "lower_priority"}, reasons)
+	assert.True(t, trace[3].Matched)
+}
+
+func TestPaywallRuleService_DecideRestrictsBanditToRuleArms(t *testing.T) {
+	appID, experimentID, paywallID := uuid.New(), uuid.New(), uuid.New()
+	armA, armB := uuid.New(), uuid.New()
+	maxRisk, maxDays := 0.5, 7
+	loyal := entity.NewPaywallRule(appID, "low churn", "", 20)
+	loyal.Conditions.MaxChurnRisk = &maxRisk
+	loyal.PaywallID = &paywallID
+	atRisk := entity.NewPaywallRule(appID, "new android users", "", 10)
+	atRisk.Conditions.Platforms = []string{"android"}
+	atRisk.Conditions.MaxDaysSinceInstall = &maxDays
+	atRisk.ExperimentID = &experimentID
+	atRisk.ArmIDs = []uuid.UUID{armA, armB}
+	atRisk.OfferCode = "WELCOME50"
+
+	user := entity.NewUser("p-1", "d-1", entity.PlatformAndroid, "1.0", "", appID)
+	user.CreatedAt = time.Now().Add(-48 * time.Hour)
+	bandit := &stubCandidateBandit{}
+	svc := NewPaywallRuleService(&stubPaywallRuleRepo{rules: []*entity.PaywallRule{loyal, atRisk}}, &stubPaywallUserRepo{user: user}, zap.NewNop()).
+		WithChurnRisk(stubChurnRisk(0.9)).
+		WithBandit(bandit)
+
+	decision, err := svc.Decide(context.Background(), appID, user.ID, "", "US")
+
+	require.NoError(t, err)
+	assert.Equal(t, atRisk.ID, decision.RuleID)
+	assert.Equal(t, "WELCOME50", decision.OfferCode)
+	assert.Equal(t, []uuid.UUID{armA, armB}, bandit.candidates)
+	require.NotNil(t, decision.ArmID)
+	assert.Equal(t, armB, *decision.ArmID)
+	assert.Equal(t, 2, decision.Audience.DaysSinceInstall)
+	assert.Nil(t, decision.Trace)
+}
+
+func TestPaywallRuleService_DryRunReportsNoMatch(t *testing.T) {
+	appID := uuid.New()
+	rule := entity.NewPaywallRule(appID, "high ltv", "", 0)
+	rule.Conditions.LTVBuckets = []string{entity.LTVBucketHigh}
+	bandit := &stubCandidateBandit{}
+	svc := NewPaywallRuleService(&stubPaywallRuleRepo{rules: []*entity.PaywallRule{rule}}, nil, zap.NewNop()).WithBandit(bandit)
+
+	decision, err := svc.DryRun(context.Background(), appID, "home", entity.PaywallAudience{LTV: 5})
+
+	assert.ErrorIs(t, err, ErrNoPaywallRuleMatched)
+	require.Len(t, decision.Trace, 1)
+	assert.Equal(t, "ltv_bucket", decision.Trace[0].Reason)
+	assert.Nil(t, bandit.candidates, "dry runs never assign an arm")
+}
+
+type stubEligibilityChecker struct {
+	decision *EligibilityDecision
+	got      EligibilityContext
+}
+
+func (s *stubEligibilityChecker) CheckEligibility(ctx context.Context, experimentID, userID uuid.UUID, eligibilityCtx EligibilityContext) (*EligibilityDecision, error) {
+	s.got = eligibilityCtx
+	return s.decision, nil
+}
+
+func TestPaywallRuleService_DecideSkipsIneligibleExperiment(t *testing.T) {
+	appID, experimentID, paywallID := uuid.New(), uuid.New(), uuid.New()
+	rule := entity.NewPaywallRule(appID, "experiment", "", 0)
+	rule.PaywallID = &paywallID
+	rule.ExperimentID = &experimentID
+
+	user := entity.NewUser("p-1", "d-1", entity.PlatformiOS, "3.1.0", "", appID)
+	bandit := &stubCandidateBandit{}
+	eligibility := &stubEligibilityChecker{decision: &EligibilityDecision{Reason: EligibilityReasonAppVersion}}
+	svc := NewPaywallRuleService(&stubPaywallRuleRepo{rules: []*entity.PaywallRule{rule}}, &stubPaywallUserRepo{user: user}, zap.NewNop()).
+		WithBandit(bandit).
+		WithEligibility(eligibility)
+
+	decision, err := svc.Decide(context.Background(), appID, user.ID, "", "US")
+
+	require.NoError(t, err)
+	assert.Equal(t, &paywallID, decision.PaywallID, "ineligible users keep the rule's paywall")
+	assert.Nil(t, decision.ArmID)
+	assert.Nil(t, bandit.candidates, "ineligible users are never assigned")
+	assert.Equal(t, EligibilityContext{Country: "US", Platform: "ios", AppVersion: "3.1.0"}, eligibility.got)
+	assert.Equal(t, "3.1.0", decision.Audience.AppVersion)
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+// pendingPurchaseHistory is how far back a user's pending purchases are listed
+const pendingPurchaseHistory = 7 * 24 * time.Hour
+
+// PurchaseResolutionNotifier tells the client that a deferred purchase was
+// approved, declined or expired
+type PurchaseResolutionNotifier interface {
+	NotifyPurchaseResolved(ctx context.Context, purchase *entity.PendingPurchase) error
+}
+
+// PendingPurchaseService tracks Ask to Buy and other deferred purchases from
+// the moment the store reports them pending until they are finalized
+type PendingPurchaseService struct {
+	repo      repository.PendingPurchaseRepository
+	notifiers []PurchaseResolutionNotifier
+	logger    *zap.Logger
+	now       func() time.Time
+}
+
+func NewPendingPurchaseService(repo repository.PendingPurchaseRepository, logger *zap.Logger) *PendingPurchaseService {
+	return &PendingPurchaseService{repo: repo, logger: logger, now: time.Now}
+}
+
+// WithNotifier adds a channel (SSE, push) told about every resolution
+func (s *PendingPurchaseService) WithNotifier(notifier PurchaseResolutionNotifier) *PendingPurchaseService {
+	s.notifiers = append(s.notifiers, notifier)
+	return s
+}
+
+// RecordPending opens a pending purchase. Reporting the same product again
+// while one is open returns the open one, with a newly reported purchase or
+// device token left out.
+func (s *PendingPurchaseService) RecordPending(ctx context.Context, purchase *entity.PendingPurchase) (*entity.PendingPurchase, error) {
+	err := s.repo.Create(ctx, purchase)
+	if err == nil {
+		return purchase, nil
+	}
+	if !errors.Is(err, entity.ErrPendingPurchaseOpen) {
+		return nil, fmt.Errorf("failed to record pending purchase: %w", err)
+	}
+	open, err := s.repo.GetOpen(ctx, repository.PendingPurchaseMatch{
+		UserID:    purchase.UserID,
+		ProductID: purchase.ProductID,
+		Platform:  purchase.Platform,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load open pending purchase: %w", err)
+	}
+	return open, nil
+}
+
+// ListForUser returns the user's pending purchases of the last week, newest first
+func (s *PendingPurchaseService) ListForUser(ctx context.Context, userID uuid.UUID) ([]*entity.PendingPurchase, error) {
+	purchases, err := s.repo.ListByUser(ctx, userID, s.now().Add(-pendingPurchaseHistory))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending purchases: %w", err)
+	}
+	return purchases, nil
+}
+
+// Resolve finalizes the open pending purchase matching match and notifies the
+// client. Returns nil without error when nothing is pending, which is the
+// usual case for purchases that were never deferred.
+func (s *PendingPurchaseService) Resolve(ctx context.Context, match repository.PendingPurchaseMatch, status entity.PendingPurchaseStatus, providerTxID string) (*entity.PendingPurchase, error) {
+	purchase, err := s.repo.GetOpen(ctx, match)
+	if err != nil {
+		if errors.Is(err, domainErrors.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to load pending purchase: %w", err)
+	}
+	if err := s.resolve(ctx, purchase, status, providerTxID); err != nil {
+		if errors.Is(err, entity.ErrPendingPurchaseResolved) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return purchase, nil
+}
+
+// ExpireStale expires pending purchases whose deferral window has closed,
+// e.g. Ask to Buy requests the parent never answered. Returns how many expired.
+func (s *PendingPurchaseService) ExpireStale(ctx context.Context, limit int) (int, error) {
+	purchases, err := s.repo.ListExpired(ctx, s.now(), limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list expired pending purchases: %w", err)
+	}
+	expired := 0
+	for _, purchase := range purchases {
+		if err := s.resolve(ctx, purchase, entity.PendingPurchaseStatusExpired, ""); err != nil {
+			if errors.Is(err, entity.ErrPendingPurchaseResolved) {
+				continue
+			}
+			return expired, err
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+func (s *PendingPurchaseService) resolve(ctx context.Context, purchase *entity.PendingPurchase, status entity.PendingPurchaseStatus, providerTxID string) error {
+	if err := purchase.Resolve(status, providerTxID, s.now()); err != nil {
+		return err
+	}
+	if err := s.repo.Resolve(ctx, purchase); err != nil {
+		if errors.Is(err, entity.ErrPendingPurchaseResolved) {
+			return err
+		}
+		return fmt.Errorf("failed to resolve pending purchase: %w", err)
+	}
+
+	// Notifications are best-effort: the client also sees the final status
+	// when it lists its pending purchases
+	for _, notifier := range s.notifiers {
+		if err := notifier.NotifyPurchaseResolved(ctx, purchase); err != nil {
+			s.logger.Warn("Failed to notify pending purchase resolution",
+				zap.String("pending_purchase_id", purchase.ID.String()),
+				zap.String("status", string(purchase.Status)),
+				zap.Error(err),
+			)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+type fakePendingPurchaseRepo struct {
+	purchases []*entity.PendingPurchase
+}
+
+func (r *fakePendingPurchaseRepo) Create(_ context.Context, p *entity.PendingPurchase) error {
+	for _, existing := range r.purchases {
+		if existing.IsPending() && existing.UserID == p.UserID && existing.ProductID == p.ProductID && existing.Platform == p.Platform {
+			return entity.ErrPendingPurchaseOpen
+		}
+	}
+	r.purchases = append(r.purchases, p)
+	return nil
+}
+
+func (r *fakePendingPurchaseRepo) GetOpen(_ context.Context, match repository.PendingPurchaseMatch) (*entity.PendingPurchase, error) {
+	for _, p := range r.purchases {
+		if !p.IsPending() {
+			continue
+		}
+		if match.PurchaseToken != "" && p.PurchaseToken == match.PurchaseToken ||
+			match.PurchaseToken == "" && p.UserID == match.UserID && p.ProductID == match.ProductID && p.Platform == match.Platform {
+			copied := *p
+			return &copied, nil
+		}
+	}
+	return nil, fmt.Errorf("pending purchase: %w", domainErrors.ErrNotFound)
+}
+
+func (r *fakePendingPurchaseRepo) ListByUser(context.Context, uuid.UUID, time.Time) ([]*entity.PendingPurchase, error) {
+	return r.purchases, nil
+}
+
+func (r *fakePendingPurchaseRepo) Resolve(_ context.Context, resolved *entity.PendingPurchase) error {
+	for _, p := range r.purchases {
+		if p.ID == resolved.ID {
+			if !p.IsPending() {
+				return entity.ErrPendingPurchaseResolved
+			}
+			*p = *resolved
+			return nil
+		}
+	}
+	return entity.ErrPendingPurchaseResolved
+}
+
+func (r *fakePendingPurchaseRepo) ListExpired(_ context.Context, now time.Time, _ int) ([]*entity.PendingPurchase, error) {
+	var expired []*entity.PendingPurchase
+	for _, p := range r.purchases {
+		if p.IsPending() && p.ExpiresAt.Before(now) {
+			copied := *p
+			expired = append(expired, &copied)
+		}
+	}
+	return expired, nil
+}
+
+type recordingResolutionNotifier struct {
+	notified []*entity.PendingPurchase
+	err      error
+}
+
+func (n *recordingResolutionNotifier) NotifyPurchaseResolved(_ context.Context, p *entity.PendingPurchase) error {
+	n.notified = append(n.notified, p)
+	return n.err
+}
+
+func TestPendingPurchaseService_RecordPendingReturnsOpenPurchase(t *testing.T) {
+	repo := &fakePendingPurchaseRepo{}
+	svc := NewPendingPurchaseService(repo, zap.NewNop())
+	userID, appID := uuid.New(), uuid.New()
+
+	first, err := svc.RecordPending(context.Background(), entity.NewPendingPurchase(appID, userID, "ios", "com.app.pro", entity.PendingReasonAskToBuy, time.Now()))
+	require.NoError(t, err)
+	again, err := svc.RecordPending(context.Background(), entity.NewPendingPurchase(appID, userID, "ios", "com.app.pro", entity.PendingReasonAskToBuy, time.Now()))
+	require.NoError(t, err)
+
+	assert.Equal(t, first.ID, again.ID)
+	assert.Len(t, repo.purchases, 1)
+}
+
+func TestPendingPurchaseService_ResolveNotifiesBestEffort(t *testing.T) {
+	userID := uuid.New()
+	purchase := entity.NewPendingPurchase(uuid.New(), userID, "android", "com.app.pro", entity.PendingReasonPaymentPending, time.Now())
+	purchase.PurchaseToken = "token-1"
+	repo := &fakePendingPurchaseRepo{purchases: []*entity.PendingPurchase{purchase}}
+	failing := &recordingResolutionNotifier{err: errors.New("redis down")}
+	push := &recordingResolutionNotifier{}
+	svc := NewPendingPurchaseService(repo, zap.NewNop()).WithNotifier(failing).WithNotifier(push)
+
+	resolved, err := svc.Resolve(context.Background(), repository.PendingPurchaseMatch{PurchaseToken: "token-1"}, entity.PendingPurchaseStatusCompleted, "GPA.1")
+
+	require.NoError(t, err)
+	require.NotNil(t, resolved)
+	assert.Equal(t, entity.PendingPurchaseStatusCompleted, repo.purchases[0].Status)
+	assert.Equal(t, "GPA.1", repo.purchases[0].ProviderTxID)
+	assert.Len(t, failing.notified, 1)
+	assert.Len(t, push.notified, 1, "a failing notifier does not stop the others")
+}
+
+func TestPendingPurchaseService_ResolveWithoutPendingPurchase(t *testing.T) {
+	svc := NewPendingPurchaseService(&fakePendingPurchaseRepo{}, zap.NewNop())
+
+	resolved, err := svc.Resolve(context.Background(), repository.PendingPurchaseMatch{PurchaseToken: "unknown"}, entity.PendingPurchaseStatusCompleted, "tx")
+
+	require.NoError(t, err)
+	assert.Nil(t, resolved)
+}
+
+func TestPendingPurchaseService_ExpireStale(t *testing.T) {
+	now := time.Now()
+	stale := entity.NewPendingPurchase(uuid.New(), uuid.New(), "ios", "com.app.pro", entity.PendingReasonAskToBuy, now.Add(-25*time.Hour))
+	fresh := entity.NewPendingPurchase(uuid.New(), uuid.New(), "ios", "com.app.pro", entity.PendingReasonAskToBuy, now.Add(-time.Hour))
+	repo := &fakePendingPurchaseRepo{purchases: []*entity.PendingPurchase{stale, fresh}}
+	notifier := &recordingResolutionNotifier{}
+	svc := NewPendingPurchaseService(repo, zap.NewNop()).WithNotifier(notifier)
+
+	expired, err := svc.ExpireStale(context.Background(), 100)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, expired)
+	assert.Equal(t, entity.PendingPurchaseStatusExpired, stale.Status)
+	assert.True(t, fresh.IsPending())
+	require.Len(t, notifier.notified, 1)
+	assert.Equal(t, stale.ID, notifier.notified[0].ID)
+}
@@ -0,0 +1,296 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/clock"
+)
+
+// Pending reward expiry run statuses
+const (
+	ExpiryRunStatusRunning   = "running"
+	ExpiryRunStatusCompleted = "completed"
+	ExpiryRunStatusFailed    = "failed"
+)
+
+// What started an expiry run
+const (
+	ExpiryRunTriggerScheduled = "scheduled"
+	ExpiryRunTriggerAdmin     = "admin"
+)
+
+const (
+	DefaultExpiryRunBatchSize     = 200
+	DefaultExpiryRunRatePerSecond = 50
+	MaxExpiryRunBatchSize         = 5000
+	MaxExpiryRunRatePerSecond     = 10000
+	// ExpiryRunStaleAfter is how long a running run may go without a
+	// checkpoint before it is considered abandoned and resumed
+	ExpiryRunStaleAfter = 10 * time.Minute
+)
+
+var (
+	ErrInvalidExpiryRun = errors.New("invalid pending reward expiry run")
+	// ErrExpiryRunInProgress is returned when another run is checkpointing
+	ErrExpiryRunInProgress = errors.New("a pending reward expiry run is already in progress")
+	ErrExpiryRunNotFound   = errors.New("pending reward expiry run not found")
+)
+
+// PendingRewardExpiryRequest configures a sweep of expired pending rewards.
+// RatePerSecond 0 records rewards unpaced; MaxBatches 0 runs until no expired
+// rewards remain.
+type PendingRewardExpiryRequest struct {
+	Trigger       string
+	BatchSize     int
+	RatePerSecond int
+	MaxBatches    int
+}
+
+func (r PendingRewardExpiryRequest) Validate() error {
+	if r.Trigger != ExpiryRunTriggerScheduled && r.Trigger != ExpiryRunTriggerAdmin {
+		return fmt.Errorf("%w: unknown trigger %q", ErrInvalidExpiryRun, r.Trigger)
+	}
+	if r.BatchSize < 1 || r.BatchSize > MaxExpiryRunBatchSize {
+		return fmt.Errorf("%w: batch size must be 1-%d", ErrInvalidExpiryRun, MaxExpiryRunBatchSize)
+	}
+	if r.RatePerSecond < 0 || r.RatePerSecond > MaxExpiryRunRatePerSecond {
+		return fmt.Errorf("%w: rate per second must be 0-%d", ErrInvalidExpiryRun, MaxExpiryRunRatePerSecond)
+	}
+	if r.MaxBatches < 0 {
+		return fmt.Errorf("%w: max batches cannot be negative", ErrInvalidExpiryRun)
+	}
+	return nil
+}
+
+// PendingRewardExpiryRun is a persisted sweep and how far it got
+type PendingRewardExpiryRun struct {
+	ID            uuid.UUID            `json:"id"`
+	Status        string               `json:"status"`
+	Trigger       string               `json:"trigger"`
+	BatchSize     int                  `json:"batch_size"`
+	RatePerSecond int                  `json:"rate_per_second"`
+	MaxBatches    int                  `json:"max_batches"`
+	ExpiredBefore time.Time            `json:"expired_before"`
+	Cursor        *ExpiredRewardCursor `json:"cursor,omitempty"`
+	BatchesDone   int                  `json:"batches_done"`
+	Processed     int                  `json:"processed"`
+	Skipped       int                  `json:"skipped"`
+	Failed        int                  `json:"failed"`
+	LastError     *string              `json:"last_error,omitempty"`
+	StartedAt     time.Time            `json:"started_at"`
+	UpdatedAt     time.Time            `json:"updated_at"`
+	CompletedAt   *time.Time           `json:"completed_at,omitempty"`
+}
+
+type PendingRewardExpiryRunRepository interface {
+	// CreateExpiryRun inserts a running run, returning ErrExpiryRunInProgress
+	// when another run is already running
+	CreateExpiryRun(ctx context.Context, run *PendingRewardExpiryRun) error
+	// GetExpiryRun returns nil for an unknown run
+	GetExpiryRun(ctx context.Context, id uuid.UUID) (*PendingRewardExpiryRun, error)
+	// GetRunningExpiryRun returns nil when no run is running
+	GetRunningExpiryRun(ctx context.Context) (*PendingRewardExpiryRun, error)
+	ListExpiryRuns(ctx context.Context, limit int) ([]*PendingRewardExpiryRun, error)
+	// SaveExpiryRun checkpoints the run's status, cursor and counters
+	SaveExpiryRun(ctx context.Context, run *PendingRewardExpiryRun) error
+}
+
+type expiredRewardPageProcessor interface {
+	ProcessExpiredPendingRewardsPage(ctx context.Context, after *ExpiredRewardCursor, expiredBefore time.Time, limit int, pace func(context.Context) error) (ExpiredRewardPage, error)
+}
+
+// PendingRewardExpiryService sweeps expired pending rewards in pages, pacing
+// how fast they are recorded against the arm stats and checkpointing after
+// every page. Recording an expired reward is idempotent, so a page cut short
+// can safely be replayed.
+type PendingRewardExpiryService struct {
+	repo      PendingRewardExpiryRunRepository
+	processor expiredRewardPageProcessor
+	now       func() time.Time
+}
+
+func NewPendingRewardExpiryService(repo PendingRewardExpiryRunRepository, processor expiredRewardPageProcessor) *PendingRewardExpiryService {
+	return &PendingRewardExpiryService{repo: repo, processor: processor, now: time.Now}
+}
+
+// WithClock makes expiry cutoffs and checkpoint times come from c. Pacing
+// still waits on real timers.
+func (s *PendingRewardExpiryService) WithClock(c clock.Clock) *PendingRewardExpiryService {
+	s.now = c.Now
+	return s
+}
+
+// Start records a new run; call Execute to run it. A running run that has
+// not checkpointed within ExpiryRunStaleAfter is returned instead, to be
+// resumed, and a live one yields ErrExpiryRunInProgress along with it.
+func (s *PendingRewardExpiryService) Start(ctx context.Context, req PendingRewardExpiryRequest) (*PendingRewardExpiryRun, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	running, err := s.repo.GetRunningExpiryRun(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get running expiry run: %w", err)
+	}
+	if running != nil {
+		if s.now().Sub(running.UpdatedAt) < ExpiryRunStaleAfter {
+			return running, ErrExpiryRunInProgress
+		}
+		return running, nil
+	}
+
+	now := s.now().UTC()
+	run := &PendingRewardExpiryRun{
+		ID:            uuid.New(),
+		Status:        ExpiryRunStatusRunning,
+		Trigger:       req.Trigger,
+		BatchSize:     req.BatchSize,
+		RatePerSecond: req.RatePerSecond,
+		MaxBatches:    req.MaxBatches,
+		ExpiredBefore: now,
+		StartedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err := s.repo.CreateExpiryRun(ctx, run); err != nil {
+		if errors.Is(err, ErrExpiryRunInProgress) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to create expiry run: %w", err)
+	}
+	return run, nil
+}
+
+// Resume loads a run so Execute continues after its last checkpoint
+func (s *PendingRewardExpiryService) Resume(ctx context.Context, runID uuid.UUID) (*PendingRewardExpiryRun, error) {
+	run, err := s.Get(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	if run.Status == ExpiryRunStatusCompleted {
+		return nil, fmt.Errorf("%w: run %s already completed", ErrInvalidExpiryRun, runID)
+	}
+	return run, nil
+}
+
+// Execute sweeps the run's remaining pages. progress is called after each
+// checkpointed page, including one cut short, with what that page did. A failure marks the run failed
+// and Resume picks it up from the last checkpoint.
+func (s *PendingRewardExpiryService) Execute(ctx context.Context, run *PendingRewardExpiryRun, progress func(*PendingRewardExpiryRun, ExpiredRewardPage)) error {
+	run.Status, run.LastError = ExpiryRunStatusRunning, nil
+	pace := newRewardPacer(run.RatePerSecond)
+
+	for run.MaxBatches == 0 || run.BatchesDone < run.MaxBatches {
+		page, err := s.processor.ProcessExpiredPendingRewardsPage(ctx, run.Cursor, run.ExpiredBefore, run.BatchSize, pace)
+		s.record(run, page)
+		if err == nil {
+			err = s.checkpoint(ctx, run)
+		}
+		if err != nil {
+			err = s.fail(ctx, run, err)
+		}
+		if progress != nil {
+			progress(run, page)
+		}
+		if err != nil {
+			return err
+		}
+		if page.Fetched < run.BatchSize {
+			break
+		}
+	}
+
+	now := s.now().UTC()
+	run.Status, run.CompletedAt = ExpiryRunStatusCompleted, &now
+	if err := s.checkpoint(ctx, run); err != nil {
+		return fmt.Errorf("failed to mark expiry run completed: %w", err)
+	}
+	return nil
+}
+
+// MarkFailed records why a run could not be executed
+func (s *PendingRewardExpiryService) MarkFailed(ctx context.Context, run *PendingRewardExpiryRun, cause error) error {
+	msg := cause.Error()
+	run.Status, run.LastError = ExpiryRunStatusFailed, &msg
+	return s.checkpoint(ctx, run)
+}
+
+// Get returns a run, or ErrExpiryRunNotFound
+func (s *PendingRewardExpiryService) Get(ctx context.Context, runID uuid.UUID) (*PendingRewardExpiryRun, error) {
+	run, err := s.repo.GetExpiryRun(ctx, runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expiry run: %w", err)
+	}
+	if run == nil {
+		return nil, ErrExpiryRunNotFound
+	}
+	return run, nil
+}
+
+// List returns the latest runs, newest first
+func (s *PendingRewardExpiryService) List(ctx context.Context, limit int) ([]*PendingRewardExpiryRun, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	runs, err := s.repo.ListExpiryRuns(ctx, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expiry runs: %w", err)
+	}
+	return runs, nil
+}
+
+func (s *PendingRewardExpiryService) record(run *PendingRewardExpiryRun, page ExpiredRewardPage) {
+	if page.Next != nil {
+		run.Cursor = page.Next
+	}
+	if page.Fetched > 0 {
+		run.BatchesDone++
+	}
+	run.Processed += page.Processed
+	run.Skipped += page.Skipped
+	run.Failed += page.Failed
+}
+
+func (s *PendingRewardExpiryService) checkpoint(ctx context.Context, run *PendingRewardExpiryRun) error {
+	run.UpdatedAt = s.now().UTC()
+	if err := s.repo.SaveExpiryRun(ctx, run); err != nil {
+		return fmt.Errorf("failed to checkpoint expiry run: %w", err)
+	}
+	return nil
+}
+
+// fail keeps the progress made before err and marks the run failed
+func (s *PendingRewardExpiryService) fail(ctx context.Context, run *PendingRewardExpiryRun, err error) error {
+	// The run's own context may be what failed
+	if markErr := s.MarkFailed(context.WithoutCancel(ctx), run, err); markErr != nil {
+		return fmt.Errorf("%w (and failed to mark run failed: %v)", err, markErr)
+	}
+	return err
+}
+
+// newRewardPacer spaces calls ratePerSecond apart; nil means unpaced
+func newRewardPacer(ratePerSecond int) func(context.Context) error {
+	if ratePerSecond <= 0 {
+		return nil
+	}
+	interval := time.Second / time.Duration(ratePerSecond)
+	var next time.Time
+	return func(ctx context.Context) error {
+		now := time.Now()
+		if wait := next.Sub(now); wait > 0 {
+			timer := time.NewTimer(wait)
+			defer timer.Stop()
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-timer.C:
+			}
+			now = next
+		}
+		next = now.Add(interval)
+		return nil
+	}
+}
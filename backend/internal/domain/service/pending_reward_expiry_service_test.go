@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type expiryRunTestRepo struct {
+	runs  map[uuid.UUID]*PendingRewardExpiryRun
+	saves []PendingRewardExpiryRun
+}
+
+func newExpiryRunTestRepo() *expiryRunTestRepo {
+	return &expiryRunTestRepo{runs: map[uuid.UUID]*PendingRewardExpiryRun{}}
+}
+
+func (r *expiryRunTestRepo) CreateExpiryRun(ctx context.Context, run *PendingRewardExpiryRun) error {
+	copied := *run
+	r.runs[run.ID] = &copied
+	return nil
+}
+
+func (r *expiryRunTestRepo) GetExpiryRun(ctx context.Context, id uuid.UUID) (*PendingRewardExpiryRun, error) {
+	if run, ok := r.runs[id]; ok {
+		copied := *run
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+func (r *expiryRunTestRepo) GetRunningExpiryRun(ctx context.Context) (*PendingRewardExpiryRun, error) {
+	for _, run := range r.runs {
+		if run.Status == ExpiryRunStatusRunning {
+			copied := *run
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *expiryRunTestRepo) ListExpiryRuns(ctx context.Context, limit int) ([]*PendingRewardExpiryRun, error) {
+	return nil, nil
+}
+
+func (r *expiryRunTestRepo) SaveExpiryRun(ctx context.Context, run *PendingRewardExpiryRun) error {
+	copied := *run
+	r.runs[run.ID] = &copied
+	r.saves = append(r.saves, copied)
+	return nil
+}
+
+// expiryPageTestProcessor serves canned pages and records each cursor
+type expiryPageTestProcessor struct {
+	pages   []ExpiredRewardPage
+	errAt   int
+	cursors []*ExpiredRewardCursor
+}
+
+func (p *expiryPageTestProcessor) ProcessExpiredPendingRewardsPage(ctx context.Context, after *ExpiredRewardCursor, expiredBefore time.Time, limit int, pace func(context.Context) error) (ExpiredRewardPage, error) {
+	call := len(p.cursors)
+	p.cursors = append(p.cursors, after)
+	if call >= len(p.pages) {
+		return ExpiredRewardPage{}, nil
+	}
+	if p.errAt > 0 && call == p.errAt {
+		return p.pages[call], errors.New("database went away")
+	}
+	return p.pages[call], nil
+}
+
+func expiryTestPage(fetched, processed, skipped int, at time.Time) ExpiredRewardPage {
+	return ExpiredRewardPage{
+		Fetched:   fetched,
+		Processed: processed,
+		Skipped:   skipped,
+		Failed:    fetched - processed - skipped,
+		Next:      &ExpiredRewardCursor{ExpiresAt: at, ID: uuid.New()},
+	}
+}
+
+func TestPendingRewardExpiryService_ExecutePagesAndCheckpoints(t *testing.T) {
+	base := time.Now().Add(-time.Hour)
+	repo := newExpiryRunTestRepo()
+	processor := &expiryPageTestProcessor{pages: []ExpiredRewardPage{
+		expiryTestPage(2, 2, 0, base),
+		expiryTestPage(2, 1, 1, base.Add(time.Minute)),
+		expiryTestPage(1, 0, 0, base.Add(2*time.Minute)),
+	}}
+	svc := NewPendingRewardExpiryService(repo, processor)
+
+	run, err := svc.Start(context.Background(), PendingRewardExpiryRequest{Trigger: ExpiryRunTriggerAdmin, BatchSize: 2})
+	require.NoError(t, err)
+	var progressed []int
+	require.NoError(t, svc.Execute(context.Background(), run, func(run *PendingRewardExpiryRun, page ExpiredRewardPage) {
+		progressed = append(progressed, run.BatchesDone)
+	}))
+
+	// The short third page ends the sweep
+	assert.Equal(t, []int{1, 2, 3}, progressed)
+	require.Len(t, processor.cursors, 3)
+	assert.Nil(t, processor.cursors[0])
+	assert.Equal(t, processor.pages[0].Next, processor.cursors[1])
+	assert.Equal(t, processor.pages[1].Next, processor.cursors[2])
+
+	stored := repo.runs[run.ID]
+	assert.Equal(t, ExpiryRunStatusCompleted, stored.Status)
+	assert.Equal(t, 3, stored.Processed)
+	assert.Equal(t, 1, stored.Skipped)
+	assert.Equal(t, 1, stored.Failed)
+	assert.Equal(t, processor.pages[2].Next, stored.Cursor)
+	assert.NotNil(t, stored.CompletedAt)
+	assert.Len(t, repo.saves, 4)
+}
+
+func TestPendingRewardExpiryService_FailedRunResumesFromCheckpoint(t *testing.T) {
+	base := time.Now().Add(-time.Hour)
+	repo := newExpiryRunTestRepo()
+	processor := &expiryPageTestProcessor{errAt: 1, pages: []ExpiredRewardPage{
+		expiryTestPage(2, 2, 0, base),
+		expiryTestPage(1, 1, 0, base.Add(time.Minute)),
+	}}
+	svc := NewPendingRewardExpiryService(repo, processor)
+
+	run, err := svc.Start(context.Background(), PendingRewardExpiryRequest{Trigger: ExpiryRunTriggerScheduled, BatchSize: 2})
+	require.NoError(t, err)
+	require.Error(t, svc.Execute(context.Background(), run, nil))
+
+	// What the cut-short page did is kept
+	stored := repo.runs[run.ID]
+	assert.Equal(t, ExpiryRunStatusFailed, stored.Status)
+	require.NotNil(t, stored.LastError)
+	assert.Equal(t, 3, stored.Processed)
+	assert.Equal(t, processor.pages[1].Next, stored.Cursor)
+
+	processor.errAt = 0
+	resumed, err := svc.Resume(context.Background(), run.ID)
+	require.NoError(t, err)
+	require.NoError(t, svc.Execute(context.Background(), resumed, nil))
+	assert.Equal(t, processor.pages[1].Next, processor.cursors[2])
+	assert.Equal(t, ExpiryRunStatusCompleted, repo.runs[run.ID].Status)
+	assert.Nil(t, repo.runs[run.ID].LastError)
+
+	_, err = svc.Resume(context.Background(), run.ID)
+	assert.ErrorIs(t, err, ErrInvalidExpiryRun)
+}
+
+func TestPendingRewardExpiryService_ExecuteStopsAtMaxBatches(t *testing.T) {
+	base := time.Now().Add(-time.Hour)
+	repo := newExpiryRunTestRepo()
+	processor := &expiryPageTestProcessor{pages: []ExpiredRewardPage{
+		expiryTestPage(1, 1, 0, base),
+		expiryTestPage(1, 1, 0, base.Add(time.Minute)),
+		expiryTestPage(1, 1, 0, base.Add(2*time.Minute)),
+	}}
+	svc := NewPendingRewardExpiryService(repo, processor)
+
+	run, err := svc.Start(context.Background(), PendingRewardExpiryRequest{Trigger: ExpiryRunTriggerScheduled, BatchSize: 1, MaxBatches: 2})
+	require.NoError(t, err)
+	require.NoError(t, svc.Execute(context.Background(), run, nil))
+
+	assert.Len(t, processor.cursors, 2)
+	assert.Equal(t, 2, repo.runs[run.ID].BatchesDone)
+	assert.Equal(t, ExpiryRunStatusCompleted, repo.runs[run.ID].Status)
+}
+
+func TestPendingRewardExpiryService_StartRejectsLiveRunAndResumesStaleOne(t *testing.T) {
+	now := time.Now()
+	repo := newExpiryRunTestRepo()
+	svc := NewPendingRewardExpiryService(repo, &expiryPageTestProcessor{})
+	svc.now = func() time.Time { return now }
+
+	_, err := svc.Start(context.Background(), PendingRewardExpiryRequest{Trigger: "cron", BatchSize: 10})
+	require.ErrorIs(t, err, ErrInvalidExpiryRun)
+	_, err = svc.Start(context.Background(), PendingRewardExpiryRequest{Trigger: ExpiryRunTriggerAdmin, BatchSize: MaxExpiryRunBatchSize + 1})
+	require.ErrorIs(t, err, ErrInvalidExpiryRun)
+
+	first, err := svc.Start(context.Background(), PendingRewardExpiryRequest{Trigger: ExpiryRunTriggerAdmin, BatchSize: 10})
+	require.NoError(t, err)
+
+	running, err := svc.Start(context.Background(), PendingRewardExpiryRequest{Trigger: ExpiryRunTriggerScheduled, BatchSize: 10})
+	require.ErrorIs(t, err, ErrExpiryRunInProgress)
+	assert.Equal(t, first.ID, running.ID)
+
+	now = now.Add(ExpiryRunStaleAfter)
+	stale, err := svc.Start(context.Background(), PendingRewardExpiryRequest{Trigger: ExpiryRunTriggerScheduled, BatchSize: 10})
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, stale.ID)
+	assert.Len(t, repo.runs, 1)
+}
+
+func TestRewardPacer_SpacesCalls(t *testing.T) {
+	assert.Nil(t, newRewardPacer(0))
+
+	pace := newRewardPacer(100)
+	started := time.Now()
+	for i := 0; i < 4; i++ {
+		require.NoError(t, pace(context.Background()))
+	}
+	assert.GreaterOrEqual(t, time.Since(started), 30*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, pace(ctx), context.Canceled)
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/clock"
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+// Pending subscribers are prompted to consent at most every
+// PriceIncreasePromptInterval, up to PriceIncreaseMaxPrompts times
+const (
+	PriceIncreasePromptInterval = 3 * 24 * time.Hour
+	PriceIncreaseMaxPrompts     = 3
+)
+
+// priceIncreasePromptBatch bounds how many subscribers one prompt run reaches
+const priceIncreasePromptBatch = 500
+
+// ErrPriceIncreasePromptsDisabled is returned when prompting without a prompter
+var ErrPriceIncreasePromptsDisabled = errors.New("price increase prompts are not configured")
+
+// PriceIncreaseNotice is a store notification about a subscription's price
+// increase: still awaiting consent, accepted, or expired without consent
+type PriceIncreaseNotice struct {
+	AppID          uuid.UUID
+	SubscriptionID uuid.UUID
+	UserID         uuid.UUID
+	Platform       string
+	ProductID      string
+	Status         entity.PriceIncreaseConsentStatus
+	NewPrice       *float64
+	Currency       string
+}
+
+// PriceIncreasePrompt is the message asking a subscriber to consent; empty
+// fields fall back to the default wording
+type PriceIncreasePrompt struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+// Default consent prompt wording
+const (
+	DefaultPriceIncreasePromptTitle = "Your subscription price is changing"
+	DefaultPriceIncreasePromptBody  = "Review and accept the new price to keep your subscription after the current period."
+)
+
+// PriceIncreasePromptResult counts the prompts of one run
+type PriceIncreasePromptResult struct {
+	Sent   int `json:"sent"`
+	Failed int `json:"failed"`
+}
+
+// PriceIncreasePrompter delivers a consent prompt to a subscriber
+type PriceIncreasePrompter interface {
+	SendPriceIncreaseConsentPrompt(ctx context.Context, user *entity.User, consent *entity.PriceIncreaseConsent, title, body string) error
+}
+
+// PriceIncreaseConsentService tracks subscribers' consent to store price
+// increases and prompts those who have not answered
+type PriceIncreaseConsentService struct {
+	repo     repository.PriceIncreaseConsentRepository
+	users    repository.UserRepository
+	prompter PriceIncreasePrompter
+	logger   *zap.Logger
+	now      func() time.Time
+}
+
+func NewPriceIncreaseConsentService(repo repository.PriceIncreaseConsentRepository, logger *zap.Logger) *PriceIncreaseConsentService {
+	return &PriceIncreaseConsentService{repo: repo, logger: logger, now: time.Now}
+}
+
+// WithPrompts lets SendPrompts reach pending subscribers through prompter
+func (s *PriceIncreaseConsentService) WithPrompts(users repository.UserRepository, prompter PriceIncreasePrompter) *PriceIncreaseConsentService {
+	s.users = users
+	s.prompter = prompter
+	return s
+}
+
+// WithClock makes notices and prompts happen at c's current time
+func (s *PriceIncreaseConsentService) WithClock(c clock.Clock) *PriceIncreaseConsentService {
+	s.now = c.Now
+	return s
+}
+
+// RecordNotice applies a store notice to the subscription's pending price
+// increase, opening one when there is none. An answer without a pending
+// increase, as when the store needs no consent, is stored already answered.
+func (s *PriceIncreaseConsentService) RecordNotice(ctx context.Context, notice PriceIncreaseNotice) (*entity.PriceIncreaseConsent, error) {
+	if !notice.Status.IsValid() {
+		return nil, fmt.Errorf("unknown price increase status %q", notice.Status)
+	}
+	now := s.now()
+
+	consent, err := s.repo.GetPending(ctx, notice.SubscriptionID)
+	if errors.Is(err, domainErrors.ErrNotFound) {
+		if answered, ok := s.answered(ctx, notice); ok {
+			// A redelivered store notice; the answer is already stored
+			return answered, nil
+		}
+		consent = entity.NewPriceIncreaseConsent(notice.AppID, notice.SubscriptionID, notice.UserID,
+			notice.Platform, notice.ProductID, notice.Status, now)
+		setPriceIncreasePrice(consent, notice)
+		err = s.repo.Create(ctx, consent)
+		if err == nil {
+			return consent, nil
+		}
+		if !errors.Is(err, entity.ErrPriceIncreaseOpen) {
+			return nil, fmt.Errorf("failed to record price increase: %w", err)
+		}
+		// Opened concurrently by another notice; apply this one to it
+		consent, err = s.repo.GetPending(ctx, notice.SubscriptionID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pending price increase: %w", err)
+	}
+
+	setPriceIncreasePrice(consent, notice)
+	consent.UpdatedAt = now
+	if notice.Status != entity.PriceIncreasePending {
+		if err := consent.Respond(notice.Status, now); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.repo.Update(ctx, consent); err != nil {
+		return nil, fmt.Errorf("failed to update price increase: %w", err)
+	}
+	return consent, nil
+}
+
+// answered returns the subscription's latest price increase when it already
+// holds the answer notice reports, for the same price when the notice has one
+func (s *PriceIncreaseConsentService) answered(ctx context.Context, notice PriceIncreaseNotice) (*entity.PriceIncreaseConsent, bool) {
+	if notice.Status == entity.PriceIncreasePending {
+		return nil, false
+	}
+	latest, err := s.repo.GetLatest(ctx, notice.SubscriptionID)
+	if err != nil || latest.Status != notice.Status {
+		return nil, false
+	}
+	if notice.NewPrice != nil && latest.NewPrice != nil && *notice.NewPrice != *latest.NewPrice {
+		return nil, false
+	}
+	return latest, true
+}
+
+func setPriceIncreasePrice(consent *entity.PriceIncreaseConsent, notice PriceIncreaseNotice) {
+	if notice.NewPrice != nil {
+		consent.NewPrice = notice.NewPrice
+		consent.Currency = notice.Currency
+	}
+}
+
+// ListPending returns an app's subscribers yet to consent, oldest notice
+// first, with the total number pending
+func (s *PriceIncreaseConsentService) ListPending(ctx context.Context, appID uuid.UUID, limit, offset int) ([]*entity.PriceIncreaseConsent, int, error) {
+	consents, total, err := s.repo.ListPending(ctx, appID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list pending price increases: %w", err)
+	}
+	return consents, total, nil
+}
+
+// SendPrompts asks pending subscribers of an app, of every app when appID is
+// uuid.Nil, to consent. Subscribers prompted within the last
+// PriceIncreasePromptInterval or PriceIncreaseMaxPrompts times are skipped.
+func (s *PriceIncreaseConsentService) SendPrompts(ctx context.Context, appID uuid.UUID, prompt PriceIncreasePrompt) (PriceIncreasePromptResult, error) {
+	var result PriceIncreasePromptResult
+	if s.prompter == nil || s.users == nil {
+		return result, ErrPriceIncreasePromptsDisabled
+	}
+	if prompt.Title == "" {
+		prompt.Title = DefaultPriceIncreasePromptTitle
+	}
+	if prompt.Body == "" {
+		prompt.Body = DefaultPriceIncreasePromptBody
+	}
+
+	now := s.now()
+	consents, err := s.repo.ListPromptable(ctx, appID, now.Add(-PriceIncreasePromptInterval), PriceIncreaseMaxPrompts, priceIncreasePromptBatch)
+	if err != nil {
+		return result, fmt.Errorf("failed to list promptable price increases: %w", err)
+	}
+
+	for _, consent := range consents {
+		user, err := s.users.GetByID(ctx, consent.UserID)
+		if err == nil {
+			err = s.prompter.SendPriceIncreaseConsentPrompt(ctx, user, consent, prompt.Title, prompt.Body)
+		}
+		if err != nil {
+			result.Failed++
+			s.logger.Warn("Failed to prompt price increase consent",
+				zap.String("subscription_id", consent.SubscriptionID.String()),
+				zap.Error(err),
+			)
+			continue
+		}
+		consent.RecordPrompt(now)
+		if err := s.repo.Update(ctx, consent); err != nil {
+			return result, fmt.Errorf("failed to record price increase prompt: %w", err)
+		}
+		result.Sent++
+	}
+	return result, nil
+}
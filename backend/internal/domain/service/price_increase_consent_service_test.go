@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/clock"
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+type memoryPriceIncreaseRepo struct {
+	consents []*entity.PriceIncreaseConsent
+}
+
+func (r *memoryPriceIncreaseRepo) Create(ctx context.Context, c *entity.PriceIncreaseConsent) error {
+	if c.IsPending() {
+		if _, err := r.GetPending(ctx, c.SubscriptionID); err == nil {
+			return entity.ErrPriceIncreaseOpen
+		}
+	}
+	copied := *c
+	r.consents = append(r.consents, &copied)
+	return nil
+}
+
+func (r *memoryPriceIncreaseRepo) GetPending(ctx context.Context, subscriptionID uuid.UUID) (*entity.PriceIncreaseConsent, error) {
+	for _, c := range r.consents {
+		if c.SubscriptionID == subscriptionID && c.IsPending() {
+			copied := *c
+			return &copied, nil
+		}
+	}
+	return nil, domainErrors.ErrNotFound
+}
+
+func (r *memoryPriceIncreaseRepo) GetLatest(ctx context.Context, subscriptionID uuid.UUID) (*entity.PriceIncreaseConsent, error) {
+	var latest *entity.PriceIncreaseConsent
+	for _, c := range r.consents {
+		if c.SubscriptionID == subscriptionID && (latest == nil || !c.NotifiedAt.Before(latest.NotifiedAt)) {
+			latest = c
+		}
+	}
+	if latest == nil {
+		return nil, domainErrors.ErrNotFound
+	}
+	copied := *latest
+	return &copied, nil
+}
+
+func (r *memoryPriceIncreaseRepo) Update(ctx context.Context, c *entity.PriceIncreaseConsent) error {
+	for i, stored := range r.consents {
+		if stored.ID == c.ID {
+			copied := *c
+			r.consents[i] = &copied
+			return nil
+		}
+	}
+	return domainErrors.ErrNotFound
+}
+
+func (r *memoryPriceIncreaseRepo) ListPending(ctx context.Context, appID uuid.UUID, limit, offset int) ([]*entity.PriceIncreaseConsent, int, error) {
+	var pending []*entity.PriceIncreaseConsent
+	for _, c := range r.consents {
+		if c.AppID == appID && c.IsPending() {
+			pending = append(pending, c)
+		}
+	}
+	return pending, len(pending), nil
+}
+
+func (r *memoryPriceIncreaseRepo) ListPromptable(ctx context.Context, appID uuid.UUID, promptedBefore time.Time, maxPrompts, limit int) ([]*entity.PriceIncreaseConsent, error) {
+	var due []*entity.PriceIncreaseConsent
+	for _, c := range r.consents {
+		if (appID == uuid.Nil || c.AppID == appID) && c.IsPending() && c.PromptCount < maxPrompts &&
+			(c.LastPromptedAt == nil || !c.LastPromptedAt.After(promptedBefore)) {
+			copied := *c
+			due = append(due, &copied)
+		}
+	}
+	return due, nil
+}
+
+type stubPriceIncreaseUsers struct {
+	repository.UserRepository
+	users map[uuid.UUID]*entity.User
+}
+
+func (s *stubPriceIncreaseUsers) GetByID(ctx context.Context, id uuid.UUID) (*entity.User, error) {
+	if u, ok := s.users[id]; ok {
+		return u, nil
+	}
+	return nil, fmt.Errorf("user: %w", domainErrors.ErrNotFound)
+}
+
+type recordingPriceIncreasePrompter struct {
+	prompted []uuid.UUID
+	bodies   []string
+}
+
+func (p *recordingPriceIncreasePrompter) SendPriceIncreaseConsentPrompt(ctx context.Context, user *entity.User, consent *entity.PriceIncreaseConsent, title, body string) error {
+	p.prompted = append(p.prompted, user.ID)
+	p.bodies = append(p.bodies, body)
+	return nil
+}
+
+func TestPriceIncreaseConsentService_RecordNotice(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC))
+	repo := &memoryPriceIncreaseRepo{}
+	svc := NewPriceIncreaseConsentService(repo, zap.NewNop()).WithClock(clk)
+	price := 12.99
+	notice := PriceIncreaseNotice{
+		AppID:          uuid.New(),
+		SubscriptionID: uuid.New(),
+		UserID:         uuid.New(),
+		Platform:       "ios",
+		ProductID:      "com.app.monthly",
+		Status:         entity.PriceIncreasePending,
+		NewPrice:       &price,
+		Currency:       "USD",
+	}
+
+	t.Run("pending notice opens the increase once", func(t *testing.T) {
+		first, err := svc.RecordNotice(ctx, notice)
+		require.NoError(t, err)
+		clk.Advance(time.Hour)
+		again, err := svc.RecordNotice(ctx, notice)
+		require.NoError(t, err)
+
+		assert.Equal(t, first.ID, again.ID)
+		assert.Len(t, repo.consents, 1)
+		assert.Equal(t, clk.Now().Add(-time.Hour), again.NotifiedAt)
+		require.NotNil(t, again.NewPrice)
+		assert.Equal(t, 12.99, *again.NewPrice)
+	})
+
+	t.Run("expiry without consent declines it", func(t *testing.T) {
+		declined := notice
+		declined.Status = entity.PriceIncreaseDeclined
+		declined.NewPrice = nil
+
+		consent, err := svc.RecordNotice(ctx, declined)
+		require.NoError(t, err)
+
+		assert.Equal(t, entity.PriceIncreaseDeclined, consent.Status)
+		require.NotNil(t, consent.RespondedAt)
+		assert.Equal(t, clk.Now(), *consent.RespondedAt)
+		require.NotNil(t, consent.NewPrice, "the announced price is kept")
+		_, err = repo.GetPending(ctx, notice.SubscriptionID)
+		assert.True(t, errors.Is(err, domainErrors.ErrNotFound))
+
+		again, err := svc.RecordNotice(ctx, declined)
+		require.NoError(t, err)
+		assert.Equal(t, consent.ID, again.ID, "a redelivered answer is not stored twice")
+		assert.Len(t, repo.consents, 1)
+	})
+
+	t.Run("acceptance without a pending notice is stored answered", func(t *testing.T) {
+		accepted := notice
+		accepted.SubscriptionID = uuid.New()
+		accepted.Status = entity.PriceIncreaseAccepted
+
+		consent, err := svc.RecordNotice(ctx, accepted)
+		require.NoError(t, err)
+
+		assert.Equal(t, entity.PriceIncreaseAccepted, consent.Status)
+		assert.Len(t, repo.consents, 2)
+	})
+
+	t.Run("unknown status is rejected", func(t *testing.T) {
+		bad := notice
+		bad.Status = "maybe"
+		_, err := svc.RecordNotice(ctx, bad)
+		assert.Error(t, err)
+	})
+}
+
+func TestPriceIncreaseConsentService_SendPrompts(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC))
+	appID := uuid.New()
+	known := &entity.User{ID: uuid.New(), Email: "known@example.com"}
+	repo := &memoryPriceIncreaseRepo{}
+	for _, userID := range []uuid.UUID{known.ID, uuid.New()} {
+		require.NoError(t, repo.Create(ctx, entity.NewPriceIncreaseConsent(appID, uuid.New(), userID, "ios", "com.app.monthly", entity.PriceIncreasePending, clk.Now())))
+	}
+	prompter := &recordingPriceIncreasePrompter{}
+	svc := NewPriceIncreaseConsentService(repo, zap.NewNop()).
+		WithClock(clk).
+		WithPrompts(&stubPriceIncreaseUsers{users: map[uuid.UUID]*entity.User{known.ID: known}}, prompter)
+
+	result, err := svc.SendPrompts(ctx, appID, PriceIncreasePrompt{})
+	require.NoError(t, err)
+
+	assert.Equal(t, PriceIncreasePromptResult{Sent: 1, Failed: 1}, result)
+	assert.Equal(t, []uuid.UUID{known.ID}, prompter.prompted)
+	assert.Equal(t, DefaultPriceIncreasePromptBody, prompter.bodies[0])
+
+	t.Run("prompted subscribers wait for the interval", func(t *testing.T) {
+		clk.Advance(PriceIncreasePromptInterval - time.Minute)
+		result, err := svc.SendPrompts(ctx, appID, PriceIncreasePrompt{Body: "Custom"})
+		require.NoError(t, err)
+		assert.Equal(t, 0, result.Sent)
+
+		clk.Advance(time.Minute)
+		result, err = svc.SendPrompts(ctx, uuid.Nil, PriceIncreasePrompt{Body: "Custom"})
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Sent)
+		assert.Equal(t, "Custom", prompter.bodies[1])
+	})
+
+	t.Run("without a prompter nothing is sent", func(t *testing.T) {
+		_, err := NewPriceIncreaseConsentService(repo, zap.NewNop()).SendPrompts(ctx, appID, PriceIncreasePrompt{})
+		assert.ErrorIs(t, err, ErrPriceIncreasePromptsDisabled)
+	})
+}
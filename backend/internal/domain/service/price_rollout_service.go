@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+// priceRolloutAssignmentTTL keeps a rollout exposure attributable to later
+// purchases; quotes refresh it while the user keeps seeing the paywall
+const priceRolloutAssignmentTTL = 30 * 24 * time.Hour
+
+// Price rollout cohorts
+const (
+	PriceCohortRamp    = "ramp"
+	PriceCohortHoldout = "holdout"
+)
+
+// rolloutAssignmentStore records which rollout arm a user was exposed to
+type rolloutAssignmentStore interface {
+	GetActiveAssignment(ctx context.Context, experimentID, userID uuid.UUID) (*Assignment, error)
+	CreateAssignment(ctx context.Context, assignment *Assignment) error
+}
+
+// PriceQuote is the price of a pricing tier a user should see while a rollout
+// is running. Holdout quotes carry no prices: the tier's current prices apply.
+type PriceQuote struct {
+	RolloutID     uuid.UUID `json:"rollout_id"`
+	PricingTierID uuid.UUID `json:"pricing_tier_id"`
+	Cohort        string    `json:"cohort"`
+	Currency      string    `json:"currency,omitempty"`
+	MonthlyPrice  *float64  `json:"monthly_price,omitempty"`
+	AnnualPrice   *float64  `json:"annual_price,omitempty"`
+	LifetimePrice *float64  `json:"lifetime_price,omitempty"`
+	ExperimentID  uuid.UUID `json:"experiment_id"`
+	ArmID         uuid.UUID `json:"arm_id"`
+}
+
+// PriceRolloutCohort summarizes one side of the rollout comparison
+type PriceRolloutCohort struct {
+	ArmID          uuid.UUID `json:"arm_id"`
+	Users          int       `json:"users"`
+	Converters     int       `json:"converters"`
+	ConversionRate float64   `json:"conversion_rate"`
+	Revenue        float64   `json:"revenue"`
+	RevenuePerUser float64   `json:"revenue_per_user"`
+}
+
+// PriceRolloutResults compares the ramp cohort with the holdout
+type PriceRolloutResults struct {
+	RolloutID      uuid.UUID          `json:"rollout_id"`
+	Status         string             `json:"status"`
+	CurrentPercent int                `json:"current_percent"`
+	Holdout        PriceRolloutCohort `json:"holdout"`
+	Ramp           PriceRolloutCohort `json:"ramp"`
+	// ConversionLift and RevenuePerUserLift are relative to the holdout
+	// (0.1 = 10% better); nil while the holdout has no data
+	ConversionLift     *float64 `json:"conversion_lift,omitempty"`
+	RevenuePerUserLift *float64 `json:"revenue_per_user_lift,omitempty"`
+	// Confidence is the two-sided confidence that the conversion rates
+	// differ (two-proportion z-test)
+	Confidence float64 `json:"confidence"`
+}
+
+// PriceRolloutService quotes staged prices and compares ramp and holdout
+type PriceRolloutService struct {
+	repo        repository.PriceRolloutRepository
+	assignments rolloutAssignmentStore
+	logger      *zap.Logger
+	now         func() time.Time
+}
+
+// NewPriceRolloutService creates a new price rollout service
+func NewPriceRolloutService(repo repository.PriceRolloutRepository, assignments rolloutAssignmentStore, logger *zap.Logger) *PriceRolloutService {
+	return &PriceRolloutService{
+		repo:        repo,
+		assignments: assignments,
+		logger:      logger,
+		now:         time.Now,
+	}
+}
+
+// Quotes returns the rollout prices for a user in country and records the
+// user's cohort in each rollout's experiment. Rollouts that have not started
+// or target another region are skipped.
+func (s *PriceRolloutService) Quotes(ctx context.Context, appID, userID uuid.UUID, country string) ([]PriceQuote, error) {
+	rollouts, err := s.repo.ListOpen(ctx, appID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list price rollouts: %w", err)
+	}
+
+	now := s.now()
+	var quotes []PriceQuote
+	for _, rollout := range rollouts {
+		if !rollout.CoversRegion(country) || now.Before(rollout.StartsAt()) {
+			continue
+		}
+		quote := PriceQuote{
+			RolloutID:     rollout.ID,
+			PricingTierID: rollout.PricingTierID,
+			Cohort:        PriceCohortHoldout,
+			ExperimentID:  rollout.ExperimentID,
+			ArmID:         rollout.HoldoutArmID,
+		}
+		if rollout.InRamp(userID, now) {
+			quote.Cohort = PriceCohortRamp
+			quote.ArmID = rollout.RampArmID
+			quote.Currency = rollout.Currency
+			quote.MonthlyPrice = rollout.MonthlyPrice
+			quote.AnnualPrice = rollout.AnnualPrice
+			quote.LifetimePrice = rollout.LifetimePrice
+		}
+		if err := s.recordExposure(ctx, quote, userID, now); err != nil {
+			// The quote is still correct; only attribution is lost
+			s.logger.Warn("Failed to record price rollout exposure",
+				zap.String("rollout_id", rollout.ID.String()),
+				zap.String("user_id", userID.String()),
+				zap.Error(err),
+			)
+		}
+		quotes = append(quotes, quote)
+	}
+	return quotes, nil
+}
+
+// recordExposure assigns the user to the quote's arm unless an active
+// assignment to it exists. Users move from holdout to ramp as the ramp grows.
+func (s *PriceRolloutService) recordExposure(ctx context.Context, quote PriceQuote, userID uuid.UUID, now time.Time) error {
+	current, err := s.assignments.GetActiveAssignment(ctx, quote.ExperimentID, userID)
+	if err != nil && !errors.Is(err, ErrAssignmentNotFound) {
+		return err
+	}
+	if current != nil && current.ArmID == quote.ArmID && current.ExpiresAt.Sub(now) > priceRolloutAssignmentTTL/2 {
+		return nil
+	}
+	return s.assignments.CreateAssignment(ctx, &Assignment{
+		ID:           uuid.New(),
+		ExperimentID: quote.ExperimentID,
+		UserID:       userID,
+		ArmID:        quote.ArmID,
+		AssignedAt:   now,
+		ExpiresAt:    now.Add(priceRolloutAssignmentTTL),
+		Metadata: map[string]interface{}{
+			"price_rollout_id": quote.RolloutID.String(),
+			"cohort":           quote.Cohort,
+		},
+	})
+}
+
+// Results compares purchases of the ramp cohort with the holdout
+func (s *PriceRolloutService) Results(ctx context.Context, rollout *entity.PriceRollout) (*PriceRolloutResults, error) {
+	now := s.now()
+	stats, err := s.repo.CohortStats(ctx, rollout, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load rollout cohorts: %w", err)
+	}
+
+	results := &PriceRolloutResults{
+		RolloutID:      rollout.ID,
+		Status:         string(rollout.Status),
+		CurrentPercent: rollout.PercentAt(now),
+		Holdout:        PriceRolloutCohort{ArmID: rollout.HoldoutArmID},
+		Ramp:           PriceRolloutCohort{ArmID: rollout.RampArmID},
+	}
+	for _, st := range stats {
+		switch st.ArmID {
+		case rollout.HoldoutArmID:
+			results.Holdout = newPriceRolloutCohort(st)
+		case rollout.RampArmID:
+			results.Ramp = newPriceRolloutCohort(st)
+		}
+	}
+
+	h, r := results.Holdout, results.Ramp
+	if h.ConversionRate > 0 {
+		lift := r.ConversionRate/h.ConversionRate - 1
+		results.ConversionLift = &lift
+	}
+	if h.RevenuePerUser > 0 {
+		lift := r.RevenuePerUser/h.RevenuePerUser - 1
+		results.RevenuePerUserLift = &lift
+	}
+	results.Confidence = twoProportionConfidence(h.Converters, h.Users, r.Converters, r.Users)
+	return results, nil
+}
+
+func newPriceRolloutCohort(st repository.PriceRolloutCohortStats) PriceRolloutCohort {
+	cohort := PriceRolloutCohort{
+		ArmID:      st.ArmID,
+		Users:      st.Users,
+		Converters: st.Converters,
+		Revenue:    st.Revenue,
+	}
+	if st.Users > 0 {
+		cohort.ConversionRate = float64(st.Converters) / float64(st.Users)
+		cohort.RevenuePerUser = st.Revenue / float64(st.Users)
+	}
+	return cohort
+}
+
+// twoProportionConfidence is 1 - p of a two-sided z-test on two conversion rates
+func twoProportionConfidence(conv1, n1, conv2, n2 int) float64 {
+	if n1 == 0 || n2 == 0 {
+		return 0
+	}
+	p1 := float64(conv1) / float64(n1)
+	p2 := float64(conv2) / float64(n2)
+	pooled := float64(conv1+conv2) / float64(n1+n2)
+	se := math.Sqrt(pooled * (1 - pooled) * (1/float64(n1) + 1/float64(n2)))
+	if se == 0 {
+		return 0
+	}
+	z := math.Abs(p1-p2) / se
+	return math.Erf(z / math.Sqrt2)
+}
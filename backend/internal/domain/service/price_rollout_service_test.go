@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+type stubPriceRolloutRepo struct {
+	repository.PriceRolloutRepository
+	open  []*entity.PriceRollout
+	stats []repository.PriceRolloutCohortStats
+}
+
+func (s *stubPriceRolloutRepo) ListOpen(ctx context.Context, appID uuid.UUID) ([]*entity.PriceRollout, error) {
+	return s.open, nil
+}
+
+func (s *stubPriceRolloutRepo) CohortStats(ctx context.Context, rollout *entity.PriceRollout, until time.Time) ([]repository.PriceRolloutCohortStats, error) {
+	return s.stats, nil
+}
+
+type stubRolloutAssignments struct {
+	active  map[uuid.UUID]*Assignment
+	created []*Assignment
+}
+
+func (s *stubRolloutAssignments) GetActiveAssignment(ctx context.Context, experimentID, userID uuid.UUID) (*Assignment, error) {
+	if a, ok := s.active[userID]; ok {
+		return a, nil
+	}
+	return nil, ErrAssignmentNotFound
+}
+
+func (s *stubRolloutAssignments) CreateAssignment(ctx context.Context, assignment *Assignment) error {
+	s.created = append(s.created, assignment)
+	return nil
+}
+
+func TestPriceRolloutService_QuotesRecordCohort(t *testing.T) {
+	now := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	rollout := entity.NewPriceRollout(uuid.New(), uuid.New(), "DE", "EUR", []entity.PriceRolloutStage{
+		{At: now.Add(-time.Hour), Percent: 50},
+	})
+	price := 12.99
+	rollout.MonthlyPrice = &price
+	notStarted := entity.NewPriceRollout(rollout.AppID, uuid.New(), "", "EUR", []entity.PriceRolloutStage{
+		{At: now.Add(time.Hour), Percent: 100},
+	})
+
+	var rampUser, holdoutUser uuid.UUID
+	for rampUser == uuid.Nil || holdoutUser == uuid.Nil {
+		id := uuid.New()
+		if rollout.InRamp(id, now) {
+			rampUser = id
+		} else {
+			holdoutUser = id
+		}
+	}
+
+	assignments := &stubRolloutAssignments{active: map[uuid.UUID]*Assignment{}}
+	svc := NewPriceRolloutService(&stubPriceRolloutRepo{open: []*entity.PriceRollout{rollout, notStarted}}, assignments, zap.NewNop())
+	svc.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	quotes, err := svc.Quotes(ctx, rollout.AppID, rampUser, "de")
+	require.NoError(t, err)
+	require.Len(t, quotes, 1)
+	assert.Equal(t, PriceCohortRamp, quotes[0].Cohort)
+	assert.Equal(t, &price, quotes[0].MonthlyPrice)
+	assert.Equal(t, rollout.RampArmID, quotes[0].ArmID)
+
+	quotes, err = svc.Quotes(ctx, rollout.AppID, holdoutUser, "DE")
+	require.NoError(t, err)
+	require.Len(t, quotes, 1)
+	assert.Equal(t, PriceCohortHoldout, quotes[0].Cohort)
+	assert.Nil(t, quotes[0].MonthlyPrice)
+	require.Len(t, assignments.created, 2)
+	assert.Equal(t, rollout.HoldoutArmID, assignments.created[1].ArmID)
+
+	// A fresh assignment to the same arm is not rewritten on every quote
+	assignments.active[holdoutUser] = assignments.created[1]
+	_, err = svc.Quotes(ctx, rollout.AppID, holdoutUser, "DE")
+	require.NoError(t, err)
+	assert.Len(t, assignments.created, 2)
+
+	quotes, err = svc.Quotes(ctx, rollout.AppID, rampUser, "FR")
+	require.NoError(t, err)
+	assert.Empty(t, quotes, "other regions keep the current price")
+}
+
+func TestPriceRolloutService_ResultsComparesWithHoldout(t *testing.T) {
+	rollout := entity.NewPriceRollout(uuid.New(), uuid.New(), "", "USD", []entity.PriceRolloutStage{
+		{At: time.Now().Add(-time.Hour), Percent: 20},
+	})
+	repo := &stubPriceRolloutRepo{stats: []repository.PriceRolloutCohortStats{
+		{ArmID: rollout.HoldoutArmID, Users: 1000, Converters: 100, Revenue: 1000},
+		{ArmID: rollout.RampArmID, Users: 1000, Converters: 80, Revenue: 1200},
+	}}
+	svc := NewPriceRolloutService(repo, &stubRolloutAssignments{}, zap.NewNop())
+
+	results, err := svc.Results(context.Background(), rollout)
+
+	require.NoError(t, err)
+	assert.Equal(t, 20, results.CurrentPercent)
+	assert.InDelta(t, 0.1, results.Holdout.ConversionRate, 1e-9)
+	assert.InDelta(t, -0.2, *results.ConversionLift, 1e-9)
+	assert.InDelta(t, 0.2, *results.RevenuePerUserLift, 1e-9)
+	assert.Greater(t, results.Confidence, 0.8)
+	assert.Less(t, results.Confidence, 0.95)
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+const (
+	// reactivationPageSize is how many candidates a detection run attributes per batch
+	reactivationPageSize = 500
+	// ReactivationLookback is how far back each detection run looks for new
+	// subscriptions; runs overlap so late-committed rows are not missed
+	ReactivationLookback = 48 * time.Hour
+)
+
+// ExperimentReactivationArm is one arm's reactivations. ReactivationRate is
+// per assigned user; LiftPercent compares it with control.
+type ExperimentReactivationArm struct {
+	ArmID            uuid.UUID `json:"arm_id"`
+	ArmName          string    `json:"arm_name"`
+	IsControl        bool      `json:"is_control"`
+	Users            int       `json:"users"`
+	Reactivations    int       `json:"reactivations"`
+	ReactivationRate float64   `json:"reactivation_rate"`
+	ResurrectedMRR   float64   `json:"resurrected_mrr"`
+	LiftPercent      *float64  `json:"lift_percent,omitempty"`
+}
+
+// ExperimentReactivationReport is a win-back experiment's results: the
+// churned users each arm brought back and the MRR they resubscribed with
+type ExperimentReactivationReport struct {
+	ExperimentID   uuid.UUID                   `json:"experiment_id"`
+	Reactivations  int                         `json:"reactivations"`
+	ResurrectedMRR float64                     `json:"resurrected_mrr"`
+	Arms           []ExperimentReactivationArm `json:"arms"`
+}
+
+// ReactivationService detects churned subscribers who resubscribed and
+// credits each reactivation to the campaign or offer that drove it
+type ReactivationService struct {
+	repo   repository.ReactivationRepository
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+// NewReactivationService creates a new reactivation service
+func NewReactivationService(repo repository.ReactivationRepository, logger *zap.Logger) *ReactivationService {
+	return &ReactivationService{repo: repo, logger: logger, now: time.Now}
+}
+
+// DetectReactivations records every subscription started within
+// ReactivationLookback that follows a lapse. Returns how many were recorded.
+func (s *ReactivationService) DetectReactivations(ctx context.Context) (int, error) {
+	since := s.now().Add(-ReactivationLookback)
+	recorded := 0
+	for {
+		candidates, err := s.repo.ListCandidates(ctx, since, reactivationPageSize)
+		if err != nil {
+			return recorded, err
+		}
+		if len(candidates) == 0 {
+			break
+		}
+		reactivations := make([]entity.SubscriptionReactivation, len(candidates))
+		for i, c := range candidates {
+			reactivations[i] = entity.AttributeReactivation(c)
+		}
+		if err := s.repo.SaveReactivations(ctx, reactivations); err != nil {
+			return recorded, err
+		}
+		recorded += len(candidates)
+		if len(candidates) < reactivationPageSize {
+			break
+		}
+	}
+	if recorded > 0 {
+		s.logger.Info("Subscription reactivations recorded", zap.Int("reactivations", recorded))
+	}
+	return recorded, nil
+}
+
+// ExperimentReport returns the reactivations credited to each arm of the
+// app's experiment. It returns ErrExperimentNotFound for an unknown experiment.
+func (s *ReactivationService) ExperimentReport(ctx context.Context, appID, experimentID uuid.UUID) (*ExperimentReactivationReport, error) {
+	stats, err := s.repo.ListArmStats(ctx, appID, experimentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reactivation arm stats: %w", err)
+	}
+	if stats == nil {
+		return nil, ErrExperimentNotFound
+	}
+
+	report := &ExperimentReactivationReport{ExperimentID: experimentID, Arms: make([]ExperimentReactivationArm, len(stats))}
+	var control *ExperimentReactivationArm
+	for i, st := range stats {
+		arm := ExperimentReactivationArm{
+			ArmID:            st.ArmID,
+			ArmName:          st.ArmName,
+			IsControl:        st.IsControl,
+			Users:            st.Users,
+			Reactivations:    st.Reactivations,
+			ReactivationRate: perSample(float64(st.Reactivations), st.Users),
+			ResurrectedMRR:   roundCents(st.MRR),
+		}
+		report.Arms[i] = arm
+		report.Reactivations += st.Reactivations
+		report.ResurrectedMRR += st.MRR
+		if arm.IsControl && control == nil {
+			control = &report.Arms[i]
+		}
+	}
+	report.ResurrectedMRR = roundCents(report.ResurrectedMRR)
+
+	if control == nil || control.ReactivationRate == 0 {
+		return report, nil
+	}
+	for i := range report.Arms {
+		arm := &report.Arms[i]
+		if arm.IsControl || arm.Users == 0 {
+			continue
+		}
+		lift := (arm.ReactivationRate - control.ReactivationRate) / control.ReactivationRate * 100
+		arm.LiftPercent = &lift
+	}
+	return report, nil
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+type reactivationTestRepo struct {
+	candidates []entity.ReactivationCandidate
+	saved      []entity.SubscriptionReactivation
+	since      time.Time
+	arms       []repository.ReactivationArmStats
+}
+
+// ListCandidates hands out pages and drops them once saved, like the real query
+func (r *reactivationTestRepo) ListCandidates(_ context.Context, since time.Time, limit int) ([]entity.ReactivationCandidate, error) {
+	r.since = since
+	page := r.candidates[len(r.saved):]
+	if len(page) > limit {
+		page = page[:limit]
+	}
+	return page, nil
+}
+
+func (r *reactivationTestRepo) SaveReactivations(_ context.Context, reactivations []entity.SubscriptionReactivation) error {
+	r.saved = append(r.saved, reactivations...)
+	return nil
+}
+
+func (r *reactivationTestRepo) ListArmStats(context.Context, uuid.UUID, uuid.UUID) ([]repository.ReactivationArmStats, error) {
+	return r.arms, nil
+}
+
+func TestReactivationService_DetectReactivationsPages(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	repo := &reactivationTestRepo{}
+	offerAt := now.Add(-time.Hour)
+	for i := 0; i < reactivationPageSize+3; i++ {
+		repo.candidates = append(repo.candidates, entity.ReactivationCandidate{
+			SubscriptionID: uuid.New(),
+			PlanType:       entity.PlanMonthly,
+			LapsedAt:       now.Add(-40 * 24 * time.Hour),
+			ReactivatedAt:  now,
+			WinbackOffer:   &entity.ReactivationTouch{ID: uuid.New(), Key: "june", At: offerAt},
+		})
+	}
+	svc := NewReactivationService(repo, zap.NewNop())
+	svc.now = func() time.Time { return now }
+
+	recorded, err := svc.DetectReactivations(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, reactivationPageSize+3, recorded)
+	assert.Equal(t, now.Add(-ReactivationLookback), repo.since)
+	require.Len(t, repo.saved, reactivationPageSize+3)
+	assert.Equal(t, entity.ReactivationSourceWinback, repo.saved[0].Source)
+	assert.Equal(t, "june", repo.saved[0].CampaignID)
+}
+
+func TestReactivationService_ExperimentReport(t *testing.T) {
+	control, variant := uuid.New(), uuid.New()
+	repo := &reactivationTestRepo{arms: []repository.ReactivationArmStats{
+		{ArmID: control, ArmName: "No offer", IsControl: true, Users: 200, Reactivations: 10, MRR: 99.9},
+		{ArmID: variant, ArmName: "50% off", Users: 200, Reactivations: 15, MRR: 149.85},
+	}}
+	svc := NewReactivationService(repo, zap.NewNop())
+
+	report, err := svc.ExperimentReport(context.Background(), uuid.New(), uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, 25, report.Reactivations)
+	assert.InDelta(t, 249.75, report.ResurrectedMRR, 1e-9)
+	require.Len(t, report.Arms, 2)
+	assert.Nil(t, report.Arms[0].LiftPercent)
+	require.NotNil(t, report.Arms[1].LiftPercent)
+	assert.InDelta(t, 50.0, *report.Arms[1].LiftPercent, 1e-9)
+
+	repo.arms = nil
+	_, err = svc.ExperimentReport(context.Background(), uuid.New(), uuid.New())
+	assert.ErrorIs(t, err, ErrExperimentNotFound)
+}
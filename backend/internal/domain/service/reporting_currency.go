@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultReportingCurrency is used when no reporting currency is configured
+const DefaultReportingCurrency = "USD"
+
+// CurrencyRateHistory stores and looks up daily USD rates
+type CurrencyRateHistory interface {
+	// SaveRates records the USD value of one unit of each currency on day
+	SaveRates(ctx context.Context, day time.Time, ratesToUSD map[string]float64, source string) error
+	// GetRateOnOrBefore returns the most recent rate recorded on or before day,
+	// or ErrCurrencyRateNotFound when the currency has no history that old
+	GetRateOnOrBefore(ctx context.Context, currency string, day time.Time) (float64, error)
+}
+
+// liveRateSource is the current-rate fallback for days without history
+type liveRateSource interface {
+	GetRate(ctx context.Context, currency string) (float64, error)
+}
+
+// CurrencyAmount is a summed amount in one original currency. Day selects the
+// historical rate used to convert it; Count is the number of rows summed.
+type CurrencyAmount struct {
+	Currency string
+	Day      time.Time
+	Amount   float64
+	Count    int
+}
+
+// CurrencyBreakout is the per-original-currency part of a converted total
+type CurrencyBreakout struct {
+	Currency        string  `json:"currency"`
+	Transactions    int     `json:"transactions"`
+	Amount          float64 `json:"amount"`
+	ConvertedAmount float64 `json:"converted_amount"`
+	Converted       bool    `json:"converted"`
+}
+
+// ConvertedTotal is an aggregate expressed in a single reporting currency.
+// Amounts in currencies without any known rate are excluded from Amount and
+// listed in Unconverted so the total is never silently mixed.
+type ConvertedTotal struct {
+	Amount      float64            `json:"amount"`
+	Currency    string             `json:"currency"`
+	ByCurrency  []CurrencyBreakout `json:"by_currency"`
+	Unconverted []string           `json:"unconverted_currencies,omitempty"`
+}
+
+// ReportingCurrencyConverter expresses multi-currency aggregates in one
+// reporting currency using the rate of the day each amount was charged.
+type ReportingCurrencyConverter struct {
+	currency string
+	history  CurrencyRateHistory
+	live     liveRateSource
+}
+
+// NewReportingCurrencyConverter creates a converter. history and live may be
+// nil; without either only same-currency and USD amounts can be converted.
+func NewReportingCurrencyConverter(currency string, history CurrencyRateHistory, live liveRateSource) *ReportingCurrencyConverter {
+	code, ok := NormalizeCurrencyCode(currency)
+	if !ok {
+		code = DefaultReportingCurrency
+	}
+	return &ReportingCurrencyConverter{currency: code, history: history, live: live}
+}
+
+// NormalizeCurrencyCode upper-cases and validates an ISO-4217 code
+func NormalizeCurrencyCode(raw string) (string, bool) {
+	code := strings.ToUpper(strings.TrimSpace(raw))
+	if len(code) != 3 {
+		return "", false
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return "", false
+		}
+	}
+	return code, true
+}
+
+// Currency returns the configured reporting currency
+func (c *ReportingCurrencyConverter) Currency() string {
+	return c.currency
+}
+
+// ResolveCurrency returns the requested override, or the configured reporting
+// currency when requested is empty
+func (c *ReportingCurrencyConverter) ResolveCurrency(requested string) (string, error) {
+	if strings.TrimSpace(requested) == "" {
+		return c.currency, nil
+	}
+	code, ok := NormalizeCurrencyCode(requested)
+	if !ok {
+		return "", fmt.Errorf("invalid currency code %q", requested)
+	}
+	return code, nil
+}
+
+// Convert converts a single amount charged on day into target
+func (c *ReportingCurrencyConverter) Convert(ctx context.Context, amount float64, from string, day time.Time, target string) (float64, error) {
+	return c.convert(ctx, amount, from, day, target, map[string]float64{})
+}
+
+// Aggregate converts and sums amounts into target, with a per-currency breakout
+func (c *ReportingCurrencyConverter) Aggregate(ctx context.Context, target string, amounts []CurrencyAmount) (*ConvertedTotal, error) {
+	total := &ConvertedTotal{Currency: target, ByCurrency: []CurrencyBreakout{}}
+	memo := map[string]float64{}
+	idx := map[string]int{}
+	unconverted := map[string]bool{}
+
+	for _, a := range amounts {
+		currency := strings.ToUpper(a.Currency)
+		i, ok := idx[currency]
+		if !ok {
+			i = len(total.ByCurrency)
+			idx[currency] = i
+			total.ByCurrency = append(total.ByCurrency, CurrencyBreakout{Currency: currency, Converted: true})
+		}
+		breakout := &total.ByCurrency[i]
+		breakout.Transactions += a.Count
+		breakout.Amount = roundCents(breakout.Amount + a.Amount)
+
+		converted, err := c.convert(ctx, a.Amount, currency, a.Day, target, memo)
+		if errors.Is(err, ErrCurrencyRateNotFound) {
+			breakout.Converted = false
+			unconverted[currency] = true
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		breakout.ConvertedAmount = roundCents(breakout.ConvertedAmount + converted)
+	}
+
+	for i := range total.ByCurrency {
+		if !total.ByCurrency[i].Converted {
+			total.ByCurrency[i].ConvertedAmount = 0
+			continue
+		}
+		total.Amount += total.ByCurrency[i].ConvertedAmount
+	}
+	total.Amount = roundCents(total.Amount)
+	for currency := range unconverted {
+		total.Unconverted = append(total.Unconverted, currency)
+	}
+	sort.Strings(total.Unconverted)
+	sort.Slice(total.ByCurrency, func(i, j int) bool {
+		return total.ByCurrency[i].Currency < total.ByCurrency[j].Currency
+	})
+
+	return total, nil
+}
+
+func (c *ReportingCurrencyConverter) convert(ctx context.Context, amount float64, from string, day time.Time, target string, memo map[string]float64) (float64, error) {
+	from = strings.ToUpper(from)
+	if from == "" {
+		from = DefaultReportingCurrency
+	}
+	if from == target || amount == 0 {
+		return amount, nil
+	}
+
+	fromRate, err := c.rateToUSD(ctx, from, day, memo)
+	if err != nil {
+		return 0, err
+	}
+	targetRate, err := c.rateToUSD(ctx, target, day, memo)
+	if err != nil {
+		return 0, err
+	}
+	return amount * fromRate / targetRate, nil
+}
+
+// rateToUSD prefers the historical rate for day and falls back to the live
+// rate when the currency has no history yet
+func (c *ReportingCurrencyConverter) rateToUSD(ctx context.Context, currency string, day time.Time, memo map[string]float64) (float64, error) {
+	if currency == "USD" {
+		return 1, nil
+	}
+	key := currency + ":" + day.UTC().Format("2006-01-02")
+	if rate, ok := memo[key]; ok {
+		if rate == 0 {
+			return 0, ErrCurrencyRateNotFound
+		}
+		return rate, nil
+	}
+
+	rate, err := c.lookupRate(ctx, currency, day)
+	if errors.Is(err, ErrCurrencyRateNotFound) {
+		memo[key] = 0
+		return 0, err
+	}
+	if err != nil {
+		return 0, err
+	}
+	memo[key] = rate
+	return rate, nil
+}
+
+func (c *ReportingCurrencyConverter) lookupRate(ctx context.Context, currency string, day time.Time) (float64, error) {
+	if c.history != nil {
+		rate, err := c.history.GetRateOnOrBefore(ctx, currency, day)
+		if err == nil && rate > 0 {
+			return rate, nil
+		}
+		if err != nil && !errors.Is(err, ErrCurrencyRateNotFound) {
+			return 0, fmt.Errorf("lookup %s rate: %w", currency, err)
+		}
+	}
+	if c.live != nil {
+		if rate, err := c.live.GetRate(ctx, currency); err == nil && rate > 0 {
+			return rate, nil
+		}
+	}
+	return 0, ErrCurrencyRateNotFound
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeRateHistory struct {
+	rates   map[string]float64 // "EUR:2026-01-15" -> rate_to_usd
+	lookups int
+	err     error
+}
+
+func (f *fakeRateHistory) SaveRates(ctx context.Context, day time.Time, ratesToUSD map[string]float64, source string) error {
+	return nil
+}
+
+func (f *fakeRateHistory) GetRateOnOrBefore(ctx context.Context, currency string, day time.Time) (float64, error) {
+	f.lookups++
+	if f.err != nil {
+		return 0, f.err
+	}
+	rate, ok := f.rates[currency+":"+day.Format("2006-01-02")]
+	if !ok {
+		return 0, ErrCurrencyRateNotFound
+	}
+	return rate, nil
+}
+
+type fakeLiveRates map[string]float64
+
+func (f fakeLiveRates) GetRate(ctx context.Context, currency string) (float64, error) {
+	if rate, ok := f[currency]; ok {
+		return rate, nil
+	}
+	return 0, ErrCurrencyRateNotFound
+}
+
+func TestReportingCurrencyConverter_AggregateUsesRateOfEachDay(t *testing.T) {
+	jan := time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2026, 2, 15, 0, 0, 0, 0, time.UTC)
+	history := &fakeRateHistory{rates: map[string]float64{
+		"EUR:2026-01-15": 1.10,
+		"EUR:2026-02-15": 1.20,
+	}}
+	converter := NewReportingCurrencyConverter("usd", history, nil)
+
+	total, err := converter.Aggregate(context.Background(), converter.Currency(), []CurrencyAmount{
+		{Currency: "EUR", Day: jan, Amount: 10, Count: 1},
+		{Currency: "EUR", Day: feb, Amount: 10, Count: 1},
+		{Currency: "USD", Day: feb, Amount: 5, Count: 2},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "USD", total.Currency)
+	assert.Equal(t, 28.0, total.Amount)
+	require.Len(t, total.ByCurrency, 2)
+	assert.Equal(t, CurrencyBreakout{Currency: "EUR", Transactions: 2, Amount: 20, ConvertedAmount: 23, Converted: true}, total.ByCurrency[0])
+	assert.Equal(t, CurrencyBreakout{Currency: "USD", Transactions: 2, Amount: 5, ConvertedAmount: 5, Converted: true}, total.ByCurrency[1])
+	assert.Empty(t, total.Unconverted)
+}
+
+func TestReportingCurrencyConverter_ConvertsIntoNonUSDTarget(t *testing.T) {
+	day := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	history := &fakeRateHistory{rates: map[string]float64{"EUR:2026-03-01": 1.25, "GBP:2026-03-01": 1.50}}
+	converter := NewReportingCurrencyConverter("EUR", history, nil)
+
+	amount, err := converter.Convert(context.Background(), 10, "GBP", day, "EUR")
+
+	require.NoError(t, err)
+	assert.InDelta(t, 12.0, amount, 0.0001)
+}
+
+func TestReportingCurrencyConverter_ExcludesCurrenciesWithoutRate(t *testing.T) {
+	day := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	history := &fakeRateHistory{rates: map[string]float64{}}
+	converter := NewReportingCurrencyConverter("USD", history, fakeLiveRates{"GBP": 1.30})
+
+	total, err := converter.Aggregate(context.Background(), "USD", []CurrencyAmount{
+		{Currency: "GBP", Day: day, Amount: 10, Count: 1},
+		{Currency: "XYZ", Day: day, Amount: 99, Count: 3},
+		{Currency: "XYZ", Day: day, Amount: 1, Count: 1},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 13.0, total.Amount, "GBP falls back to the live rate")
+	assert.Equal(t, []string{"XYZ"}, total.Unconverted)
+	assert.False(t, total.ByCurrency[1].Converted)
+	assert.Equal(t, 100.0, total.ByCurrency[1].Amount)
+	assert.Equal(t, 0.0, total.ByCurrency[1].ConvertedAmount)
+	assert.Equal(t, 2, history.lookups, "lookups are memoized per currency and day")
+}
+
+func TestReportingCurrencyConverter_PropagatesHistoryErrors(t *testing.T) {
+	converter := NewReportingCurrencyConverter("USD", &fakeRateHistory{err: errors.New("connection refused")}, nil)
+
+	_, err := converter.Aggregate(context.Background(), "USD", []CurrencyAmount{{Currency: "EUR", Day: time.Now(), Amount: 1}})
+
+	require.Error(t, err)
+}
+
+func TestReportingCurrencyConverter_ResolveCurrency(t *testing.T) {
+	converter := NewReportingCurrencyConverter("not-a-code", nil, nil)
+	assert.Equal(t, DefaultReportingCurrency, converter.Currency())
+
+	currency, err := converter.ResolveCurrency(" eur ")
+	require.NoError(t, err)
+	assert.Equal(t, "EUR", currency)
+
+	currency, err = converter.ResolveCurrency("")
+	require.NoError(t, err)
+	assert.Equal(t, "USD", currency)
+
+	_, err = converter.ResolveCurrency("E1R")
+	require.Error(t, err)
+}
@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultRetentionBatchSize  = 5000
+	defaultRetentionMaxBatches = 100
+)
+
+// RetentionPolicy deletes rows of Table whose TimeColumn is older than
+// Retention. Condition, when set, further limits which rows may go. A zero
+// Retention disables the policy.
+type RetentionPolicy struct {
+	Name       string
+	Table      string
+	TimeColumn string
+	Condition  string
+	Retention  time.Duration
+}
+
+// DefaultRetentionPolicies are the tables the retention job cleans up
+var DefaultRetentionPolicies = []RetentionPolicy{
+	{Name: "expired_assignments", Table: "ab_test_assignments", TimeColumn: "expires_at", Retention: 90 * 24 * time.Hour},
+	{Name: "bandit_user_context", Table: "bandit_user_context", TimeColumn: "updated_at", Retention: 90 * 24 * time.Hour},
+	{Name: "matomo_sent_events", Table: "matomo_staged_events", TimeColumn: "sent_at", Condition: "status = 'sent'", Retention: 7 * 24 * time.Hour},
+	{Name: "processed_webhook_events", Table: "webhook_events", TimeColumn: "created_at", Condition: "status = 'processed'", Retention: 90 * 24 * time.Hour},
+	{Name: "bandit_assignment_events", Table: "bandit_assignment_events", TimeColumn: "occurred_at", Retention: 180 * 24 * time.Hour},
+	{Name: "bandit_impression_events", Table: "bandit_impression_events", TimeColumn: "occurred_at", Retention: 180 * 24 * time.Hour},
+}
+
+// ParseRetentionPolicies applies comma-separated name=duration overrides,
+// e.g. "matomo_sent_events=72h,bandit_impression_events=off", to the default
+// policies. "off" or "0" disables a policy.
+func ParseRetentionPolicies(overrides string) ([]RetentionPolicy, error) {
+	policies := append([]RetentionPolicy(nil), DefaultRetentionPolicies...)
+	for _, entry := range strings.Split(overrides, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid retention override %q: expected name=duration", entry)
+		}
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		var retention time.Duration
+		if value != "off" && value != "0" {
+			d, err := time.ParseDuration(value)
+			if err != nil || d < time.Hour {
+				return nil, fmt.Errorf("invalid retention for %s: %q must be a duration of at least 1h, 0 or off", name, value)
+			}
+			retention = d
+		}
+		found := false
+		for i := range policies {
+			if policies[i].Name == name {
+				policies[i].Retention = retention
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown retention policy %q", name)
+		}
+	}
+	return policies, nil
+}
+
+// RetentionResult is the outcome of one policy's cleanup
+type RetentionResult struct {
+	Policy  string
+	Table   string
+	Deleted int64
+	Batches int
+	// Truncated runs stopped at the batch limit with rows still eligible
+	Truncated bool
+	Duration  time.Duration
+	Err       error
+}
+
+// RetentionState is a policy and the last result of its cleanup
+type RetentionState struct {
+	Policy           string     `json:"policy"`
+	Table            string     `json:"table"`
+	RetentionSeconds int64      `json:"retention_seconds"`
+	Enabled          bool       `json:"enabled"`
+	Status           string     `json:"status"`
+	Cutoff           *time.Time `json:"cutoff"`
+	LastDeleted      int64      `json:"last_deleted"`
+	LastBatches      int        `json:"last_batches"`
+	Truncated        bool       `json:"truncated"`
+	TotalDeleted     int64      `json:"total_deleted"`
+	LastDurationMs   int64      `json:"last_duration_ms"`
+	LastError        *string    `json:"last_error"`
+	LastRunAt        *time.Time `json:"last_run_at"`
+	LastSuccessAt    *time.Time `json:"last_success_at"`
+}
+
+// RetentionService deletes rows past their retention. Each batch is its own
+// statement so locks stay short, and a run stops after maxBatches per policy
+// so a backlog is worked off over several nights instead of in one long run.
+type RetentionService struct {
+	pool       *pgxpool.Pool
+	policies   []RetentionPolicy
+	logger     *zap.Logger
+	batchSize  int
+	maxBatches int
+	now        func() time.Time
+}
+
+// NewRetentionService creates a new retention service
+func NewRetentionService(pool *pgxpool.Pool, policies []RetentionPolicy, logger *zap.Logger) *RetentionService {
+	return &RetentionService{
+		pool:       pool,
+		policies:   policies,
+		logger:     logger,
+		batchSize:  defaultRetentionBatchSize,
+		maxBatches: defaultRetentionMaxBatches,
+		now:        time.Now,
+	}
+}
+
+// WithBatchLimits sets how many rows each delete removes and how many
+// deletes a policy gets per run
+func (s *RetentionService) WithBatchLimits(batchSize, maxBatches int) *RetentionService {
+	if batchSize > 0 {
+		s.batchSize = batchSize
+	}
+	if maxBatches > 0 {
+		s.maxBatches = maxBatches
+	}
+	return s
+}
+
+// Run cleans up every enabled policy. A failing policy does not stop the
+// others; its error is recorded in its state and in its result.
+func (s *RetentionService) Run(ctx context.Context) ([]RetentionResult, error) {
+	var results []RetentionResult
+	var errs []error
+	for _, p := range s.policies {
+		if p.Retention <= 0 {
+			continue
+		}
+		cutoff := s.now().Add(-p.Retention)
+		start := time.Now()
+		result := RetentionResult{Policy: p.Name, Table: p.Table}
+		result.Deleted, result.Batches, result.Truncated, result.Err = runRetentionBatches(ctx, s.batchSize, s.maxBatches, func(limit int) (int64, error) {
+			tag, err := s.pool.Exec(ctx, retentionDeleteQuery(p), cutoff, limit)
+			return tag.RowsAffected(), err
+		})
+		result.Duration = time.Since(start)
+		if err := s.record(ctx, p, cutoff, result); err != nil && result.Err == nil {
+			result.Err = err
+		}
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", p.Name, result.Err))
+		} else if result.Deleted > 0 {
+			s.logger.Info("Retention cleanup deleted rows", zap.String("policy", p.Name), zap.Int64("rows", result.Deleted), zap.Bool("truncated", result.Truncated))
+		}
+		results = append(results, result)
+	}
+	return results, errors.Join(errs...)
+}
+
+// runRetentionBatches calls deleteBatch until a batch comes back short or
+// maxBatches ran. truncated reports that rows may still be eligible.
+func runRetentionBatches(ctx context.Context, batchSize, maxBatches int, deleteBatch func(limit int) (int64, error)) (deleted int64, batches int, truncated bool, err error) {
+	for batches < maxBatches {
+		if err := ctx.Err(); err != nil {
+			return deleted, batches, true, err
+		}
+		n, err := deleteBatch(batchSize)
+		if err != nil {
+			return deleted, batches, false, fmt.Errorf("failed to delete batch: %w", err)
+		}
+		batches++
+		deleted += n
+		if n < int64(batchSize) {
+			return deleted, batches, false, nil
+		}
+	}
+	return deleted, batches, true, nil
+}
+
+// retentionDeleteQuery deletes up to $2 rows older than $1. Rows are picked
+// by ctid, so the policy's table needs no particular key.
+func retentionDeleteQuery(p RetentionPolicy) string {
+	where := fmt.Sprintf("%q < $1", p.TimeColumn)
+	if p.Condition != "" {
+		where += " AND " + p.Condition
+	}
+	return fmt.Sprintf(`DELETE FROM %[1]q WHERE ctid = ANY(ARRAY(SELECT ctid FROM %[1]q WHERE %[2]s LIMIT $2))`, p.Table, where)
+}
+
+func (s *RetentionService) record(ctx context.Context, p RetentionPolicy, cutoff time.Time, r RetentionResult) error {
+	status, lastError := "idle", (*string)(nil)
+	if r.Err != nil {
+		msg := r.Err.Error()
+		status, lastError = "failed", &msg
+	}
+	if _, err := s.pool.Exec(ctx, `
+		INSERT INTO retention_state (policy, table_name, status, cutoff, last_deleted, last_batches, truncated,
+		    total_deleted, last_duration_ms, last_error, last_run_at, last_success_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $5, $8, $9, now(), CASE WHEN $9::text IS NULL THEN now() END)
+		ON CONFLICT (policy) DO UPDATE SET
+		    table_name = EXCLUDED.table_name,
+		    status = EXCLUDED.status,
+		    cutoff = EXCLUDED.cutoff,
+		    last_deleted = EXCLUDED.last_deleted,
+		    last_batches = EXCLUDED.last_batches,
+		    truncated = EXCLUDED.truncated,
+		    total_deleted = retention_state.total_deleted + EXCLUDED.last_deleted,
+		    last_duration_ms = EXCLUDED.last_duration_ms,
+		    last_error = EXCLUDED.last_error,
+		    last_run_at = EXCLUDED.last_run_at,
+		    last_success_at = COALESCE(EXCLUDED.last_success_at, retention_state.last_success_at)
+	`, p.Name, p.Table, status, cutoff, r.Deleted, r.Batches, r.Truncated, r.Duration.Milliseconds(), lastError); err != nil {
+		return fmt.Errorf("failed to record retention result: %w", err)
+	}
+	return nil
+}
+
+// Status returns every policy with the last result of its cleanup; policies
+// that never ran are pending and disabled ones are reported as such
+func (s *RetentionService) Status(ctx context.Context) ([]RetentionState, error) {
+	states := make([]RetentionState, 0, len(s.policies))
+	for _, p := range s.policies {
+		state := RetentionState{
+			Policy:           p.Name,
+			Table:            p.Table,
+			RetentionSeconds: int64(p.Retention / time.Second),
+			Enabled:          p.Retention > 0,
+		}
+		err := s.pool.QueryRow(ctx, `
+			SELECT status, cutoff, last_deleted, last_batches, truncated, total_deleted,
+			       last_duration_ms, last_error, last_run_at, last_success_at
+			FROM retention_state WHERE policy = $1
+		`, p.Name).Scan(&state.Status, &state.Cutoff, &state.LastDeleted, &state.LastBatches, &state.Truncated,
+			&state.TotalDeleted, &state.LastDurationMs, &state.LastError, &state.LastRunAt, &state.LastSuccessAt)
+		if errors.Is(err, pgx.ErrNoRows) {
+			state.Status = "pending"
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read retention state of %s: %w", p.Name, err)
+		}
+		if !state.Enabled {
+			state.Status = "disabled"
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
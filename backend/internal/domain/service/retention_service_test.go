@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRetentionPolicies_AppliesOverrides(t *testing.T) {
+	policies, err := ParseRetentionPolicies("matomo_sent_events=72h, bandit_impression_events=off")
+	require.NoError(t, err)
+	require.Len(t, policies, len(DefaultRetentionPolicies))
+
+	byName := map[string]RetentionPolicy{}
+	for _, p := range policies {
+		byName[p.Name] = p
+	}
+	assert.Equal(t, 72*time.Hour, byName["matomo_sent_events"].Retention)
+	assert.Zero(t, byName["bandit_impression_events"].Retention)
+	assert.Equal(t, 90*24*time.Hour, byName["expired_assignments"].Retention)
+	// Defaults are left untouched
+	assert.Equal(t, 7*24*time.Hour, DefaultRetentionPolicies[2].Retention)
+}
+
+func TestParseRetentionPolicies_RejectsInvalidEntries(t *testing.T) {
+	for _, overrides := range []string{"matomo_sent_events", "unknown=24h", "matomo_sent_events=10m", "matomo_sent_events=7d"} {
+		_, err := ParseRetentionPolicies(overrides)
+		assert.Error(t, err, overrides)
+	}
+}
+
+func TestRunRetentionBatches_StopsAtShortBatchOrLimit(t *testing.T) {
+	remaining := int64(25)
+	deleteBatch := func(limit int) (int64, error) {
+		n := min(remaining, int64(limit))
+		remaining -= n
+		return n, nil
+	}
+
+	deleted, batches, truncated, err := runRetentionBatches(context.Background(), 10, 2, deleteBatch)
+	require.NoError(t, err)
+	assert.Equal(t, int64(20), deleted)
+	assert.Equal(t, 2, batches)
+	assert.True(t, truncated)
+
+	deleted, batches, truncated, err = runRetentionBatches(context.Background(), 10, 2, deleteBatch)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), deleted)
+	assert.Equal(t, 1, batches)
+	assert.False(t, truncated)
+}
+
+func TestRunRetentionBatches_ReturnsDeleteError(t *testing.T) {
+	_, batches, _, err := runRetentionBatches(context.Background(), 10, 5, func(int) (int64, error) {
+		return 0, errors.New("lock timeout")
+	})
+	assert.ErrorContains(t, err, "lock timeout")
+	assert.Zero(t, batches)
+}
+
+func TestRetentionDeleteQuery(t *testing.T) {
+	query := retentionDeleteQuery(RetentionPolicy{Table: "matomo_staged_events", TimeColumn: "sent_at", Condition: "status = 'sent'"})
+
+	assert.Equal(t, `DELETE FROM "matomo_staged_events" WHERE ctid = ANY(ARRAY(SELECT ctid FROM "matomo_staged_events" WHERE "sent_at" < $1 AND status = 'sent' LIMIT $2))`, query)
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"math"
+	"strings"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+// RevenueBasis selects which amount feeds LTV and bandit revenue objectives
+type RevenueBasis string
+
+const (
+	// RevenueBasisGross uses the amount charged to the customer
+	RevenueBasisGross RevenueBasis = "gross"
+	// RevenueBasisNet uses proceeds after store fee and tax
+	RevenueBasisNet RevenueBasis = "net"
+	// RevenueBasisMargin uses net proceeds minus the per-product cost (bandit rewards only)
+	RevenueBasisMargin RevenueBasis = "margin"
+)
+
+// IsValid reports whether b is a known basis
+func (b RevenueBasis) IsValid() bool {
+	switch b {
+	case RevenueBasisGross, RevenueBasisNet, RevenueBasisMargin:
+		return true
+	}
+	return false
+}
+
+// ParseRevenueBasis converts a config value into a RevenueBasis, defaulting to gross
+func ParseRevenueBasis(raw string) RevenueBasis {
+	if strings.EqualFold(strings.TrimSpace(raw), string(RevenueBasisNet)) {
+		return RevenueBasisNet
+	}
+	return RevenueBasisGross
+}
+
+// StoreFeeSchedule holds the commission rates used to estimate the store fee
+// when the store does not report it (no app store sends the fee in server
+// notifications; Stripe invoices don't carry the balance transaction; PayPal
+// sales do, see BreakdownWithFee).
+type StoreFeeSchedule struct {
+	AppleCommission  float64
+	GoogleCommission float64
+	AmazonCommission float64
+	HuaweiCommission float64
+	StripeFeePercent float64
+	StripeFeeFixed   float64
+	PayPalFeePercent float64
+	PayPalFeeFixed   float64
+}
+
+// DefaultStoreFeeSchedule returns the standard store commission rates
+func DefaultStoreFeeSchedule() StoreFeeSchedule {
+	return StoreFeeSchedule{
+		AppleCommission:  0.30,
+		GoogleCommission: 0.15,
+		AmazonCommission: 0.20,
+		HuaweiCommission: 0.15,
+		StripeFeePercent: 0.029,
+		StripeFeeFixed:   0.30,
+		PayPalFeePercent: 0.0349,
+		PayPalFeeFixed:   0.49,
+	}
+}
+
+// Breakdown splits gross into store fee, tax and net for the given provider
+// ("apple", "google", "amazon", "huawei", "stripe", "paypal"). App stores take their commission on the
+// tax-exclusive price; Stripe and PayPal charge on the full amount collected.
+func (s StoreFeeSchedule) Breakdown(provider string, gross, tax float64, countryCode string) entity.RevenueBreakdown {
+	if tax < 0 || tax > gross {
+		tax = 0
+	}
+
+	var fee float64
+	switch provider {
+	case "apple":
+		fee = (gross - tax) * s.AppleCommission
+	case "google":
+		fee = (gross - tax) * s.GoogleCommission
+	case "amazon":
+		fee = (gross - tax) * s.AmazonCommission
+	case "huawei":
+		fee = (gross - tax) * s.HuaweiCommission
+	case "stripe":
+		if gross > 0 {
+			fee = gross*s.StripeFeePercent + s.StripeFeeFixed
+		}
+	case "paypal":
+		if gross > 0 {
+			fee = gross*s.PayPalFeePercent + s.PayPalFeeFixed
+		}
+	}
+	return breakdown(gross, tax, fee, countryCode)
+}
+
+// BreakdownWithFee splits gross like Breakdown, but with the fee the payment
+// provider reported instead of the estimate
+func (s StoreFeeSchedule) BreakdownWithFee(gross, tax, fee float64, countryCode string) entity.RevenueBreakdown {
+	if tax < 0 || tax > gross {
+		tax = 0
+	}
+	return breakdown(gross, tax, math.Max(fee, 0), countryCode)
+}
+
+func breakdown(gross, tax, fee float64, countryCode string) entity.RevenueBreakdown {
+	fee = math.Min(roundCents(fee), roundCents(gross-tax))
+
+	return entity.RevenueBreakdown{
+		Gross:       roundCents(gross),
+		StoreFee:    fee,
+		Tax:         roundCents(tax),
+		Net:         roundCents(gross - tax - fee),
+		CountryCode: strings.ToUpper(countryCode),
+	}
+}
+
+// Net returns only the net amount of Breakdown
+func (s StoreFeeSchedule) Net(provider string, gross, tax float64) float64 {
+	return s.Breakdown(provider, gross, tax, "").Net
+}
+
+// RevenueProviderForPlatform maps a client platform, or an Android store, to
+// the store that collects the payment
+func RevenueProviderForPlatform(platform string) string {
+	switch strings.ToLower(platform) {
+	case "ios", "apple":
+		return "apple"
+	case "android", "google":
+		return "google"
+	case "amazon":
+		return "amazon"
+	case "huawei":
+		return "huawei"
+	default:
+		return "stripe"
+	}
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
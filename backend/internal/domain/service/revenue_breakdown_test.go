@@ -0,0 +1,56 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStoreFeeSchedule_AppleCommissionExcludesTax(t *testing.T) {
+	b := DefaultStoreFeeSchedule().Breakdown("apple", 11.99, 2.00, "de")
+
+	assert.Equal(t, 11.99, b.Gross)
+	assert.Equal(t, 2.00, b.Tax)
+	assert.Equal(t, 3.00, b.StoreFee)
+	assert.Equal(t, 6.99, b.Net)
+	assert.Equal(t, "DE", b.CountryCode)
+}
+
+func TestStoreFeeSchedule_StripePercentPlusFixed(t *testing.T) {
+	b := DefaultStoreFeeSchedule().Breakdown("stripe", 10.00, 0, "US")
+
+	assert.Equal(t, 0.59, b.StoreFee)
+	assert.Equal(t, 9.41, b.Net)
+}
+
+func TestStoreFeeSchedule_PayPal(t *testing.T) {
+	estimated := DefaultStoreFeeSchedule().Breakdown("paypal", 10.00, 0, "US")
+	assert.Equal(t, 0.84, estimated.StoreFee)
+	assert.Equal(t, 9.16, estimated.Net)
+
+	reported := DefaultStoreFeeSchedule().BreakdownWithFee(10.00, 1.00, 0.70, "us")
+	assert.Equal(t, 0.70, reported.StoreFee)
+	assert.Equal(t, 8.30, reported.Net)
+	assert.Equal(t, "US", reported.CountryCode)
+}
+
+func TestStoreFeeSchedule_FeeNeverExceedsProceeds(t *testing.T) {
+	b := DefaultStoreFeeSchedule().Breakdown("stripe", 0.20, 0, "")
+
+	assert.Equal(t, 0.20, b.StoreFee)
+	assert.Equal(t, 0.0, b.Net)
+}
+
+func TestStoreFeeSchedule_IgnoresInvalidTax(t *testing.T) {
+	b := DefaultStoreFeeSchedule().Breakdown("google", 10.00, 12.00, "")
+
+	assert.Equal(t, 0.0, b.Tax)
+	assert.Equal(t, 1.50, b.StoreFee)
+	assert.Equal(t, 8.50, b.Net)
+}
+
+func TestParseRevenueBasis(t *testing.T) {
+	assert.Equal(t, RevenueBasisNet, ParseRevenueBasis("NET"))
+	assert.Equal(t, RevenueBasisGross, ParseRevenueBasis(""))
+	assert.Equal(t, RevenueBasisGross, ParseRevenueBasis("margin"))
+}
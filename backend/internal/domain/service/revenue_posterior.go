@@ -0,0 +1,144 @@
+package service
+
+import (
+	"math"
+	"sort"
+)
+
+// Revenue per visitor is modeled as zero-inflated lognormal: a visitor
+// converts with probability p ~ Beta(alpha, beta), and a converter's
+// log(revenue) is Normal(mu, sigma^2) with a Normal-Inverse-Gamma prior.
+// The prior is centred on $1 (mu0 = 0) with the weight of one observation
+// and a log-scale variance of about 1, so a handful of purchases dominates
+// it. Expected revenue per visitor is p * exp(mu + sigma^2/2).
+const (
+	revenuePriorMu    = 0.0
+	revenuePriorKappa = 1.0
+	revenuePriorShape = 2.0
+	revenuePriorScale = 1.0
+
+	// revenuePosteriorDraws is the Monte Carlo sample size for summaries
+	revenuePosteriorDraws = 2000
+)
+
+// AddReward folds one observed reward into the arm's statistics. A positive
+// reward is a conversion whose revenue also feeds the log-revenue sums.
+func (s *ArmStats) AddReward(reward float64) {
+	if reward > 0 {
+		s.Alpha += 1.0
+		s.Conversions++
+		s.Revenue += reward
+		logRevenue := math.Log(reward)
+		s.LogRevenueSum += logRevenue
+		s.LogRevenueSqSum += logRevenue * logRevenue
+	} else {
+		s.Beta += 1.0
+	}
+	s.Samples++
+	s.AvgReward = s.Revenue / float64(s.Samples)
+}
+
+// ReplaceReward swaps a previously added positive reward for a corrected
+// positive amount, leaving the conversion counts as they were
+func (s *ArmStats) ReplaceReward(previous, corrected float64) {
+	if previous <= 0 || corrected <= 0 {
+		return
+	}
+	s.Revenue += corrected - previous
+	previousLog, correctedLog := math.Log(previous), math.Log(corrected)
+	s.LogRevenueSum += correctedLog - previousLog
+	s.LogRevenueSqSum += correctedLog*correctedLog - previousLog*previousLog
+	if s.Samples > 0 {
+		s.AvgReward = s.Revenue / float64(s.Samples)
+	}
+}
+
+// logRevenuePosterior is the Normal-Inverse-Gamma posterior over the mean
+// and variance of a converter's log revenue
+type logRevenuePosterior struct {
+	mu, kappa, shape, scale float64
+}
+
+func newLogRevenuePosterior(stats *ArmStats) logRevenuePosterior {
+	n := float64(stats.Conversions)
+	if n == 0 {
+		return logRevenuePosterior{revenuePriorMu, revenuePriorKappa, revenuePriorShape, revenuePriorScale}
+	}
+	mean := stats.LogRevenueSum / n
+	// Sum of squared deviations; clamp rounding noise below zero
+	ss := math.Max(stats.LogRevenueSqSum-n*mean*mean, 0)
+	kappa := revenuePriorKappa + n
+	return logRevenuePosterior{
+		mu:    (revenuePriorKappa*revenuePriorMu + n*mean) / kappa,
+		kappa: kappa,
+		shape: revenuePriorShape + n/2,
+		scale: revenuePriorScale + ss/2 + revenuePriorKappa*n*(mean-revenuePriorMu)*(mean-revenuePriorMu)/(2*kappa),
+	}
+}
+
+// SampleRevenuePerVisitor draws expected revenue per visitor from the arm's
+// posterior, sampling conversion and revenue jointly
+func (b *ThompsonSamplingBandit) SampleRevenuePerVisitor(stats *ArmStats) float64 {
+	conversion, revenue := b.sampleRevenuePosterior(stats, newLogRevenuePosterior(stats))
+	return conversion * revenue
+}
+
+// sampleRevenuePosterior returns one posterior draw of the conversion rate
+// and of the expected revenue per converter
+func (b *ThompsonSamplingBandit) sampleRevenuePosterior(stats *ArmStats, post logRevenuePosterior) (conversion, revenue float64) {
+	conversion = b.SampleBeta(stats.Alpha, stats.Beta)
+	variance := post.scale / sampleGamma(b.rng, post.shape)
+	mu := post.mu + b.rng.NormFloat64()*math.Sqrt(variance/post.kappa)
+	return conversion, math.Exp(mu + variance/2)
+}
+
+// RevenuePosteriorSummary describes an arm's revenue-per-visitor posterior
+type RevenuePosteriorSummary struct {
+	ConversionRate        float64 `json:"conversion_rate"`
+	RevenuePerConversion  float64 `json:"revenue_per_conversion"`
+	RevenuePerVisitor     float64 `json:"revenue_per_visitor"`
+	RevenuePerVisitorLow  float64 `json:"revenue_per_visitor_low"`
+	RevenuePerVisitorHigh float64 `json:"revenue_per_visitor_high"`
+	LogRevenueMean        float64 `json:"log_revenue_mean"`
+	LogRevenueStdDev      float64 `json:"log_revenue_std_dev"`
+}
+
+// SummarizeRevenuePosterior returns posterior medians and a 95% credible
+// interval for revenue per visitor. Medians are used because the posterior
+// mean of a lognormal is dominated by rare high-variance draws.
+func (b *ThompsonSamplingBandit) SummarizeRevenuePosterior(stats *ArmStats) *RevenuePosteriorSummary {
+	post := newLogRevenuePosterior(stats)
+	conversions := make([]float64, revenuePosteriorDraws)
+	revenues := make([]float64, revenuePosteriorDraws)
+	perVisitor := make([]float64, revenuePosteriorDraws)
+	for i := range perVisitor {
+		conversions[i], revenues[i] = b.sampleRevenuePosterior(stats, post)
+		perVisitor[i] = conversions[i] * revenues[i]
+	}
+	sort.Float64s(conversions)
+	sort.Float64s(revenues)
+	sort.Float64s(perVisitor)
+
+	logStdDev := 0.0
+	if post.shape > 1 {
+		// Mean of the inverse-gamma variance
+		logStdDev = math.Sqrt(post.scale / (post.shape - 1))
+	}
+	return &RevenuePosteriorSummary{
+		ConversionRate:        quantile(conversions, 0.5),
+		RevenuePerConversion:  quantile(revenues, 0.5),
+		RevenuePerVisitor:     quantile(perVisitor, 0.5),
+		RevenuePerVisitorLow:  quantile(perVisitor, 0.025),
+		RevenuePerVisitorHigh: quantile(perVisitor, 0.975),
+		LogRevenueMean:        post.mu,
+		LogRevenueStdDev:      logStdDev,
+	}
+}
+
+// quantile returns the q-th quantile of sorted values
+func quantile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	return sorted[int(q*float64(len(sorted)-1))]
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// lognormalArmStats simulates visitors converting with probability p and
+// paying exp(Normal(mu, sigma^2))
+func lognormalArmStats(rng *rand.Rand, visitors int, p, mu, sigma float64) *ArmStats {
+	stats := &ArmStats{Alpha: 1, Beta: 1}
+	for i := 0; i < visitors; i++ {
+		reward := 0.0
+		if rng.Float64() < p {
+			reward = math.Exp(mu + sigma*rng.NormFloat64())
+		}
+		stats.AddReward(reward)
+	}
+	return stats
+}
+
+func TestArmStats_AddReward(t *testing.T) {
+	stats := &ArmStats{Alpha: 1, Beta: 1}
+	stats.AddReward(0)
+	stats.AddReward(math.E)
+	stats.AddReward(math.E * math.E)
+
+	assert.Equal(t, 3, stats.Samples)
+	assert.Equal(t, 2, stats.Conversions)
+	assert.Equal(t, 3.0, stats.Alpha)
+	assert.Equal(t, 2.0, stats.Beta)
+	assert.InDelta(t, (math.E+math.E*math.E)/3, stats.AvgReward, 1e-9)
+	assert.InDelta(t, 3.0, stats.LogRevenueSum, 1e-9)
+	assert.InDelta(t, 5.0, stats.LogRevenueSqSum, 1e-9)
+}
+
+func TestArmStats_ReplaceReward(t *testing.T) {
+	stats := &ArmStats{Alpha: 1, Beta: 1}
+	stats.AddReward(0)
+	stats.AddReward(math.E)
+	stats.AddReward(math.E * math.E)
+
+	stats.ReplaceReward(math.E, math.E*math.E*math.E)
+
+	expected := &ArmStats{Alpha: 1, Beta: 1}
+	expected.AddReward(0)
+	expected.AddReward(math.E * math.E * math.E)
+	expected.AddReward(math.E * math.E)
+	assert.Equal(t, expected.Conversions, stats.Conversions)
+	assert.Equal(t, expected.Alpha, stats.Alpha)
+	assert.InDelta(t, expected.Revenue, stats.Revenue, 1e-9)
+	assert.InDelta(t, expected.AvgReward, stats.AvgReward, 1e-9)
+	assert.InDelta(t, expected.LogRevenueSum, stats.LogRevenueSum, 1e-9)
+	assert.InDelta(t, expected.LogRevenueSqSum, stats.LogRevenueSqSum, 1e-9)
+
+	// Non-positive rewards never fed the revenue sums
+	stats.ReplaceReward(0, 5)
+	assert.InDelta(t, expected.Revenue, stats.Revenue, 1e-9)
+}
+
+func TestThompsonSamplingBandit_SummarizeRevenuePosteriorRecoversParameters(t *testing.T) {
+	const p, mu, sigma = 0.1, 2.0, 0.5
+	stats := lognormalArmStats(rand.New(rand.NewSource(1)), 50000, p, mu, sigma)
+	bandit := NewThompsonSamplingBandit(&advancedEngineTestRepo{}, &advancedEngineTestCache{}, zap.NewNop()).WithSeed(2)
+
+	summary := bandit.SummarizeRevenuePosterior(stats)
+	assert.InDelta(t, mu, summary.LogRevenueMean, 0.05)
+	assert.InDelta(t, sigma, summary.LogRevenueStdDev, 0.05)
+	assert.InDelta(t, p, summary.ConversionRate, 0.01)
+
+	want := p * math.Exp(mu+sigma*sigma/2)
+	assert.Less(t, summary.RevenuePerVisitorLow, want)
+	assert.Greater(t, summary.RevenuePerVisitorHigh, want)
+	assert.InEpsilon(t, want, summary.RevenuePerVisitor, 0.1)
+}
+
+func TestThompsonSamplingBandit_RevenuePosteriorWidensWithVariance(t *testing.T) {
+	bandit := NewThompsonSamplingBandit(&advancedEngineTestRepo{}, &advancedEngineTestCache{}, zap.NewNop()).WithSeed(3)
+	width := func(sigma float64) float64 {
+		stats := lognormalArmStats(rand.New(rand.NewSource(4)), 2000, 0.1, 2, sigma)
+		summary := bandit.SummarizeRevenuePosterior(stats)
+		require.LessOrEqual(t, summary.RevenuePerVisitorLow, summary.RevenuePerVisitorHigh)
+		return summary.RevenuePerVisitorHigh - summary.RevenuePerVisitorLow
+	}
+	// Same conversion rate and log-mean, but one arm's revenue is far noisier
+	assert.Greater(t, width(1.5), 2*width(0.1))
+}
+
+func TestThompsonSamplingBandit_SampleRevenuePerVisitorWithoutConversions(t *testing.T) {
+	bandit := NewThompsonSamplingBandit(&advancedEngineTestRepo{}, &advancedEngineTestCache{}, zap.NewNop()).WithSeed(5)
+	stats := &ArmStats{Alpha: 1, Beta: 1}
+	for i := 0; i < 100; i++ {
+		draw := bandit.SampleRevenuePerVisitor(stats)
+		require.False(t, math.IsNaN(draw) || math.IsInf(draw, 0), "draw %v", draw)
+		require.GreaterOrEqual(t, draw, 0.0)
+	}
+}
+
+func TestThompsonSamplingBandit_RevenueObjectiveSelectsOnRevenuePerVisitor(t *testing.T) {
+	experimentID := uuid.New()
+	cheap, premium := uuid.New(), uuid.New()
+	rng := rand.New(rand.NewSource(6))
+	// cheap converts twice as often, premium earns five times more per visitor
+	cheapStats := lognormalArmStats(rng, 5000, 0.10, math.Log(1), 0.2)
+	premiumStats := lognormalArmStats(rng, 5000, 0.05, math.Log(10), 0.2)
+	cheapStats.ArmID, premiumStats.ArmID = cheap, premium
+
+	picks := func(objective ObjectiveType) map[uuid.UUID]int {
+		repo := &assignmentTestRepo{advancedEngineTestRepo: advancedEngineTestRepo{
+			arms:             []Arm{{ID: cheap, IsControl: true}, {ID: premium}},
+			armStats:         map[uuid.UUID]*ArmStats{cheap: cheapStats, premium: premiumStats},
+			experimentConfig: &ExperimentConfig{ID: experimentID, ObjectiveType: objective},
+		}}
+		bandit := NewThompsonSamplingBandit(repo, newAssignmentTestCache(), zap.NewNop()).WithSeed(7)
+		counts := map[uuid.UUID]int{}
+		for i := 0; i < 50; i++ {
+			armID, err := bandit.SelectArm(context.Background(), experimentID, uuid.New())
+			require.NoError(t, err)
+			counts[armID]++
+		}
+		return counts
+	}
+
+	assert.Greater(t, picks(ObjectiveConversion)[cheap], 45)
+	assert.Greater(t, picks(ObjectiveRevenue)[premium], 45)
+}
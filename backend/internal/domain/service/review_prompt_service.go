@@ -0,0 +1,464 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/clock"
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+// ReviewPromptExperimentCategory tags review prompt experiments in ab_tests
+const ReviewPromptExperimentCategory = "review_prompt"
+
+// SupportTicketEvent is the client analytics event apps send when a user
+// opens a support ticket
+const SupportTicketEvent = "support_ticket_opened"
+
+// How a user answered a review prompt. Shown is reported when the prompt is
+// displayed; accepted credits the decision's delay.
+const (
+	ReviewOutcomeShown     = "shown"
+	ReviewOutcomeAccepted  = "accepted"
+	ReviewOutcomeDismissed = "dismissed"
+)
+
+// Why a user is, or is not, eligible for a review prompt
+const (
+	ReviewReasonEligible            = "eligible"
+	ReviewReasonLowEngagement       = "low_engagement"
+	ReviewReasonNoRecentPurchase    = "no_recent_purchase"
+	ReviewReasonRecentRefund        = "recent_refund"
+	ReviewReasonRecentSupportTicket = "recent_support_ticket"
+	ReviewReasonCooldown            = "cooldown"
+	ReviewReasonYearlyLimit         = "yearly_limit"
+)
+
+const (
+	DefaultReviewPromptRewardWindow = 24 * time.Hour
+	MaxReviewPromptRewardWindow     = 7 * 24 * time.Hour
+	MaxReviewPromptDelay            = 14 * 24 * time.Hour
+	// reviewPromptHistory is how far back shown prompts count towards the
+	// yearly limit; the stores cap system prompts per year
+	reviewPromptHistory = 365 * 24 * time.Hour
+	// reviewPromptTransactionScan bounds the transactions read for the
+	// purchase and refund rules
+	reviewPromptTransactionScan = 50
+)
+
+var (
+	ErrInvalidReviewPromptExperiment = errors.New("invalid review prompt experiment")
+	ErrInvalidReviewOutcome          = errors.New("invalid review prompt outcome")
+	ErrReviewPromptNotFound          = errors.New("review prompt not found")
+)
+
+// ReviewPromptRules gate who may be asked for a review. A zero duration or
+// count disables its rule.
+type ReviewPromptRules struct {
+	MinSessions         int
+	PurchaseWindow      time.Duration
+	RefundWindow        time.Duration
+	SupportTicketWindow time.Duration
+	Cooldown            time.Duration
+	MaxPerYear          int
+}
+
+// DefaultReviewPromptRules asks engaged users who bought in the last month
+// and have had no refund or support ticket since, at most three times a year
+func DefaultReviewPromptRules() ReviewPromptRules {
+	return ReviewPromptRules{
+		MinSessions:         5,
+		PurchaseWindow:      30 * 24 * time.Hour,
+		RefundWindow:        90 * 24 * time.Hour,
+		SupportTicketWindow: 30 * 24 * time.Hour,
+		Cooldown:            120 * 24 * time.Hour,
+		MaxPerYear:          3,
+	}
+}
+
+// ReviewPromptExperimentRequest creates a bandit whose arms are delays before
+// asking. A decision is rewarded when the user accepts the prompt within
+// RewardWindow of its ask time.
+type ReviewPromptExperimentRequest struct {
+	AppID        uuid.UUID
+	Name         string
+	DelayHours   []int
+	RewardWindow time.Duration
+}
+
+func (r *ReviewPromptExperimentRequest) Validate() error {
+	if r.AppID == uuid.Nil {
+		return fmt.Errorf("%w: app is required", ErrInvalidReviewPromptExperiment)
+	}
+	if r.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidReviewPromptExperiment)
+	}
+	if len(r.DelayHours) < 2 {
+		return fmt.Errorf("%w: at least two delays are required", ErrInvalidReviewPromptExperiment)
+	}
+	maxDelay := int(MaxReviewPromptDelay / time.Hour)
+	seen := make(map[int]bool, len(r.DelayHours))
+	for _, delay := range r.DelayHours {
+		if delay < 0 || delay > maxDelay {
+			return fmt.Errorf("%w: delay %d is not 0-%d hours", ErrInvalidReviewPromptExperiment, delay, maxDelay)
+		}
+		if seen[delay] {
+			return fmt.Errorf("%w: delay %d is repeated", ErrInvalidReviewPromptExperiment, delay)
+		}
+		seen[delay] = true
+	}
+	if r.RewardWindow < time.Hour || r.RewardWindow > MaxReviewPromptRewardWindow || r.RewardWindow%time.Hour != 0 {
+		return fmt.Errorf("%w: reward window must be whole hours between 1 and %d", ErrInvalidReviewPromptExperiment, int(MaxReviewPromptRewardWindow/time.Hour))
+	}
+	return nil
+}
+
+// ReviewPromptArm is how long after the decision an arm asks
+type ReviewPromptArm struct {
+	ArmID      uuid.UUID `json:"arm_id"`
+	DelayHours int       `json:"delay_hours"`
+}
+
+type ReviewPromptExperiment struct {
+	ID                uuid.UUID         `json:"id"`
+	AppID             uuid.UUID         `json:"app_id"`
+	Name              string            `json:"name"`
+	Status            string            `json:"status"`
+	RewardWindowHours int               `json:"reward_window_hours"`
+	Arms              []ReviewPromptArm `json:"arms"`
+	CreatedAt         time.Time         `json:"created_at"`
+}
+
+func (e *ReviewPromptExperiment) RewardWindow() time.Duration {
+	return time.Duration(e.RewardWindowHours) * time.Hour
+}
+
+// ReviewPromptDecision is one offer to ask a user for a review at AskAt.
+// Without an experiment it has no arm or pending reward and asks at once.
+type ReviewPromptDecision struct {
+	ID              uuid.UUID  `json:"id"`
+	AppID           uuid.UUID  `json:"app_id"`
+	UserID          uuid.UUID  `json:"user_id"`
+	ExperimentID    *uuid.UUID `json:"experiment_id,omitempty"`
+	ArmID           *uuid.UUID `json:"arm_id,omitempty"`
+	PendingRewardID *uuid.UUID `json:"pending_reward_id,omitempty"`
+	DelayHours      int        `json:"delay_hours"`
+	AskAt           time.Time  `json:"ask_at"`
+	ExpiresAt       time.Time  `json:"expires_at"`
+	ShownAt         *time.Time `json:"shown_at,omitempty"`
+	Outcome         *string    `json:"outcome,omitempty"`
+	OutcomeAt       *time.Time `json:"outcome_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// IsOpen reports whether the decision still waits to be shown at now
+func (d *ReviewPromptDecision) IsOpen(now time.Time) bool {
+	return d.ShownAt == nil && d.Outcome == nil && now.Before(d.ExpiresAt)
+}
+
+// ReviewEligibility answers whether to ask the user for a review and when.
+// An ineligible answer carries the first rule that failed.
+type ReviewEligibility struct {
+	Eligible bool                  `json:"eligible"`
+	Reason   string                `json:"reason"`
+	Prompt   *ReviewPromptDecision `json:"prompt,omitempty"`
+}
+
+type ReviewPromptRepository interface {
+	// CreateReviewPromptExperiment stores the bandit experiment, one arm per
+	// delay and their delays, assigning the experiment and arm IDs
+	CreateReviewPromptExperiment(ctx context.Context, experiment *ReviewPromptExperiment) error
+	// GetRunningReviewPromptExperiment returns the app's newest running
+	// experiment, nil when there is none
+	GetRunningReviewPromptExperiment(ctx context.Context, appID uuid.UUID) (*ReviewPromptExperiment, error)
+	CreateReviewPromptDecision(ctx context.Context, decision *ReviewPromptDecision) error
+	// GetReviewPromptDecision returns nil when there is no such decision
+	GetReviewPromptDecision(ctx context.Context, decisionID uuid.UUID) (*ReviewPromptDecision, error)
+	// ListReviewPromptDecisions returns the user's decisions made since,
+	// newest first
+	ListReviewPromptDecisions(ctx context.Context, userID uuid.UUID, since time.Time) ([]*ReviewPromptDecision, error)
+	MarkReviewPromptShown(ctx context.Context, decisionID uuid.UUID, shownAt time.Time) error
+	// RecordReviewPromptOutcome stores the first answer. An accepted answer
+	// also credits the decision's pending reward while it is unexpired,
+	// reporting whether it did.
+	RecordReviewPromptOutcome(ctx context.Context, decisionID uuid.UUID, outcome string, at time.Time) (bool, error)
+	// LastSupportTicketAt returns when the user last opened a support ticket
+	// since the given time, nil when they did not
+	LastSupportTicketAt(ctx context.Context, appID, userID uuid.UUID, since time.Time) (*time.Time, error)
+}
+
+type reviewPromptUsers interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*entity.User, error)
+}
+
+type reviewPromptTransactions interface {
+	GetByUserID(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*entity.Transaction, error)
+}
+
+type reviewPromptArmSelector interface {
+	SelectArmFrom(ctx context.Context, experimentID, userID uuid.UUID, candidates []uuid.UUID) (uuid.UUID, error)
+}
+
+type reviewPromptPendingRewards interface {
+	RecordPendingReward(ctx context.Context, experimentID, armID, userID uuid.UUID, window time.Duration) (*PendingReward, error)
+}
+
+// ReviewPromptService decides whether and when to ask a user for a store
+// review. Rules keep unhappy or disengaged users out; a running review prompt
+// bandit learns how long to wait before asking. Each bandit decision records
+// a pending reward through the delayed reward strategy that an accepted
+// prompt credits, and the pending reward expiry sweep counts the rest as
+// misses.
+type ReviewPromptService struct {
+	repo         ReviewPromptRepository
+	users        reviewPromptUsers
+	transactions reviewPromptTransactions
+	selector     reviewPromptArmSelector
+	rewards      reviewPromptPendingRewards
+	rules        ReviewPromptRules
+	now          func() time.Time
+}
+
+func NewReviewPromptService(repo ReviewPromptRepository, users reviewPromptUsers, transactions reviewPromptTransactions, selector reviewPromptArmSelector, rewards reviewPromptPendingRewards) *ReviewPromptService {
+	return &ReviewPromptService{
+		repo:         repo,
+		users:        users,
+		transactions: transactions,
+		selector:     selector,
+		rewards:      rewards,
+		rules:        DefaultReviewPromptRules(),
+		now:          time.Now,
+	}
+}
+
+// WithRules replaces DefaultReviewPromptRules
+func (s *ReviewPromptService) WithRules(rules ReviewPromptRules) *ReviewPromptService {
+	s.rules = rules
+	return s
+}
+
+func (s *ReviewPromptService) WithClock(c clock.Clock) *ReviewPromptService {
+	s.now = c.Now
+	return s
+}
+
+func (s *ReviewPromptService) CreateExperiment(ctx context.Context, req ReviewPromptExperimentRequest) (*ReviewPromptExperiment, error) {
+	if req.RewardWindow == 0 {
+		req.RewardWindow = DefaultReviewPromptRewardWindow
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	delays := append([]int(nil), req.DelayHours...)
+	sort.Ints(delays)
+	experiment := &ReviewPromptExperiment{
+		AppID:             req.AppID,
+		Name:              req.Name,
+		RewardWindowHours: int(req.RewardWindow / time.Hour),
+		Arms:              make([]ReviewPromptArm, len(delays)),
+	}
+	for i, delay := range delays {
+		experiment.Arms[i] = ReviewPromptArm{DelayHours: delay}
+	}
+	if err := s.repo.CreateReviewPromptExperiment(ctx, experiment); err != nil {
+		return nil, fmt.Errorf("failed to create review prompt experiment: %w", err)
+	}
+	return experiment, nil
+}
+
+// Eligibility decides whether to ask userID for a review. An eligible user
+// gets a decision to show at its AskAt; asking again while it is open
+// returns the same decision rather than drawing another arm.
+func (s *ReviewPromptService) Eligibility(ctx context.Context, userID uuid.UUID) (*ReviewEligibility, error) {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	now := s.now()
+
+	history, err := s.repo.ListReviewPromptDecisions(ctx, userID, now.Add(-reviewPromptHistory))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list review prompts: %w", err)
+	}
+	if reason := s.historyReason(history, now); reason != "" {
+		return &ReviewEligibility{Reason: reason}, nil
+	}
+	for _, decision := range history {
+		if decision.IsOpen(now) {
+			return &ReviewEligibility{Eligible: true, Reason: ReviewReasonEligible, Prompt: decision}, nil
+		}
+	}
+
+	if user.SessionCount < s.rules.MinSessions {
+		return &ReviewEligibility{Reason: ReviewReasonLowEngagement}, nil
+	}
+	reason, err := s.purchaseReason(ctx, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	if reason != "" {
+		return &ReviewEligibility{Reason: reason}, nil
+	}
+	if s.rules.SupportTicketWindow > 0 {
+		ticketAt, err := s.repo.LastSupportTicketAt(ctx, user.AppID, userID, now.Add(-s.rules.SupportTicketWindow))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get support tickets: %w", err)
+		}
+		if ticketAt != nil {
+			return &ReviewEligibility{Reason: ReviewReasonRecentSupportTicket}, nil
+		}
+	}
+
+	decision, err := s.decide(ctx, user, now)
+	if err != nil {
+		return nil, err
+	}
+	return &ReviewEligibility{Eligible: true, Reason: ReviewReasonEligible, Prompt: decision}, nil
+}
+
+// RecordOutcome records how userID answered the prompt, reporting whether an
+// acceptance credited its delay
+func (s *ReviewPromptService) RecordOutcome(ctx context.Context, userID, decisionID uuid.UUID, outcome string) (bool, error) {
+	if outcome != ReviewOutcomeShown && outcome != ReviewOutcomeAccepted && outcome != ReviewOutcomeDismissed {
+		return false, fmt.Errorf("%w: must be %q, %q or %q", ErrInvalidReviewOutcome, ReviewOutcomeShown, ReviewOutcomeAccepted, ReviewOutcomeDismissed)
+	}
+	decision, err := s.repo.GetReviewPromptDecision(ctx, decisionID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get review prompt: %w", err)
+	}
+	if decision == nil || decision.UserID != userID {
+		return false, ErrReviewPromptNotFound
+	}
+
+	if outcome == ReviewOutcomeShown {
+		if err := s.repo.MarkReviewPromptShown(ctx, decisionID, s.now()); err != nil {
+			return false, fmt.Errorf("failed to mark review prompt shown: %w", err)
+		}
+		return false, nil
+	}
+	credited, err := s.repo.RecordReviewPromptOutcome(ctx, decisionID, outcome, s.now())
+	if err != nil {
+		return false, fmt.Errorf("failed to record review prompt outcome: %w", err)
+	}
+	return credited, nil
+}
+
+// historyReason applies the yearly limit and the cooldown to the prompts the
+// user has been shown
+func (s *ReviewPromptService) historyReason(history []*ReviewPromptDecision, now time.Time) string {
+	shown := 0
+	var lastShown time.Time
+	for _, decision := range history {
+		at := decision.ShownAt
+		if at == nil {
+			at = decision.OutcomeAt
+		}
+		if at == nil {
+			continue
+		}
+		shown++
+		if at.After(lastShown) {
+			lastShown = *at
+		}
+	}
+	if s.rules.MaxPerYear > 0 && shown >= s.rules.MaxPerYear {
+		return ReviewReasonYearlyLimit
+	}
+	if s.rules.Cooldown > 0 && shown > 0 && now.Sub(lastShown) < s.rules.Cooldown {
+		return ReviewReasonCooldown
+	}
+	return ""
+}
+
+// purchaseReason applies the refund and recent purchase rules to the user's
+// latest transactions
+func (s *ReviewPromptService) purchaseReason(ctx context.Context, userID uuid.UUID, now time.Time) (string, error) {
+	if s.rules.RefundWindow == 0 && s.rules.PurchaseWindow == 0 {
+		return "", nil
+	}
+	transactions, err := s.transactions.GetByUserID(ctx, userID, reviewPromptTransactionScan, 0)
+	if err != nil {
+		return "", fmt.Errorf("failed to get transactions: %w", err)
+	}
+
+	purchased := false
+	for _, tx := range transactions {
+		if s.rules.RefundWindow > 0 {
+			refundedAt := tx.RefundedAt
+			if refundedAt == nil && tx.Status == entity.TransactionStatusRefunded {
+				refundedAt = &tx.CreatedAt
+			}
+			if refundedAt != nil && now.Sub(*refundedAt) < s.rules.RefundWindow {
+				return ReviewReasonRecentRefund, nil
+			}
+		}
+		if tx.Status == entity.TransactionStatusSuccess && now.Sub(tx.CreatedAt) < s.rules.PurchaseWindow {
+			purchased = true
+		}
+	}
+	if s.rules.PurchaseWindow > 0 && !purchased {
+		return ReviewReasonNoRecentPurchase, nil
+	}
+	return "", nil
+}
+
+// decide records a new decision. With a running experiment the bandit picks
+// the delay and a pending reward covers it plus the reward window.
+func (s *ReviewPromptService) decide(ctx context.Context, user *entity.User, now time.Time) (*ReviewPromptDecision, error) {
+	decision := &ReviewPromptDecision{
+		ID:        uuid.New(),
+		AppID:     user.AppID,
+		UserID:    user.ID,
+		AskAt:     now,
+		ExpiresAt: now.Add(DefaultReviewPromptRewardWindow),
+		CreatedAt: now,
+	}
+
+	experiment, err := s.repo.GetRunningReviewPromptExperiment(ctx, user.AppID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get review prompt experiment: %w", err)
+	}
+	if experiment != nil && len(experiment.Arms) > 0 {
+		delays := make(map[uuid.UUID]int, len(experiment.Arms))
+		candidates := make([]uuid.UUID, 0, len(experiment.Arms))
+		for _, arm := range experiment.Arms {
+			delays[arm.ArmID] = arm.DelayHours
+			candidates = append(candidates, arm.ArmID)
+		}
+		armID, err := s.selector.SelectArmFrom(ctx, experiment.ID, user.ID, candidates)
+		switch {
+		case errors.Is(err, ErrExperimentPaused):
+			// Ask right away, outside the experiment, until it resumes
+		case err != nil:
+			return nil, fmt.Errorf("failed to select review prompt delay: %w", err)
+		default:
+			delay, ok := delays[armID]
+			if !ok {
+				return nil, fmt.Errorf("selected arm %s is not a review prompt delay", armID)
+			}
+
+			wait := time.Duration(delay) * time.Hour
+			pending, err := s.rewards.RecordPendingReward(ctx, experiment.ID, armID, user.ID, wait+experiment.RewardWindow())
+			if err != nil {
+				return nil, fmt.Errorf("failed to record review prompt pending reward: %w", err)
+			}
+			decision.ExperimentID = &experiment.ID
+			decision.ArmID = &armID
+			decision.PendingRewardID = &pending.ID
+			decision.DelayHours = delay
+			decision.AskAt = now.Add(wait)
+			decision.ExpiresAt = decision.AskAt.Add(experiment.RewardWindow())
+		}
+	}
+
+	if err := s.repo.CreateReviewPromptDecision(ctx, decision); err != nil {
+		return nil, fmt.Errorf("failed to create review prompt decision: %w", err)
+	}
+	return decision, nil
+}
@@ -0,0 +1,273 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bivex/paywall-iap/internal/clock"
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+type reviewPromptTestRepo struct {
+	experiment *ReviewPromptExperiment
+	decisions  []*ReviewPromptDecision
+	ticketAt   *time.Time
+	outcomes   []string
+}
+
+func (r *reviewPromptTestRepo) CreateReviewPromptExperiment(ctx context.Context, experiment *ReviewPromptExperiment) error {
+	experiment.ID = uuid.New()
+	for i := range experiment.Arms {
+		experiment.Arms[i].ArmID = uuid.New()
+	}
+	r.experiment = experiment
+	return nil
+}
+
+func (r *reviewPromptTestRepo) GetRunningReviewPromptExperiment(ctx context.Context, appID uuid.UUID) (*ReviewPromptExperiment, error) {
+	if r.experiment == nil || r.experiment.AppID != appID {
+		return nil, nil
+	}
+	return r.experiment, nil
+}
+
+func (r *reviewPromptTestRepo) CreateReviewPromptDecision(ctx context.Context, decision *ReviewPromptDecision) error {
+	r.decisions = append([]*ReviewPromptDecision{decision}, r.decisions...)
+	return nil
+}
+
+func (r *reviewPromptTestRepo) GetReviewPromptDecision(ctx context.Context, decisionID uuid.UUID) (*ReviewPromptDecision, error) {
+	for _, d := range r.decisions {
+		if d.ID == decisionID {
+			return d, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *reviewPromptTestRepo) ListReviewPromptDecisions(ctx context.Context, userID uuid.UUID, since time.Time) ([]*ReviewPromptDecision, error) {
+	var decisions []*ReviewPromptDecision
+	for _, d := range r.decisions {
+		if d.UserID == userID && !d.CreatedAt.Before(since) {
+			decisions = append(decisions, d)
+		}
+	}
+	return decisions, nil
+}
+
+func (r *reviewPromptTestRepo) MarkReviewPromptShown(ctx context.Context, decisionID uuid.UUID, shownAt time.Time) error {
+	d, _ := r.GetReviewPromptDecision(ctx, decisionID)
+	d.ShownAt = &shownAt
+	return nil
+}
+
+func (r *reviewPromptTestRepo) RecordReviewPromptOutcome(ctx context.Context, decisionID uuid.UUID, outcome string, at time.Time) (bool, error) {
+	d, _ := r.GetReviewPromptDecision(ctx, decisionID)
+	d.Outcome, d.OutcomeAt = &outcome, &at
+	r.outcomes = append(r.outcomes, outcome)
+	return outcome == ReviewOutcomeAccepted && d.PendingRewardID != nil, nil
+}
+
+func (r *reviewPromptTestRepo) LastSupportTicketAt(ctx context.Context, appID, userID uuid.UUID, since time.Time) (*time.Time, error) {
+	if r.ticketAt != nil && !r.ticketAt.Before(since) {
+		return r.ticketAt, nil
+	}
+	return nil, nil
+}
+
+type stubReviewPromptUsers struct {
+	repository.UserRepository
+	user *entity.User
+}
+
+func (s *stubReviewPromptUsers) GetByID(ctx context.Context, id uuid.UUID) (*entity.User, error) {
+	return s.user, nil
+}
+
+type stubReviewPromptTransactions struct {
+	transactions []*entity.Transaction
+}
+
+func (s *stubReviewPromptTransactions) GetByUserID(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*entity.Transaction, error) {
+	return s.transactions, nil
+}
+
+type reviewPromptFixture struct {
+	clk          *clock.Fake
+	user         *entity.User
+	repo         *reviewPromptTestRepo
+	transactions *stubReviewPromptTransactions
+	selector     *sendTimeTestSelector
+	rewards      *sendTimeTestRewards
+	svc          *ReviewPromptService
+}
+
+// newReviewPromptFixture sets up an engaged user who bought a week ago
+func newReviewPromptFixture() *reviewPromptFixture {
+	clk := clock.NewFake(time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC))
+	f := &reviewPromptFixture{
+		clk:      clk,
+		user:     &entity.User{ID: uuid.New(), AppID: uuid.New(), SessionCount: 8},
+		repo:     &reviewPromptTestRepo{},
+		selector: &sendTimeTestSelector{},
+		rewards:  &sendTimeTestRewards{},
+		transactions: &stubReviewPromptTransactions{transactions: []*entity.Transaction{
+			{ID: uuid.New(), Status: entity.TransactionStatusSuccess, CreatedAt: clk.Now().Add(-7 * 24 * time.Hour)},
+		}},
+	}
+	f.svc = NewReviewPromptService(f.repo, &stubReviewPromptUsers{user: f.user}, f.transactions, f.selector, f.rewards).WithClock(clk)
+	return f
+}
+
+func TestReviewPromptExperimentRequest_Validate(t *testing.T) {
+	valid := ReviewPromptExperimentRequest{AppID: uuid.New(), Name: "n", DelayHours: []int{0, 48}, RewardWindow: 24 * time.Hour}
+	require.NoError(t, valid.Validate())
+
+	for name, mutate := range map[string]func(*ReviewPromptExperimentRequest){
+		"one delay":       func(r *ReviewPromptExperimentRequest) { r.DelayHours = []int{0} },
+		"negative delay":  func(r *ReviewPromptExperimentRequest) { r.DelayHours = []int{-1, 24} },
+		"delay too long":  func(r *ReviewPromptExperimentRequest) { r.DelayHours = []int{0, 337} },
+		"repeated delay":  func(r *ReviewPromptExperimentRequest) { r.DelayHours = []int{24, 24} },
+		"window too long": func(r *ReviewPromptExperimentRequest) { r.RewardWindow = 8 * 24 * time.Hour },
+	} {
+		req := valid
+		mutate(&req)
+		assert.ErrorIs(t, req.Validate(), ErrInvalidReviewPromptExperiment, name)
+	}
+}
+
+func TestReviewPromptService_Rules(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		setup  func(f *reviewPromptFixture)
+		reason string
+	}{
+		{name: "engaged recent buyer", setup: func(f *reviewPromptFixture) {}, reason: ReviewReasonEligible},
+		{name: "few sessions", setup: func(f *reviewPromptFixture) { f.user.SessionCount = 2 }, reason: ReviewReasonLowEngagement},
+		{name: "old purchase", setup: func(f *reviewPromptFixture) {
+			f.transactions.transactions[0].CreatedAt = f.clk.Now().Add(-60 * 24 * time.Hour)
+		}, reason: ReviewReasonNoRecentPurchase},
+		{name: "refunded charge", setup: func(f *reviewPromptFixture) {
+			refundedAt := f.clk.Now().Add(-10 * 24 * time.Hour)
+			f.transactions.transactions = append(f.transactions.transactions, &entity.Transaction{
+				Status: entity.TransactionStatusRefunded, CreatedAt: refundedAt.Add(-time.Hour), RefundedAt: &refundedAt,
+			})
+		}, reason: ReviewReasonRecentRefund},
+		{name: "support ticket", setup: func(f *reviewPromptFixture) {
+			at := f.clk.Now().Add(-2 * 24 * time.Hour)
+			f.repo.ticketAt = &at
+		}, reason: ReviewReasonRecentSupportTicket},
+		{name: "shown recently", setup: func(f *reviewPromptFixture) {
+			shownAt := f.clk.Now().Add(-30 * 24 * time.Hour)
+			f.repo.decisions = []*ReviewPromptDecision{{ID: uuid.New(), UserID: f.user.ID, CreatedAt: shownAt, ShownAt: &shownAt}}
+		}, reason: ReviewReasonCooldown},
+		{name: "shown three times this year", setup: func(f *reviewPromptFixture) {
+			for _, daysAgo := range []int{150, 280, 360} {
+				shownAt := f.clk.Now().Add(-time.Duration(daysAgo) * 24 * time.Hour)
+				f.repo.decisions = append(f.repo.decisions, &ReviewPromptDecision{ID: uuid.New(), UserID: f.user.ID, CreatedAt: shownAt, ShownAt: &shownAt})
+			}
+		}, reason: ReviewReasonYearlyLimit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newReviewPromptFixture()
+			tt.setup(f)
+
+			eligibility, err := f.svc.Eligibility(ctx, f.user.ID)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.reason, eligibility.Reason)
+			assert.Equal(t, tt.reason == ReviewReasonEligible, eligibility.Eligible)
+			assert.Equal(t, tt.reason == ReviewReasonEligible, eligibility.Prompt != nil)
+		})
+	}
+}
+
+func TestReviewPromptService_WithoutExperimentAsksNow(t *testing.T) {
+	f := newReviewPromptFixture()
+
+	eligibility, err := f.svc.Eligibility(context.Background(), f.user.ID)
+	require.NoError(t, err)
+
+	require.NotNil(t, eligibility.Prompt)
+	assert.Equal(t, f.clk.Now(), eligibility.Prompt.AskAt)
+	assert.Nil(t, eligibility.Prompt.ArmID)
+	assert.Nil(t, eligibility.Prompt.PendingRewardID)
+}
+
+func TestReviewPromptService_BanditPicksDelay(t *testing.T) {
+	ctx := context.Background()
+	f := newReviewPromptFixture()
+	experiment, err := f.svc.CreateExperiment(ctx, ReviewPromptExperimentRequest{
+		AppID:      f.user.AppID,
+		Name:       "Review timing",
+		DelayHours: []int{48, 0},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 0, experiment.Arms[0].DelayHours, "delays are sorted")
+
+	// The test selector picks the first candidate; make that the 48h arm
+	experiment.Arms[0], experiment.Arms[1] = experiment.Arms[1], experiment.Arms[0]
+
+	eligibility, err := f.svc.Eligibility(ctx, f.user.ID)
+	require.NoError(t, err)
+	prompt := eligibility.Prompt
+	require.NotNil(t, prompt)
+
+	assert.Equal(t, experiment.Arms[0].ArmID, *prompt.ArmID)
+	assert.Equal(t, 48, prompt.DelayHours)
+	assert.Equal(t, f.clk.Now().Add(48*time.Hour), prompt.AskAt)
+	assert.Equal(t, 48*time.Hour+DefaultReviewPromptRewardWindow, f.rewards.window, "pending reward covers the delay and the window")
+	require.NotNil(t, prompt.PendingRewardID)
+
+	t.Run("open decision is returned again", func(t *testing.T) {
+		f.clk.Advance(time.Hour)
+		again, err := f.svc.Eligibility(ctx, f.user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, prompt.ID, again.Prompt.ID)
+		assert.Len(t, f.repo.decisions, 1)
+	})
+
+	t.Run("acceptance credits the delay and starts the cooldown", func(t *testing.T) {
+		_, err := f.svc.RecordOutcome(ctx, uuid.New(), prompt.ID, ReviewOutcomeAccepted)
+		assert.ErrorIs(t, err, ErrReviewPromptNotFound, "another user's prompt")
+
+		credited, err := f.svc.RecordOutcome(ctx, f.user.ID, prompt.ID, ReviewOutcomeAccepted)
+		require.NoError(t, err)
+		assert.True(t, credited)
+
+		eligibility, err := f.svc.Eligibility(ctx, f.user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, ReviewReasonCooldown, eligibility.Reason)
+	})
+}
+
+func TestReviewPromptService_RecordOutcome(t *testing.T) {
+	ctx := context.Background()
+	f := newReviewPromptFixture()
+	eligibility, err := f.svc.Eligibility(ctx, f.user.ID)
+	require.NoError(t, err)
+	promptID := eligibility.Prompt.ID
+
+	_, err = f.svc.RecordOutcome(ctx, f.user.ID, promptID, "rated")
+	assert.ErrorIs(t, err, ErrInvalidReviewOutcome)
+
+	credited, err := f.svc.RecordOutcome(ctx, f.user.ID, promptID, ReviewOutcomeShown)
+	require.NoError(t, err)
+	assert.False(t, credited)
+	assert.NotNil(t, eligibility.Prompt.ShownAt)
+	assert.Empty(t, f.repo.outcomes, "shown is not an answer")
+
+	credited, err = f.svc.RecordOutcome(ctx, f.user.ID, promptID, ReviewOutcomeDismissed)
+	require.NoError(t, err)
+	assert.False(t, credited)
+	assert.Equal(t, []string{ReviewOutcomeDismissed}, f.repo.outcomes)
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// MaxRewardDefinitions caps the reward definitions of one experiment
+const MaxRewardDefinitions = 20
+
+// rewardDefinitionNamespace derives the transaction ID that counts a reward
+// definition once per user and experiment
+var rewardDefinitionNamespace = uuid.MustParse("b3c1e0d4-5a6f-4e8b-9d27-71f0c2a8e9b5")
+
+// RewardDefinition rewards an arm when an assigned user reports EventType,
+// for experiments that optimize a non-monetary conversion such as a trial
+// start. With a HalfLife the reward halves for every HalfLife that passed
+// between the assignment and the event.
+type RewardDefinition struct {
+	Name      string
+	EventType string
+	Reward    float64
+	HalfLife  time.Duration
+}
+
+// Validate checks a single definition
+func (d RewardDefinition) Validate() error {
+	switch {
+	case d.Name == "" || len(d.Name) > 64:
+		return fmt.Errorf("reward definition name must be 1-64 characters")
+	case d.EventType == "" || len(d.EventType) > 128:
+		return fmt.Errorf("reward definition %q: event_type must be 1-128 characters", d.Name)
+	case d.Reward <= 0 || math.IsNaN(d.Reward) || math.IsInf(d.Reward, 0):
+		return fmt.Errorf("reward definition %q: reward must be positive", d.Name)
+	case d.HalfLife < 0:
+		return fmt.Errorf("reward definition %q: half_life must not be negative", d.Name)
+	}
+	return nil
+}
+
+// ShapedReward returns the reward for an event that came latency after the
+// assignment
+func (d RewardDefinition) ShapedReward(latency time.Duration) float64 {
+	if d.HalfLife <= 0 || latency <= 0 {
+		return d.Reward
+	}
+	return d.Reward * math.Pow(0.5, float64(latency)/float64(d.HalfLife))
+}
+
+// ValidateRewardDefinitions checks a definition set; names must be unique
+func ValidateRewardDefinitions(definitions []RewardDefinition) error {
+	if len(definitions) > MaxRewardDefinitions {
+		return fmt.Errorf("at most %d reward definitions are allowed", MaxRewardDefinitions)
+	}
+	names := make(map[string]bool, len(definitions))
+	for _, definition := range definitions {
+		if err := definition.Validate(); err != nil {
+			return err
+		}
+		if names[definition.Name] {
+			return fmt.Errorf("duplicate reward definition %q", definition.Name)
+		}
+		names[definition.Name] = true
+	}
+	return nil
+}
+
+// rewardDefinitionRepository persists reward definitions and finds the
+// running experiments that reward an event type
+type rewardDefinitionRepository interface {
+	UpdateRewardDefinitions(ctx context.Context, experimentID uuid.UUID, definitions []RewardDefinition) error
+	ListRewardDefinitionExperimentIDs(ctx context.Context, eventType string) ([]uuid.UUID, error)
+}
+
+// SetRewardDefinitions replaces the experiment's reward definitions; an empty
+// set turns reward shaping off
+func (e *AdvancedBanditEngine) SetRewardDefinitions(
+	ctx context.Context,
+	experimentID uuid.UUID,
+	definitions []RewardDefinition,
+) (*ExperimentConfig, error) {
+	if err := ValidateRewardDefinitions(definitions); err != nil {
+		return nil, err
+	}
+
+	repo, ok := e.repo.(rewardDefinitionRepository)
+	if !ok {
+		return nil, fmt.Errorf("repository does not support reward definitions")
+	}
+	if err := repo.UpdateRewardDefinitions(ctx, experimentID, definitions); err != nil {
+		return nil, err
+	}
+
+	return e.refreshExperimentConfig(ctx, experimentID)
+}
+
+// ApplyRewardDefinitions translates an event the user reported into rewards
+// for the arms they are assigned in experiments defining a reward for it. A
+// definition rewards each user once per experiment; events from before the
+// assignment are not attributed. It returns how many rewards were recorded.
+func (e *AdvancedBanditEngine) ApplyRewardDefinitions(
+	ctx context.Context,
+	userID uuid.UUID,
+	eventType string,
+	occurredAt time.Time,
+) (int, error) {
+	repo, ok := e.repo.(rewardDefinitionRepository)
+	if !ok {
+		return 0, nil
+	}
+	experimentIDs, err := repo.ListRewardDefinitionExperimentIDs(ctx, eventType)
+	if err != nil {
+		return 0, err
+	}
+
+	recorded := 0
+	for _, experimentID := range experimentIDs {
+		assignment, err := e.repo.GetActiveAssignment(ctx, experimentID, userID)
+		if err != nil {
+			return recorded, fmt.Errorf("failed to get assignment: %w", err)
+		}
+		if assignment == nil || occurredAt.Before(assignment.AssignedAt) {
+			continue
+		}
+		config, err := e.getExperimentConfig(ctx, experimentID)
+		if err != nil {
+			return recorded, err
+		}
+
+		for _, definition := range config.RewardDefinitions {
+			if definition.EventType != eventType {
+				continue
+			}
+			latency := occurredAt.Sub(assignment.AssignedAt)
+			reward := definition.ShapedReward(latency)
+			transactionID := uuid.NewSHA1(rewardDefinitionNamespace, []byte(experimentID.String()+":"+userID.String()+":"+definition.Name))
+			err := e.base.UpdateRewardWithEvent(ctx, experimentID, assignment.ArmID, reward, &ConversionEvent{
+				ExperimentID:          experimentID,
+				ArmID:                 assignment.ArmID,
+				UserID:                &userID,
+				TransactionID:         &transactionID,
+				EventType:             ConversionEventTypeDirectReward,
+				OriginalRewardValue:   reward,
+				NormalizedRewardValue: reward,
+				Metadata: map[string]interface{}{
+					"source":            "reward_definition",
+					"reward_definition": definition.Name,
+					"event_type":        eventType,
+					"latency_seconds":   latency.Seconds(),
+				},
+				OccurredAt: occurredAt.UTC(),
+			})
+			if errors.Is(err, ErrDuplicateConversion) {
+				continue
+			}
+			if err != nil {
+				return recorded, err
+			}
+			recorded++
+			e.logger.Debug("Shaped reward recorded",
+				zap.String("experiment_id", experimentID.String()),
+				zap.String("arm_id", assignment.ArmID.String()),
+				zap.String("reward_definition", definition.Name),
+				zap.Float64("reward", reward),
+			)
+		}
+	}
+	return recorded, nil
+}
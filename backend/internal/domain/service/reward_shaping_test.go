@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// rewardShapingTestRepo serves reward definitions and one user's assignment
+// and records the rewards they produce
+type rewardShapingTestRepo struct {
+	advancedEngineTestRepo
+
+	experimentIDs []uuid.UUID
+	assignment    *Assignment
+	claimed       map[uuid.UUID]bool
+	events        []*ConversionEvent
+	definitions   []RewardDefinition
+}
+
+func (r *rewardShapingTestRepo) UpdateRewardDefinitions(ctx context.Context, experimentID uuid.UUID, definitions []RewardDefinition) error {
+	r.definitions = definitions
+	r.experimentConfig = &ExperimentConfig{ID: experimentID, RewardDefinitions: definitions}
+	return nil
+}
+func (r *rewardShapingTestRepo) ListRewardDefinitionExperimentIDs(ctx context.Context, eventType string) ([]uuid.UUID, error) {
+	return r.experimentIDs, nil
+}
+func (r *rewardShapingTestRepo) GetActiveAssignment(ctx context.Context, experimentID, userID uuid.UUID) (*Assignment, error) {
+	return r.assignment, nil
+}
+func (r *rewardShapingTestRepo) ClaimConversion(ctx context.Context, transactionID, experimentID, armID uuid.UUID, source ConversionEventType) (bool, error) {
+	if r.claimed[transactionID] {
+		return false, nil
+	}
+	r.claimed[transactionID] = true
+	return true, nil
+}
+func (r *rewardShapingTestRepo) ReleaseConversion(ctx context.Context, transactionID uuid.UUID) error {
+	delete(r.claimed, transactionID)
+	return nil
+}
+func (r *rewardShapingTestRepo) AppendConversionEvent(ctx context.Context, event *ConversionEvent) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+func TestRewardDefinition_ShapedReward(t *testing.T) {
+	flat := RewardDefinition{Name: "trial", EventType: "trial_started", Reward: 2}
+	assert.Equal(t, 2.0, flat.ShapedReward(72*time.Hour))
+
+	decayed := RewardDefinition{Name: "onboarding", EventType: "onboarding_completed", Reward: 1, HalfLife: 24 * time.Hour}
+	assert.Equal(t, 1.0, decayed.ShapedReward(0))
+	assert.InDelta(t, 0.5, decayed.ShapedReward(24*time.Hour), 1e-9)
+	assert.InDelta(t, 0.25, decayed.ShapedReward(48*time.Hour), 1e-9)
+}
+
+func TestValidateRewardDefinitions(t *testing.T) {
+	valid := RewardDefinition{Name: "trial", EventType: "trial_started", Reward: 1}
+	require.NoError(t, ValidateRewardDefinitions(nil))
+	require.NoError(t, ValidateRewardDefinitions([]RewardDefinition{valid}))
+
+	for name, definitions := range map[string][]RewardDefinition{
+		"duplicate name":     {valid, valid},
+		"missing event type": {{Name: "trial", Reward: 1}},
+		"zero reward":        {{Name: "trial", EventType: "trial_started"}},
+		"negative half-life": {{Name: "trial", EventType: "trial_started", Reward: 1, HalfLife: -time.Hour}},
+	} {
+		assert.Error(t, ValidateRewardDefinitions(definitions), name)
+	}
+}
+
+func TestAdvancedBanditEngine_ApplyRewardDefinitions(t *testing.T) {
+	experimentID, armID, userID := uuid.New(), uuid.New(), uuid.New()
+	assignedAt := time.Now().UTC().Add(-48 * time.Hour)
+	repo := &rewardShapingTestRepo{
+		experimentIDs: []uuid.UUID{experimentID},
+		assignment:    &Assignment{ExperimentID: experimentID, UserID: userID, ArmID: armID, AssignedAt: assignedAt},
+		claimed:       make(map[uuid.UUID]bool),
+	}
+	cache := &advancedEngineTestCache{}
+	base := NewThompsonSamplingBandit(repo, cache, zap.NewNop())
+	engine := NewAdvancedBanditEngine(base, repo, cache, nil, nil, zap.NewNop(), &EngineConfig{})
+
+	config, err := engine.SetRewardDefinitions(context.Background(), experimentID, []RewardDefinition{
+		{Name: "trial", EventType: "trial_started", Reward: 1, HalfLife: 24 * time.Hour},
+		{Name: "onboarding", EventType: "onboarding_completed", Reward: 0.2},
+	})
+	require.NoError(t, err)
+	require.Len(t, config.RewardDefinitions, 2)
+
+	recorded, err := engine.ApplyRewardDefinitions(context.Background(), userID, "trial_started", assignedAt.Add(48*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 1, recorded)
+	require.Len(t, repo.events, 1)
+	assert.Equal(t, armID, repo.events[0].ArmID)
+	assert.InDelta(t, 0.25, repo.events[0].NormalizedRewardValue, 1e-9)
+	assert.Equal(t, "trial", repo.events[0].Metadata["reward_definition"])
+
+	// Each definition rewards a user once per experiment
+	recorded, err = engine.ApplyRewardDefinitions(context.Background(), userID, "trial_started", assignedAt.Add(50*time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, recorded)
+
+	// Events from before the assignment belong to no arm
+	recorded, err = engine.ApplyRewardDefinitions(context.Background(), userID, "onboarding_completed", assignedAt.Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, recorded)
+	assert.Len(t, repo.events, 1)
+
+	_, err = engine.SetRewardDefinitions(context.Background(), experimentID, []RewardDefinition{{Name: "bad", EventType: "x"}})
+	require.Error(t, err)
+}
@@ -0,0 +1,271 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+// segmentPageSize is how many users materialization and fan-out read at a time
+const segmentPageSize = 500
+
+type lifecycleStageLookup interface {
+	Stage(ctx context.Context, userID uuid.UUID) (entity.LifecycleStage, error)
+}
+
+// SegmentAttributesFor builds the attributes a segment predicate sees for a
+// user. country is only known at request time and is omitted when empty.
+func SegmentAttributesFor(user *entity.User, subs []*entity.Subscription, country string, now time.Time) entity.SegmentAttributes {
+	attrs := entity.SegmentAttributes{
+		entity.SegmentFieldPlatform:         string(user.Platform),
+		entity.SegmentFieldAppVersion:       user.AppVersion,
+		entity.SegmentFieldLTV:              user.LTV,
+		entity.SegmentFieldLTVBucket:        entity.LTVBucketFor(user.LTV),
+		entity.SegmentFieldDaysSinceInstall: float64(int(now.Sub(user.CreatedAt).Hours() / 24)),
+		entity.SegmentFieldSessionCount:     float64(user.SessionCount),
+	}
+	if country != "" {
+		attrs[entity.SegmentFieldCountry] = country
+	}
+	if user.PurchaseChannel != nil {
+		attrs[entity.SegmentFieldPurchaseChannel] = *user.PurchaseChannel
+	}
+
+	ent := ResolveEntitlement(subs, now)
+	attrs[entity.SegmentFieldHasActiveSubscription] = ent.HasAccess()
+	// Subscription attributes describe the entitlement, or the most recent
+	// subscription when the user no longer has access
+	current := ent.Primary
+	if current == nil {
+		for _, sub := range subs {
+			if sub.DeletedAt == nil && (current == nil || sub.CreatedAt.After(current.CreatedAt)) {
+				current = sub
+			}
+		}
+	}
+	if current != nil {
+		attrs[entity.SegmentFieldSubscriptionStatus] = string(current.Status)
+		attrs[entity.SegmentFieldSubscriptionPlan] = string(current.PlanType)
+		attrs[entity.SegmentFieldSubscriptionProductID] = current.ProductID
+		attrs[entity.SegmentFieldAutoRenew] = current.AutoRenew
+	}
+	return attrs
+}
+
+// SegmentService evaluates segments and materializes large ones
+type SegmentService struct {
+	repo      repository.SegmentRepository
+	userRepo  repository.UserRepository
+	subRepo   repository.SubscriptionRepository
+	churnRisk churnRiskPredictor
+	lifecycle lifecycleStageLookup
+	logger    *zap.Logger
+	now       func() time.Time
+}
+
+// NewSegmentService creates a new segment service
+func NewSegmentService(repo repository.SegmentRepository, userRepo repository.UserRepository, subRepo repository.SubscriptionRepository, logger *zap.Logger) *SegmentService {
+	return &SegmentService{
+		repo:     repo,
+		userRepo: userRepo,
+		subRepo:  subRepo,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// WithChurnRisk adds the churn_risk attribute; without it the attribute is unknown
+func (s *SegmentService) WithChurnRisk(predictor churnRiskPredictor) *SegmentService {
+	s.churnRisk = predictor
+	return s
+}
+
+// WithLifecycleStages adds the lifecycle_stage attribute; without it, or
+// before the user was first classified, the attribute is unknown
+func (s *SegmentService) WithLifecycleStages(lifecycle lifecycleStageLookup) *SegmentService {
+	s.lifecycle = lifecycle
+	return s
+}
+
+// Attributes loads a user's segment attributes
+func (s *SegmentService) Attributes(ctx context.Context, userID uuid.UUID, country string) (entity.SegmentAttributes, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return s.attributesFor(ctx, user, country)
+}
+
+func (s *SegmentService) attributesFor(ctx context.Context, user *entity.User, country string) (entity.SegmentAttributes, error) {
+	subs, err := s.subRepo.GetByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscriptions: %w", err)
+	}
+	attrs := SegmentAttributesFor(user, subs, country, s.now())
+	if s.churnRisk != nil {
+		if risk, err := s.churnRisk.PredictChurnRisk(ctx, user.ID); err == nil {
+			attrs[entity.SegmentFieldChurnRisk] = risk
+		}
+	}
+	if s.lifecycle != nil {
+		if stage, err := s.lifecycle.Stage(ctx, user.ID); err == nil && stage != "" {
+			attrs[entity.SegmentFieldLifecycleStage] = string(stage)
+		}
+	}
+	return attrs, nil
+}
+
+// Contains reports whether a user belongs to the segment. Materialized
+// segments answer from the last materialization.
+func (s *SegmentService) Contains(ctx context.Context, segment *entity.Segment, userID uuid.UUID, country string) (bool, error) {
+	if segment.Materialized {
+		return s.repo.IsMember(ctx, segment.ID, userID)
+	}
+	attrs, err := s.Attributes(ctx, userID, country)
+	if err != nil {
+		return false, err
+	}
+	return segment.Definition.Matches(attrs), nil
+}
+
+// IsMember reports whether a user belongs to an app's segment
+func (s *SegmentService) IsMember(ctx context.Context, appID, segmentID, userID uuid.UUID, country string) (bool, error) {
+	segment, err := s.repo.GetByID(ctx, appID, segmentID)
+	if err != nil {
+		return false, err
+	}
+	return s.Contains(ctx, segment, userID, country)
+}
+
+// AllowsExperiment reports whether a user may be assigned to an experiment:
+// always when it targets no segment, otherwise only segment members
+func (s *SegmentService) AllowsExperiment(ctx context.Context, experimentID, userID uuid.UUID, country string) (bool, error) {
+	segment, err := s.repo.GetExperimentSegment(ctx, experimentID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get experiment segment: %w", err)
+	}
+	if segment == nil {
+		return true, nil
+	}
+	return s.Contains(ctx, segment, userID, country)
+}
+
+// Materialize evaluates the segment over every user of its app and stores the
+// membership. Returns the number of members.
+func (s *SegmentService) Materialize(ctx context.Context, segment *entity.Segment) (int, error) {
+	startedAt := s.now()
+	var members []uuid.UUID
+	err := s.eachAppUser(ctx, segment.AppID, func(user *entity.User) error {
+		attrs, err := s.attributesFor(ctx, user, "")
+		if err != nil {
+			return err
+		}
+		if segment.Definition.Matches(attrs) {
+			members = append(members, user.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if err := s.repo.ReplaceMembers(ctx, segment.ID, members, startedAt); err != nil {
+		return 0, fmt.Errorf("failed to store segment members: %w", err)
+	}
+	s.logger.Info("Segment materialized",
+		zap.String("segment_id", segment.ID.String()),
+		zap.Int("members", len(members)),
+		zap.Duration("took", s.now().Sub(startedAt)),
+	)
+	return len(members), nil
+}
+
+// MaterializeAll refreshes every materialized segment. A failing segment is
+// logged and skipped so one bad definition does not block the rest.
+func (s *SegmentService) MaterializeAll(ctx context.Context) (int, error) {
+	segments, err := s.repo.ListMaterialized(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list materialized segments: %w", err)
+	}
+	refreshed := 0
+	for _, segment := range segments {
+		if _, err := s.Materialize(ctx, segment); err != nil {
+			s.logger.Error("Failed to materialize segment", zap.String("segment_id", segment.ID.String()), zap.Error(err))
+			continue
+		}
+		refreshed++
+	}
+	return refreshed, nil
+}
+
+// ForEachMember calls visit for every member of the segment and returns how
+// many were visited
+func (s *SegmentService) ForEachMember(ctx context.Context, segment *entity.Segment, visit func(*entity.User) error) (int, error) {
+	visited := 0
+	if !segment.Materialized {
+		err := s.eachAppUser(ctx, segment.AppID, func(user *entity.User) error {
+			attrs, err := s.attributesFor(ctx, user, "")
+			if err != nil {
+				return err
+			}
+			if !segment.Definition.Matches(attrs) {
+				return nil
+			}
+			visited++
+			return visit(user)
+		})
+		return visited, err
+	}
+
+	after := uuid.Nil
+	for {
+		ids, err := s.repo.ListMemberIDs(ctx, segment.ID, after, segmentPageSize)
+		if err != nil {
+			return visited, fmt.Errorf("failed to list segment members: %w", err)
+		}
+		for _, id := range ids {
+			user, err := s.userRepo.GetByID(ctx, id)
+			if err != nil {
+				s.logger.Warn("Skipping segment member", zap.String("user_id", id.String()), zap.Error(err))
+				continue
+			}
+			visited++
+			if err := visit(user); err != nil {
+				return visited, err
+			}
+		}
+		if len(ids) < segmentPageSize {
+			return visited, nil
+		}
+		after = ids[len(ids)-1]
+	}
+}
+
+func (s *SegmentService) eachAppUser(ctx context.Context, appID uuid.UUID, visit func(*entity.User) error) error {
+	after := uuid.Nil
+	for {
+		ids, err := s.repo.ListAppUserIDs(ctx, appID, after, segmentPageSize)
+		if err != nil {
+			return fmt.Errorf("failed to list app users: %w", err)
+		}
+		for _, id := range ids {
+			user, err := s.userRepo.GetByID(ctx, id)
+			if err != nil {
+				s.logger.Warn("Skipping user during segment evaluation", zap.String("user_id", id.String()), zap.Error(err))
+				continue
+			}
+			if err := visit(user); err != nil {
+				return err
+			}
+		}
+		if len(ids) < segmentPageSize {
+			return nil
+		}
+		after = ids[len(ids)-1]
+	}
+}
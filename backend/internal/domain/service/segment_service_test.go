@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+type stubSegmentRepo struct {
+	repository.SegmentRepository
+	segments   map[uuid.UUID]*entity.Segment
+	experiment map[uuid.UUID]*entity.Segment
+	userIDs    []uuid.UUID
+	members    []uuid.UUID
+}
+
+func (s *stubSegmentRepo) GetByID(ctx context.Context, appID, id uuid.UUID) (*entity.Segment, error) {
+	if seg, ok := s.segments[id]; ok && seg.AppID == appID {
+		return seg, nil
+	}
+	return nil, domainErrors.ErrNotFound
+}
+
+func (s *stubSegmentRepo) GetExperimentSegment(ctx context.Context, experimentID uuid.UUID) (*entity.Segment, error) {
+	return s.experiment[experimentID], nil
+}
+
+func (s *stubSegmentRepo) ListAppUserIDs(ctx context.Context, appID, after uuid.UUID, limit int) ([]uuid.UUID, error) {
+	if after != uuid.Nil {
+		return nil, nil
+	}
+	return s.userIDs, nil
+}
+
+func (s *stubSegmentRepo) ReplaceMembers(ctx context.Context, segmentID uuid.UUID, userIDs []uuid.UUID, at time.Time) error {
+	s.members = userIDs
+	return nil
+}
+
+type stubSegmentUsers struct {
+	repository.UserRepository
+	users map[uuid.UUID]*entity.User
+}
+
+func (s *stubSegmentUsers) GetByID(ctx context.Context, id uuid.UUID) (*entity.User, error) {
+	if u, ok := s.users[id]; ok {
+		return u, nil
+	}
+	return nil, domainErrors.ErrUserNotFound
+}
+
+type stubSegmentSubs struct {
+	repository.SubscriptionRepository
+	subs map[uuid.UUID][]*entity.Subscription
+}
+
+func (s *stubSegmentSubs) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*entity.Subscription, error) {
+	return s.subs[userID], nil
+}
+
+func segmentDefinition(t *testing.T, raw string) entity.SegmentPredicate {
+	t.Helper()
+	var p entity.SegmentPredicate
+	require.NoError(t, json.Unmarshal([]byte(raw), &p))
+	require.NoError(t, p.Validate())
+	return p
+}
+
+func newSegmentFixture(t *testing.T) (*SegmentService, *stubSegmentRepo, *entity.Segment, *entity.User, *entity.User) {
+	appID := uuid.New()
+	subscriber := entity.NewUser("p-1", "d-1", entity.PlatformiOS, "2.0", "", appID)
+	free := entity.NewUser("p-2", "d-2", entity.PlatformiOS, "2.0", "", appID)
+	sub := entity.NewSubscription(subscriber.ID, entity.SourceIAP, "ios", "com.app.premium", entity.PlanAnnual, time.Now().Add(30*24*time.Hour))
+
+	segment := entity.NewSegment(appID, "ios annual subscribers", segmentDefinition(t, `{"all":[
+		{"field":"platform","op":"eq","value":"ios"},
+		{"field":"has_active_subscription","op":"eq","value":true},
+		{"field":"subscription_plan","op":"eq","value":"annual"}
+	]}`))
+	repo := &stubSegmentRepo{
+		segments: map[uuid.UUID]*entity.Segment{segment.ID: segment},
+		userIDs:  []uuid.UUID{subscriber.ID, free.ID},
+	}
+	svc := NewSegmentService(repo,
+		&stubSegmentUsers{users: map[uuid.UUID]*entity.User{subscriber.ID: subscriber, free.ID: free}},
+		&stubSegmentSubs{subs: map[uuid.UUID][]*entity.Subscription{subscriber.ID: {sub}}},
+		zap.NewNop())
+	return svc, repo, segment, subscriber, free
+}
+
+func TestSegmentService_MaterializeStoresMatchingUsers(t *testing.T) {
+	svc, repo, segment, subscriber, _ := newSegmentFixture(t)
+	segment.Materialized = true
+
+	count, err := svc.Materialize(context.Background(), segment)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, []uuid.UUID{subscriber.ID}, repo.members)
+}
+
+func TestSegmentService_AllowsExperimentOnlyForMembers(t *testing.T) {
+	svc, repo, segment, subscriber, free := newSegmentFixture(t)
+	targeted, open := uuid.New(), uuid.New()
+	repo.experiment = map[uuid.UUID]*entity.Segment{targeted: segment}
+	ctx := context.Background()
+
+	allowed, err := svc.AllowsExperiment(ctx, targeted, subscriber.ID, "")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	allowed, err = svc.AllowsExperiment(ctx, targeted, free.ID, "")
+	require.NoError(t, err)
+	assert.False(t, allowed)
+
+	allowed, err = svc.AllowsExperiment(ctx, open, free.ID, "")
+	require.NoError(t, err)
+	assert.True(t, allowed, "experiments without a segment are open to everyone")
+}
+
+func TestPaywallRuleService_SegmentCondition(t *testing.T) {
+	segments, _, segment, subscriber, free := newSegmentFixture(t)
+	appID := segment.AppID
+	members := entity.NewPaywallRule(appID, "subscribers upsell", "", 10)
+	members.Conditions.SegmentID = &segment.ID
+	members.OfferCode = "UPGRADE"
+	fallback := entity.NewPaywallRule(appID, "default", "", 0)
+	fallback.OfferCode = "INTRO"
+
+	users := &stubPaywallUserRepo{}
+	svc := NewPaywallRuleService(&stubPaywallRuleRepo{rules: []*entity.PaywallRule{members, fallback}}, users, zap.NewNop()).
+		WithSegments(segments)
+
+	users.user = subscriber
+	decision, err := svc.Decide(context.Background(), appID, subscriber.ID, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, "UPGRADE", decision.OfferCode)
+	assert.Equal(t, []uuid.UUID{segment.ID}, decision.Audience.Segments)
+
+	users.user = free
+	decision, err = svc.Decide(context.Background(), appID, free.ID, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, "INTRO", decision.OfferCode)
+}
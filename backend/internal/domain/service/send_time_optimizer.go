@@ -0,0 +1,381 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+// What credits a send-time arm
+const (
+	SendTimeRewardOpen       = "open"
+	SendTimeRewardConversion = "conversion"
+)
+
+// SendTimeExperimentCategory tags send-time experiments in ab_tests
+const SendTimeExperimentCategory = "notification_send_time"
+
+const (
+	DefaultSendTimeRewardWindow = 24 * time.Hour
+	MaxSendTimeRewardWindow     = 7 * 24 * time.Hour
+)
+
+var (
+	ErrInvalidSendTimeExperiment  = errors.New("invalid send-time experiment")
+	ErrSendTimeExperimentNotFound = errors.New("send-time experiment not found")
+	ErrSendTimeDeliveryNotFound   = errors.New("send-time delivery not found")
+)
+
+// SendTimeExperimentRequest creates a bandit whose arms are the given hours of
+// the day. A delivery is rewarded when its push is opened, or leads to a
+// purchase, within RewardWindow of being sent.
+type SendTimeExperimentRequest struct {
+	AppID        uuid.UUID
+	SegmentID    *uuid.UUID
+	Name         string
+	Hours        []int
+	RewardEvent  string
+	RewardWindow time.Duration
+}
+
+func (r *SendTimeExperimentRequest) Validate() error {
+	if r.AppID == uuid.Nil {
+		return fmt.Errorf("%w: app is required", ErrInvalidSendTimeExperiment)
+	}
+	if r.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidSendTimeExperiment)
+	}
+	if len(r.Hours) < 2 {
+		return fmt.Errorf("%w: at least two hours are required", ErrInvalidSendTimeExperiment)
+	}
+	seen := make(map[int]bool, len(r.Hours))
+	for _, hour := range r.Hours {
+		if hour < 0 || hour > 23 {
+			return fmt.Errorf("%w: hour %d is not 0-23", ErrInvalidSendTimeExperiment, hour)
+		}
+		if seen[hour] {
+			return fmt.Errorf("%w: hour %d is repeated", ErrInvalidSendTimeExperiment, hour)
+		}
+		seen[hour] = true
+	}
+	if r.RewardEvent != SendTimeRewardOpen && r.RewardEvent != SendTimeRewardConversion {
+		return fmt.Errorf("%w: reward event must be %q or %q", ErrInvalidSendTimeExperiment, SendTimeRewardOpen, SendTimeRewardConversion)
+	}
+	if r.RewardWindow < time.Hour || r.RewardWindow > MaxSendTimeRewardWindow || r.RewardWindow%time.Hour != 0 {
+		return fmt.Errorf("%w: reward window must be whole hours between 1 and %d", ErrInvalidSendTimeExperiment, int(MaxSendTimeRewardWindow/time.Hour))
+	}
+	return nil
+}
+
+// SendTimeArm is the hour of the day an arm sends at, local to the recipient
+type SendTimeArm struct {
+	ArmID uuid.UUID `json:"arm_id"`
+	Hour  int       `json:"hour"`
+}
+
+type SendTimeExperiment struct {
+	ID                uuid.UUID     `json:"id"`
+	AppID             uuid.UUID     `json:"app_id"`
+	SegmentID         *uuid.UUID    `json:"segment_id,omitempty"`
+	Name              string        `json:"name"`
+	Status            string        `json:"status"`
+	RewardEvent       string        `json:"reward_event"`
+	RewardWindowHours int           `json:"reward_window_hours"`
+	Arms              []SendTimeArm `json:"arms"`
+	CreatedAt         time.Time     `json:"created_at"`
+}
+
+func (e *SendTimeExperiment) RewardWindow() time.Duration {
+	return time.Duration(e.RewardWindowHours) * time.Hour
+}
+
+// SendTimeDelivery is one push scheduled at its arm's hour. Its pending reward
+// expires RewardWindow after ScheduledFor.
+type SendTimeDelivery struct {
+	ID              uuid.UUID  `json:"id"`
+	ExperimentID    uuid.UUID  `json:"experiment_id"`
+	ArmID           uuid.UUID  `json:"arm_id"`
+	UserID          uuid.UUID  `json:"user_id"`
+	PendingRewardID uuid.UUID  `json:"pending_reward_id"`
+	RewardEvent     string     `json:"reward_event"`
+	SendHour        int        `json:"send_hour"`
+	Timezone        string     `json:"timezone"`
+	ScheduledFor    time.Time  `json:"scheduled_for"`
+	SentAt          *time.Time `json:"sent_at,omitempty"`
+	OpenedAt        *time.Time `json:"opened_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// SendTimeHourResult is how one hour arm has done so far
+type SendTimeHourResult struct {
+	Hour          int       `json:"hour"`
+	ArmID         uuid.UUID `json:"arm_id"`
+	Scheduled     int       `json:"scheduled"`
+	Sent          int       `json:"sent"`
+	Opened        int       `json:"opened"`
+	Rewarded      int       `json:"rewarded"`
+	Expired       int       `json:"expired"`
+	Alpha         float64   `json:"alpha"`
+	Beta          float64   `json:"beta"`
+	RewardRate    float64   `json:"reward_rate"`
+	PosteriorMean float64   `json:"posterior_mean"`
+}
+
+type SendTimeResults struct {
+	Experiment *SendTimeExperiment  `json:"experiment"`
+	Hours      []SendTimeHourResult `json:"hours"`
+	// BestHour has the highest posterior mean; nil before any reward is settled
+	BestHour *int `json:"best_hour,omitempty"`
+}
+
+type SendTimeRepository interface {
+	// CreateSendTimeExperiment stores the bandit experiment, one arm per hour
+	// and their hours, assigning the experiment and arm IDs
+	CreateSendTimeExperiment(ctx context.Context, experiment *SendTimeExperiment) error
+	// GetSendTimeExperiment returns nil when there is no such experiment
+	GetSendTimeExperiment(ctx context.Context, experimentID uuid.UUID) (*SendTimeExperiment, error)
+	CreateSendTimeDelivery(ctx context.Context, delivery *SendTimeDelivery) error
+	// GetSendTimeDelivery returns nil when there is no such delivery
+	GetSendTimeDelivery(ctx context.Context, deliveryID uuid.UUID) (*SendTimeDelivery, error)
+	MarkSendTimeDeliverySent(ctx context.Context, deliveryID uuid.UUID, sentAt time.Time) error
+	// RecordSendTimeOpen stamps the first open. For open-rewarded experiments
+	// it also credits the delivery's pending reward while it is unexpired,
+	// reporting whether it did.
+	RecordSendTimeOpen(ctx context.Context, deliveryID uuid.UUID, openedAt time.Time) (bool, error)
+	GetSendTimeResults(ctx context.Context, experimentID uuid.UUID) ([]SendTimeHourResult, error)
+}
+
+type sendTimeArmSelector interface {
+	SelectArmFrom(ctx context.Context, experimentID, userID uuid.UUID, candidates []uuid.UUID) (uuid.UUID, error)
+}
+
+type sendTimePendingRewards interface {
+	RecordPendingReward(ctx context.Context, experimentID, armID, userID uuid.UUID, window time.Duration) (*PendingReward, error)
+}
+
+type sendTimePreferences interface {
+	Get(ctx context.Context, appID, userID uuid.UUID) (*entity.NotificationPreferences, error)
+}
+
+// SendTimeOptimizer learns which hour of the day a segment responds to
+// pushes at. Each scheduled push records a pending reward through the delayed
+// reward strategy; an open or a purchase within the window credits the hour,
+// and the pending reward expiry sweep counts the rest as misses.
+type SendTimeOptimizer struct {
+	repo     SendTimeRepository
+	selector sendTimeArmSelector
+	rewards  sendTimePendingRewards
+	prefs    sendTimePreferences
+	now      func() time.Time
+}
+
+func NewSendTimeOptimizer(repo SendTimeRepository, selector sendTimeArmSelector, rewards sendTimePendingRewards) *SendTimeOptimizer {
+	return &SendTimeOptimizer{repo: repo, selector: selector, rewards: rewards, now: time.Now}
+}
+
+// WithPreferences schedules in each user's quiet-hours timezone and skips
+// hours that fall inside their quiet hours
+func (s *SendTimeOptimizer) WithPreferences(prefs sendTimePreferences) *SendTimeOptimizer {
+	s.prefs = prefs
+	return s
+}
+
+func (s *SendTimeOptimizer) CreateExperiment(ctx context.Context, req SendTimeExperimentRequest) (*SendTimeExperiment, error) {
+	if req.RewardWindow == 0 {
+		req.RewardWindow = DefaultSendTimeRewardWindow
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	hours := append([]int(nil), req.Hours...)
+	sort.Ints(hours)
+	experiment := &SendTimeExperiment{
+		AppID:             req.AppID,
+		SegmentID:         req.SegmentID,
+		Name:              req.Name,
+		RewardEvent:       req.RewardEvent,
+		RewardWindowHours: int(req.RewardWindow / time.Hour),
+		Arms:              make([]SendTimeArm, len(hours)),
+	}
+	for i, hour := range hours {
+		experiment.Arms[i] = SendTimeArm{Hour: hour}
+	}
+	if err := s.repo.CreateSendTimeExperiment(ctx, experiment); err != nil {
+		return nil, fmt.Errorf("failed to create send-time experiment: %w", err)
+	}
+	return experiment, nil
+}
+
+// GetExperiment returns the experiment when it belongs to appID
+func (s *SendTimeOptimizer) GetExperiment(ctx context.Context, appID, experimentID uuid.UUID) (*SendTimeExperiment, error) {
+	experiment, err := s.repo.GetSendTimeExperiment(ctx, experimentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get send-time experiment: %w", err)
+	}
+	if experiment == nil || experiment.AppID != appID {
+		return nil, ErrSendTimeExperimentNotFound
+	}
+	return experiment, nil
+}
+
+// Schedule picks the hour to push to userID at and records the delivery and
+// its pending reward. The caller sends the push at ScheduledFor.
+func (s *SendTimeOptimizer) Schedule(ctx context.Context, appID, experimentID, userID uuid.UUID) (*SendTimeDelivery, error) {
+	experiment, err := s.GetExperiment(ctx, appID, experimentID)
+	if err != nil {
+		return nil, err
+	}
+	if len(experiment.Arms) == 0 {
+		return nil, fmt.Errorf("%w: experiment has no hours", ErrInvalidSendTimeExperiment)
+	}
+
+	now := s.now()
+	loc, quiet, err := s.userSchedule(ctx, appID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	slots := make(map[uuid.UUID]SendTimeArm, len(experiment.Arms))
+	candidates := make([]uuid.UUID, 0, len(experiment.Arms))
+	for _, arm := range experiment.Arms {
+		slots[arm.ArmID] = arm
+		if quiet == nil || !quiet.Contains(nextSendAt(now, arm.Hour, loc)) {
+			candidates = append(candidates, arm.ArmID)
+		}
+	}
+	// Every hour being quiet leaves the choice to the bandit; the push is
+	// then held back by the preference gate like any other
+	armID, err := s.selector.SelectArmFrom(ctx, experimentID, userID, candidates)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select send hour: %w", err)
+	}
+	arm, ok := slots[armID]
+	if !ok {
+		return nil, fmt.Errorf("selected arm %s is not a send-time hour", armID)
+	}
+
+	scheduledFor := nextSendAt(now, arm.Hour, loc)
+	pending, err := s.rewards.RecordPendingReward(ctx, experimentID, armID, userID, scheduledFor.Sub(now)+experiment.RewardWindow())
+	if err != nil {
+		return nil, fmt.Errorf("failed to record send-time pending reward: %w", err)
+	}
+
+	delivery := &SendTimeDelivery{
+		ID:              uuid.New(),
+		ExperimentID:    experimentID,
+		ArmID:           armID,
+		UserID:          userID,
+		PendingRewardID: pending.ID,
+		RewardEvent:     experiment.RewardEvent,
+		SendHour:        arm.Hour,
+		Timezone:        loc.String(),
+		ScheduledFor:    scheduledFor,
+		CreatedAt:       now,
+	}
+	if err := s.repo.CreateSendTimeDelivery(ctx, delivery); err != nil {
+		return nil, fmt.Errorf("failed to create send-time delivery: %w", err)
+	}
+	return delivery, nil
+}
+
+func (s *SendTimeOptimizer) GetDelivery(ctx context.Context, deliveryID uuid.UUID) (*SendTimeDelivery, error) {
+	delivery, err := s.repo.GetSendTimeDelivery(ctx, deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get send-time delivery: %w", err)
+	}
+	if delivery == nil {
+		return nil, ErrSendTimeDeliveryNotFound
+	}
+	return delivery, nil
+}
+
+func (s *SendTimeOptimizer) MarkSent(ctx context.Context, deliveryID uuid.UUID) error {
+	if err := s.repo.MarkSendTimeDeliverySent(ctx, deliveryID, s.now()); err != nil {
+		return fmt.Errorf("failed to mark send-time delivery sent: %w", err)
+	}
+	return nil
+}
+
+// RecordOpen records that userID opened the delivery's push, reporting
+// whether the open credited its hour
+func (s *SendTimeOptimizer) RecordOpen(ctx context.Context, userID, deliveryID uuid.UUID) (bool, error) {
+	delivery, err := s.GetDelivery(ctx, deliveryID)
+	if err != nil {
+		return false, err
+	}
+	if delivery.UserID != userID {
+		return false, ErrSendTimeDeliveryNotFound
+	}
+	credited, err := s.repo.RecordSendTimeOpen(ctx, deliveryID, s.now())
+	if err != nil {
+		return false, fmt.Errorf("failed to record send-time open: %w", err)
+	}
+	return credited, nil
+}
+
+func (s *SendTimeOptimizer) Results(ctx context.Context, appID, experimentID uuid.UUID) (*SendTimeResults, error) {
+	experiment, err := s.GetExperiment(ctx, appID, experimentID)
+	if err != nil {
+		return nil, err
+	}
+	hours, err := s.repo.GetSendTimeResults(ctx, experimentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get send-time results: %w", err)
+	}
+
+	results := &SendTimeResults{Experiment: experiment, Hours: hours}
+	best := -1.0
+	for i := range hours {
+		h := &hours[i]
+		if settled := h.Rewarded + h.Expired; settled > 0 {
+			h.RewardRate = float64(h.Rewarded) / float64(settled)
+		}
+		if h.Alpha+h.Beta > 0 {
+			h.PosteriorMean = h.Alpha / (h.Alpha + h.Beta)
+		}
+		if h.Rewarded+h.Expired > 0 && h.PosteriorMean > best {
+			best = h.PosteriorMean
+			hour := h.Hour
+			results.BestHour = &hour
+		}
+	}
+	return results, nil
+}
+
+// userSchedule resolves the timezone hours are read in, and the user's quiet
+// hours if any. Without preferences every user is on UTC.
+func (s *SendTimeOptimizer) userSchedule(ctx context.Context, appID, userID uuid.UUID) (*time.Location, *entity.QuietHours, error) {
+	if s.prefs == nil {
+		return time.UTC, nil, nil
+	}
+	prefs, err := s.prefs.Get(ctx, appID, userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get notification preferences: %w", err)
+	}
+	if prefs == nil || prefs.QuietHours == nil {
+		return time.UTC, nil, nil
+	}
+	loc, err := time.LoadLocation(prefs.QuietHours.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	return loc, prefs.QuietHours, nil
+}
+
+// nextSendAt is the next time at or after now that the clock in loc reads
+// hour:00
+func nextSendAt(now time.Time, hour int, loc *time.Location) time.Time {
+	local := now.In(loc)
+	at := time.Date(local.Year(), local.Month(), local.Day(), hour, 0, 0, 0, loc)
+	if at.Before(now) {
+		at = time.Date(local.Year(), local.Month(), local.Day()+1, hour, 0, 0, 0, loc)
+	}
+	return at.UTC()
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+type sendTimeTestRepo struct {
+	experiments map[uuid.UUID]*SendTimeExperiment
+	deliveries  map[uuid.UUID]*SendTimeDelivery
+	results     []SendTimeHourResult
+	opened      []uuid.UUID
+}
+
+func newSendTimeTestRepo() *sendTimeTestRepo {
+	return &sendTimeTestRepo{experiments: map[uuid.UUID]*SendTimeExperiment{}, deliveries: map[uuid.UUID]*SendTimeDelivery{}}
+}
+
+func (r *sendTimeTestRepo) CreateSendTimeExperiment(ctx context.Context, experiment *SendTimeExperiment) error {
+	experiment.ID = uuid.New()
+	for i := range experiment.Arms {
+		experiment.Arms[i].ArmID = uuid.New()
+	}
+	r.experiments[experiment.ID] = experiment
+	return nil
+}
+
+func (r *sendTimeTestRepo) GetSendTimeExperiment(ctx context.Context, experimentID uuid.UUID) (*SendTimeExperiment, error) {
+	return r.experiments[experimentID], nil
+}
+
+func (r *sendTimeTestRepo) CreateSendTimeDelivery(ctx context.Context, delivery *SendTimeDelivery) error {
+	r.deliveries[delivery.ID] = delivery
+	return nil
+}
+
+func (r *sendTimeTestRepo) GetSendTimeDelivery(ctx context.Context, deliveryID uuid.UUID) (*SendTimeDelivery, error) {
+	return r.deliveries[deliveryID], nil
+}
+
+func (r *sendTimeTestRepo) MarkSendTimeDeliverySent(ctx context.Context, deliveryID uuid.UUID, sentAt time.Time) error {
+	return nil
+}
+
+func (r *sendTimeTestRepo) RecordSendTimeOpen(ctx context.Context, deliveryID uuid.UUID, openedAt time.Time) (bool, error) {
+	r.opened = append(r.opened, deliveryID)
+	return true, nil
+}
+
+func (r *sendTimeTestRepo) GetSendTimeResults(ctx context.Context, experimentID uuid.UUID) ([]SendTimeHourResult, error) {
+	return r.results, nil
+}
+
+// sendTimeTestSelector picks the first candidate and records the set offered
+type sendTimeTestSelector struct {
+	candidates []uuid.UUID
+}
+
+func (s *sendTimeTestSelector) SelectArmFrom(ctx context.Context, experimentID, userID uuid.UUID, candidates []uuid.UUID) (uuid.UUID, error) {
+	s.candidates = candidates
+	return candidates[0], nil
+}
+
+type sendTimeTestRewards struct {
+	window time.Duration
+}
+
+func (r *sendTimeTestRewards) RecordPendingReward(ctx context.Context, experimentID, armID, userID uuid.UUID, window time.Duration) (*PendingReward, error) {
+	r.window = window
+	return &PendingReward{ID: uuid.New(), ExperimentID: experimentID, ArmID: armID, UserID: userID}, nil
+}
+
+type sendTimeTestPrefs struct {
+	prefs *entity.NotificationPreferences
+}
+
+func (p *sendTimeTestPrefs) Get(ctx context.Context, appID, userID uuid.UUID) (*entity.NotificationPreferences, error) {
+	return p.prefs, nil
+}
+
+func newTestSendTimeExperiment(t *testing.T, svc *SendTimeOptimizer, appID uuid.UUID, hours ...int) *SendTimeExperiment {
+	t.Helper()
+	experiment, err := svc.CreateExperiment(context.Background(), SendTimeExperimentRequest{
+		AppID:       appID,
+		Name:        "Re-engagement push",
+		Hours:       hours,
+		RewardEvent: SendTimeRewardOpen,
+	})
+	require.NoError(t, err)
+	return experiment
+}
+
+func TestSendTimeExperimentRequest_Validate(t *testing.T) {
+	valid := SendTimeExperimentRequest{AppID: uuid.New(), Name: "n", Hours: []int{9, 18}, RewardEvent: SendTimeRewardOpen, RewardWindow: 24 * time.Hour}
+	require.NoError(t, valid.Validate())
+
+	for name, mutate := range map[string]func(*SendTimeExperimentRequest){
+		"one hour":          func(r *SendTimeExperimentRequest) { r.Hours = []int{9} },
+		"hour out of range": func(r *SendTimeExperimentRequest) { r.Hours = []int{9, 24} },
+		"repeated hour":     func(r *SendTimeExperimentRequest) { r.Hours = []int{9, 9} },
+		"unknown reward":    func(r *SendTimeExperimentRequest) { r.RewardEvent = "click" },
+		"window too long":   func(r *SendTimeExperimentRequest) { r.RewardWindow = 8 * 24 * time.Hour },
+		"partial hours":     func(r *SendTimeExperimentRequest) { r.RewardWindow = 90 * time.Minute },
+	} {
+		req := valid
+		mutate(&req)
+		assert.ErrorIs(t, req.Validate(), ErrInvalidSendTimeExperiment, name)
+	}
+}
+
+func TestSendTimeOptimizer_ScheduleAtNextLocalHour(t *testing.T) {
+	repo := newSendTimeTestRepo()
+	selector := &sendTimeTestSelector{}
+	rewards := &sendTimeTestRewards{}
+	svc := NewSendTimeOptimizer(repo, selector, rewards)
+	svc.now = func() time.Time { return time.Date(2026, 3, 10, 20, 30, 0, 0, time.UTC) }
+	appID, userID := uuid.New(), uuid.New()
+	experiment := newTestSendTimeExperiment(t, svc, appID, 18, 9)
+
+	delivery, err := svc.Schedule(context.Background(), appID, experiment.ID, userID)
+	require.NoError(t, err)
+
+	// Hours are stored sorted, so 09:00 is offered first and is tomorrow
+	assert.Equal(t, 9, delivery.SendHour)
+	assert.Equal(t, time.Date(2026, 3, 11, 9, 0, 0, 0, time.UTC), delivery.ScheduledFor)
+	assert.Equal(t, "UTC", delivery.Timezone)
+	assert.Equal(t, 12*time.Hour+30*time.Minute+DefaultSendTimeRewardWindow, rewards.window)
+	assert.Equal(t, SendTimeRewardOpen, delivery.RewardEvent)
+	assert.Same(t, delivery, repo.deliveries[delivery.ID])
+
+	_, err = svc.Schedule(context.Background(), uuid.New(), experiment.ID, userID)
+	assert.ErrorIs(t, err, ErrSendTimeExperimentNotFound)
+}
+
+func TestSendTimeOptimizer_ScheduleSkipsQuietHoursInUserTimezone(t *testing.T) {
+	repo := newSendTimeTestRepo()
+	selector := &sendTimeTestSelector{}
+	appID, userID := uuid.New(), uuid.New()
+	prefs := entity.DefaultNotificationPreferences(appID, userID)
+	prefs.QuietHours = &entity.QuietHours{StartMinute: 22 * 60, EndMinute: 8 * 60, Timezone: "Europe/Berlin"}
+	svc := NewSendTimeOptimizer(repo, selector, &sendTimeTestRewards{}).WithPreferences(&sendTimeTestPrefs{prefs: prefs})
+	svc.now = func() time.Time { return time.Date(2026, 7, 1, 12, 0, 0, 0, time.UTC) }
+	experiment := newTestSendTimeExperiment(t, svc, appID, 7, 19)
+
+	delivery, err := svc.Schedule(context.Background(), appID, experiment.ID, userID)
+	require.NoError(t, err)
+
+	require.Len(t, selector.candidates, 1)
+	assert.Equal(t, 19, delivery.SendHour)
+	assert.Equal(t, "Europe/Berlin", delivery.Timezone)
+	// 19:00 in Berlin summer time
+	assert.Equal(t, time.Date(2026, 7, 1, 17, 0, 0, 0, time.UTC), delivery.ScheduledFor)
+}
+
+func TestSendTimeOptimizer_RecordOpenOnlyForRecipient(t *testing.T) {
+	repo := newSendTimeTestRepo()
+	svc := NewSendTimeOptimizer(repo, &sendTimeTestSelector{}, &sendTimeTestRewards{})
+	appID, userID := uuid.New(), uuid.New()
+	experiment := newTestSendTimeExperiment(t, svc, appID, 9, 18)
+	delivery, err := svc.Schedule(context.Background(), appID, experiment.ID, userID)
+	require.NoError(t, err)
+
+	_, err = svc.RecordOpen(context.Background(), uuid.New(), delivery.ID)
+	assert.ErrorIs(t, err, ErrSendTimeDeliveryNotFound)
+	assert.Empty(t, repo.opened)
+
+	credited, err := svc.RecordOpen(context.Background(), userID, delivery.ID)
+	require.NoError(t, err)
+	assert.True(t, credited)
+	assert.Equal(t, []uuid.UUID{delivery.ID}, repo.opened)
+}
+
+func TestSendTimeOptimizer_ResultsPickSettledBestHour(t *testing.T) {
+	repo := newSendTimeTestRepo()
+	svc := NewSendTimeOptimizer(repo, &sendTimeTestSelector{}, &sendTimeTestRewards{})
+	appID := uuid.New()
+	experiment := newTestSendTimeExperiment(t, svc, appID, 9, 18, 21)
+	repo.results = []SendTimeHourResult{
+		{Hour: 9, Rewarded: 2, Expired: 8, Alpha: 3, Beta: 9},
+		{Hour: 18, Rewarded: 6, Expired: 4, Alpha: 7, Beta: 5},
+		// Nothing settled yet, however good the prior looks
+		{Hour: 21, Scheduled: 3, Alpha: 1, Beta: 1},
+	}
+
+	results, err := svc.Results(context.Background(), appID, experiment.ID)
+	require.NoError(t, err)
+
+	require.NotNil(t, results.BestHour)
+	assert.Equal(t, 18, *results.BestHour)
+	assert.InDelta(t, 0.6, results.Hours[1].RewardRate, 1e-9)
+	assert.InDelta(t, 7.0/12.0, results.Hours[1].PosteriorMean, 1e-9)
+	assert.Zero(t, results.Hours[2].RewardRate)
+}
@@ -0,0 +1,424 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+// ErrSKANUnknownApp is returned for postbacks whose App Store ID is not set as
+// any app's app_store_id
+var ErrSKANUnknownApp = errors.New("no app has this App Store ID")
+
+// SKANConversionValue is the value a client should report to SKAdNetwork
+type SKANConversionValue struct {
+	SchemaVersion int                    `json:"schema_version"`
+	FineValue     int                    `json:"fine_value"`
+	CoarseValue   entity.SKANCoarseValue `json:"coarse_value"`
+	Revenue       float64                `json:"revenue"`
+	// WindowOpen is false once the user's first 24 hours are over and the
+	// value no longer changes
+	WindowOpen bool `json:"window_open"`
+}
+
+// SKANCampaignCohort is the install cohort a campaign brought in. Attributed
+// counts are lower bounds; Weighted sums match confidences.
+type SKANCampaignCohort struct {
+	AdNetworkID      string  `json:"ad_network_id"`
+	SourceIdentifier string  `json:"source_identifier"`
+	Postbacks        int64   `json:"postbacks"`
+	Attributed       int64   `json:"attributed"`
+	Weighted         float64 `json:"weighted"`
+	Converted        int64   `json:"converted"`
+	ConversionRate   float64 `json:"conversion_rate"`
+	TotalLTV         float64 `json:"total_ltv"`
+	AvgLTV           float64 `json:"avg_ltv"`
+}
+
+// skanCandidate is a signup a postback may have come from
+type skanCandidate struct {
+	UserID    uuid.UUID
+	CreatedAt time.Time
+	Revenue   float64
+}
+
+// SKANAttributionService ingests SKAdNetwork postbacks, manages conversion
+// value schemas and attributes installs to campaigns without device IDs
+type SKANAttributionService struct {
+	pool     *pgxpool.Pool
+	verifier *SKANSignatureVerifier
+	logger   *zap.Logger
+	now      func() time.Time
+}
+
+// NewSKANAttributionService creates a new SKAdNetwork attribution service
+func NewSKANAttributionService(pool *pgxpool.Pool, logger *zap.Logger) *SKANAttributionService {
+	return &SKANAttributionService{pool: pool, logger: logger, now: time.Now}
+}
+
+// WithSignatureVerifier rejects postbacks whose attribution signature does not
+// verify; without one postbacks are stored as unverified
+func (s *SKANAttributionService) WithSignatureVerifier(v *SKANSignatureVerifier) *SKANAttributionService {
+	s.verifier = v
+	return s
+}
+
+// RecordPostback verifies and stores a postback. Networks and Apple both
+// send copies, so a repeated transaction ID returns the stored postback with
+// created false.
+func (s *SKANAttributionService) RecordPostback(ctx context.Context, body []byte) (*entity.SKANPostback, bool, error) {
+	payload, err := ParseSKANPostback(body)
+	if err != nil {
+		return nil, false, err
+	}
+	postback := payload.Entity()
+	if s.verifier != nil {
+		if err := s.verifier.Verify(payload); err != nil {
+			return nil, false, err
+		}
+		postback.SignatureVerified = true
+	}
+
+	storeID := strconv.FormatInt(payload.AppID, 10)
+	if err := s.pool.QueryRow(ctx, `
+		SELECT id FROM apps WHERE settings->>'app_store_id' = $1 AND is_active
+	`, storeID).Scan(&postback.AppID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, false, fmt.Errorf("%w: %s", ErrSKANUnknownApp, storeID)
+		}
+		return nil, false, fmt.Errorf("failed to resolve app: %w", err)
+	}
+
+	var coarse *string
+	if postback.CoarseConversionValue != "" {
+		c := string(postback.CoarseConversionValue)
+		coarse = &c
+	}
+	err = s.pool.QueryRow(ctx, `
+		INSERT INTO skan_postbacks (app_id, transaction_id, skan_version, ad_network_id, source_identifier,
+		    source_app_id, source_domain, fidelity_type, redownload, did_win, postback_sequence_index,
+		    conversion_value, coarse_conversion_value, signature_verified, payload)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''), $8, $9, $10, $11, $12, $13, $14, $15)
+		ON CONFLICT (transaction_id) DO NOTHING
+		RETURNING id, received_at
+	`, postback.AppID, postback.TransactionID, postback.Version, postback.AdNetworkID, postback.SourceIdentifier,
+		postback.SourceAppID, postback.SourceDomain, postback.FidelityType, postback.Redownload, postback.DidWin,
+		postback.PostbackSequenceIndex, postback.ConversionValue, coarse, postback.SignatureVerified, body,
+	).Scan(&postback.ID, &postback.ReceivedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = s.pool.QueryRow(ctx, `
+			SELECT id, received_at FROM skan_postbacks WHERE transaction_id = $1
+		`, postback.TransactionID).Scan(&postback.ID, &postback.ReceivedAt)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to read postback: %w", err)
+		}
+		return postback, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to store postback: %w", err)
+	}
+	return postback, true, nil
+}
+
+// CreateSchema validates buckets and stores them as the app's next schema
+// version, which becomes current for new installs
+func (s *SKANAttributionService) CreateSchema(ctx context.Context, appID uuid.UUID, buckets []entity.SKANValueBucket, createdBy *uuid.UUID) (*entity.SKANConversionSchema, error) {
+	schema := &entity.SKANConversionSchema{AppID: appID, Buckets: buckets}
+	if err := schema.Validate(); err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(schema.Buckets)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode schema: %w", err)
+	}
+	// The unique (app_id, version) constraint rejects a concurrent create
+	err = s.pool.QueryRow(ctx, `
+		INSERT INTO skan_conversion_schemas (app_id, version, buckets, created_by)
+		SELECT $1, COALESCE(MAX(version), 0) + 1, $2, $3 FROM skan_conversion_schemas WHERE app_id = $1
+		RETURNING id, version, created_at
+	`, appID, data, createdBy).Scan(&schema.ID, &schema.Version, &schema.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create schema: %w", err)
+	}
+	return schema, nil
+}
+
+// ListSchemas returns every schema version of the app, newest first
+func (s *SKANAttributionService) ListSchemas(ctx context.Context, appID uuid.UUID) ([]*entity.SKANConversionSchema, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, app_id, version, buckets, created_at
+		FROM skan_conversion_schemas
+		WHERE app_id = $1
+		ORDER BY version DESC
+	`, appID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schemas: %w", err)
+	}
+	defer rows.Close()
+	schemas := []*entity.SKANConversionSchema{}
+	for rows.Next() {
+		var schema entity.SKANConversionSchema
+		var data []byte
+		if err := rows.Scan(&schema.ID, &schema.AppID, &schema.Version, &data, &schema.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan schema: %w", err)
+		}
+		if err := json.Unmarshal(data, &schema.Buckets); err != nil {
+			return nil, fmt.Errorf("failed to decode schema %d: %w", schema.Version, err)
+		}
+		schemas = append(schemas, &schema)
+	}
+	return schemas, rows.Err()
+}
+
+// ConversionValue computes the value the user's client should report from
+// what they paid in their first 24 hours. Nil means the app has no schema.
+func (s *SKANAttributionService) ConversionValue(ctx context.Context, appID, userID uuid.UUID) (*SKANConversionValue, error) {
+	schemas, err := s.ListSchemas(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+	if len(schemas) == 0 {
+		return nil, nil
+	}
+	var c skanCandidate
+	err = s.pool.QueryRow(ctx, `
+		SELECT u.id, u.created_at,
+		       COALESCE((SELECT SUM(t.amount) FROM transactions t
+		                 WHERE t.user_id = u.id AND t.status = 'success'
+		                   AND t.created_at < u.created_at + INTERVAL '24 hours'), 0)::float8
+		FROM users u
+		WHERE u.id = $1 AND u.app_id = $2
+	`, userID, appID).Scan(&c.UserID, &c.CreatedAt, &c.Revenue)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read first-day revenue: %w", err)
+	}
+	schema := schemaAt(schemas, c.CreatedAt)
+	bucket := schema.Bucket(c.Revenue)
+	return &SKANConversionValue{
+		SchemaVersion: schema.Version,
+		FineValue:     bucket.Value,
+		CoarseValue:   bucket.Coarse,
+		Revenue:       c.Revenue,
+		WindowOpen:    s.now().Before(c.CreatedAt.Add(24 * time.Hour)),
+	}, nil
+}
+
+// AttributeInstalls matches up to limit unprocessed first postbacks to
+// signups and returns how many matched. Each postback is matched in its own
+// transaction and processed once, matched or not.
+func (s *SKANAttributionService) AttributeInstalls(ctx context.Context, limit int) (int, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, app_id, skan_version, ad_network_id, COALESCE(source_identifier, ''),
+		       conversion_value, COALESCE(coarse_conversion_value, ''), received_at
+		FROM skan_postbacks
+		WHERE processed_at IS NULL AND postback_sequence_index = 0 AND did_win
+		ORDER BY received_at
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list postbacks: %w", err)
+	}
+	var postbacks []*entity.SKANPostback
+	for rows.Next() {
+		var p entity.SKANPostback
+		var coarse string
+		if err := rows.Scan(&p.ID, &p.AppID, &p.Version, &p.AdNetworkID, &p.SourceIdentifier,
+			&p.ConversionValue, &coarse, &p.ReceivedAt); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan postback: %w", err)
+		}
+		p.CoarseConversionValue = entity.SKANCoarseValue(coarse)
+		postbacks = append(postbacks, &p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to list postbacks: %w", err)
+	}
+
+	schemas := map[uuid.UUID][]*entity.SKANConversionSchema{}
+	matched := 0
+	for _, p := range postbacks {
+		appSchemas, ok := schemas[p.AppID]
+		if !ok {
+			if appSchemas, err = s.ListSchemas(ctx, p.AppID); err != nil {
+				return matched, err
+			}
+			schemas[p.AppID] = appSchemas
+		}
+		ok, err := s.attributePostback(ctx, p, appSchemas)
+		if err != nil {
+			return matched, err
+		}
+		if ok {
+			matched++
+		}
+	}
+	return matched, nil
+}
+
+func (s *SKANAttributionService) attributePostback(ctx context.Context, p *entity.SKANPostback, schemas []*entity.SKANConversionSchema) (bool, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Locks the postback so concurrent workers skip it
+	var locked uuid.UUID
+	if err := tx.QueryRow(ctx, `
+		SELECT id FROM skan_postbacks WHERE id = $1 AND processed_at IS NULL FOR UPDATE SKIP LOCKED
+	`, p.ID).Scan(&locked); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to lock postback: %w", err)
+	}
+
+	from, to := p.InstallWindow()
+	rows, err := tx.Query(ctx, `
+		SELECT u.id, u.created_at,
+		       COALESCE((SELECT SUM(t.amount) FROM transactions t
+		                 WHERE t.user_id = u.id AND t.status = 'success'
+		                   AND t.created_at < u.created_at + INTERVAL '24 hours'), 0)::float8
+		FROM users u
+		WHERE u.app_id = $1 AND u.platform = 'ios' AND u.deleted_at IS NULL
+		  AND u.created_at >= $2 AND u.created_at < $3
+		  AND NOT EXISTS (SELECT 1 FROM install_attributions ia WHERE ia.user_id = u.id)
+	`, p.AppID, from, to)
+	if err != nil {
+		return false, fmt.Errorf("failed to list candidate signups: %w", err)
+	}
+	var candidates []skanCandidate
+	for rows.Next() {
+		var c skanCandidate
+		if err := rows.Scan(&c.UserID, &c.CreatedAt, &c.Revenue); err != nil {
+			rows.Close()
+			return false, fmt.Errorf("failed to scan candidate signup: %w", err)
+		}
+		candidates = append(candidates, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return false, fmt.Errorf("failed to list candidate signups: %w", err)
+	}
+
+	best, confidence, ok := matchSKANPostback(p, schemas, candidates)
+	if ok {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO install_attributions (user_id, app_id, postback_id, ad_network_id, source_identifier, confidence)
+			VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6)
+		`, best.UserID, p.AppID, p.ID, p.AdNetworkID, p.SourceIdentifier, confidence); err != nil {
+			return false, fmt.Errorf("failed to record attribution: %w", err)
+		}
+	}
+	if _, err := tx.Exec(ctx, `UPDATE skan_postbacks SET processed_at = now() WHERE id = $1`, p.ID); err != nil {
+		return false, fmt.Errorf("failed to mark postback processed: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit attribution: %w", err)
+	}
+	return ok, nil
+}
+
+// CampaignCohorts reports the installs, conversions and LTV each campaign
+// brought in, by postbacks received in [from, to). LTV is weighted by match
+// confidence.
+func (s *SKANAttributionService) CampaignCohorts(ctx context.Context, appID uuid.UUID, from, to time.Time) ([]SKANCampaignCohort, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT p.ad_network_id, COALESCE(p.source_identifier, ''),
+		       COUNT(*),
+		       COUNT(ia.user_id),
+		       COALESCE(SUM(ia.confidence), 0)::float8,
+		       COUNT(ia.user_id) FILTER (WHERE EXISTS (SELECT 1 FROM subscriptions s WHERE s.user_id = ia.user_id)),
+		       COALESCE(SUM(u.ltv * ia.confidence), 0)::float8
+		FROM skan_postbacks p
+		LEFT JOIN install_attributions ia ON ia.postback_id = p.id
+		LEFT JOIN users u ON u.id = ia.user_id
+		WHERE p.app_id = $1 AND p.postback_sequence_index = 0 AND p.did_win
+		  AND p.received_at >= $2 AND p.received_at < $3
+		GROUP BY 1, 2
+		ORDER BY 3 DESC, 1, 2
+	`, appID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read campaign cohorts: %w", err)
+	}
+	defer rows.Close()
+	cohorts := []SKANCampaignCohort{}
+	for rows.Next() {
+		var c SKANCampaignCohort
+		if err := rows.Scan(&c.AdNetworkID, &c.SourceIdentifier, &c.Postbacks, &c.Attributed,
+			&c.Weighted, &c.Converted, &c.TotalLTV); err != nil {
+			return nil, fmt.Errorf("failed to scan campaign cohort: %w", err)
+		}
+		if c.Attributed > 0 {
+			c.ConversionRate = float64(c.Converted) / float64(c.Attributed)
+		}
+		if c.Weighted > 0 {
+			c.AvgLTV = c.TotalLTV / c.Weighted
+		}
+		cohorts = append(cohorts, c)
+	}
+	return cohorts, rows.Err()
+}
+
+// schemaAt is the schema version current at t, the oldest one for installs
+// before any schema existed. schemas are newest first.
+func schemaAt(schemas []*entity.SKANConversionSchema, t time.Time) *entity.SKANConversionSchema {
+	for _, schema := range schemas {
+		if !schema.CreatedAt.After(t) {
+			return schema
+		}
+	}
+	return schemas[len(schemas)-1]
+}
+
+// matchSKANPostback picks the signup a postback most likely came from: among
+// candidates whose first-day revenue encodes the postback's value (fine, or
+// coarse when Apple withheld the fine value), the one closest to the middle
+// of the install window. Confidence is 1 over the number of such candidates.
+// Without schemas or a value, every candidate in the window matches.
+func matchSKANPostback(p *entity.SKANPostback, schemas []*entity.SKANConversionSchema, candidates []skanCandidate) (skanCandidate, float64, bool) {
+	var matching []skanCandidate
+	for _, c := range candidates {
+		if len(schemas) > 0 {
+			bucket := schemaAt(schemas, c.CreatedAt).Bucket(c.Revenue)
+			switch {
+			case p.ConversionValue != nil && bucket.Value != *p.ConversionValue:
+				continue
+			case p.ConversionValue == nil && p.CoarseConversionValue != "" && bucket.Coarse != p.CoarseConversionValue:
+				continue
+			}
+		}
+		matching = append(matching, c)
+	}
+	if len(matching) == 0 {
+		return skanCandidate{}, 0, false
+	}
+
+	from, to := p.InstallWindow()
+	mid := from.Add(to.Sub(from) / 2)
+	distance := func(c skanCandidate) time.Duration {
+		if d := c.CreatedAt.Sub(mid); d >= 0 {
+			return d
+		}
+		return mid.Sub(c.CreatedAt)
+	}
+	sort.SliceStable(matching, func(i, j int) bool {
+		if di, dj := distance(matching[i]), distance(matching[j]); di != dj {
+			return di < dj
+		}
+		return matching[i].UserID.String() < matching[j].UserID.String()
+	})
+	return matching[0], 1 / float64(len(matching)), true
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+func skanSchema(version int, created time.Time) *entity.SKANConversionSchema {
+	return &entity.SKANConversionSchema{Version: version, CreatedAt: created, Buckets: []entity.SKANValueBucket{
+		{Value: 0, MinRevenue: 0, Coarse: entity.SKANCoarseLow},
+		{Value: 8, MinRevenue: 4.99, Coarse: entity.SKANCoarseMedium},
+		{Value: 16, MinRevenue: 29.99, Coarse: entity.SKANCoarseHigh},
+	}}
+}
+
+func TestMatchSKANPostback_MatchesConversionValueClosestToWindow(t *testing.T) {
+	received := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	value := 8
+	p := &entity.SKANPostback{Version: "3.0", ConversionValue: &value, ReceivedAt: received}
+	schemas := []*entity.SKANConversionSchema{skanSchema(1, received.AddDate(0, -1, 0))}
+
+	// The install window is 24-72h before the postback; its middle is 48h
+	near := skanCandidate{UserID: uuid.New(), CreatedAt: received.Add(-47 * time.Hour), Revenue: 9.99}
+	far := skanCandidate{UserID: uuid.New(), CreatedAt: received.Add(-30 * time.Hour), Revenue: 4.99}
+	freeUser := skanCandidate{UserID: uuid.New(), CreatedAt: received.Add(-48 * time.Hour), Revenue: 0}
+
+	best, confidence, ok := matchSKANPostback(p, schemas, []skanCandidate{far, freeUser, near})
+	require.True(t, ok)
+	assert.Equal(t, near.UserID, best.UserID)
+	assert.InDelta(t, 0.5, confidence, 1e-9)
+}
+
+func TestMatchSKANPostback_FallsBackToCoarseValue(t *testing.T) {
+	received := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	p := &entity.SKANPostback{Version: "4.0", CoarseConversionValue: entity.SKANCoarseHigh, ReceivedAt: received}
+	schemas := []*entity.SKANConversionSchema{skanSchema(1, received.AddDate(0, -1, 0))}
+
+	payer := skanCandidate{UserID: uuid.New(), CreatedAt: received.Add(-60 * time.Hour), Revenue: 49.99}
+	other := skanCandidate{UserID: uuid.New(), CreatedAt: received.Add(-60 * time.Hour), Revenue: 4.99}
+
+	best, confidence, ok := matchSKANPostback(p, schemas, []skanCandidate{other, payer})
+	require.True(t, ok)
+	assert.Equal(t, payer.UserID, best.UserID)
+	assert.Equal(t, 1.0, confidence)
+
+	_, _, ok = matchSKANPostback(p, schemas, []skanCandidate{other})
+	assert.False(t, ok)
+}
+
+func TestSchemaAt_UsesVersionCurrentAtInstall(t *testing.T) {
+	t0 := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	schemas := []*entity.SKANConversionSchema{skanSchema(2, t0.AddDate(0, 0, 10)), skanSchema(1, t0)}
+
+	assert.Equal(t, 1, schemaAt(schemas, t0.AddDate(0, 0, 5)).Version)
+	assert.Equal(t, 2, schemaAt(schemas, t0.AddDate(0, 0, 11)).Version)
+	assert.Equal(t, 1, schemaAt(schemas, t0.AddDate(0, 0, -1)).Version)
+}
+
+func TestSKANSignatureVerifier_VerifiesVersion4Postback(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	verifier, err := NewSKANSignatureVerifier(base64.StdEncoding.EncodeToString(der))
+	require.NoError(t, err)
+
+	body := []byte(`{"version":"4.0","ad-network-id":"com.example","source-identifier":"5239","app-id":525463029,
+		"transaction-id":"6aafb7a5-0170-41b5-bbe4-fe71dedf1e31","redownload":false,"source-domain":"example.com",
+		"fidelity-type":1,"did-win":true,"coarse-conversion-value":"high","postback-sequence-index":0}`)
+	payload, err := ParseSKANPostback(body)
+	require.NoError(t, err)
+
+	msg, err := payload.signedMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "4.0\u2063com.example\u20635239\u2063525463029\u20636aafb7a5-0170-41b5-bbe4-fe71dedf1e31\u2063false\u2063example.com\u20631\u2063true\u20630", msg)
+
+	digest := sha256.Sum256([]byte(msg))
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	require.NoError(t, err)
+	payload.AttributionSignature = base64.StdEncoding.EncodeToString(sig)
+	assert.NoError(t, verifier.Verify(payload))
+
+	payload.SourceIdentifier = "9999"
+	assert.ErrorIs(t, verifier.Verify(payload), ErrSKANSignature)
+}
+
+func TestParseSKANPostback_MapsCampaignIDBeforeVersion4(t *testing.T) {
+	body, _ := json.Marshal(map[string]any{
+		"version": "3.0", "ad-network-id": "com.example", "campaign-id": 42, "app-id": 525463029,
+		"transaction-id": "t-1", "conversion-value": 8, "source-app-id": 1234567891,
+	})
+	payload, err := ParseSKANPostback(body)
+	require.NoError(t, err)
+	postback := payload.Entity()
+	assert.Equal(t, "42", postback.SourceIdentifier)
+	assert.Equal(t, "1234567891", postback.SourceAppID)
+	assert.True(t, postback.DidWin)
+
+	_, err = ParseSKANPostback([]byte(`{"version":"3.0","ad-network-id":"x","app-id":1,"transaction-id":"t","conversion-value":64}`))
+	assert.ErrorIs(t, err, ErrInvalidSKANPostback)
+}
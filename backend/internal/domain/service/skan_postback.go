@@ -0,0 +1,175 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+var (
+	// ErrInvalidSKANPostback is returned for postbacks missing required fields
+	ErrInvalidSKANPostback = errors.New("invalid SKAdNetwork postback")
+	// ErrSKANSignature is returned when a postback's attribution signature
+	// does not verify
+	ErrSKANSignature = errors.New("SKAdNetwork attribution signature does not verify")
+)
+
+// skanSignatureSeparator joins the signed fields (U+2063 INVISIBLE SEPARATOR)
+const skanSignatureSeparator = "\u2063"
+
+// SKANPostbackPayload is a postback as Apple sends it
+type SKANPostbackPayload struct {
+	Version               string `json:"version"`
+	AdNetworkID           string `json:"ad-network-id"`
+	CampaignID            *int   `json:"campaign-id"`
+	SourceIdentifier      string `json:"source-identifier"`
+	AppID                 int64  `json:"app-id"`
+	TransactionID         string `json:"transaction-id"`
+	Redownload            bool   `json:"redownload"`
+	SourceAppID           *int64 `json:"source-app-id"`
+	SourceDomain          string `json:"source-domain"`
+	FidelityType          *int   `json:"fidelity-type"`
+	ConversionValue       *int   `json:"conversion-value"`
+	CoarseConversionValue string `json:"coarse-conversion-value"`
+	DidWin                *bool  `json:"did-win"`
+	PostbackSequenceIndex int    `json:"postback-sequence-index"`
+	AttributionSignature  string `json:"attribution-signature"`
+}
+
+// ParseSKANPostback decodes and checks a postback body
+func ParseSKANPostback(body []byte) (*SKANPostbackPayload, error) {
+	var p SKANPostbackPayload
+	if err := json.Unmarshal(body, &p); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSKANPostback, err)
+	}
+	switch {
+	case p.Version == "":
+		return nil, fmt.Errorf("%w: version is required", ErrInvalidSKANPostback)
+	case p.AdNetworkID == "":
+		return nil, fmt.Errorf("%w: ad-network-id is required", ErrInvalidSKANPostback)
+	case p.TransactionID == "":
+		return nil, fmt.Errorf("%w: transaction-id is required", ErrInvalidSKANPostback)
+	case p.AppID == 0:
+		return nil, fmt.Errorf("%w: app-id is required", ErrInvalidSKANPostback)
+	case p.ConversionValue != nil && (*p.ConversionValue < 0 || *p.ConversionValue > entity.SKANMaxConversionValue):
+		return nil, fmt.Errorf("%w: conversion-value must be 0-%d", ErrInvalidSKANPostback, entity.SKANMaxConversionValue)
+	}
+	switch entity.SKANCoarseValue(p.CoarseConversionValue) {
+	case "", entity.SKANCoarseLow, entity.SKANCoarseMedium, entity.SKANCoarseHigh:
+	default:
+		return nil, fmt.Errorf("%w: unknown coarse-conversion-value %q", ErrInvalidSKANPostback, p.CoarseConversionValue)
+	}
+	return &p, nil
+}
+
+// Entity returns the postback as stored; the app is resolved by the caller
+func (p *SKANPostbackPayload) Entity() *entity.SKANPostback {
+	e := &entity.SKANPostback{
+		TransactionID:         p.TransactionID,
+		Version:               p.Version,
+		AdNetworkID:           p.AdNetworkID,
+		SourceIdentifier:      p.SourceIdentifier,
+		SourceDomain:          p.SourceDomain,
+		FidelityType:          p.FidelityType,
+		Redownload:            p.Redownload,
+		DidWin:                p.DidWin == nil || *p.DidWin,
+		PostbackSequenceIndex: p.PostbackSequenceIndex,
+		ConversionValue:       p.ConversionValue,
+		CoarseConversionValue: entity.SKANCoarseValue(p.CoarseConversionValue),
+	}
+	if e.SourceIdentifier == "" && p.CampaignID != nil {
+		e.SourceIdentifier = strconv.Itoa(*p.CampaignID)
+	}
+	if p.SourceAppID != nil {
+		e.SourceAppID = strconv.FormatInt(*p.SourceAppID, 10)
+	}
+	return e
+}
+
+// signedMessage is the version-specific field sequence Apple signs. Optional
+// source fields are only included when present.
+func (p *SKANPostbackPayload) signedMessage() (string, error) {
+	fields := []string{p.Version, p.AdNetworkID}
+	major := strings.SplitN(p.Version, ".", 2)[0]
+	if major >= "4" {
+		fields = append(fields, p.SourceIdentifier)
+	} else {
+		if p.CampaignID == nil {
+			return "", fmt.Errorf("%w: campaign-id is required before version 4", ErrInvalidSKANPostback)
+		}
+		fields = append(fields, strconv.Itoa(*p.CampaignID))
+	}
+	fields = append(fields, strconv.FormatInt(p.AppID, 10), p.TransactionID, strconv.FormatBool(p.Redownload))
+	switch {
+	case p.SourceAppID != nil:
+		fields = append(fields, strconv.FormatInt(*p.SourceAppID, 10))
+	case p.SourceDomain != "":
+		fields = append(fields, p.SourceDomain)
+	}
+	if p.FidelityType != nil {
+		fields = append(fields, strconv.Itoa(*p.FidelityType))
+	}
+	if p.DidWin != nil {
+		fields = append(fields, strconv.FormatBool(*p.DidWin))
+	}
+	if major >= "4" {
+		fields = append(fields, strconv.Itoa(p.PostbackSequenceIndex))
+	}
+	return strings.Join(fields, skanSignatureSeparator), nil
+}
+
+// SKANSignatureVerifier checks attribution signatures against Apple's
+// SKAdNetwork public key
+type SKANSignatureVerifier struct {
+	key *ecdsa.PublicKey
+}
+
+// NewSKANSignatureVerifier parses Apple's P-256 public key, as PEM or as the
+// base64 DER Apple publishes
+func NewSKANSignatureVerifier(encoded string) (*SKANSignatureVerifier, error) {
+	der := []byte(encoded)
+	if block, _ := pem.Decode(der); block != nil {
+		der = block.Bytes
+	} else {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode SKAdNetwork public key: %w", err)
+		}
+		der = decoded
+	}
+	pub, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SKAdNetwork public key: %w", err)
+	}
+	key, ok := pub.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("SKAdNetwork public key is not an ECDSA key")
+	}
+	return &SKANSignatureVerifier{key: key}, nil
+}
+
+// Verify checks the postback's attribution signature
+func (v *SKANSignatureVerifier) Verify(p *SKANPostbackPayload) error {
+	sig, err := base64.StdEncoding.DecodeString(p.AttributionSignature)
+	if err != nil || len(sig) == 0 {
+		return fmt.Errorf("%w: missing or malformed signature", ErrSKANSignature)
+	}
+	msg, err := p.signedMessage()
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256([]byte(msg))
+	if !ecdsa.VerifyASN1(v.key, digest[:], sig) {
+		return ErrSKANSignature
+	}
+	return nil
+}
@@ -4,13 +4,53 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/clock"
 )
 
+// decayRetentionHalfLives is how many half-lives a decay window keeps events
+// for; older events weigh less than 0.1% and are trimmed
+const decayRetentionHalfLives = 10
+
+// defaultWindowMinSamples applies when a window config leaves MinSamples unset
+const defaultWindowMinSamples = 100
+
+// windowStatsTTL is how long computed window stats stay cached
+const windowStatsTTL = 5 * time.Minute
+
+// recordWindowEventScript appends an event, trims the window and drops the
+// cached stats in one step, so concurrent writers can't interleave between
+// them. It bumps the window version so a reader that computed stats before
+// the write can't cache them afterwards, see cacheWindowStatsScript.
+// ARGV: score, member, trim mode ("rank", "score" or ""), trim bound.
+var recordWindowEventScript = redis.NewScript(`
+redis.call('ZADD', KEYS[1], ARGV[1], ARGV[2])
+if ARGV[3] == 'rank' then
+	redis.call('ZREMRANGEBYRANK', KEYS[1], 0, ARGV[4])
+elseif ARGV[3] == 'score' then
+	redis.call('ZREMRANGEBYSCORE', KEYS[1], '0', ARGV[4])
+end
+redis.call('DEL', KEYS[2])
+return redis.call('INCR', KEYS[3])
+`)
+
+// cacheWindowStatsScript caches stats only if the window version is still
+// the one they were computed at. ARGV: version, stats, ttl in milliseconds.
+var cacheWindowStatsScript = redis.NewScript(`
+if (redis.call('GET', KEYS[2]) or '0') ~= ARGV[1] then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+return 1
+`)
+
 // SlidingWindowStrategy implements sliding window arm statistics
 // Uses Redis Sorted Sets for O(log N) operations
 type SlidingWindowStrategy struct {
@@ -19,6 +59,7 @@ type SlidingWindowStrategy struct {
 	logger       *zap.Logger
 	experimentID uuid.UUID
 	config       *WindowConfig
+	now          func() time.Time
 }
 
 // WindowStats represents aggregated statistics within a window
@@ -56,71 +97,83 @@ func NewSlidingWindowStrategy(
 		logger:       logger,
 		experimentID: experimentID,
 		config:       config,
+		now:          time.Now,
 	}
 }
 
-// GetArmStats retrieves arm statistics for the current window
+// WithClock makes window ages and trimming horizons come from c
+func (s *SlidingWindowStrategy) WithClock(c clock.Clock) *SlidingWindowStrategy {
+	s.now = c.Now
+	return s
+}
+
+// GetArmStats retrieves arm statistics for the current window. A window with
+// fewer than MinSamples is topped up with lifetime stats, see blendWithLifetime.
 func (s *SlidingWindowStrategy) GetArmStats(ctx context.Context, armID uuid.UUID) (*ArmStats, error) {
-	statsKey := s.getStatsKey(armID)
+	stats, err := s.windowStats(ctx, armID)
+	if err != nil {
+		// Fallback to full history from repository
+		return s.repo.GetArmStats(ctx, armID)
+	}
+	if stats.Alpha+stats.Beta-2 >= float64(s.minSamples()) {
+		return stats, nil
+	}
 
-	// Try to get cached stats first
-	cachedStats, err := s.redisClient.Get(ctx, statsKey).Result()
-	if err == nil {
-		// Parse cached stats - for production, use proper serialization
-		return s.parseCachedStats(cachedStats, armID)
+	lifetime, err := s.repo.GetArmStats(ctx, armID)
+	if err != nil {
+		s.logger.Warn("Failed to load lifetime stats for window blending", zap.Error(err))
+		return stats, nil
 	}
+	return blendWithLifetime(stats, lifetime, s.minSamples()), nil
+}
 
-	if err != redis.Nil {
+// windowStats returns the stats of the window alone
+func (s *SlidingWindowStrategy) windowStats(ctx context.Context, armID uuid.UUID) (*ArmStats, error) {
+	// Try to get cached stats first, along with the version they'd be
+	// cached at if they have to be recomputed
+	cached, err := s.redisClient.MGet(ctx, s.getStatsKey(armID), s.getVersionKey(armID)).Result()
+	version := "0"
+	if err == nil {
+		// Parse cached stats - for production, use proper serialization
+		if serialized, ok := cached[0].(string); ok {
+			if stats, parseErr := s.parseCachedStats(serialized, armID); parseErr == nil {
+				return stats, nil
+			}
+		}
+		if v, ok := cached[1].(string); ok {
+			version = v
+		}
+	} else {
 		s.logger.Warn("Redis error fetching stats", zap.Error(err))
 	}
 
 	// Calculate from window events
 	stats, err := s.calculateWindowStats(ctx, armID)
 	if err != nil {
-		// Fallback to full history from repository
-		return s.repo.GetArmStats(ctx, armID)
+		return nil, err
 	}
 
-	// Cache the stats
-	if err := s.cacheStats(ctx, armID, stats); err != nil {
+	// Cache the stats unless an event was recorded meanwhile
+	if err := s.cacheStats(ctx, armID, version, stats); err != nil {
 		s.logger.Warn("Failed to cache stats", zap.Error(err))
 	}
 
 	return stats, nil
 }
 
-// RecordEvent records a reward event in the sliding window
+// RecordEvent records a reward event in the sliding window. The append, trim
+// and stats invalidation run as one script; Run falls back from EVALSHA to
+// EVAL when Redis answers NOSCRIPT, e.g. after a restart or SCRIPT FLUSH.
 func (s *SlidingWindowStrategy) RecordEvent(ctx context.Context, armID uuid.UUID, event RewardEvent) error {
-	windowKey := s.getWindowKey(armID)
-
 	// Add event to sorted set (score = timestamp)
-	score := float64(event.Timestamp.UnixMilli())
+	score := event.Timestamp.UnixMilli()
 	member := fmt.Sprintf("%s:%f:%s", event.UserID.String(), event.RewardValue, event.Currency)
 
-	pipe := s.redisClient.Pipeline()
-
-	// Add to window
-	pipe.ZAdd(ctx, windowKey, redis.Z{
-		Score:  score,
-		Member: member,
-	})
-
 	// Clean up old events based on window type
-	switch s.config.Type {
-	case WindowTypeEvents:
-		// Keep only the most recent N events
-		pipe.ZRemRangeByRank(ctx, windowKey, 0, -int64(s.config.Size)-1)
-	case WindowTypeTime:
-		// Remove events older than window size (seconds)
-		cutoff := time.Now().Add(-time.Duration(s.config.Size) * time.Second)
-		pipe.ZRemRangeByScore(ctx, windowKey, "0", fmt.Sprintf("%d", cutoff.UnixMilli()))
-	}
-
-	// Invalidate cached stats
-	statsKey := s.getStatsKey(armID)
-	pipe.Del(ctx, statsKey)
+	mode, bound := s.trimBound()
 
-	if _, err := pipe.Exec(ctx); err != nil {
+	keys := []string{s.getWindowKey(armID), s.getStatsKey(armID), s.getVersionKey(armID)}
+	if err := recordWindowEventScript.Run(ctx, s.redisClient, keys, score, member, mode, bound).Err(); err != nil {
 		return fmt.Errorf("failed to record event: %w", err)
 	}
 
@@ -143,48 +196,130 @@ func (s *SlidingWindowStrategy) calculateWindowStats(ctx context.Context, armID
 	windowKey := s.getWindowKey(armID)
 
 	// Get all events in the window
-	events, err := s.redisClient.ZRevRangeWithScores(ctx, windowKey, 0, -1).Result()
+	members, err := s.redisClient.ZRevRangeWithScores(ctx, windowKey, 0, -1).Result()
 	if err != nil && err != redis.Nil {
 		return nil, fmt.Errorf("failed to get window events: %w", err)
 	}
 
-	// Parse events and calculate stats
-	samples := 0
-	conversions := 0
-	revenue := 0.0
-
-	for _, z := range events {
+	events := make([]RewardEvent, 0, len(members))
+	for _, z := range members {
 		event, err := s.parseEventMember(z.Member.(string))
 		if err != nil {
 			s.logger.Warn("Failed to parse event", zap.Error(err))
 			continue
 		}
+		event.Timestamp = time.UnixMilli(int64(z.Score))
+		events = append(events, event)
+	}
+
+	return aggregateWindowEvents(armID, events, s.config, s.now()), nil
+}
+
+// aggregateWindowEvents sums events into arm stats. Decay windows weight each
+// event by 2^(-age/half-life), so Alpha and Beta hold discounted counts.
+func aggregateWindowEvents(armID uuid.UUID, events []RewardEvent, config *WindowConfig, now time.Time) *ArmStats {
+	var samples, conversions, revenue, logSum, logSqSum float64
+	for _, event := range events {
+		weight := 1.0
+		if config != nil && config.Type == WindowTypeDecay && config.Size > 0 {
+			age := math.Max(now.Sub(event.Timestamp).Seconds(), 0)
+			weight = math.Exp2(-age / float64(config.Size))
+		}
 
-		samples++
+		samples += weight
 		if event.RewardValue > 0 {
-			conversions++
-			revenue += event.RewardValue
+			conversions += weight
+			revenue += weight * event.RewardValue
+			logRevenue := math.Log(event.RewardValue)
+			logSum += weight * logRevenue
+			logSqSum += weight * logRevenue * logRevenue
 		}
 	}
 
-	// Calculate Beta distribution parameters
-	alpha := 1.0 + float64(conversions)
-	beta := 1.0 + float64(samples-conversions)
 	avgReward := 0.0
 	if samples > 0 {
-		avgReward = revenue / float64(samples)
+		avgReward = revenue / samples
 	}
 
 	return &ArmStats{
-		ArmID:       armID,
-		Alpha:       alpha,
-		Beta:        beta,
-		Samples:     samples,
-		Conversions: conversions,
-		Revenue:     revenue,
-		AvgReward:   avgReward,
-		UpdatedAt:   time.Now(),
-	}, nil
+		ArmID:           armID,
+		Alpha:           1.0 + conversions,
+		Beta:            1.0 + samples - conversions,
+		Samples:         int(math.Round(samples)),
+		Conversions:     int(math.Round(conversions)),
+		Revenue:         revenue,
+		AvgReward:       avgReward,
+		LogRevenueSum:   logSum,
+		LogRevenueSqSum: logSqSum,
+		UpdatedAt:       now,
+	}
+}
+
+// blendWithLifetime tops a thin window up to minSamples of evidence by adding
+// a proportional share of the arm's lifetime stats, so a freshly trimmed or
+// sparse window doesn't reset the arm to its prior
+func blendWithLifetime(window, lifetime *ArmStats, minSamples int) *ArmStats {
+	windowSamples := window.Alpha + window.Beta - 2
+	lifetimeSamples := float64(lifetime.Samples)
+	if windowSamples >= float64(minSamples) || lifetimeSamples <= 0 {
+		return window
+	}
+
+	share := math.Min(1, (float64(minSamples)-windowSamples)/lifetimeSamples)
+	lifetimeConversions := float64(lifetime.Conversions)
+
+	blended := *window
+	blended.Alpha += share * lifetimeConversions
+	blended.Beta += share * (lifetimeSamples - lifetimeConversions)
+	blended.Revenue += share * lifetime.Revenue
+	blended.LogRevenueSum += share * lifetime.LogRevenueSum
+	blended.LogRevenueSqSum += share * lifetime.LogRevenueSqSum
+
+	samples := blended.Alpha + blended.Beta - 2
+	blended.Samples = int(math.Round(samples))
+	blended.Conversions = int(math.Round(blended.Alpha - 1))
+	blended.AvgReward = blended.Revenue / samples
+	return &blended
+}
+
+// trimBound returns how events that fell out of the window are removed:
+// "rank" removes ranks 0 through bound, "score" scores up to bound and ""
+// nothing
+func (s *SlidingWindowStrategy) trimBound() (mode string, bound int64) {
+	switch s.config.Type {
+	case WindowTypeEvents:
+		// Keep only the most recent N events
+		return "rank", -int64(s.config.Size) - 1
+	case WindowTypeTime, WindowTypeDecay:
+		// Remove events older than window size (seconds), or than the
+		// retention horizon for decay windows
+		retention := time.Duration(s.config.Size) * time.Second
+		if s.config.Type == WindowTypeDecay {
+			retention *= decayRetentionHalfLives
+		}
+		return "score", s.now().Add(-retention).UnixMilli()
+	default:
+		return "", 0
+	}
+}
+
+// trim removes events that fell out of the window
+func (s *SlidingWindowStrategy) trim(ctx context.Context, windowKey string) error {
+	switch mode, bound := s.trimBound(); mode {
+	case "rank":
+		return s.redisClient.ZRemRangeByRank(ctx, windowKey, 0, bound).Err()
+	case "score":
+		return s.redisClient.ZRemRangeByScore(ctx, windowKey, "0", fmt.Sprintf("%d", bound)).Err()
+	default:
+		return nil
+	}
+}
+
+func (s *SlidingWindowStrategy) minSamples() int {
+	if s.config.MinSamples > 0 {
+		return s.config.MinSamples
+	}
+	return defaultWindowMinSamples
 }
 
 // getWindowKey returns the Redis key for the window sorted set
@@ -197,44 +332,55 @@ func (s *SlidingWindowStrategy) getStatsKey(armID uuid.UUID) string {
 	return fmt.Sprintf("bandit:window:stats:%s:%s", s.experimentID.String(), armID.String())
 }
 
+// getVersionKey returns the Redis key counting writes to the window
+func (s *SlidingWindowStrategy) getVersionKey(armID uuid.UUID) string {
+	return fmt.Sprintf("bandit:window:version:%s:%s", s.experimentID.String(), armID.String())
+}
+
 // parseEventMember parses an event member string
 func (s *SlidingWindowStrategy) parseEventMember(member string) (RewardEvent, error) {
 	// Format: userID:rewardValue:currency
-	var userID uuid.UUID
-	var rewardValue float64
-	var currency string
-
-	_, err := fmt.Sscanf(member, "%s:%f:%s", &userID, &rewardValue, &currency)
+	parts := strings.SplitN(member, ":", 3)
+	if len(parts) != 3 {
+		return RewardEvent{}, fmt.Errorf("failed to parse event: malformed member %q", member)
+	}
+	userID, err := uuid.Parse(parts[0])
 	if err != nil {
 		return RewardEvent{}, fmt.Errorf("failed to parse event: %w", err)
 	}
+	rewardValue, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return RewardEvent{}, fmt.Errorf("failed to parse event: %w", err)
+	}
+	currency := parts[2]
 
 	return RewardEvent{
 		UserID:      userID,
 		RewardValue: rewardValue,
 		Currency:    currency,
-		Timestamp:   time.Now(),
+		Timestamp:   s.now(),
 	}, nil
 }
 
-// cacheStats caches the calculated stats
-func (s *SlidingWindowStrategy) cacheStats(ctx context.Context, armID uuid.UUID, stats *ArmStats) error {
-	statsKey := s.getStatsKey(armID)
+// cacheStats caches the calculated stats if the window is still at version
+func (s *SlidingWindowStrategy) cacheStats(ctx context.Context, armID uuid.UUID, version string, stats *ArmStats) error {
+	keys := []string{s.getStatsKey(armID), s.getVersionKey(armID)}
 
 	// Serialize stats - for production, use JSON or msgpack
-	serialized := fmt.Sprintf("%.2f,%.2f,%d,%d,%.2f",
-		stats.Alpha, stats.Beta, stats.Samples, stats.Conversions, stats.Revenue)
+	serialized := fmt.Sprintf("%.2f,%.2f,%d,%d,%.2f,%g,%g",
+		stats.Alpha, stats.Beta, stats.Samples, stats.Conversions, stats.Revenue,
+		stats.LogRevenueSum, stats.LogRevenueSqSum)
 
-	return s.redisClient.Set(ctx, statsKey, serialized, 5*time.Minute).Err()
+	return cacheWindowStatsScript.Run(ctx, s.redisClient, keys, version, serialized, windowStatsTTL.Milliseconds()).Err()
 }
 
 // parseCachedStats parses cached stats from Redis
 func (s *SlidingWindowStrategy) parseCachedStats(serialized string, armID uuid.UUID) (*ArmStats, error) {
-	var alpha, beta, revenue float64
+	var alpha, beta, revenue, logSum, logSqSum float64
 	var samples, conversions int
 
-	_, err := fmt.Sscanf(serialized, "%f,%f,%d,%d,%f",
-		&alpha, &beta, &samples, &conversions, &revenue)
+	_, err := fmt.Sscanf(serialized, "%f,%f,%d,%d,%f,%g,%g",
+		&alpha, &beta, &samples, &conversions, &revenue, &logSum, &logSqSum)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse cached stats: %w", err)
 	}
@@ -245,14 +391,16 @@ func (s *SlidingWindowStrategy) parseCachedStats(serialized string, armID uuid.U
 	}
 
 	return &ArmStats{
-		ArmID:       armID,
-		Alpha:       alpha,
-		Beta:        beta,
-		Samples:     samples,
-		Conversions: conversions,
-		Revenue:     revenue,
-		AvgReward:   avgReward,
-		UpdatedAt:   time.Now(),
+		ArmID:           armID,
+		Alpha:           alpha,
+		Beta:            beta,
+		Samples:         samples,
+		Conversions:     conversions,
+		Revenue:         revenue,
+		AvgReward:       avgReward,
+		LogRevenueSum:   logSum,
+		LogRevenueSqSum: logSqSum,
+		UpdatedAt:       s.now(),
 	}, nil
 }
 
@@ -312,19 +460,7 @@ func (s *SlidingWindowStrategy) GetWindowInfo(ctx context.Context, armID uuid.UU
 
 // TrimWindow trims the window to the configured size
 func (s *SlidingWindowStrategy) TrimWindow(ctx context.Context, armID uuid.UUID) error {
-	windowKey := s.getWindowKey(armID)
-
-	switch s.config.Type {
-	case WindowTypeEvents:
-		// Keep only the most recent N events
-		return s.redisClient.ZRemRangeByRank(ctx, windowKey, 0, -int64(s.config.Size)-1).Err()
-	case WindowTypeTime:
-		// Remove events older than window size (seconds)
-		cutoff := time.Now().Add(-time.Duration(s.config.Size) * time.Second)
-		return s.redisClient.ZRemRangeByScore(ctx, windowKey, "0", fmt.Sprintf("%d", cutoff.UnixMilli())).Err()
-	default:
-		return nil
-	}
+	return s.trim(ctx, s.getWindowKey(armID))
 }
 
 // ClearWindow clears all events for an arm
@@ -335,6 +471,7 @@ func (s *SlidingWindowStrategy) ClearWindow(ctx context.Context, armID uuid.UUID
 	pipe := s.redisClient.Pipeline()
 	pipe.Del(ctx, windowKey)
 	pipe.Del(ctx, statsKey)
+	pipe.Incr(ctx, s.getVersionKey(armID))
 
 	if _, err := pipe.Exec(ctx); err != nil {
 		return fmt.Errorf("failed to clear window: %w", err)
@@ -346,18 +483,12 @@ func (s *SlidingWindowStrategy) ClearWindow(ctx context.Context, armID uuid.UUID
 
 // HasEnoughSamples checks if the window has enough samples for reliable statistics
 func (s *SlidingWindowStrategy) HasEnoughSamples(ctx context.Context, armID uuid.UUID) bool {
-	stats, err := s.GetArmStats(ctx, armID)
+	stats, err := s.windowStats(ctx, armID)
 	if err != nil {
 		return false
 	}
 
-	// Check both minimum samples and minimum conversions
-	minSamples := s.config.MinSamples
-	if minSamples == 0 {
-		minSamples = 100
-	}
-
-	return stats.Samples >= minSamples
+	return stats.Samples >= s.minSamples()
 }
 
 // GetUtilization returns the window utilization (current size / max size)
@@ -380,18 +511,36 @@ func (s *SlidingWindowStrategy) GetUtilization(ctx context.Context, armID uuid.U
 	return math.Min(utilization, 1.0), nil
 }
 
-// ExportEvents exports all events in the window for analysis
-func (s *SlidingWindowStrategy) ExportEvents(ctx context.Context, armID uuid.UUID, limit int64) ([]RewardEvent, error) {
+// ExportEvents exports a page of the arm's window events, newest first. The
+// returned cursor continues the export and is nil after the last page.
+func (s *SlidingWindowStrategy) ExportEvents(ctx context.Context, armID uuid.UUID, query WindowEventQuery) ([]RewardEvent, *WindowCursor, error) {
 	windowKey := s.getWindowKey(armID)
 
+	if query.Limit <= 0 {
+		query.Limit = DefaultWindowExportLimit
+	}
+	scoreRange := &redis.ZRangeBy{Min: "-inf", Max: "+inf", Count: query.Limit}
+	if !query.Since.IsZero() {
+		scoreRange.Min = strconv.FormatInt(query.Since.UnixMilli(), 10)
+	}
+	if !query.Until.IsZero() {
+		scoreRange.Max = strconv.FormatInt(query.Until.UnixMilli(), 10)
+	}
+	if query.Cursor != nil {
+		scoreRange.Max = strconv.FormatInt(query.Cursor.Score, 10)
+		scoreRange.Offset = query.Cursor.Skip
+	}
+
 	// Get events from newest to oldest
-	events, err := s.redisClient.ZRevRangeWithScores(ctx, windowKey, 0, limit-1).Result()
+	events, err := s.redisClient.ZRevRangeByScoreWithScores(ctx, windowKey, scoreRange).Result()
 	if err != nil && err != redis.Nil {
-		return nil, fmt.Errorf("failed to export events: %w", err)
+		return nil, nil, fmt.Errorf("failed to export events: %w", err)
 	}
 
 	result := make([]RewardEvent, 0, len(events))
-	for _, z := range events {
+	scores := make([]int64, len(events))
+	for i, z := range events {
+		scores[i] = int64(z.Score)
 		event, err := s.parseEventMember(z.Member.(string))
 		if err != nil {
 			s.logger.Warn("Failed to parse event during export", zap.Error(err))
@@ -399,11 +548,15 @@ func (s *SlidingWindowStrategy) ExportEvents(ctx context.Context, armID uuid.UUI
 		}
 
 		// Set the actual timestamp from the score
-		event.Timestamp = time.UnixMilli(int64(z.Score))
+		event.ArmID = armID
+		event.Timestamp = time.UnixMilli(scores[i])
 		result = append(result, event)
 	}
 
-	return result, nil
+	if int64(len(events)) < query.Limit {
+		return result, nil, nil
+	}
+	return result, nextWindowCursor(query.Cursor, scores), nil
 }
 
 // UpdateConfig updates the window configuration
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestSlidingWindowStrategy_ParseEventMember(t *testing.T) {
+	strategy := NewSlidingWindowStrategy(nil, nil, zap.NewNop(), uuid.New(), nil)
+	userID := uuid.New()
+
+	event, err := strategy.parseEventMember(userID.String() + ":9.990000:EUR")
+	require.NoError(t, err)
+	assert.Equal(t, userID, event.UserID)
+	assert.Equal(t, 9.99, event.RewardValue)
+	assert.Equal(t, "EUR", event.Currency)
+
+	event, err = strategy.parseEventMember(userID.String() + ":0.000000:")
+	require.NoError(t, err)
+	assert.Zero(t, event.RewardValue)
+	assert.Empty(t, event.Currency)
+
+	_, err = strategy.parseEventMember("not-a-member")
+	assert.Error(t, err)
+}
+
+func TestAggregateWindowEvents_DecayDiscountsOldEvents(t *testing.T) {
+	now := time.Now()
+	events := []RewardEvent{
+		{RewardValue: 10, Timestamp: now},                     // weight 1
+		{RewardValue: 0, Timestamp: now.Add(-time.Hour)},      // weight 1/2
+		{RewardValue: 4, Timestamp: now.Add(-2 * time.Hour)},  // weight 1/4
+		{RewardValue: 0, Timestamp: now.Add(-10 * time.Hour)}, // weight ~0
+	}
+
+	counted := aggregateWindowEvents(uuid.Nil, events, &WindowConfig{Type: WindowTypeTime, Size: 86400}, now)
+	assert.Equal(t, 4, counted.Samples)
+	assert.Equal(t, 2, counted.Conversions)
+	assert.Equal(t, 3.0, counted.Alpha)
+	assert.Equal(t, 3.0, counted.Beta)
+
+	decayed := aggregateWindowEvents(uuid.Nil, events, &WindowConfig{Type: WindowTypeDecay, Size: 3600}, now)
+	assert.InDelta(t, 1+1+0.25, decayed.Alpha, 1e-9)
+	assert.InDelta(t, 1+0.5+math.Exp2(-10), decayed.Beta, 1e-9)
+	assert.InDelta(t, 10+0.25*4, decayed.Revenue, 1e-9)
+	assert.InDelta(t, math.Log(10)+0.25*math.Log(4), decayed.LogRevenueSum, 1e-9)
+	assert.Equal(t, 2, decayed.Samples)
+}
+
+func TestBlendWithLifetime(t *testing.T) {
+	lifetime := &ArmStats{Alpha: 201, Beta: 801, Samples: 1000, Conversions: 200, Revenue: 2000}
+
+	t.Run("thin window is topped up to min samples", func(t *testing.T) {
+		window := &ArmStats{Alpha: 11, Beta: 31, Samples: 40, Conversions: 10, Revenue: 50}
+		blended := blendWithLifetime(window, lifetime, 100)
+
+		// 60 samples' worth of lifetime evidence at its 20% conversion rate
+		assert.Equal(t, 100, blended.Samples)
+		assert.InDelta(t, 11+12, blended.Alpha, 1e-9)
+		assert.InDelta(t, 31+48, blended.Beta, 1e-9)
+		assert.InDelta(t, 50+120, blended.Revenue, 1e-9)
+		assert.InDelta(t, 1.7, blended.AvgReward, 1e-9)
+		// The window itself is left untouched
+		assert.Equal(t, 11.0, window.Alpha)
+	})
+
+	t.Run("full window is used alone", func(t *testing.T) {
+		window := &ArmStats{Alpha: 51, Beta: 101, Samples: 150, Conversions: 50}
+		assert.Same(t, window, blendWithLifetime(window, lifetime, 100))
+	})
+
+	t.Run("lifetime smaller than the gap is used in full", func(t *testing.T) {
+		window := &ArmStats{Alpha: 1, Beta: 1}
+		small := &ArmStats{Samples: 10, Conversions: 5, Revenue: 20}
+		blended := blendWithLifetime(window, small, 100)
+		assert.Equal(t, 10, blended.Samples)
+		assert.InDelta(t, 6, blended.Alpha, 1e-9)
+	})
+}
+
+func TestSlidingWindowStrategy_TrimBound(t *testing.T) {
+	events := NewSlidingWindowStrategy(nil, nil, zap.NewNop(), uuid.New(), &WindowConfig{Type: WindowTypeEvents, Size: 500})
+	mode, bound := events.trimBound()
+	assert.Equal(t, "rank", mode)
+	assert.Equal(t, int64(-501), bound)
+
+	timed := NewSlidingWindowStrategy(nil, nil, zap.NewNop(), uuid.New(), &WindowConfig{Type: WindowTypeTime, Size: 3600})
+	mode, bound = timed.trimBound()
+	assert.Equal(t, "score", mode)
+	assert.InDelta(t, time.Now().Add(-time.Hour).UnixMilli(), bound, 1000)
+
+	decay := NewSlidingWindowStrategy(nil, nil, zap.NewNop(), uuid.New(), &WindowConfig{Type: WindowTypeDecay, Size: 3600})
+	_, bound = decay.trimBound()
+	assert.InDelta(t, time.Now().Add(-decayRetentionHalfLives*time.Hour).UnixMilli(), bound, 1000)
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+// maxAdjustmentDays bounds one support adjustment, either way
+const maxAdjustmentDays = 365
+
+// ErrInvalidAdjustment is returned for an adjustment of 0 or too many days
+var ErrInvalidAdjustment = errors.New("invalid subscription adjustment")
+
+// SubscriptionAdjustment is the outcome of a support adjustment
+type SubscriptionAdjustment struct {
+	SubscriptionID    uuid.UUID
+	GracePeriodID     *uuid.UUID
+	PreviousExpiresAt time.Time
+	NewExpiresAt      time.Time
+	Status            entity.SubscriptionStatus
+	// ResolvedGracePeriodID is the grace period closed because the
+	// subscription itself was extended
+	ResolvedGracePeriodID *uuid.UUID
+}
+
+// SubscriptionAdjustmentService lets support move a user's grace period or
+// subscription expiry. Changes are written straight to the rows access checks
+// and the grace period expiry pass read, so both see them on their next read.
+type SubscriptionAdjustmentService struct {
+	subscriptionRepo repository.SubscriptionRepository
+	gracePeriodRepo  repository.GracePeriodRepository
+	now              func() time.Time
+}
+
+func NewSubscriptionAdjustmentService(subscriptionRepo repository.SubscriptionRepository, gracePeriodRepo repository.GracePeriodRepository) *SubscriptionAdjustmentService {
+	return &SubscriptionAdjustmentService{subscriptionRepo: subscriptionRepo, gracePeriodRepo: gracePeriodRepo, now: time.Now}
+}
+
+// ExtendGracePeriod moves the user's active grace period by days; negative
+// days shorten it
+func (s *SubscriptionAdjustmentService) ExtendGracePeriod(ctx context.Context, userID uuid.UUID, days int) (*SubscriptionAdjustment, error) {
+	if err := validateAdjustmentDays(days); err != nil {
+		return nil, err
+	}
+	gracePeriod, err := s.gracePeriodRepo.GetActiveByUserID(ctx, userID)
+	if err != nil || gracePeriod == nil {
+		return nil, ErrGracePeriodNotFound
+	}
+
+	previous := gracePeriod.ExpiresAt
+	if err := gracePeriod.Extend(days, s.now()); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrGracePeriodNotActive, err)
+	}
+	if err := s.gracePeriodRepo.Update(ctx, gracePeriod); err != nil {
+		return nil, fmt.Errorf("failed to update grace period: %w", err)
+	}
+	return &SubscriptionAdjustment{
+		SubscriptionID:    gracePeriod.SubscriptionID,
+		GracePeriodID:     &gracePeriod.ID,
+		PreviousExpiresAt: previous,
+		NewExpiresAt:      gracePeriod.ExpiresAt,
+		Status:            entity.StatusGrace,
+	}, nil
+}
+
+// ExtendExpiry moves a subscription's expiry by days. subscriptionID picks
+// the subscription; nil takes the one granting access, else the latest. An
+// expired subscription is extended from now and reactivated, and an active
+// grace period on it is resolved so the expiry pass does not cancel it.
+func (s *SubscriptionAdjustmentService) ExtendExpiry(ctx context.Context, userID uuid.UUID, subscriptionID *uuid.UUID, days int) (*SubscriptionAdjustment, error) {
+	if err := validateAdjustmentDays(days); err != nil {
+		return nil, err
+	}
+	sub, err := s.targetSubscription(ctx, userID, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	base := sub.ExpiresAt
+	if days > 0 && base.Before(now) {
+		base = now
+	}
+	adjustment := &SubscriptionAdjustment{
+		SubscriptionID:    sub.ID,
+		PreviousExpiresAt: sub.ExpiresAt,
+		NewExpiresAt:      base.AddDate(0, 0, days),
+		Status:            sub.Status,
+	}
+	if err := s.subscriptionRepo.UpdateExpiry(ctx, sub.ID, adjustment.NewExpiresAt); err != nil {
+		return nil, fmt.Errorf("failed to update subscription expiry: %w", err)
+	}
+	if !adjustment.NewExpiresAt.After(now) {
+		return adjustment, nil
+	}
+
+	if sub.Status == entity.StatusGrace {
+		if gracePeriod, err := s.gracePeriodRepo.GetActiveBySubscriptionID(ctx, sub.ID); err == nil && gracePeriod != nil {
+			if err := gracePeriod.Resolve(); err == nil {
+				if err := s.gracePeriodRepo.Update(ctx, gracePeriod); err != nil {
+					return nil, fmt.Errorf("failed to resolve grace period: %w", err)
+				}
+				adjustment.ResolvedGracePeriodID = &gracePeriod.ID
+			}
+		}
+	}
+	if sub.Status != entity.StatusActive {
+		if err := s.subscriptionRepo.UpdateStatus(ctx, sub.ID, entity.StatusActive); err != nil {
+			return nil, fmt.Errorf("failed to reactivate subscription: %w", err)
+		}
+		adjustment.Status = entity.StatusActive
+	}
+	return adjustment, nil
+}
+
+func (s *SubscriptionAdjustmentService) targetSubscription(ctx context.Context, userID uuid.UUID, subscriptionID *uuid.UUID) (*entity.Subscription, error) {
+	if subscriptionID != nil {
+		sub, err := s.subscriptionRepo.GetByID(ctx, *subscriptionID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get subscription: %w", err)
+		}
+		if sub.UserID != userID || sub.DeletedAt != nil {
+			return nil, fmt.Errorf("subscription %s of user %s: %w", subscriptionID, userID, domainErrors.ErrNotFound)
+		}
+		return sub, nil
+	}
+
+	subs, err := s.subscriptionRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscriptions: %w", err)
+	}
+	if primary := ResolveEntitlement(subs, s.now()).Primary; primary != nil {
+		return primary, nil
+	}
+	var latest *entity.Subscription
+	for _, sub := range subs {
+		if sub.DeletedAt == nil && (latest == nil || sub.CreatedAt.After(latest.CreatedAt)) {
+			latest = sub
+		}
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("subscription of user %s: %w", userID, domainErrors.ErrNotFound)
+	}
+	return latest, nil
+}
+
+func validateAdjustmentDays(days int) error {
+	if days == 0 || days > maxAdjustmentDays || days < -maxAdjustmentDays {
+		return fmt.Errorf("%w: days must be between -%d and %d and not 0", ErrInvalidAdjustment, maxAdjustmentDays, maxAdjustmentDays)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/tests/mocks"
+)
+
+func TestSubscriptionAdjustmentService(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("ExtendGracePeriod moves the active grace period", func(t *testing.T) {
+		gracePeriodRepo := mocks.NewMockGracePeriodRepository()
+		svc := service.NewSubscriptionAdjustmentService(mocks.NewMockSubscriptionRepository(), gracePeriodRepo)
+
+		userID := uuid.New()
+		expiresAt := time.Now().Add(24 * time.Hour)
+		gracePeriod := entity.NewGracePeriod(userID, uuid.New(), expiresAt)
+		gracePeriodRepo.On("GetActiveByUserID", ctx, userID).Return(gracePeriod, nil)
+		gracePeriodRepo.On("Update", ctx, gracePeriod).Return(nil)
+
+		adjustment, err := svc.ExtendGracePeriod(ctx, userID, 5)
+		require.NoError(t, err)
+		assert.Equal(t, expiresAt, adjustment.PreviousExpiresAt)
+		assert.Equal(t, expiresAt.AddDate(0, 0, 5), adjustment.NewExpiresAt)
+		assert.Equal(t, expiresAt.AddDate(0, 0, 5), gracePeriod.ExpiresAt)
+		gracePeriodRepo.AssertExpectations(t)
+	})
+
+	t.Run("ExtendGracePeriod without a grace period", func(t *testing.T) {
+		gracePeriodRepo := mocks.NewMockGracePeriodRepository()
+		svc := service.NewSubscriptionAdjustmentService(mocks.NewMockSubscriptionRepository(), gracePeriodRepo)
+
+		userID := uuid.New()
+		gracePeriodRepo.On("GetActiveByUserID", ctx, userID).Return(nil, errors.New("no rows"))
+
+		_, err := svc.ExtendGracePeriod(ctx, userID, 5)
+		assert.ErrorIs(t, err, service.ErrGracePeriodNotFound)
+	})
+
+	t.Run("ExtendExpiry rejects zero days", func(t *testing.T) {
+		svc := service.NewSubscriptionAdjustmentService(mocks.NewMockSubscriptionRepository(), mocks.NewMockGracePeriodRepository())
+
+		_, err := svc.ExtendExpiry(ctx, uuid.New(), nil, 0)
+		assert.ErrorIs(t, err, service.ErrInvalidAdjustment)
+	})
+
+	t.Run("ExtendExpiry reactivates a grace subscription and resolves its grace period", func(t *testing.T) {
+		subscriptionRepo := mocks.NewMockSubscriptionRepository()
+		gracePeriodRepo := mocks.NewMockGracePeriodRepository()
+		svc := service.NewSubscriptionAdjustmentService(subscriptionRepo, gracePeriodRepo)
+
+		userID := uuid.New()
+		sub := entity.NewSubscription(userID, entity.SourceIAP, "ios", "com.app.pro", entity.PlanMonthly, time.Now().Add(-2*time.Hour))
+		sub.Status = entity.StatusGrace
+		gracePeriod := entity.NewGracePeriod(userID, sub.ID, time.Now().Add(24*time.Hour))
+		subscriptionRepo.On("GetByUserID", ctx, userID).Return([]*entity.Subscription{sub}, nil)
+		subscriptionRepo.On("UpdateExpiry", ctx, sub.ID, mock.AnythingOfType("time.Time")).Return(nil)
+		subscriptionRepo.On("UpdateStatus", ctx, sub.ID, entity.StatusActive).Return(nil)
+		gracePeriodRepo.On("GetActiveBySubscriptionID", ctx, sub.ID).Return(gracePeriod, nil)
+		gracePeriodRepo.On("Update", ctx, gracePeriod).Return(nil)
+
+		adjustment, err := svc.ExtendExpiry(ctx, userID, nil, 7)
+		require.NoError(t, err)
+		assert.Equal(t, entity.StatusActive, adjustment.Status)
+		assert.WithinDuration(t, time.Now().AddDate(0, 0, 7), adjustment.NewExpiresAt, time.Minute, "a lapsed subscription is extended from now")
+		require.NotNil(t, adjustment.ResolvedGracePeriodID)
+		assert.Equal(t, entity.GraceStatusResolved, gracePeriod.Status)
+		subscriptionRepo.AssertExpectations(t)
+	})
+
+	t.Run("ExtendExpiry refuses another user's subscription", func(t *testing.T) {
+		subscriptionRepo := mocks.NewMockSubscriptionRepository()
+		svc := service.NewSubscriptionAdjustmentService(subscriptionRepo, mocks.NewMockGracePeriodRepository())
+
+		sub := entity.NewSubscription(uuid.New(), entity.SourceIAP, "ios", "com.app.pro", entity.PlanMonthly, time.Now().Add(time.Hour))
+		subscriptionRepo.On("GetByID", ctx, sub.ID).Return(sub, nil)
+
+		_, err := svc.ExtendExpiry(ctx, uuid.New(), &sub.ID, 7)
+		assert.ErrorIs(t, err, domainErrors.ErrNotFound)
+	})
+}
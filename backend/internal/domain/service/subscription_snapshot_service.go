@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+// SubscriptionSnapshotService records nightly subscription snapshots and
+// reports on them
+type SubscriptionSnapshotService struct {
+	repo   repository.SubscriptionSnapshotRepository
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+// NewSubscriptionSnapshotService creates a new subscription snapshot service
+func NewSubscriptionSnapshotService(repo repository.SubscriptionSnapshotRepository, logger *zap.Logger) *SubscriptionSnapshotService {
+	return &SubscriptionSnapshotService{repo: repo, logger: logger, now: time.Now}
+}
+
+// CaptureDaily snapshots every subscription under the UTC date that ended
+// most recently. It is meant to run shortly after midnight; running it again
+// the same day replaces that date's snapshot.
+func (s *SubscriptionSnapshotService) CaptureDaily(ctx context.Context) (time.Time, int64, error) {
+	now := s.now().UTC()
+	date := now.Truncate(24*time.Hour).AddDate(0, 0, -1)
+	rows, err := s.repo.Capture(ctx, date, now)
+	if err != nil {
+		return date, 0, fmt.Errorf("failed to capture subscription snapshot: %w", err)
+	}
+	s.logger.Info("Subscription snapshot captured",
+		zap.String("date", date.Format("2006-01-02")),
+		zap.Int64("rows", rows),
+	)
+	return date, rows, nil
+}
+
+// Summary returns an app's subscription counts at the end of date
+func (s *SubscriptionSnapshotService) Summary(ctx context.Context, appID uuid.UUID, date time.Time) (*repository.SubscriptionSnapshotSummary, error) {
+	return s.repo.Summary(ctx, appID, date)
+}
+
+// DailyDiffs diffs each captured date within [from, to] against the previous
+// captured date. Gaps in capture are reported as one diff spanning the gap.
+func (s *SubscriptionSnapshotService) DailyDiffs(ctx context.Context, appID uuid.UUID, from, to time.Time) ([]repository.SubscriptionSnapshotDiff, error) {
+	dates, err := s.repo.ListDates(ctx, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshot dates: %w", err)
+	}
+
+	diffs := make([]repository.SubscriptionSnapshotDiff, 0, len(dates))
+	for i := 1; i < len(dates); i++ {
+		diff, err := s.repo.Diff(ctx, appID, dates[i-1], dates[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to diff snapshots: %w", err)
+		}
+		diffs = append(diffs, *diff)
+	}
+	return diffs, nil
+}
+
+// UserHistory returns the recorded subscription rows of a user within [from, to]
+func (s *SubscriptionSnapshotService) UserHistory(ctx context.Context, appID, userID uuid.UUID, from, to time.Time) ([]repository.SubscriptionSnapshotRow, error) {
+	return s.repo.ListUserRows(ctx, appID, userID, from, to)
+}
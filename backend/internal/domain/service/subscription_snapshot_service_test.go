@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+type stubSnapshotRepo struct {
+	repository.SubscriptionSnapshotRepository
+	dates      []time.Time
+	captured   []time.Time
+	capturedAt []time.Time
+	diffed     [][2]time.Time
+}
+
+func (s *stubSnapshotRepo) Capture(ctx context.Context, date, capturedAt time.Time) (int64, error) {
+	s.captured = append(s.captured, date)
+	s.capturedAt = append(s.capturedAt, capturedAt)
+	return 3, nil
+}
+
+func (s *stubSnapshotRepo) ListDates(ctx context.Context, from, to time.Time) ([]time.Time, error) {
+	return s.dates, nil
+}
+
+func (s *stubSnapshotRepo) Diff(ctx context.Context, appID uuid.UUID, from, to time.Time) (*repository.SubscriptionSnapshotDiff, error) {
+	s.diffed = append(s.diffed, [2]time.Time{from, to})
+	return &repository.SubscriptionSnapshotDiff{From: from, To: to}, nil
+}
+
+func TestSubscriptionSnapshotService_CaptureDailyLabelsPreviousDate(t *testing.T) {
+	repo := &stubSnapshotRepo{}
+	svc := NewSubscriptionSnapshotService(repo, zap.NewNop())
+	now := time.Date(2026, 3, 4, 0, 5, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+
+	date, rows, err := svc.CaptureDaily(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC), date)
+	assert.Equal(t, int64(3), rows)
+	assert.Equal(t, []time.Time{date}, repo.captured)
+	assert.Equal(t, []time.Time{now}, repo.capturedAt)
+}
+
+func TestSubscriptionSnapshotService_DailyDiffsConsecutiveDates(t *testing.T) {
+	d1 := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	d2 := d1.AddDate(0, 0, 1)
+	d4 := d1.AddDate(0, 0, 3)
+	repo := &stubSnapshotRepo{dates: []time.Time{d1, d2, d4}}
+	svc := NewSubscriptionSnapshotService(repo, zap.NewNop())
+
+	diffs, err := svc.DailyDiffs(context.Background(), uuid.New(), d1, d4)
+	require.NoError(t, err)
+
+	require.Len(t, diffs, 2)
+	assert.Equal(t, [][2]time.Time{{d1, d2}, {d2, d4}}, repo.diffed)
+	assert.Equal(t, d4, diffs[1].To)
+}
+
+func TestSubscriptionSnapshotService_DailyDiffsSingleDate(t *testing.T) {
+	d1 := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	svc := NewSubscriptionSnapshotService(&stubSnapshotRepo{dates: []time.Time{d1}}, zap.NewNop())
+
+	diffs, err := svc.DailyDiffs(context.Background(), uuid.New(), d1, d1)
+	require.NoError(t, err)
+	assert.Empty(t, diffs)
+}
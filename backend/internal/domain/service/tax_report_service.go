@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TaxReportPeriods are the supported grouping granularities for the tax report
+var TaxReportPeriods = map[string]bool{"day": true, "week": true, "month": true}
+
+// TaxReportService summarizes the gross / store fee / tax / net breakdown of
+// transactions and imports store financial reports to reconcile it.
+type TaxReportService struct {
+	dbPool *pgxpool.Pool
+}
+
+// NewTaxReportService creates a new tax report service
+func NewTaxReportService(dbPool *pgxpool.Pool) *TaxReportService {
+	return &TaxReportService{dbPool: dbPool}
+}
+
+// TaxReportRow is one period × country × currency bucket
+type TaxReportRow struct {
+	Period       string  `json:"period"`
+	CountryCode  string  `json:"country_code"`
+	Currency     string  `json:"currency"`
+	Transactions int     `json:"transactions"`
+	Unreconciled int     `json:"unreconciled"`
+	Gross        float64 `json:"gross"`
+	StoreFee     float64 `json:"store_fee"`
+	Tax          float64 `json:"tax"`
+	Net          float64 `json:"net"`
+}
+
+// TaxReportTotal sums all buckets of one currency; amounts in different
+// currencies are never added together.
+type TaxReportTotal struct {
+	Currency     string  `json:"currency"`
+	Transactions int     `json:"transactions"`
+	Gross        float64 `json:"gross"`
+	StoreFee     float64 `json:"store_fee"`
+	Tax          float64 `json:"tax"`
+	Net          float64 `json:"net"`
+}
+
+// TaxReport is the tax / VAT summary for a date range
+type TaxReport struct {
+	From   time.Time        `json:"from"`
+	To     time.Time        `json:"to"`
+	Period string           `json:"period"`
+	Rows   []TaxReportRow   `json:"rows"`
+	Totals []TaxReportTotal `json:"totals"`
+}
+
+// TransactionReconciliation is one line of a store financial report
+// (App Store Connect financial report, Play Console earnings, Stripe payout).
+// Net is derived from gross, tax and fee when the report doesn't carry proceeds.
+type TransactionReconciliation struct {
+	ProviderTxID string   `json:"provider_tx_id"`
+	Gross        float64  `json:"gross"`
+	StoreFee     float64  `json:"store_fee"`
+	Tax          float64  `json:"tax"`
+	Net          *float64 `json:"net,omitempty"`
+	Currency     string   `json:"currency"`
+	CountryCode  string   `json:"country_code,omitempty"`
+}
+
+// ReconcileResult reports which imported lines matched a transaction
+type ReconcileResult struct {
+	Matched   int      `json:"matched"`
+	Unmatched []string `json:"unmatched"`
+}
+
+// GetTaxReport groups successful transactions created in [from, to) by period,
+// country and currency. Transactions not yet broken down count their full
+// amount as net and are reported as unreconciled.
+func (s *TaxReportService) GetTaxReport(ctx context.Context, appID uuid.UUID, from, to time.Time, period string) (*TaxReport, error) {
+	if !TaxReportPeriods[period] {
+		return nil, fmt.Errorf("unsupported period %q", period)
+	}
+
+	rows, err := s.dbPool.Query(ctx, `
+		SELECT to_char(date_trunc($4::text, t.created_at AT TIME ZONE 'UTC'), 'YYYY-MM-DD') AS period,
+		       COALESCE(t.country_code, 'unknown')                                         AS country_code,
+		       t.currency,
+		       COUNT(*)                                                                    AS transactions,
+		       COUNT(*) FILTER (WHERE t.net_amount IS NULL)                                AS unreconciled,
+		       COALESCE(SUM(t.amount), 0)::float8                                          AS gross,
+		       COALESCE(SUM(t.store_fee), 0)::float8                                       AS store_fee,
+		       COALESCE(SUM(t.tax_amount), 0)::float8                                      AS tax,
+		       COALESCE(SUM(COALESCE(t.net_amount, t.amount)), 0)::float8                  AS net
+		FROM transactions t
+		WHERE t.app_id = $1 AND t.status = 'success'
+		  AND t.created_at >= $2 AND t.created_at < $3
+		GROUP BY 1, 2, 3
+		ORDER BY 1, 2, 3`, appID, from, to, period)
+	if err != nil {
+		return nil, fmt.Errorf("query tax report: %w", err)
+	}
+	defer rows.Close()
+
+	report := &TaxReport{From: from, To: to, Period: period, Rows: []TaxReportRow{}, Totals: []TaxReportTotal{}}
+	totalIdx := map[string]int{}
+	for rows.Next() {
+		var r TaxReportRow
+		if err := rows.Scan(&r.Period, &r.CountryCode, &r.Currency, &r.Transactions, &r.Unreconciled,
+			&r.Gross, &r.StoreFee, &r.Tax, &r.Net); err != nil {
+			return nil, fmt.Errorf("scan tax report row: %w", err)
+		}
+		report.Rows = append(report.Rows, r)
+
+		idx, ok := totalIdx[r.Currency]
+		if !ok {
+			idx = len(report.Totals)
+			totalIdx[r.Currency] = idx
+			report.Totals = append(report.Totals, TaxReportTotal{Currency: r.Currency})
+		}
+		total := &report.Totals[idx]
+		total.Transactions += r.Transactions
+		total.Gross = roundCents(total.Gross + r.Gross)
+		total.StoreFee = roundCents(total.StoreFee + r.StoreFee)
+		total.Tax = roundCents(total.Tax + r.Tax)
+		total.Net = roundCents(total.Net + r.Net)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate tax report rows: %w", err)
+	}
+
+	return report, nil
+}
+
+// ReconcileTransactions applies imported financial-report lines to the
+// matching transactions in a single database transaction.
+func (s *TaxReportService) ReconcileTransactions(ctx context.Context, appID uuid.UUID, items []TransactionReconciliation) (*ReconcileResult, error) {
+	tx, err := s.dbPool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin reconcile: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result := &ReconcileResult{Unmatched: []string{}}
+	for _, item := range items {
+		net := item.Gross - item.Tax - item.StoreFee
+		if item.Net != nil {
+			net = *item.Net
+		}
+		var countryCode *string
+		if cc := strings.ToUpper(strings.TrimSpace(item.CountryCode)); cc != "" {
+			countryCode = &cc
+		}
+
+		tag, err := tx.Exec(ctx, `
+			UPDATE transactions
+			SET amount = $3, currency = $4, store_fee = $5, tax_amount = $6, net_amount = $7,
+			    country_code = COALESCE($8, country_code), reconciled_at = now()
+			WHERE app_id = $1 AND provider_tx_id = $2 AND status = 'success'`,
+			appID, item.ProviderTxID, roundCents(item.Gross), strings.ToUpper(item.Currency),
+			roundCents(item.StoreFee), roundCents(item.Tax), roundCents(net), countryCode)
+		if err != nil {
+			return nil, fmt.Errorf("reconcile transaction %s: %w", item.ProviderTxID, err)
+		}
+		if tag.RowsAffected() == 0 {
+			result.Unmatched = append(result.Unmatched, item.ProviderTxID)
+			continue
+		}
+		result.Matched++
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit reconcile: %w", err)
+	}
+	return result, nil
+}
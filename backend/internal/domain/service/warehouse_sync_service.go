@@ -0,0 +1,382 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/infrastructure/external/warehouse"
+)
+
+const (
+	// warehouseSyncSettle keeps the sync this far behind now, so rows from
+	// transactions that commit late with earlier timestamps are not skipped
+	warehouseSyncSettle = time.Minute
+	// warehouseSyncLease is when a running sync is presumed dead and its table
+	// can be claimed again
+	warehouseSyncLease = time.Hour
+	warehouseSyncKey   = "id"
+)
+
+// WarehouseDestination is a warehouse synced tables are merged into
+type WarehouseDestination interface {
+	Name() string
+	EnsureTable(ctx context.Context, table string, columns []warehouse.Column) error
+	Merge(ctx context.Context, table, key string, columns []warehouse.Column, rows []warehouse.Row) error
+}
+
+// WarehouseSyncTable is a source table and the column its changes are
+// ordered by
+type WarehouseSyncTable struct {
+	Source      string
+	Destination string
+	Watermark   string
+	// Exclude lists columns that never leave the database
+	Exclude []string
+}
+
+// WarehouseSyncTables are the tables the sync copies
+var WarehouseSyncTables = []WarehouseSyncTable{
+	{Source: "users", Destination: "users", Watermark: "updated_at", Exclude: []string{"email"}},
+	{Source: "subscriptions", Destination: "subscriptions", Watermark: "updated_at"},
+	{Source: "transactions", Destination: "transactions", Watermark: "updated_at", Exclude: []string{"receipt_hash"}},
+	{Source: "ab_test_assignments", Destination: "assignments", Watermark: "updated_at"},
+	{Source: "bandit_conversion_events", Destination: "conversions", Watermark: "created_at"},
+}
+
+// WarehouseSyncState is the sync progress of one table
+type WarehouseSyncState struct {
+	SourceTable      string             `json:"source_table"`
+	DestinationTable string             `json:"destination_table"`
+	Destination      string             `json:"destination"`
+	Status           string             `json:"status"`
+	WatermarkAt      *time.Time         `json:"watermark_at"`
+	WatermarkID      *uuid.UUID         `json:"-"`
+	Columns          []warehouse.Column `json:"columns"`
+	RowsSynced       int64              `json:"rows_synced"`
+	LastError        *string            `json:"last_error"`
+	LastRunAt        *time.Time         `json:"last_run_at"`
+	LastSuccessAt    *time.Time         `json:"last_success_at"`
+}
+
+// WarehouseSyncService incrementally merges core tables into a warehouse.
+// Each batch advances the table's watermark, so an interrupted sync resumes
+// after its last merged batch and a repeated batch only re-merges the same
+// rows.
+type WarehouseSyncService struct {
+	pool      *pgxpool.Pool
+	dest      WarehouseDestination
+	logger    *zap.Logger
+	batchSize int
+	now       func() time.Time
+}
+
+// NewWarehouseSyncService creates a new warehouse sync service. dest may be
+// nil where only Status is used.
+func NewWarehouseSyncService(pool *pgxpool.Pool, dest WarehouseDestination, logger *zap.Logger) *WarehouseSyncService {
+	return &WarehouseSyncService{pool: pool, dest: dest, logger: logger, batchSize: 500, now: time.Now}
+}
+
+// WithBatchSize sets how many rows each merge carries
+func (s *WarehouseSyncService) WithBatchSize(n int) *WarehouseSyncService {
+	if n > 0 {
+		s.batchSize = n
+	}
+	return s
+}
+
+// Sync brings every table up to date. A failing table does not stop the
+// others; its error is recorded in its state.
+func (s *WarehouseSyncService) Sync(ctx context.Context) error {
+	if s.dest == nil {
+		return fmt.Errorf("no warehouse destination configured")
+	}
+	var errs []error
+	for _, t := range WarehouseSyncTables {
+		synced, err := s.syncTable(ctx, t)
+		if synced > 0 {
+			s.logger.Info("Warehouse table synced", zap.String("table", t.Source), zap.Int64("rows", synced))
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", t.Source, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *WarehouseSyncService) syncTable(ctx context.Context, t WarehouseSyncTable) (int64, error) {
+	state, claimed, err := s.claim(ctx, t)
+	if err != nil || !claimed {
+		return 0, err
+	}
+	synced, err := s.copyTable(ctx, t, state)
+	if err != nil {
+		s.finish(ctx, t.Source, err)
+		return synced, err
+	}
+	return synced, s.finish(ctx, t.Source, nil)
+}
+
+func (s *WarehouseSyncService) copyTable(ctx context.Context, t WarehouseSyncTable, state *WarehouseSyncState) (int64, error) {
+	source, err := s.sourceColumns(ctx, t.Source)
+	if err != nil {
+		return 0, err
+	}
+	columns, known := planWarehouseColumns(state.Columns, source, t.Exclude)
+	if len(known) != len(state.Columns) {
+		if err := s.dest.EnsureTable(ctx, t.Destination, known); err != nil {
+			return 0, err
+		}
+		if err := s.saveColumns(ctx, t.Source, known); err != nil {
+			return 0, err
+		}
+	}
+
+	query := warehouseBatchQuery(t, columns)
+	var synced int64
+	for {
+		rows, lastAt, lastID, err := s.readBatch(ctx, query, columns, state)
+		if err != nil {
+			return synced, err
+		}
+		if len(rows) == 0 {
+			return synced, nil
+		}
+		if err := s.dest.Merge(ctx, t.Destination, warehouseSyncKey, columns, rows); err != nil {
+			return synced, err
+		}
+		if _, err := s.pool.Exec(ctx, `
+			UPDATE warehouse_sync_state
+			SET watermark_at = $2, watermark_id = $3, rows_synced = rows_synced + $4
+			WHERE source_table = $1
+		`, t.Source, lastAt, lastID, len(rows)); err != nil {
+			return synced, fmt.Errorf("failed to advance watermark: %w", err)
+		}
+		synced += int64(len(rows))
+		state.WatermarkAt, state.WatermarkID = &lastAt, &lastID
+		if len(rows) < s.batchSize {
+			return synced, nil
+		}
+	}
+}
+
+// planWarehouseColumns returns the columns to select, in source order, and
+// the warehouse schema after appending new source columns. Columns keep the
+// type they were created with in the warehouse; columns dropped at the
+// source are no longer written.
+func planWarehouseColumns(known []warehouse.Column, source []warehouse.Column, exclude []string) (selected, schema []warehouse.Column) {
+	types := make(map[string]warehouse.ColumnType, len(known))
+	for _, c := range known {
+		types[c.Name] = c.Type
+	}
+	excluded := make(map[string]bool, len(exclude))
+	for _, name := range exclude {
+		excluded[name] = true
+	}
+	schema = append(schema, known...)
+	for _, c := range source {
+		if excluded[c.Name] {
+			continue
+		}
+		if typ, ok := types[c.Name]; ok {
+			selected = append(selected, warehouse.Column{Name: c.Name, Type: typ})
+			continue
+		}
+		selected = append(selected, c)
+		schema = append(schema, c)
+	}
+	return selected, schema
+}
+
+func (s *WarehouseSyncService) sourceColumns(ctx context.Context, table string) ([]warehouse.Column, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT column_name, data_type
+		FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = $1
+		ORDER BY ordinal_position
+	`, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+	defer rows.Close()
+	var columns []warehouse.Column
+	for rows.Next() {
+		var name, dataType string
+		if err := rows.Scan(&name, &dataType); err != nil {
+			return nil, fmt.Errorf("failed to scan column: %w", err)
+		}
+		columns = append(columns, warehouse.Column{Name: name, Type: warehouse.ColumnTypeForPostgres(dataType)})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("table %s not found", table)
+	}
+	return columns, nil
+}
+
+// warehouseBatchQuery selects the next batch after ($2, $3), cast to the
+// warehouse types, followed by the row's watermark and id
+func warehouseBatchQuery(t WarehouseSyncTable, columns []warehouse.Column) string {
+	exprs := make([]string, 0, len(columns)+2)
+	for _, c := range columns {
+		col := "t." + pgx.Identifier{c.Name}.Sanitize()
+		switch c.Type {
+		case warehouse.TypeInt:
+			exprs = append(exprs, col+"::bigint")
+		case warehouse.TypeFloat:
+			exprs = append(exprs, col+"::float8")
+		case warehouse.TypeBool:
+			exprs = append(exprs, col+"::boolean")
+		case warehouse.TypeTimestamp:
+			exprs = append(exprs, col+"::timestamptz")
+		case warehouse.TypeJSON:
+			exprs = append(exprs, "to_jsonb("+col+")::text")
+		default:
+			exprs = append(exprs, col+"::text")
+		}
+	}
+	wm := "t." + pgx.Identifier{t.Watermark}.Sanitize()
+	exprs = append(exprs, wm, "t.id::text")
+	return fmt.Sprintf(`
+		SELECT %s
+		FROM %s t
+		WHERE %s < $1 AND ($2::timestamptz IS NULL OR (%s, t.id) > ($2, $3::uuid))
+		ORDER BY %s, t.id
+		LIMIT $4
+	`, strings.Join(exprs, ", "), pgx.Identifier{t.Source}.Sanitize(), wm, wm, wm)
+}
+
+func (s *WarehouseSyncService) readBatch(ctx context.Context, query string, columns []warehouse.Column, state *WarehouseSyncState) ([]warehouse.Row, time.Time, uuid.UUID, error) {
+	var lastAt time.Time
+	var lastID uuid.UUID
+	rows, err := s.pool.Query(ctx, query, s.now().Add(-warehouseSyncSettle), state.WatermarkAt, state.WatermarkID, s.batchSize)
+	if err != nil {
+		return nil, lastAt, lastID, fmt.Errorf("failed to read batch: %w", err)
+	}
+	defer rows.Close()
+	var batch []warehouse.Row
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			return nil, lastAt, lastID, fmt.Errorf("failed to read row: %w", err)
+		}
+		row := make(warehouse.Row, len(columns))
+		copy(row, values[:len(columns)])
+		for i, c := range columns {
+			if raw, ok := row[i].(string); ok && c.Type == warehouse.TypeJSON {
+				row[i] = json.RawMessage(raw)
+			}
+		}
+		lastAt, _ = values[len(columns)].(time.Time)
+		idText, _ := values[len(columns)+1].(string)
+		if lastID, err = uuid.Parse(idText); err != nil {
+			return nil, lastAt, lastID, fmt.Errorf("failed to parse row id %q: %w", idText, err)
+		}
+		batch = append(batch, row)
+	}
+	return batch, lastAt, lastID, rows.Err()
+}
+
+// claim marks the table's sync as running unless another run holds it. A
+// different destination than recorded restarts the table from scratch.
+func (s *WarehouseSyncService) claim(ctx context.Context, t WarehouseSyncTable) (*WarehouseSyncState, bool, error) {
+	dest := s.dest.Name()
+	if _, err := s.pool.Exec(ctx, `
+		INSERT INTO warehouse_sync_state (source_table, destination) VALUES ($1, $2)
+		ON CONFLICT (source_table) DO NOTHING
+	`, t.Source, dest); err != nil {
+		return nil, false, fmt.Errorf("failed to create sync state: %w", err)
+	}
+	row := s.pool.QueryRow(ctx, `
+		UPDATE warehouse_sync_state
+		SET status = 'running',
+		    last_run_at = now(),
+		    destination = $2,
+		    watermark_at = CASE WHEN destination = $2 THEN watermark_at END,
+		    watermark_id = CASE WHEN destination = $2 THEN watermark_id END,
+		    columns = CASE WHEN destination = $2 THEN columns ELSE '[]' END,
+		    rows_synced = CASE WHEN destination = $2 THEN rows_synced ELSE 0 END
+		WHERE source_table = $1 AND (status <> 'running' OR last_run_at < now() - make_interval(secs => $3))
+		RETURNING `+warehouseSyncStateColumns,
+		t.Source, dest, warehouseSyncLease.Seconds())
+	state, err := scanWarehouseSyncState(row, t)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to claim sync: %w", err)
+	}
+	return state, true, nil
+}
+
+func (s *WarehouseSyncService) saveColumns(ctx context.Context, table string, columns []warehouse.Column) error {
+	raw, err := json.Marshal(columns)
+	if err != nil {
+		return err
+	}
+	if _, err := s.pool.Exec(ctx, `UPDATE warehouse_sync_state SET columns = $2 WHERE source_table = $1`, table, raw); err != nil {
+		return fmt.Errorf("failed to save warehouse columns: %w", err)
+	}
+	return nil
+}
+
+func (s *WarehouseSyncService) finish(ctx context.Context, table string, syncErr error) error {
+	var err error
+	if syncErr != nil {
+		_, err = s.pool.Exec(ctx, `
+			UPDATE warehouse_sync_state SET status = 'failed', last_error = $2 WHERE source_table = $1
+		`, table, syncErr.Error())
+	} else {
+		_, err = s.pool.Exec(ctx, `
+			UPDATE warehouse_sync_state SET status = 'idle', last_error = NULL, last_success_at = now() WHERE source_table = $1
+		`, table)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record sync result: %w", err)
+	}
+	return nil
+}
+
+// Status returns the sync state of every synced table; tables that never
+// ran are pending
+func (s *WarehouseSyncService) Status(ctx context.Context) ([]WarehouseSyncState, error) {
+	states := make([]WarehouseSyncState, 0, len(WarehouseSyncTables))
+	for _, t := range WarehouseSyncTables {
+		row := s.pool.QueryRow(ctx, `SELECT `+warehouseSyncStateColumns+` FROM warehouse_sync_state WHERE source_table = $1`, t.Source)
+		state, err := scanWarehouseSyncState(row, t)
+		if errors.Is(err, pgx.ErrNoRows) {
+			states = append(states, WarehouseSyncState{SourceTable: t.Source, DestinationTable: t.Destination, Status: "pending", Columns: []warehouse.Column{}})
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read sync state of %s: %w", t.Source, err)
+		}
+		states = append(states, *state)
+	}
+	return states, nil
+}
+
+const warehouseSyncStateColumns = `destination, status, watermark_at, watermark_id, columns, rows_synced, last_error, last_run_at, last_success_at`
+
+func scanWarehouseSyncState(row pgx.Row, t WarehouseSyncTable) (*WarehouseSyncState, error) {
+	state := WarehouseSyncState{SourceTable: t.Source, DestinationTable: t.Destination}
+	var columns []byte
+	if err := row.Scan(&state.Destination, &state.Status, &state.WatermarkAt, &state.WatermarkID, &columns,
+		&state.RowsSynced, &state.LastError, &state.LastRunAt, &state.LastSuccessAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(columns, &state.Columns); err != nil {
+		return nil, fmt.Errorf("failed to decode warehouse columns: %w", err)
+	}
+	return &state, nil
+}
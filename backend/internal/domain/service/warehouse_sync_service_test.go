@@ -0,0 +1,46 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bivex/paywall-iap/internal/infrastructure/external/warehouse"
+)
+
+func TestPlanWarehouseColumns_EvolvesSchema(t *testing.T) {
+	known := []warehouse.Column{
+		{Name: "id", Type: warehouse.TypeString},
+		{Name: "ltv", Type: warehouse.TypeFloat},
+		{Name: "legacy", Type: warehouse.TypeString},
+	}
+	source := []warehouse.Column{
+		{Name: "id", Type: warehouse.TypeString},
+		{Name: "email", Type: warehouse.TypeString},
+		// Changed at the source; the warehouse keeps FLOAT
+		{Name: "ltv", Type: warehouse.TypeInt},
+		{Name: "session_count", Type: warehouse.TypeInt},
+	}
+
+	selected, schema := planWarehouseColumns(known, source, []string{"email"})
+
+	assert.Equal(t, []warehouse.Column{
+		{Name: "id", Type: warehouse.TypeString},
+		{Name: "ltv", Type: warehouse.TypeFloat},
+		{Name: "session_count", Type: warehouse.TypeInt},
+	}, selected)
+	assert.Equal(t, append(known, warehouse.Column{Name: "session_count", Type: warehouse.TypeInt}), schema)
+}
+
+func TestWarehouseBatchQuery_CastsToWarehouseTypes(t *testing.T) {
+	query := warehouseBatchQuery(WarehouseSyncTable{Source: "transactions", Watermark: "updated_at"}, []warehouse.Column{
+		{Name: "id", Type: warehouse.TypeString},
+		{Name: "amount", Type: warehouse.TypeFloat},
+		{Name: "metadata", Type: warehouse.TypeJSON},
+	})
+
+	assert.Contains(t, query, `t."amount"::float8`)
+	assert.Contains(t, query, `to_jsonb(t."metadata")::text`)
+	assert.Contains(t, query, `(t."updated_at", t.id) > ($2, $3::uuid)`)
+	assert.Contains(t, query, `ORDER BY t."updated_at", t.id`)
+}
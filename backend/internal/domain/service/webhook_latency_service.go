@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// WebhookLossSettleDelay is how long a renewal may go without a provider
+// webhook before it counts as a suspected missing webhook
+const WebhookLossSettleDelay = 2 * time.Hour
+
+// ProviderWebhookLatency is the lag between a provider's event timestamps and
+// our receipt and processing of the webhooks, in seconds. Percentiles are nil
+// when no event in the window carried a provider timestamp.
+type ProviderWebhookLatency struct {
+	Provider             string   `json:"provider"`
+	Events               int64    `json:"events"`
+	Processed            int64    `json:"processed"`
+	ReceiptP50Seconds    *float64 `json:"receipt_p50_seconds"`
+	ReceiptP95Seconds    *float64 `json:"receipt_p95_seconds"`
+	ProcessingP50Seconds *float64 `json:"processing_p50_seconds"`
+	ProcessingP95Seconds *float64 `json:"processing_p95_seconds"`
+	// SuspectedMissing counts renewals recorded in the window, through receipt
+	// verification, for which no webhook arrived within WebhookLossSettleDelay
+	SuspectedMissing int64 `json:"suspected_missing"`
+}
+
+// WebhookLatencyReport is the per-provider webhook latency of an app
+type WebhookLatencyReport struct {
+	From      time.Time                `json:"from"`
+	To        time.Time                `json:"to"`
+	Providers []ProviderWebhookLatency `json:"providers"`
+}
+
+// WebhookLatencyService reports provider webhook latency and loss
+type WebhookLatencyService struct {
+	dbPool *pgxpool.Pool
+	now    func() time.Time
+}
+
+// NewWebhookLatencyService creates a new webhook latency service
+func NewWebhookLatencyService(dbPool *pgxpool.Pool) *WebhookLatencyService {
+	return &WebhookLatencyService{dbPool: dbPool, now: time.Now}
+}
+
+// Report computes the latency of webhooks received in the last window
+func (s *WebhookLatencyService) Report(ctx context.Context, appID uuid.UUID, window time.Duration) (*WebhookLatencyReport, error) {
+	now := s.now()
+	from := now.Add(-window)
+
+	rows, err := s.dbPool.Query(ctx, `
+		SELECT provider,
+			COUNT(*),
+			COUNT(processed_at),
+			percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM created_at - provider_event_at)),
+			percentile_cont(0.95) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM created_at - provider_event_at)),
+			percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM processed_at - provider_event_at)),
+			percentile_cont(0.95) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM processed_at - provider_event_at))
+		FROM webhook_events
+		WHERE app_id = $1 AND created_at >= $2
+		GROUP BY provider
+	`, appID, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhook latency: %w", err)
+	}
+	var latencies []ProviderWebhookLatency
+	for rows.Next() {
+		var l ProviderWebhookLatency
+		if err := rows.Scan(&l.Provider, &l.Events, &l.Processed,
+			&l.ReceiptP50Seconds, &l.ReceiptP95Seconds,
+			&l.ProcessingP50Seconds, &l.ProcessingP95Seconds); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan webhook latency: %w", err)
+		}
+		latencies = append(latencies, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read webhook latency: %w", err)
+	}
+
+	// A renewal is any ledger entry after the subscription's first one
+	rows, err = s.dbPool.Query(ctx, `
+		SELECT t.provider, COUNT(*)
+		FROM transactions t
+		WHERE t.app_id = $1 AND t.webhook_seen_at IS NULL AND t.status <> 'failed'
+		  AND t.created_at >= $2 AND t.created_at < $3
+		  AND EXISTS (
+			SELECT 1 FROM transactions p
+			WHERE p.subscription_id = t.subscription_id AND p.created_at < t.created_at
+		  )
+		GROUP BY t.provider
+	`, appID, from, now.Add(-WebhookLossSettleDelay))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read suspected missing webhooks: %w", err)
+	}
+	missing := map[string]int64{}
+	for rows.Next() {
+		var provider string
+		var count int64
+		if err := rows.Scan(&provider, &count); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan suspected missing webhooks: %w", err)
+		}
+		missing[provider] = count
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read suspected missing webhooks: %w", err)
+	}
+
+	return &WebhookLatencyReport{From: from, To: now, Providers: mergeWebhookLoss(latencies, missing)}, nil
+}
+
+// mergeWebhookLoss adds suspected missing counts to the latency rows, including
+// providers with losses but no webhooks at all, sorted by provider
+func mergeWebhookLoss(latencies []ProviderWebhookLatency, missing map[string]int64) []ProviderWebhookLatency {
+	merged := make([]ProviderWebhookLatency, 0, len(latencies)+len(missing))
+	seen := map[string]bool{}
+	for _, l := range latencies {
+		l.SuspectedMissing = missing[l.Provider]
+		seen[l.Provider] = true
+		merged = append(merged, l)
+	}
+	for provider, count := range missing {
+		if !seen[provider] {
+			merged = append(merged, ProviderWebhookLatency{Provider: provider, SuspectedMissing: count})
+		}
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Provider < merged[j].Provider })
+	return merged
+}
@@ -0,0 +1,29 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeWebhookLoss_IncludesProvidersWithOnlyLosses(t *testing.T) {
+	p50 := 3.0
+	merged := mergeWebhookLoss(
+		[]ProviderWebhookLatency{{Provider: "stripe", Events: 10, ReceiptP50Seconds: &p50}, {Provider: "apple", Events: 4}},
+		map[string]int64{"apple": 2, "google": 1},
+	)
+
+	require.Len(t, merged, 3)
+	assert.Equal(t, "apple", merged[0].Provider)
+	assert.Equal(t, int64(2), merged[0].SuspectedMissing)
+	assert.Equal(t, int64(4), merged[0].Events)
+
+	assert.Equal(t, "google", merged[1].Provider, "losses without any webhook are still reported")
+	assert.Equal(t, int64(1), merged[1].SuspectedMissing)
+	assert.Nil(t, merged[1].ReceiptP50Seconds)
+
+	assert.Equal(t, "stripe", merged[2].Provider)
+	assert.Zero(t, merged[2].SuspectedMissing)
+	assert.Equal(t, &p50, merged[2].ReceiptP50Seconds)
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	WebhookQuarantineStatusQuarantined = "quarantined"
+	WebhookQuarantineStatusReparsed    = "reparsed"
+	WebhookQuarantineStatusDiscarded   = "discarded"
+)
+
+// ErrWebhookQuarantineNotFound is returned for an unknown quarantine entry
+var ErrWebhookQuarantineNotFound = errors.New("webhook quarantine entry not found")
+
+// ErrWebhookQuarantineResolved is returned when re-parsing or discarding an
+// entry that was already re-parsed or discarded
+var ErrWebhookQuarantineResolved = errors.New("webhook quarantine entry already resolved")
+
+// WebhookQuarantineEntry is an authenticated provider delivery that could not
+// be parsed, stored with its raw request body.
+type WebhookQuarantineEntry struct {
+	ID         uuid.UUID  `json:"id"`
+	Provider   string     `json:"provider"`
+	RawBody    []byte     `json:"-"`
+	Error      string     `json:"error"`
+	Status     string     `json:"status"`
+	Attempts   int        `json:"attempts"`
+	LastError  *string    `json:"last_error,omitempty"`
+	EventType  *string    `json:"event_type,omitempty"`
+	EventID    *string    `json:"event_id,omitempty"`
+	ReceivedAt time.Time  `json:"received_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+}
+
+type WebhookQuarantineFilter struct {
+	Provider string
+	Status   string
+	Limit    int
+}
+
+type WebhookQuarantineRepository interface {
+	QuarantineWebhook(ctx context.Context, provider string, rawBody []byte, reason string) (uuid.UUID, error)
+	// ListQuarantinedWebhooks omits raw bodies; fetch one entry to inspect it
+	ListQuarantinedWebhooks(ctx context.Context, filter WebhookQuarantineFilter) ([]WebhookQuarantineEntry, error)
+	GetQuarantinedWebhook(ctx context.Context, id uuid.UUID) (*WebhookQuarantineEntry, error)
+	MarkWebhookReparsed(ctx context.Context, id uuid.UUID, eventType, eventID string) error
+	RecordWebhookReparseFailure(ctx context.Context, id uuid.UUID, reason string) error
+	DiscardQuarantinedWebhook(ctx context.Context, id uuid.UUID) error
+}
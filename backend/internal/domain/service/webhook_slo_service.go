@@ -0,0 +1,398 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/clock"
+	"github.com/bivex/paywall-iap/internal/infrastructure/metrics"
+)
+
+// WebhookSLOPeriod is the period error budgets are spent over
+const WebhookSLOPeriod = 30 * 24 * time.Hour
+
+// RenewalWebhookEventTypes are the provider event types announcing a renewal
+var RenewalWebhookEventTypes = []string{
+	"DID_RENEW",                 // Apple
+	"subscription.2",            // Google SUBSCRIPTION_RENEWED
+	"invoice.paid",              // Stripe
+	"invoice.payment_succeeded", // Stripe, older API versions
+	"SUBSCRIPTION_RENEWED",      // Amazon
+	"SUBSCRIPTION.2",            // Huawei RENEWAL
+	"SUBSCRIPTION.7",            // Huawei RENEWAL_RECURRING
+	"PAYMENT.SALE.COMPLETED",    // PayPal
+}
+
+// WebhookSLO is a webhook latency objective: Objective of the webhooks of
+// EventTypes, every webhook when empty, are processed within Threshold of the
+// provider's event time. Webhooks without a provider timestamp count from
+// their receipt.
+type WebhookSLO struct {
+	Name        string
+	Description string
+	Threshold   time.Duration
+	Objective   float64
+	EventTypes  []string
+}
+
+// RenewalWebhookSLO is the objective for renewals to reach subscription state
+func RenewalWebhookSLO(threshold time.Duration, objective float64) WebhookSLO {
+	return WebhookSLO{
+		Name:        "renewal_processing",
+		Description: fmt.Sprintf("%s of renewal webhooks reflected in subscription state within %s", formatObjective(objective), threshold),
+		Threshold:   threshold,
+		Objective:   objective,
+		EventTypes:  RenewalWebhookEventTypes,
+	}
+}
+
+// ProcessingWebhookSLO is the objective for every webhook to be processed
+func ProcessingWebhookSLO(threshold time.Duration, objective float64) WebhookSLO {
+	return WebhookSLO{
+		Name:        "webhook_processing",
+		Description: fmt.Sprintf("%s of webhooks processed within %s", formatObjective(objective), threshold),
+		Threshold:   threshold,
+		Objective:   objective,
+	}
+}
+
+func formatObjective(objective float64) string {
+	return strconv.FormatFloat(objective*100, 'f', -1, 64) + "%"
+}
+
+// BurnRateAlert fires when the error budget burns at least BurnRate times
+// faster than the period allows over both windows. The long window shows the
+// burn is significant, the short one that it is still going on.
+type BurnRateAlert struct {
+	Severity    string
+	LongWindow  time.Duration
+	ShortWindow time.Duration
+	BurnRate    float64
+}
+
+// DefaultBurnRateAlerts page when 2% of a 30 day budget burns in an hour or 5%
+// in six hours, and open a ticket when 10% burns in three days
+var DefaultBurnRateAlerts = []BurnRateAlert{
+	{Severity: "page", LongWindow: time.Hour, ShortWindow: 5 * time.Minute, BurnRate: 14.4},
+	{Severity: "page", LongWindow: 6 * time.Hour, ShortWindow: 30 * time.Minute, BurnRate: 6},
+	{Severity: "ticket", LongWindow: 3 * 24 * time.Hour, ShortWindow: 6 * time.Hour, BurnRate: 1},
+}
+
+// WebhookSLOWindow is one SLO measured over the last Window. Webhooks still
+// pending within the threshold are not counted yet.
+type WebhookSLOWindow struct {
+	Window string `json:"window"`
+	Good   int64  `json:"good"`
+	Bad    int64  `json:"bad"`
+	// SLI is the share of good webhooks, nil when none was counted
+	SLI *float64 `json:"sli"`
+	// BurnRate is how many times faster than allowed the error budget burns
+	BurnRate float64 `json:"burn_rate"`
+}
+
+// WebhookSLOAlert is the state of one burn rate alert
+type WebhookSLOAlert struct {
+	Severity      string  `json:"severity"`
+	LongWindow    string  `json:"long_window"`
+	ShortWindow   string  `json:"short_window"`
+	BurnRate      float64 `json:"burn_rate"`
+	LongBurnRate  float64 `json:"long_burn_rate"`
+	ShortBurnRate float64 `json:"short_burn_rate"`
+	Firing        bool    `json:"firing"`
+}
+
+// WebhookSLOStatus is an SLO with its windows and alerts. Status is red while
+// a page fires, yellow while a ticket fires.
+type WebhookSLOStatus struct {
+	Name             string       `json:"name"`
+	Description      string       `json:"description"`
+	ThresholdSeconds float64      `json:"threshold_seconds"`
+	Objective        float64      `json:"objective"`
+	Status           HealthStatus `json:"status"`
+	// ErrorBudgetRemaining is the share of the period's budget left, negative
+	// once overspent
+	ErrorBudgetRemaining float64            `json:"error_budget_remaining"`
+	Windows              []WebhookSLOWindow `json:"windows"`
+	Alerts               []WebhookSLOAlert  `json:"alerts"`
+}
+
+// WebhookSLOReport is the status of every webhook SLO
+type WebhookSLOReport struct {
+	EvaluatedAt time.Time          `json:"evaluated_at"`
+	SLOs        []WebhookSLOStatus `json:"slos"`
+}
+
+// webhookSLOCounts are the good and bad webhooks of one window
+type webhookSLOCounts struct {
+	good, bad int64
+}
+
+var (
+	webhookSLOBurnRate = metrics.NewGaugeVec(
+		"webhook_slo_burn_rate",
+		"How many times faster than the SLO allows the webhook latency error budget burns",
+		"slo", "window",
+	)
+	webhookSLOBudgetRemaining = metrics.NewGaugeVec(
+		"webhook_slo_error_budget_remaining",
+		"Share of the 30 day webhook latency error budget left",
+		"slo",
+	)
+	webhookSLOAlertFiring = metrics.NewGaugeVec(
+		"webhook_slo_alert_firing",
+		"1 while a webhook latency burn rate alert fires",
+		"slo", "severity", "long_window",
+	)
+)
+
+// WebhookSLOService measures webhook latency SLOs from the webhook_events
+// timestamps and alerts on their burn rates
+type WebhookSLOService struct {
+	dbPool *pgxpool.Pool
+	slos   []WebhookSLO
+	alerts []BurnRateAlert
+	logger *zap.Logger
+	now    func() time.Time
+
+	// firing remembers the alerts raised by Run, so only changes are logged
+	firing map[string]bool
+}
+
+// NewWebhookSLOService creates a webhook SLO service with the default alerts
+func NewWebhookSLOService(dbPool *pgxpool.Pool, slos []WebhookSLO, logger *zap.Logger) *WebhookSLOService {
+	return &WebhookSLOService{
+		dbPool: dbPool,
+		slos:   slos,
+		alerts: DefaultBurnRateAlerts,
+		logger: logger,
+		now:    time.Now,
+		firing: map[string]bool{},
+	}
+}
+
+// WithClock makes the evaluation windows end at c's current time
+func (s *WebhookSLOService) WithClock(c clock.Clock) *WebhookSLOService {
+	s.now = c.Now
+	return s
+}
+
+// Evaluate measures every SLO for an app, or across apps when appID is uuid.Nil
+func (s *WebhookSLOService) Evaluate(ctx context.Context, appID uuid.UUID) (*WebhookSLOReport, error) {
+	now := s.now()
+	windows := webhookSLOWindows(s.alerts)
+	seconds := make([]int64, len(windows))
+	for i, window := range windows {
+		seconds[i] = int64(window / time.Second)
+	}
+	var appFilter *uuid.UUID
+	if appID != uuid.Nil {
+		appFilter = &appID
+	}
+
+	report := &WebhookSLOReport{EvaluatedAt: now, SLOs: make([]WebhookSLOStatus, 0, len(s.slos))}
+	for _, slo := range s.slos {
+		counts, err := s.countWebhooks(ctx, appFilter, slo, now, seconds)
+		if err != nil {
+			return nil, fmt.Errorf("failed to measure webhook SLO %s: %w", slo.Name, err)
+		}
+		report.SLOs = append(report.SLOs, evaluateWebhookSLO(slo, s.alerts, windows, counts))
+	}
+	return report, nil
+}
+
+// countWebhooks counts the good and bad webhooks received in each window.
+// A webhook is bad once it took, or has been pending for, longer than the
+// threshold.
+func (s *WebhookSLOService) countWebhooks(ctx context.Context, appID *uuid.UUID, slo WebhookSLO, now time.Time, windowSeconds []int64) (map[time.Duration]webhookSLOCounts, error) {
+	eventTypes := slo.EventTypes
+	if eventTypes == nil {
+		eventTypes = []string{}
+	}
+	rows, err := s.dbPool.Query(ctx, `
+		SELECT w.seconds,
+			COUNT(e.id) FILTER (WHERE e.processed_at IS NOT NULL
+				AND EXTRACT(EPOCH FROM e.processed_at - e.started_at) <= $4::float8),
+			COUNT(e.id) FILTER (WHERE EXTRACT(EPOCH FROM COALESCE(e.processed_at, $2::timestamptz) - e.started_at) > $4::float8)
+		FROM unnest($3::bigint[]) AS w(seconds)
+		LEFT JOIN (
+			SELECT id, created_at, processed_at, COALESCE(provider_event_at, created_at) AS started_at
+			FROM webhook_events
+			WHERE ($1::uuid IS NULL OR app_id = $1)
+			  AND created_at >= $2::timestamptz - make_interval(secs => $5::float8) AND created_at <= $2::timestamptz
+			  AND (cardinality($6::text[]) = 0 OR event_type = ANY($6::text[]))
+		) e ON e.created_at >= $2::timestamptz - make_interval(secs => w.seconds::float8)
+		GROUP BY w.seconds
+	`, appID, now, windowSeconds, slo.Threshold.Seconds(), float64(windowSeconds[len(windowSeconds)-1]), eventTypes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[time.Duration]webhookSLOCounts{}
+	for rows.Next() {
+		var seconds int64
+		var c webhookSLOCounts
+		if err := rows.Scan(&seconds, &c.good, &c.bad); err != nil {
+			return nil, err
+		}
+		counts[time.Duration(seconds)*time.Second] = c
+	}
+	return counts, rows.Err()
+}
+
+// webhookSLOWindows are the alert windows and the period, shortest first
+func webhookSLOWindows(alerts []BurnRateAlert) []time.Duration {
+	seen := map[time.Duration]bool{WebhookSLOPeriod: true}
+	windows := []time.Duration{WebhookSLOPeriod}
+	for _, alert := range alerts {
+		for _, window := range []time.Duration{alert.LongWindow, alert.ShortWindow} {
+			if !seen[window] {
+				seen[window] = true
+				windows = append(windows, window)
+			}
+		}
+	}
+	sort.Slice(windows, func(i, j int) bool { return windows[i] < windows[j] })
+	return windows
+}
+
+// evaluateWebhookSLO turns window counts into burn rates and alert states
+func evaluateWebhookSLO(slo WebhookSLO, alerts []BurnRateAlert, windows []time.Duration, counts map[time.Duration]webhookSLOCounts) WebhookSLOStatus {
+	budget := 1 - slo.Objective
+	burnRates := make(map[time.Duration]float64, len(windows))
+
+	status := WebhookSLOStatus{
+		Name:             slo.Name,
+		Description:      slo.Description,
+		ThresholdSeconds: slo.Threshold.Seconds(),
+		Objective:        slo.Objective,
+		Status:           HealthGreen,
+		Windows:          make([]WebhookSLOWindow, 0, len(windows)),
+		Alerts:           make([]WebhookSLOAlert, 0, len(alerts)),
+	}
+	for _, window := range windows {
+		c := counts[window]
+		w := WebhookSLOWindow{Window: formatSLOWindow(window), Good: c.good, Bad: c.bad}
+		if total := c.good + c.bad; total > 0 {
+			sli := float64(c.good) / float64(total)
+			w.SLI = &sli
+			w.BurnRate = (1 - sli) / budget
+		}
+		burnRates[window] = w.BurnRate
+		status.Windows = append(status.Windows, w)
+	}
+	status.ErrorBudgetRemaining = 1 - burnRates[WebhookSLOPeriod]
+	if counts[WebhookSLOPeriod].good+counts[WebhookSLOPeriod].bad == 0 {
+		status.Status = HealthUnknown
+	}
+
+	for _, alert := range alerts {
+		a := WebhookSLOAlert{
+			Severity:      alert.Severity,
+			LongWindow:    formatSLOWindow(alert.LongWindow),
+			ShortWindow:   formatSLOWindow(alert.ShortWindow),
+			BurnRate:      alert.BurnRate,
+			LongBurnRate:  burnRates[alert.LongWindow],
+			ShortBurnRate: burnRates[alert.ShortWindow],
+		}
+		a.Firing = a.LongBurnRate >= alert.BurnRate && a.ShortBurnRate >= alert.BurnRate
+		if a.Firing {
+			severity := HealthYellow
+			if alert.Severity == "page" {
+				severity = HealthRed
+			}
+			if healthSeverity[severity] > healthSeverity[status.Status] {
+				status.Status = severity
+			}
+		}
+		status.Alerts = append(status.Alerts, a)
+	}
+	return status
+}
+
+// formatSLOWindow renders windows the way alerting rules name them: 5m, 6h, 3d
+func formatSLOWindow(window time.Duration) string {
+	switch {
+	case window%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", window/(24*time.Hour))
+	case window%time.Hour == 0:
+		return fmt.Sprintf("%dh", window/time.Hour)
+	default:
+		return fmt.Sprintf("%dm", window/time.Minute)
+	}
+}
+
+// Run evaluates the SLOs across apps every interval until ctx is done,
+// publishing the burn rates at /metrics and logging alerts as they start and
+// stop firing
+func (s *WebhookSLOService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.evaluateOnce(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *WebhookSLOService) evaluateOnce(ctx context.Context) {
+	evalCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	report, err := s.Evaluate(evalCtx, uuid.Nil)
+	if err != nil {
+		if ctx.Err() == nil {
+			s.logger.Warn("Failed to evaluate webhook SLOs", zap.Error(err))
+		}
+		return
+	}
+	s.publish(report)
+}
+
+// publish sets the SLO gauges and logs alert changes; alerts that start
+// firing are logged as errors so they reach Sentry
+func (s *WebhookSLOService) publish(report *WebhookSLOReport) {
+	for _, slo := range report.SLOs {
+		for _, w := range slo.Windows {
+			webhookSLOBurnRate.Set(w.BurnRate, slo.Name, w.Window)
+		}
+		webhookSLOBudgetRemaining.Set(slo.ErrorBudgetRemaining, slo.Name)
+
+		for _, alert := range slo.Alerts {
+			firing := 0.0
+			if alert.Firing {
+				firing = 1
+			}
+			webhookSLOAlertFiring.Set(firing, slo.Name, alert.Severity, alert.LongWindow)
+
+			key := slo.Name + "/" + alert.Severity + "/" + alert.LongWindow
+			if alert.Firing == s.firing[key] {
+				continue
+			}
+			s.firing[key] = alert.Firing
+			fields := []zap.Field{
+				zap.String("slo", slo.Name),
+				zap.String("severity", alert.Severity),
+				zap.String("long_window", alert.LongWindow),
+				zap.String("short_window", alert.ShortWindow),
+				zap.Float64("long_burn_rate", alert.LongBurnRate),
+				zap.Float64("short_burn_rate", alert.ShortBurnRate),
+				zap.Float64("error_budget_remaining", slo.ErrorBudgetRemaining),
+			}
+			if alert.Firing {
+				s.logger.Error("Webhook SLO is burning its error budget", fields...)
+			} else {
+				s.logger.Info("Webhook SLO burn rate back within budget", fields...)
+			}
+		}
+	}
+}
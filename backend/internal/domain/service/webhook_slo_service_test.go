@@ -0,0 +1,127 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestWebhookSLOWindows(t *testing.T) {
+	windows := webhookSLOWindows(DefaultBurnRateAlerts)
+
+	assert.Equal(t, []time.Duration{
+		5 * time.Minute, 30 * time.Minute, time.Hour, 6 * time.Hour, 3 * 24 * time.Hour, WebhookSLOPeriod,
+	}, windows)
+}
+
+func TestFormatSLOWindow(t *testing.T) {
+	assert.Equal(t, "5m", formatSLOWindow(5*time.Minute))
+	assert.Equal(t, "90m", formatSLOWindow(90*time.Minute))
+	assert.Equal(t, "6h", formatSLOWindow(6*time.Hour))
+	assert.Equal(t, "30d", formatSLOWindow(WebhookSLOPeriod))
+}
+
+func TestEvaluateWebhookSLO(t *testing.T) {
+	slo := RenewalWebhookSLO(time.Minute, 0.95)
+	windows := webhookSLOWindows(DefaultBurnRateAlerts)
+
+	t.Run("no webhooks leaves the status unknown", func(t *testing.T) {
+		status := evaluateWebhookSLO(slo, DefaultBurnRateAlerts, windows, nil)
+
+		assert.Equal(t, HealthUnknown, status.Status)
+		assert.Equal(t, 1.0, status.ErrorBudgetRemaining)
+		require.Len(t, status.Windows, len(windows))
+		assert.Nil(t, status.Windows[0].SLI)
+		for _, alert := range status.Alerts {
+			assert.False(t, alert.Firing)
+		}
+	})
+
+	t.Run("fast burn pages", func(t *testing.T) {
+		// Four in five of the last hour's renewals were late, burning the 5%
+		// budget 16 times faster than allowed
+		counts := map[time.Duration]webhookSLOCounts{
+			5 * time.Minute:    {good: 2, bad: 8},
+			30 * time.Minute:   {good: 20, bad: 10},
+			time.Hour:          {good: 20, bad: 80},
+			6 * time.Hour:      {good: 400, bad: 40},
+			3 * 24 * time.Hour: {good: 4000, bad: 40},
+			WebhookSLOPeriod:   {good: 9960, bad: 40},
+		}
+
+		status := evaluateWebhookSLO(slo, DefaultBurnRateAlerts, windows, counts)
+
+		assert.Equal(t, HealthRed, status.Status)
+		assert.Equal(t, "renewal_processing", status.Name)
+		assert.Equal(t, 60.0, status.ThresholdSeconds)
+		assert.InDelta(t, 0.92, status.ErrorBudgetRemaining, 1e-9)
+		require.Len(t, status.Alerts, 3)
+		assert.True(t, status.Alerts[0].Firing)
+		assert.InDelta(t, 16, status.Alerts[0].LongBurnRate, 1e-9)
+		assert.InDelta(t, 16, status.Alerts[0].ShortBurnRate, 1e-9)
+		assert.False(t, status.Alerts[1].Firing, "6h window burns below 6")
+		assert.False(t, status.Alerts[2].Firing, "3d window burns below 1")
+	})
+
+	t.Run("slow burn opens a ticket", func(t *testing.T) {
+		counts := map[time.Duration]webhookSLOCounts{
+			5 * time.Minute:    {good: 1},
+			30 * time.Minute:   {good: 10},
+			time.Hour:          {good: 20},
+			6 * time.Hour:      {good: 90, bad: 10},
+			3 * 24 * time.Hour: {good: 900, bad: 100},
+			WebhookSLOPeriod:   {good: 9000, bad: 1000},
+		}
+
+		status := evaluateWebhookSLO(slo, DefaultBurnRateAlerts, windows, counts)
+
+		assert.Equal(t, HealthYellow, status.Status)
+		assert.InDelta(t, -1, status.ErrorBudgetRemaining, 1e-9)
+		assert.False(t, status.Alerts[0].Firing)
+		assert.True(t, status.Alerts[2].Firing)
+	})
+
+	t.Run("a burn that stopped does not fire", func(t *testing.T) {
+		counts := map[time.Duration]webhookSLOCounts{
+			5 * time.Minute:  {good: 10},
+			time.Hour:        {good: 20, bad: 80},
+			WebhookSLOPeriod: {good: 9920, bad: 80},
+		}
+
+		status := evaluateWebhookSLO(slo, DefaultBurnRateAlerts, windows, counts)
+
+		assert.Equal(t, HealthGreen, status.Status)
+		assert.Equal(t, 0.0, status.Alerts[0].ShortBurnRate)
+		assert.False(t, status.Alerts[0].Firing)
+	})
+}
+
+func TestWebhookSLOService_PublishLogsAlertChanges(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	svc := NewWebhookSLOService(nil, nil, zap.New(core))
+	report := func(firing bool) *WebhookSLOReport {
+		return &WebhookSLOReport{SLOs: []WebhookSLOStatus{{
+			Name:                 "test_publish",
+			ErrorBudgetRemaining: 0.5,
+			Windows:              []WebhookSLOWindow{{Window: "1h", BurnRate: 20}},
+			Alerts:               []WebhookSLOAlert{{Severity: "page", LongWindow: "1h", ShortWindow: "5m", Firing: firing}},
+		}}}
+	}
+
+	svc.publish(report(true))
+	svc.publish(report(true))
+	svc.publish(report(false))
+
+	entries := logs.All()
+	require.Len(t, entries, 2)
+	assert.Equal(t, zapcore.ErrorLevel, entries[0].Level)
+	assert.Equal(t, zapcore.InfoLevel, entries[1].Level)
+	assert.Equal(t, 20.0, webhookSLOBurnRate.Value("test_publish", "1h"))
+	assert.Equal(t, 0.5, webhookSLOBudgetRemaining.Value("test_publish"))
+	assert.Equal(t, 0.0, webhookSLOAlertFiring.Value("test_publish", "page", "1h"))
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	// winProbabilityTTL bounds how long a cached win probability is served
+	winProbabilityTTL = 5 * time.Minute
+	// winProbabilityRecomputeSamples is how many new samples across the
+	// experiment's arms make a cached win probability worth recomputing
+	winProbabilityRecomputeSamples = 200
+)
+
+// WinProbabilityResult is a win probability simulation together with when it
+// ran, how many draws it took and the arm samples it saw. Simulations can be
+// below RequestedSimulations when the simulation budget capped them.
+type WinProbabilityResult struct {
+	Probabilities        map[uuid.UUID]float64 `json:"probabilities"`
+	ExpectedLoss         map[uuid.UUID]float64 `json:"expected_loss"`
+	CachedAt             time.Time             `json:"cached_at"`
+	Simulations          int                   `json:"simulations"`
+	RequestedSimulations int                   `json:"requested_simulations"`
+	Samples              int                   `json:"samples"`
+}
+
+func winProbabilityCacheKey(experimentID uuid.UUID) string {
+	return fmt.Sprintf("ab:winprob:%s", experimentID.String())
+}
+
+// CachedWinProbability returns the experiment's win probabilities from a
+// short-lived cache, simulating them only on a miss or when the cached result
+// asked for fewer than simulations draws. Once the arms gathered
+// winProbabilityRecomputeSamples new samples the cached result is still
+// served while a recompute runs in the background.
+func (b *ThompsonSamplingBandit) CachedWinProbability(ctx context.Context, experimentID uuid.UUID, simulations int) (*WinProbabilityResult, error) {
+	if cached, ok := b.cachedWinProbability(ctx, experimentID); ok && cached.RequestedSimulations >= simulations {
+		if samples, err := b.currentSamples(ctx, experimentID); err == nil && samples-cached.Samples >= winProbabilityRecomputeSamples {
+			b.flights.DoChan(winProbabilityFlight(experimentID, simulations), func() (interface{}, error) {
+				return b.refreshWinProbability(context.WithoutCancel(ctx), experimentID, simulations)
+			})
+		}
+		return cached, nil
+	}
+
+	result, err, _ := b.flights.Do(winProbabilityFlight(experimentID, simulations), func() (interface{}, error) {
+		return b.refreshWinProbability(context.WithoutCancel(ctx), experimentID, simulations)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*WinProbabilityResult), nil
+}
+
+func winProbabilityFlight(experimentID uuid.UUID, simulations int) string {
+	return fmt.Sprintf("winprob:%s:%d", experimentID.String(), simulations)
+}
+
+func (b *ThompsonSamplingBandit) cachedWinProbability(ctx context.Context, experimentID uuid.UUID) (*WinProbabilityResult, bool) {
+	raw, err := b.cache.GetBytes(ctx, winProbabilityCacheKey(experimentID))
+	if err != nil || len(raw) == 0 {
+		return nil, false
+	}
+	var cached WinProbabilityResult
+	if err := json.Unmarshal(raw, &cached); err != nil {
+		return nil, false
+	}
+	return &cached, true
+}
+
+// refreshWinProbability simulates the win probabilities and caches them
+func (b *ThompsonSamplingBandit) refreshWinProbability(ctx context.Context, experimentID uuid.UUID, simulations int) (*WinProbabilityResult, error) {
+	simulation, err := b.SimulateArms(ctx, experimentID, simulations)
+	if err != nil {
+		return nil, err
+	}
+	result := &WinProbabilityResult{
+		Probabilities:        simulation.WinProbabilities,
+		ExpectedLoss:         simulation.ExpectedLoss,
+		CachedAt:             time.Now().UTC(),
+		Simulations:          simulation.Simulations,
+		RequestedSimulations: simulations,
+		Samples:              simulation.Samples,
+	}
+	if raw, err := json.Marshal(result); err == nil {
+		if err := b.cache.SetBytes(ctx, winProbabilityCacheKey(experimentID), raw, winProbabilityTTL); err != nil {
+			b.logger.Warn("Failed to cache win probability", zap.String("experiment_id", experimentID.String()), zap.Error(err))
+		}
+	}
+	return result, nil
+}
+
+// currentSamples totals the samples of the experiment's active arms, reading
+// cached arm stats before the database
+func (b *ThompsonSamplingBandit) currentSamples(ctx context.Context, experimentID uuid.UUID) (int, error) {
+	arms, err := b.repo.GetArms(ctx, experimentID)
+	if err != nil {
+		return 0, err
+	}
+	samples := 0
+	for _, arm := range activeArms(arms) {
+		stats, err := b.cache.GetArmStats(ctx, fmt.Sprintf("ab:arm:%s", arm.ID.String()))
+		if err != nil || stats == nil {
+			if stats, err = b.repo.GetArmStats(ctx, arm.ID); err != nil {
+				return 0, err
+			}
+		}
+		samples += stats.Samples
+	}
+	return samples, nil
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// bytesTestCache keeps JSON entries in memory and misses arm stats
+type bytesTestCache struct {
+	advancedEngineTestCache
+
+	mu    sync.Mutex
+	bytes map[string][]byte
+	ttls  map[string]time.Duration
+	sets  int
+}
+
+func newBytesTestCache() *bytesTestCache {
+	return &bytesTestCache{bytes: map[string][]byte{}, ttls: map[string]time.Duration{}}
+}
+
+func (c *bytesTestCache) GetArmStats(ctx context.Context, key string) (*ArmStats, error) {
+	return nil, errors.New("cache miss")
+}
+func (c *bytesTestCache) SetBytes(ctx context.Context, key string, data []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.bytes[key], c.ttls[key] = data, ttl
+	c.sets++
+	return nil
+}
+func (c *bytesTestCache) GetBytes(ctx context.Context, key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data, ok := c.bytes[key]
+	if !ok {
+		return nil, errors.New("cache miss")
+	}
+	return data, nil
+}
+func (c *bytesTestCache) setCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.sets
+}
+
+func TestThompsonSamplingBandit_CachedWinProbability(t *testing.T) {
+	experimentID, control, variant := uuid.New(), uuid.New(), uuid.New()
+	repo := &advancedEngineTestRepo{
+		arms: []Arm{{ID: control, IsControl: true}, {ID: variant}},
+		armStats: map[uuid.UUID]*ArmStats{
+			control: {ArmID: control, Alpha: 11, Beta: 91, Samples: 100},
+			variant: {ArmID: variant, Alpha: 30, Beta: 72, Samples: 100},
+		},
+	}
+	cache := newBytesTestCache()
+	bandit := NewThompsonSamplingBandit(repo, cache, zap.NewNop()).WithSeed(1)
+
+	first, err := bandit.CachedWinProbability(context.Background(), experimentID, 1000)
+	require.NoError(t, err)
+	assert.Equal(t, 1000, first.Simulations)
+	assert.Equal(t, 200, first.Samples)
+	assert.Greater(t, first.Probabilities[variant], first.Probabilities[control])
+	assert.Equal(t, winProbabilityTTL, cache.ttls[winProbabilityCacheKey(experimentID)])
+
+	// A few new samples keep serving the cached result
+	repo.armStats[control].Samples += 10
+	second, err := bandit.CachedWinProbability(context.Background(), experimentID, 1000)
+	require.NoError(t, err)
+	assert.True(t, first.CachedAt.Equal(second.CachedAt))
+	assert.Equal(t, 1, cache.setCount())
+
+	// More draws than cached forces a fresh simulation
+	third, err := bandit.CachedWinProbability(context.Background(), experimentID, 5000)
+	require.NoError(t, err)
+	assert.Equal(t, 5000, third.Simulations)
+	assert.Equal(t, 2, cache.setCount())
+
+	// Enough new samples serve the cached result and recompute in the background
+	repo.armStats[variant].Samples += winProbabilityRecomputeSamples
+	stale, err := bandit.CachedWinProbability(context.Background(), experimentID, 5000)
+	require.NoError(t, err)
+	assert.Equal(t, 210, stale.Samples)
+	require.Eventually(t, func() bool { return cache.setCount() == 3 }, time.Second, 5*time.Millisecond)
+
+	refreshed, err := bandit.CachedWinProbability(context.Background(), experimentID, 5000)
+	require.NoError(t, err)
+	assert.Equal(t, 210+winProbabilityRecomputeSamples, refreshed.Samples)
+}
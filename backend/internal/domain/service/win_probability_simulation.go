@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"math/rand"
+	"runtime"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultSimulationBudget is the most Beta draws (simulations times arms)
+	// one win probability simulation makes by default
+	DefaultSimulationBudget = 5_000_000
+	// simulationChunk is the simulations one worker runs with one RNG. Chunks
+	// do not depend on the worker count, so a seeded bandit gives the same
+	// result on any machine.
+	simulationChunk = 2500
+)
+
+// ArmSimulation is the outcome of a Monte Carlo simulation over an
+// experiment's active arms
+type ArmSimulation struct {
+	// WinProbabilities is each arm's share of simulations it won
+	WinProbabilities map[uuid.UUID]float64
+	// ExpectedLoss is the expected conversion rate given up by choosing the
+	// arm, E[max(θ) - θ_arm]; the arm to ship is the one with the least
+	ExpectedLoss map[uuid.UUID]float64
+	// Simulations is how many simulations ran, after the budget
+	Simulations int
+	// Samples totals the samples of the arms simulated
+	Samples int
+}
+
+// WithSimulationBudget caps the Beta draws of one win probability simulation.
+// Experiments with many arms run fewer simulations to stay within it; a
+// non-positive budget restores the default.
+func (b *ThompsonSamplingBandit) WithSimulationBudget(draws int) *ThompsonSamplingBandit {
+	if draws <= 0 {
+		draws = DefaultSimulationBudget
+	}
+	b.simulationBudget = draws
+	return b
+}
+
+// WithSimulationWorkers sets how many goroutines run a simulation; a
+// non-positive count uses GOMAXPROCS
+func (b *ThompsonSamplingBandit) WithSimulationWorkers(workers int) *ThompsonSamplingBandit {
+	b.simulationWorkers = workers
+	return b
+}
+
+// SimulateArms runs up to simulations Monte Carlo draws of the experiment's
+// active arms, computing win probabilities and expected loss in the same
+// pass. The draws are split into chunks run in parallel, each with its own
+// RNG seeded from the bandit's.
+func (b *ThompsonSamplingBandit) SimulateArms(ctx context.Context, experimentID uuid.UUID, simulations int) (*ArmSimulation, error) {
+	arms, err := b.repo.GetArms(ctx, experimentID)
+	if err != nil {
+		return nil, err
+	}
+	// An archived arm can no longer be chosen, so it cannot win
+	arms = activeArms(arms)
+
+	armStats := make([]*ArmStats, 0, len(arms))
+	samples := 0
+	for _, arm := range arms {
+		stats, err := b.repo.GetArmStats(ctx, arm.ID)
+		if err != nil {
+			return nil, err
+		}
+		stats.ArmID = arm.ID
+		armStats = append(armStats, stats)
+		samples += stats.Samples
+	}
+
+	simulation := &ArmSimulation{
+		WinProbabilities: make(map[uuid.UUID]float64, len(armStats)),
+		ExpectedLoss:     make(map[uuid.UUID]float64, len(armStats)),
+		Samples:          samples,
+	}
+	if len(armStats) == 0 {
+		return simulation, nil
+	}
+	if budget := b.simulationBudget / len(armStats); simulations > budget {
+		simulations = budget
+	}
+	if simulations < 1 {
+		simulations = 1
+	}
+
+	wins, losses, err := b.simulateChunks(ctx, armStats, simulations)
+	if err != nil {
+		return nil, err
+	}
+	for i, stats := range armStats {
+		simulation.WinProbabilities[stats.ArmID] = float64(wins[i]) / float64(simulations)
+		simulation.ExpectedLoss[stats.ArmID] = losses[i] / float64(simulations)
+	}
+	simulation.Simulations = simulations
+	return simulation, nil
+}
+
+// simulationTotals is one chunk's win counts and summed losses per arm
+type simulationTotals struct {
+	wins   []int
+	losses []float64
+}
+
+// simulateChunks runs simulations draws across the worker pool and returns
+// each arm's wins and summed loss, in armStats order
+func (b *ThompsonSamplingBandit) simulateChunks(ctx context.Context, armStats []*ArmStats, simulations int) ([]int, []float64, error) {
+	chunks := (simulations + simulationChunk - 1) / simulationChunk
+	seeds := make([]int64, chunks)
+	for i := range seeds {
+		seeds[i] = b.rng.Int63()
+	}
+
+	workers := b.simulationWorkers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > chunks {
+		workers = chunks
+	}
+
+	totals := make([]simulationTotals, chunks)
+	next := make(chan int, chunks)
+	for i := 0; i < chunks; i++ {
+		next <- i
+	}
+	close(next)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for chunk := range next {
+				if ctx.Err() != nil {
+					return
+				}
+				draws := simulationChunk
+				if chunk == chunks-1 {
+					draws = simulations - chunk*simulationChunk
+				}
+				totals[chunk] = simulateChunk(rand.New(rand.NewSource(seeds[chunk])), armStats, draws)
+			}
+		}()
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	// Merge in chunk order so float sums do not depend on scheduling
+	wins := make([]int, len(armStats))
+	losses := make([]float64, len(armStats))
+	for _, total := range totals {
+		for i := range armStats {
+			wins[i] += total.wins[i]
+			losses[i] += total.losses[i]
+		}
+	}
+	return wins, losses, nil
+}
+
+// simulateChunk runs draws simulations with rng, which it owns
+func simulateChunk(rng *rand.Rand, armStats []*ArmStats, draws int) simulationTotals {
+	totals := simulationTotals{wins: make([]int, len(armStats)), losses: make([]float64, len(armStats))}
+	sampled := make([]float64, len(armStats))
+	for d := 0; d < draws; d++ {
+		best, maxSample := 0, -1.0
+		for i, stats := range armStats {
+			sampled[i] = sampleBeta(rng, stats.Alpha, stats.Beta)
+			if sampled[i] > maxSample {
+				best, maxSample = i, sampled[i]
+			}
+		}
+		totals.wins[best]++
+		for i, sample := range sampled {
+			totals.losses[i] += maxSample - sample
+		}
+	}
+	return totals
+}
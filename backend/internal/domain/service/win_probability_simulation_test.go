@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func simulationTestRepo(arms int) (*advancedEngineTestRepo, []uuid.UUID) {
+	repo := &advancedEngineTestRepo{armStats: map[uuid.UUID]*ArmStats{}}
+	ids := make([]uuid.UUID, arms)
+	for i := range ids {
+		ids[i] = uuid.New()
+		repo.arms = append(repo.arms, Arm{ID: ids[i], IsControl: i == 0})
+		repo.armStats[ids[i]] = &ArmStats{ArmID: ids[i], Alpha: float64(10 + 2*i), Beta: 90, Samples: 100}
+	}
+	return repo, ids
+}
+
+func TestThompsonSamplingBandit_SimulateArmsIsIndependentOfWorkers(t *testing.T) {
+	repo, ids := simulationTestRepo(12)
+	run := func(workers int) *ArmSimulation {
+		bandit := NewThompsonSamplingBandit(repo, &advancedEngineTestCache{}, zap.NewNop()).WithSeed(9).WithSimulationWorkers(workers)
+		simulation, err := bandit.SimulateArms(context.Background(), uuid.New(), 20000)
+		require.NoError(t, err)
+		return simulation
+	}
+
+	serial, parallel := run(1), run(8)
+	assert.Equal(t, serial, parallel)
+	assert.Equal(t, 20000, serial.Simulations)
+	assert.Equal(t, 1200, serial.Samples)
+
+	total := 0.0
+	for _, p := range serial.WinProbabilities {
+		total += p
+	}
+	assert.InDelta(t, 1, total, 1e-9)
+
+	// The strongest arm wins most often and gives up the least
+	best := ids[len(ids)-1]
+	for _, id := range ids[:len(ids)-1] {
+		assert.Greater(t, serial.WinProbabilities[best], serial.WinProbabilities[id])
+		assert.Less(t, serial.ExpectedLoss[best], serial.ExpectedLoss[id])
+	}
+	assert.Greater(t, serial.ExpectedLoss[ids[0]], 0.0)
+}
+
+func TestThompsonSamplingBandit_SimulateArmsRespectsBudget(t *testing.T) {
+	repo, _ := simulationTestRepo(10)
+	bandit := NewThompsonSamplingBandit(repo, &advancedEngineTestCache{}, zap.NewNop()).WithSeed(1).WithSimulationBudget(30000)
+
+	simulation, err := bandit.SimulateArms(context.Background(), uuid.New(), 10000)
+	require.NoError(t, err)
+	assert.Equal(t, 3000, simulation.Simulations, "10 arms within 30000 draws")
+}
+
+func TestThompsonSamplingBandit_SimulateArmsStopsWhenCancelled(t *testing.T) {
+	repo, _ := simulationTestRepo(2)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := NewThompsonSamplingBandit(repo, &advancedEngineTestCache{}, zap.NewNop()).SimulateArms(ctx, uuid.New(), 10000)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func BenchmarkThompsonSamplingBandit_SimulateArms(b *testing.B) {
+	repo, _ := simulationTestRepo(16)
+	bandit := NewThompsonSamplingBandit(repo, &advancedEngineTestCache{}, zap.NewNop()).WithSeed(1)
+	for i := 0; i < b.N; i++ {
+		if _, err := bandit.SimulateArms(context.Background(), uuid.New(), 100000); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
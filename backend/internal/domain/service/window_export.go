@@ -0,0 +1,99 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultWindowExportLimit is how many events per arm a page holds when
+	// the request doesn't say
+	DefaultWindowExportLimit = 100
+	// MaxWindowExportLimit caps the events per arm of one page
+	MaxWindowExportLimit = 1000
+)
+
+// ErrInvalidWindowCursor is returned for a cursor that wasn't issued by a
+// window export
+var ErrInvalidWindowCursor = errors.New("invalid window cursor")
+
+// WindowEventQuery selects one page of an arm's window events, newest first.
+// Zero Since and Until leave the time range open.
+type WindowEventQuery struct {
+	Since  time.Time
+	Until  time.Time
+	Limit  int64
+	Cursor *WindowCursor
+}
+
+// WindowCursor is where an arm's export continues: events scored at most
+// Score, skipping the first Skip of those scored exactly Score, which the
+// previous pages returned already
+type WindowCursor struct {
+	Score int64 `json:"s"`
+	Skip  int64 `json:"k"`
+}
+
+// WindowExportQuery selects the events of an experiment's window export.
+// Empty ArmIDs export every arm; Limit is per arm.
+type WindowExportQuery struct {
+	ArmIDs []uuid.UUID
+	Since  time.Time
+	Until  time.Time
+	Limit  int64
+	Cursor string
+}
+
+// WindowExportPage is one page of a window export. NextCursor is empty once
+// every selected arm has been exported.
+type WindowExportPage struct {
+	Events     map[uuid.UUID][]RewardEvent
+	NextCursor string
+}
+
+// nextWindowCursor returns where the export continues after a full page
+// whose scores, newest first, are scores
+func nextWindowCursor(prev *WindowCursor, scores []int64) *WindowCursor {
+	last := scores[len(scores)-1]
+	next := &WindowCursor{Score: last}
+	for i := len(scores) - 1; i >= 0 && scores[i] == last; i-- {
+		next.Skip++
+	}
+	if prev != nil && prev.Score == last && next.Skip == int64(len(scores)) {
+		next.Skip += prev.Skip
+	}
+	return next
+}
+
+func encodeWindowExportCursor(cursors map[uuid.UUID]*WindowCursor) (string, error) {
+	if len(cursors) == 0 {
+		return "", nil
+	}
+	raw, err := json.Marshal(cursors)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode window cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func decodeWindowExportCursor(cursor string) (map[uuid.UUID]*WindowCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidWindowCursor
+	}
+	var cursors map[uuid.UUID]*WindowCursor
+	if err := json.Unmarshal(raw, &cursors); err != nil || len(cursors) == 0 {
+		return nil, ErrInvalidWindowCursor
+	}
+	for _, c := range cursors {
+		if c == nil || c.Skip < 0 {
+			return nil, ErrInvalidWindowCursor
+		}
+	}
+	return cursors, nil
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNextWindowCursor_SkipsEventsSharingTheLastScore(t *testing.T) {
+	assert.Equal(t, &WindowCursor{Score: 80, Skip: 2}, nextWindowCursor(nil, []int64{100, 90, 80, 80}))
+
+	// A page entirely at the cursor's score adds to its skip
+	assert.Equal(t, &WindowCursor{Score: 80, Skip: 5}, nextWindowCursor(&WindowCursor{Score: 80, Skip: 2}, []int64{80, 80, 80}))
+	assert.Equal(t, &WindowCursor{Score: 70, Skip: 1}, nextWindowCursor(&WindowCursor{Score: 80, Skip: 2}, []int64{80, 70}))
+}
+
+func TestWindowExportCursor_RoundTrips(t *testing.T) {
+	armID := uuid.New()
+	encoded, err := encodeWindowExportCursor(map[uuid.UUID]*WindowCursor{armID: {Score: 1700000000000, Skip: 3}})
+	require.NoError(t, err)
+
+	decoded, err := decodeWindowExportCursor(encoded)
+	require.NoError(t, err)
+	assert.Equal(t, &WindowCursor{Score: 1700000000000, Skip: 3}, decoded[armID])
+
+	empty, err := encodeWindowExportCursor(nil)
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+}
+
+func TestDecodeWindowExportCursor_RejectsForeignCursors(t *testing.T) {
+	for _, cursor := range []string{"not base64!", "e30", "eyJ4Ijp7fX0", "eyIwMDAwMDAwMC0wMDAwLTAwMDAtMDAwMC0wMDAwMDAwMDAwMDAiOnsicyI6MSwiayI6LTF9fQ"} {
+		_, err := decodeWindowExportCursor(cursor)
+		assert.ErrorIs(t, err, ErrInvalidWindowCursor, cursor)
+	}
+}
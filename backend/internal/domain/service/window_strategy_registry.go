@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/clock"
+)
+
+// ErrUnknownWindowType is returned for a window type with no registered strategy
+var ErrUnknownWindowType = errors.New("unknown window type")
+
+// WindowStrategyDeps are the shared dependencies handed to window factories
+type WindowStrategyDeps struct {
+	Repo   BanditRepository
+	Redis  *redis.Client
+	Logger *zap.Logger
+	// Clock defaults to the wall clock when nil
+	Clock clock.Clock
+}
+
+// WindowStrategyFactory builds the window strategy for one experiment. config
+// may be nil, in which case the strategy picks its own defaults.
+type WindowStrategyFactory func(deps WindowStrategyDeps, experimentID uuid.UUID, config *WindowConfig) WindowStrategy
+
+// WindowStrategyRegistry maps window types to the strategies implementing them
+type WindowStrategyRegistry struct {
+	mu        sync.RWMutex
+	factories map[WindowType]WindowStrategyFactory
+}
+
+// NewWindowStrategyRegistry returns a registry with the Redis-backed events,
+// time and decay windows registered
+func NewWindowStrategyRegistry() *WindowStrategyRegistry {
+	slidingWindow := func(deps WindowStrategyDeps, experimentID uuid.UUID, config *WindowConfig) WindowStrategy {
+		strategy := NewSlidingWindowStrategy(deps.Repo, deps.Redis, deps.Logger, experimentID, config)
+		if deps.Clock != nil {
+			strategy.WithClock(deps.Clock)
+		}
+		return strategy
+	}
+	return &WindowStrategyRegistry{
+		factories: map[WindowType]WindowStrategyFactory{
+			WindowTypeEvents: slidingWindow,
+			WindowTypeTime:   slidingWindow,
+			WindowTypeDecay:  slidingWindow,
+		},
+	}
+}
+
+// Register adds or replaces the strategy for windowType
+func (r *WindowStrategyRegistry) Register(windowType WindowType, factory WindowStrategyFactory) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.factories[windowType] = factory
+}
+
+// New builds the strategy for config. Arms listed in config.ArmOverrides get
+// a strategy of their own, which may be of a different type.
+func (r *WindowStrategyRegistry) New(deps WindowStrategyDeps, experimentID uuid.UUID, config *WindowConfig) (WindowStrategy, error) {
+	strategy, err := r.build(deps, experimentID, config)
+	if err != nil || config == nil || len(config.ArmOverrides) == 0 {
+		return strategy, err
+	}
+
+	overrides := make(map[uuid.UUID]WindowStrategy, len(config.ArmOverrides))
+	for armID, override := range config.ArmOverrides {
+		armStrategy, err := r.build(deps, experimentID, override)
+		if err != nil {
+			return nil, fmt.Errorf("arm %s: %w", armID, err)
+		}
+		overrides[armID] = armStrategy
+	}
+	return &armWindowStrategy{WindowStrategy: strategy, overrides: overrides}, nil
+}
+
+func (r *WindowStrategyRegistry) build(deps WindowStrategyDeps, experimentID uuid.UUID, config *WindowConfig) (WindowStrategy, error) {
+	windowType := WindowTypeEvents
+	if config != nil && config.Type != "" {
+		windowType = config.Type
+	}
+
+	r.mu.RLock()
+	factory, ok := r.factories[windowType]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownWindowType, windowType)
+	}
+	return factory(deps, experimentID, config), nil
+}
+
+// windowManager is implemented by window strategies that keep an
+// inspectable event log
+type windowManager interface {
+	WindowStrategy
+	GetWindowInfo(ctx context.Context, armID uuid.UUID) (*WindowStats, error)
+	TrimWindow(ctx context.Context, armID uuid.UUID) error
+	GetUtilization(ctx context.Context, armID uuid.UUID) (float64, error)
+	ExportEvents(ctx context.Context, armID uuid.UUID, query WindowEventQuery) ([]RewardEvent, *WindowCursor, error)
+}
+
+// armWindowStrategy routes arms with an override to their own strategy and
+// every other arm to the experiment's
+type armWindowStrategy struct {
+	WindowStrategy
+	overrides map[uuid.UUID]WindowStrategy
+}
+
+func (s *armWindowStrategy) forArm(armID uuid.UUID) WindowStrategy {
+	if strategy, ok := s.overrides[armID]; ok {
+		return strategy
+	}
+	return s.WindowStrategy
+}
+
+func (s *armWindowStrategy) managerForArm(armID uuid.UUID) (windowManager, error) {
+	strategy := s.forArm(armID)
+	manager, ok := strategy.(windowManager)
+	if !ok {
+		return nil, fmt.Errorf("window strategy %s does not keep an event log", strategy.GetType())
+	}
+	return manager, nil
+}
+
+func (s *armWindowStrategy) GetArmStats(ctx context.Context, armID uuid.UUID) (*ArmStats, error) {
+	return s.forArm(armID).GetArmStats(ctx, armID)
+}
+
+func (s *armWindowStrategy) RecordEvent(ctx context.Context, armID uuid.UUID, event RewardEvent) error {
+	return s.forArm(armID).RecordEvent(ctx, armID, event)
+}
+
+func (s *armWindowStrategy) GetWindowInfo(ctx context.Context, armID uuid.UUID) (*WindowStats, error) {
+	manager, err := s.managerForArm(armID)
+	if err != nil {
+		return nil, err
+	}
+	return manager.GetWindowInfo(ctx, armID)
+}
+
+func (s *armWindowStrategy) TrimWindow(ctx context.Context, armID uuid.UUID) error {
+	manager, err := s.managerForArm(armID)
+	if err != nil {
+		// Nothing to trim
+		return nil
+	}
+	return manager.TrimWindow(ctx, armID)
+}
+
+func (s *armWindowStrategy) GetUtilization(ctx context.Context, armID uuid.UUID) (float64, error) {
+	manager, err := s.managerForArm(armID)
+	if err != nil {
+		return 0, err
+	}
+	return manager.GetUtilization(ctx, armID)
+}
+
+func (s *armWindowStrategy) ExportEvents(ctx context.Context, armID uuid.UUID, query WindowEventQuery) ([]RewardEvent, *WindowCursor, error) {
+	manager, err := s.managerForArm(armID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return manager.ExportEvents(ctx, armID, query)
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fixedWindowStrategy reports the same stats for every arm
+type fixedWindowStrategy struct {
+	name     string
+	samples  int
+	recorded []uuid.UUID
+}
+
+func (s *fixedWindowStrategy) GetArmStats(ctx context.Context, armID uuid.UUID) (*ArmStats, error) {
+	return &ArmStats{ArmID: armID, Samples: s.samples}, nil
+}
+
+func (s *fixedWindowStrategy) RecordEvent(ctx context.Context, armID uuid.UUID, event RewardEvent) error {
+	s.recorded = append(s.recorded, armID)
+	return nil
+}
+
+func (s *fixedWindowStrategy) GetType() string { return s.name }
+
+func TestWindowStrategyRegistry_BuildsRegisteredTypes(t *testing.T) {
+	registry := NewWindowStrategyRegistry()
+
+	for _, windowType := range []WindowType{WindowTypeEvents, WindowTypeTime, WindowTypeDecay} {
+		strategy, err := registry.New(WindowStrategyDeps{}, uuid.New(), &WindowConfig{Type: windowType, Size: 60})
+		require.NoError(t, err, windowType)
+		assert.IsType(t, &SlidingWindowStrategy{}, strategy)
+	}
+
+	// A missing config keeps the historical events-window default
+	strategy, err := registry.New(WindowStrategyDeps{}, uuid.New(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, WindowTypeEvents, strategy.(*SlidingWindowStrategy).GetConfig().Type)
+
+	_, err = registry.New(WindowStrategyDeps{}, uuid.New(), &WindowConfig{Type: WindowTypeNone})
+	assert.True(t, errors.Is(err, ErrUnknownWindowType))
+}
+
+func TestWindowStrategyRegistry_RegisterCustomType(t *testing.T) {
+	registry := NewWindowStrategyRegistry()
+	custom := &fixedWindowStrategy{name: "custom"}
+	registry.Register("custom", func(WindowStrategyDeps, uuid.UUID, *WindowConfig) WindowStrategy { return custom })
+
+	strategy, err := registry.New(WindowStrategyDeps{}, uuid.New(), &WindowConfig{Type: "custom"})
+	require.NoError(t, err)
+	assert.Same(t, custom, strategy)
+}
+
+func TestWindowStrategyRegistry_ArmOverrides(t *testing.T) {
+	registry := NewWindowStrategyRegistry()
+	experimentWide := &fixedWindowStrategy{name: "wide", samples: 1}
+	perArm := &fixedWindowStrategy{name: "per_arm", samples: 2}
+	registry.Register("wide", func(WindowStrategyDeps, uuid.UUID, *WindowConfig) WindowStrategy { return experimentWide })
+	registry.Register("per_arm", func(WindowStrategyDeps, uuid.UUID, *WindowConfig) WindowStrategy { return perArm })
+
+	overridden, other := uuid.New(), uuid.New()
+	strategy, err := registry.New(WindowStrategyDeps{}, uuid.New(), &WindowConfig{
+		Type:         "wide",
+		ArmOverrides: map[uuid.UUID]*WindowConfig{overridden: {Type: "per_arm"}},
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	stats, err := strategy.GetArmStats(ctx, overridden)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.Samples)
+	stats, err = strategy.GetArmStats(ctx, other)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Samples)
+
+	require.NoError(t, strategy.RecordEvent(ctx, overridden, RewardEvent{}))
+	require.NoError(t, strategy.RecordEvent(ctx, other, RewardEvent{}))
+	assert.Equal(t, []uuid.UUID{overridden}, perArm.recorded)
+	assert.Equal(t, []uuid.UUID{other}, experimentWide.recorded)
+
+	// Neither fake keeps an event log: trimming is a no-op, inspection fails
+	manager := strategy.(windowManager)
+	assert.NoError(t, manager.TrimWindow(ctx, overridden))
+	_, err = manager.GetWindowInfo(ctx, overridden)
+	assert.Error(t, err)
+
+	_, err = registry.New(WindowStrategyDeps{}, uuid.New(), &WindowConfig{
+		Type:         "wide",
+		ArmOverrides: map[uuid.UUID]*WindowConfig{overridden: {Type: "missing"}},
+	})
+	assert.True(t, errors.Is(err, ErrUnknownWindowType))
+}
@@ -0,0 +1,303 @@
+package valueobject
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidAppVersion        = errors.New("invalid app version")
+	ErrInvalidVersionConstraint = errors.New("invalid version constraint")
+)
+
+// AppVersion is a semantic version such as 3.2.0 or 4.0.0-beta.1. Build
+// metadata is ignored.
+type AppVersion struct {
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease string
+}
+
+// ParseAppVersion parses a semantic version. A leading "v" is accepted and
+// missing minor or patch components default to 0, so "3.2" is 3.2.0.
+func ParseAppVersion(version string) (AppVersion, error) {
+	core, _, _ := strings.Cut(strings.TrimPrefix(strings.TrimSpace(version), "v"), "+")
+	core, prerelease, hasPrerelease := strings.Cut(core, "-")
+	if hasPrerelease && prerelease == "" {
+		return AppVersion{}, fmt.Errorf("%w: %q", ErrInvalidAppVersion, version)
+	}
+	parts, err := parseVersionCore(core)
+	if err != nil || len(parts) == 0 || len(parts) > 3 {
+		return AppVersion{}, fmt.Errorf("%w: %q", ErrInvalidAppVersion, version)
+	}
+	parts = append(parts, 0, 0)
+	return AppVersion{Major: parts[0], Minor: parts[1], Patch: parts[2], Prerelease: prerelease}, nil
+}
+
+func parseVersionCore(core string) ([]int, error) {
+	if core == "" {
+		return nil, nil
+	}
+	fields := strings.Split(core, ".")
+	parts := make([]int, 0, len(fields))
+	for _, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 || field[0] == '+' {
+			return nil, ErrInvalidAppVersion
+		}
+		parts = append(parts, n)
+	}
+	return parts, nil
+}
+
+func (v AppVersion) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	return s
+}
+
+// Compare returns -1, 0 or 1. A prerelease sorts before its release.
+func (v AppVersion) Compare(other AppVersion) int {
+	for _, d := range [][2]int{{v.Major, other.Major}, {v.Minor, other.Minor}, {v.Patch, other.Patch}} {
+		if d[0] != d[1] {
+			if d[0] < d[1] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case v.Prerelease == other.Prerelease:
+		return 0
+	case v.Prerelease == "":
+		return 1
+	case other.Prerelease == "":
+		return -1
+	}
+	return comparePrerelease(v.Prerelease, other.Prerelease)
+}
+
+// comparePrerelease orders dot-separated identifiers: numeric ones
+// numerically and before alphanumeric ones, which compare lexically
+func comparePrerelease(a, b string) int {
+	left, right := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(left) && i < len(right); i++ {
+		x, xErr := strconv.Atoi(left[i])
+		y, yErr := strconv.Atoi(right[i])
+		switch {
+		case xErr == nil && yErr == nil:
+			if x != y {
+				if x < y {
+					return -1
+				}
+				return 1
+			}
+		case xErr == nil:
+			return -1
+		case yErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(left[i], right[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	switch {
+	case len(left) < len(right):
+		return -1
+	case len(left) > len(right):
+		return 1
+	}
+	return 0
+}
+
+type versionComparator struct {
+	op      string
+	version AppVersion
+}
+
+func (c versionComparator) allows(v AppVersion) bool {
+	cmp := v.Compare(c.version)
+	switch c.op {
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case "!=":
+		return cmp != 0
+	default:
+		return cmp == 0
+	}
+}
+
+// VersionConstraint is a set of version ranges such as ">=3.2.0 <4.0.0" or
+// "^3.2 || ~4.1.0". Space or comma separated comparators must all hold; "||"
+// separates alternatives. Supported operators are =, !=, >, >=, <, <=, ^
+// (same major) and ~ (same minor); "3.2", "3.2.x" and "3.2.*" match any 3.2
+// patch release.
+type VersionConstraint struct {
+	raw          string
+	alternatives [][]versionComparator
+}
+
+// ParseVersionConstraint parses a constraint expression
+func ParseVersionConstraint(constraint string) (VersionConstraint, error) {
+	raw := strings.TrimSpace(constraint)
+	if raw == "" || len(raw) > 128 {
+		return VersionConstraint{}, fmt.Errorf("%w: %q", ErrInvalidVersionConstraint, constraint)
+	}
+	parsed := VersionConstraint{raw: raw}
+	for _, alternative := range strings.Split(raw, "||") {
+		tokens := strings.FieldsFunc(alternative, func(r rune) bool { return r == ' ' || r == ',' || r == '\t' })
+		// Join operators written apart from their version, as in ">= 3.2.0"
+		for i := 0; i < len(tokens)-1; i++ {
+			if strings.Trim(tokens[i], "<>=!^~") == "" {
+				tokens[i] += tokens[i+1]
+				tokens = append(tokens[:i+1], tokens[i+2:]...)
+			}
+		}
+		if len(tokens) == 0 {
+			return VersionConstraint{}, fmt.Errorf("%w: empty range in %q", ErrInvalidVersionConstraint, constraint)
+		}
+		var comparators []versionComparator
+		for _, token := range tokens {
+			expanded, err := parseVersionComparator(token)
+			if err != nil {
+				return VersionConstraint{}, fmt.Errorf("%w: %q", ErrInvalidVersionConstraint, token)
+			}
+			comparators = append(comparators, expanded...)
+		}
+		parsed.alternatives = append(parsed.alternatives, comparators)
+	}
+	return parsed, nil
+}
+
+func parseVersionComparator(token string) ([]versionComparator, error) {
+	op := ""
+	for _, candidate := range []string{">=", "<=", "!=", "==", ">", "<", "=", "^", "~"} {
+		if strings.HasPrefix(token, candidate) {
+			op = candidate
+			break
+		}
+	}
+	versionText := strings.TrimPrefix(token, op)
+	if op == "==" {
+		op = "="
+	}
+
+	// Partial and wildcard versions ("3", "3.2", "3.2.x") cover a range
+	core, suffix, _ := strings.Cut(strings.TrimPrefix(versionText, "v"), "-")
+	fields := strings.Split(core, ".")
+	for len(fields) > 0 && (fields[len(fields)-1] == "x" || fields[len(fields)-1] == "*") {
+		fields = fields[:len(fields)-1]
+	}
+	specified := len(fields)
+	if specified < len(strings.Split(core, ".")) && suffix != "" {
+		return nil, ErrInvalidVersionConstraint
+	}
+	if specified == 0 {
+		if op != "" && op != "=" {
+			return nil, ErrInvalidVersionConstraint
+		}
+		// "*" matches every version
+		return []versionComparator{{op: ">=", version: AppVersion{Prerelease: "0"}}}, nil
+	}
+	version, err := ParseAppVersion(strings.Join(fields, ".") + prereleaseSuffix(suffix))
+	if err != nil {
+		return nil, err
+	}
+
+	switch op {
+	case "^":
+		return []versionComparator{{op: ">=", version: version}, {op: "<", version: caretUpperBound(version, specified)}}, nil
+	case "~":
+		return []versionComparator{{op: ">=", version: version}, {op: "<", version: tildeUpperBound(version, specified)}}, nil
+	case "", "=":
+		if specified == 3 {
+			return []versionComparator{{op: "=", version: version}}, nil
+		}
+		return []versionComparator{{op: ">=", version: version}, {op: "<", version: tildeUpperBound(version, specified)}}, nil
+	case ">":
+		if specified < 3 {
+			return []versionComparator{{op: ">=", version: tildeUpperBound(version, specified)}}, nil
+		}
+	case "<=":
+		if specified < 3 {
+			return []versionComparator{{op: "<", version: tildeUpperBound(version, specified)}}, nil
+		}
+	case "<":
+		// Prereleases of the bound are below it too
+		if version.Prerelease == "" {
+			version.Prerelease = "0"
+		}
+	}
+	return []versionComparator{{op: op, version: version}}, nil
+}
+
+func prereleaseSuffix(suffix string) string {
+	if suffix == "" {
+		return ""
+	}
+	return "-" + suffix
+}
+
+// caretUpperBound keeps the leftmost non-zero component of the specified ones
+func caretUpperBound(v AppVersion, specified int) AppVersion {
+	switch {
+	case v.Major > 0 || specified == 1:
+		return AppVersion{Major: v.Major + 1, Prerelease: "0"}
+	case v.Minor > 0 || specified == 2:
+		return AppVersion{Minor: v.Minor + 1, Prerelease: "0"}
+	default:
+		return AppVersion{Patch: v.Patch + 1, Prerelease: "0"}
+	}
+}
+
+// tildeUpperBound allows patch changes, or minor ones when only the major
+// version is given
+func tildeUpperBound(v AppVersion, specified int) AppVersion {
+	if specified == 1 {
+		return AppVersion{Major: v.Major + 1, Prerelease: "0"}
+	}
+	return AppVersion{Major: v.Major, Minor: v.Minor + 1, Prerelease: "0"}
+}
+
+func (c VersionConstraint) String() string {
+	return c.raw
+}
+
+// Allows reports whether version satisfies any alternative of the constraint.
+// An unparseable version never does.
+func (c VersionConstraint) Allows(version string) bool {
+	v, err := ParseAppVersion(version)
+	if err != nil {
+		return false
+	}
+	return c.AllowsVersion(v)
+}
+
+// AllowsVersion reports whether v satisfies any alternative of the constraint
+func (c VersionConstraint) AllowsVersion(v AppVersion) bool {
+	for _, comparators := range c.alternatives {
+		allowed := true
+		for _, comparator := range comparators {
+			if !comparator.allows(v) {
+				allowed = false
+				break
+			}
+		}
+		if allowed {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package valueobject
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAppVersion(t *testing.T) {
+	v, err := ParseAppVersion("v3.2")
+	require.NoError(t, err)
+	assert.Equal(t, AppVersion{Major: 3, Minor: 2}, v)
+
+	v, err = ParseAppVersion("4.0.0-beta.2+build.7")
+	require.NoError(t, err)
+	assert.Equal(t, "4.0.0-beta.2", v.String())
+
+	for _, invalid := range []string{"", "3.x", "1.2.3.4", "3.-1", "3.2.0-"} {
+		_, err := ParseAppVersion(invalid)
+		assert.ErrorIs(t, err, ErrInvalidAppVersion, invalid)
+	}
+}
+
+func TestAppVersionCompare(t *testing.T) {
+	ordered := []string{"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta.2", "1.0.0-beta.11", "1.0.0", "1.0.1", "1.2.0", "2.0.0"}
+	for i := 1; i < len(ordered); i++ {
+		a, _ := ParseAppVersion(ordered[i-1])
+		b, _ := ParseAppVersion(ordered[i])
+		assert.Equal(t, -1, a.Compare(b), "%s < %s", ordered[i-1], ordered[i])
+		assert.Equal(t, 1, b.Compare(a))
+	}
+}
+
+func TestVersionConstraintAllows(t *testing.T) {
+	tests := []struct {
+		constraint string
+		allowed    []string
+		denied     []string
+	}{
+		{">=3.2.0", []string{"3.2.0", "3.10.1", "4.0.0"}, []string{"3.1.9", "3.2.0-rc.1", "garbage", ""}},
+		{">= 3.2.0, <4", []string{"3.2.0", "3.99.0"}, []string{"4.0.0", "4.0.0-beta"}},
+		{"^3.2", []string{"3.2.0", "3.9.9"}, []string{"3.1.0", "4.0.0"}},
+		{"^0.2.3", []string{"0.2.3", "0.2.9"}, []string{"0.3.0"}},
+		{"~3.2.1", []string{"3.2.1", "3.2.8"}, []string{"3.3.0", "3.2.0"}},
+		{"3.2.x", []string{"3.2.0", "3.2.14"}, []string{"3.3.0"}},
+		{"=3.2.1 || >=4.1", []string{"3.2.1", "4.1.0"}, []string{"3.2.2", "4.0.5"}},
+		{"!=3.2.1", []string{"3.2.0"}, []string{"3.2.1"}},
+		{">3.2 <=4", []string{"3.3.0", "4.9.9"}, []string{"3.2.5", "5.0.0"}},
+		{"*", []string{"0.0.1", "9.9.9"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			c, err := ParseVersionConstraint(tt.constraint)
+			require.NoError(t, err)
+			for _, v := range tt.allowed {
+				assert.True(t, c.Allows(v), "%s should allow %s", tt.constraint, v)
+			}
+			for _, v := range tt.denied {
+				assert.False(t, c.Allows(v), "%s should deny %s", tt.constraint, v)
+			}
+		})
+	}
+}
+
+func TestParseVersionConstraintRejectsInvalid(t *testing.T) {
+	for _, invalid := range []string{"", ">=", ">=3.2.0 ||", "=>3.2", "3.2.0.1", "^*", "latest"} {
+		_, err := ParseVersionConstraint(invalid)
+		assert.ErrorIs(t, err, ErrInvalidVersionConstraint, invalid)
+	}
+}
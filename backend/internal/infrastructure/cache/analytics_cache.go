@@ -266,6 +266,9 @@ type FunnelData struct {
 	TotalExits    int                    `json:"total_exits"`
 	ConversionRate float64               `json:"conversion_rate"`
 	CachedAt      time.Time              `json:"cached_at"`
+	// Dimensions identifies a breakdown segment, e.g. arm and placement
+	Dimensions    map[string]string      `json:"dimensions,omitempty"`
+	Segments      []FunnelData           `json:"segments,omitempty"`
 }
 
 // FunnelStep represents a step in the funnel
@@ -325,6 +328,17 @@ type LTVData struct {
 	Confidence   float64           `json:"confidence"`
 	CalculatedAt time.Time         `json:"calculated_at"`
 	Factors      map[string]float64 `json:"factors"`
+
+	// Intervals are absent from entries cached before they were added
+	LTV30Interval  *LTVInterval `json:"ltv30_interval,omitempty"`
+	LTV90Interval  *LTVInterval `json:"ltv90_interval,omitempty"`
+	LTV365Interval *LTVInterval `json:"ltv365_interval,omitempty"`
+}
+
+// LTVInterval bounds a cached LTV estimate
+type LTVInterval struct {
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
 }
 
 // SetLTV stores LTV data with 1h TTL
@@ -419,25 +433,44 @@ func parseMemoryUsage(info string) int64 {
 	return 0
 }
 
-// FlushPattern removes all keys matching a pattern
-func (c *AnalyticsCache) FlushPattern(ctx context.Context, pattern string) error {
-	iter := c.client.Scan(ctx, 0, pattern, 100).Iterator()
-	var keys []string
+// FlushPattern removes all keys matching a pattern and returns how many were
+// deleted. Patterns outside the known namespaces are refused with
+// ErrUnsafeFlushPattern; there is deliberately no way to FLUSHDB from here.
+func (c *AnalyticsCache) FlushPattern(ctx context.Context, pattern string) (int, error) {
+	if !isSafeFlushPattern(pattern) {
+		return 0, fmt.Errorf("%w: %q", ErrUnsafeFlushPattern, pattern)
+	}
+
+	iter := c.client.Scan(ctx, 0, pattern, 500).Iterator()
+	keys := make([]string, 0, 500)
+	deleted := 0
+	flush := func() error {
+		if len(keys) == 0 {
+			return nil
+		}
+		if err := c.client.Del(ctx, keys...).Err(); err != nil {
+			return fmt.Errorf("failed to delete keys: %w", err)
+		}
+		deleted += len(keys)
+		keys = keys[:0]
+		return nil
+	}
 
 	for iter.Next(ctx) {
 		keys = append(keys, iter.Val())
+		if len(keys) == cap(keys) {
+			if err := flush(); err != nil {
+				return deleted, err
+			}
+		}
 	}
-
 	if err := iter.Err(); err != nil {
-		return fmt.Errorf("failed to scan keys: %w", err)
+		return deleted, fmt.Errorf("failed to scan keys: %w", err)
 	}
-
-	if len(keys) > 0 {
-		if err := c.client.Del(ctx, keys...).Err(); err != nil {
-			return fmt.Errorf("failed to delete keys: %w", err)
-		}
-		c.logger.Debug("Flushed pattern", zap.String("pattern", pattern), zap.Int("count", len(keys)))
+	if err := flush(); err != nil {
+		return deleted, err
 	}
 
-	return nil
+	c.logger.Debug("Flushed pattern", zap.String("pattern", pattern), zap.Int("count", deleted))
+	return deleted, nil
 }
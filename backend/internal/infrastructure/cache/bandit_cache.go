@@ -111,8 +111,7 @@ func (c *RedisBanditCache) GetAssignment(ctx context.Context, key string) (uuid.
 		return uuid.Nil, fmt.Errorf("failed to get assignment: %w", err)
 	}
 
-	data := cmd.String()
-	armID, err := uuid.Parse(data)
+	armID, err := uuid.Parse(cmd.Val())
 	if err != nil {
 		return uuid.Nil, fmt.Errorf("failed to parse arm ID: %w", err)
 	}
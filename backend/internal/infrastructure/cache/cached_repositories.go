@@ -0,0 +1,122 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainRepo "github.com/bivex/paywall-iap/internal/domain/repository"
+	"github.com/bivex/paywall-iap/internal/domain/service"
+)
+
+// cachedAnalyticsRepository caches the dashboard aggregates of an analytics
+// repository. The audit log is always read through.
+type cachedAnalyticsRepository struct {
+	domainRepo.AnalyticsRepository
+	cache *QueryCache
+}
+
+// currencyAnalyticsRepository is an analytics repository that also splits
+// revenue by charge currency
+type currencyAnalyticsRepository interface {
+	domainRepo.AnalyticsRepository
+	GetRevenueByCurrencyBetween(ctx context.Context, start, end time.Time) ([]service.CurrencyAmount, error)
+	GetMRRByCurrency(ctx context.Context) ([]service.CurrencyAmount, error)
+	GetMRRTrendByCurrency(ctx context.Context, months int) ([]service.CurrencyAmount, error)
+}
+
+type cachedCurrencyAnalyticsRepository struct {
+	cachedAnalyticsRepository
+	currency currencyAnalyticsRepository
+}
+
+// NewCachedAnalyticsRepository wraps repo with the query cache. The result
+// splits revenue by currency only if repo does.
+func NewCachedAnalyticsRepository(repo domainRepo.AnalyticsRepository, cache *QueryCache) domainRepo.AnalyticsRepository {
+	base := cachedAnalyticsRepository{AnalyticsRepository: repo, cache: cache}
+	if currency, ok := repo.(currencyAnalyticsRepository); ok {
+		return &cachedCurrencyAnalyticsRepository{cachedAnalyticsRepository: base, currency: currency}
+	}
+	return &base
+}
+
+func (r *cachedAnalyticsRepository) GetRevenueBetween(ctx context.Context, start, end time.Time) (float64, error) {
+	return CachedQuery(ctx, r.cache, MetricRevenue, "revenue_between", []any{start, end}, func(ctx context.Context) (float64, error) {
+		return r.AnalyticsRepository.GetRevenueBetween(ctx, start, end)
+	})
+}
+
+func (r *cachedAnalyticsRepository) GetMRR(ctx context.Context) (float64, error) {
+	return CachedQuery(ctx, r.cache, MetricRevenue, "mrr", nil, r.AnalyticsRepository.GetMRR)
+}
+
+func (r *cachedAnalyticsRepository) GetActiveSubscriptionCountAt(ctx context.Context, timestamp time.Time) (int, error) {
+	return CachedQuery(ctx, r.cache, MetricRevenue, "active_subscriptions_at", []any{timestamp}, func(ctx context.Context) (int, error) {
+		return r.AnalyticsRepository.GetActiveSubscriptionCountAt(ctx, timestamp)
+	})
+}
+
+func (r *cachedAnalyticsRepository) GetChurnedCountBetween(ctx context.Context, start, end time.Time) (int, error) {
+	return CachedQuery(ctx, r.cache, MetricRevenue, "churned_between", []any{start, end}, func(ctx context.Context) (int, error) {
+		return r.AnalyticsRepository.GetChurnedCountBetween(ctx, start, end)
+	})
+}
+
+func (r *cachedAnalyticsRepository) GetPriceIncreaseChurnedCountBetween(ctx context.Context, start, end time.Time) (int, error) {
+	return CachedQuery(ctx, r.cache, MetricRevenue, "price_increase_churned_between", []any{start, end}, func(ctx context.Context) (int, error) {
+		return r.AnalyticsRepository.GetPriceIncreaseChurnedCountBetween(ctx, start, end)
+	})
+}
+
+func (r *cachedAnalyticsRepository) GetMRRTrend(ctx context.Context, months int) ([]domainRepo.MonthlyMRR, error) {
+	return CachedQuery(ctx, r.cache, MetricTrend, "mrr_trend", []any{months}, func(ctx context.Context) ([]domainRepo.MonthlyMRR, error) {
+		return r.AnalyticsRepository.GetMRRTrend(ctx, months)
+	})
+}
+
+func (r *cachedAnalyticsRepository) GetSubscriptionStatusCounts(ctx context.Context) (*domainRepo.SubscriptionStatusCounts, error) {
+	return CachedQuery(ctx, r.cache, MetricRevenue, "subscription_status_counts", nil, r.AnalyticsRepository.GetSubscriptionStatusCounts)
+}
+
+func (r *cachedAnalyticsRepository) GetChurnRiskCount(ctx context.Context) (int, error) {
+	return CachedQuery(ctx, r.cache, MetricRevenue, "churn_risk", nil, r.AnalyticsRepository.GetChurnRiskCount)
+}
+
+func (r *cachedAnalyticsRepository) GetWebhookHealthByProvider(ctx context.Context) ([]domainRepo.WebhookProviderHealth, error) {
+	return CachedQuery(ctx, r.cache, MetricRealtime, "webhook_health", nil, r.AnalyticsRepository.GetWebhookHealthByProvider)
+}
+
+func (r *cachedCurrencyAnalyticsRepository) GetRevenueByCurrencyBetween(ctx context.Context, start, end time.Time) ([]service.CurrencyAmount, error) {
+	return CachedQuery(ctx, r.cache, MetricRevenue, "revenue_by_currency_between", []any{start, end}, func(ctx context.Context) ([]service.CurrencyAmount, error) {
+		return r.currency.GetRevenueByCurrencyBetween(ctx, start, end)
+	})
+}
+
+func (r *cachedCurrencyAnalyticsRepository) GetMRRByCurrency(ctx context.Context) ([]service.CurrencyAmount, error) {
+	return CachedQuery(ctx, r.cache, MetricRevenue, "mrr_by_currency", nil, r.currency.GetMRRByCurrency)
+}
+
+func (r *cachedCurrencyAnalyticsRepository) GetMRRTrendByCurrency(ctx context.Context, months int) ([]service.CurrencyAmount, error) {
+	return CachedQuery(ctx, r.cache, MetricTrend, "mrr_trend_by_currency", []any{months}, func(ctx context.Context) ([]service.CurrencyAmount, error) {
+		return r.currency.GetMRRTrendByCurrency(ctx, months)
+	})
+}
+
+// cachedPriceRolloutRepository caches rollout experiment results
+type cachedPriceRolloutRepository struct {
+	domainRepo.PriceRolloutRepository
+	cache *QueryCache
+}
+
+// NewCachedPriceRolloutRepository wraps repo so cohort results are cached;
+// rollouts themselves are always read through
+func NewCachedPriceRolloutRepository(repo domainRepo.PriceRolloutRepository, cache *QueryCache) domainRepo.PriceRolloutRepository {
+	return &cachedPriceRolloutRepository{PriceRolloutRepository: repo, cache: cache}
+}
+
+func (r *cachedPriceRolloutRepository) CohortStats(ctx context.Context, rollout *entity.PriceRollout, until time.Time) ([]domainRepo.PriceRolloutCohortStats, error) {
+	params := []any{rollout.ID, rollout.HoldoutArmID, rollout.RampArmID, until}
+	return CachedQuery(ctx, r.cache, MetricExperiment, "price_rollout_cohorts", params, func(ctx context.Context) ([]domainRepo.PriceRolloutCohortStats, error) {
+		return r.PriceRolloutRepository.CohortStats(ctx, rollout, until)
+	})
+}
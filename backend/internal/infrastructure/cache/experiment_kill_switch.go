@@ -0,0 +1,236 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	// killSwitchKey is the set of stopped targets: killSwitchAll,
+	// "experiment:<id>" and "arm:<id>" members
+	killSwitchKey = "experiments:killswitch"
+	// killSwitchChannel carries each change as "+<member>" or "-<member>"
+	killSwitchChannel = "experiments:killswitch:changes"
+	killSwitchAll     = "all"
+	// killSwitchResync bounds how long a broadcast missed while disconnected
+	// goes unnoticed
+	killSwitchResync = 30 * time.Second
+)
+
+// KillSwitchState lists what the experiment kill switch has stopped
+type KillSwitchState struct {
+	AllDisabled bool        `json:"all_disabled"`
+	Experiments []uuid.UUID `json:"experiments"`
+	Arms        []uuid.UUID `json:"arms"`
+}
+
+// ExperimentKillSwitch stops experiments and arms across API instances within
+// seconds. The stopped set lives in Redis; every change is broadcast over
+// pub/sub and applied to an in-memory copy, which is what arm selection
+// checks, so assignments cached for up to a day stop being served at once.
+type ExperimentKillSwitch struct {
+	client *redis.Client
+	logger *zap.Logger
+
+	mu          sync.RWMutex
+	all         bool
+	experiments map[uuid.UUID]bool
+	arms        map[uuid.UUID]bool
+}
+
+func NewExperimentKillSwitch(client *redis.Client, logger *zap.Logger) *ExperimentKillSwitch {
+	return &ExperimentKillSwitch{
+		client:      client,
+		logger:      logger,
+		experiments: map[uuid.UUID]bool{},
+		arms:        map[uuid.UUID]bool{},
+	}
+}
+
+func experimentKillMember(experimentID uuid.UUID) string {
+	return "experiment:" + experimentID.String()
+}
+
+func armKillMember(armID uuid.UUID) string {
+	return "arm:" + armID.String()
+}
+
+// ExperimentKilled reports whether the experiment is paused or all
+// experiments are disabled
+func (k *ExperimentKillSwitch) ExperimentKilled(experimentID uuid.UUID) bool {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.all || k.experiments[experimentID]
+}
+
+// ArmKilled reports whether the arm is paused
+func (k *ExperimentKillSwitch) ArmKilled(armID uuid.UUID) bool {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.arms[armID]
+}
+
+func (k *ExperimentKillSwitch) KillExperiment(ctx context.Context, experimentID uuid.UUID) error {
+	return k.set(ctx, experimentKillMember(experimentID), true)
+}
+
+func (k *ExperimentKillSwitch) ReviveExperiment(ctx context.Context, experimentID uuid.UUID) error {
+	return k.set(ctx, experimentKillMember(experimentID), false)
+}
+
+func (k *ExperimentKillSwitch) KillArm(ctx context.Context, armID uuid.UUID) error {
+	return k.set(ctx, armKillMember(armID), true)
+}
+
+func (k *ExperimentKillSwitch) ReviveArm(ctx context.Context, armID uuid.UUID) error {
+	return k.set(ctx, armKillMember(armID), false)
+}
+
+// SetAllDisabled flips the emergency switch that stops every experiment.
+// Experiments and arms paused individually stay paused when it is cleared.
+func (k *ExperimentKillSwitch) SetAllDisabled(ctx context.Context, disabled bool) error {
+	return k.set(ctx, killSwitchAll, disabled)
+}
+
+// set stores the change and broadcasts it in one transaction, then applies
+// it locally so this instance does not wait for its own broadcast
+func (k *ExperimentKillSwitch) set(ctx context.Context, member string, killed bool) error {
+	pipe := k.client.TxPipeline()
+	change := "-" + member
+	if killed {
+		pipe.SAdd(ctx, killSwitchKey, member)
+		change = "+" + member
+	} else {
+		pipe.SRem(ctx, killSwitchKey, member)
+	}
+	pipe.Publish(ctx, killSwitchChannel, change)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to update experiment kill switch: %w", err)
+	}
+	k.applyChange(change)
+	return nil
+}
+
+// State reads the stopped set from Redis
+func (k *ExperimentKillSwitch) State(ctx context.Context) (*KillSwitchState, error) {
+	members, err := k.client.SMembers(ctx, killSwitchKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load experiment kill switch: %w", err)
+	}
+	return parseKillSwitchMembers(members), nil
+}
+
+// Reload replaces the in-memory copy with the stopped set in Redis
+func (k *ExperimentKillSwitch) Reload(ctx context.Context) error {
+	state, err := k.State(ctx)
+	if err != nil {
+		return err
+	}
+	experiments := make(map[uuid.UUID]bool, len(state.Experiments))
+	for _, id := range state.Experiments {
+		experiments[id] = true
+	}
+	arms := make(map[uuid.UUID]bool, len(state.Arms))
+	for _, id := range state.Arms {
+		arms[id] = true
+	}
+
+	k.mu.Lock()
+	k.all, k.experiments, k.arms = state.AllDisabled, experiments, arms
+	k.mu.Unlock()
+	return nil
+}
+
+// Run keeps the in-memory copy in step until ctx is done: it subscribes
+// before loading the stopped set so no change falls in between, applies
+// broadcasts as they arrive and reloads every killSwitchResync
+func (k *ExperimentKillSwitch) Run(ctx context.Context) {
+	pubsub := k.client.Subscribe(ctx, killSwitchChannel)
+	defer pubsub.Close()
+	if err := k.Reload(ctx); err != nil {
+		k.logger.Warn("Failed to load experiment kill switch", zap.Error(err))
+	}
+
+	ticker := time.NewTicker(killSwitchResync)
+	defer ticker.Stop()
+	changes := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-changes:
+			if !ok {
+				return
+			}
+			k.applyChange(msg.Payload)
+		case <-ticker.C:
+			if err := k.Reload(ctx); err != nil {
+				k.logger.Warn("Failed to reload experiment kill switch", zap.Error(err))
+			}
+		}
+	}
+}
+
+// applyChange applies one broadcast change. Malformed changes are skipped;
+// the next reload corrects anything they should have changed.
+func (k *ExperimentKillSwitch) applyChange(change string) {
+	if len(change) < 2 || (change[0] != '+' && change[0] != '-') {
+		return
+	}
+	killed, member := change[0] == '+', change[1:]
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if member == killSwitchAll {
+		k.all = killed
+		return
+	}
+	kind, rawID, _ := strings.Cut(member, ":")
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return
+	}
+	var targets map[uuid.UUID]bool
+	switch kind {
+	case "experiment":
+		targets = k.experiments
+	case "arm":
+		targets = k.arms
+	default:
+		return
+	}
+	if killed {
+		targets[id] = true
+	} else {
+		delete(targets, id)
+	}
+}
+
+func parseKillSwitchMembers(members []string) *KillSwitchState {
+	state := &KillSwitchState{Experiments: []uuid.UUID{}, Arms: []uuid.UUID{}}
+	for _, member := range members {
+		if member == killSwitchAll {
+			state.AllDisabled = true
+			continue
+		}
+		kind, rawID, _ := strings.Cut(member, ":")
+		id, err := uuid.Parse(rawID)
+		if err != nil {
+			continue
+		}
+		switch kind {
+		case "experiment":
+			state.Experiments = append(state.Experiments, id)
+		case "arm":
+			state.Arms = append(state.Arms, id)
+		}
+	}
+	return state
+}
@@ -0,0 +1,42 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestExperimentKillSwitchAppliesChanges(t *testing.T) {
+	ks := NewExperimentKillSwitch(nil, zap.NewNop())
+	experimentID, armID := uuid.New(), uuid.New()
+
+	ks.applyChange("+" + experimentKillMember(experimentID))
+	ks.applyChange("+" + armKillMember(armID))
+	assert.True(t, ks.ExperimentKilled(experimentID))
+	assert.True(t, ks.ArmKilled(armID))
+	assert.False(t, ks.ExperimentKilled(uuid.New()))
+
+	ks.applyChange("+" + killSwitchAll)
+	assert.True(t, ks.ExperimentKilled(uuid.New()))
+
+	ks.applyChange("-" + killSwitchAll)
+	ks.applyChange("-" + armKillMember(armID))
+	assert.False(t, ks.ExperimentKilled(uuid.New()))
+	assert.True(t, ks.ExperimentKilled(experimentID), "clearing the emergency switch keeps individual pauses")
+	assert.False(t, ks.ArmKilled(armID))
+
+	for _, change := range []string{"", "+", "experiment:" + experimentID.String(), "-experiment:nope", "-segment:" + experimentID.String()} {
+		ks.applyChange(change)
+	}
+	assert.True(t, ks.ExperimentKilled(experimentID))
+}
+
+func TestParseKillSwitchMembers(t *testing.T) {
+	experimentID, armID := uuid.New(), uuid.New()
+
+	state := parseKillSwitchMembers([]string{killSwitchAll, experimentKillMember(experimentID), armKillMember(armID), "arm:nope"})
+
+	assert.Equal(t, &KillSwitchState{AllDisabled: true, Experiments: []uuid.UUID{experimentID}, Arms: []uuid.UUID{armID}}, state)
+}
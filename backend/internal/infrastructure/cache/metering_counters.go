@@ -0,0 +1,166 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+// meteringDirtyKey is the set of counters changed since the last flush
+const meteringDirtyKey = "meter:dirty"
+
+// consumeMeterScript counts amount uses unless that would exceed the limit.
+// An item already counted in the window (e.g. an article read again) is
+// allowed without counting. Returns {used, allowed}.
+var consumeMeterScript = redis.NewScript(`
+local used = tonumber(redis.call('GET', KEYS[1]) or '0')
+local amount = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+local ttl = tonumber(ARGV[3])
+local item = ARGV[4]
+if item ~= '' and redis.call('SISMEMBER', KEYS[2], item) == 1 then
+	return {used, 1}
+end
+if used + amount > limit then
+	return {used, 0}
+end
+used = redis.call('INCRBY', KEYS[1], amount)
+redis.call('PEXPIRE', KEYS[1], ttl)
+if item ~= '' then
+	redis.call('SADD', KEYS[2], item)
+	redis.call('PEXPIRE', KEYS[2], ttl)
+end
+redis.call('SADD', KEYS[3], KEYS[1])
+return {used, 1}
+`)
+
+// MeteringCounters keeps soft paywall meters in Redis. Keys are
+// meter:<app>:<user>:<period start unix>:<feature>; the feature goes last
+// since feature keys may contain colons.
+type MeteringCounters struct {
+	client *redis.Client
+}
+
+func NewMeteringCounters(client *redis.Client) *MeteringCounters {
+	return &MeteringCounters{client: client}
+}
+
+func meterKey(u entity.MeterUsage) string {
+	return fmt.Sprintf("meter:%s:%s:%d:%s", u.AppID, u.UserID, u.PeriodStart.Unix(), u.Feature)
+}
+
+func parseMeterKey(key string) (entity.MeterUsage, bool) {
+	parts := strings.SplitN(key, ":", 5)
+	if len(parts) != 5 || parts[0] != "meter" || parts[4] == "" {
+		return entity.MeterUsage{}, false
+	}
+	appID, err := uuid.Parse(parts[1])
+	if err != nil {
+		return entity.MeterUsage{}, false
+	}
+	userID, err := uuid.Parse(parts[2])
+	if err != nil {
+		return entity.MeterUsage{}, false
+	}
+	start, err := strconv.ParseInt(parts[3], 10, 64)
+	if err != nil {
+		return entity.MeterUsage{}, false
+	}
+	return entity.MeterUsage{AppID: appID, UserID: userID, PeriodStart: time.Unix(start, 0).UTC(), Feature: parts[4]}, true
+}
+
+// GetUsed returns the counter and whether it exists
+func (m *MeteringCounters) GetUsed(ctx context.Context, u entity.MeterUsage) (int64, bool, error) {
+	used, err := m.client.Get(ctx, meterKey(u)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get meter: %w", err)
+	}
+	return used, true, nil
+}
+
+// Seed sets the counter from persisted usage unless it exists already
+func (m *MeteringCounters) Seed(ctx context.Context, u entity.MeterUsage, ttl time.Duration) error {
+	if err := m.client.SetNX(ctx, meterKey(u), u.Used, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to seed meter: %w", err)
+	}
+	return nil
+}
+
+// Consume counts amount uses unless that would take the counter past limit
+func (m *MeteringCounters) Consume(ctx context.Context, u entity.MeterUsage, itemID string, amount, limit int64, ttl time.Duration) (int64, bool, error) {
+	key := meterKey(u)
+	res, err := consumeMeterScript.Run(ctx, m.client,
+		[]string{key, key + ":items", meteringDirtyKey},
+		amount, limit, ttl.Milliseconds(), itemID,
+	).Int64Slice()
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to consume meter: %w", err)
+	}
+	if len(res) != 2 {
+		return 0, false, fmt.Errorf("failed to consume meter: unexpected reply %v", res)
+	}
+	return res[0], res[1] == 1, nil
+}
+
+// PopDirty takes up to n changed counters off the dirty set with their
+// current values. Counters that expired since are skipped.
+func (m *MeteringCounters) PopDirty(ctx context.Context, n int) ([]*entity.MeterUsage, error) {
+	keys, err := m.client.SPopN(ctx, meteringDirtyKey, int64(n)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to pop dirty meters: %w", err)
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	values, err := m.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		// Put them back so the next flush retries
+		m.client.SAdd(ctx, meteringDirtyKey, keys)
+		return nil, fmt.Errorf("failed to read dirty meters: %w", err)
+	}
+
+	usages := make([]*entity.MeterUsage, 0, len(keys))
+	for i, key := range keys {
+		raw, ok := values[i].(string)
+		if !ok {
+			continue
+		}
+		usage, ok := parseMeterKey(key)
+		if !ok {
+			continue
+		}
+		used, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			continue
+		}
+		usage.Used = used
+		usages = append(usages, &usage)
+	}
+	return usages, nil
+}
+
+// MarkDirty puts counters back on the dirty set after a failed flush
+func (m *MeteringCounters) MarkDirty(ctx context.Context, usages []*entity.MeterUsage) error {
+	if len(usages) == 0 {
+		return nil
+	}
+	keys := make([]interface{}, 0, len(usages))
+	for _, u := range usages {
+		keys = append(keys, meterKey(*u))
+	}
+	if err := m.client.SAdd(ctx, meteringDirtyKey, keys...).Err(); err != nil {
+		return fmt.Errorf("failed to mark meters dirty: %w", err)
+	}
+	return nil
+}
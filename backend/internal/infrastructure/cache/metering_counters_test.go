@@ -0,0 +1,33 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+func TestMeterKeyRoundTrip(t *testing.T) {
+	usage := entity.MeterUsage{
+		AppID:       uuid.New(),
+		UserID:      uuid.New(),
+		Feature:     "articles:premium",
+		PeriodStart: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	parsed, ok := parseMeterKey(meterKey(usage))
+
+	require.True(t, ok)
+	assert.Equal(t, usage, parsed)
+}
+
+func TestParseMeterKeyRejectsForeignKeys(t *testing.T) {
+	for _, key := range []string{meteringDirtyKey, "meter:not-a-uuid:x:1:f", "ab:assign:1:2"} {
+		_, ok := parseMeterKey(key)
+		assert.False(t, ok, key)
+	}
+}
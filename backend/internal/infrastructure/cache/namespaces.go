@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ErrUnsafeFlushPattern is returned for patterns that are not confined to a
+// known namespace, so a typo can never wipe the whole Redis database
+var ErrUnsafeFlushPattern = errors.New("flush pattern outside known cache namespaces")
+
+// ErrUnsupportedFlushScope is returned when a namespace cannot be narrowed
+// to the requested experiment or user
+var ErrUnsupportedFlushScope = errors.New("namespace cannot be narrowed to this scope")
+
+// CacheNamespace names a family of keys that are counted and flushed together
+type CacheNamespace string
+
+const (
+	// NamespaceAnalytics covers every analytics:* key, LTV included
+	NamespaceAnalytics   CacheNamespace = "analytics"
+	NamespaceArmStats    CacheNamespace = "arm_stats"
+	NamespaceAssignments CacheNamespace = "assignments"
+	NamespaceLTV         CacheNamespace = "ltv"
+)
+
+// CacheNamespaces lists the namespaces in display order
+var CacheNamespaces = []CacheNamespace{NamespaceAnalytics, NamespaceArmStats, NamespaceAssignments, NamespaceLTV}
+
+var namespacePrefixes = map[CacheNamespace]string{
+	NamespaceAnalytics:   "analytics:",
+	NamespaceArmStats:    "ab:arm:",
+	NamespaceAssignments: "ab:assign:",
+	NamespaceLTV:         "analytics:ltv:",
+}
+
+// NamespacePattern returns the SCAN pattern for a namespace, narrowed to one
+// experiment and/or user where the key layout allows it. uuid.Nil leaves a
+// dimension unrestricted.
+func NamespacePattern(namespace CacheNamespace, experimentID, userID uuid.UUID) (string, error) {
+	prefix, ok := namespacePrefixes[namespace]
+	if !ok {
+		return "", fmt.Errorf("unknown cache namespace %q", namespace)
+	}
+
+	switch namespace {
+	case NamespaceAssignments:
+		// ab:assign:<experiment>:<user>
+		experiment, user := "*", "*"
+		if experimentID != uuid.Nil {
+			experiment = experimentID.String()
+		}
+		if userID != uuid.Nil {
+			user = userID.String()
+		}
+		if experiment == "*" && user == "*" {
+			return prefix + "*", nil
+		}
+		return prefix + experiment + ":" + user, nil
+	case NamespaceLTV:
+		if experimentID != uuid.Nil {
+			return "", fmt.Errorf("%w: %s by experiment", ErrUnsupportedFlushScope, namespace)
+		}
+		if userID != uuid.Nil {
+			return prefix + userID.String(), nil
+		}
+		return prefix + "*", nil
+	default:
+		if experimentID != uuid.Nil || userID != uuid.Nil {
+			return "", fmt.Errorf("%w: %s", ErrUnsupportedFlushScope, namespace)
+		}
+		return prefix + "*", nil
+	}
+}
+
+// isSafeFlushPattern accepts only patterns rooted in a known namespace prefix
+func isSafeFlushPattern(pattern string) bool {
+	for _, prefix := range namespacePrefixes {
+		if strings.HasPrefix(pattern, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// CountPattern returns how many keys match pattern
+func (c *AnalyticsCache) CountPattern(ctx context.Context, pattern string) (int64, error) {
+	var count int64
+	iter := c.client.Scan(ctx, 0, pattern, 500).Iterator()
+	for iter.Next(ctx) {
+		count++
+	}
+	if err := iter.Err(); err != nil {
+		return 0, fmt.Errorf("failed to scan keys: %w", err)
+	}
+	return count, nil
+}
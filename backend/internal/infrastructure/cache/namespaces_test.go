@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestNamespacePattern(t *testing.T) {
+	experimentID, userID := uuid.New(), uuid.New()
+
+	cases := []struct {
+		namespace    CacheNamespace
+		experimentID uuid.UUID
+		userID       uuid.UUID
+		want         string
+	}{
+		{NamespaceAnalytics, uuid.Nil, uuid.Nil, "analytics:*"},
+		{NamespaceArmStats, uuid.Nil, uuid.Nil, "ab:arm:*"},
+		{NamespaceAssignments, uuid.Nil, uuid.Nil, "ab:assign:*"},
+		{NamespaceAssignments, experimentID, uuid.Nil, "ab:assign:" + experimentID.String() + ":*"},
+		{NamespaceAssignments, uuid.Nil, userID, "ab:assign:*:" + userID.String()},
+		{NamespaceAssignments, experimentID, userID, "ab:assign:" + experimentID.String() + ":" + userID.String()},
+		{NamespaceLTV, uuid.Nil, userID, "analytics:ltv:" + userID.String()},
+	}
+	for _, tc := range cases {
+		pattern, err := NamespacePattern(tc.namespace, tc.experimentID, tc.userID)
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, pattern)
+		assert.True(t, isSafeFlushPattern(pattern))
+	}
+
+	_, err := NamespacePattern(NamespaceArmStats, experimentID, uuid.Nil)
+	assert.ErrorIs(t, err, ErrUnsupportedFlushScope)
+	_, err = NamespacePattern(NamespaceLTV, experimentID, uuid.Nil)
+	assert.ErrorIs(t, err, ErrUnsupportedFlushScope)
+	_, err = NamespacePattern("sessions", uuid.Nil, uuid.Nil)
+	assert.Error(t, err)
+}
+
+func TestFlushPatternRefusesPatternsOutsideNamespaces(t *testing.T) {
+	// The guard runs before Redis is touched
+	analyticsCache := NewAnalyticsCache(nil, zap.NewNop())
+	for _, pattern := range []string{"*", "", "ab:*", "currency:rate:*", "*analytics:*"} {
+		deleted, err := analyticsCache.FlushPattern(context.Background(), pattern)
+		assert.ErrorIs(t, err, ErrUnsafeFlushPattern, pattern)
+		assert.Zero(t, deleted)
+	}
+}
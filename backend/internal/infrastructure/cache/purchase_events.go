@@ -0,0 +1,87 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+// PurchaseResolvedEvent is published when a deferred purchase is finalized
+type PurchaseResolvedEvent struct {
+	PendingPurchaseID uuid.UUID `json:"pending_purchase_id"`
+	ProductID         string    `json:"product_id"`
+	Platform          string    `json:"platform"`
+	Status            string    `json:"status"`
+	ProviderTxID      string    `json:"provider_tx_id,omitempty"`
+	ResolvedAt        time.Time `json:"resolved_at"`
+}
+
+// PurchaseEventBroker fans pending purchase resolutions out over Redis pub/sub
+// so an SSE stream on any API instance sees resolutions made by the worker
+type PurchaseEventBroker struct {
+	client *redis.Client
+}
+
+func NewPurchaseEventBroker(client *redis.Client) *PurchaseEventBroker {
+	return &PurchaseEventBroker{client: client}
+}
+
+func purchaseEventsChannel(userID uuid.UUID) string {
+	return "purchases:resolved:" + userID.String()
+}
+
+// NotifyPurchaseResolved publishes the resolution to the user's channel
+func (b *PurchaseEventBroker) NotifyPurchaseResolved(ctx context.Context, purchase *entity.PendingPurchase) error {
+	event := PurchaseResolvedEvent{
+		PendingPurchaseID: purchase.ID,
+		ProductID:         purchase.ProductID,
+		Platform:          purchase.Platform,
+		Status:            string(purchase.Status),
+		ProviderTxID:      purchase.ProviderTxID,
+	}
+	if purchase.ResolvedAt != nil {
+		event.ResolvedAt = *purchase.ResolvedAt
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal purchase event: %w", err)
+	}
+	if err := b.client.Publish(ctx, purchaseEventsChannel(purchase.UserID), payload).Err(); err != nil {
+		return fmt.Errorf("failed to publish purchase event: %w", err)
+	}
+	return nil
+}
+
+// SubscribePurchaseEvents streams the user's resolutions until ctx is done or
+// the returned close function is called. Malformed messages are skipped.
+func (b *PurchaseEventBroker) SubscribePurchaseEvents(ctx context.Context, userID uuid.UUID) (<-chan PurchaseResolvedEvent, func(), error) {
+	pubsub := b.client.Subscribe(ctx, purchaseEventsChannel(userID))
+	// Wait for the subscription so no event published right after is missed
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, nil, fmt.Errorf("failed to subscribe to purchase events: %w", err)
+	}
+
+	events := make(chan PurchaseResolvedEvent)
+	go func() {
+		defer close(events)
+		for msg := range pubsub.Channel() {
+			var event PurchaseResolvedEvent
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				continue
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, func() { pubsub.Close() }, nil
+}
@@ -0,0 +1,214 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/bivex/paywall-iap/internal/appctx"
+	"github.com/bivex/paywall-iap/internal/infrastructure/metrics"
+)
+
+// MetricClass groups cached queries that tolerate the same staleness
+type MetricClass string
+
+const (
+	// MetricRealtime is operational state such as webhook backlogs
+	MetricRealtime MetricClass = "realtime"
+	// MetricRevenue is revenue, MRR and subscription counts
+	MetricRevenue MetricClass = "revenue"
+	// MetricTrend is month-grained history
+	MetricTrend MetricClass = "trend"
+	// MetricExperiment is experiment and rollout results
+	MetricExperiment MetricClass = "experiment"
+)
+
+// DefaultQueryTTLs are the cache lifetimes of each metric class
+var DefaultQueryTTLs = map[MetricClass]time.Duration{
+	MetricRealtime:   30 * time.Second,
+	MetricRevenue:    5 * time.Minute,
+	MetricTrend:      time.Hour,
+	MetricExperiment: 2 * time.Minute,
+}
+
+const (
+	queryKeyPrefix = "analytics:query:"
+	// queryGlobalScope holds entries computed without an app in the context;
+	// its generation also invalidates every app's entries
+	queryGlobalScope = "global"
+	// queryLockTTL bounds how long one instance may hold a recompute lock
+	queryLockTTL = 10 * time.Second
+	// queryLockWait is how long other instances wait for that recompute
+	// before running the query themselves
+	queryLockWait = 2 * time.Second
+	queryLockPoll = 50 * time.Millisecond
+)
+
+var queryCacheRequests = metrics.NewCounterVec(
+	"query_cache_requests_total",
+	"Cached analytics queries by metric class and outcome: hit, miss, or error when Redis was unavailable",
+	"class", "outcome",
+)
+
+// QueryCache caches read-only query results in Redis. Entries are scoped to
+// the app in the context and dropped in O(1) by bumping the app's generation,
+// so purchases and refunds can invalidate without scanning keys. A miss is
+// recomputed once per process (singleflight) and, through a short Redis lock,
+// once across instances.
+type QueryCache struct {
+	client  *redis.Client
+	logger  *zap.Logger
+	ttls    map[MetricClass]time.Duration
+	flights singleflight.Group
+}
+
+// NewQueryCache creates a query cache with the default TTLs
+func NewQueryCache(client *redis.Client, logger *zap.Logger) *QueryCache {
+	ttls := make(map[MetricClass]time.Duration, len(DefaultQueryTTLs))
+	for class, ttl := range DefaultQueryTTLs {
+		ttls[class] = ttl
+	}
+	return &QueryCache{client: client, logger: logger, ttls: ttls}
+}
+
+// WithTTL overrides the cache lifetime of a metric class
+func (q *QueryCache) WithTTL(class MetricClass, ttl time.Duration) *QueryCache {
+	q.ttls[class] = ttl
+	return q
+}
+
+// Invalidate drops every cached result of an app; uuid.Nil drops all apps'
+func (q *QueryCache) Invalidate(ctx context.Context, appID uuid.UUID) error {
+	scope := queryGlobalScope
+	if appID != uuid.Nil {
+		scope = appID.String()
+	}
+	if err := q.client.Incr(ctx, queryKeyPrefix+"gen:"+scope).Err(); err != nil {
+		return fmt.Errorf("failed to invalidate query cache: %w", err)
+	}
+	return nil
+}
+
+// CachedQuery returns the cached result of query with params, or runs load
+// and caches it. Time parameters are bucketed to the class TTL so "until now"
+// requests share an entry. Redis failures fall back to load; a nil cache
+// always loads.
+func CachedQuery[T any](ctx context.Context, q *QueryCache, class MetricClass, query string, params []any, load func(context.Context) (T, error)) (T, error) {
+	if q == nil {
+		return load(ctx)
+	}
+	ttl := q.ttls[class]
+	scope := queryGlobalScope
+	if appID, ok := appctx.AppIDFromCtx(ctx); ok && appID != uuid.Nil {
+		scope = appID.String()
+	}
+
+	gens, err := q.client.MGet(ctx, queryKeyPrefix+"gen:"+queryGlobalScope, queryKeyPrefix+"gen:"+scope).Result()
+	if err != nil {
+		queryCacheRequests.Inc(string(class), "error")
+		q.logger.Warn("Query cache unavailable", zap.String("query", query), zap.Error(err))
+		return load(ctx)
+	}
+	key := queryCacheKey(scope, class, query, gens, params, ttl)
+
+	var cached T
+	if ok, err := q.get(ctx, key, &cached); err == nil && ok {
+		queryCacheRequests.Inc(string(class), "hit")
+		return cached, nil
+	}
+	queryCacheRequests.Inc(string(class), "miss")
+
+	v, err, _ := q.flights.Do(key, func() (any, error) {
+		return recomputeQuery(ctx, q, key, ttl, load)
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return v.(T), nil
+}
+
+// recomputeQuery runs load under a cross-instance lock; without the lock it
+// waits for the holder's result and runs load itself if none appears in time
+func recomputeQuery[T any](ctx context.Context, q *QueryCache, key string, ttl time.Duration, load func(context.Context) (T, error)) (T, error) {
+	locked, err := q.client.SetNX(ctx, key+":lock", 1, queryLockTTL).Result()
+	if err == nil && !locked {
+		deadline := time.Now().Add(queryLockWait)
+		for time.Now().Before(deadline) {
+			select {
+			case <-ctx.Done():
+				var zero T
+				return zero, ctx.Err()
+			case <-time.After(queryLockPoll):
+			}
+			var cached T
+			if ok, err := q.get(ctx, key, &cached); err == nil && ok {
+				return cached, nil
+			}
+		}
+	}
+
+	result, err := load(ctx)
+	if err != nil {
+		if locked {
+			q.client.Del(ctx, key+":lock")
+		}
+		return result, err
+	}
+	if data, err := json.Marshal(result); err == nil {
+		pipe := q.client.TxPipeline()
+		pipe.Set(ctx, key, data, ttl)
+		if locked {
+			pipe.Del(ctx, key+":lock")
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			q.logger.Warn("Failed to cache query result", zap.String("key", key), zap.Error(err))
+		}
+	}
+	return result, nil
+}
+
+func (q *QueryCache) get(ctx context.Context, key string, dst any) (bool, error) {
+	data, err := q.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(data, dst); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// queryCacheKey builds analytics:query:<scope>:<class>:<query>:<generations>:<params hash>
+func queryCacheKey(scope string, class MetricClass, query string, gens []any, params []any, ttl time.Duration) string {
+	normalized := make([]any, len(params))
+	for i, p := range params {
+		if t, ok := p.(time.Time); ok && ttl > 0 {
+			p = t.UTC().Truncate(ttl)
+		}
+		normalized[i] = p
+	}
+	data, _ := json.Marshal(normalized)
+	sum := sha256.Sum256(data)
+	return fmt.Sprintf("%s%s:%s:%s:%v.%v:%s", queryKeyPrefix, scope, class, query,
+		generation(gens[0]), generation(gens[1]), hex.EncodeToString(sum[:8]))
+}
+
+func generation(v any) any {
+	if v == nil {
+		return 0
+	}
+	return v
+}
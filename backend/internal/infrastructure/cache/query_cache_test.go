@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestQueryCacheKeyBucketsTimes(t *testing.T) {
+	ttl := 5 * time.Minute
+	bucket := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	gens := []any{nil, "3"}
+
+	a := queryCacheKey("global", MetricRevenue, "revenue_between", gens, []any{bucket, bucket.Add(time.Minute)}, ttl)
+	b := queryCacheKey("global", MetricRevenue, "revenue_between", gens, []any{bucket, bucket.Add(4 * time.Minute)}, ttl)
+	c := queryCacheKey("global", MetricRevenue, "revenue_between", gens, []any{bucket, bucket.Add(6 * time.Minute)}, ttl)
+
+	assert.Equal(t, a, b)
+	assert.NotEqual(t, a, c)
+}
+
+func TestQueryCacheKeyChangesWithGeneration(t *testing.T) {
+	params := []any{12}
+
+	before := queryCacheKey("app", MetricTrend, "mrr_trend", []any{nil, nil}, params, time.Hour)
+	appBumped := queryCacheKey("app", MetricTrend, "mrr_trend", []any{nil, "1"}, params, time.Hour)
+	globalBumped := queryCacheKey("app", MetricTrend, "mrr_trend", []any{"1", nil}, params, time.Hour)
+
+	assert.NotEqual(t, before, appBumped)
+	assert.NotEqual(t, before, globalBumped)
+	assert.NotEqual(t, appBumped, globalBumped)
+}
+
+func TestCachedQueryFallsBackToLoad(t *testing.T) {
+	load := func(context.Context) (int, error) { return 42, nil }
+
+	v, err := CachedQuery(context.Background(), nil, MetricRevenue, "churn_risk", nil, load)
+	require.NoError(t, err)
+	assert.Equal(t, 42, v)
+
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", DialTimeout: 50 * time.Millisecond, MaxRetries: -1})
+	defer client.Close()
+	q := NewQueryCache(client, zap.NewNop())
+
+	v, err = CachedQuery(context.Background(), q, MetricRevenue, "churn_risk", nil, load)
+	require.NoError(t, err)
+	assert.Equal(t, 42, v)
+}
@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// verificationOutcomeTTL keeps minute buckets long enough for any health window
+const verificationOutcomeTTL = 2 * time.Hour
+
+// VerificationOutcomes counts receipt verifications per app and minute. Keys
+// are health:verify:<app>:<unix minute>, hashes with total and failed fields.
+type VerificationOutcomes struct {
+	client *redis.Client
+}
+
+func NewVerificationOutcomes(client *redis.Client) *VerificationOutcomes {
+	return &VerificationOutcomes{client: client}
+}
+
+func verificationOutcomeKey(appID uuid.UUID, minute int64) string {
+	return fmt.Sprintf("health:verify:%s:%d", appID, minute)
+}
+
+// RecordVerification counts one verification attempt in the current minute
+func (v *VerificationOutcomes) RecordVerification(ctx context.Context, appID uuid.UUID, failed bool) error {
+	key := verificationOutcomeKey(appID, time.Now().Unix()/60)
+	pipe := v.client.Pipeline()
+	pipe.HIncrBy(ctx, key, "total", 1)
+	if failed {
+		pipe.HIncrBy(ctx, key, "failed", 1)
+	}
+	pipe.Expire(ctx, key, verificationOutcomeTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to record verification outcome: %w", err)
+	}
+	return nil
+}
+
+// VerificationCounts sums attempts and failures over the window ending at now
+func (v *VerificationOutcomes) VerificationCounts(ctx context.Context, appID uuid.UUID, window time.Duration, now time.Time) (int64, int64, error) {
+	last := now.Unix() / 60
+	first := now.Add(-window).Unix()/60 + 1
+	pipe := v.client.Pipeline()
+	cmds := make([]*redis.SliceCmd, 0, last-first+1)
+	for minute := first; minute <= last; minute++ {
+		cmds = append(cmds, pipe.HMGet(ctx, verificationOutcomeKey(appID, minute), "total", "failed"))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return 0, 0, fmt.Errorf("failed to read verification outcomes: %w", err)
+	}
+
+	var total, failed int64
+	for _, cmd := range cmds {
+		values := cmd.Val()
+		total += parseCount(values, 0)
+		failed += parseCount(values, 1)
+	}
+	return total, failed, nil
+}
+
+func parseCount(values []interface{}, i int) int64 {
+	if i >= len(values) {
+		return 0
+	}
+	s, ok := values[i].(string)
+	if !ok {
+		return 0
+	}
+	n, _ := strconv.ParseInt(s, 10, 64)
+	return n
+}
@@ -17,7 +17,18 @@ type Config struct {
 	IAP          IAPConfig          `mapstructure:"iap"`
 	Sentry       SentryConfig       `mapstructure:"sentry"`
 	Lago         LagoConfig         `mapstructure:"lago"`
+	PayPal       PayPalConfig       `mapstructure:"paypal"`
 	Notification NotificationConfig `mapstructure:"notification"`
+	Revenue      RevenueConfig      `mapstructure:"revenue"`
+	Logging      LoggingConfig      `mapstructure:"logging"`
+	LoadShedding LoadSheddingConfig `mapstructure:"load_shedding"`
+	Ingest       IngestConfig       `mapstructure:"ingest"`
+	Warehouse    WarehouseConfig    `mapstructure:"warehouse"`
+	Entitlement  EntitlementConfig  `mapstructure:"entitlement"`
+	Retention    RetentionConfig    `mapstructure:"retention"`
+	Bandit       BanditConfig       `mapstructure:"bandit"`
+	Faults       FaultConfig        `mapstructure:"faults"`
+	WebhookSLO   WebhookSLOConfig   `mapstructure:"webhook_slo"`
 }
 
 // ServerConfig holds HTTP server configuration
@@ -26,14 +37,25 @@ type ServerConfig struct {
 	ReadTimeout     time.Duration `mapstructure:"read_timeout"`
 	WriteTimeout    time.Duration `mapstructure:"write_timeout"`
 	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+	// MetricsPort serves Prometheus metrics apart from the public API so it
+	// can stay off the internet; 0 disables metrics
+	MetricsPort int `mapstructure:"metrics_port"`
 }
 
-// JWTConfig holds JWT configuration
+// JWTConfig holds JWT configuration. SigningKeys is a comma-separated list of
+// kid=key pairs, key being a base64 PKCS#8 DER Ed25519 or RSA private key;
+// the first signs access tokens and all are published at
+// /.well-known/jwks.json. Setting it switches auth tokens from the shared
+// secret to RS256/EdDSA; AcceptHS256 keeps secret-signed tokens valid during
+// the migration. The keys must be distinct from the entitlement signing
+// keys, or an entitlement token would verify as an access token.
 type JWTConfig struct {
-	Secret     string        `mapstructure:"secret"`
-	AccessTTL  time.Duration `mapstructure:"access_ttl"`
-	RefreshTTL time.Duration `mapstructure:"refresh_ttl"`
-	Issuer     string        `mapstructure:"issuer"`
+	Secret      string        `mapstructure:"secret"`
+	AccessTTL   time.Duration `mapstructure:"access_ttl"`
+	RefreshTTL  time.Duration `mapstructure:"refresh_ttl"`
+	Issuer      string        `mapstructure:"issuer"`
+	SigningKeys string        `mapstructure:"signing_keys"`
+	AcceptHS256 bool          `mapstructure:"accept_hs256"`
 }
 
 // RedisConfig holds Redis configuration
@@ -58,13 +80,90 @@ type IAPConfig struct {
 	AppleWebhookSecret  string `mapstructure:"apple_webhook_secret"`
 	GoogleWebhookSecret string `mapstructure:"google_webhook_secret"`
 	IsProduction        bool   `mapstructure:"is_production"`
+
+	// Apple signed-payload (JWS x5c) verification
+	AppleRootCAPath              string `mapstructure:"apple_root_ca_path"`
+	AppleJWSCheckOCSP            bool   `mapstructure:"apple_jws_check_ocsp"`
+	AppleJWSVerificationDisabled bool   `mapstructure:"apple_jws_verification_disabled"`
+	AppleWebhookAllowSandbox     bool   `mapstructure:"apple_webhook_allow_sandbox"`
+
+	// Google Pub/Sub push (RTDN) OIDC authentication
+	GooglePubSubAudience       string `mapstructure:"google_pubsub_audience"`
+	GooglePubSubIssuer         string `mapstructure:"google_pubsub_issuer"`
+	GooglePubSubServiceAccount string `mapstructure:"google_pubsub_service_account"`
+	GooglePubSubJWKSURL        string `mapstructure:"google_pubsub_jwks_url"`
+	GooglePubSubAuthDisabled   bool   `mapstructure:"google_pubsub_auth_disabled"`
+
+	// Maximum age of a Stripe-Signature timestamp (replay protection)
+	StripeWebhookTolerance time.Duration `mapstructure:"stripe_webhook_tolerance"`
+
+	// Amazon Appstore: RVS shared secret, RVS endpoint override (RVS Cloud
+	// Sandbox or a mock) and the SNS topics Real-time Notifications may come
+	// from; no topics accepts any topic with a valid SNS signature
+	AmazonSharedSecret            string `mapstructure:"amazon_shared_secret"`
+	AmazonRVSURL                  string `mapstructure:"amazon_rvs_url"`
+	AmazonSNSTopicARNs            string `mapstructure:"amazon_sns_topic_arns"`
+	AmazonSNSVerificationDisabled bool   `mapstructure:"amazon_sns_verification_disabled"`
+
+	// Huawei AppGallery: Order / Subscription service OAuth client, the IAP
+	// public key notifications and purchase data are signed with, and an
+	// endpoint override for both services (mock)
+	HuaweiClientID                      string `mapstructure:"huawei_client_id"`
+	HuaweiClientSecret                  string `mapstructure:"huawei_client_secret"`
+	HuaweiPublicKey                     string `mapstructure:"huawei_public_key"`
+	HuaweiIAPBaseURL                    string `mapstructure:"huawei_iap_base_url"`
+	HuaweiSignatureVerificationDisabled bool   `mapstructure:"huawei_signature_verification_disabled"`
+
+	// Apple's SKAdNetwork public key (PEM or base64 DER); when set, postbacks
+	// with an invalid attribution signature are rejected
+	SKAdNetworkPublicKey string `mapstructure:"skadnetwork_public_key"`
+}
+
+// AppleNotificationEnvironments returns the App Store notification
+// environments this deployment accepts: production deployments take
+// Production (plus Sandbox when allowed), all others only Sandbox.
+func (c IAPConfig) AppleNotificationEnvironments() []string {
+	if !c.IsProduction {
+		return []string{"Sandbox"}
+	}
+	if c.AppleWebhookAllowSandbox {
+		return []string{"Production", "Sandbox"}
+	}
+	return []string{"Production"}
+}
+
+// AmazonConfigured reports whether Amazon receipts can be verified: a shared
+// secret or an RVS endpoint override (RVS Cloud Sandbox or a mock) is set
+func (c IAPConfig) AmazonConfigured() bool {
+	return c.AmazonSharedSecret != "" || c.AmazonRVSURL != ""
 }
 
-// SentryConfig holds Sentry configuration
+// HuaweiConfigured reports whether the Huawei Order / Subscription service
+// OAuth client is set
+func (c IAPConfig) HuaweiConfigured() bool {
+	return c.HuaweiClientID != "" && c.HuaweiClientSecret != ""
+}
+
+// AmazonTopicARNs returns the SNS topics Amazon notifications are accepted from
+func (c IAPConfig) AmazonTopicARNs() []string {
+	var arns []string
+	for _, arn := range strings.Split(c.AmazonSNSTopicARNs, ",") {
+		if arn = strings.TrimSpace(arn); arn != "" {
+			arns = append(arns, arn)
+		}
+	}
+	return arns
+}
+
+// SentryConfig holds Sentry configuration. SampleRate is the share of error
+// events sent; TracesSampleRate the share of HTTP requests and worker tasks
+// traced.
 type SentryConfig struct {
-	DSN         string `mapstructure:"dsn"`
-	Environment string `mapstructure:"environment"`
-	Release     string `mapstructure:"release"`
+	DSN              string  `mapstructure:"dsn"`
+	Environment      string  `mapstructure:"environment"`
+	Release          string  `mapstructure:"release"`
+	SampleRate       float64 `mapstructure:"sample_rate"`
+	TracesSampleRate float64 `mapstructure:"traces_sample_rate"`
 }
 
 // LagoConfig holds Lago billing configuration
@@ -74,6 +173,17 @@ type LagoConfig struct {
 	WebhookSecret string `mapstructure:"webhook_secret"`
 }
 
+// PayPalConfig holds PayPal Billing configuration: the ID of the webhook
+// deliveries are signed for, and the REST app used to look up subscriptions.
+// APIBaseURL selects live, sandbox or a mock.
+type PayPalConfig struct {
+	WebhookID            string `mapstructure:"webhook_id"`
+	ClientID             string `mapstructure:"client_id"`
+	ClientSecret         string `mapstructure:"client_secret"`
+	APIBaseURL           string `mapstructure:"api_base_url"`
+	VerificationDisabled bool   `mapstructure:"verification_disabled"`
+}
+
 // NotificationConfig holds push/email notification configuration
 type NotificationConfig struct {
 	FCMServerKey    string `mapstructure:"fcm_server_key"`
@@ -85,6 +195,125 @@ type NotificationConfig struct {
 	FromEmail       string `mapstructure:"from_email"`
 }
 
+// RevenueConfig holds revenue accounting configuration: which basis (gross or
+// net of store fees and tax) feeds LTV and bandit revenue objectives, and the
+// default store commission rates used when a store does not report its fee.
+// ReportingCurrency is the currency admin revenue metrics are converted into.
+type RevenueConfig struct {
+	Basis             string  `mapstructure:"basis"`
+	ReportingCurrency string  `mapstructure:"reporting_currency"`
+	AppleCommission   float64 `mapstructure:"apple_commission"`
+	GoogleCommission  float64 `mapstructure:"google_commission"`
+	AmazonCommission  float64 `mapstructure:"amazon_commission"`
+	HuaweiCommission  float64 `mapstructure:"huawei_commission"`
+	StripeFeePercent  float64 `mapstructure:"stripe_fee_percent"`
+	StripeFeeFixed    float64 `mapstructure:"stripe_fee_fixed"`
+	PayPalFeePercent  float64 `mapstructure:"paypal_fee_percent"`
+	PayPalFeeFixed    float64 `mapstructure:"paypal_fee_fixed"`
+}
+
+// LoggingConfig holds HTTP request logging configuration. SampleRate is the
+// share of successful, fast requests that are logged; failed and slow requests
+// are always logged. RouteLevels is a comma-separated list of route=level
+// pairs setting the minimum level logged for a route, e.g. "/health=warn".
+type LoggingConfig struct {
+	SampleRate           float64       `mapstructure:"sample_rate"`
+	SlowRequestThreshold time.Duration `mapstructure:"slow_request_threshold"`
+	BodyMaxBytes         int           `mapstructure:"body_max_bytes"`
+	RouteLevels          string        `mapstructure:"route_levels"`
+}
+
+// LoadSheddingConfig holds overload protection configuration. Classes is a
+// comma-separated list of class=max_in_flight/max_queue budgets for the
+// critical, normal and low priority classes, where 0 in flight is unlimited.
+// Access checks beyond AccessCheckRate per second count as low priority.
+type LoadSheddingConfig struct {
+	Enabled         bool          `mapstructure:"enabled"`
+	Classes         string        `mapstructure:"classes"`
+	QueueTimeout    time.Duration `mapstructure:"queue_timeout"`
+	RetryAfter      time.Duration `mapstructure:"retry_after"`
+	AccessCheckRate int           `mapstructure:"access_check_rate"`
+}
+
+// IngestConfig holds analytics event ingestion configuration. Up to
+// BufferSize events are held in memory and written with COPY in batches of
+// BatchSize, at least every FlushInterval; submissions beyond the buffer are
+// rejected so clients back off.
+type IngestConfig struct {
+	BufferSize    int           `mapstructure:"buffer_size"`
+	BatchSize     int           `mapstructure:"batch_size"`
+	FlushInterval time.Duration `mapstructure:"flush_interval"`
+}
+
+// WarehouseConfig holds the data warehouse sync destination. Provider is
+// bigquery, snowflake or empty to disable the sync; BatchSize is how many rows
+// each MERGE carries. Snowflake authenticates with key-pair JWTs, so
+// SnowflakePrivateKey is the user's PEM private key.
+type WarehouseConfig struct {
+	Provider                string `mapstructure:"provider"`
+	BatchSize               int    `mapstructure:"batch_size"`
+	BigQueryProjectID       string `mapstructure:"bigquery_project_id"`
+	BigQueryDataset         string `mapstructure:"bigquery_dataset"`
+	BigQueryLocation        string `mapstructure:"bigquery_location"`
+	BigQueryCredentialsJSON string `mapstructure:"bigquery_credentials_json"`
+	SnowflakeAccount        string `mapstructure:"snowflake_account"`
+	SnowflakeUser           string `mapstructure:"snowflake_user"`
+	SnowflakePrivateKey     string `mapstructure:"snowflake_private_key"`
+	SnowflakeDatabase       string `mapstructure:"snowflake_database"`
+	SnowflakeSchema         string `mapstructure:"snowflake_schema"`
+	SnowflakeWarehouse      string `mapstructure:"snowflake_warehouse"`
+	SnowflakeRole           string `mapstructure:"snowflake_role"`
+}
+
+// EntitlementConfig holds offline entitlement token signing. SigningKeys is a
+// comma-separated list of kid=key pairs, key being a base64 PKCS#8 DER
+// Ed25519 or RSA private key; the first signs and all are published so tokens
+// signed before a rotation stay verifiable. Empty disables the tokens.
+type EntitlementConfig struct {
+	SigningKeys string        `mapstructure:"signing_keys"`
+	TokenTTL    time.Duration `mapstructure:"token_ttl"`
+}
+
+// RetentionConfig holds the nightly data retention cleanup. Policies is a
+// comma-separated list of policy=duration overrides of the default
+// retentions, "off" disabling a policy; each policy deletes at most
+// BatchSize*MaxBatches rows per run.
+type RetentionConfig struct {
+	Policies   string `mapstructure:"policies"`
+	BatchSize  int    `mapstructure:"batch_size"`
+	MaxBatches int    `mapstructure:"max_batches"`
+}
+
+// BanditConfig holds the win probability simulation limits. SimulationBudget
+// caps the Beta draws (simulations times arms) of one simulation and
+// SimulationWorkers the goroutines running it, 0 using every CPU.
+type BanditConfig struct {
+	SimulationBudget  int `mapstructure:"simulation_budget"`
+	SimulationWorkers int `mapstructure:"simulation_workers"`
+}
+
+// FaultConfig holds fault injection into the Matomo, currency and store
+// clients, for resilience testing outside production. Scenario is a
+// faultinject.ParseScenario spec; a non-zero Seed makes the faults
+// reproducible.
+type FaultConfig struct {
+	Enabled  bool   `mapstructure:"enabled"`
+	Scenario string `mapstructure:"scenario"`
+	Seed     int64  `mapstructure:"seed"`
+}
+
+// WebhookSLOConfig holds the webhook latency objectives: Objective of the
+// renewal webhooks, and of all webhooks, are processed within their Threshold
+// of the provider's event time. The API evaluates the burn rates every
+// EvaluationInterval; zero disables the evaluation.
+type WebhookSLOConfig struct {
+	RenewalThreshold    time.Duration `mapstructure:"renewal_threshold"`
+	RenewalObjective    float64       `mapstructure:"renewal_objective"`
+	ProcessingThreshold time.Duration `mapstructure:"processing_threshold"`
+	ProcessingObjective float64       `mapstructure:"processing_objective"`
+	EvaluationInterval  time.Duration `mapstructure:"evaluation_interval"`
+}
+
 // Load loads configuration from environment variables
 func Load() (*Config, error) {
 	viper.SetConfigName(".env")
@@ -97,21 +326,64 @@ func Load() (*Config, error) {
 
 	// Explicitly bind environment variables
 	_ = viper.BindEnv("server.port", "SERVER_PORT")
+	_ = viper.BindEnv("server.metrics_port", "METRICS_PORT")
 	_ = viper.BindEnv("database.url", "DATABASE_URL")
 	_ = viper.BindEnv("database.max_connections", "DATABASE_MAX_CONNECTIONS")
+	_ = viper.BindEnv("database.min_connections", "DATABASE_MIN_CONNECTIONS")
+	_ = viper.BindEnv("database.max_lifetime", "DATABASE_MAX_LIFETIME")
+	_ = viper.BindEnv("database.max_lifetime_jitter", "DATABASE_MAX_LIFETIME_JITTER")
+	_ = viper.BindEnv("database.max_idle_time", "DATABASE_MAX_IDLE_TIME")
+	_ = viper.BindEnv("database.health_check", "DATABASE_HEALTH_CHECK_PERIOD")
+	_ = viper.BindEnv("database.query_exec_mode", "DATABASE_QUERY_EXEC_MODE")
+	_ = viper.BindEnv("database.statement_cache_capacity", "DATABASE_STATEMENT_CACHE_CAPACITY")
+	_ = viper.BindEnv("database.slow_query_threshold", "DATABASE_SLOW_QUERY_THRESHOLD")
 	_ = viper.BindEnv("redis.url", "REDIS_URL")
 	_ = viper.BindEnv("jwt.secret", "JWT_SECRET")
+	_ = viper.BindEnv("jwt.signing_keys", "JWT_SIGNING_KEYS")
+	_ = viper.BindEnv("jwt.accept_hs256", "JWT_ACCEPT_HS256")
 	_ = viper.BindEnv("iap.apple_shared_secret", "APPLE_SHARED_SECRET")
 	_ = viper.BindEnv("iap.apple_mock_url", "APPLE_MOCK_URL")
 	_ = viper.BindEnv("iap.google_key_json", "GOOGLE_SERVICE_ACCOUNT_JSON")
 	_ = viper.BindEnv("iap.google_iap_base_url", "GOOGLE_IAP_BASE_URL")
 	_ = viper.BindEnv("iap.is_production", "IAP_IS_PRODUCTION")
+	_ = viper.BindEnv("iap.apple_root_ca_path", "APPLE_ROOT_CA_PATH")
+	_ = viper.BindEnv("iap.apple_jws_check_ocsp", "APPLE_JWS_CHECK_OCSP")
+	_ = viper.BindEnv("iap.apple_jws_verification_disabled", "APPLE_JWS_VERIFICATION_DISABLED")
+	_ = viper.BindEnv("iap.apple_webhook_allow_sandbox", "APPLE_WEBHOOK_ALLOW_SANDBOX")
+	_ = viper.BindEnv("iap.google_pubsub_audience", "GOOGLE_PUBSUB_AUDIENCE")
+	_ = viper.BindEnv("iap.google_pubsub_issuer", "GOOGLE_PUBSUB_ISSUER")
+	_ = viper.BindEnv("iap.google_pubsub_service_account", "GOOGLE_PUBSUB_SERVICE_ACCOUNT")
+	_ = viper.BindEnv("iap.google_pubsub_jwks_url", "GOOGLE_PUBSUB_JWKS_URL")
+	_ = viper.BindEnv("iap.google_pubsub_auth_disabled", "GOOGLE_PUBSUB_AUTH_DISABLED")
+	_ = viper.BindEnv("iap.stripe_webhook_tolerance", "STRIPE_WEBHOOK_TOLERANCE")
+	_ = viper.BindEnv("iap.amazon_shared_secret", "AMAZON_SHARED_SECRET")
+	_ = viper.BindEnv("iap.amazon_rvs_url", "AMAZON_RVS_URL")
+	_ = viper.BindEnv("iap.amazon_sns_topic_arns", "AMAZON_SNS_TOPIC_ARNS")
+	_ = viper.BindEnv("iap.amazon_sns_verification_disabled", "AMAZON_SNS_VERIFICATION_DISABLED")
+	_ = viper.BindEnv("iap.huawei_client_id", "HUAWEI_CLIENT_ID")
+	_ = viper.BindEnv("iap.huawei_client_secret", "HUAWEI_CLIENT_SECRET")
+	_ = viper.BindEnv("iap.huawei_public_key", "HUAWEI_PUBLIC_KEY")
+	_ = viper.BindEnv("iap.huawei_iap_base_url", "HUAWEI_IAP_BASE_URL")
+	_ = viper.BindEnv("iap.huawei_signature_verification_disabled", "HUAWEI_SIGNATURE_VERIFICATION_DISABLED")
+	_ = viper.BindEnv("iap.skadnetwork_public_key", "SKADNETWORK_PUBLIC_KEY")
+	_ = viper.BindEnv("sentry.dsn", "SENTRY_DSN")
+	_ = viper.BindEnv("sentry.environment", "SENTRY_ENVIRONMENT")
+	_ = viper.BindEnv("sentry.release", "SENTRY_RELEASE")
+	_ = viper.BindEnv("sentry.sample_rate", "SENTRY_SAMPLE_RATE")
+	_ = viper.BindEnv("sentry.traces_sample_rate", "SENTRY_TRACES_SAMPLE_RATE")
 
 	// Lago
 	_ = viper.BindEnv("lago.api_url", "LAGO_API_URL")
 	_ = viper.BindEnv("lago.api_key", "LAGO_API_KEY")
 	_ = viper.BindEnv("lago.webhook_secret", "LAGO_WEBHOOK_SECRET")
 
+	// PayPal
+	_ = viper.BindEnv("paypal.webhook_id", "PAYPAL_WEBHOOK_ID")
+	_ = viper.BindEnv("paypal.client_id", "PAYPAL_CLIENT_ID")
+	_ = viper.BindEnv("paypal.client_secret", "PAYPAL_CLIENT_SECRET")
+	_ = viper.BindEnv("paypal.api_base_url", "PAYPAL_API_BASE_URL")
+	_ = viper.BindEnv("paypal.verification_disabled", "PAYPAL_VERIFICATION_DISABLED")
+
 	// Notifications
 	_ = viper.BindEnv("notification.fcm_server_key", "FCM_SERVER_KEY")
 	_ = viper.BindEnv("notification.apns_key_id", "APNS_KEY_ID")
@@ -121,6 +393,76 @@ func Load() (*Config, error) {
 	_ = viper.BindEnv("notification.sendgrid_api_key", "SENDGRID_API_KEY")
 	_ = viper.BindEnv("notification.from_email", "NOTIFICATION_FROM_EMAIL")
 
+	// Revenue
+	_ = viper.BindEnv("revenue.basis", "REVENUE_BASIS")
+	_ = viper.BindEnv("revenue.apple_commission", "APPLE_COMMISSION_RATE")
+	_ = viper.BindEnv("revenue.google_commission", "GOOGLE_COMMISSION_RATE")
+	_ = viper.BindEnv("revenue.amazon_commission", "AMAZON_COMMISSION_RATE")
+	_ = viper.BindEnv("revenue.huawei_commission", "HUAWEI_COMMISSION_RATE")
+	_ = viper.BindEnv("revenue.stripe_fee_percent", "STRIPE_FEE_PERCENT")
+	_ = viper.BindEnv("revenue.stripe_fee_fixed", "STRIPE_FEE_FIXED")
+	_ = viper.BindEnv("revenue.paypal_fee_percent", "PAYPAL_FEE_PERCENT")
+	_ = viper.BindEnv("revenue.paypal_fee_fixed", "PAYPAL_FEE_FIXED")
+	_ = viper.BindEnv("revenue.reporting_currency", "REPORTING_CURRENCY")
+
+	// Request logging
+	_ = viper.BindEnv("logging.sample_rate", "LOG_SAMPLE_RATE")
+	_ = viper.BindEnv("logging.slow_request_threshold", "LOG_SLOW_REQUEST_THRESHOLD")
+	_ = viper.BindEnv("logging.body_max_bytes", "LOG_BODY_MAX_BYTES")
+	_ = viper.BindEnv("logging.route_levels", "LOG_ROUTE_LEVELS")
+
+	// Load shedding
+	_ = viper.BindEnv("load_shedding.enabled", "LOAD_SHED_ENABLED")
+	_ = viper.BindEnv("load_shedding.classes", "LOAD_SHED_CLASSES")
+	_ = viper.BindEnv("load_shedding.queue_timeout", "LOAD_SHED_QUEUE_TIMEOUT")
+	_ = viper.BindEnv("load_shedding.retry_after", "LOAD_SHED_RETRY_AFTER")
+	_ = viper.BindEnv("load_shedding.access_check_rate", "LOAD_SHED_ACCESS_CHECK_RATE")
+
+	// Analytics event ingestion
+	_ = viper.BindEnv("ingest.buffer_size", "INGEST_BUFFER_SIZE")
+	_ = viper.BindEnv("ingest.batch_size", "INGEST_BATCH_SIZE")
+	_ = viper.BindEnv("ingest.flush_interval", "INGEST_FLUSH_INTERVAL")
+
+	// Data warehouse sync
+	_ = viper.BindEnv("warehouse.provider", "WAREHOUSE_PROVIDER")
+	_ = viper.BindEnv("warehouse.batch_size", "WAREHOUSE_BATCH_SIZE")
+	_ = viper.BindEnv("warehouse.bigquery_project_id", "BIGQUERY_PROJECT_ID")
+	_ = viper.BindEnv("warehouse.bigquery_dataset", "BIGQUERY_DATASET")
+	_ = viper.BindEnv("warehouse.bigquery_location", "BIGQUERY_LOCATION")
+	_ = viper.BindEnv("warehouse.bigquery_credentials_json", "BIGQUERY_CREDENTIALS_JSON")
+	_ = viper.BindEnv("warehouse.snowflake_account", "SNOWFLAKE_ACCOUNT")
+	_ = viper.BindEnv("warehouse.snowflake_user", "SNOWFLAKE_USER")
+	_ = viper.BindEnv("warehouse.snowflake_private_key", "SNOWFLAKE_PRIVATE_KEY")
+	_ = viper.BindEnv("warehouse.snowflake_database", "SNOWFLAKE_DATABASE")
+	_ = viper.BindEnv("warehouse.snowflake_schema", "SNOWFLAKE_SCHEMA")
+	_ = viper.BindEnv("warehouse.snowflake_warehouse", "SNOWFLAKE_WAREHOUSE")
+	_ = viper.BindEnv("warehouse.snowflake_role", "SNOWFLAKE_ROLE")
+
+	// Offline entitlement tokens
+	_ = viper.BindEnv("entitlement.signing_keys", "ENTITLEMENT_SIGNING_KEYS")
+	_ = viper.BindEnv("entitlement.token_ttl", "ENTITLEMENT_TOKEN_TTL")
+
+	// Data retention cleanup
+	_ = viper.BindEnv("retention.policies", "RETENTION_POLICIES")
+	_ = viper.BindEnv("retention.batch_size", "RETENTION_BATCH_SIZE")
+	_ = viper.BindEnv("retention.max_batches", "RETENTION_MAX_BATCHES")
+
+	// Bandit win probability simulation
+	_ = viper.BindEnv("bandit.simulation_budget", "BANDIT_SIMULATION_BUDGET")
+	_ = viper.BindEnv("bandit.simulation_workers", "BANDIT_SIMULATION_WORKERS")
+
+	// Fault injection
+	_ = viper.BindEnv("faults.enabled", "FAULT_INJECTION_ENABLED")
+	_ = viper.BindEnv("faults.scenario", "FAULT_INJECTION_SCENARIO")
+	_ = viper.BindEnv("faults.seed", "FAULT_INJECTION_SEED")
+
+	// Webhook latency SLOs
+	_ = viper.BindEnv("webhook_slo.renewal_threshold", "WEBHOOK_SLO_RENEWAL_THRESHOLD")
+	_ = viper.BindEnv("webhook_slo.renewal_objective", "WEBHOOK_SLO_RENEWAL_OBJECTIVE")
+	_ = viper.BindEnv("webhook_slo.processing_threshold", "WEBHOOK_SLO_PROCESSING_THRESHOLD")
+	_ = viper.BindEnv("webhook_slo.processing_objective", "WEBHOOK_SLO_PROCESSING_OBJECTIVE")
+	_ = viper.BindEnv("webhook_slo.evaluation_interval", "WEBHOOK_SLO_EVALUATION_INTERVAL")
+
 	// Set defaults
 	setDefaults()
 
@@ -155,18 +497,35 @@ func setDefaults() {
 	viper.SetDefault("server.read_timeout", 10*time.Second)
 	viper.SetDefault("server.write_timeout", 10*time.Second)
 	viper.SetDefault("server.shutdown_timeout", 30*time.Second)
+	viper.SetDefault("server.metrics_port", 9102)
 
 	// Database defaults
 	viper.SetDefault("database.max_connections", 25)
 	viper.SetDefault("database.min_connections", 5)
 	viper.SetDefault("database.max_lifetime", 1*time.Hour)
+	viper.SetDefault("database.max_lifetime_jitter", 5*time.Minute)
 	viper.SetDefault("database.max_idle_time", 30*time.Minute)
 	viper.SetDefault("database.health_check", 30*time.Second)
+	viper.SetDefault("database.query_exec_mode", "cache_statement")
+	viper.SetDefault("database.statement_cache_capacity", 512)
+	viper.SetDefault("database.slow_query_threshold", 500*time.Millisecond)
 
 	// JWT defaults
 	viper.SetDefault("jwt.access_ttl", 15*time.Minute)
 	viper.SetDefault("jwt.refresh_ttl", 720*time.Hour)
 	viper.SetDefault("jwt.issuer", "iap-system")
+	viper.SetDefault("jwt.accept_hs256", true)
+
+	// Apple JWS verification defaults
+	viper.SetDefault("iap.apple_jws_check_ocsp", true)
+	viper.SetDefault("iap.apple_jws_verification_disabled", false)
+	viper.SetDefault("iap.apple_webhook_allow_sandbox", false)
+
+	// Google Pub/Sub push authentication defaults
+	viper.SetDefault("iap.google_pubsub_issuer", "https://accounts.google.com")
+	viper.SetDefault("iap.google_pubsub_jwks_url", "https://www.googleapis.com/oauth2/v3/certs")
+	viper.SetDefault("iap.google_pubsub_auth_disabled", false)
+	viper.SetDefault("iap.stripe_webhook_tolerance", 5*time.Minute)
 
 	// Redis defaults
 	viper.SetDefault("redis.pool_size", 10)
@@ -175,6 +534,63 @@ func setDefaults() {
 	viper.SetDefault("redis.read_timeout", 3*time.Second)
 	viper.SetDefault("redis.write_timeout", 3*time.Second)
 	viper.SetDefault("redis.pool_timeout", 4*time.Second)
+
+	// Revenue defaults (standard store commission, Stripe US card and PayPal
+	// US checkout pricing)
+	viper.SetDefault("revenue.basis", "gross")
+	viper.SetDefault("revenue.apple_commission", 0.30)
+	viper.SetDefault("revenue.google_commission", 0.15)
+	viper.SetDefault("revenue.amazon_commission", 0.20)
+	viper.SetDefault("revenue.huawei_commission", 0.15)
+	viper.SetDefault("revenue.stripe_fee_percent", 0.029)
+	viper.SetDefault("revenue.stripe_fee_fixed", 0.30)
+	viper.SetDefault("revenue.paypal_fee_percent", 0.0349)
+	viper.SetDefault("revenue.paypal_fee_fixed", 0.49)
+	viper.SetDefault("revenue.reporting_currency", "USD")
+
+	// Sentry defaults
+	viper.SetDefault("sentry.sample_rate", 1.0)
+	viper.SetDefault("sentry.traces_sample_rate", 0.1)
+
+	// Request logging defaults
+	viper.SetDefault("logging.sample_rate", 1.0)
+	viper.SetDefault("logging.slow_request_threshold", 1*time.Second)
+	viper.SetDefault("logging.body_max_bytes", 4096)
+	viper.SetDefault("logging.route_levels", "/health=warn")
+
+	// Load shedding defaults: verification and webhooks are never capped
+	viper.SetDefault("load_shedding.enabled", true)
+	viper.SetDefault("load_shedding.classes", "critical=0/0,normal=512/256,low=64/0")
+	viper.SetDefault("load_shedding.queue_timeout", 250*time.Millisecond)
+	viper.SetDefault("load_shedding.retry_after", 5*time.Second)
+	viper.SetDefault("load_shedding.access_check_rate", 200)
+
+	// Analytics event ingestion defaults
+	viper.SetDefault("ingest.buffer_size", 50000)
+	viper.SetDefault("ingest.batch_size", 5000)
+	viper.SetDefault("ingest.flush_interval", 1*time.Second)
+
+	// Warehouse sync defaults
+	viper.SetDefault("warehouse.batch_size", 500)
+	viper.SetDefault("warehouse.snowflake_schema", "PUBLIC")
+
+	// Offline entitlement token defaults
+	viper.SetDefault("entitlement.token_ttl", 24*time.Hour)
+
+	// Data retention cleanup defaults
+	viper.SetDefault("retention.batch_size", 5000)
+	viper.SetDefault("retention.max_batches", 100)
+
+	// Bandit simulation defaults
+	viper.SetDefault("bandit.simulation_budget", 5000000)
+	viper.SetDefault("bandit.simulation_workers", 0)
+
+	// Webhook latency SLO defaults
+	viper.SetDefault("webhook_slo.renewal_threshold", time.Minute)
+	viper.SetDefault("webhook_slo.renewal_objective", 0.95)
+	viper.SetDefault("webhook_slo.processing_threshold", 5*time.Minute)
+	viper.SetDefault("webhook_slo.processing_objective", 0.99)
+	viper.SetDefault("webhook_slo.evaluation_interval", time.Minute)
 }
 
 func validate(cfg *Config) error {
@@ -184,11 +600,95 @@ func validate(cfg *Config) error {
 	if len(cfg.JWT.Secret) < 32 {
 		return fmt.Errorf("JWT_SECRET must be at least 32 characters")
 	}
+	if !cfg.JWT.AcceptHS256 && strings.TrimSpace(cfg.JWT.SigningKeys) == "" {
+		return fmt.Errorf("JWT_ACCEPT_HS256=false requires JWT_SIGNING_KEYS")
+	}
 	if cfg.Database.URL == "" {
 		return fmt.Errorf("DATABASE_URL is required")
 	}
+	if cfg.Database.MinConnections < 0 || cfg.Database.MaxConnections < 1 || cfg.Database.MinConnections > cfg.Database.MaxConnections {
+		return fmt.Errorf("DATABASE_MIN_CONNECTIONS must be between 0 and DATABASE_MAX_CONNECTIONS")
+	}
+	if cfg.Ingest.BatchSize < 1 || cfg.Ingest.BufferSize < cfg.Ingest.BatchSize {
+		return fmt.Errorf("INGEST_BATCH_SIZE must be between 1 and INGEST_BUFFER_SIZE")
+	}
+	if cfg.Ingest.FlushInterval <= 0 {
+		return fmt.Errorf("INGEST_FLUSH_INTERVAL must be positive")
+	}
+	switch cfg.Warehouse.Provider {
+	case "":
+	case "bigquery":
+		if cfg.Warehouse.BigQueryProjectID == "" || cfg.Warehouse.BigQueryDataset == "" {
+			return fmt.Errorf("BIGQUERY_PROJECT_ID and BIGQUERY_DATASET are required for WAREHOUSE_PROVIDER=bigquery")
+		}
+	case "snowflake":
+		if cfg.Warehouse.SnowflakeAccount == "" || cfg.Warehouse.SnowflakeUser == "" || cfg.Warehouse.SnowflakePrivateKey == "" || cfg.Warehouse.SnowflakeDatabase == "" {
+			return fmt.Errorf("SNOWFLAKE_ACCOUNT, SNOWFLAKE_USER, SNOWFLAKE_PRIVATE_KEY and SNOWFLAKE_DATABASE are required for WAREHOUSE_PROVIDER=snowflake")
+		}
+	default:
+		return fmt.Errorf("WAREHOUSE_PROVIDER must be bigquery, snowflake or empty")
+	}
+	if cfg.Warehouse.BatchSize < 1 || cfg.Warehouse.BatchSize > 10000 {
+		return fmt.Errorf("WAREHOUSE_BATCH_SIZE must be between 1 and 10000")
+	}
+	if cfg.Entitlement.TokenTTL < time.Minute || cfg.Entitlement.TokenTTL > 7*24*time.Hour {
+		return fmt.Errorf("ENTITLEMENT_TOKEN_TTL must be between 1m and 168h")
+	}
+	if cfg.Retention.BatchSize < 1 || cfg.Retention.BatchSize > 50000 {
+		return fmt.Errorf("RETENTION_BATCH_SIZE must be between 1 and 50000")
+	}
+	if cfg.Retention.MaxBatches < 1 {
+		return fmt.Errorf("RETENTION_MAX_BATCHES must be positive")
+	}
 	if cfg.Redis.URL == "" {
 		return fmt.Errorf("REDIS_URL is required")
 	}
+	if cfg.Revenue.Basis != "gross" && cfg.Revenue.Basis != "net" {
+		return fmt.Errorf("REVENUE_BASIS must be gross or net")
+	}
+	if cfg.IAP.AppleJWSVerificationDisabled && cfg.Sentry.Environment != "development" {
+		return fmt.Errorf("APPLE_JWS_VERIFICATION_DISABLED is only allowed when SENTRY_ENVIRONMENT=development")
+	}
+	if cfg.IAP.GooglePubSubAuthDisabled && cfg.Sentry.Environment != "development" {
+		return fmt.Errorf("GOOGLE_PUBSUB_AUTH_DISABLED is only allowed when SENTRY_ENVIRONMENT=development")
+	}
+	if cfg.IAP.AmazonSNSVerificationDisabled && cfg.Sentry.Environment != "development" {
+		return fmt.Errorf("AMAZON_SNS_VERIFICATION_DISABLED is only allowed when SENTRY_ENVIRONMENT=development")
+	}
+	if cfg.IAP.HuaweiSignatureVerificationDisabled && cfg.Sentry.Environment != "development" {
+		return fmt.Errorf("HUAWEI_SIGNATURE_VERIFICATION_DISABLED is only allowed when SENTRY_ENVIRONMENT=development")
+	}
+	if cfg.PayPal.VerificationDisabled && cfg.Sentry.Environment != "development" {
+		return fmt.Errorf("PAYPAL_VERIFICATION_DISABLED is only allowed when SENTRY_ENVIRONMENT=development")
+	}
+	if cfg.Faults.Enabled && (cfg.IAP.IsProduction || cfg.Sentry.Environment == "production") {
+		return fmt.Errorf("FAULT_INJECTION_ENABLED is not allowed in production")
+	}
+	if cfg.WebhookSLO.RenewalThreshold <= 0 || cfg.WebhookSLO.ProcessingThreshold <= 0 {
+		return fmt.Errorf("WEBHOOK_SLO_RENEWAL_THRESHOLD and WEBHOOK_SLO_PROCESSING_THRESHOLD must be positive durations")
+	}
+	for _, objective := range []float64{cfg.WebhookSLO.RenewalObjective, cfg.WebhookSLO.ProcessingObjective} {
+		if objective <= 0 || objective >= 1 {
+			return fmt.Errorf("WEBHOOK_SLO_RENEWAL_OBJECTIVE and WEBHOOK_SLO_PROCESSING_OBJECTIVE must be between 0 and 1")
+		}
+	}
+	if cfg.WebhookSLO.EvaluationInterval < 0 {
+		return fmt.Errorf("WEBHOOK_SLO_EVALUATION_INTERVAL must not be negative")
+	}
+	if cfg.IAP.StripeWebhookTolerance <= 0 {
+		return fmt.Errorf("STRIPE_WEBHOOK_TOLERANCE must be a positive duration")
+	}
+	if len(cfg.Revenue.ReportingCurrency) != 3 {
+		return fmt.Errorf("REPORTING_CURRENCY must be a 3-letter ISO 4217 code")
+	}
+	if cfg.Sentry.SampleRate < 0 || cfg.Sentry.SampleRate > 1 {
+		return fmt.Errorf("SENTRY_SAMPLE_RATE must be between 0 and 1")
+	}
+	if cfg.Sentry.TracesSampleRate < 0 || cfg.Sentry.TracesSampleRate > 1 {
+		return fmt.Errorf("SENTRY_TRACES_SAMPLE_RATE must be between 0 and 1")
+	}
+	if cfg.Logging.SampleRate < 0 || cfg.Logging.SampleRate > 1 {
+		return fmt.Errorf("LOG_SAMPLE_RATE must be between 0 and 1")
+	}
 	return nil
 }
@@ -4,23 +4,34 @@ import (
 	"time"
 )
 
-// DatabaseConfig holds database connection configuration
+// DatabaseConfig holds database connection configuration. QueryExecMode
+// overrides pgx's default_query_exec_mode (cache_statement); use exec or
+// simple_protocol behind a transaction-pooling PgBouncer. Queries slower than
+// SlowQueryThreshold are logged; 0 disables the log.
 type DatabaseConfig struct {
-	URL            string        `mapstructure:"url"`
-	MaxConnections int           `mapstructure:"max_connections"`
-	MinConnections int           `mapstructure:"min_connections"`
-	MaxLifetime    time.Duration `mapstructure:"max_lifetime"`
-	MaxIdleTime    time.Duration `mapstructure:"max_idle_time"`
-	HealthCheck    time.Duration `mapstructure:"health_check"`
+	URL                    string        `mapstructure:"url"`
+	MaxConnections         int           `mapstructure:"max_connections"`
+	MinConnections         int           `mapstructure:"min_connections"`
+	MaxLifetime            time.Duration `mapstructure:"max_lifetime"`
+	MaxLifetimeJitter      time.Duration `mapstructure:"max_lifetime_jitter"`
+	MaxIdleTime            time.Duration `mapstructure:"max_idle_time"`
+	HealthCheck            time.Duration `mapstructure:"health_check"`
+	QueryExecMode          string        `mapstructure:"query_exec_mode"`
+	StatementCacheCapacity int           `mapstructure:"statement_cache_capacity"`
+	SlowQueryThreshold     time.Duration `mapstructure:"slow_query_threshold"`
 }
 
 // DefaultDatabaseConfig returns default database configuration
 func DefaultDatabaseConfig() DatabaseConfig {
 	return DatabaseConfig{
-		MaxConnections: 25,
-		MinConnections: 5,
-		MaxLifetime:    1 * time.Hour,
-		MaxIdleTime:    30 * time.Minute,
-		HealthCheck:    30 * time.Second,
+		MaxConnections:         25,
+		MinConnections:         5,
+		MaxLifetime:            1 * time.Hour,
+		MaxLifetimeJitter:      5 * time.Minute,
+		MaxIdleTime:            30 * time.Minute,
+		HealthCheck:            30 * time.Second,
+		QueryExecMode:          "cache_statement",
+		StatementCacheCapacity: 512,
+		SlowQueryThreshold:     500 * time.Millisecond,
 	}
 }
@@ -55,6 +55,10 @@ func (a *IAPAdapter) VerifyAppleReceipt(ctx context.Context, receiptData string)
 		ExpiresAt:     result.ExpiresAt,
 		IsRenewable:   result.IsRenewable,
 		OriginalTxID:  result.OriginalTxID,
+		OfferType:     result.OfferType,
+		OfferCode:     result.OfferCode,
+		Environment:   result.Environment,
+		Pending:       result.Pending,
 	}, nil
 }
 
@@ -72,6 +76,10 @@ func (a *IAPAdapter) VerifyGoogleReceipt(ctx context.Context, receiptData string
 		ExpiresAt:     result.ExpiresAt,
 		IsRenewable:   result.IsRenewable,
 		OriginalTxID:  result.OriginalTxID,
+		OfferType:     result.OfferType,
+		OfferCode:     result.OfferCode,
+		Environment:   result.Environment,
+		Pending:       result.Pending,
 	}, nil
 }
 
@@ -0,0 +1,214 @@
+package iap
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SNS message types
+const (
+	SNSTypeNotification             = "Notification"
+	SNSTypeSubscriptionConfirmation = "SubscriptionConfirmation"
+	SNSTypeUnsubscribeConfirmation  = "UnsubscribeConfirmation"
+)
+
+// ErrSNSSignature is returned when an SNS message fails verification
+var ErrSNSSignature = errors.New("invalid SNS message signature")
+
+// snsHostRe matches the SNS endpoints signing certificates and subscription
+// confirmations may be served from
+var snsHostRe = regexp.MustCompile(`^sns\.[a-z0-9-]+\.amazonaws\.com(\.cn)?$`)
+
+// SNSMessage is an Amazon SNS HTTP(S) delivery. Amazon Appstore Real-time
+// Notifications are SNS notifications whose Message is the RTN JSON.
+type SNSMessage struct {
+	Type             string `json:"Type"`
+	MessageID        string `json:"MessageId"`
+	Token            string `json:"Token"`
+	TopicArn         string `json:"TopicArn"`
+	Subject          string `json:"Subject"`
+	Message          string `json:"Message"`
+	SubscribeURL     string `json:"SubscribeURL"`
+	Timestamp        string `json:"Timestamp"`
+	SignatureVersion string `json:"SignatureVersion"`
+	Signature        string `json:"Signature"`
+	SigningCertURL   string `json:"SigningCertURL"`
+}
+
+// stringToSign is the canonical form SNS signs: name/value lines in a fixed
+// order, Subject only when present
+func (m *SNSMessage) stringToSign() string {
+	var fields [][2]string
+	if m.Type == SNSTypeNotification {
+		fields = [][2]string{{"Message", m.Message}, {"MessageId", m.MessageID}}
+		if m.Subject != "" {
+			fields = append(fields, [2]string{"Subject", m.Subject})
+		}
+		fields = append(fields, [2]string{"Timestamp", m.Timestamp}, [2]string{"TopicArn", m.TopicArn}, [2]string{"Type", m.Type})
+	} else {
+		fields = [][2]string{
+			{"Message", m.Message}, {"MessageId", m.MessageID}, {"SubscribeURL", m.SubscribeURL},
+			{"Timestamp", m.Timestamp}, {"Token", m.Token}, {"TopicArn", m.TopicArn}, {"Type", m.Type},
+		}
+	}
+	var b strings.Builder
+	for _, f := range fields {
+		b.WriteString(f[0] + "\n" + f[1] + "\n")
+	}
+	return b.String()
+}
+
+// AmazonSNSVerifier verifies SNS message signatures against the SNS signing
+// certificate and confirms topic subscriptions
+type AmazonSNSVerifier struct {
+	topicARNs []string
+	client    *http.Client
+	fetchCert func(ctx context.Context, certURL string) (*x509.Certificate, error)
+
+	mu    sync.RWMutex
+	certs map[string]*x509.Certificate
+}
+
+// NewAmazonSNSVerifier creates a verifier accepting messages from the given
+// topics; no topics accepts any topic
+func NewAmazonSNSVerifier(topicARNs ...string) *AmazonSNSVerifier {
+	v := &AmazonSNSVerifier{
+		topicARNs: topicARNs,
+		client:    &http.Client{Timeout: 10 * time.Second},
+		certs:     make(map[string]*x509.Certificate),
+	}
+	v.fetchCert = v.downloadCert
+	return v
+}
+
+// Verify checks the message topic and signature
+func (v *AmazonSNSVerifier) Verify(ctx context.Context, msg *SNSMessage) error {
+	if len(v.topicARNs) > 0 && !slices.Contains(v.topicARNs, msg.TopicArn) {
+		return fmt.Errorf("%w: unexpected topic %q", ErrSNSSignature, msg.TopicArn)
+	}
+	var hash crypto.Hash
+	var digest []byte
+	switch msg.SignatureVersion {
+	case "1":
+		sum := sha1.Sum([]byte(msg.stringToSign()))
+		hash, digest = crypto.SHA1, sum[:]
+	case "2":
+		sum := sha256.Sum256([]byte(msg.stringToSign()))
+		hash, digest = crypto.SHA256, sum[:]
+	default:
+		return fmt.Errorf("%w: unsupported signature version %q", ErrSNSSignature, msg.SignatureVersion)
+	}
+	sig, err := base64.StdEncoding.DecodeString(msg.Signature)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSNSSignature, err)
+	}
+	if err := validateSNSURL(msg.SigningCertURL); err != nil {
+		return err
+	}
+
+	cert, err := v.cert(ctx, msg.SigningCertURL)
+	if err != nil {
+		return err
+	}
+	pub, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("%w: signing certificate key is not RSA", ErrSNSSignature)
+	}
+	if err := rsa.VerifyPKCS1v15(pub, hash, digest, sig); err != nil {
+		return fmt.Errorf("%w: %v", ErrSNSSignature, err)
+	}
+	return nil
+}
+
+// Confirm completes a topic subscription by visiting its SubscribeURL. The
+// message must have been verified first.
+func (v *AmazonSNSVerifier) Confirm(ctx context.Context, msg *SNSMessage) error {
+	if err := validateSNSURL(msg.SubscribeURL); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, msg.SubscribeURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to build SNS confirmation request: %w", err)
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to confirm SNS subscription: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("SNS subscription confirmation returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// validateSNSURL only allows https URLs on SNS hosts, so a forged message
+// cannot make us fetch a certificate or URL of its choosing
+func validateSNSURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "https" || !snsHostRe.MatchString(u.Hostname()) {
+		return fmt.Errorf("%w: untrusted SNS URL %q", ErrSNSSignature, raw)
+	}
+	return nil
+}
+
+func (v *AmazonSNSVerifier) cert(ctx context.Context, certURL string) (*x509.Certificate, error) {
+	v.mu.RLock()
+	cert, ok := v.certs[certURL]
+	v.mu.RUnlock()
+	if ok && time.Now().Before(cert.NotAfter) {
+		return cert, nil
+	}
+
+	cert, err := v.fetchCert(ctx, certURL)
+	if err != nil {
+		return nil, err
+	}
+	v.mu.Lock()
+	v.certs[certURL] = cert
+	v.mu.Unlock()
+	return cert, nil
+}
+
+func (v *AmazonSNSVerifier) downloadCert(ctx context.Context, certURL string) (*x509.Certificate, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, certURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build SNS certificate request: %w", err)
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch SNS signing certificate: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("SNS signing certificate returned status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SNS signing certificate: %w", err)
+	}
+	block, _ := pem.Decode(body)
+	if block == nil {
+		return nil, errors.New("SNS signing certificate is not PEM")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SNS signing certificate: %w", err)
+	}
+	return cert, nil
+}
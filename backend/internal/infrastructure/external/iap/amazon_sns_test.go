@@ -0,0 +1,64 @@
+package iap
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSNSCertURL = "https://sns.us-east-1.amazonaws.com/SimpleNotificationService-test.pem"
+
+func signedSNSMessage(t *testing.T, key *rsa.PrivateKey) *SNSMessage {
+	t.Helper()
+	msg := &SNSMessage{
+		Type:             SNSTypeNotification,
+		MessageID:        "m-1",
+		TopicArn:         "arn:aws:sns:us-east-1:123:appstore-rtn",
+		Message:          `{"notificationType":"SUBSCRIPTION_RENEWED","receiptId":"r-1"}`,
+		Timestamp:        "2026-10-01T12:00:00.000Z",
+		SignatureVersion: "2",
+		SigningCertURL:   testSNSCertURL,
+	}
+	digest := sha256.Sum256([]byte(msg.stringToSign()))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+	msg.Signature = base64.StdEncoding.EncodeToString(sig)
+	return msg
+}
+
+func TestAmazonSNSVerifier_Verify(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	verifier := NewAmazonSNSVerifier("arn:aws:sns:us-east-1:123:appstore-rtn")
+	fetched := 0
+	verifier.fetchCert = func(ctx context.Context, certURL string) (*x509.Certificate, error) {
+		fetched++
+		return &x509.Certificate{PublicKey: &key.PublicKey, NotAfter: time.Now().Add(time.Hour)}, nil
+	}
+
+	msg := signedSNSMessage(t, key)
+	require.NoError(t, verifier.Verify(context.Background(), msg))
+	require.NoError(t, verifier.Verify(context.Background(), msg))
+	assert.Equal(t, 1, fetched, "certificate is cached")
+
+	tampered := *msg
+	tampered.Message = `{"notificationType":"SUBSCRIPTION_CANCELLED","receiptId":"r-1"}`
+	assert.ErrorIs(t, verifier.Verify(context.Background(), &tampered), ErrSNSSignature)
+
+	foreignCert := *msg
+	foreignCert.SigningCertURL = "https://attacker.example.com/cert.pem"
+	assert.ErrorIs(t, verifier.Verify(context.Background(), &foreignCert), ErrSNSSignature)
+
+	otherTopic := *msg
+	otherTopic.TopicArn = "arn:aws:sns:us-east-1:999:other"
+	assert.ErrorIs(t, verifier.Verify(context.Background(), &otherTopic), ErrSNSSignature)
+}
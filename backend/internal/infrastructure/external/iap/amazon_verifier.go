@@ -0,0 +1,143 @@
+package iap
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+// DefaultAmazonRVSURL is the production Receipt Verification Service
+const DefaultAmazonRVSURL = "https://appstore-sdk.amazon.com"
+
+// AmazonReceipt is the receipt_data of an Amazon Appstore purchase: the
+// Appstore user and the receipt the Appstore SDK returned
+type AmazonReceipt struct {
+	UserID    string `json:"userId"`
+	ReceiptID string `json:"receiptId"`
+}
+
+// AmazonVerifier verifies Amazon Appstore receipts with the Receipt
+// Verification Service (RVS)
+type AmazonVerifier struct {
+	sharedSecret string
+	baseURL      string
+	client       *http.Client
+	now          func() time.Time
+}
+
+// NewAmazonVerifier creates a new Amazon verifier. baseURL overrides the RVS
+// endpoint (RVS Cloud Sandbox or a mock); empty uses production.
+func NewAmazonVerifier(sharedSecret, baseURL string) *AmazonVerifier {
+	if baseURL == "" {
+		baseURL = DefaultAmazonRVSURL
+	}
+	return &AmazonVerifier{
+		sharedSecret: sharedSecret,
+		baseURL:      strings.TrimRight(baseURL, "/"),
+		client:       &http.Client{Timeout: 10 * time.Second},
+		now:          time.Now,
+	}
+}
+
+// amazonRVSReceipt is the RVS response body. Dates are Unix milliseconds.
+type amazonRVSReceipt struct {
+	ReceiptID        string `json:"receiptId"`
+	ProductType      string `json:"productType"` // SUBSCRIPTION, ENTITLED, CONSUMABLE
+	ProductID        string `json:"productId"`
+	TermSKU          string `json:"termSku"`
+	PurchaseDate     *int64 `json:"purchaseDate"`
+	RenewalDate      *int64 `json:"renewalDate"`
+	CancelDate       *int64 `json:"cancelDate"`
+	FreeTrialEndDate *int64 `json:"freeTrialEndDate"`
+	AutoRenewing     bool   `json:"autoRenewing"`
+	TestTransaction  bool   `json:"testTransaction"`
+}
+
+// VerifyReceipt verifies an Amazon receipt; receiptData is an AmazonReceipt
+// JSON document
+func (v *AmazonVerifier) VerifyReceipt(ctx context.Context, receiptData string) (*VerifyResponse, error) {
+	var receipt AmazonReceipt
+	if err := json.Unmarshal([]byte(receiptData), &receipt); err != nil || receipt.UserID == "" || receipt.ReceiptID == "" {
+		return nil, errors.New("amazon receipt_data must be JSON with userId and receiptId")
+	}
+
+	rvs, err := v.fetch(ctx, receipt)
+	if err != nil || rvs == nil {
+		return &VerifyResponse{Valid: false}, err
+	}
+	return v.toVerifyResponse(rvs), nil
+}
+
+// fetch calls RVS; a nil receipt with no error means RVS rejected it
+func (v *AmazonVerifier) fetch(ctx context.Context, receipt AmazonReceipt) (*amazonRVSReceipt, error) {
+	endpoint := fmt.Sprintf("%s/version/1.0/verifyReceiptId/developer/%s/user/%s/receiptId/%s",
+		v.baseURL, url.PathEscape(v.sharedSecret), url.PathEscape(receipt.UserID), url.PathEscape(receipt.ReceiptID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build RVS request: %w", err)
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify receipt: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusBadRequest, http.StatusGone:
+		// Invalid or cancelled-and-removed receipt
+		return nil, nil
+	case 496:
+		return nil, errors.New("amazon RVS rejected the shared secret")
+	case 497:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("amazon RVS returned status %d", resp.StatusCode)
+	}
+
+	var rvs amazonRVSReceipt
+	if err := json.NewDecoder(resp.Body).Decode(&rvs); err != nil {
+		return nil, fmt.Errorf("failed to decode RVS response: %w", err)
+	}
+	return &rvs, nil
+}
+
+func (v *AmazonVerifier) toVerifyResponse(rvs *amazonRVSReceipt) *VerifyResponse {
+	now := v.now()
+	if rvs.CancelDate != nil && !time.UnixMilli(*rvs.CancelDate).After(now) {
+		return &VerifyResponse{Valid: false}
+	}
+
+	out := &VerifyResponse{
+		Valid:         true,
+		TransactionID: rvs.ReceiptID,
+		ProductID:     rvs.ProductID,
+		IsRenewable:   rvs.ProductType == "SUBSCRIPTION" && rvs.AutoRenewing,
+		OriginalTxID:  rvs.ReceiptID,
+		Environment:   "Production",
+		ExpiresAt:     now,
+	}
+	if rvs.TermSKU != "" {
+		out.ProductID = rvs.TermSKU
+	}
+	if rvs.TestTransaction {
+		out.Environment = "Sandbox"
+	}
+	switch {
+	case rvs.RenewalDate != nil:
+		out.ExpiresAt = time.UnixMilli(*rvs.RenewalDate)
+	case rvs.CancelDate != nil:
+		out.ExpiresAt = time.UnixMilli(*rvs.CancelDate)
+	}
+	if rvs.FreeTrialEndDate != nil && time.UnixMilli(*rvs.FreeTrialEndDate).After(now) {
+		out.OfferType = entity.OfferTypeFreeTrial
+	}
+	return out
+}
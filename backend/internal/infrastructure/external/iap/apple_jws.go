@@ -0,0 +1,295 @@
+package iap
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/x509"
+	_ "embed"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// appleRootCAG3PEM is Apple Root CA - G3, the anchor of every App Store
+// signed payload (https://www.apple.com/certificateauthority/AppleRootCA-G3.cer,
+// SHA-256 63:34:3A:BF:B8:9A:6A:03:EB:B5:7E:9B:3F:5F:A7:BE:7C:4F:5C:75:6F:30:17:B3:A8:C4:88:C3:65:3E:91:79).
+//
+//go:embed certs/AppleRootCA-G3.pem
+var appleRootCAG3PEM []byte
+
+var (
+	// Marker extensions Apple puts on the App Store receipt-signing leaf and
+	// on the Apple Worldwide Developer Relations intermediate
+	appleLeafMarkerOID         = asn1.ObjectIdentifier{1, 2, 840, 113635, 100, 6, 11, 1}
+	appleIntermediateMarkerOID = asn1.ObjectIdentifier{1, 2, 840, 113635, 100, 6, 2, 1}
+)
+
+var (
+	// ErrAppleJWSInvalid is returned when a signed payload fails signature or chain validation
+	ErrAppleJWSInvalid = errors.New("invalid apple signed payload")
+	// ErrAppleRevocationUnavailable is returned when OCSP status could not be determined
+	ErrAppleRevocationUnavailable = errors.New("apple certificate revocation status unavailable")
+)
+
+const defaultOCSPCacheTTL = time.Hour
+
+// AppleJWSVerifier verifies App Store signed payloads (server notifications,
+// signedTransactionInfo, signedRenewalInfo): an ES256 signature by the leaf of
+// the x5c header chain, which must chain to Apple Root CA - G3, carry Apple's
+// marker extensions, be within its validity period and, when OCSP checks are
+// enabled, not be revoked.
+type AppleJWSVerifier struct {
+	roots      *x509.CertPool
+	checkOCSP  bool
+	httpClient *http.Client
+	now        func() time.Time
+
+	ocspMu    sync.Mutex
+	ocspCache map[string]time.Time // issuer+serial -> good until
+}
+
+// NewAppleJWSVerifier creates a verifier anchored at Apple Root CA - G3.
+// extraRootPath optionally adds a PEM or DER root (e.g. for a rotated root
+// or a test CA); checkOCSP enables online revocation checks.
+func NewAppleJWSVerifier(extraRootPath string, checkOCSP bool) (*AppleJWSVerifier, error) {
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(appleRootCAG3PEM) {
+		return nil, errors.New("failed to parse embedded Apple root certificate")
+	}
+	if extraRootPath != "" {
+		raw, err := os.ReadFile(extraRootPath)
+		if err != nil {
+			return nil, fmt.Errorf("read apple root certificate: %w", err)
+		}
+		cert, err := parseCertificate(raw)
+		if err != nil {
+			return nil, fmt.Errorf("parse apple root certificate %s: %w", extraRootPath, err)
+		}
+		roots.AddCert(cert)
+	}
+	return newAppleJWSVerifier(roots, checkOCSP), nil
+}
+
+func newAppleJWSVerifier(roots *x509.CertPool, checkOCSP bool) *AppleJWSVerifier {
+	return &AppleJWSVerifier{
+		roots:      roots,
+		checkOCSP:  checkOCSP,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		now:        time.Now,
+		ocspCache:  make(map[string]time.Time),
+	}
+}
+
+// Verify checks a compact JWS and returns its decoded payload
+func (v *AppleJWSVerifier) Verify(ctx context.Context, token string) ([]byte, error) {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: expected 3 JWS segments, got %d", ErrAppleJWSInvalid, len(parts))
+	}
+
+	headerBytes, err := decodeSegment(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: decode header: %v", ErrAppleJWSInvalid, err)
+	}
+	var header struct {
+		Alg string   `json:"alg"`
+		X5c []string `json:"x5c"`
+	}
+	if err := json.Unmarshal(headerBytes, &header); err != nil {
+		return nil, fmt.Errorf("%w: parse header: %v", ErrAppleJWSInvalid, err)
+	}
+	if header.Alg != "ES256" {
+		return nil, fmt.Errorf("%w: unsupported alg %q", ErrAppleJWSInvalid, header.Alg)
+	}
+	if len(header.X5c) != 3 {
+		return nil, fmt.Errorf("%w: x5c must hold leaf, intermediate and root, got %d certificates", ErrAppleJWSInvalid, len(header.X5c))
+	}
+
+	chain := make([]*x509.Certificate, len(header.X5c))
+	for i, encoded := range header.X5c {
+		der, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("%w: decode x5c[%d]: %v", ErrAppleJWSInvalid, i, err)
+		}
+		if chain[i], err = x509.ParseCertificate(der); err != nil {
+			return nil, fmt.Errorf("%w: parse x5c[%d]: %v", ErrAppleJWSInvalid, i, err)
+		}
+	}
+	leaf, intermediate := chain[0], chain[1]
+
+	verified, err := v.verifyChain(leaf, intermediate)
+	if err != nil {
+		return nil, err
+	}
+
+	pub, ok := leaf.PublicKey.(*ecdsa.PublicKey)
+	if !ok || pub.Curve != elliptic.P256() {
+		return nil, fmt.Errorf("%w: leaf key is not P-256 ECDSA", ErrAppleJWSInvalid)
+	}
+	sig, err := decodeSegment(parts[2])
+	if err != nil || len(sig) != 64 {
+		return nil, fmt.Errorf("%w: malformed ES256 signature", ErrAppleJWSInvalid)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+	if !ecdsa.Verify(pub, digest[:], r, s) {
+		return nil, fmt.Errorf("%w: signature mismatch", ErrAppleJWSInvalid)
+	}
+
+	if v.checkOCSP {
+		if err := v.checkRevocation(ctx, leaf, intermediate); err != nil {
+			return nil, err
+		}
+		if err := v.checkRevocation(ctx, intermediate, verified[2]); err != nil {
+			return nil, err
+		}
+	}
+
+	payload, err := decodeSegment(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: decode payload: %v", ErrAppleJWSInvalid, err)
+	}
+	return payload, nil
+}
+
+// verifyChain validates leaf → intermediate → trusted root at the current
+// time (which also enforces certificate expiry) and Apple's marker OIDs
+func (v *AppleJWSVerifier) verifyChain(leaf, intermediate *x509.Certificate) ([]*x509.Certificate, error) {
+	if !hasExtension(leaf, appleLeafMarkerOID) {
+		return nil, fmt.Errorf("%w: leaf certificate is not an App Store signing certificate", ErrAppleJWSInvalid)
+	}
+	if !hasExtension(intermediate, appleIntermediateMarkerOID) {
+		return nil, fmt.Errorf("%w: intermediate certificate is not an Apple WWDR certificate", ErrAppleJWSInvalid)
+	}
+
+	intermediates := x509.NewCertPool()
+	intermediates.AddCert(intermediate)
+	chains, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         v.roots,
+		Intermediates: intermediates,
+		CurrentTime:   v.now(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrAppleJWSInvalid, err)
+	}
+	for _, chain := range chains {
+		if len(chain) == 3 && chain[1].Equal(intermediate) {
+			return chain, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: chain does not pass through the supplied intermediate", ErrAppleJWSInvalid)
+}
+
+// checkRevocation asks the certificate's OCSP responder for its status;
+// good responses are cached until their NextUpdate
+func (v *AppleJWSVerifier) checkRevocation(ctx context.Context, cert, issuer *x509.Certificate) error {
+	key := string(issuer.SubjectKeyId) + ":" + cert.SerialNumber.String()
+	v.ocspMu.Lock()
+	goodUntil, cached := v.ocspCache[key]
+	v.ocspMu.Unlock()
+	if cached && v.now().Before(goodUntil) {
+		return nil
+	}
+
+	if len(cert.OCSPServer) == 0 {
+		return fmt.Errorf("%w: %s has no OCSP responder", ErrAppleRevocationUnavailable, cert.Subject.CommonName)
+	}
+	reqBody, err := ocsp.CreateRequest(cert, issuer, &ocsp.RequestOptions{})
+	if err != nil {
+		return fmt.Errorf("%w: create OCSP request: %v", ErrAppleRevocationUnavailable, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cert.OCSPServer[0], bytes.NewReader(reqBody))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrAppleRevocationUnavailable, err)
+	}
+	req.Header.Set("Content-Type", "application/ocsp-request")
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrAppleRevocationUnavailable, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: OCSP responder returned %d", ErrAppleRevocationUnavailable, resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrAppleRevocationUnavailable, err)
+	}
+
+	status, err := ocsp.ParseResponseForCert(body, cert, issuer)
+	if err != nil {
+		return fmt.Errorf("%w: parse OCSP response: %v", ErrAppleRevocationUnavailable, err)
+	}
+	switch status.Status {
+	case ocsp.Good:
+	case ocsp.Revoked:
+		return fmt.Errorf("%w: %s was revoked at %s", ErrAppleJWSInvalid, cert.Subject.CommonName, status.RevokedAt.Format(time.RFC3339))
+	default:
+		return fmt.Errorf("%w: OCSP status unknown for %s", ErrAppleRevocationUnavailable, cert.Subject.CommonName)
+	}
+
+	goodUntil = v.now().Add(defaultOCSPCacheTTL)
+	if !status.NextUpdate.IsZero() && status.NextUpdate.Before(goodUntil) {
+		goodUntil = status.NextUpdate
+	}
+	v.ocspMu.Lock()
+	v.ocspCache[key] = goodUntil
+	v.ocspMu.Unlock()
+	return nil
+}
+
+// ParseAppleJWSPayload decodes the payload of a compact JWS without any
+// verification. Only for development mode with verification disabled.
+func ParseAppleJWSPayload(token string) ([]byte, error) {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: expected 3 JWS segments, got %d", ErrAppleJWSInvalid, len(parts))
+	}
+	payload, err := decodeSegment(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: decode payload: %v", ErrAppleJWSInvalid, err)
+	}
+	return payload, nil
+}
+
+// decodeSegment decodes base64url without padding, tolerating padded or
+// standard-alphabet input some tooling produces
+func decodeSegment(seg string) ([]byte, error) {
+	seg = strings.TrimRight(seg, "=")
+	if b, err := base64.RawURLEncoding.DecodeString(seg); err == nil {
+		return b, nil
+	}
+	return base64.RawStdEncoding.DecodeString(seg)
+}
+
+func hasExtension(cert *x509.Certificate, oid asn1.ObjectIdentifier) bool {
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oid) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseCertificate(raw []byte) (*x509.Certificate, error) {
+	if block, _ := pem.Decode(raw); block != nil {
+		raw = block.Bytes
+	}
+	return x509.ParseCertificate(raw)
+}
@@ -0,0 +1,226 @@
+package iap
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ocsp"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+type testChain struct {
+	root, intermediate, leaf testCert
+}
+
+func newTestCert(t *testing.T, cn string, parent *testCert, isCA bool, marker asn1.ObjectIdentifier, ocspURL string) testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	if marker != nil {
+		tmpl.ExtraExtensions = []pkix.Extension{{Id: marker, Value: []byte{0x05, 0x00}}}
+	}
+	if ocspURL != "" {
+		tmpl.OCSPServer = []string{ocspURL}
+	}
+
+	signer, signerKey := tmpl, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return testCert{cert: cert, key: key}
+}
+
+func newTestChain(t *testing.T, ocspURL string) testChain {
+	root := newTestCert(t, "Test Root CA", nil, true, nil, "")
+	intermediate := newTestCert(t, "Test WWDR", &root, true, appleIntermediateMarkerOID, ocspURL)
+	leaf := newTestCert(t, "Test App Store Signing", &intermediate, false, appleLeafMarkerOID, ocspURL)
+	return testChain{root: root, intermediate: intermediate, leaf: leaf}
+}
+
+func (c testChain) pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(c.root.cert)
+	return pool
+}
+
+func signTestJWS(t *testing.T, c testChain, payload any) string {
+	t.Helper()
+	header, err := json.Marshal(map[string]any{
+		"alg": "ES256",
+		"x5c": []string{
+			base64.StdEncoding.EncodeToString(c.leaf.cert.Raw),
+			base64.StdEncoding.EncodeToString(c.intermediate.cert.Raw),
+			base64.StdEncoding.EncodeToString(c.root.cert.Raw),
+		},
+	})
+	require.NoError(t, err)
+	body, err := json.Marshal(payload)
+	require.NoError(t, err)
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(body)
+	digest := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, c.leaf.key, digest[:])
+	require.NoError(t, err)
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestAppleJWSVerifier_VerifiesSignedPayload(t *testing.T) {
+	chain := newTestChain(t, "")
+	token := signTestJWS(t, chain, map[string]string{"notificationType": "DID_RENEW"})
+
+	payload, err := newAppleJWSVerifier(chain.pool(), false).Verify(context.Background(), token)
+
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"notificationType":"DID_RENEW"}`, string(payload))
+}
+
+func TestAppleJWSVerifier_RejectsInvalidTokens(t *testing.T) {
+	chain := newTestChain(t, "")
+	token := signTestJWS(t, chain, map[string]string{"notificationType": "DID_RENEW"})
+
+	tests := []struct {
+		name   string
+		token  string
+		roots  *x509.CertPool
+		modify func(v *AppleJWSVerifier)
+	}{
+		{name: "tampered payload", token: func() string {
+			parts := strings.Split(token, ".")
+			parts[1] = base64.RawURLEncoding.EncodeToString([]byte(`{"notificationType":"REFUND"}`))
+			return strings.Join(parts, ".")
+		}()},
+		{name: "untrusted root", token: token, roots: newTestChain(t, "").pool()},
+		{name: "expired certificate", token: token, modify: func(v *AppleJWSVerifier) {
+			v.now = func() time.Time { return time.Now().Add(48 * time.Hour) }
+		}},
+		{name: "not a JWS", token: "abc.def"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roots := tt.roots
+			if roots == nil {
+				roots = chain.pool()
+			}
+			v := newAppleJWSVerifier(roots, false)
+			if tt.modify != nil {
+				tt.modify(v)
+			}
+
+			_, err := v.Verify(context.Background(), tt.token)
+
+			assert.ErrorIs(t, err, ErrAppleJWSInvalid)
+		})
+	}
+}
+
+func TestAppleJWSVerifier_RequiresAppleMarkerExtensions(t *testing.T) {
+	root := newTestCert(t, "Test Root CA", nil, true, nil, "")
+	intermediate := newTestCert(t, "Test WWDR", &root, true, appleIntermediateMarkerOID, "")
+	leaf := newTestCert(t, "Some Other Leaf", &intermediate, false, nil, "")
+	chain := testChain{root: root, intermediate: intermediate, leaf: leaf}
+
+	_, err := newAppleJWSVerifier(chain.pool(), false).Verify(context.Background(), signTestJWS(t, chain, map[string]string{}))
+
+	assert.ErrorIs(t, err, ErrAppleJWSInvalid)
+}
+
+func TestAppleJWSVerifier_OCSP(t *testing.T) {
+	var chain testChain
+	var requests int
+	status := ocsp.Good
+	responder := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		body, _ := io.ReadAll(r.Body)
+		req, err := ocsp.ParseRequest(body)
+		require.NoError(t, err)
+
+		issuer := chain.root
+		if req.SerialNumber.Cmp(chain.leaf.cert.SerialNumber) == 0 {
+			issuer = chain.intermediate
+		}
+		resp, err := ocsp.CreateResponse(issuer.cert, issuer.cert, ocsp.Response{
+			Status:       status,
+			SerialNumber: req.SerialNumber,
+			ThisUpdate:   time.Now().Add(-time.Minute),
+			NextUpdate:   time.Now().Add(time.Hour),
+			RevokedAt:    time.Now().Add(-time.Minute),
+		}, issuer.key)
+		require.NoError(t, err)
+		w.Write(resp)
+	}))
+	defer responder.Close()
+	chain = newTestChain(t, responder.URL)
+	token := signTestJWS(t, chain, map[string]string{})
+
+	t.Run("good status is cached", func(t *testing.T) {
+		v := newAppleJWSVerifier(chain.pool(), true)
+		_, err := v.Verify(context.Background(), token)
+		require.NoError(t, err)
+		_, err = v.Verify(context.Background(), token)
+		require.NoError(t, err)
+		assert.Equal(t, 2, requests, "one request per certificate, then served from cache")
+	})
+
+	t.Run("revoked leaf is rejected", func(t *testing.T) {
+		status = ocsp.Revoked
+		defer func() { status = ocsp.Good }()
+		_, err := newAppleJWSVerifier(chain.pool(), true).Verify(context.Background(), token)
+		assert.ErrorIs(t, err, ErrAppleJWSInvalid)
+	})
+
+	t.Run("unreachable responder is retryable", func(t *testing.T) {
+		down := newTestChain(t, "http://127.0.0.1:1/ocsp")
+		_, err := newAppleJWSVerifier(down.pool(), true).Verify(context.Background(), signTestJWS(t, down, map[string]string{}))
+		assert.True(t, errors.Is(err, ErrAppleRevocationUnavailable), "got %v", err)
+	})
+}
+
+func TestNewAppleJWSVerifier_EmbedsAppleRoot(t *testing.T) {
+	v, err := NewAppleJWSVerifier("", false)
+	require.NoError(t, err)
+
+	_, err = v.Verify(context.Background(), signTestJWS(t, newTestChain(t, ""), map[string]string{}))
+	assert.ErrorIs(t, err, ErrAppleJWSInvalid, "a self-made chain must not verify against Apple's root")
+}
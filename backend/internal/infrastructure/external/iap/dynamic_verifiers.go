@@ -3,6 +3,7 @@ package iap
 import (
 	"context"
 	"fmt"
+	"net/http"
 
 	"github.com/google/uuid"
 
@@ -11,14 +12,21 @@ import (
 
 // DynamicAppleVerifier resolves Apple credentials per app_id at verify time.
 type DynamicAppleVerifier struct {
-	resolver *CredentialResolver
-	mockURL  string // dev override
+	resolver  *CredentialResolver
+	mockURL   string // dev override
+	transport http.RoundTripper
 }
 
 func NewDynamicAppleVerifier(resolver *CredentialResolver, mockURL string) *DynamicAppleVerifier {
 	return &DynamicAppleVerifier{resolver: resolver, mockURL: mockURL}
 }
 
+// WithTransport sends every app's verifyReceipt requests through rt
+func (v *DynamicAppleVerifier) WithTransport(rt http.RoundTripper) *DynamicAppleVerifier {
+	v.transport = rt
+	return v
+}
+
 func (v *DynamicAppleVerifier) VerifyReceipt(ctx context.Context, appID uuid.UUID, receiptData string) (*command.IAPVerificationResult, error) {
 	creds, err := v.resolver.Resolve(ctx, appID, "apple")
 	if err != nil {
@@ -26,7 +34,7 @@ func (v *DynamicAppleVerifier) VerifyReceipt(ctx context.Context, appID uuid.UUI
 	}
 
 	isProduction := creds.AppleEnvironment != "sandbox"
-	verifier := NewAppleVerifier(creds.AppleSharedSecret, isProduction, v.mockURL)
+	verifier := NewAppleVerifier(creds.AppleSharedSecret, isProduction, v.mockURL).WithTransport(v.transport)
 	result, err := verifier.VerifyReceipt(ctx, receiptData)
 	if err != nil {
 		return nil, err
@@ -38,26 +46,37 @@ func (v *DynamicAppleVerifier) VerifyReceipt(ctx context.Context, appID uuid.UUI
 		ExpiresAt:     result.ExpiresAt,
 		IsRenewable:   result.IsRenewable,
 		OriginalTxID:  result.OriginalTxID,
+		OfferType:     result.OfferType,
+		OfferCode:     result.OfferCode,
+		Environment:   result.Environment,
+		Pending:       result.Pending,
 	}, nil
 }
 
 // DynamicGoogleVerifier resolves Google credentials per app_id at verify time.
 type DynamicGoogleVerifier struct {
-	resolver *CredentialResolver
-	mockURL  string // dev override
+	resolver  *CredentialResolver
+	mockURL   string // dev override
+	transport http.RoundTripper
 }
 
 func NewDynamicGoogleVerifier(resolver *CredentialResolver, mockURL string) *DynamicGoogleVerifier {
 	return &DynamicGoogleVerifier{resolver: resolver, mockURL: mockURL}
 }
 
+// WithTransport sends every app's Google Play API requests through rt
+func (v *DynamicGoogleVerifier) WithTransport(rt http.RoundTripper) *DynamicGoogleVerifier {
+	v.transport = rt
+	return v
+}
+
 func (v *DynamicGoogleVerifier) VerifyReceipt(ctx context.Context, appID uuid.UUID, receiptData string) (*command.IAPVerificationResult, error) {
 	creds, err := v.resolver.Resolve(ctx, appID, "google")
 	if err != nil {
 		return nil, fmt.Errorf("google credentials not configured for app %s: %w", appID, err)
 	}
 
-	verifier := NewGoogleVerifier(creds.GoogleServiceAccount, true, v.mockURL)
+	verifier := NewGoogleVerifier(creds.GoogleServiceAccount, true, v.mockURL).WithTransport(v.transport)
 	result, err := verifier.VerifyReceipt(ctx, receiptData)
 	if err != nil {
 		return nil, err
@@ -69,5 +88,43 @@ func (v *DynamicGoogleVerifier) VerifyReceipt(ctx context.Context, appID uuid.UU
 		ExpiresAt:     result.ExpiresAt,
 		IsRenewable:   result.IsRenewable,
 		OriginalTxID:  result.OriginalTxID,
+		OfferType:     result.OfferType,
+		OfferCode:     result.OfferCode,
+		Environment:   result.Environment,
+		Pending:       result.Pending,
+	}, nil
+}
+
+type receiptVerifier interface {
+	VerifyReceipt(ctx context.Context, receiptData string) (*VerifyResponse, error)
+}
+
+// StaticStoreVerifier verifies every app's receipts with one store account
+// configured in the environment (Amazon and Huawei have no per-app
+// credentials yet).
+type StaticStoreVerifier struct {
+	verifier receiptVerifier
+}
+
+func NewStaticStoreVerifier(verifier receiptVerifier) *StaticStoreVerifier {
+	return &StaticStoreVerifier{verifier: verifier}
+}
+
+func (v *StaticStoreVerifier) VerifyReceipt(ctx context.Context, _ uuid.UUID, receiptData string) (*command.IAPVerificationResult, error) {
+	result, err := v.verifier.VerifyReceipt(ctx, receiptData)
+	if err != nil {
+		return nil, err
+	}
+	return &command.IAPVerificationResult{
+		Valid:         result.Valid,
+		TransactionID: result.TransactionID,
+		ProductID:     result.ProductID,
+		ExpiresAt:     result.ExpiresAt,
+		IsRenewable:   result.IsRenewable,
+		OriginalTxID:  result.OriginalTxID,
+		OfferType:     result.OfferType,
+		OfferCode:     result.OfferCode,
+		Environment:   result.Environment,
+		Pending:       result.Pending,
 	}, nil
 }
@@ -0,0 +1,212 @@
+package iap
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// DefaultGoogleJWKSURL serves the keys Google signs OIDC ID tokens with
+	DefaultGoogleJWKSURL = "https://www.googleapis.com/oauth2/v3/certs"
+	// DefaultGoogleOIDCIssuer is the issuer of Pub/Sub push tokens
+	DefaultGoogleOIDCIssuer = "https://accounts.google.com"
+
+	defaultJWKSCacheTTL = time.Hour
+	// minJWKSRefreshInterval limits refetches triggered by unknown key IDs
+	minJWKSRefreshInterval = time.Minute
+)
+
+// ErrGoogleOIDCInvalid is returned when a push token fails validation
+var ErrGoogleOIDCInvalid = errors.New("invalid google oidc token")
+
+// GoogleOIDCConfig describes the tokens a Pub/Sub push subscription sends
+type GoogleOIDCConfig struct {
+	// Audience configured on the push subscription (usually the endpoint URL)
+	Audience string
+	// Issuer expected in the iss claim; accounts.google.com with or without the
+	// https:// scheme is treated as the same issuer
+	Issuer string
+	// ServiceAccountEmail, when set, must match the verified email claim
+	ServiceAccountEmail string
+	// JWKSURL overrides DefaultGoogleJWKSURL
+	JWKSURL string
+}
+
+// GoogleOIDCVerifier validates the Authorization bearer token Pub/Sub push
+// attaches to RTDN deliveries: an RS256 ID token signed by Google for the
+// configured audience and service account.
+type GoogleOIDCVerifier struct {
+	cfg        GoogleOIDCConfig
+	httpClient *http.Client
+	now        func() time.Time
+
+	mu          sync.Mutex
+	keys        map[string]*rsa.PublicKey
+	expiresAt   time.Time
+	lastFetched time.Time
+}
+
+// GoogleOIDCClaims are the claims of a Pub/Sub push token
+type GoogleOIDCClaims struct {
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	jwt.RegisteredClaims
+}
+
+// NewGoogleOIDCVerifier creates a verifier; the JWKS is fetched on first use
+func NewGoogleOIDCVerifier(cfg GoogleOIDCConfig) (*GoogleOIDCVerifier, error) {
+	if cfg.Audience == "" {
+		return nil, errors.New("google oidc audience is required")
+	}
+	if cfg.Issuer == "" {
+		cfg.Issuer = DefaultGoogleOIDCIssuer
+	}
+	if cfg.JWKSURL == "" {
+		cfg.JWKSURL = DefaultGoogleJWKSURL
+	}
+	return &GoogleOIDCVerifier{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		now:        time.Now,
+		keys:       make(map[string]*rsa.PublicKey),
+	}, nil
+}
+
+// Verify validates a raw bearer token and returns its claims
+func (v *GoogleOIDCVerifier) Verify(ctx context.Context, token string) (*GoogleOIDCClaims, error) {
+	claims := &GoogleOIDCClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.key(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}),
+		jwt.WithAudience(v.cfg.Audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithTimeFunc(v.now),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrGoogleOIDCInvalid, err)
+	}
+
+	if normalizeGoogleIssuer(claims.Issuer) != normalizeGoogleIssuer(v.cfg.Issuer) {
+		return nil, fmt.Errorf("%w: unexpected issuer %q", ErrGoogleOIDCInvalid, claims.Issuer)
+	}
+	if v.cfg.ServiceAccountEmail != "" {
+		if !claims.EmailVerified || !strings.EqualFold(claims.Email, v.cfg.ServiceAccountEmail) {
+			return nil, fmt.Errorf("%w: token was not issued to %s", ErrGoogleOIDCInvalid, v.cfg.ServiceAccountEmail)
+		}
+	}
+	return claims, nil
+}
+
+// key returns the signing key for kid, refreshing the JWKS when the cache
+// expired or the key is unknown (Google rotates keys every few days)
+func (v *GoogleOIDCVerifier) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	now := v.now()
+	key, ok := v.keys[kid]
+	if ok && now.Before(v.expiresAt) {
+		return key, nil
+	}
+	if !ok && !v.lastFetched.IsZero() && now.Sub(v.lastFetched) < minJWKSRefreshInterval && now.Before(v.expiresAt) {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	if err := v.refreshLocked(ctx); err != nil {
+		if ok {
+			// Serve the stale key rather than failing every push while
+			// Google's endpoint is unreachable
+			return key, nil
+		}
+		return nil, err
+	}
+	if key, ok = v.keys[kid]; !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+func (v *GoogleOIDCVerifier) refreshLocked(ctx context.Context) error {
+	v.lastFetched = v.now()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.cfg.JWKSURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("fetch google jwks: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetch google jwks: status %d", resp.StatusCode)
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&jwks); err != nil {
+		return fmt.Errorf("decode google jwks: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(jwks.Keys))
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	if len(keys) == 0 {
+		return errors.New("google jwks contains no RSA keys")
+	}
+
+	v.keys = keys
+	v.expiresAt = v.now().Add(cacheMaxAge(resp.Header.Get("Cache-Control")))
+	return nil
+}
+
+// cacheMaxAge reads max-age from a Cache-Control header
+func cacheMaxAge(header string) time.Duration {
+	for _, directive := range strings.Split(header, ",") {
+		directive = strings.TrimSpace(directive)
+		if value, ok := strings.CutPrefix(directive, "max-age="); ok {
+			if secs, err := strconv.Atoi(value); err == nil && secs > 0 {
+				return time.Duration(secs) * time.Second
+			}
+		}
+	}
+	return defaultJWKSCacheTTL
+}
+
+func normalizeGoogleIssuer(iss string) string {
+	return strings.TrimPrefix(iss, "https://")
+}
@@ -0,0 +1,168 @@
+package iap
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAudience = "https://api.example.com/webhook/google"
+
+type testJWKS struct {
+	server  *httptest.Server
+	key     *rsa.PrivateKey
+	kid     string
+	fetches int
+}
+
+func newTestJWKS(t *testing.T) *testJWKS {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	j := &testJWKS{key: key, kid: "key-1"}
+	j.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		j.fetches++
+		w.Header().Set("Cache-Control", "public, max-age=600")
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kid": j.kid,
+			"kty": "RSA",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	}))
+	t.Cleanup(j.server.Close)
+	return j
+}
+
+func (j *testJWKS) sign(t *testing.T, claims GoogleOIDCClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = j.kid
+	signed, err := token.SignedString(j.key)
+	require.NoError(t, err)
+	return signed
+}
+
+func validPushClaims() GoogleOIDCClaims {
+	now := time.Now()
+	return GoogleOIDCClaims{
+		Email:         "rtdn@project.iam.gserviceaccount.com",
+		EmailVerified: true,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "accounts.google.com",
+			Audience:  jwt.ClaimStrings{testAudience},
+			IssuedAt:  jwt.NewNumericDate(now.Add(-time.Minute)),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+		},
+	}
+}
+
+func newTestOIDCVerifier(t *testing.T, j *testJWKS) *GoogleOIDCVerifier {
+	v, err := NewGoogleOIDCVerifier(GoogleOIDCConfig{
+		Audience:            testAudience,
+		ServiceAccountEmail: "rtdn@project.iam.gserviceaccount.com",
+		JWKSURL:             j.server.URL,
+	})
+	require.NoError(t, err)
+	return v
+}
+
+func TestGoogleOIDCVerifier_AcceptsPushToken(t *testing.T) {
+	j := newTestJWKS(t)
+	v := newTestOIDCVerifier(t, j)
+
+	for i := 0; i < 3; i++ {
+		claims, err := v.Verify(context.Background(), j.sign(t, validPushClaims()))
+		require.NoError(t, err)
+		assert.Equal(t, "rtdn@project.iam.gserviceaccount.com", claims.Email)
+	}
+	assert.Equal(t, 1, j.fetches, "JWKS is cached")
+}
+
+func TestGoogleOIDCVerifier_RejectsInvalidTokens(t *testing.T) {
+	j := newTestJWKS(t)
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		token func() string
+	}{
+		{name: "wrong audience", token: func() string {
+			c := validPushClaims()
+			c.Audience = jwt.ClaimStrings{"https://other.example.com"}
+			return j.sign(t, c)
+		}},
+		{name: "wrong issuer", token: func() string {
+			c := validPushClaims()
+			c.Issuer = "https://evil.example.com"
+			return j.sign(t, c)
+		}},
+		{name: "other service account", token: func() string {
+			c := validPushClaims()
+			c.Email = "someone@else.iam.gserviceaccount.com"
+			return j.sign(t, c)
+		}},
+		{name: "unverified email", token: func() string {
+			c := validPushClaims()
+			c.EmailVerified = false
+			return j.sign(t, c)
+		}},
+		{name: "expired", token: func() string {
+			c := validPushClaims()
+			c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+			return j.sign(t, c)
+		}},
+		{name: "signed by unknown key", token: func() string {
+			token := jwt.NewWithClaims(jwt.SigningMethodRS256, validPushClaims())
+			token.Header["kid"] = j.kid
+			signed, err := token.SignedString(other)
+			require.NoError(t, err)
+			return signed
+		}},
+		{name: "HS256", token: func() string {
+			token := jwt.NewWithClaims(jwt.SigningMethodHS256, validPushClaims())
+			signed, err := token.SignedString([]byte("secret"))
+			require.NoError(t, err)
+			return signed
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newTestOIDCVerifier(t, j).Verify(context.Background(), tt.token())
+			assert.ErrorIs(t, err, ErrGoogleOIDCInvalid)
+		})
+	}
+}
+
+func TestGoogleOIDCVerifier_RefreshesOnKeyRotation(t *testing.T) {
+	j := newTestJWKS(t)
+	v := newTestOIDCVerifier(t, j)
+	_, err := v.Verify(context.Background(), j.sign(t, validPushClaims()))
+	require.NoError(t, err)
+
+	j.kid = "key-2"
+	v.now = func() time.Time { return time.Now().Add(2 * minJWKSRefreshInterval) }
+	_, err = v.Verify(context.Background(), j.sign(t, validPushClaims()))
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, j.fetches)
+}
+
+func TestNewGoogleOIDCVerifier_RequiresAudience(t *testing.T) {
+	_, err := NewGoogleOIDCVerifier(GoogleOIDCConfig{})
+	assert.Error(t, err)
+}
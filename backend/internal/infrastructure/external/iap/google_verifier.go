@@ -4,11 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"time"
 
+	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/androidpublisher/v3"
 	"google.golang.org/api/option"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
 )
 
 // GoogleVerifier verifies Google Play IAP receipts
@@ -16,7 +20,8 @@ type GoogleVerifier struct {
 	serviceAccountJSON string
 	isProduction       bool
 	// baseURL overrides the Google Play API endpoint (used for mock/testing)
-	baseURL string
+	baseURL   string
+	transport http.RoundTripper
 }
 
 // NewGoogleVerifier creates a new Google verifier.
@@ -29,6 +34,13 @@ func NewGoogleVerifier(serviceAccountJSON string, isProduction bool, baseURL str
 	}
 }
 
+// WithTransport sends Google Play API requests through rt, e.g. a fault
+// injecting one
+func (v *GoogleVerifier) WithTransport(rt http.RoundTripper) *GoogleVerifier {
+	v.transport = rt
+	return v
+}
+
 // VerifyReceipt verifies a Google Play IAP receipt
 func (v *GoogleVerifier) VerifyReceipt(ctx context.Context, receiptData string) (*VerifyResponse, error) {
 	// For development/mvp, return a mock response
@@ -42,6 +54,7 @@ func (v *GoogleVerifier) VerifyReceipt(ctx context.Context, receiptData string)
 			ExpiresAt:     time.Now().Add(30 * 24 * time.Hour),
 			IsRenewable:   true,
 			OriginalTxID:  "android-mock-original-tx",
+			Environment:   "Sandbox",
 		}, nil
 	}
 
@@ -50,6 +63,9 @@ func (v *GoogleVerifier) VerifyReceipt(ctx context.Context, receiptData string)
 	if v.baseURL != "" {
 		// Mock / test mode: skip auth and point to local server
 		opts = append(opts, option.WithEndpoint(v.baseURL), option.WithoutAuthentication())
+		if v.transport != nil {
+			opts = append(opts, option.WithHTTPClient(&http.Client{Transport: v.transport}))
+		}
 	} else {
 		// Production / sandbox: use real service-account credentials
 		conf, err := google.CredentialsFromJSON(
@@ -60,7 +76,13 @@ func (v *GoogleVerifier) VerifyReceipt(ctx context.Context, receiptData string)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse service account credentials: %w", err)
 		}
-		opts = append(opts, option.WithTokenSource(conf.TokenSource))
+		if v.transport != nil {
+			// A custom HTTP client replaces the token source, so authorize it here
+			authCtx := context.WithValue(ctx, oauth2.HTTPClient, &http.Client{Transport: v.transport})
+			opts = append(opts, option.WithHTTPClient(oauth2.NewClient(authCtx, conf.TokenSource)))
+		} else {
+			opts = append(opts, option.WithTokenSource(conf.TokenSource))
+		}
 	}
 
 	// Build the Android Publisher client
@@ -76,13 +98,13 @@ func (v *GoogleVerifier) VerifyReceipt(ctx context.Context, receiptData string)
 		PackageName   string `json:"packageName"`
 		ProductID     string `json:"productId"`
 		PurchaseToken string `json:"purchaseToken"`
-		Type          string `json:"type"` // "subscription" (default) or "product"
+		Type          string `json:"type"` // "subscription" (default), "inapp" or "product"
 	}
 	if err := json.Unmarshal([]byte(receiptData), &receipt); err != nil {
 		return nil, fmt.Errorf("failed to parse receipt data: %w", err)
 	}
 
-	if receipt.Type == "product" {
+	if receipt.Type == "inapp" || receipt.Type == "product" {
 		prod, err := service.Purchases.Products.Get(
 			receipt.PackageName,
 			receipt.ProductID,
@@ -100,6 +122,8 @@ func (v *GoogleVerifier) VerifyReceipt(ctx context.Context, receiptData string)
 			ExpiresAt:     time.Time{}, // one-time products have no expiry
 			IsRenewable:   false,
 			OriginalTxID:  receipt.PurchaseToken,
+			Environment:   googleEnvironment(prod.PurchaseType),
+			Pending:       prod.PurchaseState == 2,
 		}, nil
 	}
 
@@ -121,10 +145,13 @@ func (v *GoogleVerifier) VerifyReceipt(ctx context.Context, receiptData string)
 	//   "user canceled" (also 0) because Go zeroes missing JSON fields.
 	// - A subscription that the user has canceled but whose expiry is still in the
 	//   future is legitimately active — the user paid for that period.
-	paymentReceived := sub.PaymentState != nil && *sub.PaymentState == 1
+	// PaymentState 2 is a free trial: no payment yet, but the user is entitled.
+	paymentReceived := sub.PaymentState != nil && (*sub.PaymentState == 1 || *sub.PaymentState == 2)
 	notExpired := expiresAt.After(time.Now())
 	isValid := paymentReceived && notExpired
 
+	offerType, offerCode := googleOffer(sub)
+
 	return &VerifyResponse{
 		Valid:         isValid,
 		TransactionID: receipt.PurchaseToken,
@@ -132,5 +159,34 @@ func (v *GoogleVerifier) VerifyReceipt(ctx context.Context, receiptData string)
 		ExpiresAt:     expiresAt,
 		IsRenewable:   sub.AutoRenewing,
 		OriginalTxID:  receipt.PurchaseToken,
+		OfferType:     offerType,
+		OfferCode:     offerCode,
+		Environment:   googleEnvironment(sub.PurchaseType),
+		// PaymentState 0: payment pending (e.g. cash or bank transfer)
+		Pending: sub.PaymentState != nil && *sub.PaymentState == 0,
 	}, nil
 }
+
+// googleEnvironment reports license-tester purchases (purchaseType 0) as
+// sandbox; Google has no separate sandbox endpoint.
+func googleEnvironment(purchaseType *int64) string {
+	if purchaseType != nil && *purchaseType == 0 {
+		return "Sandbox"
+	}
+	return "Production"
+}
+
+// googleOffer maps the subscription purchase to an offer type.
+// Only vanity promo codes are detected: one-time codes report promotionType 0,
+// which the API client cannot tell apart from "no promotion".
+func googleOffer(sub *androidpublisher.SubscriptionPurchase) (entity.OfferType, string) {
+	switch {
+	case sub.PromotionCode != "":
+		return entity.OfferTypeOfferCode, sub.PromotionCode
+	case sub.PaymentState != nil && *sub.PaymentState == 2:
+		return entity.OfferTypeFreeTrial, ""
+	case sub.IntroductoryPriceInfo != nil:
+		return entity.OfferTypeIntroductory, ""
+	}
+	return "", ""
+}
@@ -0,0 +1,300 @@
+package iap
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+// Huawei IAP endpoints (China/global site "drcn")
+const (
+	DefaultHuaweiOrderURL        = "https://orders-drcn.iap.cloud.huawei.com"
+	DefaultHuaweiSubscriptionURL = "https://subscr-drcn.iap.cloud.huawei.com"
+	DefaultHuaweiTokenURL        = "https://oauth-login.cloud.huawei.com/oauth2/v3/token"
+)
+
+// ErrHuaweiSignature is returned when Huawei-signed data fails verification
+var ErrHuaweiSignature = errors.New("invalid huawei signature")
+
+// ParseHuaweiPublicKey parses the base64 X.509 RSA public key shown in
+// AppGallery Connect (In-App Purchases → Public key)
+func ParseHuaweiPublicKey(encoded string) (*rsa.PublicKey, error) {
+	der, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("decode huawei public key: %w", err)
+	}
+	parsed, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("parse huawei public key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("huawei public key is %T, not RSA", parsed)
+	}
+	return key, nil
+}
+
+// VerifyHuaweiSignature checks a base64 signature over content. algorithm is
+// Huawei's signatureAlgorithm field: "SHA256WithRSA/PSS", or empty / any
+// other value for PKCS#1 v1.5 SHA256WithRSA.
+func VerifyHuaweiSignature(key *rsa.PublicKey, content, signature, algorithm string) error {
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrHuaweiSignature, err)
+	}
+	digest := sha256.Sum256([]byte(content))
+	if strings.EqualFold(algorithm, "SHA256WithRSA/PSS") {
+		err = rsa.VerifyPSS(key, crypto.SHA256, digest[:], sig, nil)
+	} else {
+		err = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrHuaweiSignature, err)
+	}
+	return nil
+}
+
+// HuaweiReceipt is the receipt_data of a Huawei AppGallery purchase. Type is
+// "subscription" (SubscriptionID required) or "inapp".
+type HuaweiReceipt struct {
+	PurchaseToken  string `json:"purchaseToken"`
+	ProductID      string `json:"productId"`
+	SubscriptionID string `json:"subscriptionId"`
+	Type           string `json:"type"`
+}
+
+// HuaweiPurchase is the InappPurchaseData Huawei returns for a purchase.
+// Dates are Unix milliseconds.
+type HuaweiPurchase struct {
+	OrderID        string `json:"orderId"`
+	PurchaseToken  string `json:"purchaseToken"`
+	ProductID      string `json:"productId"`
+	SubscriptionID string `json:"subscriptionId"`
+	ExpirationDate int64  `json:"expirationDate"`
+	// PurchaseState is 0 purchased, 1 cancelled, 2 refunded
+	PurchaseState int  `json:"purchaseState"`
+	SubIsValid    bool `json:"subIsvalid"`
+	AutoRenewing  bool `json:"autoRenewing"`
+	// PurchaseType is 0 for sandbox purchases and absent in production
+	PurchaseType *int `json:"purchaseType"`
+	TrialFlag    int  `json:"trialFlag"`
+	// IntroductoryFlag is 1 while an introductory price applies
+	IntroductoryFlag int `json:"introductoryFlag"`
+}
+
+// HuaweiVerifier verifies Huawei AppGallery purchases with the Order and
+// Subscription services
+type HuaweiVerifier struct {
+	clientID     string
+	clientSecret string
+	orderURL     string
+	subURL       string
+	tokenURL     string
+	publicKey    *rsa.PublicKey
+	client       *http.Client
+	now          func() time.Time
+
+	mu          sync.Mutex
+	accessToken string
+	tokenExpiry time.Time
+}
+
+// NewHuaweiVerifier creates a new Huawei verifier. baseURL overrides the
+// Order, Subscription and OAuth endpoints (mock); publicKey, when set, is used
+// to verify the purchase data signature.
+func NewHuaweiVerifier(clientID, clientSecret, baseURL string, publicKey *rsa.PublicKey) *HuaweiVerifier {
+	v := &HuaweiVerifier{
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		orderURL:     DefaultHuaweiOrderURL,
+		subURL:       DefaultHuaweiSubscriptionURL,
+		tokenURL:     DefaultHuaweiTokenURL,
+		publicKey:    publicKey,
+		client:       &http.Client{Timeout: 10 * time.Second},
+		now:          time.Now,
+	}
+	if baseURL = strings.TrimRight(baseURL, "/"); baseURL != "" {
+		v.orderURL = baseURL
+		v.subURL = baseURL
+		v.tokenURL = baseURL + "/oauth2/v3/token"
+	}
+	return v
+}
+
+// VerifyReceipt verifies a Huawei purchase; receiptData is a HuaweiReceipt
+// JSON document
+func (v *HuaweiVerifier) VerifyReceipt(ctx context.Context, receiptData string) (*VerifyResponse, error) {
+	var receipt HuaweiReceipt
+	if err := json.Unmarshal([]byte(receiptData), &receipt); err != nil || receipt.PurchaseToken == "" {
+		return nil, errors.New("huawei receipt_data must be JSON with purchaseToken")
+	}
+
+	purchase, err := v.Purchase(ctx, receipt)
+	if err != nil || purchase == nil {
+		return &VerifyResponse{Valid: false}, err
+	}
+	return v.toVerifyResponse(receipt, purchase), nil
+}
+
+// Purchase fetches the current state of a purchase. A nil purchase with no
+// error means Huawei does not know the purchase token.
+func (v *HuaweiVerifier) Purchase(ctx context.Context, receipt HuaweiReceipt) (*HuaweiPurchase, error) {
+	endpoint := v.orderURL + "/applications/purchases/tokens/verify"
+	body := map[string]string{"purchaseToken": receipt.PurchaseToken, "productId": receipt.ProductID}
+	if receipt.Type == "subscription" || receipt.SubscriptionID != "" {
+		endpoint = v.subURL + "/sub/applications/v2/purchases/get"
+		body = map[string]string{"purchaseToken": receipt.PurchaseToken, "subscriptionId": receipt.SubscriptionID}
+	}
+
+	var result struct {
+		ResponseCode       string `json:"responseCode"`
+		ResponseMessage    string `json:"responseMessage"`
+		InappPurchaseData  string `json:"inappPurchaseData"`
+		DataSignature      string `json:"dataSignature"`
+		SignatureAlgorithm string `json:"signatureAlgorithm"`
+	}
+	if err := v.post(ctx, endpoint, body, &result); err != nil {
+		return nil, err
+	}
+	switch result.ResponseCode {
+	case "0":
+	case "-1", "1":
+		// Internal error or failed authentication: retryable, not a verdict
+		return nil, fmt.Errorf("huawei IAP error %s: %s", result.ResponseCode, result.ResponseMessage)
+	default:
+		return nil, nil
+	}
+
+	if v.publicKey != nil {
+		if err := VerifyHuaweiSignature(v.publicKey, result.InappPurchaseData, result.DataSignature, result.SignatureAlgorithm); err != nil {
+			return nil, err
+		}
+	}
+	var purchase HuaweiPurchase
+	if err := json.Unmarshal([]byte(result.InappPurchaseData), &purchase); err != nil {
+		return nil, fmt.Errorf("failed to decode huawei purchase data: %w", err)
+	}
+	return &purchase, nil
+}
+
+func (v *HuaweiVerifier) toVerifyResponse(receipt HuaweiReceipt, p *HuaweiPurchase) *VerifyResponse {
+	subscription := receipt.Type == "subscription" || receipt.SubscriptionID != ""
+	if p.PurchaseState != 0 || (subscription && !p.SubIsValid) {
+		return &VerifyResponse{Valid: false}
+	}
+
+	out := &VerifyResponse{
+		Valid:         true,
+		TransactionID: p.PurchaseToken,
+		ProductID:     p.ProductID,
+		ExpiresAt:     v.now(),
+		IsRenewable:   p.AutoRenewing,
+		OriginalTxID:  p.PurchaseToken,
+		Environment:   "Production",
+	}
+	if p.ExpirationDate > 0 {
+		out.ExpiresAt = time.UnixMilli(p.ExpirationDate)
+	}
+	if p.PurchaseType != nil && *p.PurchaseType == 0 {
+		out.Environment = "Sandbox"
+	}
+	switch {
+	case p.TrialFlag == 1:
+		out.OfferType = entity.OfferTypeFreeTrial
+	case p.IntroductoryFlag == 1:
+		out.OfferType = entity.OfferTypeIntroductory
+	}
+	return out
+}
+
+// post sends an authenticated JSON request to a Huawei IAP service
+func (v *HuaweiVerifier) post(ctx context.Context, endpoint string, body any, out any) error {
+	token, err := v.token(ctx)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to build huawei request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte("APPAT:"+token)))
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call huawei IAP: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		v.mu.Lock()
+		v.accessToken = ""
+		v.mu.Unlock()
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("huawei IAP returned status %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode huawei IAP response: %w", err)
+	}
+	return nil
+}
+
+// token returns a cached app-level access token, refreshing it a minute
+// before it expires
+func (v *HuaweiVerifier) token(ctx context.Context) (string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.accessToken != "" && v.now().Before(v.tokenExpiry) {
+		return v.accessToken, nil
+	}
+
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {v.clientID},
+		"client_secret": {v.clientSecret},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to build huawei token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get huawei access token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("huawei token endpoint returned status %d", resp.StatusCode)
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("failed to decode huawei access token: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", errors.New("huawei token endpoint returned no access token")
+	}
+	v.accessToken = token.AccessToken
+	v.tokenExpiry = v.now().Add(time.Duration(token.ExpiresIn)*time.Second - time.Minute)
+	return v.accessToken, nil
+}
@@ -0,0 +1,73 @@
+package iap
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+func signHuawei(t *testing.T, key *rsa.PrivateKey, content string) string {
+	t.Helper()
+	digest := sha256.Sum256([]byte(content))
+	sig, err := rsa.SignPSS(rand.Reader, key, crypto.SHA256, digest[:], nil)
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(sig)
+}
+
+func TestHuaweiVerifier_Subscription(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	purchaseData := `{"orderId":"o-1","purchaseToken":"tok-1","productId":"com.app.monthly","expirationDate":1893456000000,"subIsvalid":true,"autoRenewing":true,"trialFlag":1}`
+	signature := signHuawei(t, key, purchaseData)
+
+	tokenCalls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oauth2/v3/token":
+			tokenCalls++
+			_ = json.NewEncoder(w).Encode(map[string]any{"access_token": "at", "expires_in": 3600})
+		case "/sub/applications/v2/purchases/get":
+			assert.Equal(t, "Basic "+base64.StdEncoding.EncodeToString([]byte("APPAT:at")), r.Header.Get("Authorization"))
+			_ = json.NewEncoder(w).Encode(map[string]string{
+				"responseCode":       "0",
+				"inappPurchaseData":  purchaseData,
+				"dataSignature":      signature,
+				"signatureAlgorithm": "SHA256WithRSA/PSS",
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	verifier := NewHuaweiVerifier("client", "secret", srv.URL, &key.PublicKey)
+	receipt := `{"purchaseToken":"tok-1","productId":"com.app.monthly","subscriptionId":"s-1","type":"subscription"}`
+	result, err := verifier.VerifyReceipt(context.Background(), receipt)
+	require.NoError(t, err)
+	assert.True(t, result.Valid)
+	assert.Equal(t, "tok-1", result.TransactionID)
+	assert.Equal(t, int64(1893456000000), result.ExpiresAt.UnixMilli())
+	assert.Equal(t, entity.OfferTypeFreeTrial, result.OfferType)
+
+	_, err = verifier.VerifyReceipt(context.Background(), receipt)
+	require.NoError(t, err)
+	assert.Equal(t, 1, tokenCalls, "access token is cached")
+
+	// Purchase data signed by another key is rejected
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, err = NewHuaweiVerifier("client", "secret", srv.URL, &other.PublicKey).VerifyReceipt(context.Background(), receipt)
+	assert.ErrorIs(t, err, ErrHuaweiSignature)
+}
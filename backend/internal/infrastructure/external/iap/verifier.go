@@ -11,6 +11,8 @@ import (
 	"time"
 
 	iap "github.com/awa/go-iap/appstore"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
 )
 
 // numericStringPatcher is an http.RoundTripper that patches non-numeric
@@ -68,6 +70,7 @@ type AppleVerifier struct {
 	sharedSecret string
 	environment  iap.Environment // iap.Sandbox or iap.Production
 	mockURL      string          // if set, overrides both Sandbox and Production URLs (for local testing)
+	transport    http.RoundTripper
 }
 
 // NewAppleVerifier creates a new Apple verifier.
@@ -86,6 +89,13 @@ func NewAppleVerifier(sharedSecret string, isProduction bool, mockURL string) *A
 	}
 }
 
+// WithTransport sends verifyReceipt requests through rt, e.g. a fault
+// injecting one
+func (v *AppleVerifier) WithTransport(rt http.RoundTripper) *AppleVerifier {
+	v.transport = rt
+	return v
+}
+
 // VerifyResponse represents the verification response
 type VerifyResponse struct {
 	Valid         bool
@@ -94,6 +104,14 @@ type VerifyResponse struct {
 	ExpiresAt     time.Time
 	IsRenewable   bool
 	OriginalTxID  string
+	OfferType     entity.OfferType
+	OfferCode     string
+	// Environment is the store environment the receipt was issued in
+	// ("Production" or "Sandbox"); empty when the store does not say
+	Environment string
+	// Pending is set when payment is deferred (pending payment method, Ask
+	// to Buy); Valid is false until the store finalizes the purchase
+	Pending bool
 }
 
 // VerifyReceipt verifies an Apple IAP receipt
@@ -107,20 +125,27 @@ func (v *AppleVerifier) VerifyReceipt(ctx context.Context, receiptData string) (
 			ExpiresAt:     time.Now().Add(30 * 24 * time.Hour),
 			IsRenewable:   true,
 			OriginalTxID:  "mock-original-tx",
+			Environment:   string(iap.Sandbox),
 		}, nil
 	}
 
 	// Build go-iap client and optionally redirect to mock.
 	// When using the local mock, also wrap the transport to patch non-numeric
 	// original_transaction_id values (mock uses string IDs; go-iap expects numbers).
+	transport := v.transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
 	var client *iap.Client
 	if v.mockURL != "" {
 		client = iap.NewWithClient(&http.Client{
-			Transport: &numericStringPatcher{wrapped: http.DefaultTransport},
+			Transport: &numericStringPatcher{wrapped: transport},
 		})
 		mockVerifyURL := v.mockURL + "/verifyReceipt"
 		client.ProductionURL = mockVerifyURL
 		client.SandboxURL = mockVerifyURL
+	} else if v.transport != nil {
+		client = iap.NewWithClient(&http.Client{Timeout: 10 * time.Second, Transport: v.transport})
 	} else {
 		client = iap.New()
 	}
@@ -141,6 +166,11 @@ func (v *AppleVerifier) VerifyReceipt(ctx context.Context, receiptData string) (
 	}
 
 	latestReceipts := result.LatestReceiptInfo
+	if len(latestReceipts) == 0 {
+		// latest_receipt_info only lists subscriptions; consumables appear in
+		// receipt.in_app until the app finishes the transaction
+		latestReceipts = newestInApp(result.Receipt.InApp)
+	}
 	if len(latestReceipts) == 0 {
 		return &VerifyResponse{Valid: false}, nil
 	}
@@ -160,6 +190,8 @@ func (v *AppleVerifier) VerifyReceipt(ctx context.Context, receiptData string) (
 		expiresAt, _ = time.Parse(time.RFC3339, first.ExpiresDate.ExpiresDate)
 	}
 
+	offerType, offerCode := appleOffer(first)
+
 	return &VerifyResponse{
 		Valid:         true,
 		TransactionID: first.TransactionID,
@@ -167,9 +199,45 @@ func (v *AppleVerifier) VerifyReceipt(ctx context.Context, receiptData string) (
 		ExpiresAt:     expiresAt,
 		IsRenewable:   first.IsInIntroOfferPeriod == "false",
 		OriginalTxID:  string(first.OriginalTransactionID),
+		OfferType:     offerType,
+		OfferCode:     offerCode,
+		Environment:   string(result.Environment),
 	}, nil
 }
 
+// newestInApp returns the most recently purchased in-app transaction
+func newestInApp(inApp []iap.InApp) []iap.InApp {
+	var newest *iap.InApp
+	var newestMS int64
+	for i := range inApp {
+		ms, _ := strconv.ParseInt(inApp[i].PurchaseDateMS, 10, 64)
+		if newest == nil || ms > newestMS {
+			newest, newestMS = &inApp[i], ms
+		}
+	}
+	if newest == nil {
+		return nil
+	}
+	return []iap.InApp{*newest}
+}
+
+// appleOffer maps the receipt's offer flags to an offer type. Offer codes and
+// promotional offers take precedence: Apple also sets the intro flags when a
+// code grants a free or discounted first period.
+func appleOffer(info iap.InApp) (entity.OfferType, string) {
+	switch {
+	case info.OfferCodeRefName != "":
+		return entity.OfferTypeOfferCode, info.OfferCodeRefName
+	case info.PromotionalOfferID != "":
+		return entity.OfferTypePromotional, info.PromotionalOfferID
+	case info.IsTrialPeriod == "true":
+		return entity.OfferTypeFreeTrial, ""
+	case info.IsInIntroOfferPeriod == "true":
+		return entity.OfferTypeIntroductory, ""
+	}
+	return "", ""
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
@@ -56,6 +56,12 @@ func NewClient(config Config, logger *zap.Logger) *Client {
 	}
 }
 
+// WithTransport sends Matomo requests through rt, e.g. a fault injecting one
+func (c *Client) WithTransport(rt http.RoundTripper) *Client {
+	c.httpClient.Transport = rt
+	return c
+}
+
 // TrackEventRequest represents a standard event tracking request
 type TrackEventRequest struct {
 	Category        string            `json:"category"`
@@ -0,0 +1,118 @@
+package paypal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// API base URLs
+const (
+	LiveBaseURL    = "https://api-m.paypal.com"
+	SandboxBaseURL = "https://api-m.sandbox.paypal.com"
+)
+
+// Subscription is the part of a PayPal Billing subscription we act on
+type Subscription struct {
+	ID          string `json:"id"`
+	PlanID      string `json:"plan_id"`
+	Status      string `json:"status"`
+	CustomID    string `json:"custom_id"`
+	BillingInfo struct {
+		NextBillingTime *time.Time `json:"next_billing_time"`
+	} `json:"billing_info"`
+}
+
+// Client calls the PayPal REST API with client credentials
+type Client struct {
+	baseURL      string
+	clientID     string
+	clientSecret string
+	httpClient   *http.Client
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+// NewClient creates a PayPal REST client; an empty baseURL uses the live API
+func NewClient(baseURL, clientID, clientSecret string) *Client {
+	if baseURL == "" {
+		baseURL = LiveBaseURL
+	}
+	return &Client{
+		baseURL:      strings.TrimRight(baseURL, "/"),
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		httpClient:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// GetSubscription fetches a Billing subscription (I-...)
+func (c *Client) GetSubscription(ctx context.Context, id string) (*Subscription, error) {
+	token, err := c.accessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/v1/billing/subscriptions/"+url.PathEscape(id), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build subscription request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	var sub Subscription
+	if err := c.do(req, &sub); err != nil {
+		return nil, fmt.Errorf("failed to fetch subscription %s: %w", id, err)
+	}
+	return &sub, nil
+}
+
+// accessToken returns a cached OAuth token, fetching a new one shortly before
+// the cached one expires
+func (c *Client) accessToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && time.Now().Before(c.tokenExpiry) {
+		return c.token, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/v1/oauth2/token",
+		strings.NewReader("grant_type=client_credentials"))
+	if err != nil {
+		return "", fmt.Errorf("failed to build token request: %w", err)
+	}
+	req.SetBasicAuth(c.clientID, c.clientSecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := c.do(req, &token); err != nil {
+		return "", fmt.Errorf("failed to obtain PayPal access token: %w", err)
+	}
+	c.token = token.AccessToken
+	c.tokenExpiry = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - time.Minute)
+	return c.token, nil
+}
+
+func (c *Client) do(req *http.Request, out any) error {
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d: %s", resp.StatusCode, body)
+	}
+	return json.Unmarshal(body, out)
+}
@@ -0,0 +1,171 @@
+package paypal
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func signedTransmission(t *testing.T, key *rsa.PrivateKey, webhookID string, body []byte) Transmission {
+	t.Helper()
+	tr := Transmission{
+		ID:       "69cd13f0-d67a-11e5-baa3-778b53f4ae55",
+		Time:     "2026-10-01T12:00:00Z",
+		CertURL:  "https://api.paypal.com/v1/notifications/certs/CERT-360caa42-fca2a594-1d93a270",
+		AuthAlgo: "SHA256withRSA",
+	}
+	digest := sha256.Sum256([]byte(signedMessage(tr, webhookID, body)))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+	tr.Sig = base64.StdEncoding.EncodeToString(sig)
+	return tr
+}
+
+// issueCert creates a certificate for commonName signed by parent, or a
+// self-signed one when parent is nil
+func issueCert(t *testing.T, commonName string, isCA bool, key *rsa.PrivateKey, parent *x509.Certificate, parentKey *rsa.PrivateKey) *x509.Certificate {
+	t.Helper()
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:     time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	if isCA {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+	}
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
+
+func TestWebhookVerifier_Verify(t *testing.T) {
+	caKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ca := issueCert(t, "Test Root CA", true, caKey, nil, nil)
+	leaf := issueCert(t, "messageverificationcerts.paypal.com", false, key, ca, caKey)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+
+	newVerifier := func(chain ...*x509.Certificate) (*WebhookVerifier, *int) {
+		verifier := NewWebhookVerifier("WH-1")
+		verifier.roots = roots
+		verifier.now = func() time.Time { return time.Date(2026, 10, 1, 12, 2, 0, 0, time.UTC) }
+		fetched := 0
+		verifier.fetchCert = func(ctx context.Context, certURL string) ([]*x509.Certificate, error) {
+			fetched++
+			return chain, nil
+		}
+		return verifier, &fetched
+	}
+	verifier, fetched := newVerifier(leaf)
+	ctx := context.Background()
+	body := []byte(`{"id":"WH-EVT-1","event_type":"BILLING.SUBSCRIPTION.ACTIVATED"}`)
+
+	tr := signedTransmission(t, key, "WH-1", body)
+	require.NoError(t, verifier.Verify(ctx, tr, body))
+	require.NoError(t, verifier.Verify(ctx, tr, body))
+	assert.Equal(t, 1, *fetched, "certificate is cached")
+
+	assert.ErrorIs(t, verifier.Verify(ctx, tr, []byte(`{"id":"WH-EVT-1","event_type":"BILLING.SUBSCRIPTION.CANCELLED"}`)), ErrSignature)
+
+	otherWebhook := signedTransmission(t, key, "WH-2", body)
+	assert.ErrorIs(t, verifier.Verify(ctx, otherWebhook, body), ErrSignature)
+
+	foreignCert := tr
+	foreignCert.CertURL = "https://attacker.example.com/cert.pem"
+	assert.ErrorIs(t, verifier.Verify(ctx, foreignCert, body), ErrSignature)
+
+	weakAlgo := tr
+	weakAlgo.AuthAlgo = "SHA1withRSA"
+	assert.ErrorIs(t, verifier.Verify(ctx, weakAlgo, body), ErrSignature)
+
+	assert.ErrorIs(t, verifier.Verify(ctx, Transmission{}, body), ErrSignature)
+
+	t.Run("stale transmission time", func(t *testing.T) {
+		verifier, _ := newVerifier(leaf)
+		verifier.now = func() time.Time { return time.Date(2026, 10, 1, 12, 30, 0, 0, time.UTC) }
+		assert.ErrorIs(t, verifier.Verify(ctx, tr, body), ErrSignature)
+
+		malformed := tr
+		malformed.Time = "yesterday"
+		assert.ErrorIs(t, verifier.Verify(ctx, malformed, body), ErrSignature)
+	})
+
+	t.Run("untrusted root", func(t *testing.T) {
+		selfSigned := issueCert(t, "messageverificationcerts.paypal.com", false, key, nil, nil)
+		verifier, _ := newVerifier(selfSigned)
+		assert.ErrorIs(t, verifier.Verify(ctx, tr, body), ErrSignature)
+	})
+
+	t.Run("unexpected subject", func(t *testing.T) {
+		other := issueCert(t, "attacker.example.com", false, key, ca, caKey)
+		verifier, _ := newVerifier(other)
+		assert.ErrorIs(t, verifier.Verify(ctx, tr, body), ErrSignature)
+	})
+
+	t.Run("chain through intermediate", func(t *testing.T) {
+		midKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		mid := issueCert(t, "Test Intermediate CA", true, midKey, ca, caKey)
+		leaf := issueCert(t, "messageverificationcerts.paypal.com", false, key, mid, midKey)
+		verifier, _ := newVerifier(leaf, mid)
+		assert.NoError(t, verifier.Verify(ctx, tr, body))
+	})
+}
+
+func TestClient_GetSubscription(t *testing.T) {
+	tokenRequests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/oauth2/token":
+			tokenRequests++
+			user, pass, _ := r.BasicAuth()
+			assert.Equal(t, "client", user)
+			assert.Equal(t, "secret", pass)
+			_, _ = w.Write([]byte(`{"access_token":"tok","expires_in":3600}`))
+		case "/v1/billing/subscriptions/I-1":
+			assert.Equal(t, "Bearer tok", r.Header.Get("Authorization"))
+			_, _ = w.Write([]byte(`{"id":"I-1","plan_id":"P-1","status":"ACTIVE","billing_info":{"next_billing_time":"2026-11-01T10:00:00Z"}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	client := NewClient(srv.URL, "client", "secret")
+	for range 2 {
+		sub, err := client.GetSubscription(context.Background(), "I-1")
+		require.NoError(t, err)
+		assert.Equal(t, "P-1", sub.PlanID)
+		require.NotNil(t, sub.BillingInfo.NextBillingTime)
+		assert.Equal(t, time.Date(2026, 11, 1, 10, 0, 0, 0, time.UTC), sub.BillingInfo.NextBillingTime.UTC())
+	}
+	assert.Equal(t, 1, tokenRequests, "token is cached")
+
+	_, err := client.GetSubscription(context.Background(), "I-missing")
+	assert.Error(t, err)
+}
@@ -0,0 +1,218 @@
+package warehouse
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"golang.org/x/oauth2/google"
+	bigquery "google.golang.org/api/bigquery/v2"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+)
+
+// bigQueryTimestampLayout is the TIMESTAMP literal format of query parameters
+const bigQueryTimestampLayout = "2006-01-02 15:04:05.999999-07:00"
+
+var bigQueryTypes = map[ColumnType]string{
+	TypeString:    "STRING",
+	TypeInt:       "INT64",
+	TypeFloat:     "FLOAT64",
+	TypeBool:      "BOOL",
+	TypeTimestamp: "TIMESTAMP",
+	TypeJSON:      "JSON",
+}
+
+// BigQueryConfig selects the dataset synced tables live in. Without
+// CredentialsJSON, application default credentials are used; Endpoint
+// overrides the API for tests.
+type BigQueryConfig struct {
+	ProjectID       string
+	Dataset         string
+	Location        string
+	CredentialsJSON string
+	Endpoint        string
+}
+
+// BigQuery merges batches with a MERGE over an array-of-structs parameter,
+// so each batch is one DML job
+type BigQuery struct {
+	svc          *bigquery.Service
+	cfg          BigQueryConfig
+	pollInterval time.Duration
+}
+
+// NewBigQuery creates a BigQuery destination
+func NewBigQuery(ctx context.Context, cfg BigQueryConfig) (*BigQuery, error) {
+	var opts []option.ClientOption
+	switch {
+	case cfg.Endpoint != "":
+		opts = append(opts, option.WithEndpoint(cfg.Endpoint), option.WithoutAuthentication())
+	case cfg.CredentialsJSON != "":
+		creds, err := google.CredentialsFromJSON(ctx, []byte(cfg.CredentialsJSON), bigquery.BigqueryScope)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse BigQuery credentials: %w", err)
+		}
+		opts = append(opts, option.WithTokenSource(creds.TokenSource))
+	default:
+		opts = append(opts, option.WithScopes(bigquery.BigqueryScope))
+	}
+	svc, err := bigquery.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create BigQuery client: %w", err)
+	}
+	return &BigQuery{svc: svc, cfg: cfg, pollInterval: time.Second}, nil
+}
+
+// Name identifies the destination in the sync state
+func (b *BigQuery) Name() string {
+	return "bigquery:" + b.cfg.ProjectID + "." + b.cfg.Dataset
+}
+
+// EnsureTable creates the table or appends the columns it lacks. BigQuery only
+// allows adding nullable columns, which is all the sync needs.
+func (b *BigQuery) EnsureTable(ctx context.Context, table string, columns []Column) error {
+	existing, err := b.svc.Tables.Get(b.cfg.ProjectID, b.cfg.Dataset, table).Context(ctx).Do()
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+		_, err = b.svc.Tables.Insert(b.cfg.ProjectID, b.cfg.Dataset, &bigquery.Table{
+			TableReference: &bigquery.TableReference{ProjectId: b.cfg.ProjectID, DatasetId: b.cfg.Dataset, TableId: table},
+			Schema:         &bigquery.TableSchema{Fields: bigQueryFields(columns)},
+		}).Context(ctx).Do()
+		if err != nil {
+			return fmt.Errorf("failed to create BigQuery table %s: %w", table, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get BigQuery table %s: %w", table, err)
+	}
+
+	have := make(map[string]bool)
+	fields := existing.Schema.Fields
+	for _, f := range fields {
+		have[f.Name] = true
+	}
+	var missing []Column
+	for _, c := range columns {
+		if !have[c.Name] {
+			missing = append(missing, c)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	fields = append(fields, bigQueryFields(missing)...)
+	if _, err := b.svc.Tables.Patch(b.cfg.ProjectID, b.cfg.Dataset, table, &bigquery.Table{
+		Schema: &bigquery.TableSchema{Fields: fields},
+	}).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("failed to add columns to BigQuery table %s: %w", table, err)
+	}
+	return nil
+}
+
+func bigQueryFields(columns []Column) []*bigquery.TableFieldSchema {
+	fields := make([]*bigquery.TableFieldSchema, len(columns))
+	for i, c := range columns {
+		fields[i] = &bigquery.TableFieldSchema{Name: c.Name, Type: bigQueryTypes[c.Type], Mode: "NULLABLE"}
+	}
+	return fields
+}
+
+// Merge upserts rows by key
+func (b *BigQuery) Merge(ctx context.Context, table, key string, columns []Column, rows []Row) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	req := &bigquery.QueryRequest{
+		Query:           bigQueryMergeSQL(b.cfg.ProjectID, b.cfg.Dataset, table, key, columns),
+		UseLegacySql:    googleapi.Bool(false),
+		ParameterMode:   "NAMED",
+		QueryParameters: []*bigquery.QueryParameter{bigQueryRowsParameter(columns, rows)},
+		Location:        b.cfg.Location,
+	}
+	resp, err := b.svc.Jobs.Query(b.cfg.ProjectID, req).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("failed to merge into BigQuery table %s: %w", table, err)
+	}
+	complete, errs, ref := resp.JobComplete, resp.Errors, resp.JobReference
+	for !complete && len(errs) == 0 {
+		if ref == nil {
+			return fmt.Errorf("BigQuery merge into %s returned no job reference", table)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(b.pollInterval):
+		}
+		res, err := b.svc.Jobs.GetQueryResults(ref.ProjectId, ref.JobId).Location(ref.Location).MaxResults(0).Context(ctx).Do()
+		if err != nil {
+			return fmt.Errorf("failed to poll BigQuery merge into %s: %w", table, err)
+		}
+		complete, errs = res.JobComplete, res.Errors
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("BigQuery merge into %s failed: %s", table, errs[0].Message)
+	}
+	return nil
+}
+
+func bigQueryMergeSQL(project, dataset, table, key string, columns []Column) string {
+	stmt := fmt.Sprintf("MERGE `%s.%s.%s` t USING UNNEST(@rows) s ON t.%s = s.%s", project, dataset, table, key, key)
+	if sets := updateAssignments(columns, key); sets != "" {
+		stmt += " WHEN MATCHED THEN UPDATE SET " + sets
+	}
+	return stmt + fmt.Sprintf(" WHEN NOT MATCHED THEN INSERT (%s) VALUES (%s)", columnNames(columns, ""), columnNames(columns, "s."))
+}
+
+// bigQueryRowsParameter is the @rows ARRAY<STRUCT<...>> parameter
+func bigQueryRowsParameter(columns []Column, rows []Row) *bigquery.QueryParameter {
+	structTypes := make([]*bigquery.QueryParameterTypeStructTypes, len(columns))
+	for i, c := range columns {
+		structTypes[i] = &bigquery.QueryParameterTypeStructTypes{Name: c.Name, Type: &bigquery.QueryParameterType{Type: bigQueryTypes[c.Type]}}
+	}
+	values := make([]*bigquery.QueryParameterValue, len(rows))
+	for r, row := range rows {
+		fields := make(map[string]bigquery.QueryParameterValue, len(columns))
+		for i, c := range columns {
+			fields[c.Name] = bigQueryValue(row[i])
+		}
+		values[r] = &bigquery.QueryParameterValue{StructValues: fields}
+	}
+	return &bigquery.QueryParameter{
+		Name:           "rows",
+		ParameterType:  &bigquery.QueryParameterType{Type: "ARRAY", ArrayType: &bigquery.QueryParameterType{Type: "STRUCT", StructTypes: structTypes}},
+		ParameterValue: &bigquery.QueryParameterValue{ArrayValues: values},
+	}
+}
+
+func bigQueryValue(v any) bigquery.QueryParameterValue {
+	var s string
+	switch v := v.(type) {
+	case nil:
+		return bigquery.QueryParameterValue{NullFields: []string{"Value"}}
+	case string:
+		s = v
+	case int64:
+		s = strconv.FormatInt(v, 10)
+	case float64:
+		s = strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		s = strconv.FormatBool(v)
+	case time.Time:
+		s = v.UTC().Format(bigQueryTimestampLayout)
+	case json.RawMessage:
+		if len(v) == 0 {
+			return bigquery.QueryParameterValue{NullFields: []string{"Value"}}
+		}
+		s = string(v)
+	default:
+		s = fmt.Sprint(v)
+	}
+	// Empty strings are values, not nulls
+	return bigquery.QueryParameterValue{Value: s, ForceSendFields: []string{"Value"}}
+}
@@ -0,0 +1,268 @@
+package warehouse
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// snowflakeTokenTTL is the lifetime of key-pair JWTs; Snowflake accepts at
+// most an hour
+const snowflakeTokenTTL = 59 * time.Minute
+
+var snowflakeTypes = map[ColumnType]string{
+	TypeString:    "VARCHAR",
+	TypeInt:       "NUMBER(38,0)",
+	TypeFloat:     "FLOAT",
+	TypeBool:      "BOOLEAN",
+	TypeTimestamp: "TIMESTAMP_TZ",
+	TypeJSON:      "VARIANT",
+}
+
+// SnowflakeConfig selects the schema synced tables live in. PrivateKey is
+// the PEM key registered as the user's RSA_PUBLIC_KEY; BaseURL overrides
+// https://<account>.snowflakecomputing.com for tests.
+type SnowflakeConfig struct {
+	Account    string
+	User       string
+	PrivateKey string
+	Database   string
+	Schema     string
+	Warehouse  string
+	Role       string
+	BaseURL    string
+}
+
+// Snowflake runs statements through the SQL API. Each batch is bound as one
+// JSON array and merged from FLATTEN, so a batch is one statement.
+type Snowflake struct {
+	cfg          SnowflakeConfig
+	key          *rsa.PrivateKey
+	issuer       string
+	subject      string
+	httpClient   *http.Client
+	pollInterval time.Duration
+
+	mu       sync.Mutex
+	token    string
+	tokenExp time.Time
+}
+
+// NewSnowflake creates a Snowflake destination
+func NewSnowflake(cfg SnowflakeConfig) (*Snowflake, error) {
+	block, _ := pem.Decode([]byte(cfg.PrivateKey))
+	if block == nil {
+		return nil, fmt.Errorf("Snowflake private key is not PEM")
+	}
+	var key *rsa.PrivateKey
+	if parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		rsaKey, ok := parsed.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("Snowflake private key is not an RSA key")
+		}
+		key = rsaKey
+	} else if rsaKey, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		key = rsaKey
+	} else {
+		return nil, fmt.Errorf("failed to parse Snowflake private key: %w", err)
+	}
+	pub, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode Snowflake public key: %w", err)
+	}
+	fingerprint := sha256.Sum256(pub)
+
+	// JWT claims use the account locator without region, upper-cased
+	account := strings.ToUpper(strings.SplitN(cfg.Account, ".", 2)[0])
+	user := strings.ToUpper(cfg.User)
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = "https://" + cfg.Account + ".snowflakecomputing.com"
+	}
+	return &Snowflake{
+		cfg:          cfg,
+		key:          key,
+		issuer:       account + "." + user + ".SHA256:" + base64.StdEncoding.EncodeToString(fingerprint[:]),
+		subject:      account + "." + user,
+		httpClient:   &http.Client{Timeout: 60 * time.Second},
+		pollInterval: time.Second,
+	}, nil
+}
+
+// Name identifies the destination in the sync state
+func (s *Snowflake) Name() string {
+	return "snowflake:" + s.cfg.Account + "." + s.cfg.Database + "." + s.cfg.Schema
+}
+
+// EnsureTable creates the table or adds the columns it lacks
+func (s *Snowflake) EnsureTable(ctx context.Context, table string, columns []Column) error {
+	defs := make([]string, len(columns))
+	for i, c := range columns {
+		defs[i] = c.Name + " " + snowflakeTypes[c.Type]
+	}
+	if err := s.execute(ctx, "CREATE TABLE IF NOT EXISTS "+table+" ("+strings.Join(defs, ", ")+")", nil); err != nil {
+		return fmt.Errorf("failed to create Snowflake table %s: %w", table, err)
+	}
+	for _, def := range defs {
+		if err := s.execute(ctx, "ALTER TABLE "+table+" ADD COLUMN IF NOT EXISTS "+def, nil); err != nil {
+			return fmt.Errorf("failed to add column to Snowflake table %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// Merge upserts rows by key
+func (s *Snowflake) Merge(ctx context.Context, table, key string, columns []Column, rows []Row) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	objects := make([]map[string]any, len(rows))
+	for i, row := range rows {
+		objects[i] = rowObject(columns, row)
+	}
+	batch, err := json.Marshal(objects)
+	if err != nil {
+		return fmt.Errorf("failed to encode Snowflake batch: %w", err)
+	}
+	if err := s.execute(ctx, snowflakeMergeSQL(table, key, columns), map[string]snowflakeBinding{"1": {Type: "TEXT", Value: string(batch)}}); err != nil {
+		return fmt.Errorf("failed to merge into Snowflake table %s: %w", table, err)
+	}
+	return nil
+}
+
+func snowflakeMergeSQL(table, key string, columns []Column) string {
+	selects := make([]string, len(columns))
+	for i, c := range columns {
+		if c.Type == TypeJSON {
+			selects[i] = fmt.Sprintf(`STRIP_NULL_VALUE(f.value:"%s") AS %s`, c.Name, c.Name)
+		} else {
+			selects[i] = fmt.Sprintf(`f.value:"%s"::%s AS %s`, c.Name, snowflakeTypes[c.Type], c.Name)
+		}
+	}
+	stmt := fmt.Sprintf("MERGE INTO %s t USING (SELECT %s FROM TABLE(FLATTEN(INPUT => PARSE_JSON(?))) f) s ON t.%s = s.%s",
+		table, strings.Join(selects, ", "), key, key)
+	if sets := updateAssignments(columns, key); sets != "" {
+		stmt += " WHEN MATCHED THEN UPDATE SET " + sets
+	}
+	return stmt + fmt.Sprintf(" WHEN NOT MATCHED THEN INSERT (%s) VALUES (%s)", columnNames(columns, ""), columnNames(columns, "s."))
+}
+
+type snowflakeBinding struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type snowflakeStatementRequest struct {
+	Statement string                      `json:"statement"`
+	Timeout   int                         `json:"timeout"`
+	Database  string                      `json:"database"`
+	Schema    string                      `json:"schema"`
+	Warehouse string                      `json:"warehouse,omitempty"`
+	Role      string                      `json:"role,omitempty"`
+	Bindings  map[string]snowflakeBinding `json:"bindings,omitempty"`
+}
+
+type snowflakeStatementResponse struct {
+	Message            string `json:"message"`
+	StatementStatusURL string `json:"statementStatusUrl"`
+}
+
+// execute runs a statement and waits for it to finish
+func (s *Snowflake) execute(ctx context.Context, statement string, bindings map[string]snowflakeBinding) error {
+	body, err := json.Marshal(snowflakeStatementRequest{
+		Statement: statement,
+		Timeout:   300,
+		Database:  s.cfg.Database,
+		Schema:    s.cfg.Schema,
+		Warehouse: s.cfg.Warehouse,
+		Role:      s.cfg.Role,
+		Bindings:  bindings,
+	})
+	if err != nil {
+		return err
+	}
+	status, resp, err := s.do(ctx, http.MethodPost, "/api/v2/statements?requestId="+uuid.NewString(), body)
+	for err == nil && status == http.StatusAccepted {
+		if resp.StatementStatusURL == "" {
+			return fmt.Errorf("statement accepted without a status URL")
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(s.pollInterval):
+		}
+		status, resp, err = s.do(ctx, http.MethodGet, resp.StatementStatusURL, nil)
+	}
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("snowflake returned %d: %s", status, resp.Message)
+	}
+	return nil
+}
+
+func (s *Snowflake) do(ctx context.Context, method, path string, body []byte) (int, *snowflakeStatementResponse, error) {
+	token, err := s.bearerToken()
+	if err != nil {
+		return 0, nil, err
+	}
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.cfg.BaseURL+path, reader)
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("X-Snowflake-Authorization-Token-Type", "KEYPAIR_JWT")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("snowflake request failed: %w", err)
+	}
+	defer res.Body.Close()
+	var out snowflakeStatementResponse
+	if err := json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(&out); err != nil && err != io.EOF {
+		return res.StatusCode, nil, fmt.Errorf("failed to decode Snowflake response (%d): %w", res.StatusCode, err)
+	}
+	return res.StatusCode, &out, nil
+}
+
+// bearerToken returns a cached key-pair JWT, renewed ten minutes before it
+// expires
+func (s *Snowflake) bearerToken() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if s.token != "" && now.Add(10*time.Minute).Before(s.tokenExp) {
+		return s.token, nil
+	}
+	exp := now.Add(snowflakeTokenTTL)
+	token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.RegisteredClaims{
+		Issuer:    s.issuer,
+		Subject:   s.subject,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(exp),
+	}).SignedString(s.key)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign Snowflake token: %w", err)
+	}
+	s.token, s.tokenExp = token, exp
+	return token, nil
+}
@@ -0,0 +1,87 @@
+// Package warehouse merges rows into BigQuery and Snowflake tables for the
+// incremental warehouse sync.
+package warehouse
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// ColumnType is a column type both warehouses can store
+type ColumnType string
+
+const (
+	TypeString    ColumnType = "string"
+	TypeInt       ColumnType = "int"
+	TypeFloat     ColumnType = "float"
+	TypeBool      ColumnType = "bool"
+	TypeTimestamp ColumnType = "timestamp"
+	TypeJSON      ColumnType = "json"
+)
+
+// Column is a synced column
+type Column struct {
+	Name string     `json:"name"`
+	Type ColumnType `json:"type"`
+}
+
+// Row holds one value per column: string, int64, float64, bool, time.Time,
+// json.RawMessage for JSON columns, or nil
+type Row []any
+
+// ColumnTypeForPostgres maps an information_schema data_type to the column
+// type it is synced as; arrays and other types are synced as text
+func ColumnTypeForPostgres(dataType string) ColumnType {
+	switch dataType {
+	case "smallint", "integer", "bigint":
+		return TypeInt
+	case "numeric", "real", "double precision":
+		return TypeFloat
+	case "boolean":
+		return TypeBool
+	case "date", "timestamp with time zone", "timestamp without time zone":
+		return TypeTimestamp
+	case "json", "jsonb":
+		return TypeJSON
+	}
+	return TypeString
+}
+
+// rowObject is a row keyed by column name, with timestamps in RFC 3339
+func rowObject(columns []Column, row Row) map[string]any {
+	obj := make(map[string]any, len(columns))
+	for i, c := range columns {
+		switch v := row[i].(type) {
+		case time.Time:
+			obj[c.Name] = v.UTC().Format(time.RFC3339Nano)
+		case json.RawMessage:
+			if len(v) == 0 {
+				obj[c.Name] = nil
+			} else {
+				obj[c.Name] = v
+			}
+		default:
+			obj[c.Name] = v
+		}
+	}
+	return obj
+}
+
+func columnNames(columns []Column, prefix string) string {
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = prefix + c.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+func updateAssignments(columns []Column, key string) string {
+	sets := make([]string, 0, len(columns))
+	for _, c := range columns {
+		if c.Name != key {
+			sets = append(sets, c.Name+" = s."+c.Name)
+		}
+	}
+	return strings.Join(sets, ", ")
+}
@@ -0,0 +1,98 @@
+package warehouse
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testColumns = []Column{
+	{Name: "id", Type: TypeString},
+	{Name: "email", Type: TypeString},
+	{Name: "ltv", Type: TypeFloat},
+	{Name: "created_at", Type: TypeTimestamp},
+	{Name: "metadata", Type: TypeJSON},
+}
+
+var testRow = Row{"u-1", "", 12.5, time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC), nil}
+
+func TestBigQueryRowsParameter_KeepsEmptyStringsAndNulls(t *testing.T) {
+	raw, err := json.Marshal(bigQueryRowsParameter(testColumns, []Row{testRow}))
+	require.NoError(t, err)
+
+	var param struct {
+		ParameterValue struct {
+			ArrayValues []struct {
+				StructValues map[string]map[string]any `json:"structValues"`
+			} `json:"arrayValues"`
+		} `json:"parameterValue"`
+	}
+	require.NoError(t, json.Unmarshal(raw, &param))
+	fields := param.ParameterValue.ArrayValues[0].StructValues
+
+	assert.Equal(t, "", fields["email"]["value"])
+	assert.Contains(t, fields["metadata"], "value")
+	assert.Nil(t, fields["metadata"]["value"])
+	assert.Equal(t, "2026-03-01 10:00:00+00:00", fields["created_at"]["value"])
+}
+
+func TestSnowflakeMerge_BindsBatchAsJSON(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	var got snowflakeStatementRequest
+	var tokenType string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenType = r.Header.Get("X-Snowflake-Authorization-Token-Type")
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"message":"Statement executed successfully."}`))
+	}))
+	defer srv.Close()
+
+	sf, err := NewSnowflake(SnowflakeConfig{Account: "xy12345.us-east-1", User: "sync", PrivateKey: string(pemKey), Database: "ANALYTICS", Schema: "PUBLIC", BaseURL: srv.URL})
+	require.NoError(t, err)
+	require.NoError(t, sf.Merge(context.Background(), "users", "id", testColumns, []Row{testRow}))
+
+	assert.Equal(t, "KEYPAIR_JWT", tokenType)
+	assert.Contains(t, got.Statement, "MERGE INTO users t USING")
+	assert.Contains(t, got.Statement, `STRIP_NULL_VALUE(f.value:"metadata") AS metadata`)
+	assert.NotContains(t, got.Statement, "id = s.id,")
+
+	var batch []map[string]any
+	require.NoError(t, json.Unmarshal([]byte(got.Bindings["1"].Value), &batch))
+	require.Len(t, batch, 1)
+	assert.Equal(t, "2026-03-01T10:00:00Z", batch[0]["created_at"])
+	assert.Nil(t, batch[0]["metadata"])
+}
+
+func TestSnowflakeExecute_ReportsErrors(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, _ = w.Write([]byte(`{"message":"SQL compilation error"}`))
+	}))
+	defer srv.Close()
+
+	sf, err := NewSnowflake(SnowflakeConfig{Account: "acme-prod", User: "sync", PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), Database: "ANALYTICS", Schema: "PUBLIC", BaseURL: srv.URL})
+	require.NoError(t, err)
+
+	err = sf.EnsureTable(context.Background(), "users", testColumns)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "SQL compilation error")
+}
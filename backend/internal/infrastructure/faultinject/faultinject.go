@@ -0,0 +1,298 @@
+// Package faultinject injects latency, connection errors, error statuses and
+// malformed responses into outbound HTTP calls, so retries and fallbacks can
+// be exercised against misbehaving dependencies. It is for non-production
+// environments only; the config refuses to enable it in production.
+package faultinject
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/infrastructure/config"
+)
+
+// Dependencies faults can target
+const (
+	TargetMatomo   = "matomo"
+	TargetCurrency = "currency"
+	TargetApple    = "apple"
+	TargetGoogle   = "google"
+)
+
+var targets = map[string]bool{TargetMatomo: true, TargetCurrency: true, TargetApple: true, TargetGoogle: true}
+
+// ErrInjected is the connection failure returned for an injected error
+var ErrInjected = errors.New("injected connection failure")
+
+// malformedBody is served for an injected malformed response: neither valid
+// JSON nor valid XML
+const malformedBody = `{"status":0,"injected":<`
+
+// Fault describes how one dependency misbehaves. Each request first waits
+// Latency, then fails with ErrorRate probability, is answered with Status
+// with StatusRate probability or with a malformed 200 with MalformedRate
+// probability, and otherwise reaches the dependency.
+type Fault struct {
+	Latency       time.Duration
+	ErrorRate     float64
+	Status        int
+	StatusRate    float64
+	MalformedRate float64
+}
+
+// Scenario maps targets to their fault
+type Scenario map[string]Fault
+
+// ParseScenario parses a semicolon-separated list of target:key=value,...
+// faults, for example
+//
+//	matomo:error=0.5,latency=2s;currency:malformed=1;apple:status=503,status_rate=0.3
+//
+// Keys are latency (a Go duration), error, status_rate and malformed (rates
+// between 0 and 1) and status (an HTTP status, 503 by default). Setting status
+// alone answers every request with it.
+func ParseScenario(spec string) (Scenario, error) {
+	scenario := Scenario{}
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		target, settings, ok := strings.Cut(entry, ":")
+		target = strings.TrimSpace(target)
+		if !ok || !targets[target] {
+			return nil, fmt.Errorf("unknown fault target in %q", entry)
+		}
+		if _, dup := scenario[target]; dup {
+			return nil, fmt.Errorf("fault target %s is listed twice", target)
+		}
+		fault, err := parseFault(settings)
+		if err != nil {
+			return nil, fmt.Errorf("fault target %s: %w", target, err)
+		}
+		scenario[target] = fault
+	}
+	return scenario, nil
+}
+
+func parseFault(settings string) (Fault, error) {
+	var fault Fault
+	statusRateSet := false
+	for _, setting := range strings.Split(settings, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(setting), "=")
+		if !ok {
+			return Fault{}, fmt.Errorf("expected key=value, got %q", setting)
+		}
+		var err error
+		switch key {
+		case "latency":
+			fault.Latency, err = time.ParseDuration(value)
+			if err == nil && fault.Latency < 0 {
+				err = errors.New("must not be negative")
+			}
+		case "error":
+			fault.ErrorRate, err = parseRate(value)
+		case "status":
+			fault.Status, err = strconv.Atoi(value)
+			if err == nil && (fault.Status < 100 || fault.Status > 599) {
+				err = errors.New("must be an HTTP status")
+			}
+		case "status_rate":
+			fault.StatusRate, err = parseRate(value)
+			statusRateSet = true
+		case "malformed":
+			fault.MalformedRate, err = parseRate(value)
+		default:
+			return Fault{}, fmt.Errorf("unknown fault %q", key)
+		}
+		if err != nil {
+			return Fault{}, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	if fault.Status != 0 && !statusRateSet {
+		fault.StatusRate = 1
+	}
+	if fault.StatusRate > 0 && fault.Status == 0 {
+		fault.Status = http.StatusServiceUnavailable
+	}
+	if fault.ErrorRate+fault.StatusRate+fault.MalformedRate > 1 {
+		return Fault{}, errors.New("error, status_rate and malformed add up to more than 1")
+	}
+	return fault, nil
+}
+
+func parseRate(value string) (float64, error) {
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil || rate < 0 || rate > 1 {
+		return 0, fmt.Errorf("%q is not a rate between 0 and 1", value)
+	}
+	return rate, nil
+}
+
+// String lists the scenario in ParseScenario syntax, targets sorted
+func (s Scenario) String() string {
+	names := make([]string, 0, len(s))
+	for target := range s {
+		names = append(names, target)
+	}
+	sort.Strings(names)
+	parts := make([]string, 0, len(names))
+	for _, target := range names {
+		f := s[target]
+		settings := []string{}
+		if f.Latency > 0 {
+			settings = append(settings, "latency="+f.Latency.String())
+		}
+		if f.ErrorRate > 0 {
+			settings = append(settings, "error="+strconv.FormatFloat(f.ErrorRate, 'g', -1, 64))
+		}
+		if f.StatusRate > 0 {
+			settings = append(settings, "status="+strconv.Itoa(f.Status), "status_rate="+strconv.FormatFloat(f.StatusRate, 'g', -1, 64))
+		}
+		if f.MalformedRate > 0 {
+			settings = append(settings, "malformed="+strconv.FormatFloat(f.MalformedRate, 'g', -1, 64))
+		}
+		parts = append(parts, target+":"+strings.Join(settings, ","))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Injector hands out transports that apply a scenario's faults. A nil
+// Injector injects nothing.
+type Injector struct {
+	scenario Scenario
+	logger   *zap.Logger
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// New returns an injector for scenario. A non-zero seed makes the injected
+// faults reproducible.
+func New(scenario Scenario, seed int64, logger *zap.Logger) *Injector {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &Injector{scenario: scenario, logger: logger, rng: rand.New(rand.NewSource(seed))}
+}
+
+// NewFromConfig returns the injector cfg enables, nil when it is disabled
+func NewFromConfig(cfg config.FaultConfig, logger *zap.Logger) (*Injector, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	scenario, err := ParseScenario(cfg.Scenario)
+	if err != nil {
+		return nil, err
+	}
+	return New(scenario, cfg.Seed, logger), nil
+}
+
+// Scenario returns the faults the injector applies
+func (i *Injector) Scenario() Scenario {
+	if i == nil {
+		return nil
+	}
+	return i.scenario
+}
+
+// Transport wraps base, http.DefaultTransport when nil, with the faults of
+// target. Without a fault for target, or on a nil Injector, base is returned
+// as is, so clients keep their default transport.
+func (i *Injector) Transport(target string, base http.RoundTripper) http.RoundTripper {
+	if i == nil {
+		return base
+	}
+	fault, ok := i.scenario[target]
+	if !ok {
+		return base
+	}
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{injector: i, target: target, fault: fault, base: base}
+}
+
+func (i *Injector) roll() float64 {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.rng.Float64()
+}
+
+type transport struct {
+	injector *Injector
+	target   string
+	fault    Fault
+	base     http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.fault.Latency > 0 {
+		timer := time.NewTimer(t.fault.Latency)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			closeBody(req)
+			return nil, req.Context().Err()
+		}
+	}
+
+	roll := t.injector.roll()
+	switch {
+	case roll < t.fault.ErrorRate:
+		t.log(req, "error")
+		closeBody(req)
+		return nil, fmt.Errorf("%s: %w", t.target, ErrInjected)
+	case roll < t.fault.ErrorRate+t.fault.StatusRate:
+		t.log(req, "status")
+		closeBody(req)
+		return response(req, t.fault.Status, fmt.Sprintf(`{"error":"injected %d from %s"}`, t.fault.Status, t.target)), nil
+	case roll < t.fault.ErrorRate+t.fault.StatusRate+t.fault.MalformedRate:
+		t.log(req, "malformed")
+		closeBody(req)
+		return response(req, http.StatusOK, malformedBody), nil
+	}
+	return t.base.RoundTrip(req)
+}
+
+func (t *transport) log(req *http.Request, fault string) {
+	t.injector.logger.Debug("Injected fault",
+		zap.String("target", t.target),
+		zap.String("fault", fault),
+		zap.String("host", req.URL.Host),
+	)
+}
+
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+}
+
+func response(req *http.Request, status int, body string) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
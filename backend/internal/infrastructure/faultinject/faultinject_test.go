@@ -0,0 +1,147 @@
+package faultinject
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bivex/paywall-iap/internal/infrastructure/config"
+)
+
+func TestParseScenario(t *testing.T) {
+	scenario, err := ParseScenario(" matomo:error=0.5,latency=2s; currency:malformed=1 ;apple:status=502")
+	require.NoError(t, err)
+	assert.Equal(t, Fault{Latency: 2 * time.Second, ErrorRate: 0.5}, scenario[TargetMatomo])
+	assert.Equal(t, Fault{MalformedRate: 1}, scenario[TargetCurrency])
+	assert.Equal(t, Fault{Status: 502, StatusRate: 1}, scenario[TargetApple])
+	assert.Equal(t, "apple:status=502,status_rate=1;currency:malformed=1;matomo:latency=2s,error=0.5", scenario.String())
+
+	scenario, err = ParseScenario("google:status_rate=0.25")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, scenario[TargetGoogle].Status)
+
+	empty, err := ParseScenario("")
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+
+	for _, spec := range []string{
+		"stripe:error=1",
+		"matomo",
+		"matomo:error=2",
+		"matomo:latency=-1s",
+		"matomo:timeout=1s",
+		"matomo:status=99",
+		"matomo:error=0.6,malformed=0.6",
+		"matomo:error=1;matomo:malformed=1",
+	} {
+		_, err := ParseScenario(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func newUpstream(t *testing.T, hits *atomic.Int32) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func get(t *testing.T, rt http.RoundTripper, ctx context.Context, url string) (*http.Response, error) {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	require.NoError(t, err)
+	return (&http.Client{Transport: rt}).Do(req)
+}
+
+func TestInjector_Transport(t *testing.T) {
+	var hits atomic.Int32
+	upstream := newUpstream(t, &hits)
+
+	faults := New(Scenario{
+		TargetMatomo:   {ErrorRate: 1},
+		TargetCurrency: {Status: http.StatusBadGateway, StatusRate: 1},
+		TargetApple:    {MalformedRate: 1},
+	}, 1, nil)
+
+	_, err := get(t, faults.Transport(TargetMatomo, nil), context.Background(), upstream.URL)
+	assert.ErrorIs(t, err, ErrInjected)
+
+	resp, err := get(t, faults.Transport(TargetCurrency, nil), context.Background(), upstream.URL)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	_ = resp.Body.Close()
+
+	resp, err = get(t, faults.Transport(TargetApple, nil), context.Background(), upstream.URL)
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Error(t, json.Unmarshal(body, &map[string]interface{}{}))
+	assert.Zero(t, hits.Load(), "injected faults never reach the dependency")
+
+	// Targets without a fault, and a nil injector, keep the base transport
+	base := http.DefaultTransport
+	assert.Equal(t, base, faults.Transport(TargetGoogle, base))
+	var disabled *Injector
+	assert.Nil(t, disabled.Transport(TargetMatomo, nil))
+}
+
+func TestInjector_PartialFaultsAreReproducible(t *testing.T) {
+	var hits atomic.Int32
+	upstream := newUpstream(t, &hits)
+
+	outcomes := func() []bool {
+		rt := New(Scenario{TargetMatomo: {ErrorRate: 0.5}}, 42, nil).Transport(TargetMatomo, nil)
+		failed := make([]bool, 40)
+		for i := range failed {
+			resp, err := get(t, rt, context.Background(), upstream.URL)
+			failed[i] = errors.Is(err, ErrInjected)
+			if err == nil {
+				_ = resp.Body.Close()
+			}
+		}
+		return failed
+	}
+
+	first := outcomes()
+	assert.Equal(t, first, outcomes())
+	assert.Contains(t, first, true)
+	assert.Contains(t, first, false)
+}
+
+func TestInjector_LatencyHonoursDeadline(t *testing.T) {
+	var hits atomic.Int32
+	upstream := newUpstream(t, &hits)
+	rt := New(Scenario{TargetCurrency: {Latency: time.Minute}}, 1, nil).Transport(TargetCurrency, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := get(t, rt, ctx, upstream.URL)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Zero(t, hits.Load())
+}
+
+func TestNewFromConfig(t *testing.T) {
+	faults, err := NewFromConfig(config.FaultConfig{Scenario: "matomo:error=1"}, nil)
+	require.NoError(t, err)
+	assert.Nil(t, faults, "disabled")
+
+	faults, err = NewFromConfig(config.FaultConfig{Enabled: true, Scenario: "matomo:error=1", Seed: 7}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, faults.Scenario()[TargetMatomo].ErrorRate)
+
+	_, err = NewFromConfig(config.FaultConfig{Enabled: true, Scenario: "nope:error=1"}, nil)
+	assert.Error(t, err)
+}
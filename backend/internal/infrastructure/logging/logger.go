@@ -46,7 +46,10 @@ func Init(cfg *config.SentryConfig) error {
 			Dsn:              cfg.DSN,
 			Environment:      cfg.Environment,
 			Release:          cfg.Release,
-			TracesSampleRate: 0.1,
+			SampleRate:       cfg.SampleRate,
+			EnableTracing:    cfg.TracesSampleRate > 0,
+			TracesSampleRate: cfg.TracesSampleRate,
+			BeforeSend:       redactSentryEvent,
 		}); err != nil {
 			Logger.Warn("Sentry init failed", zap.Error(err))
 		} else {
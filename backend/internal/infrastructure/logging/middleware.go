@@ -1,49 +1,280 @@
 package logging
 
 import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/bivex/paywall-iap/internal/infrastructure/config"
 )
 
-// RequestMiddleware creates a middleware that logs HTTP requests
-func RequestMiddleware(logger *zap.Logger) gin.HandlerFunc {
+// RequestLogSettings are the request logging controls that can be changed
+// while the server runs. RouteLevels maps a route pattern (as registered,
+// e.g. /v1/admin/users/:id) to the minimum level logged for it.
+type RequestLogSettings struct {
+	SampleRate           float64
+	SlowRequestThreshold time.Duration
+	RouteLevels          map[string]zapcore.Level
+}
+
+// Validate checks the settings are usable
+func (s RequestLogSettings) Validate() error {
+	if s.SampleRate < 0 || s.SampleRate > 1 {
+		return fmt.Errorf("sample rate must be between 0 and 1")
+	}
+	if s.SlowRequestThreshold < 0 {
+		return fmt.Errorf("slow request threshold must not be negative")
+	}
+	return nil
+}
+
+// ParseRouteLevels parses a comma-separated list of route=level pairs
+func ParseRouteLevels(spec string) (map[string]zapcore.Level, error) {
+	levels := map[string]zapcore.Level{}
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		route, levelName, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(route) == "" {
+			return nil, fmt.Errorf("invalid route level %q: want route=level", pair)
+		}
+		level, err := zapcore.ParseLevel(strings.TrimSpace(levelName))
+		if err != nil {
+			return nil, fmt.Errorf("invalid route level %q: %w", pair, err)
+		}
+		levels[strings.TrimSpace(route)] = level
+	}
+	return levels, nil
+}
+
+// RequestLogger logs one entry per HTTP request. Failed requests are logged
+// with their redacted request and response bodies, slow requests are always
+// logged, and the remaining successful requests are sampled.
+type RequestLogger struct {
+	logger       *zap.Logger
+	bodyMaxBytes int
+	sample       func() float64
+
+	mu       sync.RWMutex
+	settings RequestLogSettings
+}
+
+// NewRequestLogger creates a request logger from the logging configuration
+func NewRequestLogger(logger *zap.Logger, cfg config.LoggingConfig) (*RequestLogger, error) {
+	routeLevels, err := ParseRouteLevels(cfg.RouteLevels)
+	if err != nil {
+		return nil, err
+	}
+	settings := RequestLogSettings{
+		SampleRate:           cfg.SampleRate,
+		SlowRequestThreshold: cfg.SlowRequestThreshold,
+		RouteLevels:          routeLevels,
+	}
+	if err := settings.Validate(); err != nil {
+		return nil, err
+	}
+	return &RequestLogger{
+		logger:       logger,
+		bodyMaxBytes: cfg.BodyMaxBytes,
+		sample:       rand.Float64,
+		settings:     settings,
+	}, nil
+}
+
+// Settings returns a copy of the current settings
+func (l *RequestLogger) Settings() RequestLogSettings {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	out := l.settings
+	out.RouteLevels = make(map[string]zapcore.Level, len(l.settings.RouteLevels))
+	for route, level := range l.settings.RouteLevels {
+		out.RouteLevels[route] = level
+	}
+	return out
+}
+
+// Update replaces the settings. Changes apply to this process only.
+func (l *RequestLogger) Update(settings RequestLogSettings) error {
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+	if settings.RouteLevels == nil {
+		settings.RouteLevels = map[string]zapcore.Level{}
+	}
+	l.mu.Lock()
+	l.settings = settings
+	l.mu.Unlock()
+	return nil
+}
+
+// Middleware returns the Gin middleware
+func (l *RequestLogger) Middleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
 		path := c.Request.URL.Path
-		query := c.Request.URL.RawQuery
 
 		// Generate request ID
 		requestID := uuid.New().String()
 		c.Set("request_id", requestID)
 
 		// Create request logger
-		requestLogger := logger.With(zap.String("request_id", requestID))
+		requestLogger := l.logger.With(zap.String("request_id", requestID))
 		c.Set("logger", requestLogger)
 
-		// Log request
-		requestLogger.Debug("incoming request",
-			zap.String("method", c.Request.Method),
-			zap.String("path", path),
-			zap.String("query", query),
-			zap.String("client_ip", c.ClientIP()),
-		)
+		requestBody := l.captureRequestBody(c)
+		responseBody := &cappedBuffer{limit: l.bodyMaxBytes}
+		if l.bodyMaxBytes > 0 {
+			c.Writer = &bodyLogWriter{ResponseWriter: c.Writer, body: responseBody}
+		}
 
 		// Process request
 		c.Next()
 
-		// Log response
 		latency := time.Since(start)
 		statusCode := c.Writer.Status()
-		requestLogger.Info("request completed",
+		route := c.FullPath()
+		if route == "" {
+			route = path
+		}
+
+		settings := l.Settings()
+		slow := settings.SlowRequestThreshold > 0 && latency >= settings.SlowRequestThreshold
+		level := zapcore.InfoLevel
+		switch {
+		case statusCode >= 500:
+			level = zapcore.ErrorLevel
+		case statusCode >= 400 || slow:
+			level = zapcore.WarnLevel
+		}
+		if min, ok := settings.RouteLevels[route]; ok && level < min {
+			return
+		}
+		if level == zapcore.InfoLevel && settings.SampleRate < 1 && l.sample() >= settings.SampleRate {
+			return
+		}
+
+		fields := []zap.Field{
 			zap.String("method", c.Request.Method),
 			zap.String("path", path),
+			zap.String("route", route),
 			zap.Int("status", statusCode),
 			zap.Duration("latency", latency),
-		)
+			zap.String("client_ip", c.ClientIP()),
+		}
+		if query := c.Request.URL.Query(); len(query) > 0 {
+			fields = append(fields, zap.String("query", RedactQuery(query)))
+		}
+		if slow {
+			fields = append(fields, zap.Bool("slow", true))
+		}
+		if statusCode >= 400 {
+			fields = append(fields,
+				zap.String("request_body", redactCaptured(requestBody, c.ContentType())),
+				zap.String("response_body", redactCaptured(responseBody, c.Writer.Header().Get("Content-Type"))),
+			)
+		}
+		if len(c.Errors) > 0 {
+			fields = append(fields, zap.String("errors", RedactString(c.Errors.String())))
+		}
+
+		if ce := requestLogger.Check(level, "request completed"); ce != nil {
+			ce.Write(fields...)
+		}
+	}
+}
+
+// RequestMiddleware creates a middleware that logs every HTTP request with
+// the default settings
+func RequestMiddleware(logger *zap.Logger) gin.HandlerFunc {
+	requestLogger, err := NewRequestLogger(logger, config.LoggingConfig{SampleRate: 1, BodyMaxBytes: 4096})
+	if err != nil {
+		panic(err)
+	}
+	return requestLogger.Middleware()
+}
+
+// captureRequestBody keeps the first bodyMaxBytes of the request body for
+// logging. The body handed to handlers is unchanged, so webhook signature
+// checks still see the raw bytes.
+func (l *RequestLogger) captureRequestBody(c *gin.Context) *cappedBuffer {
+	captured := &cappedBuffer{limit: l.bodyMaxBytes}
+	if l.bodyMaxBytes <= 0 || c.Request.Body == nil || c.Request.Body == http.NoBody {
+		return captured
+	}
+	prefix, err := io.ReadAll(io.LimitReader(c.Request.Body, int64(l.bodyMaxBytes)+1))
+	_, _ = captured.Write(prefix)
+	c.Request.Body = &replayBody{Reader: io.MultiReader(bytes.NewReader(prefix), errReader{err}, c.Request.Body), Closer: c.Request.Body}
+	return captured
+}
+
+func redactCaptured(b *cappedBuffer, contentType string) string {
+	out := RedactBody(b.buf.Bytes(), contentType)
+	if b.truncated {
+		out += "…(truncated)"
+	}
+	return out
+}
+
+// cappedBuffer keeps at most limit bytes and notes whether more were written
+type cappedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	room := b.limit - b.buf.Len()
+	if room < len(p) {
+		b.truncated = true
+		if room > 0 {
+			b.buf.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+type bodyLogWriter struct {
+	gin.ResponseWriter
+	body *cappedBuffer
+}
+
+func (w *bodyLogWriter) Write(p []byte) (int, error) {
+	_, _ = w.body.Write(p)
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *bodyLogWriter) WriteString(s string) (int, error) {
+	_, _ = w.body.Write([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+type replayBody struct {
+	io.Reader
+	io.Closer
+}
+
+// errReader surfaces a read error hit while capturing the body to the
+// handler instead of silently truncating it
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
 	}
+	return 0, io.EOF
 }
 
 // GetLogger retrieves the logger from the Gin context
@@ -0,0 +1,82 @@
+package logging
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/bivex/paywall-iap/internal/infrastructure/config"
+)
+
+func newTestRequestLogger(t *testing.T, cfg config.LoggingConfig) (*RequestLogger, *observer.ObservedLogs, *gin.Engine) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	core, logs := observer.New(zapcore.DebugLevel)
+	requestLogger, err := NewRequestLogger(zap.New(core), cfg)
+	require.NoError(t, err)
+
+	r := gin.New()
+	r.Use(requestLogger.Middleware())
+	r.POST("/login", func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "bad password for " + string(body)})
+	})
+	r.GET("/ok", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return requestLogger, logs, r
+}
+
+func TestRequestLogger_FailedRequestLogsRedactedBodies(t *testing.T) {
+	_, logs, r := newTestRequestLogger(t, config.LoggingConfig{SampleRate: 1, BodyMaxBytes: 1024})
+
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"email":"jane@example.com","password":"hunter2"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	// The handler still sees the full body
+	assert.Contains(t, w.Body.String(), "hunter2")
+
+	entries := logs.All()
+	require.Len(t, entries, 1)
+	assert.Equal(t, zapcore.WarnLevel, entries[0].Level)
+	fields := entries[0].ContextMap()
+	assert.Equal(t, "/login", fields["route"])
+	assert.NotContains(t, fields["request_body"], "hunter2")
+	assert.NotContains(t, fields["request_body"], "jane@example.com")
+	assert.NotContains(t, fields["response_body"], "jane@example.com")
+}
+
+func TestRequestLogger_SamplingAndRouteLevels(t *testing.T) {
+	requestLogger, logs, r := newTestRequestLogger(t, config.LoggingConfig{SampleRate: 0.5, RouteLevels: "/login=error"})
+	requestLogger.sample = func() float64 { return 0.7 }
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ok", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/login", strings.NewReader("x")))
+	assert.Zero(t, logs.Len(), "sampled-out success and warn below the route minimum are dropped")
+
+	require.NoError(t, requestLogger.Update(RequestLogSettings{SampleRate: 0.5, SlowRequestThreshold: time.Nanosecond}))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ok", nil))
+	require.Equal(t, 1, logs.Len(), "slow requests bypass sampling")
+	assert.Equal(t, true, logs.All()[0].ContextMap()["slow"])
+}
+
+func TestParseRouteLevels(t *testing.T) {
+	levels, err := ParseRouteLevels(" /health=warn, /v1/users/:id=error ,")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]zapcore.Level{"/health": zapcore.WarnLevel, "/v1/users/:id": zapcore.ErrorLevel}, levels)
+
+	_, err = ParseRouteLevels("/health")
+	assert.Error(t, err)
+	_, err = ParseRouteLevels("/health=loud")
+	assert.Error(t, err)
+}
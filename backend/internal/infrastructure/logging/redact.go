@@ -0,0 +1,126 @@
+package logging
+
+import (
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are field names whose values are never logged, compared
+// lowercased with '_' and '-' removed
+var sensitiveKeys = map[string]bool{
+	"email":                 true,
+	"password":              true,
+	"newpassword":           true,
+	"currentpassword":       true,
+	"authorization":         true,
+	"cookie":                true,
+	"receipt":               true,
+	"receiptdata":           true,
+	"latestreceipt":         true,
+	"purchasetoken":         true,
+	"signedpayload":         true,
+	"signedtransactioninfo": true,
+	"signedrenewalinfo":     true,
+	"signature":             true,
+	"apikey":                true,
+	"cardnumber":            true,
+}
+
+// sensitiveKeyParts catch the many spellings of tokens and secrets
+// (access_token, refreshToken, client_secret, webhook_secret, ...)
+var sensitiveKeyParts = []string{"token", "secret", "password"}
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	jwtPattern   = regexp.MustCompile(`eyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]*`)
+	// Receipts, purchase tokens and other opaque credentials are long
+	// base64 or base64url runs
+	blobPattern = regexp.MustCompile(`[A-Za-z0-9+/_\-]{80,}={0,2}`)
+)
+
+func isSensitiveKey(key string) bool {
+	normalized := strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(key))
+	if sensitiveKeys[normalized] {
+		return true
+	}
+	for _, part := range sensitiveKeyParts {
+		if strings.Contains(normalized, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactString masks emails, JWTs and receipt-like blobs in free text
+func RedactString(s string) string {
+	s = jwtPattern.ReplaceAllString(s, redacted)
+	s = emailPattern.ReplaceAllString(s, redacted)
+	return blobPattern.ReplaceAllString(s, redacted)
+}
+
+// RedactBody returns a body safe to log. JSON and form bodies have the values
+// of sensitive fields masked; anything else falls back to RedactString.
+func RedactBody(body []byte, contentType string) string {
+	if len(body) == 0 {
+		return ""
+	}
+	contentType = strings.ToLower(contentType)
+
+	if strings.Contains(contentType, "json") || json.Valid(body) {
+		var v interface{}
+		if err := json.Unmarshal(body, &v); err == nil {
+			if out, err := json.Marshal(redactValue(v)); err == nil {
+				return string(out)
+			}
+		}
+	}
+	if strings.Contains(contentType, "x-www-form-urlencoded") {
+		if values, err := url.ParseQuery(string(body)); err == nil {
+			return RedactQuery(values)
+		}
+	}
+	return RedactString(string(body))
+}
+
+// RedactQuery encodes query or form values with sensitive ones masked
+func RedactQuery(values url.Values) string {
+	out := make(url.Values, len(values))
+	for key, vals := range values {
+		for _, val := range vals {
+			if isSensitiveKey(key) {
+				val = redacted
+			} else {
+				val = RedactString(val)
+			}
+			out.Add(key, val)
+		}
+	}
+	return out.Encode()
+}
+
+func redactValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for key, child := range val {
+			if isSensitiveKey(key) {
+				val[key] = redacted
+				continue
+			}
+			val[key] = redactValue(child)
+		}
+		return val
+	case []interface{}:
+		for i, child := range val {
+			val[i] = redactValue(child)
+		}
+		return val
+	case string:
+		return RedactString(val)
+	default:
+		return v
+	}
+}
@@ -0,0 +1,38 @@
+package logging
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactBody_JSON(t *testing.T) {
+	body := `{"email":"jane@example.com","receipt_data":"MIIT","refreshToken":"abc","platform":"ios","note":"contact jane@example.com","items":[{"purchase_token":"xyz","sku":"pro"}]}`
+
+	out := RedactBody([]byte(body), "application/json")
+
+	assert.NotContains(t, out, "jane@example.com")
+	assert.NotContains(t, out, "MIIT")
+	assert.NotContains(t, out, `"abc"`)
+	assert.NotContains(t, out, "xyz")
+	assert.Contains(t, out, `"platform":"ios"`)
+	assert.Contains(t, out, `"sku":"pro"`)
+}
+
+func TestRedactBody_FormAndText(t *testing.T) {
+	form := RedactBody([]byte("grant_type=client_credentials&client_secret=s3cret"), "application/x-www-form-urlencoded")
+	assert.Contains(t, form, "grant_type=client_credentials")
+	assert.NotContains(t, form, "s3cret")
+
+	text := RedactBody([]byte("bearer eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiIxIn0.sig for "+strings.Repeat("A", 120)), "text/plain")
+	assert.NotContains(t, text, "eyJ")
+	assert.NotContains(t, text, strings.Repeat("A", 120))
+}
+
+func TestRedactQuery(t *testing.T) {
+	out := RedactQuery(url.Values{"token": {"abc"}, "limit": {"10"}})
+	assert.Contains(t, out, "limit=10")
+	assert.NotContains(t, out, "abc")
+}
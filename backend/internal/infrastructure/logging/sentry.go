@@ -0,0 +1,127 @@
+package logging
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/gin-gonic/gin"
+)
+
+// sentryFlushTimeout bounds how long a panicking request waits for its
+// event to be sent before the panic continues
+const sentryFlushTimeout = 2 * time.Second
+
+// SentryMiddleware traces each HTTP request as a Sentry transaction named
+// after its route and reports panics and 5xx responses with the request's
+// user, app and experiment attached. It is a no-op unless Sentry is
+// initialized. Register it after gin.Recovery so panics reach it first.
+func SentryMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if sentry.CurrentHub().Client() == nil {
+			c.Next()
+			return
+		}
+
+		hub := sentry.CurrentHub().Clone()
+		hub.Scope().SetRequest(c.Request)
+		ctx := sentry.SetHubOnContext(c.Request.Context(), hub)
+
+		route := c.FullPath()
+		source := sentry.SourceRoute
+		if route == "" {
+			route = c.Request.URL.Path
+			source = sentry.SourceURL
+		}
+		transaction := sentry.StartTransaction(ctx, c.Request.Method+" "+route,
+			sentry.WithOpName("http.server"),
+			sentry.WithTransactionSource(source),
+			sentry.ContinueFromHeaders(c.GetHeader(sentry.SentryTraceHeader), c.GetHeader(sentry.SentryBaggageHeader)),
+		)
+		c.Request = c.Request.WithContext(transaction.Context())
+
+		defer func() {
+			// Identity is known only once the auth middleware has run
+			enrichSentryScope(hub.Scope(), c)
+			if recovered := recover(); recovered != nil {
+				transaction.Status = sentry.SpanStatusInternalError
+				transaction.Finish()
+				hub.RecoverWithContext(c.Request.Context(), recovered)
+				hub.Flush(sentryFlushTimeout)
+				panic(recovered)
+			}
+
+			status := c.Writer.Status()
+			transaction.Status = sentry.HTTPtoSpanStatus(status)
+			transaction.SetData("http.response.status_code", status)
+			transaction.Finish()
+			if status >= http.StatusInternalServerError {
+				hub.CaptureMessage(sentryMessage(c, status))
+			}
+		}()
+
+		c.Next()
+	}
+}
+
+func enrichSentryScope(scope *sentry.Scope, c *gin.Context) {
+	if requestID := c.GetString("request_id"); requestID != "" {
+		scope.SetTag("request_id", requestID)
+	}
+	if userID := c.GetString("user_id"); userID != "" {
+		scope.SetUser(sentry.User{ID: userID})
+		scope.SetTag("user_id", userID)
+	}
+	if appID := c.GetString("app_id"); appID != "" {
+		scope.SetTag("app_id", appID)
+	} else if appID := c.GetHeader("X-App-ID"); appID != "" {
+		scope.SetTag("app_id", appID)
+	}
+	if experimentID := sentryExperimentID(c); experimentID != "" {
+		scope.SetTag("experiment_id", experimentID)
+	}
+}
+
+// sentryExperimentID finds the experiment a request is about, from the
+// experiments/:id path segment or an experiment_id query parameter
+func sentryExperimentID(c *gin.Context) string {
+	if strings.Contains(c.FullPath(), "/experiments/:id") {
+		return c.Param("id")
+	}
+	if id := c.Param("experiment_id"); id != "" {
+		return id
+	}
+	return c.Query("experiment_id")
+}
+
+func sentryMessage(c *gin.Context, status int) string {
+	msg := fmt.Sprintf("%s %s returned %d", c.Request.Method, c.FullPath(), status)
+	if len(c.Errors) > 0 {
+		msg += ": " + c.Errors.String()
+	}
+	return msg
+}
+
+// redactSentryEvent masks emails, tokens and receipts in event messages,
+// exception values and breadcrumbs before they leave the process
+func redactSentryEvent(event *sentry.Event, _ *sentry.EventHint) *sentry.Event {
+	event.Message = RedactString(event.Message)
+	for i := range event.Exception {
+		event.Exception[i].Value = RedactString(event.Exception[i].Value)
+	}
+	for _, breadcrumb := range event.Breadcrumbs {
+		breadcrumb.Message = RedactString(breadcrumb.Message)
+	}
+	if event.Request != nil {
+		if values, err := url.ParseQuery(event.Request.QueryString); err == nil {
+			event.Request.QueryString = RedactQuery(values)
+		} else {
+			event.Request.QueryString = RedactString(event.Request.QueryString)
+		}
+		event.Request.Data = RedactBody([]byte(event.Request.Data), "")
+	}
+	return event
+}
@@ -0,0 +1,51 @@
+package logging
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSentryMiddleware_Captures5xxWithRequestContext(t *testing.T) {
+	transport := &sentry.MockTransport{}
+	require.NoError(t, sentry.Init(sentry.ClientOptions{
+		Dsn:        "https://public@sentry.example.com/1",
+		Transport:  transport,
+		BeforeSend: redactSentryEvent,
+	}))
+	t.Cleanup(func() { sentry.CurrentHub().BindClient(nil) })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(SentryMiddleware())
+	r.GET("/v1/bandit/experiments/:id/stats", func(c *gin.Context) {
+		c.Set("user_id", "u-1")
+		_ = c.Error(assert.AnError)
+		c.Status(http.StatusInternalServerError)
+	})
+	r.GET("/ok", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ok", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/bandit/experiments/e-1/stats?token=abc", nil))
+
+	events := transport.Events()
+	require.Len(t, events, 1)
+	assert.Equal(t, "u-1", events[0].User.ID)
+	assert.Equal(t, "e-1", events[0].Tags["experiment_id"])
+	assert.NotContains(t, events[0].Request.QueryString, "abc")
+}
+
+func TestRedactSentryEvent(t *testing.T) {
+	event := &sentry.Event{
+		Message:   "lookup failed for jane@example.com",
+		Exception: []sentry.Exception{{Value: "bad token eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiIxIn0.sig"}},
+	}
+	out := redactSentryEvent(event, nil)
+	assert.NotContains(t, out.Message, "jane@example.com")
+	assert.NotContains(t, out.Exception[0].Value, "eyJ")
+}
@@ -0,0 +1,305 @@
+// Package metrics is a minimal in-process metrics registry exposed in the
+// Prometheus text exposition format at /metrics.
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	registryMu sync.Mutex
+	registry   = map[string]collector{}
+)
+
+// collector is a registered metric family
+type collector interface {
+	write(b *strings.Builder)
+}
+
+// CounterVec is a monotonically increasing counter partitioned by labels
+type CounterVec struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	values map[string]float64 // joined label values -> count
+}
+
+// NewCounterVec creates and registers a counter. Registering the same name
+// twice returns the existing counter.
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if existing, ok := registry[name].(*CounterVec); ok {
+		return existing
+	}
+	c := &CounterVec{name: name, help: help, labels: labels, values: map[string]float64{}}
+	registry[name] = c
+	return c
+}
+
+// Inc adds one to the series identified by labelValues
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds delta to the series identified by labelValues
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	key := seriesKey(c.name, c.labels, labelValues)
+	c.mu.Lock()
+	c.values[key] += delta
+	c.mu.Unlock()
+}
+
+// Value returns the current value of a series
+func (c *CounterVec) Value(labelValues ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[strings.Join(labelValues, "\xff")]
+}
+
+func (c *CounterVec) write(b *strings.Builder) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+	keys := make([]string, 0, len(c.values))
+	for k := range c.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		b.WriteString(c.name + labelPairs(c.labels, k))
+		fmt.Fprintf(b, " %g\n", c.values[k])
+	}
+}
+
+// GaugeVec is a value that can go up and down, partitioned by labels
+type GaugeVec struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// NewGaugeVec creates and registers a gauge. Registering the same name twice
+// returns the existing gauge.
+func NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if existing, ok := registry[name].(*GaugeVec); ok {
+		return existing
+	}
+	g := &GaugeVec{name: name, help: help, labels: labels, values: map[string]float64{}}
+	registry[name] = g
+	return g
+}
+
+// Set sets the series identified by labelValues to value
+func (g *GaugeVec) Set(value float64, labelValues ...string) {
+	key := seriesKey(g.name, g.labels, labelValues)
+	g.mu.Lock()
+	g.values[key] = value
+	g.mu.Unlock()
+}
+
+// Value returns the current value of a series
+func (g *GaugeVec) Value(labelValues ...string) float64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.values[strings.Join(labelValues, "\xff")]
+}
+
+func (g *GaugeVec) write(b *strings.Builder) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s gauge\n", g.name, g.help, g.name)
+	keys := make([]string, 0, len(g.values))
+	for k := range g.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		b.WriteString(g.name + labelPairs(g.labels, k))
+		fmt.Fprintf(b, " %g\n", g.values[k])
+	}
+}
+
+// DefaultLatencyBuckets are upper bounds in seconds, from one second to a day
+var DefaultLatencyBuckets = []float64{1, 5, 15, 30, 60, 300, 900, 1800, 3600, 6 * 3600, 24 * 3600}
+
+// HistogramVec counts observations into cumulative buckets partitioned by labels
+type HistogramVec struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// NewHistogramVec creates and registers a histogram with the given sorted
+// bucket upper bounds. Registering the same name twice returns the existing
+// histogram.
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if existing, ok := registry[name].(*HistogramVec); ok {
+		return existing
+	}
+	h := &HistogramVec{name: name, help: help, labels: labels, buckets: buckets, series: map[string]*histogramSeries{}}
+	registry[name] = h
+	return h
+}
+
+// Observe records one value in the series identified by labelValues
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	key := seriesKey(h.name, h.labels, labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	for i, upper := range h.buckets {
+		if value <= upper {
+			s.counts[i]++
+			break
+		}
+	}
+	s.count++
+	s.sum += value
+}
+
+// Count returns the number of observations in a series
+func (h *HistogramVec) Count(labelValues ...string) uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s, ok := h.series[strings.Join(labelValues, "\xff")]; ok {
+		return s.count
+	}
+	return 0
+}
+
+func (h *HistogramVec) write(b *strings.Builder) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	keys := make([]string, 0, len(h.series))
+	for k := range h.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s := h.series[k]
+		pairs := labelPairs(h.labels, k)
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(b, "%s_bucket%s %d\n", h.name, withLE(pairs, fmt.Sprintf("%g", upper)), cumulative)
+		}
+		fmt.Fprintf(b, "%s_bucket%s %d\n", h.name, withLE(pairs, "+Inf"), s.count)
+		fmt.Fprintf(b, "%s_sum%s %g\n", h.name, pairs, s.sum)
+		fmt.Fprintf(b, "%s_count%s %d\n", h.name, pairs, s.count)
+	}
+}
+
+// valueFunc is a metric read from fn at scrape time
+type valueFunc struct {
+	name string
+	help string
+	kind string
+	fn   func() float64
+}
+
+// NewGaugeFunc registers a gauge whose value is read from fn on every scrape
+func NewGaugeFunc(name, help string, fn func() float64) {
+	registerFunc(name, help, "gauge", fn)
+}
+
+// NewCounterFunc registers a counter whose cumulative value is read from fn on
+// every scrape
+func NewCounterFunc(name, help string, fn func() float64) {
+	registerFunc(name, help, "counter", fn)
+}
+
+// registerFunc replaces an earlier function of the same name, so the latest
+// source (e.g. a recreated pool) is reported
+func registerFunc(name, help, kind string, fn func() float64) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[name] = &valueFunc{name: name, help: help, kind: kind, fn: fn}
+}
+
+func (v *valueFunc) write(b *strings.Builder) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n%s %g\n", v.name, v.help, v.name, v.kind, v.name, v.fn())
+}
+
+func seriesKey(name string, labels, labelValues []string) string {
+	if len(labelValues) != len(labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", name, len(labels), len(labelValues)))
+	}
+	return strings.Join(labelValues, "\xff")
+}
+
+// labelPairs renders a joined series key as {label="value",...}
+func labelPairs(labels []string, key string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	values := strings.Split(key, "\xff")
+	pairs := make([]string, len(labels))
+	for i, label := range labels {
+		pairs[i] = fmt.Sprintf("%s=%q", label, values[i])
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// withLE adds the bucket bound to rendered label pairs
+func withLE(pairs, upper string) string {
+	le := fmt.Sprintf("le=%q", upper)
+	if pairs == "" {
+		return "{" + le + "}"
+	}
+	return pairs[:len(pairs)-1] + "," + le + "}"
+}
+
+// Handler serves every registered metric
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		registryMu.Lock()
+		names := make([]string, 0, len(registry))
+		for name := range registry {
+			names = append(names, name)
+		}
+		registryMu.Unlock()
+		sort.Strings(names)
+
+		var b strings.Builder
+		for _, name := range names {
+			registryMu.Lock()
+			m := registry[name]
+			registryMu.Unlock()
+			m.write(&b)
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_, _ = w.Write([]byte(b.String()))
+	})
+}
@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCounterVec_ExposesSeriesInTextFormat(t *testing.T) {
+	c := NewCounterVec("test_requests_total", "Test requests", "method", "code")
+	c.Inc("GET", "200")
+	c.Add(2, "POST", "500")
+	c.Inc("GET", "200")
+
+	assert.Same(t, c, NewCounterVec("test_requests_total", "ignored"))
+	assert.Equal(t, 2.0, c.Value("GET", "200"))
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Contains(t, rec.Body.String(), "# TYPE test_requests_total counter\n"+
+		`test_requests_total{method="GET",code="200"} 2`+"\n"+
+		`test_requests_total{method="POST",code="500"} 2`+"\n")
+}
+
+func TestCounterVec_PanicsOnLabelMismatch(t *testing.T) {
+	c := NewCounterVec("test_mismatch_total", "Mismatch", "a")
+	assert.Panics(t, func() { c.Inc() })
+}
+
+func TestGaugeVec_KeepsLatestValue(t *testing.T) {
+	g := NewGaugeVec("test_burn_rate", "Test burn rate", "slo", "window")
+	g.Set(3, "renewal", "1h")
+	g.Set(0.5, "renewal", "1h")
+	g.Set(2, "renewal", "5m")
+
+	assert.Same(t, g, NewGaugeVec("test_burn_rate", "ignored"))
+	assert.Equal(t, 0.5, g.Value("renewal", "1h"))
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Contains(t, rec.Body.String(), "# TYPE test_burn_rate gauge\n"+
+		`test_burn_rate{slo="renewal",window="1h"} 0.5`+"\n"+
+		`test_burn_rate{slo="renewal",window="5m"} 2`+"\n")
+}
+
+func TestHistogramVec_ExposesCumulativeBuckets(t *testing.T) {
+	h := NewHistogramVec("test_lag_seconds", "Test lag", []float64{1, 10}, "provider")
+	h.Observe(0.5, "apple")
+	h.Observe(5, "apple")
+	h.Observe(30, "apple")
+
+	assert.Same(t, h, NewHistogramVec("test_lag_seconds", "ignored", nil))
+	assert.Equal(t, uint64(3), h.Count("apple"))
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Contains(t, rec.Body.String(), "# TYPE test_lag_seconds histogram\n"+
+		`test_lag_seconds_bucket{provider="apple",le="1"} 1`+"\n"+
+		`test_lag_seconds_bucket{provider="apple",le="10"} 2`+"\n"+
+		`test_lag_seconds_bucket{provider="apple",le="+Inf"} 3`+"\n"+
+		`test_lag_seconds_sum{provider="apple"} 35.5`+"\n"+
+		`test_lag_seconds_count{provider="apple"} 3`+"\n")
+}
+
+func TestValueFuncs_ReadAtScrape(t *testing.T) {
+	var idle float64
+	NewGaugeFunc("test_idle_connections", "Idle connections", func() float64 { return idle })
+	NewCounterFunc("test_wait_seconds_total", "Wait time", func() float64 { return 1.5 })
+	idle = 3
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Contains(t, rec.Body.String(), "# TYPE test_idle_connections gauge\ntest_idle_connections 3\n")
+	assert.Contains(t, rec.Body.String(), "# TYPE test_wait_seconds_total counter\ntest_wait_seconds_total 1.5\n")
+}
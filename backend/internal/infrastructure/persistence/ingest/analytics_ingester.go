@@ -0,0 +1,223 @@
+// Package ingest bulk-loads high-volume analytics events into PostgreSQL.
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/infrastructure/config"
+	"github.com/bivex/paywall-iap/internal/infrastructure/metrics"
+)
+
+// drainTimeout bounds the final flush when the ingester stops
+const drainTimeout = 10 * time.Second
+
+// ErrBufferFull is returned by Submit when the in-memory buffer cannot take
+// the events; callers should shed the request and let the client retry
+var ErrBufferFull = errors.New("analytics ingest buffer is full")
+
+var analyticsEventColumns = []string{
+	"id", "app_id", "user_id", "source", "event_name", "properties", "occurred_at", "received_at",
+}
+
+var ingestFlushSeconds = metrics.NewHistogramVec(
+	"analytics_ingest_flush_seconds",
+	"Time to COPY one batch of analytics events",
+	[]float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5},
+	"outcome",
+)
+
+var ingestEvents = metrics.NewCounterVec(
+	"analytics_ingest_events_total",
+	"Analytics events by ingestion outcome: flushed, rejected when the buffer was full, or dropped after a failed flush",
+	"outcome",
+)
+
+// copier is the subset of pgxpool.Pool the ingester writes through
+type copier interface {
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
+
+// AnalyticsIngester buffers analytics events in memory and writes them to the
+// partitioned analytics_events table with COPY. The buffer is bounded: when a
+// flush fails the batch is kept for the next attempt, so a slow or unavailable
+// database turns into backpressure on Submit rather than unbounded memory.
+type AnalyticsIngester struct {
+	db            copier
+	logger        *zap.Logger
+	bufferSize    int
+	batchSize     int
+	flushInterval time.Duration
+	now           func() time.Time
+
+	mu     sync.Mutex
+	buffer []entity.AnalyticsEvent
+	full   chan struct{} // signalled when a whole batch is buffered
+
+	partitionMonth time.Time // month whose partitions were last ensured
+}
+
+// NewAnalyticsIngester creates an ingester; call Run to start flushing
+func NewAnalyticsIngester(db copier, cfg config.IngestConfig, logger *zap.Logger) *AnalyticsIngester {
+	i := &AnalyticsIngester{
+		db:            db,
+		logger:        logger,
+		bufferSize:    cfg.BufferSize,
+		batchSize:     cfg.BatchSize,
+		flushInterval: cfg.FlushInterval,
+		now:           time.Now,
+		full:          make(chan struct{}, 1),
+	}
+	metrics.NewGaugeFunc("analytics_ingest_buffered_events", "Analytics events waiting in memory to be flushed",
+		func() float64 { return float64(i.Buffered()) })
+	return i
+}
+
+// Submit buffers the events, all or none. IDs and receipt times are filled in
+// when missing.
+func (i *AnalyticsIngester) Submit(events ...entity.AnalyticsEvent) error {
+	now := i.now()
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if len(i.buffer)+len(events) > i.bufferSize {
+		ingestEvents.Add(float64(len(events)), "rejected")
+		return ErrBufferFull
+	}
+	for _, e := range events {
+		if e.ID == uuid.Nil {
+			e.ID = uuid.New()
+		}
+		if e.ReceivedAt.IsZero() {
+			e.ReceivedAt = now
+		}
+		if e.Properties == nil {
+			e.Properties = map[string]any{}
+		}
+		i.buffer = append(i.buffer, e)
+	}
+	if len(i.buffer) >= i.batchSize {
+		select {
+		case i.full <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Buffered returns the number of events waiting to be flushed
+func (i *AnalyticsIngester) Buffered() int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return len(i.buffer)
+}
+
+// Capacity returns how many events the buffer holds before Submit rejects
+func (i *AnalyticsIngester) Capacity() int {
+	return i.bufferSize
+}
+
+// Run flushes every FlushInterval, or as soon as a batch is full, until ctx is
+// cancelled; it then drains the buffer once more before returning
+func (i *AnalyticsIngester) Run(ctx context.Context) {
+	ticker := time.NewTicker(i.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			drainCtx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+			if err := i.Flush(drainCtx); err != nil {
+				i.logger.Error("Failed to drain analytics events on shutdown",
+					zap.Int("events", i.Buffered()), zap.Error(err))
+			}
+			cancel()
+			return
+		case <-ticker.C:
+		case <-i.full:
+		}
+		if err := i.Flush(ctx); err != nil && ctx.Err() == nil {
+			i.logger.Warn("Failed to flush analytics events", zap.Int("buffered", i.Buffered()), zap.Error(err))
+		}
+	}
+}
+
+// Flush writes everything buffered in batches. Events of a failed batch and
+// the batches after it are put back for the next flush.
+func (i *AnalyticsIngester) Flush(ctx context.Context) error {
+	if err := i.ensurePartitions(ctx); err != nil {
+		// Events outside the created partitions land in the default one
+		i.logger.Warn("Failed to create analytics event partitions", zap.Error(err))
+	}
+
+	i.mu.Lock()
+	pending := i.buffer
+	i.buffer = nil
+	i.mu.Unlock()
+
+	for start := 0; start < len(pending); start += i.batchSize {
+		end := min(start+i.batchSize, len(pending))
+		if err := i.copyBatch(ctx, pending[start:end]); err != nil {
+			i.requeue(pending[start:])
+			return err
+		}
+	}
+	return nil
+}
+
+func (i *AnalyticsIngester) copyBatch(ctx context.Context, batch []entity.AnalyticsEvent) error {
+	started := i.now()
+	_, err := i.db.CopyFrom(ctx, pgx.Identifier{"analytics_events"}, analyticsEventColumns,
+		pgx.CopyFromSlice(len(batch), func(n int) ([]any, error) {
+			e := batch[n]
+			return []any{e.ID, e.AppID, e.UserID, e.Source, e.Name, e.Properties, e.OccurredAt, e.ReceivedAt}, nil
+		}))
+	outcome := "ok"
+	if err != nil {
+		outcome = "error"
+	}
+	ingestFlushSeconds.Observe(i.now().Sub(started).Seconds(), outcome)
+	if err != nil {
+		return fmt.Errorf("failed to copy %d analytics events: %w", len(batch), err)
+	}
+	ingestEvents.Add(float64(len(batch)), "flushed")
+	return nil
+}
+
+// requeue puts unflushed events back ahead of those submitted meanwhile,
+// dropping the oldest if the buffer cannot hold both
+func (i *AnalyticsIngester) requeue(events []entity.AnalyticsEvent) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	merged := append(events, i.buffer...)
+	if over := len(merged) - i.bufferSize; over > 0 {
+		ingestEvents.Add(float64(over), "dropped")
+		merged = merged[over:]
+	}
+	i.buffer = merged
+}
+
+// ensurePartitions creates this month's and next month's partitions once per
+// month, so events never pile up in the default partition
+func (i *AnalyticsIngester) ensurePartitions(ctx context.Context) error {
+	now := i.now().UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if month.Equal(i.partitionMonth) {
+		return nil
+	}
+	for _, at := range []time.Time{month, month.AddDate(0, 1, 0)} {
+		if _, err := i.db.Exec(ctx, `SELECT ensure_analytics_events_partition($1)`, at); err != nil {
+			return fmt.Errorf("failed to ensure partition for %s: %w", at.Format("2006-01"), err)
+		}
+	}
+	i.partitionMonth = month
+	return nil
+}
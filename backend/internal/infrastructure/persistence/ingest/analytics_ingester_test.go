@@ -0,0 +1,122 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/infrastructure/config"
+)
+
+type fakeCopier struct {
+	mu         sync.Mutex
+	fail       error
+	batches    [][][]any
+	partitions []time.Time
+}
+
+func (f *fakeCopier) CopyFrom(_ context.Context, _ pgx.Identifier, _ []string, src pgx.CopyFromSource) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.fail != nil {
+		return 0, f.fail
+	}
+	var rows [][]any
+	for src.Next() {
+		values, err := src.Values()
+		if err != nil {
+			return 0, err
+		}
+		rows = append(rows, values)
+	}
+	f.batches = append(f.batches, rows)
+	return int64(len(rows)), nil
+}
+
+func (f *fakeCopier) Exec(_ context.Context, _ string, args ...any) (pgconn.CommandTag, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.partitions = append(f.partitions, args[0].(time.Time))
+	return pgconn.CommandTag{}, nil
+}
+
+func (f *fakeCopier) rows() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, b := range f.batches {
+		n += len(b)
+	}
+	return n
+}
+
+func clientEvent(name string) entity.AnalyticsEvent {
+	return entity.AnalyticsEvent{Source: entity.AnalyticsSourceClient, Name: name, OccurredAt: time.Now()}
+}
+
+func TestAnalyticsIngester_FlushesInBatches(t *testing.T) {
+	db := &fakeCopier{}
+	ing := NewAnalyticsIngester(db, config.IngestConfig{BufferSize: 10, BatchSize: 2, FlushInterval: time.Hour}, zap.NewNop())
+	ing.now = func() time.Time { return time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC) }
+
+	require.NoError(t, ing.Submit(clientEvent("a"), clientEvent("b"), clientEvent("c")))
+	require.NoError(t, ing.Flush(context.Background()))
+
+	require.Len(t, db.batches, 2)
+	assert.Len(t, db.batches[0], 2)
+	assert.Len(t, db.batches[1], 1)
+	assert.Equal(t, "c", db.batches[1][0][4])
+	assert.NotNil(t, db.batches[0][0][5], "properties default to an empty object")
+	assert.Zero(t, ing.Buffered())
+
+	assert.Equal(t, []time.Time{
+		time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC),
+	}, db.partitions)
+	require.NoError(t, ing.Flush(context.Background()))
+	assert.Len(t, db.partitions, 2, "partitions are only ensured once per month")
+}
+
+func TestAnalyticsIngester_BackpressureWhenFlushFails(t *testing.T) {
+	db := &fakeCopier{fail: errors.New("connection refused")}
+	ing := NewAnalyticsIngester(db, config.IngestConfig{BufferSize: 3, BatchSize: 2, FlushInterval: time.Hour}, zap.NewNop())
+
+	require.NoError(t, ing.Submit(clientEvent("a"), clientEvent("b")))
+	assert.Error(t, ing.Flush(context.Background()))
+	assert.Equal(t, 2, ing.Buffered(), "a failed batch is kept for the next flush")
+
+	assert.ErrorIs(t, ing.Submit(clientEvent("c"), clientEvent("d")), ErrBufferFull)
+	require.NoError(t, ing.Submit(clientEvent("c")))
+
+	db.fail = nil
+	require.NoError(t, ing.Flush(context.Background()))
+	assert.Equal(t, 3, db.rows())
+}
+
+func TestAnalyticsIngester_RunFlushesFullBatchAndDrainsOnStop(t *testing.T) {
+	db := &fakeCopier{}
+	ing := NewAnalyticsIngester(db, config.IngestConfig{BufferSize: 10, BatchSize: 2, FlushInterval: time.Hour}, zap.NewNop())
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		ing.Run(ctx)
+		close(done)
+	}()
+
+	require.NoError(t, ing.Submit(clientEvent("a"), clientEvent("b")))
+	require.Eventually(t, func() bool { return db.rows() == 2 }, time.Second, 5*time.Millisecond)
+
+	require.NoError(t, ing.Submit(clientEvent("c")))
+	cancel()
+	<-done
+	assert.Equal(t, 3, db.rows())
+}
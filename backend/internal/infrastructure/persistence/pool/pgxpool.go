@@ -4,10 +4,21 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/bivex/paywall-iap/internal/infrastructure/config"
+	"github.com/bivex/paywall-iap/internal/infrastructure/logging"
+	"github.com/bivex/paywall-iap/internal/infrastructure/metrics"
 )
 
+var queryExecModes = map[string]pgx.QueryExecMode{
+	"cache_statement": pgx.QueryExecModeCacheStatement,
+	"cache_describe":  pgx.QueryExecModeCacheDescribe,
+	"describe_exec":   pgx.QueryExecModeDescribeExec,
+	"exec":            pgx.QueryExecModeExec,
+	"simple_protocol": pgx.QueryExecModeSimpleProtocol,
+}
+
 // NewPool creates a new PostgreSQL connection pool
 func NewPool(ctx context.Context, cfg config.DatabaseConfig) (*pgxpool.Pool, error) {
 	config, err := pgxpool.ParseConfig(cfg.URL)
@@ -21,17 +32,50 @@ func NewPool(ctx context.Context, cfg config.DatabaseConfig) (*pgxpool.Pool, err
 	config.MaxConns = int32(cfg.MaxConnections)
 	config.MinConns = int32(cfg.MinConnections)
 	config.MaxConnLifetime = cfg.MaxLifetime
+	config.MaxConnLifetimeJitter = cfg.MaxLifetimeJitter
 	config.MaxConnIdleTime = cfg.MaxIdleTime
 	config.HealthCheckPeriod = cfg.HealthCheck
 
+	// Prepared statements are cached per connection; the URL's
+	// default_query_exec_mode applies when no mode is configured
+	if cfg.QueryExecMode != "" {
+		mode, ok := queryExecModes[cfg.QueryExecMode]
+		if !ok {
+			return nil, fmt.Errorf("unknown query exec mode %q", cfg.QueryExecMode)
+		}
+		config.ConnConfig.DefaultQueryExecMode = mode
+	}
+	if cfg.StatementCacheCapacity > 0 {
+		config.ConnConfig.StatementCacheCapacity = cfg.StatementCacheCapacity
+	}
+	config.ConnConfig.Tracer = newQueryTracer(logging.Logger, cfg.SlowQueryThreshold)
+
 	pool, err := pgxpool.NewWithConfig(ctx, config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create connection pool: %w", err)
 	}
+	registerPoolMetrics(pool)
 
 	return pool, nil
 }
 
+// registerPoolMetrics exposes the pool's connection counts and cumulative
+// acquire wait time
+func registerPoolMetrics(pool *pgxpool.Pool) {
+	metrics.NewGaugeFunc("db_pool_acquired_connections", "Connections currently checked out of the pool",
+		func() float64 { return float64(pool.Stat().AcquiredConns()) })
+	metrics.NewGaugeFunc("db_pool_idle_connections", "Idle connections in the pool",
+		func() float64 { return float64(pool.Stat().IdleConns()) })
+	metrics.NewGaugeFunc("db_pool_total_connections", "Open connections in the pool",
+		func() float64 { return float64(pool.Stat().TotalConns()) })
+	metrics.NewGaugeFunc("db_pool_max_connections", "Maximum size of the pool",
+		func() float64 { return float64(pool.Stat().MaxConns()) })
+	metrics.NewCounterFunc("db_pool_empty_acquire_wait_seconds_total", "Time spent waiting for a connection because the pool had none idle",
+		func() float64 { return pool.Stat().EmptyAcquireWaitTime().Seconds() })
+	metrics.NewCounterFunc("db_pool_empty_acquire_total", "Acquires that had to wait because the pool had no idle connection",
+		func() float64 { return float64(pool.Stat().EmptyAcquireCount()) })
+}
+
 // Ping verifies the database connection is alive
 func Ping(ctx context.Context, pool *pgxpool.Pool) error {
 	return pool.Ping(ctx)
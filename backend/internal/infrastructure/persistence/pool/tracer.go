@@ -0,0 +1,109 @@
+package pool
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/infrastructure/metrics"
+)
+
+// slowQueryMaxSQL bounds the statement text logged for a slow query
+const slowQueryMaxSQL = 1000
+
+var poolAcquireSeconds = metrics.NewHistogramVec(
+	"db_pool_acquire_seconds",
+	"Time to acquire a connection from the pool",
+	[]float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5},
+	"outcome",
+)
+
+var slowQueries = metrics.NewCounterVec(
+	"db_slow_queries_total",
+	"Queries that took longer than the slow query threshold",
+	"command",
+)
+
+type queryStartKey struct{}
+
+type queryStart struct {
+	sql string
+	at  time.Time
+}
+
+type acquireStartKey struct{}
+
+// queryTracer logs queries slower than the threshold and times pool acquires.
+// Query arguments are never logged: they can hold receipts and user data.
+type queryTracer struct {
+	logger    *zap.Logger
+	threshold time.Duration
+	now       func() time.Time
+}
+
+var (
+	_ pgx.QueryTracer       = (*queryTracer)(nil)
+	_ pgxpool.AcquireTracer = (*queryTracer)(nil)
+)
+
+func newQueryTracer(logger *zap.Logger, threshold time.Duration) *queryTracer {
+	return &queryTracer{logger: logger, threshold: threshold, now: time.Now}
+}
+
+func (t *queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	if t.threshold <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, queryStartKey{}, queryStart{sql: data.SQL, at: t.now()})
+}
+
+func (t *queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	start, ok := ctx.Value(queryStartKey{}).(queryStart)
+	if !ok {
+		return
+	}
+	elapsed := t.now().Sub(start.at)
+	if elapsed < t.threshold {
+		return
+	}
+
+	sql := strings.Join(strings.Fields(start.sql), " ")
+	if len(sql) > slowQueryMaxSQL {
+		sql = sql[:slowQueryMaxSQL] + "…"
+	}
+	command := "unknown"
+	if fields := strings.Fields(sql); len(fields) > 0 {
+		command = strings.ToLower(fields[0])
+	}
+	slowQueries.Inc(command)
+
+	fields := []zap.Field{
+		zap.String("sql", sql),
+		zap.Duration("duration", elapsed),
+		zap.Int64("rows", data.CommandTag.RowsAffected()),
+	}
+	if data.Err != nil {
+		fields = append(fields, zap.Error(data.Err))
+	}
+	t.logger.Warn("Slow query", fields...)
+}
+
+func (t *queryTracer) TraceAcquireStart(ctx context.Context, _ *pgxpool.Pool, _ pgxpool.TraceAcquireStartData) context.Context {
+	return context.WithValue(ctx, acquireStartKey{}, t.now())
+}
+
+func (t *queryTracer) TraceAcquireEnd(ctx context.Context, _ *pgxpool.Pool, data pgxpool.TraceAcquireEndData) {
+	start, ok := ctx.Value(acquireStartKey{}).(time.Time)
+	if !ok {
+		return
+	}
+	outcome := "ok"
+	if data.Err != nil {
+		outcome = "error"
+	}
+	poolAcquireSeconds.Observe(t.now().Sub(start).Seconds(), outcome)
+}
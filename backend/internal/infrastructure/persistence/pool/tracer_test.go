@@ -0,0 +1,70 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func newTestTracer(threshold time.Duration) (*queryTracer, *observer.ObservedLogs, *time.Time) {
+	core, logs := observer.New(zap.WarnLevel)
+	now := time.Now()
+	tracer := newQueryTracer(zap.New(core), threshold)
+	tracer.now = func() time.Time { return now }
+	return tracer, logs, &now
+}
+
+func TestQueryTracer_LogsOnlySlowQueriesWithoutArgs(t *testing.T) {
+	tracer, logs, now := newTestTracer(100 * time.Millisecond)
+
+	ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "SELECT 1"})
+	*now = now.Add(10 * time.Millisecond)
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+	assert.Zero(t, logs.Len(), "fast query")
+
+	before := slowQueries.Value("update")
+	ctx = tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{
+		SQL:  "UPDATE users\n   SET email = $1\n WHERE id = $2",
+		Args: []any{"someone@example.com", 42},
+	})
+	*now = now.Add(250 * time.Millisecond)
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag("UPDATE 1"), Err: errors.New("boom")})
+
+	require.Equal(t, 1, logs.Len())
+	entry := logs.All()[0].ContextMap()
+	assert.Equal(t, "UPDATE users SET email = $1 WHERE id = $2", entry["sql"])
+	assert.Equal(t, 250*time.Millisecond, entry["duration"])
+	assert.Equal(t, int64(1), entry["rows"])
+	assert.NotContains(t, logs.All()[0].Entry.Message+entry["sql"].(string), "someone@example.com")
+	assert.Equal(t, before+1, slowQueries.Value("update"))
+}
+
+func TestQueryTracer_DisabledThreshold(t *testing.T) {
+	tracer, logs, now := newTestTracer(0)
+
+	ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "SELECT pg_sleep(10)"})
+	*now = now.Add(10 * time.Second)
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+
+	assert.Zero(t, logs.Len())
+}
+
+func TestQueryTracer_TimesAcquires(t *testing.T) {
+	tracer, _, now := newTestTracer(0)
+	before := poolAcquireSeconds.Count("error")
+
+	ctx := tracer.TraceAcquireStart(context.Background(), nil, pgxpool.TraceAcquireStartData{})
+	*now = now.Add(20 * time.Millisecond)
+	tracer.TraceAcquireEnd(ctx, nil, pgxpool.TraceAcquireEndData{Err: context.DeadlineExceeded})
+
+	assert.Equal(t, before+1, poolAcquireSeconds.Count("error"))
+}
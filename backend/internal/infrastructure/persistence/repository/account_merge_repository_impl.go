@@ -0,0 +1,364 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+const accountMergeColumns = `id, app_id, source_user_id, target_user_id, conflict_policy, status,
+	reason, merged_by, source_ltv::float8, moved, conflicts, created_at, undo_until, undone_at`
+
+// mergeMove moves one table's rows from the source to the target user.
+// filter narrows the rows beyond user_id = $1; $2 is the target and $3 the
+// merge. Tables are moved in order and moved back in reverse.
+type mergeMove struct {
+	table  string
+	filter string
+}
+
+var accountMergeMoves = []mergeMove{
+	// A second active subscription stays with the source: only one may be
+	// active per user and app
+	{table: "subscriptions", filter: `NOT (status = 'active' AND deleted_at IS NULL AND EXISTS (
+		SELECT 1 FROM subscriptions t
+		WHERE t.user_id = $2 AND t.app_id = subscriptions.app_id AND t.status = 'active' AND t.deleted_at IS NULL))`},
+	{table: "transactions", filter: movedSubscription},
+	{table: "grace_periods", filter: movedSubscription},
+	{table: "dunning", filter: movedSubscription},
+	{table: "lifetime_entitlements"},
+	{table: "offer_redemptions"},
+	{table: "ab_test_assignments", filter: `experiment_id NOT IN (
+		SELECT experiment_id FROM ab_test_assignments WHERE user_id = $2)`},
+	{table: "bandit_assignment_events", filter: `assignment_id IN (
+		SELECT row_id FROM account_merge_moves WHERE merge_id = $3 AND table_name = 'ab_test_assignments')`},
+	{table: "bandit_impression_events"},
+	{table: "bandit_conversion_events"},
+	{table: "matomo_staged_events"},
+}
+
+// movedSubscription keeps rows with the subscription they belong to
+const movedSubscription = `subscription_id IN (
+	SELECT row_id FROM account_merge_moves WHERE merge_id = $3 AND table_name = 'subscriptions')`
+
+// AccountMergeRepositoryImpl implements AccountMergeRepository
+type AccountMergeRepositoryImpl struct {
+	pool *pgxpool.Pool
+}
+
+// NewAccountMergeRepository creates a new account merge repository
+func NewAccountMergeRepository(pool *pgxpool.Pool) repository.AccountMergeRepository {
+	return &AccountMergeRepositoryImpl{pool: pool}
+}
+
+// Merge moves the source user's rows to the target
+func (r *AccountMergeRepositoryImpl) Merge(ctx context.Context, m *entity.AccountMerge, dryRun bool) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	sourceLTV, err := lockMergeUsers(ctx, tx, m)
+	if err != nil {
+		return err
+	}
+	m.SourceLTV = sourceLTV
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO account_merges (
+			id, app_id, source_user_id, target_user_id, conflict_policy, status,
+			reason, merged_by, created_at, undo_until
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`, m.ID, m.AppID, m.SourceUserID, m.TargetUserID, m.Policy, m.Status,
+		m.Reason, m.MergedBy, m.CreatedAt, m.UndoUntil); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return fmt.Errorf("%w: user %s is already merged", entity.ErrInvalidAccountMerge, m.SourceUserID)
+		}
+		return fmt.Errorf("failed to create account merge: %w", err)
+	}
+
+	if m.Conflicts, err = mergeConflicts(ctx, tx, m); err != nil {
+		return err
+	}
+	if m.Policy == entity.MergeKeepSource {
+		if err := rewriteTargetAssignments(ctx, tx, m); err != nil {
+			return err
+		}
+	}
+
+	m.Moved = map[string]int64{}
+	for _, move := range accountMergeMoves {
+		filter := "true"
+		if move.filter != "" {
+			filter = move.filter
+		}
+		tag, err := tx.Exec(ctx, `
+			WITH moved AS (
+				UPDATE `+move.table+` SET user_id = $2
+				WHERE user_id = $1 AND (`+filter+`)
+				RETURNING id
+			)
+			INSERT INTO account_merge_moves (merge_id, table_name, row_id)
+			SELECT $3, '`+move.table+`', id FROM moved
+		`, m.SourceUserID, m.TargetUserID, m.ID)
+		if err != nil {
+			return fmt.Errorf("failed to move %s: %w", move.table, err)
+		}
+		if tag.RowsAffected() > 0 {
+			m.Moved[move.table] = tag.RowsAffected()
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE users SET ltv = ltv + $2 WHERE id = $1`, m.TargetUserID, sourceLTV); err != nil {
+		return fmt.Errorf("failed to transfer ltv: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `UPDATE users SET ltv = 0, deleted_at = $2 WHERE id = $1`, m.SourceUserID, m.CreatedAt); err != nil {
+		return fmt.Errorf("failed to retire merged user: %w", err)
+	}
+
+	moved, err := json.Marshal(m.Moved)
+	if err != nil {
+		return err
+	}
+	conflicts, err := json.Marshal(m.Conflicts)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE account_merges SET source_ltv = $2, moved = $3, conflicts = $4 WHERE id = $1
+	`, m.ID, sourceLTV, moved, conflicts); err != nil {
+		return fmt.Errorf("failed to record account merge: %w", err)
+	}
+
+	if dryRun {
+		return nil
+	}
+	return tx.Commit(ctx)
+}
+
+// Undo moves the journaled rows back to the source user
+func (r *AccountMergeRepositoryImpl) Undo(ctx context.Context, m *entity.AccountMerge) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE account_merges SET status = $2, undone_at = $3
+		WHERE id = $1 AND status = 'merged'
+	`, m.ID, m.Status, m.UndoneAt)
+	if err != nil {
+		return fmt.Errorf("failed to undo account merge: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: merge is no longer merged", entity.ErrAccountMergeNotUndoable)
+	}
+
+	for i := len(accountMergeMoves) - 1; i >= 0; i-- {
+		table := accountMergeMoves[i].table
+		if _, err := tx.Exec(ctx, `
+			UPDATE `+table+` SET user_id = $1
+			WHERE user_id = $2 AND id IN (
+				SELECT row_id FROM account_merge_moves
+				WHERE merge_id = $3 AND table_name = '`+table+`' AND previous IS NULL
+			)
+		`, m.SourceUserID, m.TargetUserID, m.ID); err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+				return fmt.Errorf("%w: %s conflict with rows created since the merge", entity.ErrAccountMergeNotUndoable, table)
+			}
+			return fmt.Errorf("failed to move back %s: %w", table, err)
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE ab_test_assignments a
+		SET arm_id = (mv.previous->>'arm_id')::uuid,
+			assigned_at = (mv.previous->>'assigned_at')::timestamptz,
+			expires_at = (mv.previous->>'expires_at')::timestamptz
+		FROM account_merge_moves mv
+		WHERE mv.merge_id = $1 AND mv.table_name = 'ab_test_assignments'
+			AND mv.previous IS NOT NULL AND a.id = mv.row_id
+	`, m.ID); err != nil {
+		return fmt.Errorf("failed to restore experiment assignments: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE users SET ltv = GREATEST(ltv - $2, 0) WHERE id = $1`, m.TargetUserID, m.SourceLTV); err != nil {
+		return fmt.Errorf("failed to transfer ltv back: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `UPDATE users SET ltv = $2, deleted_at = NULL WHERE id = $1`, m.SourceUserID, m.SourceLTV); err != nil {
+		return fmt.Errorf("failed to restore merged user: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
+// GetByID retrieves a merge
+func (r *AccountMergeRepositoryImpl) GetByID(ctx context.Context, id uuid.UUID) (*entity.AccountMerge, error) {
+	m, err := scanAccountMerge(r.pool.QueryRow(ctx, `
+		SELECT `+accountMergeColumns+` FROM account_merges WHERE id = $1
+	`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("account merge: %w", domainErrors.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account merge: %w", err)
+	}
+	return m, nil
+}
+
+// ListByUser retrieves merges where the user is the source or the target
+func (r *AccountMergeRepositoryImpl) ListByUser(ctx context.Context, userID uuid.UUID) ([]*entity.AccountMerge, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+accountMergeColumns+`
+		FROM account_merges
+		WHERE source_user_id = $1 OR target_user_id = $1
+		ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list account merges: %w", err)
+	}
+	defer rows.Close()
+
+	var merges []*entity.AccountMerge
+	for rows.Next() {
+		m, err := scanAccountMerge(rows)
+		if err != nil {
+			return nil, err
+		}
+		merges = append(merges, m)
+	}
+	return merges, rows.Err()
+}
+
+// lockMergeUsers locks both users and checks they can be merged; returns the
+// source's lifetime value
+func lockMergeUsers(ctx context.Context, tx pgx.Tx, m *entity.AccountMerge) (float64, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT id, app_id, deleted_at, ltv::float8 FROM users
+		WHERE id = ANY($1)
+		ORDER BY id
+		FOR UPDATE
+	`, []uuid.UUID{m.SourceUserID, m.TargetUserID})
+	if err != nil {
+		return 0, fmt.Errorf("failed to lock users: %w", err)
+	}
+	defer rows.Close()
+
+	found := 0
+	var sourceLTV float64
+	for rows.Next() {
+		var id, appID uuid.UUID
+		var deletedAt *time.Time
+		var ltv float64
+		if err := rows.Scan(&id, &appID, &deletedAt, &ltv); err != nil {
+			return 0, err
+		}
+		if appID != m.AppID {
+			return 0, fmt.Errorf("%w: user %s belongs to another app", entity.ErrInvalidAccountMerge, id)
+		}
+		if deletedAt != nil {
+			return 0, fmt.Errorf("%w: user %s is deleted", entity.ErrInvalidAccountMerge, id)
+		}
+		if id == m.SourceUserID {
+			sourceLTV = ltv
+		}
+		found++
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if found != 2 {
+		return 0, fmt.Errorf("%w: both users must exist", entity.ErrInvalidAccountMerge)
+	}
+	return sourceLTV, nil
+}
+
+// mergeConflicts lists what both users hold: active subscriptions in the app
+// and assignments to the same experiment
+func mergeConflicts(ctx context.Context, tx pgx.Tx, m *entity.AccountMerge) ([]entity.AccountMergeConflict, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT 'active_subscription', s.id, t.id
+		FROM subscriptions s
+		JOIN subscriptions t ON t.user_id = $2 AND t.app_id = s.app_id
+			AND t.status = 'active' AND t.deleted_at IS NULL
+		WHERE s.user_id = $1 AND s.status = 'active' AND s.deleted_at IS NULL
+		UNION ALL
+		SELECT 'experiment_assignment', s.id, t.id
+		FROM ab_test_assignments s
+		JOIN ab_test_assignments t ON t.user_id = $2 AND t.experiment_id = s.experiment_id
+		WHERE s.user_id = $1
+	`, m.SourceUserID, m.TargetUserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find merge conflicts: %w", err)
+	}
+	defer rows.Close()
+
+	conflicts := []entity.AccountMergeConflict{}
+	for rows.Next() {
+		var c entity.AccountMergeConflict
+		if err := rows.Scan(&c.Kind, &c.SourceID, &c.TargetID); err != nil {
+			return nil, err
+		}
+		c.Resolution = "kept_target"
+		if m.Policy == entity.MergeKeepSource && c.Kind == entity.MergeConflictExperimentAssignment {
+			c.Resolution = "kept_source"
+		}
+		conflicts = append(conflicts, c)
+	}
+	return conflicts, rows.Err()
+}
+
+// rewriteTargetAssignments gives the target the source's arm in experiments
+// both are assigned to, journaling the target's previous assignment
+func rewriteTargetAssignments(ctx context.Context, tx pgx.Tx, m *entity.AccountMerge) error {
+	_, err := tx.Exec(ctx, `
+		WITH rewritten AS (
+			UPDATE ab_test_assignments t
+			SET arm_id = s.arm_id, assigned_at = s.assigned_at, expires_at = s.expires_at
+			FROM ab_test_assignments s, ab_test_assignments prev
+			WHERE s.user_id = $1 AND t.user_id = $2 AND t.experiment_id = s.experiment_id
+				AND prev.id = t.id
+			RETURNING t.id, prev.arm_id, prev.assigned_at, prev.expires_at
+		)
+		INSERT INTO account_merge_moves (merge_id, table_name, row_id, previous)
+		SELECT $3, 'ab_test_assignments', id,
+			jsonb_build_object('arm_id', arm_id, 'assigned_at', assigned_at, 'expires_at', expires_at)
+		FROM rewritten
+	`, m.SourceUserID, m.TargetUserID, m.ID)
+	if err != nil {
+		return fmt.Errorf("failed to resolve experiment assignments: %w", err)
+	}
+	return nil
+}
+
+func scanAccountMerge(row pgx.Row) (*entity.AccountMerge, error) {
+	var m entity.AccountMerge
+	var moved, conflicts []byte
+	err := row.Scan(&m.ID, &m.AppID, &m.SourceUserID, &m.TargetUserID, &m.Policy, &m.Status,
+		&m.Reason, &m.MergedBy, &m.SourceLTV, &moved, &conflicts, &m.CreatedAt, &m.UndoUntil, &m.UndoneAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(moved, &m.Moved); err != nil {
+		return nil, fmt.Errorf("failed to decode moved rows: %w", err)
+	}
+	if err := json.Unmarshal(conflicts, &m.Conflicts); err != nil {
+		return nil, fmt.Errorf("failed to decode merge conflicts: %w", err)
+	}
+	return &m, nil
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	domainRepo "github.com/bivex/paywall-iap/internal/domain/repository"
+	"github.com/bivex/paywall-iap/internal/domain/service"
 	"github.com/bivex/paywall-iap/internal/appctx"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -100,6 +101,22 @@ func (r *AnalyticsRepositoryImpl) GetChurnedCountBetween(ctx context.Context, st
 	return count, err
 }
 
+func (r *AnalyticsRepositoryImpl) GetPriceIncreaseChurnedCountBetween(ctx context.Context, start, end time.Time) (int, error) {
+	appID, hasApp := appctx.AppIDFromCtx(ctx)
+	var count int
+	var err error
+	if hasApp {
+		err = r.pool.QueryRow(ctx,
+			`SELECT COUNT(*) FROM price_increase_consents WHERE status = 'declined' AND responded_at >= $1 AND responded_at < $2 AND app_id = $3`,
+			start, end, appID).Scan(&count)
+	} else {
+		err = r.pool.QueryRow(ctx,
+			`SELECT COUNT(*) FROM price_increase_consents WHERE status = 'declined' AND responded_at >= $1 AND responded_at < $2`,
+			start, end).Scan(&count)
+	}
+	return count, err
+}
+
 // GetMRRTrend returns monthly MRR for the last N months (oldest first).
 func (r *AnalyticsRepositoryImpl) GetMRRTrend(ctx context.Context, months int) ([]domainRepo.MonthlyMRR, error) {
 	appID, hasApp := appctx.AppIDFromCtx(ctx)
@@ -156,6 +173,121 @@ func (r *AnalyticsRepositoryImpl) GetMRRTrend(ctx context.Context, months int) (
 	return result, rows.Err()
 }
 
+// GetRevenueByCurrencyBetween sums successful revenue per currency and day so
+// each day can be converted at its own rate.
+func (r *AnalyticsRepositoryImpl) GetRevenueByCurrencyBetween(ctx context.Context, start, end time.Time) ([]service.CurrencyAmount, error) {
+	appID, hasApp := appctx.AppIDFromCtx(ctx)
+	appFilter := ""
+	args := []interface{}{start, end}
+	if hasApp {
+		appFilter = "AND app_id = $3"
+		args = append(args, appID)
+	}
+	query := fmt.Sprintf(`
+		SELECT currency, date_trunc('day', created_at AT TIME ZONE 'UTC') AS day,
+		       COALESCE(SUM(amount), 0)::float8, COUNT(*)
+		FROM transactions
+		WHERE status = 'success' AND created_at >= $1 AND created_at < $2 %s
+		GROUP BY 1, 2
+		ORDER BY 2, 1
+	`, appFilter)
+	return r.queryCurrencyAmounts(ctx, query, args...)
+}
+
+// GetMRRByCurrency returns current MRR per charge currency, using the same
+// latest-transaction-per-subscription rule as GetMRR.
+func (r *AnalyticsRepositoryImpl) GetMRRByCurrency(ctx context.Context) ([]service.CurrencyAmount, error) {
+	appID, hasApp := appctx.AppIDFromCtx(ctx)
+	appFilter := ""
+	args := []interface{}{}
+	if hasApp {
+		appFilter = "AND s.app_id = $1"
+		args = append(args, appID)
+	}
+	query := fmt.Sprintf(`
+		SELECT currency, date_trunc('day', now() AT TIME ZONE 'UTC'),
+			COALESCE(SUM(
+				CASE
+					WHEN plan_type = 'monthly' THEN amount
+					WHEN plan_type = 'annual' THEN amount / 12.0
+					ELSE 0
+				END
+			), 0)::float8, COUNT(*)
+		FROM (
+			SELECT DISTINCT ON (s.id) s.plan_type, t.amount, t.currency
+			FROM subscriptions s
+			JOIN transactions t ON s.id = t.subscription_id
+			WHERE s.status = 'active' AND t.status = 'success' %s
+			ORDER BY s.id, t.created_at DESC
+		) as active_subs
+		GROUP BY 1
+		ORDER BY 1
+	`, appFilter)
+	return r.queryCurrencyAmounts(ctx, query, args...)
+}
+
+// GetMRRTrendByCurrency is GetMRRTrend split by charge currency; Day is the month start.
+func (r *AnalyticsRepositoryImpl) GetMRRTrendByCurrency(ctx context.Context, months int) ([]service.CurrencyAmount, error) {
+	appID, hasApp := appctx.AppIDFromCtx(ctx)
+	appFilter := ""
+	args := []interface{}{months}
+	if hasApp {
+		appFilter = "AND s.app_id = $2"
+		args = append(args, appID)
+	}
+	query := fmt.Sprintf(`
+		WITH months AS (
+			SELECT generate_series(
+				date_trunc('month', now()) - ($1 - 1) * interval '1 month',
+				date_trunc('month', now()),
+				interval '1 month'
+			) AS month_start
+		)
+		SELECT
+			t.currency,
+			m.month_start,
+			COALESCE(SUM(
+				CASE
+					WHEN s.plan_type = 'monthly' THEN t.amount
+					WHEN s.plan_type = 'annual'  THEN t.amount / 12.0
+					ELSE 0
+				END
+			), 0)::float8 AS mrr,
+			COUNT(t.id)
+		FROM months m
+		JOIN subscriptions s
+			ON s.status = 'active'
+			AND date_trunc('month', s.created_at) <= m.month_start
+			AND (s.expires_at >= m.month_start + interval '1 month' OR s.status != 'expired')
+			%s
+		JOIN transactions t
+			ON t.subscription_id = s.id
+			AND t.status = 'success'
+			AND date_trunc('month', t.created_at) = m.month_start
+		GROUP BY m.month_start, t.currency
+		ORDER BY m.month_start ASC, t.currency
+	`, appFilter)
+	return r.queryCurrencyAmounts(ctx, query, args...)
+}
+
+func (r *AnalyticsRepositoryImpl) queryCurrencyAmounts(ctx context.Context, query string, args ...interface{}) ([]service.CurrencyAmount, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]service.CurrencyAmount, 0)
+	for rows.Next() {
+		var a service.CurrencyAmount
+		if err := rows.Scan(&a.Currency, &a.Day, &a.Amount, &a.Count); err != nil {
+			return nil, err
+		}
+		result = append(result, a)
+	}
+	return result, rows.Err()
+}
+
 // GetSubscriptionStatusCounts returns counts broken down by status.
 func (r *AnalyticsRepositoryImpl) GetSubscriptionStatusCounts(ctx context.Context) (*domainRepo.SubscriptionStatusCounts, error) {
 	appID, hasApp := appctx.AppIDFromCtx(ctx)
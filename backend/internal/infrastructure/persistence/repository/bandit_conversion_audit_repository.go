@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+)
+
+// conversionCorrectionsJoin sums the currency corrections of each conversion
+// event e and picks the rate of the latest one
+const conversionCorrectionsJoin = `
+	LEFT JOIN LATERAL (
+		SELECT SUM(c.normalized_reward_value) AS delta,
+		       (array_agg(c.conversion_rate ORDER BY c.created_at DESC))[1] AS rate,
+		       (array_agg(c.rate_source ORDER BY c.created_at DESC))[1] AS source,
+		       COUNT(*) AS corrections
+		FROM bandit_conversion_events c
+		WHERE c.corrects_event_id = e.id
+		  AND c.event_type = 'currency_correction'
+	) c ON TRUE`
+
+// ListConversionAudit lists an experiment's conversion events newest first,
+// with corrections folded into their normalized amount and rate
+func (r *PostgresBanditRepository) ListConversionAudit(ctx context.Context, experimentID uuid.UUID, query service.ConversionAuditQuery) ([]service.ConversionAuditRecord, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT e.id, e.arm_id, e.event_type, e.original_reward_value, COALESCE(e.original_currency, ''),
+		       e.normalized_reward_value + COALESCE(c.delta, 0), COALESCE(e.normalized_currency, ''),
+		       COALESCE(c.rate, e.conversion_rate, 0), COALESCE(c.source, e.rate_source, ''),
+		       COALESCE(c.corrections, 0) > 0, e.occurred_at
+		FROM bandit_conversion_events e`+conversionCorrectionsJoin+`
+		WHERE e.experiment_id = $1
+		  AND e.event_type <> 'currency_correction'
+		  AND ($2 = '' OR e.original_currency = $2)
+		  AND ($3::timestamptz IS NULL OR e.occurred_at >= $3)
+		  AND ($4::timestamptz IS NULL OR e.occurred_at < $4)
+		ORDER BY e.occurred_at DESC, e.id DESC
+		LIMIT $5
+	`, experimentID, query.Currency, nullableTime(query.Since), nullableTime(query.Until), query.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query conversion audit: %w", err)
+	}
+	defer rows.Close()
+
+	records := make([]service.ConversionAuditRecord, 0)
+	for rows.Next() {
+		var record service.ConversionAuditRecord
+		if err := rows.Scan(
+			&record.ID,
+			&record.ArmID,
+			&record.EventType,
+			&record.OriginalAmount,
+			&record.OriginalCurrency,
+			&record.NormalizedAmount,
+			&record.NormalizedCurrency,
+			&record.ConversionRate,
+			&record.RateSource,
+			&record.Corrected,
+			&record.OccurredAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan conversion audit record: %w", err)
+		}
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate conversion audit: %w", err)
+	}
+
+	return records, nil
+}
+
+// reconvertedEvent is a converted reward selected for re-conversion, at its
+// currently effective normalized amount and rate
+type reconvertedEvent struct {
+	id          uuid.UUID
+	armID       uuid.UUID
+	userID      *uuid.UUID
+	original    float64
+	normalized  float64
+	rate        float64
+	productCost float64
+}
+
+// ReconvertCurrency re-converts the experiment's rewards in req.Currency at
+// req.Rate. Each change is appended as a currency_correction event and folded
+// into the arm stats and the objective stats of basis, all in one transaction.
+func (r *PostgresBanditRepository) ReconvertCurrency(ctx context.Context, experimentID uuid.UUID, req service.CurrencyReconversion, basis service.RevenueBasis) (*service.CurrencyReconversionResult, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin reconversion transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT e.id, e.arm_id, e.user_id, e.original_reward_value,
+		       e.normalized_reward_value + COALESCE(c.delta, 0),
+		       COALESCE(c.rate, e.conversion_rate),
+		       COALESCE((e.metadata->>'product_cost')::double precision, 0)
+		FROM bandit_conversion_events e`+conversionCorrectionsJoin+`
+		WHERE e.experiment_id = $1
+		  AND e.original_currency = $2
+		  AND e.normalized_currency = 'USD'
+		  AND e.conversion_rate IS NOT NULL
+		  AND e.event_type IN ('direct_reward', 'delayed_conversion')
+		  AND ($3::timestamptz IS NULL OR e.occurred_at >= $3)
+		  AND ($4::timestamptz IS NULL OR e.occurred_at < $4)
+		ORDER BY e.occurred_at, e.id
+		FOR UPDATE OF e
+	`, experimentID, req.Currency, nullableTime(req.Since), nullableTime(req.Until))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reconversion events: %w", err)
+	}
+
+	var events []reconvertedEvent
+	for rows.Next() {
+		var event reconvertedEvent
+		if err := rows.Scan(&event.id, &event.armID, &event.userID, &event.original, &event.normalized, &event.rate, &event.productCost); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan reconversion event: %w", err)
+		}
+		events = append(events, event)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate reconversion events: %w", err)
+	}
+
+	type rewardSwap struct{ previous, corrected float64 }
+	result := &service.CurrencyReconversionResult{ArmIDs: []uuid.UUID{}}
+	swaps := make(map[uuid.UUID][]rewardSwap)
+	now := time.Now().UTC()
+
+	for _, event := range events {
+		if math.Abs(event.rate-req.Rate) < 1e-12 {
+			continue
+		}
+		corrected := service.CorrectedConversionReward(event.normalized, event.productCost, event.rate, req.Rate)
+		if event.normalized <= 0 || corrected <= 0 {
+			result.EventsSkipped++
+			continue
+		}
+
+		eventID := event.id
+		if _, err := r.insertConversionEventTx(ctx, tx, &service.ConversionEvent{
+			ExperimentID:          experimentID,
+			ArmID:                 event.armID,
+			UserID:                event.userID,
+			EventType:             service.ConversionEventTypeCurrencyCorrection,
+			OriginalRewardValue:   event.original,
+			OriginalCurrency:      req.Currency,
+			NormalizedRewardValue: corrected - event.normalized,
+			NormalizedCurrency:    "USD",
+			ConversionRate:        req.Rate,
+			RateSource:            req.Source,
+			CorrectsEventID:       &eventID,
+			Metadata: map[string]interface{}{
+				"source":           "currency_reconversion",
+				"previous_rate":    event.rate,
+				"previous_reward":  event.normalized,
+				"corrected_reward": corrected,
+			},
+			OccurredAt: now,
+		}); err != nil {
+			return nil, err
+		}
+
+		if _, seen := swaps[event.armID]; !seen {
+			result.ArmIDs = append(result.ArmIDs, event.armID)
+		}
+		swaps[event.armID] = append(swaps[event.armID], rewardSwap{event.normalized, corrected})
+		result.EventsCorrected++
+		result.RevenueDelta += corrected - event.normalized
+	}
+
+	for _, armID := range result.ArmIDs {
+		stats, err := r.loadArmStatsTx(ctx, tx, armID)
+		if err != nil {
+			return nil, err
+		}
+		var armDelta float64
+		for _, swap := range swaps[armID] {
+			stats.ReplaceReward(swap.previous, swap.corrected)
+			armDelta += swap.corrected - swap.previous
+		}
+		if err := r.saveArmStatsTx(ctx, tx, stats); err != nil {
+			return nil, err
+		}
+
+		if _, err := tx.Exec(ctx, `
+			UPDATE bandit_arm_objective_stats
+			SET total_revenue = total_revenue + $2,
+			    updated_at = NOW()
+			WHERE arm_id = $1
+			  AND reward_basis = CASE WHEN objective_type = 'conversion' THEN 'gross' ELSE $3 END
+		`, armID, armDelta, string(service.ObjectiveStatsBasis(service.ObjectiveRevenue, basis))); err != nil {
+			return nil, fmt.Errorf("failed to adjust objective stats revenue: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit reconversion transaction: %w", err)
+	}
+
+	return result, nil
+}
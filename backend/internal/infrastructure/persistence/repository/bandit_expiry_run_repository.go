@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+)
+
+// GetExpiredPendingRewardsPage lists unprocessed rewards that expired before
+// expiredBefore, after the cursor, in (expires_at, id) order. Rewards the
+// sweep skipped or failed stay behind the cursor instead of being refetched.
+func (r *PostgresBanditRepository) GetExpiredPendingRewardsPage(ctx context.Context, after *service.ExpiredRewardCursor, expiredBefore time.Time, limit int) ([]*service.PendingReward, error) {
+	var afterExpiresAt *time.Time
+	var afterID *uuid.UUID
+	if after != nil {
+		afterExpiresAt, afterID = &after.ExpiresAt, &after.ID
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, experiment_id, arm_id, user_id, assigned_at, expires_at, converted,
+		       conversion_value, conversion_currency, converted_at, processed_at
+		FROM bandit_pending_rewards
+		WHERE converted = FALSE
+		  AND processed_at IS NULL
+		  AND expires_at < $1
+		  AND ($2::timestamptz IS NULL OR (expires_at, id) > ($2::timestamptz, $3::uuid))
+		ORDER BY expires_at, id
+		LIMIT $4
+	`, expiredBefore, afterExpiresAt, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query expired rewards page: %w", err)
+	}
+	defer rows.Close()
+
+	var rewards []*service.PendingReward
+	for rows.Next() {
+		var reward service.PendingReward
+		if err := scanPendingReward(rows, &reward); err != nil {
+			return nil, fmt.Errorf("failed to scan pending reward: %w", err)
+		}
+		rewards = append(rewards, &reward)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate expired rewards page: %w", err)
+	}
+	return rewards, nil
+}
+
+const expiryRunColumns = `id, status, trigger, batch_size, rate_per_second, max_batches, expired_before,
+	cursor_expires_at, cursor_id, batches_done, processed, skipped, failed, last_error,
+	started_at, updated_at, completed_at`
+
+// CreateExpiryRun inserts a running sweep. The partial unique index on
+// running sweeps turns a concurrent start into ErrExpiryRunInProgress.
+func (r *PostgresBanditRepository) CreateExpiryRun(ctx context.Context, run *service.PendingRewardExpiryRun) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO bandit_expiry_runs (id, status, trigger, batch_size, rate_per_second, max_batches, expired_before, started_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, run.ID, run.Status, run.Trigger, run.BatchSize, run.RatePerSecond, run.MaxBatches, run.ExpiredBefore, run.StartedAt, run.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return service.ErrExpiryRunInProgress
+		}
+		return fmt.Errorf("failed to insert expiry run: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresBanditRepository) GetExpiryRun(ctx context.Context, id uuid.UUID) (*service.PendingRewardExpiryRun, error) {
+	run, err := scanExpiryRun(r.pool.QueryRow(ctx, `SELECT `+expiryRunColumns+` FROM bandit_expiry_runs WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expiry run: %w", err)
+	}
+	return run, nil
+}
+
+func (r *PostgresBanditRepository) GetRunningExpiryRun(ctx context.Context) (*service.PendingRewardExpiryRun, error) {
+	run, err := scanExpiryRun(r.pool.QueryRow(ctx, `SELECT `+expiryRunColumns+` FROM bandit_expiry_runs WHERE status = 'running'`))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get running expiry run: %w", err)
+	}
+	return run, nil
+}
+
+func (r *PostgresBanditRepository) ListExpiryRuns(ctx context.Context, limit int) ([]*service.PendingRewardExpiryRun, error) {
+	rows, err := r.pool.Query(ctx, `SELECT `+expiryRunColumns+` FROM bandit_expiry_runs ORDER BY started_at DESC, id LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query expiry runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := make([]*service.PendingRewardExpiryRun, 0)
+	for rows.Next() {
+		run, err := scanExpiryRun(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan expiry run: %w", err)
+		}
+		runs = append(runs, run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate expiry runs: %w", err)
+	}
+	return runs, nil
+}
+
+func (r *PostgresBanditRepository) SaveExpiryRun(ctx context.Context, run *service.PendingRewardExpiryRun) error {
+	var cursorExpiresAt *time.Time
+	var cursorID *uuid.UUID
+	if run.Cursor != nil {
+		cursorExpiresAt, cursorID = &run.Cursor.ExpiresAt, &run.Cursor.ID
+	}
+	if _, err := r.pool.Exec(ctx, `
+		UPDATE bandit_expiry_runs
+		SET status = $2, cursor_expires_at = $3, cursor_id = $4, batches_done = $5,
+		    processed = $6, skipped = $7, failed = $8, last_error = $9, updated_at = $10, completed_at = $11
+		WHERE id = $1
+	`, run.ID, run.Status, cursorExpiresAt, cursorID, run.BatchesDone,
+		run.Processed, run.Skipped, run.Failed, run.LastError, run.UpdatedAt, run.CompletedAt); err != nil {
+		return fmt.Errorf("failed to save expiry run: %w", err)
+	}
+	return nil
+}
+
+func scanExpiryRun(row pgx.Row) (*service.PendingRewardExpiryRun, error) {
+	var run service.PendingRewardExpiryRun
+	var cursorExpiresAt *time.Time
+	var cursorID *uuid.UUID
+	if err := row.Scan(&run.ID, &run.Status, &run.Trigger, &run.BatchSize, &run.RatePerSecond, &run.MaxBatches, &run.ExpiredBefore,
+		&cursorExpiresAt, &cursorID, &run.BatchesDone, &run.Processed, &run.Skipped, &run.Failed, &run.LastError,
+		&run.StartedAt, &run.UpdatedAt, &run.CompletedAt); err != nil {
+		return nil, err
+	}
+	if cursorExpiresAt != nil && cursorID != nil {
+		run.Cursor = &service.ExpiredRewardCursor{ExpiresAt: *cursorExpiresAt, ID: *cursorID}
+	}
+	return &run, nil
+}
@@ -16,13 +16,22 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/bivex/paywall-iap/internal/appctx"
+	"github.com/bivex/paywall-iap/internal/clock"
 	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/infrastructure/metrics"
+)
+
+var conversionDuplicateAttempts = metrics.NewCounterVec(
+	"bandit_conversion_duplicates_total",
+	"Conversions rejected because their transaction was already counted, by source",
+	"source",
 )
 
 // PostgresBanditRepository implements bandit data persistence using PostgreSQL
 type PostgresBanditRepository struct {
 	pool   *pgxpool.Pool
 	logger *zap.Logger
+	now    func() time.Time
 }
 
 type pendingRewardScanner interface {
@@ -34,9 +43,17 @@ func NewPostgresBanditRepository(pool *pgxpool.Pool, logger *zap.Logger) *Postgr
 	return &PostgresBanditRepository{
 		pool:   pool,
 		logger: logger,
+		now:    time.Now,
 	}
 }
 
+// WithClock makes expiry checks and default event times use c instead of
+// the database clock; bookkeeping columns like updated_at still use NOW()
+func (r *PostgresBanditRepository) WithClock(c clock.Clock) *PostgresBanditRepository {
+	r.now = c.Now
+	return r
+}
+
 func scanPendingReward(scanner pendingRewardScanner, reward *service.PendingReward) error {
 	var conversionValue sql.NullFloat64
 	var conversionCurrency sql.NullString
@@ -78,13 +95,16 @@ func scanPendingReward(scanner pendingRewardScanner, reward *service.PendingRewa
 	return nil
 }
 
-// GetArms retrieves all arms for an experiment
+// GetArms retrieves all arms for an experiment, archived arms included. An
+// archived experiment has no arms, so nothing is selected for it.
 func (r *PostgresBanditRepository) GetArms(ctx context.Context, experimentID uuid.UUID) ([]service.Arm, error) {
 	query := `
-		SELECT id, experiment_id, name, description, is_control, traffic_weight
-		FROM ab_test_arms
-		WHERE experiment_id = $1
-		ORDER BY is_control DESC, name ASC
+		SELECT a.id, a.experiment_id, a.name, a.description, a.is_control, a.traffic_weight,
+		       a.archived_at, COALESCE(a.reassignment_policy, '')
+		FROM ab_test_arms a
+		JOIN ab_tests e ON e.id = a.experiment_id
+		WHERE a.experiment_id = $1 AND e.status <> 'archived'
+		ORDER BY a.is_control DESC, a.name ASC
 	`
 
 	rows, err := r.pool.Query(ctx, query, experimentID)
@@ -103,6 +123,8 @@ func (r *PostgresBanditRepository) GetArms(ctx context.Context, experimentID uui
 			&arm.Description,
 			&arm.IsControl,
 			&arm.TrafficWeight,
+			&arm.ArchivedAt,
+			&arm.ReassignmentPolicy,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan arm: %w", err)
 		}
@@ -119,7 +141,8 @@ func (r *PostgresBanditRepository) GetArms(ctx context.Context, experimentID uui
 // GetArmStats retrieves statistics for a specific arm
 func (r *PostgresBanditRepository) GetArmStats(ctx context.Context, armID uuid.UUID) (*service.ArmStats, error) {
 	query := `
-		SELECT arm_id, alpha, beta, samples, conversions, revenue, avg_reward, updated_at
+		SELECT arm_id, alpha, beta, samples, conversions, revenue, avg_reward,
+		       log_revenue_sum, log_revenue_sq_sum, updated_at
 		FROM ab_test_arm_stats
 		WHERE arm_id = $1
 	`
@@ -133,6 +156,8 @@ func (r *PostgresBanditRepository) GetArmStats(ctx context.Context, armID uuid.U
 		&stats.Conversions,
 		&stats.Revenue,
 		&stats.AvgReward,
+		&stats.LogRevenueSum,
+		&stats.LogRevenueSqSum,
 		&stats.UpdatedAt,
 	)
 
@@ -156,7 +181,7 @@ func (r *PostgresBanditRepository) GetArmStats(ctx context.Context, armID uuid.U
 			Conversions: 0,
 			Revenue:     0,
 			AvgReward:   0,
-			UpdatedAt:   time.Now(),
+			UpdatedAt:   r.now(),
 		}, nil
 	}
 
@@ -170,8 +195,9 @@ func (r *PostgresBanditRepository) GetArmStats(ctx context.Context, armID uuid.U
 // UpdateArmStats updates statistics for a specific arm
 func (r *PostgresBanditRepository) UpdateArmStats(ctx context.Context, stats *service.ArmStats) error {
 	query := `
-		INSERT INTO ab_test_arm_stats (arm_id, alpha, beta, samples, conversions, revenue, avg_reward)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO ab_test_arm_stats (arm_id, alpha, beta, samples, conversions, revenue, avg_reward,
+		                               log_revenue_sum, log_revenue_sq_sum)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 		ON CONFLICT (arm_id)
 		DO UPDATE SET
 			alpha = $2,
@@ -180,6 +206,8 @@ func (r *PostgresBanditRepository) UpdateArmStats(ctx context.Context, stats *se
 			conversions = $5,
 			revenue = $6,
 			avg_reward = $7,
+			log_revenue_sum = $8,
+			log_revenue_sq_sum = $9,
 			updated_at = NOW()
 	`
 
@@ -191,6 +219,8 @@ func (r *PostgresBanditRepository) UpdateArmStats(ctx context.Context, stats *se
 		stats.Conversions,
 		stats.Revenue,
 		stats.AvgReward,
+		stats.LogRevenueSum,
+		stats.LogRevenueSqSum,
 	)
 
 	if err != nil {
@@ -215,7 +245,7 @@ func (r *PostgresBanditRepository) CreateAssignment(ctx context.Context, assignm
 	}
 	defer tx.Rollback(ctx)
 
-	assignedAt := normalizeOccurredAt(assignment.AssignedAt)
+	assignedAt := r.normalizeOccurredAt(assignment.AssignedAt)
 	var metadataJSON []byte
 	if assignment.Metadata != nil {
 		metadataJSON, err = json.Marshal(assignment.Metadata)
@@ -223,15 +253,21 @@ func (r *PostgresBanditRepository) CreateAssignment(ctx context.Context, assignm
 			return fmt.Errorf("failed to marshal assignment event metadata: %w", err)
 		}
 	}
+	eventType := assignment.EventType
+	if eventType == "" {
+		eventType = service.AssignmentEventTypeAssigned
+	}
 	var assignmentID uuid.UUID
 	err = tx.QueryRow(ctx, `
-		INSERT INTO ab_test_assignments (id, experiment_id, user_id, arm_id, assigned_at, expires_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO ab_test_assignments (id, experiment_id, user_id, arm_id, assigned_at, expires_at, app_version)
+		VALUES ($1, $2, $3, $4, $5, $6, (SELECT app_version FROM users WHERE id = $3))
 		ON CONFLICT (experiment_id, user_id)
 		DO UPDATE SET
 			arm_id = EXCLUDED.arm_id,
 			assigned_at = EXCLUDED.assigned_at,
-			expires_at = EXCLUDED.expires_at
+			expires_at = EXCLUDED.expires_at,
+			app_version = EXCLUDED.app_version,
+			excluded_at = NULL
 		RETURNING id
 	`,
 		assignment.ID,
@@ -261,7 +297,7 @@ func (r *PostgresBanditRepository) CreateAssignment(ctx context.Context, assignm
 		assignment.ExperimentID,
 		assignment.UserID,
 		assignment.ArmID,
-		service.AssignmentEventTypeAssigned,
+		eventType,
 		metadataJSON,
 		assignedAt,
 	)
@@ -283,26 +319,33 @@ func (r *PostgresBanditRepository) CreateAssignment(ctx context.Context, assignm
 	return nil
 }
 
-// GetActiveAssignment retrieves the active (non-expired) assignment for a user in an experiment
+// GetActiveAssignment retrieves the active (non-expired) assignment for a user
+// in an experiment; archived experiments have none
 func (r *PostgresBanditRepository) GetActiveAssignment(ctx context.Context, experimentID, userID uuid.UUID) (*service.Assignment, error) {
 	query := `
-		SELECT id, experiment_id, user_id, arm_id, assigned_at, expires_at
-		FROM ab_test_assignments
-		WHERE experiment_id = $1
-			AND user_id = $2
-			AND expires_at > NOW()
-		ORDER BY assigned_at DESC
+		SELECT a.id, a.experiment_id, a.user_id, a.arm_id, a.assigned_at, a.expires_at, a.excluded_at,
+		       CASE WHEN arm.archived_at IS NOT NULL THEN COALESCE(arm.reassignment_policy, 'reassign') ELSE '' END
+		FROM ab_test_assignments a
+		JOIN ab_test_arms arm ON arm.id = a.arm_id
+		JOIN ab_tests e ON e.id = a.experiment_id
+		WHERE a.experiment_id = $1
+			AND a.user_id = $2
+			AND a.expires_at > $3
+			AND e.status <> 'archived'
+		ORDER BY a.assigned_at DESC
 		LIMIT 1
 	`
 
 	var assignment service.Assignment
-	err := r.pool.QueryRow(ctx, query, experimentID, userID).Scan(
+	err := r.pool.QueryRow(ctx, query, experimentID, userID, r.now()).Scan(
 		&assignment.ID,
 		&assignment.ExperimentID,
 		&assignment.UserID,
 		&assignment.ArmID,
 		&assignment.AssignedAt,
 		&assignment.ExpiresAt,
+		&assignment.ExcludedAt,
+		&assignment.ArchivedArmPolicy,
 	)
 
 	if err == pgx.ErrNoRows {
@@ -316,6 +359,110 @@ func (r *PostgresBanditRepository) GetActiveAssignment(ctx context.Context, expe
 	return &assignment, nil
 }
 
+// ListArchivedArmAssignments returns live, not yet excluded assignments to
+// archived arms whose policy is reassign or exclude. uuid.Nil lists every
+// archived arm.
+func (r *PostgresBanditRepository) ListArchivedArmAssignments(ctx context.Context, armID uuid.UUID, limit int) ([]service.Assignment, error) {
+	query := `
+		SELECT a.id, a.experiment_id, a.user_id, a.arm_id, a.assigned_at, a.expires_at,
+		       COALESCE(arm.reassignment_policy, 'reassign')
+		FROM ab_test_assignments a
+		JOIN ab_test_arms arm ON arm.id = a.arm_id
+		WHERE arm.archived_at IS NOT NULL
+			AND COALESCE(arm.reassignment_policy, 'reassign') <> 'keep'
+			AND ($1::uuid IS NULL OR a.arm_id = $1)
+			AND a.excluded_at IS NULL
+			AND a.expires_at > $3
+		ORDER BY a.assigned_at ASC
+		LIMIT $2
+	`
+
+	var armFilter *uuid.UUID
+	if armID != uuid.Nil {
+		armFilter = &armID
+	}
+	rows, err := r.pool.Query(ctx, query, armFilter, limit, r.now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query archived arm assignments: %w", err)
+	}
+	defer rows.Close()
+
+	var assignments []service.Assignment
+	for rows.Next() {
+		var assignment service.Assignment
+		if err := rows.Scan(
+			&assignment.ID,
+			&assignment.ExperimentID,
+			&assignment.UserID,
+			&assignment.ArmID,
+			&assignment.AssignedAt,
+			&assignment.ExpiresAt,
+			&assignment.ArchivedArmPolicy,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan archived arm assignment: %w", err)
+		}
+		assignments = append(assignments, assignment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating archived arm assignments: %w", err)
+	}
+
+	return assignments, nil
+}
+
+// ExcludeAssignment drops a user from the experiment for the rest of their
+// assignment and appends an excluded event to its history
+func (r *PostgresBanditRepository) ExcludeAssignment(ctx context.Context, assignment *service.Assignment, excludedAt time.Time) error {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to begin exclusion transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	commandTag, err := tx.Exec(ctx, `
+		UPDATE ab_test_assignments
+		SET excluded_at = $3
+		WHERE id = $1 AND arm_id = $2 AND excluded_at IS NULL
+	`, assignment.ID, assignment.ArmID, excludedAt)
+	if err != nil {
+		return fmt.Errorf("failed to exclude assignment: %w", err)
+	}
+	if commandTag.RowsAffected() == 0 {
+		// Already excluded or replaced
+		return nil
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO bandit_assignment_events (
+			assignment_id,
+			experiment_id,
+			user_id,
+			arm_id,
+			event_type,
+			metadata,
+			occurred_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`,
+		assignment.ID,
+		assignment.ExperimentID,
+		assignment.UserID,
+		assignment.ArmID,
+		service.AssignmentEventTypeExcluded,
+		[]byte(`{"reason":"arm_archived"}`),
+		excludedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to append exclusion event: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit exclusion transaction: %w", err)
+	}
+	assignment.ExcludedAt = &excludedAt
+	return nil
+}
+
 // SaveConversion records a conversion event for an arm
 func (r *PostgresBanditRepository) SaveConversion(ctx context.Context, experimentID, armID, userID uuid.UUID, amount float64) error {
 	return r.AppendConversionEvent(ctx, &service.ConversionEvent{
@@ -325,7 +472,7 @@ func (r *PostgresBanditRepository) SaveConversion(ctx context.Context, experimen
 		EventType:             service.ConversionEventTypeDirectReward,
 		OriginalRewardValue:   amount,
 		NormalizedRewardValue: amount,
-		OccurredAt:            time.Now().UTC(),
+		OccurredAt:            r.now().UTC(),
 	})
 }
 
@@ -351,10 +498,13 @@ func (r *PostgresBanditRepository) AppendConversionEvent(ctx context.Context, ev
 			original_currency,
 			normalized_reward_value,
 			normalized_currency,
+			conversion_rate,
+			rate_source,
+			corrects_event_id,
 			metadata,
 			occurred_at
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), $9, NULLIF($10, ''), $11, $12)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), $9, NULLIF($10, ''), NULLIF($11, 0), NULLIF($12, ''), $13, $14, $15)
 	`,
 		event.ExperimentID,
 		event.ArmID,
@@ -366,8 +516,11 @@ func (r *PostgresBanditRepository) AppendConversionEvent(ctx context.Context, ev
 		event.OriginalCurrency,
 		event.NormalizedRewardValue,
 		event.NormalizedCurrency,
+		event.ConversionRate,
+		event.RateSource,
+		nullableUUID(event.CorrectsEventID),
 		metadataJSON,
-		normalizeOccurredAt(event.OccurredAt),
+		r.normalizeOccurredAt(event.OccurredAt),
 	)
 	if err != nil {
 		return fmt.Errorf("failed to append conversion event: %w", err)
@@ -386,23 +539,30 @@ func (r *PostgresBanditRepository) AppendImpressionEvent(ctx context.Context, ev
 		}
 	}
 
+	var placement *string
+	if event.Placement != "" {
+		placement = &event.Placement
+	}
+
 	_, err = r.pool.Exec(ctx, `
 		INSERT INTO bandit_impression_events (
 			experiment_id,
 			arm_id,
 			user_id,
 			event_type,
+			placement,
 			metadata,
 			occurred_at
 		)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 	`,
 		event.ExperimentID,
 		event.ArmID,
 		event.UserID,
 		event.EventType,
+		placement,
 		metadataJSON,
-		normalizeOccurredAt(event.OccurredAt),
+		r.normalizeOccurredAt(event.OccurredAt),
 	)
 	if err != nil {
 		return fmt.Errorf("failed to append impression event: %w", err)
@@ -447,7 +607,7 @@ func (r *PostgresBanditRepository) AppendWinnerRecommendationEvent(ctx context.C
 		event.ObservedSamples,
 		event.MinSampleSize,
 		detailsJSON,
-		normalizeOccurredAt(event.OccurredAt),
+		r.normalizeOccurredAt(event.OccurredAt),
 	)
 	if err != nil {
 		var pgErr *pgconn.PgError
@@ -463,13 +623,56 @@ func (r *PostgresBanditRepository) AppendWinnerRecommendationEvent(ctx context.C
 	return nil
 }
 
-func (r *PostgresBanditRepository) ProcessPendingConversion(ctx context.Context, transactionID, userID uuid.UUID, conversionValue float64, currency string, processedAt time.Time) (*service.PendingReward, bool, error) {
+// ClaimConversion reserves transactionID for a conversion of armID. It
+// reports false, counting a duplicate attempt, when the transaction was
+// already counted.
+func (r *PostgresBanditRepository) ClaimConversion(ctx context.Context, transactionID, experimentID, armID uuid.UUID, source service.ConversionEventType) (bool, error) {
+	result, err := r.pool.Exec(ctx, `
+		INSERT INTO bandit_conversion_transactions (transaction_id, experiment_id, arm_id, source)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (transaction_id) DO NOTHING
+	`, transactionID, experimentID, armID, source)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim conversion transaction: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		conversionDuplicateAttempts.Inc(string(source))
+		return false, nil
+	}
+	return true, nil
+}
+
+// ReleaseConversion drops the claim on transactionID after its conversion
+// failed to record
+func (r *PostgresBanditRepository) ReleaseConversion(ctx context.Context, transactionID uuid.UUID) error {
+	if _, err := r.pool.Exec(ctx, `DELETE FROM bandit_conversion_transactions WHERE transaction_id = $1`, transactionID); err != nil {
+		return fmt.Errorf("failed to release conversion transaction: %w", err)
+	}
+	return nil
+}
+
+// ProcessPendingConversion credits the user's most recent open pending reward
+// with the conversion. A transaction already counted on any path returns
+// service.ErrDuplicateConversion.
+func (r *PostgresBanditRepository) ProcessPendingConversion(ctx context.Context, transactionID, userID uuid.UUID, conversionValue float64, currency string, normalize service.ConversionNormalizer, processedAt time.Time) (*service.PendingReward, bool, error) {
 	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
 		return nil, false, fmt.Errorf("failed to begin pending conversion transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
+	// A replay must not credit a different pending reward than the original
+	var counted bool
+	if err := tx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM bandit_conversion_transactions WHERE transaction_id = $1)
+	`, transactionID).Scan(&counted); err != nil {
+		return nil, false, fmt.Errorf("failed to check conversion transaction: %w", err)
+	}
+	if counted {
+		conversionDuplicateAttempts.Inc(string(service.ConversionEventTypeDelayedConversion))
+		return nil, false, fmt.Errorf("%w: %s", service.ErrDuplicateConversion, transactionID)
+	}
+
 	matchedPending := &service.PendingReward{}
 	err = scanPendingReward(tx.QueryRow(ctx, `
 		SELECT id, experiment_id, arm_id, user_id, assigned_at, expires_at, converted,
@@ -479,6 +682,12 @@ func (r *PostgresBanditRepository) ProcessPendingConversion(ctx context.Context,
 		  AND converted = FALSE
 		  AND processed_at IS NULL
 		  AND expires_at > $2
+		  -- Send-time deliveries rewarded on opens, or not yet sent, take no purchases
+		  AND NOT EXISTS (
+			SELECT 1 FROM notification_send_time_deliveries d
+			WHERE d.pending_reward_id = bandit_pending_rewards.id
+			  AND (d.reward_event = 'open' OR d.scheduled_for > $2)
+		  )
 		ORDER BY assigned_at DESC
 		LIMIT 1
 		FOR UPDATE
@@ -490,6 +699,28 @@ func (r *PostgresBanditRepository) ProcessPendingConversion(ctx context.Context,
 		return nil, false, fmt.Errorf("failed to load pending reward for conversion: %w", err)
 	}
 
+	claim, err := tx.Exec(ctx, `
+		INSERT INTO bandit_conversion_transactions (transaction_id, experiment_id, arm_id, source)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (transaction_id) DO NOTHING
+	`, transactionID, matchedPending.ExperimentID, matchedPending.ArmID, service.ConversionEventTypeDelayedConversion)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to claim conversion transaction: %w", err)
+	}
+	if claim.RowsAffected() == 0 {
+		// A concurrent delivery of the same transaction won
+		conversionDuplicateAttempts.Inc(string(service.ConversionEventTypeDelayedConversion))
+		return nil, false, fmt.Errorf("%w: %s", service.ErrDuplicateConversion, transactionID)
+	}
+
+	// The arm is credited in the matched experiment's currency and basis
+	normalized := normalize.Normalize(ctx, matchedPending, conversionValue, currency)
+	metadata := map[string]interface{}{
+		"source": "postgres_bandit_repository",
+	}
+	for key, value := range normalized.Metadata {
+		metadata[key] = value
+	}
 	inserted, err := r.insertConversionEventTx(ctx, tx, &service.ConversionEvent{
 		ExperimentID:          matchedPending.ExperimentID,
 		ArmID:                 matchedPending.ArmID,
@@ -499,12 +730,12 @@ func (r *PostgresBanditRepository) ProcessPendingConversion(ctx context.Context,
 		EventType:             service.ConversionEventTypeDelayedConversion,
 		OriginalRewardValue:   conversionValue,
 		OriginalCurrency:      currency,
-		NormalizedRewardValue: conversionValue,
-		NormalizedCurrency:    currency,
-		Metadata: map[string]interface{}{
-			"source": "postgres_bandit_repository",
-		},
-		OccurredAt: processedAt,
+		NormalizedRewardValue: normalized.Value,
+		NormalizedCurrency:    normalized.Currency,
+		ConversionRate:        normalized.Rate.Rate,
+		RateSource:            normalized.Rate.Source,
+		Metadata:              metadata,
+		OccurredAt:            processedAt,
 	})
 	if err != nil {
 		return nil, false, err
@@ -516,7 +747,7 @@ func (r *PostgresBanditRepository) ProcessPendingConversion(ctx context.Context,
 		return matchedPending, false, nil
 	}
 
-	if err := r.applyRewardToArmTx(ctx, tx, matchedPending.ArmID, conversionValue); err != nil {
+	if err := r.applyRewardToArmTx(ctx, tx, matchedPending.ArmID, normalized.Value); err != nil {
 		return nil, false, err
 	}
 
@@ -662,10 +893,10 @@ func (r *PostgresBanditRepository) GetAssignmentHistory(ctx context.Context, use
 func (r *PostgresBanditRepository) CleanupExpiredAssignments(ctx context.Context, olderThan time.Duration) (int64, error) {
 	query := `
 		DELETE FROM ab_test_assignments
-		WHERE expires_at < NOW() - $1::interval
+		WHERE expires_at < $1
 	`
 
-	result, err := r.pool.Exec(ctx, query, olderThan.String())
+	result, err := r.pool.Exec(ctx, query, r.now().Add(-olderThan))
 	if err != nil {
 		return 0, fmt.Errorf("failed to cleanup expired assignments: %w", err)
 	}
@@ -762,10 +993,10 @@ func (r *PostgresBanditRepository) ListObjectiveSyncExperimentIDs(ctx context.Co
 func (r *PostgresBanditRepository) CleanupStaleUserContext(ctx context.Context, olderThan time.Duration) (int64, error) {
 	query := `
 		DELETE FROM bandit_user_context
-		WHERE updated_at < NOW() - $1::interval
+		WHERE updated_at < $1
 	`
 
-	result, err := r.pool.Exec(ctx, query, olderThan.String())
+	result, err := r.pool.Exec(ctx, query, r.now().Add(-olderThan))
 	if err != nil {
 		return 0, fmt.Errorf("failed to cleanup stale user context: %w", err)
 	}
@@ -778,7 +1009,8 @@ func (r *PostgresBanditRepository) CleanupStaleUserContext(ctx context.Context,
 // GetAllArmStatsForExperiment retrieves statistics for all arms in an experiment
 func (r *PostgresBanditRepository) GetAllArmStatsForExperiment(ctx context.Context, experimentID uuid.UUID) (map[uuid.UUID]*service.ArmStats, error) {
 	query := `
-		SELECT s.arm_id, s.alpha, s.beta, s.samples, s.conversions, s.revenue, s.avg_reward, s.updated_at
+		SELECT s.arm_id, s.alpha, s.beta, s.samples, s.conversions, s.revenue, s.avg_reward,
+		       s.log_revenue_sum, s.log_revenue_sq_sum, s.updated_at
 		FROM ab_test_arm_stats s
 		INNER JOIN ab_test_arms a ON a.id = s.arm_id
 		WHERE a.experiment_id = $1
@@ -801,6 +1033,8 @@ func (r *PostgresBanditRepository) GetAllArmStatsForExperiment(ctx context.Conte
 			&s.Conversions,
 			&s.Revenue,
 			&s.AvgReward,
+			&s.LogRevenueSum,
+			&s.LogRevenueSqSum,
 			&s.UpdatedAt,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan arm stats: %w", err)
@@ -944,14 +1178,18 @@ type Experiment struct {
 func (r *PostgresBanditRepository) GetExperimentConfig(ctx context.Context, experimentID uuid.UUID) (*service.ExperimentConfig, error) {
 	query := `
 		SELECT id, objective_type, objective_weights, window_type, window_size, window_min_samples,
-		       enable_contextual, enable_delayed, enable_currency, exploration_alpha
+		       enable_contextual, enable_delayed, enable_currency, exploration_alpha,
+		       reward_basis, product_costs, end_at, window_arm_overrides, conversion_window_hours,
+		       reward_definitions
 		FROM ab_tests
 		WHERE id = $1
 	`
 
 	var config service.ExperimentConfig
-	var objectiveWeightsJSON []byte
+	var objectiveWeightsJSON, productCostsJSON, windowOverridesJSON, rewardDefinitionsJSON []byte
 	var windowType, windowSize, windowMinSamples interface{}
+	var rewardBasis *string
+	var conversionWindowHours *int
 
 	err := r.pool.QueryRow(ctx, query, experimentID).Scan(
 		&config.ID,
@@ -964,6 +1202,12 @@ func (r *PostgresBanditRepository) GetExperimentConfig(ctx context.Context, expe
 		&config.EnableDelayed,
 		&config.EnableCurrency,
 		&config.ExplorationAlpha,
+		&rewardBasis,
+		&productCostsJSON,
+		&config.EndAt,
+		&windowOverridesJSON,
+		&conversionWindowHours,
+		&rewardDefinitionsJSON,
 	)
 
 	if err == pgx.ErrNoRows {
@@ -980,6 +1224,25 @@ func (r *PostgresBanditRepository) GetExperimentConfig(ctx context.Context, expe
 			r.logger.Warn("Failed to parse objective weights", zap.Error(err))
 		}
 	}
+	if rewardBasis != nil {
+		config.RewardBasis = service.RevenueBasis(*rewardBasis)
+	}
+	if conversionWindowHours != nil {
+		config.ConversionWindow = time.Duration(*conversionWindowHours) * time.Hour
+	}
+	if len(productCostsJSON) > 0 {
+		if err := json.Unmarshal(productCostsJSON, &config.ProductCosts); err != nil {
+			r.logger.Warn("Failed to parse product costs", zap.Error(err))
+		}
+	}
+	if len(rewardDefinitionsJSON) > 0 {
+		definitions, err := parseRewardDefinitions(rewardDefinitionsJSON)
+		if err != nil {
+			r.logger.Warn("Failed to parse reward definitions", zap.Error(err))
+		} else {
+			config.RewardDefinitions = definitions
+		}
+	}
 
 	// Build window config if any values are set
 	if windowType != nil || windowSize != nil || windowMinSamples != nil {
@@ -995,10 +1258,74 @@ func (r *PostgresBanditRepository) GetExperimentConfig(ctx context.Context, expe
 			config.WindowConfig.MinSamples = int(wms)
 		}
 	}
+	if len(windowOverridesJSON) > 0 {
+		overrides, err := parseWindowArmOverrides(windowOverridesJSON)
+		if err != nil {
+			r.logger.Warn("Failed to parse window arm overrides", zap.Error(err))
+		} else if len(overrides) > 0 {
+			if config.WindowConfig == nil {
+				config.WindowConfig = &service.WindowConfig{}
+			}
+			config.WindowConfig.ArmOverrides = overrides
+		}
+	}
 
 	return &config, nil
 }
 
+// rewardDefinitionJSON is one entry of ab_tests.reward_definitions
+type rewardDefinitionJSON struct {
+	Name            string  `json:"name"`
+	EventType       string  `json:"event_type"`
+	Reward          float64 `json:"reward"`
+	HalfLifeSeconds int64   `json:"half_life_seconds,omitempty"`
+}
+
+func parseRewardDefinitions(data []byte) ([]service.RewardDefinition, error) {
+	var raw []rewardDefinitionJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	definitions := make([]service.RewardDefinition, 0, len(raw))
+	for _, definition := range raw {
+		definitions = append(definitions, service.RewardDefinition{
+			Name:      definition.Name,
+			EventType: definition.EventType,
+			Reward:    definition.Reward,
+			HalfLife:  time.Duration(definition.HalfLifeSeconds) * time.Second,
+		})
+	}
+	return definitions, nil
+}
+
+// windowOverrideJSON is one entry of ab_tests.window_arm_overrides
+type windowOverrideJSON struct {
+	Type       string `json:"type"`
+	Size       int    `json:"size"`
+	MinSamples int    `json:"min_samples"`
+}
+
+func parseWindowArmOverrides(data []byte) (map[uuid.UUID]*service.WindowConfig, error) {
+	var raw map[string]windowOverrideJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	overrides := make(map[uuid.UUID]*service.WindowConfig, len(raw))
+	for key, override := range raw {
+		armID, err := uuid.Parse(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid arm id %q: %w", key, err)
+		}
+		overrides[armID] = &service.WindowConfig{
+			Type:       service.WindowType(override.Type),
+			Size:       override.Size,
+			MinSamples: override.MinSamples,
+		}
+	}
+	return overrides, nil
+}
+
 // UpdateObjectiveConfig persists objective configuration fields for an experiment.
 func (r *PostgresBanditRepository) UpdateObjectiveConfig(
 	ctx context.Context,
@@ -1034,32 +1361,196 @@ func (r *PostgresBanditRepository) UpdateObjectiveConfig(
 	return nil
 }
 
-// GetUserContext retrieves user context for contextual bandits
+// UpdateRewardDefinitions replaces an experiment's reward definitions; an
+// empty set clears them.
+func (r *PostgresBanditRepository) UpdateRewardDefinitions(
+	ctx context.Context,
+	experimentID uuid.UUID,
+	definitions []service.RewardDefinition,
+) error {
+	var definitionsJSON []byte
+	if len(definitions) > 0 {
+		raw := make([]rewardDefinitionJSON, 0, len(definitions))
+		for _, definition := range definitions {
+			raw = append(raw, rewardDefinitionJSON{
+				Name:            definition.Name,
+				EventType:       definition.EventType,
+				Reward:          definition.Reward,
+				HalfLifeSeconds: int64(definition.HalfLife / time.Second),
+			})
+		}
+		var err error
+		definitionsJSON, err = json.Marshal(raw)
+		if err != nil {
+			return fmt.Errorf("failed to marshal reward definitions: %w", err)
+		}
+	}
+
+	result, err := r.pool.Exec(ctx, `
+		UPDATE ab_tests
+		SET reward_definitions = $2,
+		    updated_at = NOW()
+		WHERE id = $1
+	`, experimentID, definitionsJSON)
+	if err != nil {
+		return fmt.Errorf("failed to update reward definitions: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("experiment not found")
+	}
+
+	return nil
+}
+
+// ListRewardDefinitionExperimentIDs returns the running bandit experiments
+// with a reward definition for eventType, scoped to the app in ctx if any
+func (r *PostgresBanditRepository) ListRewardDefinitionExperimentIDs(ctx context.Context, eventType string) ([]uuid.UUID, error) {
+	filter, err := json.Marshal([]map[string]string{{"event_type": eventType}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal reward definition filter: %w", err)
+	}
+
+	appID, hasApp := appctx.AppIDFromCtx(ctx)
+	appFilter := ""
+	args := []interface{}{filter}
+	if hasApp {
+		appFilter = "AND app_id = $2"
+		args = append(args, appID)
+	}
+	query := fmt.Sprintf(`
+		SELECT id
+		FROM ab_tests
+		WHERE is_bandit = TRUE
+		  AND status = 'running'
+		  AND reward_definitions @> $1::jsonb
+		  %s
+		ORDER BY id`, appFilter)
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reward definition experiments: %w", err)
+	}
+	defer rows.Close()
+
+	ids := make([]uuid.UUID, 0)
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan reward definition experiment id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate reward definition experiments: %w", err)
+	}
+	return ids, nil
+}
+
+// UpdateRewardBasisConfig persists the reward basis and per-product costs for an experiment.
+func (r *PostgresBanditRepository) UpdateRewardBasisConfig(
+	ctx context.Context,
+	experimentID uuid.UUID,
+	basis service.RevenueBasis,
+	productCosts map[string]float64,
+) error {
+	var productCostsJSON []byte
+	var err error
+	if productCosts != nil {
+		productCostsJSON, err = json.Marshal(productCosts)
+		if err != nil {
+			return fmt.Errorf("failed to marshal product costs: %w", err)
+		}
+	}
+
+	query := `
+		UPDATE ab_tests
+		SET reward_basis = $2,
+		    product_costs = $3,
+		    updated_at = NOW()
+		WHERE id = $1
+	`
+
+	result, err := r.pool.Exec(ctx, query, experimentID, string(basis), productCostsJSON)
+	if err != nil {
+		return fmt.Errorf("failed to update reward basis config: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("experiment not found")
+	}
+
+	return nil
+}
+
+// UpdateConversionWindow stores the experiment's conversion window, NULL for
+// the default when window is zero, and moves the expiry of its open pending
+// rewards to assigned_at plus the effective window
+func (r *PostgresBanditRepository) UpdateConversionWindow(ctx context.Context, experimentID uuid.UUID, window, effective time.Duration) error {
+	var hours *int
+	if window > 0 {
+		h := int(window / time.Hour)
+		hours = &h
+	}
+
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to begin conversion window transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `
+		UPDATE ab_tests
+		SET conversion_window_hours = $2,
+		    updated_at = NOW()
+		WHERE id = $1
+	`, experimentID, hours)
+	if err != nil {
+		return fmt.Errorf("failed to update conversion window: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("experiment not found")
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE bandit_pending_rewards
+		SET expires_at = assigned_at + make_interval(secs => $2)
+		WHERE experiment_id = $1
+		  AND converted = FALSE
+		  AND processed_at IS NULL
+	`, experimentID, effective.Seconds()); err != nil {
+		return fmt.Errorf("failed to re-base pending rewards: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit conversion window transaction: %w", err)
+	}
+	return nil
+}
+
+// GetUserContext retrieves user context for contextual bandits, with the
+// user's MMP attribution and lifecycle stage. Users without a stored context
+// get an empty one.
 func (r *PostgresBanditRepository) GetUserContext(ctx context.Context, userID uuid.UUID) (*service.UserContext, error) {
 	query := `
-		SELECT user_id, country, device, app_version, days_since_install, total_spent, last_purchase_at, updated_at
-		FROM bandit_user_context
-		WHERE user_id = $1
+		SELECT COALESCE(c.country, ''), COALESCE(c.device, ''), COALESCE(c.app_version, ''),
+		       COALESCE(c.days_since_install, 0), COALESCE(c.total_spent, 0)::float8, c.last_purchase_at,
+		       COALESCE(ua.channel, ''), COALESCE(ua.campaign, ''), COALESCE(ls.stage, '')
+		FROM (SELECT $1::uuid AS user_id) q
+		LEFT JOIN bandit_user_context c ON c.user_id = q.user_id
+		LEFT JOIN user_acquisitions ua ON ua.user_id = q.user_id
+		LEFT JOIN user_lifecycle_stages ls ON ls.user_id = q.user_id
 	`
 
-	var userCtx service.UserContext
-	var updatedAt time.Time
+	userCtx := service.UserContext{UserID: userID}
 	err := r.pool.QueryRow(ctx, query, userID).Scan(
-		&userCtx.UserID,
 		&userCtx.Country,
 		&userCtx.Device,
 		&userCtx.AppVersion,
 		&userCtx.DaysSinceInstall,
 		&userCtx.TotalSpent,
 		&userCtx.LastPurchaseAt,
-		&updatedAt,
+		&userCtx.AcquisitionChannel,
+		&userCtx.Campaign,
+		&userCtx.LifecycleStage,
 	)
-
-	if err == pgx.ErrNoRows {
-		// Return empty context if not found
-		return &service.UserContext{UserID: userID}, nil
-	}
-
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user context: %w", err)
 	}
@@ -1105,15 +1596,17 @@ func (r *PostgresBanditRepository) SetUserContext(ctx context.Context, userCtx *
 // =====================================================
 
 // GetObjectiveStats retrieves objective-specific statistics for an arm
-func (r *PostgresBanditRepository) GetObjectiveStats(ctx context.Context, armID uuid.UUID, objectiveType service.ObjectiveType) (*service.ArmObjectiveStats, error) {
+func (r *PostgresBanditRepository) GetObjectiveStats(ctx context.Context, armID uuid.UUID, objectiveType service.ObjectiveType, basis service.RevenueBasis) (*service.ArmObjectiveStats, error) {
 	query := `
-		SELECT arm_id, objective_type, alpha, beta, samples, conversions, total_revenue, avg_ltv
+		SELECT arm_id, objective_type, alpha, beta, samples, conversions, total_revenue, avg_ltv, reward_basis,
+		       COALESCE(original_revenue, '{}')
 		FROM bandit_arm_objective_stats
-		WHERE arm_id = $1 AND objective_type = $2
+		WHERE arm_id = $1 AND objective_type = $2 AND reward_basis = $3
 	`
 
+	basis = service.ObjectiveStatsBasis(objectiveType, basis)
 	var stats service.ArmObjectiveStats
-	err := r.pool.QueryRow(ctx, query, armID, objectiveType).Scan(
+	err := r.pool.QueryRow(ctx, query, armID, objectiveType, string(basis)).Scan(
 		&stats.ArmID,
 		&stats.ObjectiveType,
 		&stats.Alpha,
@@ -1122,6 +1615,8 @@ func (r *PostgresBanditRepository) GetObjectiveStats(ctx context.Context, armID
 		&stats.Conversions,
 		&stats.TotalRevenue,
 		&stats.AvgLTV,
+		&stats.RewardBasis,
+		&stats.OriginalRevenue,
 	)
 
 	if err == pgx.ErrNoRows {
@@ -1135,6 +1630,7 @@ func (r *PostgresBanditRepository) GetObjectiveStats(ctx context.Context, armID
 			Conversions:   0,
 			TotalRevenue:  0,
 			AvgLTV:        0,
+			RewardBasis:   basis,
 		}, nil
 	}
 
@@ -1148,9 +1644,9 @@ func (r *PostgresBanditRepository) GetObjectiveStats(ctx context.Context, armID
 // UpdateObjectiveStats updates objective-specific statistics
 func (r *PostgresBanditRepository) UpdateObjectiveStats(ctx context.Context, stats *service.ArmObjectiveStats) error {
 	query := `
-		INSERT INTO bandit_arm_objective_stats (arm_id, objective_type, alpha, beta, samples, conversions, total_revenue, avg_ltv)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		ON CONFLICT (arm_id, objective_type)
+		INSERT INTO bandit_arm_objective_stats (arm_id, objective_type, alpha, beta, samples, conversions, total_revenue, avg_ltv, reward_basis, original_revenue)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (arm_id, objective_type, reward_basis)
 		DO UPDATE SET
 			alpha = $3,
 			beta = $4,
@@ -1158,9 +1654,19 @@ func (r *PostgresBanditRepository) UpdateObjectiveStats(ctx context.Context, sta
 			conversions = $6,
 			total_revenue = $7,
 			avg_ltv = $8,
+			original_revenue = COALESCE($10, bandit_arm_objective_stats.original_revenue),
 			updated_at = NOW()
 	`
 
+	// Syncs from base stats carry no original amounts; keep the stored ones
+	var originalRevenue []byte
+	if stats.OriginalRevenue != nil {
+		var err error
+		if originalRevenue, err = json.Marshal(stats.OriginalRevenue); err != nil {
+			return fmt.Errorf("failed to marshal objective original revenue: %w", err)
+		}
+	}
+
 	_, err := r.pool.Exec(ctx, query,
 		stats.ArmID,
 		stats.ObjectiveType,
@@ -1170,6 +1676,8 @@ func (r *PostgresBanditRepository) UpdateObjectiveStats(ctx context.Context, sta
 		stats.Conversions,
 		stats.TotalRevenue,
 		stats.AvgLTV,
+		string(service.ObjectiveStatsBasis(stats.ObjectiveType, stats.RewardBasis)),
+		originalRevenue,
 	)
 
 	if err != nil {
@@ -1179,15 +1687,17 @@ func (r *PostgresBanditRepository) UpdateObjectiveStats(ctx context.Context, sta
 	return nil
 }
 
-// GetAllObjectiveStats retrieves all objective statistics for an arm
-func (r *PostgresBanditRepository) GetAllObjectiveStats(ctx context.Context, armID uuid.UUID) (map[service.ObjectiveType]*service.ArmObjectiveStats, error) {
+// GetAllObjectiveStats retrieves all objective statistics for an arm under the given basis
+func (r *PostgresBanditRepository) GetAllObjectiveStats(ctx context.Context, armID uuid.UUID, basis service.RevenueBasis) (map[service.ObjectiveType]*service.ArmObjectiveStats, error) {
 	query := `
-		SELECT arm_id, objective_type, alpha, beta, samples, conversions, total_revenue, avg_ltv
+		SELECT arm_id, objective_type, alpha, beta, samples, conversions, total_revenue, avg_ltv, reward_basis,
+		       COALESCE(original_revenue, '{}')
 		FROM bandit_arm_objective_stats
 		WHERE arm_id = $1
+		  AND reward_basis = CASE WHEN objective_type = 'conversion' THEN 'gross' ELSE $2 END
 	`
 
-	rows, err := r.pool.Query(ctx, query, armID)
+	rows, err := r.pool.Query(ctx, query, armID, string(service.ObjectiveStatsBasis(service.ObjectiveRevenue, basis)))
 	if err != nil {
 		return nil, fmt.Errorf("failed to query objective stats: %w", err)
 	}
@@ -1205,6 +1715,8 @@ func (r *PostgresBanditRepository) GetAllObjectiveStats(ctx context.Context, arm
 			&s.Conversions,
 			&s.TotalRevenue,
 			&s.AvgLTV,
+			&s.RewardBasis,
+			&s.OriginalRevenue,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan objective stats: %w", err)
 		}
@@ -1264,22 +1776,18 @@ func (r *PostgresBanditRepository) GetPendingReward(ctx context.Context, id uuid
 	return &reward, nil
 }
 
-// GetPendingRewardsByUser retrieves pending rewards for a user
-func (r *PostgresBanditRepository) GetPendingRewardsByUser(ctx context.Context, userID, experimentID uuid.UUID) ([]*service.PendingReward, error) {
+// GetPendingRewardsByUser retrieves pending rewards for a user, in every
+// experiment when experimentID is nil
+func (r *PostgresBanditRepository) GetPendingRewardsByUser(ctx context.Context, userID uuid.UUID, experimentID *uuid.UUID) ([]*service.PendingReward, error) {
 	query := `
 		SELECT id, experiment_id, arm_id, user_id, assigned_at, expires_at, converted,
 		       conversion_value, conversion_currency, converted_at, processed_at
 		FROM bandit_pending_rewards
-		WHERE user_id = $1 AND ($2::uuid IS NULL OR experiment_id = $2)
+		WHERE user_id = $1 AND ($2::uuid IS NULL OR experiment_id = $2::uuid)
 		ORDER BY assigned_at DESC
 	`
 
-	var experimentParam interface{}
-	if experimentID != uuid.Nil {
-		experimentParam = experimentID
-	}
-
-	rows, err := r.pool.Query(ctx, query, userID, experimentParam)
+	rows, err := r.pool.Query(ctx, query, userID, experimentID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query pending rewards: %w", err)
 	}
@@ -1293,6 +1801,9 @@ func (r *PostgresBanditRepository) GetPendingRewardsByUser(ctx context.Context,
 		}
 		rewards = append(rewards, &reward)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate pending rewards: %w", err)
+	}
 
 	return rewards, nil
 }
@@ -1303,12 +1814,12 @@ func (r *PostgresBanditRepository) GetExpiredPendingRewards(ctx context.Context,
 		SELECT id, experiment_id, arm_id, user_id, assigned_at, expires_at, converted,
 		       conversion_value, conversion_currency, converted_at, processed_at
 		FROM bandit_pending_rewards
-		WHERE expires_at < NOW() AND converted = FALSE AND processed_at IS NULL
+		WHERE expires_at < $2 AND converted = FALSE AND processed_at IS NULL
 		ORDER BY expires_at ASC
 		LIMIT $1
 	`
 
-	rows, err := r.pool.Query(ctx, query, limit)
+	rows, err := r.pool.Query(ctx, query, limit, r.now())
 	if err != nil {
 		return nil, fmt.Errorf("failed to query expired rewards: %w", err)
 	}
@@ -1376,21 +1887,15 @@ func (r *PostgresBanditRepository) applyRewardToArmTx(ctx context.Context, tx pg
 		return err
 	}
 
-	if reward > 0 {
-		stats.Alpha += 1.0
-		stats.Conversions++
-		stats.Revenue += reward
-	} else {
-		stats.Beta += 1.0
-	}
-	stats.Samples++
-	if stats.Samples > 0 {
-		stats.AvgReward = stats.Revenue / float64(stats.Samples)
-	}
+	stats.AddReward(reward)
+	return r.saveArmStatsTx(ctx, tx, stats)
+}
 
-	_, err = tx.Exec(ctx, `
-		INSERT INTO ab_test_arm_stats (arm_id, alpha, beta, samples, conversions, revenue, avg_reward)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+func (r *PostgresBanditRepository) saveArmStatsTx(ctx context.Context, tx pgx.Tx, stats *service.ArmStats) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO ab_test_arm_stats (arm_id, alpha, beta, samples, conversions, revenue, avg_reward,
+		                               log_revenue_sum, log_revenue_sq_sum)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 		ON CONFLICT (arm_id)
 		DO UPDATE SET
 			alpha = EXCLUDED.alpha,
@@ -1399,8 +1904,11 @@ func (r *PostgresBanditRepository) applyRewardToArmTx(ctx context.Context, tx pg
 			conversions = EXCLUDED.conversions,
 			revenue = EXCLUDED.revenue,
 			avg_reward = EXCLUDED.avg_reward,
+			log_revenue_sum = EXCLUDED.log_revenue_sum,
+			log_revenue_sq_sum = EXCLUDED.log_revenue_sq_sum,
 			updated_at = NOW()
-	`, stats.ArmID, stats.Alpha, stats.Beta, stats.Samples, stats.Conversions, stats.Revenue, stats.AvgReward)
+	`, stats.ArmID, stats.Alpha, stats.Beta, stats.Samples, stats.Conversions, stats.Revenue, stats.AvgReward,
+		stats.LogRevenueSum, stats.LogRevenueSqSum)
 	if err != nil {
 		return fmt.Errorf("failed to persist transactional arm stats: %w", err)
 	}
@@ -1411,7 +1919,8 @@ func (r *PostgresBanditRepository) applyRewardToArmTx(ctx context.Context, tx pg
 func (r *PostgresBanditRepository) loadArmStatsTx(ctx context.Context, tx pgx.Tx, armID uuid.UUID) (*service.ArmStats, error) {
 	stats := &service.ArmStats{}
 	err := tx.QueryRow(ctx, `
-		SELECT arm_id, alpha, beta, samples, conversions, revenue, avg_reward, updated_at
+		SELECT arm_id, alpha, beta, samples, conversions, revenue, avg_reward,
+		       log_revenue_sum, log_revenue_sq_sum, updated_at
 		FROM ab_test_arm_stats
 		WHERE arm_id = $1
 		FOR UPDATE
@@ -1423,6 +1932,8 @@ func (r *PostgresBanditRepository) loadArmStatsTx(ctx context.Context, tx pgx.Tx
 		&stats.Conversions,
 		&stats.Revenue,
 		&stats.AvgReward,
+		&stats.LogRevenueSum,
+		&stats.LogRevenueSqSum,
 		&stats.UpdatedAt,
 	)
 	if err == pgx.ErrNoRows {
@@ -1456,10 +1967,13 @@ func (r *PostgresBanditRepository) insertConversionEventTx(ctx context.Context,
 			original_currency,
 			normalized_reward_value,
 			normalized_currency,
+			conversion_rate,
+			rate_source,
+			corrects_event_id,
 			metadata,
 			occurred_at
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), $9, NULLIF($10, ''), $11, $12)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), $9, NULLIF($10, ''), NULLIF($11, 0), NULLIF($12, ''), $13, $14, $15)
 		ON CONFLICT DO NOTHING
 	`,
 		event.ExperimentID,
@@ -1472,8 +1986,11 @@ func (r *PostgresBanditRepository) insertConversionEventTx(ctx context.Context,
 		event.OriginalCurrency,
 		event.NormalizedRewardValue,
 		event.NormalizedCurrency,
+		event.ConversionRate,
+		event.RateSource,
+		nullableUUID(event.CorrectsEventID),
 		metadataJSON,
-		normalizeOccurredAt(event.OccurredAt),
+		r.normalizeOccurredAt(event.OccurredAt),
 	)
 	if err != nil {
 		return false, fmt.Errorf("failed to insert conversion event: %w", err)
@@ -1488,9 +2005,16 @@ func nullableUUID(id *uuid.UUID) interface{} {
 	return *id
 }
 
-func normalizeOccurredAt(occurredAt time.Time) time.Time {
+func nullableTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC()
+}
+
+func (r *PostgresBanditRepository) normalizeOccurredAt(occurredAt time.Time) time.Time {
 	if occurredAt.IsZero() {
-		return time.Now().UTC()
+		return r.now().UTC()
 	}
 	return occurredAt.UTC()
 }
@@ -0,0 +1,329 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+)
+
+const reviewPromptDecisionColumns = `
+	id, app_id, user_id, experiment_id, arm_id, pending_reward_id, delay_hours,
+	ask_at, expires_at, shown_at, outcome, outcome_at, created_at`
+
+// CreateReviewPromptExperiment stores a running Thompson Sampling experiment
+// with delayed rewards, one arm per delay, and the delays themselves
+func (r *PostgresBanditRepository) CreateReviewPromptExperiment(ctx context.Context, experiment *service.ReviewPromptExperiment) error {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to begin review prompt experiment transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	experiment.ID = uuid.New()
+	experiment.Status = "running"
+	err = tx.QueryRow(ctx, `
+		INSERT INTO ab_tests (id, app_id, name, description, status, start_at,
+		                      algorithm_type, is_bandit, category, enable_delayed, conversion_window_hours)
+		VALUES ($1, $2, $3, 'Store review prompt timing', $4, NOW(),
+		        'thompson_sampling', TRUE, $5, TRUE, $6)
+		RETURNING created_at
+	`, experiment.ID, experiment.AppID, experiment.Name, experiment.Status,
+		service.ReviewPromptExperimentCategory, experiment.RewardWindowHours,
+	).Scan(&experiment.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create review prompt experiment: %w", err)
+	}
+
+	for i := range experiment.Arms {
+		arm := &experiment.Arms[i]
+		arm.ArmID = uuid.New()
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO ab_test_arms (id, experiment_id, name, description, is_control, traffic_weight)
+			VALUES ($1, $2, $3, $4, FALSE, 1.0)
+		`, arm.ArmID, experiment.ID, fmt.Sprintf("+%dh", arm.DelayHours), "Ask for a review this long after the decision"); err != nil {
+			return fmt.Errorf("failed to create review prompt arm: %w", err)
+		}
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO review_prompt_arms (arm_id, experiment_id, delay_hours)
+			VALUES ($1, $2, $3)
+		`, arm.ArmID, experiment.ID, arm.DelayHours); err != nil {
+			return fmt.Errorf("failed to create review prompt delay: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit review prompt experiment: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresBanditRepository) GetRunningReviewPromptExperiment(ctx context.Context, appID uuid.UUID) (*service.ReviewPromptExperiment, error) {
+	experiment := &service.ReviewPromptExperiment{}
+	err := r.pool.QueryRow(ctx, `
+		SELECT id, app_id, name, status, COALESCE(conversion_window_hours, 24), created_at
+		FROM ab_tests
+		WHERE app_id = $1
+		  AND category = $2
+		  AND status = 'running'
+		ORDER BY created_at DESC
+		LIMIT 1
+	`, appID, service.ReviewPromptExperimentCategory).Scan(
+		&experiment.ID,
+		&experiment.AppID,
+		&experiment.Name,
+		&experiment.Status,
+		&experiment.RewardWindowHours,
+		&experiment.CreatedAt,
+	)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get review prompt experiment: %w", err)
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT a.arm_id, a.delay_hours
+		FROM review_prompt_arms a
+		JOIN ab_test_arms arm ON arm.id = a.arm_id
+		WHERE a.experiment_id = $1
+		  AND arm.archived_at IS NULL
+		ORDER BY a.delay_hours
+	`, experiment.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query review prompt delays: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var arm service.ReviewPromptArm
+		if err := rows.Scan(&arm.ArmID, &arm.DelayHours); err != nil {
+			return nil, fmt.Errorf("failed to scan review prompt delay: %w", err)
+		}
+		experiment.Arms = append(experiment.Arms, arm)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate review prompt delays: %w", err)
+	}
+	return experiment, nil
+}
+
+func (r *PostgresBanditRepository) CreateReviewPromptDecision(ctx context.Context, decision *service.ReviewPromptDecision) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO review_prompt_decisions (
+			id, app_id, user_id, experiment_id, arm_id, pending_reward_id,
+			delay_hours, ask_at, expires_at, created_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`, decision.ID, decision.AppID, decision.UserID, decision.ExperimentID, decision.ArmID,
+		decision.PendingRewardID, decision.DelayHours, decision.AskAt, decision.ExpiresAt, decision.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create review prompt decision: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresBanditRepository) GetReviewPromptDecision(ctx context.Context, decisionID uuid.UUID) (*service.ReviewPromptDecision, error) {
+	decision, err := scanReviewPromptDecision(r.pool.QueryRow(ctx, `
+		SELECT `+reviewPromptDecisionColumns+`
+		FROM review_prompt_decisions
+		WHERE id = $1
+	`, decisionID))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get review prompt decision: %w", err)
+	}
+	return decision, nil
+}
+
+func (r *PostgresBanditRepository) ListReviewPromptDecisions(ctx context.Context, userID uuid.UUID, since time.Time) ([]*service.ReviewPromptDecision, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+reviewPromptDecisionColumns+`
+		FROM review_prompt_decisions
+		WHERE user_id = $1
+		  AND created_at >= $2
+		ORDER BY created_at DESC
+	`, userID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query review prompt decisions: %w", err)
+	}
+	defer rows.Close()
+
+	var decisions []*service.ReviewPromptDecision
+	for rows.Next() {
+		decision, err := scanReviewPromptDecision(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan review prompt decision: %w", err)
+		}
+		decisions = append(decisions, decision)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate review prompt decisions: %w", err)
+	}
+	return decisions, nil
+}
+
+func (r *PostgresBanditRepository) MarkReviewPromptShown(ctx context.Context, decisionID uuid.UUID, shownAt time.Time) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE review_prompt_decisions
+		SET shown_at = COALESCE(shown_at, $2)
+		WHERE id = $1
+	`, decisionID, shownAt)
+	if err != nil {
+		return fmt.Errorf("failed to mark review prompt shown: %w", err)
+	}
+	return nil
+}
+
+// RecordReviewPromptOutcome stores the decision's first answer, which also
+// counts as shown, and credits its pending reward with 1 when accepted
+func (r *PostgresBanditRepository) RecordReviewPromptOutcome(ctx context.Context, decisionID uuid.UUID, outcome string, at time.Time) (bool, error) {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return false, fmt.Errorf("failed to begin review prompt outcome transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		pendingID *uuid.UUID
+		answered  *string
+	)
+	err = tx.QueryRow(ctx, `
+		SELECT pending_reward_id, outcome
+		FROM review_prompt_decisions
+		WHERE id = $1
+		FOR UPDATE
+	`, decisionID).Scan(&pendingID, &answered)
+	if err == pgx.ErrNoRows || (err == nil && answered != nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to load review prompt decision: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE review_prompt_decisions
+		SET outcome = $2, outcome_at = $3, shown_at = COALESCE(shown_at, $3)
+		WHERE id = $1
+	`, decisionID, outcome, at); err != nil {
+		return false, fmt.Errorf("failed to record review prompt outcome: %w", err)
+	}
+
+	credited := false
+	if outcome == service.ReviewOutcomeAccepted && pendingID != nil {
+		credited, err = r.creditReviewPromptTx(ctx, tx, decisionID, *pendingID, at)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit review prompt outcome: %w", err)
+	}
+	return credited, nil
+}
+
+func (r *PostgresBanditRepository) creditReviewPromptTx(ctx context.Context, tx pgx.Tx, decisionID, pendingID uuid.UUID, acceptedAt time.Time) (bool, error) {
+	pending := &service.PendingReward{}
+	err := scanPendingReward(tx.QueryRow(ctx, `
+		SELECT id, experiment_id, arm_id, user_id, assigned_at, expires_at, converted,
+		       conversion_value, conversion_currency, converted_at, processed_at
+		FROM bandit_pending_rewards
+		WHERE id = $1
+		  AND converted = FALSE
+		  AND processed_at IS NULL
+		  AND expires_at > $2
+		FOR UPDATE
+	`, pendingID, acceptedAt), pending)
+	if err == pgx.ErrNoRows {
+		// Accepted after the window, or already settled by the expiry sweep
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to load review prompt pending reward: %w", err)
+	}
+
+	inserted, err := r.insertConversionEventTx(ctx, tx, &service.ConversionEvent{
+		ExperimentID:          pending.ExperimentID,
+		ArmID:                 pending.ArmID,
+		UserID:                &pending.UserID,
+		PendingRewardID:       &pending.ID,
+		EventType:             service.ConversionEventTypeReviewPromptAccepted,
+		OriginalRewardValue:   1,
+		NormalizedRewardValue: 1,
+		Metadata: map[string]interface{}{
+			"source":      "review_prompt",
+			"decision_id": decisionID.String(),
+		},
+		OccurredAt: acceptedAt,
+	})
+	if err != nil {
+		return false, err
+	}
+	if !inserted {
+		return false, nil
+	}
+
+	if err := r.applyRewardToArmTx(ctx, tx, pending.ArmID, 1); err != nil {
+		return false, err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE bandit_pending_rewards
+		SET converted = TRUE,
+		    conversion_value = 1,
+		    converted_at = $2,
+		    processed_at = $2
+		WHERE id = $1
+	`, pending.ID, acceptedAt); err != nil {
+		return false, fmt.Errorf("failed to mark review prompt pending reward converted: %w", err)
+	}
+	return true, nil
+}
+
+// LastSupportTicketAt reads the support ticket events the app sends through
+// event ingestion
+func (r *PostgresBanditRepository) LastSupportTicketAt(ctx context.Context, appID, userID uuid.UUID, since time.Time) (*time.Time, error) {
+	var last *time.Time
+	err := r.pool.QueryRow(ctx, `
+		SELECT MAX(occurred_at)
+		FROM analytics_events
+		WHERE user_id = $1
+		  AND app_id = $2
+		  AND event_name = $3
+		  AND occurred_at >= $4
+	`, userID, appID, service.SupportTicketEvent, since).Scan(&last)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last support ticket: %w", err)
+	}
+	return last, nil
+}
+
+func scanReviewPromptDecision(row pgx.Row) (*service.ReviewPromptDecision, error) {
+	decision := &service.ReviewPromptDecision{}
+	err := row.Scan(
+		&decision.ID,
+		&decision.AppID,
+		&decision.UserID,
+		&decision.ExperimentID,
+		&decision.ArmID,
+		&decision.PendingRewardID,
+		&decision.DelayHours,
+		&decision.AskAt,
+		&decision.ExpiresAt,
+		&decision.ShownAt,
+		&decision.Outcome,
+		&decision.OutcomeAt,
+		&decision.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return decision, nil
+}
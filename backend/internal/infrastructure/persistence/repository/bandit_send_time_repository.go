@@ -0,0 +1,316 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+)
+
+// CreateSendTimeExperiment stores a running Thompson Sampling experiment with
+// delayed rewards, one arm per hour, and the hours themselves
+func (r *PostgresBanditRepository) CreateSendTimeExperiment(ctx context.Context, experiment *service.SendTimeExperiment) error {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to begin send-time experiment transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	experiment.ID = uuid.New()
+	experiment.Status = "running"
+	err = tx.QueryRow(ctx, `
+		INSERT INTO ab_tests (id, app_id, segment_id, name, description, status, start_at,
+		                      algorithm_type, is_bandit, category, enable_delayed, conversion_window_hours)
+		VALUES ($1, $2, $3, $4, 'Notification send-time optimization', $5, NOW(),
+		        'thompson_sampling', TRUE, $6, TRUE, $7)
+		RETURNING created_at
+	`, experiment.ID, experiment.AppID, experiment.SegmentID, experiment.Name, experiment.Status,
+		service.SendTimeExperimentCategory, experiment.RewardWindowHours,
+	).Scan(&experiment.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create send-time experiment: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO notification_send_time_experiments (experiment_id, reward_event, created_at)
+		VALUES ($1, $2, $3)
+	`, experiment.ID, experiment.RewardEvent, experiment.CreatedAt); err != nil {
+		return fmt.Errorf("failed to create send-time settings: %w", err)
+	}
+
+	for i := range experiment.Arms {
+		arm := &experiment.Arms[i]
+		arm.ArmID = uuid.New()
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO ab_test_arms (id, experiment_id, name, description, is_control, traffic_weight)
+			VALUES ($1, $2, $3, $4, FALSE, 1.0)
+		`, arm.ArmID, experiment.ID, fmt.Sprintf("%02d:00", arm.Hour), "Send at this hour, recipient local time"); err != nil {
+			return fmt.Errorf("failed to create send-time arm: %w", err)
+		}
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO notification_send_time_arms (arm_id, experiment_id, send_hour)
+			VALUES ($1, $2, $3)
+		`, arm.ArmID, experiment.ID, arm.Hour); err != nil {
+			return fmt.Errorf("failed to create send-time hour: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit send-time experiment: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresBanditRepository) GetSendTimeExperiment(ctx context.Context, experimentID uuid.UUID) (*service.SendTimeExperiment, error) {
+	experiment := &service.SendTimeExperiment{}
+	err := r.pool.QueryRow(ctx, `
+		SELECT t.id, t.app_id, t.segment_id, t.name, t.status, e.reward_event,
+		       COALESCE(t.conversion_window_hours, 24), e.created_at
+		FROM notification_send_time_experiments e
+		JOIN ab_tests t ON t.id = e.experiment_id
+		WHERE e.experiment_id = $1
+	`, experimentID).Scan(
+		&experiment.ID,
+		&experiment.AppID,
+		&experiment.SegmentID,
+		&experiment.Name,
+		&experiment.Status,
+		&experiment.RewardEvent,
+		&experiment.RewardWindowHours,
+		&experiment.CreatedAt,
+	)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get send-time experiment: %w", err)
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT a.arm_id, a.send_hour
+		FROM notification_send_time_arms a
+		JOIN ab_test_arms arm ON arm.id = a.arm_id
+		WHERE a.experiment_id = $1
+		  AND arm.archived_at IS NULL
+		ORDER BY a.send_hour
+	`, experimentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query send-time hours: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var arm service.SendTimeArm
+		if err := rows.Scan(&arm.ArmID, &arm.Hour); err != nil {
+			return nil, fmt.Errorf("failed to scan send-time hour: %w", err)
+		}
+		experiment.Arms = append(experiment.Arms, arm)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate send-time hours: %w", err)
+	}
+	return experiment, nil
+}
+
+func (r *PostgresBanditRepository) CreateSendTimeDelivery(ctx context.Context, delivery *service.SendTimeDelivery) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO notification_send_time_deliveries (
+			id, experiment_id, arm_id, user_id, pending_reward_id, reward_event,
+			send_hour, timezone, scheduled_for, created_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`, delivery.ID, delivery.ExperimentID, delivery.ArmID, delivery.UserID, delivery.PendingRewardID,
+		delivery.RewardEvent, delivery.SendHour, delivery.Timezone, delivery.ScheduledFor, delivery.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create send-time delivery: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresBanditRepository) GetSendTimeDelivery(ctx context.Context, deliveryID uuid.UUID) (*service.SendTimeDelivery, error) {
+	delivery := &service.SendTimeDelivery{}
+	err := r.pool.QueryRow(ctx, `
+		SELECT id, experiment_id, arm_id, user_id, pending_reward_id, reward_event,
+		       send_hour, timezone, scheduled_for, sent_at, opened_at, created_at
+		FROM notification_send_time_deliveries
+		WHERE id = $1
+	`, deliveryID).Scan(
+		&delivery.ID,
+		&delivery.ExperimentID,
+		&delivery.ArmID,
+		&delivery.UserID,
+		&delivery.PendingRewardID,
+		&delivery.RewardEvent,
+		&delivery.SendHour,
+		&delivery.Timezone,
+		&delivery.ScheduledFor,
+		&delivery.SentAt,
+		&delivery.OpenedAt,
+		&delivery.CreatedAt,
+	)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get send-time delivery: %w", err)
+	}
+	return delivery, nil
+}
+
+func (r *PostgresBanditRepository) MarkSendTimeDeliverySent(ctx context.Context, deliveryID uuid.UUID, sentAt time.Time) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE notification_send_time_deliveries
+		SET sent_at = COALESCE(sent_at, $2)
+		WHERE id = $1
+	`, deliveryID, sentAt)
+	if err != nil {
+		return fmt.Errorf("failed to mark send-time delivery sent: %w", err)
+	}
+	return nil
+}
+
+// RecordSendTimeOpen stamps the delivery's first open and, when the
+// experiment is rewarded on opens, credits its pending reward with 1
+func (r *PostgresBanditRepository) RecordSendTimeOpen(ctx context.Context, deliveryID uuid.UUID, openedAt time.Time) (bool, error) {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return false, fmt.Errorf("failed to begin send-time open transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		rewardEvent string
+		pendingID   uuid.UUID
+		alreadyOpen *time.Time
+	)
+	err = tx.QueryRow(ctx, `
+		SELECT reward_event, pending_reward_id, opened_at
+		FROM notification_send_time_deliveries
+		WHERE id = $1
+		FOR UPDATE
+	`, deliveryID).Scan(&rewardEvent, &pendingID, &alreadyOpen)
+	if err == pgx.ErrNoRows || (err == nil && alreadyOpen != nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to load send-time delivery: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE notification_send_time_deliveries SET opened_at = $2 WHERE id = $1
+	`, deliveryID, openedAt); err != nil {
+		return false, fmt.Errorf("failed to record send-time open: %w", err)
+	}
+
+	credited := false
+	if rewardEvent == service.SendTimeRewardOpen {
+		credited, err = r.creditSendTimeOpenTx(ctx, tx, deliveryID, pendingID, openedAt)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit send-time open: %w", err)
+	}
+	return credited, nil
+}
+
+func (r *PostgresBanditRepository) creditSendTimeOpenTx(ctx context.Context, tx pgx.Tx, deliveryID, pendingID uuid.UUID, openedAt time.Time) (bool, error) {
+	pending := &service.PendingReward{}
+	err := scanPendingReward(tx.QueryRow(ctx, `
+		SELECT id, experiment_id, arm_id, user_id, assigned_at, expires_at, converted,
+		       conversion_value, conversion_currency, converted_at, processed_at
+		FROM bandit_pending_rewards
+		WHERE id = $1
+		  AND converted = FALSE
+		  AND processed_at IS NULL
+		  AND expires_at > $2
+		FOR UPDATE
+	`, pendingID, openedAt), pending)
+	if err == pgx.ErrNoRows {
+		// Opened after the window, or already settled by the expiry sweep
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to load send-time pending reward: %w", err)
+	}
+
+	inserted, err := r.insertConversionEventTx(ctx, tx, &service.ConversionEvent{
+		ExperimentID:          pending.ExperimentID,
+		ArmID:                 pending.ArmID,
+		UserID:                &pending.UserID,
+		PendingRewardID:       &pending.ID,
+		EventType:             service.ConversionEventTypeNotificationOpen,
+		OriginalRewardValue:   1,
+		NormalizedRewardValue: 1,
+		Metadata: map[string]interface{}{
+			"source":      "notification_send_time",
+			"delivery_id": deliveryID.String(),
+		},
+		OccurredAt: openedAt,
+	})
+	if err != nil {
+		return false, err
+	}
+	if !inserted {
+		return false, nil
+	}
+
+	if err := r.applyRewardToArmTx(ctx, tx, pending.ArmID, 1); err != nil {
+		return false, err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE bandit_pending_rewards
+		SET converted = TRUE,
+		    conversion_value = 1,
+		    converted_at = $2,
+		    processed_at = $2
+		WHERE id = $1
+	`, pending.ID, openedAt); err != nil {
+		return false, fmt.Errorf("failed to mark send-time pending reward converted: %w", err)
+	}
+	return true, nil
+}
+
+// GetSendTimeResults counts each hour's deliveries and how their pending
+// rewards settled, alongside the arm's posterior
+func (r *PostgresBanditRepository) GetSendTimeResults(ctx context.Context, experimentID uuid.UUID) ([]service.SendTimeHourResult, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT a.send_hour, a.arm_id,
+		       COUNT(d.id),
+		       COUNT(d.sent_at),
+		       COUNT(d.opened_at),
+		       COUNT(d.id) FILTER (WHERE p.converted),
+		       COUNT(d.id) FILTER (WHERE p.processed_at IS NOT NULL AND NOT p.converted),
+		       COALESCE(s.alpha, 1), COALESCE(s.beta, 1)
+		FROM notification_send_time_arms a
+		LEFT JOIN notification_send_time_deliveries d ON d.arm_id = a.arm_id
+		LEFT JOIN bandit_pending_rewards p ON p.id = d.pending_reward_id
+		LEFT JOIN ab_test_arm_stats s ON s.arm_id = a.arm_id
+		WHERE a.experiment_id = $1
+		GROUP BY a.send_hour, a.arm_id, s.alpha, s.beta
+		ORDER BY a.send_hour
+	`, experimentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query send-time results: %w", err)
+	}
+	defer rows.Close()
+
+	var results []service.SendTimeHourResult
+	for rows.Next() {
+		var h service.SendTimeHourResult
+		if err := rows.Scan(&h.Hour, &h.ArmID, &h.Scheduled, &h.Sent, &h.Opened, &h.Rewarded, &h.Expired, &h.Alpha, &h.Beta); err != nil {
+			return nil, fmt.Errorf("failed to scan send-time result: %w", err)
+		}
+		results = append(results, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate send-time results: %w", err)
+	}
+	return results, nil
+}
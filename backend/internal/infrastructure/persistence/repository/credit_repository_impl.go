@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+const creditEntryColumns = `id, app_id, user_id, kind, delta, balance_after, idempotency_key,
+	COALESCE(product_id, ''), COALESCE(platform, ''), COALESCE(provider_tx_id, ''),
+	amount::float8, currency, COALESCE(description, ''), created_at`
+
+// CreditRepositoryImpl implements CreditRepository
+type CreditRepositoryImpl struct {
+	pool *pgxpool.Pool
+}
+
+// NewCreditRepository creates a new credit ledger repository
+func NewCreditRepository(pool *pgxpool.Pool) repository.CreditRepository {
+	return &CreditRepositoryImpl{pool: pool}
+}
+
+// Apply records the ledger entry and updates the balance in one transaction
+func (r *CreditRepositoryImpl) Apply(ctx context.Context, e *entity.CreditEntry) (*entity.CreditEntry, bool, error) {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to begin credit transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	existing, err := getCreditEntryByKey(ctx, tx, e)
+	if err == nil {
+		return existing, false, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, err
+	}
+
+	// A debit on a user without a balance row inserts a negative balance,
+	// which the CHECK constraint rejects like any other overdraft
+	var balance int64
+	err = tx.QueryRow(ctx, `
+		INSERT INTO credit_balances (app_id, user_id, balance, updated_at)
+		VALUES ($1, $2, $3, now())
+		ON CONFLICT (app_id, user_id) DO UPDATE
+		SET balance = credit_balances.balance + EXCLUDED.balance, updated_at = now()
+		RETURNING balance
+	`, e.AppID, e.UserID, e.Delta).Scan(&balance)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23514" {
+			return nil, false, entity.ErrInsufficientCredits
+		}
+		return nil, false, fmt.Errorf("failed to update credit balance: %w", err)
+	}
+	e.BalanceAfter = balance
+
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO credit_ledger (
+			id, app_id, user_id, kind, delta, balance_after, idempotency_key,
+			product_id, platform, provider_tx_id, amount, currency, description, created_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), NULLIF($9, ''), NULLIF($10, ''), $11, $12, NULLIF($13, ''), $14)
+		ON CONFLICT DO NOTHING
+	`, e.ID, e.AppID, e.UserID, e.Kind, e.Delta, e.BalanceAfter, e.IdempotencyKey,
+		e.ProductID, e.Platform, e.ProviderTxID, e.Amount, e.Currency, e.Description, e.CreatedAt)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to insert credit ledger entry: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		// A concurrent request with the same key committed first; drop our
+		// balance change and return its entry
+		if err := tx.Rollback(ctx); err != nil {
+			return nil, false, fmt.Errorf("failed to roll back duplicate credit entry: %w", err)
+		}
+		existing, err := getCreditEntryByKey(ctx, r.pool, e)
+		if err != nil {
+			return nil, false, err
+		}
+		return existing, false, nil
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, false, fmt.Errorf("failed to commit credit entry: %w", err)
+	}
+	return e, true, nil
+}
+
+// GetBalance returns the user's current credit balance
+func (r *CreditRepositoryImpl) GetBalance(ctx context.Context, appID, userID uuid.UUID) (int64, error) {
+	var balance int64
+	err := r.pool.QueryRow(ctx, `
+		SELECT balance FROM credit_balances WHERE app_id = $1 AND user_id = $2
+	`, appID, userID).Scan(&balance)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get credit balance: %w", err)
+	}
+	return balance, nil
+}
+
+// ListEntries retrieves the user's most recent ledger entries, newest first
+func (r *CreditRepositoryImpl) ListEntries(ctx context.Context, appID, userID uuid.UUID, limit int) ([]*entity.CreditEntry, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+creditEntryColumns+`
+		FROM credit_ledger
+		WHERE app_id = $1 AND user_id = $2
+		ORDER BY created_at DESC
+		LIMIT $3
+	`, appID, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list credit ledger: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*entity.CreditEntry
+	for rows.Next() {
+		e, err := scanCreditEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+type creditQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// getCreditEntryByKey finds the entry stored under e's idempotency key.
+// Receipt keys are app-wide; consume keys are scoped to e's user.
+func getCreditEntryByKey(ctx context.Context, q creditQuerier, e *entity.CreditEntry) (*entity.CreditEntry, error) {
+	stored, err := scanCreditEntry(q.QueryRow(ctx, `
+		SELECT `+creditEntryColumns+`
+		FROM credit_ledger
+		WHERE app_id = $1 AND kind = $2 AND idempotency_key = $3
+		  AND (kind = 'purchase' OR user_id = $4)
+	`, e.AppID, e.Kind, e.IdempotencyKey, e.UserID))
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get credit ledger entry: %w", err)
+	}
+	return stored, err
+}
+
+func scanCreditEntry(row pgx.Row) (*entity.CreditEntry, error) {
+	var e entity.CreditEntry
+	err := row.Scan(
+		&e.ID, &e.AppID, &e.UserID, &e.Kind, &e.Delta, &e.BalanceAfter, &e.IdempotencyKey,
+		&e.ProductID, &e.Platform, &e.ProviderTxID, &e.Amount, &e.Currency, &e.Description, &e.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
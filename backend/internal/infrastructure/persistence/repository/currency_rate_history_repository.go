@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+)
+
+// CurrencyRateHistoryRepository persists daily USD rates in currency_rate_history
+type CurrencyRateHistoryRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewCurrencyRateHistoryRepository(pool *pgxpool.Pool) *CurrencyRateHistoryRepository {
+	return &CurrencyRateHistoryRepository{pool: pool}
+}
+
+func (r *CurrencyRateHistoryRepository) SaveRates(ctx context.Context, day time.Time, ratesToUSD map[string]float64, source string) error {
+	batch := &pgx.Batch{}
+	for currency, rate := range ratesToUSD {
+		batch.Queue(`
+			INSERT INTO currency_rate_history (currency, rate_date, rate_to_usd, source)
+			VALUES ($1, $2::date, $3, $4)
+			ON CONFLICT (currency, rate_date) DO UPDATE
+			SET rate_to_usd = EXCLUDED.rate_to_usd, source = EXCLUDED.source`,
+			currency, day.UTC().Format("2006-01-02"), rate, source)
+	}
+	if batch.Len() == 0 {
+		return nil
+	}
+
+	if err := r.pool.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to save currency rate history: %w", err)
+	}
+	return nil
+}
+
+func (r *CurrencyRateHistoryRepository) GetRateOnOrBefore(ctx context.Context, currency string, day time.Time) (float64, error) {
+	var rate float64
+	err := r.pool.QueryRow(ctx, `
+		SELECT rate_to_usd::float8
+		FROM currency_rate_history
+		WHERE currency = $1 AND rate_date <= $2::date
+		ORDER BY rate_date DESC
+		LIMIT 1`, currency, day.UTC().Format("2006-01-02")).Scan(&rate)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, service.ErrCurrencyRateNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get currency rate history: %w", err)
+	}
+	return rate, nil
+}
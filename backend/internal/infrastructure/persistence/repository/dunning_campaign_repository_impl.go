@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+const dunningCampaignColumns = `id, name, variant, reminder_offsets_hours, weight,
+	bandit_experiment_id, bandit_arm_id, is_active, created_at, updated_at`
+
+// DunningCampaignRepositoryImpl implements DunningCampaignRepository
+type DunningCampaignRepositoryImpl struct {
+	pool *pgxpool.Pool
+}
+
+// NewDunningCampaignRepository creates a new dunning campaign repository
+func NewDunningCampaignRepository(pool *pgxpool.Pool) repository.DunningCampaignRepository {
+	return &DunningCampaignRepositoryImpl{pool: pool}
+}
+
+// Create inserts a campaign
+func (r *DunningCampaignRepositoryImpl) Create(ctx context.Context, c *entity.DunningCampaign) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO dunning_campaigns (
+			id, name, variant, reminder_offsets_hours, weight,
+			bandit_experiment_id, bandit_arm_id, is_active, created_at, updated_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`, c.ID, c.Name, c.Variant, offsetHours(c.ReminderOffsets), c.Weight,
+		c.BanditExperimentID, c.BanditArmID, c.IsActive, c.CreatedAt, c.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return fmt.Errorf("%w: name %q is taken", entity.ErrInvalidDunningCampaign, c.Name)
+		}
+		return fmt.Errorf("failed to create dunning campaign: %w", err)
+	}
+	return nil
+}
+
+// Update saves a campaign's configuration
+func (r *DunningCampaignRepositoryImpl) Update(ctx context.Context, c *entity.DunningCampaign) error {
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE dunning_campaigns
+		SET name = $2, variant = $3, reminder_offsets_hours = $4, weight = $5,
+			bandit_experiment_id = $6, bandit_arm_id = $7, is_active = $8, updated_at = now()
+		WHERE id = $1
+	`, c.ID, c.Name, c.Variant, offsetHours(c.ReminderOffsets), c.Weight,
+		c.BanditExperimentID, c.BanditArmID, c.IsActive)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return fmt.Errorf("%w: name %q is taken", entity.ErrInvalidDunningCampaign, c.Name)
+		}
+		return fmt.Errorf("failed to update dunning campaign: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("dunning campaign: %w", domainErrors.ErrNotFound)
+	}
+	return nil
+}
+
+// GetByID retrieves a campaign
+func (r *DunningCampaignRepositoryImpl) GetByID(ctx context.Context, id uuid.UUID) (*entity.DunningCampaign, error) {
+	c, err := scanDunningCampaign(r.pool.QueryRow(ctx, `
+		SELECT `+dunningCampaignColumns+` FROM dunning_campaigns WHERE id = $1
+	`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("dunning campaign: %w", domainErrors.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dunning campaign: %w", err)
+	}
+	return c, nil
+}
+
+// List retrieves campaigns ordered by name
+func (r *DunningCampaignRepositoryImpl) List(ctx context.Context, activeOnly bool) ([]*entity.DunningCampaign, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+dunningCampaignColumns+`
+		FROM dunning_campaigns
+		WHERE is_active OR NOT $1
+		ORDER BY name
+	`, activeOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dunning campaigns: %w", err)
+	}
+	defer rows.Close()
+
+	var campaigns []*entity.DunningCampaign
+	for rows.Next() {
+		c, err := scanDunningCampaign(rows)
+		if err != nil {
+			return nil, err
+		}
+		campaigns = append(campaigns, c)
+	}
+	return campaigns, rows.Err()
+}
+
+// RecoveryStats aggregates dunning outcomes per campaign
+func (r *DunningCampaignRepositoryImpl) RecoveryStats(ctx context.Context, since time.Time) ([]*entity.DunningCampaignStats, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT
+			d.campaign_id,
+			COALESCE(c.name, ''),
+			COALESCE(c.variant, d.variant, ''),
+			COUNT(*),
+			COUNT(*) FILTER (WHERE d.status = 'recovered'),
+			COUNT(*) FILTER (WHERE d.status = 'failed'),
+			COUNT(*) FILTER (WHERE d.status IN ('pending', 'in_progress')),
+			COALESCE(AVG(EXTRACT(EPOCH FROM d.recovered_at - d.created_at) / 3600)
+				FILTER (WHERE d.status = 'recovered'), 0)::float8
+		FROM dunning d
+		LEFT JOIN dunning_campaigns c ON c.id = d.campaign_id
+		WHERE d.created_at >= $1
+		GROUP BY d.campaign_id, c.name, c.variant, d.variant
+		ORDER BY COUNT(*) DESC
+	`, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate dunning recovery: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []*entity.DunningCampaignStats
+	for rows.Next() {
+		var s entity.DunningCampaignStats
+		if err := rows.Scan(&s.CampaignID, &s.Name, &s.Variant, &s.Started, &s.Recovered,
+			&s.Failed, &s.InProgress, &s.AvgHoursToRecovery); err != nil {
+			return nil, err
+		}
+		stats = append(stats, &s)
+	}
+	return stats, rows.Err()
+}
+
+func scanDunningCampaign(row pgx.Row) (*entity.DunningCampaign, error) {
+	var c entity.DunningCampaign
+	var hours []int32
+	err := row.Scan(&c.ID, &c.Name, &c.Variant, &hours, &c.Weight,
+		&c.BanditExperimentID, &c.BanditArmID, &c.IsActive, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range hours {
+		c.ReminderOffsets = append(c.ReminderOffsets, time.Duration(h)*time.Hour)
+	}
+	return &c, nil
+}
+
+func offsetHours(offsets []time.Duration) []int32 {
+	hours := make([]int32, 0, len(offsets))
+	for _, offset := range offsets {
+		hours = append(hours, int32(offset/time.Hour))
+	}
+	return hours
+}
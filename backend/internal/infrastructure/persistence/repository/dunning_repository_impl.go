@@ -12,6 +12,11 @@ import (
 	"github.com/bivex/paywall-iap/internal/domain/entity"
 )
 
+const dunningColumns = `id, subscription_id, user_id, status, attempt_count, max_attempts, next_attempt_at,
+	last_attempt_at, recovered_at, failed_at, created_at, updated_at,
+	campaign_id, COALESCE(variant, ''), COALESCE(trigger_reason, ''), COALESCE(platform, ''),
+	COALESCE(payment_fix_url, '')`
+
 // DunningRepositoryImpl implements DunningRepository using pgxpool
 type DunningRepositoryImpl struct {
 	pool *pgxpool.Pool
@@ -28,32 +33,23 @@ func NewDunningRepository(pool *pgxpool.Pool) *DunningRepositoryImpl {
 func (r *DunningRepositoryImpl) Create(ctx context.Context, dunning *entity.Dunning) error {
 	query := `
 		INSERT INTO dunning (
-			id, subscription_id, user_id, status, attempt_count, max_attempts, next_attempt_at, 
-			created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			id, subscription_id, user_id, status, attempt_count, max_attempts, next_attempt_at,
+			created_at, updated_at, campaign_id, variant, trigger_reason, platform, payment_fix_url
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NULLIF($11, ''), NULLIF($12, ''), NULLIF($13, ''), NULLIF($14, ''))
 	`
 	_, err := r.pool.Exec(ctx, query,
 		dunning.ID, dunning.SubscriptionID, dunning.UserID, dunning.Status,
 		dunning.AttemptCount, dunning.MaxAttempts, dunning.NextAttemptAt,
 		dunning.CreatedAt, dunning.UpdatedAt,
+		dunning.CampaignID, dunning.Variant, string(dunning.Reason), dunning.Platform, dunning.PaymentFixURL,
 	)
 	return err
 }
 
 // GetByID retrieves a dunning record by ID
 func (r *DunningRepositoryImpl) GetByID(ctx context.Context, id uuid.UUID) (*entity.Dunning, error) {
-	query := `
-		SELECT 
-			id, subscription_id, user_id, status, attempt_count, max_attempts, next_attempt_at, 
-			last_attempt_at, recovered_at, failed_at, created_at, updated_at
-		FROM dunning
-		WHERE id = $1
-	`
-	d := &entity.Dunning{}
-	err := r.pool.QueryRow(ctx, query, id).Scan(
-		&d.ID, &d.SubscriptionID, &d.UserID, &d.Status, &d.AttemptCount, &d.MaxAttempts, &d.NextAttemptAt,
-		&d.LastAttemptAt, &d.RecoveredAt, &d.FailedAt, &d.CreatedAt, &d.UpdatedAt,
-	)
+	query := `SELECT ` + dunningColumns + ` FROM dunning WHERE id = $1`
+	d, err := scanDunning(r.pool.QueryRow(ctx, query, id))
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, errors.New("dunning not found")
 	}
@@ -63,18 +59,12 @@ func (r *DunningRepositoryImpl) GetByID(ctx context.Context, id uuid.UUID) (*ent
 // GetActiveBySubscriptionID retrieves the currently active dunning for a subscription
 func (r *DunningRepositoryImpl) GetActiveBySubscriptionID(ctx context.Context, subscriptionID uuid.UUID) (*entity.Dunning, error) {
 	query := `
-		SELECT 
-			id, subscription_id, user_id, status, attempt_count, max_attempts, next_attempt_at, 
-			last_attempt_at, recovered_at, failed_at, created_at, updated_at
+		SELECT ` + dunningColumns + `
 		FROM dunning
 		WHERE subscription_id = $1 AND status IN ('pending', 'in_progress')
 		LIMIT 1
 	`
-	d := &entity.Dunning{}
-	err := r.pool.QueryRow(ctx, query, subscriptionID).Scan(
-		&d.ID, &d.SubscriptionID, &d.UserID, &d.Status, &d.AttemptCount, &d.MaxAttempts, &d.NextAttemptAt,
-		&d.LastAttemptAt, &d.RecoveredAt, &d.FailedAt, &d.CreatedAt, &d.UpdatedAt,
-	)
+	d, err := scanDunning(r.pool.QueryRow(ctx, query, subscriptionID))
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, nil // Return nil, nil when no active dunning found
 	}
@@ -100,9 +90,7 @@ func (r *DunningRepositoryImpl) Update(ctx context.Context, dunning *entity.Dunn
 // GetPendingAttempts retrieves dunning records with NextAttemptAt <= NOW()
 func (r *DunningRepositoryImpl) GetPendingAttempts(ctx context.Context, limit int) ([]*entity.Dunning, error) {
 	query := `
-		SELECT 
-			id, subscription_id, user_id, status, attempt_count, max_attempts, next_attempt_at, 
-			last_attempt_at, recovered_at, failed_at, created_at, updated_at
+		SELECT ` + dunningColumns + `
 		FROM dunning
 		WHERE status IN ('pending', 'in_progress') AND next_attempt_at <= $1
 		LIMIT $2
@@ -115,11 +103,7 @@ func (r *DunningRepositoryImpl) GetPendingAttempts(ctx context.Context, limit in
 
 	var results []*entity.Dunning
 	for rows.Next() {
-		d := &entity.Dunning{}
-		err := rows.Scan(
-			&d.ID, &d.SubscriptionID, &d.UserID, &d.Status, &d.AttemptCount, &d.MaxAttempts, &d.NextAttemptAt,
-			&d.LastAttemptAt, &d.RecoveredAt, &d.FailedAt, &d.CreatedAt, &d.UpdatedAt,
-		)
+		d, err := scanDunning(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -128,3 +112,18 @@ func (r *DunningRepositoryImpl) GetPendingAttempts(ctx context.Context, limit in
 
 	return results, nil
 }
+
+func scanDunning(row pgx.Row) (*entity.Dunning, error) {
+	d := &entity.Dunning{}
+	var reason string
+	err := row.Scan(
+		&d.ID, &d.SubscriptionID, &d.UserID, &d.Status, &d.AttemptCount, &d.MaxAttempts, &d.NextAttemptAt,
+		&d.LastAttemptAt, &d.RecoveredAt, &d.FailedAt, &d.CreatedAt, &d.UpdatedAt,
+		&d.CampaignID, &d.Variant, &reason, &d.Platform, &d.PaymentFixURL,
+	)
+	if err != nil {
+		return nil, err
+	}
+	d.Reason = entity.DunningReason(reason)
+	return d, nil
+}
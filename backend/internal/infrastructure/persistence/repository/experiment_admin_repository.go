@@ -59,6 +59,14 @@ func (r *ExperimentAdminRepository) UpdateExperimentDraft(ctx context.Context, e
 	if err != nil {
 		return fmt.Errorf("failed to marshal experiment automation policy: %w", err)
 	}
+	revenueGuardrailJSON, err := nullableJSON(input.RevenueGuardrail)
+	if err != nil {
+		return fmt.Errorf("failed to marshal experiment revenue guardrail: %w", err)
+	}
+	eligibilityJSON, err := nullableJSON(input.Eligibility)
+	if err != nil {
+		return fmt.Errorf("failed to marshal experiment eligibility: %w", err)
+	}
 
 	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
@@ -77,6 +85,9 @@ func (r *ExperimentAdminRepository) UpdateExperimentDraft(ctx context.Context, e
 		    start_at = $8,
 		    end_at = $9,
 		    automation_policy = $10,
+		    revenue_guardrail = $11,
+		    category = NULLIF($12, ''),
+		    eligibility = $13,
 		    updated_at = now()
 		WHERE id = $1`,
 		experimentID,
@@ -89,6 +100,9 @@ func (r *ExperimentAdminRepository) UpdateExperimentDraft(ctx context.Context, e
 		input.StartAt,
 		input.EndAt,
 		automationPolicyJSON,
+		revenueGuardrailJSON,
+		input.Category,
+		eligibilityJSON,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to update experiment draft: %w", err)
@@ -159,6 +173,14 @@ func (r *ExperimentAdminRepository) syncDraftExperimentArms(ctx context.Context,
 		if arm.ID == nil {
 			continue
 		}
+		discountJSON, err := nullableJSON(arm.Discount)
+		if err != nil {
+			return fmt.Errorf("failed to marshal experiment arm discount: %w", err)
+		}
+		payloadJSON, err := nullableJSON(arm.Payload)
+		if err != nil {
+			return fmt.Errorf("failed to marshal experiment arm payload: %w", err)
+		}
 		commandTag, err := tx.Exec(ctx, `
 			UPDATE ab_test_arms
 			SET name = $3,
@@ -166,8 +188,10 @@ func (r *ExperimentAdminRepository) syncDraftExperimentArms(ctx context.Context,
 			    is_control = $5,
 			    traffic_weight = $6,
 			    pricing_tier_id = $7,
+			    discount = $8,
+			    payload = $9,
 			    updated_at = now()
-			WHERE id = $1 AND experiment_id = $2`, *arm.ID, experimentID, arm.Name, arm.Description, arm.IsControl, arm.TrafficWeight, arm.PricingTierID)
+			WHERE id = $1 AND experiment_id = $2`, *arm.ID, experimentID, arm.Name, arm.Description, arm.IsControl, arm.TrafficWeight, arm.PricingTierID, discountJSON, payloadJSON)
 		if err != nil {
 			return fmt.Errorf("failed to update experiment arm: %w", err)
 		}
@@ -180,9 +204,17 @@ func (r *ExperimentAdminRepository) syncDraftExperimentArms(ctx context.Context,
 		if arm.ID != nil {
 			continue
 		}
+		discountJSON, err := nullableJSON(arm.Discount)
+		if err != nil {
+			return fmt.Errorf("failed to marshal experiment arm discount: %w", err)
+		}
+		payloadJSON, err := nullableJSON(arm.Payload)
+		if err != nil {
+			return fmt.Errorf("failed to marshal experiment arm payload: %w", err)
+		}
 		if _, err := tx.Exec(ctx, `
-			INSERT INTO ab_test_arms (id, experiment_id, name, description, is_control, traffic_weight, pricing_tier_id)
-			VALUES ($1, $2, $3, $4, $5, $6, $7)`, uuid.New(), experimentID, arm.Name, arm.Description, arm.IsControl, arm.TrafficWeight, arm.PricingTierID); err != nil {
+			INSERT INTO ab_test_arms (id, experiment_id, name, description, is_control, traffic_weight, pricing_tier_id, discount, payload)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`, uuid.New(), experimentID, arm.Name, arm.Description, arm.IsControl, arm.TrafficWeight, arm.PricingTierID, discountJSON, payloadJSON); err != nil {
 			return fmt.Errorf("failed to insert experiment arm: %w", err)
 		}
 	}
@@ -190,6 +222,199 @@ func (r *ExperimentAdminRepository) syncDraftExperimentArms(ctx context.Context,
 	return nil
 }
 
+// ArchiveExperimentArm marks an arm archived with the policy for its users.
+// The experiment's arms are locked so two archives cannot retire every arm.
+func (r *ExperimentAdminRepository) ArchiveExperimentArm(ctx context.Context, experimentID, armID uuid.UUID, policy service.ArmReassignmentPolicy, archivedAt time.Time) error {
+	return r.archiveExperimentArm(ctx, experimentID, armID, policy, archivedAt, "manual", nil)
+}
+
+// StopExperimentArmForGuardrail archives a discount arm that breached its
+// revenue guardrail, reassigning its users, and logs the decision.
+func (r *ExperimentAdminRepository) StopExperimentArmForGuardrail(ctx context.Context, experimentID, armID uuid.UUID, stoppedAt time.Time, details map[string]interface{}) error {
+	return r.archiveExperimentArm(ctx, experimentID, armID, service.ArmReassignmentReassign, stoppedAt, service.ArmArchiveReasonGuardrail, details)
+}
+
+func (r *ExperimentAdminRepository) archiveExperimentArm(ctx context.Context, experimentID, armID uuid.UUID, policy service.ArmReassignmentPolicy, archivedAt time.Time, reason string, decision map[string]interface{}) error {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to begin arm archive transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT id, archived_at IS NOT NULL
+		FROM ab_test_arms
+		WHERE experiment_id = $1
+		FOR UPDATE`, experimentID)
+	if err != nil {
+		return fmt.Errorf("failed to lock experiment arms: %w", err)
+	}
+	found := false
+	liveArms := 0
+	for rows.Next() {
+		var id uuid.UUID
+		var archived bool
+		if err := rows.Scan(&id, &archived); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan experiment arm: %w", err)
+		}
+		if id == armID {
+			found = true
+			if archived {
+				rows.Close()
+				return service.ErrExperimentArmArchived
+			}
+		}
+		if !archived {
+			liveArms++
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate experiment arms: %w", err)
+	}
+	if !found {
+		return service.ErrExperimentArmNotFound
+	}
+	if liveArms <= 1 {
+		return service.ErrLastExperimentArm
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE ab_test_arms
+		SET archived_at = $3,
+		    reassignment_policy = $4,
+		    archive_reason = $5,
+		    updated_at = now()
+		WHERE id = $1 AND experiment_id = $2`, armID, experimentID, archivedAt, string(policy), reason); err != nil {
+		return fmt.Errorf("failed to archive experiment arm: %w", err)
+	}
+
+	if decision != nil {
+		detailsJSON, err := json.Marshal(decision)
+		if err != nil {
+			return fmt.Errorf("failed to marshal guardrail decision details: %w", err)
+		}
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO experiment_automation_decision_log (
+				experiment_id, source, decision_type, reason, from_status, to_status, idempotency_key, details
+			)
+			SELECT id, 'experiment_automation_reconciler', 'arm_guardrail_stop', $2, status, status, $3, $4
+			FROM ab_tests
+			WHERE id = $1
+			ON CONFLICT (idempotency_key) DO NOTHING`,
+			experimentID, reason, fmt.Sprintf("experiment:%s:arm:%s:%s", experimentID, armID, reason), detailsJSON); err != nil {
+			return fmt.Errorf("failed to insert guardrail decision log: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit arm archive transaction: %w", err)
+	}
+	return nil
+}
+
+// ArchiveExperiment moves a completed experiment's arm stats into
+// ab_test_arm_stats_archive and marks it archived, all in one transaction.
+// It returns ErrInvalidStatusTransition if the experiment is no longer
+// completed.
+func (r *ExperimentAdminRepository) ArchiveExperiment(ctx context.Context, experimentID uuid.UUID, archivedAt time.Time, audit *service.ExperimentStatusTransitionAudit) error {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to begin experiment archive transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE ab_tests
+		SET status = 'archived', archived_at = $2, updated_at = now()
+		WHERE id = $1 AND status = 'completed'`, experimentID, archivedAt)
+	if err != nil {
+		return fmt.Errorf("failed to archive experiment: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return service.ErrInvalidStatusTransition
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO ab_test_arm_stats_archive (arm_id, experiment_id, stats, objective_stats, archived_at)
+		SELECT a.id, a.experiment_id,
+		       (SELECT to_jsonb(s) FROM ab_test_arm_stats s WHERE s.arm_id = a.id),
+		       COALESCE((SELECT jsonb_agg(to_jsonb(o)) FROM bandit_arm_objective_stats o WHERE o.arm_id = a.id), '[]'),
+		       $2
+		FROM ab_test_arms a
+		WHERE a.experiment_id = $1
+		ON CONFLICT (arm_id) DO NOTHING`, experimentID, archivedAt); err != nil {
+		return fmt.Errorf("failed to archive experiment arm stats: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM bandit_arm_objective_stats
+		WHERE arm_id IN (SELECT id FROM ab_test_arms WHERE experiment_id = $1)`, experimentID); err != nil {
+		return fmt.Errorf("failed to remove archived objective stats: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM ab_test_arm_stats
+		WHERE arm_id IN (SELECT id FROM ab_test_arms WHERE experiment_id = $1)`, experimentID); err != nil {
+		return fmt.Errorf("failed to remove archived arm stats: %w", err)
+	}
+	if err := insertExperimentLifecycleAudit(ctx, tx, experimentID, "completed", "archived", audit); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit experiment archive transaction: %w", err)
+	}
+	return nil
+}
+
+// UnarchiveExperiment restores an archived experiment's arm stats exactly as
+// they were archived and returns it to completed. It returns
+// ErrInvalidStatusTransition if the experiment is no longer archived.
+func (r *ExperimentAdminRepository) UnarchiveExperiment(ctx context.Context, experimentID uuid.UUID, audit *service.ExperimentStatusTransitionAudit) error {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to begin experiment unarchive transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE ab_tests
+		SET status = 'completed', archived_at = NULL, updated_at = now()
+		WHERE id = $1 AND status = 'archived'`, experimentID)
+	if err != nil {
+		return fmt.Errorf("failed to unarchive experiment: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return service.ErrInvalidStatusTransition
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO ab_test_arm_stats
+		SELECT (jsonb_populate_record(NULL::ab_test_arm_stats, r.stats)).*
+		FROM ab_test_arm_stats_archive r
+		WHERE r.experiment_id = $1 AND r.stats IS NOT NULL
+		ON CONFLICT DO NOTHING`, experimentID); err != nil {
+		return fmt.Errorf("failed to restore archived arm stats: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO bandit_arm_objective_stats
+		SELECT (jsonb_populate_record(NULL::bandit_arm_objective_stats, o.value)).*
+		FROM ab_test_arm_stats_archive r, jsonb_array_elements(r.objective_stats) o
+		WHERE r.experiment_id = $1
+		ON CONFLICT DO NOTHING`, experimentID); err != nil {
+		return fmt.Errorf("failed to restore archived objective stats: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM ab_test_arm_stats_archive WHERE experiment_id = $1`, experimentID); err != nil {
+		return fmt.Errorf("failed to clear arm stats archive: %w", err)
+	}
+	if err := insertExperimentLifecycleAudit(ctx, tx, experimentID, "archived", "completed", audit); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit experiment unarchive transaction: %w", err)
+	}
+	return nil
+}
+
 func ensureDraftExperimentPricingTiersExist(ctx context.Context, tx pgx.Tx, arms []service.ExperimentArmInput) error {
 	seen := make(map[uuid.UUID]struct{})
 	for _, arm := range arms {
@@ -578,13 +803,13 @@ func (r *ExperimentAdminRepository) UpdateExperimentWinnerConfidence(ctx context
 }
 
 func (r *ExperimentAdminRepository) GetExperimentObjectiveConfig(ctx context.Context, experimentID uuid.UUID) (*service.ExperimentConfig, error) {
-	var objectiveType sql.NullString
+	var objectiveType, rewardBasis sql.NullString
 	var objectiveWeightsJSON []byte
 
 	err := r.pool.QueryRow(ctx, `
-		SELECT objective_type, objective_weights
+		SELECT objective_type, objective_weights, reward_basis
 		FROM ab_tests
-		WHERE id = $1`, experimentID).Scan(&objectiveType, &objectiveWeightsJSON)
+		WHERE id = $1`, experimentID).Scan(&objectiveType, &objectiveWeightsJSON, &rewardBasis)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, service.ErrExperimentNotFound
@@ -595,7 +820,11 @@ func (r *ExperimentAdminRepository) GetExperimentObjectiveConfig(ctx context.Con
 		return nil, nil
 	}
 
-	config := &service.ExperimentConfig{ID: experimentID, ObjectiveType: service.ObjectiveType(objectiveType.String)}
+	config := &service.ExperimentConfig{
+		ID:            experimentID,
+		ObjectiveType: service.ObjectiveType(objectiveType.String),
+		RewardBasis:   service.RevenueBasis(rewardBasis.String),
+	}
 	if len(objectiveWeightsJSON) > 0 {
 		if err := json.Unmarshal(objectiveWeightsJSON, &config.ObjectiveWeights); err != nil {
 			return nil, fmt.Errorf("failed to decode experiment objective weights: %w", err)
@@ -659,3 +888,188 @@ func (r *ExperimentAdminRepository) ListExperimentRepairCandidateIDs(ctx context
 
 	return ids, nil
 }
+
+// ListExperimentDiscountGuardrailStates returns running experiments with an
+// enabled revenue guardrail and the stats of all their arms
+func (r *ExperimentAdminRepository) ListExperimentDiscountGuardrailStates(ctx context.Context) ([]service.ExperimentDiscountGuardrailState, error) {
+	appID, hasApp := appctx.AppIDFromCtx(ctx)
+	appFilter := ""
+	args := []interface{}{}
+	if hasApp {
+		appFilter = "AND e.app_id = $1"
+		args = append(args, appID)
+	}
+	query := fmt.Sprintf(`
+		SELECT e.id,
+		       e.revenue_guardrail,
+		       a.id,
+		       a.is_control,
+		       a.discount,
+		       COALESCE(s.samples, 0)::int,
+		       COALESCE(s.conversions, 0)::int,
+		       COALESCE(s.revenue, 0)::double precision,
+		       a.archived_at IS NOT NULL,
+		       COALESCE(a.archive_reason, '')
+		FROM ab_tests e
+		INNER JOIN ab_test_arms a ON a.experiment_id = e.id
+		LEFT JOIN ab_test_arm_stats s ON s.arm_id = a.id
+		WHERE e.status = 'running'
+		  AND (e.revenue_guardrail->>'enabled')::boolean IS TRUE
+		  AND EXISTS (SELECT 1 FROM ab_test_arms d WHERE d.experiment_id = e.id AND d.discount IS NOT NULL AND d.archived_at IS NULL)
+		  %s
+		ORDER BY e.id, a.is_control DESC, a.created_at ASC`, appFilter)
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query experiment guardrail states: %w", err)
+	}
+	defer rows.Close()
+
+	states := make([]service.ExperimentDiscountGuardrailState, 0)
+	for rows.Next() {
+		var experimentID uuid.UUID
+		var guardrailJSON, discountJSON []byte
+		var arm service.DiscountGuardrailArm
+		if err := rows.Scan(
+			&experimentID,
+			&guardrailJSON,
+			&arm.ArmID,
+			&arm.IsControl,
+			&discountJSON,
+			&arm.Samples,
+			&arm.Conversions,
+			&arm.Revenue,
+			&arm.Archived,
+			&arm.ArchiveReason,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan experiment guardrail state: %w", err)
+		}
+		if len(discountJSON) > 0 {
+			arm.Discount = new(service.ArmDiscount)
+			if err := json.Unmarshal(discountJSON, arm.Discount); err != nil {
+				return nil, fmt.Errorf("failed to decode experiment arm discount: %w", err)
+			}
+		}
+
+		if len(states) == 0 || states[len(states)-1].ID != experimentID {
+			state := service.ExperimentDiscountGuardrailState{ID: experimentID}
+			if err := json.Unmarshal(guardrailJSON, &state.Guardrail); err != nil {
+				return nil, fmt.Errorf("failed to decode experiment revenue guardrail: %w", err)
+			}
+			states = append(states, state)
+		}
+		last := &states[len(states)-1]
+		last.Arms = append(last.Arms, arm)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate experiment guardrail states: %w", err)
+	}
+	return states, nil
+}
+
+// GetExperimentEligibility returns the experiment's eligibility and control
+// arm, or nil when the experiment does not exist
+func (r *ExperimentAdminRepository) GetExperimentEligibility(ctx context.Context, experimentID uuid.UUID) (*service.ExperimentEligibilityRule, error) {
+	var eligibilityJSON []byte
+	var controlArmID uuid.NullUUID
+	err := r.pool.QueryRow(ctx, `
+		SELECT e.eligibility,
+		       (SELECT a.id FROM ab_test_arms a
+		        WHERE a.experiment_id = e.id AND a.is_control AND a.archived_at IS NULL
+		        ORDER BY a.created_at
+		        LIMIT 1)
+		FROM ab_tests e
+		WHERE e.id = $1`, experimentID).Scan(&eligibilityJSON, &controlArmID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to load experiment eligibility: %w", err)
+	}
+	rule := &service.ExperimentEligibilityRule{ControlArmID: controlArmID.UUID}
+	if len(eligibilityJSON) > 0 {
+		rule.Eligibility = new(service.ExperimentEligibility)
+		if err := json.Unmarshal(eligibilityJSON, rule.Eligibility); err != nil {
+			return nil, fmt.Errorf("failed to decode experiment eligibility: %w", err)
+		}
+	}
+	return rule, nil
+}
+
+// GetExperimentArmPayload returns the variant content of an arm, or nil when
+// it has none
+func (r *ExperimentAdminRepository) GetExperimentArmPayload(ctx context.Context, experimentID, armID uuid.UUID) (*service.ArmPayload, error) {
+	var payloadJSON []byte
+	err := r.pool.QueryRow(ctx, `
+		SELECT payload FROM ab_test_arms WHERE id = $1 AND experiment_id = $2`, armID, experimentID).Scan(&payloadJSON)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, service.ErrExperimentArmNotFound
+		}
+		return nil, fmt.Errorf("failed to load experiment arm payload: %w", err)
+	}
+	if len(payloadJSON) == 0 {
+		return nil, nil
+	}
+	var payload service.ArmPayload
+	if err := json.Unmarshal(payloadJSON, &payload); err != nil {
+		return nil, fmt.Errorf("failed to decode experiment arm payload: %w", err)
+	}
+	return &payload, nil
+}
+
+// ListUserAssignments returns the user's latest live assignment in each of
+// the app's running experiments. Exclusions and archived arms whose users are
+// moved on are left out, since the next assignment request would change them.
+func (r *ExperimentAdminRepository) ListUserAssignments(ctx context.Context, appID, userID uuid.UUID) ([]service.UserExperimentAssignment, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT experiment_id, experiment_name, arm_id, arm_name, is_control, payload, assigned_at, expires_at
+		FROM (
+			SELECT DISTINCT ON (a.experiment_id)
+			       a.experiment_id, e.name AS experiment_name, a.arm_id, arm.name AS arm_name,
+			       arm.is_control, arm.payload, a.assigned_at, a.expires_at, a.excluded_at,
+			       arm.archived_at, arm.reassignment_policy
+			FROM ab_test_assignments a
+			JOIN ab_tests e ON e.id = a.experiment_id
+			JOIN ab_test_arms arm ON arm.id = a.arm_id
+			WHERE a.user_id = $1
+			  AND e.app_id = $2
+			  AND e.status = 'running'
+			  AND a.expires_at > NOW()
+			ORDER BY a.experiment_id, a.assigned_at DESC
+		) latest
+		WHERE excluded_at IS NULL
+		  AND (archived_at IS NULL OR COALESCE(reassignment_policy, 'reassign') = 'keep')
+		ORDER BY assigned_at DESC`, userID, appID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user assignments: %w", err)
+	}
+	defer rows.Close()
+
+	assignments := []service.UserExperimentAssignment{}
+	for rows.Next() {
+		var a service.UserExperimentAssignment
+		var payloadJSON []byte
+		if err := rows.Scan(&a.ExperimentID, &a.ExperimentName, &a.ArmID, &a.ArmName, &a.IsControl, &payloadJSON, &a.AssignedAt, &a.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("failed to scan user assignment: %w", err)
+		}
+		if len(payloadJSON) > 0 {
+			a.Payload = new(service.ArmPayload)
+			if err := json.Unmarshal(payloadJSON, a.Payload); err != nil {
+				return nil, fmt.Errorf("failed to decode experiment arm payload: %w", err)
+			}
+		}
+		assignments = append(assignments, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate user assignments: %w", err)
+	}
+	return assignments, nil
+}
+
+// nullableJSON encodes v, or SQL NULL when v is nil
+func nullableJSON[T any](v *T) ([]byte, error) {
+	if v == nil {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
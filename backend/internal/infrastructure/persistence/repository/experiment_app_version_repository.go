@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+)
+
+// ListExperimentAppVersionStats counts each arm's assigned and converted users
+// and their revenue per app version at assignment. It returns nil when the
+// experiment does not exist.
+func (r *ExperimentAdminRepository) ListExperimentAppVersionStats(ctx context.Context, experimentID uuid.UUID) (*service.ExperimentAppVersionInput, error) {
+	input := &service.ExperimentAppVersionInput{ExperimentID: experimentID}
+	var objectiveType string
+	err := r.pool.QueryRow(ctx, `
+		SELECT COALESCE(objective_type, 'conversion') FROM ab_tests WHERE id = $1`, experimentID).Scan(&objectiveType)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to load experiment: %w", err)
+	}
+	input.ObjectiveType = service.ObjectiveType(objectiveType)
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT COALESCE(s.app_version, ''),
+		       a.id,
+		       a.is_control,
+		       count(*)::int,
+		       count(c.user_id)::int,
+		       COALESCE(sum(c.revenue), 0)::double precision
+		FROM ab_test_assignments s
+		INNER JOIN ab_test_arms a ON a.id = s.arm_id
+		LEFT JOIN LATERAL (
+			SELECT ce.user_id, sum(ce.normalized_reward_value) AS revenue
+			FROM bandit_conversion_events ce
+			WHERE ce.experiment_id = s.experiment_id
+			  AND ce.arm_id = s.arm_id
+			  AND ce.user_id = s.user_id
+			  AND ce.event_type <> 'expired_pending_reward'
+			GROUP BY ce.user_id
+		) c ON true
+		WHERE s.experiment_id = $1
+		GROUP BY 1, a.id, a.is_control, a.created_at
+		ORDER BY a.is_control DESC, a.created_at, 1`, experimentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query experiment app version stats: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var stat service.ExperimentAppVersionArmStats
+		if err := rows.Scan(&stat.AppVersion, &stat.ArmID, &stat.IsControl, &stat.Users, &stat.Conversions, &stat.Revenue); err != nil {
+			return nil, fmt.Errorf("failed to scan experiment app version stats: %w", err)
+		}
+		input.Stats = append(input.Stats, stat)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate experiment app version stats: %w", err)
+	}
+	return input, nil
+}
@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+)
+
+// CreateExperimentFromSpec inserts a draft experiment and its arms
+func (r *ExperimentAdminRepository) CreateExperimentFromSpec(ctx context.Context, appID uuid.UUID, spec service.ExperimentSpec) (uuid.UUID, error) {
+	automationPolicyJSON, err := json.Marshal(spec.AutomationPolicy)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to marshal experiment automation policy: %w", err)
+	}
+	revenueGuardrailJSON, err := nullableJSON(spec.RevenueGuardrail)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to marshal experiment revenue guardrail: %w", err)
+	}
+	eligibilityJSON, err := nullableJSON(spec.Eligibility)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to marshal experiment eligibility: %w", err)
+	}
+	var objectiveWeightsJSON []byte
+	if len(spec.ObjectiveWeights) > 0 {
+		if objectiveWeightsJSON, err = json.Marshal(spec.ObjectiveWeights); err != nil {
+			return uuid.Nil, fmt.Errorf("failed to marshal experiment objective weights: %w", err)
+		}
+	}
+	var windowType *string
+	var windowSize, windowMinSamples *int
+	if spec.Window != nil {
+		value := string(spec.Window.Type)
+		windowType, windowSize, windowMinSamples = &value, &spec.Window.Size, &spec.Window.MinSamples
+	}
+	var rewardBasis *string
+	if spec.RewardBasis != "" {
+		value := string(spec.RewardBasis)
+		rewardBasis = &value
+	}
+	objectiveType := spec.ObjectiveType
+	if objectiveType == "" {
+		objectiveType = service.ObjectiveConversion
+	}
+	var category *string
+	if spec.Category != "" {
+		category = &spec.Category
+	}
+
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to begin experiment create transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := ensureDraftExperimentPricingTiersExist(ctx, tx, spec.Arms); err != nil {
+		return uuid.Nil, err
+	}
+
+	experimentID := uuid.New()
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO ab_tests (
+			id, app_id, name, description, status, start_at, end_at,
+			algorithm_type, is_bandit, min_sample_size, confidence_threshold, automation_policy,
+			objective_type, objective_weights, reward_basis,
+			window_type, window_size, window_min_samples, revenue_guardrail, category, eligibility
+		)
+		VALUES ($1, $2, $3, $4, 'draft', $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)`,
+		experimentID, appID, spec.Name, spec.Description, spec.StartAt, spec.EndAt,
+		spec.AlgorithmType, spec.IsBandit, spec.MinSampleSize, spec.ConfidenceThreshold, automationPolicyJSON,
+		string(objectiveType), objectiveWeightsJSON, rewardBasis,
+		windowType, windowSize, windowMinSamples, revenueGuardrailJSON, category, eligibilityJSON,
+	); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to insert experiment: %w", err)
+	}
+
+	for _, arm := range spec.Arms {
+		discountJSON, err := nullableJSON(arm.Discount)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("failed to marshal experiment arm discount: %w", err)
+		}
+		payloadJSON, err := nullableJSON(arm.Payload)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("failed to marshal experiment arm payload: %w", err)
+		}
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO ab_test_arms (id, experiment_id, name, description, is_control, traffic_weight, pricing_tier_id, discount, payload)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`, uuid.New(), experimentID, arm.Name, arm.Description, arm.IsControl, arm.TrafficWeight, arm.PricingTierID, discountJSON, payloadJSON); err != nil {
+			return uuid.Nil, fmt.Errorf("failed to insert experiment arm: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to commit experiment create transaction: %w", err)
+	}
+	return experimentID, nil
+}
+
+// CloneExperiment copies an experiment of the app into a new draft: its
+// configuration, category, eligibility, segment and live arms, but no schedule, stats, assignments
+// or automation lock. Per-arm window overrides follow their arms.
+func (r *ExperimentAdminRepository) CloneExperiment(ctx context.Context, appID, experimentID uuid.UUID, name string) (uuid.UUID, error) {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to begin experiment clone transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	cloneID := uuid.New()
+	var overridesJSON []byte
+	err = tx.QueryRow(ctx, `
+		INSERT INTO ab_tests (
+			id, app_id, name, description, status,
+			algorithm_type, is_bandit, min_sample_size, confidence_threshold, automation_policy,
+			window_type, window_size, window_min_samples,
+			objective_type, objective_weights, price_normalization,
+			enable_contextual, enable_delayed, enable_currency, exploration_alpha,
+			reward_basis, product_costs, segment_id, revenue_guardrail, category, eligibility,
+			conversion_window_hours
+		)
+		SELECT $3, app_id, COALESCE(NULLIF($4, ''), name || ' (copy)'), description, 'draft',
+		       algorithm_type, is_bandit, min_sample_size, confidence_threshold,
+		       COALESCE(automation_policy, '{}'::jsonb) - 'locked_until' - 'locked_by' - 'lock_reason' || '{"manual_override": false}'::jsonb,
+		       window_type, window_size, window_min_samples,
+		       objective_type, objective_weights, price_normalization,
+		       enable_contextual, enable_delayed, enable_currency, exploration_alpha,
+		       reward_basis, product_costs, segment_id, revenue_guardrail, category, eligibility,
+		       conversion_window_hours
+		FROM ab_tests
+		WHERE id = $1 AND app_id = $2
+		RETURNING (SELECT window_arm_overrides FROM ab_tests WHERE id = $1)`,
+		experimentID, appID, cloneID, name).Scan(&overridesJSON)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return uuid.Nil, service.ErrExperimentNotFound
+		}
+		return uuid.Nil, fmt.Errorf("failed to clone experiment: %w", err)
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT id FROM ab_test_arms
+		WHERE experiment_id = $1 AND archived_at IS NULL
+		ORDER BY created_at, id`, experimentID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to load experiment arms: %w", err)
+	}
+	armIDs := make(map[uuid.UUID]uuid.UUID)
+	order := make([]uuid.UUID, 0)
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return uuid.Nil, fmt.Errorf("failed to scan experiment arm: %w", err)
+		}
+		armIDs[id] = uuid.New()
+		order = append(order, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to iterate experiment arms: %w", err)
+	}
+
+	for _, id := range order {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO ab_test_arms (id, experiment_id, name, description, is_control, traffic_weight, pricing_tier_id, discount, payload)
+			SELECT $3, $2, name, description, is_control, traffic_weight, pricing_tier_id, discount, payload
+			FROM ab_test_arms
+			WHERE id = $1`, id, cloneID, armIDs[id]); err != nil {
+			return uuid.Nil, fmt.Errorf("failed to clone experiment arm: %w", err)
+		}
+	}
+
+	if len(overridesJSON) > 0 {
+		var overrides map[string]json.RawMessage
+		if err := json.Unmarshal(overridesJSON, &overrides); err != nil {
+			return uuid.Nil, fmt.Errorf("failed to decode window arm overrides: %w", err)
+		}
+		remapped := make(map[string]json.RawMessage, len(overrides))
+		for armID, override := range overrides {
+			if id, err := uuid.Parse(armID); err == nil {
+				if cloneArmID, ok := armIDs[id]; ok {
+					remapped[cloneArmID.String()] = override
+				}
+			}
+		}
+		if len(remapped) > 0 {
+			remappedJSON, err := json.Marshal(remapped)
+			if err != nil {
+				return uuid.Nil, fmt.Errorf("failed to marshal window arm overrides: %w", err)
+			}
+			if _, err := tx.Exec(ctx, `UPDATE ab_tests SET window_arm_overrides = $2 WHERE id = $1`, cloneID, remappedJSON); err != nil {
+				return uuid.Nil, fmt.Errorf("failed to copy window arm overrides: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to commit experiment clone transaction: %w", err)
+	}
+	return cloneID, nil
+}
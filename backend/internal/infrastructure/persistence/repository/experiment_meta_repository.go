@@ -0,0 +1,251 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+)
+
+// ListExperimentOutcomeInputs loads every completed experiment with its arm
+// stats, the winner an admin confirmed on completion and the users its app
+// gained since then. Archived variants are left out.
+func (r *ExperimentAdminRepository) ListExperimentOutcomeInputs(ctx context.Context) ([]service.ExperimentOutcomeInput, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT e.id,
+		       e.app_id,
+		       COALESCE(e.category, ''),
+		       COALESCE(e.objective_type, 'conversion'),
+		       COALESCE(c.created_at, e.end_at, e.updated_at),
+		       CASE WHEN c.details->>'reason' = 'confirm_recommended_winner' THEN c.details->>'winning_arm_id' END,
+		       e.winner_confidence::double precision,
+		       COALESCE(e.confidence_threshold, 0.95)::double precision,
+		       (SELECT count(*) FROM users u
+		        WHERE u.app_id = e.app_id
+		          AND u.deleted_at IS NULL
+		          AND u.created_at > COALESCE(c.created_at, e.end_at, e.updated_at))
+		FROM ab_tests e
+		LEFT JOIN LATERAL (
+			SELECT l.created_at, l.details
+			FROM experiment_lifecycle_audit_log l
+			WHERE l.experiment_id = e.id AND l.to_status = 'completed'
+			ORDER BY l.created_at DESC
+			LIMIT 1
+		) c ON true
+		WHERE e.status = 'completed'
+		ORDER BY e.id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query completed experiments: %w", err)
+	}
+	defer rows.Close()
+
+	inputs := make([]service.ExperimentOutcomeInput, 0)
+	index := make(map[uuid.UUID]int)
+	for rows.Next() {
+		var input service.ExperimentOutcomeInput
+		var objectiveType string
+		var launchedArmID *string
+		if err := rows.Scan(
+			&input.ExperimentID,
+			&input.AppID,
+			&input.Category,
+			&objectiveType,
+			&input.CompletedAt,
+			&launchedArmID,
+			&input.WinnerConfidence,
+			&input.ConfidenceThreshold,
+			&input.UsersSinceCompletion,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan completed experiment: %w", err)
+		}
+		input.ObjectiveType = service.ObjectiveType(objectiveType)
+		if launchedArmID != nil {
+			if id, err := uuid.Parse(*launchedArmID); err == nil {
+				input.LaunchedArmID = &id
+			}
+		}
+		index[input.ExperimentID] = len(inputs)
+		inputs = append(inputs, input)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate completed experiments: %w", err)
+	}
+	rows.Close()
+
+	armRows, err := r.pool.Query(ctx, `
+		SELECT a.experiment_id,
+		       a.id,
+		       a.is_control,
+		       COALESCE(s.samples, 0)::int,
+		       COALESCE(s.conversions, 0)::int,
+		       COALESCE(s.revenue, 0)::double precision
+		FROM ab_test_arms a
+		INNER JOIN ab_tests e ON e.id = a.experiment_id
+		LEFT JOIN ab_test_arm_stats s ON s.arm_id = a.id
+		WHERE e.status = 'completed'
+		  AND (a.archived_at IS NULL OR a.is_control)
+		ORDER BY a.experiment_id, a.is_control DESC, a.created_at ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query completed experiment arms: %w", err)
+	}
+	defer armRows.Close()
+	for armRows.Next() {
+		var experimentID uuid.UUID
+		var arm service.ExperimentOutcomeArm
+		if err := armRows.Scan(&experimentID, &arm.ArmID, &arm.IsControl, &arm.Samples, &arm.Conversions, &arm.Revenue); err != nil {
+			return nil, fmt.Errorf("failed to scan completed experiment arm: %w", err)
+		}
+		if i, ok := index[experimentID]; ok {
+			inputs[i].Arms = append(inputs[i].Arms, arm)
+		}
+	}
+	if err := armRows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate completed experiment arms: %w", err)
+	}
+	return inputs, nil
+}
+
+// SaveExperimentOutcomes upserts the outcome of each experiment
+func (r *ExperimentAdminRepository) SaveExperimentOutcomes(ctx context.Context, outcomes []service.ExperimentOutcome) error {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to begin experiment outcome transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, outcome := range outcomes {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO experiment_outcomes (
+				experiment_id, app_id, category, objective_type, metric, completed_at,
+				winner_arm_id, winner_is_control, launched, control_metric, variant_metric, lift_percent,
+				revenue_per_user_uplift, users_since_completion, revenue_gained, recorded_at
+			)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, now())
+			ON CONFLICT (experiment_id) DO UPDATE SET
+				category = EXCLUDED.category,
+				objective_type = EXCLUDED.objective_type,
+				metric = EXCLUDED.metric,
+				completed_at = EXCLUDED.completed_at,
+				winner_arm_id = EXCLUDED.winner_arm_id,
+				winner_is_control = EXCLUDED.winner_is_control,
+				launched = EXCLUDED.launched,
+				control_metric = EXCLUDED.control_metric,
+				variant_metric = EXCLUDED.variant_metric,
+				lift_percent = EXCLUDED.lift_percent,
+				revenue_per_user_uplift = EXCLUDED.revenue_per_user_uplift,
+				users_since_completion = EXCLUDED.users_since_completion,
+				revenue_gained = EXCLUDED.revenue_gained,
+				recorded_at = EXCLUDED.recorded_at`,
+			outcome.ExperimentID, outcome.AppID, outcome.Category, string(outcome.ObjectiveType), outcome.Metric, outcome.CompletedAt,
+			outcome.WinnerArmID, outcome.WinnerIsControl, outcome.Launched, outcome.ControlMetric, outcome.VariantMetric, outcome.LiftPercent,
+			outcome.RevenuePerUserUplift, outcome.UsersSinceCompletion, outcome.RevenueGained,
+		); err != nil {
+			return fmt.Errorf("failed to save experiment outcome: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit experiment outcome transaction: %w", err)
+	}
+	return nil
+}
+
+// SaveExperimentMetaSnapshot replaces the app's snapshot for date
+func (r *ExperimentAdminRepository) SaveExperimentMetaSnapshot(ctx context.Context, appID uuid.UUID, date time.Time, categories []service.ExperimentMetaCategory) error {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to begin experiment meta snapshot transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM experiment_meta_snapshots WHERE app_id = $1 AND snapshot_date = $2`, appID, date); err != nil {
+		return fmt.Errorf("failed to clear experiment meta snapshot: %w", err)
+	}
+	for _, category := range categories {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO experiment_meta_snapshots (
+				app_id, snapshot_date, category, experiments, conclusive, wins, launched,
+				win_rate, avg_lift_percent, revenue_gained
+			)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+			appID, date, category.Category, category.Experiments, category.Conclusive, category.Wins, category.Launched,
+			category.WinRate, category.AvgLiftPercent, category.RevenueGained,
+		); err != nil {
+			return fmt.Errorf("failed to insert experiment meta snapshot: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit experiment meta snapshot transaction: %w", err)
+	}
+	return nil
+}
+
+// ListExperimentOutcomes returns the app's recorded outcomes, latest first
+func (r *ExperimentAdminRepository) ListExperimentOutcomes(ctx context.Context, appID uuid.UUID) ([]service.ExperimentOutcome, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT experiment_id, app_id, category, objective_type, metric, completed_at,
+		       winner_arm_id, winner_is_control, launched, control_metric, variant_metric, lift_percent,
+		       revenue_per_user_uplift, users_since_completion, revenue_gained
+		FROM experiment_outcomes
+		WHERE app_id = $1
+		ORDER BY completed_at DESC, experiment_id`, appID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query experiment outcomes: %w", err)
+	}
+	defer rows.Close()
+
+	outcomes := make([]service.ExperimentOutcome, 0)
+	for rows.Next() {
+		var outcome service.ExperimentOutcome
+		var objectiveType string
+		if err := rows.Scan(
+			&outcome.ExperimentID, &outcome.AppID, &outcome.Category, &objectiveType, &outcome.Metric, &outcome.CompletedAt,
+			&outcome.WinnerArmID, &outcome.WinnerIsControl, &outcome.Launched, &outcome.ControlMetric, &outcome.VariantMetric, &outcome.LiftPercent,
+			&outcome.RevenuePerUserUplift, &outcome.UsersSinceCompletion, &outcome.RevenueGained,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan experiment outcome: %w", err)
+		}
+		outcome.ObjectiveType = service.ObjectiveType(objectiveType)
+		outcomes = append(outcomes, outcome)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate experiment outcomes: %w", err)
+	}
+	return outcomes, nil
+}
+
+// ListExperimentMetaSnapshots returns the app's snapshots within [from, to]
+func (r *ExperimentAdminRepository) ListExperimentMetaSnapshots(ctx context.Context, appID uuid.UUID, from, to time.Time) ([]service.ExperimentMetaSnapshot, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT snapshot_date, category, experiments, conclusive, wins, launched,
+		       win_rate, avg_lift_percent, revenue_gained
+		FROM experiment_meta_snapshots
+		WHERE app_id = $1 AND snapshot_date BETWEEN $2 AND $3
+		ORDER BY snapshot_date, category`, appID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query experiment meta snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	snapshots := make([]service.ExperimentMetaSnapshot, 0)
+	for rows.Next() {
+		var snapshot service.ExperimentMetaSnapshot
+		if err := rows.Scan(
+			&snapshot.Date, &snapshot.Category, &snapshot.Experiments, &snapshot.Conclusive, &snapshot.Wins, &snapshot.Launched,
+			&snapshot.WinRate, &snapshot.AvgLiftPercent, &snapshot.RevenueGained,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan experiment meta snapshot: %w", err)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate experiment meta snapshots: %w", err)
+	}
+	return snapshots, nil
+}
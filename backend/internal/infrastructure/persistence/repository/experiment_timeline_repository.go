@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+)
+
+// ListExperimentTimelineTargets returns the running and paused experiments
+func (r *ExperimentAdminRepository) ListExperimentTimelineTargets(ctx context.Context) ([]service.ExperimentTimelineTarget, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, app_id
+		FROM ab_tests
+		WHERE status IN ('running', 'paused')
+		ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query timeline experiments: %w", err)
+	}
+	defer rows.Close()
+
+	targets := make([]service.ExperimentTimelineTarget, 0)
+	for rows.Next() {
+		var target service.ExperimentTimelineTarget
+		if err := rows.Scan(&target.ExperimentID, &target.AppID); err != nil {
+			return nil, fmt.Errorf("failed to scan timeline experiment: %w", err)
+		}
+		targets = append(targets, target)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate timeline experiments: %w", err)
+	}
+	return targets, nil
+}
+
+// GetExperimentTimelineActivity totals each arm's impressions, rewarded
+// conversions and revenue within the day. Currency corrections count towards
+// revenue but not conversions; expired pending rewards count towards neither.
+func (r *ExperimentAdminRepository) GetExperimentTimelineActivity(ctx context.Context, experimentID uuid.UUID, day time.Time) ([]service.ExperimentTimelineDay, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT a.id,
+		       (SELECT count(*)
+		        FROM bandit_impression_events i
+		        WHERE i.experiment_id = a.experiment_id AND i.arm_id = a.id
+		          AND i.occurred_at >= $2 AND i.occurred_at < $3),
+		       COALESCE(c.conversions, 0),
+		       COALESCE(c.revenue, 0)::double precision
+		FROM ab_test_arms a
+		LEFT JOIN LATERAL (
+			SELECT count(*) FILTER (WHERE ce.event_type <> 'currency_correction' AND ce.normalized_reward_value > 0) AS conversions,
+			       sum(ce.normalized_reward_value) AS revenue
+			FROM bandit_conversion_events ce
+			WHERE ce.experiment_id = a.experiment_id AND ce.arm_id = a.id
+			  AND ce.event_type <> 'expired_pending_reward'
+			  AND ce.occurred_at >= $2 AND ce.occurred_at < $3
+		) c ON true
+		WHERE a.experiment_id = $1
+		ORDER BY a.is_control DESC, a.created_at, a.id`, experimentID, day, day.Add(24*time.Hour))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query experiment timeline activity: %w", err)
+	}
+	defer rows.Close()
+
+	days := make([]service.ExperimentTimelineDay, 0)
+	for rows.Next() {
+		row := service.ExperimentTimelineDay{Date: day}
+		if err := rows.Scan(&row.ArmID, &row.Exposures, &row.Conversions, &row.Revenue); err != nil {
+			return nil, fmt.Errorf("failed to scan experiment timeline activity: %w", err)
+		}
+		days = append(days, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate experiment timeline activity: %w", err)
+	}
+	return days, nil
+}
+
+// SaveExperimentTimelineDay replaces the experiment's rows for the day
+func (r *ExperimentAdminRepository) SaveExperimentTimelineDay(ctx context.Context, target service.ExperimentTimelineTarget, day time.Time, rows []service.ExperimentTimelineDay) error {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to begin experiment timeline transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM experiment_timeline_daily WHERE experiment_id = $1 AND timeline_date = $2`, target.ExperimentID, day); err != nil {
+		return fmt.Errorf("failed to clear experiment timeline day: %w", err)
+	}
+	for _, row := range rows {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO experiment_timeline_daily (
+				experiment_id, arm_id, app_id, timeline_date, exposures, conversions, revenue, win_probability
+			)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+			target.ExperimentID, row.ArmID, target.AppID, day, row.Exposures, row.Conversions, row.Revenue, row.WinProbability,
+		); err != nil {
+			return fmt.Errorf("failed to insert experiment timeline day: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit experiment timeline transaction: %w", err)
+	}
+	return nil
+}
+
+// ListExperimentTimelineArms returns the arms of the app's experiment, nil
+// when the app has no such experiment
+func (r *ExperimentAdminRepository) ListExperimentTimelineArms(ctx context.Context, appID, experimentID uuid.UUID) ([]service.ExperimentTimelineArm, error) {
+	var exists bool
+	if err := r.pool.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM ab_tests WHERE id = $1 AND app_id = $2)`, experimentID, appID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to load experiment: %w", err)
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, name, is_control
+		FROM ab_test_arms
+		WHERE experiment_id = $1
+		ORDER BY is_control DESC, created_at, id`, experimentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query experiment arms: %w", err)
+	}
+	defer rows.Close()
+
+	arms := make([]service.ExperimentTimelineArm, 0)
+	for rows.Next() {
+		var arm service.ExperimentTimelineArm
+		if err := rows.Scan(&arm.ArmID, &arm.Name, &arm.IsControl); err != nil {
+			return nil, fmt.Errorf("failed to scan experiment arm: %w", err)
+		}
+		arms = append(arms, arm)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate experiment arms: %w", err)
+	}
+	return arms, nil
+}
+
+// ListExperimentTimelineDays returns the recorded days within [from, to)
+func (r *ExperimentAdminRepository) ListExperimentTimelineDays(ctx context.Context, experimentID uuid.UUID, from, to time.Time) ([]service.ExperimentTimelineDay, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT arm_id, timeline_date, exposures, conversions, revenue, win_probability
+		FROM experiment_timeline_daily
+		WHERE experiment_id = $1 AND timeline_date >= $2 AND timeline_date < $3
+		ORDER BY timeline_date, arm_id`, experimentID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query experiment timeline: %w", err)
+	}
+	defer rows.Close()
+
+	days := make([]service.ExperimentTimelineDay, 0)
+	for rows.Next() {
+		var row service.ExperimentTimelineDay
+		if err := rows.Scan(&row.ArmID, &row.Date, &row.Exposures, &row.Conversions, &row.Revenue, &row.WinProbability); err != nil {
+			return nil, fmt.Errorf("failed to scan experiment timeline day: %w", err)
+		}
+		days = append(days, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate experiment timeline: %w", err)
+	}
+	return days, nil
+}
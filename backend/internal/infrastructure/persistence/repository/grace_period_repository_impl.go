@@ -129,7 +129,7 @@ func (r *GracePeriodRepositoryImpl) GetActiveBySubscriptionID(ctx context.Contex
 func (r *GracePeriodRepositoryImpl) Update(ctx context.Context, gracePeriod *entity.GracePeriod) error {
 	query := `
 		UPDATE grace_periods
-		SET status = $2, resolved_at = $3, updated_at = $4
+		SET status = $2, resolved_at = $3, updated_at = $4, expires_at = $5
 		WHERE id = $1
 	`
 
@@ -138,6 +138,7 @@ func (r *GracePeriodRepositoryImpl) Update(ctx context.Context, gracePeriod *ent
 		gracePeriod.Status,
 		gracePeriod.ResolvedAt,
 		gracePeriod.UpdatedAt,
+		gracePeriod.ExpiresAt,
 	)
 
 	return err
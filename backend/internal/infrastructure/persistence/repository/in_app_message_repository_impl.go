@@ -0,0 +1,232 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+const inAppMessageColumns = `id, app_id, name, placement, format, trigger, trigger_window_hours,
+	priority, enabled, starts_at, ends_at, conditions, content, experiment_id, variants,
+	max_impressions, min_interval_hours, created_at, updated_at`
+
+// InAppMessageRepositoryImpl implements InAppMessageRepository
+type InAppMessageRepositoryImpl struct {
+	pool *pgxpool.Pool
+}
+
+// NewInAppMessageRepository creates a new in-app message repository
+func NewInAppMessageRepository(pool *pgxpool.Pool) repository.InAppMessageRepository {
+	return &InAppMessageRepositoryImpl{pool: pool}
+}
+
+// List retrieves all messages of an app, highest priority first
+func (r *InAppMessageRepositoryImpl) List(ctx context.Context, appID uuid.UUID) ([]*entity.InAppMessage, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+inAppMessageColumns+`
+		FROM in_app_messages
+		WHERE app_id = $1
+		ORDER BY priority DESC, created_at
+	`, appID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []*entity.InAppMessage
+	for rows.Next() {
+		m, err := scanInAppMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
+
+// GetByID retrieves a message of an app
+func (r *InAppMessageRepositoryImpl) GetByID(ctx context.Context, appID, id uuid.UUID) (*entity.InAppMessage, error) {
+	m, err := scanInAppMessage(r.pool.QueryRow(ctx, `
+		SELECT `+inAppMessageColumns+`
+		FROM in_app_messages
+		WHERE app_id = $1 AND id = $2
+	`, appID, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("in-app message %s: %w", id, domainErrors.ErrNotFound)
+	}
+	return m, err
+}
+
+// Create creates a new message
+func (r *InAppMessageRepositoryImpl) Create(ctx context.Context, m *entity.InAppMessage) error {
+	conditions, content, variants, err := encodeInAppMessage(m)
+	if err != nil {
+		return err
+	}
+	_, err = r.pool.Exec(ctx, `
+		INSERT INTO in_app_messages (`+inAppMessageColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
+	`, m.ID, m.AppID, m.Name, m.Placement, string(m.Format), string(m.Trigger), m.TriggerWindowHours,
+		m.Priority, m.Enabled, m.StartsAt, m.EndsAt, conditions, content, m.ExperimentID, variants,
+		m.MaxImpressions, m.MinIntervalHours, m.CreatedAt, m.UpdatedAt)
+	return err
+}
+
+// Update replaces an existing message
+func (r *InAppMessageRepositoryImpl) Update(ctx context.Context, m *entity.InAppMessage) error {
+	conditions, content, variants, err := encodeInAppMessage(m)
+	if err != nil {
+		return err
+	}
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE in_app_messages
+		SET name = $3, placement = $4, format = $5, trigger = $6, trigger_window_hours = $7,
+			priority = $8, enabled = $9, starts_at = $10, ends_at = $11, conditions = $12,
+			content = $13, experiment_id = $14, variants = $15, max_impressions = $16,
+			min_interval_hours = $17, updated_at = $18
+		WHERE app_id = $1 AND id = $2
+	`, m.AppID, m.ID, m.Name, m.Placement, string(m.Format), string(m.Trigger), m.TriggerWindowHours,
+		m.Priority, m.Enabled, m.StartsAt, m.EndsAt, conditions, content, m.ExperimentID, variants,
+		m.MaxImpressions, m.MinIntervalHours, m.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("in-app message %s: %w", m.ID, domainErrors.ErrNotFound)
+	}
+	return nil
+}
+
+// Delete removes a message and its events
+func (r *InAppMessageRepositoryImpl) Delete(ctx context.Context, appID, id uuid.UUID) error {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM in_app_messages WHERE app_id = $1 AND id = $2`, appID, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("in-app message %s: %w", id, domainErrors.ErrNotFound)
+	}
+	return nil
+}
+
+// RecordEvent stores an impression, click or dismissal
+func (r *InAppMessageRepositoryImpl) RecordEvent(ctx context.Context, e *entity.InAppMessageEvent) error {
+	if _, err := r.pool.Exec(ctx, `
+		INSERT INTO in_app_message_events (id, message_id, user_id, arm_id, event, occurred_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, e.ID, e.MessageID, e.UserID, e.ArmID, string(e.Type), e.OccurredAt); err != nil {
+		return fmt.Errorf("failed to record in-app message event: %w", err)
+	}
+	return nil
+}
+
+// History returns what the user did with each message they ever saw
+func (r *InAppMessageRepositoryImpl) History(ctx context.Context, userID uuid.UUID) (map[uuid.UUID]entity.InAppMessageHistory, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT message_id,
+		       COUNT(*) FILTER (WHERE event = 'impression'),
+		       MAX(occurred_at) FILTER (WHERE event = 'impression'),
+		       bool_or(event IN ('click', 'dismiss'))
+		FROM in_app_message_events
+		WHERE user_id = $1
+		GROUP BY message_id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read in-app message history: %w", err)
+	}
+	defer rows.Close()
+	history := map[uuid.UUID]entity.InAppMessageHistory{}
+	for rows.Next() {
+		var id uuid.UUID
+		var h entity.InAppMessageHistory
+		if err := rows.Scan(&id, &h.Impressions, &h.LastImpressionAt, &h.Closed); err != nil {
+			return nil, fmt.Errorf("failed to scan in-app message history: %w", err)
+		}
+		history[id] = h
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read in-app message history: %w", err)
+	}
+	return history, nil
+}
+
+// Stats totals a message's events per arm
+func (r *InAppMessageRepositoryImpl) Stats(ctx context.Context, messageID uuid.UUID) ([]entity.InAppMessageStats, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT arm_id,
+		       COUNT(*) FILTER (WHERE event = 'impression'),
+		       COUNT(*) FILTER (WHERE event = 'click'),
+		       COUNT(*) FILTER (WHERE event = 'dismiss'),
+		       COUNT(DISTINCT user_id)
+		FROM in_app_message_events
+		WHERE message_id = $1
+		GROUP BY arm_id
+		ORDER BY arm_id NULLS FIRST
+	`, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read in-app message stats: %w", err)
+	}
+	defer rows.Close()
+	stats := []entity.InAppMessageStats{}
+	for rows.Next() {
+		var s entity.InAppMessageStats
+		if err := rows.Scan(&s.ArmID, &s.Impressions, &s.Clicks, &s.Dismissals, &s.UniqueUsers); err != nil {
+			return nil, fmt.Errorf("failed to scan in-app message stats: %w", err)
+		}
+		stats = append(stats, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read in-app message stats: %w", err)
+	}
+	return stats, nil
+}
+
+func scanInAppMessage(row pgx.Row) (*entity.InAppMessage, error) {
+	m := &entity.InAppMessage{}
+	var format, trigger string
+	var conditions, content, variants []byte
+	if err := row.Scan(
+		&m.ID, &m.AppID, &m.Name, &m.Placement, &format, &trigger, &m.TriggerWindowHours,
+		&m.Priority, &m.Enabled, &m.StartsAt, &m.EndsAt, &conditions, &content, &m.ExperimentID, &variants,
+		&m.MaxImpressions, &m.MinIntervalHours, &m.CreatedAt, &m.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	m.Format, m.Trigger = entity.InAppMessageFormat(format), entity.InAppMessageTrigger(trigger)
+	if err := json.Unmarshal(conditions, &m.Conditions); err != nil {
+		return nil, fmt.Errorf("decode conditions of in-app message %s: %w", m.ID, err)
+	}
+	if err := json.Unmarshal(content, &m.Content); err != nil {
+		return nil, fmt.Errorf("decode content of in-app message %s: %w", m.ID, err)
+	}
+	if err := json.Unmarshal(variants, &m.Variants); err != nil {
+		return nil, fmt.Errorf("decode variants of in-app message %s: %w", m.ID, err)
+	}
+	return m, nil
+}
+
+func encodeInAppMessage(m *entity.InAppMessage) (conditions, content, variants []byte, err error) {
+	if conditions, err = json.Marshal(m.Conditions); err != nil {
+		return nil, nil, nil, err
+	}
+	if content, err = json.Marshal(m.Content); err != nil {
+		return nil, nil, nil, err
+	}
+	v := m.Variants
+	if v == nil {
+		v = []entity.InAppMessageVariant{}
+	}
+	if variants, err = json.Marshal(v); err != nil {
+		return nil, nil, nil, err
+	}
+	return conditions, content, variants, nil
+}
@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+// LifecycleStageRepositoryImpl implements LifecycleStageRepository
+type LifecycleStageRepositoryImpl struct {
+	pool *pgxpool.Pool
+}
+
+// NewLifecycleStageRepository creates a new lifecycle stage repository
+func NewLifecycleStageRepository(pool *pgxpool.Pool) repository.LifecycleStageRepository {
+	return &LifecycleStageRepositoryImpl{pool: pool}
+}
+
+// ListSignals returns a page of users with their lifecycle signals. The
+// current subscription is the one ending last; a free trial counts while it
+// was redeemed within that subscription's current billing period.
+func (r *LifecycleStageRepositoryImpl) ListSignals(ctx context.Context, afterUserID uuid.UUID, limit int, now time.Time) ([]entity.LifecycleSignals, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT u.id, u.app_id, u.created_at, seen.last_seen_at,
+		       cur.status, cur.plan_type, cur.auto_renew, cur.created_at, cur.expires_at,
+		       lapse.lapsed_at,
+		       COALESCE(cur.plan_type <> 'lifetime' AND EXISTS (
+		           SELECT 1 FROM offer_redemptions r
+		           WHERE r.app_id = u.app_id AND r.user_id = u.id
+		             AND r.product_id = cur.product_id
+		             AND r.offer_type = 'free_trial'
+		             AND r.redeemed_at > cur.expires_at - CASE cur.plan_type WHEN 'annual' THEN INTERVAL '1 year' ELSE INTERVAL '1 month' END
+		       ), false),
+		       st.stage, st.entered_at
+		FROM users u
+		LEFT JOIN LATERAL (
+		    SELECT MAX(last_seen_at) AS last_seen_at FROM user_sessions WHERE user_id = u.id
+		) seen ON true
+		LEFT JOIN LATERAL (
+		    SELECT status, plan_type, auto_renew, product_id, created_at, expires_at
+		    FROM subscriptions
+		    WHERE user_id = u.id AND deleted_at IS NULL
+		    ORDER BY expires_at DESC
+		    LIMIT 1
+		) cur ON true
+		LEFT JOIN LATERAL (
+		    SELECT MAX(expires_at) AS lapsed_at
+		    FROM subscriptions
+		    WHERE user_id = u.id AND deleted_at IS NULL AND expires_at <= cur.created_at
+		) lapse ON true
+		LEFT JOIN user_lifecycle_stages st ON st.user_id = u.id
+		WHERE u.id > $1 AND u.deleted_at IS NULL AND u.created_at <= $3
+		ORDER BY u.id
+		LIMIT $2
+	`, afterUserID, limit, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list lifecycle signals: %w", err)
+	}
+	defer rows.Close()
+
+	var signals []entity.LifecycleSignals
+	for rows.Next() {
+		var s entity.LifecycleSignals
+		var status, planType, previousStage *string
+		var autoRenew *bool
+		var startedAt, expiresAt, previousEnteredAt *time.Time
+		if err := rows.Scan(&s.UserID, &s.AppID, &s.SignupAt, &s.LastSeenAt,
+			&status, &planType, &autoRenew, &startedAt, &expiresAt,
+			&s.LapsedAt, &s.InTrial, &previousStage, &previousEnteredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan lifecycle signals: %w", err)
+		}
+		if status != nil {
+			s.Current = &entity.LifecycleSubscription{
+				Status:    entity.SubscriptionStatus(*status),
+				PlanType:  entity.PlanType(*planType),
+				AutoRenew: *autoRenew,
+				StartedAt: *startedAt,
+				ExpiresAt: *expiresAt,
+			}
+		}
+		if previousStage != nil {
+			s.PreviousStage = entity.LifecycleStage(*previousStage)
+			s.PreviousEnteredAt = *previousEnteredAt
+		}
+		signals = append(signals, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate lifecycle signals: %w", err)
+	}
+	return signals, nil
+}
+
+// SaveStages upserts a batch of stages in one statement
+func (r *LifecycleStageRepositoryImpl) SaveStages(ctx context.Context, stages []entity.UserLifecycleStage) error {
+	if len(stages) == 0 {
+		return nil
+	}
+	userIDs := make([]uuid.UUID, len(stages))
+	appIDs := make([]uuid.UUID, len(stages))
+	names := make([]string, len(stages))
+	enteredAt := make([]time.Time, len(stages))
+	computedAt := make([]time.Time, len(stages))
+	for i, s := range stages {
+		userIDs[i], appIDs[i], names[i] = s.UserID, s.AppID, string(s.Stage)
+		enteredAt[i], computedAt[i] = s.EnteredAt, s.ComputedAt
+	}
+	if _, err := r.pool.Exec(ctx, `
+		INSERT INTO user_lifecycle_stages (user_id, app_id, stage, entered_at, computed_at)
+		SELECT * FROM unnest($1::uuid[], $2::uuid[], $3::text[], $4::timestamptz[], $5::timestamptz[])
+		ON CONFLICT (user_id) DO UPDATE
+		SET stage = EXCLUDED.stage,
+		    entered_at = CASE WHEN user_lifecycle_stages.stage = EXCLUDED.stage
+		                      THEN user_lifecycle_stages.entered_at
+		                      ELSE EXCLUDED.entered_at END,
+		    computed_at = EXCLUDED.computed_at
+	`, userIDs, appIDs, names, enteredAt, computedAt); err != nil {
+		return fmt.Errorf("failed to save lifecycle stages: %w", err)
+	}
+	return nil
+}
+
+// GetStage returns the user's stage, nil before it was first computed
+func (r *LifecycleStageRepositoryImpl) GetStage(ctx context.Context, userID uuid.UUID) (*entity.UserLifecycleStage, error) {
+	var s entity.UserLifecycleStage
+	var stage string
+	err := r.pool.QueryRow(ctx, `
+		SELECT user_id, app_id, stage, entered_at, computed_at
+		FROM user_lifecycle_stages
+		WHERE user_id = $1
+	`, userID).Scan(&s.UserID, &s.AppID, &stage, &s.EnteredAt, &s.ComputedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lifecycle stage: %w", err)
+	}
+	s.Stage = entity.LifecycleStage(stage)
+	return &s, nil
+}
+
+// CountByStage returns how many of the app's users are in each stage
+func (r *LifecycleStageRepositoryImpl) CountByStage(ctx context.Context, appID uuid.UUID) (map[entity.LifecycleStage]int64, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT stage, COUNT(*)
+		FROM user_lifecycle_stages
+		WHERE app_id = $1
+		GROUP BY stage
+	`, appID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count lifecycle stages: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[entity.LifecycleStage]int64)
+	for rows.Next() {
+		var stage string
+		var n int64
+		if err := rows.Scan(&stage, &n); err != nil {
+			return nil, fmt.Errorf("failed to scan lifecycle stage count: %w", err)
+		}
+		counts[entity.LifecycleStage(stage)] = n
+	}
+	return counts, rows.Err()
+}
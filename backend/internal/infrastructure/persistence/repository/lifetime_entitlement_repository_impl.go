@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+const lifetimeEntitlementColumns = `id, app_id, user_id, platform, product_id, provider_tx_id,
+	COALESCE(original_tx_id, ''), amount::float8, status, COALESCE(revoke_reason, ''),
+	purchased_at, revoked_at`
+
+// LifetimeEntitlementRepositoryImpl implements LifetimeEntitlementRepository
+type LifetimeEntitlementRepositoryImpl struct {
+	pool *pgxpool.Pool
+}
+
+// NewLifetimeEntitlementRepository creates a new lifetime entitlement repository
+func NewLifetimeEntitlementRepository(pool *pgxpool.Pool) repository.LifetimeEntitlementRepository {
+	return &LifetimeEntitlementRepositoryImpl{pool: pool}
+}
+
+// Create inserts the unlock unless its store transaction is already recorded
+func (r *LifetimeEntitlementRepositoryImpl) Create(ctx context.Context, e *entity.LifetimeEntitlement) (*entity.LifetimeEntitlement, bool, error) {
+	tag, err := r.pool.Exec(ctx, `
+		INSERT INTO lifetime_entitlements (
+			id, app_id, user_id, platform, product_id, provider_tx_id, original_tx_id,
+			amount, status, purchased_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9, $10)
+		ON CONFLICT (platform, provider_tx_id) DO NOTHING
+	`, e.ID, e.AppID, e.UserID, e.Platform, e.ProductID, e.ProviderTxID, e.OriginalTxID,
+		e.Amount, e.Status, e.PurchasedAt)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create lifetime entitlement: %w", err)
+	}
+	if tag.RowsAffected() == 1 {
+		return e, true, nil
+	}
+
+	existing, err := scanLifetimeEntitlement(r.pool.QueryRow(ctx, `
+		SELECT `+lifetimeEntitlementColumns+`
+		FROM lifetime_entitlements
+		WHERE platform = $1 AND provider_tx_id = $2
+	`, e.Platform, e.ProviderTxID))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get lifetime entitlement: %w", err)
+	}
+	return existing, false, nil
+}
+
+// ListActiveByUser retrieves the user's unrevoked unlocks, oldest first
+func (r *LifetimeEntitlementRepositoryImpl) ListActiveByUser(ctx context.Context, userID uuid.UUID) ([]*entity.LifetimeEntitlement, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+lifetimeEntitlementColumns+`
+		FROM lifetime_entitlements
+		WHERE user_id = $1 AND status = 'active'
+		ORDER BY purchased_at
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list lifetime entitlements: %w", err)
+	}
+	return collectLifetimeEntitlements(rows)
+}
+
+// RevokeByTransaction revokes the active unlocks bought with txID
+func (r *LifetimeEntitlementRepositoryImpl) RevokeByTransaction(ctx context.Context, platform, txID, reason string, at time.Time) ([]*entity.LifetimeEntitlement, error) {
+	rows, err := r.pool.Query(ctx, `
+		UPDATE lifetime_entitlements
+		SET status = 'revoked', revoke_reason = NULLIF($3, ''), revoked_at = $4
+		WHERE platform = $1 AND (provider_tx_id = $2 OR original_tx_id = $2) AND status = 'active'
+		RETURNING `+lifetimeEntitlementColumns,
+		platform, txID, reason, at)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke lifetime entitlements: %w", err)
+	}
+	return collectLifetimeEntitlements(rows)
+}
+
+func collectLifetimeEntitlements(rows pgx.Rows) ([]*entity.LifetimeEntitlement, error) {
+	defer rows.Close()
+	var entitlements []*entity.LifetimeEntitlement
+	for rows.Next() {
+		e, err := scanLifetimeEntitlement(rows)
+		if err != nil {
+			return nil, err
+		}
+		entitlements = append(entitlements, e)
+	}
+	return entitlements, rows.Err()
+}
+
+func scanLifetimeEntitlement(row pgx.Row) (*entity.LifetimeEntitlement, error) {
+	var e entity.LifetimeEntitlement
+	err := row.Scan(
+		&e.ID, &e.AppID, &e.UserID, &e.Platform, &e.ProductID, &e.ProviderTxID,
+		&e.OriginalTxID, &e.Amount, &e.Status, &e.RevokeReason,
+		&e.PurchasedAt, &e.RevokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
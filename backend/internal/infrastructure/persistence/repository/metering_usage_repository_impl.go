@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+// MeteringUsageRepositoryImpl implements MeteringUsageRepository
+type MeteringUsageRepositoryImpl struct {
+	pool *pgxpool.Pool
+}
+
+// NewMeteringUsageRepository creates a new metering usage repository
+func NewMeteringUsageRepository(pool *pgxpool.Pool) repository.MeteringUsageRepository {
+	return &MeteringUsageRepositoryImpl{pool: pool}
+}
+
+// GetUsed returns the uses spent in the window, 0 when none were recorded
+func (r *MeteringUsageRepositoryImpl) GetUsed(ctx context.Context, appID, userID uuid.UUID, feature string, periodStart time.Time) (int64, error) {
+	var used int64
+	err := r.pool.QueryRow(ctx, `
+		SELECT used FROM metering_usage
+		WHERE app_id = $1 AND user_id = $2 AND feature = $3 AND period_start = $4
+	`, appID, userID, feature, periodStart).Scan(&used)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get metering usage: %w", err)
+	}
+	return used, nil
+}
+
+// Upsert saves the counters in one batch. GREATEST keeps a flush that raced
+// with a reseeded counter from lowering the stored count.
+func (r *MeteringUsageRepositoryImpl) Upsert(ctx context.Context, usages []*entity.MeterUsage) error {
+	if len(usages) == 0 {
+		return nil
+	}
+	batch := &pgx.Batch{}
+	for _, u := range usages {
+		batch.Queue(`
+			INSERT INTO metering_usage (app_id, user_id, feature, period_start, used, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (app_id, user_id, feature, period_start)
+			DO UPDATE SET used = GREATEST(metering_usage.used, EXCLUDED.used), updated_at = EXCLUDED.updated_at
+		`, u.AppID, u.UserID, u.Feature, u.PeriodStart, u.Used, u.UpdatedAt)
+	}
+	if err := r.pool.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to save metering usage: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+// NotificationPreferenceRepositoryImpl implements NotificationPreferenceRepository
+type NotificationPreferenceRepositoryImpl struct {
+	pool *pgxpool.Pool
+}
+
+// NewNotificationPreferenceRepository creates a new notification preference repository
+func NewNotificationPreferenceRepository(pool *pgxpool.Pool) repository.NotificationPreferenceRepository {
+	return &NotificationPreferenceRepositoryImpl{pool: pool}
+}
+
+// Get returns the user's preferences, nil when they never set any
+func (r *NotificationPreferenceRepositoryImpl) Get(ctx context.Context, userID uuid.UUID) (*entity.NotificationPreferences, error) {
+	var p entity.NotificationPreferences
+	var categories []string
+	var quietStart, quietEnd *int
+	var timezone string
+	err := r.pool.QueryRow(ctx, `
+		SELECT user_id, app_id, push_enabled, email_enabled, disabled_categories,
+		       quiet_start_minute, quiet_end_minute, timezone, updated_at
+		FROM notification_preferences
+		WHERE user_id = $1
+	`, userID).Scan(&p.UserID, &p.AppID, &p.PushEnabled, &p.EmailEnabled, &categories,
+		&quietStart, &quietEnd, &timezone, &p.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification preferences: %w", err)
+	}
+	for _, c := range categories {
+		p.DisabledCategories = append(p.DisabledCategories, entity.NotificationCategory(c))
+	}
+	if quietStart != nil && quietEnd != nil {
+		p.QuietHours = &entity.QuietHours{StartMinute: *quietStart, EndMinute: *quietEnd, Timezone: timezone}
+	}
+	return &p, nil
+}
+
+// Upsert saves the user's preferences
+func (r *NotificationPreferenceRepositoryImpl) Upsert(ctx context.Context, p *entity.NotificationPreferences) error {
+	categories := make([]string, len(p.DisabledCategories))
+	for i, c := range p.DisabledCategories {
+		categories[i] = string(c)
+	}
+	var quietStart, quietEnd *int
+	timezone := "UTC"
+	if q := p.QuietHours; q != nil {
+		quietStart, quietEnd, timezone = &q.StartMinute, &q.EndMinute, q.Timezone
+	}
+	if _, err := r.pool.Exec(ctx, `
+		INSERT INTO notification_preferences
+			(user_id, app_id, push_enabled, email_enabled, disabled_categories,
+			 quiet_start_minute, quiet_end_minute, timezone, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (user_id) DO UPDATE
+		SET push_enabled = EXCLUDED.push_enabled,
+		    email_enabled = EXCLUDED.email_enabled,
+		    disabled_categories = EXCLUDED.disabled_categories,
+		    quiet_start_minute = EXCLUDED.quiet_start_minute,
+		    quiet_end_minute = EXCLUDED.quiet_end_minute,
+		    timezone = EXCLUDED.timezone,
+		    updated_at = EXCLUDED.updated_at
+	`, p.UserID, p.AppID, p.PushEnabled, p.EmailEnabled, categories,
+		quietStart, quietEnd, timezone, p.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to save notification preferences: %w", err)
+	}
+	return nil
+}
+
+// RecordSuppression counts one suppressed notification against the user's
+// app on day
+func (r *NotificationPreferenceRepositoryImpl) RecordSuppression(ctx context.Context, userID uuid.UUID, day time.Time, category entity.NotificationCategory, channel entity.NotificationChannel, reason entity.SuppressionReason) error {
+	if _, err := r.pool.Exec(ctx, `
+		INSERT INTO notification_suppressions (app_id, day, category, channel, reason, count)
+		SELECT app_id, $2::date, $3, $4, $5, 1 FROM users WHERE id = $1
+		ON CONFLICT (app_id, day, category, channel, reason) DO UPDATE
+		SET count = notification_suppressions.count + 1
+	`, userID, day.Format("2006-01-02"), string(category), string(channel), string(reason)); err != nil {
+		return fmt.Errorf("failed to record notification suppression: %w", err)
+	}
+	return nil
+}
+
+// SuppressionCounts returns the app's counts for days in [from, to]
+func (r *NotificationPreferenceRepositoryImpl) SuppressionCounts(ctx context.Context, appID uuid.UUID, from, to time.Time) ([]entity.NotificationSuppressionCount, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT to_char(day, 'YYYY-MM-DD'), category, channel, reason, count
+		FROM notification_suppressions
+		WHERE app_id = $1 AND day BETWEEN $2::date AND $3::date
+		ORDER BY day, category, channel, reason
+	`, appID, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read notification suppressions: %w", err)
+	}
+	defer rows.Close()
+	counts := []entity.NotificationSuppressionCount{}
+	for rows.Next() {
+		var c entity.NotificationSuppressionCount
+		var category, channel, reason string
+		if err := rows.Scan(&c.Day, &category, &channel, &reason, &c.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan notification suppression: %w", err)
+		}
+		c.Category, c.Channel, c.Reason = entity.NotificationCategory(category), entity.NotificationChannel(channel), entity.SuppressionReason(reason)
+		counts = append(counts, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read notification suppressions: %w", err)
+	}
+	return counts, nil
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+const oauthClientColumns = `id, client_id, secret_hash, name, app_id, scopes, created_by, created_at, last_used_at, revoked_at`
+
+// OAuthClientRepositoryImpl implements OAuthClientRepository
+type OAuthClientRepositoryImpl struct {
+	pool *pgxpool.Pool
+}
+
+// NewOAuthClientRepository creates a new OAuth client repository
+func NewOAuthClientRepository(pool *pgxpool.Pool) repository.OAuthClientRepository {
+	return &OAuthClientRepositoryImpl{pool: pool}
+}
+
+// Create stores a new client
+func (r *OAuthClientRepositoryImpl) Create(ctx context.Context, client *entity.OAuthClient) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO oauth_clients (id, client_id, secret_hash, name, app_id, scopes, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, client.ID, client.ClientID, client.SecretHash, client.Name, client.AppID, client.Scopes, client.CreatedBy, client.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return &domainErrors.ConflictError{
+				Entity: "oauth_client",
+				Reason: "client_id already exists",
+				Err:    err,
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// List retrieves all clients, newest first
+func (r *OAuthClientRepositoryImpl) List(ctx context.Context) ([]*entity.OAuthClient, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+oauthClientColumns+`
+		FROM oauth_clients
+		ORDER BY created_at DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var clients []*entity.OAuthClient
+	for rows.Next() {
+		client, err := scanOAuthClient(rows)
+		if err != nil {
+			return nil, err
+		}
+		clients = append(clients, client)
+	}
+	return clients, rows.Err()
+}
+
+// GetByClientID retrieves a client by its public client_id
+func (r *OAuthClientRepositoryImpl) GetByClientID(ctx context.Context, clientID string) (*entity.OAuthClient, error) {
+	client, err := scanOAuthClient(r.pool.QueryRow(ctx, `
+		SELECT `+oauthClientColumns+`
+		FROM oauth_clients
+		WHERE client_id = $1
+	`, clientID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("oauth client %s: %w", clientID, domainErrors.ErrNotFound)
+	}
+	return client, err
+}
+
+// Revoke marks a client revoked
+func (r *OAuthClientRepositoryImpl) Revoke(ctx context.Context, id uuid.UUID, at time.Time) error {
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE oauth_clients SET revoked_at = $2
+		WHERE id = $1 AND revoked_at IS NULL
+	`, id, at)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("oauth client %s: %w", id, domainErrors.ErrNotFound)
+	}
+	return nil
+}
+
+// TouchLastUsed records a successful token issuance
+func (r *OAuthClientRepositoryImpl) TouchLastUsed(ctx context.Context, id uuid.UUID, at time.Time) error {
+	_, err := r.pool.Exec(ctx, `UPDATE oauth_clients SET last_used_at = $2 WHERE id = $1`, id, at)
+	return err
+}
+
+func scanOAuthClient(row pgx.Row) (*entity.OAuthClient, error) {
+	client := &entity.OAuthClient{}
+	if err := row.Scan(
+		&client.ID, &client.ClientID, &client.SecretHash, &client.Name, &client.AppID, &client.Scopes,
+		&client.CreatedBy, &client.CreatedAt, &client.LastUsedAt, &client.RevokedAt,
+	); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+// OfferRedemptionRepositoryImpl implements OfferRedemptionRepository
+type OfferRedemptionRepositoryImpl struct {
+	pool *pgxpool.Pool
+}
+
+// NewOfferRedemptionRepository creates a new offer redemption repository
+func NewOfferRedemptionRepository(pool *pgxpool.Pool) repository.OfferRedemptionRepository {
+	return &OfferRedemptionRepositoryImpl{pool: pool}
+}
+
+// Create records a redemption
+func (r *OfferRedemptionRepositoryImpl) Create(ctx context.Context, redemption *entity.OfferRedemption) error {
+	query := `
+		INSERT INTO offer_redemptions (id, app_id, user_id, product_id, offer_type, offer_code, transaction_id, provider_tx_id, platform, redeemed_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, NULLIF($8, ''), $9, $10)
+		ON CONFLICT DO NOTHING
+	`
+
+	_, err := r.pool.Exec(ctx, query,
+		redemption.ID,
+		redemption.AppID,
+		redemption.UserID,
+		redemption.ProductID,
+		redemption.OfferType,
+		redemption.OfferCode,
+		redemption.TransactionID,
+		redemption.ProviderTxID,
+		redemption.Platform,
+		redemption.RedeemedAt,
+	)
+
+	return err
+}
+
+// GetByUserID retrieves all redemptions of a user within an app
+func (r *OfferRedemptionRepositoryImpl) GetByUserID(ctx context.Context, appID, userID uuid.UUID) ([]*entity.OfferRedemption, error) {
+	query := `
+		SELECT id, app_id, user_id, product_id, offer_type, COALESCE(offer_code, ''), transaction_id, COALESCE(provider_tx_id, ''), platform, redeemed_at
+		FROM offer_redemptions
+		WHERE app_id = $1 AND user_id = $2
+		ORDER BY redeemed_at DESC
+	`
+
+	rows, err := r.pool.Query(ctx, query, appID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var redemptions []*entity.OfferRedemption
+	for rows.Next() {
+		redemption := &entity.OfferRedemption{}
+		err := rows.Scan(
+			&redemption.ID,
+			&redemption.AppID,
+			&redemption.UserID,
+			&redemption.ProductID,
+			&redemption.OfferType,
+			&redemption.OfferCode,
+			&redemption.TransactionID,
+			&redemption.ProviderTxID,
+			&redemption.Platform,
+			&redemption.RedeemedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		redemptions = append(redemptions, redemption)
+	}
+
+	return redemptions, rows.Err()
+}
+
+// SetCampaign upserts or removes the campaign of an offer code
+func (r *OfferRedemptionRepositoryImpl) SetCampaign(ctx context.Context, appID uuid.UUID, offerCode, campaign string) error {
+	if campaign == "" {
+		_, err := r.pool.Exec(ctx, `DELETE FROM offer_code_campaigns WHERE app_id = $1 AND offer_code = $2`, appID, offerCode)
+		return err
+	}
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO offer_code_campaigns (app_id, offer_code, campaign)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (app_id, offer_code) DO UPDATE
+		SET campaign = EXCLUDED.campaign, updated_at = now()
+	`, appID, offerCode, campaign)
+	return err
+}
+
+// ListCodeOutcomes follows each user's first redemption of a batch to the
+// payments and subscription of the redeemed product
+func (r *OfferRedemptionRepositoryImpl) ListCodeOutcomes(ctx context.Context, appID uuid.UUID, from, to time.Time) ([]repository.OfferCodeOutcome, error) {
+	rows, err := r.pool.Query(ctx, `
+		WITH first_redemptions AS (
+		    SELECT DISTINCT ON (o.offer_type, o.offer_code, o.user_id)
+		           o.offer_type, o.offer_code, o.user_id, o.product_id, o.provider_tx_id, o.redeemed_at
+		    FROM offer_redemptions o
+		    WHERE o.app_id = $1 AND o.offer_type IN ('offer_code', 'win_back') AND o.offer_code IS NOT NULL
+		    ORDER BY o.offer_type, o.offer_code, o.user_id, o.redeemed_at
+		)
+		SELECT r.offer_type, r.offer_code, COALESCE(c.campaign, ''),
+		       COUNT(*),
+		       COUNT(*) FILTER (WHERE EXISTS (
+		           SELECT 1 FROM transactions t
+		           JOIN subscriptions s ON s.id = t.subscription_id
+		           WHERE s.user_id = r.user_id AND s.product_id = r.product_id
+		             AND t.status = 'success' AND t.amount > 0 AND t.created_at > r.redeemed_at
+		             AND t.provider_tx_id IS DISTINCT FROM r.provider_tx_id
+		       )),
+		       COUNT(*) FILTER (WHERE EXISTS (
+		           SELECT 1 FROM subscriptions s
+		           WHERE s.user_id = r.user_id AND s.product_id = r.product_id AND s.deleted_at IS NULL
+		             AND s.status IN ('active', 'grace') AND s.expires_at > now()
+		       ))
+		FROM first_redemptions r
+		LEFT JOIN offer_code_campaigns c ON c.app_id = $1 AND c.offer_code = r.offer_code
+		WHERE r.redeemed_at >= $2 AND r.redeemed_at < $3
+		GROUP BY 1, 2, 3
+		ORDER BY 4 DESC, 2
+	`, appID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list offer code outcomes: %w", err)
+	}
+	defer rows.Close()
+
+	var outcomes []repository.OfferCodeOutcome
+	for rows.Next() {
+		var o repository.OfferCodeOutcome
+		if err := rows.Scan(&o.OfferType, &o.OfferCode, &o.Campaign, &o.Users, &o.Renewed, &o.Retained); err != nil {
+			return nil, fmt.Errorf("failed to scan offer code outcome: %w", err)
+		}
+		outcomes = append(outcomes, o)
+	}
+	return outcomes, rows.Err()
+}
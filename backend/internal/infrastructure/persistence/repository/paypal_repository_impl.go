@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+const payPalPlanColumns = `plan_id, app_id, product_id, plan_type, created_at, updated_at`
+
+// PayPalRepositoryImpl implements PayPalRepository
+type PayPalRepositoryImpl struct {
+	pool *pgxpool.Pool
+}
+
+// NewPayPalRepository creates a new PayPal repository
+func NewPayPalRepository(pool *pgxpool.Pool) repository.PayPalRepository {
+	return &PayPalRepositoryImpl{pool: pool}
+}
+
+// UpsertPlan creates or replaces the mapping of a PayPal plan
+func (r *PayPalRepositoryImpl) UpsertPlan(ctx context.Context, plan *entity.PayPalPlan) error {
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO paypal_plans (plan_id, app_id, product_id, plan_type)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (plan_id) DO UPDATE
+		SET product_id = EXCLUDED.product_id, plan_type = EXCLUDED.plan_type, updated_at = now()
+		WHERE paypal_plans.app_id = EXCLUDED.app_id
+		RETURNING created_at, updated_at
+	`, plan.PlanID, plan.AppID, plan.ProductID, plan.PlanType).Scan(&plan.CreatedAt, &plan.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return entity.ErrPayPalPlanTaken
+	}
+	return err
+}
+
+// GetPlan retrieves the mapping of a PayPal plan
+func (r *PayPalRepositoryImpl) GetPlan(ctx context.Context, planID string) (*entity.PayPalPlan, error) {
+	plan, err := scanPayPalPlan(r.pool.QueryRow(ctx, `
+		SELECT `+payPalPlanColumns+` FROM paypal_plans WHERE plan_id = $1
+	`, planID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domainErrors.ErrNotFound
+	}
+	return plan, err
+}
+
+// ListPlans retrieves an app's plan mappings
+func (r *PayPalRepositoryImpl) ListPlans(ctx context.Context, appID uuid.UUID) ([]*entity.PayPalPlan, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+payPalPlanColumns+` FROM paypal_plans WHERE app_id = $1 ORDER BY product_id, plan_id
+	`, appID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	plans := []*entity.PayPalPlan{}
+	for rows.Next() {
+		plan, err := scanPayPalPlan(rows)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, plan)
+	}
+	return plans, rows.Err()
+}
+
+// DeletePlan removes a plan mapping
+func (r *PayPalRepositoryImpl) DeletePlan(ctx context.Context, appID uuid.UUID, planID string) error {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM paypal_plans WHERE app_id = $1 AND plan_id = $2`, appID, planID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return domainErrors.ErrNotFound
+	}
+	return nil
+}
+
+// GetSubscription retrieves a PayPal subscription
+func (r *PayPalRepositoryImpl) GetSubscription(ctx context.Context, id string) (*entity.PayPalSubscription, error) {
+	s := &entity.PayPalSubscription{}
+	err := r.pool.QueryRow(ctx, `
+		SELECT paypal_subscription_id, app_id, user_id, subscription_id, plan_id, status, created_at, updated_at
+		FROM paypal_subscriptions
+		WHERE paypal_subscription_id = $1
+	`, id).Scan(&s.ID, &s.AppID, &s.UserID, &s.SubscriptionID, &s.PlanID, &s.Status, &s.CreatedAt, &s.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domainErrors.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SaveSubscription creates or updates a PayPal subscription link
+func (r *PayPalRepositoryImpl) SaveSubscription(ctx context.Context, s *entity.PayPalSubscription) error {
+	return r.pool.QueryRow(ctx, `
+		INSERT INTO paypal_subscriptions (paypal_subscription_id, app_id, user_id, subscription_id, plan_id, status)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (paypal_subscription_id) DO UPDATE
+		SET plan_id = EXCLUDED.plan_id, status = EXCLUDED.status, updated_at = now()
+		RETURNING created_at, updated_at
+	`, s.ID, s.AppID, s.UserID, s.SubscriptionID, s.PlanID, s.Status).Scan(&s.CreatedAt, &s.UpdatedAt)
+}
+
+func scanPayPalPlan(row pgx.Row) (*entity.PayPalPlan, error) {
+	p := &entity.PayPalPlan{}
+	if err := row.Scan(&p.PlanID, &p.AppID, &p.ProductID, &p.PlanType, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
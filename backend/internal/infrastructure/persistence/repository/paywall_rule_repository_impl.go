@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+const paywallRuleColumns = `id, app_id, name, placement, priority, enabled, conditions,
+	paywall_id, COALESCE(offer_code, ''), experiment_id, arm_ids, created_at, updated_at`
+
+// PaywallRuleRepositoryImpl implements PaywallRuleRepository
+type PaywallRuleRepositoryImpl struct {
+	pool *pgxpool.Pool
+}
+
+// NewPaywallRuleRepository creates a new paywall rule repository
+func NewPaywallRuleRepository(pool *pgxpool.Pool) repository.PaywallRuleRepository {
+	return &PaywallRuleRepositoryImpl{pool: pool}
+}
+
+// List retrieves all rules of an app in evaluation order
+func (r *PaywallRuleRepositoryImpl) List(ctx context.Context, appID uuid.UUID) ([]*entity.PaywallRule, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+paywallRuleColumns+`
+		FROM paywall_rules
+		WHERE app_id = $1
+		ORDER BY priority DESC, created_at
+	`, appID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []*entity.PaywallRule
+	for rows.Next() {
+		rule, err := scanPaywallRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// GetByID retrieves a rule of an app
+func (r *PaywallRuleRepositoryImpl) GetByID(ctx context.Context, appID, id uuid.UUID) (*entity.PaywallRule, error) {
+	rule, err := scanPaywallRule(r.pool.QueryRow(ctx, `
+		SELECT `+paywallRuleColumns+`
+		FROM paywall_rules
+		WHERE app_id = $1 AND id = $2
+	`, appID, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("paywall rule %s: %w", id, domainErrors.ErrNotFound)
+	}
+	return rule, err
+}
+
+// Create creates a new rule
+func (r *PaywallRuleRepositoryImpl) Create(ctx context.Context, rule *entity.PaywallRule) error {
+	conditions, err := json.Marshal(rule.Conditions)
+	if err != nil {
+		return err
+	}
+	_, err = r.pool.Exec(ctx, `
+		INSERT INTO paywall_rules (id, app_id, name, placement, priority, enabled, conditions,
+			paywall_id, offer_code, experiment_id, arm_ids, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), $10, $11, $12, $13)
+	`, rule.ID, rule.AppID, rule.Name, rule.Placement, rule.Priority, rule.Enabled, conditions,
+		rule.PaywallID, rule.OfferCode, rule.ExperimentID, armIDs(rule), rule.CreatedAt, rule.UpdatedAt)
+	return err
+}
+
+// Update replaces an existing rule
+func (r *PaywallRuleRepositoryImpl) Update(ctx context.Context, rule *entity.PaywallRule) error {
+	conditions, err := json.Marshal(rule.Conditions)
+	if err != nil {
+		return err
+	}
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE paywall_rules
+		SET name = $3, placement = $4, priority = $5, enabled = $6, conditions = $7,
+			paywall_id = $8, offer_code = NULLIF($9, ''), experiment_id = $10, arm_ids = $11,
+			updated_at = $12
+		WHERE app_id = $1 AND id = $2
+	`, rule.AppID, rule.ID, rule.Name, rule.Placement, rule.Priority, rule.Enabled, conditions,
+		rule.PaywallID, rule.OfferCode, rule.ExperimentID, armIDs(rule), rule.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("paywall rule %s: %w", rule.ID, domainErrors.ErrNotFound)
+	}
+	return nil
+}
+
+// Delete removes a rule
+func (r *PaywallRuleRepositoryImpl) Delete(ctx context.Context, appID, id uuid.UUID) error {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM paywall_rules WHERE app_id = $1 AND id = $2`, appID, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("paywall rule %s: %w", id, domainErrors.ErrNotFound)
+	}
+	return nil
+}
+
+func scanPaywallRule(row pgx.Row) (*entity.PaywallRule, error) {
+	rule := &entity.PaywallRule{}
+	var conditions []byte
+	if err := row.Scan(
+		&rule.ID, &rule.AppID, &rule.Name, &rule.Placement, &rule.Priority, &rule.Enabled, &conditions,
+		&rule.PaywallID, &rule.OfferCode, &rule.ExperimentID, &rule.ArmIDs, &rule.CreatedAt, &rule.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(conditions, &rule.Conditions); err != nil {
+		return nil, fmt.Errorf("decode conditions of paywall rule %s: %w", rule.ID, err)
+	}
+	return rule, nil
+}
+
+// armIDs never passes NULL for the NOT NULL arm_ids column
+func armIDs(rule *entity.PaywallRule) []uuid.UUID {
+	if rule.ArmIDs == nil {
+		return []uuid.UUID{}
+	}
+	return rule.ArmIDs
+}
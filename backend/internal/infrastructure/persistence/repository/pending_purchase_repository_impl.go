@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+const pendingPurchaseColumns = `id, app_id, user_id, platform, product_id, reason, status,
+	COALESCE(purchase_token, ''), COALESCE(device_token, ''), COALESCE(provider_tx_id, ''),
+	created_at, expires_at, resolved_at`
+
+// PendingPurchaseRepositoryImpl implements PendingPurchaseRepository
+type PendingPurchaseRepositoryImpl struct {
+	pool *pgxpool.Pool
+}
+
+// NewPendingPurchaseRepository creates a new pending purchase repository
+func NewPendingPurchaseRepository(pool *pgxpool.Pool) repository.PendingPurchaseRepository {
+	return &PendingPurchaseRepositoryImpl{pool: pool}
+}
+
+// Create stores a new pending purchase
+func (r *PendingPurchaseRepositoryImpl) Create(ctx context.Context, p *entity.PendingPurchase) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO pending_purchases (
+			id, app_id, user_id, platform, product_id, reason, status,
+			purchase_token, device_token, created_at, expires_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), NULLIF($9, ''), $10, $11)
+	`, p.ID, p.AppID, p.UserID, p.Platform, p.ProductID, p.Reason, p.Status,
+		p.PurchaseToken, p.DeviceToken, p.CreatedAt, p.ExpiresAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return entity.ErrPendingPurchaseOpen
+		}
+		return err
+	}
+	return nil
+}
+
+// GetOpen retrieves the open pending purchase matching match
+func (r *PendingPurchaseRepositoryImpl) GetOpen(ctx context.Context, match repository.PendingPurchaseMatch) (*entity.PendingPurchase, error) {
+	var row pgx.Row
+	if match.PurchaseToken != "" {
+		row = r.pool.QueryRow(ctx, `
+			SELECT `+pendingPurchaseColumns+`
+			FROM pending_purchases
+			WHERE purchase_token = $1 AND status = 'pending'
+			ORDER BY created_at DESC
+			LIMIT 1
+		`, match.PurchaseToken)
+	} else {
+		row = r.pool.QueryRow(ctx, `
+			SELECT `+pendingPurchaseColumns+`
+			FROM pending_purchases
+			WHERE user_id = $1 AND product_id = $2 AND platform = $3 AND status = 'pending'
+		`, match.UserID, match.ProductID, match.Platform)
+	}
+	p, err := scanPendingPurchase(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("pending purchase: %w", domainErrors.ErrNotFound)
+	}
+	return p, err
+}
+
+// ListByUser retrieves a user's pending purchases created since, newest first
+func (r *PendingPurchaseRepositoryImpl) ListByUser(ctx context.Context, userID uuid.UUID, since time.Time) ([]*entity.PendingPurchase, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+pendingPurchaseColumns+`
+		FROM pending_purchases
+		WHERE user_id = $1 AND created_at >= $2
+		ORDER BY created_at DESC
+	`, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	return collectPendingPurchases(rows)
+}
+
+// Resolve stores the final status of a still-pending purchase
+func (r *PendingPurchaseRepositoryImpl) Resolve(ctx context.Context, p *entity.PendingPurchase) error {
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE pending_purchases
+		SET status = $2, provider_tx_id = NULLIF($3, ''), resolved_at = $4
+		WHERE id = $1 AND status = 'pending'
+	`, p.ID, p.Status, p.ProviderTxID, p.ResolvedAt)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return entity.ErrPendingPurchaseResolved
+	}
+	return nil
+}
+
+// ListExpired retrieves open pending purchases whose window closed before now
+func (r *PendingPurchaseRepositoryImpl) ListExpired(ctx context.Context, now time.Time, limit int) ([]*entity.PendingPurchase, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+pendingPurchaseColumns+`
+		FROM pending_purchases
+		WHERE status = 'pending' AND expires_at < $1
+		ORDER BY expires_at
+		LIMIT $2
+	`, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	return collectPendingPurchases(rows)
+}
+
+func collectPendingPurchases(rows pgx.Rows) ([]*entity.PendingPurchase, error) {
+	defer rows.Close()
+	var purchases []*entity.PendingPurchase
+	for rows.Next() {
+		p, err := scanPendingPurchase(rows)
+		if err != nil {
+			return nil, err
+		}
+		purchases = append(purchases, p)
+	}
+	return purchases, rows.Err()
+}
+
+func scanPendingPurchase(row pgx.Row) (*entity.PendingPurchase, error) {
+	p := &entity.PendingPurchase{}
+	if err := row.Scan(
+		&p.ID, &p.AppID, &p.UserID, &p.Platform, &p.ProductID, &p.Reason, &p.Status,
+		&p.PurchaseToken, &p.DeviceToken, &p.ProviderTxID,
+		&p.CreatedAt, &p.ExpiresAt, &p.ResolvedAt,
+	); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+const priceIncreaseConsentColumns = `id, app_id, subscription_id, user_id, platform, product_id, status,
+	new_price::float8, COALESCE(currency, ''), notified_at, responded_at, prompt_count, last_prompted_at,
+	created_at, updated_at`
+
+// PriceIncreaseConsentRepositoryImpl implements PriceIncreaseConsentRepository
+type PriceIncreaseConsentRepositoryImpl struct {
+	pool *pgxpool.Pool
+}
+
+// NewPriceIncreaseConsentRepository creates a new price increase consent repository
+func NewPriceIncreaseConsentRepository(pool *pgxpool.Pool) repository.PriceIncreaseConsentRepository {
+	return &PriceIncreaseConsentRepositoryImpl{pool: pool}
+}
+
+// Create stores a new price increase
+func (r *PriceIncreaseConsentRepositoryImpl) Create(ctx context.Context, c *entity.PriceIncreaseConsent) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO price_increase_consents (
+			id, app_id, subscription_id, user_id, platform, product_id, status,
+			new_price, currency, notified_at, responded_at, created_at, updated_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), $10, $11, $12, $13)
+	`, c.ID, c.AppID, c.SubscriptionID, c.UserID, c.Platform, c.ProductID, c.Status,
+		c.NewPrice, c.Currency, c.NotifiedAt, c.RespondedAt, c.CreatedAt, c.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return entity.ErrPriceIncreaseOpen
+		}
+		return err
+	}
+	return nil
+}
+
+// GetPending retrieves the pending price increase of a subscription
+func (r *PriceIncreaseConsentRepositoryImpl) GetPending(ctx context.Context, subscriptionID uuid.UUID) (*entity.PriceIncreaseConsent, error) {
+	c, err := scanPriceIncreaseConsent(r.pool.QueryRow(ctx, `
+		SELECT `+priceIncreaseConsentColumns+`
+		FROM price_increase_consents
+		WHERE subscription_id = $1 AND status = 'pending'
+	`, subscriptionID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("price increase consent: %w", domainErrors.ErrNotFound)
+	}
+	return c, err
+}
+
+// GetLatest retrieves the most recently notified price increase of a subscription
+func (r *PriceIncreaseConsentRepositoryImpl) GetLatest(ctx context.Context, subscriptionID uuid.UUID) (*entity.PriceIncreaseConsent, error) {
+	c, err := scanPriceIncreaseConsent(r.pool.QueryRow(ctx, `
+		SELECT `+priceIncreaseConsentColumns+`
+		FROM price_increase_consents
+		WHERE subscription_id = $1
+		ORDER BY notified_at DESC, created_at DESC
+		LIMIT 1
+	`, subscriptionID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("price increase consent: %w", domainErrors.ErrNotFound)
+	}
+	return c, err
+}
+
+// Update stores the status, price and prompt counters of a price increase
+func (r *PriceIncreaseConsentRepositoryImpl) Update(ctx context.Context, c *entity.PriceIncreaseConsent) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE price_increase_consents
+		SET status = $2, new_price = $3, currency = NULLIF($4, ''), responded_at = $5,
+		    prompt_count = $6, last_prompted_at = $7, updated_at = $8
+		WHERE id = $1
+	`, c.ID, c.Status, c.NewPrice, c.Currency, c.RespondedAt, c.PromptCount, c.LastPromptedAt, c.UpdatedAt)
+	return err
+}
+
+// ListPending retrieves an app's pending price increases, oldest notice first
+func (r *PriceIncreaseConsentRepositoryImpl) ListPending(ctx context.Context, appID uuid.UUID, limit, offset int) ([]*entity.PriceIncreaseConsent, int, error) {
+	var total int
+	if err := r.pool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM price_increase_consents WHERE app_id = $1 AND status = 'pending'
+	`, appID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+priceIncreaseConsentColumns+`
+		FROM price_increase_consents
+		WHERE app_id = $1 AND status = 'pending'
+		ORDER BY notified_at, id
+		LIMIT $2 OFFSET $3
+	`, appID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	consents, err := collectPriceIncreaseConsents(rows)
+	return consents, total, err
+}
+
+// ListPromptable retrieves pending price increases due another prompt
+func (r *PriceIncreaseConsentRepositoryImpl) ListPromptable(ctx context.Context, appID uuid.UUID, promptedBefore time.Time, maxPrompts, limit int) ([]*entity.PriceIncreaseConsent, error) {
+	var appFilter *uuid.UUID
+	if appID != uuid.Nil {
+		appFilter = &appID
+	}
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+priceIncreaseConsentColumns+`
+		FROM price_increase_consents
+		WHERE status = 'pending'
+		  AND ($1::uuid IS NULL OR app_id = $1)
+		  AND prompt_count < $2
+		  AND (last_prompted_at IS NULL OR last_prompted_at <= $3)
+		ORDER BY notified_at, id
+		LIMIT $4
+	`, appFilter, maxPrompts, promptedBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+	return collectPriceIncreaseConsents(rows)
+}
+
+func collectPriceIncreaseConsents(rows pgx.Rows) ([]*entity.PriceIncreaseConsent, error) {
+	defer rows.Close()
+	consents := []*entity.PriceIncreaseConsent{}
+	for rows.Next() {
+		c, err := scanPriceIncreaseConsent(rows)
+		if err != nil {
+			return nil, err
+		}
+		consents = append(consents, c)
+	}
+	return consents, rows.Err()
+}
+
+func scanPriceIncreaseConsent(row pgx.Row) (*entity.PriceIncreaseConsent, error) {
+	c := &entity.PriceIncreaseConsent{}
+	if err := row.Scan(
+		&c.ID, &c.AppID, &c.SubscriptionID, &c.UserID, &c.Platform, &c.ProductID, &c.Status,
+		&c.NewPrice, &c.Currency, &c.NotifiedAt, &c.RespondedAt, &c.PromptCount, &c.LastPromptedAt,
+		&c.CreatedAt, &c.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
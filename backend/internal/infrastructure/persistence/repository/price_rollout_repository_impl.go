@@ -0,0 +1,265 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+const priceRolloutColumns = `id, app_id, pricing_tier_id, region, currency,
+	monthly_price::double precision, annual_price::double precision, lifetime_price::double precision,
+	stages, status, paused_at, experiment_id, holdout_arm_id, ramp_arm_id, created_at, updated_at`
+
+// PriceRolloutRepositoryImpl implements PriceRolloutRepository
+type PriceRolloutRepositoryImpl struct {
+	pool *pgxpool.Pool
+}
+
+// NewPriceRolloutRepository creates a new price rollout repository
+func NewPriceRolloutRepository(pool *pgxpool.Pool) repository.PriceRolloutRepository {
+	return &PriceRolloutRepositoryImpl{pool: pool}
+}
+
+// List retrieves all rollouts of an app, newest first
+func (r *PriceRolloutRepositoryImpl) List(ctx context.Context, appID uuid.UUID) ([]*entity.PriceRollout, error) {
+	return r.query(ctx, `
+		SELECT `+priceRolloutColumns+`
+		FROM price_rollouts
+		WHERE app_id = $1
+		ORDER BY created_at DESC
+	`, appID)
+}
+
+// ListOpen retrieves the active and paused rollouts of an app
+func (r *PriceRolloutRepositoryImpl) ListOpen(ctx context.Context, appID uuid.UUID) ([]*entity.PriceRollout, error) {
+	return r.query(ctx, `
+		SELECT `+priceRolloutColumns+`
+		FROM price_rollouts
+		WHERE app_id = $1 AND status IN ('active', 'paused')
+		ORDER BY created_at
+	`, appID)
+}
+
+// GetByID retrieves a rollout of an app
+func (r *PriceRolloutRepositoryImpl) GetByID(ctx context.Context, appID, id uuid.UUID) (*entity.PriceRollout, error) {
+	rollout, err := scanPriceRollout(r.pool.QueryRow(ctx, `
+		SELECT `+priceRolloutColumns+`
+		FROM price_rollouts
+		WHERE app_id = $1 AND id = $2
+	`, appID, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("price rollout %s: %w", id, domainErrors.ErrNotFound)
+	}
+	return rollout, err
+}
+
+// Create creates the rollout together with its running holdout experiment
+func (r *PriceRolloutRepositoryImpl) Create(ctx context.Context, rollout *entity.PriceRollout, experimentName string) error {
+	stages, err := json.Marshal(rollout.Stages)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	var tierExists bool
+	if err := tx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM pricing_tiers WHERE id = $1 AND app_id = $2 AND deleted_at IS NULL)
+	`, rollout.PricingTierID, rollout.AppID).Scan(&tierExists); err != nil {
+		return err
+	}
+	if !tierExists {
+		return fmt.Errorf("pricing tier %s: %w", rollout.PricingTierID, domainErrors.ErrNotFound)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO ab_tests (id, app_id, name, description, status, start_at, is_bandit)
+		VALUES ($1, $2, $3, $4, 'running', $5, false)
+	`, rollout.ExperimentID, rollout.AppID, experimentName,
+		"Holdout comparison for price rollout "+rollout.ID.String(), rollout.StartsAt()); err != nil {
+		return fmt.Errorf("failed to create rollout experiment: %w", err)
+	}
+	for _, arm := range []struct {
+		id        uuid.UUID
+		name      string
+		isControl bool
+	}{
+		{rollout.HoldoutArmID, "holdout", true},
+		{rollout.RampArmID, "ramp", false},
+	} {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO ab_test_arms (id, experiment_id, name, description, is_control, traffic_weight, pricing_tier_id)
+			VALUES ($1, $2, $3, '', $4, 1.0, $5)
+		`, arm.id, rollout.ExperimentID, arm.name, arm.isControl, rollout.PricingTierID); err != nil {
+			return fmt.Errorf("failed to create rollout arm: %w", err)
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO price_rollouts (
+			id, app_id, pricing_tier_id, region, currency, monthly_price, annual_price, lifetime_price,
+			stages, status, experiment_id, holdout_arm_id, ramp_arm_id, created_at, updated_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+	`, rollout.ID, rollout.AppID, rollout.PricingTierID, rollout.Region, rollout.Currency,
+		rollout.MonthlyPrice, rollout.AnnualPrice, rollout.LifetimePrice, stages, rollout.Status,
+		rollout.ExperimentID, rollout.HoldoutArmID, rollout.RampArmID, rollout.CreatedAt, rollout.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return &domainErrors.ConflictError{
+				Entity: "price_rollout",
+				Reason: "the tier already has an open rollout in this region",
+				Err:    err,
+			}
+		}
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// Update persists status and schedule. Finishing a rollout completes its
+// experiment; completing it also writes the new prices to the pricing tier.
+func (r *PriceRolloutRepositoryImpl) Update(ctx context.Context, rollout *entity.PriceRollout) error {
+	stages, err := json.Marshal(rollout.Stages)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE price_rollouts
+		SET stages = $3, status = $4, paused_at = $5, updated_at = $6
+		WHERE app_id = $1 AND id = $2
+	`, rollout.AppID, rollout.ID, stages, rollout.Status, rollout.PausedAt, rollout.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("price rollout %s: %w", rollout.ID, domainErrors.ErrNotFound)
+	}
+
+	switch rollout.Status {
+	case entity.PriceRolloutCompleted:
+		// Prices left unset by the rollout keep their current value
+		if _, err := tx.Exec(ctx, `
+			UPDATE pricing_tiers
+			SET currency = $2,
+			    monthly_price = COALESCE($3, monthly_price),
+			    annual_price = COALESCE($4, annual_price),
+			    lifetime_price = COALESCE($5, lifetime_price),
+			    updated_at = now()
+			WHERE id = $1
+		`, rollout.PricingTierID, rollout.Currency, rollout.MonthlyPrice, rollout.AnnualPrice, rollout.LifetimePrice); err != nil {
+			return fmt.Errorf("failed to apply rollout prices: %w", err)
+		}
+		fallthrough
+	case entity.PriceRolloutRolledBack:
+		if _, err := tx.Exec(ctx, `
+			UPDATE ab_tests SET status = 'completed', end_at = $2, updated_at = now()
+			WHERE id = $1 AND status <> 'completed'
+		`, rollout.ExperimentID, rollout.UpdatedAt); err != nil {
+			return fmt.Errorf("failed to complete rollout experiment: %w", err)
+		}
+	case entity.PriceRolloutPaused, entity.PriceRolloutActive:
+		status := "running"
+		if rollout.Status == entity.PriceRolloutPaused {
+			status = "paused"
+		}
+		if _, err := tx.Exec(ctx, `
+			UPDATE ab_tests SET status = $2, updated_at = now() WHERE id = $1
+		`, rollout.ExperimentID, status); err != nil {
+			return fmt.Errorf("failed to update rollout experiment: %w", err)
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// CohortStats returns per-arm outcomes of the rollout experiment
+func (r *PriceRolloutRepositoryImpl) CohortStats(ctx context.Context, rollout *entity.PriceRollout, until time.Time) ([]repository.PriceRolloutCohortStats, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT arm.id,
+		       COUNT(DISTINCT a.user_id)::int,
+		       COUNT(DISTINCT t.user_id)::int,
+		       COALESCE(SUM(t.amount), 0)::double precision
+		FROM ab_test_arms arm
+		LEFT JOIN ab_test_assignments a ON a.arm_id = arm.id AND a.experiment_id = arm.experiment_id
+		LEFT JOIN transactions t ON t.user_id = a.user_id
+		                         AND t.status = 'success'
+		                         AND t.created_at >= a.assigned_at
+		                         AND t.created_at < $2
+		WHERE arm.experiment_id = $1
+		GROUP BY arm.id
+	`, rollout.ExperimentID, until)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []repository.PriceRolloutCohortStats
+	for rows.Next() {
+		var s repository.PriceRolloutCohortStats
+		if err := rows.Scan(&s.ArmID, &s.Users, &s.Converters, &s.Revenue); err != nil {
+			return nil, err
+		}
+		stats = append(stats, s)
+	}
+	return stats, rows.Err()
+}
+
+func (r *PriceRolloutRepositoryImpl) query(ctx context.Context, sql string, args ...any) ([]*entity.PriceRollout, error) {
+	rows, err := r.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rollouts []*entity.PriceRollout
+	for rows.Next() {
+		rollout, err := scanPriceRollout(rows)
+		if err != nil {
+			return nil, err
+		}
+		rollouts = append(rollouts, rollout)
+	}
+	return rollouts, rows.Err()
+}
+
+func scanPriceRollout(row pgx.Row) (*entity.PriceRollout, error) {
+	rollout := &entity.PriceRollout{}
+	var stages []byte
+	var status string
+	if err := row.Scan(
+		&rollout.ID, &rollout.AppID, &rollout.PricingTierID, &rollout.Region, &rollout.Currency,
+		&rollout.MonthlyPrice, &rollout.AnnualPrice, &rollout.LifetimePrice,
+		&stages, &status, &rollout.PausedAt, &rollout.ExperimentID, &rollout.HoldoutArmID, &rollout.RampArmID,
+		&rollout.CreatedAt, &rollout.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	rollout.Status = entity.PriceRolloutStatus(status)
+	if err := json.Unmarshal(stages, &rollout.Stages); err != nil {
+		return nil, fmt.Errorf("decode stages of price rollout %s: %w", rollout.ID, err)
+	}
+	return rollout, nil
+}
@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+// ReactivationRepositoryImpl implements ReactivationRepository
+type ReactivationRepositoryImpl struct {
+	pool *pgxpool.Pool
+}
+
+// NewReactivationRepository creates a new reactivation repository
+func NewReactivationRepository(pool *pgxpool.Pool) repository.ReactivationRepository {
+	return &ReactivationRepositoryImpl{pool: pool}
+}
+
+// ListCandidates returns subscriptions started once every earlier subscription
+// of the user had expired, with the latest accepted win-back offer, offer code
+// or promotional redemption of the product and experiment assignment since.
+func (r *ReactivationRepositoryImpl) ListCandidates(ctx context.Context, since time.Time, limit int) ([]entity.ReactivationCandidate, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT s.id, s.user_id, s.app_id, s.plan_type, lapse.lapsed_at, s.created_at,
+		       wo.id, wo.campaign_id, wo.accepted_at,
+		       rd.id, rd.offer_code, rd.redeemed_at,
+		       asg.experiment_id, asg.arm_id, asg.assigned_at
+		FROM subscriptions s
+		JOIN LATERAL (
+		    SELECT MAX(p.expires_at) AS lapsed_at
+		    FROM subscriptions p
+		    WHERE p.user_id = s.user_id AND p.id <> s.id AND p.deleted_at IS NULL
+		      AND p.created_at < s.created_at
+		) lapse ON lapse.lapsed_at <= s.created_at
+		LEFT JOIN LATERAL (
+		    SELECT o.id, o.campaign_id, o.accepted_at
+		    FROM winback_offers o
+		    WHERE o.user_id = s.user_id AND o.status = 'accepted'
+		      AND o.accepted_at > lapse.lapsed_at AND o.accepted_at <= s.created_at
+		    ORDER BY o.accepted_at DESC
+		    LIMIT 1
+		) wo ON true
+		LEFT JOIN LATERAL (
+		    SELECT o.id, COALESCE(o.offer_code, '') AS offer_code, o.redeemed_at
+		    FROM offer_redemptions o
+		    WHERE o.app_id = s.app_id AND o.user_id = s.user_id AND o.product_id = s.product_id
+		      AND o.offer_type IN ('offer_code', 'promotional', 'win_back')
+		      AND o.redeemed_at > lapse.lapsed_at
+		      AND o.redeemed_at <= s.created_at + $3 * INTERVAL '1 second'
+		    ORDER BY o.redeemed_at DESC
+		    LIMIT 1
+		) rd ON true
+		LEFT JOIN LATERAL (
+		    SELECT a.experiment_id, a.arm_id, a.assigned_at
+		    FROM ab_test_assignments a
+		    WHERE a.user_id = s.user_id
+		      AND a.assigned_at > lapse.lapsed_at AND a.assigned_at <= s.created_at
+		    ORDER BY a.assigned_at DESC
+		    LIMIT 1
+		) asg ON true
+		WHERE s.created_at >= $1 AND s.deleted_at IS NULL
+		  AND NOT EXISTS (SELECT 1 FROM subscription_reactivations sr WHERE sr.subscription_id = s.id)
+		ORDER BY s.created_at, s.id
+		LIMIT $2
+	`, since, limit, int64(entity.ReactivationRedemptionSlack/time.Second))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reactivation candidates: %w", err)
+	}
+	defer rows.Close()
+
+	var candidates []entity.ReactivationCandidate
+	for rows.Next() {
+		var c entity.ReactivationCandidate
+		var planType string
+		var offerID, redemptionID, experimentID, armID *uuid.UUID
+		var campaignID, offerCode *string
+		var acceptedAt, redeemedAt, assignedAt *time.Time
+		if err := rows.Scan(&c.SubscriptionID, &c.UserID, &c.AppID, &planType, &c.LapsedAt, &c.ReactivatedAt,
+			&offerID, &campaignID, &acceptedAt,
+			&redemptionID, &offerCode, &redeemedAt,
+			&experimentID, &armID, &assignedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan reactivation candidate: %w", err)
+		}
+		c.PlanType = entity.PlanType(planType)
+		if offerID != nil {
+			c.WinbackOffer = &entity.ReactivationTouch{ID: *offerID, Key: *campaignID, At: *acceptedAt}
+		}
+		if redemptionID != nil {
+			c.Redemption = &entity.ReactivationTouch{ID: *redemptionID, Key: *offerCode, At: *redeemedAt}
+		}
+		if experimentID != nil {
+			c.Assignment = &entity.ReactivationTouch{ID: *experimentID, ArmID: *armID, At: *assignedAt}
+		}
+		candidates = append(candidates, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate reactivation candidates: %w", err)
+	}
+	return candidates, nil
+}
+
+// SaveReactivations inserts a batch of reactivations in one statement
+func (r *ReactivationRepositoryImpl) SaveReactivations(ctx context.Context, reactivations []entity.SubscriptionReactivation) error {
+	if len(reactivations) == 0 {
+		return nil
+	}
+	n := len(reactivations)
+	subscriptionIDs, appIDs, userIDs := make([]uuid.UUID, n), make([]uuid.UUID, n), make([]uuid.UUID, n)
+	planTypes, sources := make([]string, n), make([]string, n)
+	mrr := make([]float64, n)
+	lapsedAt, reactivatedAt := make([]time.Time, n), make([]time.Time, n)
+	offerIDs, redemptionIDs := make([]*uuid.UUID, n), make([]*uuid.UUID, n)
+	experimentIDs, armIDs := make([]*uuid.UUID, n), make([]*uuid.UUID, n)
+	campaignIDs, offerCodes := make([]*string, n), make([]*string, n)
+	for i, re := range reactivations {
+		subscriptionIDs[i], appIDs[i], userIDs[i] = re.SubscriptionID, re.AppID, re.UserID
+		planTypes[i], sources[i], mrr[i] = string(re.PlanType), string(re.Source), re.MRR
+		lapsedAt[i], reactivatedAt[i] = re.LapsedAt, re.ReactivatedAt
+		offerIDs[i], redemptionIDs[i] = re.WinbackOfferID, re.OfferRedemptionID
+		experimentIDs[i], armIDs[i] = re.ExperimentID, re.ArmID
+		campaignIDs[i], offerCodes[i] = nullableText(re.CampaignID), nullableText(re.OfferCode)
+	}
+	if _, err := r.pool.Exec(ctx, `
+		INSERT INTO subscription_reactivations (
+		    subscription_id, app_id, user_id, plan_type, mrr, lapsed_at, reactivated_at, source,
+		    winback_offer_id, campaign_id, offer_redemption_id, offer_code, experiment_id, arm_id)
+		SELECT * FROM unnest($1::uuid[], $2::uuid[], $3::uuid[], $4::text[], $5::numeric[], $6::timestamptz[],
+		    $7::timestamptz[], $8::text[], $9::uuid[], $10::text[], $11::uuid[], $12::text[], $13::uuid[], $14::uuid[])
+		ON CONFLICT (subscription_id) DO NOTHING
+	`, subscriptionIDs, appIDs, userIDs, planTypes, mrr, lapsedAt, reactivatedAt, sources,
+		offerIDs, campaignIDs, redemptionIDs, offerCodes, experimentIDs, armIDs); err != nil {
+		return fmt.Errorf("failed to save subscription reactivations: %w", err)
+	}
+	return nil
+}
+
+// ListArmStats counts each arm's assigned users and the reactivations and
+// resurrected MRR credited to it
+func (r *ReactivationRepositoryImpl) ListArmStats(ctx context.Context, appID, experimentID uuid.UUID) ([]repository.ReactivationArmStats, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT a.id, a.name, a.is_control,
+		       (SELECT COUNT(*) FROM ab_test_assignments s WHERE s.arm_id = a.id)::int,
+		       COUNT(sr.subscription_id)::int,
+		       COALESCE(SUM(sr.mrr), 0)::float8
+		FROM ab_test_arms a
+		JOIN ab_tests e ON e.id = a.experiment_id
+		LEFT JOIN subscription_reactivations sr ON sr.experiment_id = e.id AND sr.arm_id = a.id
+		WHERE e.id = $1 AND e.app_id = $2
+		GROUP BY a.id, a.name, a.is_control, a.created_at
+		ORDER BY a.is_control DESC, a.created_at
+	`, experimentID, appID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reactivation arm stats: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []repository.ReactivationArmStats
+	for rows.Next() {
+		var s repository.ReactivationArmStats
+		if err := rows.Scan(&s.ArmID, &s.ArmName, &s.IsControl, &s.Users, &s.Reactivations, &s.MRR); err != nil {
+			return nil, fmt.Errorf("failed to scan reactivation arm stats: %w", err)
+		}
+		stats = append(stats, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate reactivation arm stats: %w", err)
+	}
+	return stats, nil
+}
+
+func nullableText(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
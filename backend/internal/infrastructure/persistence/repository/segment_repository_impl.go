@@ -0,0 +1,246 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+const segmentColumns = `s.id, s.app_id, s.name, s.description, s.definition, s.materialized,
+	s.member_count, s.materialized_at, s.created_at, s.updated_at`
+
+// SegmentRepositoryImpl implements SegmentRepository
+type SegmentRepositoryImpl struct {
+	pool *pgxpool.Pool
+}
+
+// NewSegmentRepository creates a new segment repository
+func NewSegmentRepository(pool *pgxpool.Pool) repository.SegmentRepository {
+	return &SegmentRepositoryImpl{pool: pool}
+}
+
+// List retrieves all segments of an app
+func (r *SegmentRepositoryImpl) List(ctx context.Context, appID uuid.UUID) ([]*entity.Segment, error) {
+	return r.query(ctx, `
+		SELECT `+segmentColumns+`
+		FROM segments s
+		WHERE s.app_id = $1
+		ORDER BY s.name
+	`, appID)
+}
+
+// ListMaterialized retrieves the materialized segments of every app
+func (r *SegmentRepositoryImpl) ListMaterialized(ctx context.Context) ([]*entity.Segment, error) {
+	return r.query(ctx, `
+		SELECT `+segmentColumns+`
+		FROM segments s
+		WHERE s.materialized = true
+		ORDER BY s.materialized_at NULLS FIRST
+	`)
+}
+
+// GetByID retrieves a segment of an app
+func (r *SegmentRepositoryImpl) GetByID(ctx context.Context, appID, id uuid.UUID) (*entity.Segment, error) {
+	segment, err := scanSegment(r.pool.QueryRow(ctx, `
+		SELECT `+segmentColumns+`
+		FROM segments s
+		WHERE s.app_id = $1 AND s.id = $2
+	`, appID, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("segment %s: %w", id, domainErrors.ErrNotFound)
+	}
+	return segment, err
+}
+
+// Create creates a new segment
+func (r *SegmentRepositoryImpl) Create(ctx context.Context, segment *entity.Segment) error {
+	definition, err := json.Marshal(segment.Definition)
+	if err != nil {
+		return err
+	}
+	_, err = r.pool.Exec(ctx, `
+		INSERT INTO segments (id, app_id, name, description, definition, materialized, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, segment.ID, segment.AppID, segment.Name, segment.Description, definition, segment.Materialized,
+		segment.CreatedAt, segment.UpdatedAt)
+	return err
+}
+
+// Update replaces an existing segment's definition and settings
+func (r *SegmentRepositoryImpl) Update(ctx context.Context, segment *entity.Segment) error {
+	definition, err := json.Marshal(segment.Definition)
+	if err != nil {
+		return err
+	}
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE segments
+		SET name = $3, description = $4, definition = $5, materialized = $6, updated_at = $7
+		WHERE app_id = $1 AND id = $2
+	`, segment.AppID, segment.ID, segment.Name, segment.Description, definition, segment.Materialized, segment.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("segment %s: %w", segment.ID, domainErrors.ErrNotFound)
+	}
+	return nil
+}
+
+// Delete removes a segment and its members
+func (r *SegmentRepositoryImpl) Delete(ctx context.Context, appID, id uuid.UUID) error {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM segments WHERE app_id = $1 AND id = $2`, appID, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("segment %s: %w", id, domainErrors.ErrNotFound)
+	}
+	return nil
+}
+
+// ReplaceMembers atomically replaces the materialized membership
+func (r *SegmentRepositoryImpl) ReplaceMembers(ctx context.Context, segmentID uuid.UUID, userIDs []uuid.UUID, materializedAt time.Time) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	if _, err := tx.Exec(ctx, `DELETE FROM segment_members WHERE segment_id = $1`, segmentID); err != nil {
+		return err
+	}
+	if len(userIDs) > 0 {
+		rows := make([][]any, 0, len(userIDs))
+		for _, id := range userIDs {
+			rows = append(rows, []any{segmentID, id, materializedAt})
+		}
+		if _, err := tx.CopyFrom(ctx, pgx.Identifier{"segment_members"}, []string{"segment_id", "user_id", "added_at"}, pgx.CopyFromRows(rows)); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE segments SET member_count = $2, materialized_at = $3 WHERE id = $1
+	`, segmentID, len(userIDs), materializedAt); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// IsMember reports whether a user is in a materialized segment
+func (r *SegmentRepositoryImpl) IsMember(ctx context.Context, segmentID, userID uuid.UUID) (bool, error) {
+	var exists bool
+	err := r.pool.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM segment_members WHERE segment_id = $1 AND user_id = $2)
+	`, segmentID, userID).Scan(&exists)
+	return exists, err
+}
+
+// ListMemberIDs pages through a materialized segment ordered by user ID
+func (r *SegmentRepositoryImpl) ListMemberIDs(ctx context.Context, segmentID, after uuid.UUID, limit int) ([]uuid.UUID, error) {
+	return r.queryIDs(ctx, `
+		SELECT user_id FROM segment_members
+		WHERE segment_id = $1 AND user_id > $2
+		ORDER BY user_id
+		LIMIT $3
+	`, segmentID, after, limit)
+}
+
+// ListAppUserIDs pages through an app's users ordered by ID
+func (r *SegmentRepositoryImpl) ListAppUserIDs(ctx context.Context, appID, after uuid.UUID, limit int) ([]uuid.UUID, error) {
+	return r.queryIDs(ctx, `
+		SELECT id FROM users
+		WHERE app_id = $1 AND deleted_at IS NULL AND id > $2
+		ORDER BY id
+		LIMIT $3
+	`, appID, after, limit)
+}
+
+// GetExperimentSegment returns the segment an experiment targets, or nil
+func (r *SegmentRepositoryImpl) GetExperimentSegment(ctx context.Context, experimentID uuid.UUID) (*entity.Segment, error) {
+	segment, err := scanSegment(r.pool.QueryRow(ctx, `
+		SELECT `+segmentColumns+`
+		FROM ab_tests t
+		JOIN segments s ON s.id = t.segment_id
+		WHERE t.id = $1
+	`, experimentID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return segment, err
+}
+
+// SetExperimentSegment targets an experiment of an app at a segment (nil clears it)
+func (r *SegmentRepositoryImpl) SetExperimentSegment(ctx context.Context, appID, experimentID uuid.UUID, segmentID *uuid.UUID) error {
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE ab_tests SET segment_id = $3, updated_at = now()
+		WHERE app_id = $1 AND id = $2
+	`, appID, experimentID, segmentID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("experiment %s: %w", experimentID, domainErrors.ErrNotFound)
+	}
+	return nil
+}
+
+func (r *SegmentRepositoryImpl) query(ctx context.Context, sql string, args ...any) ([]*entity.Segment, error) {
+	rows, err := r.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var segments []*entity.Segment
+	for rows.Next() {
+		segment, err := scanSegment(rows)
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, segment)
+	}
+	return segments, rows.Err()
+}
+
+func (r *SegmentRepositoryImpl) queryIDs(ctx context.Context, sql string, args ...any) ([]uuid.UUID, error) {
+	rows, err := r.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func scanSegment(row pgx.Row) (*entity.Segment, error) {
+	segment := &entity.Segment{}
+	var definition []byte
+	if err := row.Scan(
+		&segment.ID, &segment.AppID, &segment.Name, &segment.Description, &definition, &segment.Materialized,
+		&segment.MemberCount, &segment.MaterializedAt, &segment.CreatedAt, &segment.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(definition, &segment.Definition); err != nil {
+		return nil, fmt.Errorf("decode definition of segment %s: %w", segment.ID, err)
+	}
+	return segment, nil
+}
@@ -193,9 +193,31 @@ func (r *subscriptionRepositoryImpl) GetTotalRevenue(ctx context.Context, userID
 	}
 }
 
+func (r *subscriptionRepositoryImpl) GetNetRevenue(ctx context.Context, userID uuid.UUID) (float64, error) {
+	appID, _ := appctx.AppIDFromCtx(ctx)
+	result, err := r.queries.GetNetLTVByUserID(ctx, generated.GetNetLTVByUserIDParams{
+		AppID:  appID,
+		UserID: userID,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get net revenue: %w", err)
+	}
+	switch v := result.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	default:
+		return 0, nil
+	}
+}
+
 func (r *subscriptionRepositoryImpl) mapToEntity(row generated.Subscription) *entity.Subscription {
 	return &entity.Subscription{
 		ID:        row.ID,
+		AppID:     row.AppID,
 		UserID:    row.UserID,
 		Status:    entity.SubscriptionStatus(row.Status),
 		Source:    entity.SubscriptionSource(row.Source),
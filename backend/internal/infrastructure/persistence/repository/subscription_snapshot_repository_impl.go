@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+// SubscriptionSnapshotRepositoryImpl implements SubscriptionSnapshotRepository
+type SubscriptionSnapshotRepositoryImpl struct {
+	pool *pgxpool.Pool
+}
+
+// NewSubscriptionSnapshotRepository creates a new subscription snapshot repository
+func NewSubscriptionSnapshotRepository(pool *pgxpool.Pool) repository.SubscriptionSnapshotRepository {
+	return &SubscriptionSnapshotRepositoryImpl{pool: pool}
+}
+
+// Capture replaces the snapshot of date with the current state of every
+// subscription, evaluated at capturedAt. Returns the number of rows.
+func (r *SubscriptionSnapshotRepositoryImpl) Capture(ctx context.Context, date, capturedAt time.Time) (int64, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	// Re-running a date replaces it, so the run row is written first and the
+	// old rows dropped
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO subscription_snapshot_runs (snapshot_date, captured_at, row_count)
+		VALUES ($1, $2, 0)
+		ON CONFLICT (snapshot_date) DO UPDATE SET captured_at = EXCLUDED.captured_at
+	`, date, capturedAt); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM subscription_snapshots WHERE snapshot_date = $1`, date); err != nil {
+		return 0, err
+	}
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO subscription_snapshots (
+			snapshot_date, subscription_id, app_id, user_id, status, plan_type, product_id,
+			platform, source, expires_at, auto_renew, active, captured_at
+		)
+		SELECT $1, s.id, s.app_id, s.user_id, s.status, s.plan_type, s.product_id,
+		       s.platform, s.source, s.expires_at, s.auto_renew,
+		       s.status IN ('active', 'grace') AND s.expires_at > $2, $2
+		FROM subscriptions s
+		WHERE s.deleted_at IS NULL AND s.created_at <= $2
+	`, date, capturedAt)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE subscription_snapshot_runs SET row_count = $2 WHERE snapshot_date = $1
+	`, date, tag.RowsAffected()); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// Summary counts an app's snapshot of date; ErrNotFound when none was taken
+func (r *SubscriptionSnapshotRepositoryImpl) Summary(ctx context.Context, appID uuid.UUID, date time.Time) (*repository.SubscriptionSnapshotSummary, error) {
+	summary := &repository.SubscriptionSnapshotSummary{
+		Date:             date,
+		ByStatus:         map[string]int{},
+		ActiveByPlan:     map[string]int{},
+		ActiveByPlatform: map[string]int{},
+	}
+	err := r.pool.QueryRow(ctx, `
+		SELECT captured_at FROM subscription_snapshot_runs WHERE snapshot_date = $1
+	`, date).Scan(&summary.CapturedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("subscription snapshot %s: %w", date.Format("2006-01-02"), domainErrors.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT status, plan_type, platform, active, COUNT(*)::int
+		FROM subscription_snapshots
+		WHERE app_id = $1 AND snapshot_date = $2
+		GROUP BY status, plan_type, platform, active
+	`, appID, date)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var status, plan, platform string
+		var active bool
+		var n int
+		if err := rows.Scan(&status, &plan, &platform, &active, &n); err != nil {
+			return nil, err
+		}
+		summary.Total += n
+		summary.ByStatus[status] += n
+		if active {
+			summary.Active += n
+			summary.ActiveByPlan[plan] += n
+			summary.ActiveByPlatform[platform] += n
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	err = r.pool.QueryRow(ctx, `
+		SELECT COUNT(DISTINCT user_id)::int
+		FROM subscription_snapshots
+		WHERE app_id = $1 AND snapshot_date = $2 AND active
+	`, appID, date).Scan(&summary.ActiveUsers)
+	return summary, err
+}
+
+// Diff compares an app's snapshots of two dates
+func (r *SubscriptionSnapshotRepositoryImpl) Diff(ctx context.Context, appID uuid.UUID, from, to time.Time) (*repository.SubscriptionSnapshotDiff, error) {
+	diff := &repository.SubscriptionSnapshotDiff{From: from, To: to}
+	err := r.pool.QueryRow(ctx, `
+		WITH per_user AS (
+			SELECT snapshot_date,
+			       user_id,
+			       MAX(CASE plan_type WHEN 'monthly' THEN 1 WHEN 'annual' THEN 2 WHEN 'lifetime' THEN 3 ELSE 0 END) AS plan_rank,
+			       string_agg(product_id, ',' ORDER BY product_id) AS products
+			FROM subscription_snapshots
+			WHERE app_id = $1 AND snapshot_date IN ($2, $3) AND active
+			GROUP BY snapshot_date, user_id
+		),
+		f AS (SELECT * FROM per_user WHERE snapshot_date = $2),
+		t AS (SELECT * FROM per_user WHERE snapshot_date = $3)
+		SELECT COUNT(*) FILTER (WHERE f.user_id IS NULL)::int,
+		       COUNT(*) FILTER (WHERE t.user_id IS NULL)::int,
+		       COUNT(*) FILTER (WHERE t.plan_rank > f.plan_rank)::int,
+		       COUNT(*) FILTER (WHERE t.plan_rank < f.plan_rank)::int,
+		       COUNT(*) FILTER (WHERE t.plan_rank = f.plan_rank AND t.products <> f.products)::int,
+		       COUNT(*) FILTER (WHERE t.plan_rank = f.plan_rank AND t.products = f.products)::int
+		FROM f
+		FULL OUTER JOIN t ON t.user_id = f.user_id
+	`, appID, from, to).Scan(&diff.New, &diff.Churned, &diff.Upgraded, &diff.Downgraded, &diff.Crossgraded, &diff.Retained)
+	if err != nil {
+		return nil, err
+	}
+	return diff, nil
+}
+
+// ListDates returns the captured snapshot dates within [from, to]
+func (r *SubscriptionSnapshotRepositoryImpl) ListDates(ctx context.Context, from, to time.Time) ([]time.Time, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT snapshot_date FROM subscription_snapshot_runs
+		WHERE snapshot_date BETWEEN $1 AND $2
+		ORDER BY snapshot_date
+	`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var dates []time.Time
+	for rows.Next() {
+		var d time.Time
+		if err := rows.Scan(&d); err != nil {
+			return nil, err
+		}
+		dates = append(dates, d)
+	}
+	return dates, rows.Err()
+}
+
+// ListUserRows returns a user's snapshot rows within [from, to], oldest first
+func (r *SubscriptionSnapshotRepositoryImpl) ListUserRows(ctx context.Context, appID, userID uuid.UUID, from, to time.Time) ([]repository.SubscriptionSnapshotRow, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT snapshot_date, subscription_id, user_id, status, plan_type, product_id,
+		       platform, source, expires_at, auto_renew, active
+		FROM subscription_snapshots
+		WHERE app_id = $1 AND user_id = $2 AND snapshot_date BETWEEN $3 AND $4
+		ORDER BY snapshot_date, subscription_id
+	`, appID, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []repository.SubscriptionSnapshotRow
+	for rows.Next() {
+		var row repository.SubscriptionSnapshotRow
+		if err := rows.Scan(
+			&row.Date, &row.SubscriptionID, &row.UserID, &row.Status, &row.PlanType, &row.ProductID,
+			&row.Platform, &row.Source, &row.ExpiresAt, &row.AutoRenew, &row.Active,
+		); err != nil {
+			return nil, err
+		}
+		out = append(out, row)
+	}
+	return out, rows.Err()
+}
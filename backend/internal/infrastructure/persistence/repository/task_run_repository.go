@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+// TaskRunRepositoryImpl implements TaskRunRepository
+type TaskRunRepositoryImpl struct {
+	pool *pgxpool.Pool
+}
+
+// NewTaskRunRepository creates a new task run repository
+func NewTaskRunRepository(pool *pgxpool.Pool) repository.TaskRunRepository {
+	return &TaskRunRepositoryImpl{pool: pool}
+}
+
+func (r *TaskRunRepositoryImpl) StartTaskRun(ctx context.Context, input repository.TaskRunStartInput) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO task_runs (task_type, task_id, queue, retry_count, status)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id`,
+		input.TaskType,
+		input.TaskID,
+		input.Queue,
+		input.RetryCount,
+		entity.TaskRunStatusRunning,
+	).Scan(&id)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to start task run: %w", err)
+	}
+	return id, nil
+}
+
+func (r *TaskRunRepositoryImpl) FinishTaskRun(ctx context.Context, runID uuid.UUID, status string, errMsg string, duration time.Duration) error {
+	var errValue *string
+	if errMsg != "" {
+		errValue = &errMsg
+	}
+
+	_, err := r.pool.Exec(ctx, `
+		UPDATE task_runs
+		SET status = $2,
+		    error = $3,
+		    finished_at = now(),
+		    duration_ms = $4
+		WHERE id = $1`, runID, status, errValue, duration.Milliseconds())
+	if err != nil {
+		return fmt.Errorf("failed to finish task run: %w", err)
+	}
+	return nil
+}
+
+func (r *TaskRunRepositoryImpl) ListTaskRuns(ctx context.Context, filter repository.TaskRunFilter) ([]entity.TaskRun, error) {
+	limit := filter.Limit
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, task_type, task_id, queue, retry_count, status, error, started_at, finished_at, duration_ms
+		FROM task_runs
+		WHERE ($1 = '' OR task_type = $1)
+		  AND ($2 = '' OR status = $2)
+		ORDER BY started_at DESC
+		LIMIT $3`, filter.TaskType, filter.Status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list task runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := make([]entity.TaskRun, 0)
+	for rows.Next() {
+		var run entity.TaskRun
+		if err := rows.Scan(
+			&run.ID,
+			&run.TaskType,
+			&run.TaskID,
+			&run.Queue,
+			&run.RetryCount,
+			&run.Status,
+			&run.Error,
+			&run.StartedAt,
+			&run.FinishedAt,
+			&run.DurationMs,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan task run: %w", err)
+		}
+		runs = append(runs, run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate task runs: %w", err)
+	}
+	return runs, nil
+}
+
+func (r *TaskRunRepositoryImpl) GetTaskRunStats(ctx context.Context, since time.Time) ([]entity.TaskRunStats, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT
+			task_type,
+			COUNT(*),
+			COUNT(*) FILTER (WHERE status = 'succeeded'),
+			COUNT(*) FILTER (WHERE status = 'failed'),
+			COUNT(*) FILTER (WHERE status = 'running'),
+			COALESCE(AVG(duration_ms), 0)::float8,
+			COALESCE(MAX(duration_ms), 0)::bigint,
+			MAX(started_at),
+			MAX(finished_at) FILTER (WHERE status = 'succeeded'),
+			MAX(finished_at) FILTER (WHERE status = 'failed')
+		FROM task_runs
+		WHERE started_at >= $1
+		GROUP BY task_type
+		ORDER BY task_type`, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task run stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := make([]entity.TaskRunStats, 0)
+	for rows.Next() {
+		var s entity.TaskRunStats
+		if err := rows.Scan(
+			&s.TaskType,
+			&s.Total,
+			&s.Succeeded,
+			&s.Failed,
+			&s.Running,
+			&s.AvgDurationMs,
+			&s.MaxDurationMs,
+			&s.LastStartedAt,
+			&s.LastSucceededAt,
+			&s.LastFailedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan task run stats: %w", err)
+		}
+		if finished := s.Succeeded + s.Failed; finished > 0 {
+			s.FailureRate = float64(s.Failed) / float64(finished)
+		}
+		stats = append(stats, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate task run stats: %w", err)
+	}
+	return stats, nil
+}
@@ -31,6 +31,23 @@ func (r *transactionRepositoryImpl) Create(ctx context.Context, txn *entity.Tran
 		Status:         string(txn.Status),
 		ReceiptHash:    &txn.ReceiptHash,
 		ProviderTxID:   &txn.ProviderTxID,
+		StoreFee:       txn.StoreFee,
+		TaxAmount:      txn.TaxAmount,
+		NetAmount:      txn.NetAmount,
+		Provider:       txn.Provider,
+		Environment:    txn.Environment,
+	}
+	if txn.Environment == "" {
+		params.Environment = entity.TransactionEnvironmentProduction
+	}
+	if txn.CountryCode != "" {
+		params.CountryCode = &txn.CountryCode
+	}
+	if txn.OriginalTransactionID != "" {
+		params.OriginalTransactionID = &txn.OriginalTransactionID
+	}
+	if txn.ProductID != "" {
+		params.ProductID = &txn.ProductID
 	}
 
 	_, err := r.queries.CreateTransaction(ctx, params)
@@ -113,6 +130,29 @@ func (r *transactionRepositoryImpl) GetSegmentedLTV(ctx context.Context, periodD
 	return result, nil
 }
 
+func (r *transactionRepositoryImpl) ReconcileRevenue(ctx context.Context, appID uuid.UUID, providerTxID, currency string, breakdown entity.RevenueBreakdown) (bool, error) {
+	net := breakdown.Net
+	params := generated.ReconcileTransactionRevenueParams{
+		AppID:        appID,
+		ProviderTxID: &providerTxID,
+		Amount:       breakdown.Gross,
+		Currency:     currency,
+		StoreFee:     breakdown.StoreFee,
+		TaxAmount:    breakdown.Tax,
+		NetAmount:    &net,
+	}
+	if breakdown.CountryCode != "" {
+		params.CountryCode = &breakdown.CountryCode
+	}
+
+	rows, err := r.queries.ReconcileTransactionRevenue(ctx, params)
+	if err != nil {
+		return false, fmt.Errorf("failed to reconcile transaction revenue: %w", err)
+	}
+
+	return rows > 0, nil
+}
+
 func (r *transactionRepositoryImpl) CheckDuplicateReceipt(ctx context.Context, receiptHash string) (bool, error) {
 	_, err := r.queries.CheckDuplicateReceipt(ctx, &receiptHash)
 	if err != nil {
@@ -126,7 +166,19 @@ func (r *transactionRepositoryImpl) CheckDuplicateReceipt(ctx context.Context, r
 }
 
 func (r *transactionRepositoryImpl) mapToEntity(row generated.Transaction) *entity.Transaction {
-	var receiptHash, providerTxID string
+	var receiptHash, providerTxID, countryCode, originalTxID, productID, refundReference string
+	if row.CountryCode != nil {
+		countryCode = *row.CountryCode
+	}
+	if row.OriginalTransactionID != nil {
+		originalTxID = *row.OriginalTransactionID
+	}
+	if row.ProductID != nil {
+		productID = *row.ProductID
+	}
+	if row.RefundReference != nil {
+		refundReference = *row.RefundReference
+	}
 	if row.ReceiptHash != nil {
 		receiptHash = *row.ReceiptHash
 	}
@@ -136,6 +188,7 @@ func (r *transactionRepositoryImpl) mapToEntity(row generated.Transaction) *enti
 
 	return &entity.Transaction{
 		ID:             row.ID,
+		AppID:          row.AppID,
 		UserID:         row.UserID,
 		SubscriptionID: row.SubscriptionID,
 		Amount:         row.Amount,
@@ -144,5 +197,17 @@ func (r *transactionRepositoryImpl) mapToEntity(row generated.Transaction) *enti
 		ReceiptHash:    receiptHash,
 		ProviderTxID:   providerTxID,
 		CreatedAt:      row.CreatedAt,
+		StoreFee:       row.StoreFee,
+		TaxAmount:      row.TaxAmount,
+		NetAmount:      row.NetAmount,
+		CountryCode:    countryCode,
+		ReconciledAt:   row.ReconciledAt,
+
+		Provider:              row.Provider,
+		OriginalTransactionID: originalTxID,
+		ProductID:             productID,
+		Environment:           row.Environment,
+		RefundedAt:            row.RefundedAt,
+		RefundReference:       refundReference,
 	}
 }
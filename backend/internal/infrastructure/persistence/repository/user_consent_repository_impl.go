@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+// UserConsentRepositoryImpl implements UserConsentRepository
+type UserConsentRepositoryImpl struct {
+	pool *pgxpool.Pool
+}
+
+// NewUserConsentRepository creates a new user consent repository
+func NewUserConsentRepository(pool *pgxpool.Pool) repository.UserConsentRepository {
+	return &UserConsentRepositoryImpl{pool: pool}
+}
+
+// Get returns the user's consent, nil when they never set one
+func (r *UserConsentRepositoryImpl) Get(ctx context.Context, userID uuid.UUID) (*entity.UserConsent, error) {
+	var c entity.UserConsent
+	err := r.pool.QueryRow(ctx, `
+		SELECT user_id, app_id, analytics, personalization, marketing, updated_at
+		FROM user_consents
+		WHERE user_id = $1
+	`, userID).Scan(&c.UserID, &c.AppID, &c.Analytics, &c.Personalization, &c.Marketing, &c.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user consent: %w", err)
+	}
+	return &c, nil
+}
+
+// Upsert saves the user's consent
+func (r *UserConsentRepositoryImpl) Upsert(ctx context.Context, c *entity.UserConsent) error {
+	if _, err := r.pool.Exec(ctx, `
+		INSERT INTO user_consents (user_id, app_id, analytics, personalization, marketing, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id) DO UPDATE
+		SET analytics = EXCLUDED.analytics,
+		    personalization = EXCLUDED.personalization,
+		    marketing = EXCLUDED.marketing,
+		    updated_at = EXCLUDED.updated_at
+	`, c.UserID, c.AppID, c.Analytics, c.Personalization, c.Marketing, c.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to save user consent: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+const userSessionColumns = `id, user_id, app_id, refresh_jti, access_jti, device_id, device_fingerprint,
+	platform, app_version, user_agent, ip_address, created_at, last_seen_at, expires_at, revoked_at`
+
+// UserSessionRepositoryImpl implements UserSessionRepository
+type UserSessionRepositoryImpl struct {
+	pool *pgxpool.Pool
+}
+
+// NewUserSessionRepository creates a new user session repository
+func NewUserSessionRepository(pool *pgxpool.Pool) repository.UserSessionRepository {
+	return &UserSessionRepositoryImpl{pool: pool}
+}
+
+// Create stores a new session
+func (r *UserSessionRepositoryImpl) Create(ctx context.Context, s *entity.UserSession) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO user_sessions (
+			id, user_id, app_id, refresh_jti, access_jti, device_id, device_fingerprint,
+			platform, app_version, user_agent, ip_address, created_at, last_seen_at, expires_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`, s.ID, s.UserID, s.AppID, s.RefreshJTI, s.AccessJTI, s.Device.DeviceID, s.DeviceFingerprint,
+		s.Device.Platform, s.Device.AppVersion, s.Device.UserAgent, s.Device.IPAddress,
+		s.CreatedAt, s.LastSeenAt, s.ExpiresAt)
+	return err
+}
+
+// GetByID retrieves a session
+func (r *UserSessionRepositoryImpl) GetByID(ctx context.Context, id uuid.UUID) (*entity.UserSession, error) {
+	session, err := scanUserSession(r.pool.QueryRow(ctx, `
+		SELECT `+userSessionColumns+` FROM user_sessions WHERE id = $1
+	`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("user session %s: %w", id, domainErrors.ErrNotFound)
+	}
+	return session, err
+}
+
+// ListActive retrieves a user's live sessions, most recently used first
+func (r *UserSessionRepositoryImpl) ListActive(ctx context.Context, userID uuid.UUID, now time.Time) ([]*entity.UserSession, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+userSessionColumns+`
+		FROM user_sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > $2
+		ORDER BY last_seen_at DESC
+	`, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []*entity.UserSession
+	for rows.Next() {
+		session, err := scanUserSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// Rotate stores the tokens of a refresh if previousRefreshJTI is still current
+func (r *UserSessionRepositoryImpl) Rotate(ctx context.Context, s *entity.UserSession, previousRefreshJTI string) error {
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE user_sessions
+		SET refresh_jti = $3, access_jti = $4, app_version = $5, ip_address = $6,
+		    last_seen_at = $7, expires_at = $8
+		WHERE id = $1 AND refresh_jti = $2 AND revoked_at IS NULL
+	`, s.ID, previousRefreshJTI, s.RefreshJTI, s.AccessJTI, s.Device.AppVersion, s.Device.IPAddress,
+		s.LastSeenAt, s.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("user session %s: %w", s.ID, domainErrors.ErrNotFound)
+	}
+	return nil
+}
+
+// Revoke marks a user's session revoked
+func (r *UserSessionRepositoryImpl) Revoke(ctx context.Context, userID, id uuid.UUID, at time.Time) error {
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE user_sessions SET revoked_at = $3
+		WHERE id = $2 AND user_id = $1 AND revoked_at IS NULL
+	`, userID, id, at)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("user session %s: %w", id, domainErrors.ErrNotFound)
+	}
+	return nil
+}
+
+// DeleteStale removes sessions that expired or were revoked before cutoff
+func (r *UserSessionRepositoryImpl) DeleteStale(ctx context.Context, cutoff time.Time) (int64, error) {
+	tag, err := r.pool.Exec(ctx, `
+		DELETE FROM user_sessions WHERE expires_at < $1 OR revoked_at < $1
+	`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func scanUserSession(row pgx.Row) (*entity.UserSession, error) {
+	s := &entity.UserSession{}
+	if err := row.Scan(
+		&s.ID, &s.UserID, &s.AppID, &s.RefreshJTI, &s.AccessJTI, &s.Device.DeviceID, &s.DeviceFingerprint,
+		&s.Device.Platform, &s.Device.AppVersion, &s.Device.UserAgent, &s.Device.IPAddress,
+		&s.CreatedAt, &s.LastSeenAt, &s.ExpiresAt, &s.RevokedAt,
+	); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type WebhookQuarantineRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewWebhookQuarantineRepository(pool *pgxpool.Pool) *WebhookQuarantineRepository {
+	return &WebhookQuarantineRepository{pool: pool}
+}
+
+const webhookQuarantineColumns = `id, provider, error, status, attempts, last_error, event_type, event_id, received_at, resolved_at`
+
+func (r *WebhookQuarantineRepository) QuarantineWebhook(ctx context.Context, provider string, rawBody []byte, reason string) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO webhook_quarantine (provider, raw_body, error)
+		VALUES ($1, $2, $3)
+		RETURNING id`, provider, rawBody, reason).Scan(&id)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to quarantine webhook: %w", err)
+	}
+	return id, nil
+}
+
+func (r *WebhookQuarantineRepository) ListQuarantinedWebhooks(ctx context.Context, filter service.WebhookQuarantineFilter) ([]service.WebhookQuarantineEntry, error) {
+	limit := filter.Limit
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+webhookQuarantineColumns+`
+		FROM webhook_quarantine
+		WHERE ($1 = '' OR provider = $1)
+		  AND ($2 = '' OR status = $2)
+		ORDER BY received_at DESC
+		LIMIT $3`, filter.Provider, filter.Status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list quarantined webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]service.WebhookQuarantineEntry, 0)
+	for rows.Next() {
+		var entry service.WebhookQuarantineEntry
+		if err := rows.Scan(
+			&entry.ID,
+			&entry.Provider,
+			&entry.Error,
+			&entry.Status,
+			&entry.Attempts,
+			&entry.LastError,
+			&entry.EventType,
+			&entry.EventID,
+			&entry.ReceivedAt,
+			&entry.ResolvedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan quarantined webhook: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate quarantined webhooks: %w", err)
+	}
+	return entries, nil
+}
+
+func (r *WebhookQuarantineRepository) GetQuarantinedWebhook(ctx context.Context, id uuid.UUID) (*service.WebhookQuarantineEntry, error) {
+	var entry service.WebhookQuarantineEntry
+	err := r.pool.QueryRow(ctx, `
+		SELECT `+webhookQuarantineColumns+`, raw_body
+		FROM webhook_quarantine
+		WHERE id = $1`, id).Scan(
+		&entry.ID,
+		&entry.Provider,
+		&entry.Error,
+		&entry.Status,
+		&entry.Attempts,
+		&entry.LastError,
+		&entry.EventType,
+		&entry.EventID,
+		&entry.ReceivedAt,
+		&entry.ResolvedAt,
+		&entry.RawBody,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, service.ErrWebhookQuarantineNotFound
+		}
+		return nil, fmt.Errorf("failed to get quarantined webhook: %w", err)
+	}
+	return &entry, nil
+}
+
+func (r *WebhookQuarantineRepository) MarkWebhookReparsed(ctx context.Context, id uuid.UUID, eventType, eventID string) error {
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE webhook_quarantine
+		SET status = $2,
+		    attempts = attempts + 1,
+		    event_type = $3,
+		    event_id = $4,
+		    resolved_at = now()
+		WHERE id = $1 AND status = $5`,
+		id, service.WebhookQuarantineStatusReparsed, eventType, eventID, service.WebhookQuarantineStatusQuarantined)
+	if err != nil {
+		return fmt.Errorf("failed to mark webhook reparsed: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return service.ErrWebhookQuarantineResolved
+	}
+	return nil
+}
+
+func (r *WebhookQuarantineRepository) RecordWebhookReparseFailure(ctx context.Context, id uuid.UUID, reason string) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE webhook_quarantine
+		SET attempts = attempts + 1,
+		    last_error = $2
+		WHERE id = $1`, id, reason)
+	if err != nil {
+		return fmt.Errorf("failed to record webhook reparse failure: %w", err)
+	}
+	return nil
+}
+
+func (r *WebhookQuarantineRepository) DiscardQuarantinedWebhook(ctx context.Context, id uuid.UUID) error {
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE webhook_quarantine
+		SET status = $2,
+		    resolved_at = now()
+		WHERE id = $1 AND status = $3`,
+		id, service.WebhookQuarantineStatusDiscarded, service.WebhookQuarantineStatusQuarantined)
+	if err != nil {
+		return fmt.Errorf("failed to discard quarantined webhook: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return service.ErrWebhookQuarantineResolved
+	}
+	return nil
+}
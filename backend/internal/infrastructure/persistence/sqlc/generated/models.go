@@ -84,16 +84,28 @@ type Subscription struct {
 }
 
 type Transaction struct {
-	ID             uuid.UUID `json:"id"`
-	AppID          uuid.UUID `json:"app_id"`
-	UserID         uuid.UUID `json:"user_id"`
-	SubscriptionID uuid.UUID `json:"subscription_id"`
-	Amount         float64   `json:"amount"`
-	Currency       string    `json:"currency"`
-	Status         string    `json:"status"`
-	ReceiptHash    *string   `json:"receipt_hash"`
-	ProviderTxID   *string   `json:"provider_tx_id"`
-	CreatedAt      time.Time `json:"created_at"`
+	ID                    uuid.UUID  `json:"id"`
+	AppID                 uuid.UUID  `json:"app_id"`
+	UserID                uuid.UUID  `json:"user_id"`
+	SubscriptionID        uuid.UUID  `json:"subscription_id"`
+	Amount                float64    `json:"amount"`
+	Currency              string     `json:"currency"`
+	Status                string     `json:"status"`
+	ReceiptHash           *string    `json:"receipt_hash"`
+	ProviderTxID          *string    `json:"provider_tx_id"`
+	CreatedAt             time.Time  `json:"created_at"`
+	StoreFee              float64    `json:"store_fee"`
+	TaxAmount             float64    `json:"tax_amount"`
+	NetAmount             *float64   `json:"net_amount"`
+	CountryCode           *string    `json:"country_code"`
+	ReconciledAt          *time.Time `json:"reconciled_at"`
+	Provider              string     `json:"provider"`
+	OriginalTransactionID *string    `json:"original_transaction_id"`
+	ProductID             *string    `json:"product_id"`
+	Environment           string     `json:"environment"`
+	RefundedAt            *time.Time `json:"refunded_at"`
+	RefundReference       *string    `json:"refund_reference"`
+	WebhookSeenAt         *time.Time `json:"webhook_seen_at"`
 }
 
 type User struct {
@@ -115,11 +127,16 @@ type User struct {
 }
 
 type WebhookEvent struct {
-	ID          uuid.UUID  `json:"id"`
-	Provider    string     `json:"provider"`
-	EventType   string     `json:"event_type"`
-	EventID     string     `json:"event_id"`
-	Payload     []byte     `json:"payload"`
-	ProcessedAt *time.Time `json:"processed_at"`
-	CreatedAt   time.Time  `json:"created_at"`
+	ID              uuid.UUID  `json:"id"`
+	Provider        string     `json:"provider"`
+	EventType       string     `json:"event_type"`
+	EventID         string     `json:"event_id"`
+	Payload         []byte     `json:"payload"`
+	ProcessedAt     *time.Time `json:"processed_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	Status          string     `json:"status"`
+	Attempts        int32      `json:"attempts"`
+	LockedAt        *time.Time `json:"locked_at"`
+	LastError       *string    `json:"last_error"`
+	ProviderEventAt *time.Time `json:"provider_event_at"`
 }
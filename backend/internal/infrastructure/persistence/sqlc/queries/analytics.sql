@@ -1,5 +1,10 @@
 -- name: UpsertAnalyticsAggregate :exec
-INSERT INTO analytics_aggregates (metric_name, metric_date, metric_value)
-VALUES ($1, $2, $3)
-ON CONFLICT (metric_name, metric_date) DO UPDATE
-    SET metric_value = EXCLUDED.metric_value, updated_at = now();
+INSERT INTO analytics_aggregates (app_id, metric_name, metric_date, metric_value, reporting_timezone)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (app_id, metric_name, metric_date, dimensions) DO UPDATE
+    SET metric_value = EXCLUDED.metric_value,
+        reporting_timezone = EXCLUDED.reporting_timezone,
+        updated_at = now();
+
+-- name: RebucketDailyRevenueAggregates :one
+SELECT rebucket_daily_revenue_aggregates($1)::int AS replaced;
@@ -13,6 +13,12 @@ SELECT * FROM subscriptions
 WHERE app_id = $1 AND user_id = $2 AND status = 'active' AND deleted_at IS NULL
 LIMIT 1;
 
+-- name: GetActiveSubscriptionByUserIDAndSource :one
+SELECT * FROM subscriptions
+WHERE app_id = $1 AND user_id = $2 AND source = $3 AND status = 'active' AND deleted_at IS NULL
+ORDER BY created_at DESC
+LIMIT 1;
+
 -- name: GetAccessCheck :one
 SELECT id, status, expires_at FROM subscriptions
 WHERE app_id = $1
@@ -1,6 +1,8 @@
 -- name: CreateTransaction :one
-INSERT INTO transactions (app_id, user_id, subscription_id, amount, currency, status, receipt_hash, provider_tx_id)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+INSERT INTO transactions (app_id, user_id, subscription_id, amount, currency, status, receipt_hash, provider_tx_id,
+                          store_fee, tax_amount, net_amount, country_code,
+                          provider, original_transaction_id, product_id, environment)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
 RETURNING *;
 
 -- name: GetTransactionByID :one
@@ -24,6 +26,38 @@ SELECT COALESCE(SUM(amount), 0) AS ltv
 FROM transactions
 WHERE app_id = $1 AND user_id = $2 AND status = 'success';
 
+-- name: GetNetLTVByUserID :one
+SELECT COALESCE(SUM(COALESCE(net_amount, amount)), 0) AS ltv
+FROM transactions
+WHERE app_id = $1 AND user_id = $2 AND status = 'success';
+
+-- name: ReconcileTransactionRevenue :execrows
+UPDATE transactions
+SET amount        = $3,
+    currency      = $4,
+    store_fee     = $5,
+    tax_amount    = $6,
+    net_amount    = $7,
+    country_code  = $8,
+    reconciled_at = now()
+WHERE app_id = $1 AND provider_tx_id = $2 AND status = 'success';
+
+-- name: MarkSubscriptionTransactionsWebhookSeen :exec
+-- Called once a provider webhook for the subscription has been processed, so
+-- its recent renewals are not reported as missing webhooks. Older entries
+-- belong to an earlier billing period whose own webhook never came.
+UPDATE transactions
+SET webhook_seen_at = now()
+WHERE subscription_id = $1 AND webhook_seen_at IS NULL
+  AND created_at >= now() - INTERVAL '3 days';
+
+-- name: MarkTransactionRefunded :execrows
+UPDATE transactions
+SET status           = 'refunded',
+    refunded_at      = $3,
+    refund_reference = $4
+WHERE app_id = $1 AND provider_tx_id = $2 AND status = 'success';
+
 -- name: GetTransactionsBySubscriptionID :many
 SELECT * FROM transactions
 WHERE subscription_id = $1
@@ -44,3 +78,9 @@ WHERE app_id = $1
   AND status = 'success'
   AND created_at >= $2
   AND created_at < $3;
+
+-- name: GetTransactionIDByProviderTxID :one
+SELECT id FROM transactions
+WHERE app_id = $1 AND provider_tx_id = $2
+ORDER BY created_at
+LIMIT 1;
@@ -1,17 +1,39 @@
 -- name: InsertWebhookEvent :exec
-INSERT INTO webhook_events (provider, event_type, event_id, payload)
-VALUES ($1, $2, $3, $4)
+INSERT INTO webhook_events (provider, event_type, event_id, payload, provider_event_at)
+VALUES ($1, $2, $3, $4, $5)
 ON CONFLICT (provider, event_id) DO NOTHING;
 
 -- name: GetUnprocessedWebhookEvents :many
 SELECT * FROM webhook_events
-WHERE processed_at IS NULL
+WHERE status IN ('received', 'failed')
 ORDER BY created_at ASC
 LIMIT 100;
 
+-- name: ClaimWebhookEvent :one
+-- Moves an event to 'processing'. Rows locked by a concurrent claimer are skipped,
+-- and events already processed (or claimed within the last 15 minutes) don't match,
+-- so at most one worker processes a given (provider, event_id) at a time. The
+-- claim commits before processing: a failed or abandoned attempt is claimed again,
+-- so delivery is at-least-once and handlers must be idempotent.
+UPDATE webhook_events
+SET status = 'processing', attempts = attempts + 1, locked_at = now()
+WHERE id = (
+    SELECT w.id FROM webhook_events w
+    WHERE w.provider = $1 AND w.event_id = $2
+      AND (w.status IN ('received', 'failed')
+           OR (w.status = 'processing' AND w.locked_at < now() - INTERVAL '15 minutes'))
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
 -- name: MarkWebhookEventProcessed :exec
 UPDATE webhook_events
-SET processed_at = now()
+SET status = 'processed', processed_at = now(), last_error = NULL
+WHERE id = $1;
+
+-- name: MarkWebhookEventFailed :exec
+UPDATE webhook_events
+SET status = 'failed', last_error = $2
 WHERE id = $1;
 
 -- name: GetWebhookEventByProviderAndID :one
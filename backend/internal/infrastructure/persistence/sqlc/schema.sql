@@ -35,7 +35,7 @@ CREATE TABLE subscriptions (
     app_id          UUID NOT NULL REFERENCES apps(id),
     user_id         UUID NOT NULL REFERENCES users(id),
     status          TEXT NOT NULL CHECK (status IN ('active', 'expired', 'cancelled', 'grace')),
-    source          TEXT NOT NULL CHECK (source IN ('iap', 'stripe', 'paddle')),
+    source          TEXT NOT NULL CHECK (source IN ('iap', 'stripe', 'paddle', 'paypal')),
     platform        TEXT NOT NULL CHECK (platform IN ('ios', 'android', 'web')),
     product_id      TEXT NOT NULL,
     plan_type       TEXT NOT NULL CHECK (plan_type IN ('monthly', 'annual', 'lifetime')),
@@ -56,17 +56,34 @@ CREATE TABLE transactions (
     status              TEXT NOT NULL CHECK (status IN ('success', 'failed', 'refunded')),
     receipt_hash        TEXT,
     provider_tx_id      TEXT,
-    created_at          TIMESTAMPTZ NOT NULL DEFAULT now()
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT now(),
+    store_fee           NUMERIC(10,2) NOT NULL DEFAULT 0,
+    tax_amount          NUMERIC(10,2) NOT NULL DEFAULT 0,
+    net_amount          NUMERIC(10,2),
+    country_code        TEXT,
+    reconciled_at       TIMESTAMPTZ,
+    provider                TEXT NOT NULL CHECK (provider IN ('apple', 'google', 'stripe', 'paddle', 'amazon', 'huawei', 'paypal')),
+    original_transaction_id TEXT,
+    product_id              TEXT,
+    environment             TEXT NOT NULL DEFAULT 'production' CHECK (environment IN ('production', 'sandbox')),
+    refunded_at             TIMESTAMPTZ,
+    refund_reference        TEXT,
+    webhook_seen_at         TIMESTAMPTZ
 );
 
 CREATE TABLE webhook_events (
     id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-    provider        TEXT NOT NULL CHECK (provider IN ('stripe', 'apple', 'google', 'paddle')),
+    provider        TEXT NOT NULL CHECK (provider IN ('stripe', 'apple', 'google', 'paddle', 'amazon', 'huawei', 'paypal')),
     event_type      TEXT NOT NULL,
     event_id        TEXT NOT NULL,
     payload         JSONB NOT NULL,
     processed_at    TIMESTAMPTZ,
-    created_at      TIMESTAMPTZ NOT NULL DEFAULT now()
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    status          TEXT NOT NULL DEFAULT 'received' CHECK (status IN ('received', 'processing', 'processed', 'failed')),
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    locked_at       TIMESTAMPTZ,
+    last_error      TEXT,
+    provider_event_at TIMESTAMPTZ
 );
 
 CREATE UNIQUE INDEX idx_webhook_events_unique
@@ -0,0 +1,172 @@
+// Package signing holds the asymmetric keys tokens verifiable by third
+// parties are signed with, and publishes their public halves as a JWKS.
+package signing
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrUnknownKey is returned when a token names a key the set does not hold
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// key is one named signing key
+type key struct {
+	ID      string
+	private crypto.Signer
+	method  jwt.SigningMethod
+}
+
+// KeySet signs with its first key and verifies with any of them. Rotating
+// means prepending a new key and dropping the old one once every token it
+// signed has expired.
+type KeySet struct {
+	keys []key
+}
+
+// minRSABits is the smallest RSA modulus accepted for signing
+const minRSABits = 2048
+
+// ParseKeySet reads a comma-separated list of kid=key pairs, where key is a
+// base64 PKCS#8 DER Ed25519 or RSA private key. The first pair signs.
+func ParseKeySet(spec string) (*KeySet, error) {
+	set := &KeySet{}
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		kid, encoded, ok := strings.Cut(pair, "=")
+		kid = strings.TrimSpace(kid)
+		if !ok || kid == "" {
+			return nil, fmt.Errorf("signing key %q: expected kid=base64 key", pair)
+		}
+		der, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("signing key %q: %w", kid, err)
+		}
+		parsed, err := x509.ParsePKCS8PrivateKey(der)
+		if err != nil {
+			return nil, fmt.Errorf("signing key %q: %w", kid, err)
+		}
+		if err := set.Add(kid, parsed); err != nil {
+			return nil, err
+		}
+	}
+	return set, nil
+}
+
+// Add appends a key; the first key added signs. Ed25519 keys sign EdDSA and
+// RSA keys RS256.
+func (s *KeySet) Add(kid string, private any) error {
+	for _, k := range s.keys {
+		if k.ID == kid {
+			return fmt.Errorf("signing key %q is listed twice", kid)
+		}
+	}
+	switch k := private.(type) {
+	case ed25519.PrivateKey:
+		s.keys = append(s.keys, key{ID: kid, private: k, method: jwt.SigningMethodEdDSA})
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < minRSABits {
+			return fmt.Errorf("signing key %q: RSA keys must be at least %d bits", kid, minRSABits)
+		}
+		s.keys = append(s.keys, key{ID: kid, private: k, method: jwt.SigningMethodRS256})
+	default:
+		return fmt.Errorf("signing key %q: unsupported key type %T", kid, private)
+	}
+	return nil
+}
+
+// Empty reports whether the set holds no key, so nothing can be signed
+func (s *KeySet) Empty() bool {
+	return s == nil || len(s.keys) == 0
+}
+
+// SigningKeyID is the kid new tokens carry
+func (s *KeySet) SigningKeyID() string {
+	if s.Empty() {
+		return ""
+	}
+	return s.keys[0].ID
+}
+
+// Sign signs claims with the current key, naming it in the kid header
+func (s *KeySet) Sign(claims jwt.Claims) (string, error) {
+	if s.Empty() {
+		return "", errors.New("no signing key configured")
+	}
+	signer := s.keys[0]
+	token := jwt.NewWithClaims(signer.method, claims)
+	token.Header["kid"] = signer.ID
+	return token.SignedString(signer.private)
+}
+
+// Keyfunc resolves the verification key of a token by its kid, for
+// jwt.ParseWithClaims
+func (s *KeySet) Keyfunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	if s != nil {
+		for _, k := range s.keys {
+			if k.ID != kid {
+				continue
+			}
+			if token.Method.Alg() != k.method.Alg() {
+				return nil, fmt.Errorf("key %q does not sign %s", kid, token.Method.Alg())
+			}
+			return k.private.Public(), nil
+		}
+	}
+	return nil, fmt.Errorf("%w %q", ErrUnknownKey, kid)
+}
+
+// JWK is the public half of a key, in RFC 7517 / RFC 8037 form
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+}
+
+// JWKS is a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys of every key in the set, signing key first
+func (s *KeySet) JWKS() JWKS {
+	out := JWKS{Keys: []JWK{}}
+	if s == nil {
+		return out
+	}
+	for _, k := range s.keys {
+		jwk := JWK{KeyID: k.ID, Algorithm: k.method.Alg(), Use: "sig"}
+		switch pub := k.private.Public().(type) {
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		default:
+			continue
+		}
+		out.Keys = append(out.Keys, jwk)
+	}
+	return out
+}
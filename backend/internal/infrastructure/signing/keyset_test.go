@@ -0,0 +1,98 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encodedKey(t *testing.T) (string, ed25519.PrivateKey) {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(der), priv
+}
+
+func TestKeySet_RotationKeepsOldTokensVerifiable(t *testing.T) {
+	oldKey, _ := encodedKey(t)
+	newKey, _ := encodedKey(t)
+	claims := jwt.RegisteredClaims{Subject: "user", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))}
+
+	before, err := ParseKeySet("2026-01=" + oldKey)
+	require.NoError(t, err)
+	oldToken, err := before.Sign(claims)
+	require.NoError(t, err)
+
+	after, err := ParseKeySet("2026-07=" + newKey + ", 2026-01=" + oldKey)
+	require.NoError(t, err)
+	assert.Equal(t, "2026-07", after.SigningKeyID())
+	newToken, err := after.Sign(claims)
+	require.NoError(t, err)
+
+	for _, raw := range []string{oldToken, newToken} {
+		_, err := jwt.ParseWithClaims(raw, &jwt.RegisteredClaims{}, after.Keyfunc)
+		assert.NoError(t, err)
+	}
+	_, err = jwt.ParseWithClaims(newToken, &jwt.RegisteredClaims{}, before.Keyfunc)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+
+	jwks := after.JWKS()
+	require.Len(t, jwks.Keys, 2)
+	assert.Equal(t, "2026-07", jwks.Keys[0].KeyID)
+	assert.Equal(t, "EdDSA", jwks.Keys[0].Algorithm)
+}
+
+func TestKeySet_RSA(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	set := &KeySet{}
+	require.NoError(t, set.Add("rsa-1", priv))
+
+	raw, err := set.Sign(jwt.RegisteredClaims{Subject: "user"})
+	require.NoError(t, err)
+	token, err := jwt.ParseWithClaims(raw, &jwt.RegisteredClaims{}, set.Keyfunc)
+	require.NoError(t, err)
+	assert.Equal(t, "RS256", token.Method.Alg())
+
+	jwk := set.JWKS().Keys[0]
+	assert.Equal(t, "RSA", jwk.KeyType)
+	assert.Equal(t, "AQAB", jwk.E)
+	assert.NotEmpty(t, jwk.N)
+
+	// A token naming the key under another algorithm is refused
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{Subject: "user"})
+	forged.Header["kid"] = "rsa-1"
+	forgedRaw, err := forged.SignedString([]byte("secret"))
+	require.NoError(t, err)
+	_, err = jwt.ParseWithClaims(forgedRaw, &jwt.RegisteredClaims{}, set.Keyfunc)
+	assert.Error(t, err)
+
+	weak, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	assert.Error(t, set.Add("weak", weak))
+}
+
+func TestParseKeySet_Rejects(t *testing.T) {
+	key, _ := encodedKey(t)
+	_, err := ParseKeySet("a=" + key + ",a=" + key)
+	assert.Error(t, err, "duplicate kid")
+	_, err = ParseKeySet("nokid")
+	assert.Error(t, err)
+	_, err = ParseKeySet("a=not-base64!")
+	assert.Error(t, err)
+
+	set, err := ParseKeySet("")
+	require.NoError(t, err)
+	assert.True(t, set.Empty())
+	assert.Empty(t, set.JWKS().Keys)
+}
@@ -0,0 +1,180 @@
+// Package webhookschema validates provider webhook payloads against the JSON
+// schemas embedded under schemas/. It implements the subset of JSON Schema the
+// provider schemas use: type, required, properties, enum and minLength.
+// Unknown properties are always allowed so new provider fields never cause
+// an event to be rejected.
+package webhookschema
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Schema names, one per embedded schemas/<name>.json
+const (
+	StripeEvent        = "stripe_event"
+	AppleNotification  = "apple_notification"
+	GooglePubSubPush   = "google_pubsub_push"
+	GoogleRTDN         = "google_rtdn"
+	AmazonSNSMessage   = "amazon_sns_message"
+	AmazonRTN          = "amazon_rtn"
+	HuaweiNotification = "huawei_notification"
+	PayPalEvent        = "paypal_event"
+)
+
+//go:embed schemas/*.json
+var schemaFiles embed.FS
+
+// ErrInvalidPayload is wrapped by every validation failure
+var ErrInvalidPayload = errors.New("payload does not match schema")
+
+// ValidationError lists every violation found in a payload
+type ValidationError struct {
+	Schema     string
+	Violations []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Schema, strings.Join(e.Violations, "; "))
+}
+
+func (e *ValidationError) Unwrap() error { return ErrInvalidPayload }
+
+type schema struct {
+	Type       string             `json:"type"`
+	Required   []string           `json:"required"`
+	Properties map[string]*schema `json:"properties"`
+	Enum       []any              `json:"enum"`
+	MinLength  *int               `json:"minLength"`
+}
+
+var schemas = mustLoadSchemas()
+
+func mustLoadSchemas() map[string]*schema {
+	entries, err := schemaFiles.ReadDir("schemas")
+	if err != nil {
+		panic(fmt.Sprintf("webhookschema: %v", err))
+	}
+	loaded := make(map[string]*schema, len(entries))
+	for _, entry := range entries {
+		raw, err := schemaFiles.ReadFile(path.Join("schemas", entry.Name()))
+		if err != nil {
+			panic(fmt.Sprintf("webhookschema: %v", err))
+		}
+		var s schema
+		if err := json.Unmarshal(raw, &s); err != nil {
+			panic(fmt.Sprintf("webhookschema: %s: %v", entry.Name(), err))
+		}
+		loaded[strings.TrimSuffix(entry.Name(), ".json")] = &s
+	}
+	return loaded
+}
+
+// Validate checks document against the named schema. Malformed JSON and
+// schema violations both return an error wrapping ErrInvalidPayload.
+func Validate(name string, document []byte) error {
+	s, ok := schemas[name]
+	if !ok {
+		return fmt.Errorf("unknown webhook schema %q", name)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(document))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return &ValidationError{Schema: name, Violations: []string{"invalid JSON: " + err.Error()}}
+	}
+
+	var violations []string
+	s.validate("$", value, &violations)
+	if len(violations) > 0 {
+		return &ValidationError{Schema: name, Violations: violations}
+	}
+	return nil
+}
+
+func (s *schema) validate(at string, value any, violations *[]string) {
+	if s.Type != "" && !hasType(value, s.Type) {
+		*violations = append(*violations, fmt.Sprintf("%s: expected %s, got %s", at, s.Type, typeName(value)))
+		return
+	}
+
+	if len(s.Enum) > 0 && !inEnum(value, s.Enum) {
+		*violations = append(*violations, fmt.Sprintf("%s: %v is not one of %v", at, value, s.Enum))
+	}
+	if str, ok := value.(string); ok && s.MinLength != nil && len(str) < *s.MinLength {
+		*violations = append(*violations, fmt.Sprintf("%s: shorter than %d characters", at, *s.MinLength))
+	}
+
+	object, ok := value.(map[string]any)
+	if !ok {
+		return
+	}
+	for _, field := range s.Required {
+		if _, present := object[field]; !present {
+			*violations = append(*violations, fmt.Sprintf("%s.%s: required", at, field))
+		}
+	}
+	// Sorted so violations are reported in a stable order
+	fields := make([]string, 0, len(s.Properties))
+	for field := range s.Properties {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		if fieldValue, present := object[field]; present {
+			s.Properties[field].validate(at+"."+field, fieldValue, violations)
+		}
+	}
+}
+
+func hasType(value any, want string) bool {
+	switch want {
+	case "integer":
+		n, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		_, err := n.Int64()
+		return err == nil
+	case "number":
+		_, ok := value.(json.Number)
+		return ok
+	default:
+		return typeName(value) == want
+	}
+}
+
+func typeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func inEnum(value any, enum []any) bool {
+	for _, allowed := range enum {
+		if fmt.Sprint(allowed) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+package webhookschema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate_AcceptsWellFormedPayloads(t *testing.T) {
+	cases := map[string]string{
+		StripeEvent:        `{"id":"evt_1","object":"event","type":"invoice.paid","created":1700000000,"data":{"object":{"id":"in_1"}},"api_version":"2024-06-20"}`,
+		AppleNotification:  `{"notificationType":"DID_RENEW","notificationUUID":"n-1","data":{"environment":"Production","signedTransactionInfo":"a.b.c"}}`,
+		GooglePubSubPush:   `{"message":{"data":"e30=","messageId":"m-1"},"subscription":"projects/p/subscriptions/s"}`,
+		GoogleRTDN:         `{"version":"1.0","packageName":"com.example","subscriptionNotification":{"notificationType":4,"purchaseToken":"tok"}}`,
+		AmazonSNSMessage:   `{"Type":"Notification","MessageId":"m-1","TopicArn":"arn:aws:sns:us-east-1:1:rtn","Message":"{}","Timestamp":"2024-01-01T00:00:00.000Z"}`,
+		AmazonRTN:          `{"appPackageName":"com.example","receiptId":"r-1","notificationType":"SUBSCRIPTION_MODIFIED","timestamp":1700000000000}`,
+		HuaweiNotification: `{"version":"v2","eventType":"ORDER","notifyTime":1700000000000,"orderNotification":{"notificationType":1,"purchaseToken":"tok"}}`,
+		PayPalEvent:        `{"id":"WH-1","event_type":"BILLING.SUBSCRIPTION.ACTIVATED","resource_type":"subscription","create_time":"2026-10-01T12:00:00Z","resource":{"id":"I-1"}}`,
+	}
+	for name, document := range cases {
+		assert.NoError(t, Validate(name, []byte(document)), name)
+	}
+}
+
+func TestValidate_ReportsEveryViolation(t *testing.T) {
+	err := Validate(StripeEvent, []byte(`{"id":"","type":42,"object":"charge"}`))
+	require.ErrorIs(t, err, ErrInvalidPayload)
+
+	var validationErr *ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.ElementsMatch(t, []string{
+		"$.data: required",
+		"$.id: shorter than 1 characters",
+		"$.object: charge is not one of [event]",
+		"$.type: expected string, got number",
+	}, validationErr.Violations)
+}
+
+func TestValidate_RejectsMalformedJSONAndWrongTypes(t *testing.T) {
+	assert.ErrorIs(t, Validate(GooglePubSubPush, []byte(`{"message":`)), ErrInvalidPayload)
+	assert.ErrorIs(t, Validate(GoogleRTDN, []byte(`{"packageName":"com.example","subscriptionNotification":{"notificationType":4.5,"purchaseToken":"tok"}}`)), ErrInvalidPayload)
+	assert.ErrorIs(t, Validate(AppleNotification, []byte(`[]`)), ErrInvalidPayload)
+	assert.Error(t, Validate("paypal", []byte(`{}`)))
+}
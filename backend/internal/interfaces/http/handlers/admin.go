@@ -1,9 +1,13 @@
 package handlers
 
 import (
+	"context"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -11,6 +15,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
@@ -40,9 +45,14 @@ type AdminHandler struct {
 	userProfileService          *service.UserProfileService
 	winbackService              *service.WinbackService
 	experimentAdminService      *service.ExperimentAdminService
+	experimentTemplateService   *service.ExperimentTemplateService
+	experimentMetaService       *service.ExperimentMetaAnalyticsService
+	experimentTimelineService   *service.ExperimentTimelineService
+	experimentAppVersionService *service.ExperimentAppVersionService
 	experimentRepairService     *service.ExperimentRepairService
 	winnerRecommendationService *service.ExperimentWinnerRecommendationService
 	asynqClient                 *asynq.Client
+	currencyConverter           *service.ReportingCurrencyConverter
 }
 
 // NewAdminHandler creates a new admin handler
@@ -61,12 +71,20 @@ func NewAdminHandler(
 	asynqClient *asynq.Client,
 ) *AdminHandler {
 	var experimentAdminService *service.ExperimentAdminService
+	var experimentTemplateService *service.ExperimentTemplateService
+	var experimentMetaService *service.ExperimentMetaAnalyticsService
+	var experimentTimelineService *service.ExperimentTimelineService
+	var experimentAppVersionService *service.ExperimentAppVersionService
 	var experimentRepairService *service.ExperimentRepairService
 	var winnerRecommendationService *service.ExperimentWinnerRecommendationService
 	if dbPool != nil {
 		experimentRepo := persistenceRepo.NewExperimentAdminRepository(dbPool)
 		banditRepo := persistenceRepo.NewPostgresBanditRepository(dbPool, zap.NewNop())
-		experimentAdminService = service.NewExperimentAdminService(experimentRepo)
+		experimentAdminService = service.NewExperimentAdminService(experimentRepo).WithArchive(experimentRepo)
+		experimentTemplateService = service.NewExperimentTemplateService(experimentRepo)
+		experimentMetaService = service.NewExperimentMetaAnalyticsService(experimentRepo, zap.NewNop())
+		experimentTimelineService = service.NewExperimentTimelineService(experimentRepo, zap.NewNop())
+		experimentAppVersionService = service.NewExperimentAppVersionService(experimentRepo)
 		experimentRepairService = service.NewExperimentRepairService(
 			experimentRepo,
 			banditRepo,
@@ -87,12 +105,44 @@ func NewAdminHandler(
 		userProfileService:          userProfileService,
 		winbackService:              winbackService,
 		experimentAdminService:      experimentAdminService,
+		experimentTemplateService:   experimentTemplateService,
+		experimentMetaService:       experimentMetaService,
+		experimentTimelineService:   experimentTimelineService,
+		experimentAppVersionService: experimentAppVersionService,
 		experimentRepairService:     experimentRepairService,
 		winnerRecommendationService: winnerRecommendationService,
 		asynqClient:                 asynqClient,
+		currencyConverter:           service.NewReportingCurrencyConverter(service.DefaultReportingCurrency, nil, nil),
 	}
 }
 
+// WithReportingCurrency sets the converter used for monetary totals in admin responses
+func (h *AdminHandler) WithReportingCurrency(converter *service.ReportingCurrencyConverter) *AdminHandler {
+	if converter != nil {
+		h.currencyConverter = converter
+	}
+	return h
+}
+
+// WithKillSwitch makes experiment pauses and resumes take effect on every
+// API instance within seconds
+func (h *AdminHandler) WithKillSwitch(ks service.ExperimentSwitchboard) *AdminHandler {
+	if h.experimentAdminService != nil {
+		h.experimentAdminService.WithKillSwitch(ks)
+	}
+	return h
+}
+
+// reportingCurrency resolves the ?currency= override, writing a 400 when it is invalid
+func (h *AdminHandler) reportingCurrency(c *gin.Context) (string, bool) {
+	currency, err := h.currencyConverter.ResolveCurrency(c.Query("currency"))
+	if err != nil {
+		response.BadRequest(c, "currency must be a 3-letter ISO 4217 code")
+		return "", false
+	}
+	return currency, true
+}
+
 // GrantSubscription manually grants a subscription to a user
 // @Summary Grant subscription to user
 // @Tags admin
@@ -289,6 +339,7 @@ func (h *AdminHandler) GetHealth(c *gin.Context) {
 // @Tags admin
 // @Produce json
 // @Security Bearer
+// @Param currency query string false "Reporting currency override (ISO 4217)"
 // @Success 200 {object} response.SuccessResponse{data=object}
 // @Router /admin/dashboard/metrics [get]
 func (h *AdminHandler) GetDashboardMetrics(c *gin.Context) {
@@ -296,6 +347,11 @@ func (h *AdminHandler) GetDashboardMetrics(c *gin.Context) {
 	now := time.Now()
 	monthAgo := now.AddDate(0, -1, 0)
 
+	currency, ok := h.reportingCurrency(c)
+	if !ok {
+		return
+	}
+
 	// Active user count
 	activeUsers, err := h.queries.CountUsers(ctx, appctx.MustAppIDFromCtx(ctx))
 	if err != nil {
@@ -311,7 +367,7 @@ func (h *AdminHandler) GetDashboardMetrics(c *gin.Context) {
 	}
 
 	// Revenue metrics (MRR / ARR)
-	revenue, err := h.analyticsService.CalculateRevenueMetrics(ctx, monthAgo, now)
+	revenue, err := h.analyticsService.CalculateRevenueMetricsIn(ctx, monthAgo, now, currency)
 	if err != nil {
 		response.InternalError(c, "Failed to calculate revenue")
 		return
@@ -325,7 +381,7 @@ func (h *AdminHandler) GetDashboardMetrics(c *gin.Context) {
 	}
 
 	// MRR trend — last 6 months
-	mrrTrend, err := h.analyticsService.GetMRRTrend(ctx, 6)
+	mrrTrend, err := h.analyticsService.GetMRRTrendIn(ctx, 6, currency)
 	if err != nil {
 		response.InternalError(c, "Failed to calculate MRR trend")
 		return
@@ -355,14 +411,20 @@ func (h *AdminHandler) GetDashboardMetrics(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"active_users":   activeUsers,
 		"active_subs":    activeSubs,
+		"currency":       revenue.Currency,
 		"mrr":            revenue.MRR,
 		"arr":            revenue.ARR,
+		"daily_revenue":  revenue.DailyRevenue,
 		"churn_risk":     churnRisk,
 		"mrr_trend":      mrrTrend,
 		"status_counts":  statusCounts,
 		"audit_log":      auditLog,
 		"webhook_health": webhookHealth,
 		"last_updated":   now,
+
+		"mrr_by_currency":           revenue.MRRByCurrency,
+		"daily_revenue_by_currency": revenue.DailyByCurrency,
+		"unconverted_currencies":    revenue.UnconvertedCurrencies,
 	})
 }
 
@@ -410,7 +472,7 @@ func (h *AdminHandler) GetAuditLog(c *gin.Context) {
 }
 
 // SearchUsers returns a filtered, paginated list of users.
-// Query: page, limit, search (email/platform_user_id), platform (ios/android/web), role
+// Query: page, limit, search (email/platform_user_id), platform (ios/android/web), role, lifecycle_stage
 func (h *AdminHandler) SearchUsers(c *gin.Context) {
 	ctx := c.Request.Context()
 
@@ -427,6 +489,11 @@ func (h *AdminHandler) SearchUsers(c *gin.Context) {
 	search := c.Query("search")
 	platform := c.Query("platform")
 	role := c.Query("role")
+	lifecycleStage := c.Query("lifecycle_stage")
+	if lifecycleStage != "" && !entity.LifecycleStage(lifecycleStage).IsValid() {
+		response.BadRequest(c, "Invalid lifecycle_stage")
+		return
+	}
 
 	// app_id is always the first bound parameter — mandatory scope filter.
 	appID := appctx.MustAppIDFromCtx(ctx)
@@ -449,11 +516,16 @@ func (h *AdminHandler) SearchUsers(c *gin.Context) {
 		where = append(where, fmt.Sprintf("u.role = $%d", idx))
 		idx++
 	}
+	if lifecycleStage != "" {
+		args = append(args, lifecycleStage)
+		where = append(where, fmt.Sprintf("ls.stage = $%d", idx))
+		idx++
+	}
 
 	whereSQL := "WHERE " + strings.Join(where, " AND ")
 
 	var total int64
-	countQ := fmt.Sprintf(`SELECT COUNT(*) FROM users u %s`, whereSQL)
+	countQ := fmt.Sprintf(`SELECT COUNT(*) FROM users u LEFT JOIN user_lifecycle_stages ls ON ls.user_id = u.id %s`, whereSQL)
 	if err := h.dbPool.QueryRow(ctx, countQ, args...).Scan(&total); err != nil {
 		response.InternalError(c, "Failed to count users")
 		return
@@ -465,8 +537,10 @@ SELECT
 u.id, u.platform_user_id, u.platform, u.email, u.role,
 u.ltv, u.app_version, u.created_at,
 COALESCE(s.status, 'none') AS sub_status,
-COALESCE(s.expires_at::text, '') AS sub_expires_at
+COALESCE(s.expires_at::text, '') AS sub_expires_at,
+COALESCE(ls.stage, '') AS lifecycle_stage
 FROM users u
+LEFT JOIN user_lifecycle_stages ls ON ls.user_id = u.id
 LEFT JOIN LATERAL (
 SELECT status, expires_at FROM subscriptions
 WHERE user_id = u.id
@@ -496,6 +570,7 @@ LIMIT $%d OFFSET $%d
 		CreatedAt      string  `json:"created_at"`
 		SubStatus      string  `json:"sub_status"`
 		SubExpiresAt   string  `json:"sub_expires_at"`
+		LifecycleStage string  `json:"lifecycle_stage"`
 	}
 
 	var uid uuid.UUID
@@ -504,7 +579,7 @@ LIMIT $%d OFFSET $%d
 		var r UserRow
 		var createdAt time.Time
 		if err := rows.Scan(&uid, &r.PlatformUserID, &r.Platform, &r.Email, &r.Role,
-			&r.LTV, &r.AppVersion, &createdAt, &r.SubStatus, &r.SubExpiresAt); err != nil {
+			&r.LTV, &r.AppVersion, &createdAt, &r.SubStatus, &r.SubExpiresAt, &r.LifecycleStage); err != nil {
 			response.InternalError(c, "Failed to scan user")
 			return
 		}
@@ -692,11 +767,22 @@ RETURNING id`,
 // Churn rate = churned this month / (active + churned) × 100
 // LTV = total successful revenue / distinct users with transactions
 // New subs = count of subscriptions created this month
+// Resurrected MRR = list-price MRR of subscriptions reactivated this month
+// All amounts are in the reporting currency, or ?currency= when given.
 func (h *AdminHandler) GetAnalyticsReport(c *gin.Context) {
 	ctx := c.Request.Context()
 	appID := appctx.MustAppIDFromCtx(ctx)
 
-	report, err := h.analyticsReportService.GetReport(ctx, appID)
+	currency, ok := h.reportingCurrency(c)
+	if !ok {
+		return
+	}
+
+	report, err := h.analyticsReportService.GetReport(ctx, appID, currency)
+	if errors.Is(err, service.ErrCurrencyRateNotFound) {
+		response.BadRequest(c, "No exchange rate available for "+currency)
+		return
+	}
 	if err != nil {
 		response.InternalError(c, err.Error())
 		return
@@ -767,9 +853,11 @@ func (h *AdminHandler) ListWebhooks(c *gin.Context) {
 		idx++
 	}
 	if status == "pending" {
-		where = append(where, "processed_at IS NULL")
+		where = append(where, "status IN ('received', 'processing')")
 	} else if status == "processed" {
-		where = append(where, "processed_at IS NOT NULL")
+		where = append(where, "status = 'processed'")
+	} else if status == "failed" {
+		where = append(where, "status = 'failed'")
 	}
 	if search != "" {
 		args = append(args, "%"+search+"%")
@@ -796,22 +884,24 @@ func (h *AdminHandler) ListWebhooks(c *gin.Context) {
 		Total     int64 `json:"total"`
 		Pending   int64 `json:"pending"`
 		Processed int64 `json:"processed"`
+		Failed    int64 `json:"failed"`
 	}
 	var summary Summary
 	sumQ := fmt.Sprintf(`
 		SELECT
 		  COUNT(*),
-		  COUNT(*) FILTER (WHERE processed_at IS NULL),
-		  COUNT(*) FILTER (WHERE processed_at IS NOT NULL)
+		  COUNT(*) FILTER (WHERE status IN ('received', 'processing')),
+		  COUNT(*) FILTER (WHERE status = 'processed'),
+		  COUNT(*) FILTER (WHERE status = 'failed')
 		FROM webhook_events %s`, whereSQL)
-	if err := h.dbPool.QueryRow(ctx, sumQ, args...).Scan(&summary.Total, &summary.Pending, &summary.Processed); err != nil {
+	if err := h.dbPool.QueryRow(ctx, sumQ, args...).Scan(&summary.Total, &summary.Pending, &summary.Processed, &summary.Failed); err != nil {
 		response.InternalError(c, "Failed to get webhook summary")
 		return
 	}
 
 	dataArgs := append(args, limit, offset)
 	dataQ := fmt.Sprintf(`
-		SELECT id, provider, event_type, COALESCE(event_id,''), processed_at, created_at
+		SELECT id, provider, event_type, COALESCE(event_id,''), status, attempts, last_error, processed_at, created_at
 		FROM webhook_events
 		%s
 		ORDER BY created_at DESC
@@ -829,6 +919,9 @@ func (h *AdminHandler) ListWebhooks(c *gin.Context) {
 		Provider    string  `json:"provider"`
 		EventType   string  `json:"event_type"`
 		EventID     string  `json:"event_id"`
+		Status      string  `json:"status"`
+		Attempts    int     `json:"attempts"`
+		LastError   *string `json:"last_error"`
 		Processed   bool    `json:"processed"`
 		ProcessedAt *string `json:"processed_at"`
 		CreatedAt   string  `json:"created_at"`
@@ -840,7 +933,7 @@ func (h *AdminHandler) ListWebhooks(c *gin.Context) {
 		var id uuid.UUID
 		var processedAt *time.Time
 		var createdAt time.Time
-		if scanErr := rows.Scan(&id, &r.Provider, &r.EventType, &r.EventID, &processedAt, &createdAt); scanErr != nil {
+		if scanErr := rows.Scan(&id, &r.Provider, &r.EventType, &r.EventID, &r.Status, &r.Attempts, &r.LastError, &processedAt, &createdAt); scanErr != nil {
 			continue
 		}
 		r.ID = id.String()
@@ -864,7 +957,7 @@ func (h *AdminHandler) ListWebhooks(c *gin.Context) {
 	})
 }
 
-// ReplayWebhook re-enqueues an unprocessed webhook event for processing.
+// ReplayWebhook resets a webhook event to 'received' and re-enqueues it for processing.
 // POST /v1/admin/webhooks/:id/replay
 func (h *AdminHandler) ReplayWebhook(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -874,15 +967,26 @@ func (h *AdminHandler) ReplayWebhook(c *gin.Context) {
 		return
 	}
 
-	var provider, eventType, eventID string
-	var processedAt *time.Time
+	var provider, eventType, eventID, status string
 	err = h.dbPool.QueryRow(ctx,
-		`SELECT provider, event_type, event_id, processed_at FROM webhook_events WHERE id = $1`, id,
-	).Scan(&provider, &eventType, &eventID, &processedAt)
+		`SELECT provider, event_type, event_id, status FROM webhook_events WHERE id = $1`, id,
+	).Scan(&provider, &eventType, &eventID, &status)
 	if err != nil {
 		response.NotFound(c, "Webhook event not found")
 		return
 	}
+	if status == "processing" {
+		response.Conflict(c, "Webhook event is currently being processed")
+		return
+	}
+
+	// The worker only claims events in 'received'/'failed', so reset the state first.
+	if _, err := h.dbPool.Exec(ctx,
+		`UPDATE webhook_events SET status = 'received', processed_at = NULL, locked_at = NULL WHERE id = $1 AND status <> 'processing'`, id,
+	); err != nil {
+		response.InternalError(c, "Failed to reset webhook event")
+		return
+	}
 
 	payload, _ := json.Marshal(map[string]string{
 		"provider":   provider,
@@ -1226,7 +1330,7 @@ func (h *AdminHandler) GetTransactionDetail(c *gin.Context) {
 	c.JSON(200, d)
 }
 
-// GET /admin/transactions?page=1&limit=20&status=success&source=iap&platform=ios&search=email&date_from=2024-01-01&date_to=2024-12-31
+// GET /admin/transactions?page=1&limit=20&status=success&source=iap&platform=ios&provider=apple&environment=production&product_id=...&original_transaction_id=...&search=email&date_from=2024-01-01&date_to=2024-12-31
 func (h *AdminHandler) ListTransactions(c *gin.Context) {
 	ctx := c.Request.Context()
 
@@ -1240,56 +1344,13 @@ func (h *AdminHandler) ListTransactions(c *gin.Context) {
 	}
 	offset := (page - 1) * limit
 
-	status := c.Query("status")
-	source := c.Query("source")
-	platform := c.Query("platform")
-	search := c.Query("search")
-	dateFrom := c.Query("date_from")
-	dateTo := c.Query("date_to")
-
-	appID := httpmiddleware.GetAppID(c)
-	args := []interface{}{appID}
-	where := []string{"t.app_id = $1"}
-	idx := 2
-
-	if status != "" {
-		args = append(args, status)
-		where = append(where, fmt.Sprintf("t.status = $%d", idx))
-		idx++
-	}
-	if source != "" {
-		args = append(args, source)
-		where = append(where, fmt.Sprintf("s.source = $%d", idx))
-		idx++
-	}
-	if platform != "" {
-		args = append(args, platform)
-		where = append(where, fmt.Sprintf("s.platform = $%d", idx))
-		idx++
-	}
-	if search != "" {
-		args = append(args, "%"+search+"%")
-		where = append(where, fmt.Sprintf("u.email ILIKE $%d", idx))
-		idx++
-	}
-	if dateFrom != "" {
-		args = append(args, dateFrom)
-		where = append(where, fmt.Sprintf("t.created_at >= $%d::date", idx))
-		idx++
-	}
-	if dateTo != "" {
-		args = append(args, dateTo)
-		where = append(where, fmt.Sprintf("t.created_at < ($%d::date + INTERVAL '1 day')", idx))
-		idx++
+	currency, ok := h.reportingCurrency(c)
+	if !ok {
+		return
 	}
 
-	whereSQL := "WHERE " + strings.Join(where, " AND ")
-
-	baseQ := fmt.Sprintf(`
-FROM transactions t
-JOIN users u ON u.id = t.user_id
-JOIN subscriptions s ON s.id = t.subscription_id
-%s`, whereSQL)
+	baseQ, args := transactionFilter(c)
+	idx := len(args) + 1
 
 	// totals for reconciliation summary
 	type Summary struct {
@@ -1299,6 +1360,11 @@ JOIN subscriptions s ON s.id = t.subscription_id
 		RefundedCount int64   `json:"refunded_count"`
 		TotalRevenue  float64 `json:"total_revenue"`
 		TotalRefunded float64 `json:"total_refunded"`
+		Currency      string  `json:"currency"`
+
+		RevenueByCurrency     []service.CurrencyBreakout `json:"revenue_by_currency"`
+		RefundedByCurrency    []service.CurrencyBreakout `json:"refunded_by_currency"`
+		UnconvertedCurrencies []string                   `json:"unconverted_currencies,omitempty"`
 	}
 	var summary Summary
 	sumQ := fmt.Sprintf(`
@@ -1317,20 +1383,26 @@ COALESCE(SUM(t.amount) FILTER (WHERE t.status = 'refunded'), 0)
 		response.InternalError(c, "Failed to get transaction summary")
 		return
 	}
+	revenue, refunded, err := h.convertTransactionSummary(ctx, baseQ, args, currency)
+	if err != nil {
+		response.InternalError(c, "Failed to convert transaction summary")
+		return
+	}
+	summary.Currency = currency
+	summary.TotalRevenue, summary.RevenueByCurrency = revenue.Amount, revenue.ByCurrency
+	summary.TotalRefunded, summary.RefundedByCurrency = refunded.Amount, refunded.ByCurrency
+	for _, code := range append(revenue.Unconverted, refunded.Unconverted...) {
+		if !slices.Contains(summary.UnconvertedCurrencies, code) {
+			summary.UnconvertedCurrencies = append(summary.UnconvertedCurrencies, code)
+		}
+	}
 
 	args = append(args, limit, offset)
 	dataQ := fmt.Sprintf(`
-SELECT
-t.id, t.amount, t.currency, t.status,
-COALESCE(t.provider_tx_id, '') AS provider_tx_id,
-COALESCE(t.receipt_hash, '') AS receipt_hash,
-t.created_at,
-u.id AS user_id, COALESCE(u.email, '') AS email,
-s.source, s.platform, s.plan_type,
-s.id AS subscription_id
+%s
 %s
 ORDER BY t.created_at DESC
-LIMIT $%d OFFSET $%d`, baseQ, idx, idx+1)
+LIMIT $%d OFFSET $%d`, transactionColumns, baseQ, idx, idx+1)
 
 	rows, err := h.dbPool.Query(ctx, dataQ, args...)
 	if err != nil {
@@ -1339,40 +1411,13 @@ LIMIT $%d OFFSET $%d`, baseQ, idx, idx+1)
 	}
 	defer rows.Close()
 
-	type TxRow struct {
-		ID             string  `json:"id"`
-		Amount         float64 `json:"amount"`
-		Currency       string  `json:"currency"`
-		Status         string  `json:"status"`
-		ProviderTxID   string  `json:"provider_tx_id"`
-		ReceiptHash    string  `json:"receipt_hash"`
-		CreatedAt      string  `json:"created_at"`
-		UserID         string  `json:"user_id"`
-		Email          string  `json:"email"`
-		Source         string  `json:"source"`
-		Platform       string  `json:"platform"`
-		PlanType       string  `json:"plan_type"`
-		SubscriptionID string  `json:"subscription_id"`
-	}
-
-	result := make([]TxRow, 0, limit)
+	result := make([]transactionRow, 0, limit)
 	for rows.Next() {
-		var r TxRow
-		var txID, userID, subScanID uuid.UUID
-		var createdAt time.Time
-		if err := rows.Scan(
-			&txID, &r.Amount, &r.Currency, &r.Status,
-			&r.ProviderTxID, &r.ReceiptHash, &createdAt,
-			&userID, &r.Email,
-			&r.Source, &r.Platform, &r.PlanType, &subScanID,
-		); err != nil {
+		r, err := scanTransactionRow(rows)
+		if err != nil {
 			response.InternalError(c, "Failed to scan transaction row")
 			return
 		}
-		r.ID = txID.String()
-		r.UserID = userID.String()
-		r.SubscriptionID = subScanID.String()
-		r.CreatedAt = createdAt.Format(time.RFC3339)
 		result = append(result, r)
 	}
 
@@ -1387,6 +1432,202 @@ LIMIT $%d OFFSET $%d`, baseQ, idx, idx+1)
 	})
 }
 
+// transactionExportLimit caps the rows a single CSV export returns
+const transactionExportLimit = 50000
+
+// ExportTransactions streams the ledger rows matching the ListTransactions
+// filters as CSV, newest first.
+// GET /admin/transactions/export?provider=stripe&date_from=2024-01-01
+func (h *AdminHandler) ExportTransactions(c *gin.Context) {
+	baseQ, args := transactionFilter(c)
+	args = append(args, transactionExportLimit)
+	rows, err := h.dbPool.Query(c.Request.Context(), fmt.Sprintf(`
+%s
+%s
+ORDER BY t.created_at DESC
+LIMIT $%d`, transactionColumns, baseQ, len(args)), args...)
+	if err != nil {
+		response.InternalError(c, "Failed to export transactions")
+		return
+	}
+	defer rows.Close()
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="transactions-%s.csv"`, time.Now().UTC().Format("20060102")))
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write([]string{
+		"id", "created_at", "provider", "environment", "status", "amount", "currency",
+		"product_id", "provider_tx_id", "original_transaction_id", "refunded_at", "refund_reference",
+		"user_id", "email", "subscription_id", "source", "platform", "plan_type",
+	})
+	for rows.Next() {
+		r, err := scanTransactionRow(rows)
+		if err != nil {
+			// Headers are already sent; the client sees a truncated file
+			_ = c.Error(err)
+			break
+		}
+		_ = w.Write([]string{
+			r.ID, r.CreatedAt, r.Provider, r.Environment, r.Status,
+			strconv.FormatFloat(r.Amount, 'f', 2, 64), r.Currency,
+			r.ProductID, r.ProviderTxID, r.OriginalTransactionID, r.RefundedAt, r.RefundReference,
+			r.UserID, r.Email, r.SubscriptionID, r.Source, r.Platform, r.PlanType,
+		})
+	}
+	w.Flush()
+}
+
+// transactionFilter builds the FROM/WHERE clause shared by the ledger list,
+// summary and export from the request's query parameters. The returned args
+// start with the app ID.
+func transactionFilter(c *gin.Context) (string, []interface{}) {
+	args := []interface{}{httpmiddleware.GetAppID(c)}
+	where := []string{"t.app_id = $1"}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+
+	if v := c.Query("status"); v != "" {
+		add("t.status = $%d", v)
+	}
+	if v := c.Query("source"); v != "" {
+		add("s.source = $%d", v)
+	}
+	if v := c.Query("platform"); v != "" {
+		add("s.platform = $%d", v)
+	}
+	if v := c.Query("provider"); v != "" {
+		add("t.provider = $%d", strings.ToLower(v))
+	}
+	if v := c.Query("environment"); v != "" {
+		add("t.environment = $%d", strings.ToLower(v))
+	}
+	if v := c.Query("product_id"); v != "" {
+		add("t.product_id = $%d", v)
+	}
+	if v := c.Query("original_transaction_id"); v != "" {
+		add("t.original_transaction_id = $%d", v)
+	}
+	if v := c.Query("search"); v != "" {
+		// email, or an exact store / invoice id
+		args = append(args, "%"+v+"%", v)
+		n := len(args)
+		where = append(where, fmt.Sprintf("(u.email ILIKE $%d OR t.provider_tx_id = $%d OR t.original_transaction_id = $%d)", n-1, n, n))
+	}
+	if v := c.Query("date_from"); v != "" {
+		add("t.created_at >= $%d::date", v)
+	}
+	if v := c.Query("date_to"); v != "" {
+		add("t.created_at < ($%d::date + INTERVAL '1 day')", v)
+	}
+
+	return fmt.Sprintf(`
+FROM transactions t
+JOIN users u ON u.id = t.user_id
+JOIN subscriptions s ON s.id = t.subscription_id
+WHERE %s`, strings.Join(where, " AND ")), args
+}
+
+// transactionColumns is the select list scanTransactionRow reads
+const transactionColumns = `SELECT
+t.id, t.amount, t.currency, t.status,
+COALESCE(t.provider_tx_id, '') AS provider_tx_id,
+COALESCE(t.receipt_hash, '') AS receipt_hash,
+t.created_at,
+u.id AS user_id, COALESCE(u.email, '') AS email,
+s.source, s.platform, s.plan_type,
+s.id AS subscription_id,
+t.provider, t.environment,
+COALESCE(t.product_id, '') AS product_id,
+COALESCE(t.original_transaction_id, '') AS original_transaction_id,
+t.refunded_at,
+COALESCE(t.refund_reference, '') AS refund_reference`
+
+// transactionRow is a ledger entry as listed and exported by the admin API
+type transactionRow struct {
+	ID                    string  `json:"id"`
+	Amount                float64 `json:"amount"`
+	Currency              string  `json:"currency"`
+	Status                string  `json:"status"`
+	ProviderTxID          string  `json:"provider_tx_id"`
+	ReceiptHash           string  `json:"receipt_hash"`
+	CreatedAt             string  `json:"created_at"`
+	UserID                string  `json:"user_id"`
+	Email                 string  `json:"email"`
+	Source                string  `json:"source"`
+	Platform              string  `json:"platform"`
+	PlanType              string  `json:"plan_type"`
+	SubscriptionID        string  `json:"subscription_id"`
+	Provider              string  `json:"provider"`
+	Environment           string  `json:"environment"`
+	ProductID             string  `json:"product_id"`
+	OriginalTransactionID string  `json:"original_transaction_id"`
+	RefundedAt            string  `json:"refunded_at,omitempty"`
+	RefundReference       string  `json:"refund_reference,omitempty"`
+}
+
+func scanTransactionRow(rows pgx.Rows) (transactionRow, error) {
+	var r transactionRow
+	var txID, userID, subID uuid.UUID
+	var createdAt time.Time
+	var refundedAt *time.Time
+	if err := rows.Scan(
+		&txID, &r.Amount, &r.Currency, &r.Status,
+		&r.ProviderTxID, &r.ReceiptHash, &createdAt,
+		&userID, &r.Email,
+		&r.Source, &r.Platform, &r.PlanType, &subID,
+		&r.Provider, &r.Environment, &r.ProductID, &r.OriginalTransactionID,
+		&refundedAt, &r.RefundReference,
+	); err != nil {
+		return r, err
+	}
+	r.ID = txID.String()
+	r.UserID = userID.String()
+	r.SubscriptionID = subID.String()
+	r.CreatedAt = createdAt.Format(time.RFC3339)
+	if refundedAt != nil {
+		r.RefundedAt = refundedAt.Format(time.RFC3339)
+	}
+	return r, nil
+}
+
+// convertTransactionSummary converts the success and refunded sums (which
+// the summary query adds up across currencies) into currency at each day's
+// rate, with per-currency breakouts.
+func (h *AdminHandler) convertTransactionSummary(ctx context.Context, baseQ string, args []interface{}, currency string) (revenue, refunded *service.ConvertedTotal, err error) {
+	rows, err := h.dbPool.Query(ctx, fmt.Sprintf(`
+SELECT t.status, t.currency, date_trunc('day', t.created_at AT TIME ZONE 'UTC'),
+COALESCE(SUM(t.amount), 0)::float8, COUNT(*)
+%s AND t.status IN ('success', 'refunded')
+GROUP BY 1, 2, 3`, baseQ), args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	byStatus := map[string][]service.CurrencyAmount{}
+	for rows.Next() {
+		var status string
+		var a service.CurrencyAmount
+		if err := rows.Scan(&status, &a.Currency, &a.Day, &a.Amount, &a.Count); err != nil {
+			return nil, nil, err
+		}
+		byStatus[status] = append(byStatus[status], a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	if revenue, err = h.currencyConverter.Aggregate(ctx, currency, byStatus["success"]); err != nil {
+		return nil, nil, err
+	}
+	if refunded, err = h.currencyConverter.Aggregate(ctx, currency, byStatus["refunded"]); err != nil {
+		return nil, nil, err
+	}
+	return revenue, refunded, nil
+}
+
 // GetUserProfile returns a full 360° user profile: identity, subscriptions, transactions, audit log, dunning.
 func (h *AdminHandler) GetUserProfile(c *gin.Context) {
 	ctx := c.Request.Context()
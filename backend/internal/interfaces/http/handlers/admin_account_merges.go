@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+type accountMerger interface {
+	Merge(ctx context.Context, req service.AccountMergeRequest) (*entity.AccountMerge, error)
+	Undo(ctx context.Context, appID, mergeID uuid.UUID) (*entity.AccountMerge, error)
+	Get(ctx context.Context, appID, mergeID uuid.UUID) (*entity.AccountMerge, error)
+	ListForUser(ctx context.Context, userID uuid.UUID) ([]*entity.AccountMerge, error)
+}
+
+// AdminAccountMergeHandler lets support merge duplicate accounts of one
+// person and undo a merge within its undo window
+type AdminAccountMergeHandler struct {
+	merger accountMerger
+	audit  adminAuditLogger
+	logger *zap.Logger
+}
+
+func NewAdminAccountMergeHandler(merger accountMerger, audit adminAuditLogger, logger *zap.Logger) *AdminAccountMergeHandler {
+	return &AdminAccountMergeHandler{merger: merger, audit: audit, logger: logger}
+}
+
+// AccountMergeRequest folds source_user_id into target_user_id. With dry_run
+// the response shows what would move and conflict, and nothing changes.
+type AccountMergeRequest struct {
+	SourceUserID   uuid.UUID `json:"source_user_id" binding:"required"`
+	TargetUserID   uuid.UUID `json:"target_user_id" binding:"required"`
+	ConflictPolicy string    `json:"conflict_policy" binding:"omitempty,oneof=keep_target keep_source"`
+	Reason         string    `json:"reason" binding:"required,max=500"`
+	DryRun         bool      `json:"dry_run"`
+}
+
+// UndoAccountMergeRequest gives the reason a merge is reverted
+type UndoAccountMergeRequest struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+// AccountMergeResponse is a merge as returned to the admin
+type AccountMergeResponse struct {
+	ID             uuid.UUID                     `json:"id"`
+	SourceUserID   uuid.UUID                     `json:"source_user_id"`
+	TargetUserID   uuid.UUID                     `json:"target_user_id"`
+	ConflictPolicy string                        `json:"conflict_policy"`
+	Status         string                        `json:"status"`
+	Reason         string                        `json:"reason"`
+	MergedBy       *uuid.UUID                    `json:"merged_by,omitempty"`
+	SourceLTV      float64                       `json:"source_ltv"`
+	Moved          map[string]int64              `json:"moved"`
+	Conflicts      []entity.AccountMergeConflict `json:"conflicts"`
+	DryRun         bool                          `json:"dry_run,omitempty"`
+	CreatedAt      time.Time                     `json:"created_at"`
+	UndoUntil      time.Time                     `json:"undo_until"`
+	UndoneAt       *time.Time                    `json:"undone_at,omitempty"`
+}
+
+func toAccountMergeResponse(m *entity.AccountMerge) AccountMergeResponse {
+	conflicts := m.Conflicts
+	if conflicts == nil {
+		conflicts = []entity.AccountMergeConflict{}
+	}
+	return AccountMergeResponse{
+		ID:             m.ID,
+		SourceUserID:   m.SourceUserID,
+		TargetUserID:   m.TargetUserID,
+		ConflictPolicy: string(m.Policy),
+		Status:         string(m.Status),
+		Reason:         m.Reason,
+		MergedBy:       m.MergedBy,
+		SourceLTV:      m.SourceLTV,
+		Moved:          m.Moved,
+		Conflicts:      conflicts,
+		CreatedAt:      m.CreatedAt,
+		UndoUntil:      m.UndoUntil,
+		UndoneAt:       m.UndoneAt,
+	}
+}
+
+// MergeAccounts POST /v1/admin/account-merges
+func (h *AdminAccountMergeHandler) MergeAccounts(c *gin.Context) {
+	var req AccountMergeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+
+	var mergedBy *uuid.UUID
+	if adminID, ok := c.Get("admin_id"); ok {
+		if id, ok := adminID.(uuid.UUID); ok {
+			mergedBy = &id
+		}
+	}
+	merge, err := h.merger.Merge(c.Request.Context(), service.AccountMergeRequest{
+		AppID:        httpmiddleware.GetAppID(c),
+		SourceUserID: req.SourceUserID,
+		TargetUserID: req.TargetUserID,
+		Policy:       entity.MergeConflictPolicy(req.ConflictPolicy),
+		Reason:       req.Reason,
+		MergedBy:     mergedBy,
+		DryRun:       req.DryRun,
+	})
+	if err != nil {
+		h.writeMergeError(c, err)
+		return
+	}
+
+	resp := toAccountMergeResponse(merge)
+	if req.DryRun {
+		resp.DryRun = true
+		response.OK(c, resp)
+		return
+	}
+	h.logAction(c, "merge_accounts", merge, req.Reason)
+	response.Created(c, resp)
+}
+
+// GetAccountMerge GET /v1/admin/account-merges/:id
+func (h *AdminAccountMergeHandler) GetAccountMerge(c *gin.Context) {
+	mergeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid merge ID")
+		return
+	}
+	merge, err := h.merger.Get(c.Request.Context(), httpmiddleware.GetAppID(c), mergeID)
+	if err != nil {
+		h.writeMergeError(c, err)
+		return
+	}
+	response.OK(c, toAccountMergeResponse(merge))
+}
+
+// UndoAccountMerge POST /v1/admin/account-merges/:id/undo
+func (h *AdminAccountMergeHandler) UndoAccountMerge(c *gin.Context) {
+	mergeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid merge ID")
+		return
+	}
+	var req UndoAccountMergeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+
+	merge, err := h.merger.Undo(c.Request.Context(), httpmiddleware.GetAppID(c), mergeID)
+	if err != nil {
+		h.writeMergeError(c, err)
+		return
+	}
+
+	h.logAction(c, "undo_account_merge", merge, req.Reason)
+	response.OK(c, toAccountMergeResponse(merge))
+}
+
+// ListUserAccountMerges GET /v1/admin/users/:id/account-merges
+func (h *AdminAccountMergeHandler) ListUserAccountMerges(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid user ID")
+		return
+	}
+	merges, err := h.merger.ListForUser(c.Request.Context(), userID)
+	if err != nil {
+		h.writeMergeError(c, err)
+		return
+	}
+
+	appID := httpmiddleware.GetAppID(c)
+	items := make([]AccountMergeResponse, 0, len(merges))
+	for _, m := range merges {
+		if m.AppID == appID {
+			items = append(items, toAccountMergeResponse(m))
+		}
+	}
+	response.OK(c, gin.H{"merges": items, "total": len(items)})
+}
+
+func (h *AdminAccountMergeHandler) writeMergeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, entity.ErrInvalidAccountMerge):
+		response.UnprocessableEntity(c, err.Error())
+	case errors.Is(err, entity.ErrAccountMergeNotUndoable):
+		response.Conflict(c, err.Error())
+	case errors.Is(err, domainErrors.ErrNotFound):
+		response.NotFound(c, "Account merge not found")
+	default:
+		h.logger.Error("Account merge failed", zap.Error(err))
+		response.InternalError(c, "Account merge failed")
+	}
+}
+
+func (h *AdminAccountMergeHandler) logAction(c *gin.Context, action string, merge *entity.AccountMerge, reason string) {
+	adminIDValue, _ := c.Get("admin_id")
+	adminID, ok := adminIDValue.(uuid.UUID)
+	if !ok || h.audit == nil {
+		return
+	}
+	details := map[string]interface{}{
+		"merge_id":        merge.ID.String(),
+		"source_user_id":  merge.SourceUserID.String(),
+		"target_user_id":  merge.TargetUserID.String(),
+		"conflict_policy": string(merge.Policy),
+		"moved":           merge.Moved,
+		"conflicts":       len(merge.Conflicts),
+		"reason":          reason,
+	}
+	if err := h.audit.LogAction(c.Request.Context(), adminID, action, "user", &merge.TargetUserID, details); err != nil {
+		h.logger.Warn("Failed to audit account merge", zap.String("action", action), zap.Error(err))
+	}
+}
@@ -0,0 +1,134 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/handlers"
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+)
+
+type fakeAccountMerger struct {
+	requests []service.AccountMergeRequest
+	undoErr  error
+}
+
+func (f *fakeAccountMerger) Merge(ctx context.Context, req service.AccountMergeRequest) (*entity.AccountMerge, error) {
+	f.requests = append(f.requests, req)
+	m, err := entity.NewAccountMerge(req.AppID, req.SourceUserID, req.TargetUserID, req.Policy, req.Reason, req.MergedBy, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	m.Moved["transactions"] = 4
+	return m, nil
+}
+
+func (f *fakeAccountMerger) Undo(ctx context.Context, appID, mergeID uuid.UUID) (*entity.AccountMerge, error) {
+	if f.undoErr != nil {
+		return nil, f.undoErr
+	}
+	m, _ := entity.NewAccountMerge(appID, uuid.New(), uuid.New(), entity.MergeKeepTarget, "duplicate", nil, time.Now())
+	m.ID = mergeID
+	return m, m.Undo(time.Now())
+}
+
+func (f *fakeAccountMerger) Get(ctx context.Context, appID, mergeID uuid.UUID) (*entity.AccountMerge, error) {
+	return nil, fmt.Errorf("account merge: %w", domainErrors.ErrNotFound)
+}
+
+func (f *fakeAccountMerger) ListForUser(ctx context.Context, userID uuid.UUID) ([]*entity.AccountMerge, error) {
+	return nil, nil
+}
+
+func postAccountMergeJSON(h *handlers.AdminAccountMergeHandler, path, body string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("admin_id", uuid.New())
+		c.Set(httpmiddleware.AppIDKey, uuid.New())
+		c.Next()
+	})
+	r.POST("/v1/admin/account-merges", h.MergeAccounts)
+	r.POST("/v1/admin/account-merges/:id/undo", h.UndoAccountMerge)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestMergeAccounts_AuditsMerge(t *testing.T) {
+	merger := &fakeAccountMerger{}
+	audit := &fakeAuditLogger{}
+	h := handlers.NewAdminAccountMergeHandler(merger, audit, zap.NewNop())
+	source, target := uuid.New(), uuid.New()
+
+	w := postAccountMergeJSON(h, "/v1/admin/account-merges",
+		`{"source_user_id":"`+source.String()+`","target_user_id":"`+target.String()+`","conflict_policy":"keep_source","reason":"email and Apple SSO"}`)
+
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	require.Len(t, merger.requests, 1)
+	assert.Equal(t, entity.MergeKeepSource, merger.requests[0].Policy)
+	assert.NotNil(t, merger.requests[0].MergedBy)
+	require.Equal(t, []string{"merge_accounts"}, audit.actions)
+	assert.Equal(t, source.String(), audit.details[0]["source_user_id"])
+
+	var body struct {
+		Data handlers.AccountMergeResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, int64(4), body.Data.Moved["transactions"])
+	assert.NotNil(t, body.Data.Conflicts)
+}
+
+func TestMergeAccounts_DryRunIsNotAudited(t *testing.T) {
+	audit := &fakeAuditLogger{}
+	h := handlers.NewAdminAccountMergeHandler(&fakeAccountMerger{}, audit, zap.NewNop())
+
+	w := postAccountMergeJSON(h, "/v1/admin/account-merges",
+		`{"source_user_id":"`+uuid.NewString()+`","target_user_id":"`+uuid.NewString()+`","reason":"check","dry_run":true}`)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"dry_run":true`)
+	assert.Empty(t, audit.actions)
+}
+
+func TestMergeAccounts_RejectsSelfMerge(t *testing.T) {
+	h := handlers.NewAdminAccountMergeHandler(&fakeAccountMerger{}, &fakeAuditLogger{}, zap.NewNop())
+	user := uuid.NewString()
+
+	w := postAccountMergeJSON(h, "/v1/admin/account-merges",
+		`{"source_user_id":"`+user+`","target_user_id":"`+user+`","reason":"oops"}`)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+}
+
+func TestUndoAccountMerge(t *testing.T) {
+	audit := &fakeAuditLogger{}
+	h := handlers.NewAdminAccountMergeHandler(&fakeAccountMerger{}, audit, zap.NewNop())
+
+	w := postAccountMergeJSON(h, "/v1/admin/account-merges/"+uuid.NewString()+"/undo", `{"reason":"wrong person"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"status":"undone"`)
+	assert.Equal(t, []string{"undo_account_merge"}, audit.actions)
+
+	closed := handlers.NewAdminAccountMergeHandler(&fakeAccountMerger{undoErr: entity.ErrAccountMergeNotUndoable}, &fakeAuditLogger{}, zap.NewNop())
+	w = postAccountMergeJSON(closed, "/v1/admin/account-merges/"+uuid.NewString()+"/undo", `{"reason":"late"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
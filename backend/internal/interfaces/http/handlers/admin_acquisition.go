@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+const (
+	defaultAcquisitionWindow = 30 * 24 * time.Hour
+	maxAcquisitionWindow     = 366 * 24 * time.Hour
+)
+
+type acquisitionReporter interface {
+	Cohorts(ctx context.Context, appID uuid.UUID, from, to time.Time, dimension service.AcquisitionDimension) ([]service.AcquisitionCohort, error)
+}
+
+// AdminAcquisitionHandler reports signup conversion and LTV by acquisition
+// source
+type AdminAcquisitionHandler struct {
+	acquisitions acquisitionReporter
+	now          func() time.Time
+}
+
+func NewAdminAcquisitionHandler(acquisitions acquisitionReporter) *AdminAcquisitionHandler {
+	return &AdminAcquisitionHandler{acquisitions: acquisitions, now: time.Now}
+}
+
+// GetAcquisitionCohorts GET /v1/admin/analytics/acquisition?from=YYYY-MM-DD&to=YYYY-MM-DD&group_by=channel
+// Cohorts are by signup date; to is inclusive and the default range is the
+// last 30 days. group_by is channel (default), media_source, campaign or adset.
+func (h *AdminAcquisitionHandler) GetAcquisitionCohorts(c *gin.Context) {
+	dimension := service.AcquisitionDimension(c.DefaultQuery("group_by", string(service.AcquisitionByChannel)))
+	if !dimension.IsValid() {
+		response.BadRequest(c, "group_by must be channel, media_source, campaign or adset")
+		return
+	}
+	to := h.now().UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+	if raw := c.Query("to"); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			response.BadRequest(c, "to must be a date in YYYY-MM-DD format")
+			return
+		}
+		to = parsed.Add(24 * time.Hour)
+	}
+	from := to.Add(-defaultAcquisitionWindow)
+	if raw := c.Query("from"); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			response.BadRequest(c, "from must be a date in YYYY-MM-DD format")
+			return
+		}
+		from = parsed
+	}
+	if !from.Before(to) || to.Sub(from) > maxAcquisitionWindow {
+		response.BadRequest(c, "from must be before to and the range at most 366 days")
+		return
+	}
+
+	cohorts, err := h.acquisitions.Cohorts(c.Request.Context(), httpmiddleware.GetAppID(c), from, to, dimension)
+	if err != nil {
+		response.InternalError(c, "Failed to build acquisition cohorts")
+		return
+	}
+	response.OK(c, gin.H{"from": from, "to": to, "group_by": dimension, "cohorts": cohorts})
+}
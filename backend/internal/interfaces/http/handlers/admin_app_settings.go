@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -36,6 +37,12 @@ type appSettingsRequest struct {
 	StoreEnvironment        *string             `json:"store_environment" binding:"omitempty,oneof=production sandbox"`
 	Entitlements            map[string][]string `json:"entitlements"`
 	SubscriptionRequiredFor []string            `json:"subscription_required_for"`
+	Consumables             map[string]entity.ConsumableProduct `json:"consumables"`
+	LifetimeProducts        map[string]entity.LifetimeProduct   `json:"lifetime_products"`
+	Metering                map[string]entity.MeteringRule      `json:"metering"`
+	ReportingTimezone       *string                             `json:"reporting_timezone"`
+	AppStoreID              *string                             `json:"app_store_id"`
+	MMPCallbackToken        *string                             `json:"mmp_callback_token"`
 }
 
 // GetAppSettings GET /v1/admin/apps/:id/settings
@@ -121,6 +128,61 @@ func (h *AppSettingsHandler) PutAppSettings(c *gin.Context) {
 	if req.SubscriptionRequiredFor != nil {
 		current.SubscriptionRequiredFor = req.SubscriptionRequiredFor
 	}
+	if req.Consumables != nil {
+		for productID, product := range req.Consumables {
+			if product.Credits <= 0 || product.Price < 0 {
+				response.UnprocessableEntity(c, "consumables."+productID+": credits must be positive and price not negative")
+				return
+			}
+		}
+		current.Consumables = req.Consumables
+	}
+	if req.LifetimeProducts != nil {
+		for productID, product := range req.LifetimeProducts {
+			if product.Price < 0 {
+				response.UnprocessableEntity(c, "lifetime_products."+productID+": price must not be negative")
+				return
+			}
+			if _, ok := current.Consumables[productID]; ok {
+				response.UnprocessableEntity(c, "lifetime_products."+productID+": product is already configured as a consumable")
+				return
+			}
+		}
+		current.LifetimeProducts = req.LifetimeProducts
+	}
+	if req.Metering != nil {
+		for feature, rule := range req.Metering {
+			if err := rule.Validate(); err != nil {
+				response.UnprocessableEntity(c, "metering."+feature+": "+err.Error())
+				return
+			}
+		}
+		current.Metering = req.Metering
+	}
+	if req.ReportingTimezone != nil {
+		tz := strings.TrimSpace(*req.ReportingTimezone)
+		if err := entity.ValidateReportingTimezone(tz); err != nil {
+			response.UnprocessableEntity(c, err.Error())
+			return
+		}
+		current.ReportingTimezone = tz
+	}
+	if req.AppStoreID != nil {
+		storeID := strings.TrimSpace(*req.AppStoreID)
+		if _, err := strconv.ParseUint(storeID, 10, 64); storeID != "" && err != nil {
+			response.UnprocessableEntity(c, "app_store_id must be the numeric App Store ID")
+			return
+		}
+		current.AppStoreID = storeID
+	}
+	if req.MMPCallbackToken != nil {
+		token := strings.TrimSpace(*req.MMPCallbackToken)
+		if token != "" && len(token) < 16 {
+			response.UnprocessableEntity(c, "mmp_callback_token must be at least 16 characters")
+			return
+		}
+		current.MMPCallbackToken = token
+	}
 
 	if err := h.appRepo.UpdateSettings(c.Request.Context(), id, current); err != nil {
 		if isNotFound(err) {
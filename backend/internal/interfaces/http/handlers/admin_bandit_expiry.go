@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+	"github.com/bivex/paywall-iap/internal/worker/tasks"
+)
+
+type pendingRewardExpiryRuns interface {
+	Start(ctx context.Context, req service.PendingRewardExpiryRequest) (*service.PendingRewardExpiryRun, error)
+	MarkFailed(ctx context.Context, run *service.PendingRewardExpiryRun, cause error) error
+	Get(ctx context.Context, runID uuid.UUID) (*service.PendingRewardExpiryRun, error)
+	List(ctx context.Context, limit int) ([]*service.PendingRewardExpiryRun, error)
+}
+
+type startExpiryRunRequest struct {
+	BatchSize     *int `json:"batch_size"`
+	RatePerSecond *int `json:"rate_per_second"`
+	MaxBatches    int  `json:"max_batches"`
+}
+
+// AdminBanditExpiryHandler starts and reports on sweeps of expired bandit
+// pending rewards, which the worker executes
+type AdminBanditExpiryHandler struct {
+	runs  pendingRewardExpiryRuns
+	queue taskEnqueuer
+}
+
+func NewAdminBanditExpiryHandler(runs pendingRewardExpiryRuns, queue taskEnqueuer) *AdminBanditExpiryHandler {
+	return &AdminBanditExpiryHandler{runs: runs, queue: queue}
+}
+
+// StartExpiryRun POST /v1/admin/bandit/expiry-runs
+func (h *AdminBanditExpiryHandler) StartExpiryRun(c *gin.Context) {
+	var req startExpiryRunRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		response.BadRequest(c, "Invalid request body")
+		return
+	}
+	batchSize, rate := service.DefaultExpiryRunBatchSize, service.DefaultExpiryRunRatePerSecond
+	if req.BatchSize != nil {
+		batchSize = *req.BatchSize
+	}
+	if req.RatePerSecond != nil {
+		rate = *req.RatePerSecond
+	}
+
+	ctx := c.Request.Context()
+	run, err := h.runs.Start(ctx, service.PendingRewardExpiryRequest{
+		Trigger:       service.ExpiryRunTriggerAdmin,
+		BatchSize:     batchSize,
+		RatePerSecond: rate,
+		MaxBatches:    req.MaxBatches,
+	})
+	switch {
+	case errors.Is(err, service.ErrInvalidExpiryRun):
+		response.BadRequest(c, err.Error())
+		return
+	case errors.Is(err, service.ErrExpiryRunInProgress):
+		if run != nil {
+			response.Conflict(c, fmt.Sprintf("Expiry run %s is already in progress", run.ID))
+			return
+		}
+		response.Conflict(c, "An expiry run is already in progress")
+		return
+	case err != nil:
+		response.InternalError(c, "Failed to start expiry run")
+		return
+	}
+
+	if _, err := h.queue.Enqueue(tasks.NewExpirePendingRewardsTask(run.ID)); err != nil {
+		_ = h.runs.MarkFailed(ctx, run, fmt.Errorf("failed to enqueue run: %w", err))
+		response.InternalError(c, "Failed to enqueue expiry run")
+		return
+	}
+	response.Send(c, http.StatusAccepted, gin.H{"run": run})
+}
+
+// ListExpiryRuns GET /v1/admin/bandit/expiry-runs?limit=
+func (h *AdminBanditExpiryHandler) ListExpiryRuns(c *gin.Context) {
+	limit := 20
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 || parsed > 100 {
+			response.BadRequest(c, "limit must be between 1 and 100")
+			return
+		}
+		limit = parsed
+	}
+
+	runs, err := h.runs.List(c.Request.Context(), limit)
+	if err != nil {
+		response.InternalError(c, "Failed to list expiry runs")
+		return
+	}
+	response.OK(c, gin.H{"runs": runs, "total": len(runs)})
+}
+
+// GetExpiryRun GET /v1/admin/bandit/expiry-runs/:id
+func (h *AdminBanditExpiryHandler) GetExpiryRun(c *gin.Context) {
+	runID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid run ID")
+		return
+	}
+
+	run, err := h.runs.Get(c.Request.Context(), runID)
+	if errors.Is(err, service.ErrExpiryRunNotFound) {
+		response.NotFound(c, "Expiry run not found")
+		return
+	}
+	if err != nil {
+		response.InternalError(c, "Failed to get expiry run")
+		return
+	}
+	response.OK(c, gin.H{"run": run})
+}
@@ -0,0 +1,136 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/handlers"
+	"github.com/bivex/paywall-iap/internal/worker/tasks"
+)
+
+type fakeExpiryRuns struct {
+	started  *service.PendingRewardExpiryRequest
+	startErr error
+	running  *service.PendingRewardExpiryRun
+	failed   bool
+}
+
+func (f *fakeExpiryRuns) Start(_ context.Context, req service.PendingRewardExpiryRequest) (*service.PendingRewardExpiryRun, error) {
+	f.started = &req
+	if f.startErr != nil {
+		return f.running, f.startErr
+	}
+	return &service.PendingRewardExpiryRun{ID: uuid.New(), Status: service.ExpiryRunStatusRunning, Trigger: req.Trigger, BatchSize: req.BatchSize}, nil
+}
+
+func (f *fakeExpiryRuns) MarkFailed(context.Context, *service.PendingRewardExpiryRun, error) error {
+	f.failed = true
+	return nil
+}
+
+func (f *fakeExpiryRuns) Get(_ context.Context, runID uuid.UUID) (*service.PendingRewardExpiryRun, error) {
+	return nil, service.ErrExpiryRunNotFound
+}
+
+func (f *fakeExpiryRuns) List(context.Context, int) ([]*service.PendingRewardExpiryRun, error) {
+	return nil, nil
+}
+
+type recordingEnqueuer struct {
+	tasks []*asynq.Task
+	err   error
+}
+
+func (q *recordingEnqueuer) Enqueue(task *asynq.Task, _ ...asynq.Option) (*asynq.TaskInfo, error) {
+	q.tasks = append(q.tasks, task)
+	return &asynq.TaskInfo{}, q.err
+}
+
+func newBanditExpiryRouter(h *handlers.AdminBanditExpiryHandler) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/v1/admin/bandit/expiry-runs", h.StartExpiryRun)
+	r.GET("/v1/admin/bandit/expiry-runs/:id", h.GetExpiryRun)
+	return r
+}
+
+func TestStartExpiryRun_EnqueuesRun(t *testing.T) {
+	runs := &fakeExpiryRuns{}
+	queue := &recordingEnqueuer{}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1/admin/bandit/expiry-runs", strings.NewReader(`{"batch_size":500,"rate_per_second":0}`))
+	req.Header.Set("Content-Type", "application/json")
+	newBanditExpiryRouter(handlers.NewAdminBanditExpiryHandler(runs, queue)).ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, service.PendingRewardExpiryRequest{Trigger: service.ExpiryRunTriggerAdmin, BatchSize: 500}, *runs.started)
+	require.Len(t, queue.tasks, 1)
+	assert.Equal(t, tasks.TypeExpirePendingRewards, queue.tasks[0].Type())
+
+	var body struct {
+		Data struct {
+			Run service.PendingRewardExpiryRun `json:"run"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	var payload tasks.ExpirePendingRewardsPayload
+	require.NoError(t, json.Unmarshal(queue.tasks[0].Payload(), &payload))
+	assert.Equal(t, body.Data.Run.ID.String(), payload.RunID)
+}
+
+func TestStartExpiryRun_UsesDefaultsWithoutBody(t *testing.T) {
+	runs := &fakeExpiryRuns{}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1/admin/bandit/expiry-runs", nil)
+	newBanditExpiryRouter(handlers.NewAdminBanditExpiryHandler(runs, &recordingEnqueuer{})).ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, service.DefaultExpiryRunBatchSize, runs.started.BatchSize)
+	assert.Equal(t, service.DefaultExpiryRunRatePerSecond, runs.started.RatePerSecond)
+}
+
+func TestStartExpiryRun_ConflictsWithRunningRun(t *testing.T) {
+	running := &service.PendingRewardExpiryRun{ID: uuid.New()}
+	runs := &fakeExpiryRuns{startErr: service.ErrExpiryRunInProgress, running: running}
+	queue := &recordingEnqueuer{}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1/admin/bandit/expiry-runs", nil)
+	newBanditExpiryRouter(handlers.NewAdminBanditExpiryHandler(runs, queue)).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), running.ID.String())
+	assert.Empty(t, queue.tasks)
+}
+
+func TestStartExpiryRun_MarksRunFailedWhenEnqueueFails(t *testing.T) {
+	runs := &fakeExpiryRuns{}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1/admin/bandit/expiry-runs", nil)
+	newBanditExpiryRouter(handlers.NewAdminBanditExpiryHandler(runs, &recordingEnqueuer{err: assert.AnError})).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.True(t, runs.failed)
+}
+
+func TestGetExpiryRun_NotFound(t *testing.T) {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/v1/admin/bandit/expiry-runs/"+uuid.NewString(), nil)
+	newBanditExpiryRouter(handlers.NewAdminBanditExpiryHandler(&fakeExpiryRuns{}, &recordingEnqueuer{})).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/infrastructure/cache"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+type adminCacheStore interface {
+	GetCacheStats(ctx context.Context) (*cache.CacheStats, error)
+	CountPattern(ctx context.Context, pattern string) (int64, error)
+	FlushPattern(ctx context.Context, pattern string) (int, error)
+	SetLTV(ctx context.Context, userID string, data *cache.LTVData) error
+}
+
+type banditCacheWarmer interface {
+	WarmArmStats(ctx context.Context, experimentID uuid.UUID) (int, error)
+	WarmAssignment(ctx context.Context, experimentID, userID uuid.UUID) (uuid.UUID, error)
+}
+
+type ltvEstimator interface {
+	CalculateLTV(ctx context.Context, userID uuid.UUID) (*service.LTVEstimates, error)
+}
+
+type adminAuditLogger interface {
+	LogAction(ctx context.Context, adminID uuid.UUID, action, targetType string, targetUserID *uuid.UUID, details map[string]interface{}) error
+}
+
+// AdminCacheHandler inspects, flushes and warms the Redis caches. Flushes are
+// limited to the known namespaces and every flush or warm is audit logged.
+type AdminCacheHandler struct {
+	store  adminCacheStore
+	bandit banditCacheWarmer
+	ltv    ltvEstimator
+	audit  adminAuditLogger
+	logger *zap.Logger
+}
+
+func NewAdminCacheHandler(store adminCacheStore, bandit banditCacheWarmer, ltv ltvEstimator, audit adminAuditLogger, logger *zap.Logger) *AdminCacheHandler {
+	return &AdminCacheHandler{store: store, bandit: bandit, ltv: ltv, audit: audit, logger: logger}
+}
+
+// CacheNamespaceCount is the number of keys in one namespace
+type CacheNamespaceCount struct {
+	Namespace cache.CacheNamespace `json:"namespace"`
+	Pattern   string               `json:"pattern"`
+	Keys      int64                `json:"keys"`
+}
+
+// AdminCacheStats summarizes Redis usage by namespace
+type AdminCacheStats struct {
+	Namespaces  []CacheNamespaceCount `json:"namespaces"`
+	TotalKeys   int64                 `json:"total_keys"`
+	MemoryBytes int64                 `json:"memory_bytes"`
+}
+
+// GetCacheStats GET /v1/admin/cache
+// Counts come from SCAN and are approximate while keys are being written.
+func (h *AdminCacheHandler) GetCacheStats(c *gin.Context) {
+	ctx := c.Request.Context()
+	stats, err := h.store.GetCacheStats(ctx)
+	if err != nil {
+		h.logger.Error("Failed to read cache stats", zap.Error(err))
+		response.InternalError(c, "Failed to read cache stats")
+		return
+	}
+
+	result := AdminCacheStats{
+		Namespaces:  make([]CacheNamespaceCount, 0, len(cache.CacheNamespaces)),
+		TotalKeys:   stats.Keys,
+		MemoryBytes: stats.Memory,
+	}
+	for _, namespace := range cache.CacheNamespaces {
+		pattern, err := cache.NamespacePattern(namespace, uuid.Nil, uuid.Nil)
+		if err != nil {
+			response.InternalError(c, "Failed to build cache pattern")
+			return
+		}
+		keys, err := h.store.CountPattern(ctx, pattern)
+		if err != nil {
+			h.logger.Error("Failed to count cache keys", zap.String("pattern", pattern), zap.Error(err))
+			response.InternalError(c, "Failed to count cache keys")
+			return
+		}
+		result.Namespaces = append(result.Namespaces, CacheNamespaceCount{Namespace: namespace, Pattern: pattern, Keys: keys})
+	}
+
+	response.OK(c, result)
+}
+
+type flushCacheRequest struct {
+	Namespace    string `json:"namespace" binding:"required"`
+	ExperimentID string `json:"experiment_id" binding:"omitempty,uuid"`
+	UserID       string `json:"user_id" binding:"omitempty,uuid"`
+}
+
+// FlushCacheResponse reports what a flush removed
+type FlushCacheResponse struct {
+	Namespace cache.CacheNamespace `json:"namespace"`
+	Pattern   string               `json:"pattern"`
+	Deleted   int                  `json:"deleted"`
+}
+
+// FlushCache POST /v1/admin/cache/flush
+// Removes one namespace, optionally narrowed to an experiment (assignments)
+// or a user (assignments, ltv).
+func (h *AdminCacheHandler) FlushCache(c *gin.Context) {
+	var req flushCacheRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body")
+		return
+	}
+	experimentID, userID := parseOptionalUUID(req.ExperimentID), parseOptionalUUID(req.UserID)
+
+	namespace := cache.CacheNamespace(req.Namespace)
+	pattern, err := cache.NamespacePattern(namespace, experimentID, userID)
+	if err != nil {
+		response.UnprocessableEntity(c, err.Error())
+		return
+	}
+
+	ctx := c.Request.Context()
+	deleted, err := h.store.FlushPattern(ctx, pattern)
+	if err != nil {
+		if errors.Is(err, cache.ErrUnsafeFlushPattern) {
+			response.UnprocessableEntity(c, err.Error())
+			return
+		}
+		h.logger.Error("Failed to flush cache", zap.String("pattern", pattern), zap.Int("deleted", deleted), zap.Error(err))
+		response.InternalError(c, "Failed to flush cache")
+		return
+	}
+
+	h.logger.Info("Flushed cache namespace", zap.String("pattern", pattern), zap.Int("deleted", deleted))
+	h.logAction(c, "flush_cache", userID, map[string]interface{}{
+		"namespace":     req.Namespace,
+		"pattern":       pattern,
+		"deleted":       deleted,
+		"experiment_id": req.ExperimentID,
+	})
+
+	response.OK(c, FlushCacheResponse{Namespace: namespace, Pattern: pattern, Deleted: deleted})
+}
+
+type warmCacheRequest struct {
+	ExperimentID string `json:"experiment_id" binding:"omitempty,uuid"`
+	UserID       string `json:"user_id" binding:"omitempty,uuid"`
+}
+
+// WarmCacheResponse reports what a warm loaded. AssignedArmID is set when both
+// an experiment and a user were given and the user has a live assignment.
+type WarmCacheResponse struct {
+	ArmStatsWarmed int     `json:"arm_stats_warmed"`
+	LTVWarmed      bool    `json:"ltv_warmed"`
+	AssignedArmID  *string `json:"assigned_arm_id,omitempty"`
+	UserExcluded   bool    `json:"user_excluded,omitempty"`
+}
+
+// WarmCache POST /v1/admin/cache/warm
+// An experiment warms its arm stats, a user their LTV, and both together the
+// user's assignment in that experiment.
+func (h *AdminCacheHandler) WarmCache(c *gin.Context) {
+	var req warmCacheRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body")
+		return
+	}
+	experimentID, userID := parseOptionalUUID(req.ExperimentID), parseOptionalUUID(req.UserID)
+	if experimentID == uuid.Nil && userID == uuid.Nil {
+		response.BadRequest(c, "experiment_id or user_id is required")
+		return
+	}
+
+	ctx := c.Request.Context()
+	var result WarmCacheResponse
+	if experimentID != uuid.Nil {
+		warmed, err := h.bandit.WarmArmStats(ctx, experimentID)
+		if err != nil {
+			if errors.Is(err, service.ErrExperimentArmsNotFound) {
+				response.NotFound(c, "Experiment not found")
+				return
+			}
+			h.logger.Error("Failed to warm arm stats", zap.String("experiment_id", req.ExperimentID), zap.Error(err))
+			response.InternalError(c, "Failed to warm arm stats")
+			return
+		}
+		result.ArmStatsWarmed = warmed
+
+		if userID != uuid.Nil {
+			armID, err := h.bandit.WarmAssignment(ctx, experimentID, userID)
+			switch {
+			case errors.Is(err, service.ErrUserExcluded):
+				result.UserExcluded = true
+			case err != nil:
+				h.logger.Error("Failed to warm assignment", zap.String("user_id", req.UserID), zap.Error(err))
+				response.InternalError(c, "Failed to warm assignment")
+				return
+			case armID != uuid.Nil:
+				assigned := armID.String()
+				result.AssignedArmID = &assigned
+			}
+		}
+	}
+
+	if userID != uuid.Nil {
+		estimates, err := h.ltv.CalculateLTV(ctx, userID)
+		if err != nil {
+			h.logger.Error("Failed to warm LTV", zap.String("user_id", req.UserID), zap.Error(err))
+			response.InternalError(c, "Failed to warm LTV")
+			return
+		}
+		if err := h.store.SetLTV(ctx, req.UserID, ltvCacheData(req.UserID, estimates)); err != nil {
+			h.logger.Error("Failed to cache LTV", zap.String("user_id", req.UserID), zap.Error(err))
+			response.InternalError(c, "Failed to warm LTV")
+			return
+		}
+		result.LTVWarmed = true
+	}
+
+	h.logAction(c, "warm_cache", userID, map[string]interface{}{
+		"experiment_id":    req.ExperimentID,
+		"arm_stats_warmed": result.ArmStatsWarmed,
+		"ltv_warmed":       result.LTVWarmed,
+	})
+
+	response.OK(c, result)
+}
+
+func (h *AdminCacheHandler) logAction(c *gin.Context, action string, userID uuid.UUID, details map[string]interface{}) {
+	adminIDValue, _ := c.Get("admin_id")
+	adminID, ok := adminIDValue.(uuid.UUID)
+	if !ok {
+		return
+	}
+	var targetUserID *uuid.UUID
+	if userID != uuid.Nil {
+		targetUserID = &userID
+	}
+	if err := h.audit.LogAction(c.Request.Context(), adminID, action, "cache", targetUserID, details); err != nil {
+		h.logger.Warn("Failed to audit cache action", zap.String("action", action), zap.Error(err))
+	}
+}
+
+// parseOptionalUUID parses an already validated optional UUID; empty is uuid.Nil
+func parseOptionalUUID(value string) uuid.UUID {
+	if value == "" {
+		return uuid.Nil
+	}
+	parsed, _ := uuid.Parse(value)
+	return parsed
+}
@@ -0,0 +1,165 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/infrastructure/cache"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/handlers"
+)
+
+type fakeCacheStore struct {
+	counts  map[string]int64
+	flushed []string
+	ltv     map[string]*cache.LTVData
+}
+
+func (f *fakeCacheStore) GetCacheStats(ctx context.Context) (*cache.CacheStats, error) {
+	return &cache.CacheStats{Keys: 42, Memory: 2048}, nil
+}
+
+func (f *fakeCacheStore) CountPattern(ctx context.Context, pattern string) (int64, error) {
+	return f.counts[pattern], nil
+}
+
+func (f *fakeCacheStore) FlushPattern(ctx context.Context, pattern string) (int, error) {
+	f.flushed = append(f.flushed, pattern)
+	return 3, nil
+}
+
+func (f *fakeCacheStore) SetLTV(ctx context.Context, userID string, data *cache.LTVData) error {
+	f.ltv[userID] = data
+	return nil
+}
+
+type fakeBanditCacheWarmer struct {
+	armID uuid.UUID
+	err   error
+}
+
+func (f *fakeBanditCacheWarmer) WarmArmStats(ctx context.Context, experimentID uuid.UUID) (int, error) {
+	if f.err != nil {
+		return 0, f.err
+	}
+	return 2, nil
+}
+
+func (f *fakeBanditCacheWarmer) WarmAssignment(ctx context.Context, experimentID, userID uuid.UUID) (uuid.UUID, error) {
+	return f.armID, nil
+}
+
+type fakeLTVEstimator struct{}
+
+func (fakeLTVEstimator) CalculateLTV(ctx context.Context, userID uuid.UUID) (*service.LTVEstimates, error) {
+	return &service.LTVEstimates{UserID: userID.String(), LTV30: 9.99}, nil
+}
+
+type fakeAuditLogger struct {
+	actions []string
+	details []map[string]interface{}
+}
+
+func (f *fakeAuditLogger) LogAction(ctx context.Context, adminID uuid.UUID, action, targetType string, targetUserID *uuid.UUID, details map[string]interface{}) error {
+	f.actions = append(f.actions, action)
+	f.details = append(f.details, details)
+	return nil
+}
+
+func newAdminCacheRouter(h *handlers.AdminCacheHandler) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("admin_id", uuid.New())
+		c.Next()
+	})
+	r.GET("/v1/admin/cache", h.GetCacheStats)
+	r.POST("/v1/admin/cache/flush", h.FlushCache)
+	r.POST("/v1/admin/cache/warm", h.WarmCache)
+	return r
+}
+
+func postCacheJSON(r *gin.Engine, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestAdminCache_GetCacheStatsCountsNamespaces(t *testing.T) {
+	store := &fakeCacheStore{counts: map[string]int64{"ab:assign:*": 7, "analytics:ltv:*": 2}}
+	r := newAdminCacheRouter(handlers.NewAdminCacheHandler(store, &fakeBanditCacheWarmer{}, fakeLTVEstimator{}, &fakeAuditLogger{}, zap.NewNop()))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/cache", nil))
+	require.Equal(t, http.StatusOK, w.Code, "body=%s", w.Body.String())
+
+	var body struct {
+		Data handlers.AdminCacheStats `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, int64(42), body.Data.TotalKeys)
+	require.Len(t, body.Data.Namespaces, len(cache.CacheNamespaces))
+	counts := map[cache.CacheNamespace]int64{}
+	for _, ns := range body.Data.Namespaces {
+		counts[ns.Namespace] = ns.Keys
+	}
+	assert.Equal(t, int64(7), counts[cache.NamespaceAssignments])
+	assert.Equal(t, int64(2), counts[cache.NamespaceLTV])
+}
+
+func TestAdminCache_FlushCacheScopesAndAudits(t *testing.T) {
+	store := &fakeCacheStore{}
+	audit := &fakeAuditLogger{}
+	r := newAdminCacheRouter(handlers.NewAdminCacheHandler(store, &fakeBanditCacheWarmer{}, fakeLTVEstimator{}, audit, zap.NewNop()))
+	experimentID := uuid.New()
+
+	w := postCacheJSON(r, "/v1/admin/cache/flush", `{"namespace":"assignments","experiment_id":"`+experimentID.String()+`"}`)
+	require.Equal(t, http.StatusOK, w.Code, "body=%s", w.Body.String())
+	assert.Equal(t, []string{"ab:assign:" + experimentID.String() + ":*"}, store.flushed)
+	assert.Equal(t, []string{"flush_cache"}, audit.actions)
+	assert.Equal(t, 3, audit.details[0]["deleted"])
+
+	assert.Equal(t, http.StatusUnprocessableEntity, postCacheJSON(r, "/v1/admin/cache/flush", `{"namespace":"*"}`).Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, postCacheJSON(r, "/v1/admin/cache/flush", `{"namespace":"arm_stats","experiment_id":"`+experimentID.String()+`"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, postCacheJSON(r, "/v1/admin/cache/flush", `{}`).Code)
+	assert.Len(t, store.flushed, 1)
+}
+
+func TestAdminCache_WarmCacheLoadsArmStatsAssignmentAndLTV(t *testing.T) {
+	store := &fakeCacheStore{ltv: map[string]*cache.LTVData{}}
+	audit := &fakeAuditLogger{}
+	armID := uuid.New()
+	r := newAdminCacheRouter(handlers.NewAdminCacheHandler(store, &fakeBanditCacheWarmer{armID: armID}, fakeLTVEstimator{}, audit, zap.NewNop()))
+	userID := uuid.New()
+
+	w := postCacheJSON(r, "/v1/admin/cache/warm", `{"experiment_id":"`+uuid.New().String()+`","user_id":"`+userID.String()+`"}`)
+	require.Equal(t, http.StatusOK, w.Code, "body=%s", w.Body.String())
+
+	var body struct {
+		Data handlers.WarmCacheResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, 2, body.Data.ArmStatsWarmed)
+	assert.True(t, body.Data.LTVWarmed)
+	require.NotNil(t, body.Data.AssignedArmID)
+	assert.Equal(t, armID.String(), *body.Data.AssignedArmID)
+	assert.Equal(t, 9.99, store.ltv[userID.String()].LTV30)
+	assert.Equal(t, []string{"warm_cache"}, audit.actions)
+
+	assert.Equal(t, http.StatusBadRequest, postCacheJSON(r, "/v1/admin/cache/warm", `{}`).Code)
+
+	missing := newAdminCacheRouter(handlers.NewAdminCacheHandler(store, &fakeBanditCacheWarmer{err: service.ErrExperimentArmsNotFound}, fakeLTVEstimator{}, audit, zap.NewNop()))
+	assert.Equal(t, http.StatusNotFound, postCacheJSON(missing, "/v1/admin/cache/warm", `{"experiment_id":"`+uuid.New().String()+`"}`).Code)
+}
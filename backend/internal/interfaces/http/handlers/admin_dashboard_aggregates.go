@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+// maxDashboardAggregateDays matches the 400-day window of the views
+const maxDashboardAggregateDays = 365
+
+type dashboardAggregateReader interface {
+	Aggregates(ctx context.Context, appID uuid.UUID, days int) (*service.DashboardAggregates, error)
+}
+
+// AdminDashboardAggregatesHandler serves dashboard metrics from the
+// periodically refreshed materialized views
+type AdminDashboardAggregatesHandler struct {
+	views  dashboardAggregateReader
+	logger *zap.Logger
+}
+
+func NewAdminDashboardAggregatesHandler(views dashboardAggregateReader, logger *zap.Logger) *AdminDashboardAggregatesHandler {
+	return &AdminDashboardAggregatesHandler{views: views, logger: logger}
+}
+
+// GetDashboardAggregates GET /v1/admin/dashboard/aggregates?days=30
+func (h *AdminDashboardAggregatesHandler) GetDashboardAggregates(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days < 1 || days > maxDashboardAggregateDays {
+		response.BadRequest(c, "days must be between 1 and 365")
+		return
+	}
+
+	appID := httpmiddleware.GetAppID(c)
+	agg, err := h.views.Aggregates(c.Request.Context(), appID, days)
+	if err != nil {
+		h.logger.Error("Failed to read dashboard aggregates", zap.String("app_id", appID.String()), zap.Error(err))
+		response.InternalError(c, "Failed to read dashboard aggregates")
+		return
+	}
+	response.OK(c, agg)
+}
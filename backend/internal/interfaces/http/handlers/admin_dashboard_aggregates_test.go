@@ -0,0 +1,74 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/handlers"
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+)
+
+type fakeDashboardAggregates struct {
+	appID uuid.UUID
+	days  int
+}
+
+func (f *fakeDashboardAggregates) Aggregates(ctx context.Context, appID uuid.UUID, days int) (*service.DashboardAggregates, error) {
+	f.appID, f.days = appID, days
+	return &service.DashboardAggregates{
+		ActiveSubsByPlan: []service.PlanSubscriptions{{PlanType: "monthly", ProductID: "pro_monthly", Active: 40}},
+		Freshness:        []service.ViewFreshness{{View: "mv_daily_revenue", AgeSeconds: 3600, Stale: true}},
+		Stale:            true,
+	}, nil
+}
+
+func getDashboardAggregates(h *handlers.AdminDashboardAggregatesHandler, appID uuid.UUID, query string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set(httpmiddleware.AppIDKey, appID)
+		c.Next()
+	})
+	r.GET("/v1/admin/dashboard/aggregates", h.GetDashboardAggregates)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/dashboard/aggregates"+query, nil))
+	return w
+}
+
+func TestGetDashboardAggregates_ReportsStaleness(t *testing.T) {
+	views := &fakeDashboardAggregates{}
+	appID := uuid.New()
+
+	w := getDashboardAggregates(handlers.NewAdminDashboardAggregatesHandler(views, zap.NewNop()), appID, "")
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, appID, views.appID)
+	assert.Equal(t, 30, views.days)
+	var body struct {
+		Data service.DashboardAggregates `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.True(t, body.Data.Stale)
+	require.Len(t, body.Data.Freshness, 1)
+	assert.Equal(t, 3600.0, body.Data.Freshness[0].AgeSeconds)
+}
+
+func TestGetDashboardAggregates_RejectsDays(t *testing.T) {
+	h := handlers.NewAdminDashboardAggregatesHandler(&fakeDashboardAggregates{}, zap.NewNop())
+
+	for _, query := range []string{"?days=0", "?days=366", "?days=month"} {
+		w := getDashboardAggregates(h, uuid.New(), query)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+type dunningCampaignStore interface {
+	Create(ctx context.Context, c *entity.DunningCampaign) error
+	Update(ctx context.Context, c *entity.DunningCampaign) error
+	GetByID(ctx context.Context, id uuid.UUID) (*entity.DunningCampaign, error)
+	List(ctx context.Context, activeOnly bool) ([]*entity.DunningCampaign, error)
+	RecoveryStats(ctx context.Context, since time.Time) ([]*entity.DunningCampaignStats, error)
+}
+
+// AdminDunningHandler manages dunning campaign variants and reports how many
+// failed renewals each one recovered
+type AdminDunningHandler struct {
+	repo   dunningCampaignStore
+	audit  adminAuditLogger
+	logger *zap.Logger
+}
+
+func NewAdminDunningHandler(repo dunningCampaignStore, audit adminAuditLogger, logger *zap.Logger) *AdminDunningHandler {
+	return &AdminDunningHandler{repo: repo, audit: audit, logger: logger}
+}
+
+// DunningCampaignRequest creates or replaces a campaign. The first reminder
+// goes out when the payment fails; reminder_offsets_hours schedule the
+// follow-ups and the last one ends the campaign.
+type DunningCampaignRequest struct {
+	Name                 string     `json:"name" binding:"required,max=100"`
+	Variant              string     `json:"variant" binding:"required,max=50"`
+	ReminderOffsetsHours []int      `json:"reminder_offsets_hours" binding:"required"`
+	Weight               int        `json:"weight"`
+	BanditExperimentID   *uuid.UUID `json:"bandit_experiment_id"`
+	BanditArmID          *uuid.UUID `json:"bandit_arm_id"`
+	IsActive             *bool      `json:"is_active"`
+}
+
+// DunningCampaignResponse is a campaign as returned to the admin
+type DunningCampaignResponse struct {
+	ID                   uuid.UUID  `json:"id"`
+	Name                 string     `json:"name"`
+	Variant              string     `json:"variant"`
+	ReminderOffsetsHours []int      `json:"reminder_offsets_hours"`
+	Weight               int        `json:"weight"`
+	BanditExperimentID   *uuid.UUID `json:"bandit_experiment_id,omitempty"`
+	BanditArmID          *uuid.UUID `json:"bandit_arm_id,omitempty"`
+	IsActive             bool       `json:"is_active"`
+	CreatedAt            time.Time  `json:"created_at"`
+	UpdatedAt            time.Time  `json:"updated_at"`
+}
+
+// DunningRecoveryRow is the recovery outcome of one campaign variant; the row
+// without campaign_id covers dunning on the default schedule
+type DunningRecoveryRow struct {
+	CampaignID         *uuid.UUID `json:"campaign_id,omitempty"`
+	Name               string     `json:"name"`
+	Variant            string     `json:"variant"`
+	Started            int64      `json:"started"`
+	Recovered          int64      `json:"recovered"`
+	Failed             int64      `json:"failed"`
+	InProgress         int64      `json:"in_progress"`
+	RecoveryRate       float64    `json:"recovery_rate"`
+	AvgHoursToRecovery float64    `json:"avg_hours_to_recovery"`
+}
+
+func toDunningCampaignResponse(c *entity.DunningCampaign) DunningCampaignResponse {
+	hours := make([]int, 0, len(c.ReminderOffsets))
+	for _, offset := range c.ReminderOffsets {
+		hours = append(hours, int(offset/time.Hour))
+	}
+	return DunningCampaignResponse{
+		ID:                   c.ID,
+		Name:                 c.Name,
+		Variant:              c.Variant,
+		ReminderOffsetsHours: hours,
+		Weight:               c.Weight,
+		BanditExperimentID:   c.BanditExperimentID,
+		BanditArmID:          c.BanditArmID,
+		IsActive:             c.IsActive,
+		CreatedAt:            c.CreatedAt,
+		UpdatedAt:            c.UpdatedAt,
+	}
+}
+
+// applyTo copies the request onto c; weight defaults to 1 and is_active to true
+func (r *DunningCampaignRequest) applyTo(c *entity.DunningCampaign) {
+	c.Name = r.Name
+	c.Variant = r.Variant
+	c.ReminderOffsets = c.ReminderOffsets[:0]
+	for _, h := range r.ReminderOffsetsHours {
+		c.ReminderOffsets = append(c.ReminderOffsets, time.Duration(h)*time.Hour)
+	}
+	c.Weight = r.Weight
+	if c.Weight == 0 {
+		c.Weight = 1
+	}
+	c.BanditExperimentID = r.BanditExperimentID
+	c.BanditArmID = r.BanditArmID
+	c.IsActive = r.IsActive == nil || *r.IsActive
+}
+
+// ListDunningCampaigns GET /v1/admin/dunning/campaigns
+func (h *AdminDunningHandler) ListDunningCampaigns(c *gin.Context) {
+	campaigns, err := h.repo.List(c.Request.Context(), false)
+	if err != nil {
+		h.logger.Error("Failed to list dunning campaigns", zap.Error(err))
+		response.InternalError(c, "Failed to list dunning campaigns")
+		return
+	}
+	items := make([]DunningCampaignResponse, 0, len(campaigns))
+	for _, campaign := range campaigns {
+		items = append(items, toDunningCampaignResponse(campaign))
+	}
+	response.OK(c, gin.H{"campaigns": items, "total": len(items)})
+}
+
+// CreateDunningCampaign POST /v1/admin/dunning/campaigns
+func (h *AdminDunningHandler) CreateDunningCampaign(c *gin.Context) {
+	var req DunningCampaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+	campaign := &entity.DunningCampaign{ID: uuid.New(), CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	req.applyTo(campaign)
+	if err := campaign.Validate(); err != nil {
+		response.UnprocessableEntity(c, err.Error())
+		return
+	}
+
+	if err := h.repo.Create(c.Request.Context(), campaign); err != nil {
+		h.writeStoreError(c, err)
+		return
+	}
+	h.logAction(c, "create_dunning_campaign", campaign)
+	response.Created(c, toDunningCampaignResponse(campaign))
+}
+
+// UpdateDunningCampaign PUT /v1/admin/dunning/campaigns/:id
+// Replaces the campaign's configuration. Running dunning keeps its reminder
+// count; later reminders follow the new offsets.
+func (h *AdminDunningHandler) UpdateDunningCampaign(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid campaign ID")
+		return
+	}
+	var req DunningCampaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+
+	campaign, err := h.repo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		h.writeStoreError(c, err)
+		return
+	}
+	req.applyTo(campaign)
+	if err := campaign.Validate(); err != nil {
+		response.UnprocessableEntity(c, err.Error())
+		return
+	}
+	if err := h.repo.Update(c.Request.Context(), campaign); err != nil {
+		h.writeStoreError(c, err)
+		return
+	}
+	campaign.UpdatedAt = time.Now()
+	h.logAction(c, "update_dunning_campaign", campaign)
+	response.OK(c, toDunningCampaignResponse(campaign))
+}
+
+// GetDunningRecovery GET /v1/admin/dunning/recovery?days=30
+// Recovery rate per campaign variant of the dunning started in the window.
+func (h *AdminDunningHandler) GetDunningRecovery(c *gin.Context) {
+	days := 30
+	if raw := c.Query("days"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 || parsed > 365 {
+			response.BadRequest(c, "days must be between 1 and 365")
+			return
+		}
+		days = parsed
+	}
+	since := time.Now().AddDate(0, 0, -days)
+
+	stats, err := h.repo.RecoveryStats(c.Request.Context(), since)
+	if err != nil {
+		h.logger.Error("Failed to load dunning recovery", zap.Error(err))
+		response.InternalError(c, "Failed to load dunning recovery")
+		return
+	}
+
+	rows := make([]DunningRecoveryRow, 0, len(stats))
+	total := entity.DunningCampaignStats{}
+	for _, s := range stats {
+		rows = append(rows, DunningRecoveryRow{
+			CampaignID:         s.CampaignID,
+			Name:               s.Name,
+			Variant:            s.Variant,
+			Started:            s.Started,
+			Recovered:          s.Recovered,
+			Failed:             s.Failed,
+			InProgress:         s.InProgress,
+			RecoveryRate:       s.RecoveryRate(),
+			AvgHoursToRecovery: s.AvgHoursToRecovery,
+		})
+		total.Started += s.Started
+		total.Recovered += s.Recovered
+		total.Failed += s.Failed
+		total.InProgress += s.InProgress
+	}
+
+	response.OK(c, gin.H{
+		"since":     since.UTC().Format(time.RFC3339),
+		"campaigns": rows,
+		"total": DunningRecoveryRow{
+			Started:      total.Started,
+			Recovered:    total.Recovered,
+			Failed:       total.Failed,
+			InProgress:   total.InProgress,
+			RecoveryRate: total.RecoveryRate(),
+		},
+	})
+}
+
+func (h *AdminDunningHandler) writeStoreError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, domainErrors.ErrNotFound):
+		response.NotFound(c, "Dunning campaign not found")
+	case errors.Is(err, entity.ErrInvalidDunningCampaign):
+		response.Conflict(c, err.Error())
+	default:
+		h.logger.Error("Failed to save dunning campaign", zap.Error(err))
+		response.InternalError(c, "Failed to save dunning campaign")
+	}
+}
+
+func (h *AdminDunningHandler) logAction(c *gin.Context, action string, campaign *entity.DunningCampaign) {
+	adminIDValue, _ := c.Get("admin_id")
+	adminID, ok := adminIDValue.(uuid.UUID)
+	if !ok || h.audit == nil {
+		return
+	}
+	details := map[string]interface{}{
+		"campaign_id": campaign.ID.String(),
+		"name":        campaign.Name,
+		"variant":     campaign.Variant,
+		"is_active":   campaign.IsActive,
+	}
+	if err := h.audit.LogAction(c.Request.Context(), adminID, action, "dunning_campaign", nil, details); err != nil {
+		h.logger.Warn("Failed to audit dunning campaign change", zap.String("action", action), zap.Error(err))
+	}
+}
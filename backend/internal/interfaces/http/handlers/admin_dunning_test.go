@@ -0,0 +1,155 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/handlers"
+)
+
+type fakeDunningCampaignStore struct {
+	campaigns map[uuid.UUID]*entity.DunningCampaign
+	stats     []*entity.DunningCampaignStats
+}
+
+func (f *fakeDunningCampaignStore) Create(ctx context.Context, c *entity.DunningCampaign) error {
+	f.campaigns[c.ID] = c
+	return nil
+}
+
+func (f *fakeDunningCampaignStore) Update(ctx context.Context, c *entity.DunningCampaign) error {
+	f.campaigns[c.ID] = c
+	return nil
+}
+
+func (f *fakeDunningCampaignStore) GetByID(ctx context.Context, id uuid.UUID) (*entity.DunningCampaign, error) {
+	c, ok := f.campaigns[id]
+	if !ok {
+		return nil, fmt.Errorf("dunning campaign: %w", domainErrors.ErrNotFound)
+	}
+	copied := *c
+	return &copied, nil
+}
+
+func (f *fakeDunningCampaignStore) List(ctx context.Context, activeOnly bool) ([]*entity.DunningCampaign, error) {
+	var out []*entity.DunningCampaign
+	for _, c := range f.campaigns {
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+func (f *fakeDunningCampaignStore) RecoveryStats(ctx context.Context, since time.Time) ([]*entity.DunningCampaignStats, error) {
+	return f.stats, nil
+}
+
+func newAdminDunningRouter(h *handlers.AdminDunningHandler) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("admin_id", uuid.New())
+		c.Next()
+	})
+	r.GET("/v1/admin/dunning/campaigns", h.ListDunningCampaigns)
+	r.POST("/v1/admin/dunning/campaigns", h.CreateDunningCampaign)
+	r.PUT("/v1/admin/dunning/campaigns/:id", h.UpdateDunningCampaign)
+	r.GET("/v1/admin/dunning/recovery", h.GetDunningRecovery)
+	return r
+}
+
+func sendDunningJSON(r *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestAdminDunning_CreateCampaignAudits(t *testing.T) {
+	store := &fakeDunningCampaignStore{campaigns: map[uuid.UUID]*entity.DunningCampaign{}}
+	audit := &fakeAuditLogger{}
+	r := newAdminDunningRouter(handlers.NewAdminDunningHandler(store, audit, zap.NewNop()))
+
+	w := sendDunningJSON(r, http.MethodPost, "/v1/admin/dunning/campaigns",
+		`{"name":"fast","variant":"b","reminder_offsets_hours":[24,72,144]}`)
+	require.Equal(t, http.StatusCreated, w.Code, "body=%s", w.Body.String())
+
+	var body struct {
+		Data handlers.DunningCampaignResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, []int{24, 72, 144}, body.Data.ReminderOffsetsHours)
+	assert.Equal(t, 1, body.Data.Weight)
+	assert.True(t, body.Data.IsActive)
+	require.Len(t, store.campaigns, 1)
+	assert.Equal(t, []string{"create_dunning_campaign"}, audit.actions)
+}
+
+func TestAdminDunning_CreateRejectsUnorderedOffsets(t *testing.T) {
+	store := &fakeDunningCampaignStore{campaigns: map[uuid.UUID]*entity.DunningCampaign{}}
+	r := newAdminDunningRouter(handlers.NewAdminDunningHandler(store, &fakeAuditLogger{}, zap.NewNop()))
+
+	w := sendDunningJSON(r, http.MethodPost, "/v1/admin/dunning/campaigns",
+		`{"name":"bad","variant":"c","reminder_offsets_hours":[72,24]}`)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Empty(t, store.campaigns)
+}
+
+func TestAdminDunning_UpdateUnknownCampaign(t *testing.T) {
+	store := &fakeDunningCampaignStore{campaigns: map[uuid.UUID]*entity.DunningCampaign{}}
+	r := newAdminDunningRouter(handlers.NewAdminDunningHandler(store, &fakeAuditLogger{}, zap.NewNop()))
+
+	w := sendDunningJSON(r, http.MethodPut, "/v1/admin/dunning/campaigns/"+uuid.NewString(),
+		`{"name":"fast","variant":"b","reminder_offsets_hours":[24]}`)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestAdminDunning_RecoveryReportsRates(t *testing.T) {
+	campaignID := uuid.New()
+	store := &fakeDunningCampaignStore{stats: []*entity.DunningCampaignStats{
+		{CampaignID: &campaignID, Name: "fast", Variant: "b", Started: 10, Recovered: 4, Failed: 5, InProgress: 1},
+		{Name: "default", Started: 10, Recovered: 2, Failed: 8},
+	}}
+	r := newAdminDunningRouter(handlers.NewAdminDunningHandler(store, &fakeAuditLogger{}, zap.NewNop()))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/dunning/recovery?days=7", nil))
+	require.Equal(t, http.StatusOK, w.Code, "body=%s", w.Body.String())
+
+	var body struct {
+		Data struct {
+			Campaigns []handlers.DunningRecoveryRow `json:"campaigns"`
+			Total     handlers.DunningRecoveryRow   `json:"total"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.Len(t, body.Data.Campaigns, 2)
+	assert.InDelta(t, 0.4, body.Data.Campaigns[0].RecoveryRate, 1e-9)
+	assert.Equal(t, int64(20), body.Data.Total.Started)
+	assert.InDelta(t, 0.3, body.Data.Total.RecoveryRate, 1e-9)
+}
+
+func TestAdminDunning_RecoveryRejectsBadWindow(t *testing.T) {
+	r := newAdminDunningRouter(handlers.NewAdminDunningHandler(&fakeDunningCampaignStore{}, &fakeAuditLogger{}, zap.NewNop()))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/dunning/recovery?days=0", nil))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+type embedTokenManager interface {
+	Issue(ctx context.Context, appID uuid.UUID, spec service.EmbedTokenSpec, createdBy *uuid.UUID) (*entity.AnalyticsEmbedToken, string, error)
+	List(ctx context.Context, appID uuid.UUID) ([]*entity.AnalyticsEmbedToken, error)
+	Revoke(ctx context.Context, appID, id uuid.UUID) error
+}
+
+// AdminEmbedTokensHandler mints and revokes embedded analytics tokens
+type AdminEmbedTokensHandler struct {
+	tokens embedTokenManager
+}
+
+func NewAdminEmbedTokensHandler(tokens embedTokenManager) *AdminEmbedTokensHandler {
+	return &AdminEmbedTokensHandler{tokens: tokens}
+}
+
+type createEmbedTokenRequest struct {
+	Label              string                  `json:"label" binding:"max=200"`
+	Metrics            []entity.EmbedMetric    `json:"metrics" binding:"required,min=1,max=16"`
+	Dimensions         []entity.EmbedDimension `json:"dimensions" binding:"required,min=1,max=16"`
+	MaxRangeDays       int                     `json:"max_range_days"`
+	RateLimitPerMinute int                     `json:"rate_limit_per_minute"`
+	TTLSeconds         int                     `json:"ttl_seconds"`
+}
+
+// CreateEmbedToken POST /v1/admin/analytics/embed-tokens
+// The signed token is only returned here; it carries no scope itself, so
+// revoking the token ID revokes it.
+func (h *AdminEmbedTokensHandler) CreateEmbedToken(c *gin.Context) {
+	var req createEmbedTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+	adminID, _ := adminIDFromContext(c)
+	token, signed, err := h.tokens.Issue(c.Request.Context(), httpmiddleware.GetAppID(c), service.EmbedTokenSpec{
+		Label:              req.Label,
+		Metrics:            req.Metrics,
+		Dimensions:         req.Dimensions,
+		MaxRangeDays:       req.MaxRangeDays,
+		RateLimitPerMinute: req.RateLimitPerMinute,
+		TTL:                time.Duration(req.TTLSeconds) * time.Second,
+	}, adminID)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidEmbedTokenSpec) {
+			response.UnprocessableEntity(c, err.Error())
+			return
+		}
+		response.InternalError(c, "Failed to create embed token")
+		return
+	}
+	response.Created(c, gin.H{"token": signed, "embed_token": token})
+}
+
+// ListEmbedTokens GET /v1/admin/analytics/embed-tokens
+func (h *AdminEmbedTokensHandler) ListEmbedTokens(c *gin.Context) {
+	tokens, err := h.tokens.List(c.Request.Context(), httpmiddleware.GetAppID(c))
+	if err != nil {
+		response.InternalError(c, "Failed to list embed tokens")
+		return
+	}
+	response.OK(c, gin.H{"embed_tokens": tokens})
+}
+
+// RevokeEmbedToken DELETE /v1/admin/analytics/embed-tokens/:id
+func (h *AdminEmbedTokensHandler) RevokeEmbedToken(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid embed token ID")
+		return
+	}
+	if err := h.tokens.Revoke(c.Request.Context(), httpmiddleware.GetAppID(c), id); err != nil {
+		if errors.Is(err, service.ErrEmbedTokenNotFound) {
+			response.NotFound(c, "Embed token not found")
+			return
+		}
+		response.InternalError(c, "Failed to revoke embed token")
+		return
+	}
+	response.OK(c, gin.H{"revoked": true})
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+// GetAdminExperimentAppVersions GET /v1/admin/experiments/:id/app-versions
+// compares the experiment's arms within each app version users were assigned
+// on and flags versions where a winning variant regresses
+func (h *AdminHandler) GetAdminExperimentAppVersions(c *gin.Context) {
+	experimentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid experiment ID")
+		return
+	}
+	minUsers, err := strconv.Atoi(c.DefaultQuery("min_users", strconv.Itoa(service.DefaultAppVersionMinUsersPerArm)))
+	if err != nil || minUsers < 1 || minUsers > 1000000 {
+		response.BadRequest(c, "min_users must be between 1 and 1000000")
+		return
+	}
+	if h.experimentAppVersionService == nil {
+		response.InternalError(c, "Experiment service is unavailable")
+		return
+	}
+
+	report, err := h.experimentAppVersionService.Report(c.Request.Context(), experimentID, minUsers)
+	if err != nil {
+		if errors.Is(err, service.ErrExperimentNotFound) {
+			response.NotFound(c, "Experiment not found")
+			return
+		}
+		response.InternalError(c, "Failed to load experiment results by app version")
+		return
+	}
+	response.OK(c, report)
+}
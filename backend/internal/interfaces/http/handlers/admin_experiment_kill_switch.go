@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/infrastructure/cache"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+type experimentKillSwitch interface {
+	KillArm(ctx context.Context, armID uuid.UUID) error
+	ReviveArm(ctx context.Context, armID uuid.UUID) error
+	SetAllDisabled(ctx context.Context, disabled bool) error
+	State(ctx context.Context) (*cache.KillSwitchState, error)
+}
+
+type experimentArmLister interface {
+	GetArms(ctx context.Context, experimentID uuid.UUID) ([]service.Arm, error)
+}
+
+// AdminExperimentKillSwitchHandler pauses arms and disables all experiments
+// through the kill switch, which every API instance honours within seconds.
+// Whole experiments are paused with POST /experiments/:id/pause.
+type AdminExperimentKillSwitchHandler struct {
+	killSwitch experimentKillSwitch
+	arms       experimentArmLister
+	logger     *zap.Logger
+}
+
+func NewAdminExperimentKillSwitchHandler(killSwitch experimentKillSwitch, arms experimentArmLister, logger *zap.Logger) *AdminExperimentKillSwitchHandler {
+	return &AdminExperimentKillSwitchHandler{killSwitch: killSwitch, arms: arms, logger: logger}
+}
+
+// ExperimentArmSwitchResponse is an arm's kill switch state after a change
+type ExperimentArmSwitchResponse struct {
+	ExperimentID uuid.UUID `json:"experiment_id"`
+	ArmID        uuid.UUID `json:"arm_id"`
+	Paused       bool      `json:"paused"`
+}
+
+// UpdateKillSwitchRequest flips the emergency switch
+type UpdateKillSwitchRequest struct {
+	DisableAll *bool `json:"disable_all" binding:"required"`
+}
+
+// GetKillSwitch GET /v1/admin/experiments/kill-switch
+func (h *AdminExperimentKillSwitchHandler) GetKillSwitch(c *gin.Context) {
+	state, err := h.killSwitch.State(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to load experiment kill switch", zap.Error(err))
+		response.InternalError(c, "Failed to load experiment kill switch")
+		return
+	}
+	response.OK(c, state)
+}
+
+// UpdateKillSwitch PUT /v1/admin/experiments/kill-switch
+// disable_all stops arm selection for every experiment of every app; users
+// get the default experience until it is cleared.
+func (h *AdminExperimentKillSwitchHandler) UpdateKillSwitch(c *gin.Context) {
+	var req UpdateKillSwitchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "disable_all is required")
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.killSwitch.SetAllDisabled(ctx, *req.DisableAll); err != nil {
+		h.logger.Error("Failed to update experiment kill switch", zap.Error(err))
+		response.InternalError(c, "Failed to update experiment kill switch")
+		return
+	}
+	h.logger.Warn("Experiment kill switch changed",
+		zap.Bool("disable_all", *req.DisableAll),
+		zap.String("admin_id", c.GetString("user_id")),
+	)
+
+	state, err := h.killSwitch.State(ctx)
+	if err != nil {
+		h.logger.Error("Failed to load experiment kill switch", zap.Error(err))
+		response.InternalError(c, "Failed to load experiment kill switch")
+		return
+	}
+	response.OK(c, state)
+}
+
+// PauseExperimentArm POST /v1/admin/experiments/:id/arms/:arm_id/pause
+// Users on the arm are moved to another live arm on their next request.
+func (h *AdminExperimentKillSwitchHandler) PauseExperimentArm(c *gin.Context) {
+	h.switchArm(c, true)
+}
+
+// ResumeExperimentArm POST /v1/admin/experiments/:id/arms/:arm_id/resume
+func (h *AdminExperimentKillSwitchHandler) ResumeExperimentArm(c *gin.Context) {
+	h.switchArm(c, false)
+}
+
+func (h *AdminExperimentKillSwitchHandler) switchArm(c *gin.Context, paused bool) {
+	experimentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid experiment ID")
+		return
+	}
+	armID, err := uuid.Parse(c.Param("arm_id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid arm ID")
+		return
+	}
+
+	ctx := c.Request.Context()
+	arms, err := h.arms.GetArms(ctx, experimentID)
+	if err != nil {
+		h.logger.Error("Failed to load experiment arms", zap.String("experiment_id", experimentID.String()), zap.Error(err))
+		response.InternalError(c, "Failed to load experiment arms")
+		return
+	}
+	found := false
+	for _, arm := range arms {
+		found = found || arm.ID == armID
+	}
+	if !found {
+		response.NotFound(c, "Experiment arm not found")
+		return
+	}
+
+	if paused {
+		err = h.killSwitch.KillArm(ctx, armID)
+	} else {
+		err = h.killSwitch.ReviveArm(ctx, armID)
+	}
+	if err != nil {
+		h.logger.Error("Failed to update arm kill switch", zap.String("arm_id", armID.String()), zap.Error(err))
+		response.InternalError(c, "Failed to update arm kill switch")
+		return
+	}
+	h.logger.Info("Experiment arm kill switch changed",
+		zap.String("experiment_id", experimentID.String()),
+		zap.String("arm_id", armID.String()),
+		zap.Bool("paused", paused),
+		zap.String("admin_id", c.GetString("user_id")),
+	)
+	response.OK(c, ExperimentArmSwitchResponse{ExperimentID: experimentID, ArmID: armID, Paused: paused})
+}
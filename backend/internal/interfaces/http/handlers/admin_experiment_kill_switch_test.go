@@ -0,0 +1,115 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/infrastructure/cache"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/handlers"
+)
+
+type fakeKillSwitch struct {
+	all  bool
+	arms map[uuid.UUID]bool
+}
+
+func (f *fakeKillSwitch) KillArm(_ context.Context, armID uuid.UUID) error {
+	f.arms[armID] = true
+	return nil
+}
+
+func (f *fakeKillSwitch) ReviveArm(_ context.Context, armID uuid.UUID) error {
+	delete(f.arms, armID)
+	return nil
+}
+
+func (f *fakeKillSwitch) SetAllDisabled(_ context.Context, disabled bool) error {
+	f.all = disabled
+	return nil
+}
+
+func (f *fakeKillSwitch) State(context.Context) (*cache.KillSwitchState, error) {
+	state := &cache.KillSwitchState{AllDisabled: f.all, Experiments: []uuid.UUID{}, Arms: []uuid.UUID{}}
+	for id := range f.arms {
+		state.Arms = append(state.Arms, id)
+	}
+	return state, nil
+}
+
+type fakeExperimentArms map[uuid.UUID][]service.Arm
+
+func (f fakeExperimentArms) GetArms(_ context.Context, experimentID uuid.UUID) ([]service.Arm, error) {
+	return f[experimentID], nil
+}
+
+func newKillSwitchRouter(ks *fakeKillSwitch, arms fakeExperimentArms) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := handlers.NewAdminExperimentKillSwitchHandler(ks, arms, zap.NewNop())
+	r := gin.New()
+	r.GET("/v1/admin/experiments/kill-switch", h.GetKillSwitch)
+	r.PUT("/v1/admin/experiments/kill-switch", h.UpdateKillSwitch)
+	r.POST("/v1/admin/experiments/:id/arms/:arm_id/pause", h.PauseExperimentArm)
+	r.POST("/v1/admin/experiments/:id/arms/:arm_id/resume", h.ResumeExperimentArm)
+	return r
+}
+
+func TestExperimentKillSwitch_PauseAndResumeArm(t *testing.T) {
+	experimentID, armID := uuid.New(), uuid.New()
+	ks := &fakeKillSwitch{arms: map[uuid.UUID]bool{}}
+	router := newKillSwitchRouter(ks, fakeExperimentArms{experimentID: {{ID: armID}}})
+	path := "/v1/admin/experiments/" + experimentID.String() + "/arms/" + armID.String()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path+"/pause", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.True(t, ks.arms[armID])
+	var body struct {
+		Data handlers.ExperimentArmSwitchResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.True(t, body.Data.Paused)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path+"/resume", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.False(t, ks.arms[armID])
+
+	t.Run("arm of another experiment", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		other := "/v1/admin/experiments/" + uuid.NewString() + "/arms/" + armID.String() + "/pause"
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, other, nil))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.False(t, ks.arms[armID])
+	})
+}
+
+func TestExperimentKillSwitch_DisableAll(t *testing.T) {
+	ks := &fakeKillSwitch{arms: map[uuid.UUID]bool{}}
+	router := newKillSwitchRouter(ks, fakeExperimentArms{})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/v1/admin/experiments/kill-switch", strings.NewReader(`{"disable_all":true}`)))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.True(t, ks.all)
+	var body struct {
+		Data cache.KillSwitchState `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.True(t, body.Data.AllDisabled)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/v1/admin/experiments/kill-switch", strings.NewReader(`{}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.True(t, ks.all, "a body without disable_all must not clear the switch")
+}
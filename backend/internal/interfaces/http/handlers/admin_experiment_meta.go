@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+const maxExperimentMetaHistoryDays = 365
+
+// GetAdminExperimentMeta GET /v1/admin/experiments/meta?days=90 reports win
+// rate, average lift and revenue gained across completed experiments
+func (h *AdminHandler) GetAdminExperimentMeta(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "90"))
+	if err != nil || days < 1 || days > maxExperimentMetaHistoryDays {
+		response.BadRequest(c, "days must be between 1 and 365")
+		return
+	}
+	if h.experimentMetaService == nil {
+		response.InternalError(c, "Experiment service is unavailable")
+		return
+	}
+
+	report, err := h.experimentMetaService.Report(c.Request.Context(), httpmiddleware.GetAppID(c), days)
+	if err != nil {
+		response.InternalError(c, "Failed to load experiment meta-analytics")
+		return
+	}
+	response.OK(c, report)
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+type createExperimentFromTemplateRequest struct {
+	Name         string                         `json:"name"`
+	Description  string                         `json:"description"`
+	StartAt      *time.Time                     `json:"start_at"`
+	EndAt        *time.Time                     `json:"end_at"`
+	Eligibility  *service.ExperimentEligibility `json:"eligibility,omitempty"`
+	PricingTiers map[string]uuid.UUID           `json:"pricing_tiers,omitempty"`
+	Discounts    map[string]service.ArmDiscount `json:"discounts,omitempty"`
+	Payloads     map[string]service.ArmPayload  `json:"payloads,omitempty"`
+}
+
+type cloneAdminExperimentRequest struct {
+	Name string `json:"name"`
+}
+
+// ListAdminExperimentTemplates returns the built-in experiment templates
+func (h *AdminHandler) ListAdminExperimentTemplates(c *gin.Context) {
+	response.OK(c, service.ExperimentTemplates())
+}
+
+// CreateAdminExperimentFromTemplate creates a draft experiment from a template
+func (h *AdminHandler) CreateAdminExperimentFromTemplate(c *gin.Context) {
+	var req createExperimentFromTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid experiment template payload")
+		return
+	}
+	if containsNullByte(req.Name) || containsControlCharacter(req.Name) {
+		response.UnprocessableEntity(c, "Experiment name cannot contain control characters")
+		return
+	}
+	if containsNullByte(req.Description) {
+		response.UnprocessableEntity(c, "Experiment description cannot contain null bytes")
+		return
+	}
+	if req.StartAt != nil && req.EndAt != nil && req.EndAt.Before(*req.StartAt) {
+		response.UnprocessableEntity(c, "End time must be after start time")
+		return
+	}
+	if h.experimentTemplateService == nil {
+		response.InternalError(c, "Experiment service is unavailable")
+		return
+	}
+
+	experimentID, err := h.experimentTemplateService.CreateFromTemplate(c.Request.Context(), httpmiddleware.GetAppID(c), c.Param("key"), service.ExperimentFromTemplateInput{
+		Name:         req.Name,
+		Description:  req.Description,
+		StartAt:      req.StartAt,
+		EndAt:        req.EndAt,
+		Eligibility:  req.Eligibility,
+		PricingTiers: req.PricingTiers,
+		Discounts:    req.Discounts,
+		Payloads:     req.Payloads,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrExperimentTemplateNotFound):
+			response.NotFound(c, "Experiment template not found")
+		case errors.Is(err, service.ErrExperimentTemplateArm), errors.Is(err, service.ErrInvalidArmDiscount), errors.Is(err, service.ErrInvalidArmPayload),
+			errors.Is(err, service.ErrInvalidExperimentEligibility):
+			response.UnprocessableEntity(c, err.Error())
+		case errors.Is(err, service.ErrPricingTierNotFound):
+			response.UnprocessableEntity(c, "Linked pricing tier not found")
+		default:
+			response.InternalError(c, "Failed to create experiment from template")
+		}
+		return
+	}
+	h.logExperimentCreatedFrom(c, experimentID, "create_experiment_from_template", map[string]interface{}{"template": c.Param("key")})
+
+	experiment, err := h.getAdminExperimentByID(c, experimentID)
+	if err != nil {
+		response.InternalError(c, "Failed to load created experiment")
+		return
+	}
+	response.Created(c, experiment)
+}
+
+// CloneAdminExperiment copies an experiment into a new draft
+func (h *AdminHandler) CloneAdminExperiment(c *gin.Context) {
+	experimentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid experiment ID")
+		return
+	}
+	var req cloneAdminExperimentRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, "Invalid clone payload")
+			return
+		}
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if containsNullByte(req.Name) || containsControlCharacter(req.Name) {
+		response.UnprocessableEntity(c, "Experiment name cannot contain control characters")
+		return
+	}
+	if h.experimentTemplateService == nil {
+		response.InternalError(c, "Experiment service is unavailable")
+		return
+	}
+
+	cloneID, err := h.experimentTemplateService.CloneExperiment(c.Request.Context(), httpmiddleware.GetAppID(c), experimentID, req.Name)
+	if err != nil {
+		if errors.Is(err, service.ErrExperimentNotFound) {
+			response.NotFound(c, "Experiment not found")
+			return
+		}
+		response.InternalError(c, "Failed to clone experiment")
+		return
+	}
+	h.logExperimentCreatedFrom(c, cloneID, "clone_experiment", map[string]interface{}{"source_experiment_id": experimentID.String()})
+
+	experiment, err := h.getAdminExperimentByID(c, cloneID)
+	if err != nil {
+		response.InternalError(c, "Failed to load cloned experiment")
+		return
+	}
+	response.Created(c, experiment)
+}
+
+func (h *AdminHandler) logExperimentCreatedFrom(c *gin.Context, experimentID uuid.UUID, action string, details map[string]interface{}) {
+	if h.auditService == nil {
+		return
+	}
+	adminID, ok := adminIDFromContext(c)
+	if !ok {
+		return
+	}
+	details["experiment_id"] = experimentID.String()
+	_ = h.auditService.LogAction(c.Request.Context(), *adminID, action, "experiment", nil, details)
+}
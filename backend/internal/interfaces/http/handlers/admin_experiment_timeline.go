@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+const maxExperimentTimelineDays = 365
+
+// GetAdminExperimentTimeline GET /v1/admin/experiments/:id/timeline?days=30
+// returns each arm's daily exposures, conversions, revenue and win
+// probability as recorded by the nightly timeline job
+func (h *AdminHandler) GetAdminExperimentTimeline(c *gin.Context) {
+	experimentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid experiment ID")
+		return
+	}
+	days, err := strconv.Atoi(c.DefaultQuery("days", strconv.Itoa(service.DefaultExperimentTimelineDays)))
+	if err != nil || days < 1 || days > maxExperimentTimelineDays {
+		response.BadRequest(c, "days must be between 1 and 365")
+		return
+	}
+	if h.experimentTimelineService == nil {
+		response.InternalError(c, "Experiment service is unavailable")
+		return
+	}
+
+	timeline, err := h.experimentTimelineService.Timeline(c.Request.Context(), httpmiddleware.GetAppID(c), experimentID, days)
+	if err != nil {
+		if errors.Is(err, service.ErrExperimentNotFound) {
+			response.NotFound(c, "Experiment not found")
+			return
+		}
+		response.InternalError(c, "Failed to load experiment timeline")
+		return
+	}
+	response.OK(c, timeline)
+}
@@ -12,10 +12,14 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
 
 	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/infrastructure/cache"
+	"github.com/bivex/paywall-iap/internal/infrastructure/logging"
 	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
 	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+	"github.com/bivex/paywall-iap/internal/worker/tasks"
 )
 
 const (
@@ -34,6 +38,12 @@ type AdminExperimentArm struct {
 	Conversions   int        `json:"conversions"`
 	Revenue       float64    `json:"revenue"`
 	AvgReward     float64    `json:"avg_reward"`
+	ArchivedAt    *time.Time `json:"archived_at,omitempty"`
+	// ReassignmentPolicy and ArchiveReason are set for archived arms
+	ReassignmentPolicy string               `json:"reassignment_policy,omitempty"`
+	ArchiveReason      string               `json:"archive_reason,omitempty"`
+	Discount           *service.ArmDiscount `json:"discount,omitempty"`
+	Payload            *service.ArmPayload  `json:"payload,omitempty"`
 }
 
 type AdminExperiment struct {
@@ -41,6 +51,8 @@ type AdminExperiment struct {
 	Name                       string                             `json:"name"`
 	Description                string                             `json:"description"`
 	Status                     string                             `json:"status"`
+	Category                   string                             `json:"category,omitempty"`
+	Eligibility                *service.ExperimentEligibility     `json:"eligibility,omitempty"`
 	AlgorithmType              *string                            `json:"algorithm_type"`
 	IsBandit                   bool                               `json:"is_bandit"`
 	MinSampleSize              int                                `json:"min_sample_size"`
@@ -50,6 +62,8 @@ type AdminExperiment struct {
 	StartAt                    *time.Time                         `json:"start_at"`
 	EndAt                      *time.Time                         `json:"end_at"`
 	AutomationPolicy           service.ExperimentAutomationPolicy `json:"automation_policy"`
+	RevenueGuardrail           *service.RevenueGuardrail          `json:"revenue_guardrail,omitempty"`
+	DiscountImpact             *service.DiscountGuardrailReport   `json:"discount_impact,omitempty"`
 	LatestLifecycleAudit       *AdminExperimentLifecycleAudit     `json:"latest_lifecycle_audit,omitempty"`
 	CreatedAt                  time.Time                          `json:"created_at"`
 	UpdatedAt                  time.Time                          `json:"updated_at"`
@@ -154,16 +168,19 @@ func scanAdminExperimentWinnerRecommendationAudit(scanner interface{ Scan(dest .
 }
 
 type createAdminExperimentArmRequest struct {
-	Name          string     `json:"name"`
-	Description   *string    `json:"description"`
-	IsControl     bool       `json:"is_control"`
-	TrafficWeight float64    `json:"traffic_weight"`
-	PricingTierID *uuid.UUID `json:"pricing_tier_id,omitempty"`
+	Name          string               `json:"name"`
+	Description   *string              `json:"description"`
+	IsControl     bool                 `json:"is_control"`
+	TrafficWeight float64              `json:"traffic_weight"`
+	PricingTierID *uuid.UUID           `json:"pricing_tier_id,omitempty"`
+	Discount      *service.ArmDiscount `json:"discount,omitempty"`
+	Payload       *service.ArmPayload  `json:"payload,omitempty"`
 }
 
 type createAdminExperimentRequest struct {
 	Name                       string                              `json:"name"`
 	Description                *string                             `json:"description"`
+	Category                   string                              `json:"category,omitempty"`
 	Status                     string                              `json:"status"`
 	AlgorithmType              *string                             `json:"algorithm_type"`
 	IsBandit                   *bool                               `json:"is_bandit"`
@@ -172,12 +189,15 @@ type createAdminExperimentRequest struct {
 	StartAt                    *time.Time                          `json:"start_at"`
 	EndAt                      *time.Time                          `json:"end_at"`
 	AutomationPolicy           *service.ExperimentAutomationPolicy `json:"automation_policy,omitempty"`
+	RevenueGuardrail           *service.RevenueGuardrail           `json:"revenue_guardrail,omitempty"`
+	Eligibility                *service.ExperimentEligibility      `json:"eligibility,omitempty"`
 	Arms                       []createAdminExperimentArmRequest   `json:"arms"`
 }
 
 type updateAdminExperimentRequest struct {
 	Name                       string                              `json:"name"`
 	Description                *string                             `json:"description"`
+	Category                   string                              `json:"category,omitempty"`
 	AlgorithmType              *string                             `json:"algorithm_type"`
 	IsBandit                   *bool                               `json:"is_bandit"`
 	MinSampleSize              int                                 `json:"min_sample_size"`
@@ -185,16 +205,20 @@ type updateAdminExperimentRequest struct {
 	StartAt                    *time.Time                          `json:"start_at"`
 	EndAt                      *time.Time                          `json:"end_at"`
 	AutomationPolicy           *service.ExperimentAutomationPolicy `json:"automation_policy,omitempty"`
+	RevenueGuardrail           *service.RevenueGuardrail           `json:"revenue_guardrail,omitempty"`
+	Eligibility                *service.ExperimentEligibility      `json:"eligibility,omitempty"`
 	Arms                       []updateAdminExperimentArmRequest   `json:"arms,omitempty"`
 }
 
 type updateAdminExperimentArmRequest struct {
-	ID            *uuid.UUID `json:"id,omitempty"`
-	Name          string     `json:"name"`
-	Description   *string    `json:"description"`
-	IsControl     bool       `json:"is_control"`
-	TrafficWeight float64    `json:"traffic_weight"`
-	PricingTierID *uuid.UUID `json:"pricing_tier_id,omitempty"`
+	ID            *uuid.UUID           `json:"id,omitempty"`
+	Name          string               `json:"name"`
+	Description   *string              `json:"description"`
+	IsControl     bool                 `json:"is_control"`
+	TrafficWeight float64              `json:"traffic_weight"`
+	PricingTierID *uuid.UUID           `json:"pricing_tier_id,omitempty"`
+	Discount      *service.ArmDiscount `json:"discount,omitempty"`
+	Payload       *service.ArmPayload  `json:"payload,omitempty"`
 }
 
 type updateAdminExperimentArmPricingTierRequest struct {
@@ -311,6 +335,7 @@ func normalizeCreateAdminExperimentRequest(req createAdminExperimentRequest) cre
 	}
 	normalizedPolicy := service.NormalizeExperimentAutomationPolicy(req.AutomationPolicy)
 	req.AutomationPolicy = &normalizedPolicy
+	req.Eligibility = normalizeAdminExperimentEligibility(req.Eligibility)
 	return req
 }
 
@@ -324,9 +349,18 @@ func normalizeUpdateAdminExperimentRequest(req updateAdminExperimentRequest) upd
 	}
 	normalizedPolicy := service.NormalizeExperimentAutomationPolicy(req.AutomationPolicy)
 	req.AutomationPolicy = &normalizedPolicy
+	req.Eligibility = normalizeAdminExperimentEligibility(req.Eligibility)
 	return req
 }
 
+func normalizeAdminExperimentEligibility(eligibility *service.ExperimentEligibility) *service.ExperimentEligibility {
+	if eligibility == nil {
+		return nil
+	}
+	normalized := eligibility.Normalize()
+	return &normalized
+}
+
 func normalizeOptionalTrimmedString(value *string) *string {
 	if value == nil {
 		return nil
@@ -691,6 +725,12 @@ func validateCreateAdminExperimentRequest(req createAdminExperimentRequest) stri
 		if arm.TrafficWeight <= 0 {
 			return "Traffic weight must be greater than zero"
 		}
+		if message := validateAdminExperimentArmDiscount(arm.IsControl, arm.Discount); message != "" {
+			return message
+		}
+		if message := validateAdminExperimentArmPayload(arm.Payload); message != "" {
+			return message
+		}
 		if arm.IsControl {
 			controlCount++
 		}
@@ -698,6 +738,15 @@ func validateCreateAdminExperimentRequest(req createAdminExperimentRequest) stri
 	if controlCount != 1 {
 		return "Exactly one control arm is required"
 	}
+	if message := validateAdminExperimentRevenueGuardrail(req.RevenueGuardrail); message != "" {
+		return message
+	}
+	if message := validateAdminExperimentCategory(req.Category); message != "" {
+		return message
+	}
+	if message := validateAdminExperimentEligibility(req.Eligibility); message != "" {
+		return message
+	}
 	switch *req.AlgorithmType {
 	case "thompson_sampling", "ucb", "epsilon_greedy":
 	default:
@@ -748,6 +797,15 @@ func validateUpdateAdminExperimentRequest(req updateAdminExperimentRequest) stri
 	default:
 		return "Algorithm type must be thompson_sampling, ucb, or epsilon_greedy"
 	}
+	if message := validateAdminExperimentRevenueGuardrail(req.RevenueGuardrail); message != "" {
+		return message
+	}
+	if message := validateAdminExperimentCategory(req.Category); message != "" {
+		return message
+	}
+	if message := validateAdminExperimentEligibility(req.Eligibility); message != "" {
+		return message
+	}
 	if req.Arms != nil {
 		if len(req.Arms) < 2 {
 			return "At least two experiment arms are required"
@@ -773,6 +831,12 @@ func validateUpdateAdminExperimentRequest(req updateAdminExperimentRequest) stri
 			if arm.TrafficWeight <= 0 {
 				return "Traffic weight must be greater than zero"
 			}
+			if message := validateAdminExperimentArmDiscount(arm.IsControl, arm.Discount); message != "" {
+				return message
+			}
+			if message := validateAdminExperimentArmPayload(arm.Payload); message != "" {
+				return message
+			}
 			if arm.ID != nil {
 				if _, exists := seenIDs[*arm.ID]; exists {
 					return "Each persisted experiment arm may only appear once"
@@ -803,11 +867,63 @@ func experimentArmInputsFromUpdateRequest(arms []updateAdminExperimentArmRequest
 			IsControl:     arm.IsControl,
 			TrafficWeight: arm.TrafficWeight,
 			PricingTierID: arm.PricingTierID,
+			Discount:      arm.Discount,
+			Payload:       arm.Payload,
 		})
 	}
 	return result
 }
 
+func validateAdminExperimentArmDiscount(isControl bool, discount *service.ArmDiscount) string {
+	if discount == nil {
+		return ""
+	}
+	if isControl {
+		return "The control arm cannot carry a discount"
+	}
+	if err := discount.Validate(); err != nil {
+		return "Arm discount: " + strings.TrimPrefix(err.Error(), service.ErrInvalidArmDiscount.Error()+": ")
+	}
+	return ""
+}
+
+func validateAdminExperimentArmPayload(payload *service.ArmPayload) string {
+	if payload == nil {
+		return ""
+	}
+	if err := payload.Validate(); err != nil {
+		return "Arm payload: " + strings.TrimPrefix(err.Error(), service.ErrInvalidArmPayload.Error()+": ")
+	}
+	return ""
+}
+
+func validateAdminExperimentRevenueGuardrail(guardrail *service.RevenueGuardrail) string {
+	if guardrail == nil {
+		return ""
+	}
+	if err := guardrail.Validate(); err != nil {
+		return "Revenue guardrail: " + strings.TrimPrefix(err.Error(), service.ErrInvalidRevenueGuardrail.Error()+": ")
+	}
+	return ""
+}
+
+func validateAdminExperimentEligibility(eligibility *service.ExperimentEligibility) string {
+	if eligibility == nil {
+		return ""
+	}
+	if err := eligibility.Validate(); err != nil {
+		return "Experiment eligibility: " + strings.TrimPrefix(err.Error(), service.ErrInvalidExperimentEligibility.Error()+": ")
+	}
+	return ""
+}
+
+func validateAdminExperimentCategory(category string) string {
+	if _, err := service.NormalizeExperimentCategory(category); err != nil {
+		return "Experiment category: " + strings.TrimPrefix(err.Error(), service.ErrInvalidExperimentCategory.Error()+": ")
+	}
+	return ""
+}
+
 func validateUpdateAdminExperimentArmPricingTiersRequest(req updateAdminExperimentArmPricingTiersRequest) string {
 	if len(req.Arms) == 0 {
 		return "At least one arm pricing tier update is required"
@@ -932,6 +1048,7 @@ func scanAdminExperimentArm(scanner interface{ Scan(dest ...any) error }) (Admin
 	var arm AdminExperimentArm
 	var description sql.NullString
 	var pricingTierID uuid.NullUUID
+	var discountJSON, payloadJSON []byte
 	err := scanner.Scan(
 		&arm.ID,
 		&arm.Name,
@@ -943,10 +1060,27 @@ func scanAdminExperimentArm(scanner interface{ Scan(dest ...any) error }) (Admin
 		&arm.Conversions,
 		&arm.Revenue,
 		&arm.AvgReward,
+		&arm.ArchivedAt,
+		&arm.ReassignmentPolicy,
+		&arm.ArchiveReason,
+		&discountJSON,
+		&payloadJSON,
 	)
 	if err != nil {
 		return AdminExperimentArm{}, err
 	}
+	if len(payloadJSON) > 0 {
+		arm.Payload = new(service.ArmPayload)
+		if err := json.Unmarshal(payloadJSON, arm.Payload); err != nil {
+			return AdminExperimentArm{}, err
+		}
+	}
+	if len(discountJSON) > 0 {
+		arm.Discount = new(service.ArmDiscount)
+		if err := json.Unmarshal(discountJSON, arm.Discount); err != nil {
+			return AdminExperimentArm{}, err
+		}
+	}
 	if description.Valid {
 		arm.Description = description.String
 	}
@@ -1059,6 +1193,20 @@ func (h *AdminHandler) hasExperimentArmPricingTierColumn(c *gin.Context) bool {
 	return h.hasColumn(c.Request.Context(), "ab_test_arms", "pricing_tier_id")
 }
 
+func (h *AdminHandler) hasExperimentArmArchiveColumns(c *gin.Context) bool {
+	return h.hasColumn(c.Request.Context(), "ab_test_arms", "archived_at")
+}
+
+func (h *AdminHandler) hasExperimentDiscountColumns(c *gin.Context) bool {
+	return h.hasColumn(c.Request.Context(), "ab_test_arms", "discount")
+}
+
+func (h *AdminHandler) hasExperimentArmPayloadColumn(c *gin.Context) bool {
+	return h.hasColumn(c.Request.Context(), "ab_test_arms", "payload")
+}
+
+// adminExperimentListQuery lists the app's experiments ($1), archived ones
+// only when $2 is true
 func adminExperimentListQuery(withAssignments bool, withLifecycleAudit bool, withAutomationPolicy bool) string {
 	lifecycleColumns := adminExperimentSelectLatestLifecycleMissing
 	lifecycleJoin := ""
@@ -1072,11 +1220,11 @@ func adminExperimentListQuery(withAssignments bool, withLifecycleAudit bool, wit
 	}
 	if withAssignments {
 		return adminExperimentSelectBase + automationPolicyColumns + lifecycleColumns + adminExperimentSelectMeta + adminExperimentSelectWithAssignments + adminExperimentSelectFrom + lifecycleJoin + `
-		WHERE e.app_id = $1
+		WHERE e.app_id = $1 AND ($2 OR e.status <> 'archived')
 		ORDER BY e.created_at DESC`
 	}
 	return adminExperimentSelectBase + automationPolicyColumns + lifecycleColumns + adminExperimentSelectMeta + adminExperimentSelectStatsOnly + adminExperimentSelectFrom + lifecycleJoin + `
-		WHERE e.app_id = $1
+		WHERE e.app_id = $1 AND ($2 OR e.status <> 'archived')
 		ORDER BY e.created_at DESC`
 }
 
@@ -1104,6 +1252,18 @@ func (h *AdminHandler) listExperimentArms(ctx *gin.Context, experimentID uuid.UU
 	if h.hasExperimentArmPricingTierColumn(ctx) {
 		pricingTierSelect = `a.pricing_tier_id`
 	}
+	archiveSelect := `NULL::timestamptz, ''`
+	if h.hasExperimentArmArchiveColumns(ctx) {
+		archiveSelect = `a.archived_at, COALESCE(a.reassignment_policy, '')`
+	}
+	discountSelect := `'', NULL::jsonb`
+	if h.hasExperimentDiscountColumns(ctx) {
+		discountSelect = `COALESCE(a.archive_reason, ''), a.discount`
+	}
+	payloadSelect := `NULL::jsonb`
+	if h.hasExperimentArmPayloadColumn(ctx) {
+		payloadSelect = `a.payload`
+	}
 	rows, err := h.dbPool.Query(ctx.Request.Context(), `
 		SELECT a.id,
 		       a.name,
@@ -1114,7 +1274,10 @@ func (h *AdminHandler) listExperimentArms(ctx *gin.Context, experimentID uuid.UU
 		       COALESCE(s.samples, 0)::int,
 		       COALESCE(s.conversions, 0)::int,
 		       COALESCE(s.revenue, 0)::double precision,
-		       COALESCE(s.avg_reward, 0)::double precision
+		       COALESCE(s.avg_reward, 0)::double precision,
+		       `+archiveSelect+`,
+		       `+discountSelect+`,
+		       `+payloadSelect+`
 		FROM ab_test_arms a
 		LEFT JOIN ab_test_arm_stats s ON s.arm_id = a.id
 		WHERE a.experiment_id = $1
@@ -1151,9 +1314,75 @@ func (h *AdminHandler) getAdminExperimentByID(c *gin.Context, experimentID uuid.
 	if err := h.enrichWinnerRecommendation(c.Request.Context(), &experiment, "admin_experiments_detail"); err != nil {
 		return AdminExperiment{}, err
 	}
+	if err := h.enrichDiscountImpact(c, &experiment); err != nil {
+		return AdminExperiment{}, err
+	}
+	if err := h.enrichExperimentSettings(c, &experiment); err != nil {
+		return AdminExperiment{}, err
+	}
 	return experiment, nil
 }
 
+// enrichExperimentSettings loads the experiment's category and eligibility
+func (h *AdminHandler) enrichExperimentSettings(c *gin.Context, experiment *AdminExperiment) error {
+	ctx := c.Request.Context()
+	categorySelect := `''`
+	if h.hasColumn(ctx, "ab_tests", "category") {
+		categorySelect = `COALESCE(category, '')`
+	}
+	eligibilitySelect := `NULL::jsonb`
+	if h.hasColumn(ctx, "ab_tests", "eligibility") {
+		eligibilitySelect = `eligibility`
+	}
+	var eligibilityJSON []byte
+	if err := h.dbPool.QueryRow(ctx, `
+		SELECT `+categorySelect+`, `+eligibilitySelect+` FROM ab_tests WHERE id = $1`, experiment.ID).Scan(&experiment.Category, &eligibilityJSON); err != nil {
+		return err
+	}
+	if len(eligibilityJSON) > 0 {
+		experiment.Eligibility = new(service.ExperimentEligibility)
+		if err := json.Unmarshal(eligibilityJSON, experiment.Eligibility); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// enrichDiscountImpact loads the experiment's revenue guardrail and reports
+// how its discount arms compare with control
+func (h *AdminHandler) enrichDiscountImpact(c *gin.Context, experiment *AdminExperiment) error {
+	if !h.hasExperimentDiscountColumns(c) {
+		return nil
+	}
+	var guardrailJSON []byte
+	if err := h.dbPool.QueryRow(c.Request.Context(), `
+		SELECT revenue_guardrail FROM ab_tests WHERE id = $1`, experiment.ID).Scan(&guardrailJSON); err != nil {
+		return err
+	}
+	if len(guardrailJSON) > 0 {
+		experiment.RevenueGuardrail = new(service.RevenueGuardrail)
+		if err := json.Unmarshal(guardrailJSON, experiment.RevenueGuardrail); err != nil {
+			return err
+		}
+	}
+
+	arms := make([]service.DiscountGuardrailArm, 0, len(experiment.Arms))
+	for _, arm := range experiment.Arms {
+		arms = append(arms, service.DiscountGuardrailArm{
+			ArmID:         arm.ID,
+			IsControl:     arm.IsControl,
+			Discount:      arm.Discount,
+			Samples:       arm.Samples,
+			Conversions:   arm.Conversions,
+			Revenue:       arm.Revenue,
+			Archived:      arm.ArchivedAt != nil,
+			ArchiveReason: arm.ArchiveReason,
+		})
+	}
+	experiment.DiscountImpact = service.EvaluateDiscountGuardrail(experiment.RevenueGuardrail, arms)
+	return nil
+}
+
 func (h *AdminHandler) enrichWinnerRecommendation(ctx context.Context, experiment *AdminExperiment, source ...string) error {
 	if experiment == nil || h.winnerRecommendationService == nil {
 		return nil
@@ -1312,12 +1541,15 @@ func (h *AdminHandler) GetAdminExperimentWinnerRecommendationAuditHistory(c *gin
 	response.OK(c, history)
 }
 
+// ListAdminExperiments GET /v1/admin/experiments
+// Archived experiments are left out unless include_archived=true.
 func (h *AdminHandler) ListAdminExperiments(c *gin.Context) {
 	appID := httpmiddleware.GetAppID(c)
+	includeArchived := c.Query("include_archived") == "true"
 	withAssignments := h.hasAssignmentTable(c)
 	withLifecycleAudit := h.hasLifecycleAuditTable(c)
 	withAutomationPolicy := h.hasExperimentAutomationPolicyColumn(c)
-	rows, err := h.dbPool.Query(c.Request.Context(), adminExperimentListQuery(withAssignments, withLifecycleAudit, withAutomationPolicy), appID)
+	rows, err := h.dbPool.Query(c.Request.Context(), adminExperimentListQuery(withAssignments, withLifecycleAudit, withAutomationPolicy), appID, includeArchived)
 	if err != nil {
 		response.InternalError(c, "Failed to load experiments")
 		return
@@ -1341,6 +1573,14 @@ func (h *AdminHandler) ListAdminExperiments(c *gin.Context) {
 			response.InternalError(c, "Failed to load experiments")
 			return
 		}
+		if err := h.enrichDiscountImpact(c, &experiment); err != nil {
+			response.InternalError(c, "Failed to load experiments")
+			return
+		}
+		if err := h.enrichExperimentSettings(c, &experiment); err != nil {
+			response.InternalError(c, "Failed to load experiments")
+			return
+		}
 		experiments = append(experiments, experiment)
 	}
 	if rows.Err() != nil {
@@ -1381,14 +1621,34 @@ func (h *AdminHandler) CreateAdminExperiment(c *gin.Context) {
 		response.InternalError(c, "Failed to encode experiment automation policy")
 		return
 	}
+	var revenueGuardrailJSON []byte
+	if req.RevenueGuardrail != nil {
+		if revenueGuardrailJSON, err = json.Marshal(req.RevenueGuardrail); err != nil {
+			response.InternalError(c, "Failed to encode experiment revenue guardrail")
+			return
+		}
+	}
+
+	var category *string
+	if normalized, _ := service.NormalizeExperimentCategory(req.Category); normalized != "" {
+		category = &normalized
+	}
+	var eligibilityJSON []byte
+	if req.Eligibility != nil {
+		if eligibilityJSON, err = json.Marshal(req.Eligibility); err != nil {
+			response.InternalError(c, "Failed to encode experiment eligibility")
+			return
+		}
+	}
 
 	appID := httpmiddleware.GetAppID(c)
 	_, err = tx.Exec(ctx, `
 		INSERT INTO ab_tests (
 			id, app_id, name, description, status, start_at, end_at,
-			algorithm_type, is_bandit, min_sample_size, confidence_threshold, automation_policy
+			algorithm_type, is_bandit, min_sample_size, confidence_threshold, automation_policy,
+			revenue_guardrail, category, eligibility
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`,
 		experimentID,
 		appID,
 		req.Name,
@@ -1401,6 +1661,9 @@ func (h *AdminHandler) CreateAdminExperiment(c *gin.Context) {
 		req.MinSampleSize,
 		req.ConfidenceThresholdPercent/100,
 		automationPolicyJSON,
+		revenueGuardrailJSON,
+		category,
+		eligibilityJSON,
 	)
 	if err != nil {
 		response.InternalError(c, "Failed to create experiment")
@@ -1417,9 +1680,22 @@ func (h *AdminHandler) CreateAdminExperiment(c *gin.Context) {
 	}
 
 	for _, arm := range req.Arms {
+		var discountJSON, payloadJSON []byte
+		if arm.Discount != nil {
+			if discountJSON, err = json.Marshal(arm.Discount); err != nil {
+				response.InternalError(c, "Failed to encode experiment arm discount")
+				return
+			}
+		}
+		if arm.Payload != nil {
+			if payloadJSON, err = json.Marshal(arm.Payload); err != nil {
+				response.InternalError(c, "Failed to encode experiment arm payload")
+				return
+			}
+		}
 		_, err = tx.Exec(ctx, `
-				INSERT INTO ab_test_arms (id, experiment_id, name, description, is_control, traffic_weight, pricing_tier_id)
-				VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+				INSERT INTO ab_test_arms (id, experiment_id, name, description, is_control, traffic_weight, pricing_tier_id, discount, payload)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
 			uuid.New(),
 			experimentID,
 			arm.Name,
@@ -1427,6 +1703,8 @@ func (h *AdminHandler) CreateAdminExperiment(c *gin.Context) {
 			arm.IsControl,
 			arm.TrafficWeight,
 			arm.PricingTierID,
+			discountJSON,
+			payloadJSON,
 		)
 		if err != nil {
 			response.InternalError(c, "Failed to create experiment arms")
@@ -1494,6 +1772,7 @@ func (h *AdminHandler) UpdateAdminExperiment(c *gin.Context) {
 		return
 	}
 
+	category, _ := service.NormalizeExperimentCategory(req.Category)
 	err = h.experimentAdminService.UpdateDraftExperiment(c.Request.Context(), experimentID, service.UpdateExperimentInput{
 		Name:                req.Name,
 		Description:         *req.Description,
@@ -1504,6 +1783,9 @@ func (h *AdminHandler) UpdateAdminExperiment(c *gin.Context) {
 		StartAt:             req.StartAt,
 		EndAt:               req.EndAt,
 		AutomationPolicy:    service.NormalizeExperimentAutomationPolicy(req.AutomationPolicy),
+		RevenueGuardrail:    req.RevenueGuardrail,
+		Category:            category,
+		Eligibility:         req.Eligibility,
 		Arms:                experimentArmInputsFromUpdateRequest(req.Arms),
 	})
 	if err != nil {
@@ -1662,6 +1944,143 @@ func (h *AdminHandler) UpdateAdminExperimentArmPricingTiers(c *gin.Context) {
 	response.OK(c, updatedExperiment)
 }
 
+type archiveAdminExperimentArmRequest struct {
+	ReassignmentPolicy string `json:"reassignment_policy"`
+}
+
+// ArchiveAdminExperimentArm retires an arm of a running or paused experiment
+// and queues the migration of its users under the chosen policy
+func (h *AdminHandler) ArchiveAdminExperimentArm(c *gin.Context) {
+	experimentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid experiment ID")
+		return
+	}
+	armID, err := uuid.Parse(c.Param("arm_id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid arm ID")
+		return
+	}
+
+	var req archiveAdminExperimentArmRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid arm archive payload")
+		return
+	}
+	policy, err := service.ParseArmReassignmentPolicy(strings.TrimSpace(req.ReassignmentPolicy))
+	if err != nil {
+		response.UnprocessableEntity(c, "reassignment_policy must be keep, reassign or exclude")
+		return
+	}
+	if h.experimentAdminService == nil {
+		response.InternalError(c, "Experiment service is unavailable")
+		return
+	}
+
+	if err := h.experimentAdminService.ArchiveExperimentArm(c.Request.Context(), experimentID, armID, policy); err != nil {
+		switch {
+		case errors.Is(err, service.ErrExperimentNotFound):
+			response.NotFound(c, "Experiment not found")
+		case errors.Is(err, service.ErrExperimentArmNotFound):
+			response.NotFound(c, "Experiment arm not found")
+		case errors.Is(err, service.ErrExperimentArmArchived):
+			response.Conflict(c, "Experiment arm is already archived")
+		case errors.Is(err, service.ErrExperimentArmNotArchivable), errors.Is(err, service.ErrLastExperimentArm):
+			response.UnprocessableEntity(c, err.Error())
+		default:
+			response.InternalError(c, "Failed to archive experiment arm")
+		}
+		return
+	}
+
+	// The scheduled sweep moves the users if this enqueue fails
+	if policy != service.ArmReassignmentKeep && h.asynqClient != nil {
+		_, _ = h.asynqClient.Enqueue(tasks.NewMigrateArchivedArmAssignmentsTask(armID))
+	}
+
+	updatedExperiment, err := h.getAdminExperimentByID(c, experimentID)
+	if err != nil {
+		response.InternalError(c, "Failed to load updated experiment")
+		return
+	}
+
+	response.OK(c, updatedExperiment)
+}
+
+// ArchiveAdminExperiment POST /v1/admin/experiments/:id/archive
+// Moves a completed experiment's arm stats to the archive and stops serving
+// it; its cached assignments and arm stats are dropped.
+func (h *AdminHandler) ArchiveAdminExperiment(c *gin.Context) {
+	h.moveAdminExperimentArchive(c, true)
+}
+
+// UnarchiveAdminExperiment POST /v1/admin/experiments/:id/unarchive
+// Restores the archived arm stats and returns the experiment to completed.
+func (h *AdminHandler) UnarchiveAdminExperiment(c *gin.Context) {
+	h.moveAdminExperimentArchive(c, false)
+}
+
+func (h *AdminHandler) moveAdminExperimentArchive(c *gin.Context, archive bool) {
+	if !bindRequiredEmptyJSONObject(c) {
+		return
+	}
+	experimentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid experiment ID")
+		return
+	}
+	if h.experimentAdminService == nil {
+		response.InternalError(c, "Experiment service is unavailable")
+		return
+	}
+
+	ctx := c.Request.Context()
+	if archive {
+		err = h.experimentAdminService.ArchiveExperiment(ctx, experimentID, buildAdminExperimentStatusAudit(c, "manual_archived", nil))
+	} else {
+		err = h.experimentAdminService.UnarchiveExperiment(ctx, experimentID, buildAdminExperimentStatusAudit(c, "manual_unarchived", nil))
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrExperimentNotFound):
+			response.NotFound(c, "Experiment not found")
+		case errors.Is(err, service.ErrInvalidStatusTransition):
+			response.NotFound(c, err.Error())
+		default:
+			response.InternalError(c, "Failed to update experiment archive")
+		}
+		return
+	}
+
+	updatedExperiment, err := h.getAdminExperimentByID(c, experimentID)
+	if err != nil {
+		response.InternalError(c, "Failed to load updated experiment")
+		return
+	}
+	if archive {
+		h.invalidateArchivedExperimentCaches(ctx, updatedExperiment)
+	}
+	response.OK(c, updatedExperiment)
+}
+
+// invalidateArchivedExperimentCaches drops the archived experiment's cached
+// assignments and arm stats, which would otherwise keep serving it until
+// they expire
+func (h *AdminHandler) invalidateArchivedExperimentCaches(ctx context.Context, experiment AdminExperiment) {
+	if h.redisClient == nil {
+		return
+	}
+	banditCache := cache.NewRedisBanditCache(h.redisClient, zap.NewNop())
+	if err := banditCache.BulkInvalidateAssignments(ctx, experiment.ID); err != nil {
+		logging.Logger.Warn("Failed to invalidate archived experiment assignments", zap.String("experiment_id", experiment.ID.String()), zap.Error(err))
+	}
+	for _, arm := range experiment.Arms {
+		if err := banditCache.InvalidateArmStats(ctx, arm.ID); err != nil {
+			logging.Logger.Warn("Failed to invalidate archived arm stats", zap.String("arm_id", arm.ID.String()), zap.Error(err))
+		}
+	}
+}
+
 func (h *AdminHandler) PauseAdminExperiment(c *gin.Context) {
 	h.updateAdminExperimentStatus(c, "paused")
 }
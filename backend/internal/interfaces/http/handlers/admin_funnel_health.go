@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+type funnelHealthChecker interface {
+	Check(ctx context.Context, appID uuid.UUID) (*service.FunnelHealth, error)
+}
+
+// AdminFunnelHealthHandler reports a traffic-light status for the purchase
+// funnel with the factors behind it
+type AdminFunnelHealthHandler struct {
+	checker funnelHealthChecker
+	logger  *zap.Logger
+}
+
+func NewAdminFunnelHealthHandler(checker funnelHealthChecker, logger *zap.Logger) *AdminFunnelHealthHandler {
+	return &AdminFunnelHealthHandler{checker: checker, logger: logger}
+}
+
+// GetFunnelHealth GET /v1/admin/health/funnel
+// Always 200: a red funnel is a finding, not a failed request.
+func (h *AdminFunnelHealthHandler) GetFunnelHealth(c *gin.Context) {
+	appID := httpmiddleware.GetAppID(c)
+	health, err := h.checker.Check(c.Request.Context(), appID)
+	if err != nil {
+		h.logger.Error("Failed to check funnel health", zap.String("app_id", appID.String()), zap.Error(err))
+		response.InternalError(c, "Failed to check funnel health")
+		return
+	}
+	response.OK(c, health)
+}
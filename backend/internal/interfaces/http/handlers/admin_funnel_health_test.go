@@ -0,0 +1,73 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/handlers"
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+)
+
+type fakeFunnelHealthChecker struct {
+	signals service.FunnelHealthSignals
+	err     error
+	appID   uuid.UUID
+}
+
+func (f *fakeFunnelHealthChecker) Check(ctx context.Context, appID uuid.UUID) (*service.FunnelHealth, error) {
+	f.appID = appID
+	if f.err != nil {
+		return nil, f.err
+	}
+	return service.ScoreFunnelHealth(f.signals, time.Now()), nil
+}
+
+func getFunnelHealth(h *handlers.AdminFunnelHealthHandler, appID uuid.UUID) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set(httpmiddleware.AppIDKey, appID)
+		c.Next()
+	})
+	r.GET("/v1/admin/health/funnel", h.GetFunnelHealth)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/health/funnel", nil))
+	return w
+}
+
+func TestGetFunnelHealth_RedFunnelIsOK(t *testing.T) {
+	checker := &fakeFunnelHealthChecker{signals: service.FunnelHealthSignals{OldestWebhookAge: time.Hour}}
+	appID := uuid.New()
+
+	w := getFunnelHealth(handlers.NewAdminFunnelHealthHandler(checker, zap.NewNop()), appID)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, appID, checker.appID)
+	var body struct {
+		Data service.FunnelHealth `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, service.HealthRed, body.Data.Status)
+	assert.Len(t, body.Data.Factors, 4)
+}
+
+func TestGetFunnelHealth_CheckFailure(t *testing.T) {
+	checker := &fakeFunnelHealthChecker{err: errors.New("db down")}
+
+	w := getFunnelHealth(handlers.NewAdminFunnelHealthHandler(checker, zap.NewNop()), uuid.New())
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
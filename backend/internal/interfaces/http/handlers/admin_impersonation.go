@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+type impersonationTokenIssuer interface {
+	GenerateImpersonationToken(userID, appID, adminID string, ttl time.Duration) (string, string, time.Time, error)
+}
+
+type impersonationUserLookup interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*entity.User, error)
+}
+
+// ImpersonateUserRequest asks for a token acting as the user. TTLMinutes
+// defaults to 15 and may be at most 60.
+type ImpersonateUserRequest struct {
+	Reason     string `json:"reason" binding:"required,max=500"`
+	TTLMinutes int    `json:"ttl_minutes" binding:"omitempty,min=1,max=60"`
+}
+
+// ImpersonationTokenResponse is a read-only access token for the user
+type ImpersonationTokenResponse struct {
+	AccessToken  string    `json:"access_token"`
+	TokenType    string    `json:"token_type"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	UserID       uuid.UUID `json:"user_id"`
+	Impersonated bool      `json:"impersonated"`
+}
+
+// AdminImpersonationHandler issues support tokens that see the API exactly
+// as a user does
+type AdminImpersonationHandler struct {
+	tokens impersonationTokenIssuer
+	users  impersonationUserLookup
+	audit  adminAuditLogger
+	logger *zap.Logger
+}
+
+func NewAdminImpersonationHandler(tokens impersonationTokenIssuer, users impersonationUserLookup, audit adminAuditLogger, logger *zap.Logger) *AdminImpersonationHandler {
+	return &AdminImpersonationHandler{tokens: tokens, users: users, audit: audit, logger: logger}
+}
+
+// ImpersonateUser POST /v1/admin/users/:id/impersonate
+// The token only allows reads (paywall, assignments, access checks), cannot
+// reach admin routes, and every request made with it is audited.
+func (h *AdminImpersonationHandler) ImpersonateUser(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid user ID")
+		return
+	}
+	var req ImpersonateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+	adminID, ok := adminIDFromContext(c)
+	if !ok {
+		response.Forbidden(c, "Impersonation requires an admin user")
+		return
+	}
+
+	appID := httpmiddleware.GetAppID(c)
+	user, err := h.users.GetByID(c.Request.Context(), userID)
+	if errors.Is(err, domainErrors.ErrUserNotFound) || (err == nil && (user.AppID != appID || user.IsDeleted())) {
+		response.NotFound(c, "User not found")
+		return
+	}
+	if err != nil {
+		response.InternalError(c, "Failed to get user")
+		return
+	}
+	if user.IsAdmin() {
+		response.Forbidden(c, "Admin accounts cannot be impersonated")
+		return
+	}
+
+	ttl := time.Duration(req.TTLMinutes) * time.Minute
+	token, jti, expiresAt, err := h.tokens.GenerateImpersonationToken(userID.String(), appID.String(), adminID.String(), ttl)
+	if err != nil {
+		h.logger.Error("Failed to issue impersonation token", zap.String("user_id", userID.String()), zap.Error(err))
+		response.InternalError(c, "Failed to issue impersonation token")
+		return
+	}
+	if h.audit != nil {
+		details := map[string]interface{}{
+			"reason":     req.Reason,
+			"jti":        jti,
+			"expires_at": expiresAt.Format(time.RFC3339),
+		}
+		if err := h.audit.LogAction(c.Request.Context(), *adminID, "impersonate_user", "user", &userID, details); err != nil {
+			h.logger.Warn("Failed to audit impersonation", zap.String("user_id", userID.String()), zap.Error(err))
+		}
+	}
+
+	response.Created(c, ImpersonationTokenResponse{
+		AccessToken:  token,
+		TokenType:    "Bearer",
+		ExpiresAt:    expiresAt,
+		UserID:       userID,
+		Impersonated: true,
+	})
+}
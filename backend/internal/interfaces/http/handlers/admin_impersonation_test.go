@@ -0,0 +1,90 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/handlers"
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+)
+
+type fakeImpersonationTokens struct {
+	userID, adminID string
+	ttl             time.Duration
+}
+
+func (f *fakeImpersonationTokens) GenerateImpersonationToken(userID, appID, adminID string, ttl time.Duration) (string, string, time.Time, error) {
+	f.userID, f.adminID, f.ttl = userID, adminID, ttl
+	return "imp-token", "jti-1", time.Now().Add(ttl), nil
+}
+
+type fakeImpersonationUsers map[uuid.UUID]*entity.User
+
+func (f fakeImpersonationUsers) GetByID(_ context.Context, id uuid.UUID) (*entity.User, error) {
+	if u, ok := f[id]; ok {
+		return u, nil
+	}
+	return nil, domainErrors.ErrUserNotFound
+}
+
+type fakeAdminAudit struct {
+	actions []string
+}
+
+func (f *fakeAdminAudit) LogAction(_ context.Context, _ uuid.UUID, action, _ string, _ *uuid.UUID, _ map[string]interface{}) error {
+	f.actions = append(f.actions, action)
+	return nil
+}
+
+func TestImpersonateUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	appID, adminID := uuid.New(), uuid.New()
+	member := &entity.User{ID: uuid.New(), AppID: appID, Role: "user"}
+	otherApp := &entity.User{ID: uuid.New(), AppID: uuid.New(), Role: "user"}
+	admin := &entity.User{ID: uuid.New(), AppID: appID, Role: "admin"}
+	tokens, audit := &fakeImpersonationTokens{}, &fakeAdminAudit{}
+	h := handlers.NewAdminImpersonationHandler(tokens, fakeImpersonationUsers{member.ID: member, otherApp.ID: otherApp, admin.ID: admin}, audit, zap.NewNop())
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("admin_id", adminID)
+		c.Set(httpmiddleware.AppIDKey, appID)
+		c.Next()
+	})
+	r.POST("/v1/admin/users/:id/impersonate", h.ImpersonateUser)
+	post := func(userID uuid.UUID, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/v1/admin/users/"+userID.String()+"/impersonate", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post(member.ID, `{"reason":"ticket 4411: paywall shows wrong price","ttl_minutes":10}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"access_token":"imp-token"`)
+	assert.Contains(t, w.Body.String(), `"impersonated":true`)
+	assert.Equal(t, member.ID.String(), tokens.userID)
+	assert.Equal(t, adminID.String(), tokens.adminID)
+	assert.Equal(t, 10*time.Minute, tokens.ttl)
+	assert.Equal(t, []string{"impersonate_user"}, audit.actions)
+
+	assert.Equal(t, http.StatusBadRequest, post(member.ID, `{}`).Code)
+	assert.Equal(t, http.StatusBadRequest, post(member.ID, `{"reason":"x","ttl_minutes":120}`).Code)
+	assert.Equal(t, http.StatusNotFound, post(otherApp.ID, `{"reason":"x"}`).Code)
+	assert.Equal(t, http.StatusNotFound, post(uuid.New(), `{"reason":"x"}`).Code)
+	assert.Equal(t, http.StatusForbidden, post(admin.ID, `{"reason":"x"}`).Code)
+	assert.Len(t, audit.actions, 1)
+}
@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+type inAppMessageStatsReporter interface {
+	Stats(ctx context.Context, appID, messageID uuid.UUID) ([]entity.InAppMessageStats, error)
+}
+
+// InAppMessage is the admin representation of a scheduled in-app message
+type InAppMessage struct {
+	ID                 uuid.UUID                    `json:"id"`
+	Name               string                       `json:"name"`
+	Placement          string                       `json:"placement"`
+	Format             entity.InAppMessageFormat    `json:"format"`
+	Trigger            entity.InAppMessageTrigger   `json:"trigger"`
+	TriggerWindowHours int                          `json:"trigger_window_hours"`
+	Priority           int                          `json:"priority"`
+	Enabled            bool                         `json:"enabled"`
+	StartsAt           *time.Time                   `json:"starts_at,omitempty"`
+	EndsAt             *time.Time                   `json:"ends_at,omitempty"`
+	Conditions         entity.PaywallRuleConditions `json:"conditions"`
+	Content            entity.InAppMessageContent   `json:"content"`
+	ExperimentID       *uuid.UUID                   `json:"experiment_id,omitempty"`
+	Variants           []entity.InAppMessageVariant `json:"variants"`
+	MaxImpressions     int                          `json:"max_impressions"`
+	MinIntervalHours   int                          `json:"min_interval_hours"`
+	CreatedAt          time.Time                    `json:"created_at"`
+	UpdatedAt          time.Time                    `json:"updated_at"`
+}
+
+type inAppMessageUpsertRequest struct {
+	Name               string                       `json:"name"`
+	Placement          string                       `json:"placement"`
+	Format             entity.InAppMessageFormat    `json:"format"`
+	Trigger            entity.InAppMessageTrigger   `json:"trigger"`
+	TriggerWindowHours int                          `json:"trigger_window_hours"`
+	Priority           int                          `json:"priority"`
+	Enabled            *bool                        `json:"enabled"`
+	StartsAt           *time.Time                   `json:"starts_at"`
+	EndsAt             *time.Time                   `json:"ends_at"`
+	Conditions         entity.PaywallRuleConditions `json:"conditions"`
+	Content            entity.InAppMessageContent   `json:"content"`
+	ExperimentID       *uuid.UUID                   `json:"experiment_id"`
+	Variants           []entity.InAppMessageVariant `json:"variants"`
+	MaxImpressions     int                          `json:"max_impressions"`
+	MinIntervalHours   int                          `json:"min_interval_hours"`
+}
+
+// AdminInAppMessagesHandler manages scheduled in-app messages
+type AdminInAppMessagesHandler struct {
+	messages repository.InAppMessageRepository
+	stats    inAppMessageStatsReporter
+}
+
+func NewAdminInAppMessagesHandler(messages repository.InAppMessageRepository, stats inAppMessageStatsReporter) *AdminInAppMessagesHandler {
+	return &AdminInAppMessagesHandler{messages: messages, stats: stats}
+}
+
+func toInAppMessage(m *entity.InAppMessage) InAppMessage {
+	variants := m.Variants
+	if variants == nil {
+		variants = []entity.InAppMessageVariant{}
+	}
+	return InAppMessage{
+		ID:                 m.ID,
+		Name:               m.Name,
+		Placement:          m.Placement,
+		Format:             m.Format,
+		Trigger:            m.Trigger,
+		TriggerWindowHours: m.TriggerWindowHours,
+		Priority:           m.Priority,
+		Enabled:            m.Enabled,
+		StartsAt:           m.StartsAt,
+		EndsAt:             m.EndsAt,
+		Conditions:         m.Conditions,
+		Content:            m.Content,
+		ExperimentID:       m.ExperimentID,
+		Variants:           variants,
+		MaxImpressions:     m.MaxImpressions,
+		MinIntervalHours:   m.MinIntervalHours,
+		CreatedAt:          m.CreatedAt,
+		UpdatedAt:          m.UpdatedAt,
+	}
+}
+
+// bindInAppMessage parses and validates the request and applies it to m
+func bindInAppMessage(c *gin.Context, m *entity.InAppMessage) bool {
+	var req inAppMessageUpsertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body")
+		return false
+	}
+	if strings.TrimSpace(req.Content.Title) == "" {
+		response.BadRequest(c, "content.title is required")
+		return false
+	}
+
+	next := *m
+	next.Name = strings.TrimSpace(req.Name)
+	next.Placement = strings.TrimSpace(req.Placement)
+	next.Format = req.Format
+	next.Trigger = req.Trigger
+	if next.Trigger == "" {
+		next.Trigger = entity.TriggerAlways
+	}
+	next.TriggerWindowHours = req.TriggerWindowHours
+	next.Priority = req.Priority
+	if req.Enabled != nil {
+		next.Enabled = *req.Enabled
+	}
+	next.StartsAt = req.StartsAt
+	next.EndsAt = req.EndsAt
+	next.Conditions = req.Conditions
+	next.Content = req.Content
+	next.ExperimentID = req.ExperimentID
+	next.Variants = req.Variants
+	next.MaxImpressions = req.MaxImpressions
+	next.MinIntervalHours = req.MinIntervalHours
+	if err := next.Validate(); err != nil {
+		response.BadRequest(c, err.Error())
+		return false
+	}
+	*m = next
+	return true
+}
+
+// ListInAppMessages GET /v1/admin/in-app-messages
+// Messages are returned highest priority first.
+func (h *AdminInAppMessagesHandler) ListInAppMessages(c *gin.Context) {
+	messages, err := h.messages.List(c.Request.Context(), httpmiddleware.GetAppID(c))
+	if err != nil {
+		response.InternalError(c, "Failed to list in-app messages")
+		return
+	}
+
+	out := make([]InAppMessage, 0, len(messages))
+	for _, m := range messages {
+		out = append(out, toInAppMessage(m))
+	}
+	response.OK(c, gin.H{"messages": out, "total": len(out)})
+}
+
+// GetInAppMessage GET /v1/admin/in-app-messages/:id
+func (h *AdminInAppMessagesHandler) GetInAppMessage(c *gin.Context) {
+	m, ok := h.loadInAppMessage(c)
+	if !ok {
+		return
+	}
+	response.OK(c, toInAppMessage(m))
+}
+
+// CreateInAppMessage POST /v1/admin/in-app-messages
+func (h *AdminInAppMessagesHandler) CreateInAppMessage(c *gin.Context) {
+	m := entity.NewInAppMessage(httpmiddleware.GetAppID(c), "", "")
+	if !bindInAppMessage(c, m) {
+		return
+	}
+
+	if err := h.messages.Create(c.Request.Context(), m); err != nil {
+		response.InternalError(c, "Failed to create in-app message")
+		return
+	}
+	response.Created(c, toInAppMessage(m))
+}
+
+// UpdateInAppMessage PUT /v1/admin/in-app-messages/:id
+func (h *AdminInAppMessagesHandler) UpdateInAppMessage(c *gin.Context) {
+	m, ok := h.loadInAppMessage(c)
+	if !ok {
+		return
+	}
+	if !bindInAppMessage(c, m) {
+		return
+	}
+	m.UpdatedAt = time.Now()
+
+	if err := h.messages.Update(c.Request.Context(), m); err != nil {
+		if errors.Is(err, domainErrors.ErrNotFound) {
+			response.NotFound(c, "In-app message not found")
+			return
+		}
+		response.InternalError(c, "Failed to update in-app message")
+		return
+	}
+	response.OK(c, toInAppMessage(m))
+}
+
+// DeleteInAppMessage DELETE /v1/admin/in-app-messages/:id
+func (h *AdminInAppMessagesHandler) DeleteInAppMessage(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid message ID")
+		return
+	}
+
+	err = h.messages.Delete(c.Request.Context(), httpmiddleware.GetAppID(c), id)
+	if errors.Is(err, domainErrors.ErrNotFound) {
+		response.NotFound(c, "In-app message not found")
+		return
+	}
+	if err != nil {
+		response.InternalError(c, "Failed to delete in-app message")
+		return
+	}
+	response.NoContent(c)
+}
+
+// GetInAppMessageStats GET /v1/admin/in-app-messages/:id/stats
+// Totals impressions, clicks and dismissals per variant arm.
+func (h *AdminInAppMessagesHandler) GetInAppMessageStats(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid message ID")
+		return
+	}
+
+	stats, err := h.stats.Stats(c.Request.Context(), httpmiddleware.GetAppID(c), id)
+	if errors.Is(err, domainErrors.ErrNotFound) {
+		response.NotFound(c, "In-app message not found")
+		return
+	}
+	if err != nil {
+		response.InternalError(c, "Failed to get in-app message stats")
+		return
+	}
+	response.OK(c, gin.H{"message_id": id, "arms": stats})
+}
+
+func (h *AdminInAppMessagesHandler) loadInAppMessage(c *gin.Context) (*entity.InAppMessage, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid message ID")
+		return nil, false
+	}
+
+	m, err := h.messages.GetByID(c.Request.Context(), httpmiddleware.GetAppID(c), id)
+	if errors.Is(err, domainErrors.ErrNotFound) {
+		response.NotFound(c, "In-app message not found")
+		return nil, false
+	}
+	if err != nil {
+		response.InternalError(c, "Failed to get in-app message")
+		return nil, false
+	}
+	return m, true
+}
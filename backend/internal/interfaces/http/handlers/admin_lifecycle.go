@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+type lifecycleStageReporter interface {
+	StageCounts(ctx context.Context, appID uuid.UUID) ([]service.LifecycleStageCount, error)
+}
+
+// AdminLifecycleHandler reports how an app's users spread over lifecycle stages
+type AdminLifecycleHandler struct {
+	lifecycle lifecycleStageReporter
+}
+
+func NewAdminLifecycleHandler(lifecycle lifecycleStageReporter) *AdminLifecycleHandler {
+	return &AdminLifecycleHandler{lifecycle: lifecycle}
+}
+
+// GetStageCounts GET /v1/admin/analytics/lifecycle-stages
+func (h *AdminLifecycleHandler) GetStageCounts(c *gin.Context) {
+	counts, err := h.lifecycle.StageCounts(c.Request.Context(), httpmiddleware.GetAppID(c))
+	if err != nil {
+		response.InternalError(c, "Failed to count lifecycle stages")
+		return
+	}
+	var total int64
+	for _, count := range counts {
+		total += count.Users
+	}
+	response.OK(c, gin.H{"stages": counts, "total": total})
+}
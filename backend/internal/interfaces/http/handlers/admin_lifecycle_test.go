@@ -0,0 +1,50 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/handlers"
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+)
+
+type fakeLifecycleReporter struct {
+	appID uuid.UUID
+}
+
+func (f *fakeLifecycleReporter) StageCounts(_ context.Context, appID uuid.UUID) ([]service.LifecycleStageCount, error) {
+	f.appID = appID
+	return []service.LifecycleStageCount{
+		{Stage: entity.LifecycleNew, Users: 7},
+		{Stage: entity.LifecycleAtRisk, Users: 3},
+	}, nil
+}
+
+func TestAdminLifecycle_GetStageCounts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	appID := uuid.New()
+	reporter := &fakeLifecycleReporter{}
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set(httpmiddleware.AppIDKey, appID)
+		c.Next()
+	})
+	r.GET("/v1/admin/analytics/lifecycle-stages", handlers.NewAdminLifecycleHandler(reporter).GetStageCounts)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/analytics/lifecycle-stages", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, appID, reporter.appID)
+	assert.Contains(t, w.Body.String(), `"total":10`)
+	assert.Contains(t, w.Body.String(), `{"stage":"at_risk","users":3}`)
+}
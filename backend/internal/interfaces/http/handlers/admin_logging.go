@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/bivex/paywall-iap/internal/infrastructure/logging"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+type requestLogSettings interface {
+	Settings() logging.RequestLogSettings
+	Update(settings logging.RequestLogSettings) error
+}
+
+// RequestLogSettings is the admin representation of the request logging
+// controls. Route levels are the minimum level logged per route pattern.
+type RequestLogSettings struct {
+	SampleRate             float64           `json:"sample_rate"`
+	SlowRequestThresholdMs int64             `json:"slow_request_threshold_ms"`
+	RouteLevels            map[string]string `json:"route_levels"`
+}
+
+// AdminLoggingHandler reads and changes request logging settings at runtime.
+// Changes apply to the API instance serving the request until it restarts.
+type AdminLoggingHandler struct {
+	settings requestLogSettings
+}
+
+func NewAdminLoggingHandler(settings requestLogSettings) *AdminLoggingHandler {
+	return &AdminLoggingHandler{settings: settings}
+}
+
+func toRequestLogSettings(s logging.RequestLogSettings) RequestLogSettings {
+	levels := make(map[string]string, len(s.RouteLevels))
+	for route, level := range s.RouteLevels {
+		levels[route] = level.String()
+	}
+	return RequestLogSettings{
+		SampleRate:             s.SampleRate,
+		SlowRequestThresholdMs: s.SlowRequestThreshold.Milliseconds(),
+		RouteLevels:            levels,
+	}
+}
+
+// GetRequestLogSettings GET /v1/admin/logging
+func (h *AdminLoggingHandler) GetRequestLogSettings(c *gin.Context) {
+	response.OK(c, toRequestLogSettings(h.settings.Settings()))
+}
+
+// UpdateRequestLogSettings PUT /v1/admin/logging
+func (h *AdminLoggingHandler) UpdateRequestLogSettings(c *gin.Context) {
+	var req RequestLogSettings
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body")
+		return
+	}
+
+	settings := logging.RequestLogSettings{
+		SampleRate:           req.SampleRate,
+		SlowRequestThreshold: time.Duration(req.SlowRequestThresholdMs) * time.Millisecond,
+		RouteLevels:          make(map[string]zapcore.Level, len(req.RouteLevels)),
+	}
+	for route, name := range req.RouteLevels {
+		level, err := zapcore.ParseLevel(name)
+		if err != nil || route == "" {
+			response.BadRequest(c, "Invalid level for route "+route)
+			return
+		}
+		settings.RouteLevels[route] = level
+	}
+	if err := h.settings.Update(settings); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	response.OK(c, toRequestLogSettings(h.settings.Settings()))
+}
@@ -0,0 +1,50 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/bivex/paywall-iap/internal/infrastructure/config"
+	"github.com/bivex/paywall-iap/internal/infrastructure/logging"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/handlers"
+)
+
+func TestAdminLogging_UpdateRequestLogSettings(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	requestLogger, err := logging.NewRequestLogger(zap.NewNop(), config.LoggingConfig{SampleRate: 1})
+	require.NoError(t, err)
+	h := handlers.NewAdminLoggingHandler(requestLogger)
+	r := gin.New()
+	r.PUT("/v1/admin/logging", h.UpdateRequestLogSettings)
+
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/v1/admin/logging", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := put(`{"sample_rate":0.1,"slow_request_threshold_ms":500,"route_levels":{"/health":"error"}}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var body struct {
+		Data handlers.RequestLogSettings `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, 0.1, body.Data.SampleRate)
+	assert.Equal(t, int64(500), body.Data.SlowRequestThresholdMs)
+	assert.Equal(t, zapcore.ErrorLevel, requestLogger.Settings().RouteLevels["/health"])
+
+	assert.Equal(t, http.StatusBadRequest, put(`{"sample_rate":2}`).Code)
+	assert.Equal(t, http.StatusBadRequest, put(`{"sample_rate":1,"route_levels":{"/health":"loud"}}`).Code)
+	assert.Equal(t, 0.1, requestLogger.Settings().SampleRate)
+}
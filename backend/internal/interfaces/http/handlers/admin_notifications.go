@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+const (
+	defaultSuppressionWindow = 30 * 24 * time.Hour
+	maxSuppressionWindow     = 366 * 24 * time.Hour
+)
+
+type notificationSuppressionReporter interface {
+	Suppressions(ctx context.Context, appID uuid.UUID, from, to time.Time) (*service.NotificationSuppressionReport, error)
+}
+
+// AdminNotificationsHandler reports notifications held back by user
+// preferences
+type AdminNotificationsHandler struct {
+	suppressions notificationSuppressionReporter
+	now          func() time.Time
+}
+
+func NewAdminNotificationsHandler(suppressions notificationSuppressionReporter) *AdminNotificationsHandler {
+	return &AdminNotificationsHandler{suppressions: suppressions, now: time.Now}
+}
+
+// GetSuppressions GET /v1/admin/notifications/suppressions?from=YYYY-MM-DD&to=YYYY-MM-DD
+// Days are UTC; to is inclusive and the default range is the last 30 days.
+func (h *AdminNotificationsHandler) GetSuppressions(c *gin.Context) {
+	to := h.now().UTC().Truncate(24 * time.Hour)
+	if raw := c.Query("to"); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			response.BadRequest(c, "to must be a date in YYYY-MM-DD format")
+			return
+		}
+		to = parsed
+	}
+	from := to.Add(-defaultSuppressionWindow + 24*time.Hour)
+	if raw := c.Query("from"); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			response.BadRequest(c, "from must be a date in YYYY-MM-DD format")
+			return
+		}
+		from = parsed
+	}
+	if to.Before(from) || to.Sub(from) >= maxSuppressionWindow {
+		response.BadRequest(c, "from must not be after to and the range at most 366 days")
+		return
+	}
+
+	report, err := h.suppressions.Suppressions(c.Request.Context(), httpmiddleware.GetAppID(c), from, to)
+	if err != nil {
+		response.InternalError(c, "Failed to report notification suppressions")
+		return
+	}
+	response.OK(c, report)
+}
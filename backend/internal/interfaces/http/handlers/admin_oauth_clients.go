@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+type oauthClientCreator interface {
+	CreateClient(ctx context.Context, name string, appID *uuid.UUID, scopes []string, createdBy uuid.UUID) (*entity.OAuthClient, string, error)
+}
+
+// OAuthClient is the admin representation of an OAuth client. The secret is
+// only returned by CreateOAuthClient.
+type OAuthClient struct {
+	ID           uuid.UUID  `json:"id"`
+	ClientID     string     `json:"client_id"`
+	ClientSecret string     `json:"client_secret,omitempty"`
+	Name         string     `json:"name"`
+	AppID        *uuid.UUID `json:"app_id,omitempty"`
+	Scopes       []string   `json:"scopes"`
+	CreatedBy    uuid.UUID  `json:"created_by"`
+	CreatedAt    time.Time  `json:"created_at"`
+	LastUsedAt   *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+}
+
+type oauthClientCreateRequest struct {
+	Name   string     `json:"name" binding:"required"`
+	AppID  *uuid.UUID `json:"app_id"`
+	Scopes []string   `json:"scopes" binding:"required"`
+}
+
+// AdminOAuthClientsHandler manages the machine clients allowed to use the
+// client-credentials grant.
+type AdminOAuthClientsHandler struct {
+	clients repository.OAuthClientRepository
+	creator oauthClientCreator
+	now     func() time.Time
+}
+
+func NewAdminOAuthClientsHandler(clients repository.OAuthClientRepository, creator oauthClientCreator) *AdminOAuthClientsHandler {
+	return &AdminOAuthClientsHandler{clients: clients, creator: creator, now: time.Now}
+}
+
+func toOAuthClient(client *entity.OAuthClient) OAuthClient {
+	return OAuthClient{
+		ID:         client.ID,
+		ClientID:   client.ClientID,
+		Name:       client.Name,
+		AppID:      client.AppID,
+		Scopes:     client.Scopes,
+		CreatedBy:  client.CreatedBy,
+		CreatedAt:  client.CreatedAt,
+		LastUsedAt: client.LastUsedAt,
+		RevokedAt:  client.RevokedAt,
+	}
+}
+
+// ListOAuthClients GET /v1/admin/oauth-clients
+func (h *AdminOAuthClientsHandler) ListOAuthClients(c *gin.Context) {
+	clients, err := h.clients.List(c.Request.Context())
+	if err != nil {
+		response.InternalError(c, "Failed to list OAuth clients")
+		return
+	}
+
+	out := make([]OAuthClient, 0, len(clients))
+	for _, client := range clients {
+		out = append(out, toOAuthClient(client))
+	}
+	response.OK(c, gin.H{"clients": out, "total": len(out)})
+}
+
+// CreateOAuthClient POST /v1/admin/oauth-clients
+// The response carries the client secret; it is not shown again.
+func (h *AdminOAuthClientsHandler) CreateOAuthClient(c *gin.Context) {
+	var req oauthClientCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body")
+		return
+	}
+	adminIDValue, ok := c.Get("admin_id")
+	if !ok {
+		response.Unauthorized(c, "Admin identity required")
+		return
+	}
+	adminID, ok := adminIDValue.(uuid.UUID)
+	if !ok {
+		response.Unauthorized(c, "Admin identity required")
+		return
+	}
+
+	if err := entity.NewOAuthClient("", req.Name, req.AppID, req.Scopes, adminID).Validate(); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	client, secret, err := h.creator.CreateClient(c.Request.Context(), req.Name, req.AppID, req.Scopes, adminID)
+	var conflict *domainErrors.ConflictError
+	switch {
+	case errors.As(err, &conflict):
+		response.Conflict(c, "OAuth client already exists")
+		return
+	case err != nil:
+		response.InternalError(c, "Failed to create OAuth client")
+		return
+	}
+
+	out := toOAuthClient(client)
+	out.ClientSecret = secret
+	response.Created(c, out)
+}
+
+// RevokeOAuthClient DELETE /v1/admin/oauth-clients/:id
+// Tokens already issued to the client stop working immediately.
+func (h *AdminOAuthClientsHandler) RevokeOAuthClient(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid client ID")
+		return
+	}
+	err = h.clients.Revoke(c.Request.Context(), id, h.now())
+	if errors.Is(err, domainErrors.ErrNotFound) {
+		response.NotFound(c, "OAuth client not found")
+		return
+	}
+	if err != nil {
+		response.InternalError(c, "Failed to revoke OAuth client")
+		return
+	}
+	response.NoContent(c)
+}
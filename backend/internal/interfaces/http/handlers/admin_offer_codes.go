@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+const (
+	defaultOfferCodeWindow = 90 * 24 * time.Hour
+	maxOfferCodeWindow     = 366 * 24 * time.Hour
+)
+
+type offerCodeReporter interface {
+	CodeReports(ctx context.Context, appID uuid.UUID, from, to time.Time) ([]service.OfferCodeReport, error)
+	SetCampaign(ctx context.Context, appID uuid.UUID, offerCode, campaign string) error
+}
+
+// AdminOfferCodeHandler reports what became of the users who redeemed offer
+// codes and win-back offers, by batch and campaign
+type AdminOfferCodeHandler struct {
+	offers offerCodeReporter
+	now    func() time.Time
+}
+
+func NewAdminOfferCodeHandler(offers offerCodeReporter) *AdminOfferCodeHandler {
+	return &AdminOfferCodeHandler{offers: offers, now: time.Now}
+}
+
+// SetOfferCodeCampaignRequest attributes an offer code batch to a campaign
+type SetOfferCodeCampaignRequest struct {
+	// OfferCode is the offer code reference name or the win-back offer ID
+	OfferCode string `json:"offer_code" binding:"required"`
+	// Campaign is empty to remove the attribution
+	Campaign string `json:"campaign"`
+}
+
+// GetOfferCodeOutcomes GET /v1/admin/analytics/offer-codes?from=YYYY-MM-DD&to=YYYY-MM-DD
+// Batches are by the users' first redemption date; to is inclusive and the
+// default range is the last 90 days.
+func (h *AdminOfferCodeHandler) GetOfferCodeOutcomes(c *gin.Context) {
+	to := h.now().UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+	if raw := c.Query("to"); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			response.BadRequest(c, "to must be a date in YYYY-MM-DD format")
+			return
+		}
+		to = parsed.Add(24 * time.Hour)
+	}
+	from := to.Add(-defaultOfferCodeWindow)
+	if raw := c.Query("from"); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			response.BadRequest(c, "from must be a date in YYYY-MM-DD format")
+			return
+		}
+		from = parsed
+	}
+	if !from.Before(to) || to.Sub(from) > maxOfferCodeWindow {
+		response.BadRequest(c, "from must be before to and the range at most 366 days")
+		return
+	}
+
+	reports, err := h.offers.CodeReports(c.Request.Context(), httpmiddleware.GetAppID(c), from, to)
+	if err != nil {
+		response.InternalError(c, "Failed to build offer code report")
+		return
+	}
+	response.OK(c, gin.H{"from": from, "to": to, "offer_codes": reports})
+}
+
+// SetOfferCodeCampaign PUT /v1/admin/offer-codes/campaign
+func (h *AdminOfferCodeHandler) SetOfferCodeCampaign(c *gin.Context) {
+	var req SetOfferCodeCampaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	err := h.offers.SetCampaign(c.Request.Context(), httpmiddleware.GetAppID(c), req.OfferCode, req.Campaign)
+	if errors.Is(err, service.ErrOfferCodeRequired) {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	if err != nil {
+		response.InternalError(c, "Failed to set offer code campaign")
+		return
+	}
+	response.OK(c, req)
+}
@@ -0,0 +1,87 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/handlers"
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+)
+
+type fakeOfferCodeReporter struct {
+	from, to  time.Time
+	campaigns map[string]string
+}
+
+func (f *fakeOfferCodeReporter) CodeReports(_ context.Context, _ uuid.UUID, from, to time.Time) ([]service.OfferCodeReport, error) {
+	f.from, f.to = from, to
+	return []service.OfferCodeReport{{OfferType: entity.OfferTypeOfferCode, OfferCode: "SPRING50", Campaign: f.campaigns["SPRING50"], Redemptions: 4, Retained: 3, RetentionRate: 0.75}}, nil
+}
+
+func (f *fakeOfferCodeReporter) SetCampaign(_ context.Context, _ uuid.UUID, offerCode, campaign string) error {
+	if strings.TrimSpace(offerCode) == "" {
+		return service.ErrOfferCodeRequired
+	}
+	f.campaigns[offerCode] = campaign
+	return nil
+}
+
+func newOfferCodeRouter(offers *fakeOfferCodeReporter) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := handlers.NewAdminOfferCodeHandler(offers)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set(httpmiddleware.AppIDKey, uuid.New())
+		c.Next()
+	})
+	r.GET("/v1/admin/analytics/offer-codes", h.GetOfferCodeOutcomes)
+	r.PUT("/v1/admin/offer-codes/campaign", h.SetOfferCodeCampaign)
+	return r
+}
+
+func TestAdminOfferCodes_ReportByCampaign(t *testing.T) {
+	offers := &fakeOfferCodeReporter{campaigns: map[string]string{}}
+	router := newOfferCodeRouter(offers)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/v1/admin/offer-codes/campaign", strings.NewReader(`{"offer_code":"SPRING50","campaign":"spring-email"}`)))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/analytics/offer-codes?from=2026-03-01&to=2026-03-31", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC), offers.to, "to is inclusive")
+	var body struct {
+		Data struct {
+			OfferCodes []service.OfferCodeReport `json:"offer_codes"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.Len(t, body.Data.OfferCodes, 1)
+	assert.Equal(t, "spring-email", body.Data.OfferCodes[0].Campaign)
+	assert.Equal(t, 0.75, body.Data.OfferCodes[0].RetentionRate)
+}
+
+func TestAdminOfferCodes_RejectsBadInput(t *testing.T) {
+	router := newOfferCodeRouter(&fakeOfferCodeReporter{campaigns: map[string]string{}})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/analytics/offer-codes?from=2026-04-01&to=2026-03-01", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/v1/admin/offer-codes/campaign", strings.NewReader(`{"offer_code":" ","campaign":"x"}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
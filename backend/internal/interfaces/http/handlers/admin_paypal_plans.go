@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+type payPalPlanStore interface {
+	UpsertPlan(ctx context.Context, plan *entity.PayPalPlan) error
+	ListPlans(ctx context.Context, appID uuid.UUID) ([]*entity.PayPalPlan, error)
+	DeletePlan(ctx context.Context, appID uuid.UUID, planID string) error
+}
+
+// AdminPayPalPlansHandler maps PayPal billing plans to products, so activated
+// PayPal subscriptions provision the right entitlement
+type AdminPayPalPlansHandler struct {
+	plans payPalPlanStore
+}
+
+func NewAdminPayPalPlansHandler(plans payPalPlanStore) *AdminPayPalPlansHandler {
+	return &AdminPayPalPlansHandler{plans: plans}
+}
+
+type putPayPalPlanRequest struct {
+	ProductID string          `json:"product_id" binding:"required,max=200"`
+	PlanType  entity.PlanType `json:"plan_type" binding:"required"`
+}
+
+// ListPayPalPlans GET /v1/admin/paypal/plans
+func (h *AdminPayPalPlansHandler) ListPayPalPlans(c *gin.Context) {
+	plans, err := h.plans.ListPlans(c.Request.Context(), httpmiddleware.GetAppID(c))
+	if err != nil {
+		response.InternalError(c, "Failed to list PayPal plans")
+		return
+	}
+	response.OK(c, gin.H{"plans": plans})
+}
+
+// PutPayPalPlan PUT /v1/admin/paypal/plans/:plan_id
+func (h *AdminPayPalPlansHandler) PutPayPalPlan(c *gin.Context) {
+	var req putPayPalPlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+	plan := &entity.PayPalPlan{
+		PlanID:    c.Param("plan_id"),
+		AppID:     httpmiddleware.GetAppID(c),
+		ProductID: req.ProductID,
+		PlanType:  req.PlanType,
+	}
+	if err := plan.Validate(); err != nil {
+		response.UnprocessableEntity(c, err.Error())
+		return
+	}
+	if err := h.plans.UpsertPlan(c.Request.Context(), plan); err != nil {
+		if errors.Is(err, entity.ErrPayPalPlanTaken) {
+			response.Conflict(c, err.Error())
+			return
+		}
+		response.InternalError(c, "Failed to save PayPal plan")
+		return
+	}
+	response.OK(c, gin.H{"plan": plan})
+}
+
+// DeletePayPalPlan DELETE /v1/admin/paypal/plans/:plan_id
+// Subscriptions already provisioned from the plan are unaffected.
+func (h *AdminPayPalPlansHandler) DeletePayPalPlan(c *gin.Context) {
+	if err := h.plans.DeletePlan(c.Request.Context(), httpmiddleware.GetAppID(c), c.Param("plan_id")); err != nil {
+		if errors.Is(err, domainErrors.ErrNotFound) {
+			response.NotFound(c, "PayPal plan not found")
+			return
+		}
+		response.InternalError(c, "Failed to delete PayPal plan")
+		return
+	}
+	response.OK(c, gin.H{"deleted": true})
+}
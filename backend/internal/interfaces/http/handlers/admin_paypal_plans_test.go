@@ -0,0 +1,93 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/handlers"
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+)
+
+type fakePayPalPlanStore struct {
+	plans map[string]*entity.PayPalPlan
+}
+
+func (f *fakePayPalPlanStore) UpsertPlan(ctx context.Context, plan *entity.PayPalPlan) error {
+	if existing, ok := f.plans[plan.PlanID]; ok && existing.AppID != plan.AppID {
+		return entity.ErrPayPalPlanTaken
+	}
+	f.plans[plan.PlanID] = plan
+	return nil
+}
+
+func (f *fakePayPalPlanStore) ListPlans(ctx context.Context, appID uuid.UUID) ([]*entity.PayPalPlan, error) {
+	var out []*entity.PayPalPlan
+	for _, p := range f.plans {
+		if p.AppID == appID {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakePayPalPlanStore) DeletePlan(ctx context.Context, appID uuid.UUID, planID string) error {
+	if p, ok := f.plans[planID]; !ok || p.AppID != appID {
+		return domainErrors.ErrNotFound
+	}
+	delete(f.plans, planID)
+	return nil
+}
+
+func newAdminPayPalPlansRouter(store *fakePayPalPlanStore, appID uuid.UUID) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := handlers.NewAdminPayPalPlansHandler(store)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set(httpmiddleware.AppIDKey, appID)
+		c.Next()
+	})
+	r.GET("/v1/admin/paypal/plans", h.ListPayPalPlans)
+	r.PUT("/v1/admin/paypal/plans/:plan_id", h.PutPayPalPlan)
+	r.DELETE("/v1/admin/paypal/plans/:plan_id", h.DeletePayPalPlan)
+	return r
+}
+
+func TestAdminPayPalPlans(t *testing.T) {
+	appID := uuid.New()
+	store := &fakePayPalPlanStore{plans: map[string]*entity.PayPalPlan{}}
+	r := newAdminPayPalPlansRouter(store, appID)
+	put := func(r *gin.Engine, planID, body string) int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/v1/admin/paypal/plans/"+planID, strings.NewReader(body)))
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, put(r, "P-1", `{"product_id":"pro_monthly","plan_type":"monthly"}`))
+	require.Contains(t, store.plans, "P-1")
+	assert.Equal(t, appID, store.plans["P-1"].AppID)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, put(r, "P-2", `{"product_id":"pro_lifetime","plan_type":"lifetime"}`))
+	assert.Equal(t, http.StatusConflict, put(newAdminPayPalPlansRouter(store, uuid.New()), "P-1", `{"product_id":"other","plan_type":"annual"}`))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/paypal/plans", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"plan_id":"P-1"`)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/v1/admin/paypal/plans/P-1", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/v1/admin/paypal/plans/P-1", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
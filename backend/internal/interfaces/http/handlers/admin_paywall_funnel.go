@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/infrastructure/cache"
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+const (
+	defaultPaywallFunnelWindow = 30 * 24 * time.Hour
+	maxPaywallFunnelWindow     = 366 * 24 * time.Hour
+	maxPaywallPlacementLength  = 100
+)
+
+// Internal funnel steps, in order
+const (
+	PaywallFunnelStepShown     = "paywall_shown"
+	PaywallFunnelStepInitiated = "purchase_initiated"
+	PaywallFunnelStepCompleted = "purchase_completed"
+)
+
+type paywallFunnelReporter interface {
+	GetPaywallFunnel(ctx context.Context, appID uuid.UUID, query service.PaywallFunnelQuery) ([]service.PaywallFunnelSegment, error)
+}
+
+type funnelDataCache interface {
+	GetFunnelData(ctx context.Context, funnelID string, dateFrom, dateTo time.Time) (*cache.FunnelData, error)
+	SetFunnelData(ctx context.Context, funnelID string, dateFrom, dateTo time.Time, data *cache.FunnelData) error
+}
+
+// AdminPaywallFunnelHandler serves the paywall funnel computed from our own
+// ingested events rather than the Matomo Funnels plugin.
+type AdminPaywallFunnelHandler struct {
+	reporter paywallFunnelReporter
+	cache    funnelDataCache
+	logger   *zap.Logger
+	now      func() time.Time
+}
+
+func NewAdminPaywallFunnelHandler(reporter paywallFunnelReporter, funnelCache funnelDataCache, logger *zap.Logger) *AdminPaywallFunnelHandler {
+	return &AdminPaywallFunnelHandler{reporter: reporter, cache: funnelCache, logger: logger, now: time.Now}
+}
+
+// GetInternalFunnel GET /v1/admin/analytics/funnels/internal?from=YYYY-MM-DD&to=YYYY-MM-DD&experiment_id=&placement=
+// to is inclusive; the default range is the last 30 days. The response is the
+// overall funnel with one segment per experiment arm × placement.
+func (h *AdminPaywallFunnelHandler) GetInternalFunnel(c *gin.Context) {
+	to := h.now().UTC().Truncate(24 * time.Hour)
+	if raw := c.Query("to"); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			response.BadRequest(c, "to must be a date in YYYY-MM-DD format")
+			return
+		}
+		to = parsed
+	}
+	from := to.Add(24 * time.Hour).Add(-defaultPaywallFunnelWindow)
+	if raw := c.Query("from"); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			response.BadRequest(c, "from must be a date in YYYY-MM-DD format")
+			return
+		}
+		from = parsed
+	}
+	end := to.Add(24 * time.Hour)
+	if !from.Before(end) || end.Sub(from) > maxPaywallFunnelWindow {
+		response.BadRequest(c, "from must not be after to and the range at most 366 days")
+		return
+	}
+
+	query := service.PaywallFunnelQuery{From: from, To: end, Placement: c.Query("placement")}
+	if len(query.Placement) > maxPaywallPlacementLength {
+		response.BadRequest(c, "placement must be at most 100 characters")
+		return
+	}
+	if raw := c.Query("experiment_id"); raw != "" {
+		experimentID, err := uuid.Parse(raw)
+		if err != nil {
+			response.BadRequest(c, "Invalid experiment ID")
+			return
+		}
+		query.ExperimentID = &experimentID
+	}
+
+	ctx := c.Request.Context()
+	appID := httpmiddleware.GetAppID(c)
+	funnelID := paywallFunnelID(appID, query)
+	if cached, err := h.cache.GetFunnelData(ctx, funnelID, from, to); err == nil && cached != nil {
+		response.OK(c, cached)
+		return
+	}
+
+	segments, err := h.reporter.GetPaywallFunnel(ctx, appID, query)
+	if err != nil {
+		h.logger.Error("Failed to compute paywall funnel", zap.String("funnel_id", funnelID), zap.Error(err))
+		response.InternalError(c, "Failed to compute paywall funnel")
+		return
+	}
+
+	funnel := buildPaywallFunnel(funnelID, from, to, segments)
+	if err := h.cache.SetFunnelData(ctx, funnelID, from, to, funnel); err != nil {
+		h.logger.Warn("Failed to cache paywall funnel", zap.String("funnel_id", funnelID), zap.Error(err))
+	}
+
+	response.OK(c, funnel)
+}
+
+// paywallFunnelID keys the cache entry by app and filters; the date range is
+// added by the cache
+func paywallFunnelID(appID uuid.UUID, query service.PaywallFunnelQuery) string {
+	experiment := "all"
+	if query.ExperimentID != nil {
+		experiment = query.ExperimentID.String()
+	}
+	placement := "all"
+	if query.Placement != "" {
+		placement = query.Placement
+	}
+	return fmt.Sprintf("internal:%s:%s:%s", appID, experiment, placement)
+}
+
+// buildPaywallFunnel sums the segments into the overall funnel and keeps each
+// one as a breakdown entry
+func buildPaywallFunnel(funnelID string, from, to time.Time, segments []service.PaywallFunnelSegment) *cache.FunnelData {
+	var shown, initiated, completed int
+	breakdown := make([]cache.FunnelData, 0, len(segments))
+	for _, segment := range segments {
+		shown += segment.Shown
+		initiated += segment.Initiated
+		completed += segment.Completed
+
+		data := newPaywallFunnelData(funnelID, from, to, segment.Shown, segment.Initiated, segment.Completed)
+		data.Dimensions = map[string]string{
+			"experiment_id": segment.ExperimentID.String(),
+			"arm_id":        segment.ArmID.String(),
+			"arm_name":      segment.ArmName,
+			"placement":     segment.Placement,
+		}
+		breakdown = append(breakdown, *data)
+	}
+
+	funnel := newPaywallFunnelData(funnelID, from, to, shown, initiated, completed)
+	funnel.Segments = breakdown
+	return funnel
+}
+
+func newPaywallFunnelData(funnelID string, from, to time.Time, shown, initiated, completed int) *cache.FunnelData {
+	return &cache.FunnelData{
+		FunnelID: funnelID,
+		DateFrom: from,
+		DateTo:   to,
+		Steps: []cache.FunnelStep{
+			paywallFunnelStep(PaywallFunnelStepShown, "Paywall shown", shown, initiated),
+			paywallFunnelStep(PaywallFunnelStepInitiated, "Purchase initiated", initiated, completed),
+			paywallFunnelStep(PaywallFunnelStepCompleted, "Purchase completed", completed, completed),
+		},
+		TotalEntries:   shown,
+		TotalExits:     shown - completed,
+		ConversionRate: funnelRate(completed, shown),
+	}
+}
+
+// paywallFunnelStep reports the users who reached the step but not the next
+func paywallFunnelStep(id, name string, visitors, next int) cache.FunnelStep {
+	dropoff := visitors - next
+	return cache.FunnelStep{
+		StepID:      id,
+		StepName:    name,
+		Visitors:    visitors,
+		Dropoff:     dropoff,
+		DropoffRate: funnelRate(dropoff, visitors),
+	}
+}
+
+func funnelRate(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) / float64(total)
+}
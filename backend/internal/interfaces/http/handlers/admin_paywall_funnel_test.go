@@ -0,0 +1,127 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/infrastructure/cache"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/handlers"
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+)
+
+type mockPaywallFunnelReporter struct {
+	mock.Mock
+}
+
+func (m *mockPaywallFunnelReporter) GetPaywallFunnel(ctx context.Context, appID uuid.UUID, query service.PaywallFunnelQuery) ([]service.PaywallFunnelSegment, error) {
+	args := m.Called(ctx, appID, query)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]service.PaywallFunnelSegment), args.Error(1)
+}
+
+type fakeFunnelCache struct {
+	entries map[string]*cache.FunnelData
+}
+
+func (f *fakeFunnelCache) key(funnelID string, dateFrom, dateTo time.Time) string {
+	return funnelID + ":" + dateFrom.Format("2006-01-02") + ":" + dateTo.Format("2006-01-02")
+}
+
+func (f *fakeFunnelCache) GetFunnelData(ctx context.Context, funnelID string, dateFrom, dateTo time.Time) (*cache.FunnelData, error) {
+	data, ok := f.entries[f.key(funnelID, dateFrom, dateTo)]
+	if !ok {
+		return nil, errors.New("funnel data not found")
+	}
+	return data, nil
+}
+
+func (f *fakeFunnelCache) SetFunnelData(ctx context.Context, funnelID string, dateFrom, dateTo time.Time, data *cache.FunnelData) error {
+	f.entries[f.key(funnelID, dateFrom, dateTo)] = data
+	return nil
+}
+
+func newPaywallFunnelRouter(h *handlers.AdminPaywallFunnelHandler, appID uuid.UUID) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set(httpmiddleware.AppIDKey, appID)
+		c.Next()
+	})
+	r.GET("/v1/admin/analytics/funnels/internal", h.GetInternalFunnel)
+	return r
+}
+
+func TestGetInternalFunnel_BuildsStepsPerArmAndPlacement(t *testing.T) {
+	appID := uuid.New()
+	experimentID := uuid.New()
+	controlID, variantID := uuid.New(), uuid.New()
+	reporter := new(mockPaywallFunnelReporter)
+	funnelCache := &fakeFunnelCache{entries: map[string]*cache.FunnelData{}}
+	query := service.PaywallFunnelQuery{
+		From:         time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		To:           time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC),
+		ExperimentID: &experimentID,
+	}
+	reporter.On("GetPaywallFunnel", mock.Anything, appID, query).Return([]service.PaywallFunnelSegment{
+		{ExperimentID: experimentID, ArmID: controlID, ArmName: "control", Placement: "onboarding", Shown: 100, Initiated: 20, Completed: 5},
+		{ExperimentID: experimentID, ArmID: variantID, ArmName: "variant", Placement: "onboarding", Shown: 100, Initiated: 30, Completed: 15},
+	}, nil).Once()
+
+	router := newPaywallFunnelRouter(handlers.NewAdminPaywallFunnelHandler(reporter, funnelCache, zap.NewNop()), appID)
+	url := "/v1/admin/analytics/funnels/internal?from=2026-03-01&to=2026-03-31&experiment_id=" + experimentID.String()
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		require.Equal(t, http.StatusOK, rec.Code, "body=%s", rec.Body.String())
+
+		var body struct {
+			Data cache.FunnelData `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		funnel := body.Data
+		require.Len(t, funnel.Steps, 3)
+		assert.Equal(t, handlers.PaywallFunnelStepShown, funnel.Steps[0].StepID)
+		assert.Equal(t, 200, funnel.Steps[0].Visitors)
+		assert.Equal(t, 150, funnel.Steps[0].Dropoff)
+		assert.Equal(t, 50, funnel.Steps[1].Visitors)
+		assert.InDelta(t, 0.6, funnel.Steps[1].DropoffRate, 1e-9)
+		assert.Equal(t, 20, funnel.Steps[2].Visitors)
+		assert.Equal(t, 180, funnel.TotalExits)
+		assert.InDelta(t, 0.1, funnel.ConversionRate, 1e-9)
+		require.Len(t, funnel.Segments, 2)
+		assert.Equal(t, "variant", funnel.Segments[1].Dimensions["arm_name"])
+		assert.Equal(t, "onboarding", funnel.Segments[1].Dimensions["placement"])
+		assert.InDelta(t, 0.15, funnel.Segments[1].ConversionRate, 1e-9)
+	}
+
+	// The second request is served from the cache
+	reporter.AssertExpectations(t)
+}
+
+func TestGetInternalFunnel_RejectsInvalidRange(t *testing.T) {
+	reporter := new(mockPaywallFunnelReporter)
+	handler := handlers.NewAdminPaywallFunnelHandler(reporter, &fakeFunnelCache{entries: map[string]*cache.FunnelData{}}, zap.NewNop())
+	router := newPaywallFunnelRouter(handler, uuid.New())
+
+	for _, query := range []string{"from=2026-04-02&to=2026-04-01", "from=2025-01-01&to=2026-04-01", "to=04-01-2026", "experiment_id=nope"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/admin/analytics/funnels/internal?"+query, nil))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+	reporter.AssertNotCalled(t, "GetPaywallFunnel", mock.Anything, mock.Anything, mock.Anything)
+}
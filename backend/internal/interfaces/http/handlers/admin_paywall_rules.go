@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+type paywallRuleEvaluator interface {
+	DryRunForUser(ctx context.Context, appID, userID uuid.UUID, placement, country string) (*service.PaywallDecision, error)
+	DryRun(ctx context.Context, appID uuid.UUID, placement string, audience entity.PaywallAudience) (*service.PaywallDecision, error)
+}
+
+// PaywallRule is the admin representation of a personalization rule
+type PaywallRule struct {
+	ID           uuid.UUID                    `json:"id"`
+	Name         string                       `json:"name"`
+	Placement    string                       `json:"placement"`
+	Priority     int                          `json:"priority"`
+	Enabled      bool                         `json:"enabled"`
+	Conditions   entity.PaywallRuleConditions `json:"conditions"`
+	PaywallID    *uuid.UUID                   `json:"paywall_id,omitempty"`
+	OfferCode    string                       `json:"offer_code,omitempty"`
+	ExperimentID *uuid.UUID                   `json:"experiment_id,omitempty"`
+	ArmIDs       []uuid.UUID                  `json:"arm_ids"`
+	CreatedAt    time.Time                    `json:"created_at"`
+	UpdatedAt    time.Time                    `json:"updated_at"`
+}
+
+type paywallRuleUpsertRequest struct {
+	Name         string                       `json:"name"`
+	Placement    string                       `json:"placement"`
+	Priority     int                          `json:"priority"`
+	Enabled      *bool                        `json:"enabled"`
+	Conditions   entity.PaywallRuleConditions `json:"conditions"`
+	PaywallID    *uuid.UUID                   `json:"paywall_id"`
+	OfferCode    string                       `json:"offer_code"`
+	ExperimentID *uuid.UUID                   `json:"experiment_id"`
+	ArmIDs       []uuid.UUID                  `json:"arm_ids"`
+}
+
+type paywallRuleDryRunRequest struct {
+	Placement string `json:"placement"`
+	// UserID loads the audience (and segment membership) from a real user;
+	// Audience is used otherwise
+	UserID   *uuid.UUID             `json:"user_id"`
+	Country  string                 `json:"country"`
+	Audience entity.PaywallAudience `json:"audience"`
+}
+
+// AdminPaywallRulesHandler manages paywall personalization rules.
+type AdminPaywallRulesHandler struct {
+	rules     repository.PaywallRuleRepository
+	evaluator paywallRuleEvaluator
+}
+
+func NewAdminPaywallRulesHandler(rules repository.PaywallRuleRepository, evaluator paywallRuleEvaluator) *AdminPaywallRulesHandler {
+	return &AdminPaywallRulesHandler{rules: rules, evaluator: evaluator}
+}
+
+func toPaywallRule(r *entity.PaywallRule) PaywallRule {
+	armIDs := r.ArmIDs
+	if armIDs == nil {
+		armIDs = []uuid.UUID{}
+	}
+	return PaywallRule{
+		ID:           r.ID,
+		Name:         r.Name,
+		Placement:    r.Placement,
+		Priority:     r.Priority,
+		Enabled:      r.Enabled,
+		Conditions:   r.Conditions,
+		PaywallID:    r.PaywallID,
+		OfferCode:    r.OfferCode,
+		ExperimentID: r.ExperimentID,
+		ArmIDs:       armIDs,
+		CreatedAt:    r.CreatedAt,
+		UpdatedAt:    r.UpdatedAt,
+	}
+}
+
+// bindPaywallRule parses and validates the request and applies it to rule
+func bindPaywallRule(c *gin.Context, rule *entity.PaywallRule) bool {
+	var req paywallRuleUpsertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body")
+		return false
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	req.OfferCode = strings.TrimSpace(req.OfferCode)
+	if req.Name == "" {
+		response.BadRequest(c, "name is required")
+		return false
+	}
+	if req.PaywallID == nil && req.OfferCode == "" && req.ExperimentID == nil {
+		response.BadRequest(c, "one of paywall_id, offer_code or experiment_id is required")
+		return false
+	}
+	if len(req.ArmIDs) > 0 && req.ExperimentID == nil {
+		response.BadRequest(c, "arm_ids require experiment_id")
+		return false
+	}
+	if err := req.Conditions.Validate(); err != nil {
+		response.BadRequest(c, err.Error())
+		return false
+	}
+
+	rule.Name = req.Name
+	rule.Placement = strings.TrimSpace(req.Placement)
+	rule.Priority = req.Priority
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+	rule.Conditions = req.Conditions
+	rule.PaywallID = req.PaywallID
+	rule.OfferCode = req.OfferCode
+	rule.ExperimentID = req.ExperimentID
+	rule.ArmIDs = req.ArmIDs
+	return true
+}
+
+// ListPaywallRules GET /v1/admin/paywall-rules
+// Rules are returned in evaluation order (highest priority first).
+func (h *AdminPaywallRulesHandler) ListPaywallRules(c *gin.Context) {
+	rules, err := h.rules.List(c.Request.Context(), httpmiddleware.GetAppID(c))
+	if err != nil {
+		response.InternalError(c, "Failed to list paywall rules")
+		return
+	}
+
+	out := make([]PaywallRule, 0, len(rules))
+	for _, r := range rules {
+		out = append(out, toPaywallRule(r))
+	}
+	response.OK(c, gin.H{"rules": out, "total": len(out)})
+}
+
+// GetPaywallRule GET /v1/admin/paywall-rules/:id
+func (h *AdminPaywallRulesHandler) GetPaywallRule(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid rule ID")
+		return
+	}
+
+	rule, err := h.rules.GetByID(c.Request.Context(), httpmiddleware.GetAppID(c), id)
+	if errors.Is(err, domainErrors.ErrNotFound) {
+		response.NotFound(c, "Paywall rule not found")
+		return
+	}
+	if err != nil {
+		response.InternalError(c, "Failed to get paywall rule")
+		return
+	}
+	response.OK(c, toPaywallRule(rule))
+}
+
+// CreatePaywallRule POST /v1/admin/paywall-rules
+func (h *AdminPaywallRulesHandler) CreatePaywallRule(c *gin.Context) {
+	rule := entity.NewPaywallRule(httpmiddleware.GetAppID(c), "", "", 0)
+	if !bindPaywallRule(c, rule) {
+		return
+	}
+
+	if err := h.rules.Create(c.Request.Context(), rule); err != nil {
+		response.InternalError(c, "Failed to create paywall rule")
+		return
+	}
+	response.Created(c, toPaywallRule(rule))
+}
+
+// UpdatePaywallRule PUT /v1/admin/paywall-rules/:id
+func (h *AdminPaywallRulesHandler) UpdatePaywallRule(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid rule ID")
+		return
+	}
+
+	rule, err := h.rules.GetByID(c.Request.Context(), httpmiddleware.GetAppID(c), id)
+	if errors.Is(err, domainErrors.ErrNotFound) {
+		response.NotFound(c, "Paywall rule not found")
+		return
+	}
+	if err != nil {
+		response.InternalError(c, "Failed to get paywall rule")
+		return
+	}
+	if !bindPaywallRule(c, rule) {
+		return
+	}
+	rule.UpdatedAt = time.Now()
+
+	if err := h.rules.Update(c.Request.Context(), rule); err != nil {
+		if errors.Is(err, domainErrors.ErrNotFound) {
+			response.NotFound(c, "Paywall rule not found")
+			return
+		}
+		response.InternalError(c, "Failed to update paywall rule")
+		return
+	}
+	response.OK(c, toPaywallRule(rule))
+}
+
+// DeletePaywallRule DELETE /v1/admin/paywall-rules/:id
+func (h *AdminPaywallRulesHandler) DeletePaywallRule(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid rule ID")
+		return
+	}
+
+	err = h.rules.Delete(c.Request.Context(), httpmiddleware.GetAppID(c), id)
+	if errors.Is(err, domainErrors.ErrNotFound) {
+		response.NotFound(c, "Paywall rule not found")
+		return
+	}
+	if err != nil {
+		response.InternalError(c, "Failed to delete paywall rule")
+		return
+	}
+	response.NoContent(c)
+}
+
+// DryRunPaywallRules POST /v1/admin/paywall-rules/dry-run
+// Evaluates the app's rules for a user or a hand-written audience and returns
+// the decision with a per-rule trace. No arm is assigned.
+func (h *AdminPaywallRulesHandler) DryRunPaywallRules(c *gin.Context) {
+	var req paywallRuleDryRunRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body")
+		return
+	}
+
+	var decision *service.PaywallDecision
+	var err error
+	if req.UserID != nil {
+		decision, err = h.evaluator.DryRunForUser(c.Request.Context(), httpmiddleware.GetAppID(c), *req.UserID, req.Placement, req.Country)
+		if errors.Is(err, domainErrors.ErrUserNotFound) {
+			response.NotFound(c, "User not found")
+			return
+		}
+	} else {
+		decision, err = h.evaluator.DryRun(c.Request.Context(), httpmiddleware.GetAppID(c), req.Placement, req.Audience)
+	}
+	if err != nil && !errors.Is(err, service.ErrNoPaywallRuleMatched) {
+		response.InternalError(c, "Failed to evaluate paywall rules")
+		return
+	}
+	response.OK(c, gin.H{"matched": err == nil, "decision": decision})
+}
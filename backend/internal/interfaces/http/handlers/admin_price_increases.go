@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+	"github.com/bivex/paywall-iap/internal/worker/tasks"
+)
+
+type pendingPriceIncreaseLister interface {
+	ListPending(ctx context.Context, appID uuid.UUID, limit, offset int) ([]*entity.PriceIncreaseConsent, int, error)
+}
+
+// PriceIncreaseConsent is a subscriber's answer to a store price increase
+type PriceIncreaseConsent struct {
+	ID             uuid.UUID  `json:"id"`
+	SubscriptionID uuid.UUID  `json:"subscription_id"`
+	UserID         uuid.UUID  `json:"user_id"`
+	Platform       string     `json:"platform"`
+	ProductID      string     `json:"product_id"`
+	Status         string     `json:"status"`
+	NewPrice       *float64   `json:"new_price"`
+	Currency       string     `json:"currency,omitempty"`
+	NotifiedAt     time.Time  `json:"notified_at"`
+	PromptCount    int        `json:"prompt_count"`
+	LastPromptedAt *time.Time `json:"last_prompted_at"`
+}
+
+type priceIncreasePromptRequest struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+// AdminPriceIncreasesHandler lists subscribers yet to consent to a store
+// price increase and prompts them
+type AdminPriceIncreasesHandler struct {
+	consents pendingPriceIncreaseLister
+	queue    taskEnqueuer
+	logger   *zap.Logger
+}
+
+func NewAdminPriceIncreasesHandler(consents pendingPriceIncreaseLister, queue taskEnqueuer, logger *zap.Logger) *AdminPriceIncreasesHandler {
+	return &AdminPriceIncreasesHandler{consents: consents, queue: queue, logger: logger}
+}
+
+// ListPendingConsents GET /v1/admin/price-increases/pending?page=1&limit=50
+func (h *AdminPriceIncreasesHandler) ListPendingConsents(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	appID := httpmiddleware.GetAppID(c)
+	consents, total, err := h.consents.ListPending(c.Request.Context(), appID, limit, (page-1)*limit)
+	if err != nil {
+		h.logger.Error("Failed to list pending price increases", zap.String("app_id", appID.String()), zap.Error(err))
+		response.InternalError(c, "Failed to list pending price increases")
+		return
+	}
+
+	rows := make([]PriceIncreaseConsent, 0, len(consents))
+	for _, consent := range consents {
+		rows = append(rows, PriceIncreaseConsent{
+			ID:             consent.ID,
+			SubscriptionID: consent.SubscriptionID,
+			UserID:         consent.UserID,
+			Platform:       consent.Platform,
+			ProductID:      consent.ProductID,
+			Status:         string(consent.Status),
+			NewPrice:       consent.NewPrice,
+			Currency:       consent.Currency,
+			NotifiedAt:     consent.NotifiedAt,
+			PromptCount:    consent.PromptCount,
+			LastPromptedAt: consent.LastPromptedAt,
+		})
+	}
+	response.OK(c, gin.H{
+		"rows":        rows,
+		"total":       total,
+		"page":        page,
+		"limit":       limit,
+		"total_pages": (total + limit - 1) / limit,
+	})
+}
+
+// PromptPendingConsents POST /v1/admin/price-increases/prompt
+// Asks the app's pending subscribers to consent in the background. Title and
+// body are optional; subscribers prompted recently are skipped.
+func (h *AdminPriceIncreasesHandler) PromptPendingConsents(c *gin.Context) {
+	var req priceIncreasePromptRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		response.BadRequest(c, "Invalid request body")
+		return
+	}
+
+	appID := httpmiddleware.GetAppID(c)
+	payload, _ := json.Marshal(tasks.PromptPriceIncreasePayload{
+		AppID: appID.String(),
+		Title: req.Title,
+		Body:  req.Body,
+	})
+	if _, err := h.queue.Enqueue(asynq.NewTask(tasks.TypePromptPriceIncreaseConsent, payload)); err != nil {
+		h.logger.Error("Failed to enqueue price increase prompts", zap.String("app_id", appID.String()), zap.Error(err))
+		response.InternalError(c, "Failed to enqueue price increase prompts")
+		return
+	}
+	response.Send(c, http.StatusAccepted, gin.H{"queued": true})
+}
@@ -0,0 +1,90 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/handlers"
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+	"github.com/bivex/paywall-iap/internal/worker/tasks"
+)
+
+type fakePendingPriceIncreases struct {
+	appID         uuid.UUID
+	limit, offset int
+}
+
+func (f *fakePendingPriceIncreases) ListPending(ctx context.Context, appID uuid.UUID, limit, offset int) ([]*entity.PriceIncreaseConsent, int, error) {
+	f.appID, f.limit, f.offset = appID, limit, offset
+	consent := entity.NewPriceIncreaseConsent(appID, uuid.New(), uuid.New(), "ios", "com.app.monthly", entity.PriceIncreasePending, time.Now())
+	return []*entity.PriceIncreaseConsent{consent}, 21, nil
+}
+
+func priceIncreaseRouter(h *handlers.AdminPriceIncreasesHandler, appID uuid.UUID) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set(httpmiddleware.AppIDKey, appID)
+		c.Next()
+	})
+	r.GET("/v1/admin/price-increases/pending", h.ListPendingConsents)
+	r.POST("/v1/admin/price-increases/prompt", h.PromptPendingConsents)
+	return r
+}
+
+func TestListPendingConsents_Pages(t *testing.T) {
+	lister := &fakePendingPriceIncreases{}
+	appID := uuid.New()
+	r := priceIncreaseRouter(handlers.NewAdminPriceIncreasesHandler(lister, &recordingEnqueuer{}, zap.NewNop()), appID)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/price-increases/pending?page=3&limit=10", nil))
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, appID, lister.appID)
+	assert.Equal(t, 10, lister.limit)
+	assert.Equal(t, 20, lister.offset)
+	var body struct {
+		Data struct {
+			Rows       []handlers.PriceIncreaseConsent `json:"rows"`
+			Total      int                             `json:"total"`
+			TotalPages int                             `json:"total_pages"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.Len(t, body.Data.Rows, 1)
+	assert.Equal(t, "pending", body.Data.Rows[0].Status)
+	assert.Equal(t, 21, body.Data.Total)
+	assert.Equal(t, 3, body.Data.TotalPages)
+}
+
+func TestPromptPendingConsents_EnqueuesForApp(t *testing.T) {
+	queue := &recordingEnqueuer{}
+	appID := uuid.New()
+	r := priceIncreaseRouter(handlers.NewAdminPriceIncreasesHandler(&fakePendingPriceIncreases{}, queue, zap.NewNop()), appID)
+
+	for _, body := range []string{"", `{"title":"New price"}`} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/admin/price-increases/prompt", strings.NewReader(body)))
+		require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	}
+
+	require.Len(t, queue.tasks, 2)
+	assert.Equal(t, tasks.TypePromptPriceIncreaseConsent, queue.tasks[1].Type())
+	var payload tasks.PromptPriceIncreasePayload
+	require.NoError(t, json.Unmarshal(queue.tasks[1].Payload(), &payload))
+	assert.Equal(t, appID.String(), payload.AppID)
+	assert.Equal(t, "New price", payload.Title)
+}
@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+type priceRolloutAnalyzer interface {
+	Results(ctx context.Context, rollout *entity.PriceRollout) (*service.PriceRolloutResults, error)
+}
+
+// PriceRollout is the admin representation of a staged price rollout
+type PriceRollout struct {
+	ID             uuid.UUID                  `json:"id"`
+	PricingTierID  uuid.UUID                  `json:"pricing_tier_id"`
+	Region         string                     `json:"region"`
+	Currency       string                     `json:"currency"`
+	MonthlyPrice   *float64                   `json:"monthly_price,omitempty"`
+	AnnualPrice    *float64                   `json:"annual_price,omitempty"`
+	LifetimePrice  *float64                   `json:"lifetime_price,omitempty"`
+	Stages         []entity.PriceRolloutStage `json:"stages"`
+	Status         string                     `json:"status"`
+	CurrentPercent int                        `json:"current_percent"`
+	PausedAt       *time.Time                 `json:"paused_at,omitempty"`
+	ExperimentID   uuid.UUID                  `json:"experiment_id"`
+	HoldoutArmID   uuid.UUID                  `json:"holdout_arm_id"`
+	RampArmID      uuid.UUID                  `json:"ramp_arm_id"`
+	CreatedAt      time.Time                  `json:"created_at"`
+	UpdatedAt      time.Time                  `json:"updated_at"`
+}
+
+type priceRolloutCreateRequest struct {
+	PricingTierID uuid.UUID                  `json:"pricing_tier_id" binding:"required"`
+	Region        string                     `json:"region"`
+	Currency      string                     `json:"currency"`
+	MonthlyPrice  *float64                   `json:"monthly_price"`
+	AnnualPrice   *float64                   `json:"annual_price"`
+	LifetimePrice *float64                   `json:"lifetime_price"`
+	Stages        []entity.PriceRolloutStage `json:"stages"`
+}
+
+// AdminPriceRolloutsHandler schedules staged price changes per pricing tier
+// and region.
+type AdminPriceRolloutsHandler struct {
+	rollouts repository.PriceRolloutRepository
+	analyzer priceRolloutAnalyzer
+	now      func() time.Time
+}
+
+func NewAdminPriceRolloutsHandler(rollouts repository.PriceRolloutRepository, analyzer priceRolloutAnalyzer) *AdminPriceRolloutsHandler {
+	return &AdminPriceRolloutsHandler{rollouts: rollouts, analyzer: analyzer, now: time.Now}
+}
+
+func toPriceRollout(r *entity.PriceRollout, now time.Time) PriceRollout {
+	return PriceRollout{
+		ID:             r.ID,
+		PricingTierID:  r.PricingTierID,
+		Region:         r.Region,
+		Currency:       r.Currency,
+		MonthlyPrice:   r.MonthlyPrice,
+		AnnualPrice:    r.AnnualPrice,
+		LifetimePrice:  r.LifetimePrice,
+		Stages:         r.Stages,
+		Status:         string(r.Status),
+		CurrentPercent: r.PercentAt(now),
+		PausedAt:       r.PausedAt,
+		ExperimentID:   r.ExperimentID,
+		HoldoutArmID:   r.HoldoutArmID,
+		RampArmID:      r.RampArmID,
+		CreatedAt:      r.CreatedAt,
+		UpdatedAt:      r.UpdatedAt,
+	}
+}
+
+// loadPriceRollout resolves :id within the app, writing the error response on failure
+func (h *AdminPriceRolloutsHandler) loadPriceRollout(c *gin.Context) (*entity.PriceRollout, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid rollout ID")
+		return nil, false
+	}
+	rollout, err := h.rollouts.GetByID(c.Request.Context(), httpmiddleware.GetAppID(c), id)
+	if errors.Is(err, domainErrors.ErrNotFound) {
+		response.NotFound(c, "Price rollout not found")
+		return nil, false
+	}
+	if err != nil {
+		response.InternalError(c, "Failed to get price rollout")
+		return nil, false
+	}
+	return rollout, true
+}
+
+// ListPriceRollouts GET /v1/admin/price-rollouts
+func (h *AdminPriceRolloutsHandler) ListPriceRollouts(c *gin.Context) {
+	rollouts, err := h.rollouts.List(c.Request.Context(), httpmiddleware.GetAppID(c))
+	if err != nil {
+		response.InternalError(c, "Failed to list price rollouts")
+		return
+	}
+
+	now := h.now()
+	out := make([]PriceRollout, 0, len(rollouts))
+	for _, r := range rollouts {
+		out = append(out, toPriceRollout(r, now))
+	}
+	response.OK(c, gin.H{"rollouts": out, "total": len(out)})
+}
+
+// GetPriceRollout GET /v1/admin/price-rollouts/:id
+func (h *AdminPriceRolloutsHandler) GetPriceRollout(c *gin.Context) {
+	rollout, ok := h.loadPriceRollout(c)
+	if !ok {
+		return
+	}
+	response.OK(c, toPriceRollout(rollout, h.now()))
+}
+
+// CreatePriceRollout POST /v1/admin/price-rollouts
+// Creates the rollout and its holdout experiment. Users see the new price
+// once the first stage is reached.
+func (h *AdminPriceRolloutsHandler) CreatePriceRollout(c *gin.Context) {
+	var req priceRolloutCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body")
+		return
+	}
+
+	rollout := entity.NewPriceRollout(httpmiddleware.GetAppID(c), req.PricingTierID, req.Region, req.Currency, req.Stages)
+	rollout.MonthlyPrice = req.MonthlyPrice
+	rollout.AnnualPrice = req.AnnualPrice
+	rollout.LifetimePrice = req.LifetimePrice
+	if err := rollout.Validate(); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	name := "Price rollout " + rollout.ID.String()[:8]
+	if rollout.Region != "" {
+		name += " (" + rollout.Region + ")"
+	}
+	err := h.rollouts.Create(c.Request.Context(), rollout, name)
+	var conflict *domainErrors.ConflictError
+	switch {
+	case errors.Is(err, domainErrors.ErrNotFound):
+		response.BadRequest(c, "pricing_tier_id does not belong to this app")
+		return
+	case errors.As(err, &conflict):
+		response.Conflict(c, "The pricing tier already has an open rollout in this region")
+		return
+	case err != nil:
+		response.InternalError(c, "Failed to create price rollout")
+		return
+	}
+	response.Created(c, toPriceRollout(rollout, h.now()))
+}
+
+// PausePriceRollout POST /v1/admin/price-rollouts/:id/pause
+// Users already on the new price keep it; nobody else is added.
+func (h *AdminPriceRolloutsHandler) PausePriceRollout(c *gin.Context) {
+	h.transition(c, func(r *entity.PriceRollout, now time.Time) error { return r.Pause(now) })
+}
+
+// ResumePriceRollout POST /v1/admin/price-rollouts/:id/resume
+func (h *AdminPriceRolloutsHandler) ResumePriceRollout(c *gin.Context) {
+	h.transition(c, func(r *entity.PriceRollout, now time.Time) error { return r.Resume(now) })
+}
+
+// CompletePriceRollout POST /v1/admin/price-rollouts/:id/complete
+// Writes the new prices to the pricing tier and ends the holdout experiment.
+func (h *AdminPriceRolloutsHandler) CompletePriceRollout(c *gin.Context) {
+	h.transition(c, func(r *entity.PriceRollout, now time.Time) error {
+		return r.Finish(entity.PriceRolloutCompleted, now)
+	})
+}
+
+// RollbackPriceRollout POST /v1/admin/price-rollouts/:id/rollback
+// Returns everyone to the tier's current prices.
+func (h *AdminPriceRolloutsHandler) RollbackPriceRollout(c *gin.Context) {
+	h.transition(c, func(r *entity.PriceRollout, now time.Time) error {
+		return r.Finish(entity.PriceRolloutRolledBack, now)
+	})
+}
+
+// GetPriceRolloutResults GET /v1/admin/price-rollouts/:id/results
+// Compares conversion and revenue per user of the ramp cohort with the holdout.
+func (h *AdminPriceRolloutsHandler) GetPriceRolloutResults(c *gin.Context) {
+	rollout, ok := h.loadPriceRollout(c)
+	if !ok {
+		return
+	}
+	results, err := h.analyzer.Results(c.Request.Context(), rollout)
+	if err != nil {
+		response.InternalError(c, "Failed to compute rollout results")
+		return
+	}
+	response.OK(c, results)
+}
+
+func (h *AdminPriceRolloutsHandler) transition(c *gin.Context, apply func(*entity.PriceRollout, time.Time) error) {
+	rollout, ok := h.loadPriceRollout(c)
+	if !ok {
+		return
+	}
+	now := h.now()
+	if err := apply(rollout, now); err != nil {
+		response.Conflict(c, err.Error())
+		return
+	}
+
+	if err := h.rollouts.Update(c.Request.Context(), rollout); err != nil {
+		if errors.Is(err, domainErrors.ErrNotFound) {
+			response.NotFound(c, "Price rollout not found")
+			return
+		}
+		response.InternalError(c, "Failed to update price rollout")
+		return
+	}
+	response.OK(c, toPriceRollout(rollout, now))
+}
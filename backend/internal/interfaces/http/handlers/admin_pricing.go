@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"regexp"
 	"strings"
 	"time"
 
@@ -28,6 +29,16 @@ type PricingTier struct {
 	IsActive     bool      `json:"is_active"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
+	IntroOffer   *PricingTierIntroOffer `json:"intro_offer"`
+}
+
+// PricingTierIntroOffer is the introductory price shown on the paywall to users
+// who are still eligible for it (see GET /v1/products/:id/eligibility).
+type PricingTierIntroOffer struct {
+	Price       *float64 `json:"price"`
+	Period      string   `json:"period"`
+	PaymentMode string   `json:"payment_mode"`
+	Cycles      int      `json:"cycles"`
 }
 
 type pricingTierUpsertRequest struct {
@@ -39,6 +50,7 @@ type pricingTierUpsertRequest struct {
 	Currency     string   `json:"currency"`
 	Features     []string `json:"features"`
 	IsActive     bool     `json:"is_active"`
+	IntroOffer   *PricingTierIntroOffer `json:"intro_offer"`
 }
 
 type pricingTierScanner interface {
@@ -59,6 +71,14 @@ func normalizePricingTierRequest(req pricingTierUpsertRequest) pricingTierUpsert
 	}
 	req.Features = features
 
+	if req.IntroOffer != nil {
+		req.IntroOffer.Period = strings.ToUpper(strings.TrimSpace(req.IntroOffer.Period))
+		req.IntroOffer.PaymentMode = strings.TrimSpace(req.IntroOffer.PaymentMode)
+		if req.IntroOffer.Cycles == 0 {
+			req.IntroOffer.Cycles = 1
+		}
+	}
+
 	return req
 }
 
@@ -86,9 +106,47 @@ func validatePricingTierRequest(req pricingTierUpsertRequest) string {
 	if req.LifetimePrice != nil && *req.LifetimePrice <= 0 {
 		return "Lifetime price must be greater than zero"
 	}
+	if req.IntroOffer != nil {
+		return validatePricingTierIntroOffer(*req.IntroOffer)
+	}
 	return ""
 }
 
+var introPeriodPattern = regexp.MustCompile(`^P[1-9][0-9]*[DWMY]$`)
+
+func validatePricingTierIntroOffer(offer PricingTierIntroOffer) string {
+	if !introPeriodPattern.MatchString(offer.Period) {
+		return "Intro period must be an ISO-8601 duration such as P1W or P3M"
+	}
+	if offer.Cycles < 1 {
+		return "Intro cycles must be at least 1"
+	}
+	switch offer.PaymentMode {
+	case "free_trial":
+		if offer.Price != nil && *offer.Price != 0 {
+			return "Free trial intro offers cannot have a price"
+		}
+	case "pay_as_you_go", "pay_up_front":
+		if offer.Price == nil || *offer.Price <= 0 {
+			return "Intro price must be greater than zero"
+		}
+	default:
+		return "Intro payment mode must be one of free_trial, pay_as_you_go, pay_up_front"
+	}
+	return ""
+}
+
+// introOfferColumns flattens the optional intro offer into the nullable pricing_tiers columns.
+func introOfferColumns(offer *PricingTierIntroOffer) (price *float64, period, paymentMode *string, cycles *int) {
+	if offer == nil {
+		return nil, nil, nil, nil
+	}
+	if offer.PaymentMode != "free_trial" {
+		price = offer.Price
+	}
+	return price, &offer.Period, &offer.PaymentMode, &offer.Cycles
+}
+
 func scanPricingTier(scanner pricingTierScanner) (PricingTier, error) {
 	var (
 		id          uuid.UUID
@@ -102,6 +160,10 @@ func scanPricingTier(scanner pricingTierScanner) (PricingTier, error) {
 		isActive    bool
 		createdAt   time.Time
 		updatedAt   time.Time
+		introPrice  sql.NullFloat64
+		introPeriod string
+		introMode   string
+		introCycles sql.NullInt32
 	)
 
 	err := scanner.Scan(
@@ -116,6 +178,10 @@ func scanPricingTier(scanner pricingTierScanner) (PricingTier, error) {
 		&isActive,
 		&createdAt,
 		&updatedAt,
+		&introPrice,
+		&introPeriod,
+		&introMode,
+		&introCycles,
 	)
 	if err != nil {
 		return PricingTier{}, err
@@ -150,6 +216,17 @@ func scanPricingTier(scanner pricingTierScanner) (PricingTier, error) {
 		value := lifetime.Float64
 		tier.LifetimePrice = &value
 	}
+	if introMode != "" {
+		tier.IntroOffer = &PricingTierIntroOffer{
+			Period:      introPeriod,
+			PaymentMode: introMode,
+			Cycles:      int(introCycles.Int32),
+		}
+		if introPrice.Valid {
+			value := introPrice.Float64
+			tier.IntroOffer.Price = &value
+		}
+	}
 
 	return tier, nil
 }
@@ -195,6 +272,9 @@ func (h *AdminHandler) logPricingTierAction(c *gin.Context, action string, tier
 	if tier.LifetimePrice != nil {
 		details["lifetime_price"] = *tier.LifetimePrice
 	}
+	if tier.IntroOffer != nil {
+		details["intro_offer"] = tier.IntroOffer
+	}
 
 	_ = h.auditService.LogAction(c.Request.Context(), adminID, action, "pricing_tier", pricingTierTargetID(tier.ID), details)
 }
@@ -212,7 +292,11 @@ func (h *AdminHandler) ListPricingTiers(c *gin.Context) {
 		       COALESCE(features, '[]'::jsonb),
 		       is_active,
 		       created_at,
-		       updated_at
+		       updated_at,
+		       intro_price::double precision,
+		       COALESCE(intro_period, ''),
+		       COALESCE(intro_payment_mode, ''),
+		       intro_cycles
 		FROM pricing_tiers
 		WHERE deleted_at IS NULL AND app_id = $1
 		ORDER BY created_at DESC`, appID)
@@ -256,6 +340,7 @@ func (h *AdminHandler) CreatePricingTier(c *gin.Context) {
 		response.InternalError(c, "Failed to encode pricing tier features")
 		return
 	}
+	introPrice, introPeriod, introMode, introCycles := introOfferColumns(req.IntroOffer)
 
 	appID := httpmiddleware.GetAppID(c)
 
@@ -270,9 +355,13 @@ func (h *AdminHandler) CreatePricingTier(c *gin.Context) {
 			currency,
 			features,
 			is_active,
+			intro_price,
+			intro_period,
+			intro_payment_mode,
+			intro_cycles,
 			updated_at
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8::jsonb, $9, now())
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8::jsonb, $9, $10, $11, $12, $13, now())
 		RETURNING id,
 		          name,
 		          COALESCE(description, ''),
@@ -283,7 +372,11 @@ func (h *AdminHandler) CreatePricingTier(c *gin.Context) {
 		          COALESCE(features, '[]'::jsonb),
 		          is_active,
 		          created_at,
-		          updated_at`,
+		          updated_at,
+		          intro_price::double precision,
+		          COALESCE(intro_period, ''),
+		          COALESCE(intro_payment_mode, ''),
+		          intro_cycles`,
 		appID,
 		req.Name,
 		req.Description,
@@ -293,6 +386,10 @@ func (h *AdminHandler) CreatePricingTier(c *gin.Context) {
 		req.Currency,
 		featuresJSON,
 		req.IsActive,
+		introPrice,
+		introPeriod,
+		introMode,
+		introCycles,
 	))
 	if err != nil {
 		if pricingTierConflict(err) {
@@ -330,6 +427,7 @@ func (h *AdminHandler) UpdatePricingTier(c *gin.Context) {
 		response.InternalError(c, "Failed to encode pricing tier features")
 		return
 	}
+	introPrice, introPeriod, introMode, introCycles := introOfferColumns(req.IntroOffer)
 
 	tier, err := scanPricingTier(h.dbPool.QueryRow(c.Request.Context(), `
 		UPDATE pricing_tiers
@@ -341,6 +439,10 @@ func (h *AdminHandler) UpdatePricingTier(c *gin.Context) {
 		    currency = $7,
 		    features = $8::jsonb,
 		    is_active = $9,
+		    intro_price = $10,
+		    intro_period = $11,
+		    intro_payment_mode = $12,
+		    intro_cycles = $13,
 		    updated_at = now()
 		WHERE id = $1 AND deleted_at IS NULL
 		RETURNING id,
@@ -353,7 +455,11 @@ func (h *AdminHandler) UpdatePricingTier(c *gin.Context) {
 		          COALESCE(features, '[]'::jsonb),
 		          is_active,
 		          created_at,
-		          updated_at`,
+		          updated_at,
+		          intro_price::double precision,
+		          COALESCE(intro_period, ''),
+		          COALESCE(intro_payment_mode, ''),
+		          intro_cycles`,
 		tierID,
 		req.Name,
 		req.Description,
@@ -363,6 +469,10 @@ func (h *AdminHandler) UpdatePricingTier(c *gin.Context) {
 		req.Currency,
 		featuresJSON,
 		req.IsActive,
+		introPrice,
+		introPeriod,
+		introMode,
+		introCycles,
 	))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -411,7 +521,11 @@ func (h *AdminHandler) setPricingTierActive(c *gin.Context, isActive bool) {
 		          COALESCE(features, '[]'::jsonb),
 		          is_active,
 		          created_at,
-		          updated_at`,
+		          updated_at,
+		          intro_price::double precision,
+		          COALESCE(intro_period, ''),
+		          COALESCE(intro_payment_mode, ''),
+		          intro_cycles`,
 		tierID,
 		isActive,
 	))
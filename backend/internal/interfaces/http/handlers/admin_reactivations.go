@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+type experimentReactivationReporter interface {
+	ExperimentReport(ctx context.Context, appID, experimentID uuid.UUID) (*service.ExperimentReactivationReport, error)
+}
+
+// AdminReactivationHandler reports the churned subscribers win-back
+// experiments brought back
+type AdminReactivationHandler struct {
+	reactivations experimentReactivationReporter
+}
+
+func NewAdminReactivationHandler(reactivations experimentReactivationReporter) *AdminReactivationHandler {
+	return &AdminReactivationHandler{reactivations: reactivations}
+}
+
+// GetExperimentReactivations GET /v1/admin/experiments/:id/reactivations
+func (h *AdminReactivationHandler) GetExperimentReactivations(c *gin.Context) {
+	experimentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid experiment ID")
+		return
+	}
+	report, err := h.reactivations.ExperimentReport(c.Request.Context(), httpmiddleware.GetAppID(c), experimentID)
+	if errors.Is(err, service.ErrExperimentNotFound) {
+		response.NotFound(c, "Experiment not found")
+		return
+	}
+	if err != nil {
+		response.InternalError(c, "Failed to load experiment reactivations")
+		return
+	}
+	response.OK(c, report)
+}
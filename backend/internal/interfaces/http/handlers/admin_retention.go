@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+type retentionStatus interface {
+	Status(ctx context.Context) ([]service.RetentionState, error)
+}
+
+// AdminRetentionHandler reports data retention cleanup results
+type AdminRetentionHandler struct {
+	retention retentionStatus
+}
+
+func NewAdminRetentionHandler(retention retentionStatus) *AdminRetentionHandler {
+	return &AdminRetentionHandler{retention: retention}
+}
+
+// GetRetentionStatus GET /v1/admin/retention
+func (h *AdminRetentionHandler) GetRetentionStatus(c *gin.Context) {
+	policies, err := h.retention.Status(c.Request.Context())
+	if err != nil {
+		response.InternalError(c, "Failed to get retention status")
+		return
+	}
+	response.OK(c, gin.H{"policies": policies})
+}
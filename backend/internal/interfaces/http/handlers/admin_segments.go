@@ -0,0 +1,382 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+	"github.com/bivex/paywall-iap/internal/worker/tasks"
+)
+
+type segmentEvaluator interface {
+	Attributes(ctx context.Context, userID uuid.UUID, country string) (entity.SegmentAttributes, error)
+	Contains(ctx context.Context, segment *entity.Segment, userID uuid.UUID, country string) (bool, error)
+}
+
+type taskEnqueuer interface {
+	Enqueue(task *asynq.Task, opts ...asynq.Option) (*asynq.TaskInfo, error)
+}
+
+// Segment is the admin representation of an audience segment
+type Segment struct {
+	ID             uuid.UUID               `json:"id"`
+	Name           string                  `json:"name"`
+	Description    string                  `json:"description"`
+	Definition     entity.SegmentPredicate `json:"definition"`
+	Materialized   bool                    `json:"materialized"`
+	MemberCount    *int                    `json:"member_count,omitempty"`
+	MaterializedAt *time.Time              `json:"materialized_at,omitempty"`
+	CreatedAt      time.Time               `json:"created_at"`
+	UpdatedAt      time.Time               `json:"updated_at"`
+}
+
+type segmentUpsertRequest struct {
+	Name         string                  `json:"name"`
+	Description  string                  `json:"description"`
+	Definition   entity.SegmentPredicate `json:"definition"`
+	Materialized bool                    `json:"materialized"`
+}
+
+type segmentEvaluateRequest struct {
+	UserID  uuid.UUID `json:"user_id" binding:"required"`
+	Country string    `json:"country"`
+}
+
+type segmentNotifyRequest struct {
+	Title string `json:"title" binding:"required"`
+	Body  string `json:"body" binding:"required"`
+	// SendTimeExperimentID sends each push at the hour the experiment picks
+	SendTimeExperimentID *uuid.UUID `json:"send_time_experiment_id"`
+}
+
+type sendTimeExperimentLookup interface {
+	GetExperiment(ctx context.Context, appID, experimentID uuid.UUID) (*service.SendTimeExperiment, error)
+}
+
+type experimentSegmentRequest struct {
+	// SegmentID nil removes the targeting
+	SegmentID *uuid.UUID `json:"segment_id"`
+}
+
+// AdminSegmentsHandler manages reusable audience segments.
+type AdminSegmentsHandler struct {
+	segments  repository.SegmentRepository
+	evaluator segmentEvaluator
+	queue     taskEnqueuer
+	sendTime  sendTimeExperimentLookup
+}
+
+func NewAdminSegmentsHandler(segments repository.SegmentRepository, evaluator segmentEvaluator, queue taskEnqueuer) *AdminSegmentsHandler {
+	return &AdminSegmentsHandler{segments: segments, evaluator: evaluator, queue: queue}
+}
+
+// WithSendTime lets segment notifications name a send-time experiment
+func (h *AdminSegmentsHandler) WithSendTime(experiments sendTimeExperimentLookup) *AdminSegmentsHandler {
+	h.sendTime = experiments
+	return h
+}
+
+func toSegment(s *entity.Segment) Segment {
+	out := Segment{
+		ID:           s.ID,
+		Name:         s.Name,
+		Description:  s.Description,
+		Definition:   s.Definition,
+		Materialized: s.Materialized,
+		CreatedAt:    s.CreatedAt,
+		UpdatedAt:    s.UpdatedAt,
+	}
+	if s.Materialized {
+		count := s.MemberCount
+		out.MemberCount = &count
+		out.MaterializedAt = s.MaterializedAt
+	}
+	return out
+}
+
+// bindSegment parses and validates the request and applies it to segment
+func bindSegment(c *gin.Context, segment *entity.Segment) bool {
+	var req segmentUpsertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body")
+		return false
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		response.BadRequest(c, "name is required")
+		return false
+	}
+
+	segment.Name = req.Name
+	segment.Description = req.Description
+	segment.Definition = req.Definition
+	segment.Materialized = req.Materialized
+	if err := segment.Validate(); err != nil {
+		response.BadRequest(c, err.Error())
+		return false
+	}
+	return true
+}
+
+// loadSegment resolves :id within the app, writing the error response on failure
+func (h *AdminSegmentsHandler) loadSegment(c *gin.Context) (*entity.Segment, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid segment ID")
+		return nil, false
+	}
+	segment, err := h.segments.GetByID(c.Request.Context(), httpmiddleware.GetAppID(c), id)
+	if errors.Is(err, domainErrors.ErrNotFound) {
+		response.NotFound(c, "Segment not found")
+		return nil, false
+	}
+	if err != nil {
+		response.InternalError(c, "Failed to get segment")
+		return nil, false
+	}
+	return segment, true
+}
+
+// ListSegments GET /v1/admin/segments
+func (h *AdminSegmentsHandler) ListSegments(c *gin.Context) {
+	segments, err := h.segments.List(c.Request.Context(), httpmiddleware.GetAppID(c))
+	if err != nil {
+		response.InternalError(c, "Failed to list segments")
+		return
+	}
+
+	out := make([]Segment, 0, len(segments))
+	for _, s := range segments {
+		out = append(out, toSegment(s))
+	}
+	response.OK(c, gin.H{"segments": out, "total": len(out)})
+}
+
+// GetSegment GET /v1/admin/segments/:id
+func (h *AdminSegmentsHandler) GetSegment(c *gin.Context) {
+	segment, ok := h.loadSegment(c)
+	if !ok {
+		return
+	}
+	response.OK(c, toSegment(segment))
+}
+
+// CreateSegment POST /v1/admin/segments
+func (h *AdminSegmentsHandler) CreateSegment(c *gin.Context) {
+	segment := entity.NewSegment(httpmiddleware.GetAppID(c), "", entity.SegmentPredicate{})
+	if !bindSegment(c, segment) {
+		return
+	}
+
+	if err := h.segments.Create(c.Request.Context(), segment); err != nil {
+		response.InternalError(c, "Failed to create segment")
+		return
+	}
+	if segment.Materialized {
+		// On failure the hourly refresh picks the segment up
+		_ = h.enqueueMaterialize(segment)
+	}
+	response.Created(c, toSegment(segment))
+}
+
+// UpdateSegment PUT /v1/admin/segments/:id
+// Changing a materialized segment queues a fresh materialization.
+func (h *AdminSegmentsHandler) UpdateSegment(c *gin.Context) {
+	segment, ok := h.loadSegment(c)
+	if !ok {
+		return
+	}
+	if !bindSegment(c, segment) {
+		return
+	}
+	segment.UpdatedAt = time.Now()
+
+	if err := h.segments.Update(c.Request.Context(), segment); err != nil {
+		if errors.Is(err, domainErrors.ErrNotFound) {
+			response.NotFound(c, "Segment not found")
+			return
+		}
+		response.InternalError(c, "Failed to update segment")
+		return
+	}
+	if segment.Materialized {
+		// On failure the hourly refresh picks the segment up
+		_ = h.enqueueMaterialize(segment)
+	}
+	response.OK(c, toSegment(segment))
+}
+
+// DeleteSegment DELETE /v1/admin/segments/:id
+// Experiments targeting the segment become untargeted; paywall rules that
+// reference it stop matching.
+func (h *AdminSegmentsHandler) DeleteSegment(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid segment ID")
+		return
+	}
+
+	err = h.segments.Delete(c.Request.Context(), httpmiddleware.GetAppID(c), id)
+	if errors.Is(err, domainErrors.ErrNotFound) {
+		response.NotFound(c, "Segment not found")
+		return
+	}
+	if err != nil {
+		response.InternalError(c, "Failed to delete segment")
+		return
+	}
+	response.NoContent(c)
+}
+
+// EvaluateSegment POST /v1/admin/segments/:id/evaluate
+// Shows whether a user is a member and the attributes the definition saw.
+func (h *AdminSegmentsHandler) EvaluateSegment(c *gin.Context) {
+	segment, ok := h.loadSegment(c)
+	if !ok {
+		return
+	}
+	var req segmentEvaluateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "user_id is required")
+		return
+	}
+
+	attrs, err := h.evaluator.Attributes(c.Request.Context(), req.UserID, req.Country)
+	if errors.Is(err, domainErrors.ErrUserNotFound) {
+		response.NotFound(c, "User not found")
+		return
+	}
+	if err != nil {
+		response.InternalError(c, "Failed to load user attributes")
+		return
+	}
+	member, err := h.evaluator.Contains(c.Request.Context(), segment, req.UserID, req.Country)
+	if err != nil {
+		response.InternalError(c, "Failed to evaluate segment")
+		return
+	}
+	response.OK(c, gin.H{"member": member, "attributes": attrs, "materialized": segment.Materialized})
+}
+
+// MaterializeSegment POST /v1/admin/segments/:id/materialize
+func (h *AdminSegmentsHandler) MaterializeSegment(c *gin.Context) {
+	segment, ok := h.loadSegment(c)
+	if !ok {
+		return
+	}
+	if !segment.Materialized {
+		response.BadRequest(c, "Segment is evaluated in real time; set materialized first")
+		return
+	}
+	if err := h.enqueueMaterialize(segment); err != nil {
+		response.InternalError(c, "Failed to enqueue materialization")
+		return
+	}
+	response.Send(c, http.StatusAccepted, gin.H{"segment_id": segment.ID, "queued": true})
+}
+
+// NotifySegment POST /v1/admin/segments/:id/notify
+// Sends a message to every member in the background.
+func (h *AdminSegmentsHandler) NotifySegment(c *gin.Context) {
+	segment, ok := h.loadSegment(c)
+	if !ok {
+		return
+	}
+	var req segmentNotifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "title and body are required")
+		return
+	}
+
+	notify := tasks.NotifySegmentPayload{
+		AppID:     segment.AppID.String(),
+		SegmentID: segment.ID.String(),
+		Title:     req.Title,
+		Body:      req.Body,
+	}
+	if req.SendTimeExperimentID != nil {
+		if h.sendTime == nil {
+			response.BadRequest(c, "Send-time experiments are not available")
+			return
+		}
+		_, err := h.sendTime.GetExperiment(c.Request.Context(), segment.AppID, *req.SendTimeExperimentID)
+		if errors.Is(err, service.ErrSendTimeExperimentNotFound) {
+			response.BadRequest(c, "send_time_experiment_id does not belong to this app")
+			return
+		}
+		if err != nil {
+			response.InternalError(c, "Failed to get send-time experiment")
+			return
+		}
+		notify.SendTimeExperimentID = req.SendTimeExperimentID.String()
+	}
+
+	payload, _ := json.Marshal(notify)
+	if _, err := h.queue.Enqueue(asynq.NewTask(tasks.TypeNotifySegment, payload)); err != nil {
+		response.InternalError(c, "Failed to enqueue notification")
+		return
+	}
+	response.Send(c, http.StatusAccepted, gin.H{"segment_id": segment.ID, "queued": true})
+}
+
+// SetExperimentSegment PUT /v1/admin/experiments/:id/segment
+// Only segment members are assigned to the experiment from then on; existing
+// assignments are kept.
+func (h *AdminSegmentsHandler) SetExperimentSegment(c *gin.Context) {
+	appID := httpmiddleware.GetAppID(c)
+	experimentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid experiment ID")
+		return
+	}
+	var req experimentSegmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body")
+		return
+	}
+
+	if req.SegmentID != nil {
+		_, err := h.segments.GetByID(c.Request.Context(), appID, *req.SegmentID)
+		if errors.Is(err, domainErrors.ErrNotFound) {
+			response.BadRequest(c, "segment_id does not belong to this app")
+			return
+		}
+		if err != nil {
+			response.InternalError(c, "Failed to get segment")
+			return
+		}
+	}
+
+	err = h.segments.SetExperimentSegment(c.Request.Context(), appID, experimentID, req.SegmentID)
+	if errors.Is(err, domainErrors.ErrNotFound) {
+		response.NotFound(c, "Experiment not found")
+		return
+	}
+	if err != nil {
+		response.InternalError(c, "Failed to update experiment segment")
+		return
+	}
+	response.OK(c, gin.H{"experiment_id": experimentID, "segment_id": req.SegmentID})
+}
+
+func (h *AdminSegmentsHandler) enqueueMaterialize(segment *entity.Segment) error {
+	payload, _ := json.Marshal(tasks.MaterializeSegmentsPayload{
+		AppID:     segment.AppID.String(),
+		SegmentID: segment.ID.String(),
+	})
+	_, err := h.queue.Enqueue(asynq.NewTask(tasks.TypeMaterializeSegments, payload), asynq.Queue("low"))
+	return err
+}
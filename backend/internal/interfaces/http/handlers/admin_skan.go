@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+const (
+	defaultSKANCohortWindow = 30 * 24 * time.Hour
+	maxSKANCohortWindow     = 366 * 24 * time.Hour
+)
+
+type skanSchemaManager interface {
+	ListSchemas(ctx context.Context, appID uuid.UUID) ([]*entity.SKANConversionSchema, error)
+	CreateSchema(ctx context.Context, appID uuid.UUID, buckets []entity.SKANValueBucket, createdBy *uuid.UUID) (*entity.SKANConversionSchema, error)
+	CampaignCohorts(ctx context.Context, appID uuid.UUID, from, to time.Time) ([]service.SKANCampaignCohort, error)
+}
+
+// AdminSKANHandler manages SKAdNetwork conversion schemas and reports
+// campaign cohorts
+type AdminSKANHandler struct {
+	skan skanSchemaManager
+	now  func() time.Time
+}
+
+func NewAdminSKANHandler(skan skanSchemaManager) *AdminSKANHandler {
+	return &AdminSKANHandler{skan: skan, now: time.Now}
+}
+
+type createSKANSchemaRequest struct {
+	Buckets []entity.SKANValueBucket `json:"buckets" binding:"required,min=1,max=64"`
+}
+
+// ListSKANSchemas GET /v1/admin/attribution/skan/schemas
+func (h *AdminSKANHandler) ListSKANSchemas(c *gin.Context) {
+	schemas, err := h.skan.ListSchemas(c.Request.Context(), httpmiddleware.GetAppID(c))
+	if err != nil {
+		response.InternalError(c, "Failed to list conversion schemas")
+		return
+	}
+	response.OK(c, gin.H{"schemas": schemas})
+}
+
+// CreateSKANSchema POST /v1/admin/attribution/skan/schemas
+// Stores the next schema version; installs from now on are decoded with it.
+func (h *AdminSKANHandler) CreateSKANSchema(c *gin.Context) {
+	var req createSKANSchemaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+	adminID, _ := adminIDFromContext(c)
+	schema, err := h.skan.CreateSchema(c.Request.Context(), httpmiddleware.GetAppID(c), req.Buckets, adminID)
+	if err != nil {
+		if errors.Is(err, entity.ErrInvalidSKANSchema) {
+			response.UnprocessableEntity(c, err.Error())
+			return
+		}
+		response.InternalError(c, "Failed to create conversion schema")
+		return
+	}
+	response.Created(c, schema)
+}
+
+// GetSKANCampaignCohorts GET /v1/admin/attribution/skan/campaigns?from=YYYY-MM-DD&to=YYYY-MM-DD
+// Cohorts are by postback receipt date; to is inclusive and the default range
+// is the last 30 days.
+func (h *AdminSKANHandler) GetSKANCampaignCohorts(c *gin.Context) {
+	to := h.now().UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+	if raw := c.Query("to"); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			response.BadRequest(c, "to must be a date in YYYY-MM-DD format")
+			return
+		}
+		to = parsed.Add(24 * time.Hour)
+	}
+	from := to.Add(-defaultSKANCohortWindow)
+	if raw := c.Query("from"); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			response.BadRequest(c, "from must be a date in YYYY-MM-DD format")
+			return
+		}
+		from = parsed
+	}
+	if !from.Before(to) || to.Sub(from) > maxSKANCohortWindow {
+		response.BadRequest(c, "from must be before to and the range at most 366 days")
+		return
+	}
+
+	cohorts, err := h.skan.CampaignCohorts(c.Request.Context(), httpmiddleware.GetAppID(c), from, to)
+	if err != nil {
+		response.InternalError(c, "Failed to build campaign cohorts")
+		return
+	}
+	response.OK(c, gin.H{"from": from, "to": to, "campaigns": cohorts})
+}
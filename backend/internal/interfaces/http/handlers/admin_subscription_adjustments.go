@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+type subscriptionAdjuster interface {
+	ExtendGracePeriod(ctx context.Context, userID uuid.UUID, days int) (*service.SubscriptionAdjustment, error)
+	ExtendExpiry(ctx context.Context, userID uuid.UUID, subscriptionID *uuid.UUID, days int) (*service.SubscriptionAdjustment, error)
+}
+
+// AdminSubscriptionAdjustmentHandler lets support extend a user's grace
+// period or subscription expiry; every change is audited with its reason
+type AdminSubscriptionAdjustmentHandler struct {
+	adjuster subscriptionAdjuster
+	audit    adminAuditLogger
+	logger   *zap.Logger
+}
+
+func NewAdminSubscriptionAdjustmentHandler(adjuster subscriptionAdjuster, audit adminAuditLogger, logger *zap.Logger) *AdminSubscriptionAdjustmentHandler {
+	return &AdminSubscriptionAdjustmentHandler{adjuster: adjuster, audit: audit, logger: logger}
+}
+
+// ExtendGracePeriodRequest moves the active grace period by days; negative
+// days shorten it
+type ExtendGracePeriodRequest struct {
+	Days   int    `json:"days" binding:"required"`
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+// ExtendSubscriptionRequest moves a subscription's expiry by days. Without
+// subscription_id the subscription granting access, else the latest, is used.
+type ExtendSubscriptionRequest struct {
+	Days           int        `json:"days" binding:"required"`
+	Reason         string     `json:"reason" binding:"required,max=500"`
+	SubscriptionID *uuid.UUID `json:"subscription_id"`
+}
+
+// SubscriptionAdjustmentResponse is the outcome of an adjustment
+type SubscriptionAdjustmentResponse struct {
+	SubscriptionID        uuid.UUID  `json:"subscription_id"`
+	GracePeriodID         *uuid.UUID `json:"grace_period_id,omitempty"`
+	PreviousExpiresAt     time.Time  `json:"previous_expires_at"`
+	NewExpiresAt          time.Time  `json:"new_expires_at"`
+	Status                string     `json:"status"`
+	ResolvedGracePeriodID *uuid.UUID `json:"resolved_grace_period_id,omitempty"`
+}
+
+func toSubscriptionAdjustmentResponse(a *service.SubscriptionAdjustment) SubscriptionAdjustmentResponse {
+	return SubscriptionAdjustmentResponse{
+		SubscriptionID:        a.SubscriptionID,
+		GracePeriodID:         a.GracePeriodID,
+		PreviousExpiresAt:     a.PreviousExpiresAt,
+		NewExpiresAt:          a.NewExpiresAt,
+		Status:                string(a.Status),
+		ResolvedGracePeriodID: a.ResolvedGracePeriodID,
+	}
+}
+
+// ExtendGracePeriod POST /v1/admin/users/:id/grace-period/extend
+func (h *AdminSubscriptionAdjustmentHandler) ExtendGracePeriod(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid user ID")
+		return
+	}
+	var req ExtendGracePeriodRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+
+	adjustment, err := h.adjuster.ExtendGracePeriod(c.Request.Context(), userID, req.Days)
+	if err != nil {
+		h.writeAdjustmentError(c, userID, err)
+		return
+	}
+
+	h.logAction(c, "extend_grace_period", userID, req.Reason, req.Days, adjustment)
+	response.OK(c, toSubscriptionAdjustmentResponse(adjustment))
+}
+
+// ExtendSubscription POST /v1/admin/users/:id/subscription/extend
+func (h *AdminSubscriptionAdjustmentHandler) ExtendSubscription(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid user ID")
+		return
+	}
+	var req ExtendSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+
+	adjustment, err := h.adjuster.ExtendExpiry(c.Request.Context(), userID, req.SubscriptionID, req.Days)
+	if err != nil {
+		h.writeAdjustmentError(c, userID, err)
+		return
+	}
+
+	h.logAction(c, "extend_subscription_expiry", userID, req.Reason, req.Days, adjustment)
+	response.OK(c, toSubscriptionAdjustmentResponse(adjustment))
+}
+
+func (h *AdminSubscriptionAdjustmentHandler) writeAdjustmentError(c *gin.Context, userID uuid.UUID, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidAdjustment):
+		response.UnprocessableEntity(c, err.Error())
+	case errors.Is(err, service.ErrGracePeriodNotFound):
+		response.NotFound(c, "No active grace period")
+	case errors.Is(err, domainErrors.ErrNotFound):
+		response.NotFound(c, "Subscription not found")
+	case errors.Is(err, service.ErrGracePeriodNotActive):
+		response.Conflict(c, "Grace period is not active")
+	default:
+		h.logger.Error("Failed to adjust subscription", zap.String("user_id", userID.String()), zap.Error(err))
+		response.InternalError(c, "Failed to adjust subscription")
+	}
+}
+
+func (h *AdminSubscriptionAdjustmentHandler) logAction(c *gin.Context, action string, userID uuid.UUID, reason string, days int, adjustment *service.SubscriptionAdjustment) {
+	adminIDValue, _ := c.Get("admin_id")
+	adminID, ok := adminIDValue.(uuid.UUID)
+	if !ok || h.audit == nil {
+		return
+	}
+	details := map[string]interface{}{
+		"reason":              reason,
+		"days":                days,
+		"subscription_id":     adjustment.SubscriptionID.String(),
+		"previous_expires_at": adjustment.PreviousExpiresAt.Format(time.RFC3339),
+		"new_expires_at":      adjustment.NewExpiresAt.Format(time.RFC3339),
+	}
+	if adjustment.GracePeriodID != nil {
+		details["grace_period_id"] = adjustment.GracePeriodID.String()
+	}
+	if adjustment.ResolvedGracePeriodID != nil {
+		details["resolved_grace_period_id"] = adjustment.ResolvedGracePeriodID.String()
+	}
+	if err := h.audit.LogAction(c.Request.Context(), adminID, action, "user", &userID, details); err != nil {
+		h.logger.Warn("Failed to audit subscription adjustment", zap.String("action", action), zap.Error(err))
+	}
+}
@@ -0,0 +1,130 @@
+package handlers_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/handlers"
+)
+
+type fakeSubscriptionAdjuster struct {
+	graceErr error
+	expiry   *uuid.UUID
+}
+
+func (f *fakeSubscriptionAdjuster) ExtendGracePeriod(ctx context.Context, userID uuid.UUID, days int) (*service.SubscriptionAdjustment, error) {
+	if f.graceErr != nil {
+		return nil, f.graceErr
+	}
+	graceID := uuid.New()
+	now := time.Now()
+	return &service.SubscriptionAdjustment{
+		SubscriptionID:    uuid.New(),
+		GracePeriodID:     &graceID,
+		PreviousExpiresAt: now,
+		NewExpiresAt:      now.AddDate(0, 0, days),
+		Status:            entity.StatusGrace,
+	}, nil
+}
+
+func (f *fakeSubscriptionAdjuster) ExtendExpiry(ctx context.Context, userID uuid.UUID, subscriptionID *uuid.UUID, days int) (*service.SubscriptionAdjustment, error) {
+	f.expiry = subscriptionID
+	now := time.Now()
+	return &service.SubscriptionAdjustment{
+		SubscriptionID:    *subscriptionID,
+		PreviousExpiresAt: now,
+		NewExpiresAt:      now.AddDate(0, 0, days),
+		Status:            entity.StatusActive,
+	}, nil
+}
+
+func postAdjustmentJSON(h *handlers.AdminSubscriptionAdjustmentHandler, path, body string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("admin_id", uuid.New())
+		c.Next()
+	})
+	r.POST("/v1/admin/users/:id/grace-period/extend", h.ExtendGracePeriod)
+	r.POST("/v1/admin/users/:id/subscription/extend", h.ExtendSubscription)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestExtendGracePeriod_AuditsReason(t *testing.T) {
+	audit := &fakeAuditLogger{}
+	h := handlers.NewAdminSubscriptionAdjustmentHandler(&fakeSubscriptionAdjuster{}, audit, zap.NewNop())
+
+	w := postAdjustmentJSON(h, "/v1/admin/users/"+uuid.NewString()+"/grace-period/extend", `{"days":3,"reason":"store outage"}`)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Equal(t, []string{"extend_grace_period"}, audit.actions)
+	assert.Equal(t, "store outage", audit.details[0]["reason"])
+	assert.Equal(t, 3, audit.details[0]["days"])
+	assert.Contains(t, audit.details[0], "grace_period_id")
+}
+
+func TestExtendGracePeriod_RequiresReason(t *testing.T) {
+	audit := &fakeAuditLogger{}
+	h := handlers.NewAdminSubscriptionAdjustmentHandler(&fakeSubscriptionAdjuster{}, audit, zap.NewNop())
+
+	w := postAdjustmentJSON(h, "/v1/admin/users/"+uuid.NewString()+"/grace-period/extend", `{"days":3}`)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Empty(t, audit.actions)
+}
+
+func TestExtendGracePeriod_MapsErrors(t *testing.T) {
+	cases := map[string]struct {
+		err  error
+		code int
+	}{
+		"not found":  {service.ErrGracePeriodNotFound, http.StatusNotFound},
+		"not active": {fmt.Errorf("%w: resolved", service.ErrGracePeriodNotActive), http.StatusConflict},
+		"invalid":    {fmt.Errorf("%w: too long", service.ErrInvalidAdjustment), http.StatusUnprocessableEntity},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			audit := &fakeAuditLogger{}
+			h := handlers.NewAdminSubscriptionAdjustmentHandler(&fakeSubscriptionAdjuster{graceErr: tc.err}, audit, zap.NewNop())
+
+			w := postAdjustmentJSON(h, "/v1/admin/users/"+uuid.NewString()+"/grace-period/extend", `{"days":3,"reason":"support"}`)
+
+			assert.Equal(t, tc.code, w.Code)
+			assert.Empty(t, audit.actions)
+		})
+	}
+}
+
+func TestExtendSubscription_PassesSubscriptionID(t *testing.T) {
+	adjuster := &fakeSubscriptionAdjuster{}
+	audit := &fakeAuditLogger{}
+	h := handlers.NewAdminSubscriptionAdjustmentHandler(adjuster, audit, zap.NewNop())
+	subID := uuid.New()
+
+	w := postAdjustmentJSON(h, "/v1/admin/users/"+uuid.NewString()+"/subscription/extend",
+		`{"days":7,"reason":"goodwill","subscription_id":"`+subID.String()+`"}`)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NotNil(t, adjuster.expiry)
+	assert.Equal(t, subID, *adjuster.expiry)
+	require.Equal(t, []string{"extend_subscription_expiry"}, audit.actions)
+	assert.Equal(t, subID.String(), audit.details[0]["subscription_id"])
+}
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+const (
+	defaultSnapshotWindow = 30 * 24 * time.Hour
+	maxSnapshotWindow     = 366 * 24 * time.Hour
+)
+
+type subscriptionSnapshotReporter interface {
+	Summary(ctx context.Context, appID uuid.UUID, date time.Time) (*repository.SubscriptionSnapshotSummary, error)
+	DailyDiffs(ctx context.Context, appID uuid.UUID, from, to time.Time) ([]repository.SubscriptionSnapshotDiff, error)
+	UserHistory(ctx context.Context, appID, userID uuid.UUID, from, to time.Time) ([]repository.SubscriptionSnapshotRow, error)
+}
+
+// SubscriptionSnapshotSummary is an app's subscription counts on a snapshot date
+type SubscriptionSnapshotSummary struct {
+	Date             string         `json:"date"`
+	CapturedAt       time.Time      `json:"captured_at"`
+	Total            int            `json:"total"`
+	Active           int            `json:"active"`
+	ActiveUsers      int            `json:"active_users"`
+	ByStatus         map[string]int `json:"by_status"`
+	ActiveByPlan     map[string]int `json:"active_by_plan"`
+	ActiveByPlatform map[string]int `json:"active_by_platform"`
+}
+
+// SubscriptionSnapshotDiff is the change in active users between two snapshot dates
+type SubscriptionSnapshotDiff struct {
+	From        string `json:"from"`
+	To          string `json:"to"`
+	New         int    `json:"new"`
+	Churned     int    `json:"churned"`
+	Upgraded    int    `json:"upgraded"`
+	Downgraded  int    `json:"downgraded"`
+	Crossgraded int    `json:"crossgraded"`
+	Retained    int    `json:"retained"`
+}
+
+// SubscriptionSnapshotRow is one subscription of a user as recorded on a date
+type SubscriptionSnapshotRow struct {
+	Date           string    `json:"date"`
+	SubscriptionID uuid.UUID `json:"subscription_id"`
+	Status         string    `json:"status"`
+	PlanType       string    `json:"plan_type"`
+	ProductID      string    `json:"product_id"`
+	Platform       string    `json:"platform"`
+	Source         string    `json:"source"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	AutoRenew      bool      `json:"auto_renew"`
+	Active         bool      `json:"active"`
+}
+
+// AdminSubscriptionSnapshotsHandler answers point-in-time subscription
+// questions from the nightly snapshots.
+type AdminSubscriptionSnapshotsHandler struct {
+	reporter subscriptionSnapshotReporter
+	now      func() time.Time
+}
+
+func NewAdminSubscriptionSnapshotsHandler(reporter subscriptionSnapshotReporter) *AdminSubscriptionSnapshotsHandler {
+	return &AdminSubscriptionSnapshotsHandler{reporter: reporter, now: time.Now}
+}
+
+// GetSnapshot GET /v1/admin/analytics/subscription-snapshots?date=YYYY-MM-DD
+// date defaults to yesterday, the most recent complete day.
+func (h *AdminSubscriptionSnapshotsHandler) GetSnapshot(c *gin.Context) {
+	date := h.now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -1)
+	if raw := c.Query("date"); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			response.BadRequest(c, "date must be a date in YYYY-MM-DD format")
+			return
+		}
+		date = parsed
+	}
+
+	summary, err := h.reporter.Summary(c.Request.Context(), httpmiddleware.GetAppID(c), date)
+	if errors.Is(err, domainErrors.ErrNotFound) {
+		response.NotFound(c, "No subscription snapshot for this date")
+		return
+	}
+	if err != nil {
+		response.InternalError(c, "Failed to get subscription snapshot")
+		return
+	}
+
+	response.OK(c, SubscriptionSnapshotSummary{
+		Date:             summary.Date.Format("2006-01-02"),
+		CapturedAt:       summary.CapturedAt,
+		Total:            summary.Total,
+		Active:           summary.Active,
+		ActiveUsers:      summary.ActiveUsers,
+		ByStatus:         summary.ByStatus,
+		ActiveByPlan:     summary.ActiveByPlan,
+		ActiveByPlatform: summary.ActiveByPlatform,
+	})
+}
+
+// GetSnapshotDiffs GET /v1/admin/analytics/subscription-snapshots/diff?from=YYYY-MM-DD&to=YYYY-MM-DD
+// Returns one diff per captured date against the previous captured date;
+// both bounds are inclusive and the default range is the last 30 days.
+func (h *AdminSubscriptionSnapshotsHandler) GetSnapshotDiffs(c *gin.Context) {
+	from, to, ok := h.snapshotRange(c)
+	if !ok {
+		return
+	}
+
+	diffs, err := h.reporter.DailyDiffs(c.Request.Context(), httpmiddleware.GetAppID(c), from, to)
+	if err != nil {
+		response.InternalError(c, "Failed to diff subscription snapshots")
+		return
+	}
+
+	out := make([]SubscriptionSnapshotDiff, 0, len(diffs))
+	for _, d := range diffs {
+		out = append(out, SubscriptionSnapshotDiff{
+			From:        d.From.Format("2006-01-02"),
+			To:          d.To.Format("2006-01-02"),
+			New:         d.New,
+			Churned:     d.Churned,
+			Upgraded:    d.Upgraded,
+			Downgraded:  d.Downgraded,
+			Crossgraded: d.Crossgraded,
+			Retained:    d.Retained,
+		})
+	}
+	response.OK(c, gin.H{"diffs": out, "total": len(out)})
+}
+
+// GetUserSnapshots GET /v1/admin/analytics/subscription-snapshots/users/:user_id?from=YYYY-MM-DD&to=YYYY-MM-DD
+func (h *AdminSubscriptionSnapshotsHandler) GetUserSnapshots(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid user ID")
+		return
+	}
+	from, to, ok := h.snapshotRange(c)
+	if !ok {
+		return
+	}
+
+	rows, err := h.reporter.UserHistory(c.Request.Context(), httpmiddleware.GetAppID(c), userID, from, to)
+	if err != nil {
+		response.InternalError(c, "Failed to get subscription history")
+		return
+	}
+
+	out := make([]SubscriptionSnapshotRow, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, SubscriptionSnapshotRow{
+			Date:           r.Date.Format("2006-01-02"),
+			SubscriptionID: r.SubscriptionID,
+			Status:         r.Status,
+			PlanType:       r.PlanType,
+			ProductID:      r.ProductID,
+			Platform:       r.Platform,
+			Source:         r.Source,
+			ExpiresAt:      r.ExpiresAt,
+			AutoRenew:      r.AutoRenew,
+			Active:         r.Active,
+		})
+	}
+	response.OK(c, gin.H{"user_id": userID, "snapshots": out, "total": len(out)})
+}
+
+// snapshotRange parses the inclusive from / to dates, writing the error
+// response on failure
+func (h *AdminSubscriptionSnapshotsHandler) snapshotRange(c *gin.Context) (time.Time, time.Time, bool) {
+	to := h.now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -1)
+	if raw := c.Query("to"); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			response.BadRequest(c, "to must be a date in YYYY-MM-DD format")
+			return time.Time{}, time.Time{}, false
+		}
+		to = parsed
+	}
+	from := to.Add(-defaultSnapshotWindow)
+	if raw := c.Query("from"); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			response.BadRequest(c, "from must be a date in YYYY-MM-DD format")
+			return time.Time{}, time.Time{}, false
+		}
+		from = parsed
+	}
+	if from.After(to) || to.Sub(from) > maxSnapshotWindow {
+		response.BadRequest(c, "from must not be after to and the range at most 366 days")
+		return time.Time{}, time.Time{}, false
+	}
+	return from, to, true
+}
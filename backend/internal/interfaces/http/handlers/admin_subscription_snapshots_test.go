@@ -0,0 +1,105 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/handlers"
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+)
+
+type mockSnapshotReporter struct {
+	mock.Mock
+}
+
+func (m *mockSnapshotReporter) Summary(ctx context.Context, appID uuid.UUID, date time.Time) (*repository.SubscriptionSnapshotSummary, error) {
+	args := m.Called(ctx, appID, date)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repository.SubscriptionSnapshotSummary), args.Error(1)
+}
+
+func (m *mockSnapshotReporter) DailyDiffs(ctx context.Context, appID uuid.UUID, from, to time.Time) ([]repository.SubscriptionSnapshotDiff, error) {
+	args := m.Called(ctx, appID, from, to)
+	return args.Get(0).([]repository.SubscriptionSnapshotDiff), args.Error(1)
+}
+
+func (m *mockSnapshotReporter) UserHistory(ctx context.Context, appID, userID uuid.UUID, from, to time.Time) ([]repository.SubscriptionSnapshotRow, error) {
+	args := m.Called(ctx, appID, userID, from, to)
+	return args.Get(0).([]repository.SubscriptionSnapshotRow), args.Error(1)
+}
+
+func newSnapshotRouter(h *handlers.AdminSubscriptionSnapshotsHandler, appID uuid.UUID) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set(httpmiddleware.AppIDKey, appID)
+		c.Next()
+	})
+	r.GET("/v1/admin/analytics/subscription-snapshots", h.GetSnapshot)
+	r.GET("/v1/admin/analytics/subscription-snapshots/diff", h.GetSnapshotDiffs)
+	return r
+}
+
+func TestGetSnapshot_ReturnsCountsForDate(t *testing.T) {
+	appID := uuid.New()
+	date := time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC)
+	reporter := new(mockSnapshotReporter)
+	reporter.On("Summary", mock.Anything, appID, date).Return(&repository.SubscriptionSnapshotSummary{
+		Date:        date,
+		Total:       5,
+		Active:      4,
+		ActiveUsers: 4,
+		ByStatus:    map[string]int{"active": 4, "expired": 1},
+	}, nil)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/v1/admin/analytics/subscription-snapshots?date=2026-03-03", nil)
+	newSnapshotRouter(handlers.NewAdminSubscriptionSnapshotsHandler(reporter), appID).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var body struct {
+		Data handlers.SubscriptionSnapshotSummary `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "2026-03-03", body.Data.Date)
+	assert.Equal(t, 4, body.Data.Active)
+	reporter.AssertExpectations(t)
+}
+
+func TestGetSnapshot_NotCaptured(t *testing.T) {
+	reporter := new(mockSnapshotReporter)
+	reporter.On("Summary", mock.Anything, mock.Anything, mock.Anything).
+		Return(nil, fmt.Errorf("subscription snapshot: %w", domainErrors.ErrNotFound))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/v1/admin/analytics/subscription-snapshots?date=2020-01-01", nil)
+	newSnapshotRouter(handlers.NewAdminSubscriptionSnapshotsHandler(reporter), uuid.New()).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestGetSnapshotDiffs_RejectsInvertedRange(t *testing.T) {
+	reporter := new(mockSnapshotReporter)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/v1/admin/analytics/subscription-snapshots/diff?from=2026-03-05&to=2026-03-01", nil)
+	newSnapshotRouter(handlers.NewAdminSubscriptionSnapshotsHandler(reporter), uuid.New()).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	reporter.AssertNotCalled(t, "DailyDiffs", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+const defaultTaskRunStatsWindow = 24 * time.Hour
+
+type taskRunReader interface {
+	ListTaskRuns(ctx context.Context, filter repository.TaskRunFilter) ([]entity.TaskRun, error)
+	GetTaskRunStats(ctx context.Context, since time.Time) ([]entity.TaskRunStats, error)
+}
+
+// AdminTaskRunsHandler exposes the asynq task execution history recorded by the worker.
+type AdminTaskRunsHandler struct {
+	repo taskRunReader
+	now  func() time.Time
+}
+
+func NewAdminTaskRunsHandler(repo taskRunReader) *AdminTaskRunsHandler {
+	return &AdminTaskRunsHandler{repo: repo, now: time.Now}
+}
+
+// ListTaskRuns GET /v1/admin/task-runs?task_type=&status=&limit=
+func (h *AdminTaskRunsHandler) ListTaskRuns(c *gin.Context) {
+	filter := repository.TaskRunFilter{
+		TaskType: c.Query("task_type"),
+		Status:   c.Query("status"),
+		Limit:    100,
+	}
+	switch filter.Status {
+	case "", entity.TaskRunStatusRunning, entity.TaskRunStatusSucceeded, entity.TaskRunStatusFailed:
+	default:
+		response.BadRequest(c, "status must be one of running, succeeded, failed")
+		return
+	}
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 || limit > 500 {
+			response.BadRequest(c, "limit must be between 1 and 500")
+			return
+		}
+		filter.Limit = limit
+	}
+
+	runs, err := h.repo.ListTaskRuns(c.Request.Context(), filter)
+	if err != nil {
+		response.InternalError(c, "Failed to list task runs")
+		return
+	}
+
+	response.OK(c, gin.H{"task_runs": runs, "total": len(runs)})
+}
+
+// GetTaskRunStats GET /v1/admin/task-runs/stats?window_hours=24
+func (h *AdminTaskRunsHandler) GetTaskRunStats(c *gin.Context) {
+	window := defaultTaskRunStatsWindow
+	if raw := c.Query("window_hours"); raw != "" {
+		hours, err := strconv.Atoi(raw)
+		if err != nil || hours <= 0 || hours > 24*90 {
+			response.BadRequest(c, "window_hours must be between 1 and 2160")
+			return
+		}
+		window = time.Duration(hours) * time.Hour
+	}
+	since := h.now().UTC().Add(-window)
+
+	stats, err := h.repo.GetTaskRunStats(c.Request.Context(), since)
+	if err != nil {
+		response.InternalError(c, "Failed to get task run stats")
+		return
+	}
+
+	var total, failed int64
+	for _, s := range stats {
+		total += s.Succeeded + s.Failed
+		failed += s.Failed
+	}
+	failureRate := 0.0
+	if total > 0 {
+		failureRate = float64(failed) / float64(total)
+	}
+
+	response.OK(c, gin.H{
+		"since":        since,
+		"window_hours": int(window.Hours()),
+		"failure_rate": failureRate,
+		"task_types":   stats,
+	})
+}
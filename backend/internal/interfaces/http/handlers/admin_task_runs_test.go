@@ -0,0 +1,120 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/handlers"
+)
+
+type mockTaskRunReader struct {
+	mock.Mock
+}
+
+func (m *mockTaskRunReader) ListTaskRuns(ctx context.Context, filter repository.TaskRunFilter) ([]entity.TaskRun, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]entity.TaskRun), args.Error(1)
+}
+
+func (m *mockTaskRunReader) GetTaskRunStats(ctx context.Context, since time.Time) ([]entity.TaskRunStats, error) {
+	args := m.Called(ctx, since)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]entity.TaskRunStats), args.Error(1)
+}
+
+func newTaskRunsRouter(h *handlers.AdminTaskRunsHandler) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/v1/admin/task-runs", h.ListTaskRuns)
+	r.GET("/v1/admin/task-runs/stats", h.GetTaskRunStats)
+	return r
+}
+
+func TestListTaskRuns_PassesFilter(t *testing.T) {
+	repo := new(mockTaskRunReader)
+	run := entity.TaskRun{ID: uuid.New(), TaskType: "compute:analytics", Status: entity.TaskRunStatusFailed, StartedAt: time.Now()}
+	repo.On("ListTaskRuns", mock.Anything, repository.TaskRunFilter{TaskType: "compute:analytics", Status: "failed", Limit: 20}).
+		Return([]entity.TaskRun{run}, nil)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/v1/admin/task-runs?task_type=compute:analytics&status=failed&limit=20", nil)
+	newTaskRunsRouter(handlers.NewAdminTaskRunsHandler(repo)).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var body struct {
+		Data struct {
+			TaskRuns []entity.TaskRun `json:"task_runs"`
+			Total    int              `json:"total"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, 1, body.Data.Total)
+	assert.Equal(t, run.ID, body.Data.TaskRuns[0].ID)
+	repo.AssertExpectations(t)
+}
+
+func TestListTaskRuns_RejectsInvalidStatus(t *testing.T) {
+	repo := new(mockTaskRunReader)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/v1/admin/task-runs?status=bogus", nil)
+	newTaskRunsRouter(handlers.NewAdminTaskRunsHandler(repo)).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	repo.AssertNotCalled(t, "ListTaskRuns")
+}
+
+func TestGetTaskRunStats_ComputesOverallFailureRate(t *testing.T) {
+	repo := new(mockTaskRunReader)
+	repo.On("GetTaskRunStats", mock.Anything, mock.AnythingOfType("time.Time")).Return([]entity.TaskRunStats{
+		{TaskType: "compute:analytics", Total: 3, Succeeded: 1, Failed: 2},
+		{TaskType: "update:ltv", Total: 2, Succeeded: 2},
+	}, nil)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/v1/admin/task-runs/stats?window_hours=12", nil)
+	newTaskRunsRouter(handlers.NewAdminTaskRunsHandler(repo)).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var body struct {
+		Data struct {
+			WindowHours int                   `json:"window_hours"`
+			FailureRate float64               `json:"failure_rate"`
+			TaskTypes   []entity.TaskRunStats `json:"task_types"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, 12, body.Data.WindowHours)
+	assert.InDelta(t, 0.4, body.Data.FailureRate, 1e-9)
+	assert.Len(t, body.Data.TaskTypes, 2)
+	repo.AssertExpectations(t)
+}
+
+func TestGetTaskRunStats_RepoError(t *testing.T) {
+	repo := new(mockTaskRunReader)
+	repo.On("GetTaskRunStats", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("db error"))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/v1/admin/task-runs/stats", nil)
+	newTaskRunsRouter(handlers.NewAdminTaskRunsHandler(repo)).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+const (
+	defaultTaxReportWindow = 30 * 24 * time.Hour
+	maxTaxReportWindow     = 366 * 24 * time.Hour
+	maxReconcileItems      = 1000
+)
+
+type taxReporter interface {
+	GetTaxReport(ctx context.Context, appID uuid.UUID, from, to time.Time, period string) (*service.TaxReport, error)
+	ReconcileTransactions(ctx context.Context, appID uuid.UUID, items []service.TransactionReconciliation) (*service.ReconcileResult, error)
+}
+
+// AdminTaxHandler exposes the tax / VAT revenue breakdown and financial-report reconciliation.
+type AdminTaxHandler struct {
+	reporter taxReporter
+	now      func() time.Time
+}
+
+func NewAdminTaxHandler(reporter taxReporter) *AdminTaxHandler {
+	return &AdminTaxHandler{reporter: reporter, now: time.Now}
+}
+
+// GetTaxReport GET /v1/admin/analytics/tax-report?from=YYYY-MM-DD&to=YYYY-MM-DD&period=month
+// to is inclusive; the default range is the last 30 days.
+func (h *AdminTaxHandler) GetTaxReport(c *gin.Context) {
+	period := c.DefaultQuery("period", "month")
+	if !service.TaxReportPeriods[period] {
+		response.BadRequest(c, "period must be one of day, week, month")
+		return
+	}
+
+	to := h.now().UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+	if raw := c.Query("to"); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			response.BadRequest(c, "to must be a date in YYYY-MM-DD format")
+			return
+		}
+		to = parsed.Add(24 * time.Hour)
+	}
+	from := to.Add(-defaultTaxReportWindow)
+	if raw := c.Query("from"); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			response.BadRequest(c, "from must be a date in YYYY-MM-DD format")
+			return
+		}
+		from = parsed
+	}
+	if !from.Before(to) || to.Sub(from) > maxTaxReportWindow {
+		response.BadRequest(c, "from must be before to and the range at most 366 days")
+		return
+	}
+
+	report, err := h.reporter.GetTaxReport(c.Request.Context(), httpmiddleware.GetAppID(c), from, to, period)
+	if err != nil {
+		response.InternalError(c, "Failed to build tax report")
+		return
+	}
+
+	response.OK(c, report)
+}
+
+type reconcileTransactionsRequest struct {
+	Transactions []service.TransactionReconciliation `json:"transactions" binding:"required"`
+}
+
+// ReconcileTransactions POST /v1/admin/transactions/reconcile
+// Imports store financial-report lines (gross, fee, tax, proceeds) keyed by provider transaction ID.
+func (h *AdminTaxHandler) ReconcileTransactions(c *gin.Context) {
+	var req reconcileTransactionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body")
+		return
+	}
+	if len(req.Transactions) == 0 || len(req.Transactions) > maxReconcileItems {
+		response.BadRequest(c, "transactions must contain between 1 and 1000 items")
+		return
+	}
+	for _, item := range req.Transactions {
+		if item.ProviderTxID == "" || len(item.Currency) != 3 {
+			response.BadRequest(c, "each transaction needs provider_tx_id and a 3-letter currency")
+			return
+		}
+		if item.Gross < 0 || item.StoreFee < 0 || item.Tax < 0 || item.StoreFee+item.Tax > item.Gross {
+			response.BadRequest(c, "amounts must be non-negative and store_fee + tax must not exceed gross")
+			return
+		}
+	}
+
+	result, err := h.reporter.ReconcileTransactions(c.Request.Context(), httpmiddleware.GetAppID(c), req.Transactions)
+	if err != nil {
+		response.InternalError(c, "Failed to reconcile transactions")
+		return
+	}
+
+	response.OK(c, result)
+}
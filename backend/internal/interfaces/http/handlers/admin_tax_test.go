@@ -0,0 +1,133 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/handlers"
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+)
+
+type mockTaxReporter struct {
+	mock.Mock
+}
+
+func (m *mockTaxReporter) GetTaxReport(ctx context.Context, appID uuid.UUID, from, to time.Time, period string) (*service.TaxReport, error) {
+	args := m.Called(ctx, appID, from, to, period)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.TaxReport), args.Error(1)
+}
+
+func (m *mockTaxReporter) ReconcileTransactions(ctx context.Context, appID uuid.UUID, items []service.TransactionReconciliation) (*service.ReconcileResult, error) {
+	args := m.Called(ctx, appID, items)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.ReconcileResult), args.Error(1)
+}
+
+func newTaxRouter(h *handlers.AdminTaxHandler, appID uuid.UUID) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set(httpmiddleware.AppIDKey, appID)
+		c.Next()
+	})
+	r.GET("/v1/admin/analytics/tax-report", h.GetTaxReport)
+	r.POST("/v1/admin/transactions/reconcile", h.ReconcileTransactions)
+	return r
+}
+
+func TestGetTaxReport_ToDateIsInclusive(t *testing.T) {
+	appID := uuid.New()
+	reporter := new(mockTaxReporter)
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+	reporter.On("GetTaxReport", mock.Anything, appID, from, to, "month").Return(&service.TaxReport{
+		Period: "month",
+		Rows:   []service.TaxReportRow{{Period: "2026-01-01", CountryCode: "DE", Currency: "EUR", Transactions: 2, Gross: 23.98, Tax: 3.83, StoreFee: 6.05, Net: 14.10}},
+	}, nil)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/v1/admin/analytics/tax-report?from=2026-01-01&to=2026-03-31", nil)
+	newTaxRouter(handlers.NewAdminTaxHandler(reporter), appID).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var body struct {
+		Data service.TaxReport `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.Len(t, body.Data.Rows, 1)
+	assert.Equal(t, "DE", body.Data.Rows[0].CountryCode)
+	reporter.AssertExpectations(t)
+}
+
+func TestGetTaxReport_RejectsInvalidPeriod(t *testing.T) {
+	reporter := new(mockTaxReporter)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/v1/admin/analytics/tax-report?period=year", nil)
+	newTaxRouter(handlers.NewAdminTaxHandler(reporter), uuid.New()).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	reporter.AssertNotCalled(t, "GetTaxReport")
+}
+
+func TestReconcileTransactions_RejectsFeeAboveGross(t *testing.T) {
+	reporter := new(mockTaxReporter)
+	payload, _ := json.Marshal(map[string]interface{}{
+		"transactions": []map[string]interface{}{
+			{"provider_tx_id": "1000000001", "gross": 9.99, "store_fee": 8, "tax": 3, "currency": "USD"},
+		},
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1/admin/transactions/reconcile", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	newTaxRouter(handlers.NewAdminTaxHandler(reporter), uuid.New()).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	reporter.AssertNotCalled(t, "ReconcileTransactions")
+}
+
+func TestReconcileTransactions_ReturnsUnmatched(t *testing.T) {
+	appID := uuid.New()
+	reporter := new(mockTaxReporter)
+	reporter.On("ReconcileTransactions", mock.Anything, appID, mock.MatchedBy(func(items []service.TransactionReconciliation) bool {
+		return len(items) == 2
+	})).Return(&service.ReconcileResult{Matched: 1, Unmatched: []string{"1000000002"}}, nil)
+	payload, _ := json.Marshal(map[string]interface{}{
+		"transactions": []map[string]interface{}{
+			{"provider_tx_id": "1000000001", "gross": 9.99, "store_fee": 2.52, "tax": 1.59, "currency": "EUR", "country_code": "de"},
+			{"provider_tx_id": "1000000002", "gross": 4.99, "net": 3.49, "currency": "USD"},
+		},
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1/admin/transactions/reconcile", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	newTaxRouter(handlers.NewAdminTaxHandler(reporter), appID).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var body struct {
+		Data service.ReconcileResult `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, 1, body.Data.Matched)
+	assert.Equal(t, []string{"1000000002"}, body.Data.Unmatched)
+	reporter.AssertExpectations(t)
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+)
+
+func TestTransactionFilter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	appID := uuid.New()
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/v1/admin/transactions?provider=Apple&environment=sandbox&original_transaction_id=1000&search=tx_42&date_to=2026-01-31", nil)
+	c.Set(httpmiddleware.AppIDKey, appID)
+
+	q, args := transactionFilter(c)
+
+	assert.Contains(t, q, "t.app_id = $1 AND t.provider = $2 AND t.environment = $3 AND t.original_transaction_id = $4")
+	assert.Contains(t, q, "(u.email ILIKE $5 OR t.provider_tx_id = $6 OR t.original_transaction_id = $6)")
+	assert.Contains(t, q, "t.created_at < ($7::date + INTERVAL '1 day')")
+	assert.Equal(t, []interface{}{appID, "apple", "sandbox", "1000", "%tx_42%", "tx_42", "2026-01-31"}, args)
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+type warehouseSyncStatus interface {
+	Status(ctx context.Context) ([]service.WarehouseSyncState, error)
+}
+
+// AdminWarehouseHandler reports data warehouse sync progress
+type AdminWarehouseHandler struct {
+	sync     warehouseSyncStatus
+	provider string
+}
+
+func NewAdminWarehouseHandler(sync warehouseSyncStatus, provider string) *AdminWarehouseHandler {
+	return &AdminWarehouseHandler{sync: sync, provider: provider}
+}
+
+// GetWarehouseSyncStatus GET /v1/admin/warehouse/sync-status
+func (h *AdminWarehouseHandler) GetWarehouseSyncStatus(c *gin.Context) {
+	tables, err := h.sync.Status(c.Request.Context())
+	if err != nil {
+		response.InternalError(c, "Failed to get warehouse sync status")
+		return
+	}
+	response.OK(c, gin.H{"provider": h.provider, "enabled": h.provider != "", "tables": tables})
+}
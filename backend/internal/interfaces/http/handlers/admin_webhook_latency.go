@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+// maxWebhookLatencyWindowHours bounds the report to 30 days
+const maxWebhookLatencyWindowHours = 720
+
+type webhookLatencyReporter interface {
+	Report(ctx context.Context, appID uuid.UUID, window time.Duration) (*service.WebhookLatencyReport, error)
+}
+
+// AdminWebhookLatencyHandler reports per-provider webhook lag and suspected
+// missing webhooks
+type AdminWebhookLatencyHandler struct {
+	reporter webhookLatencyReporter
+	logger   *zap.Logger
+}
+
+func NewAdminWebhookLatencyHandler(reporter webhookLatencyReporter, logger *zap.Logger) *AdminWebhookLatencyHandler {
+	return &AdminWebhookLatencyHandler{reporter: reporter, logger: logger}
+}
+
+// GetWebhookLatency GET /v1/admin/webhooks/latency?window_hours=24
+func (h *AdminWebhookLatencyHandler) GetWebhookLatency(c *gin.Context) {
+	hours, err := strconv.Atoi(c.DefaultQuery("window_hours", "24"))
+	if err != nil || hours < 1 || hours > maxWebhookLatencyWindowHours {
+		response.BadRequest(c, "window_hours must be between 1 and 720")
+		return
+	}
+
+	appID := httpmiddleware.GetAppID(c)
+	report, err := h.reporter.Report(c.Request.Context(), appID, time.Duration(hours)*time.Hour)
+	if err != nil {
+		h.logger.Error("Failed to report webhook latency", zap.String("app_id", appID.String()), zap.Error(err))
+		response.InternalError(c, "Failed to report webhook latency")
+		return
+	}
+	response.OK(c, report)
+}
@@ -0,0 +1,74 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/handlers"
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+)
+
+type fakeWebhookLatencyReporter struct {
+	appID  uuid.UUID
+	window time.Duration
+}
+
+func (f *fakeWebhookLatencyReporter) Report(ctx context.Context, appID uuid.UUID, window time.Duration) (*service.WebhookLatencyReport, error) {
+	f.appID, f.window = appID, window
+	p95 := 42.0
+	return &service.WebhookLatencyReport{Providers: []service.ProviderWebhookLatency{
+		{Provider: "apple", Events: 12, ReceiptP95Seconds: &p95, SuspectedMissing: 1},
+	}}, nil
+}
+
+func getWebhookLatency(h *handlers.AdminWebhookLatencyHandler, appID uuid.UUID, query string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set(httpmiddleware.AppIDKey, appID)
+		c.Next()
+	})
+	r.GET("/v1/admin/webhooks/latency", h.GetWebhookLatency)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/webhooks/latency"+query, nil))
+	return w
+}
+
+func TestGetWebhookLatency_ReportsWindow(t *testing.T) {
+	reporter := &fakeWebhookLatencyReporter{}
+	appID := uuid.New()
+
+	w := getWebhookLatency(handlers.NewAdminWebhookLatencyHandler(reporter, zap.NewNop()), appID, "?window_hours=6")
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, appID, reporter.appID)
+	assert.Equal(t, 6*time.Hour, reporter.window)
+	var body struct {
+		Data service.WebhookLatencyReport `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.Len(t, body.Data.Providers, 1)
+	assert.Equal(t, int64(1), body.Data.Providers[0].SuspectedMissing)
+	assert.Nil(t, body.Data.Providers[0].ReceiptP50Seconds)
+}
+
+func TestGetWebhookLatency_RejectsWindow(t *testing.T) {
+	h := handlers.NewAdminWebhookLatencyHandler(&fakeWebhookLatencyReporter{}, zap.NewNop())
+
+	for _, query := range []string{"?window_hours=0", "?window_hours=721", "?window_hours=day"} {
+		w := getWebhookLatency(h, uuid.New(), query)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+type webhookQuarantineReader interface {
+	ListQuarantinedWebhooks(ctx context.Context, filter service.WebhookQuarantineFilter) ([]service.WebhookQuarantineEntry, error)
+	GetQuarantinedWebhook(ctx context.Context, id uuid.UUID) (*service.WebhookQuarantineEntry, error)
+	MarkWebhookReparsed(ctx context.Context, id uuid.UUID, eventType, eventID string) error
+	RecordWebhookReparseFailure(ctx context.Context, id uuid.UUID, reason string) error
+	DiscardQuarantinedWebhook(ctx context.Context, id uuid.UUID) error
+}
+
+type webhookReparser interface {
+	ReparseWebhook(ctx context.Context, provider string, rawBody []byte) (*ParsedWebhook, error)
+}
+
+// AdminWebhookQuarantineHandler inspects malformed webhook deliveries and
+// re-parses them once the parsing code has been fixed
+type AdminWebhookQuarantineHandler struct {
+	repo     webhookQuarantineReader
+	reparser webhookReparser
+	audit    adminAuditLogger
+	logger   *zap.Logger
+}
+
+func NewAdminWebhookQuarantineHandler(repo webhookQuarantineReader, reparser webhookReparser, audit adminAuditLogger, logger *zap.Logger) *AdminWebhookQuarantineHandler {
+	return &AdminWebhookQuarantineHandler{repo: repo, reparser: reparser, audit: audit, logger: logger}
+}
+
+// WebhookQuarantineDetail is a quarantine entry with its raw body. Bodies that
+// are not valid UTF-8 are returned base64-encoded.
+type WebhookQuarantineDetail struct {
+	service.WebhookQuarantineEntry
+	RawBody         string `json:"raw_body"`
+	RawBodyEncoding string `json:"raw_body_encoding"`
+}
+
+// ListQuarantinedWebhooks GET /v1/admin/webhooks/quarantine?provider=&status=&limit=
+func (h *AdminWebhookQuarantineHandler) ListQuarantinedWebhooks(c *gin.Context) {
+	filter := service.WebhookQuarantineFilter{
+		Provider: c.Query("provider"),
+		Status:   c.Query("status"),
+		Limit:    100,
+	}
+	switch filter.Provider {
+	case "", "stripe", "apple", "google", "amazon", "huawei", "paypal":
+	default:
+		response.BadRequest(c, "provider must be one of stripe, apple, google, amazon, huawei, paypal")
+		return
+	}
+	switch filter.Status {
+	case "", service.WebhookQuarantineStatusQuarantined, service.WebhookQuarantineStatusReparsed, service.WebhookQuarantineStatusDiscarded:
+	default:
+		response.BadRequest(c, "status must be one of quarantined, reparsed, discarded")
+		return
+	}
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 || limit > 500 {
+			response.BadRequest(c, "limit must be between 1 and 500")
+			return
+		}
+		filter.Limit = limit
+	}
+
+	entries, err := h.repo.ListQuarantinedWebhooks(c.Request.Context(), filter)
+	if err != nil {
+		h.logger.Error("Failed to list quarantined webhooks", zap.Error(err))
+		response.InternalError(c, "Failed to list quarantined webhooks")
+		return
+	}
+
+	response.OK(c, gin.H{"webhooks": entries, "total": len(entries)})
+}
+
+// GetQuarantinedWebhook GET /v1/admin/webhooks/quarantine/:id
+func (h *AdminWebhookQuarantineHandler) GetQuarantinedWebhook(c *gin.Context) {
+	entry, ok := h.loadEntry(c)
+	if !ok {
+		return
+	}
+
+	detail := WebhookQuarantineDetail{WebhookQuarantineEntry: *entry, RawBodyEncoding: "text"}
+	if utf8.Valid(entry.RawBody) {
+		detail.RawBody = string(entry.RawBody)
+	} else {
+		detail.RawBody = base64.StdEncoding.EncodeToString(entry.RawBody)
+		detail.RawBodyEncoding = "base64"
+	}
+
+	response.OK(c, detail)
+}
+
+// ReparseQuarantinedWebhook POST /v1/admin/webhooks/quarantine/:id/reparse
+// Runs the raw body through the current parsing code; on success the event is
+// stored and queued for processing exactly like a fresh delivery.
+func (h *AdminWebhookQuarantineHandler) ReparseQuarantinedWebhook(c *gin.Context) {
+	entry, ok := h.loadEntry(c)
+	if !ok {
+		return
+	}
+	if entry.Status != service.WebhookQuarantineStatusQuarantined {
+		response.Conflict(c, "Webhook is already "+entry.Status)
+		return
+	}
+
+	ctx := c.Request.Context()
+	event, err := h.reparser.ReparseWebhook(ctx, entry.Provider, entry.RawBody)
+	if err != nil {
+		if recordErr := h.repo.RecordWebhookReparseFailure(ctx, entry.ID, err.Error()); recordErr != nil {
+			h.logger.Warn("Failed to record webhook reparse failure", zap.String("id", entry.ID.String()), zap.Error(recordErr))
+		}
+		if errors.Is(err, ErrMalformedWebhook) {
+			response.UnprocessableEntity(c, err.Error())
+			return
+		}
+		h.logger.Error("Failed to reparse webhook", zap.String("id", entry.ID.String()), zap.Error(err))
+		response.InternalError(c, "Failed to reparse webhook")
+		return
+	}
+
+	if err := h.repo.MarkWebhookReparsed(ctx, entry.ID, event.EventType, event.EventID); err != nil {
+		if errors.Is(err, service.ErrWebhookQuarantineResolved) {
+			response.Conflict(c, "Webhook was resolved concurrently")
+			return
+		}
+		h.logger.Error("Failed to mark webhook reparsed", zap.String("id", entry.ID.String()), zap.Error(err))
+		response.InternalError(c, "Failed to mark webhook reparsed")
+		return
+	}
+
+	h.logAction(c, "reparse_webhook", map[string]interface{}{
+		"quarantine_id": entry.ID.String(),
+		"provider":      entry.Provider,
+		"event_type":    event.EventType,
+		"event_id":      event.EventID,
+	})
+
+	response.OK(c, event)
+}
+
+// DiscardQuarantinedWebhook POST /v1/admin/webhooks/quarantine/:id/discard
+func (h *AdminWebhookQuarantineHandler) DiscardQuarantinedWebhook(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid quarantine ID")
+		return
+	}
+
+	if err := h.repo.DiscardQuarantinedWebhook(c.Request.Context(), id); err != nil {
+		if errors.Is(err, service.ErrWebhookQuarantineResolved) {
+			response.Conflict(c, "Webhook is not quarantined")
+			return
+		}
+		h.logger.Error("Failed to discard quarantined webhook", zap.String("id", id.String()), zap.Error(err))
+		response.InternalError(c, "Failed to discard quarantined webhook")
+		return
+	}
+
+	h.logAction(c, "discard_webhook", map[string]interface{}{"quarantine_id": id.String()})
+
+	response.OK(c, gin.H{"id": id, "status": service.WebhookQuarantineStatusDiscarded})
+}
+
+func (h *AdminWebhookQuarantineHandler) loadEntry(c *gin.Context) (*service.WebhookQuarantineEntry, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid quarantine ID")
+		return nil, false
+	}
+	entry, err := h.repo.GetQuarantinedWebhook(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, service.ErrWebhookQuarantineNotFound) {
+			response.NotFound(c, "Quarantined webhook not found")
+			return nil, false
+		}
+		h.logger.Error("Failed to get quarantined webhook", zap.String("id", id.String()), zap.Error(err))
+		response.InternalError(c, "Failed to get quarantined webhook")
+		return nil, false
+	}
+	return entry, true
+}
+
+func (h *AdminWebhookQuarantineHandler) logAction(c *gin.Context, action string, details map[string]interface{}) {
+	adminIDValue, _ := c.Get("admin_id")
+	adminID, ok := adminIDValue.(uuid.UUID)
+	if !ok {
+		return
+	}
+	if err := h.audit.LogAction(c.Request.Context(), adminID, action, "webhook", nil, details); err != nil {
+		h.logger.Warn("Failed to audit webhook quarantine action", zap.String("action", action), zap.Error(err))
+	}
+}
@@ -0,0 +1,169 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/handlers"
+)
+
+type fakeQuarantineRepo struct {
+	entries  map[uuid.UUID]*service.WebhookQuarantineEntry
+	failures []string
+}
+
+func (f *fakeQuarantineRepo) ListQuarantinedWebhooks(ctx context.Context, filter service.WebhookQuarantineFilter) ([]service.WebhookQuarantineEntry, error) {
+	entries := make([]service.WebhookQuarantineEntry, 0)
+	for _, entry := range f.entries {
+		if filter.Status == "" || entry.Status == filter.Status {
+			entries = append(entries, *entry)
+		}
+	}
+	return entries, nil
+}
+
+func (f *fakeQuarantineRepo) GetQuarantinedWebhook(ctx context.Context, id uuid.UUID) (*service.WebhookQuarantineEntry, error) {
+	entry, ok := f.entries[id]
+	if !ok {
+		return nil, service.ErrWebhookQuarantineNotFound
+	}
+	copied := *entry
+	return &copied, nil
+}
+
+func (f *fakeQuarantineRepo) MarkWebhookReparsed(ctx context.Context, id uuid.UUID, eventType, eventID string) error {
+	f.entries[id].Status = service.WebhookQuarantineStatusReparsed
+	f.entries[id].EventID = &eventID
+	return nil
+}
+
+func (f *fakeQuarantineRepo) RecordWebhookReparseFailure(ctx context.Context, id uuid.UUID, reason string) error {
+	f.failures = append(f.failures, reason)
+	return nil
+}
+
+func (f *fakeQuarantineRepo) DiscardQuarantinedWebhook(ctx context.Context, id uuid.UUID) error {
+	if f.entries[id].Status != service.WebhookQuarantineStatusQuarantined {
+		return service.ErrWebhookQuarantineResolved
+	}
+	f.entries[id].Status = service.WebhookQuarantineStatusDiscarded
+	return nil
+}
+
+type fakeWebhookReparser struct {
+	err error
+}
+
+func (f fakeWebhookReparser) ReparseWebhook(ctx context.Context, provider string, rawBody []byte) (*handlers.ParsedWebhook, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &handlers.ParsedWebhook{Provider: provider, EventType: "invoice.paid", EventID: "evt_1"}, nil
+}
+
+func newQuarantineRouter(repo *fakeQuarantineRepo, reparser fakeWebhookReparser, audit *fakeAuditLogger) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := handlers.NewAdminWebhookQuarantineHandler(repo, reparser, audit, zap.NewNop())
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("admin_id", uuid.New())
+		c.Next()
+	})
+	r.GET("/v1/admin/webhooks/quarantine", h.ListQuarantinedWebhooks)
+	r.GET("/v1/admin/webhooks/quarantine/:id", h.GetQuarantinedWebhook)
+	r.POST("/v1/admin/webhooks/quarantine/:id/reparse", h.ReparseQuarantinedWebhook)
+	r.POST("/v1/admin/webhooks/quarantine/:id/discard", h.DiscardQuarantinedWebhook)
+	return r
+}
+
+func quarantineRequest(r *gin.Engine, method, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+	return w
+}
+
+func newQuarantinedEntry(body []byte) *service.WebhookQuarantineEntry {
+	return &service.WebhookQuarantineEntry{
+		ID:       uuid.New(),
+		Provider: "stripe",
+		RawBody:  body,
+		Error:    "Invalid event body: stripe_event: $.type: required",
+		Status:   service.WebhookQuarantineStatusQuarantined,
+	}
+}
+
+func TestAdminWebhookQuarantine_ListAndInspect(t *testing.T) {
+	text := newQuarantinedEntry([]byte(`{"id":"evt_1"}`))
+	binary := newQuarantinedEntry([]byte{0xff, 0xfe})
+	repo := &fakeQuarantineRepo{entries: map[uuid.UUID]*service.WebhookQuarantineEntry{text.ID: text, binary.ID: binary}}
+	r := newQuarantineRouter(repo, fakeWebhookReparser{}, &fakeAuditLogger{})
+
+	w := quarantineRequest(r, http.MethodGet, "/v1/admin/webhooks/quarantine?status=quarantined")
+	require.Equal(t, http.StatusOK, w.Code, "body=%s", w.Body.String())
+	assert.Contains(t, w.Body.String(), `"total":2`)
+	assert.NotContains(t, w.Body.String(), "raw_body")
+
+	assert.Equal(t, http.StatusBadRequest, quarantineRequest(r, http.MethodGet, "/v1/admin/webhooks/quarantine?provider=paddle").Code)
+
+	var detail struct {
+		Data handlers.WebhookQuarantineDetail `json:"data"`
+	}
+	w = quarantineRequest(r, http.MethodGet, "/v1/admin/webhooks/quarantine/"+text.ID.String())
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &detail))
+	assert.Equal(t, `{"id":"evt_1"}`, detail.Data.RawBody)
+	assert.Equal(t, "text", detail.Data.RawBodyEncoding)
+
+	w = quarantineRequest(r, http.MethodGet, "/v1/admin/webhooks/quarantine/"+binary.ID.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &detail))
+	assert.Equal(t, "//4=", detail.Data.RawBody)
+	assert.Equal(t, "base64", detail.Data.RawBodyEncoding)
+
+	assert.Equal(t, http.StatusNotFound, quarantineRequest(r, http.MethodGet, "/v1/admin/webhooks/quarantine/"+uuid.New().String()).Code)
+}
+
+func TestAdminWebhookQuarantine_ReparseMarksEntryAndAudits(t *testing.T) {
+	entry := newQuarantinedEntry([]byte(`{"id":"evt_1","type":"invoice.paid","data":{"object":{}}}`))
+	repo := &fakeQuarantineRepo{entries: map[uuid.UUID]*service.WebhookQuarantineEntry{entry.ID: entry}}
+	audit := &fakeAuditLogger{}
+	r := newQuarantineRouter(repo, fakeWebhookReparser{}, audit)
+	path := "/v1/admin/webhooks/quarantine/" + entry.ID.String() + "/reparse"
+
+	w := quarantineRequest(r, http.MethodPost, path)
+	require.Equal(t, http.StatusOK, w.Code, "body=%s", w.Body.String())
+	assert.Contains(t, w.Body.String(), `"event_id":"evt_1"`)
+	assert.Equal(t, service.WebhookQuarantineStatusReparsed, entry.Status)
+	assert.Equal(t, []string{"reparse_webhook"}, audit.actions)
+
+	assert.Equal(t, http.StatusConflict, quarantineRequest(r, http.MethodPost, path).Code)
+	assert.Equal(t, http.StatusConflict, quarantineRequest(r, http.MethodPost, "/v1/admin/webhooks/quarantine/"+entry.ID.String()+"/discard").Code)
+}
+
+func TestAdminWebhookQuarantine_ReparseFailureIsRecorded(t *testing.T) {
+	entry := newQuarantinedEntry([]byte(`{"id":"evt_1"}`))
+	repo := &fakeQuarantineRepo{entries: map[uuid.UUID]*service.WebhookQuarantineEntry{entry.ID: entry}}
+	malformed := fmt.Errorf("still broken: %w", handlers.ErrMalformedWebhook)
+	r := newQuarantineRouter(repo, fakeWebhookReparser{err: malformed}, &fakeAuditLogger{})
+
+	w := quarantineRequest(r, http.MethodPost, "/v1/admin/webhooks/quarantine/"+entry.ID.String()+"/reparse")
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, service.WebhookQuarantineStatusQuarantined, entry.Status)
+	assert.Equal(t, []string{malformed.Error()}, repo.failures)
+
+	failing := newQuarantineRouter(repo, fakeWebhookReparser{err: errors.New("redis down")}, &fakeAuditLogger{})
+	w = quarantineRequest(failing, http.MethodPost, "/v1/admin/webhooks/quarantine/"+entry.ID.String()+"/reparse")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Len(t, repo.failures, 2)
+}
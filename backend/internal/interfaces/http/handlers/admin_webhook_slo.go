@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+type webhookSLOEvaluator interface {
+	Evaluate(ctx context.Context, appID uuid.UUID) (*service.WebhookSLOReport, error)
+}
+
+// AdminWebhookSLOHandler reports webhook latency SLOs with their burn rates
+type AdminWebhookSLOHandler struct {
+	evaluator webhookSLOEvaluator
+	logger    *zap.Logger
+}
+
+func NewAdminWebhookSLOHandler(evaluator webhookSLOEvaluator, logger *zap.Logger) *AdminWebhookSLOHandler {
+	return &AdminWebhookSLOHandler{evaluator: evaluator, logger: logger}
+}
+
+// GetWebhookSLOs GET /v1/admin/webhooks/slo
+func (h *AdminWebhookSLOHandler) GetWebhookSLOs(c *gin.Context) {
+	appID := httpmiddleware.GetAppID(c)
+	report, err := h.evaluator.Evaluate(c.Request.Context(), appID)
+	if err != nil {
+		h.logger.Error("Failed to evaluate webhook SLOs", zap.String("app_id", appID.String()), zap.Error(err))
+		response.InternalError(c, "Failed to evaluate webhook SLOs")
+		return
+	}
+	response.OK(c, report)
+}
@@ -0,0 +1,77 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/handlers"
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+)
+
+type fakeWebhookSLOEvaluator struct {
+	appID uuid.UUID
+	err   error
+}
+
+func (f *fakeWebhookSLOEvaluator) Evaluate(ctx context.Context, appID uuid.UUID) (*service.WebhookSLOReport, error) {
+	f.appID = appID
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &service.WebhookSLOReport{SLOs: []service.WebhookSLOStatus{{
+		Name:      "renewal_processing",
+		Objective: 0.95,
+		Status:    service.HealthRed,
+		Alerts:    []service.WebhookSLOAlert{{Severity: "page", LongWindow: "1h", ShortWindow: "5m", Firing: true}},
+	}}}, nil
+}
+
+func getWebhookSLOs(h *handlers.AdminWebhookSLOHandler, appID uuid.UUID) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set(httpmiddleware.AppIDKey, appID)
+		c.Next()
+	})
+	r.GET("/v1/admin/webhooks/slo", h.GetWebhookSLOs)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/webhooks/slo", nil))
+	return w
+}
+
+func TestGetWebhookSLOs_ReportsApp(t *testing.T) {
+	evaluator := &fakeWebhookSLOEvaluator{}
+	appID := uuid.New()
+
+	w := getWebhookSLOs(handlers.NewAdminWebhookSLOHandler(evaluator, zap.NewNop()), appID)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, appID, evaluator.appID)
+	var body struct {
+		Data service.WebhookSLOReport `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.Len(t, body.Data.SLOs, 1)
+	assert.Equal(t, service.HealthRed, body.Data.SLOs[0].Status)
+	assert.True(t, body.Data.SLOs[0].Alerts[0].Firing)
+}
+
+func TestGetWebhookSLOs_EvaluationFails(t *testing.T) {
+	h := handlers.NewAdminWebhookSLOHandler(&fakeWebhookSLOEvaluator{err: errors.New("db down")}, zap.NewNop())
+
+	w := getWebhookSLOs(h, uuid.New())
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
@@ -65,8 +65,15 @@ func (h *AnalyticsHandlersExtended) GetLTV(c *gin.Context) {
 	}
 
 	// Cache the result
-	ltvData := &cache.LTVData{
-		UserID:       userIDStr,
+	h.analyticsCache.SetLTV(c.Request.Context(), userIDStr, ltvCacheData(userIDStr, estimates))
+
+	response.OK(c, estimates)
+}
+
+// ltvCacheData converts LTV estimates to their cached form
+func ltvCacheData(userID string, estimates *service.LTVEstimates) *cache.LTVData {
+	return &cache.LTVData{
+		UserID:       userID,
 		LTV30:        estimates.LTV30,
 		LTV90:        estimates.LTV90,
 		LTV365:       estimates.LTV365,
@@ -74,10 +81,15 @@ func (h *AnalyticsHandlersExtended) GetLTV(c *gin.Context) {
 		Confidence:   estimates.Confidence,
 		CalculatedAt: estimates.CalculatedAt,
 		Factors:      estimates.Factors,
+
+		LTV30Interval:  cachedLTVInterval(estimates.LTV30Interval),
+		LTV90Interval:  cachedLTVInterval(estimates.LTV90Interval),
+		LTV365Interval: cachedLTVInterval(estimates.LTV365Interval),
 	}
-	h.analyticsCache.SetLTV(c.Request.Context(), userIDStr, ltvData)
+}
 
-	response.OK(c, estimates)
+func cachedLTVInterval(interval service.LTVInterval) *cache.LTVInterval {
+	return &cache.LTVInterval{Lower: interval.Lower, Upper: interval.Upper}
 }
 
 // UpdateLTV updates LTV after a purchase
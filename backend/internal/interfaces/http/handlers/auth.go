@@ -10,10 +10,12 @@ import (
 	"github.com/bivex/paywall-iap/internal/application/command"
 	"github.com/bivex/paywall-iap/internal/application/dto"
 	"github.com/bivex/paywall-iap/internal/application/middleware"
+	"github.com/bivex/paywall-iap/internal/domain/entity"
 	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
 	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
 )
 
 // AuthHandler handles authentication endpoints
@@ -21,6 +23,7 @@ type AuthHandler struct {
 	registerCmd   *command.RegisterCommand
 	adminLoginCmd *command.AdminLoginCommand
 	jwtMiddleware *middleware.JWTMiddleware
+	sessions      *command.UserSessionCommand
 }
 
 // NewAuthHandler creates a new auth handler
@@ -36,6 +39,13 @@ func NewAuthHandler(
 	}
 }
 
+// WithSessions rotates refresh tokens within device sessions and enables
+// the session list endpoints
+func (h *AuthHandler) WithSessions(sessions *command.UserSessionCommand) *AuthHandler {
+	h.sessions = sessions
+	return h
+}
+
 // Register handles user registration
 // @Summary Register a new user
 // @Tags auth
@@ -52,6 +62,8 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		response.BadRequest(c, err.Error())
 		return
 	}
+	req.UserAgent = c.Request.UserAgent()
+	req.IPAddress = c.ClientIP()
 
 	resp, err := h.registerCmd.Execute(c.Request.Context(), &req)
 	if err != nil {
@@ -100,6 +112,11 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 		response.Unauthorized(c, "Invalid refresh token")
 		return
 	}
+	// Impersonation and client-credentials tokens are never refreshable
+	if claims.ImpersonatedBy != "" || claims.ClientID != "" {
+		response.Unauthorized(c, "Invalid refresh token")
+		return
+	}
 
 	// Check blocklist — token may have been explicitly revoked
 	revoked, err := h.jwtMiddleware.IsRevoked(ctx, claims.JTI)
@@ -112,18 +129,40 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 		return
 	}
 
-	// Issue new access token
-	accessToken, _, err := h.jwtMiddleware.GenerateAccessToken(claims.UserID)
-	if err != nil {
-		response.InternalError(c, "Failed to generate access token")
-		return
-	}
+	var accessToken, newRefreshToken string
+	if h.sessions != nil {
+		// Rotate within the device session
+		tokens, err := h.sessions.Refresh(ctx, claims, entity.DeviceInfo{
+			UserAgent: c.Request.UserAgent(),
+			IPAddress: c.ClientIP(),
+		})
+		if errors.Is(err, command.ErrSessionRevoked) {
+			response.Unauthorized(c, "Session has been revoked")
+			return
+		}
+		if errors.Is(err, command.ErrTokenNotRefreshable) {
+			response.Unauthorized(c, "Invalid refresh token")
+			return
+		}
+		if err != nil {
+			response.InternalError(c, "Failed to refresh session")
+			return
+		}
+		accessToken, newRefreshToken = tokens.AccessToken, tokens.RefreshToken
+	} else {
+		// Issue new access token
+		accessToken, _, err = h.jwtMiddleware.GenerateAccessToken(claims.UserID)
+		if err != nil {
+			response.InternalError(c, "Failed to generate access token")
+			return
+		}
 
-	// Rotate: issue a new refresh token
-	newRefreshToken, _, err := h.jwtMiddleware.GenerateRefreshToken(claims.UserID)
-	if err != nil {
-		response.InternalError(c, "Failed to generate refresh token")
-		return
+		// Rotate: issue a new refresh token
+		newRefreshToken, _, err = h.jwtMiddleware.GenerateRefreshToken(claims.UserID)
+		if err != nil {
+			response.InternalError(c, "Failed to generate refresh token")
+			return
+		}
 	}
 
 	// Revoke the old refresh token (remaining TTL from its expiry)
@@ -142,6 +181,77 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 	})
 }
 
+// ListSessions lists the devices signed in to the current user's account
+// @Summary List active sessions
+// @Tags auth
+// @Produce json
+// @Success 200 {object} response.SuccessResponse{data=[]dto.SessionResponse}
+// @Failure 401 {object} response.ErrorResponse
+// @Router /auth/sessions [get]
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		response.Unauthorized(c, "Invalid user")
+		return
+	}
+
+	sessions, err := h.sessions.List(c.Request.Context(), userID)
+	if err != nil {
+		response.InternalError(c, "Failed to list sessions")
+		return
+	}
+
+	current := c.GetString("session_id")
+	out := make([]dto.SessionResponse, 0, len(sessions))
+	for _, s := range sessions {
+		out = append(out, dto.SessionResponse{
+			ID:         s.ID.String(),
+			DeviceID:   s.Device.DeviceID,
+			Platform:   s.Device.Platform,
+			AppVersion: s.Device.AppVersion,
+			UserAgent:  s.Device.UserAgent,
+			IPAddress:  s.Device.IPAddress,
+			CreatedAt:  s.CreatedAt.Format(time.RFC3339),
+			LastSeenAt: s.LastSeenAt.Format(time.RFC3339),
+			ExpiresAt:  s.ExpiresAt.Format(time.RFC3339),
+			Current:    s.ID.String() == current,
+		})
+	}
+	response.OK(c, out)
+}
+
+// RevokeSession signs one of the current user's devices out
+// @Summary Revoke a session
+// @Tags auth
+// @Param id path string true "Session ID"
+// @Success 204
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Router /auth/sessions/{id} [delete]
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		response.Unauthorized(c, "Invalid user")
+		return
+	}
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid session ID")
+		return
+	}
+
+	err = h.sessions.Revoke(c.Request.Context(), userID, sessionID)
+	if errors.Is(err, domainErrors.ErrNotFound) {
+		response.NotFound(c, "Session not found")
+		return
+	}
+	if err != nil {
+		response.InternalError(c, "Failed to revoke session")
+		return
+	}
+	response.NoContent(c)
+}
+
 // AdminLogin handles admin login with email + password.
 // @Summary Admin login
 // @Tags admin-auth
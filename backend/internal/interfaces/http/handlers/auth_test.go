@@ -101,6 +101,42 @@ func TestAdminLogout_RejectsEmptyRefreshToken(t *testing.T) {
 	require.Contains(t, recorder.Body.String(), `"Invalid request body"`)
 }
 
+func TestRefreshToken_RejectsImpersonationToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	jwtm := middleware.NewJWTMiddleware("test-secret", redis.NewClient(&redis.Options{Addr: "localhost:0"}), time.Minute)
+	token, _, _, err := jwtm.GenerateImpersonationToken("user-1", "", "admin-1", 0)
+	require.NoError(t, err)
+
+	handler := NewAuthHandler(nil, nil, jwtm)
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/v1/auth/refresh", strings.NewReader(`{"refresh_token":"`+token+`"}`))
+	ctx.Request.Header.Set("Content-Type", "application/json")
+
+	handler.RefreshToken(ctx)
+
+	require.Equal(t, http.StatusUnauthorized, recorder.Code, "body=%s", recorder.Body.String())
+	require.Contains(t, recorder.Body.String(), `"Invalid refresh token"`)
+}
+
+func TestRefreshToken_RejectsClientCredentialsToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	jwtm := middleware.NewJWTMiddleware("test-secret", redis.NewClient(&redis.Options{Addr: "localhost:0"}), time.Minute)
+	token, _, err := jwtm.GenerateClientToken("client-1", "", []string{"read:analytics"})
+	require.NoError(t, err)
+
+	handler := NewAuthHandler(nil, nil, jwtm)
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/v1/auth/refresh", strings.NewReader(`{"refresh_token":"`+token+`"}`))
+	ctx.Request.Header.Set("Content-Type", "application/json")
+
+	handler.RefreshToken(ctx)
+
+	require.Equal(t, http.StatusUnauthorized, recorder.Code, "body=%s", recorder.Body.String())
+	require.Contains(t, recorder.Body.String(), `"Invalid refresh token"`)
+}
+
 func TestRegister_RejectsUnknownFields(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	handler := NewAuthHandler(nil, nil, nil)
@@ -14,9 +14,44 @@ import (
 	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
 )
 
+// statisticsWinSimulations is the Monte Carlo draws behind the win
+// probabilities of the statistics endpoint
+const statisticsWinSimulations = 1000
+
 // BanditHandler handles multi-armed bandit endpoints
 type BanditHandler struct {
 	banditService BanditService
+	segments      experimentSegmentGate
+	eligibility   experimentEligibilityGate
+	payloads      armPayloadSource
+	products      productRewardRecorder
+}
+
+// experimentSegmentGate restricts experiments that target a segment
+type experimentSegmentGate interface {
+	AllowsExperiment(ctx context.Context, experimentID, userID uuid.UUID, country string) (bool, error)
+}
+
+// experimentEligibilityGate enforces an experiment's country, platform and
+// version constraints
+type experimentEligibilityGate interface {
+	CheckEligibility(ctx context.Context, experimentID, userID uuid.UUID, eligibilityCtx service.EligibilityContext) (*service.EligibilityDecision, error)
+}
+
+// armPayloadSource loads the variant content returned with an assignment
+type armPayloadSource interface {
+	GetExperimentArmPayload(ctx context.Context, experimentID, armID uuid.UUID) (*service.ArmPayload, error)
+}
+
+// cachedWinProbabilities serves win probabilities from a short-lived cache
+type cachedWinProbabilities interface {
+	CachedWinProbability(ctx context.Context, experimentID uuid.UUID, simulations int) (*service.WinProbabilityResult, error)
+}
+
+// productRewardRecorder records a purchase reward under the experiment's
+// reward basis, which for margin subtracts the product's cost
+type productRewardRecorder interface {
+	RecordProductReward(ctx context.Context, experimentID, armID, userID uuid.UUID, transactionID *uuid.UUID, productID string, reward float64, currency string, userContext service.UserContext) error
 }
 
 // BanditService defines the interface for bandit operations
@@ -28,6 +63,7 @@ type BanditService interface {
 	UpdateRewardWithEvent(ctx context.Context, experimentID, armID uuid.UUID, reward float64, event *service.ConversionEvent) error
 	GetArmStatistics(ctx context.Context, experimentID uuid.UUID) (map[uuid.UUID]*service.ArmStats, error)
 	CalculateWinProbability(ctx context.Context, experimentID uuid.UUID, simulations int) (map[uuid.UUID]float64, error)
+	SummarizeRevenuePosterior(stats *service.ArmStats) *service.RevenuePosteriorSummary
 }
 
 // NewBanditHandler creates a new bandit handler
@@ -37,10 +73,43 @@ func NewBanditHandler(banditService BanditService) *BanditHandler {
 	}
 }
 
+// WithSegmentGate only assigns users in an experiment's target segment
+func (h *BanditHandler) WithSegmentGate(gate experimentSegmentGate) *BanditHandler {
+	h.segments = gate
+	return h
+}
+
+// WithEligibility shows users outside an experiment's eligibility its control
+// arm without assigning them
+func (h *BanditHandler) WithEligibility(gate experimentEligibilityGate) *BanditHandler {
+	h.eligibility = gate
+	return h
+}
+
+// WithArmPayloads returns each assigned arm's variant content
+func (h *BanditHandler) WithArmPayloads(source armPayloadSource) *BanditHandler {
+	h.payloads = source
+	return h
+}
+
+// WithProductRewards records rewards that name a product under the
+// experiment's reward basis
+func (h *BanditHandler) WithProductRewards(recorder productRewardRecorder) *BanditHandler {
+	h.products = recorder
+	return h
+}
+
 // AssignRequest represents the request to assign a user to a variant
 type AssignRequest struct {
 	ExperimentID string `json:"experiment_id" binding:"required,uuid"`
 	UserID       string `json:"user_id" binding:"required,uuid"`
+	// Country is used by real-time segments targeting the experiment
+	Country string `json:"country,omitempty"`
+	// Platform and AppVersion override the user's stored values for
+	// eligibility checks; OSVersion is checked against min_os_versions
+	Platform   string `json:"platform,omitempty" binding:"omitempty,oneof=ios android web"`
+	AppVersion string `json:"app_version,omitempty" binding:"max=32"`
+	OSVersion  string `json:"os_version,omitempty" binding:"max=32"`
 }
 
 // AssignResponse represents the response with the assigned variant
@@ -49,6 +118,11 @@ type AssignResponse struct {
 	UserID       string `json:"user_id"`
 	ArmID        string `json:"arm_id"`
 	IsNew        bool   `json:"is_new"` // true if this is a new assignment (not from cache)
+	// Payload is the variant content the client renders, if the arm has any
+	Payload *service.ArmPayload `json:"payload,omitempty"`
+	// Excluded users are shown the control arm without being assigned
+	Excluded        bool   `json:"excluded,omitempty"`
+	ExclusionReason string `json:"exclusion_reason,omitempty"`
 }
 
 // Assign assigns a user to an experiment arm using Thompson Sampling
@@ -81,6 +155,49 @@ func (h *BanditHandler) Assign(c *gin.Context) {
 		return
 	}
 
+	if h.segments != nil {
+		allowed, err := h.segments.AllowsExperiment(c.Request.Context(), experimentID, userID, req.Country)
+		if err != nil {
+			response.InternalError(c, "Failed to check experiment segment")
+			return
+		}
+		if !allowed {
+			response.Forbidden(c, "User is not in the experiment's target segment")
+			return
+		}
+	}
+
+	if h.eligibility != nil {
+		decision, err := h.eligibility.CheckEligibility(c.Request.Context(), experimentID, userID, service.EligibilityContext{
+			Country:    req.Country,
+			Platform:   req.Platform,
+			AppVersion: req.AppVersion,
+			OSVersion:  req.OSVersion,
+		})
+		if err != nil {
+			response.InternalError(c, "Failed to check experiment eligibility")
+			return
+		}
+		if !decision.Eligible {
+			if decision.FallbackArmID == uuid.Nil {
+				response.Forbidden(c, "User is not eligible for the experiment")
+				return
+			}
+			resp := AssignResponse{
+				ExperimentID:    req.ExperimentID,
+				UserID:          req.UserID,
+				ArmID:           decision.FallbackArmID.String(),
+				Excluded:        true,
+				ExclusionReason: decision.Reason,
+			}
+			if !h.attachArmPayload(c, experimentID, decision.FallbackArmID, &resp) {
+				return
+			}
+			response.OK(c, resp)
+			return
+		}
+	}
+
 	// Get arm assignment using Thompson Sampling
 	armID, isNew, err := h.banditService.SelectArmWithMeta(c.Request.Context(), experimentID, userID)
 	if err != nil {
@@ -88,6 +205,14 @@ func (h *BanditHandler) Assign(c *gin.Context) {
 			response.NotFound(c, "Experiment not found or has no arms")
 			return
 		}
+		if errors.Is(err, service.ErrUserExcluded) {
+			response.Conflict(c, "User was excluded from the experiment")
+			return
+		}
+		if errors.Is(err, service.ErrExperimentPaused) {
+			response.Conflict(c, "Experiment is paused")
+			return
+		}
 
 		response.InternalError(c, "Failed to assign arm: "+err.Error())
 		return
@@ -99,10 +224,28 @@ func (h *BanditHandler) Assign(c *gin.Context) {
 		ArmID:        armID.String(),
 		IsNew:        isNew,
 	}
+	if !h.attachArmPayload(c, experimentID, armID, &resp) {
+		return
+	}
 
 	response.OK(c, resp)
 }
 
+// attachArmPayload sets the arm's variant content on resp, writing an error
+// response and returning false when it cannot be loaded
+func (h *BanditHandler) attachArmPayload(c *gin.Context, experimentID, armID uuid.UUID, resp *AssignResponse) bool {
+	if h.payloads == nil {
+		return true
+	}
+	payload, err := h.payloads.GetExperimentArmPayload(c.Request.Context(), experimentID, armID)
+	if err != nil && !errors.Is(err, service.ErrExperimentArmNotFound) {
+		response.InternalError(c, "Failed to load arm payload")
+		return false
+	}
+	resp.Payload = payload
+	return true
+}
+
 // RewardRequest represents the request to record a reward/conversion
 type RewardRequest struct {
 	ExperimentID string   `json:"experiment_id" binding:"required,uuid"`
@@ -110,6 +253,10 @@ type RewardRequest struct {
 	UserID       string   `json:"user_id" binding:"required,uuid"`
 	Reward       *float64 `json:"reward" binding:"required"`
 	Currency     string   `json:"currency,omitempty"`
+	// TransactionID makes the reward idempotent: a transaction is counted once
+	TransactionID string `json:"transaction_id,omitempty" binding:"omitempty,uuid"`
+	// ProductID is the purchased product; the margin reward basis subtracts its cost
+	ProductID string `json:"product_id,omitempty" binding:"max=200"`
 }
 
 // RewardResponse represents the response after recording a reward
@@ -118,15 +265,30 @@ type RewardResponse struct {
 	ArmID        string  `json:"arm_id"`
 	Reward       float64 `json:"reward"`
 	Updated      bool    `json:"updated"`
+	// Duplicate is set when the transaction was already counted
+	Duplicate bool `json:"duplicate,omitempty"`
 }
 
 type ImpressionRequest struct {
 	ExperimentID string                 `json:"experiment_id" binding:"required,uuid"`
 	ArmID        string                 `json:"arm_id" binding:"required,uuid"`
 	UserID       string                 `json:"user_id" binding:"required,uuid"`
+	EventType    string                 `json:"event_type,omitempty" binding:"omitempty,oneof=impression purchase_initiated"`
+	Placement    string                 `json:"placement,omitempty" binding:"max=100"`
 	Metadata     map[string]interface{} `json:"metadata,omitempty"`
 }
 
+// placement falls back to metadata.placement for clients that predate the field
+func (r ImpressionRequest) placement() string {
+	if r.Placement != "" {
+		return r.Placement
+	}
+	if placement, ok := r.Metadata["placement"].(string); ok && len(placement) <= 100 {
+		return placement
+	}
+	return ""
+}
+
 type ImpressionResponse struct {
 	ExperimentID string `json:"experiment_id"`
 	ArmID        string `json:"arm_id"`
@@ -134,7 +296,8 @@ type ImpressionResponse struct {
 	Tracked      bool   `json:"tracked"`
 }
 
-// Impression records an exposure/impression for an assigned arm
+// Impression records an exposure/impression for an assigned arm, or with
+// event_type purchase_initiated the user starting checkout from it
 // @Summary Record impression for arm
 // @Tags bandit
 // @Accept json
@@ -170,11 +333,17 @@ func (h *BanditHandler) Impression(c *gin.Context) {
 		return
 	}
 
+	eventType := service.ImpressionEventTypeImpression
+	if req.EventType != "" {
+		eventType = service.ImpressionEventType(req.EventType)
+	}
+
 	err = h.banditService.TrackImpression(c.Request.Context(), experimentID, armID, userID, &service.ImpressionEvent{
 		ExperimentID: experimentID,
 		ArmID:        armID,
 		UserID:       userID,
-		EventType:    service.ImpressionEventTypeImpression,
+		EventType:    eventType,
+		Placement:    req.placement(),
 		Metadata:     req.Metadata,
 		OccurredAt:   time.Now().UTC(),
 	})
@@ -238,26 +407,51 @@ func (h *BanditHandler) Reward(c *gin.Context) {
 
 	reward := *req.Reward
 
+	var transactionID *uuid.UUID
+	if req.TransactionID != "" {
+		parsed, err := uuid.Parse(req.TransactionID)
+		if err != nil {
+			response.BadRequest(c, "Invalid transaction ID")
+			return
+		}
+		transactionID = &parsed
+	}
+
 	// Update the bandit with the reward
-	err = h.banditService.UpdateRewardWithEvent(c.Request.Context(), experimentID, armID, reward, &service.ConversionEvent{
-		ExperimentID:          experimentID,
-		ArmID:                 armID,
-		UserID:                &userID,
-		EventType:             service.ConversionEventTypeDirectReward,
-		OriginalRewardValue:   reward,
-		OriginalCurrency:      req.Currency,
-		NormalizedRewardValue: reward,
-		NormalizedCurrency:    req.Currency,
-		Metadata: map[string]interface{}{
-			"source": "bandit_reward_api",
-		},
-		OccurredAt: time.Now().UTC(),
-	})
+	if req.ProductID != "" && h.products != nil {
+		err = h.products.RecordProductReward(c.Request.Context(), experimentID, armID, userID, transactionID,
+			req.ProductID, reward, req.Currency, service.UserContext{UserID: userID})
+	} else {
+		err = h.banditService.UpdateRewardWithEvent(c.Request.Context(), experimentID, armID, reward, &service.ConversionEvent{
+			ExperimentID:          experimentID,
+			ArmID:                 armID,
+			UserID:                &userID,
+			TransactionID:         transactionID,
+			EventType:             service.ConversionEventTypeDirectReward,
+			OriginalRewardValue:   reward,
+			OriginalCurrency:      req.Currency,
+			NormalizedRewardValue: reward,
+			NormalizedCurrency:    req.Currency,
+			Metadata: map[string]interface{}{
+				"source": "bandit_reward_api",
+			},
+			OccurredAt: time.Now().UTC(),
+		})
+	}
 	if err != nil {
 		if errors.Is(err, service.ErrBanditArmNotFound) {
 			response.NotFound(c, "Arm not found")
 			return
 		}
+		if errors.Is(err, service.ErrDuplicateConversion) {
+			response.OK(c, RewardResponse{
+				ExperimentID: req.ExperimentID,
+				ArmID:        req.ArmID,
+				Reward:       reward,
+				Duplicate:    true,
+			})
+			return
+		}
 
 		response.InternalError(c, "Failed to record reward: "+err.Error())
 		return
@@ -283,6 +477,13 @@ type StatisticsResponse struct {
 	ExperimentID string             `json:"experiment_id"`
 	Arms         []ArmStatistics    `json:"arms"`
 	WinProbs     map[string]float64 `json:"win_probabilities,omitempty"`
+	// WinProbsCachedAt is when the win probabilities were simulated and
+	// WinProbsSimulations how many draws they took
+	WinProbsCachedAt    *time.Time `json:"win_probabilities_cached_at,omitempty"`
+	WinProbsSimulations int        `json:"win_probabilities_simulations,omitempty"`
+	// ExpectedLoss is each arm's expected conversion rate given up by
+	// choosing it, from the same simulation as the win probabilities
+	ExpectedLoss map[string]float64 `json:"expected_loss,omitempty"`
 }
 
 // ArmStatistics represents statistics for a single arm
@@ -295,6 +496,8 @@ type ArmStatistics struct {
 	Revenue        float64 `json:"revenue"`
 	AvgReward      float64 `json:"avg_reward"`
 	ConversionRate float64 `json:"conversion_rate"`
+	// RevenuePosterior summarizes the Bayesian revenue-per-visitor model
+	RevenuePosterior *service.RevenuePosteriorSummary `json:"revenue_posterior"`
 }
 
 // Statistics returns statistics for all arms in an experiment
@@ -355,6 +558,8 @@ func (h *BanditHandler) Statistics(c *gin.Context) {
 			Revenue:        stats.Revenue,
 			AvgReward:      stats.AvgReward,
 			ConversionRate: conversionRate,
+
+			RevenuePosterior: h.banditService.SummarizeRevenuePosterior(stats),
 		})
 	}
 
@@ -389,19 +594,40 @@ func (h *BanditHandler) Statistics(c *gin.Context) {
 			return
 		}
 
-		winProbs, err := h.banditService.CalculateWinProbability(c.Request.Context(), experimentID, 1000)
+		result, err := h.winProbabilities(c.Request.Context(), experimentID)
 		if err == nil {
 			probs := make(map[string]float64)
-			for armID, prob := range winProbs {
+			for armID, prob := range result.Probabilities {
 				probs[armID.String()] = prob
 			}
 			resp.WinProbs = probs
+			if len(result.ExpectedLoss) > 0 {
+				resp.ExpectedLoss = make(map[string]float64, len(result.ExpectedLoss))
+				for armID, loss := range result.ExpectedLoss {
+					resp.ExpectedLoss[armID.String()] = loss
+				}
+			}
+			resp.WinProbsCachedAt = &result.CachedAt
+			resp.WinProbsSimulations = result.Simulations
 		}
 	}
 
 	response.OK(c, resp)
 }
 
+// winProbabilities returns the cached win probabilities when the service
+// caches them and simulates them otherwise
+func (h *BanditHandler) winProbabilities(ctx context.Context, experimentID uuid.UUID) (*service.WinProbabilityResult, error) {
+	if cached, ok := h.banditService.(cachedWinProbabilities); ok {
+		return cached.CachedWinProbability(ctx, experimentID, statisticsWinSimulations)
+	}
+	probs, err := h.banditService.CalculateWinProbability(ctx, experimentID, statisticsWinSimulations)
+	if err != nil {
+		return nil, err
+	}
+	return &service.WinProbabilityResult{Probabilities: probs, CachedAt: time.Now().UTC(), Simulations: statisticsWinSimulations}, nil
+}
+
 // Health returns the health status of the bandit service
 // @Summary Bandit service health check
 // @Tags bandit
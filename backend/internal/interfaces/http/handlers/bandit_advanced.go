@@ -7,12 +7,13 @@ import (
 	"math"
 	"math/big"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"github.com/gorilla/mux"
 	"go.uber.org/zap"
 
 	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
@@ -46,118 +47,101 @@ func NewBanditAdvancedHandler(
 	}
 }
 
-// RegisterRoutes registers all advanced bandit routes
-func (h *BanditAdvancedHandler) RegisterRoutes(router *mux.Router) {
-	// Currency management
-	router.HandleFunc("/api/bandit/currency/rates", h.GetCurrencyRates).Methods("GET")
-	router.HandleFunc("/api/bandit/currency/update", h.UpdateCurrencyRates).Methods("POST")
-	router.HandleFunc("/api/bandit/currency/convert", h.ConvertCurrency).Methods("POST")
-
-	// Objective management
-	router.HandleFunc("/api/bandit/experiments/{id}/objectives", h.GetObjectiveScores).Methods("GET")
-	router.HandleFunc("/api/bandit/experiments/{id}/objectives/config", h.GetObjectiveConfig).Methods("GET")
-	router.HandleFunc("/api/bandit/experiments/{id}/objectives/config", h.SetObjectiveConfig).Methods("PUT")
-
-	// Window management
-	router.HandleFunc("/api/bandit/experiments/{id}/window/info", h.GetWindowInfo).Methods("GET")
-	router.HandleFunc("/api/bandit/experiments/{id}/window/trim", h.TrimWindow).Methods("POST")
-	router.HandleFunc("/api/bandit/experiments/{id}/window/events", h.ExportWindowEvents).Methods("GET")
-
-	// Delayed feedback
-	router.HandleFunc("/api/bandit/conversions", h.ProcessConversion).Methods("POST")
-	router.HandleFunc("/api/bandit/pending/{id}", h.GetPendingReward).Methods("GET")
-	router.HandleFunc("/api/bandit/users/{id}/pending", h.GetUserPendingRewards).Methods("GET")
-
-	// Metrics and monitoring
-	router.HandleFunc("/api/bandit/experiments/{id}/metrics", h.GetMetrics).Methods("GET")
-	router.HandleFunc("/api/bandit/maintenance", h.RunMaintenance).Methods("POST")
-}
-
 // GetCurrencyRates returns current currency rates
-func (h *BanditAdvancedHandler) GetCurrencyRates(w http.ResponseWriter, r *http.Request) {
+func (h *BanditAdvancedHandler) GetCurrencyRates(c *gin.Context) {
 	if h.currencyService == nil {
-		http.Error(w, "Currency service not available", http.StatusServiceUnavailable)
+		respondError(c, http.StatusServiceUnavailable, "Currency service not available")
 		return
 	}
 
 	supported := h.currencyService.GetSupportedCurrencies()
 	rates := make(map[string]float64)
+	asOf := make(map[string]time.Time)
+	stale := make([]string, 0)
 
-	ctx := r.Context()
+	ctx := c.Request.Context()
 	for _, currency := range supported {
 		if currency == "USD" {
 			rates[currency] = 1.0
 			continue
 		}
-		rate, err := h.currencyService.GetRate(ctx, currency)
-		if err == nil {
-			rates[currency] = rate
+		quote, err := h.currencyService.GetRateQuote(ctx, currency)
+		if err != nil {
+			continue
+		}
+		rates[currency] = quote.Rate
+		asOf[currency] = quote.UpdatedAt
+		if quote.Stale {
+			stale = append(stale, currency)
 		}
 	}
+	sort.Strings(stale)
 
-	respondJSON(w, http.StatusOK, map[string]interface{}{
+	c.JSON(http.StatusOK, map[string]interface{}{
 		"base":    "USD",
 		"rates":   rates,
+		"as_of":   asOf,
+		"stale":   stale,
 		"updated": time.Now(),
 	})
 }
 
 // UpdateCurrencyRates triggers a currency rate update
-func (h *BanditAdvancedHandler) UpdateCurrencyRates(w http.ResponseWriter, r *http.Request) {
+func (h *BanditAdvancedHandler) UpdateCurrencyRates(c *gin.Context) {
 	if h.currencyService == nil {
-		http.Error(w, "Currency service not available", http.StatusServiceUnavailable)
+		respondError(c, http.StatusServiceUnavailable, "Currency service not available")
 		return
 	}
 
-	if err := h.currencyService.UpdateRates(r.Context()); err != nil {
+	if err := h.currencyService.UpdateRates(c.Request.Context()); err != nil {
 		h.logger.Error("Failed to update currency rates", zap.Error(err))
-		respondError(w, statusForServiceError(err, http.StatusInternalServerError), "Failed to update rates")
+		respondError(c, statusForServiceError(err, http.StatusInternalServerError), "Failed to update rates")
 		return
 	}
 
-	respondJSON(w, http.StatusOK, map[string]interface{}{
+	c.JSON(http.StatusOK, map[string]interface{}{
 		"message": "Currency rates updated successfully",
 		"updated": time.Now(),
 	})
 }
 
 // ConvertCurrency converts an amount between currencies
-func (h *BanditAdvancedHandler) ConvertCurrency(w http.ResponseWriter, r *http.Request) {
+func (h *BanditAdvancedHandler) ConvertCurrency(c *gin.Context) {
 	var req struct {
 		Amount   json.Number `json:"amount"`
 		Currency string      `json:"currency"`
 	}
 
-	decoder := json.NewDecoder(r.Body)
+	decoder := json.NewDecoder(c.Request.Body)
 	decoder.UseNumber()
 	decoder.DisallowUnknownFields()
 	if err := decoder.Decode(&req); err != nil {
-		respondError(w, http.StatusBadRequest, "Invalid request body")
+		respondError(c, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 	amount, ok := parseConvertibleCurrencyAmount(req.Amount)
 
 	if !ok || !isISO4217CurrencyCode(req.Currency) {
-		respondError(w, http.StatusBadRequest, "Invalid request body")
+		respondError(c, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	if h.currencyService == nil {
-		http.Error(w, "Currency service not available", http.StatusServiceUnavailable)
+		respondError(c, http.StatusServiceUnavailable, "Currency service not available")
 		return
 	}
 
-	converted, err := h.currencyService.ConvertToUSD(r.Context(), amount, req.Currency)
+	converted, err := h.currencyService.ConvertToUSD(c.Request.Context(), amount, req.Currency)
 	if err != nil {
-		respondError(w, http.StatusBadRequest, err.Error())
+		respondError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 	if !isFiniteJSONNumber(converted) {
-		respondError(w, http.StatusBadRequest, "Amount is too large")
+		respondError(c, http.StatusBadRequest, "Amount is too large")
 		return
 	}
 
-	respondJSON(w, http.StatusOK, map[string]interface{}{
+	c.JSON(http.StatusOK, map[string]interface{}{
 		"original_amount":   amount,
 		"original_currency": req.Currency,
 		"converted_amount":  converted,
@@ -166,77 +150,200 @@ func (h *BanditAdvancedHandler) ConvertCurrency(w http.ResponseWriter, r *http.R
 }
 
 // GetObjectiveScores returns objective scores for all arms
-func (h *BanditAdvancedHandler) GetObjectiveScores(w http.ResponseWriter, r *http.Request) {
-	experimentID, err := parseUUIDPathParamAfter(r, "experiments")
+func (h *BanditAdvancedHandler) GetObjectiveScores(c *gin.Context) {
+	experimentID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		respondError(w, http.StatusBadRequest, "Invalid experiment ID")
+		respondError(c, http.StatusBadRequest, "Invalid experiment ID")
 		return
 	}
 
-	scores, err := h.engine.GetObjectiveScores(r.Context(), experimentID)
+	scores, err := h.engine.GetObjectiveScores(c.Request.Context(), experimentID)
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, err.Error())
+		respondError(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	respondJSON(w, http.StatusOK, scores)
+	c.JSON(http.StatusOK, scores)
 }
 
 // GetObjectiveConfig returns the persisted objective configuration for an experiment.
-func (h *BanditAdvancedHandler) GetObjectiveConfig(w http.ResponseWriter, r *http.Request) {
-	experimentID, err := parseUUIDPathParamAfter(r, "experiments")
+func (h *BanditAdvancedHandler) GetObjectiveConfig(c *gin.Context) {
+	experimentID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		respondError(w, http.StatusBadRequest, "Invalid experiment ID")
+		respondError(c, http.StatusBadRequest, "Invalid experiment ID")
 		return
 	}
 
-	config, err := h.engine.GetObjectiveConfig(r.Context(), experimentID)
+	config, err := h.engine.GetObjectiveConfig(c.Request.Context(), experimentID)
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, err.Error())
+		respondError(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	respondJSON(w, http.StatusOK, map[string]interface{}{
-		"experiment_id":  experimentID,
-		"objective_type": config.ObjectiveType,
-		"weights":        normalizeObjectiveWeights(config.ObjectiveWeights),
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"experiment_id":           experimentID,
+		"objective_type":          config.ObjectiveType,
+		"weights":                 normalizeObjectiveWeights(config.ObjectiveWeights),
+		"reward_basis":            config.RewardBasis,
+		"product_costs":           normalizeObjectiveWeights(config.ProductCosts),
+		"conversion_window_hours": conversionWindowHours(config.ConversionWindow),
+		"reward_definitions":      rewardDefinitionBodies(config.RewardDefinitions),
 	})
 }
 
 // SetObjectiveConfig updates the objective configuration for an experiment
-func (h *BanditAdvancedHandler) SetObjectiveConfig(w http.ResponseWriter, r *http.Request) {
-	experimentID, err := parseUUIDPathParamAfter(r, "experiments")
+func (h *BanditAdvancedHandler) SetObjectiveConfig(c *gin.Context) {
+	experimentID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		respondError(w, http.StatusBadRequest, "Invalid experiment ID")
+		respondError(c, http.StatusBadRequest, "Invalid experiment ID")
 		return
 	}
 
 	var req struct {
 		ObjectiveType    service.ObjectiveType `json:"objective_type"`
 		ObjectiveWeights map[string]float64    `json:"objective_weights"`
+		RewardBasis      service.RevenueBasis  `json:"reward_basis,omitempty"`
+		ProductCosts     map[string]float64    `json:"product_costs,omitempty"`
+		// ConversionWindowHours sets the delayed feedback window; 0 restores the default
+		ConversionWindowHours *int `json:"conversion_window_hours,omitempty"`
+		// RewardDefinitions replaces the event rewards; an empty list removes them
+		RewardDefinitions *[]rewardDefinitionBody `json:"reward_definitions,omitempty"`
 	}
 
-	decoder := json.NewDecoder(r.Body)
+	decoder := json.NewDecoder(c.Request.Body)
 	decoder.DisallowUnknownFields()
 	if err := decoder.Decode(&req); err != nil {
-		respondError(w, http.StatusBadRequest, "Invalid request body")
+		respondError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.RewardBasis != "" && !req.RewardBasis.IsValid() {
+		respondError(c, http.StatusBadRequest, "reward_basis must be one of gross, net, margin")
+		return
+	}
+	if req.ConversionWindowHours != nil && (*req.ConversionWindowHours < 0 || *req.ConversionWindowHours > 720) {
+		respondError(c, http.StatusBadRequest, "conversion_window_hours must be between 0 and 720")
 		return
 	}
 
-	config, err := h.engine.SetObjectiveConfig(r.Context(), experimentID, req.ObjectiveType, req.ObjectiveWeights)
+	var rewardDefinitions []service.RewardDefinition
+	if req.RewardDefinitions != nil {
+		rewardDefinitions = make([]service.RewardDefinition, 0, len(*req.RewardDefinitions))
+		for _, body := range *req.RewardDefinitions {
+			definition, ok := body.definition()
+			if !ok {
+				respondError(c, http.StatusBadRequest, "reward_definitions half_life_hours must be between 0 and 8760")
+				return
+			}
+			rewardDefinitions = append(rewardDefinitions, definition)
+		}
+		if err := service.ValidateRewardDefinitions(rewardDefinitions); err != nil {
+			respondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	config, err := h.engine.SetObjectiveConfig(c.Request.Context(), experimentID, req.ObjectiveType, req.ObjectiveWeights)
 	if err != nil {
-		respondError(w, statusForServiceError(err, http.StatusBadRequest), err.Error())
+		respondError(c, statusForServiceError(err, http.StatusBadRequest), err.Error())
 		return
 	}
 
-	respondJSON(w, http.StatusOK, map[string]interface{}{
-		"message":        "Configuration updated",
-		"experiment_id":  experimentID,
-		"objective_type": config.ObjectiveType,
-		"weights":        normalizeObjectiveWeights(config.ObjectiveWeights),
+	if req.RewardBasis != "" {
+		basisConfig, err := h.engine.SetRewardBasis(c.Request.Context(), experimentID, req.RewardBasis, req.ProductCosts)
+		if err != nil {
+			respondError(c, statusForServiceError(err, http.StatusBadRequest), err.Error())
+			return
+		}
+		config.RewardBasis = basisConfig.RewardBasis
+		config.ProductCosts = basisConfig.ProductCosts
+	} else if current, err := h.engine.GetObjectiveConfig(c.Request.Context(), experimentID); err == nil {
+		config.RewardBasis = current.RewardBasis
+		config.ProductCosts = current.ProductCosts
+	}
+
+	if req.ConversionWindowHours != nil {
+		windowConfig, err := h.engine.SetConversionWindow(c.Request.Context(), experimentID, time.Duration(*req.ConversionWindowHours)*time.Hour)
+		if err != nil {
+			respondError(c, statusForServiceError(err, http.StatusBadRequest), err.Error())
+			return
+		}
+		config.ConversionWindow = windowConfig.ConversionWindow
+	} else if current, err := h.engine.GetObjectiveConfig(c.Request.Context(), experimentID); err == nil {
+		config.ConversionWindow = current.ConversionWindow
+	}
+
+	if req.RewardDefinitions != nil {
+		definitionsConfig, err := h.engine.SetRewardDefinitions(c.Request.Context(), experimentID, rewardDefinitions)
+		if err != nil {
+			respondError(c, statusForServiceError(err, http.StatusBadRequest), err.Error())
+			return
+		}
+		config.RewardDefinitions = definitionsConfig.RewardDefinitions
+	} else if current, err := h.engine.GetObjectiveConfig(c.Request.Context(), experimentID); err == nil {
+		config.RewardDefinitions = current.RewardDefinitions
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"message":                 "Configuration updated",
+		"experiment_id":           experimentID,
+		"objective_type":          config.ObjectiveType,
+		"weights":                 normalizeObjectiveWeights(config.ObjectiveWeights),
+		"reward_basis":            config.RewardBasis,
+		"product_costs":           normalizeObjectiveWeights(config.ProductCosts),
+		"conversion_window_hours": conversionWindowHours(config.ConversionWindow),
+		"reward_definitions":      rewardDefinitionBodies(config.RewardDefinitions),
 	})
 }
 
+// maxRewardHalfLifeHours bounds a reward definition's half-life to a year
+const maxRewardHalfLifeHours = 8760
+
+// rewardDefinitionBody is a reward definition as the API reads and writes it
+type rewardDefinitionBody struct {
+	Name      string  `json:"name"`
+	EventType string  `json:"event_type"`
+	Reward    float64 `json:"reward"`
+	// HalfLifeHours halves the reward per elapsed half-life since assignment;
+	// 0 keeps it constant
+	HalfLifeHours float64 `json:"half_life_hours,omitempty"`
+}
+
+func (b rewardDefinitionBody) definition() (service.RewardDefinition, bool) {
+	if b.HalfLifeHours < 0 || b.HalfLifeHours > maxRewardHalfLifeHours || math.IsNaN(b.HalfLifeHours) {
+		return service.RewardDefinition{}, false
+	}
+	return service.RewardDefinition{
+		Name:      b.Name,
+		EventType: b.EventType,
+		Reward:    b.Reward,
+		HalfLife:  time.Duration(b.HalfLifeHours * float64(time.Hour)).Round(time.Second),
+	}, true
+}
+
+func rewardDefinitionBodies(definitions []service.RewardDefinition) []rewardDefinitionBody {
+	bodies := make([]rewardDefinitionBody, 0, len(definitions))
+	for _, definition := range definitions {
+		bodies = append(bodies, rewardDefinitionBody{
+			Name:          definition.Name,
+			EventType:     definition.EventType,
+			Reward:        definition.Reward,
+			HalfLifeHours: definition.HalfLife.Hours(),
+		})
+	}
+	return bodies
+}
+
+// conversionWindowHours reports an experiment's own conversion window, nil
+// when it uses the default
+func conversionWindowHours(window time.Duration) *int {
+	if window <= 0 {
+		return nil
+	}
+	hours := int(window / time.Hour)
+	return &hours
+}
+
 func normalizeObjectiveWeights(weights map[string]float64) map[string]float64 {
 	if weights == nil {
 		return map[string]float64{}
@@ -246,86 +353,159 @@ func normalizeObjectiveWeights(weights map[string]float64) map[string]float64 {
 }
 
 // GetWindowInfo returns window information for an experiment
-func (h *BanditAdvancedHandler) GetWindowInfo(w http.ResponseWriter, r *http.Request) {
-	experimentID, err := parseUUIDPathParamAfter(r, "experiments")
+func (h *BanditAdvancedHandler) GetWindowInfo(c *gin.Context) {
+	experimentID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		respondError(w, http.StatusBadRequest, "Invalid experiment ID")
+		respondError(c, http.StatusBadRequest, "Invalid experiment ID")
 		return
 	}
 
-	info, err := h.engine.GetWindowInfo(r.Context(), experimentID)
+	info, err := h.engine.GetWindowInfo(c.Request.Context(), experimentID)
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, err.Error())
+		respondError(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	respondJSON(w, http.StatusOK, map[string]interface{}{
+	c.JSON(http.StatusOK, map[string]interface{}{
 		"experiment_id": experimentID,
 		"windows":       info,
 	})
 }
 
 // TrimWindow trims the sliding window for an experiment
-func (h *BanditAdvancedHandler) TrimWindow(w http.ResponseWriter, r *http.Request) {
-	experimentID, err := parseUUIDPathParamAfter(r, "experiments")
+func (h *BanditAdvancedHandler) TrimWindow(c *gin.Context) {
+	experimentID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		respondError(w, http.StatusBadRequest, "Invalid experiment ID")
+		respondError(c, http.StatusBadRequest, "Invalid experiment ID")
 		return
 	}
 
-	if err := h.engine.TrimWindow(r.Context(), experimentID); err != nil {
-		respondError(w, http.StatusInternalServerError, err.Error())
+	if err := h.engine.TrimWindow(c.Request.Context(), experimentID); err != nil {
+		respondError(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	respondJSON(w, http.StatusOK, map[string]interface{}{
+	c.JSON(http.StatusOK, map[string]interface{}{
 		"experiment_id": experimentID,
 		"message":       "Window trimmed successfully",
 	})
 }
 
-// ExportWindowEvents exports events from the sliding window
-func (h *BanditAdvancedHandler) ExportWindowEvents(w http.ResponseWriter, r *http.Request) {
-	experimentID, err := parseUUIDPathParamAfter(r, "experiments")
+// ExportWindowEvents exports events from the sliding window, newest first.
+// JSON responses are paged by cursor with up to limit events per arm;
+// format=ndjson (or Accept: application/x-ndjson) streams every matching
+// event instead, one per line. arm_id, since and until narrow both.
+func (h *BanditAdvancedHandler) ExportWindowEvents(c *gin.Context) {
+	experimentID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		respondError(w, http.StatusBadRequest, "Invalid experiment ID")
+		respondError(c, http.StatusBadRequest, "Invalid experiment ID")
 		return
 	}
 
-	limit := int64(100)
-	if queryValues, hasLimit := r.URL.Query()["limit"]; hasLimit {
+	query, errMessage := parseWindowExportQuery(c.Request)
+	if errMessage != "" {
+		respondError(c, http.StatusBadRequest, errMessage)
+		return
+	}
+
+	if c.Query("format") == "ndjson" || strings.Contains(c.GetHeader("Accept"), "application/x-ndjson") {
+		h.streamWindowEvents(c, experimentID, query)
+		return
+	}
+
+	page, err := h.engine.ExportWindowEvents(c.Request.Context(), experimentID, query)
+	if errors.Is(err, service.ErrInvalidWindowCursor) {
+		respondError(c, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
+	if err != nil {
+		respondError(c, statusForServiceError(err, http.StatusInternalServerError), err.Error())
+		return
+	}
+
+	var nextCursor *string
+	if page.NextCursor != "" {
+		nextCursor = &page.NextCursor
+	}
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"experiment_id": experimentID,
+		"events":        page.Events,
+		"limit":         query.Limit,
+		"next_cursor":   nextCursor,
+	})
+}
+
+// streamWindowEvents writes the export as NDJSON, flushing after each page.
+// Errors after the first line can only end the stream early.
+func (h *BanditAdvancedHandler) streamWindowEvents(c *gin.Context, experimentID uuid.UUID, query service.WindowExportQuery) {
+	encoder := json.NewEncoder(c.Writer)
+	started := false
+	err := h.engine.StreamWindowEvents(c.Request.Context(), experimentID, query, func(_ uuid.UUID, events []service.RewardEvent) error {
+		if !started {
+			c.Header("Content-Type", "application/x-ndjson")
+			c.Status(http.StatusOK)
+			started = true
+		}
+		for _, event := range events {
+			if err := encoder.Encode(event); err != nil {
+				return err
+			}
+		}
+		c.Writer.Flush()
+		return nil
+	})
+	if err != nil && !started {
+		respondError(c, statusForServiceError(err, http.StatusInternalServerError), err.Error())
+		return
+	}
+	if err != nil {
+		h.logger.Warn("Window event stream ended early", zap.String("experiment_id", experimentID.String()), zap.Error(err))
+		return
+	}
+	if !started {
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Status(http.StatusOK)
+	}
+}
+
+// parseWindowExportQuery reads the export filters; a non-empty message
+// describes the first invalid one
+func parseWindowExportQuery(r *http.Request) (service.WindowExportQuery, string) {
+	values := r.URL.Query()
+	query := service.WindowExportQuery{Limit: service.DefaultWindowExportLimit, Cursor: values.Get("cursor")}
+
+	if queryValues, hasLimit := values["limit"]; hasLimit {
 		rawLimit := ""
 		if len(queryValues) > 0 {
 			rawLimit = strings.TrimSpace(queryValues[0])
 		}
-		if rawLimit == "" {
-			respondError(w, http.StatusBadRequest, "Invalid limit")
-			return
-		}
-
 		parsedLimit, parseErr := strconv.ParseInt(rawLimit, 10, 64)
-		if parseErr != nil || parsedLimit <= 0 {
-			respondError(w, http.StatusBadRequest, "Invalid limit")
-			return
+		if parseErr != nil || parsedLimit <= 0 || parsedLimit > service.MaxWindowExportLimit {
+			return query, "Invalid limit"
 		}
-		limit = parsedLimit
+		query.Limit = parsedLimit
 	}
 
-	events, err := h.engine.ExportWindowEvents(r.Context(), experimentID, limit)
-	if err != nil {
-		respondError(w, http.StatusInternalServerError, err.Error())
-		return
+	for _, raw := range values["arm_id"] {
+		for _, part := range strings.Split(raw, ",") {
+			armID, err := uuid.Parse(strings.TrimSpace(part))
+			if err != nil {
+				return query, "Invalid arm_id"
+			}
+			query.ArmIDs = append(query.ArmIDs, armID)
+		}
 	}
 
-	respondJSON(w, http.StatusOK, map[string]interface{}{
-		"experiment_id": experimentID,
-		"events":        events,
-		"limit":         limit,
-	})
+	since, until, errMessage := parseTimeRangeQuery(values.Get("since"), values.Get("until"))
+	if errMessage != "" {
+		return query, errMessage
+	}
+	query.Since, query.Until = since, until
+	return query, ""
 }
 
 // ProcessConversion processes a delayed conversion
-func (h *BanditAdvancedHandler) ProcessConversion(w http.ResponseWriter, r *http.Request) {
+func (h *BanditAdvancedHandler) ProcessConversion(c *gin.Context) {
 	var req struct {
 		TransactionID   uuid.UUID `json:"transaction_id"`
 		UserID          uuid.UUID `json:"user_id"`
@@ -333,102 +513,258 @@ func (h *BanditAdvancedHandler) ProcessConversion(w http.ResponseWriter, r *http
 		Currency        string    `json:"currency"`
 	}
 
-	decoder := json.NewDecoder(r.Body)
+	decoder := json.NewDecoder(c.Request.Body)
 	decoder.DisallowUnknownFields()
 	if err := decoder.Decode(&req); err != nil {
-		respondError(w, http.StatusBadRequest, "Invalid request body")
+		respondError(c, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	if req.TransactionID == uuid.Nil || req.UserID == uuid.Nil || req.ConversionValue == nil || !isISO4217CurrencyCode(req.Currency) {
-		respondError(w, http.StatusBadRequest, "Invalid request body")
+		respondError(c, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	if err := h.engine.ProcessConversion(
-		r.Context(),
+		c.Request.Context(),
 		req.TransactionID,
 		req.UserID,
 		*req.ConversionValue,
 		req.Currency,
 	); err != nil {
-		respondError(w, http.StatusInternalServerError, err.Error())
+		if errors.Is(err, service.ErrDuplicateConversion) {
+			c.JSON(http.StatusOK, map[string]interface{}{
+				"message":        "Conversion already processed",
+				"transaction_id": req.TransactionID,
+				"duplicate":      true,
+			})
+			return
+		}
+		respondError(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	respondJSON(w, http.StatusOK, map[string]interface{}{
+	c.JSON(http.StatusOK, map[string]interface{}{
 		"message":        "Conversion processed successfully",
 		"transaction_id": req.TransactionID,
 	})
 }
 
+// ListConversions returns an experiment's conversion events with both the
+// amount received and the amount the bandit was credited with
+func (h *BanditAdvancedHandler) ListConversions(c *gin.Context) {
+	experimentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid experiment ID")
+		return
+	}
+
+	query, errMessage := parseConversionAuditQuery(c.Request)
+	if errMessage != "" {
+		respondError(c, http.StatusBadRequest, errMessage)
+		return
+	}
+
+	records, err := h.engine.ListConversionAudit(c.Request.Context(), experimentID, query)
+	if err != nil {
+		respondError(c, statusForServiceError(err, http.StatusInternalServerError), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"experiment_id": experimentID,
+		"conversions":   records,
+		"limit":         query.Limit,
+	})
+}
+
+// parseConversionAuditQuery reads the conversion audit filters; a non-empty
+// message describes the first invalid one
+func parseConversionAuditQuery(r *http.Request) (service.ConversionAuditQuery, string) {
+	values := r.URL.Query()
+	query := service.ConversionAuditQuery{Limit: service.DefaultConversionAuditLimit}
+
+	if _, hasLimit := values["limit"]; hasLimit {
+		parsedLimit, err := strconv.Atoi(strings.TrimSpace(values.Get("limit")))
+		if err != nil || parsedLimit <= 0 || parsedLimit > service.MaxConversionAuditLimit {
+			return query, "Invalid limit"
+		}
+		query.Limit = parsedLimit
+	}
+
+	if currency := values.Get("currency"); currency != "" {
+		if !isISO4217CurrencyCode(currency) {
+			return query, "Invalid currency"
+		}
+		query.Currency = currency
+	}
+
+	since, until, errMessage := parseTimeRangeQuery(values.Get("since"), values.Get("until"))
+	if errMessage != "" {
+		return query, errMessage
+	}
+	query.Since, query.Until = since, until
+	return query, ""
+}
+
+// ReconvertCurrency re-converts an experiment's rewards in one currency at a
+// corrected rate, e.g. after a bad rate was found in the audit
+func (h *BanditAdvancedHandler) ReconvertCurrency(c *gin.Context) {
+	experimentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid experiment ID")
+		return
+	}
+
+	var req struct {
+		Currency string   `json:"currency"`
+		Rate     *float64 `json:"rate"`
+		Source   string   `json:"source"`
+		Since    string   `json:"since"`
+		Until    string   `json:"until"`
+	}
+	decoder := json.NewDecoder(c.Request.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if !isISO4217CurrencyCode(req.Currency) || req.Currency == "USD" {
+		respondError(c, http.StatusBadRequest, "Invalid currency")
+		return
+	}
+	if req.Rate == nil || !isFiniteJSONNumber(*req.Rate) || *req.Rate <= 0 {
+		respondError(c, http.StatusBadRequest, "Invalid rate")
+		return
+	}
+	since, until, errMessage := parseTimeRangeQuery(req.Since, req.Until)
+	if errMessage != "" {
+		respondError(c, http.StatusBadRequest, errMessage)
+		return
+	}
+
+	result, err := h.engine.ReconvertCurrency(c.Request.Context(), experimentID, service.CurrencyReconversion{
+		Currency: req.Currency,
+		Rate:     *req.Rate,
+		Source:   strings.TrimSpace(req.Source),
+		Since:    since,
+		Until:    until,
+	})
+	if err != nil {
+		h.logger.Error("Failed to reconvert rewards", zap.String("experiment_id", experimentID.String()), zap.Error(err))
+		respondError(c, statusForServiceError(err, http.StatusInternalServerError), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"experiment_id": experimentID,
+		"currency":      req.Currency,
+		"rate":          *req.Rate,
+		"result":        result,
+	})
+}
+
+// parseTimeRangeQuery parses optional RFC 3339 since/until bounds
+func parseTimeRangeQuery(rawSince, rawUntil string) (time.Time, time.Time, string) {
+	var since, until time.Time
+	for _, bound := range []struct {
+		name string
+		raw  string
+		dst  *time.Time
+	}{{"since", rawSince, &since}, {"until", rawUntil, &until}} {
+		if bound.raw == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, bound.raw)
+		if err != nil {
+			return since, until, "Invalid " + bound.name
+		}
+		*bound.dst = parsed
+	}
+	if !since.IsZero() && !until.IsZero() && since.After(until) {
+		return since, until, "Invalid time range"
+	}
+	return since, until, ""
+}
+
 // GetPendingReward returns a pending reward by ID
-func (h *BanditAdvancedHandler) GetPendingReward(w http.ResponseWriter, r *http.Request) {
-	pendingID, err := parseUUIDPathParamAfter(r, "pending")
+func (h *BanditAdvancedHandler) GetPendingReward(c *gin.Context) {
+	pendingID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		respondError(w, http.StatusBadRequest, "Invalid pending reward ID")
+		respondError(c, http.StatusBadRequest, "Invalid pending reward ID")
 		return
 	}
 
-	pendingReward, err := h.engine.GetPendingReward(r.Context(), pendingID)
+	pendingReward, err := h.engine.GetPendingReward(c.Request.Context(), pendingID)
 	if err != nil {
-		respondError(w, statusForServiceError(err, http.StatusInternalServerError), err.Error())
+		respondError(c, statusForServiceError(err, http.StatusInternalServerError), err.Error())
 		return
 	}
 
-	respondJSON(w, http.StatusOK, pendingReward)
+	c.JSON(http.StatusOK, pendingReward)
 }
 
-// GetUserPendingRewards returns all pending rewards for a user
-func (h *BanditAdvancedHandler) GetUserPendingRewards(w http.ResponseWriter, r *http.Request) {
-	userID, err := parseUUIDPathParamAfter(r, "users")
+// GetUserPendingRewards returns a user's pending rewards, optionally limited
+// to the experiment_id query parameter
+func (h *BanditAdvancedHandler) GetUserPendingRewards(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		respondError(w, http.StatusBadRequest, "Invalid user ID")
+		respondError(c, http.StatusBadRequest, "Invalid user ID")
 		return
 	}
 
-	rewards, err := h.engine.GetUserPendingRewards(r.Context(), userID)
+	var experimentID *uuid.UUID
+	if raw := c.Query("experiment_id"); raw != "" {
+		parsed, err := uuid.Parse(raw)
+		if err != nil || parsed == uuid.Nil {
+			respondError(c, http.StatusBadRequest, "Invalid experiment ID")
+			return
+		}
+		experimentID = &parsed
+	}
+
+	rewards, err := h.engine.GetUserPendingRewards(c.Request.Context(), userID, experimentID)
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, err.Error())
+		respondError(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 	if rewards == nil {
 		rewards = []*service.PendingReward{}
 	}
 
-	respondJSON(w, http.StatusOK, map[string]interface{}{
+	c.JSON(http.StatusOK, map[string]interface{}{
 		"user_id": userID,
 		"rewards": rewards,
 	})
 }
 
 // GetMetrics returns production metrics for an experiment
-func (h *BanditAdvancedHandler) GetMetrics(w http.ResponseWriter, r *http.Request) {
-	experimentID, err := parseUUIDPathParamAfter(r, "experiments")
+func (h *BanditAdvancedHandler) GetMetrics(c *gin.Context) {
+	experimentID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		respondError(w, http.StatusBadRequest, "Invalid experiment ID")
+		respondError(c, http.StatusBadRequest, "Invalid experiment ID")
 		return
 	}
 
-	metrics, err := h.engine.GetMetrics(r.Context(), experimentID)
+	metrics, err := h.engine.GetMetrics(c.Request.Context(), experimentID)
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, err.Error())
+		respondError(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	respondJSON(w, http.StatusOK, metrics)
+	c.JSON(http.StatusOK, metrics)
 }
 
 // RunMaintenance triggers maintenance tasks
-func (h *BanditAdvancedHandler) RunMaintenance(w http.ResponseWriter, r *http.Request) {
+func (h *BanditAdvancedHandler) RunMaintenance(c *gin.Context) {
 	if h.engine == nil {
-		respondError(w, http.StatusServiceUnavailable, "Bandit engine not available")
+		respondError(c, http.StatusServiceUnavailable, "Bandit engine not available")
 		return
 	}
 
 	var req runMaintenanceRequest
-	if err := decodeOptionalJSONBody(r, &req); err != nil {
-		respondError(w, http.StatusBadRequest, "Invalid request body")
+	if err := decodeOptionalJSONBody(c.Request, &req); err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
@@ -443,9 +779,9 @@ func (h *BanditAdvancedHandler) RunMaintenance(w http.ResponseWriter, r *http.Re
 	)
 	switch scope {
 	case "full":
-		summary, err = h.engine.RunMaintenanceDetailed(r.Context())
+		summary, err = h.engine.RunMaintenanceDetailed(c.Request.Context())
 	case "cleanup_old_context_data":
-		deleted, cleanupErr := h.engine.CleanupOldContextData(r.Context(), hoursToDuration(req.OlderThanHours))
+		deleted, cleanupErr := h.engine.CleanupOldContextData(c.Request.Context(), hoursToDuration(req.OlderThanHours))
 		if cleanupErr != nil {
 			err = cleanupErr
 			break
@@ -454,7 +790,7 @@ func (h *BanditAdvancedHandler) RunMaintenance(w http.ResponseWriter, r *http.Re
 			"stale_contexts_deleted": deleted,
 		}
 	case "cleanup_expired_assignments":
-		deleted, cleanupErr := h.engine.CleanupExpiredAssignments(r.Context(), hoursToDuration(req.OlderThanHours))
+		deleted, cleanupErr := h.engine.CleanupExpiredAssignments(c.Request.Context(), hoursToDuration(req.OlderThanHours))
 		if cleanupErr != nil {
 			err = cleanupErr
 			break
@@ -463,15 +799,15 @@ func (h *BanditAdvancedHandler) RunMaintenance(w http.ResponseWriter, r *http.Re
 			"expired_assignments_deleted": deleted,
 		}
 	default:
-		respondError(w, http.StatusBadRequest, "Invalid maintenance scope")
+		respondError(c, http.StatusBadRequest, "Invalid maintenance scope")
 		return
 	}
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, err.Error())
+		respondError(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	respondJSON(w, http.StatusOK, map[string]interface{}{
+	c.JSON(http.StatusOK, map[string]interface{}{
 		"message":   "Maintenance completed successfully",
 		"scope":     scope,
 		"summary":   summary,
@@ -510,35 +846,12 @@ func hoursToDuration(hours int) time.Duration {
 
 // Helper functions
 
-func respondJSON(w http.ResponseWriter, status int, data interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(data)
-}
-
-func respondError(w http.ResponseWriter, status int, message string) {
-	respondJSON(w, status, map[string]interface{}{
+func respondError(c *gin.Context, status int, message string) {
+	c.JSON(status, gin.H{
 		"error": message,
 	})
 }
 
-func parseUUIDPathParamAfter(r *http.Request, segment string) (uuid.UUID, error) {
-	if vars := mux.Vars(r); len(vars) > 0 {
-		if raw, ok := vars["id"]; ok && raw != "" {
-			return uuid.Parse(raw)
-		}
-	}
-
-	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
-	for index := 0; index < len(parts)-1; index++ {
-		if parts[index] == segment {
-			return uuid.Parse(parts[index+1])
-		}
-	}
-
-	return uuid.Parse("")
-}
-
 func statusForServiceError(err error, defaultStatus int) int {
 	if err == nil {
 		return defaultStatus
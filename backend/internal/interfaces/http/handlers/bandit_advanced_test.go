@@ -113,32 +113,40 @@ func (c *routerPathTestCache) SetAssignment(_ context.Context, _ string, _ uuid.
 	return nil
 }
 
-func TestParseUUIDPathParamAfter_ParsesGinStyleExperimentPath(t *testing.T) {
-	experimentID := uuid.New()
-	req := httptest.NewRequest("GET", "/v1/bandit/experiments/"+experimentID.String()+"/objectives", nil)
-
-	parsed, err := parseUUIDPathParamAfter(req, "experiments")
-
-	require.NoError(t, err)
-	require.Equal(t, experimentID, parsed)
+func (c *routerPathTestCache) SetBytes(_ context.Context, _ string, _ []byte, _ time.Duration) error {
+	return nil
 }
 
-func TestParseUUIDPathParamAfter_ParsesNestedUserPath(t *testing.T) {
-	userID := uuid.New()
-	req := httptest.NewRequest("GET", "/v1/bandit/users/"+userID.String()+"/pending", nil)
+func (c *routerPathTestCache) GetBytes(_ context.Context, _ string) ([]byte, error) {
+	return nil, nil
+}
 
-	parsed, err := parseUUIDPathParamAfter(req, "users")
+func (c *routerPathTestCache) DeleteKey(_ context.Context, _ string) error {
+	return nil
+}
 
-	require.NoError(t, err)
-	require.Equal(t, userID, parsed)
+// serveBanditAdvanced serves req with handler mounted at method and pattern
+func serveBanditAdvanced(method, pattern string, handler gin.HandlerFunc, req *http.Request) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Handle(method, pattern, handler)
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	return res
 }
 
-func TestParseUUIDPathParamAfter_ReturnsErrorForMissingSegment(t *testing.T) {
-	req := httptest.NewRequest("GET", "/v1/bandit/metrics", nil)
+func TestBanditAdvancedHandler_RejectsInvalidPathIDs(t *testing.T) {
+	handler := NewBanditAdvancedHandler(nil, nil, zap.NewNop())
 
-	_, err := parseUUIDPathParamAfter(req, "experiments")
+	res := serveBanditAdvanced(http.MethodGet, "/v1/bandit/experiments/:id/window/info", handler.GetWindowInfo,
+		httptest.NewRequest(http.MethodGet, "/v1/bandit/experiments/not-a-uuid/window/info", nil))
+	require.Equal(t, http.StatusBadRequest, res.Code, "body=%s", res.Body.String())
+	require.JSONEq(t, `{"error":"Invalid experiment ID"}`, res.Body.String())
 
-	require.Error(t, err)
+	res = serveBanditAdvanced(http.MethodGet, "/v1/bandit/users/:id/pending", handler.GetUserPendingRewards,
+		httptest.NewRequest(http.MethodGet, "/v1/bandit/users/not-a-uuid/pending", nil))
+	require.Equal(t, http.StatusBadRequest, res.Code, "body=%s", res.Body.String())
+	require.JSONEq(t, `{"error":"Invalid user ID"}`, res.Body.String())
 }
 
 func TestStatusForServiceError_ReturnsNotFoundForNotFoundErrors(t *testing.T) {
@@ -180,7 +188,7 @@ func TestParseConvertibleCurrencyAmount(t *testing.T) {
 	require.False(t, ok)
 }
 
-func TestGetObjectiveScores_GinWrappedRouteAcceptsValidExperimentID(t *testing.T) {
+func TestGetObjectiveScores_GinRouteAcceptsValidExperimentID(t *testing.T) {
 	t.Helper()
 	gin.SetMode(gin.TestMode)
 
@@ -226,7 +234,7 @@ func TestGetObjectiveScores_GinWrappedRouteAcceptsValidExperimentID(t *testing.T
 	router := gin.New()
 	v1 := router.Group("/v1")
 	bandit := v1.Group("/bandit")
-	bandit.GET("/experiments/:id/objectives", gin.WrapF(handler.GetObjectiveScores))
+	bandit.GET("/experiments/:id/objectives", handler.GetObjectiveScores)
 
 	req := httptest.NewRequest(http.MethodGet, "/v1/bandit/experiments/"+experimentID.String()+"/objectives", nil)
 	res := httptest.NewRecorder()
@@ -241,7 +249,7 @@ func TestGetObjectiveScores_GinWrappedRouteAcceptsValidExperimentID(t *testing.T
 	require.Contains(t, body[armID.String()], string(service.ObjectiveHybrid), "body=%s", res.Body.String())
 }
 
-func TestGetObjectiveConfig_GinWrappedRouteAcceptsValidExperimentID(t *testing.T) {
+func TestGetObjectiveConfig_GinRouteAcceptsValidExperimentID(t *testing.T) {
 	t.Helper()
 	gin.SetMode(gin.TestMode)
 
@@ -267,7 +275,7 @@ func TestGetObjectiveConfig_GinWrappedRouteAcceptsValidExperimentID(t *testing.T
 	router := gin.New()
 	v1 := router.Group("/v1")
 	bandit := v1.Group("/bandit")
-	bandit.GET("/experiments/:id/objectives/config", gin.WrapF(handler.GetObjectiveConfig))
+	bandit.GET("/experiments/:id/objectives/config", handler.GetObjectiveConfig)
 
 	req := httptest.NewRequest(http.MethodGet, "/v1/bandit/experiments/"+experimentID.String()+"/objectives/config", nil)
 	res := httptest.NewRecorder()
@@ -294,9 +302,7 @@ func TestProcessConversion_RejectsNullCurrency(t *testing.T) {
 
 	handler := NewBanditAdvancedHandler(nil, nil, zap.NewNop())
 	req := httptest.NewRequest(http.MethodPost, "/v1/bandit/conversions", strings.NewReader(`{"transaction_id":"e3e70682-c209-4cac-629f-6fbed82c07cd","user_id":"e3e70682-c209-4cac-629f-6fbed82c07cd","conversion_value":0,"currency":null}`))
-	res := httptest.NewRecorder()
-
-	handler.ProcessConversion(res, req)
+	res := serveBanditAdvanced(http.MethodPost, "/v1/bandit/conversions", handler.ProcessConversion, req)
 
 	require.Equal(t, http.StatusBadRequest, res.Code, "body=%s", res.Body.String())
 	require.JSONEq(t, `{"error":"Invalid request body"}`, res.Body.String())
@@ -307,9 +313,7 @@ func TestProcessConversion_RejectsNullConversionValue(t *testing.T) {
 
 	handler := NewBanditAdvancedHandler(nil, nil, zap.NewNop())
 	req := httptest.NewRequest(http.MethodPost, "/v1/bandit/conversions", strings.NewReader(`{"transaction_id":"e3e70682-c209-4cac-629f-6fbed82c07cd","user_id":"e3e70682-c209-4cac-629f-6fbed82c07cd","conversion_value":null,"currency":"USD"}`))
-	res := httptest.NewRecorder()
-
-	handler.ProcessConversion(res, req)
+	res := serveBanditAdvanced(http.MethodPost, "/v1/bandit/conversions", handler.ProcessConversion, req)
 
 	require.Equal(t, http.StatusBadRequest, res.Code, "body=%s", res.Body.String())
 	require.JSONEq(t, `{"error":"Invalid request body"}`, res.Body.String())
@@ -320,9 +324,7 @@ func TestProcessConversion_RejectsInvalidCurrencyCode(t *testing.T) {
 
 	handler := NewBanditAdvancedHandler(nil, nil, zap.NewNop())
 	req := httptest.NewRequest(http.MethodPost, "/v1/bandit/conversions", strings.NewReader(`{"transaction_id":"e3e70682-c209-4cac-629f-6fbed82c07cd","user_id":"e3e70682-c209-4cac-629f-6fbed82c07cd","conversion_value":0,"currency":"0"}`))
-	res := httptest.NewRecorder()
-
-	handler.ProcessConversion(res, req)
+	res := serveBanditAdvanced(http.MethodPost, "/v1/bandit/conversions", handler.ProcessConversion, req)
 
 	require.Equal(t, http.StatusBadRequest, res.Code, "body=%s", res.Body.String())
 	require.JSONEq(t, `{"error":"Invalid request body"}`, res.Body.String())
@@ -333,9 +335,7 @@ func TestProcessConversion_RejectsUnknownFields(t *testing.T) {
 
 	handler := NewBanditAdvancedHandler(nil, nil, zap.NewNop())
 	req := httptest.NewRequest(http.MethodPost, "/v1/bandit/conversions", strings.NewReader(`{"transaction_id":"e3e70682-c209-4cac-629f-6fbed82c07cd","user_id":"e3e70682-c209-4cac-629f-6fbed82c07cd","conversion_value":0,"currency":"USD","x-schemathesis-unknown-property":42}`))
-	res := httptest.NewRecorder()
-
-	handler.ProcessConversion(res, req)
+	res := serveBanditAdvanced(http.MethodPost, "/v1/bandit/conversions", handler.ProcessConversion, req)
 
 	require.Equal(t, http.StatusBadRequest, res.Code, "body=%s", res.Body.String())
 	require.JSONEq(t, `{"error":"Invalid request body"}`, res.Body.String())
@@ -346,9 +346,7 @@ func TestConvertCurrency_RejectsNullCurrency(t *testing.T) {
 
 	handler := NewBanditAdvancedHandler(nil, nil, zap.NewNop())
 	req := httptest.NewRequest(http.MethodPost, "/v1/bandit/currency/convert", strings.NewReader(`{"amount":0,"currency":null}`))
-	res := httptest.NewRecorder()
-
-	handler.ConvertCurrency(res, req)
+	res := serveBanditAdvanced(http.MethodPost, "/v1/bandit/currency/convert", handler.ConvertCurrency, req)
 
 	require.Equal(t, http.StatusBadRequest, res.Code, "body=%s", res.Body.String())
 	require.JSONEq(t, `{"error":"Invalid request body"}`, res.Body.String())
@@ -359,9 +357,7 @@ func TestConvertCurrency_RejectsNullAmount(t *testing.T) {
 
 	handler := NewBanditAdvancedHandler(nil, nil, zap.NewNop())
 	req := httptest.NewRequest(http.MethodPost, "/v1/bandit/currency/convert", strings.NewReader(`{"amount":null,"currency":"USD"}`))
-	res := httptest.NewRecorder()
-
-	handler.ConvertCurrency(res, req)
+	res := serveBanditAdvanced(http.MethodPost, "/v1/bandit/currency/convert", handler.ConvertCurrency, req)
 
 	require.Equal(t, http.StatusBadRequest, res.Code, "body=%s", res.Body.String())
 	require.JSONEq(t, `{"error":"Invalid request body"}`, res.Body.String())
@@ -372,14 +368,81 @@ func TestExportWindowEvents_RejectsEmptyLimit(t *testing.T) {
 
 	handler := NewBanditAdvancedHandler(nil, nil, zap.NewNop())
 	req := httptest.NewRequest(http.MethodGet, "/v1/bandit/experiments/e3e70682-c209-4cac-629f-6fbed82c07cd/window/events?limit=", nil)
-	res := httptest.NewRecorder()
-
-	handler.ExportWindowEvents(res, req)
+	res := serveBanditAdvanced(http.MethodGet, "/v1/bandit/experiments/:id/window/events", handler.ExportWindowEvents, req)
 
 	require.Equal(t, http.StatusBadRequest, res.Code, "body=%s", res.Body.String())
 	require.JSONEq(t, `{"error":"Invalid limit"}`, res.Body.String())
 }
 
+func TestExportWindowEvents_RejectsInvalidFilters(t *testing.T) {
+	handler := NewBanditAdvancedHandler(nil, nil, zap.NewNop())
+	for query, message := range map[string]string{
+		"limit=1001":       "Invalid limit",
+		"arm_id=nope":      "Invalid arm_id",
+		"since=yesterday":  "Invalid since",
+		"until=2026-13-01": "Invalid until",
+		"since=2026-02-01T00:00:00Z&until=2026-01-01T00:00:00Z": "Invalid time range",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/v1/bandit/experiments/e3e70682-c209-4cac-629f-6fbed82c07cd/window/events?"+query, nil)
+		res := serveBanditAdvanced(http.MethodGet, "/v1/bandit/experiments/:id/window/events", handler.ExportWindowEvents, req)
+
+		require.Equal(t, http.StatusBadRequest, res.Code, "query=%s body=%s", query, res.Body.String())
+		require.JSONEq(t, `{"error":"`+message+`"}`, res.Body.String(), query)
+	}
+}
+
+func TestParseWindowExportQuery_ReadsFilters(t *testing.T) {
+	armA, armB := uuid.New(), uuid.New()
+	req := httptest.NewRequest(http.MethodGet, "/v1/bandit/experiments/e3e70682-c209-4cac-629f-6fbed82c07cd/window/events?arm_id="+armA.String()+","+armB.String()+"&since=2026-01-01T00:00:00Z&limit=50&cursor=abc", nil)
+
+	query, message := parseWindowExportQuery(req)
+
+	require.Empty(t, message)
+	require.Equal(t, []uuid.UUID{armA, armB}, query.ArmIDs)
+	require.Equal(t, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), query.Since.UTC())
+	require.True(t, query.Until.IsZero())
+	require.Equal(t, int64(50), query.Limit)
+	require.Equal(t, "abc", query.Cursor)
+}
+
+func TestParseConversionAuditQuery(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/v1/bandit/experiments/e3e70682-c209-4cac-629f-6fbed82c07cd/conversions?currency=EUR&since=2026-01-01T00:00:00Z&limit=25", nil)
+
+	query, message := parseConversionAuditQuery(req)
+
+	require.Empty(t, message)
+	require.Equal(t, "EUR", query.Currency)
+	require.Equal(t, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), query.Since.UTC())
+	require.Equal(t, 25, query.Limit)
+
+	for rawQuery, expected := range map[string]string{
+		"limit=0":        "Invalid limit",
+		"currency=eur":   "Invalid currency",
+		"until=tomorrow": "Invalid until",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/v1/bandit/experiments/e3e70682-c209-4cac-629f-6fbed82c07cd/conversions?"+rawQuery, nil)
+		_, message := parseConversionAuditQuery(req)
+		require.Equal(t, expected, message, rawQuery)
+	}
+}
+
+func TestReconvertCurrency_RejectsInvalidBodies(t *testing.T) {
+	handler := NewBanditAdvancedHandler(nil, nil, zap.NewNop())
+	for body, message := range map[string]string{
+		`{"currency":"EUR"}`:                         "Invalid rate",
+		`{"currency":"EUR","rate":0}`:                "Invalid rate",
+		`{"currency":"USD","rate":1}`:                "Invalid currency",
+		`{"currency":"EUR","rate":1,"extra":true}`:   "Invalid request body",
+		`{"currency":"EUR","rate":1,"since":"soon"}`: "Invalid since",
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/v1/bandit/experiments/e3e70682-c209-4cac-629f-6fbed82c07cd/conversions/reconvert", strings.NewReader(body))
+		res := serveBanditAdvanced(http.MethodPost, "/v1/bandit/experiments/:id/conversions/reconvert", handler.ReconvertCurrency, req)
+
+		require.Equal(t, http.StatusBadRequest, res.Code, "body=%s", res.Body.String())
+		require.JSONEq(t, `{"error":"`+message+`"}`, res.Body.String(), body)
+	}
+}
+
 func TestRunMaintenance_TargetedCleanupOldContextData(t *testing.T) {
 	t.Helper()
 
@@ -389,9 +452,7 @@ func TestRunMaintenance_TargetedCleanupOldContextData(t *testing.T) {
 	engine := service.NewAdvancedBanditEngine(base, repo, cache, nil, nil, zap.NewNop(), &service.EngineConfig{})
 	handler := NewBanditAdvancedHandler(engine, nil, zap.NewNop())
 	req := httptest.NewRequest(http.MethodPost, "/v1/bandit/maintenance", strings.NewReader(`{"scope":"cleanup_old_context_data","older_than_hours":48}`))
-	res := httptest.NewRecorder()
-
-	handler.RunMaintenance(res, req)
+	res := serveBanditAdvanced(http.MethodPost, "/v1/bandit/maintenance", handler.RunMaintenance, req)
 
 	require.Equal(t, http.StatusOK, res.Code, "body=%s", res.Body.String())
 	var body struct {
@@ -413,9 +474,7 @@ func TestRunMaintenance_TargetedCleanupExpiredAssignments(t *testing.T) {
 	engine := service.NewAdvancedBanditEngine(base, repo, cache, nil, nil, zap.NewNop(), &service.EngineConfig{})
 	handler := NewBanditAdvancedHandler(engine, nil, zap.NewNop())
 	req := httptest.NewRequest(http.MethodPost, "/v1/bandit/maintenance", strings.NewReader(`{"scope":"cleanup_expired_assignments","older_than_hours":12}`))
-	res := httptest.NewRecorder()
-
-	handler.RunMaintenance(res, req)
+	res := serveBanditAdvanced(http.MethodPost, "/v1/bandit/maintenance", handler.RunMaintenance, req)
 
 	require.Equal(t, http.StatusOK, res.Code, "body=%s", res.Body.String())
 	var body struct {
@@ -433,9 +492,7 @@ func TestRunMaintenance_RejectsUnknownScope(t *testing.T) {
 
 	handler := NewBanditAdvancedHandler(nil, nil, zap.NewNop())
 	req := httptest.NewRequest(http.MethodPost, "/v1/bandit/maintenance", strings.NewReader(`{"scope":"cleanup_everything"}`))
-	res := httptest.NewRecorder()
-
-	handler.RunMaintenance(res, req)
+	res := serveBanditAdvanced(http.MethodPost, "/v1/bandit/maintenance", handler.RunMaintenance, req)
 
 	require.Equal(t, http.StatusServiceUnavailable, res.Code, "body=%s", res.Body.String())
 }
@@ -449,9 +506,7 @@ func TestRunMaintenance_RejectsInvalidScopeWhenEnginePresent(t *testing.T) {
 	engine := service.NewAdvancedBanditEngine(base, repo, cache, nil, nil, zap.NewNop(), &service.EngineConfig{})
 	handler := NewBanditAdvancedHandler(engine, nil, zap.NewNop())
 	req := httptest.NewRequest(http.MethodPost, "/v1/bandit/maintenance", strings.NewReader(`{"scope":"cleanup_everything"}`))
-	res := httptest.NewRecorder()
-
-	handler.RunMaintenance(res, req)
+	res := serveBanditAdvanced(http.MethodPost, "/v1/bandit/maintenance", handler.RunMaintenance, req)
 
 	require.Equal(t, http.StatusBadRequest, res.Code, "body=%s", res.Body.String())
 	require.JSONEq(t, `{"error":"Invalid maintenance scope"}`, res.Body.String())
@@ -2,10 +2,12 @@ package handlers
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -24,6 +26,10 @@ func (s banditServiceStub) SelectArm(ctx context.Context, experimentID, userID u
 	return uuid.Nil, nil
 }
 
+func (s banditServiceStub) SelectArmWithMeta(ctx context.Context, experimentID, userID uuid.UUID) (uuid.UUID, bool, error) {
+	return uuid.Nil, false, nil
+}
+
 func (s banditServiceStub) TrackImpression(ctx context.Context, experimentID, armID, userID uuid.UUID, event *service.ImpressionEvent) error {
 	if s.trackImpressionFunc != nil {
 		return s.trackImpressionFunc(ctx, experimentID, armID, userID, event)
@@ -56,6 +62,10 @@ func (s banditServiceStub) CalculateWinProbability(ctx context.Context, experime
 	return nil, nil
 }
 
+func (s banditServiceStub) SummarizeRevenuePosterior(stats *service.ArmStats) *service.RevenuePosteriorSummary {
+	return nil
+}
+
 func TestReward_AcceptsZeroReward(t *testing.T) {
 	t.Helper()
 	gin.SetMode(gin.TestMode)
@@ -104,9 +114,49 @@ func TestImpression_AcceptsMetadata(t *testing.T) {
 	require.NotNil(t, recorded)
 	require.Equal(t, service.ImpressionEventTypeImpression, recorded.EventType)
 	require.Equal(t, "paywall", recorded.Metadata["placement"])
+	require.Equal(t, "paywall", recorded.Placement)
 	require.Contains(t, recorder.Body.String(), `"tracked":true`)
 }
 
+func TestImpression_RecordsPurchaseInitiated(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var recorded *service.ImpressionEvent
+	handler := NewBanditHandler(banditServiceStub{
+		trackImpressionFunc: func(ctx context.Context, experimentID, armID, userID uuid.UUID, event *service.ImpressionEvent) error {
+			recorded = event
+			return nil
+		},
+	})
+
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/v1/bandit/impression", strings.NewReader(`{"experiment_id":"e3e70682-c209-4cac-629f-6fbed82c07cd","arm_id":"e3e70682-c209-4cac-629f-6fbed82c07cd","user_id":"e3e70682-c209-4cac-629f-6fbed82c07cd","event_type":"purchase_initiated","placement":"onboarding","metadata":{"placement":"settings"}}`))
+	ctx.Request.Header.Set("Content-Type", "application/json")
+
+	handler.Impression(ctx)
+
+	require.Equal(t, http.StatusOK, recorder.Code, "body=%s", recorder.Body.String())
+	require.NotNil(t, recorded)
+	require.Equal(t, service.ImpressionEventTypePurchaseInitiated, recorded.EventType)
+	require.Equal(t, "onboarding", recorded.Placement)
+}
+
+func TestImpression_RejectsUnknownEventType(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	handler := NewBanditHandler(banditServiceStub{})
+
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/v1/bandit/impression", strings.NewReader(`{"experiment_id":"e3e70682-c209-4cac-629f-6fbed82c07cd","arm_id":"e3e70682-c209-4cac-629f-6fbed82c07cd","user_id":"e3e70682-c209-4cac-629f-6fbed82c07cd","event_type":"purchase_completed"}`))
+	ctx.Request.Header.Set("Content-Type", "application/json")
+
+	handler.Impression(ctx)
+
+	require.Equal(t, http.StatusBadRequest, recorder.Code, "body=%s", recorder.Body.String())
+}
+
 func TestImpression_ReturnsNotFoundForMissingArm(t *testing.T) {
 	t.Helper()
 	gin.SetMode(gin.TestMode)
@@ -149,6 +199,66 @@ func TestReward_ReturnsNotFoundForMissingArm(t *testing.T) {
 	require.Contains(t, recorder.Body.String(), `"Arm not found"`)
 }
 
+func TestReward_ReportsDuplicateTransaction(t *testing.T) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	var transactionID *uuid.UUID
+	handler := NewBanditHandler(banditServiceStub{
+		updateRewardWithEventFunc: func(ctx context.Context, experimentID, armID uuid.UUID, reward float64, event *service.ConversionEvent) error {
+			transactionID = event.TransactionID
+			return fmt.Errorf("%w: %s", service.ErrDuplicateConversion, event.TransactionID)
+		},
+	})
+
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/v1/bandit/reward", strings.NewReader(`{"experiment_id":"e3e70682-c209-4cac-629f-6fbed82c07cd","arm_id":"e3e70682-c209-4cac-629f-6fbed82c07cd","user_id":"e3e70682-c209-4cac-629f-6fbed82c07cd","reward":9.99,"transaction_id":"f728b4fa-4248-4e3a-8a5d-2f346baa9455"}`))
+	ctx.Request.Header.Set("Content-Type", "application/json")
+
+	handler.Reward(ctx)
+
+	require.Equal(t, http.StatusOK, recorder.Code, "body=%s", recorder.Body.String())
+	require.NotNil(t, transactionID)
+	require.Equal(t, "f728b4fa-4248-4e3a-8a5d-2f346baa9455", transactionID.String())
+	require.Contains(t, recorder.Body.String(), `"updated":false`)
+	require.Contains(t, recorder.Body.String(), `"duplicate":true`)
+}
+
+type productRewardRecorderStub struct {
+	productID     string
+	transactionID *uuid.UUID
+}
+
+func (s *productRewardRecorderStub) RecordProductReward(_ context.Context, _, _, _ uuid.UUID, transactionID *uuid.UUID, productID string, _ float64, _ string, _ service.UserContext) error {
+	s.productID, s.transactionID = productID, transactionID
+	return nil
+}
+
+func TestReward_RecordsProductRewardUnderRewardBasis(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	products := &productRewardRecorderStub{}
+	handler := NewBanditHandler(banditServiceStub{
+		updateRewardWithEventFunc: func(context.Context, uuid.UUID, uuid.UUID, float64, *service.ConversionEvent) error {
+			t.Fatal("product rewards must go through the reward basis")
+			return nil
+		},
+	}).WithProductRewards(products)
+
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/v1/bandit/reward", strings.NewReader(`{"experiment_id":"e3e70682-c209-4cac-629f-6fbed82c07cd","arm_id":"e3e70682-c209-4cac-629f-6fbed82c07cd","user_id":"e3e70682-c209-4cac-629f-6fbed82c07cd","reward":9.99,"product_id":"pro_monthly","transaction_id":"f728b4fa-4248-4e3a-8a5d-2f346baa9455"}`))
+	ctx.Request.Header.Set("Content-Type", "application/json")
+
+	handler.Reward(ctx)
+
+	require.Equal(t, http.StatusOK, recorder.Code, "body=%s", recorder.Body.String())
+	require.Equal(t, "pro_monthly", products.productID)
+	require.NotNil(t, products.transactionID)
+	require.Equal(t, "f728b4fa-4248-4e3a-8a5d-2f346baa9455", products.transactionID.String())
+}
+
 func TestStatistics_RejectsEmptyWinProbs(t *testing.T) {
 	t.Helper()
 	gin.SetMode(gin.TestMode)
@@ -196,3 +306,93 @@ func TestStatistics_RejectsUnknownQueryParameter(t *testing.T) {
 	require.Equal(t, http.StatusBadRequest, recorder.Code, "body=%s", recorder.Body.String())
 	require.Contains(t, recorder.Body.String(), `"Unknown query parameter: extra"`)
 }
+
+type armPayloadSourceStub map[uuid.UUID]*service.ArmPayload
+
+func (s armPayloadSourceStub) GetExperimentArmPayload(_ context.Context, _, armID uuid.UUID) (*service.ArmPayload, error) {
+	return s[armID], nil
+}
+
+func TestAssign_ReturnsArmPayload(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	handler := NewBanditHandler(banditServiceStub{}).WithArmPayloads(armPayloadSourceStub{
+		uuid.Nil: {LayoutKey: "hero_v2", PriceID: "pro_monthly_499"},
+	})
+
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/v1/bandit/assign", strings.NewReader(`{"experiment_id":"e3e70682-c209-4cac-629f-6fbed82c07cd","user_id":"e3e70682-c209-4cac-629f-6fbed82c07cd"}`))
+	ctx.Request.Header.Set("Content-Type", "application/json")
+
+	handler.Assign(ctx)
+
+	require.Equal(t, http.StatusOK, recorder.Code, "body=%s", recorder.Body.String())
+	require.Contains(t, recorder.Body.String(), `"payload":{"price_id":"pro_monthly_499","layout_key":"hero_v2"}`)
+}
+
+type eligibilityGateStub struct {
+	decision *service.EligibilityDecision
+	got      service.EligibilityContext
+}
+
+func (s *eligibilityGateStub) CheckEligibility(_ context.Context, _, _ uuid.UUID, eligibilityCtx service.EligibilityContext) (*service.EligibilityDecision, error) {
+	s.got = eligibilityCtx
+	return s.decision, nil
+}
+
+func TestAssign_IneligibleUserFallsThroughToControl(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	controlArmID := uuid.MustParse("7c9e6679-7425-40de-944b-e07fc1f90ae7")
+	gate := &eligibilityGateStub{decision: &service.EligibilityDecision{
+		Reason:        service.EligibilityReasonCountry,
+		FallbackArmID: controlArmID,
+	}}
+	handler := NewBanditHandler(banditServiceStub{}).WithEligibility(gate)
+
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/v1/bandit/assign", strings.NewReader(`{"experiment_id":"e3e70682-c209-4cac-629f-6fbed82c07cd","user_id":"e3e70682-c209-4cac-629f-6fbed82c07cd","country":"FR","platform":"ios","os_version":"17.1"}`))
+	ctx.Request.Header.Set("Content-Type", "application/json")
+
+	handler.Assign(ctx)
+
+	require.Equal(t, http.StatusOK, recorder.Code, "body=%s", recorder.Body.String())
+	require.Contains(t, recorder.Body.String(), `"arm_id":"`+controlArmID.String()+`"`)
+	require.Contains(t, recorder.Body.String(), `"excluded":true,"exclusion_reason":"country_not_allowed"`)
+	require.Equal(t, service.EligibilityContext{Country: "FR", Platform: "ios", OSVersion: "17.1"}, gate.got)
+}
+
+type cachedWinProbabilityStub struct {
+	banditServiceStub
+	result *service.WinProbabilityResult
+}
+
+func (s cachedWinProbabilityStub) CachedWinProbability(ctx context.Context, experimentID uuid.UUID, simulations int) (*service.WinProbabilityResult, error) {
+	return s.result, nil
+}
+
+func TestStatistics_ReturnsCachedWinProbabilities(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	armID := uuid.MustParse("f728b4fa-4248-4e3a-8a5d-2f346baa9455")
+	handler := NewBanditHandler(cachedWinProbabilityStub{result: &service.WinProbabilityResult{
+		Probabilities: map[uuid.UUID]float64{armID: 0.75},
+		ExpectedLoss:  map[uuid.UUID]float64{armID: 0.125},
+		CachedAt:      time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC),
+		Simulations:   1000,
+	}})
+
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/v1/bandit/statistics?experiment_id=e3e70682-c209-4cac-629f-6fbed82c07cd&win_probs=true", nil)
+
+	handler.Statistics(ctx)
+
+	require.Equal(t, http.StatusOK, recorder.Code, "body=%s", recorder.Body.String())
+	require.Contains(t, recorder.Body.String(), `"`+armID.String()+`":0.75`)
+	require.Contains(t, recorder.Body.String(), `"win_probabilities_cached_at":"2024-05-10T12:00:00Z"`)
+	require.Contains(t, recorder.Body.String(), `"win_probabilities_simulations":1000`)
+	require.Contains(t, recorder.Body.String(), `"expected_loss":{"`+armID.String()+`":0.125}`)
+}
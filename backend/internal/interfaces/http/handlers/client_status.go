@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+type clientStatusSource interface {
+	Status(ctx context.Context, appID uuid.UUID) *service.ClientStatus
+}
+
+// ClientStatusHandler serves the public status mobile clients adapt to
+// during partial outages
+type ClientStatusHandler struct {
+	status clientStatusSource
+}
+
+func NewClientStatusHandler(status clientStatusSource) *ClientStatusHandler {
+	return &ClientStatusHandler{status: status}
+}
+
+// GetStatus GET /v1/status
+// Degraded features and the retry backoff to use, for the app in X-App-ID.
+// Always 200: a failed health check is reported as degraded.
+func (h *ClientStatusHandler) GetStatus(c *gin.Context) {
+	status := h.status.Status(c.Request.Context(), httpmiddleware.GetAppID(c))
+	c.Header("Cache-Control", "public, max-age="+strconv.Itoa(int(service.ClientStatusTTL.Seconds())))
+	c.Header("Vary", "X-App-ID")
+	response.OK(c, status)
+}
@@ -0,0 +1,60 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/handlers"
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+)
+
+type fakeClientStatus struct {
+	appID uuid.UUID
+}
+
+func (f *fakeClientStatus) Status(_ context.Context, appID uuid.UUID) *service.ClientStatus {
+	f.appID = appID
+	return &service.ClientStatus{
+		Status:   service.ClientStatusDegraded,
+		Degraded: service.ClientDegradedFeatures{Verification: true},
+		Backoff:  service.ClientBackoff{InitialDelayMs: 5000, MaxDelayMs: 600000, Multiplier: 3, Jitter: 0.5, PollIntervalSeconds: 30},
+	}
+}
+
+func TestClientStatus_GetStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	status := &fakeClientStatus{}
+	r := gin.New()
+	r.GET("/v1/status", httpmiddleware.RequireAppID(), handlers.NewClientStatusHandler(status).GetStatus)
+	appID := uuid.New()
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/v1/status", nil)
+	req.Header.Set("X-App-ID", appID.String())
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, appID, status.appID)
+	assert.Equal(t, "public, max-age=15", w.Header().Get("Cache-Control"))
+	var body struct {
+		Data service.ClientStatus `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.True(t, body.Data.Degraded.Verification)
+	assert.Equal(t, 5000, body.Data.Backoff.InitialDelayMs)
+
+	t.Run("app is required", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/status", nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
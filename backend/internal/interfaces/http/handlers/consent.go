@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+type consentStore interface {
+	Get(ctx context.Context, appID, userID uuid.UUID) (*entity.UserConsent, error)
+	Update(ctx context.Context, appID, userID uuid.UUID, update service.ConsentUpdate) (*entity.UserConsent, error)
+}
+
+// ConsentHandler lets users see and change what their data is used for
+type ConsentHandler struct {
+	consent consentStore
+	logger  *zap.Logger
+}
+
+func NewConsentHandler(consent consentStore, logger *zap.Logger) *ConsentHandler {
+	return &ConsentHandler{consent: consent, logger: logger}
+}
+
+// UpdateConsentRequest changes the purposes that are present
+type UpdateConsentRequest struct {
+	Analytics       *bool `json:"analytics"`
+	Personalization *bool `json:"personalization"`
+	Marketing       *bool `json:"marketing"`
+}
+
+// ConsentResponse is the user's current consent
+type ConsentResponse struct {
+	Analytics       bool       `json:"analytics"`
+	Personalization bool       `json:"personalization"`
+	Marketing       bool       `json:"marketing"`
+	UpdatedAt       *time.Time `json:"updated_at"`
+}
+
+func toConsentResponse(c *entity.UserConsent) ConsentResponse {
+	resp := ConsentResponse{Analytics: c.Analytics, Personalization: c.Personalization, Marketing: c.Marketing}
+	if !c.UpdatedAt.IsZero() {
+		resp.UpdatedAt = &c.UpdatedAt
+	}
+	return resp
+}
+
+// GetConsent GET /v1/users/me/consent
+func (h *ConsentHandler) GetConsent(c *gin.Context) {
+	userID, appID, ok := creditsCaller(c)
+	if !ok {
+		return
+	}
+	consent, err := h.consent.Get(c.Request.Context(), appID, userID)
+	if err != nil {
+		h.logger.Error("Failed to get consent", zap.Error(err))
+		response.InternalError(c, "Failed to get consent")
+		return
+	}
+	response.OK(c, toConsentResponse(consent))
+}
+
+// UpdateConsent PUT /v1/users/me/consent
+// Withdrawn consent applies to events and targeting from the next request on.
+func (h *ConsentHandler) UpdateConsent(c *gin.Context) {
+	userID, appID, ok := creditsCaller(c)
+	if !ok {
+		return
+	}
+	var req UpdateConsentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+	if req.Analytics == nil && req.Personalization == nil && req.Marketing == nil {
+		response.BadRequest(c, "At least one of analytics, personalization or marketing is required")
+		return
+	}
+
+	consent, err := h.consent.Update(c.Request.Context(), appID, userID, service.ConsentUpdate{
+		Analytics:       req.Analytics,
+		Personalization: req.Personalization,
+		Marketing:       req.Marketing,
+	})
+	if err != nil {
+		h.logger.Error("Failed to update consent", zap.Error(err))
+		response.InternalError(c, "Failed to update consent")
+		return
+	}
+	response.OK(c, toConsentResponse(consent))
+}
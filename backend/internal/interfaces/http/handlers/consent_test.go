@@ -0,0 +1,81 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/handlers"
+)
+
+type fakeConsentStore struct {
+	consent *entity.UserConsent
+}
+
+func (f *fakeConsentStore) Get(ctx context.Context, appID, userID uuid.UUID) (*entity.UserConsent, error) {
+	return f.consent, nil
+}
+
+func (f *fakeConsentStore) Update(ctx context.Context, appID, userID uuid.UUID, update service.ConsentUpdate) (*entity.UserConsent, error) {
+	if update.Analytics != nil {
+		f.consent.Analytics = *update.Analytics
+	}
+	if update.Personalization != nil {
+		f.consent.Personalization = *update.Personalization
+	}
+	if update.Marketing != nil {
+		f.consent.Marketing = *update.Marketing
+	}
+	f.consent.UpdatedAt = time.Now()
+	return f.consent, nil
+}
+
+func putConsent(h *handlers.ConsentHandler, body string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", uuid.NewString())
+		c.Set("app_id", uuid.NewString())
+		c.Next()
+	})
+	r.PUT("/v1/users/me/consent", h.UpdateConsent)
+	req := httptest.NewRequest(http.MethodPut, "/v1/users/me/consent", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestUpdateConsent_ChangesOnlyGivenPurposes(t *testing.T) {
+	store := &fakeConsentStore{consent: entity.DefaultUserConsent(uuid.New(), uuid.New())}
+	w := putConsent(handlers.NewConsentHandler(store, zap.NewNop()), `{"analytics":false}`)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp struct {
+		Data handlers.ConsentResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.False(t, resp.Data.Analytics)
+	assert.True(t, resp.Data.Personalization)
+	assert.True(t, resp.Data.Marketing)
+	assert.NotNil(t, resp.Data.UpdatedAt)
+}
+
+func TestUpdateConsent_RequiresAPurpose(t *testing.T) {
+	store := &fakeConsentStore{consent: entity.DefaultUserConsent(uuid.New(), uuid.New())}
+	w := putConsent(handlers.NewConsentHandler(store, zap.NewNop()), `{}`)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
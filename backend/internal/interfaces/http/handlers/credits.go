@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/application/dto"
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+type consumableVerifier interface {
+	Execute(ctx context.Context, userID string, appID uuid.UUID, req *dto.VerifyIAPRequest) (*dto.CreditPurchaseResponse, error)
+}
+
+type creditLedger interface {
+	Consume(ctx context.Context, entry *entity.CreditEntry) (*entity.CreditEntry, error)
+	Balance(ctx context.Context, appID, userID uuid.UUID) (int64, []*entity.CreditEntry, error)
+}
+
+// CreditsHandler handles consumable purchases and the credit balance they fund
+type CreditsHandler struct {
+	verifier consumableVerifier
+	ledger   creditLedger
+	logger   *zap.Logger
+}
+
+func NewCreditsHandler(verifier consumableVerifier, ledger creditLedger, logger *zap.Logger) *CreditsHandler {
+	return &CreditsHandler{verifier: verifier, ledger: ledger, logger: logger}
+}
+
+// VerifyConsumable POST /v1/credits/purchases
+// Verifies a consumable receipt and credits the product's credits. Posting the
+// same receipt again returns the original entry with is_new false.
+func (h *CreditsHandler) VerifyConsumable(c *gin.Context) {
+	userID, appID, ok := creditsCaller(c)
+	if !ok {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, 65536)
+	var req dto.VerifyIAPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request format: "+err.Error())
+		return
+	}
+
+	resp, err := h.verifier.Execute(c.Request.Context(), userID.String(), appID, &req)
+	if err != nil {
+		switch {
+		case isValidationError(err):
+			response.BadRequest(c, err.Error())
+		case errors.Is(err, domainErrors.ErrReceiptAlreadyProcessed):
+			response.Error(c, http.StatusConflict, "RECEIPT_ALREADY_PROCESSED", "receipt already processed")
+		default:
+			response.UnprocessableEntity(c, err.Error())
+		}
+		return
+	}
+
+	response.OK(c, resp)
+}
+
+// ConsumeCredits POST /v1/credits/consume
+// Debits credits atomically. Retrying with the same idempotency_key returns
+// the original debit instead of charging twice.
+func (h *CreditsHandler) ConsumeCredits(c *gin.Context) {
+	userID, appID, ok := creditsCaller(c)
+	if !ok {
+		return
+	}
+
+	var req dto.ConsumeCreditsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+	entry, err := entity.NewCreditConsumption(appID, userID, req.Amount, req.IdempotencyKey, req.Description)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	stored, err := h.ledger.Consume(c.Request.Context(), entry)
+	if err != nil {
+		switch {
+		case errors.Is(err, entity.ErrInsufficientCredits):
+			response.Error(c, http.StatusConflict, "INSUFFICIENT_CREDITS", "insufficient credits")
+		case errors.Is(err, entity.ErrIdempotencyKeyReused):
+			response.UnprocessableEntity(c, err.Error())
+		default:
+			h.logger.Error("Failed to consume credits", zap.String("user_id", userID.String()), zap.Error(err))
+			response.InternalError(c, "Failed to consume credits")
+		}
+		return
+	}
+
+	response.OK(c, toCreditEntryResponse(stored))
+}
+
+// GetBalance GET /v1/credits
+func (h *CreditsHandler) GetBalance(c *gin.Context) {
+	userID, appID, ok := creditsCaller(c)
+	if !ok {
+		return
+	}
+
+	balance, entries, err := h.ledger.Balance(c.Request.Context(), appID, userID)
+	if err != nil {
+		h.logger.Error("Failed to load credit balance", zap.String("user_id", userID.String()), zap.Error(err))
+		response.InternalError(c, "Failed to load credit balance")
+		return
+	}
+
+	resp := dto.CreditBalanceResponse{Balance: balance, Entries: make([]dto.CreditEntryResponse, 0, len(entries))}
+	for _, e := range entries {
+		resp.Entries = append(resp.Entries, toCreditEntryResponse(e))
+	}
+	response.OK(c, resp)
+}
+
+func creditsCaller(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		response.Unauthorized(c, "User not authenticated")
+		return uuid.Nil, uuid.Nil, false
+	}
+	appID, err := uuid.Parse(c.GetString("app_id"))
+	if err != nil {
+		response.BadRequest(c, "invalid or missing app_id in token")
+		return uuid.Nil, uuid.Nil, false
+	}
+	return userID, appID, true
+}
+
+func toCreditEntryResponse(e *entity.CreditEntry) dto.CreditEntryResponse {
+	return dto.CreditEntryResponse{
+		ID:           e.ID.String(),
+		Kind:         string(e.Kind),
+		Delta:        e.Delta,
+		BalanceAfter: e.BalanceAfter,
+		ProductID:    e.ProductID,
+		Description:  e.Description,
+		CreatedAt:    e.CreatedAt.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,94 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/application/dto"
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/handlers"
+)
+
+type fakeCreditLedger struct {
+	balance  int64
+	consumed []*entity.CreditEntry
+}
+
+func (f *fakeCreditLedger) Consume(ctx context.Context, entry *entity.CreditEntry) (*entity.CreditEntry, error) {
+	if f.balance+entry.Delta < 0 {
+		return nil, entity.ErrInsufficientCredits
+	}
+	f.balance += entry.Delta
+	entry.BalanceAfter = f.balance
+	f.consumed = append(f.consumed, entry)
+	return entry, nil
+}
+
+func (f *fakeCreditLedger) Balance(ctx context.Context, appID, userID uuid.UUID) (int64, []*entity.CreditEntry, error) {
+	return f.balance, f.consumed, nil
+}
+
+func newCreditsRouter(h *handlers.CreditsHandler) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", uuid.NewString())
+		c.Set("app_id", uuid.NewString())
+		c.Next()
+	})
+	r.GET("/v1/credits", h.GetBalance)
+	r.POST("/v1/credits/consume", h.ConsumeCredits)
+	return r
+}
+
+func postConsume(router *gin.Engine, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1/credits/consume", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestConsumeCredits_DebitsBalance(t *testing.T) {
+	ledger := &fakeCreditLedger{balance: 100}
+	router := newCreditsRouter(handlers.NewCreditsHandler(nil, ledger, zap.NewNop()))
+
+	w := postConsume(router, `{"amount":40,"idempotency_key":"level-3-hint"}`)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var body struct {
+		Data dto.CreditEntryResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, int64(-40), body.Data.Delta)
+	assert.Equal(t, int64(60), body.Data.BalanceAfter)
+}
+
+func TestConsumeCredits_InsufficientBalance(t *testing.T) {
+	router := newCreditsRouter(handlers.NewCreditsHandler(nil, &fakeCreditLedger{balance: 10}, zap.NewNop()))
+
+	w := postConsume(router, `{"amount":40,"idempotency_key":"level-3-hint"}`)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "INSUFFICIENT_CREDITS")
+}
+
+func TestConsumeCredits_RequiresIdempotencyKey(t *testing.T) {
+	ledger := &fakeCreditLedger{balance: 100}
+	router := newCreditsRouter(handlers.NewCreditsHandler(nil, ledger, zap.NewNop()))
+
+	w := postConsume(router, `{"amount":40}`)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Empty(t, ledger.consumed)
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/application/middleware"
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+const embedTokenContextKey = "embed_token"
+
+type embedAnalytics interface {
+	Authenticate(ctx context.Context, raw string) (*entity.AnalyticsEmbedToken, error)
+	Query(ctx context.Context, t *entity.AnalyticsEmbedToken, q service.EmbedQuery) (*service.EmbedQueryResult, error)
+}
+
+// EmbedAnalyticsHandler serves chart queries to customer-facing dashboards
+// holding an embed token instead of an admin JWT
+type EmbedAnalyticsHandler struct {
+	embed  embedAnalytics
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+func NewEmbedAnalyticsHandler(embed embedAnalytics, logger *zap.Logger) *EmbedAnalyticsHandler {
+	return &EmbedAnalyticsHandler{embed: embed, logger: logger, now: time.Now}
+}
+
+// allowEmbedOrigin lets dashboards on any origin call the API; tokens are
+// sent as bearer headers, never cookies
+func allowEmbedOrigin(c *gin.Context) {
+	c.Header("Access-Control-Allow-Origin", "*")
+	c.Header("Access-Control-Allow-Methods", "GET, OPTIONS")
+	c.Header("Access-Control-Allow-Headers", "Authorization")
+	c.Header("Access-Control-Expose-Headers", "X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset, Retry-After")
+	c.Header("Access-Control-Max-Age", "600")
+}
+
+// Preflight OPTIONS /v1/embed/analytics
+func (h *EmbedAnalyticsHandler) Preflight(c *gin.Context) {
+	allowEmbedOrigin(c)
+	c.Status(http.StatusNoContent)
+}
+
+// RequireToken authenticates the embed token from the Authorization header
+// or the token query parameter
+func (h *EmbedAnalyticsHandler) RequireToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		allowEmbedOrigin(c)
+		raw := c.Query("token")
+		if header := c.GetHeader("Authorization"); header != "" {
+			raw = strings.TrimPrefix(header, "Bearer ")
+		}
+		if raw == "" {
+			response.Unauthorized(c, "Embed token required")
+			c.Abort()
+			return
+		}
+		token, err := h.embed.Authenticate(c.Request.Context(), raw)
+		if err != nil {
+			if errors.Is(err, service.ErrEmbedTokenInvalid) {
+				response.Unauthorized(c, "Invalid or expired embed token")
+			} else {
+				h.logger.Error("failed to authenticate embed token", zap.Error(err))
+				response.InternalError(c, "Failed to authenticate embed token")
+			}
+			c.Abort()
+			return
+		}
+		c.Set(embedTokenContextKey, token)
+		c.Next()
+	}
+}
+
+func embedTokenFromContext(c *gin.Context) *entity.AnalyticsEmbedToken {
+	token, _ := c.Get(embedTokenContextKey)
+	t, _ := token.(*entity.AnalyticsEmbedToken)
+	return t
+}
+
+// ByEmbedToken rate limits by embed token ID
+func ByEmbedToken(c *gin.Context) string {
+	if t := embedTokenFromContext(c); t != nil {
+		return "embed:" + t.ID.String()
+	}
+	return ""
+}
+
+// EmbedTokenRateLimit is the per-minute limit the token was minted with
+func EmbedTokenRateLimit(c *gin.Context) middleware.RateLimitConfig {
+	var perMinute int
+	if t := embedTokenFromContext(c); t != nil {
+		perMinute = t.RateLimitPerMinute
+	}
+	return middleware.RateLimitConfig{Rate: perMinute, Burst: perMinute, Period: time.Minute}
+}
+
+// Query GET /v1/embed/analytics?metric=&dimension=&from=YYYY-MM-DD&to=YYYY-MM-DD
+// from and to are inclusive reporting days; the default range is the last 30
+// days, bounded by the token's range limit.
+func (h *EmbedAnalyticsHandler) Query(c *gin.Context) {
+	token := embedTokenFromContext(c)
+	q := service.EmbedQuery{
+		Metric:    entity.EmbedMetric(c.Query("metric")),
+		Dimension: entity.EmbedDimension(c.DefaultQuery("dimension", string(entity.EmbedByDay))),
+	}
+	if !q.Metric.IsValid() || !q.Dimension.IsValid() {
+		response.BadRequest(c, "Unknown metric or dimension")
+		return
+	}
+	q.From, q.To = service.DefaultEmbedQueryRange(token, h.now())
+	if raw := c.Query("to"); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			response.BadRequest(c, "to must be a date in YYYY-MM-DD format")
+			return
+		}
+		q.From, q.To = parsed.Add(q.From.Sub(q.To)), parsed
+	}
+	if raw := c.Query("from"); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			response.BadRequest(c, "from must be a date in YYYY-MM-DD format")
+			return
+		}
+		q.From = parsed
+	}
+
+	result, err := h.embed.Query(c.Request.Context(), token, q)
+	if err != nil {
+		if errors.Is(err, service.ErrEmbedQueryNotAllowed) {
+			response.Forbidden(c, err.Error())
+			return
+		}
+		h.logger.Error("embedded analytics query failed", zap.Error(err))
+		response.InternalError(c, "Failed to run query")
+		return
+	}
+	response.OK(c, result)
+}
@@ -0,0 +1,76 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/handlers"
+)
+
+type fakeEmbedAnalytics struct {
+	token   *entity.AnalyticsEmbedToken
+	queries []service.EmbedQuery
+}
+
+func (f *fakeEmbedAnalytics) Authenticate(ctx context.Context, raw string) (*entity.AnalyticsEmbedToken, error) {
+	if raw != "good" {
+		return nil, service.ErrEmbedTokenInvalid
+	}
+	return f.token, nil
+}
+
+func (f *fakeEmbedAnalytics) Query(ctx context.Context, t *entity.AnalyticsEmbedToken, q service.EmbedQuery) (*service.EmbedQueryResult, error) {
+	f.queries = append(f.queries, q)
+	if err := service.CheckEmbedQuery(t, q); err != nil {
+		return nil, err
+	}
+	return &service.EmbedQueryResult{Metric: q.Metric, Dimension: q.Dimension, Points: []service.EmbedPoint{}}, nil
+}
+
+func serveEmbed(h *handlers.EmbedAnalyticsHandler, req *http.Request) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/v1/embed/analytics", h.RequireToken(), h.Query)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestEmbedAnalytics_AuthenticatesAndScopes(t *testing.T) {
+	embed := &fakeEmbedAnalytics{token: &entity.AnalyticsEmbedToken{
+		ID:           uuid.New(),
+		Metrics:      []entity.EmbedMetric{entity.EmbedRevenue},
+		Dimensions:   []entity.EmbedDimension{entity.EmbedByDay},
+		MaxRangeDays: 30,
+	}}
+	h := handlers.NewEmbedAnalyticsHandler(embed, zap.NewNop())
+
+	w := serveEmbed(h, httptest.NewRequest(http.MethodGet, "/v1/embed/analytics?metric=revenue&token=bad", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, embed.queries)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/embed/analytics?metric=revenue&to=2026-10-10", nil)
+	req.Header.Set("Authorization", "Bearer good")
+	w = serveEmbed(h, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.Len(t, embed.queries, 1)
+	assert.Equal(t, time.Date(2026, 9, 11, 0, 0, 0, 0, time.UTC), embed.queries[0].From)
+
+	w = serveEmbed(h, httptest.NewRequest(http.MethodGet, "/v1/embed/analytics?metric=revenue&dimension=currency&token=good", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = serveEmbed(h, httptest.NewRequest(http.MethodGet, "/v1/embed/analytics?metric=ltv&token=good", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/infrastructure/persistence/ingest"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+// eventsRetryAfterSeconds is how long clients are told to wait when the
+// ingest buffer is full
+const eventsRetryAfterSeconds = 5
+
+type eventSubmitter interface {
+	Submit(events ...entity.AnalyticsEvent) error
+}
+
+// eventRewarder turns a user's event into rewards for the bandit experiments
+// that define one for it
+type eventRewarder interface {
+	ApplyRewardDefinitions(ctx context.Context, userID uuid.UUID, eventType string, occurredAt time.Time) (int, error)
+}
+
+type consentChecker interface {
+	Allows(ctx context.Context, userID uuid.UUID, purpose entity.ConsentPurpose) bool
+}
+
+// EventsHandler accepts batches of client analytics events for bulk ingestion
+type EventsHandler struct {
+	ingester eventSubmitter
+	logger   *zap.Logger
+	consent  consentChecker
+	rewards  eventRewarder
+	now      func() time.Time
+}
+
+func NewEventsHandler(ingester eventSubmitter, logger *zap.Logger) *EventsHandler {
+	return &EventsHandler{ingester: ingester, logger: logger, now: time.Now}
+}
+
+// WithConsent anonymizes the events of users who have not consented to
+// analytics: they are still counted but carry no user or properties
+func (h *EventsHandler) WithConsent(consent consentChecker) *EventsHandler {
+	h.consent = consent
+	return h
+}
+
+// WithRewardShaping rewards bandit arms for the events of assigned users in
+// experiments with reward definitions
+func (h *EventsHandler) WithRewardShaping(rewards eventRewarder) *EventsHandler {
+	h.rewards = rewards
+	return h
+}
+
+// ClientEventRequest is one analytics event reported by the app
+type ClientEventRequest struct {
+	Name       string         `json:"name" binding:"required,max=128"`
+	OccurredAt time.Time      `json:"occurred_at" binding:"required"`
+	Properties map[string]any `json:"properties"`
+}
+
+// TrackEventsRequest is a batch of analytics events
+type TrackEventsRequest struct {
+	Events []ClientEventRequest `json:"events" binding:"required,min=1,max=500,dive"`
+}
+
+// TrackEventsResponse counts the events accepted for ingestion
+type TrackEventsResponse struct {
+	Accepted int `json:"accepted"`
+}
+
+// TrackEvents POST /v1/events
+func (h *EventsHandler) TrackEvents(c *gin.Context) {
+	userID, appID, ok := creditsCaller(c)
+	if !ok {
+		return
+	}
+	var req TrackEventsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+
+	now := h.now()
+	tracked := h.consent == nil || h.consent.Allows(c.Request.Context(), userID, entity.ConsentAnalytics)
+	events := make([]entity.AnalyticsEvent, 0, len(req.Events))
+	for _, e := range req.Events {
+		event := entity.AnalyticsEvent{
+			AppID:      &appID,
+			UserID:     &userID,
+			Source:     entity.AnalyticsSourceClient,
+			Name:       e.Name,
+			Properties: e.Properties,
+			OccurredAt: e.OccurredAt,
+			ReceivedAt: now,
+		}
+		if !tracked {
+			event.UserID = nil
+			event.Properties = nil
+		}
+		if err := event.Validate(now); err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		events = append(events, event)
+	}
+
+	if err := h.ingester.Submit(events...); err != nil {
+		if errors.Is(err, ingest.ErrBufferFull) {
+			c.Header("Retry-After", strconv.Itoa(eventsRetryAfterSeconds))
+			response.ServiceUnavailable(c, "Event ingestion is backed up, retry later")
+			return
+		}
+		h.logger.Error("Failed to submit analytics events", zap.Error(err))
+		response.InternalError(c, "Failed to accept events")
+		return
+	}
+	if tracked {
+		h.applyRewards(c.Request.Context(), userID, events)
+	}
+	response.Send(c, http.StatusAccepted, TrackEventsResponse{Accepted: len(events)})
+}
+
+// applyRewards feeds each event name of the batch, at its first occurrence,
+// to reward shaping. Failures are logged; the events are already accepted.
+func (h *EventsHandler) applyRewards(ctx context.Context, userID uuid.UUID, events []entity.AnalyticsEvent) {
+	if h.rewards == nil {
+		return
+	}
+	first := make(map[string]time.Time, len(events))
+	names := make([]string, 0, len(events))
+	for _, event := range events {
+		seen, ok := first[event.Name]
+		if !ok {
+			names = append(names, event.Name)
+		}
+		if !ok || event.OccurredAt.Before(seen) {
+			first[event.Name] = event.OccurredAt
+		}
+	}
+	for _, name := range names {
+		if _, err := h.rewards.ApplyRewardDefinitions(ctx, userID, name, first[name]); err != nil {
+			h.logger.Warn("Failed to apply reward definitions",
+				zap.String("user_id", userID.String()),
+				zap.String("event", name),
+				zap.Error(err),
+			)
+		}
+	}
+}
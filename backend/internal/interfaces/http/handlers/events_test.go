@@ -0,0 +1,134 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/infrastructure/persistence/ingest"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/handlers"
+)
+
+type fakeEventSubmitter struct {
+	err       error
+	submitted []entity.AnalyticsEvent
+}
+
+func (f *fakeEventSubmitter) Submit(events ...entity.AnalyticsEvent) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.submitted = append(f.submitted, events...)
+	return nil
+}
+
+func postEvents(h *handlers.EventsHandler, body string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", uuid.NewString())
+		c.Set("app_id", uuid.NewString())
+		c.Next()
+	})
+	r.POST("/v1/events", h.TrackEvents)
+	req := httptest.NewRequest(http.MethodPost, "/v1/events", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestTrackEvents_SubmitsBatch(t *testing.T) {
+	ingester := &fakeEventSubmitter{}
+	at := time.Now().UTC().Add(-time.Minute).Format(time.RFC3339)
+	w := postEvents(handlers.NewEventsHandler(ingester, zap.NewNop()),
+		`{"events":[{"name":"paywall_viewed","occurred_at":"`+at+`","properties":{"placement":"onboarding"}},{"name":"trial_started","occurred_at":"`+at+`"}]}`)
+
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	require.Len(t, ingester.submitted, 2)
+	assert.Equal(t, entity.AnalyticsSourceClient, ingester.submitted[0].Source)
+	assert.Equal(t, "onboarding", ingester.submitted[0].Properties["placement"])
+	assert.NotNil(t, ingester.submitted[1].AppID)
+}
+
+func TestTrackEvents_BackpressureIsRetryable(t *testing.T) {
+	at := time.Now().UTC().Format(time.RFC3339)
+	w := postEvents(handlers.NewEventsHandler(&fakeEventSubmitter{err: ingest.ErrBufferFull}, zap.NewNop()),
+		`{"events":[{"name":"paywall_viewed","occurred_at":"`+at+`"}]}`)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "5", w.Header().Get("Retry-After"))
+}
+
+func TestTrackEvents_RejectsStaleEvents(t *testing.T) {
+	ingester := &fakeEventSubmitter{}
+	stale := time.Now().UTC().Add(-30 * 24 * time.Hour).Format(time.RFC3339)
+	w := postEvents(handlers.NewEventsHandler(ingester, zap.NewNop()),
+		`{"events":[{"name":"paywall_viewed","occurred_at":"`+stale+`"}]}`)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Empty(t, ingester.submitted)
+}
+
+type denyConsent struct{}
+
+func (denyConsent) Allows(ctx context.Context, userID uuid.UUID, purpose entity.ConsentPurpose) bool {
+	return false
+}
+
+func TestTrackEvents_AnonymizesWithoutAnalyticsConsent(t *testing.T) {
+	ingester := &fakeEventSubmitter{}
+	at := time.Now().UTC().Format(time.RFC3339)
+	h := handlers.NewEventsHandler(ingester, zap.NewNop()).WithConsent(denyConsent{})
+	w := postEvents(h, `{"events":[{"name":"paywall_viewed","occurred_at":"`+at+`","properties":{"email":"a@b.c"}}]}`)
+
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	require.Len(t, ingester.submitted, 1)
+	assert.Nil(t, ingester.submitted[0].UserID)
+	assert.Nil(t, ingester.submitted[0].Properties)
+	assert.NotNil(t, ingester.submitted[0].AppID)
+}
+
+type fakeEventRewarder struct {
+	applied map[string]time.Time
+}
+
+func (f *fakeEventRewarder) ApplyRewardDefinitions(ctx context.Context, userID uuid.UUID, eventType string, occurredAt time.Time) (int, error) {
+	if f.applied == nil {
+		f.applied = make(map[string]time.Time)
+	}
+	f.applied[eventType] = occurredAt
+	return 1, nil
+}
+
+func TestTrackEvents_AppliesRewardDefinitionsOncePerName(t *testing.T) {
+	rewarder := &fakeEventRewarder{}
+	first := time.Now().UTC().Add(-2 * time.Minute).Truncate(time.Second)
+	later := first.Add(time.Minute)
+	h := handlers.NewEventsHandler(&fakeEventSubmitter{}, zap.NewNop()).WithRewardShaping(rewarder)
+	w := postEvents(h, `{"events":[`+
+		`{"name":"trial_started","occurred_at":"`+later.Format(time.RFC3339)+`"},`+
+		`{"name":"trial_started","occurred_at":"`+first.Format(time.RFC3339)+`"},`+
+		`{"name":"paywall_viewed","occurred_at":"`+later.Format(time.RFC3339)+`"}]}`)
+
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	require.Len(t, rewarder.applied, 2)
+	assert.True(t, first.Equal(rewarder.applied["trial_started"]), "the first occurrence is rewarded")
+
+	// Anonymized events carry no user to reward
+	rewarder = &fakeEventRewarder{}
+	h = handlers.NewEventsHandler(&fakeEventSubmitter{}, zap.NewNop()).WithConsent(denyConsent{}).WithRewardShaping(rewarder)
+	w = postEvents(h, `{"events":[{"name":"trial_started","occurred_at":"`+first.Format(time.RFC3339)+`"}]}`)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	assert.Empty(t, rewarder.applied)
+}
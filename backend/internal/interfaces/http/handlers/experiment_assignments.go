@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+type userAssignmentSource interface {
+	ListUserAssignments(ctx context.Context, appID, userID uuid.UUID) ([]service.UserExperimentAssignment, error)
+}
+
+// ExperimentAssignmentsHandler lists a user's live experiment assignments in
+// one call, so clients can bootstrap every variant at launch
+type ExperimentAssignmentsHandler struct {
+	assignments userAssignmentSource
+}
+
+func NewExperimentAssignmentsHandler(assignments userAssignmentSource) *ExperimentAssignmentsHandler {
+	return &ExperimentAssignmentsHandler{assignments: assignments}
+}
+
+// ListMine GET /v1/users/me/experiments
+func (h *ExperimentAssignmentsHandler) ListMine(c *gin.Context) {
+	userID, appID, ok := creditsCaller(c)
+	if !ok {
+		return
+	}
+	h.list(c, appID, userID)
+}
+
+// ListForUser GET /v1/admin/users/:id/experiments
+func (h *ExperimentAssignmentsHandler) ListForUser(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid user ID")
+		return
+	}
+	h.list(c, httpmiddleware.GetAppID(c), userID)
+}
+
+func (h *ExperimentAssignmentsHandler) list(c *gin.Context, appID, userID uuid.UUID) {
+	assignments, err := h.assignments.ListUserAssignments(c.Request.Context(), appID, userID)
+	if err != nil {
+		response.InternalError(c, "Failed to list experiment assignments")
+		return
+	}
+	response.OK(c, gin.H{"assignments": assignments})
+}
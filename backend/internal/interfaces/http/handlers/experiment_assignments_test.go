@@ -0,0 +1,90 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/handlers"
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+)
+
+type fakeUserAssignments struct {
+	appID, userID uuid.UUID
+	assignments   []service.UserExperimentAssignment
+}
+
+func (f *fakeUserAssignments) ListUserAssignments(_ context.Context, appID, userID uuid.UUID) ([]service.UserExperimentAssignment, error) {
+	f.appID, f.userID = appID, userID
+	return f.assignments, nil
+}
+
+func TestExperimentAssignments_ListMineUsesTokenScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	userID, appID := uuid.New(), uuid.New()
+	source := &fakeUserAssignments{assignments: []service.UserExperimentAssignment{{
+		ExperimentID:   uuid.New(),
+		ExperimentName: "Paywall copy",
+		ArmID:          uuid.New(),
+		ArmName:        "B",
+		Payload:        &service.ArmPayload{Copy: &service.ArmPayloadCopy{Title: "Go Pro"}},
+		AssignedAt:     time.Now(),
+		ExpiresAt:      time.Now().Add(24 * time.Hour),
+	}}}
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", userID.String())
+		c.Set("app_id", appID.String())
+		c.Next()
+	})
+	r.GET("/v1/users/me/experiments", handlers.NewExperimentAssignmentsHandler(source).ListMine)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/users/me/experiments", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, appID, source.appID)
+	assert.Equal(t, userID, source.userID)
+	var body struct {
+		Data struct {
+			Assignments []service.UserExperimentAssignment `json:"assignments"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.Len(t, body.Data.Assignments, 1)
+	require.NotNil(t, body.Data.Assignments[0].Payload)
+	require.NotNil(t, body.Data.Assignments[0].Payload.Copy)
+	assert.Equal(t, "Go Pro", body.Data.Assignments[0].Payload.Copy.Title)
+}
+
+func TestExperimentAssignments_ListForUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	appID := uuid.New()
+	source := &fakeUserAssignments{}
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set(httpmiddleware.AppIDKey, appID)
+		c.Next()
+	})
+	r.GET("/v1/admin/users/:id/experiments", handlers.NewExperimentAssignmentsHandler(source).ListForUser)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/users/not-a-uuid/experiments", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	userID := uuid.New()
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/users/"+userID.String()+"/experiments", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, appID, source.appID)
+	assert.Equal(t, userID, source.userID)
+}
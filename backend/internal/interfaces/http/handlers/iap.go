@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"strings"
@@ -15,11 +16,16 @@ import (
 	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
 )
 
+type verificationOutcomeRecorder interface {
+	RecordVerification(ctx context.Context, appID uuid.UUID, failed bool) error
+}
+
 // IAPHandler handles IAP verification endpoints
 type IAPHandler struct {
 	verifyIAPCmd     *command.VerifyIAPCommand
 	jwtMiddleware   *middleware.JWTMiddleware
 	rateLimiter     *middleware.RateLimiter
+	outcomes        verificationOutcomeRecorder
 }
 
 // NewIAPHandler creates a new IAP handler
@@ -35,6 +41,13 @@ func NewIAPHandler(
 	}
 }
 
+// WithVerificationOutcomes counts verification successes and store failures
+// for the funnel health check
+func (h *IAPHandler) WithVerificationOutcomes(outcomes verificationOutcomeRecorder) *IAPHandler {
+	h.outcomes = outcomes
+	return h
+}
+
 // VerifyReceipt handles IAP receipt verification
 // @Summary Verify IAP receipt
 // @Tags iap
@@ -84,14 +97,23 @@ func (h *IAPHandler) VerifyReceipt(c *gin.Context) {
 		case errors.Is(err, domainErrors.ErrReceiptAlreadyProcessed) || errors.Is(err, domainErrors.ErrDuplicateReceipt):
 			response.Error(c, http.StatusConflict, "RECEIPT_ALREADY_PROCESSED", "receipt already processed")
 		default:
+			h.recordOutcome(c, appID, true)
 			response.UnprocessableEntity(c, err.Error())
 		}
 		return
 	}
 
+	h.recordOutcome(c, appID, false)
 	response.OK(c, resp)
 }
 
+// recordOutcome is best-effort; a Redis hiccup must not fail the purchase
+func (h *IAPHandler) recordOutcome(c *gin.Context, appID uuid.UUID, failed bool) {
+	if h.outcomes != nil {
+		_ = h.outcomes.RecordVerification(c.Request.Context(), appID, failed)
+	}
+}
+
 func isValidationError(err error) bool {
 msg := err.Error()
 return strings.HasPrefix(msg, "validation failed") ||
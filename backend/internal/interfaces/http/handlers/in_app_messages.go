@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+type inAppMessageScheduler interface {
+	Messages(ctx context.Context, appID, userID uuid.UUID, placement, country string) ([]service.InAppMessageDelivery, error)
+	RecordEvent(ctx context.Context, appID, userID, messageID uuid.UUID, eventType entity.InAppMessageEventType, armID *uuid.UUID, placement string) error
+}
+
+// InAppMessagesHandler serves scheduled in-app messages to the app
+type InAppMessagesHandler struct {
+	messages inAppMessageScheduler
+	logger   *zap.Logger
+}
+
+func NewInAppMessagesHandler(messages inAppMessageScheduler, logger *zap.Logger) *InAppMessagesHandler {
+	return &InAppMessagesHandler{messages: messages, logger: logger}
+}
+
+type inAppMessageEventRequest struct {
+	Event     entity.InAppMessageEventType `json:"event" binding:"required"`
+	ArmID     *uuid.UUID                   `json:"arm_id"`
+	Placement string                       `json:"placement"`
+}
+
+// GetMessages GET /v1/messages?placement=&country=
+// Returns at most one message per format; country is the client's
+// storefront / geo country.
+func (h *InAppMessagesHandler) GetMessages(c *gin.Context) {
+	userID, appID, ok := creditsCaller(c)
+	if !ok {
+		return
+	}
+	messages, err := h.messages.Messages(c.Request.Context(), appID, userID, c.Query("placement"), c.Query("country"))
+	if errors.Is(err, domainErrors.ErrUserNotFound) {
+		response.NotFound(c, "User not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to select in-app messages", zap.Error(err))
+		response.InternalError(c, "Failed to select in-app messages")
+		return
+	}
+	response.OK(c, gin.H{"messages": messages})
+}
+
+// TrackMessageEvent POST /v1/messages/:id/events
+// Reports an impression, click or dismissal; arm_id is the variant shown.
+func (h *InAppMessagesHandler) TrackMessageEvent(c *gin.Context) {
+	userID, appID, ok := creditsCaller(c)
+	if !ok {
+		return
+	}
+	messageID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid message ID")
+		return
+	}
+	var req inAppMessageEventRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+
+	err = h.messages.RecordEvent(c.Request.Context(), appID, userID, messageID, req.Event, req.ArmID, req.Placement)
+	switch {
+	case errors.Is(err, service.ErrInvalidInAppMessageEvent):
+		response.UnprocessableEntity(c, err.Error())
+	case errors.Is(err, domainErrors.ErrNotFound):
+		response.NotFound(c, "Message not found")
+	case err != nil:
+		h.logger.Error("Failed to record in-app message event", zap.Error(err))
+		response.InternalError(c, "Failed to record in-app message event")
+	default:
+		response.NoContent(c)
+	}
+}
@@ -0,0 +1,61 @@
+package handlers_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/handlers"
+)
+
+type fakeInAppMessages struct {
+	known uuid.UUID
+	got   entity.InAppMessageEventType
+}
+
+func (f *fakeInAppMessages) Messages(ctx context.Context, appID, userID uuid.UUID, placement, country string) ([]service.InAppMessageDelivery, error) {
+	return nil, nil
+}
+
+func (f *fakeInAppMessages) RecordEvent(ctx context.Context, appID, userID, messageID uuid.UUID, eventType entity.InAppMessageEventType, armID *uuid.UUID, placement string) error {
+	if messageID != f.known {
+		return fmt.Errorf("in-app message %s: %w", messageID, domainErrors.ErrNotFound)
+	}
+	if !eventType.IsValid() {
+		return service.ErrInvalidInAppMessageEvent
+	}
+	f.got = eventType
+	return nil
+}
+
+func TestTrackMessageEvent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	fake := &fakeInAppMessages{known: uuid.New()}
+	r := gin.New()
+	r.POST("/v1/messages/:id/events", func(c *gin.Context) {
+		c.Set("user_id", uuid.NewString())
+		c.Set("app_id", uuid.NewString())
+	}, handlers.NewInAppMessagesHandler(fake, zap.NewNop()).TrackMessageEvent)
+	post := func(id, body string) int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/messages/"+id+"/events", strings.NewReader(body)))
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusNoContent, post(fake.known.String(), `{"event":"click"}`))
+	assert.Equal(t, entity.InAppMessageClick, fake.got)
+	assert.Equal(t, http.StatusUnprocessableEntity, post(fake.known.String(), `{"event":"swipe"}`))
+	assert.Equal(t, http.StatusNotFound, post(uuid.NewString(), `{"event":"impression"}`))
+	assert.Equal(t, http.StatusBadRequest, post("nope", `{"event":"impression"}`))
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/bivex/paywall-iap/internal/infrastructure/signing"
+)
+
+type jwksPublisher interface {
+	JWKS() signing.JWKS
+}
+
+// JWKSHandler publishes the public keys a kind of token is signed with
+type JWKSHandler struct {
+	keys jwksPublisher
+}
+
+func NewJWKSHandler(keys jwksPublisher) *JWKSHandler {
+	return &JWKSHandler{keys: keys}
+}
+
+// GetJWKS GET /.well-known/jwks.json (auth tokens), GET /v1/entitlements/jwks.json
+// A plain JWKS (no envelope) so standard JWT libraries can load it. Clients
+// should cache it and refetch when a token names an unknown kid.
+func (h *JWKSHandler) GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.JSON(http.StatusOK, h.keys.JWKS())
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/application/dto"
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+type lifetimeVerifier interface {
+	Execute(ctx context.Context, userID string, appID uuid.UUID, req *dto.VerifyIAPRequest) (*dto.LifetimeEntitlementResponse, error)
+}
+
+type lifetimeEntitlements interface {
+	ListActive(ctx context.Context, userID uuid.UUID) ([]*entity.LifetimeEntitlement, error)
+}
+
+// LifetimeHandler handles non-consumable (lifetime unlock) purchases
+type LifetimeHandler struct {
+	verifier     lifetimeVerifier
+	entitlements lifetimeEntitlements
+	logger       *zap.Logger
+}
+
+func NewLifetimeHandler(verifier lifetimeVerifier, entitlements lifetimeEntitlements, logger *zap.Logger) *LifetimeHandler {
+	return &LifetimeHandler{verifier: verifier, entitlements: entitlements, logger: logger}
+}
+
+// VerifyLifetimePurchase POST /v1/purchases/lifetime
+// Verifies a non-consumable receipt and unlocks the product permanently.
+// Posting the same purchase again, e.g. on restore, returns the unlock with
+// is_new false.
+func (h *LifetimeHandler) VerifyLifetimePurchase(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+	appID, err := uuid.Parse(c.GetString("app_id"))
+	if err != nil {
+		response.BadRequest(c, "invalid or missing app_id in token")
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, 65536)
+	var req dto.VerifyIAPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request format: "+err.Error())
+		return
+	}
+
+	resp, err := h.verifier.Execute(c.Request.Context(), userID.String(), appID, &req)
+	if err != nil {
+		switch {
+		case isValidationError(err):
+			response.BadRequest(c, err.Error())
+		case errors.Is(err, domainErrors.ErrReceiptAlreadyProcessed):
+			response.Error(c, http.StatusConflict, "RECEIPT_ALREADY_PROCESSED", "receipt already processed")
+		default:
+			response.UnprocessableEntity(c, err.Error())
+		}
+		return
+	}
+
+	response.OK(c, resp)
+}
+
+// ListLifetimePurchases GET /v1/purchases/lifetime
+// Returns the user's active lifetime unlocks.
+func (h *LifetimeHandler) ListLifetimePurchases(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	unlocks, err := h.entitlements.ListActive(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Error("Failed to list lifetime purchases", zap.String("user_id", userID.String()), zap.Error(err))
+		response.InternalError(c, "Failed to list lifetime purchases")
+		return
+	}
+
+	items := make([]dto.LifetimeEntitlementResponse, 0, len(unlocks))
+	for _, e := range unlocks {
+		items = append(items, dto.LifetimeEntitlementResponse{
+			ID:          e.ID.String(),
+			Platform:    e.Platform,
+			ProductID:   e.ProductID,
+			Status:      string(e.Status),
+			PurchasedAt: e.PurchasedAt.Format(time.RFC3339),
+		})
+	}
+	response.OK(c, gin.H{"purchases": items, "total": len(items)})
+}
@@ -0,0 +1,72 @@
+package handlers_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/application/dto"
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/handlers"
+)
+
+type fakeLifetimeVerifier struct{ err error }
+
+func (f fakeLifetimeVerifier) Execute(ctx context.Context, userID string, appID uuid.UUID, req *dto.VerifyIAPRequest) (*dto.LifetimeEntitlementResponse, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &dto.LifetimeEntitlementResponse{ID: uuid.NewString(), Platform: req.Platform, ProductID: req.ProductID, Status: "active", IsNew: true}, nil
+}
+
+type fakeLifetimeEntitlements struct{}
+
+func (fakeLifetimeEntitlements) ListActive(ctx context.Context, userID uuid.UUID) ([]*entity.LifetimeEntitlement, error) {
+	return nil, nil
+}
+
+func postLifetime(h *handlers.LifetimeHandler) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", uuid.NewString())
+		c.Set("app_id", uuid.NewString())
+		c.Next()
+	})
+	r.POST("/v1/purchases/lifetime", h.VerifyLifetimePurchase)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1/purchases/lifetime",
+		strings.NewReader(`{"platform":"ios","receipt_data":"receipt","product_id":"com.app.lifetime"}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestVerifyLifetimePurchase_Unlocks(t *testing.T) {
+	w := postLifetime(handlers.NewLifetimeHandler(fakeLifetimeVerifier{}, fakeLifetimeEntitlements{}, zap.NewNop()))
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"product_id":"com.app.lifetime"`)
+}
+
+func TestVerifyLifetimePurchase_ErrorMapping(t *testing.T) {
+	cases := map[error]int{
+		domainErrors.NewValidationError("product_id", "is not a lifetime product of this app"):            http.StatusBadRequest,
+		fmt.Errorf("%w: purchase was unlocked for another user", domainErrors.ErrReceiptAlreadyProcessed): http.StatusConflict,
+		fmt.Errorf("%w: purchase was refunded", domainErrors.ErrReceiptInvalid):                           http.StatusUnprocessableEntity,
+	}
+	for err, status := range cases {
+		w := postLifetime(handlers.NewLifetimeHandler(fakeLifetimeVerifier{err: err}, fakeLifetimeEntitlements{}, zap.NewNop()))
+		assert.Equal(t, status, w.Code, err.Error())
+	}
+}
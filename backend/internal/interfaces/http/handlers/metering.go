@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/application/dto"
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+type meter interface {
+	Consume(ctx context.Context, appID, userID uuid.UUID, feature, itemID string, amount int64) (entity.MeterState, bool, error)
+	State(ctx context.Context, appID, userID uuid.UUID, feature string) (entity.MeterState, error)
+	States(ctx context.Context, appID, userID uuid.UUID) ([]entity.MeterState, error)
+}
+
+type accessChecker interface {
+	Execute(ctx context.Context, userID string) (*dto.AccessCheckResponse, error)
+}
+
+// MeteringHandler serves soft paywall meters: N free uses of a feature
+// before the hard paywall
+type MeteringHandler struct {
+	meter  meter
+	access accessChecker
+	logger *zap.Logger
+}
+
+func NewMeteringHandler(meter meter, logger *zap.Logger) *MeteringHandler {
+	return &MeteringHandler{meter: meter, logger: logger}
+}
+
+// WithAccessCheck exempts users with access (subscription or lifetime
+// unlock) from metering
+func (h *MeteringHandler) WithAccessCheck(access accessChecker) *MeteringHandler {
+	h.access = access
+	return h
+}
+
+// ConsumeMeterRequest spends free uses of a metered feature
+type ConsumeMeterRequest struct {
+	Feature string `json:"feature" binding:"required,max=100"`
+	// ItemID identifies what was used (e.g. an article); using the same item
+	// again in the window spends nothing
+	ItemID string `json:"item_id" binding:"max=200"`
+	Amount int64  `json:"amount" binding:"omitempty,min=1,max=1000"`
+}
+
+// MeterStateResponse tells the client which paywall to show for a feature
+type MeterStateResponse struct {
+	Feature     string     `json:"feature"`
+	Used        int64      `json:"used"`
+	FreeUses    int64      `json:"free_uses"`
+	Remaining   int64      `json:"remaining"`
+	Paywall     string     `json:"paywall"`
+	PeriodStart *time.Time `json:"period_start,omitempty"`
+	ResetsAt    *time.Time `json:"resets_at,omitempty"`
+	Unlimited   bool       `json:"unlimited"`
+}
+
+// ConsumeMeterResponse is the outcome of a consume; allowed false means the
+// use was not granted and the hard paywall applies
+type ConsumeMeterResponse struct {
+	Allowed bool               `json:"allowed"`
+	Meter   MeterStateResponse `json:"meter"`
+}
+
+func toMeterStateResponse(s entity.MeterState) MeterStateResponse {
+	resp := MeterStateResponse{
+		Feature:   s.Feature,
+		Used:      s.Used,
+		FreeUses:  s.FreeUses,
+		Remaining: s.Remaining,
+		Paywall:   string(s.Paywall),
+		ResetsAt:  s.ResetsAt,
+		Unlimited: s.Unlimited,
+	}
+	if !s.PeriodStart.IsZero() {
+		resp.PeriodStart = &s.PeriodStart
+	}
+	return resp
+}
+
+func unlimitedMeter(feature string) MeterStateResponse {
+	return toMeterStateResponse(entity.MeterState{Feature: feature, Paywall: entity.PaywallModeNone, Unlimited: true})
+}
+
+// ConsumeMeter POST /v1/metering/consume
+func (h *MeteringHandler) ConsumeMeter(c *gin.Context) {
+	userID, appID, ok := creditsCaller(c)
+	if !ok {
+		return
+	}
+	var req ConsumeMeterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+	if req.Amount == 0 {
+		req.Amount = 1
+	}
+
+	unlimited, ok := h.hasAccess(c, userID)
+	if !ok {
+		return
+	}
+	if unlimited {
+		response.OK(c, ConsumeMeterResponse{Allowed: true, Meter: unlimitedMeter(req.Feature)})
+		return
+	}
+
+	state, allowed, err := h.meter.Consume(c.Request.Context(), appID, userID, req.Feature, req.ItemID, req.Amount)
+	if err != nil {
+		h.writeMeterError(c, userID, err)
+		return
+	}
+	response.OK(c, ConsumeMeterResponse{Allowed: allowed, Meter: toMeterStateResponse(state)})
+}
+
+// GetMeterState GET /v1/metering/state?feature=
+// Without feature, returns the meters of every metered feature.
+func (h *MeteringHandler) GetMeterState(c *gin.Context) {
+	userID, appID, ok := creditsCaller(c)
+	if !ok {
+		return
+	}
+	feature := c.Query("feature")
+
+	unlimited, ok := h.hasAccess(c, userID)
+	if !ok {
+		return
+	}
+	if unlimited && feature != "" {
+		response.OK(c, unlimitedMeter(feature))
+		return
+	}
+
+	if feature != "" {
+		state, err := h.meter.State(c.Request.Context(), appID, userID, feature)
+		if err != nil {
+			h.writeMeterError(c, userID, err)
+			return
+		}
+		response.OK(c, toMeterStateResponse(state))
+		return
+	}
+
+	states, err := h.meter.States(c.Request.Context(), appID, userID)
+	if err != nil {
+		h.writeMeterError(c, userID, err)
+		return
+	}
+	items := make([]MeterStateResponse, 0, len(states))
+	for _, s := range states {
+		if unlimited {
+			items = append(items, unlimitedMeter(s.Feature))
+			continue
+		}
+		items = append(items, toMeterStateResponse(s))
+	}
+	response.OK(c, gin.H{"meters": items, "total": len(items)})
+}
+
+// hasAccess reports whether the user is exempt from metering. An access
+// check failure is answered with 500 rather than metering a paying user.
+func (h *MeteringHandler) hasAccess(c *gin.Context, userID uuid.UUID) (bool, bool) {
+	if h.access == nil {
+		return false, true
+	}
+	resp, err := h.access.Execute(c.Request.Context(), userID.String())
+	if err != nil {
+		h.logger.Error("Failed to check access for metering", zap.String("user_id", userID.String()), zap.Error(err))
+		response.InternalError(c, "Failed to check access")
+		return false, false
+	}
+	return resp.HasAccess, true
+}
+
+func (h *MeteringHandler) writeMeterError(c *gin.Context, userID uuid.UUID, err error) {
+	if errors.Is(err, entity.ErrFeatureNotMetered) {
+		response.NotFound(c, err.Error())
+		return
+	}
+	h.logger.Error("Failed to meter feature", zap.String("user_id", userID.String()), zap.Error(err))
+	response.InternalError(c, "Failed to meter feature")
+}
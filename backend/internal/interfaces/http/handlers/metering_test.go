@@ -0,0 +1,112 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/application/dto"
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/handlers"
+)
+
+type fakeMeter struct {
+	consumed []string
+	state    entity.MeterState
+	allowed  bool
+}
+
+func (f *fakeMeter) Consume(ctx context.Context, appID, userID uuid.UUID, feature, itemID string, amount int64) (entity.MeterState, bool, error) {
+	f.consumed = append(f.consumed, feature)
+	return f.state, f.allowed, nil
+}
+
+func (f *fakeMeter) State(ctx context.Context, appID, userID uuid.UUID, feature string) (entity.MeterState, error) {
+	if feature != f.state.Feature {
+		return entity.MeterState{}, entity.ErrFeatureNotMetered
+	}
+	return f.state, nil
+}
+
+func (f *fakeMeter) States(ctx context.Context, appID, userID uuid.UUID) ([]entity.MeterState, error) {
+	return []entity.MeterState{f.state}, nil
+}
+
+type fakeAccessChecker struct {
+	hasAccess bool
+}
+
+func (f fakeAccessChecker) Execute(ctx context.Context, userID string) (*dto.AccessCheckResponse, error) {
+	return &dto.AccessCheckResponse{HasAccess: f.hasAccess}, nil
+}
+
+func newMeteringRouter(h *handlers.MeteringHandler) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", uuid.NewString())
+		c.Set("app_id", uuid.NewString())
+		c.Next()
+	})
+	r.POST("/v1/metering/consume", h.ConsumeMeter)
+	r.GET("/v1/metering/state", h.GetMeterState)
+	return r
+}
+
+func postMeterConsume(r *gin.Engine, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/v1/metering/consume", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestConsumeMeter_ExhaustedCallsForHardPaywall(t *testing.T) {
+	meter := &fakeMeter{state: entity.MeterState{Feature: "articles", Used: 3, FreeUses: 3, Paywall: entity.PaywallModeHard}}
+	r := newMeteringRouter(handlers.NewMeteringHandler(meter, zap.NewNop()).WithAccessCheck(fakeAccessChecker{}))
+
+	w := postMeterConsume(r, `{"feature":"articles","item_id":"story-9"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var body struct {
+		Data handlers.ConsumeMeterResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.False(t, body.Data.Allowed)
+	assert.Equal(t, "hard", body.Data.Meter.Paywall)
+}
+
+func TestConsumeMeter_SubscribersAreNotMetered(t *testing.T) {
+	meter := &fakeMeter{}
+	r := newMeteringRouter(handlers.NewMeteringHandler(meter, zap.NewNop()).WithAccessCheck(fakeAccessChecker{hasAccess: true}))
+
+	w := postMeterConsume(r, `{"feature":"articles"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var body struct {
+		Data handlers.ConsumeMeterResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.True(t, body.Data.Allowed)
+	assert.True(t, body.Data.Meter.Unlimited)
+	assert.Empty(t, meter.consumed)
+}
+
+func TestGetMeterState_UnknownFeature(t *testing.T) {
+	meter := &fakeMeter{state: entity.MeterState{Feature: "articles"}}
+	r := newMeteringRouter(handlers.NewMeteringHandler(meter, zap.NewNop()))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/metering/state?feature=exports", nil))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+// mmpCallbackMaxBytes bounds AppsFlyer push bodies, which carry the full raw
+// data row
+const mmpCallbackMaxBytes = 64 << 10
+
+type acquisitionRecorder interface {
+	Record(ctx context.Context, appID uuid.UUID, a *entity.UserAcquisition) (bool, error)
+}
+
+type appSettingsReader interface {
+	GetSettings(ctx context.Context, id uuid.UUID) (*entity.AppSettings, error)
+}
+
+// MMPHandler receives install and reattribution callbacks from Adjust and
+// AppsFlyer
+type MMPHandler struct {
+	acquisitions acquisitionRecorder
+	apps         appSettingsReader
+	logger       *zap.Logger
+}
+
+func NewMMPHandler(acquisitions acquisitionRecorder, apps appSettingsReader, logger *zap.Logger) *MMPHandler {
+	return &MMPHandler{acquisitions: acquisitions, apps: apps, logger: logger}
+}
+
+// AppsFlyerCallback POST /webhook/mmp/appsflyer/:app_id?token=
+func (h *MMPHandler) AppsFlyerCallback(c *gin.Context) {
+	appID, ok := h.authorize(c)
+	if !ok {
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, mmpCallbackMaxBytes))
+	if err != nil {
+		response.BadRequest(c, "Invalid callback body")
+		return
+	}
+	acquisition, err := service.ParseAppsFlyerCallback(body)
+	h.record(c, appID, acquisition, err)
+}
+
+// AdjustCallback GET|POST /webhook/mmp/adjust/:app_id?token=
+// Adjust expands the callback URL placeholders into query parameters.
+func (h *MMPHandler) AdjustCallback(c *gin.Context) {
+	appID, ok := h.authorize(c)
+	if !ok {
+		return
+	}
+	acquisition, err := service.ParseAdjustCallback(c.Request.URL.Query())
+	h.record(c, appID, acquisition, err)
+}
+
+// authorize checks the callback token against the app's mmp_callback_token
+func (h *MMPHandler) authorize(c *gin.Context) (uuid.UUID, bool) {
+	appID, err := uuid.Parse(c.Param("app_id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid app ID")
+		return uuid.Nil, false
+	}
+	settings, err := h.apps.GetSettings(c.Request.Context(), appID)
+	if err != nil || settings.MMPCallbackToken == "" {
+		response.Unauthorized(c, "Unknown app or MMP callbacks not configured")
+		return uuid.Nil, false
+	}
+	token := c.Query("token")
+	if token == "" {
+		token = c.GetHeader("X-MMP-Token")
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(settings.MMPCallbackToken)) != 1 {
+		response.Unauthorized(c, "Invalid callback token")
+		return uuid.Nil, false
+	}
+	return appID, true
+}
+
+func (h *MMPHandler) record(c *gin.Context, appID uuid.UUID, acquisition *entity.UserAcquisition, err error) {
+	switch {
+	case errors.Is(err, service.ErrMMPEventIgnored):
+		c.Status(http.StatusOK)
+		return
+	case err != nil:
+		response.BadRequest(c, err.Error())
+		return
+	}
+	applied, err := h.acquisitions.Record(c.Request.Context(), appID, acquisition)
+	if err != nil {
+		h.logger.Error("Failed to record MMP callback", zap.Error(err))
+		response.InternalError(c, "Failed to record callback")
+		return
+	}
+	if applied {
+		h.logger.Info("MMP attribution recorded",
+			zap.String("app_id", appID.String()),
+			zap.String("provider", string(acquisition.Provider)),
+			zap.String("event", string(acquisition.Event)),
+			zap.String("channel", string(acquisition.Channel)))
+	}
+	c.Status(http.StatusOK)
+}
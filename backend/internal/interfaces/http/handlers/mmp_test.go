@@ -0,0 +1,82 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/handlers"
+)
+
+type fakeAcquisitions struct {
+	recorded []*entity.UserAcquisition
+}
+
+func (f *fakeAcquisitions) Record(ctx context.Context, appID uuid.UUID, a *entity.UserAcquisition) (bool, error) {
+	f.recorded = append(f.recorded, a)
+	return true, nil
+}
+
+type fakeMMPApps struct {
+	token string
+}
+
+func (f fakeMMPApps) GetSettings(ctx context.Context, id uuid.UUID) (*entity.AppSettings, error) {
+	return &entity.AppSettings{MMPCallbackToken: f.token}, nil
+}
+
+func serveMMP(h *handlers.MMPHandler, req *http.Request) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/webhook/mmp/appsflyer/:app_id", h.AppsFlyerCallback)
+	r.GET("/webhook/mmp/adjust/:app_id", h.AdjustCallback)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestAppsFlyerCallback_RequiresToken(t *testing.T) {
+	acquisitions := &fakeAcquisitions{}
+	h := handlers.NewMMPHandler(acquisitions, fakeMMPApps{token: "0123456789abcdef"}, zap.NewNop())
+	body := `{"event_name":"install","customer_user_id":"u-1","media_source":"Facebook Ads","campaign":"spring","install_time":"2026-03-01 10:00:00.000","event_time":"2026-03-01 10:00:05.000"}`
+	path := "/webhook/mmp/appsflyer/" + uuid.NewString()
+
+	w := serveMMP(h, httptest.NewRequest(http.MethodPost, path+"?token=wrong", strings.NewReader(body)))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Empty(t, acquisitions.recorded)
+
+	w = serveMMP(h, httptest.NewRequest(http.MethodPost, path+"?token=0123456789abcdef", strings.NewReader(body)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.Len(t, acquisitions.recorded, 1)
+	assert.Equal(t, entity.AcquisitionPaidSocial, acquisitions.recorded[0].Channel)
+	assert.Equal(t, "spring", acquisitions.recorded[0].Campaign)
+}
+
+func TestAdjustCallback_IgnoresSessions(t *testing.T) {
+	acquisitions := &fakeAcquisitions{}
+	h := handlers.NewMMPHandler(acquisitions, fakeMMPApps{token: "0123456789abcdef"}, zap.NewNop())
+	path := "/webhook/mmp/adjust/" + uuid.NewString() + "?token=0123456789abcdef&activity_kind=session&user_id=u-1"
+
+	w := serveMMP(h, httptest.NewRequest(http.MethodGet, path, nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, acquisitions.recorded)
+}
+
+func TestMMPCallbacks_RejectedWhenNotConfigured(t *testing.T) {
+	h := handlers.NewMMPHandler(&fakeAcquisitions{}, fakeMMPApps{}, zap.NewNop())
+	path := "/webhook/mmp/adjust/" + uuid.NewString() + "?token=&activity_kind=install&user_id=u-1&installed_at=1772359200"
+
+	w := serveMMP(h, httptest.NewRequest(http.MethodGet, path, nil))
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+type notificationPreferenceStore interface {
+	Get(ctx context.Context, appID, userID uuid.UUID) (*entity.NotificationPreferences, error)
+	Put(ctx context.Context, prefs *entity.NotificationPreferences) error
+}
+
+// NotificationPreferencesHandler backs the app's notification settings screen
+type NotificationPreferencesHandler struct {
+	prefs  notificationPreferenceStore
+	logger *zap.Logger
+}
+
+func NewNotificationPreferencesHandler(prefs notificationPreferenceStore, logger *zap.Logger) *NotificationPreferencesHandler {
+	return &NotificationPreferencesHandler{prefs: prefs, logger: logger}
+}
+
+// NotificationChannels are the channels a user receives notifications on
+type NotificationChannels struct {
+	Push  bool `json:"push"`
+	Email bool `json:"email"`
+}
+
+// QuietHoursBody is a daily window in HH:MM, local to Timezone
+type QuietHoursBody struct {
+	Start    string `json:"start" binding:"required"`
+	End      string `json:"end" binding:"required"`
+	Timezone string `json:"timezone" binding:"required"`
+}
+
+// NotificationPreferencesBody is the full set of preferences; categories
+// left out are enabled
+type NotificationPreferencesBody struct {
+	Channels   *NotificationChannels                `json:"channels" binding:"required"`
+	Categories map[entity.NotificationCategory]bool `json:"categories"`
+	QuietHours *QuietHoursBody                      `json:"quiet_hours"`
+	UpdatedAt  *time.Time                           `json:"updated_at,omitempty"`
+}
+
+func toNotificationPreferencesBody(p *entity.NotificationPreferences) NotificationPreferencesBody {
+	body := NotificationPreferencesBody{
+		Channels:   &NotificationChannels{Push: p.PushEnabled, Email: p.EmailEnabled},
+		Categories: make(map[entity.NotificationCategory]bool, len(entity.NotificationCategories)),
+	}
+	for _, c := range entity.NotificationCategories {
+		body.Categories[c] = true
+	}
+	for _, c := range p.DisabledCategories {
+		body.Categories[c] = false
+	}
+	if q := p.QuietHours; q != nil {
+		body.QuietHours = &QuietHoursBody{Start: formatMinuteOfDay(q.StartMinute), End: formatMinuteOfDay(q.EndMinute), Timezone: q.Timezone}
+	}
+	if !p.UpdatedAt.IsZero() {
+		body.UpdatedAt = &p.UpdatedAt
+	}
+	return body
+}
+
+func formatMinuteOfDay(minute int) string {
+	return fmt.Sprintf("%02d:%02d", minute/60, minute%60)
+}
+
+func parseMinuteOfDay(raw string) (int, error) {
+	t, err := time.Parse("15:04", raw)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// GetNotificationPreferences GET /v1/users/me/notification-preferences
+func (h *NotificationPreferencesHandler) GetNotificationPreferences(c *gin.Context) {
+	userID, appID, ok := creditsCaller(c)
+	if !ok {
+		return
+	}
+	prefs, err := h.prefs.Get(c.Request.Context(), appID, userID)
+	if err != nil {
+		h.logger.Error("Failed to get notification preferences", zap.Error(err))
+		response.InternalError(c, "Failed to get notification preferences")
+		return
+	}
+	response.OK(c, toNotificationPreferencesBody(prefs))
+}
+
+// PutNotificationPreferences PUT /v1/users/me/notification-preferences
+// Replaces every preference; quiet_hours null turns quiet hours off.
+func (h *NotificationPreferencesHandler) PutNotificationPreferences(c *gin.Context) {
+	userID, appID, ok := creditsCaller(c)
+	if !ok {
+		return
+	}
+	var req NotificationPreferencesBody
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+
+	prefs := &entity.NotificationPreferences{
+		UserID:       userID,
+		AppID:        appID,
+		PushEnabled:  req.Channels.Push,
+		EmailEnabled: req.Channels.Email,
+	}
+	for category, enabled := range req.Categories {
+		if !enabled {
+			prefs.DisabledCategories = append(prefs.DisabledCategories, category)
+		}
+	}
+	slices.Sort(prefs.DisabledCategories)
+	if q := req.QuietHours; q != nil {
+		start, err := parseMinuteOfDay(q.Start)
+		if err != nil {
+			response.BadRequest(c, "quiet_hours.start must be a time in HH:MM format")
+			return
+		}
+		end, err := parseMinuteOfDay(q.End)
+		if err != nil {
+			response.BadRequest(c, "quiet_hours.end must be a time in HH:MM format")
+			return
+		}
+		prefs.QuietHours = &entity.QuietHours{StartMinute: start, EndMinute: end, Timezone: q.Timezone}
+	}
+
+	if err := h.prefs.Put(c.Request.Context(), prefs); err != nil {
+		if errors.Is(err, entity.ErrInvalidNotificationPreferences) {
+			response.UnprocessableEntity(c, err.Error())
+			return
+		}
+		h.logger.Error("Failed to save notification preferences", zap.Error(err))
+		response.InternalError(c, "Failed to save notification preferences")
+		return
+	}
+	response.OK(c, toNotificationPreferencesBody(prefs))
+}
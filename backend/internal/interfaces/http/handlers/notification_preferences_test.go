@@ -0,0 +1,71 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/handlers"
+)
+
+type fakeNotificationPrefs struct {
+	saved *entity.NotificationPreferences
+}
+
+func (f *fakeNotificationPrefs) Get(ctx context.Context, appID, userID uuid.UUID) (*entity.NotificationPreferences, error) {
+	return entity.DefaultNotificationPreferences(appID, userID), nil
+}
+
+func (f *fakeNotificationPrefs) Put(ctx context.Context, p *entity.NotificationPreferences) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	f.saved = p
+	return nil
+}
+
+func serveNotificationPrefs(h *handlers.NotificationPreferencesHandler, body string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.PUT("/v1/users/me/notification-preferences", func(c *gin.Context) {
+		c.Set("user_id", uuid.NewString())
+		c.Set("app_id", uuid.NewString())
+	}, h.PutNotificationPreferences)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/v1/users/me/notification-preferences", strings.NewReader(body)))
+	return w
+}
+
+func TestPutNotificationPreferences(t *testing.T) {
+	prefs := &fakeNotificationPrefs{}
+	h := handlers.NewNotificationPreferencesHandler(prefs, zap.NewNop())
+
+	w := serveNotificationPrefs(h, `{"channels":{"push":true,"email":false},"categories":{"marketing":false},"quiet_hours":{"start":"22:30","end":"07:00","timezone":"Europe/Berlin"}}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NotNil(t, prefs.saved)
+	assert.Equal(t, []entity.NotificationCategory{entity.NotificationMarketing}, prefs.saved.DisabledCategories)
+	assert.Equal(t, 22*60+30, prefs.saved.QuietHours.StartMinute)
+
+	var resp struct {
+		Data handlers.NotificationPreferencesBody `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.True(t, resp.Data.Categories[entity.NotificationBilling])
+	assert.Equal(t, "07:00", resp.Data.QuietHours.End)
+
+	w = serveNotificationPrefs(h, `{"channels":{"push":true},"quiet_hours":{"start":"25:00","end":"07:00","timezone":"UTC"}}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serveNotificationPrefs(h, `{"channels":{"push":true},"categories":{"newsletter":false}}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+}
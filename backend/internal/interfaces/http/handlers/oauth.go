@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/bivex/paywall-iap/internal/application/command"
+	"github.com/bivex/paywall-iap/internal/application/dto"
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+type clientCredentialsIssuer interface {
+	Authenticate(ctx context.Context, clientID, secret string) (*entity.OAuthClient, error)
+	IssueToken(ctx context.Context, clientID, secret, scope string) (*dto.ClientTokenResponse, error)
+	Introspect(ctx context.Context, token string) (*dto.TokenIntrospectionResponse, error)
+}
+
+// OAuthHandler implements the OAuth2 client-credentials token and
+// introspection endpoints. Requests are form-encoded and responses use the
+// RFC 6749 / RFC 7662 shapes rather than the API envelope so standard OAuth
+// client libraries work unchanged.
+type OAuthHandler struct {
+	issuer clientCredentialsIssuer
+}
+
+func NewOAuthHandler(issuer clientCredentialsIssuer) *OAuthHandler {
+	return &OAuthHandler{issuer: issuer}
+}
+
+// Token POST /v1/oauth/token
+// grant_type=client_credentials with the client credentials in HTTP Basic
+// auth or as client_id / client_secret form fields, and an optional
+// space-delimited scope.
+func (h *OAuthHandler) Token(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	if c.PostForm("grant_type") != "client_credentials" {
+		oauthError(c, http.StatusBadRequest, "unsupported_grant_type", "only client_credentials is supported")
+		return
+	}
+	clientID, secret, ok := clientCredentials(c)
+	if !ok {
+		oauthError(c, http.StatusBadRequest, "invalid_request", "client_id and client_secret are required")
+		return
+	}
+
+	resp, err := h.issuer.IssueToken(c.Request.Context(), clientID, secret, c.PostForm("scope"))
+	switch {
+	case errors.Is(err, command.ErrInvalidClient):
+		invalidClient(c)
+		return
+	case errors.Is(err, entity.ErrOAuthScope):
+		oauthError(c, http.StatusBadRequest, "invalid_scope", err.Error())
+		return
+	case err != nil:
+		oauthError(c, http.StatusInternalServerError, "server_error", "failed to issue token")
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// Introspect POST /v1/oauth/introspect
+// The caller authenticates with its own client credentials; token is the
+// access token to inspect.
+func (h *OAuthHandler) Introspect(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	clientID, secret, ok := clientCredentials(c)
+	if !ok {
+		invalidClient(c)
+		return
+	}
+	if _, err := h.issuer.Authenticate(c.Request.Context(), clientID, secret); err != nil {
+		invalidClient(c)
+		return
+	}
+	token := c.PostForm("token")
+	if token == "" {
+		oauthError(c, http.StatusBadRequest, "invalid_request", "token is required")
+		return
+	}
+
+	resp, err := h.issuer.Introspect(c.Request.Context(), token)
+	if err != nil {
+		oauthError(c, http.StatusInternalServerError, "server_error", "failed to introspect token")
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// clientCredentials reads HTTP Basic credentials, falling back to the form
+func clientCredentials(c *gin.Context) (string, string, bool) {
+	if id, secret, ok := c.Request.BasicAuth(); ok {
+		return id, secret, id != "" && secret != ""
+	}
+	id, secret := c.PostForm("client_id"), c.PostForm("client_secret")
+	return id, secret, id != "" && secret != ""
+}
+
+func invalidClient(c *gin.Context) {
+	c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+	oauthError(c, http.StatusUnauthorized, "invalid_client", "client authentication failed")
+}
+
+func oauthError(c *gin.Context, status int, code, description string) {
+	c.JSON(status, gin.H{"error": code, "error_description": description})
+}
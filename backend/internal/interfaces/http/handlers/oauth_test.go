@@ -0,0 +1,108 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bivex/paywall-iap/internal/application/command"
+	"github.com/bivex/paywall-iap/internal/application/dto"
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/handlers"
+)
+
+type mockClientCredentials struct {
+	mock.Mock
+}
+
+func (m *mockClientCredentials) Authenticate(ctx context.Context, clientID, secret string) (*entity.OAuthClient, error) {
+	args := m.Called(ctx, clientID, secret)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.OAuthClient), args.Error(1)
+}
+
+func (m *mockClientCredentials) IssueToken(ctx context.Context, clientID, secret, scope string) (*dto.ClientTokenResponse, error) {
+	args := m.Called(ctx, clientID, secret, scope)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.ClientTokenResponse), args.Error(1)
+}
+
+func (m *mockClientCredentials) Introspect(ctx context.Context, token string) (*dto.TokenIntrospectionResponse, error) {
+	args := m.Called(ctx, token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.TokenIntrospectionResponse), args.Error(1)
+}
+
+func postOAuthToken(h *handlers.OAuthHandler, form url.Values, basicUser, basicPass string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/v1/oauth/token", h.Token)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/oauth/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if basicUser != "" {
+		req.SetBasicAuth(basicUser, basicPass)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestOAuthToken_BasicAuth(t *testing.T) {
+	issuer := new(mockClientCredentials)
+	issuer.On("IssueToken", mock.Anything, "cli_1", "s3cret", "read:analytics").
+		Return(&dto.ClientTokenResponse{AccessToken: "tok", TokenType: "Bearer", ExpiresIn: 900, Scope: "read:analytics"}, nil)
+
+	w := postOAuthToken(handlers.NewOAuthHandler(issuer),
+		url.Values{"grant_type": {"client_credentials"}, "scope": {"read:analytics"}}, "cli_1", "s3cret")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	var body dto.ClientTokenResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "tok", body.AccessToken)
+	issuer.AssertExpectations(t)
+}
+
+func TestOAuthToken_Errors(t *testing.T) {
+	issuer := new(mockClientCredentials)
+	issuer.On("IssueToken", mock.Anything, "cli_1", "wrong", "").Return(nil, command.ErrInvalidClient)
+	issuer.On("IssueToken", mock.Anything, "cli_1", "s3cret", "write:analytics").Return(nil, entity.ErrOAuthScope)
+	h := handlers.NewOAuthHandler(issuer)
+
+	cases := []struct {
+		name   string
+		form   url.Values
+		status int
+		code   string
+	}{
+		{"grant type", url.Values{"grant_type": {"password"}}, http.StatusBadRequest, "unsupported_grant_type"},
+		{"missing credentials", url.Values{"grant_type": {"client_credentials"}}, http.StatusBadRequest, "invalid_request"},
+		{"bad secret", url.Values{"grant_type": {"client_credentials"}, "client_id": {"cli_1"}, "client_secret": {"wrong"}}, http.StatusUnauthorized, "invalid_client"},
+		{"scope", url.Values{"grant_type": {"client_credentials"}, "client_id": {"cli_1"}, "client_secret": {"s3cret"}, "scope": {"write:analytics"}}, http.StatusBadRequest, "invalid_scope"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := postOAuthToken(h, tc.form, "", "")
+			assert.Equal(t, tc.status, w.Code)
+			var body map[string]string
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, tc.code, body["error"])
+		})
+	}
+}
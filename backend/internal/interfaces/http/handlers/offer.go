@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+type offerEligibilityChecker interface {
+	CheckEligibility(ctx context.Context, appID, userID uuid.UUID, productID string) (*service.OfferEligibility, error)
+}
+
+// OfferHandler handles intro offer / offer code endpoints for the app
+type OfferHandler struct {
+	offerService offerEligibilityChecker
+}
+
+// NewOfferHandler creates a new offer handler
+func NewOfferHandler(offerService offerEligibilityChecker) *OfferHandler {
+	return &OfferHandler{offerService: offerService}
+}
+
+// GetEligibility returns whether the user can still receive the intro price or free trial of a product
+// @Summary Get intro offer eligibility
+// @Tags offers
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Store product ID"
+// @Success 200 {object} response.SuccessResponse{data=service.OfferEligibility}
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Router /products/{id}/eligibility [get]
+func (h *OfferHandler) GetEligibility(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+	appID, err := uuid.Parse(c.GetString("app_id"))
+	if err != nil {
+		response.BadRequest(c, "invalid or missing app_id in token")
+		return
+	}
+
+	productID := strings.TrimSpace(c.Param("id"))
+	if productID == "" {
+		response.BadRequest(c, "product id is required")
+		return
+	}
+
+	eligibility, err := h.offerService.CheckEligibility(c.Request.Context(), appID, userID, productID)
+	if err != nil {
+		response.InternalError(c, "Failed to check offer eligibility")
+		return
+	}
+
+	response.OK(c, eligibility)
+}
@@ -0,0 +1,73 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/handlers"
+)
+
+type mockOfferEligibilityChecker struct {
+	mock.Mock
+}
+
+func (m *mockOfferEligibilityChecker) CheckEligibility(ctx context.Context, appID, userID uuid.UUID, productID string) (*service.OfferEligibility, error) {
+	args := m.Called(ctx, appID, userID, productID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.OfferEligibility), args.Error(1)
+}
+
+func newOfferRouter(h *handlers.OfferHandler, userID, appID string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", userID)
+		c.Set("app_id", appID)
+		c.Next()
+	})
+	r.GET("/v1/products/:id/eligibility", h.GetEligibility)
+	return r
+}
+
+func TestGetEligibility_ReturnsServiceResult(t *testing.T) {
+	userID, appID := uuid.New(), uuid.New()
+	checker := new(mockOfferEligibilityChecker)
+	checker.On("CheckEligibility", mock.Anything, appID, userID, "com.app.premium.monthly").
+		Return(&service.OfferEligibility{ProductID: "com.app.premium.monthly", ConsumedOffers: []string{"free_trial"}}, nil)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/v1/products/com.app.premium.monthly/eligibility", nil)
+	newOfferRouter(handlers.NewOfferHandler(checker), userID.String(), appID.String()).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var body struct {
+		Data service.OfferEligibility `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.False(t, body.Data.IntroEligible)
+	assert.Equal(t, []string{"free_trial"}, body.Data.ConsumedOffers)
+	checker.AssertExpectations(t)
+}
+
+func TestGetEligibility_RequiresAppID(t *testing.T) {
+	checker := new(mockOfferEligibilityChecker)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/v1/products/com.app.premium.monthly/eligibility", nil)
+	newOfferRouter(handlers.NewOfferHandler(checker), uuid.NewString(), "").ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	checker.AssertNotCalled(t, "CheckEligibility")
+}
@@ -1,12 +1,17 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 
 	"github.com/bivex/paywall-iap/internal/application/command"
 	"github.com/bivex/paywall-iap/internal/application/dto"
 	"github.com/bivex/paywall-iap/internal/application/middleware"
 	"github.com/bivex/paywall-iap/internal/application/query"
+	"github.com/bivex/paywall-iap/internal/domain/service"
 	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
 )
 
@@ -16,6 +21,11 @@ type PaywallHandler struct {
 	captureEmailCmd       *command.CaptureEmailCommand
 	trackSessionCmd       *command.TrackSessionCommand
 	jwtMiddleware         *middleware.JWTMiddleware
+	rules                 paywallDecider
+}
+
+type paywallDecider interface {
+	Decide(ctx context.Context, appID, userID uuid.UUID, placement, country string) (*service.PaywallDecision, error)
 }
 
 func NewPaywallHandler(
@@ -32,6 +42,12 @@ func NewPaywallHandler(
 	}
 }
 
+// WithPaywallRules enables rule-based paywall selection on GET /user/paywall
+func (h *PaywallHandler) WithPaywallRules(rules paywallDecider) *PaywallHandler {
+	h.rules = rules
+	return h
+}
+
 // GetTriggerStatus returns whether to show paywall and D2C button for the authenticated user
 // @Summary Get paywall trigger status
 // @Tags paywall
@@ -111,3 +127,45 @@ func (h *PaywallHandler) TrackSession(c *gin.Context) {
 
 	response.OK(c, dto.TrackSessionResponse{SessionCount: count})
 }
+
+// GetPaywall selects the paywall and offer to show at a placement using the
+// app's personalization rules. country is the client's storefront / geo
+// country since it is not stored on the user.
+// @Summary Get the personalized paywall for a placement
+// @Tags paywall
+// @Produce json
+// @Security Bearer
+// @Param placement query string false "Placement"
+// @Param country query string false "ISO country code"
+// @Success 200 {object} response.SuccessResponse{data=service.PaywallDecision}
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Router /user/paywall [get]
+func (h *PaywallHandler) GetPaywall(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+	appID, err := uuid.Parse(c.GetString("app_id"))
+	if err != nil {
+		response.BadRequest(c, "invalid or missing app_id in token")
+		return
+	}
+	if h.rules == nil {
+		response.NotFound(c, "No paywall configured")
+		return
+	}
+
+	decision, err := h.rules.Decide(c.Request.Context(), appID, userID, c.Query("placement"), c.Query("country"))
+	if errors.Is(err, service.ErrNoPaywallRuleMatched) {
+		response.NotFound(c, "No paywall configured")
+		return
+	}
+	if err != nil {
+		response.InternalError(c, "Failed to select paywall")
+		return
+	}
+
+	response.OK(c, decision)
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/infrastructure/cache"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+// pendingPurchaseHeartbeat keeps idle SSE connections open through proxies
+const pendingPurchaseHeartbeat = 25 * time.Second
+
+type pendingPurchaseTracker interface {
+	RecordPending(ctx context.Context, purchase *entity.PendingPurchase) (*entity.PendingPurchase, error)
+	ListForUser(ctx context.Context, userID uuid.UUID) ([]*entity.PendingPurchase, error)
+}
+
+type purchaseEventSubscriber interface {
+	SubscribePurchaseEvents(ctx context.Context, userID uuid.UUID) (<-chan cache.PurchaseResolvedEvent, func(), error)
+}
+
+// PendingPurchaseHandler lets the app report deferred purchases (Ask to Buy,
+// pending payment) and learn when the store finalizes them
+type PendingPurchaseHandler struct {
+	service pendingPurchaseTracker
+	events  purchaseEventSubscriber
+	logger  *zap.Logger
+}
+
+func NewPendingPurchaseHandler(service pendingPurchaseTracker, events purchaseEventSubscriber, logger *zap.Logger) *PendingPurchaseHandler {
+	return &PendingPurchaseHandler{service: service, events: events, logger: logger}
+}
+
+// ReportPendingPurchaseRequest is sent when StoreKit returns .pending or Play
+// Billing returns a PENDING purchase
+type ReportPendingPurchaseRequest struct {
+	Platform      string `json:"platform" binding:"required,oneof=ios android"`
+	ProductID     string `json:"product_id" binding:"required"`
+	Reason        string `json:"reason" binding:"omitempty,oneof=ask_to_buy payment_pending"`
+	PurchaseToken string `json:"purchase_token"`
+	// DeviceToken receives a push notification when the purchase resolves
+	DeviceToken string `json:"device_token"`
+}
+
+// PendingPurchaseResponse is a pending purchase as returned to the app
+type PendingPurchaseResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	Platform   string     `json:"platform"`
+	ProductID  string     `json:"product_id"`
+	Reason     string     `json:"reason"`
+	Status     string     `json:"status"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+}
+
+func toPendingPurchaseResponse(p *entity.PendingPurchase) PendingPurchaseResponse {
+	return PendingPurchaseResponse{
+		ID:         p.ID,
+		Platform:   p.Platform,
+		ProductID:  p.ProductID,
+		Reason:     string(p.Reason),
+		Status:     string(p.Status),
+		CreatedAt:  p.CreatedAt,
+		ExpiresAt:  p.ExpiresAt,
+		ResolvedAt: p.ResolvedAt,
+	}
+}
+
+// ReportPendingPurchase POST /v1/purchases/pending
+// Reporting the same product again while it is pending returns the open purchase.
+func (h *PendingPurchaseHandler) ReportPendingPurchase(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+	appID, err := uuid.Parse(c.GetString("app_id"))
+	if err != nil {
+		response.BadRequest(c, "invalid or missing app_id in token")
+		return
+	}
+
+	var req ReportPendingPurchaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request: "+err.Error())
+		return
+	}
+	reason := entity.PendingReasonAskToBuy
+	if req.Reason != "" {
+		reason = entity.PendingPurchaseReason(req.Reason)
+	}
+
+	purchase := entity.NewPendingPurchase(appID, userID, req.Platform, req.ProductID, reason, time.Now())
+	purchase.PurchaseToken = req.PurchaseToken
+	purchase.DeviceToken = req.DeviceToken
+	purchase, err = h.service.RecordPending(c.Request.Context(), purchase)
+	if err != nil {
+		h.logger.Error("Failed to record pending purchase", zap.String("user_id", userID.String()), zap.Error(err))
+		response.InternalError(c, "Failed to record pending purchase")
+		return
+	}
+
+	response.Created(c, toPendingPurchaseResponse(purchase))
+}
+
+// ListPendingPurchases GET /v1/purchases/pending
+// Returns the last week of the user's deferred purchases, resolved ones included.
+func (h *PendingPurchaseHandler) ListPendingPurchases(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	purchases, err := h.service.ListForUser(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Error("Failed to list pending purchases", zap.String("user_id", userID.String()), zap.Error(err))
+		response.InternalError(c, "Failed to list pending purchases")
+		return
+	}
+
+	items := make([]PendingPurchaseResponse, 0, len(purchases))
+	for _, p := range purchases {
+		items = append(items, toPendingPurchaseResponse(p))
+	}
+	response.OK(c, gin.H{"purchases": items, "total": len(items)})
+}
+
+// StreamPurchaseEvents GET /v1/purchases/pending/events
+// Server-sent events: one "purchase_resolved" event per finalized purchase.
+func (h *PendingPurchaseHandler) StreamPurchaseEvents(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+	if h.events == nil {
+		response.ServiceUnavailable(c, "Purchase events are not available")
+		return
+	}
+
+	ctx := c.Request.Context()
+	events, closeEvents, err := h.events.SubscribePurchaseEvents(ctx, userID)
+	if err != nil {
+		h.logger.Error("Failed to subscribe to purchase events", zap.String("user_id", userID.String()), zap.Error(err))
+		response.ServiceUnavailable(c, "Purchase events are not available")
+		return
+	}
+	defer closeEvents()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	heartbeat := time.NewTicker(pendingPurchaseHeartbeat)
+	defer heartbeat.Stop()
+
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			c.SSEvent("purchase_resolved", event)
+		case <-heartbeat.C:
+			c.SSEvent("heartbeat", gin.H{"at": time.Now().UTC()})
+		case <-ctx.Done():
+			return
+		}
+		c.Writer.Flush()
+	}
+}
@@ -0,0 +1,107 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/infrastructure/cache"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/handlers"
+)
+
+type fakePendingPurchaseTracker struct {
+	recorded []*entity.PendingPurchase
+}
+
+func (f *fakePendingPurchaseTracker) RecordPending(ctx context.Context, purchase *entity.PendingPurchase) (*entity.PendingPurchase, error) {
+	f.recorded = append(f.recorded, purchase)
+	return purchase, nil
+}
+
+func (f *fakePendingPurchaseTracker) ListForUser(ctx context.Context, userID uuid.UUID) ([]*entity.PendingPurchase, error) {
+	return f.recorded, nil
+}
+
+type fakePurchaseEvents struct {
+	events chan cache.PurchaseResolvedEvent
+}
+
+func (f *fakePurchaseEvents) SubscribePurchaseEvents(ctx context.Context, userID uuid.UUID) (<-chan cache.PurchaseResolvedEvent, func(), error) {
+	return f.events, func() {}, nil
+}
+
+func newPendingPurchaseRouter(h *handlers.PendingPurchaseHandler, userID, appID string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", userID)
+		c.Set("app_id", appID)
+		c.Next()
+	})
+	r.POST("/v1/purchases/pending", h.ReportPendingPurchase)
+	r.GET("/v1/purchases/pending", h.ListPendingPurchases)
+	r.GET("/v1/purchases/pending/events", h.StreamPurchaseEvents)
+	return r
+}
+
+func TestReportPendingPurchase_DefaultsToAskToBuy(t *testing.T) {
+	userID, appID := uuid.New(), uuid.New()
+	tracker := &fakePendingPurchaseTracker{}
+	router := newPendingPurchaseRouter(handlers.NewPendingPurchaseHandler(tracker, nil, zap.NewNop()), userID.String(), appID.String())
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1/purchases/pending",
+		strings.NewReader(`{"platform":"ios","product_id":"com.app.premium.monthly","device_token":"apns-1"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	require.Len(t, tracker.recorded, 1)
+	assert.Equal(t, entity.PendingReasonAskToBuy, tracker.recorded[0].Reason)
+	assert.Equal(t, userID, tracker.recorded[0].UserID)
+	assert.Equal(t, "apns-1", tracker.recorded[0].DeviceToken)
+
+	var body struct {
+		Data handlers.PendingPurchaseResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "pending", body.Data.Status)
+}
+
+func TestReportPendingPurchase_RejectsUnknownPlatform(t *testing.T) {
+	tracker := &fakePendingPurchaseTracker{}
+	router := newPendingPurchaseRouter(handlers.NewPendingPurchaseHandler(tracker, nil, zap.NewNop()), uuid.NewString(), uuid.NewString())
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1/purchases/pending",
+		strings.NewReader(`{"platform":"web","product_id":"com.app.premium.monthly"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Empty(t, tracker.recorded)
+}
+
+func TestStreamPurchaseEvents_WritesResolution(t *testing.T) {
+	events := &fakePurchaseEvents{events: make(chan cache.PurchaseResolvedEvent, 1)}
+	events.events <- cache.PurchaseResolvedEvent{PendingPurchaseID: uuid.New(), ProductID: "com.app.premium.monthly", Status: "completed"}
+	close(events.events)
+	router := newPendingPurchaseRouter(handlers.NewPendingPurchaseHandler(&fakePendingPurchaseTracker{}, events, zap.NewNop()), uuid.NewString(), uuid.NewString())
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/purchases/pending/events", nil))
+
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/event-stream")
+	assert.Contains(t, w.Body.String(), "event:purchase_resolved")
+	assert.Contains(t, w.Body.String(), `"status":"completed"`)
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+type reviewPrompts interface {
+	CreateExperiment(ctx context.Context, req service.ReviewPromptExperimentRequest) (*service.ReviewPromptExperiment, error)
+	Eligibility(ctx context.Context, userID uuid.UUID) (*service.ReviewEligibility, error)
+	RecordOutcome(ctx context.Context, userID, decisionID uuid.UUID, outcome string) (bool, error)
+}
+
+type createReviewPromptExperimentRequest struct {
+	Name              string `json:"name" binding:"required"`
+	DelayHours        []int  `json:"delay_hours" binding:"required"`
+	RewardWindowHours int    `json:"reward_window_hours"`
+}
+
+type reviewOutcomeRequest struct {
+	Outcome string `json:"outcome" binding:"required"`
+}
+
+// ReviewPromptHandler tells the app whether and when to ask for a store
+// review, takes its reports of how the user answered, and manages the review
+// prompt experiments that learn the timing
+type ReviewPromptHandler struct {
+	prompts reviewPrompts
+	logger  *zap.Logger
+}
+
+func NewReviewPromptHandler(prompts reviewPrompts, logger *zap.Logger) *ReviewPromptHandler {
+	return &ReviewPromptHandler{prompts: prompts, logger: logger}
+}
+
+// GetEligibility GET /v1/prompts/review-eligibility
+// An eligible answer carries a prompt to show at its ask_at; report how it
+// went with its id.
+func (h *ReviewPromptHandler) GetEligibility(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	eligibility, err := h.prompts.Eligibility(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Error("Failed to get review eligibility", zap.String("user_id", userID.String()), zap.Error(err))
+		response.InternalError(c, "Failed to get review eligibility")
+		return
+	}
+	response.OK(c, eligibility)
+}
+
+// RecordOutcome POST /v1/prompts/review/:id/outcome
+// Called by the app when the prompt was shown, accepted or dismissed.
+func (h *ReviewPromptHandler) RecordOutcome(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+	promptID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid prompt ID")
+		return
+	}
+	var req reviewOutcomeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "outcome is required")
+		return
+	}
+
+	credited, err := h.prompts.RecordOutcome(c.Request.Context(), userID, promptID, req.Outcome)
+	if errors.Is(err, service.ErrInvalidReviewOutcome) {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	if errors.Is(err, service.ErrReviewPromptNotFound) {
+		response.NotFound(c, "Review prompt not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to record review prompt outcome", zap.String("prompt_id", promptID.String()), zap.Error(err))
+		response.InternalError(c, "Failed to record review prompt outcome")
+		return
+	}
+	response.OK(c, gin.H{"prompt_id": promptID, "outcome": req.Outcome, "credited": credited})
+}
+
+// CreateExperiment POST /v1/admin/review-prompt-experiments
+// Results are read like any bandit's, from /v1/bandit/statistics.
+func (h *ReviewPromptHandler) CreateExperiment(c *gin.Context) {
+	var req createReviewPromptExperimentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "name and delay_hours are required")
+		return
+	}
+
+	experiment, err := h.prompts.CreateExperiment(c.Request.Context(), service.ReviewPromptExperimentRequest{
+		AppID:        httpmiddleware.GetAppID(c),
+		Name:         req.Name,
+		DelayHours:   req.DelayHours,
+		RewardWindow: time.Duration(req.RewardWindowHours) * time.Hour,
+	})
+	if errors.Is(err, service.ErrInvalidReviewPromptExperiment) {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to create review prompt experiment", zap.Error(err))
+		response.InternalError(c, "Failed to create review prompt experiment")
+		return
+	}
+	response.Created(c, gin.H{"experiment": experiment})
+}
@@ -0,0 +1,130 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/handlers"
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+)
+
+type fakeReviewPrompts struct {
+	user    uuid.UUID
+	created *service.ReviewPromptExperimentRequest
+}
+
+func (f *fakeReviewPrompts) CreateExperiment(_ context.Context, req service.ReviewPromptExperimentRequest) (*service.ReviewPromptExperiment, error) {
+	f.created = &req
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	return &service.ReviewPromptExperiment{ID: uuid.New(), AppID: req.AppID, Name: req.Name}, nil
+}
+
+func (f *fakeReviewPrompts) Eligibility(_ context.Context, userID uuid.UUID) (*service.ReviewEligibility, error) {
+	if userID != f.user {
+		return &service.ReviewEligibility{Reason: service.ReviewReasonLowEngagement}, nil
+	}
+	return &service.ReviewEligibility{
+		Eligible: true,
+		Reason:   service.ReviewReasonEligible,
+		Prompt:   &service.ReviewPromptDecision{ID: uuid.New(), UserID: userID, DelayHours: 24},
+	}, nil
+}
+
+func (f *fakeReviewPrompts) RecordOutcome(_ context.Context, userID, _ uuid.UUID, outcome string) (bool, error) {
+	if outcome != service.ReviewOutcomeShown && outcome != service.ReviewOutcomeAccepted && outcome != service.ReviewOutcomeDismissed {
+		return false, service.ErrInvalidReviewOutcome
+	}
+	if userID != f.user {
+		return false, service.ErrReviewPromptNotFound
+	}
+	return outcome == service.ReviewOutcomeAccepted, nil
+}
+
+func newReviewPromptRouter(h *handlers.ReviewPromptHandler, userID uuid.UUID) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", userID.String())
+		c.Set(httpmiddleware.AppIDKey, uuid.New())
+		c.Next()
+	})
+	r.GET("/v1/prompts/review-eligibility", h.GetEligibility)
+	r.POST("/v1/prompts/review/:id/outcome", h.RecordOutcome)
+	r.POST("/v1/admin/review-prompt-experiments", h.CreateExperiment)
+	return r
+}
+
+func TestReviewEligibility_ReturnsPrompt(t *testing.T) {
+	userID := uuid.New()
+	router := newReviewPromptRouter(handlers.NewReviewPromptHandler(&fakeReviewPrompts{user: userID}, zap.NewNop()), userID)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/prompts/review-eligibility", nil))
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var body struct {
+		Data service.ReviewEligibility `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.True(t, body.Data.Eligible)
+	require.NotNil(t, body.Data.Prompt)
+	assert.Equal(t, 24, body.Data.Prompt.DelayHours)
+}
+
+func TestReviewOutcome(t *testing.T) {
+	userID := uuid.New()
+	router := newReviewPromptRouter(handlers.NewReviewPromptHandler(&fakeReviewPrompts{user: userID}, zap.NewNop()), userID)
+	path := "/v1/prompts/review/" + uuid.NewString() + "/outcome"
+
+	tests := []struct {
+		name string
+		path string
+		body string
+		want int
+	}{
+		{name: "accepted", path: path, body: `{"outcome":"accepted"}`, want: http.StatusOK},
+		{name: "unknown outcome", path: path, body: `{"outcome":"rated"}`, want: http.StatusBadRequest},
+		{name: "missing outcome", path: path, body: `{}`, want: http.StatusBadRequest},
+		{name: "bad id", path: "/v1/prompts/review/nope/outcome", body: `{"outcome":"shown"}`, want: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body)))
+			assert.Equal(t, tt.want, w.Code, w.Body.String())
+		})
+	}
+
+	t.Run("another user's prompt", func(t *testing.T) {
+		other := newReviewPromptRouter(handlers.NewReviewPromptHandler(&fakeReviewPrompts{user: userID}, zap.NewNop()), uuid.New())
+		w := httptest.NewRecorder()
+		other.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"outcome":"accepted"}`)))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestCreateReviewPromptExperiment_RejectsSingleDelay(t *testing.T) {
+	prompts := &fakeReviewPrompts{}
+	router := newReviewPromptRouter(handlers.NewReviewPromptHandler(prompts, zap.NewNop()), uuid.New())
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/admin/review-prompt-experiments",
+		strings.NewReader(`{"name":"Review timing","delay_hours":[24],"reward_window_hours":24}`)))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	require.NotNil(t, prompts.created)
+	assert.Equal(t, []int{24}, prompts.created.DelayHours)
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+type sendTimeExperiments interface {
+	CreateExperiment(ctx context.Context, req service.SendTimeExperimentRequest) (*service.SendTimeExperiment, error)
+	Results(ctx context.Context, appID, experimentID uuid.UUID) (*service.SendTimeResults, error)
+	RecordOpen(ctx context.Context, userID, deliveryID uuid.UUID) (bool, error)
+}
+
+type segmentLookup interface {
+	GetByID(ctx context.Context, appID, id uuid.UUID) (*entity.Segment, error)
+}
+
+type createSendTimeExperimentRequest struct {
+	Name              string     `json:"name" binding:"required"`
+	SegmentID         *uuid.UUID `json:"segment_id"`
+	Hours             []int      `json:"hours" binding:"required"`
+	RewardEvent       string     `json:"reward_event" binding:"required"`
+	RewardWindowHours int        `json:"reward_window_hours"`
+}
+
+// SendTimeHandler manages notification send-time experiments and takes the
+// app's reports of opened send-time pushes
+type SendTimeHandler struct {
+	experiments sendTimeExperiments
+	segments    segmentLookup
+}
+
+func NewSendTimeHandler(experiments sendTimeExperiments, segments segmentLookup) *SendTimeHandler {
+	return &SendTimeHandler{experiments: experiments, segments: segments}
+}
+
+// CreateExperiment POST /v1/admin/send-time-experiments
+func (h *SendTimeHandler) CreateExperiment(c *gin.Context) {
+	appID := httpmiddleware.GetAppID(c)
+	var req createSendTimeExperimentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "name, hours and reward_event are required")
+		return
+	}
+	ctx := c.Request.Context()
+	if req.SegmentID != nil {
+		_, err := h.segments.GetByID(ctx, appID, *req.SegmentID)
+		if errors.Is(err, domainErrors.ErrNotFound) {
+			response.BadRequest(c, "segment_id does not belong to this app")
+			return
+		}
+		if err != nil {
+			response.InternalError(c, "Failed to get segment")
+			return
+		}
+	}
+
+	experiment, err := h.experiments.CreateExperiment(ctx, service.SendTimeExperimentRequest{
+		AppID:        appID,
+		SegmentID:    req.SegmentID,
+		Name:         req.Name,
+		Hours:        req.Hours,
+		RewardEvent:  req.RewardEvent,
+		RewardWindow: time.Duration(req.RewardWindowHours) * time.Hour,
+	})
+	if errors.Is(err, service.ErrInvalidSendTimeExperiment) {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	if err != nil {
+		response.InternalError(c, "Failed to create send-time experiment")
+		return
+	}
+	response.Created(c, gin.H{"experiment": experiment})
+}
+
+// GetResults GET /v1/admin/send-time-experiments/:id/results
+func (h *SendTimeHandler) GetResults(c *gin.Context) {
+	experimentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid experiment ID")
+		return
+	}
+	results, err := h.experiments.Results(c.Request.Context(), httpmiddleware.GetAppID(c), experimentID)
+	if errors.Is(err, service.ErrSendTimeExperimentNotFound) {
+		response.NotFound(c, "Send-time experiment not found")
+		return
+	}
+	if err != nil {
+		response.InternalError(c, "Failed to get send-time results")
+		return
+	}
+	response.OK(c, results)
+}
+
+// RecordOpen POST /v1/notifications/deliveries/:id/open
+// Called by the app with the delivery_id of a send-time push the user opened.
+func (h *SendTimeHandler) RecordOpen(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+	deliveryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid delivery ID")
+		return
+	}
+
+	credited, err := h.experiments.RecordOpen(c.Request.Context(), userID, deliveryID)
+	if errors.Is(err, service.ErrSendTimeDeliveryNotFound) {
+		response.NotFound(c, "Delivery not found")
+		return
+	}
+	if err != nil {
+		response.InternalError(c, "Failed to record notification open")
+		return
+	}
+	response.OK(c, gin.H{"delivery_id": deliveryID, "credited": credited})
+}
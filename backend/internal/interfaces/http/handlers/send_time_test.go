@@ -0,0 +1,104 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/handlers"
+	httpmiddleware "github.com/bivex/paywall-iap/internal/interfaces/http/middleware"
+)
+
+type fakeSendTimeExperiments struct {
+	created   *service.SendTimeExperimentRequest
+	recipient uuid.UUID
+}
+
+func (f *fakeSendTimeExperiments) CreateExperiment(_ context.Context, req service.SendTimeExperimentRequest) (*service.SendTimeExperiment, error) {
+	f.created = &req
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	return &service.SendTimeExperiment{ID: uuid.New(), AppID: req.AppID, Name: req.Name}, nil
+}
+
+func (f *fakeSendTimeExperiments) Results(context.Context, uuid.UUID, uuid.UUID) (*service.SendTimeResults, error) {
+	return nil, service.ErrSendTimeExperimentNotFound
+}
+
+func (f *fakeSendTimeExperiments) RecordOpen(_ context.Context, userID, _ uuid.UUID) (bool, error) {
+	if userID != f.recipient {
+		return false, service.ErrSendTimeDeliveryNotFound
+	}
+	return true, nil
+}
+
+type noSegments struct{}
+
+func (noSegments) GetByID(context.Context, uuid.UUID, uuid.UUID) (*entity.Segment, error) {
+	return &entity.Segment{}, nil
+}
+
+func newSendTimeRouter(h *handlers.SendTimeHandler, userID uuid.UUID) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", userID.String())
+		c.Set(httpmiddleware.AppIDKey, uuid.New())
+		c.Next()
+	})
+	r.POST("/v1/admin/send-time-experiments", h.CreateExperiment)
+	r.GET("/v1/admin/send-time-experiments/:id/results", h.GetResults)
+	r.POST("/v1/notifications/deliveries/:id/open", h.RecordOpen)
+	return r
+}
+
+func TestSendTimeCreateExperiment_RejectsInvalidHours(t *testing.T) {
+	experiments := &fakeSendTimeExperiments{}
+	router := newSendTimeRouter(handlers.NewSendTimeHandler(experiments, noSegments{}), uuid.New())
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1/admin/send-time-experiments",
+		strings.NewReader(`{"name":"Evening push","hours":[9,30],"reward_event":"open","reward_window_hours":12}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	require.NotNil(t, experiments.created)
+	assert.Equal(t, []int{9, 30}, experiments.created.Hours)
+}
+
+func TestSendTimeGetResults_NotFound(t *testing.T) {
+	router := newSendTimeRouter(handlers.NewSendTimeHandler(&fakeSendTimeExperiments{}, noSegments{}), uuid.New())
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/send-time-experiments/"+uuid.NewString()+"/results", nil))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestSendTimeRecordOpen(t *testing.T) {
+	recipient := uuid.New()
+	experiments := &fakeSendTimeExperiments{recipient: recipient}
+	path := "/v1/notifications/deliveries/" + uuid.NewString() + "/open"
+
+	w := httptest.NewRecorder()
+	newSendTimeRouter(handlers.NewSendTimeHandler(experiments, noSegments{}), recipient).
+		ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"credited":true`)
+
+	w = httptest.NewRecorder()
+	newSendTimeRouter(handlers.NewSendTimeHandler(experiments, noSegments{}), uuid.New()).
+		ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+)
+
+// skanPostbackMaxBytes bounds postback bodies; Apple's are well under 2 KB
+const skanPostbackMaxBytes = 16 << 10
+
+type skanAttribution interface {
+	RecordPostback(ctx context.Context, body []byte) (*entity.SKANPostback, bool, error)
+	ConversionValue(ctx context.Context, appID, userID uuid.UUID) (*service.SKANConversionValue, error)
+}
+
+// SKANHandler receives SKAdNetwork postbacks and tells clients which
+// conversion value to report
+type SKANHandler struct {
+	attribution skanAttribution
+	logger      *zap.Logger
+}
+
+func NewSKANHandler(attribution skanAttribution, logger *zap.Logger) *SKANHandler {
+	return &SKANHandler{attribution: attribution, logger: logger}
+}
+
+// ReceivePostback POST /.well-known/skadnetwork/report-attribution/
+// Apple and ad networks post install postbacks here; the app is resolved from
+// the postback's app-id. Repeated postbacks are accepted and ignored.
+func (h *SKANHandler) ReceivePostback(c *gin.Context) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, skanPostbackMaxBytes))
+	if err != nil {
+		response.BadRequest(c, "Invalid postback body")
+		return
+	}
+	postback, created, err := h.attribution.RecordPostback(c.Request.Context(), body)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidSKANPostback):
+			response.BadRequest(c, err.Error())
+		case errors.Is(err, service.ErrSKANSignature):
+			response.Unauthorized(c, err.Error())
+		case errors.Is(err, service.ErrSKANUnknownApp):
+			response.NotFound(c, err.Error())
+		default:
+			h.logger.Error("Failed to record SKAdNetwork postback", zap.Error(err))
+			response.InternalError(c, "Failed to record postback")
+		}
+		return
+	}
+	if created {
+		h.logger.Info("SKAdNetwork postback received",
+			zap.String("app_id", postback.AppID.String()),
+			zap.String("ad_network_id", postback.AdNetworkID),
+			zap.Int("sequence", postback.PostbackSequenceIndex))
+	}
+	c.Status(http.StatusOK)
+}
+
+// GetConversionValue GET /v1/attribution/skan/conversion-value
+// Returns 404 when the app has no conversion value schema.
+func (h *SKANHandler) GetConversionValue(c *gin.Context) {
+	userID, appID, ok := creditsCaller(c)
+	if !ok {
+		return
+	}
+	value, err := h.attribution.ConversionValue(c.Request.Context(), appID, userID)
+	if err != nil {
+		h.logger.Error("Failed to compute SKAdNetwork conversion value", zap.Error(err))
+		response.InternalError(c, "Failed to compute conversion value")
+		return
+	}
+	if value == nil {
+		response.NotFound(c, "App has no SKAdNetwork conversion schema")
+		return
+	}
+	response.OK(c, value)
+}
@@ -0,0 +1,73 @@
+package handlers_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/handlers"
+)
+
+type fakeSKANAttribution struct {
+	err   error
+	value *service.SKANConversionValue
+}
+
+func (f *fakeSKANAttribution) RecordPostback(ctx context.Context, body []byte) (*entity.SKANPostback, bool, error) {
+	if f.err != nil {
+		return nil, false, f.err
+	}
+	return &entity.SKANPostback{ID: uuid.New(), AppID: uuid.New()}, true, nil
+}
+
+func (f *fakeSKANAttribution) ConversionValue(ctx context.Context, appID, userID uuid.UUID) (*service.SKANConversionValue, error) {
+	return f.value, nil
+}
+
+func postSKANPostback(h *handlers.SKANHandler) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/.well-known/skadnetwork/report-attribution/", h.ReceivePostback)
+	req := httptest.NewRequest(http.MethodPost, "/.well-known/skadnetwork/report-attribution/", strings.NewReader(`{"version":"4.0"}`))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestReceivePostback_MapsErrors(t *testing.T) {
+	cases := map[int]error{
+		http.StatusOK:           nil,
+		http.StatusBadRequest:   fmt.Errorf("%w: transaction-id is required", service.ErrInvalidSKANPostback),
+		http.StatusUnauthorized: service.ErrSKANSignature,
+		http.StatusNotFound:     fmt.Errorf("%w: 123", service.ErrSKANUnknownApp),
+	}
+	for want, err := range cases {
+		w := postSKANPostback(handlers.NewSKANHandler(&fakeSKANAttribution{err: err}, zap.NewNop()))
+		assert.Equal(t, want, w.Code, "%v", err)
+	}
+}
+
+func TestGetConversionValue_NotFoundWithoutSchema(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", uuid.NewString())
+		c.Set("app_id", uuid.NewString())
+		c.Next()
+	})
+	r.GET("/v1/attribution/skan/conversion-value", handlers.NewSKANHandler(&fakeSKANAttribution{}, zap.NewNop()).GetConversionValue)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/attribution/skan/conversion-value", nil))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -1,21 +1,31 @@
 package handlers
 
 import (
+	"context"
 	"crypto/hmac"
+	"crypto/rsa"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	iapext "github.com/bivex/paywall-iap/internal/infrastructure/external/iap"
 	"github.com/bivex/paywall-iap/internal/infrastructure/logging"
+	"github.com/bivex/paywall-iap/internal/infrastructure/metrics"
 	"github.com/bivex/paywall-iap/internal/infrastructure/persistence/sqlc/generated"
+	"github.com/bivex/paywall-iap/internal/infrastructure/webhookschema"
 	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
 	"github.com/bivex/paywall-iap/internal/worker/tasks"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/hibiken/asynq"
 	"go.uber.org/zap"
 )
@@ -28,6 +38,96 @@ type WebhookHandler struct {
 	allowedIPs          map[string][]string // service -> IPs
 	queries             *generated.Queries
 	asynqClient         *asynq.Client
+
+	// Apple signed-payload verification; nil only in development with
+	// APPLE_JWS_VERIFICATION_DISABLED set
+	appleJWS          appleJWSVerifier
+	appleEnvironments map[string]bool
+
+	// Google Pub/Sub push authentication; nil only in development with
+	// GOOGLE_PUBSUB_AUTH_DISABLED set
+	googleOIDC googleOIDCVerifier
+
+	// Amazon SNS signature verification and Huawei subscription notification
+	// signing key; nil only in development with verification disabled.
+	// Huawei notifications are refused until configured.
+	amazonSNS     amazonSNSVerifier
+	huaweiKey     *rsa.PublicKey
+	huaweiEnabled bool
+
+	// PayPal transmission signature verification; nil only in development
+	// with PAYPAL_VERIFICATION_DISABLED set. Refused until configured.
+	payPal        payPalWebhookVerifier
+	payPalEnabled bool
+
+	// stripeTolerance bounds the age of a Stripe-Signature timestamp
+	stripeTolerance time.Duration
+	now             func() time.Time
+
+	// quarantine keeps malformed authenticated deliveries; nil rejects them
+	quarantine webhookQuarantineStore
+
+	// analytics receives an event per accepted delivery; nil disables it
+	analytics eventSubmitter
+}
+
+// DefaultStripeSignatureTolerance matches the tolerance of Stripe's own libraries
+const DefaultStripeSignatureTolerance = 5 * time.Minute
+
+var webhookSignatureRejections = metrics.NewCounterVec(
+	"webhook_signature_rejections_total",
+	"Webhook deliveries rejected because their signature or token failed verification",
+	"provider", "reason",
+)
+
+var webhookReceiptLag = metrics.NewHistogramVec(
+	"webhook_receipt_lag_seconds",
+	"Time between the provider's event timestamp and our receipt of the webhook",
+	metrics.DefaultLatencyBuckets,
+	"provider",
+)
+
+var webhookQuarantined = metrics.NewCounterVec(
+	"webhook_quarantined_total",
+	"Authenticated webhook deliveries quarantined because their payload could not be parsed",
+	"provider",
+)
+
+// ErrMalformedWebhook is wrapped by every payload parsing failure
+var ErrMalformedWebhook = errors.New("malformed webhook payload")
+
+// malformedWebhookError is a payload parsing failure; message is the response
+// given when there is no quarantine store
+type malformedWebhookError struct {
+	message string
+	err     error
+}
+
+func (e *malformedWebhookError) Error() string { return e.message + ": " + e.err.Error() }
+
+func (e *malformedWebhookError) Unwrap() []error { return []error{ErrMalformedWebhook, e.err} }
+
+// ParsedWebhook is a delivery ready to be stored in webhook_events and
+// processed by the worker
+type ParsedWebhook struct {
+	Provider  string `json:"provider"`
+	EventType string `json:"event_type"`
+	EventID   string `json:"event_id"`
+	Payload   []byte `json:"-"`
+	// EventAt is when the provider says the event happened; nil if not reported
+	EventAt *time.Time `json:"event_at,omitempty"`
+}
+
+type webhookQuarantineStore interface {
+	QuarantineWebhook(ctx context.Context, provider string, rawBody []byte, reason string) (uuid.UUID, error)
+}
+
+type appleJWSVerifier interface {
+	Verify(ctx context.Context, token string) ([]byte, error)
+}
+
+type googleOIDCVerifier interface {
+	Verify(ctx context.Context, token string) (*iapext.GoogleOIDCClaims, error)
 }
 
 // NewWebhookHandler creates a new webhook handler
@@ -39,7 +139,55 @@ func NewWebhookHandler(stripeSecret, appleSecret, googleSecret string, queries *
 		queries:             queries,
 		asynqClient:         asynqClient,
 		allowedIPs:          WebhookIPConfig,
+		stripeTolerance:     DefaultStripeSignatureTolerance,
+		now:                 time.Now,
+	}
+}
+
+// WithStripeTolerance sets how old a Stripe-Signature timestamp may be before
+// the delivery is rejected as a possible replay
+func (h *WebhookHandler) WithStripeTolerance(tolerance time.Duration) *WebhookHandler {
+	if tolerance > 0 {
+		h.stripeTolerance = tolerance
 	}
+	return h
+}
+
+// WithAppleVerification verifies Apple notifications (and the signed
+// transaction / renewal info they carry) with verifier and only accepts the
+// given notification environments ("Production", "Sandbox"). A nil verifier
+// disables verification and must only be used in development.
+func (h *WebhookHandler) WithAppleVerification(verifier appleJWSVerifier, environments ...string) *WebhookHandler {
+	h.appleJWS = verifier
+	h.appleEnvironments = make(map[string]bool, len(environments))
+	for _, env := range environments {
+		h.appleEnvironments[env] = true
+	}
+	return h
+}
+
+// WithGooglePushAuthentication requires every RTDN push to carry a
+// Google-signed OIDC bearer token accepted by verifier. A nil verifier
+// disables the check and must only be used in development.
+func (h *WebhookHandler) WithGooglePushAuthentication(verifier googleOIDCVerifier) *WebhookHandler {
+	h.googleOIDC = verifier
+	return h
+}
+
+// WithQuarantine keeps authenticated deliveries whose payload cannot be parsed
+// in store and acknowledges them, instead of rejecting them with a 400 that
+// the provider never retries
+func (h *WebhookHandler) WithQuarantine(store webhookQuarantineStore) *WebhookHandler {
+	h.quarantine = store
+	return h
+}
+
+// WithAnalyticsEvents records every accepted delivery as a webhook-sourced
+// analytics event. Redeliveries are recorded again; event_id in the
+// properties identifies them.
+func (h *WebhookHandler) WithAnalyticsEvents(ingester eventSubmitter) *WebhookHandler {
+	h.analytics = ingester
+	return h
 }
 
 // StripeWebhook handles Stripe webhook events
@@ -61,6 +209,7 @@ func (h *WebhookHandler) StripeWebhook(c *gin.Context) {
 	signature := c.GetHeader("Stripe-Signature")
 	if h.stripeWebhookSecret != "" && h.stripeWebhookSecret != "whsec_dummy" {
 		if signature == "" {
+			webhookSignatureRejections.Inc("stripe", "missing_signature")
 			response.Unauthorized(c, "Missing signature")
 			return
 		}
@@ -75,43 +224,42 @@ func (h *WebhookHandler) StripeWebhook(c *gin.Context) {
 
 	// Verify HMAC
 	if h.stripeWebhookSecret != "" && h.stripeWebhookSecret != "whsec_dummy" {
-		if !h.verifyStripeHMAC(body, signature) {
-			response.Unauthorized(c, "Invalid signature")
+		if err := h.verifyStripeSignature(body, signature); err != nil {
+			webhookSignatureRejections.Inc("stripe", err.reason)
+			logging.Logger.Warn("Rejected Stripe webhook", zap.String("reason", err.reason))
+			response.Unauthorized(c, err.message)
 			return
 		}
 	}
 
-	// Parse event ID and type from Stripe JSON body
-	var event struct {
-		ID   string `json:"id"`
-		Type string `json:"type"`
-	}
-	if err := json.Unmarshal(body, &event); err != nil {
-		response.BadRequest(c, "Invalid event body")
+	event, err := parseStripeEvent(body)
+	if err != nil {
+		h.rejectMalformed(c, "stripe", body, err)
 		return
 	}
 
-	if err := h.queries.InsertWebhookEvent(c.Request.Context(), generated.InsertWebhookEventParams{
-		Provider:  "stripe",
-		EventType: event.Type,
-		EventID:   event.ID,
-		Payload:   body,
-	}); err != nil {
-		// Log but return 200 — Stripe retries on failure
-		_ = err
-	}
+	h.acceptEvent(c, event)
+}
 
-	// Enqueue background processing task
-	payload, _ := json.Marshal(map[string]string{
-		"provider":   "stripe",
-		"event_type": event.Type,
-		"event_id":   event.ID,
-	})
-	if _, err := h.asynqClient.Enqueue(asynq.NewTask(tasks.TypeProcessWebhook, payload)); err != nil {
-		logging.Logger.Error("Failed to enqueue webhook task", zap.Error(err))
+// parseStripeEvent validates a Stripe event body and extracts its ID and type
+func parseStripeEvent(body []byte) (*ParsedWebhook, error) {
+	if err := webhookschema.Validate(webhookschema.StripeEvent, body); err != nil {
+		return nil, &malformedWebhookError{message: "Invalid event body", err: err}
 	}
-
-	c.JSON(http.StatusOK, gin.H{"status": "received"})
+	var event struct {
+		ID      string `json:"id"`
+		Type    string `json:"type"`
+		Created int64  `json:"created"` // unix seconds
+	}
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, &malformedWebhookError{message: "Invalid event body", err: err}
+	}
+	parsed := &ParsedWebhook{Provider: "stripe", EventType: event.Type, EventID: event.ID, Payload: body}
+	if event.Created > 0 {
+		at := time.Unix(event.Created, 0)
+		parsed.EventAt = &at
+	}
+	return parsed, nil
 }
 
 // AppleWebhook handles Apple S2S notifications
@@ -129,70 +277,138 @@ func (h *WebhookHandler) AppleWebhook(c *gin.Context) {
 		}
 	}
 
-	// Apple sends a JWS compact token as the raw body
 	body, err := io.ReadAll(c.Request.Body)
 	if err != nil {
 		response.BadRequest(c, "Failed to read body")
 		return
 	}
 
-	jwsToken := strings.TrimSpace(string(body))
+	jwsToken, ok := appleSignedPayload(body)
+	if !ok {
+		response.BadRequest(c, "Missing signedPayload")
+		return
+	}
 
-	// JWS compact format: header.payload.signature (three dot-separated base64url parts)
-	parts := strings.Split(jwsToken, ".")
-	if len(parts) != 3 {
-		response.BadRequest(c, "Invalid JWS token format")
+	payloadBytes, err := h.decodeAppleJWS(c.Request.Context(), jwsToken)
+	if err != nil {
+		h.respondAppleJWSError(c, err)
 		return
 	}
 
-	// Decode the payload (middle part) — base64url with no padding
-	payloadBytes, err := base64.RawURLEncoding.DecodeString(parts[1])
+	event, err := h.parseAppleNotification(c.Request.Context(), payloadBytes)
 	if err != nil {
-		response.BadRequest(c, "Failed to decode JWS payload")
+		var envErr *appleEnvironmentError
+		switch {
+		case errors.As(err, &envErr):
+			response.BadRequest(c, envErr.Error())
+		case errors.Is(err, ErrMalformedWebhook):
+			h.rejectMalformed(c, "apple", body, err)
+		default:
+			h.respondAppleJWSError(c, err)
+		}
 		return
 	}
 
-	// Parse the Apple notification envelope
+	h.acceptEvent(c, event)
+}
+
+// appleSignedPayload extracts the JWS from a notification body. App Store
+// Server Notifications V2 post {"signedPayload": "<JWS>"}; a bare compact JWS
+// body is accepted too.
+func appleSignedPayload(body []byte) (string, bool) {
+	jwsToken := strings.TrimSpace(string(body))
+	if !strings.HasPrefix(jwsToken, "{") {
+		return jwsToken, jwsToken != ""
+	}
+	var envelope struct {
+		SignedPayload string `json:"signedPayload"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil || envelope.SignedPayload == "" {
+		return "", false
+	}
+	return envelope.SignedPayload, true
+}
+
+// appleEnvironmentError rejects a notification from an environment this
+// deployment does not accept
+type appleEnvironmentError struct {
+	environment string
+}
+
+func (e *appleEnvironmentError) Error() string {
+	return fmt.Sprintf("Notification environment %q is not accepted by this deployment", e.environment)
+}
+
+// parseAppleNotification validates a verified notification payload, checks its
+// environment and verifies the nested signed payloads the worker decodes
+func (h *WebhookHandler) parseAppleNotification(ctx context.Context, payloadBytes []byte) (*ParsedWebhook, error) {
+	if err := webhookschema.Validate(webhookschema.AppleNotification, payloadBytes); err != nil {
+		return nil, &malformedWebhookError{message: "Failed to parse notification payload", err: err}
+	}
 	var notification struct {
 		NotificationType string `json:"notificationType"`
 		NotificationUUID string `json:"notificationUUID"`
+		SignedDate       int64  `json:"signedDate"` // unix ms
 		Data             struct {
+			Environment           string `json:"environment"`
 			SignedTransactionInfo string `json:"signedTransactionInfo"`
 			SignedRenewalInfo     string `json:"signedRenewalInfo"`
 		} `json:"data"`
 	}
 	if err := json.Unmarshal(payloadBytes, &notification); err != nil {
-		response.BadRequest(c, "Failed to parse notification payload")
-		return
+		return nil, &malformedWebhookError{message: "Failed to parse notification payload", err: err}
 	}
 
-	// Skip JWS signature verification in dev (no Apple cert configured)
-	// In production: verify using Apple's WWDR certificate chain
-	if h.appleWebhookSecret != "" {
-		// Full verification would parse the x5c chain from the JWS header
-		// and verify against Apple's root CA. Omitted in this implementation.
+	if len(h.appleEnvironments) > 0 && !h.appleEnvironments[notification.Data.Environment] {
+		return nil, &appleEnvironmentError{environment: notification.Data.Environment}
 	}
 
-	if err := h.queries.InsertWebhookEvent(c.Request.Context(), generated.InsertWebhookEventParams{
+	for _, nested := range []string{notification.Data.SignedTransactionInfo, notification.Data.SignedRenewalInfo} {
+		if nested == "" {
+			continue
+		}
+		if _, err := h.decodeAppleJWS(ctx, nested); err != nil {
+			return nil, err
+		}
+	}
+
+	parsed := &ParsedWebhook{
 		Provider:  "apple",
 		EventType: notification.NotificationType,
 		EventID:   notification.NotificationUUID,
 		Payload:   payloadBytes,
-	}); err != nil {
-		_ = err // idempotent insert — ignore duplicate errors
 	}
+	if notification.SignedDate > 0 {
+		at := time.UnixMilli(notification.SignedDate)
+		parsed.EventAt = &at
+	}
+	return parsed, nil
+}
 
-	// Enqueue background processing task
-	taskPayload, _ := json.Marshal(map[string]string{
-		"provider":   "apple",
-		"event_type": notification.NotificationType,
-		"event_id":   notification.NotificationUUID,
-	})
-	if _, err := h.asynqClient.Enqueue(asynq.NewTask(tasks.TypeProcessWebhook, taskPayload)); err != nil {
-		logging.Logger.Error("Failed to enqueue Apple webhook task", zap.Error(err))
+// decodeAppleJWS verifies an Apple compact JWS and returns its payload. With
+// verification disabled (development only) the payload is decoded as-is.
+func (h *WebhookHandler) decodeAppleJWS(ctx context.Context, token string) ([]byte, error) {
+	if h.appleJWS != nil {
+		return h.appleJWS.Verify(ctx, token)
 	}
+	return iapext.ParseAppleJWSPayload(token)
+}
 
-	c.JSON(http.StatusOK, gin.H{"status": "received"})
+// respondAppleJWSError maps verification failures: a transient OCSP outage is
+// a 503 so Apple retries the notification, anything else is rejected
+func (h *WebhookHandler) respondAppleJWSError(c *gin.Context, err error) {
+	if errors.Is(err, iapext.ErrAppleRevocationUnavailable) {
+		logging.Logger.Warn("Apple certificate revocation check failed", zap.Error(err))
+		response.ServiceUnavailable(c, "Could not verify Apple certificate status")
+		return
+	}
+	if h.appleJWS == nil {
+		response.BadRequest(c, "Invalid JWS token")
+		return
+	}
+	webhookSignatureRejections.Inc("apple", "invalid_jws")
+	logging.Logger.Warn("Rejected Apple notification with invalid signature", zap.Error(err))
+	response.Unauthorized(c, "Invalid JWS signature")
 }
 
 // GoogleWebhook handles Google RTDN notifications
@@ -210,13 +426,44 @@ func (h *WebhookHandler) GoogleWebhook(c *gin.Context) {
 		}
 	}
 
-	// Google sends Pub/Sub push as JSON with base64-encoded message.data
+	// Pub/Sub push authenticates with an OIDC token for the subscription's
+	// service account; reject anything else before touching the body
+	if h.googleOIDC != nil {
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			webhookSignatureRejections.Inc("google", "missing_token")
+			response.Unauthorized(c, "Missing bearer token")
+			return
+		}
+		if _, err := h.googleOIDC.Verify(c.Request.Context(), token); err != nil {
+			webhookSignatureRejections.Inc("google", "invalid_token")
+			logging.Logger.Warn("Rejected Google push with invalid OIDC token", zap.Error(err))
+			response.Unauthorized(c, "Invalid bearer token")
+			return
+		}
+	}
+
 	body, err := io.ReadAll(c.Request.Body)
 	if err != nil {
 		response.BadRequest(c, "Failed to read body")
 		return
 	}
 
+	event, err := parseGooglePush(body)
+	if err != nil {
+		h.rejectMalformed(c, "google", body, err)
+		return
+	}
+
+	h.acceptEvent(c, event)
+}
+
+// parseGooglePush unwraps a Pub/Sub push (JSON with base64-encoded
+// message.data) and validates the RTDN notification it carries
+func parseGooglePush(body []byte) (*ParsedWebhook, error) {
+	if err := webhookschema.Validate(webhookschema.GooglePubSubPush, body); err != nil {
+		return nil, &malformedWebhookError{message: "Invalid Pub/Sub message", err: err}
+	}
 	var pubsubMessage struct {
 		Message struct {
 			Data      string `json:"data"`      // base64-encoded
@@ -225,85 +472,234 @@ func (h *WebhookHandler) GoogleWebhook(c *gin.Context) {
 		Subscription string `json:"subscription"`
 	}
 	if err := json.Unmarshal(body, &pubsubMessage); err != nil {
-		response.BadRequest(c, "Invalid Pub/Sub message")
-		return
+		return nil, &malformedWebhookError{message: "Invalid Pub/Sub message", err: err}
 	}
 
-	// Decode the notification payload
 	notificationBytes, err := base64.StdEncoding.DecodeString(pubsubMessage.Message.Data)
 	if err != nil {
-		response.BadRequest(c, "Failed to decode Pub/Sub data")
-		return
+		return nil, &malformedWebhookError{message: "Failed to decode Pub/Sub data", err: err}
 	}
 
-	// Parse notification type from the RTDN payload
+	if err := webhookschema.Validate(webhookschema.GoogleRTDN, notificationBytes); err != nil {
+		return nil, &malformedWebhookError{message: "Failed to parse RTDN notification", err: err}
+	}
 	var rtdn struct {
 		SubscriptionNotification struct {
 			NotificationType int    `json:"notificationType"`
 			PurchaseToken    string `json:"purchaseToken"`
 			SubscriptionID   string `json:"subscriptionId"`
 		} `json:"subscriptionNotification"`
-		PackageName string `json:"packageName"`
+		PackageName     string `json:"packageName"`
+		EventTimeMillis string `json:"eventTimeMillis"`
 	}
 	if err := json.Unmarshal(notificationBytes, &rtdn); err != nil {
-		response.BadRequest(c, "Failed to parse RTDN notification")
-		return
+		return nil, &malformedWebhookError{message: "Failed to parse RTDN notification", err: err}
 	}
 
-	// Verify Google JWT in Authorization header (skip in dev)
-	if h.googleWebhookSecret != "" {
-		// In production: validate the Authorization: Bearer token is a valid
-		// Google-signed OIDC token for the configured service account.
+	parsed := &ParsedWebhook{
+		Provider:  "google",
+		EventType: fmt.Sprintf("subscription.%d", rtdn.SubscriptionNotification.NotificationType),
+		EventID:   pubsubMessage.Message.MessageID,
+		Payload:   notificationBytes,
+	}
+	if ms, err := strconv.ParseInt(rtdn.EventTimeMillis, 10, 64); err == nil && ms > 0 {
+		at := time.UnixMilli(ms)
+		parsed.EventAt = &at
 	}
+	return parsed, nil
+}
 
-	eventType := fmt.Sprintf("subscription.%d", rtdn.SubscriptionNotification.NotificationType)
-	eventID := pubsubMessage.Message.MessageID
+// ReparseWebhook runs a quarantined delivery's raw body through the current
+// payload parsing and, on success, stores and enqueues the event. Signature,
+// token and IP checks are skipped: the delivery was authenticated on receipt.
+// Nested Apple signed payloads are still verified. Bodies that still fail to
+// parse return an error wrapping ErrMalformedWebhook.
+func (h *WebhookHandler) ReparseWebhook(ctx context.Context, provider string, rawBody []byte) (*ParsedWebhook, error) {
+	var event *ParsedWebhook
+	var err error
+	switch provider {
+	case "stripe":
+		event, err = parseStripeEvent(rawBody)
+	case "apple":
+		jwsToken, ok := appleSignedPayload(rawBody)
+		if !ok {
+			return nil, &malformedWebhookError{message: "Missing signedPayload", err: errors.New("no signed payload in body")}
+		}
+		payloadBytes, decodeErr := iapext.ParseAppleJWSPayload(jwsToken)
+		if decodeErr != nil {
+			return nil, &malformedWebhookError{message: "Invalid JWS token", err: decodeErr}
+		}
+		event, err = h.parseAppleNotification(ctx, payloadBytes)
+	case "google":
+		event, err = parseGooglePush(rawBody)
+	case "amazon":
+		event, err = parseAmazonDelivery(rawBody)
+	case "huawei":
+		event, err = parseHuaweiNotification(rawBody)
+	case "paypal":
+		event, err = parsePayPalEvent(rawBody)
+	default:
+		return nil, fmt.Errorf("unknown webhook provider %q", provider)
+	}
+	if err != nil {
+		return nil, err
+	}
 
-	if err := h.queries.InsertWebhookEvent(c.Request.Context(), generated.InsertWebhookEventParams{
-		Provider:  "google",
-		EventType: eventType,
-		EventID:   eventID,
-		Payload:   notificationBytes,
+	if err := h.storeAndEnqueue(ctx, event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// acceptEvent stores and enqueues a parsed delivery and acknowledges it
+func (h *WebhookHandler) acceptEvent(c *gin.Context, event *ParsedWebhook) {
+	if event.EventAt != nil {
+		if lag := h.now().Sub(*event.EventAt); lag >= 0 {
+			webhookReceiptLag.Observe(lag.Seconds(), event.Provider)
+		}
+	}
+	if err := h.storeAndEnqueue(c.Request.Context(), event); err != nil {
+		logging.Logger.Error("Failed to enqueue webhook task", zap.String("provider", event.Provider), zap.Error(err))
+	}
+	h.trackAnalyticsEvent(event)
+	c.JSON(http.StatusOK, gin.H{"status": "received"})
+}
+
+func (h *WebhookHandler) storeAndEnqueue(ctx context.Context, event *ParsedWebhook) error {
+	if err := h.queries.InsertWebhookEvent(ctx, generated.InsertWebhookEventParams{
+		Provider:        event.Provider,
+		EventType:       event.EventType,
+		EventID:         event.EventID,
+		Payload:         event.Payload,
+		ProviderEventAt: event.EventAt,
 	}); err != nil {
-		_ = err
+		_ = err // idempotent insert — ignore duplicate errors
 	}
 
-	// Enqueue background processing task (same pattern as Stripe).
 	taskPayload, _ := json.Marshal(map[string]string{
-		"provider":   "google",
-		"event_type": eventType,
-		"event_id":   eventID,
+		"provider":   event.Provider,
+		"event_type": event.EventType,
+		"event_id":   event.EventID,
 	})
 	if _, err := h.asynqClient.Enqueue(asynq.NewTask(tasks.TypeProcessWebhook, taskPayload)); err != nil {
-		logging.Logger.Error("Failed to enqueue Google webhook task", zap.Error(err))
+		return fmt.Errorf("failed to enqueue webhook task: %w", err)
 	}
+	return nil
+}
 
-	c.JSON(http.StatusOK, gin.H{"status": "received"})
+// trackAnalyticsEvent submits the delivery for analytics ingestion. It never
+// fails the webhook: a full buffer only loses the analytics event.
+func (h *WebhookHandler) trackAnalyticsEvent(event *ParsedWebhook) {
+	if h.analytics == nil {
+		return
+	}
+	now := h.now()
+	occurredAt := now
+	if event.EventAt != nil {
+		occurredAt = *event.EventAt
+	}
+	err := h.analytics.Submit(entity.AnalyticsEvent{
+		Source: entity.AnalyticsSourceWebhook,
+		Name:   "webhook." + event.Provider + "." + strings.ToLower(event.EventType),
+		Properties: map[string]any{
+			"provider":   event.Provider,
+			"event_type": event.EventType,
+			"event_id":   event.EventID,
+		},
+		OccurredAt: occurredAt,
+		ReceivedAt: now,
+	})
+	if err != nil {
+		logging.Logger.Warn("Failed to record webhook analytics event", zap.String("provider", event.Provider), zap.Error(err))
+	}
 }
 
-// verifyStripeHMAC verifies Stripe webhook signature
-func (h *WebhookHandler) verifyStripeHMAC(body []byte, signature string) bool {
+// rejectMalformed quarantines an authenticated delivery that failed to parse
+// and acknowledges it. Without a quarantine store it is rejected as before; if
+// the store write fails the provider gets a 500 and retries the delivery.
+func (h *WebhookHandler) rejectMalformed(c *gin.Context, provider string, rawBody []byte, err error) {
+	var malformed *malformedWebhookError
+	if !errors.As(err, &malformed) {
+		response.InternalError(c, "Failed to parse webhook")
+		return
+	}
+	if h.quarantine == nil {
+		response.BadRequest(c, malformed.message)
+		return
+	}
+
+	id, qErr := h.quarantine.QuarantineWebhook(c.Request.Context(), provider, rawBody, malformed.Error())
+	if qErr != nil {
+		logging.Logger.Error("Failed to quarantine malformed webhook", zap.String("provider", provider), zap.Error(qErr))
+		response.InternalError(c, "Failed to store webhook")
+		return
+	}
+
+	webhookQuarantined.Inc(provider)
+	logging.Logger.Warn("Quarantined malformed webhook",
+		zap.String("provider", provider),
+		zap.String("quarantine_id", id.String()),
+		zap.Error(malformed.err),
+	)
+	c.JSON(http.StatusOK, gin.H{"status": "quarantined", "quarantine_id": id})
+}
+
+// stripeSignatureError describes why a Stripe-Signature header was rejected
+type stripeSignatureError struct {
+	reason  string // metric label
+	message string // client-facing
+}
+
+// verifyStripeSignature checks a "t=<unix>,v1=<hex>[,v1=<hex>...]" header:
+// the timestamp must be within the tolerance window and at least one v1
+// signature must match HMAC-SHA256(secret, "<t>.<body>"). Stripe sends several
+// v1 entries while a signing secret is being rolled.
+func (h *WebhookHandler) verifyStripeSignature(body []byte, header string) *stripeSignatureError {
 	if h.stripeWebhookSecret == "" {
 		// Skip verification in development
-		return true
+		return nil
 	}
 
-	// Stripe signature format: t=timestamp,v1=hmac
-	parts := strings.Split(signature, ",")
-	if len(parts) != 2 {
-		return false
+	var timestamp string
+	var signatures [][]byte
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			if sig, err := hex.DecodeString(value); err == nil {
+				signatures = append(signatures, sig)
+			}
+		}
+	}
+	if timestamp == "" || len(signatures) == 0 {
+		return &stripeSignatureError{reason: "malformed", message: "Invalid signature"}
 	}
 
-	timestamp := strings.TrimPrefix(parts[0], "t=")
-	v1 := strings.TrimPrefix(parts[1], "v1=")
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return &stripeSignatureError{reason: "malformed", message: "Invalid signature"}
+	}
+	if age := h.now().Sub(time.Unix(ts, 0)); age > h.stripeTolerance || age < -h.stripeTolerance {
+		return &stripeSignatureError{reason: "timestamp_out_of_tolerance", message: "Signature timestamp outside tolerance"}
+	}
 
-	// Create expected signature
-	payload := []byte(timestamp + "." + string(body))
 	mac := hmac.New(sha256.New, []byte(h.stripeWebhookSecret))
-	mac.Write(payload)
-	expected := hex.EncodeToString(mac.Sum(nil))
-
-	return hmac.Equal([]byte(v1), []byte(expected))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	expected := mac.Sum(nil)
+
+	for _, sig := range signatures {
+		if hmac.Equal(sig, expected) {
+			return nil
+		}
+	}
+	return &stripeSignatureError{reason: "mismatch", message: "Invalid signature"}
 }
 
 // verifyIP checks if the client IP is in the allowed list
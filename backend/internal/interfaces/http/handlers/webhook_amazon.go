@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	iapext "github.com/bivex/paywall-iap/internal/infrastructure/external/iap"
+	"github.com/bivex/paywall-iap/internal/infrastructure/logging"
+	"github.com/bivex/paywall-iap/internal/infrastructure/webhookschema"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type amazonSNSVerifier interface {
+	Verify(ctx context.Context, msg *iapext.SNSMessage) error
+	Confirm(ctx context.Context, msg *iapext.SNSMessage) error
+}
+
+// WithAmazonNotifications verifies the SNS signature of every Amazon Appstore
+// real-time notification and confirms topic subscriptions with verifier. A
+// nil verifier disables verification and must only be used in development.
+func (h *WebhookHandler) WithAmazonNotifications(verifier amazonSNSVerifier) *WebhookHandler {
+	h.amazonSNS = verifier
+	return h
+}
+
+// AmazonWebhook handles Amazon Appstore real-time notifications delivered by SNS
+// @Summary Amazon Appstore webhook
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Router /webhook/amazon [post]
+func (h *WebhookHandler) AmazonWebhook(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		response.BadRequest(c, "Failed to read body")
+		return
+	}
+
+	// The envelope carries the signature, so a malformed one cannot be
+	// authenticated and is rejected rather than quarantined
+	msg, err := decodeSNSMessage(body)
+	if err != nil {
+		response.BadRequest(c, "Invalid SNS message")
+		return
+	}
+
+	if h.amazonSNS != nil {
+		if err := h.amazonSNS.Verify(c.Request.Context(), msg); err != nil {
+			webhookSignatureRejections.Inc("amazon", "invalid_signature")
+			logging.Logger.Warn("Rejected Amazon SNS message", zap.String("topic", msg.TopicArn), zap.Error(err))
+			response.Unauthorized(c, "Invalid SNS signature")
+			return
+		}
+	}
+
+	switch msg.Type {
+	case iapext.SNSTypeSubscriptionConfirmation:
+		if h.amazonSNS == nil {
+			logging.Logger.Warn("Amazon SNS subscription not confirmed: verification disabled", zap.String("topic", msg.TopicArn))
+			c.JSON(http.StatusOK, gin.H{"status": "ignored"})
+			return
+		}
+		if err := h.amazonSNS.Confirm(c.Request.Context(), msg); err != nil {
+			logging.Logger.Error("Failed to confirm Amazon SNS subscription", zap.String("topic", msg.TopicArn), zap.Error(err))
+			response.InternalError(c, "Failed to confirm subscription")
+			return
+		}
+		logging.Logger.Info("Confirmed Amazon SNS subscription", zap.String("topic", msg.TopicArn))
+		c.JSON(http.StatusOK, gin.H{"status": "confirmed"})
+		return
+	case iapext.SNSTypeUnsubscribeConfirmation:
+		logging.Logger.Warn("Amazon SNS topic unsubscribed", zap.String("topic", msg.TopicArn))
+		c.JSON(http.StatusOK, gin.H{"status": "received"})
+		return
+	}
+
+	event, err := parseAmazonNotification(msg)
+	if err != nil {
+		h.rejectMalformed(c, "amazon", body, err)
+		return
+	}
+
+	h.acceptEvent(c, event)
+}
+
+func decodeSNSMessage(body []byte) (*iapext.SNSMessage, error) {
+	if err := webhookschema.Validate(webhookschema.AmazonSNSMessage, body); err != nil {
+		return nil, err
+	}
+	var msg iapext.SNSMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// parseAmazonDelivery parses a raw SNS delivery body, for re-parsing
+// quarantined notifications
+func parseAmazonDelivery(body []byte) (*ParsedWebhook, error) {
+	msg, err := decodeSNSMessage(body)
+	if err != nil {
+		return nil, &malformedWebhookError{message: "Invalid SNS message", err: err}
+	}
+	if msg.Type != iapext.SNSTypeNotification {
+		return nil, &malformedWebhookError{message: "Invalid SNS message", err: errors.New("not a notification")}
+	}
+	return parseAmazonNotification(msg)
+}
+
+// parseAmazonNotification validates the RTN an SNS notification carries. The
+// stored payload is the RTN itself; the SNS MessageId identifies the event.
+func parseAmazonNotification(msg *iapext.SNSMessage) (*ParsedWebhook, error) {
+	rtnBytes := []byte(msg.Message)
+	if err := webhookschema.Validate(webhookschema.AmazonRTN, rtnBytes); err != nil {
+		return nil, &malformedWebhookError{message: "Failed to parse Amazon notification", err: err}
+	}
+	var rtn struct {
+		NotificationType string `json:"notificationType"`
+		Timestamp        int64  `json:"timestamp"` // unix milliseconds
+	}
+	if err := json.Unmarshal(rtnBytes, &rtn); err != nil {
+		return nil, &malformedWebhookError{message: "Failed to parse Amazon notification", err: err}
+	}
+
+	parsed := &ParsedWebhook{
+		Provider:  "amazon",
+		EventType: rtn.NotificationType,
+		EventID:   msg.MessageID,
+		Payload:   rtnBytes,
+	}
+	if rtn.Timestamp > 0 {
+		at := time.UnixMilli(rtn.Timestamp)
+		parsed.EventAt = &at
+	} else if at, err := time.Parse(time.RFC3339, msg.Timestamp); err == nil {
+		parsed.EventAt = &at
+	}
+	return parsed, nil
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	iapext "github.com/bivex/paywall-iap/internal/infrastructure/external/iap"
+	"github.com/bivex/paywall-iap/internal/infrastructure/logging"
+	"github.com/bivex/paywall-iap/internal/infrastructure/webhookschema"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// huaweiNotification is a Huawei IAP server notification (version 2)
+type huaweiNotification struct {
+	EventType         string `json:"eventType"` // ORDER | SUBSCRIPTION
+	NotifyTime        int64  `json:"notifyTime"`
+	OrderNotification *struct {
+		NotificationType int    `json:"notificationType"`
+		PurchaseToken    string `json:"purchaseToken"`
+	} `json:"orderNotification"`
+	SubNotification *struct {
+		StatusUpdateNotification string `json:"statusUpdateNotification"`
+		NotificationSignature    string `json:"notificationSignature"`
+		SignatureAlgorithm       string `json:"signatureAlgorithm"`
+	} `json:"subNotification"`
+}
+
+// WithHuaweiNotifications verifies the signature of Huawei subscription
+// notifications with the AppGallery IAP public key. ORDER notifications are
+// unsigned; the worker only acts on them after querying the Order service. A
+// nil key disables verification and must only be used in development.
+func (h *WebhookHandler) WithHuaweiNotifications(publicKey *rsa.PublicKey) *WebhookHandler {
+	h.huaweiKey = publicKey
+	h.huaweiEnabled = true
+	return h
+}
+
+// HuaweiWebhook handles Huawei AppGallery IAP server notifications
+// @Summary Huawei AppGallery webhook
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Router /webhook/huawei [post]
+func (h *WebhookHandler) HuaweiWebhook(c *gin.Context) {
+	if !h.huaweiEnabled {
+		response.NotFound(c, "Huawei notifications are not configured")
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		response.BadRequest(c, "Failed to read body")
+		return
+	}
+
+	var envelope huaweiNotification
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		response.BadRequest(c, "Invalid notification body")
+		return
+	}
+
+	// Only signed subscription notifications are authenticated; anything
+	// else that fails to parse is rejected rather than quarantined
+	authenticated := false
+	if sub := envelope.SubNotification; envelope.EventType == "SUBSCRIPTION" && sub != nil && h.huaweiKey != nil {
+		if err := iapext.VerifyHuaweiSignature(h.huaweiKey, sub.StatusUpdateNotification, sub.NotificationSignature, sub.SignatureAlgorithm); err != nil {
+			webhookSignatureRejections.Inc("huawei", "invalid_signature")
+			logging.Logger.Warn("Rejected Huawei notification", zap.Error(err))
+			response.Unauthorized(c, "Invalid notification signature")
+			return
+		}
+		authenticated = true
+	}
+
+	event, err := parseHuaweiNotification(body)
+	if err != nil {
+		var malformed *malformedWebhookError
+		if !authenticated && errors.As(err, &malformed) {
+			response.BadRequest(c, malformed.message)
+			return
+		}
+		h.rejectMalformed(c, "huawei", body, err)
+		return
+	}
+
+	h.acceptEvent(c, event)
+}
+
+// parseHuaweiNotification validates a Huawei notification. Huawei gives
+// notifications no ID, so the event ID is a digest of the body: a redelivery
+// is byte-identical and deduplicates. EventType is "<eventType>.<n>".
+func parseHuaweiNotification(body []byte) (*ParsedWebhook, error) {
+	if err := webhookschema.Validate(webhookschema.HuaweiNotification, body); err != nil {
+		return nil, &malformedWebhookError{message: "Invalid notification body", err: err}
+	}
+	var n huaweiNotification
+	if err := json.Unmarshal(body, &n); err != nil {
+		return nil, &malformedWebhookError{message: "Invalid notification body", err: err}
+	}
+
+	var notificationType int
+	switch {
+	case n.EventType == "ORDER" && n.OrderNotification != nil:
+		notificationType = n.OrderNotification.NotificationType
+	case n.EventType == "SUBSCRIPTION" && n.SubNotification != nil:
+		var status struct {
+			NotificationType *int   `json:"notificationType"`
+			PurchaseToken    string `json:"purchaseToken"`
+		}
+		if err := json.Unmarshal([]byte(n.SubNotification.StatusUpdateNotification), &status); err != nil {
+			return nil, &malformedWebhookError{message: "Invalid statusUpdateNotification", err: err}
+		}
+		if status.NotificationType == nil || status.PurchaseToken == "" {
+			return nil, &malformedWebhookError{message: "Invalid statusUpdateNotification", err: errors.New("notificationType and purchaseToken are required")}
+		}
+		notificationType = *status.NotificationType
+	default:
+		return nil, &malformedWebhookError{message: "Invalid notification body", err: fmt.Errorf("%s notification without its notification object", n.EventType)}
+	}
+
+	digest := sha256.Sum256(body)
+	parsed := &ParsedWebhook{
+		Provider:  "huawei",
+		EventType: fmt.Sprintf("%s.%d", n.EventType, notificationType),
+		EventID:   hex.EncodeToString(digest[:16]),
+		Payload:   body,
+	}
+	if n.NotifyTime > 0 {
+		at := time.UnixMilli(n.NotifyTime)
+		parsed.EventAt = &at
+	}
+	return parsed, nil
+}
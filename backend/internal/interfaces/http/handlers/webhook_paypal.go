@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/bivex/paywall-iap/internal/infrastructure/external/paypal"
+	"github.com/bivex/paywall-iap/internal/infrastructure/logging"
+	"github.com/bivex/paywall-iap/internal/infrastructure/webhookschema"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/response"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type payPalWebhookVerifier interface {
+	Verify(ctx context.Context, t paypal.Transmission, body []byte) error
+}
+
+// WithPayPalVerification accepts PayPal Billing webhooks, verifying their
+// transmission signature with verifier. A nil verifier disables verification
+// and must only be used in development. PayPal webhooks are refused until
+// configured.
+func (h *WebhookHandler) WithPayPalVerification(verifier payPalWebhookVerifier) *WebhookHandler {
+	h.payPal = verifier
+	h.payPalEnabled = true
+	return h
+}
+
+// PayPalWebhook handles PayPal Billing subscription and sale webhooks
+// @Summary PayPal webhook
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Router /webhook/paypal [post]
+func (h *WebhookHandler) PayPalWebhook(c *gin.Context) {
+	if !h.payPalEnabled {
+		response.NotFound(c, "PayPal webhooks are not configured")
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		response.BadRequest(c, "Failed to read body")
+		return
+	}
+
+	if h.payPal != nil {
+		transmission := paypal.TransmissionFromHeaders(c.Request.Header)
+		if err := h.payPal.Verify(c.Request.Context(), transmission, body); err != nil {
+			reason := "invalid_signature"
+			if transmission.Sig == "" {
+				reason = "missing_signature"
+			}
+			webhookSignatureRejections.Inc("paypal", reason)
+			logging.Logger.Warn("Rejected PayPal webhook", zap.String("transmission_id", transmission.ID), zap.Error(err))
+			response.Unauthorized(c, "Invalid webhook signature")
+			return
+		}
+	}
+
+	event, err := parsePayPalEvent(body)
+	if err != nil {
+		h.rejectMalformed(c, "paypal", body, err)
+		return
+	}
+
+	h.acceptEvent(c, event)
+}
+
+// parsePayPalEvent validates a PayPal webhook event and extracts its ID and type
+func parsePayPalEvent(body []byte) (*ParsedWebhook, error) {
+	if err := webhookschema.Validate(webhookschema.PayPalEvent, body); err != nil {
+		return nil, &malformedWebhookError{message: "Invalid event body", err: err}
+	}
+	var event struct {
+		ID         string `json:"id"`
+		EventType  string `json:"event_type"`
+		CreateTime string `json:"create_time"`
+	}
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, &malformedWebhookError{message: "Invalid event body", err: err}
+	}
+	parsed := &ParsedWebhook{Provider: "paypal", EventType: event.EventType, EventID: event.ID, Payload: body}
+	if at, err := time.Parse(time.RFC3339, event.CreateTime); err == nil {
+		parsed.EventAt = &at
+	}
+	return parsed, nil
+}
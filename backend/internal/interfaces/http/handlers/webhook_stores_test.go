@@ -0,0 +1,160 @@
+package handlers_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	iapext "github.com/bivex/paywall-iap/internal/infrastructure/external/iap"
+	"github.com/bivex/paywall-iap/internal/infrastructure/external/paypal"
+	"github.com/bivex/paywall-iap/internal/infrastructure/metrics"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/handlers"
+)
+
+type stubSNSVerifier struct {
+	err       error
+	confirmed []string
+}
+
+func (s *stubSNSVerifier) Verify(ctx context.Context, msg *iapext.SNSMessage) error {
+	return s.err
+}
+
+func (s *stubSNSVerifier) Confirm(ctx context.Context, msg *iapext.SNSMessage) error {
+	s.confirmed = append(s.confirmed, msg.SubscribeURL)
+	return nil
+}
+
+func snsBody(t *testing.T, msgType, message string) string {
+	t.Helper()
+	body, err := json.Marshal(map[string]string{
+		"Type":         msgType,
+		"MessageId":    "m-1",
+		"TopicArn":     "arn:aws:sns:us-east-1:1:appstore-rtn",
+		"Message":      message,
+		"Timestamp":    "2024-01-01T00:00:00.000Z",
+		"SubscribeURL": "https://sns.us-east-1.amazonaws.com/?Action=ConfirmSubscription",
+	})
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestAmazonWebhook_VerifiesAndConfirms(t *testing.T) {
+	rejecting := handlers.NewWebhookHandler("", "", "", nil, nil).
+		WithAmazonNotifications(&stubSNSVerifier{err: iapext.ErrSNSSignature})
+	w := postWebhook(newWebhookRouter(rejecting), "/webhook/amazon", snsBody(t, "Notification", `{"receiptId":"r-1","notificationType":"SUBSCRIPTION_RENEWED"}`), nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, rec.Body.String(), `webhook_signature_rejections_total{provider="amazon",reason="invalid_signature"}`)
+
+	verifier := &stubSNSVerifier{}
+	h := handlers.NewWebhookHandler("", "", "", nil, nil).WithAmazonNotifications(verifier)
+	w = postWebhook(newWebhookRouter(h), "/webhook/amazon", snsBody(t, "SubscriptionConfirmation", "confirm"), nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"confirmed"`)
+	assert.Len(t, verifier.confirmed, 1)
+
+	// A verified envelope whose RTN is malformed is quarantined
+	store := &fakeQuarantineStore{bodies: map[string][]byte{}}
+	body := snsBody(t, "Notification", `{"notificationType":"SUBSCRIPTION_RENEWED"}`)
+	w = postWebhook(newWebhookRouter(h.WithQuarantine(store)), "/webhook/amazon", body, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"quarantined"`)
+	assert.Equal(t, body, string(store.bodies["amazon"]))
+}
+
+func huaweiSubscriptionBody(t *testing.T, key *rsa.PrivateKey, status string) string {
+	t.Helper()
+	digest := sha256.Sum256([]byte(status))
+	sig, err := rsa.SignPSS(rand.Reader, key, crypto.SHA256, digest[:], nil)
+	require.NoError(t, err)
+	body, err := json.Marshal(map[string]any{
+		"version":   "v2",
+		"eventType": "SUBSCRIPTION",
+		"subNotification": map[string]string{
+			"statusUpdateNotification": status,
+			"notificationSignature":    base64.StdEncoding.EncodeToString(sig),
+			"signatureAlgorithm":       "SHA256WithRSA/PSS",
+		},
+	})
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestHuaweiWebhook_AuthenticatesSubscriptionNotifications(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	unconfigured := handlers.NewWebhookHandler("", "", "", nil, nil)
+	assert.Equal(t, http.StatusNotFound, postWebhook(newWebhookRouter(unconfigured), "/webhook/huawei", `{}`, nil).Code)
+
+	store := &fakeQuarantineStore{bodies: map[string][]byte{}}
+	h := handlers.NewWebhookHandler("", "", "", nil, nil).
+		WithHuaweiNotifications(&key.PublicKey).
+		WithQuarantine(store)
+	r := newWebhookRouter(h)
+
+	forged := huaweiSubscriptionBody(t, other, `{"notificationType":2,"purchaseToken":"tok"}`)
+	assert.Equal(t, http.StatusUnauthorized, postWebhook(r, "/webhook/huawei", forged, nil).Code)
+
+	// Unsigned ORDER notifications are never quarantined
+	assert.Equal(t, http.StatusBadRequest, postWebhook(r, "/webhook/huawei", `{"eventType":"ORDER","orderNotification":{"notificationType":1}}`, nil).Code)
+	assert.Empty(t, store.bodies)
+
+	malformed := huaweiSubscriptionBody(t, key, `{"notificationType":2}`)
+	w := postWebhook(r, "/webhook/huawei", malformed, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"quarantined"`)
+	assert.Equal(t, malformed, string(store.bodies["huawei"]))
+}
+
+type stubPayPalVerifier struct {
+	err          error
+	transmission paypal.Transmission
+}
+
+func (s *stubPayPalVerifier) Verify(ctx context.Context, t paypal.Transmission, body []byte) error {
+	s.transmission = t
+	return s.err
+}
+
+func TestPayPalWebhook_VerifiesTransmission(t *testing.T) {
+	unconfigured := handlers.NewWebhookHandler("", "", "", nil, nil)
+	assert.Equal(t, http.StatusNotFound, postWebhook(newWebhookRouter(unconfigured), "/webhook/paypal", `{}`, nil).Code)
+
+	header := http.Header{}
+	header.Set(paypal.HeaderTransmissionID, "t-1")
+	header.Set(paypal.HeaderTransmissionSig, "c2ln")
+	rejecting := &stubPayPalVerifier{err: paypal.ErrSignature}
+	h := handlers.NewWebhookHandler("", "", "", nil, nil).WithPayPalVerification(rejecting)
+	w := postWebhook(newWebhookRouter(h), "/webhook/paypal", `{"id":"WH-1","event_type":"BILLING.SUBSCRIPTION.ACTIVATED","resource":{}}`, header)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "t-1", rejecting.transmission.ID)
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, rec.Body.String(), `webhook_signature_rejections_total{provider="paypal",reason="invalid_signature"}`)
+
+	// A verified delivery that is not a PayPal event is quarantined
+	store := &fakeQuarantineStore{bodies: map[string][]byte{}}
+	h = handlers.NewWebhookHandler("", "", "", nil, nil).
+		WithPayPalVerification(&stubPayPalVerifier{}).
+		WithQuarantine(store)
+	body := `{"id":"WH-2","resource":{}}`
+	w = postWebhook(newWebhookRouter(h), "/webhook/paypal", body, header)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"quarantined"`)
+	assert.Equal(t, body, string(store.bodies["paypal"]))
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func stripeSign(secret string, ts int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.%s", ts, body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyStripeSignature(t *testing.T) {
+	const secret = "whsec_test"
+	now := time.Unix(1_760_000_000, 0)
+	body := []byte(`{"id":"evt_1","type":"invoice.paid"}`)
+	valid := stripeSign(secret, now.Unix(), body)
+
+	tests := []struct {
+		name   string
+		header string
+		reason string
+	}{
+		{name: "valid", header: fmt.Sprintf("t=%d,v1=%s", now.Unix(), valid)},
+		{name: "valid among several v1 during secret roll", header: fmt.Sprintf("t=%d,v1=%s,v1=%s,v0=deadbeef", now.Unix(), stripeSign("whsec_old", now.Unix(), body), valid)},
+		{name: "within tolerance", header: fmt.Sprintf("t=%d,v1=%s", now.Add(-4*time.Minute).Unix(), stripeSign(secret, now.Add(-4*time.Minute).Unix(), body))},
+		{name: "replayed after tolerance", header: fmt.Sprintf("t=%d,v1=%s", now.Add(-6*time.Minute).Unix(), stripeSign(secret, now.Add(-6*time.Minute).Unix(), body)), reason: "timestamp_out_of_tolerance"},
+		{name: "timestamp in the future", header: fmt.Sprintf("t=%d,v1=%s", now.Add(time.Hour).Unix(), stripeSign(secret, now.Add(time.Hour).Unix(), body)), reason: "timestamp_out_of_tolerance"},
+		{name: "signature for other timestamp", header: fmt.Sprintf("t=%d,v1=%s", now.Unix()-1, valid), reason: "mismatch"},
+		{name: "no v1", header: fmt.Sprintf("t=%d,v0=%s", now.Unix(), valid), reason: "malformed"},
+		{name: "no timestamp", header: "v1=" + valid, reason: "malformed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewWebhookHandler(secret, "", "", nil, nil)
+			h.now = func() time.Time { return now }
+
+			err := h.verifyStripeSignature(body, tt.header)
+
+			if tt.reason == "" {
+				assert.Nil(t, err)
+				return
+			}
+			require.NotNil(t, err)
+			assert.Equal(t, tt.reason, err.reason)
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	iapext "github.com/bivex/paywall-iap/internal/infrastructure/external/iap"
+	"github.com/bivex/paywall-iap/internal/infrastructure/logging"
+	"github.com/bivex/paywall-iap/internal/infrastructure/metrics"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/handlers"
+)
+
+type stubAppleVerifier struct {
+	payload []byte
+	err     error
+}
+
+func (s stubAppleVerifier) Verify(ctx context.Context, token string) ([]byte, error) {
+	return s.payload, s.err
+}
+
+type stubGoogleVerifier struct {
+	err error
+}
+
+func (s stubGoogleVerifier) Verify(ctx context.Context, token string) (*iapext.GoogleOIDCClaims, error) {
+	return &iapext.GoogleOIDCClaims{}, s.err
+}
+
+func newWebhookRouter(h *handlers.WebhookHandler) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	logging.Logger = zap.NewNop()
+	r := gin.New()
+	r.POST("/webhook/apple", h.AppleWebhook)
+	r.POST("/webhook/google", h.GoogleWebhook)
+	r.POST("/webhook/stripe", h.StripeWebhook)
+	r.POST("/webhook/amazon", h.AmazonWebhook)
+	r.POST("/webhook/huawei", h.HuaweiWebhook)
+	r.POST("/webhook/paypal", h.PayPalWebhook)
+	return r
+}
+
+func postWebhook(r *gin.Engine, path, body string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.RemoteAddr = "54.187.174.169:443" // a Stripe webhook IP
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestAppleWebhook_SignatureFailures(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{name: "invalid signature", err: iapext.ErrAppleJWSInvalid, status: http.StatusUnauthorized},
+		{name: "revocation unavailable", err: iapext.ErrAppleRevocationUnavailable, status: http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := handlers.NewWebhookHandler("", "", "", nil, nil).
+				WithAppleVerification(stubAppleVerifier{err: tt.err}, "Production")
+
+			w := postWebhook(newWebhookRouter(h), "/webhook/apple", `{"signedPayload":"a.b.c"}`, nil)
+
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
+
+func TestAppleWebhook_RejectsUnacceptedEnvironment(t *testing.T) {
+	payload := []byte(`{"notificationType":"DID_RENEW","notificationUUID":"n-1","data":{"environment":"Sandbox"}}`)
+	h := handlers.NewWebhookHandler("", "", "", nil, nil).
+		WithAppleVerification(stubAppleVerifier{payload: payload}, "Production")
+
+	w := postWebhook(newWebhookRouter(h), "/webhook/apple", `{"signedPayload":"a.b.c"}`, nil)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Sandbox")
+}
+
+func TestGoogleWebhook_RequiresValidOIDCToken(t *testing.T) {
+	data := base64.StdEncoding.EncodeToString([]byte(`{"packageName":"com.example"}`))
+	body := `{"message":{"data":"` + data + `","messageId":"m-1"}}`
+
+	tests := []struct {
+		name   string
+		header http.Header
+		err    error
+	}{
+		{name: "missing header"},
+		{name: "not a bearer token", header: http.Header{"Authorization": {"Basic dXNlcjpwYXNz"}}},
+		{name: "rejected token", header: http.Header{"Authorization": {"Bearer token"}}, err: iapext.ErrGoogleOIDCInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := handlers.NewWebhookHandler("", "", "", nil, nil).
+				WithGooglePushAuthentication(stubGoogleVerifier{err: tt.err})
+
+			w := postWebhook(newWebhookRouter(h), "/webhook/google", body, tt.header)
+
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+		})
+	}
+}
+
+func TestStripeWebhook_RejectsStaleSignatureAndRecordsMetric(t *testing.T) {
+	h := handlers.NewWebhookHandler("whsec_test", "", "", nil, nil)
+	stale := time.Now().Add(-time.Hour).Unix()
+	header := http.Header{"Stripe-Signature": {fmt.Sprintf("t=%d,v1=%s", stale, strings.Repeat("0", 64))}}
+
+	w := postWebhook(newWebhookRouter(h), "/webhook/stripe", `{"id":"evt_1"}`, header)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, rec.Body.String(), `webhook_signature_rejections_total{provider="stripe",reason="timestamp_out_of_tolerance"}`)
+}
+
+type fakeQuarantineStore struct {
+	bodies  map[string][]byte
+	reasons []string
+	err     error
+}
+
+func (f *fakeQuarantineStore) QuarantineWebhook(ctx context.Context, provider string, rawBody []byte, reason string) (uuid.UUID, error) {
+	if f.err != nil {
+		return uuid.Nil, f.err
+	}
+	f.bodies[provider] = rawBody
+	f.reasons = append(f.reasons, reason)
+	return uuid.New(), nil
+}
+
+func TestWebhooks_QuarantineMalformedPayloads(t *testing.T) {
+	rtdn := base64.StdEncoding.EncodeToString([]byte(`{"packageName":"com.example","subscriptionNotification":{"notificationType":"renewed"}}`))
+
+	tests := []struct {
+		name     string
+		path     string
+		provider string
+		body     string
+		header   http.Header
+		handler  func(*handlers.WebhookHandler) *handlers.WebhookHandler
+	}{
+		{name: "stripe without type", path: "/webhook/stripe", provider: "stripe", body: `{"id":"evt_1","data":{"object":{}}}`},
+		{name: "stripe not JSON", path: "/webhook/stripe", provider: "stripe", body: `id=evt_1`},
+		{
+			name: "apple without notificationUUID", path: "/webhook/apple", provider: "apple", body: `{"signedPayload":"a.b.c"}`,
+			handler: func(h *handlers.WebhookHandler) *handlers.WebhookHandler {
+				return h.WithAppleVerification(stubAppleVerifier{payload: []byte(`{"notificationType":"DID_RENEW"}`)})
+			},
+		},
+		{
+			name: "google with string notificationType", path: "/webhook/google", provider: "google",
+			body:   `{"message":{"data":"` + rtdn + `","messageId":"m-1"}}`,
+			header: http.Header{"Authorization": {"Bearer token"}},
+			handler: func(h *handlers.WebhookHandler) *handlers.WebhookHandler {
+				return h.WithGooglePushAuthentication(stubGoogleVerifier{})
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			build := func() *handlers.WebhookHandler {
+				h := handlers.NewWebhookHandler("", "", "", nil, nil)
+				if tt.handler != nil {
+					h = tt.handler(h)
+				}
+				return h
+			}
+
+			rejected := postWebhook(newWebhookRouter(build()), tt.path, tt.body, tt.header)
+			assert.Equal(t, http.StatusBadRequest, rejected.Code)
+
+			store := &fakeQuarantineStore{bodies: map[string][]byte{}}
+			w := postWebhook(newWebhookRouter(build().WithQuarantine(store)), tt.path, tt.body, tt.header)
+			require.Equal(t, http.StatusOK, w.Code, "body=%s", w.Body.String())
+			assert.Contains(t, w.Body.String(), `"status":"quarantined"`)
+			assert.Equal(t, tt.body, string(store.bodies[tt.provider]))
+			require.Len(t, store.reasons, 1)
+
+			failing := &fakeQuarantineStore{err: errors.New("db down")}
+			w = postWebhook(newWebhookRouter(build().WithQuarantine(failing)), tt.path, tt.body, tt.header)
+			assert.Equal(t, http.StatusInternalServerError, w.Code)
+		})
+	}
+}
+
+func TestWebhooks_UnauthenticatedBodiesAreNotQuarantined(t *testing.T) {
+	store := &fakeQuarantineStore{bodies: map[string][]byte{}}
+	h := handlers.NewWebhookHandler("", "", "", nil, nil).
+		WithAppleVerification(stubAppleVerifier{err: iapext.ErrAppleJWSInvalid}, "Production").
+		WithQuarantine(store)
+	r := newWebhookRouter(h)
+
+	assert.Equal(t, http.StatusBadRequest, postWebhook(r, "/webhook/apple", `{"notificationType":"DID_RENEW"}`, nil).Code)
+	assert.Equal(t, http.StatusUnauthorized, postWebhook(r, "/webhook/apple", `{"signedPayload":"a.b.c"}`, nil).Code)
+	assert.Empty(t, store.bodies)
+}
+
+func TestReparseWebhook_ReportsStillMalformedPayload(t *testing.T) {
+	h := handlers.NewWebhookHandler("", "", "", nil, nil)
+
+	_, err := h.ReparseWebhook(context.Background(), "stripe", []byte(`{"id":"evt_1"}`))
+	assert.ErrorIs(t, err, handlers.ErrMalformedWebhook)
+	assert.Contains(t, err.Error(), "$.type: required")
+
+	_, err = h.ReparseWebhook(context.Background(), "paddle", []byte(`{}`))
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, handlers.ErrMalformedWebhook)
+}
@@ -13,6 +13,9 @@ import (
 type Meta struct {
 	RequestID string    `json:"request_id"`
 	Timestamp time.Time `json:"timestamp"`
+	// Impersonated is set when support made the request with an impersonation
+	// token, so clients can show a banner
+	Impersonated bool `json:"impersonated,omitempty"`
 }
 
 // SuccessResponse represents a successful API response
@@ -39,8 +42,9 @@ func Send(c *gin.Context, statusCode int, data interface{}) {
 	c.JSON(statusCode, SuccessResponse{
 		Data: data,
 		Meta: Meta{
-			RequestID: requestID,
-			Timestamp: time.Now(),
+			RequestID:    requestID,
+			Timestamp:    time.Now(),
+			Impersonated: c.GetString("impersonated_by") != "",
 		},
 	})
 }
@@ -71,8 +75,9 @@ func Error(c *gin.Context, statusCode int, errCode string, message string) {
 		Error:   errCode,
 		Message: message,
 		Meta: Meta{
-			RequestID: requestID,
-			Timestamp: time.Now(),
+			RequestID:    requestID,
+			Timestamp:    time.Now(),
+			Impersonated: c.GetString("impersonated_by") != "",
 		},
 	})
 }
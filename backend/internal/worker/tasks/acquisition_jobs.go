@@ -0,0 +1,46 @@
+package tasks
+
+import (
+	"context"
+
+	"github.com/hibiken/asynq"
+	"go.uber.org/zap"
+)
+
+const TypeResolveAcquisitionUsers = "attribution:resolve_acquisition_users"
+
+type acquisitionUserResolver interface {
+	ResolveUsers(ctx context.Context) (int64, error)
+}
+
+// AcquisitionJobHandler links MMP attributions to users who signed up after
+// the callback arrived
+type AcquisitionJobHandler struct {
+	acquisitions acquisitionUserResolver
+	logger       *zap.Logger
+}
+
+// NewAcquisitionJobHandler creates a new acquisition job handler
+func NewAcquisitionJobHandler(acquisitions acquisitionUserResolver, logger *zap.Logger) *AcquisitionJobHandler {
+	return &AcquisitionJobHandler{acquisitions: acquisitions, logger: logger}
+}
+
+// RegisterAcquisitionTasks registers acquisition task handlers with the server mux.
+func RegisterAcquisitionTasks(mux *asynq.ServeMux, h *AcquisitionJobHandler) {
+	mux.HandleFunc(TypeResolveAcquisitionUsers, h.HandleResolveAcquisitionUsers)
+}
+
+// RegisterAcquisitionScheduledTasks resolves pending attributions hourly
+func RegisterAcquisitionScheduledTasks(scheduler *asynq.Scheduler) error {
+	_, err := scheduler.Register("25 * * * *", asynq.NewTask(TypeResolveAcquisitionUsers, nil))
+	return err
+}
+
+// HandleResolveAcquisitionUsers links pending attributions to their users
+func (h *AcquisitionJobHandler) HandleResolveAcquisitionUsers(ctx context.Context, t *asynq.Task) error {
+	resolved, err := h.acquisitions.ResolveUsers(ctx)
+	if resolved > 0 {
+		h.logger.Info("Acquisition attributions linked to users", zap.Int64("resolved", resolved))
+	}
+	return err
+}
@@ -0,0 +1,35 @@
+package tasks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// analyticsInvalidator drops an app's cached analytics results (see
+// cache.QueryCache); uuid.Nil drops every app's
+type analyticsInvalidator interface {
+	Invalidate(ctx context.Context, appID uuid.UUID) error
+}
+
+// WithAnalyticsInvalidation invalidates cached revenue analytics whenever a
+// payment or refund reaches the ledger.
+func (h *TaskHandlers) WithAnalyticsInvalidation(inv analyticsInvalidator) *TaskHandlers {
+	h.analyticsCache = inv
+	return h
+}
+
+// invalidateAnalytics is best effort: cached results expire on their own, so
+// a failure is logged, never returned.
+func (h *TaskHandlers) invalidateAnalytics(ctx context.Context, appID uuid.UUID) {
+	if h.analyticsCache == nil {
+		return
+	}
+	if err := h.analyticsCache.Invalidate(ctx, appID); err != nil {
+		h.logger.Warn("failed to invalidate analytics cache",
+			zap.String("app_id", appID.String()),
+			zap.Error(err),
+		)
+	}
+}
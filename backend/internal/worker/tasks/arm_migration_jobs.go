@@ -0,0 +1,109 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+)
+
+const TypeMigrateArchivedArmAssignments = "bandit:arms:migrate_assignments"
+
+const defaultArmMigrationLimit = 500
+
+// MigrateArchivedArmAssignmentsPayload narrows a run to one archived arm;
+// an empty ArmID sweeps every arm
+type MigrateArchivedArmAssignmentsPayload struct {
+	ArmID string `json:"arm_id,omitempty"`
+	Limit int    `json:"limit"`
+}
+
+type archivedArmAssignmentMigrator interface {
+	MigrateArchivedArmAssignments(ctx context.Context, armID uuid.UUID, limit int) (service.ArmMigrationResult, error)
+}
+
+func RegisterArmMigrationTasks(mux *asynq.ServeMux, migrator archivedArmAssignmentMigrator, executor scheduledJobExecutor, logger *zap.Logger) {
+	mux.HandleFunc(TypeMigrateArchivedArmAssignments, newArmMigrationTaskHandler(migrator, executor, logger))
+}
+
+// RegisterArmMigrationScheduledTasks sweeps up users an arm-specific run
+// missed or failed to move
+func RegisterArmMigrationScheduledTasks(scheduler *asynq.Scheduler) error {
+	_, err := scheduler.Register("*/5 * * * *", asynq.NewTask(TypeMigrateArchivedArmAssignments, mustMarshalJSON(MigrateArchivedArmAssignmentsPayload{Limit: defaultArmMigrationLimit})))
+	return err
+}
+
+// NewMigrateArchivedArmAssignmentsTask builds the task enqueued when an arm is archived
+func NewMigrateArchivedArmAssignmentsTask(armID uuid.UUID) *asynq.Task {
+	return asynq.NewTask(TypeMigrateArchivedArmAssignments, mustMarshalJSON(MigrateArchivedArmAssignmentsPayload{
+		ArmID: armID.String(),
+		Limit: defaultArmMigrationLimit,
+	}))
+}
+
+func newArmMigrationTaskHandler(migrator archivedArmAssignmentMigrator, executor scheduledJobExecutor, logger *zap.Logger) func(context.Context, *asynq.Task) error {
+	return func(ctx context.Context, task *asynq.Task) error {
+		payload := MigrateArchivedArmAssignmentsPayload{Limit: defaultArmMigrationLimit}
+		if len(task.Payload()) > 0 {
+			if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+				logger.Warn("Failed to parse arm migration payload", zap.Error(err))
+				payload = MigrateArchivedArmAssignmentsPayload{Limit: defaultArmMigrationLimit}
+			}
+		}
+		if payload.Limit <= 0 {
+			payload.Limit = defaultArmMigrationLimit
+		}
+		armID := uuid.Nil
+		if payload.ArmID != "" {
+			parsed, err := uuid.Parse(payload.ArmID)
+			if err != nil {
+				// Retrying cannot fix a malformed payload
+				logger.Error("Invalid arm_id in arm migration payload", zap.String("arm_id", payload.ArmID))
+				return nil
+			}
+			armID = parsed
+		}
+
+		executed, err := executor.ExecuteScheduled(ctx, service.ScheduledAutomationJobSpec{
+			JobName: TypeMigrateArchivedArmAssignments,
+			Source:  "asynq_scheduler",
+			Window:  5 * time.Minute,
+		}, task.Payload(), func(ctx context.Context) (map[string]any, error) {
+			result, err := migrator.MigrateArchivedArmAssignments(ctx, armID, payload.Limit)
+			details := map[string]any{
+				"limit":      payload.Limit,
+				"scanned":    result.Scanned,
+				"reassigned": result.Reassigned,
+				"excluded":   result.Excluded,
+				"failed":     result.Failed,
+			}
+			if armID != uuid.Nil {
+				details["arm_id"] = armID.String()
+			}
+			if err != nil {
+				return details, err
+			}
+
+			logger.Info("Archived arm assignments migrated",
+				zap.Int("scanned", result.Scanned),
+				zap.Int("reassigned", result.Reassigned),
+				zap.Int("excluded", result.Excluded),
+				zap.Int("failed", result.Failed),
+			)
+			return details, nil
+		})
+		if err != nil {
+			logger.Error("Failed to migrate archived arm assignments", zap.Error(err))
+			return err
+		}
+		if !executed {
+			logger.Info("Skipping duplicate archived arm migration within scheduled window")
+		}
+		return nil
+	}
+}
@@ -0,0 +1,45 @@
+package tasks
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+)
+
+type fakeArmMigrator struct {
+	result    service.ArmMigrationResult
+	lastArmID uuid.UUID
+	lastLimit int
+}
+
+func (f *fakeArmMigrator) MigrateArchivedArmAssignments(_ context.Context, armID uuid.UUID, limit int) (service.ArmMigrationResult, error) {
+	f.lastArmID = armID
+	f.lastLimit = limit
+	return f.result, nil
+}
+
+func TestRegisterArmMigrationTasks_MigratesArchivedArm(t *testing.T) {
+	migrator := &fakeArmMigrator{result: service.ArmMigrationResult{Scanned: 3, Reassigned: 2, Excluded: 1}}
+	executor := &fakeBanditMaintenanceExecutor{}
+	mux := asynq.NewServeMux()
+	RegisterArmMigrationTasks(mux, migrator, executor, zap.NewNop())
+
+	armID := uuid.New()
+	require.NoError(t, mux.ProcessTask(context.Background(), NewMigrateArchivedArmAssignmentsTask(armID)))
+	assert.Equal(t, armID, migrator.lastArmID)
+	assert.Equal(t, defaultArmMigrationLimit, migrator.lastLimit)
+	assert.Equal(t, TypeMigrateArchivedArmAssignments, executor.lastSpec.JobName)
+	assert.Equal(t, 2, executor.lastDetails["reassigned"])
+	assert.Equal(t, 1, executor.lastDetails["excluded"])
+
+	// The scheduled sweep covers every archived arm
+	require.NoError(t, mux.ProcessTask(context.Background(), asynq.NewTask(TypeMigrateArchivedArmAssignments, nil)))
+	assert.Equal(t, uuid.Nil, migrator.lastArmID)
+}
@@ -0,0 +1,56 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/infrastructure/persistence/sqlc/generated"
+)
+
+// WithBanditConversions feeds purchases reported by store webhooks to bandit
+// experiments as delayed conversions
+func (h *TaskHandlers) WithBanditConversions(recorder service.ConversionRecorder) *TaskHandlers {
+	h.conversions = recorder
+	return h
+}
+
+// recordBanditConversion closes the user's pending bandit reward with a
+// purchase. The conversion is keyed by the purchase's transactions row, the
+// same ID client-reported purchases use, so a webhook and a client report of
+// one purchase close a single pending reward. transactionID is the row the
+// caller just created; uuid.Nil looks up the reconciled row. Attribution is
+// best-effort: failures are logged, never returned, since the purchase itself
+// is already booked.
+func (h *TaskHandlers) recordBanditConversion(ctx context.Context, provider string, appID uuid.UUID, providerTxID string, transactionID, userID uuid.UUID, amount float64, currency string) {
+	if h.conversions == nil || providerTxID == "" || amount <= 0 {
+		return
+	}
+	logger := h.logger.With(
+		zap.String("provider_tx_id", providerTxID),
+		zap.String("user_id", userID.String()),
+	)
+	if transactionID == uuid.Nil {
+		var err error
+		transactionID, err = h.queries.GetTransactionIDByProviderTxID(ctx, generated.GetTransactionIDByProviderTxIDParams{
+			AppID:        appID,
+			ProviderTxID: &providerTxID,
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Nothing was booked for this notification
+			return
+		}
+		if err != nil {
+			logger.Warn(provider+": failed to look up transaction for bandit conversion", zap.Error(err))
+			return
+		}
+	}
+	err := h.conversions.ProcessConversion(ctx, transactionID, userID, amount, currency)
+	if err != nil && !errors.Is(err, service.ErrDuplicateConversion) {
+		logger.Warn(provider+": failed to record bandit conversion", zap.Error(err))
+	}
+}
@@ -0,0 +1,62 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+)
+
+type recordedConversion struct {
+	transactionID uuid.UUID
+	userID        uuid.UUID
+	value         float64
+	currency      string
+}
+
+type fakeConversionRecorder struct {
+	calls []recordedConversion
+	err   error
+}
+
+func (f *fakeConversionRecorder) ProcessConversion(_ context.Context, transactionID, userID uuid.UUID, conversionValue float64, currency string) error {
+	f.calls = append(f.calls, recordedConversion{transactionID, userID, conversionValue, currency})
+	return f.err
+}
+
+func TestRecordBanditConversion(t *testing.T) {
+	recorder := &fakeConversionRecorder{}
+	h := (&TaskHandlers{logger: zap.NewNop()}).WithBanditConversions(recorder)
+	appID, userID, transactionID := uuid.New(), uuid.New(), uuid.New()
+
+	h.recordBanditConversion(context.Background(), "apple", appID, "2000000123", transactionID, userID, 9.99, "EUR")
+
+	if assert.Len(t, recorder.calls, 1) {
+		assert.Equal(t, recordedConversion{transactionID, userID, 9.99, "EUR"}, recorder.calls[0],
+			"keyed by the booked transaction, like client-reported purchases")
+	}
+
+	// Nothing to attribute
+	h.recordBanditConversion(context.Background(), "paypal", appID, "", transactionID, userID, 9.99, "USD")
+	h.recordBanditConversion(context.Background(), "paypal", appID, "SALE-1", transactionID, userID, 0, "USD")
+	assert.Len(t, recorder.calls, 1)
+
+	// Replays and failures never fail the webhook
+	recorder.err = service.ErrDuplicateConversion
+	h.recordBanditConversion(context.Background(), "paypal", appID, "SALE-1", uuid.New(), userID, 4.99, "USD")
+	recorder.err = errors.New("redis down")
+	h.recordBanditConversion(context.Background(), "paypal", appID, "SALE-2", uuid.New(), userID, 4.99, "USD")
+	assert.Len(t, recorder.calls, 3)
+}
+
+func TestRecordBanditConversion_NoRecorder(t *testing.T) {
+	h := &TaskHandlers{logger: zap.NewNop()}
+	assert.NotPanics(t, func() {
+		h.recordBanditConversion(context.Background(), "stripe", uuid.New(), "in_1", uuid.New(), uuid.New(), 10, "USD")
+	})
+}
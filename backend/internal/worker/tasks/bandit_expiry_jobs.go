@@ -0,0 +1,142 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/infrastructure/metrics"
+)
+
+const TypeExpirePendingRewards = "bandit:pending:expire_run"
+
+// Scheduled sweeps stop after this many pages and leave the rest to the next
+// one, so a backlog never holds a worker past the task timeout
+const defaultScheduledExpiryMaxBatches = 25
+
+var expiredRewardOutcomes = metrics.NewCounterVec(
+	"bandit_expired_rewards_total",
+	"Expired bandit pending rewards handled by expiry runs, by outcome",
+	"outcome",
+)
+
+var expiryRunBatches = metrics.NewCounterVec(
+	"bandit_expiry_run_batches_total",
+	"Pages of expired bandit pending rewards swept, by trigger",
+	"trigger",
+)
+
+// ExpirePendingRewardsPayload resumes RunID when set; otherwise a scheduled
+// run is started with the given pacing
+type ExpirePendingRewardsPayload struct {
+	RunID         string `json:"run_id,omitempty"`
+	BatchSize     int    `json:"batch_size,omitempty"`
+	RatePerSecond int    `json:"rate_per_second,omitempty"`
+	MaxBatches    int    `json:"max_batches,omitempty"`
+}
+
+type pendingRewardExpiryRunner interface {
+	Start(ctx context.Context, req service.PendingRewardExpiryRequest) (*service.PendingRewardExpiryRun, error)
+	Resume(ctx context.Context, runID uuid.UUID) (*service.PendingRewardExpiryRun, error)
+	Execute(ctx context.Context, run *service.PendingRewardExpiryRun, progress func(*service.PendingRewardExpiryRun, service.ExpiredRewardPage)) error
+}
+
+func RegisterPendingRewardExpiryTasks(mux *asynq.ServeMux, runner pendingRewardExpiryRunner, logger *zap.Logger) {
+	mux.HandleFunc(TypeExpirePendingRewards, newPendingRewardExpiryTaskHandler(runner, logger))
+}
+
+// RegisterPendingRewardExpiryScheduledTasks sweeps expired pending rewards
+// every 15 minutes
+func RegisterPendingRewardExpiryScheduledTasks(scheduler *asynq.Scheduler) error {
+	_, err := scheduler.Register("*/15 * * * *", asynq.NewTask(TypeExpirePendingRewards, mustMarshalJSON(ExpirePendingRewardsPayload{
+		BatchSize:     service.DefaultExpiryRunBatchSize,
+		RatePerSecond: service.DefaultExpiryRunRatePerSecond,
+		MaxBatches:    defaultScheduledExpiryMaxBatches,
+	})))
+	return err
+}
+
+// NewExpirePendingRewardsTask builds the task that executes a run started
+// elsewhere, such as from the admin API
+func NewExpirePendingRewardsTask(runID uuid.UUID) *asynq.Task {
+	return asynq.NewTask(TypeExpirePendingRewards, mustMarshalJSON(ExpirePendingRewardsPayload{RunID: runID.String()}))
+}
+
+func newPendingRewardExpiryTaskHandler(runner pendingRewardExpiryRunner, logger *zap.Logger) func(context.Context, *asynq.Task) error {
+	return func(ctx context.Context, task *asynq.Task) error {
+		var payload ExpirePendingRewardsPayload
+		if len(task.Payload()) > 0 {
+			if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+				logger.Warn("Failed to parse pending reward expiry payload", zap.Error(err))
+				payload = ExpirePendingRewardsPayload{}
+			}
+		}
+
+		var (
+			run *service.PendingRewardExpiryRun
+			err error
+		)
+		if payload.RunID != "" {
+			runID, parseErr := uuid.Parse(payload.RunID)
+			if parseErr != nil {
+				// Retrying cannot fix a malformed payload
+				logger.Error("Invalid run_id in pending reward expiry payload", zap.String("run_id", payload.RunID))
+				return nil
+			}
+			run, err = runner.Resume(ctx, runID)
+		} else {
+			if payload.BatchSize <= 0 {
+				payload.BatchSize = service.DefaultExpiryRunBatchSize
+			}
+			run, err = runner.Start(ctx, service.PendingRewardExpiryRequest{
+				Trigger:       service.ExpiryRunTriggerScheduled,
+				BatchSize:     payload.BatchSize,
+				RatePerSecond: payload.RatePerSecond,
+				MaxBatches:    payload.MaxBatches,
+			})
+		}
+		switch {
+		case errors.Is(err, service.ErrExpiryRunInProgress):
+			logger.Info("Skipping pending reward expiry while another run is in progress")
+			return nil
+		case errors.Is(err, service.ErrExpiryRunNotFound), errors.Is(err, service.ErrInvalidExpiryRun):
+			logger.Warn("Skipping pending reward expiry run", zap.Error(err))
+			return nil
+		case err != nil:
+			logger.Error("Failed to start pending reward expiry run", zap.Error(err))
+			return err
+		}
+
+		started := time.Now()
+		trigger := run.Trigger
+		err = runner.Execute(ctx, run, func(_ *service.PendingRewardExpiryRun, page service.ExpiredRewardPage) {
+			expiredRewardOutcomes.Add(float64(page.Processed), "processed")
+			expiredRewardOutcomes.Add(float64(page.Skipped), "skipped")
+			expiredRewardOutcomes.Add(float64(page.Failed), "failed")
+			if page.Fetched > 0 {
+				expiryRunBatches.Inc(trigger)
+			}
+		})
+		fields := []zap.Field{
+			zap.String("run_id", run.ID.String()),
+			zap.String("trigger", trigger),
+			zap.Int("batches", run.BatchesDone),
+			zap.Int("processed", run.Processed),
+			zap.Int("skipped", run.Skipped),
+			zap.Int("failed", run.Failed),
+			zap.Duration("elapsed", time.Since(started)),
+		}
+		if err != nil {
+			logger.Error("Pending reward expiry run failed; it resumes from its last checkpoint", append(fields, zap.Error(err))...)
+			return err
+		}
+		logger.Info("Pending reward expiry run completed", fields...)
+		return nil
+	}
+}
@@ -0,0 +1,81 @@
+package tasks
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+)
+
+type fakeExpiryRunner struct {
+	startReq  *service.PendingRewardExpiryRequest
+	startErr  error
+	resumedID uuid.UUID
+	page      service.ExpiredRewardPage
+	executed  bool
+}
+
+func (f *fakeExpiryRunner) Start(_ context.Context, req service.PendingRewardExpiryRequest) (*service.PendingRewardExpiryRun, error) {
+	f.startReq = &req
+	return &service.PendingRewardExpiryRun{ID: uuid.New(), Trigger: req.Trigger}, f.startErr
+}
+
+func (f *fakeExpiryRunner) Resume(_ context.Context, runID uuid.UUID) (*service.PendingRewardExpiryRun, error) {
+	f.resumedID = runID
+	return &service.PendingRewardExpiryRun{ID: runID, Trigger: service.ExpiryRunTriggerAdmin}, nil
+}
+
+func (f *fakeExpiryRunner) Execute(_ context.Context, run *service.PendingRewardExpiryRun, progress func(*service.PendingRewardExpiryRun, service.ExpiredRewardPage)) error {
+	f.executed = true
+	progress(run, f.page)
+	return nil
+}
+
+func TestPendingRewardExpiryTask_StartsScheduledRunAndCountsOutcomes(t *testing.T) {
+	runner := &fakeExpiryRunner{page: service.ExpiredRewardPage{Fetched: 4, Processed: 2, Skipped: 1, Failed: 1}}
+	mux := asynq.NewServeMux()
+	RegisterPendingRewardExpiryTasks(mux, runner, zap.NewNop())
+	processed := expiredRewardOutcomes.Value("processed")
+	skipped := expiredRewardOutcomes.Value("skipped")
+
+	err := mux.ProcessTask(context.Background(), asynq.NewTask(TypeExpirePendingRewards, mustMarshalJSON(ExpirePendingRewardsPayload{RatePerSecond: 20, MaxBatches: 3})))
+
+	require.NoError(t, err)
+	require.NotNil(t, runner.startReq)
+	assert.Equal(t, service.PendingRewardExpiryRequest{
+		Trigger:       service.ExpiryRunTriggerScheduled,
+		BatchSize:     service.DefaultExpiryRunBatchSize,
+		RatePerSecond: 20,
+		MaxBatches:    3,
+	}, *runner.startReq)
+	assert.True(t, runner.executed)
+	assert.Equal(t, processed+2, expiredRewardOutcomes.Value("processed"))
+	assert.Equal(t, skipped+1, expiredRewardOutcomes.Value("skipped"))
+}
+
+func TestPendingRewardExpiryTask_ResumesAdminRun(t *testing.T) {
+	runner := &fakeExpiryRunner{}
+	mux := asynq.NewServeMux()
+	RegisterPendingRewardExpiryTasks(mux, runner, zap.NewNop())
+	runID := uuid.New()
+
+	require.NoError(t, mux.ProcessTask(context.Background(), NewExpirePendingRewardsTask(runID)))
+	assert.Equal(t, runID, runner.resumedID)
+	assert.Nil(t, runner.startReq)
+	assert.True(t, runner.executed)
+}
+
+func TestPendingRewardExpiryTask_SkipsWhileAnotherRunIsLive(t *testing.T) {
+	runner := &fakeExpiryRunner{startErr: service.ErrExpiryRunInProgress}
+	mux := asynq.NewServeMux()
+	RegisterPendingRewardExpiryTasks(mux, runner, zap.NewNop())
+
+	require.NoError(t, mux.ProcessTask(context.Background(), asynq.NewTask(TypeExpirePendingRewards, nil)))
+	assert.False(t, runner.executed)
+}
@@ -252,9 +252,6 @@ func (j *BanditMaintenanceJobs) RunFullMaintenance(
 	}
 
 	tasks := make([]string, 0, 6)
-	if summary.ExpiredPendingRewards >= 0 {
-		tasks = append(tasks, "processed_expired_pending_rewards")
-	}
 	if summary.WindowsTrimmed > 0 || summary.WindowExperimentsScanned > 0 {
 		tasks = append(tasks, "trimmed_sliding_windows")
 	}
@@ -51,7 +51,7 @@ func (f *fakeBanditMaintenanceExecutor) ExecuteScheduled(ctx context.Context, sp
 }
 
 func TestRegisterBanditMaintenanceTasks_FullMaintenanceWritesStructuredSummary(t *testing.T) {
-	engine := &fakeBanditMaintenanceEngine{fullSummary: &service.BanditMaintenanceSummary{ExpiredPendingRewards: 2, WindowsTrimmed: 4, ObjectiveStatsSynced: 6}}
+	engine := &fakeBanditMaintenanceEngine{fullSummary: &service.BanditMaintenanceSummary{CurrencyRatesUpdated: true, WindowsTrimmed: 4, ObjectiveStatsSynced: 6}}
 	executor := &fakeBanditMaintenanceExecutor{}
 	mux := asynq.NewServeMux()
 	RegisterBanditMaintenanceTasks(mux, engine, executor, zap.NewNop())
@@ -61,7 +61,8 @@ func TestRegisterBanditMaintenanceTasks_FullMaintenanceWritesStructuredSummary(t
 	require.NoError(t, err)
 	assert.Equal(t, "bandit:maintenance:full", executor.lastSpec.JobName)
 	assert.Equal(t, 6*time.Hour, executor.lastSpec.Window)
-	assert.Equal(t, 2, executor.lastDetails["expired_pending_rewards"])
+	assert.Equal(t, true, executor.lastDetails["currency_rates_updated"])
+	assert.NotContains(t, executor.lastDetails, "expired_pending_rewards")
 	assert.Equal(t, 4, executor.lastDetails["windows_trimmed"])
 	assert.Equal(t, 6, executor.lastDetails["objective_stats_synced"])
 }
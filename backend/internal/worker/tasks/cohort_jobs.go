@@ -4,12 +4,14 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"time"
 
 	"github.com/hibiken/asynq"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 
+	"github.com/bivex/paywall-iap/internal/clock"
 	"github.com/bivex/paywall-iap/internal/infrastructure/logging"
 	matomoClient "github.com/bivex/paywall-iap/internal/infrastructure/external/matomo"
 )
@@ -24,6 +26,7 @@ type CohortWorker struct {
 	matomoClient *matomoClient.Client
 	analyticsRepo AnalyticsRepository
 	logger       *zap.Logger
+	now          func() time.Time
 }
 
 // AnalyticsRepository defines the interface for storing analytics data
@@ -53,9 +56,16 @@ func NewCohortWorker(
 		matomoClient: matomoClient,
 		analyticsRepo: analyticsRepo,
 		logger:       logging.Logger,
+		now:          time.Now,
 	}
 }
 
+// WithClock makes aggregate timestamps and cohort dates come from c
+func (w *CohortWorker) WithClock(c clock.Clock) *CohortWorker {
+	w.now = c.Now
+	return w
+}
+
 // CohortAggregationPayload represents the job payload
 type CohortAggregationPayload struct {
 	Date         string `json:"date"`         // YYYY-MM-DD
@@ -113,6 +123,7 @@ func (w *CohortWorker) HandleCohortAggregation(ctx context.Context, t *asynq.Tas
 	}
 
 	// Aggregate data for each cohort
+	now := w.now()
 	for _, cohort := range cohortData.Cohorts {
 		aggregate := &CohortAggregate{
 			ID:         uuid.New(),
@@ -121,8 +132,8 @@ func (w *CohortWorker) HandleCohortAggregation(ctx context.Context, t *asynq.Tas
 			CohortSize: cohort.SampleSize,
 			Retention:  make(map[string]int),
 			Revenue:    make(map[string]float64),
-			CreatedAt:  time.Now(),
-			UpdatedAt:  time.Now(),
+			CreatedAt:  now,
+			UpdatedAt:  now,
 		}
 
 		// Extract retention data
@@ -202,7 +213,7 @@ func RegisterCohortHandlers(mux *asynq.ServeMux, worker *CohortWorker) {
 // CalculateLTVFromCohorts calculates LTV estimates from cohort data
 func (w *CohortWorker) CalculateLTVFromCohorts(ctx context.Context, userID uuid.UUID) (map[string]float64, error) {
 	// Get user's cohort data (assuming user joined 30 days ago)
-	joinDate := time.Now().AddDate(0, 0, -30)
+	joinDate := w.now().AddDate(0, 0, -30)
 
 	// Fetch cohort aggregates for the past 30 days
 	metricName := "cohort_day_" + joinDate.Format("2006-01-02")
@@ -214,22 +225,36 @@ func (w *CohortWorker) CalculateLTVFromCohorts(ctx context.Context, userID uuid.
 	// Calculate LTV estimates
 	ltv := make(map[string]float64)
 
-	// LTV30 - average revenue from cohort over 30 days
+	// LTV30 - average revenue from cohort over 30 days. The *_stderr keys are
+	// the standard error of that average; 90 and 365 days are extrapolated from
+	// it, so their error scales with them.
 	if rev30, ok := cohortData.Revenue["day30"]; ok && cohortData.CohortSize > 0 {
-		ltv["ltv30"] = rev30 / float64(cohortData.CohortSize)
+		ltv30 := rev30 / float64(cohortData.CohortSize)
+		stderr := cohortLTVStdErr(rev30, cohortData.CohortSize, cohortData.Retention["day30"])
+		ltv["ltv30"] = ltv30
+		ltv["ltv30_stderr"] = stderr
+		ltv["ltv90"] = ltv30 * 3 // Simple 3x extrapolation
+		ltv["ltv90_stderr"] = stderr * 3
+		ltv["ltv365"] = ltv30 * 12 // Simple 12x extrapolation
+		ltv["ltv365_stderr"] = stderr * 12
 	}
 
-	// LTV90 - extrapolate from 30-day data
-	if rev30, ok := cohortData.Revenue["day30"]; ok {
-		ltv["ltv90"] = rev30 * 3 // Simple 3x extrapolation
-	}
+	return ltv, nil
+}
 
-	// LTV365 - extrapolate from 30-day data
-	if rev30, ok := cohortData.Revenue["day30"]; ok {
-		ltv["ltv365"] = rev30 * 12 // Simple 12x extrapolation
+// cohortLTVStdErr is the standard error of a cohort's average revenue per
+// user, treating the retained users as payers sharing the revenue equally
+func cohortLTVStdErr(revenue float64, cohortSize, retained int) float64 {
+	if cohortSize == 0 || retained <= 0 {
+		return 0
 	}
-
-	return ltv, nil
+	if retained > cohortSize {
+		retained = cohortSize
+	}
+	perPayer := revenue / float64(retained)
+	payerShare := float64(retained) / float64(cohortSize)
+	variance := perPayer * perPayer * payerShare * (1 - payerShare)
+	return math.Sqrt(variance / float64(cohortSize))
 }
 
 // GetCohortMetrics retrieves cohort metrics for a date range
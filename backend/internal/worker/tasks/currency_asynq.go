@@ -133,6 +133,8 @@ func RegisterBanditMaintenanceTasks(mux *asynq.ServeMux, advancedEngine banditMa
 		return nil
 	})
 
+	// A single unpaced batch, kept for manual enqueues; scheduled sweeps use
+	// TypeExpirePendingRewards
 	mux.HandleFunc("bandit:maintenance:process_expired", func(ctx context.Context, t *asynq.Task) error {
 		// Parse batch size from payload
 		var payload struct {
@@ -177,7 +179,6 @@ func banditMaintenanceSummaryDetails(summary *service.BanditMaintenanceSummary)
 	}
 	return map[string]any{
 		"maintenance":                   "full",
-		"expired_pending_rewards":       summary.ExpiredPendingRewards,
 		"currency_rates_updated":        summary.CurrencyRatesUpdated,
 		"window_experiments_scanned":    summary.WindowExperimentsScanned,
 		"windows_trimmed":               summary.WindowsTrimmed,
@@ -202,11 +203,7 @@ func RegisterBanditMaintenanceScheduledTasks(scheduler *asynq.Scheduler) error {
 		return err
 	}
 
-	// Process expired rewards every 15 minutes
-	_, err = scheduler.Register("*/15 * * * *", asynq.NewTask("bandit:maintenance:process_expired", nil))
-	if err != nil {
-		return err
-	}
+	// Expired pending rewards are swept by TypeExpirePendingRewards
 
 	return nil
 }
@@ -0,0 +1,49 @@
+package tasks
+
+import (
+	"context"
+
+	"github.com/hibiken/asynq"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+)
+
+const (
+	TypeRefreshDashboardViews = "analytics:refresh_dashboard_views"
+)
+
+type dashboardViewRefresher interface {
+	RefreshAll(ctx context.Context) ([]service.ViewRefresh, error)
+}
+
+// DashboardViewJobHandler refreshes the dashboard materialized views
+type DashboardViewJobHandler struct {
+	views  dashboardViewRefresher
+	logger *zap.Logger
+}
+
+// NewDashboardViewJobHandler creates a new dashboard view job handler
+func NewDashboardViewJobHandler(views dashboardViewRefresher, logger *zap.Logger) *DashboardViewJobHandler {
+	return &DashboardViewJobHandler{views: views, logger: logger}
+}
+
+// RegisterDashboardViewTasks registers dashboard view task handlers with the server mux.
+func RegisterDashboardViewTasks(mux *asynq.ServeMux, h *DashboardViewJobHandler) {
+	mux.HandleFunc(TypeRefreshDashboardViews, h.HandleRefreshDashboardViews)
+}
+
+// RegisterDashboardViewScheduledTasks refreshes the views every 15 minutes
+func RegisterDashboardViewScheduledTasks(scheduler *asynq.Scheduler) error {
+	_, err := scheduler.Register("*/15 * * * *", asynq.NewTask(TypeRefreshDashboardViews, nil))
+	return err
+}
+
+// HandleRefreshDashboardViews refreshes every dashboard view
+func (h *DashboardViewJobHandler) HandleRefreshDashboardViews(ctx context.Context, t *asynq.Task) error {
+	refreshes, err := h.views.RefreshAll(ctx)
+	for _, r := range refreshes {
+		h.logger.Info("Dashboard view refreshed", zap.String("view", r.View), zap.Duration("duration", r.Duration))
+	}
+	return err
+}
@@ -9,8 +9,10 @@ import (
 	"github.com/hibiken/asynq"
 	"go.uber.org/zap"
 
+	"github.com/bivex/paywall-iap/internal/domain/entity"
 	"github.com/bivex/paywall-iap/internal/domain/service"
 	"github.com/bivex/paywall-iap/internal/infrastructure/logging"
+	"github.com/bivex/paywall-iap/internal/infrastructure/persistence/sqlc/generated"
 )
 
 const (
@@ -86,6 +88,70 @@ func (h *DunningJobHandler) HandleCheckPendingDunning(ctx context.Context, t *as
 	return nil
 }
 
+// dunningTracker opens and closes dunning from store notifications
+// (see service.DunningService)
+type dunningTracker interface {
+	StartCampaign(ctx context.Context, start service.DunningStart) (*entity.Dunning, error)
+	RecordOutcome(ctx context.Context, subscriptionID uuid.UUID, recovered bool) (*entity.Dunning, error)
+}
+
+// WithDunning starts a dunning campaign when a store reports a failed renewal
+// and records its outcome when the subscription recovers or is lost.
+func (h *TaskHandlers) WithDunning(tracker dunningTracker) *TaskHandlers {
+	h.dunning = tracker
+	return h
+}
+
+// startDunning opens dunning for a subscription the store put in grace or on
+// hold. Failures are logged: the status update must still go ahead.
+func (h *TaskHandlers) startDunning(ctx context.Context, sub generated.Subscription, reason entity.DunningReason) {
+	if h.dunning == nil {
+		return
+	}
+	d, err := h.dunning.StartCampaign(ctx, service.DunningStart{
+		AppID:          sub.AppID,
+		SubscriptionID: sub.ID,
+		UserID:         sub.UserID,
+		Platform:       sub.Platform,
+		ProductID:      sub.ProductID,
+		Reason:         reason,
+	})
+	if err != nil {
+		h.logger.Warn("failed to start dunning",
+			zap.String("subscription_id", sub.ID.String()),
+			zap.Error(err),
+		)
+		return
+	}
+	h.logger.Info("dunning started",
+		zap.String("subscription_id", sub.ID.String()),
+		zap.String("dunning_id", d.ID.String()),
+		zap.String("variant", d.Variant),
+	)
+}
+
+// closeDunning records whether the subscription's payment recovered.
+// Failures are logged, never returned.
+func (h *TaskHandlers) closeDunning(ctx context.Context, subscriptionID uuid.UUID, recovered bool) {
+	if h.dunning == nil {
+		return
+	}
+	d, err := h.dunning.RecordOutcome(ctx, subscriptionID, recovered)
+	if err != nil {
+		h.logger.Warn("failed to record dunning outcome",
+			zap.String("subscription_id", subscriptionID.String()),
+			zap.Error(err),
+		)
+		return
+	}
+	if d != nil {
+		h.logger.Info("dunning closed",
+			zap.String("dunning_id", d.ID.String()),
+			zap.String("status", string(d.Status)),
+		)
+	}
+}
+
 // ScheduleDunningJobs schedules recurring dunning jobs
 func ScheduleDunningJobs(scheduler *asynq.Scheduler) error {
 	// Check for pending dunning every hour
@@ -28,12 +28,14 @@ func RegisterExperimentAutomationTasks(mux *asynq.ServeMux, reconciler *service.
 				zap.Int("started", len(result.Started)),
 				zap.Int("completed", len(result.Completed)),
 				zap.Int("skipped", result.Skipped),
+				zap.Int("guardrail_stopped", len(result.GuardrailStopped)),
 			)
 
 			return map[string]any{
-				"started":   len(result.Started),
-				"completed": len(result.Completed),
-				"skipped":   result.Skipped,
+				"started":           len(result.Started),
+				"completed":         len(result.Completed),
+				"skipped":           result.Skipped,
+				"guardrail_stopped": len(result.GuardrailStopped),
 			}, nil
 		})
 		if err != nil {
@@ -0,0 +1,40 @@
+package tasks
+
+import (
+	"context"
+
+	"github.com/hibiken/asynq"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+)
+
+const (
+	TypeRecordExperimentMeta = "analytics:experiment_meta"
+)
+
+// ExperimentMetaJobHandler handles experiment meta-analytics jobs
+type ExperimentMetaJobHandler struct {
+	metaService *service.ExperimentMetaAnalyticsService
+}
+
+// NewExperimentMetaJobHandler creates a new experiment meta-analytics job handler
+func NewExperimentMetaJobHandler(metaService *service.ExperimentMetaAnalyticsService) *ExperimentMetaJobHandler {
+	return &ExperimentMetaJobHandler{metaService: metaService}
+}
+
+// RegisterExperimentMetaTasks registers experiment meta-analytics task handlers with the server mux.
+func RegisterExperimentMetaTasks(mux *asynq.ServeMux, h *ExperimentMetaJobHandler) {
+	mux.HandleFunc(TypeRecordExperimentMeta, h.HandleRecordExperimentMeta)
+}
+
+// RegisterExperimentMetaScheduledTasks records experiment outcomes once a day
+func RegisterExperimentMetaScheduledTasks(scheduler *asynq.Scheduler) error {
+	_, err := scheduler.Register("20 0 * * *", asynq.NewTask(TypeRecordExperimentMeta, nil))
+	return err
+}
+
+// HandleRecordExperimentMeta records completed experiment outcomes and the day's aggregates
+func (h *ExperimentMetaJobHandler) HandleRecordExperimentMeta(ctx context.Context, t *asynq.Task) error {
+	_, err := h.metaService.RecordDaily(ctx)
+	return err
+}
@@ -0,0 +1,40 @@
+package tasks
+
+import (
+	"context"
+
+	"github.com/hibiken/asynq"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+)
+
+const (
+	TypeRecordExperimentTimeline = "analytics:experiment_timeline"
+)
+
+// ExperimentTimelineJobHandler handles experiment timeline jobs
+type ExperimentTimelineJobHandler struct {
+	timelineService *service.ExperimentTimelineService
+}
+
+// NewExperimentTimelineJobHandler creates a new experiment timeline job handler
+func NewExperimentTimelineJobHandler(timelineService *service.ExperimentTimelineService) *ExperimentTimelineJobHandler {
+	return &ExperimentTimelineJobHandler{timelineService: timelineService}
+}
+
+// RegisterExperimentTimelineTasks registers experiment timeline task handlers with the server mux.
+func RegisterExperimentTimelineTasks(mux *asynq.ServeMux, h *ExperimentTimelineJobHandler) {
+	mux.HandleFunc(TypeRecordExperimentTimeline, h.HandleRecordExperimentTimeline)
+}
+
+// RegisterExperimentTimelineScheduledTasks records the previous day just after midnight UTC
+func RegisterExperimentTimelineScheduledTasks(scheduler *asynq.Scheduler) error {
+	_, err := scheduler.Register("25 0 * * *", asynq.NewTask(TypeRecordExperimentTimeline, nil))
+	return err
+}
+
+// HandleRecordExperimentTimeline materializes yesterday's per-arm series of running experiments
+func (h *ExperimentTimelineJobHandler) HandleRecordExperimentTimeline(ctx context.Context, t *asynq.Task) error {
+	_, err := h.timelineService.RecordDaily(ctx)
+	return err
+}
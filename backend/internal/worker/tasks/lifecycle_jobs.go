@@ -0,0 +1,41 @@
+package tasks
+
+import (
+	"context"
+
+	"github.com/hibiken/asynq"
+)
+
+const TypeRecomputeLifecycleStages = "users:recompute_lifecycle_stages"
+
+type lifecycleRecomputer interface {
+	RecomputeAll(ctx context.Context) (int, int, error)
+}
+
+// LifecycleJobHandler recomputes users' lifecycle stages
+type LifecycleJobHandler struct {
+	lifecycle lifecycleRecomputer
+}
+
+// NewLifecycleJobHandler creates a new lifecycle job handler
+func NewLifecycleJobHandler(lifecycle lifecycleRecomputer) *LifecycleJobHandler {
+	return &LifecycleJobHandler{lifecycle: lifecycle}
+}
+
+// RegisterLifecycleTasks registers lifecycle task handlers with the server mux.
+func RegisterLifecycleTasks(mux *asynq.ServeMux, h *LifecycleJobHandler) {
+	mux.HandleFunc(TypeRecomputeLifecycleStages, h.HandleRecomputeLifecycleStages)
+}
+
+// RegisterLifecycleScheduledTasks recomputes stages nightly, after the
+// subscription snapshot
+func RegisterLifecycleScheduledTasks(scheduler *asynq.Scheduler) error {
+	_, err := scheduler.Register("40 0 * * *", asynq.NewTask(TypeRecomputeLifecycleStages, nil))
+	return err
+}
+
+// HandleRecomputeLifecycleStages classifies every user into a lifecycle stage
+func (h *LifecycleJobHandler) HandleRecomputeLifecycleStages(ctx context.Context, t *asynq.Task) error {
+	_, _, err := h.lifecycle.RecomputeAll(ctx)
+	return err
+}
@@ -0,0 +1,42 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// lifetimeEntitlementRevoker ends lifetime unlocks whose purchase was
+// refunded or revoked (see service.LifetimeEntitlementService)
+type lifetimeEntitlementRevoker interface {
+	RevokeByTransaction(ctx context.Context, platform, txID, reason string) (int, error)
+}
+
+// WithLifetimeEntitlements revokes lifetime unlocks on store refunds.
+func (h *TaskHandlers) WithLifetimeEntitlements(revoker lifetimeEntitlementRevoker) *TaskHandlers {
+	h.lifetimeEntitlements = revoker
+	return h
+}
+
+// revokeLifetimeEntitlements revokes the unlocks bought with txID. Lifetime
+// purchases have no subscription, so this runs before the subscription
+// lookup; for subscription transactions it revokes nothing.
+func (h *TaskHandlers) revokeLifetimeEntitlements(ctx context.Context, platform, txID, reason string) error {
+	if h.lifetimeEntitlements == nil || txID == "" {
+		return nil
+	}
+	revoked, err := h.lifetimeEntitlements.RevokeByTransaction(ctx, platform, txID, reason)
+	if err != nil {
+		return fmt.Errorf("revoke lifetime entitlements: %w", err)
+	}
+	if revoked > 0 {
+		h.logger.Info("lifetime entitlements revoked",
+			zap.String("platform", platform),
+			zap.String("tx_id", txID),
+			zap.String("reason", reason),
+			zap.Int("count", revoked),
+		)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package tasks
+
+import (
+	"context"
+
+	"github.com/hibiken/asynq"
+	"go.uber.org/zap"
+)
+
+const (
+	TypeFlushMeteringUsage = "metering:flush_usage"
+)
+
+const (
+	// flushMeteringBatch bounds how many counters one upsert batch saves
+	flushMeteringBatch = 500
+	// flushMeteringRounds bounds how many batches one run saves; the rest
+	// wait for the next run
+	flushMeteringRounds = 20
+)
+
+type meteringFlusher interface {
+	FlushUsage(ctx context.Context, batch int) (int, error)
+}
+
+// MeteringJobHandler persists soft paywall meters from Redis to Postgres
+type MeteringJobHandler struct {
+	service meteringFlusher
+	logger  *zap.Logger
+}
+
+// NewMeteringJobHandler creates a new metering job handler
+func NewMeteringJobHandler(service meteringFlusher, logger *zap.Logger) *MeteringJobHandler {
+	return &MeteringJobHandler{service: service, logger: logger}
+}
+
+// RegisterMeteringTasks registers metering task handlers with the server mux.
+func RegisterMeteringTasks(mux *asynq.ServeMux, h *MeteringJobHandler) {
+	mux.HandleFunc(TypeFlushMeteringUsage, h.HandleFlushMeteringUsage)
+}
+
+// RegisterMeteringScheduledTasks flushes changed meters every 5 minutes
+func RegisterMeteringScheduledTasks(scheduler *asynq.Scheduler) error {
+	_, err := scheduler.Register("*/5 * * * *", asynq.NewTask(TypeFlushMeteringUsage, nil))
+	return err
+}
+
+// HandleFlushMeteringUsage saves the counters changed since the last run
+func (h *MeteringJobHandler) HandleFlushMeteringUsage(ctx context.Context, t *asynq.Task) error {
+	total := 0
+	for round := 0; round < flushMeteringRounds; round++ {
+		flushed, err := h.service.FlushUsage(ctx, flushMeteringBatch)
+		total += flushed
+		if err != nil {
+			return err
+		}
+		if flushed < flushMeteringBatch {
+			break
+		}
+	}
+	if total > 0 {
+		h.logger.Info("Metering usage flushed", zap.Int("counters", total))
+	}
+	return nil
+}
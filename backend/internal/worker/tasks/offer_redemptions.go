@@ -0,0 +1,75 @@
+package tasks
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/infrastructure/persistence/sqlc/generated"
+)
+
+// offerRedemptionRecorder stores the offers store notifications report
+// (see service.OfferService)
+type offerRedemptionRecorder interface {
+	RecordRedemption(ctx context.Context, redemption *entity.OfferRedemption) error
+}
+
+// WithOfferRedemptions records the offers App Store transactions redeemed,
+// so offer code batches and win-back offers can be reported by campaign.
+func (h *TaskHandlers) WithOfferRedemptions(recorder offerRedemptionRecorder) *TaskHandlers {
+	h.offerRedemptions = recorder
+	return h
+}
+
+// appleTransactionOffer is the offer a signed transaction redeemed
+type appleTransactionOffer struct {
+	OfferType         int    `json:"offerType"`
+	OfferIdentifier   string `json:"offerIdentifier"`
+	OfferDiscountType string `json:"offerDiscountType"`
+}
+
+// offerType maps Apple's offerType: 1 introductory, 2 promotional, 3 offer
+// code, 4 win-back. offerIdentifier is the offer code reference name, or the
+// promotional or win-back offer ID.
+// https://developer.apple.com/documentation/appstoreserverapi/offertype
+func (o appleTransactionOffer) offerType() (entity.OfferType, bool) {
+	switch o.OfferType {
+	case 1:
+		if o.OfferDiscountType == "FREE_TRIAL" {
+			return entity.OfferTypeFreeTrial, true
+		}
+		return entity.OfferTypeIntroductory, true
+	case 2:
+		return entity.OfferTypePromotional, true
+	case 3:
+		return entity.OfferTypeOfferCode, true
+	case 4:
+		return entity.OfferTypeWinBack, true
+	}
+	return "", false
+}
+
+// recordAppleOfferRedemption stores the offer a purchase or renewal redeemed.
+// The redemption receipt verification recorded for the same transaction is
+// kept. Failures are logged: the notification is processed regardless.
+func (h *TaskHandlers) recordAppleOfferRedemption(ctx context.Context, sub generated.Subscription, offer appleTransactionOffer, rev appleTransactionRevenue) {
+	offerType, ok := offer.offerType()
+	if h.offerRedemptions == nil || !ok || rev.TransactionID == "" {
+		return
+	}
+	productID := rev.ProductID
+	if productID == "" {
+		productID = sub.ProductID
+	}
+	redemption := entity.NewOfferRedemption(sub.AppID, sub.UserID, productID, offerType, "ios")
+	redemption.OfferCode = offer.OfferIdentifier
+	redemption.ProviderTxID = rev.TransactionID
+	if err := h.offerRedemptions.RecordRedemption(ctx, redemption); err != nil {
+		h.logger.Warn("apple s2s: failed to record offer redemption",
+			zap.String("subscription_id", sub.ID.String()),
+			zap.String("offer_type", string(offerType)),
+			zap.Error(err),
+		)
+	}
+}
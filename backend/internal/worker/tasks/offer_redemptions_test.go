@@ -0,0 +1,47 @@
+package tasks
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+func TestAppleTransactionOfferType(t *testing.T) {
+	tests := []struct {
+		offer appleTransactionOffer
+		want  entity.OfferType
+	}{
+		{offer: appleTransactionOffer{OfferType: 1, OfferDiscountType: "FREE_TRIAL"}, want: entity.OfferTypeFreeTrial},
+		{offer: appleTransactionOffer{OfferType: 1, OfferDiscountType: "PAY_AS_YOU_GO"}, want: entity.OfferTypeIntroductory},
+		{offer: appleTransactionOffer{OfferType: 2, OfferIdentifier: "promo.annual"}, want: entity.OfferTypePromotional},
+		{offer: appleTransactionOffer{OfferType: 3, OfferIdentifier: "SPRING50"}, want: entity.OfferTypeOfferCode},
+		{offer: appleTransactionOffer{OfferType: 4, OfferIdentifier: "winback.annual"}, want: entity.OfferTypeWinBack},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.want), func(t *testing.T) {
+			got, ok := tt.offer.offerType()
+			assert.True(t, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, ok := appleTransactionOffer{}.offerType()
+	assert.False(t, ok, "a transaction without an offer redeems nothing")
+}
+
+func TestAppleTransactionOffer_DecodesWithRevenue(t *testing.T) {
+	var txInfo struct {
+		appleTransactionRevenue
+		appleTransactionOffer
+	}
+	payload := `{"transactionId":"2000000123","productId":"com.app.annual","price":29990,"offerType":3,"offerIdentifier":"SPRING50"}`
+	require.NoError(t, json.Unmarshal([]byte(payload), &txInfo))
+
+	assert.Equal(t, "2000000123", txInfo.TransactionID)
+	assert.Equal(t, 3, txInfo.OfferType)
+	assert.Equal(t, "SPRING50", txInfo.OfferIdentifier)
+}
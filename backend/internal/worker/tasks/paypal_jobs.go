@@ -0,0 +1,373 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	domainErrors "github.com/bivex/paywall-iap/internal/domain/errors"
+	"github.com/bivex/paywall-iap/internal/infrastructure/external/paypal"
+	"github.com/bivex/paywall-iap/internal/infrastructure/persistence/sqlc/generated"
+)
+
+// payPalStore resolves PayPal plans and subscription links
+type payPalStore interface {
+	GetPlan(ctx context.Context, planID string) (*entity.PayPalPlan, error)
+	GetSubscription(ctx context.Context, id string) (*entity.PayPalSubscription, error)
+	SaveSubscription(ctx context.Context, sub *entity.PayPalSubscription) error
+}
+
+// payPalSubscriptionFetcher reads the current state of a PayPal subscription
+type payPalSubscriptionFetcher interface {
+	GetSubscription(ctx context.Context, id string) (*paypal.Subscription, error)
+}
+
+// WithPayPal processes PayPal Billing webhooks. client, when set, is asked
+// for the next billing time after each payment; without it the period of the
+// mapped plan is used. Without a store PayPal events are ignored.
+func (h *TaskHandlers) WithPayPal(store payPalStore, client payPalSubscriptionFetcher) *TaskHandlers {
+	h.payPal = store
+	h.payPalClient = client
+	return h
+}
+
+// payPalSale is a PAYMENT.SALE resource, or the refund of one
+type payPalSale struct {
+	ID                 string      `json:"id"`
+	SaleID             string      `json:"sale_id"`
+	BillingAgreementID string      `json:"billing_agreement_id"`
+	Amount             payPalMoney `json:"amount"`
+	TransactionFee     payPalMoney `json:"transaction_fee"`
+	CreateTime         *time.Time  `json:"create_time"`
+}
+
+type payPalMoney struct {
+	Total    string `json:"total"`
+	Value    string `json:"value"`
+	Currency string `json:"currency"`
+}
+
+// amount returns the value of a v1 amount ("total") or money ("value") object
+func (m payPalMoney) amount() (float64, bool) {
+	raw := m.Total
+	if raw == "" {
+		raw = m.Value
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	return v, err == nil
+}
+
+// handlePayPalEvent processes a PayPal Billing webhook
+func (h *TaskHandlers) handlePayPalEvent(ctx context.Context, event generated.WebhookEvent) error {
+	if h.payPal == nil {
+		h.logger.Warn("paypal: not configured, event ignored", zap.String("event_id", event.EventID))
+		return nil
+	}
+	var body struct {
+		EventType string          `json:"event_type"`
+		Resource  json.RawMessage `json:"resource"`
+	}
+	if err := json.Unmarshal(event.Payload, &body); err != nil {
+		return fmt.Errorf("paypal: unmarshal payload: %w", err)
+	}
+
+	switch {
+	case strings.HasPrefix(body.EventType, "BILLING.SUBSCRIPTION."):
+		var sub paypal.Subscription
+		if err := json.Unmarshal(body.Resource, &sub); err != nil {
+			return fmt.Errorf("paypal: unmarshal subscription: %w", err)
+		}
+		return h.syncPayPalSubscription(ctx, body.EventType, &sub)
+	case body.EventType == "PAYMENT.SALE.COMPLETED":
+		var sale payPalSale
+		if err := json.Unmarshal(body.Resource, &sale); err != nil {
+			return fmt.Errorf("paypal: unmarshal sale: %w", err)
+		}
+		return h.recordPayPalSale(ctx, &sale)
+	case body.EventType == "PAYMENT.SALE.REFUNDED", body.EventType == "PAYMENT.SALE.REVERSED":
+		var refund payPalSale
+		if err := json.Unmarshal(body.Resource, &refund); err != nil {
+			return fmt.Errorf("paypal: unmarshal refund: %w", err)
+		}
+		return h.recordPayPalRefund(ctx, &refund)
+	default:
+		h.logger.Info("paypal: event type not handled", zap.String("event_type", body.EventType))
+		return nil
+	}
+}
+
+// syncPayPalSubscription provisions the subscription on activation and moves
+// it through the state machine on later lifecycle events
+func (h *TaskHandlers) syncPayPalSubscription(ctx context.Context, eventType string, res *paypal.Subscription) error {
+	event, ok := payPalSubscriptionEvent(eventType)
+	if !ok {
+		h.logger.Info("paypal: subscription event not handled", zap.String("event_type", eventType))
+		return nil
+	}
+
+	link, err := h.payPal.GetSubscription(ctx, res.ID)
+	if errors.Is(err, domainErrors.ErrNotFound) {
+		if event.Type.Status() != entity.StatusActive {
+			h.logger.Warn("paypal: event for a subscription never activated",
+				zap.String("event_type", eventType),
+				zap.String("paypal_subscription_id", res.ID),
+			)
+			return nil
+		}
+		return h.provisionPayPalSubscription(ctx, res)
+	}
+	if err != nil {
+		return fmt.Errorf("paypal: get subscription link: %w", err)
+	}
+
+	sub, err := h.queries.GetSubscriptionByID(ctx, link.SubscriptionID)
+	if err != nil {
+		return fmt.Errorf("paypal: get subscription %s: %w", link.SubscriptionID, err)
+	}
+	change, err := h.applySubscriptionEvent(ctx, sub, event)
+	if err != nil {
+		return err
+	}
+	if !change.Rejected {
+		h.followDunning(ctx, sub, event.Type)
+	}
+
+	if res.Status != "" {
+		link.Status = res.Status
+	}
+	if res.PlanID != "" {
+		link.PlanID = res.PlanID
+	}
+	if err := h.payPal.SaveSubscription(ctx, link); err != nil {
+		return fmt.Errorf("paypal: save subscription link: %w", err)
+	}
+	h.invalidateAnalytics(ctx, sub.AppID)
+	return nil
+}
+
+// provisionPayPalSubscription creates the subscription an activated PayPal
+// subscription pays for. The web paywall passes our user ID as custom_id.
+func (h *TaskHandlers) provisionPayPalSubscription(ctx context.Context, res *paypal.Subscription) error {
+	plan, err := h.payPal.GetPlan(ctx, res.PlanID)
+	if err != nil {
+		return fmt.Errorf("paypal: plan %q: %w", res.PlanID, err)
+	}
+	userID, err := uuid.Parse(res.CustomID)
+	if err != nil {
+		return fmt.Errorf("paypal: subscription %s: custom_id is not a user ID: %w", res.ID, err)
+	}
+	user, err := h.queries.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("paypal: find user %s: %w", userID, err)
+	}
+	if user.AppID != plan.AppID {
+		return fmt.Errorf("paypal: user %s does not belong to the app of plan %s", userID, plan.PlanID)
+	}
+
+	expiresAt := plan.PeriodEnd(time.Now())
+	if next := res.BillingInfo.NextBillingTime; next != nil {
+		expiresAt = *next
+	}
+
+	// A retried activation whose link was never saved finds the subscription
+	// it already created; reuse it rather than provisioning a second one
+	sub, err := h.queries.GetActiveSubscriptionByUserIDAndSource(ctx, generated.GetActiveSubscriptionByUserIDAndSourceParams{
+		AppID:  plan.AppID,
+		UserID: user.ID,
+		Source: string(entity.SourcePayPal),
+	})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("paypal: check active subscription: %w", err)
+	}
+	if err != nil || sub.ProductID != plan.ProductID {
+		sub, err = h.queries.CreateSubscription(ctx, generated.CreateSubscriptionParams{
+			AppID:     plan.AppID,
+			UserID:    user.ID,
+			Status:    string(entity.StatusActive),
+			Source:    string(entity.SourcePayPal),
+			Platform:  "web",
+			ProductID: plan.ProductID,
+			PlanType:  string(plan.PlanType),
+			ExpiresAt: expiresAt,
+			AutoRenew: true,
+		})
+		if err != nil {
+			return fmt.Errorf("paypal: create subscription: %w", err)
+		}
+	}
+
+	status := res.Status
+	if status == "" {
+		status = entity.PayPalStatusActive
+	}
+	if err := h.payPal.SaveSubscription(ctx, &entity.PayPalSubscription{
+		ID:             res.ID,
+		AppID:          plan.AppID,
+		UserID:         user.ID,
+		SubscriptionID: sub.ID,
+		PlanID:         plan.PlanID,
+		Status:         status,
+	}); err != nil {
+		return fmt.Errorf("paypal: save subscription link: %w", err)
+	}
+
+	h.logger.Info("paypal: subscription provisioned",
+		zap.String("user_id", user.ID.String()),
+		zap.String("paypal_subscription_id", res.ID),
+		zap.String("product_id", plan.ProductID),
+	)
+	h.invalidateAnalytics(ctx, plan.AppID)
+	return nil
+}
+
+// recordPayPalSale books a subscription payment and extends access to the
+// next billing time. A sale arriving before its subscription's activation
+// fails and is retried.
+func (h *TaskHandlers) recordPayPalSale(ctx context.Context, sale *payPalSale) error {
+	if sale.BillingAgreementID == "" {
+		h.logger.Info("paypal: sale without subscription, ignored", zap.String("sale_id", sale.ID))
+		return nil
+	}
+	link, err := h.payPal.GetSubscription(ctx, sale.BillingAgreementID)
+	if err != nil {
+		return fmt.Errorf("paypal: sale %s for subscription %s: %w", sale.ID, sale.BillingAgreementID, err)
+	}
+	sub, err := h.queries.GetSubscriptionByID(ctx, link.SubscriptionID)
+	if err != nil {
+		return fmt.Errorf("paypal: get subscription %s: %w", link.SubscriptionID, err)
+	}
+
+	gross, ok := sale.Amount.amount()
+	if !ok {
+		return fmt.Errorf("paypal: sale %s has no amount", sale.ID)
+	}
+	breakdown := h.feeSchedule.Breakdown("paypal", gross, 0, "")
+	if fee, ok := sale.TransactionFee.amount(); ok {
+		breakdown = h.feeSchedule.BreakdownWithFee(gross, 0, fee, "")
+	}
+	currency := strings.ToUpper(sale.Amount.Currency)
+	net := breakdown.Net
+	saleID := sale.ID
+
+	rows, err := h.queries.ReconcileTransactionRevenue(ctx, generated.ReconcileTransactionRevenueParams{
+		AppID:        sub.AppID,
+		ProviderTxID: &saleID,
+		Amount:       breakdown.Gross,
+		Currency:     currency,
+		StoreFee:     breakdown.StoreFee,
+		TaxAmount:    breakdown.Tax,
+		NetAmount:    &net,
+	})
+	if err != nil {
+		return fmt.Errorf("paypal: reconcile sale %s: %w", sale.ID, err)
+	}
+	var transactionID uuid.UUID
+	if rows == 0 {
+		created, err := h.queries.CreateTransaction(ctx, generated.CreateTransactionParams{
+			AppID:          sub.AppID,
+			UserID:         sub.UserID,
+			SubscriptionID: sub.ID,
+			Amount:         breakdown.Gross,
+			Currency:       currency,
+			Status:         "success",
+			ProviderTxID:   &saleID,
+			StoreFee:       breakdown.StoreFee,
+			TaxAmount:      breakdown.Tax,
+			NetAmount:      &net,
+			Provider:       entity.TransactionProviderPayPal,
+			ProductID:      &sub.ProductID,
+			Environment:    entity.TransactionEnvironmentProduction,
+		})
+		if err != nil {
+			return fmt.Errorf("paypal: record sale %s: %w", sale.ID, err)
+		}
+		transactionID = created.ID
+	}
+
+	renewal := entity.SubscriptionEvent{
+		Type:         entity.SubscriptionEventRenewed,
+		Provider:     entity.TransactionProviderPayPal,
+		ProviderType: "PAYMENT.SALE.COMPLETED",
+		ExpiresAt:    h.payPalPeriodEnd(ctx, link, sale),
+	}
+	if _, err := h.applySubscriptionEvent(ctx, sub, renewal); err != nil {
+		return err
+	}
+
+	h.logger.Info("paypal: sale recorded",
+		zap.String("sale_id", sale.ID),
+		zap.Float64("gross", breakdown.Gross),
+		zap.Float64("net", breakdown.Net),
+	)
+	h.recordBanditConversion(ctx, "paypal", sub.AppID, sale.ID, transactionID, sub.UserID, breakdown.Gross, currency)
+	h.closeDunning(ctx, sub.ID, true)
+	h.markWebhookSeen(ctx, sub.ID)
+	h.invalidateAnalytics(ctx, sub.AppID)
+	return nil
+}
+
+// payPalPeriodEnd returns when the period a sale paid for ends: PayPal's next
+// billing time when it can be fetched, else the mapped plan's period
+func (h *TaskHandlers) payPalPeriodEnd(ctx context.Context, link *entity.PayPalSubscription, sale *payPalSale) time.Time {
+	if h.payPalClient != nil {
+		res, err := h.payPalClient.GetSubscription(ctx, link.ID)
+		if err == nil && res.BillingInfo.NextBillingTime != nil {
+			return *res.BillingInfo.NextBillingTime
+		}
+		h.logger.Warn("paypal: next billing time unavailable, using plan period",
+			zap.String("paypal_subscription_id", link.ID),
+			zap.Error(err),
+		)
+	}
+	plan, err := h.payPal.GetPlan(ctx, link.PlanID)
+	if err != nil {
+		return time.Time{}
+	}
+	from := time.Now()
+	if sale.CreateTime != nil {
+		from = *sale.CreateTime
+	}
+	return plan.PeriodEnd(from)
+}
+
+// recordPayPalRefund marks the refunded or reversed sale as refunded
+func (h *TaskHandlers) recordPayPalRefund(ctx context.Context, refund *payPalSale) error {
+	saleID := refund.SaleID
+	if saleID == "" {
+		saleID = refund.ID
+	}
+	sub, err := h.queries.GetSubscriptionByProviderTxID(ctx, &saleID)
+	if err != nil {
+		h.logger.Warn("paypal: refund for an unknown sale", zap.String("sale_id", saleID), zap.Error(err))
+		return nil
+	}
+	refundedAt := time.Now()
+	if refund.CreateTime != nil {
+		refundedAt = *refund.CreateTime
+	}
+	reference := refund.ID
+	rows, err := h.queries.MarkTransactionRefunded(ctx, generated.MarkTransactionRefundedParams{
+		AppID:           sub.AppID,
+		ProviderTxID:    &saleID,
+		RefundedAt:      &refundedAt,
+		RefundReference: &reference,
+	})
+	if err != nil {
+		return fmt.Errorf("paypal: record refund: %w", err)
+	}
+	h.logger.Info("paypal: sale refunded",
+		zap.String("sale_id", saleID),
+		zap.Int64("transactions", rows),
+	)
+	h.invalidateAnalytics(ctx, sub.AppID)
+	return nil
+}
@@ -0,0 +1,48 @@
+package tasks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+func TestPayPalSubscriptionEvent(t *testing.T) {
+	tests := []struct {
+		eventType string
+		status    entity.SubscriptionStatus
+		dunning   entity.DunningReason
+	}{
+		{eventType: "BILLING.SUBSCRIPTION.ACTIVATED", status: entity.StatusActive},
+		{eventType: "BILLING.SUBSCRIPTION.RE-ACTIVATED", status: entity.StatusActive},
+		{eventType: "BILLING.SUBSCRIPTION.CANCELLED", status: entity.StatusCancelled},
+		{eventType: "BILLING.SUBSCRIPTION.SUSPENDED", status: entity.StatusCancelled, dunning: entity.DunningReasonOnHold},
+		{eventType: "BILLING.SUBSCRIPTION.PAYMENT.FAILED", status: entity.StatusGrace, dunning: entity.DunningReasonGrace},
+		{eventType: "BILLING.SUBSCRIPTION.EXPIRED", status: entity.StatusExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.eventType, func(t *testing.T) {
+			event, ok := payPalSubscriptionEvent(tt.eventType)
+			assert.True(t, ok)
+			assert.Equal(t, tt.status, event.Type.Status())
+			assert.Equal(t, tt.dunning, event.Type.DunningReason())
+		})
+	}
+
+	_, ok := payPalSubscriptionEvent("BILLING.SUBSCRIPTION.CREATED")
+	assert.False(t, ok, "created subscriptions are not paid for yet")
+}
+
+func TestPayPalMoneyAmount(t *testing.T) {
+	v, ok := payPalMoney{Total: "9.99", Currency: "USD"}.amount()
+	assert.True(t, ok)
+	assert.Equal(t, 9.99, v)
+
+	v, ok = payPalMoney{Value: "0.64"}.amount()
+	assert.True(t, ok)
+	assert.Equal(t, 0.64, v)
+
+	_, ok = payPalMoney{}.amount()
+	assert.False(t, ok)
+}
@@ -0,0 +1,106 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hibiken/asynq"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+const (
+	TypeExpirePendingPurchases = "purchases:expire_pending"
+)
+
+// expirePendingBatch bounds how many pending purchases one run expires
+const expirePendingBatch = 500
+
+type pendingPurchaseExpirer interface {
+	ExpireStale(ctx context.Context, limit int) (int, error)
+}
+
+// PendingPurchaseJobHandler handles deferred purchase jobs
+type PendingPurchaseJobHandler struct {
+	service pendingPurchaseExpirer
+	logger  *zap.Logger
+}
+
+// NewPendingPurchaseJobHandler creates a new pending purchase job handler
+func NewPendingPurchaseJobHandler(service pendingPurchaseExpirer, logger *zap.Logger) *PendingPurchaseJobHandler {
+	return &PendingPurchaseJobHandler{service: service, logger: logger}
+}
+
+// RegisterPendingPurchaseTasks registers pending purchase task handlers with the server mux.
+func RegisterPendingPurchaseTasks(mux *asynq.ServeMux, h *PendingPurchaseJobHandler) {
+	mux.HandleFunc(TypeExpirePendingPurchases, h.HandleExpirePendingPurchases)
+}
+
+// RegisterPendingPurchaseScheduledTasks expires unanswered pending purchases every 15 minutes
+func RegisterPendingPurchaseScheduledTasks(scheduler *asynq.Scheduler) error {
+	_, err := scheduler.Register("*/15 * * * *", asynq.NewTask(TypeExpirePendingPurchases, nil))
+	return err
+}
+
+// HandleExpirePendingPurchases expires pending purchases past their deferral window
+func (h *PendingPurchaseJobHandler) HandleExpirePendingPurchases(ctx context.Context, t *asynq.Task) error {
+	expired, err := h.service.ExpireStale(ctx, expirePendingBatch)
+	if err != nil {
+		return err
+	}
+	if expired > 0 {
+		h.logger.Info("Pending purchases expired", zap.Int("expired", expired))
+	}
+	return nil
+}
+
+// PurchaseResolutionPush sends a push notification for resolved pending
+// purchases that were reported with a device token
+type PurchaseResolutionPush struct {
+	client *asynq.Client
+}
+
+// NewPurchaseResolutionPush creates a push notifier enqueueing send:notification tasks
+func NewPurchaseResolutionPush(client *asynq.Client) *PurchaseResolutionPush {
+	return &PurchaseResolutionPush{client: client}
+}
+
+// NotifyPurchaseResolved enqueues the push; purchases without a device token are skipped
+func (p *PurchaseResolutionPush) NotifyPurchaseResolved(ctx context.Context, purchase *entity.PendingPurchase) error {
+	if purchase.DeviceToken == "" {
+		return nil
+	}
+	payload, err := json.Marshal(map[string]string{
+		"user_id":      purchase.UserID.String(),
+		"type":         "purchase_" + string(purchase.Status),
+		"title":        purchaseResolutionTitle(purchase.Status),
+		"body":         purchase.ProductID,
+		"device_token": purchase.DeviceToken,
+		"category":     string(entity.NotificationPurchase),
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := p.client.EnqueueContext(ctx, asynq.NewTask(TypeSendNotification, payload)); err != nil {
+		return fmt.Errorf("failed to enqueue purchase notification: %w", err)
+	}
+	return nil
+}
+
+func purchaseResolutionTitle(status entity.PendingPurchaseStatus) string {
+	switch status {
+	case entity.PendingPurchaseStatusCompleted:
+		return "Purchase approved"
+	case entity.PendingPurchaseStatusDeclined:
+		return "Purchase declined"
+	default:
+		return "Purchase request expired"
+	}
+}
+
+type pendingPurchaseResolver interface {
+	Resolve(ctx context.Context, match repository.PendingPurchaseMatch, status entity.PendingPurchaseStatus, providerTxID string) (*entity.PendingPurchase, error)
+}
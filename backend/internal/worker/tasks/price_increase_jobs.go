@@ -0,0 +1,169 @@
+package tasks
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/infrastructure/persistence/sqlc/generated"
+)
+
+const (
+	TypePromptPriceIncreaseConsent = "price_increase:prompt"
+)
+
+// PromptPriceIncreasePayload asks the pending subscribers of an app, of every
+// app when AppID is empty, to consent to a price increase
+type PromptPriceIncreasePayload struct {
+	AppID string `json:"app_id,omitempty"`
+	Title string `json:"title,omitempty"`
+	Body  string `json:"body,omitempty"`
+}
+
+type priceIncreasePrompter interface {
+	SendPrompts(ctx context.Context, appID uuid.UUID, prompt service.PriceIncreasePrompt) (service.PriceIncreasePromptResult, error)
+}
+
+// PriceIncreaseJobHandler handles price increase consent jobs
+type PriceIncreaseJobHandler struct {
+	service priceIncreasePrompter
+	logger  *zap.Logger
+}
+
+// NewPriceIncreaseJobHandler creates a new price increase job handler
+func NewPriceIncreaseJobHandler(service priceIncreasePrompter, logger *zap.Logger) *PriceIncreaseJobHandler {
+	return &PriceIncreaseJobHandler{service: service, logger: logger}
+}
+
+// RegisterPriceIncreaseTasks registers price increase task handlers with the server mux.
+func RegisterPriceIncreaseTasks(mux *asynq.ServeMux, h *PriceIncreaseJobHandler) {
+	mux.HandleFunc(TypePromptPriceIncreaseConsent, h.HandlePromptPriceIncreaseConsent)
+}
+
+// RegisterPriceIncreaseScheduledTasks prompts pending subscribers of every app daily
+func RegisterPriceIncreaseScheduledTasks(scheduler *asynq.Scheduler) error {
+	_, err := scheduler.Register("0 16 * * *", asynq.NewTask(TypePromptPriceIncreaseConsent, nil))
+	return err
+}
+
+// HandlePromptPriceIncreaseConsent prompts subscribers yet to consent to a
+// price increase. Those prompted recently are skipped, so running it again is
+// harmless.
+func (h *PriceIncreaseJobHandler) HandlePromptPriceIncreaseConsent(ctx context.Context, t *asynq.Task) error {
+	var p PromptPriceIncreasePayload
+	if len(t.Payload()) > 0 {
+		if err := json.Unmarshal(t.Payload(), &p); err != nil {
+			return fmt.Errorf("json.Unmarshal failed: %v", err)
+		}
+	}
+	appID := uuid.Nil
+	if p.AppID != "" {
+		var err error
+		if appID, err = uuid.Parse(p.AppID); err != nil {
+			return fmt.Errorf("invalid app_id: %v", err)
+		}
+	}
+
+	result, err := h.service.SendPrompts(ctx, appID, service.PriceIncreasePrompt{Title: p.Title, Body: p.Body})
+	if err != nil {
+		return err
+	}
+	if result.Sent > 0 || result.Failed > 0 {
+		h.logger.Info("Price increase consent prompts sent",
+			zap.String("app_id", p.AppID),
+			zap.Int("sent", result.Sent),
+			zap.Int("failed", result.Failed),
+		)
+	}
+	return nil
+}
+
+// priceIncreaseConsentTracker records the consent store notifications report
+// (see service.PriceIncreaseConsentService)
+type priceIncreaseConsentTracker interface {
+	RecordNotice(ctx context.Context, notice service.PriceIncreaseNotice) (*entity.PriceIncreaseConsent, error)
+}
+
+// WithPriceIncreaseConsents tracks subscribers' consent to App Store price
+// increases from PRICE_INCREASE and EXPIRED notifications.
+func (h *TaskHandlers) WithPriceIncreaseConsents(tracker priceIncreaseConsentTracker) *TaskHandlers {
+	h.priceIncreases = tracker
+	return h
+}
+
+// applePriceIncreaseStatus maps an App Store notification to the consent it
+// reports: PRICE_INCREASE is pending until its ACCEPTED subtype, and EXPIRED
+// with subtype PRICE_INCREASE ended without consent
+func applePriceIncreaseStatus(notifType, subtype string) (entity.PriceIncreaseConsentStatus, bool) {
+	switch {
+	case notifType == "PRICE_INCREASE" && subtype == "ACCEPTED":
+		return entity.PriceIncreaseAccepted, true
+	case notifType == "PRICE_INCREASE":
+		return entity.PriceIncreasePending, true
+	case notifType == "EXPIRED" && subtype == "PRICE_INCREASE":
+		return entity.PriceIncreaseDeclined, true
+	}
+	return "", false
+}
+
+// appleRenewalPrice decodes the renewal price from signedRenewalInfo. Apple
+// reports it in milliunits of the currency; nil when absent.
+func appleRenewalPrice(signedRenewalInfo string) (*float64, string) {
+	parts := strings.Split(signedRenewalInfo, ".")
+	if len(parts) != 3 {
+		return nil, ""
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ""
+	}
+	var info struct {
+		RenewalPrice *int64 `json:"renewalPrice"`
+		Currency     string `json:"currency"`
+	}
+	if err := json.Unmarshal(payload, &info); err != nil || info.RenewalPrice == nil {
+		return nil, ""
+	}
+	price := float64(*info.RenewalPrice) / 1000
+	return &price, strings.ToUpper(info.Currency)
+}
+
+// recordPriceIncrease stores the consent a notification reported. Failures
+// are logged: the status update must still go ahead.
+func (h *TaskHandlers) recordPriceIncrease(ctx context.Context, sub generated.Subscription, status entity.PriceIncreaseConsentStatus, signedRenewalInfo string) {
+	if h.priceIncreases == nil {
+		return
+	}
+	price, currency := appleRenewalPrice(signedRenewalInfo)
+	consent, err := h.priceIncreases.RecordNotice(ctx, service.PriceIncreaseNotice{
+		AppID:          sub.AppID,
+		SubscriptionID: sub.ID,
+		UserID:         sub.UserID,
+		Platform:       sub.Platform,
+		ProductID:      sub.ProductID,
+		Status:         status,
+		NewPrice:       price,
+		Currency:       currency,
+	})
+	if err != nil {
+		h.logger.Warn("failed to record price increase consent",
+			zap.String("subscription_id", sub.ID.String()),
+			zap.String("status", string(status)),
+			zap.Error(err),
+		)
+		return
+	}
+	h.logger.Info("price increase consent recorded",
+		zap.String("subscription_id", sub.ID.String()),
+		zap.String("consent_id", consent.ID.String()),
+		zap.String("status", string(consent.Status)),
+	)
+}
@@ -0,0 +1,48 @@
+package tasks
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+func TestApplePriceIncreaseStatus(t *testing.T) {
+	tests := []struct {
+		notifType, subtype string
+		want               entity.PriceIncreaseConsentStatus
+		ok                 bool
+	}{
+		{notifType: "PRICE_INCREASE", subtype: "PENDING", want: entity.PriceIncreasePending, ok: true},
+		{notifType: "PRICE_INCREASE", subtype: "ACCEPTED", want: entity.PriceIncreaseAccepted, ok: true},
+		{notifType: "EXPIRED", subtype: "PRICE_INCREASE", want: entity.PriceIncreaseDeclined, ok: true},
+		{notifType: "EXPIRED", subtype: "VOLUNTARY"},
+		{notifType: "DID_RENEW"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.notifType+"/"+tt.subtype, func(t *testing.T) {
+			got, ok := applePriceIncreaseStatus(tt.notifType, tt.subtype)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAppleRenewalPrice(t *testing.T) {
+	signed := func(payload string) string {
+		return "e30." + base64.RawURLEncoding.EncodeToString([]byte(payload)) + ".sig"
+	}
+
+	price, currency := appleRenewalPrice(signed(`{"renewalPrice":12990,"currency":"usd"}`))
+	require.NotNil(t, price)
+	assert.Equal(t, 12.99, *price)
+	assert.Equal(t, "USD", currency)
+
+	price, _ = appleRenewalPrice(signed(`{"autoRenewStatus":1}`))
+	assert.Nil(t, price)
+	price, _ = appleRenewalPrice("")
+	assert.Nil(t, price)
+}
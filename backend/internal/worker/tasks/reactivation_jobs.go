@@ -0,0 +1,40 @@
+package tasks
+
+import (
+	"context"
+
+	"github.com/hibiken/asynq"
+)
+
+const TypeDetectReactivations = "subscriptions:detect_reactivations"
+
+type reactivationDetector interface {
+	DetectReactivations(ctx context.Context) (int, error)
+}
+
+// ReactivationJobHandler records churned subscribers who resubscribed
+type ReactivationJobHandler struct {
+	reactivations reactivationDetector
+}
+
+// NewReactivationJobHandler creates a new reactivation job handler
+func NewReactivationJobHandler(reactivations reactivationDetector) *ReactivationJobHandler {
+	return &ReactivationJobHandler{reactivations: reactivations}
+}
+
+// RegisterReactivationTasks registers reactivation task handlers with the server mux.
+func RegisterReactivationTasks(mux *asynq.ServeMux, h *ReactivationJobHandler) {
+	mux.HandleFunc(TypeDetectReactivations, h.HandleDetectReactivations)
+}
+
+// RegisterReactivationScheduledTasks detects reactivations hourly
+func RegisterReactivationScheduledTasks(scheduler *asynq.Scheduler) error {
+	_, err := scheduler.Register("25 * * * *", asynq.NewTask(TypeDetectReactivations, nil))
+	return err
+}
+
+// HandleDetectReactivations records and attributes new reactivations
+func (h *ReactivationJobHandler) HandleDetectReactivations(ctx context.Context, t *asynq.Task) error {
+	_, err := h.reactivations.DetectReactivations(ctx)
+	return err
+}
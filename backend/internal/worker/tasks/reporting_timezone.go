@@ -0,0 +1,85 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bivex/paywall-iap/internal/appctx"
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+// reportingAppLister lists the apps whose daily analytics are computed,
+// with their reporting time zones (see repository.AppRepository)
+type reportingAppLister interface {
+	List(ctx context.Context) ([]*entity.App, error)
+	GetSettings(ctx context.Context, id uuid.UUID) (*entity.AppSettings, error)
+}
+
+// reportingApp is an app and the zone its business day is reported in
+type reportingApp struct {
+	id  uuid.UUID
+	loc *time.Location
+}
+
+// WithReportingApps computes daily analytics for every active app, each on
+// its own reporting day. Without it only the app in the task context is
+// computed, on UTC days.
+func (h *TaskHandlers) WithReportingApps(apps reportingAppLister) *TaskHandlers {
+	h.reportingApps = apps
+	return h
+}
+
+// analyticsApps resolves the apps a compute-analytics task covers: the one
+// named in the payload or context, else every active app.
+func (h *TaskHandlers) analyticsApps(ctx context.Context, rawAppID string) ([]reportingApp, error) {
+	appID, scoped := appctx.AppIDFromCtx(ctx)
+	if rawAppID != "" {
+		parsed, err := uuid.Parse(rawAppID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid app_id: %w", err)
+		}
+		appID, scoped = parsed, true
+	}
+	if h.reportingApps == nil {
+		return []reportingApp{{id: appID, loc: time.UTC}}, nil
+	}
+	if scoped {
+		settings, err := h.reportingApps.GetSettings(ctx, appID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load app settings: %w", err)
+		}
+		return []reportingApp{{id: appID, loc: settings.ReportingLocation()}}, nil
+	}
+
+	all, err := h.reportingApps.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list apps: %w", err)
+	}
+	apps := make([]reportingApp, 0, len(all))
+	for _, app := range all {
+		if !app.IsActive {
+			continue
+		}
+		settings, err := h.reportingApps.GetSettings(ctx, app.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load settings of app %s: %w", app.ID, err)
+		}
+		apps = append(apps, reportingApp{id: app.ID, loc: settings.ReportingLocation()})
+	}
+	return apps, nil
+}
+
+// reportingDayEnded reports whether the app's previous local day ended within
+// the last interval, so an hourly run computes each app once per local day
+func reportingDayEnded(now time.Time, loc *time.Location, interval time.Duration) bool {
+	start, _ := entity.ReportingDay(now, loc)
+	return now.Sub(start) < interval
+}
+
+// aggregateDate is the DATE value of the local day starting at start
+func aggregateDate(start time.Time) time.Time {
+	return time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+}
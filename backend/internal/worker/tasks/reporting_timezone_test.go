@@ -0,0 +1,34 @@
+package tasks
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportingDayEnded_OncePerLocalDay(t *testing.T) {
+	for _, zone := range []string{"UTC", "America/New_York", "Asia/Kolkata", "Australia/Adelaide"} {
+		loc, err := time.LoadLocation(zone)
+		require.NoError(t, err)
+
+		// Hourly runs over two days, including the end of US daylight time
+		due := 0
+		for run := time.Date(2026, 10, 31, 12, 0, 0, 0, time.UTC); run.Before(time.Date(2026, 11, 2, 12, 0, 0, 0, time.UTC)); run = run.Add(time.Hour) {
+			if reportingDayEnded(run, loc, time.Hour) {
+				due++
+			}
+		}
+		assert.Equal(t, 2, due, zone)
+	}
+}
+
+func TestAggregateDate_KeepsLocalCalendarDay(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+
+	start := time.Date(2026, 10, 14, 0, 0, 0, 0, tokyo)
+
+	assert.Equal(t, time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC), aggregateDate(start))
+}
@@ -0,0 +1,73 @@
+package tasks
+
+import (
+	"context"
+
+	"github.com/hibiken/asynq"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/infrastructure/metrics"
+)
+
+const TypeRetentionCleanup = "retention:cleanup"
+
+var retentionDeletedRows = metrics.NewCounterVec(
+	"retention_deleted_rows_total",
+	"Rows deleted by the data retention cleanup, by policy",
+	"policy",
+)
+
+var retentionRuns = metrics.NewCounterVec(
+	"retention_policy_runs_total",
+	"Data retention policy cleanups, by policy and outcome (complete, truncated or failed)",
+	"policy", "outcome",
+)
+
+type retentionRunner interface {
+	Run(ctx context.Context) ([]service.RetentionResult, error)
+}
+
+// RetentionJobHandler deletes rows past their retention
+type RetentionJobHandler struct {
+	retention retentionRunner
+	logger    *zap.Logger
+}
+
+// NewRetentionJobHandler creates a new retention job handler
+func NewRetentionJobHandler(retention retentionRunner, logger *zap.Logger) *RetentionJobHandler {
+	return &RetentionJobHandler{retention: retention, logger: logger}
+}
+
+// RegisterRetentionTasks registers retention task handlers with the server mux.
+func RegisterRetentionTasks(mux *asynq.ServeMux, h *RetentionJobHandler) {
+	mux.HandleFunc(TypeRetentionCleanup, h.HandleRetentionCleanup)
+}
+
+// RegisterRetentionScheduledTasks cleans up nightly, outside peak traffic
+func RegisterRetentionScheduledTasks(scheduler *asynq.Scheduler) error {
+	_, err := scheduler.Register("15 4 * * *", asynq.NewTask(TypeRetentionCleanup, nil))
+	return err
+}
+
+// HandleRetentionCleanup runs every retention policy. Policies that hit the
+// batch limit continue the next night.
+func (h *RetentionJobHandler) HandleRetentionCleanup(ctx context.Context, t *asynq.Task) error {
+	results, err := h.retention.Run(ctx)
+	for _, r := range results {
+		retentionDeletedRows.Add(float64(r.Deleted), r.Policy)
+		outcome := "complete"
+		switch {
+		case r.Err != nil:
+			outcome = "failed"
+		case r.Truncated:
+			outcome = "truncated"
+		}
+		retentionRuns.Inc(r.Policy, outcome)
+	}
+	if err != nil {
+		h.logger.Error("Retention cleanup failed", zap.Error(err))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hibiken/asynq"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+)
+
+type fakeRetentionRunner struct {
+	results []service.RetentionResult
+	err     error
+}
+
+func (f fakeRetentionRunner) Run(context.Context) ([]service.RetentionResult, error) {
+	return f.results, f.err
+}
+
+func TestHandleRetentionCleanup_CountsEveryPolicy(t *testing.T) {
+	failure := errors.New("statement timeout")
+	runner := fakeRetentionRunner{
+		results: []service.RetentionResult{
+			{Policy: "matomo_sent_events", Deleted: 120},
+			{Policy: "bandit_impression_events", Deleted: 5000, Truncated: true},
+			{Policy: "processed_webhook_events", Err: failure},
+		},
+		err: failure,
+	}
+	deleted := retentionDeletedRows.Value("matomo_sent_events")
+	complete := retentionRuns.Value("matomo_sent_events", "complete")
+	truncated := retentionRuns.Value("bandit_impression_events", "truncated")
+	failed := retentionRuns.Value("processed_webhook_events", "failed")
+
+	err := NewRetentionJobHandler(runner, zap.NewNop()).HandleRetentionCleanup(context.Background(), asynq.NewTask(TypeRetentionCleanup, nil))
+
+	assert.ErrorIs(t, err, failure)
+	assert.Equal(t, deleted+120, retentionDeletedRows.Value("matomo_sent_events"))
+	assert.Equal(t, complete+1, retentionRuns.Value("matomo_sent_events", "complete"))
+	assert.Equal(t, truncated+1, retentionRuns.Value("bandit_impression_events", "truncated"))
+	assert.Equal(t, failed+1, retentionRuns.Value("processed_webhook_events", "failed"))
+}
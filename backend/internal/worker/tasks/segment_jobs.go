@@ -0,0 +1,182 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+	"github.com/bivex/paywall-iap/internal/domain/service"
+)
+
+const (
+	TypeMaterializeSegments = "segment:materialize"
+	TypeNotifySegment       = "segment:notify"
+)
+
+// MaterializeSegmentsPayload selects one segment to materialize; when empty,
+// every materialized segment is refreshed
+type MaterializeSegmentsPayload struct {
+	AppID     string `json:"app_id,omitempty"`
+	SegmentID string `json:"segment_id,omitempty"`
+}
+
+// NotifySegmentPayload is a message sent to every member of a segment
+type NotifySegmentPayload struct {
+	AppID     string `json:"app_id"`
+	SegmentID string `json:"segment_id"`
+	Title     string `json:"title"`
+	Body      string `json:"body"`
+	// SendTimeExperimentID schedules each member's push at the hour the
+	// send-time experiment picks instead of sending it now
+	SendTimeExperimentID string `json:"send_time_experiment_id,omitempty"`
+}
+
+// SegmentJobHandler handles segment background jobs
+type SegmentJobHandler struct {
+	segmentService      *service.SegmentService
+	segmentRepo         repository.SegmentRepository
+	notificationService *service.NotificationService
+	sendTime            sendTimeScheduler
+	sendTimeQueue       taskQueue
+	logger              *zap.Logger
+}
+
+// NewSegmentJobHandler creates a new segment job handler
+func NewSegmentJobHandler(
+	segmentService *service.SegmentService,
+	segmentRepo repository.SegmentRepository,
+	notificationService *service.NotificationService,
+	logger *zap.Logger,
+) *SegmentJobHandler {
+	return &SegmentJobHandler{
+		segmentService:      segmentService,
+		segmentRepo:         segmentRepo,
+		notificationService: notificationService,
+		logger:              logger,
+	}
+}
+
+// WithSendTime lets segment notifications be scheduled by a send-time
+// experiment, queueing each push on queue
+func (h *SegmentJobHandler) WithSendTime(scheduler sendTimeScheduler, queue taskQueue) *SegmentJobHandler {
+	h.sendTime = scheduler
+	h.sendTimeQueue = queue
+	return h
+}
+
+// RegisterSegmentTasks registers segment task handlers with the server mux.
+func RegisterSegmentTasks(mux *asynq.ServeMux, h *SegmentJobHandler) {
+	mux.HandleFunc(TypeMaterializeSegments, h.HandleMaterializeSegments)
+	mux.HandleFunc(TypeNotifySegment, h.HandleNotifySegment)
+}
+
+// RegisterSegmentScheduledTasks refreshes materialized segments hourly
+func RegisterSegmentScheduledTasks(scheduler *asynq.Scheduler) error {
+	_, err := scheduler.Register("30 * * * *", asynq.NewTask(TypeMaterializeSegments, nil))
+	return err
+}
+
+// HandleMaterializeSegments recomputes segment membership
+func (h *SegmentJobHandler) HandleMaterializeSegments(ctx context.Context, t *asynq.Task) error {
+	var p MaterializeSegmentsPayload
+	if len(t.Payload()) > 0 {
+		if err := json.Unmarshal(t.Payload(), &p); err != nil {
+			return fmt.Errorf("json.Unmarshal failed: %v", err)
+		}
+	}
+
+	if p.SegmentID == "" {
+		refreshed, err := h.segmentService.MaterializeAll(ctx)
+		if err != nil {
+			return err
+		}
+		h.logger.Info("Materialized segments refreshed", zap.Int("segments", refreshed))
+		return nil
+	}
+
+	segment, err := h.loadSegment(ctx, p.AppID, p.SegmentID)
+	if err != nil {
+		return err
+	}
+	_, err = h.segmentService.Materialize(ctx, segment)
+	return err
+}
+
+// HandleNotifySegment sends a message to every member of a segment
+func (h *SegmentJobHandler) HandleNotifySegment(ctx context.Context, t *asynq.Task) error {
+	var p NotifySegmentPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("json.Unmarshal failed: %v", err)
+	}
+
+	segment, err := h.loadSegment(ctx, p.AppID, p.SegmentID)
+	if err != nil {
+		return err
+	}
+
+	var experimentID uuid.UUID
+	if p.SendTimeExperimentID != "" {
+		if experimentID, err = h.loadSendTimeExperiment(ctx, segment.AppID, p.SendTimeExperimentID); err != nil {
+			// Retrying cannot fix a bad experiment; sending now would skew it
+			h.logger.Error("Dropping send-time segment notification", zap.String("segment_id", segment.ID.String()), zap.Error(err))
+			return nil
+		}
+	}
+
+	failed := 0
+	sent, err := h.segmentService.ForEachMember(ctx, segment, func(user *entity.User) error {
+		var err error
+		if experimentID != uuid.Nil {
+			err = h.scheduleSendTime(ctx, segment.AppID, experimentID, user.ID, p)
+		} else {
+			err = h.notificationService.SendSegmentNotification(ctx, user, p.Title, p.Body)
+		}
+		if err != nil {
+			failed++
+			h.logger.Warn("Failed to notify segment member", zap.String("user_id", user.ID.String()), zap.Error(err))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	h.logger.Info("Segment notification sent",
+		zap.String("segment_id", segment.ID.String()),
+		zap.Int("recipients", sent),
+		zap.Int("failed", failed),
+	)
+	return nil
+}
+
+func (h *SegmentJobHandler) loadSendTimeExperiment(ctx context.Context, appID uuid.UUID, rawExperimentID string) (uuid.UUID, error) {
+	if h.sendTime == nil {
+		return uuid.Nil, fmt.Errorf("send-time experiments are not configured")
+	}
+	experimentID, err := uuid.Parse(rawExperimentID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid send_time_experiment_id: %v", err)
+	}
+	if _, err := h.sendTime.GetExperiment(ctx, appID, experimentID); err != nil {
+		return uuid.Nil, err
+	}
+	return experimentID, nil
+}
+
+func (h *SegmentJobHandler) loadSegment(ctx context.Context, rawAppID, rawSegmentID string) (*entity.Segment, error) {
+	appID, err := uuid.Parse(rawAppID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid app_id: %v", err)
+	}
+	segmentID, err := uuid.Parse(rawSegmentID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid segment_id: %v", err)
+	}
+	return h.segmentRepo.GetByID(ctx, appID, segmentID)
+}
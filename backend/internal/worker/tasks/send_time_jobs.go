@@ -0,0 +1,114 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/infrastructure/metrics"
+)
+
+const TypeDeliverSendTimeNotification = "notification:send_time:deliver"
+
+var sendTimePushes = metrics.NewCounterVec(
+	"notification_send_time_deliveries_total",
+	"Send-time experiment pushes, by outcome",
+	"outcome",
+)
+
+// SendTimeDeliveryPayload sends one scheduled send-time push
+type SendTimeDeliveryPayload struct {
+	DeliveryID string `json:"delivery_id"`
+	Title      string `json:"title"`
+	Body       string `json:"body"`
+}
+
+type sendTimeScheduler interface {
+	GetExperiment(ctx context.Context, appID, experimentID uuid.UUID) (*service.SendTimeExperiment, error)
+	Schedule(ctx context.Context, appID, experimentID, userID uuid.UUID) (*service.SendTimeDelivery, error)
+}
+
+type sendTimeDeliveries interface {
+	GetDelivery(ctx context.Context, deliveryID uuid.UUID) (*service.SendTimeDelivery, error)
+	MarkSent(ctx context.Context, deliveryID uuid.UUID) error
+}
+
+type sendTimeNotifier interface {
+	SendSendTimeNotification(ctx context.Context, userID, deliveryID uuid.UUID, title, body string) error
+}
+
+type taskQueue interface {
+	EnqueueContext(ctx context.Context, task *asynq.Task, opts ...asynq.Option) (*asynq.TaskInfo, error)
+}
+
+func RegisterSendTimeTasks(mux *asynq.ServeMux, deliveries sendTimeDeliveries, notifier sendTimeNotifier, logger *zap.Logger) {
+	mux.HandleFunc(TypeDeliverSendTimeNotification, newSendTimeDeliveryHandler(deliveries, notifier, logger))
+}
+
+// NewSendTimeDeliveryTask builds the push for a delivery; enqueue it with
+// asynq.ProcessAt(delivery.ScheduledFor)
+func NewSendTimeDeliveryTask(delivery *service.SendTimeDelivery, title, body string) *asynq.Task {
+	return asynq.NewTask(TypeDeliverSendTimeNotification, mustMarshalJSON(SendTimeDeliveryPayload{
+		DeliveryID: delivery.ID.String(),
+		Title:      title,
+		Body:       body,
+	}))
+}
+
+func newSendTimeDeliveryHandler(deliveries sendTimeDeliveries, notifier sendTimeNotifier, logger *zap.Logger) func(context.Context, *asynq.Task) error {
+	return func(ctx context.Context, task *asynq.Task) error {
+		var payload SendTimeDeliveryPayload
+		if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+			return fmt.Errorf("json.Unmarshal failed: %v", err)
+		}
+		deliveryID, err := uuid.Parse(payload.DeliveryID)
+		if err != nil {
+			// Retrying cannot fix a malformed payload
+			logger.Error("Invalid delivery_id in send-time payload", zap.String("delivery_id", payload.DeliveryID))
+			return nil
+		}
+
+		delivery, err := deliveries.GetDelivery(ctx, deliveryID)
+		if errors.Is(err, service.ErrSendTimeDeliveryNotFound) {
+			logger.Warn("Skipping send-time push for a deleted delivery", zap.String("delivery_id", payload.DeliveryID))
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if delivery.SentAt != nil {
+			return nil
+		}
+
+		if err := notifier.SendSendTimeNotification(ctx, delivery.UserID, delivery.ID, payload.Title, payload.Body); err != nil {
+			sendTimePushes.Inc("failed")
+			return fmt.Errorf("failed to send send-time push: %w", err)
+		}
+		if err := deliveries.MarkSent(ctx, delivery.ID); err != nil {
+			// The push went out; a retry would send it twice
+			logger.Warn("Failed to mark send-time delivery sent", zap.String("delivery_id", payload.DeliveryID), zap.Error(err))
+		}
+		sendTimePushes.Inc("sent")
+		return nil
+	}
+}
+
+// scheduleSendTime picks the member's send hour and queues the push for it
+func (h *SegmentJobHandler) scheduleSendTime(ctx context.Context, appID, experimentID, userID uuid.UUID, p NotifySegmentPayload) error {
+	delivery, err := h.sendTime.Schedule(ctx, appID, experimentID, userID)
+	if err != nil {
+		return err
+	}
+	task := NewSendTimeDeliveryTask(delivery, p.Title, p.Body)
+	if _, err := h.sendTimeQueue.EnqueueContext(ctx, task, asynq.ProcessAt(delivery.ScheduledFor)); err != nil {
+		return fmt.Errorf("failed to enqueue send-time push: %w", err)
+	}
+	sendTimePushes.Inc("scheduled")
+	return nil
+}
@@ -0,0 +1,72 @@
+package tasks
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+)
+
+type fakeSendTimeDeliveries struct {
+	delivery *service.SendTimeDelivery
+	sent     []uuid.UUID
+}
+
+func (f *fakeSendTimeDeliveries) GetDelivery(_ context.Context, deliveryID uuid.UUID) (*service.SendTimeDelivery, error) {
+	if f.delivery == nil || f.delivery.ID != deliveryID {
+		return nil, service.ErrSendTimeDeliveryNotFound
+	}
+	return f.delivery, nil
+}
+
+func (f *fakeSendTimeDeliveries) MarkSent(_ context.Context, deliveryID uuid.UUID) error {
+	f.sent = append(f.sent, deliveryID)
+	return nil
+}
+
+type fakeSendTimeNotifier struct {
+	pushes []string
+}
+
+func (f *fakeSendTimeNotifier) SendSendTimeNotification(_ context.Context, userID, deliveryID uuid.UUID, title, body string) error {
+	f.pushes = append(f.pushes, deliveryID.String()+":"+title)
+	return nil
+}
+
+func TestSendTimeDeliveryTask_SendsOnceAndMarksSent(t *testing.T) {
+	delivery := &service.SendTimeDelivery{ID: uuid.New(), UserID: uuid.New(), ScheduledFor: time.Now()}
+	deliveries := &fakeSendTimeDeliveries{delivery: delivery}
+	notifier := &fakeSendTimeNotifier{}
+	mux := asynq.NewServeMux()
+	RegisterSendTimeTasks(mux, deliveries, notifier, zap.NewNop())
+	sent := sendTimePushes.Value("sent")
+
+	task := NewSendTimeDeliveryTask(delivery, "We miss you", "Come back")
+	require.NoError(t, mux.ProcessTask(context.Background(), task))
+	assert.Equal(t, []string{delivery.ID.String() + ":We miss you"}, notifier.pushes)
+	assert.Equal(t, []uuid.UUID{delivery.ID}, deliveries.sent)
+	assert.Equal(t, sent+1, sendTimePushes.Value("sent"))
+
+	// A redelivered task does not push again
+	sentAt := time.Now()
+	delivery.SentAt = &sentAt
+	require.NoError(t, mux.ProcessTask(context.Background(), task))
+	assert.Len(t, notifier.pushes, 1)
+}
+
+func TestSendTimeDeliveryTask_SkipsMissingDelivery(t *testing.T) {
+	notifier := &fakeSendTimeNotifier{}
+	mux := asynq.NewServeMux()
+	RegisterSendTimeTasks(mux, &fakeSendTimeDeliveries{}, notifier, zap.NewNop())
+
+	task := NewSendTimeDeliveryTask(&service.SendTimeDelivery{ID: uuid.New()}, "t", "b")
+	require.NoError(t, mux.ProcessTask(context.Background(), task))
+	assert.Empty(t, notifier.pushes)
+}
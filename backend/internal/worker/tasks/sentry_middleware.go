@@ -0,0 +1,85 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/hibiken/asynq"
+)
+
+// taskSubject holds the identifiers task payloads commonly carry
+type taskSubject struct {
+	UserID       string `json:"user_id"`
+	ExperimentID string `json:"experiment_id"`
+	AppID        string `json:"app_id"`
+}
+
+// SentryMiddleware traces every task as a Sentry transaction and reports
+// panics and final failures tagged with the task type, ID, retry count and
+// the user and experiment from the payload. Failures that asynq will retry
+// are not reported; the last attempt or a SkipRetry error is. It is a no-op
+// unless Sentry is initialized.
+func SentryMiddleware() asynq.MiddlewareFunc {
+	return func(next asynq.Handler) asynq.Handler {
+		return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) (err error) {
+			if sentry.CurrentHub().Client() == nil {
+				return next.ProcessTask(ctx, t)
+			}
+
+			hub := sentry.CurrentHub().Clone()
+			scope := hub.Scope()
+			taskID, _ := asynq.GetTaskID(ctx)
+			queue, _ := asynq.GetQueueName(ctx)
+			retried, _ := asynq.GetRetryCount(ctx)
+			maxRetry, _ := asynq.GetMaxRetry(ctx)
+			scope.SetTag("task_type", t.Type())
+			scope.SetTag("task_id", taskID)
+			scope.SetTag("queue", queue)
+			scope.SetTag("retry", strconv.Itoa(retried))
+
+			var subject taskSubject
+			if json.Unmarshal(t.Payload(), &subject) == nil {
+				if subject.UserID != "" {
+					scope.SetUser(sentry.User{ID: subject.UserID})
+					scope.SetTag("user_id", subject.UserID)
+				}
+				if subject.ExperimentID != "" {
+					scope.SetTag("experiment_id", subject.ExperimentID)
+				}
+				if subject.AppID != "" {
+					scope.SetTag("app_id", subject.AppID)
+				}
+			}
+
+			ctx = sentry.SetHubOnContext(ctx, hub)
+			transaction := sentry.StartTransaction(ctx, t.Type(),
+				sentry.WithOpName("queue.task"),
+				sentry.WithTransactionSource(sentry.SourceTask),
+			)
+			defer func() {
+				if recovered := recover(); recovered != nil {
+					transaction.Status = sentry.SpanStatusInternalError
+					transaction.Finish()
+					hub.RecoverWithContext(ctx, recovered)
+					hub.Flush(2 * time.Second)
+					panic(recovered)
+				}
+				if err != nil {
+					transaction.Status = sentry.SpanStatusInternalError
+					if retried >= maxRetry || errors.Is(err, asynq.SkipRetry) {
+						hub.CaptureException(err)
+					}
+				} else {
+					transaction.Status = sentry.SpanStatusOK
+				}
+				transaction.Finish()
+			}()
+
+			return next.ProcessTask(transaction.Context(), t)
+		})
+	}
+}
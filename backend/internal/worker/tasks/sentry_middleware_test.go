@@ -0,0 +1,50 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/hibiken/asynq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func initTestSentry(t *testing.T) *sentry.MockTransport {
+	t.Helper()
+	transport := &sentry.MockTransport{}
+	require.NoError(t, sentry.Init(sentry.ClientOptions{Dsn: "https://public@sentry.example.com/1", Transport: transport}))
+	t.Cleanup(func() { sentry.CurrentHub().BindClient(nil) })
+	return transport
+}
+
+func TestSentryMiddleware_CapturesFinalFailureWithPayloadTags(t *testing.T) {
+	transport := initTestSentry(t)
+	handler := SentryMiddleware()(asynq.HandlerFunc(func(context.Context, *asynq.Task) error {
+		return errors.New("boom")
+	}))
+
+	task := asynq.NewTask("test:task", []byte(`{"user_id":"u-1","experiment_id":"e-1"}`))
+	err := handler.ProcessTask(context.Background(), task)
+	require.Error(t, err)
+
+	events := transport.Events()
+	require.Len(t, events, 1)
+	assert.Equal(t, "test:task", events[0].Tags["task_type"])
+	assert.Equal(t, "e-1", events[0].Tags["experiment_id"])
+	assert.Equal(t, "u-1", events[0].User.ID)
+}
+
+func TestSentryMiddleware_CapturesPanicAndRepanics(t *testing.T) {
+	transport := initTestSentry(t)
+	handler := SentryMiddleware()(asynq.HandlerFunc(func(context.Context, *asynq.Task) error {
+		panic("task exploded")
+	}))
+
+	assert.PanicsWithValue(t, "task exploded", func() {
+		_ = handler.ProcessTask(context.Background(), asynq.NewTask("test:panic", nil))
+	})
+	require.Len(t, transport.Events(), 1)
+	assert.Equal(t, "test:panic", transport.Events()[0].Tags["task_type"])
+}
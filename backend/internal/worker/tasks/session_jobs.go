@@ -0,0 +1,51 @@
+package tasks
+
+import (
+	"context"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+const (
+	TypeCleanupSessions = "auth:cleanup_sessions"
+)
+
+// sessionRetention keeps expired and revoked sessions around for a week so
+// support can still see recently signed-out devices
+const sessionRetention = 7 * 24 * time.Hour
+
+// SessionJobHandler handles user session jobs
+type SessionJobHandler struct {
+	sessionRepo repository.UserSessionRepository
+	logger      *zap.Logger
+}
+
+// NewSessionJobHandler creates a new session job handler
+func NewSessionJobHandler(sessionRepo repository.UserSessionRepository, logger *zap.Logger) *SessionJobHandler {
+	return &SessionJobHandler{sessionRepo: sessionRepo, logger: logger}
+}
+
+// RegisterSessionTasks registers session task handlers with the server mux.
+func RegisterSessionTasks(mux *asynq.ServeMux, h *SessionJobHandler) {
+	mux.HandleFunc(TypeCleanupSessions, h.HandleCleanupSessions)
+}
+
+// RegisterSessionScheduledTasks removes stale sessions daily
+func RegisterSessionScheduledTasks(scheduler *asynq.Scheduler) error {
+	_, err := scheduler.Register("30 3 * * *", asynq.NewTask(TypeCleanupSessions, nil))
+	return err
+}
+
+// HandleCleanupSessions deletes sessions expired or revoked before the retention window
+func (h *SessionJobHandler) HandleCleanupSessions(ctx context.Context, t *asynq.Task) error {
+	deleted, err := h.sessionRepo.DeleteStale(ctx, time.Now().Add(-sessionRetention))
+	if err != nil {
+		return err
+	}
+	h.logger.Info("Stale sessions cleaned up", zap.Int64("deleted", deleted))
+	return nil
+}
@@ -0,0 +1,50 @@
+package tasks
+
+import (
+	"context"
+
+	"github.com/hibiken/asynq"
+	"go.uber.org/zap"
+)
+
+const (
+	TypeAttributeSKANInstalls = "attribution:skan_installs"
+
+	// skanAttributionBatch is how many postbacks one run matches
+	skanAttributionBatch = 1000
+)
+
+type skanInstallAttributor interface {
+	AttributeInstalls(ctx context.Context, limit int) (int, error)
+}
+
+// SKANJobHandler matches SKAdNetwork postbacks to signups
+type SKANJobHandler struct {
+	attribution skanInstallAttributor
+	logger      *zap.Logger
+}
+
+// NewSKANJobHandler creates a new SKAdNetwork job handler
+func NewSKANJobHandler(attribution skanInstallAttributor, logger *zap.Logger) *SKANJobHandler {
+	return &SKANJobHandler{attribution: attribution, logger: logger}
+}
+
+// RegisterSKANTasks registers SKAdNetwork task handlers with the server mux.
+func RegisterSKANTasks(mux *asynq.ServeMux, h *SKANJobHandler) {
+	mux.HandleFunc(TypeAttributeSKANInstalls, h.HandleAttributeSKANInstalls)
+}
+
+// RegisterSKANScheduledTasks attributes new postbacks hourly
+func RegisterSKANScheduledTasks(scheduler *asynq.Scheduler) error {
+	_, err := scheduler.Register("15 * * * *", asynq.NewTask(TypeAttributeSKANInstalls, nil))
+	return err
+}
+
+// HandleAttributeSKANInstalls attributes a batch of unprocessed postbacks
+func (h *SKANJobHandler) HandleAttributeSKANInstalls(ctx context.Context, t *asynq.Task) error {
+	matched, err := h.attribution.AttributeInstalls(ctx, skanAttributionBatch)
+	if matched > 0 {
+		h.logger.Info("SKAdNetwork installs attributed", zap.Int("matched", matched))
+	}
+	return err
+}
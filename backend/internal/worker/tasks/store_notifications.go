@@ -0,0 +1,205 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/appctx"
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	iapext "github.com/bivex/paywall-iap/internal/infrastructure/external/iap"
+	"github.com/bivex/paywall-iap/internal/infrastructure/persistence/sqlc/generated"
+)
+
+// storePurchaseVerifier fetches the current state of an Amazon or Huawei
+// purchase. Their notifications only say that a purchase changed; the store
+// API is the source of truth for its state.
+type storePurchaseVerifier interface {
+	VerifyReceipt(ctx context.Context, receiptData string) (*iapext.VerifyResponse, error)
+}
+
+// WithStoreVerifier re-verifies purchases of provider ("amazon", "huawei")
+// when one of its notifications arrives. Without a verifier the provider's
+// notifications are acknowledged and ignored.
+func (h *TaskHandlers) WithStoreVerifier(provider string, verifier storePurchaseVerifier) *TaskHandlers {
+	if h.storeVerifiers == nil {
+		h.storeVerifiers = make(map[string]storePurchaseVerifier)
+	}
+	h.storeVerifiers[provider] = verifier
+	return h
+}
+
+// Huawei subscription notification types (statusUpdateNotification)
+const (
+	huaweiSubscriptionCancel        = 1
+	huaweiSubscriptionInGracePeriod = 8
+	huaweiSubscriptionOnHold        = 9
+)
+
+// Huawei order notification types
+const huaweiOrderRefunded = 2
+
+// handleAmazonRTNEvent processes an Amazon Appstore real-time notification.
+// The stored payload is the RTN carried by the SNS message.
+func (h *TaskHandlers) handleAmazonRTNEvent(ctx context.Context, event generated.WebhookEvent) error {
+	var rtn struct {
+		ReceiptID        string `json:"receiptId"`
+		AppUserID        string `json:"appUserId"`
+		NotificationType string `json:"notificationType"`
+	}
+	if err := json.Unmarshal(event.Payload, &rtn); err != nil {
+		return fmt.Errorf("amazon: unmarshal payload: %w", err)
+	}
+	if rtn.ReceiptID == "" || rtn.AppUserID == "" {
+		return fmt.Errorf("amazon: notification without receiptId and appUserId")
+	}
+
+	h.logger.Info("amazon: processing",
+		zap.String("notificationType", rtn.NotificationType),
+		zap.String("receiptId", rtn.ReceiptID),
+	)
+	receipt, _ := json.Marshal(iapext.AmazonReceipt{UserID: rtn.AppUserID, ReceiptID: rtn.ReceiptID})
+	_, err := h.syncStorePurchase(ctx, "amazon", rtn.ReceiptID, string(receipt), "")
+	return err
+}
+
+// handleHuaweiEvent processes a Huawei IAP server notification. The stored
+// payload is the whole notification; subscription notifications were
+// signature-checked on receipt, order notifications are unsigned and only
+// acted on through the Order service.
+func (h *TaskHandlers) handleHuaweiEvent(ctx context.Context, event generated.WebhookEvent) error {
+	var notif struct {
+		EventType         string `json:"eventType"`
+		OrderNotification *struct {
+			NotificationType int    `json:"notificationType"`
+			PurchaseToken    string `json:"purchaseToken"`
+			ProductID        string `json:"productId"`
+		} `json:"orderNotification"`
+		SubNotification *struct {
+			StatusUpdateNotification string `json:"statusUpdateNotification"`
+		} `json:"subNotification"`
+	}
+	if err := json.Unmarshal(event.Payload, &notif); err != nil {
+		return fmt.Errorf("huawei: unmarshal payload: %w", err)
+	}
+
+	var receipt iapext.HuaweiReceipt
+	var notificationType int
+	refund := false
+	dunning := entity.DunningReason("")
+	switch {
+	case notif.EventType == "ORDER" && notif.OrderNotification != nil:
+		on := notif.OrderNotification
+		receipt = iapext.HuaweiReceipt{PurchaseToken: on.PurchaseToken, ProductID: on.ProductID, Type: "inapp"}
+		notificationType = on.NotificationType
+		refund = on.NotificationType == huaweiOrderRefunded
+	case notif.EventType == "SUBSCRIPTION" && notif.SubNotification != nil:
+		var status struct {
+			NotificationType int    `json:"notificationType"`
+			PurchaseToken    string `json:"purchaseToken"`
+			SubscriptionID   string `json:"subscriptionId"`
+			ProductID        string `json:"productId"`
+		}
+		if err := json.Unmarshal([]byte(notif.SubNotification.StatusUpdateNotification), &status); err != nil {
+			return fmt.Errorf("huawei: unmarshal statusUpdateNotification: %w", err)
+		}
+		receipt = iapext.HuaweiReceipt{PurchaseToken: status.PurchaseToken, ProductID: status.ProductID, SubscriptionID: status.SubscriptionID, Type: "subscription"}
+		notificationType = status.NotificationType
+		switch status.NotificationType {
+		case huaweiSubscriptionCancel:
+			refund = true
+		case huaweiSubscriptionInGracePeriod:
+			dunning = entity.DunningReasonGrace
+		case huaweiSubscriptionOnHold:
+			dunning = entity.DunningReasonOnHold
+		}
+	default:
+		return fmt.Errorf("huawei: unsupported %q notification", notif.EventType)
+	}
+	if receipt.PurchaseToken == "" {
+		return fmt.Errorf("huawei: notification without purchaseToken")
+	}
+
+	h.logger.Info("huawei: processing",
+		zap.String("eventType", notif.EventType),
+		zap.Int("notificationType", notificationType),
+		zap.String("subscriptionId", receipt.SubscriptionID),
+	)
+	receiptData, _ := json.Marshal(receipt)
+	result, err := h.syncStorePurchase(ctx, "huawei", receipt.PurchaseToken, string(receiptData), dunning)
+	if err != nil {
+		return err
+	}
+
+	// Only record a refund the Order / Subscription service confirms, since
+	// order notifications are not signed
+	if refund && result != nil && !result.Valid {
+		if err := h.revokeLifetimeEntitlements(ctx, "android", receipt.PurchaseToken, "refund"); err != nil {
+			return fmt.Errorf("huawei: %w", err)
+		}
+		return h.recordStoreRefund(ctx, "huawei", receipt.PurchaseToken)
+	}
+	return nil
+}
+
+// syncStorePurchase re-verifies a purchase with its store and brings the
+// subscription it belongs to in line. It returns the verification result, or
+// nil when the provider has no verifier.
+func (h *TaskHandlers) syncStorePurchase(ctx context.Context, provider, providerTxID, receiptData string, dunning entity.DunningReason) (*iapext.VerifyResponse, error) {
+	verifier := h.storeVerifiers[provider]
+	if verifier == nil {
+		h.logger.Warn(provider+": no purchase verifier configured, notification ignored", zap.String("provider_tx_id", providerTxID))
+		return nil, nil
+	}
+	result, err := verifier.VerifyReceipt(ctx, receiptData)
+	if err != nil {
+		return nil, fmt.Errorf("%s: verify purchase: %w", provider, err)
+	}
+
+	sub, err := h.queries.GetSubscriptionByProviderTxID(ctx, &providerTxID)
+	if err != nil {
+		// Not a subscription, or the notification beat /verify/iap
+		h.logger.Warn(provider+": subscription not found for purchase",
+			zap.String("provider_tx_id", providerTxID),
+			zap.Error(err),
+		)
+		return result, nil
+	}
+
+	event := storeSubscriptionEvent(provider, result, dunning, time.Now())
+	change, err := h.applySubscriptionEvent(ctx, sub, event)
+	if err != nil {
+		return nil, err
+	}
+	if change.Rejected {
+		return result, nil
+	}
+	h.followDunning(ctx, sub, event.Type)
+	if change.ExpiryExtended {
+		h.markWebhookSeen(ctx, sub.ID)
+	}
+	return result, nil
+}
+
+// recordStoreRefund marks the ledger entry of a store purchase as refunded
+func (h *TaskHandlers) recordStoreRefund(ctx context.Context, provider, providerTxID string) error {
+	appID, _ := appctx.AppIDFromCtx(ctx)
+	refundedAt := time.Now()
+	rows, err := h.queries.MarkTransactionRefunded(ctx, generated.MarkTransactionRefundedParams{
+		AppID:        appID,
+		ProviderTxID: &providerTxID,
+		RefundedAt:   &refundedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("%s: record refund: %w", provider, err)
+	}
+	h.logger.Info(provider+": purchase refunded",
+		zap.String("provider_tx_id", providerTxID),
+		zap.Int64("transactions", rows),
+	)
+	h.invalidateAnalytics(ctx, appID)
+	return nil
+}
@@ -0,0 +1,36 @@
+package tasks
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	iapext "github.com/bivex/paywall-iap/internal/infrastructure/external/iap"
+)
+
+func TestStoreSubscriptionEvent(t *testing.T) {
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	renewing := &iapext.VerifyResponse{Valid: true, IsRenewable: true, ExpiresAt: now.Add(24 * time.Hour)}
+	tests := []struct {
+		name    string
+		result  *iapext.VerifyResponse
+		dunning entity.DunningReason
+		want    entity.SubscriptionStatus
+	}{
+		{name: "renewing", result: renewing, want: entity.StatusActive},
+		{name: "auto-renew off", result: &iapext.VerifyResponse{Valid: true, ExpiresAt: now.Add(time.Hour)}, want: entity.StatusCancelled},
+		{name: "lapsed", result: &iapext.VerifyResponse{Valid: true, IsRenewable: true, ExpiresAt: now.Add(-time.Hour)}, want: entity.StatusExpired},
+		{name: "revoked", result: &iapext.VerifyResponse{Valid: false}, want: entity.StatusExpired},
+		{name: "grace period", result: renewing, dunning: entity.DunningReasonGrace, want: entity.StatusGrace},
+		{name: "account hold", result: &iapext.VerifyResponse{Valid: false}, dunning: entity.DunningReasonOnHold, want: entity.StatusCancelled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := storeSubscriptionEvent(entity.TransactionProviderAmazon, tt.result, tt.dunning, now)
+			assert.Equal(t, tt.want, event.Type.Status())
+			assert.Equal(t, tt.dunning, event.Type.DunningReason())
+		})
+	}
+}
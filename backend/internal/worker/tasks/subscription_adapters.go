@@ -0,0 +1,113 @@
+package tasks
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	iapext "github.com/bivex/paywall-iap/internal/infrastructure/external/iap"
+)
+
+// Each store reports subscription changes in its own vocabulary. The
+// adapters below translate a notification into the subscription event it
+// stands for; applySubscriptionEvent applies the lifecycle rules from there.
+
+// appleSubscriptionEvent maps an App Store Server Notification v2 type.
+// expiresAt is the transaction's expiry, trusted from purchases and renewals
+// only.
+// https://developer.apple.com/documentation/appstoreservernotifications/notificationtype
+func appleSubscriptionEvent(notifType string, expiresAt time.Time) (entity.SubscriptionEvent, bool) {
+	event := entity.SubscriptionEvent{Provider: entity.TransactionProviderApple, ProviderType: notifType}
+	switch notifType {
+	case "SUBSCRIBED":
+		event.Type = entity.SubscriptionEventPurchased
+		event.ExpiresAt = expiresAt
+	case "DID_RENEW":
+		event.Type = entity.SubscriptionEventRenewed
+		event.ExpiresAt = expiresAt
+	case "DID_FAIL_TO_RENEW":
+		event.Type = entity.SubscriptionEventBillingRetry
+	case "CANCEL":
+		event.Type = entity.SubscriptionEventAutoRenewOff
+	case "EXPIRED", "GRACE_PERIOD_EXPIRED":
+		event.Type = entity.SubscriptionEventExpired
+	case "REFUND", "REVOKE":
+		event.Type = entity.SubscriptionEventRevoked
+	default:
+		return event, false
+	}
+	return event, true
+}
+
+// googleSubscriptionEvent maps a Play RTDN subscriptionNotification type.
+// Play notifications carry no expiry, so renewals extend by the plan period.
+func googleSubscriptionEvent(notificationType int) (entity.SubscriptionEvent, bool) {
+	event := entity.SubscriptionEvent{Provider: entity.TransactionProviderGoogle, ProviderType: strconv.Itoa(notificationType)}
+	switch notificationType {
+	case rtdnSubscriptionPurchased:
+		event.Type = entity.SubscriptionEventPurchased
+	case rtdnSubscriptionRenewed, rtdnSubscriptionRecovered, rtdnSubscriptionRestarted:
+		event.Type = entity.SubscriptionEventRenewed
+	case rtdnSubscriptionInGracePeriod:
+		event.Type = entity.SubscriptionEventBillingRetry
+	case rtdnSubscriptionCanceled:
+		event.Type = entity.SubscriptionEventAutoRenewOff
+	case rtdnSubscriptionOnHold:
+		event.Type = entity.SubscriptionEventOnHold
+	case rtdnSubscriptionPaused:
+		event.Type = entity.SubscriptionEventPaused
+	case rtdnSubscriptionExpired:
+		event.Type = entity.SubscriptionEventExpired
+	case rtdnSubscriptionRevoked:
+		event.Type = entity.SubscriptionEventRevoked
+	default:
+		return event, false
+	}
+	return event, true
+}
+
+// storeSubscriptionEvent maps a re-verified Amazon or Huawei purchase.
+// dunning is the billing retry state the notification reported, which the
+// purchase state alone does not show.
+func storeSubscriptionEvent(provider string, result *iapext.VerifyResponse, dunning entity.DunningReason, now time.Time) entity.SubscriptionEvent {
+	event := entity.SubscriptionEvent{Provider: provider, ProviderType: string(dunning)}
+	if result.Valid {
+		event.ExpiresAt = result.ExpiresAt
+	}
+	switch {
+	case dunning == entity.DunningReasonOnHold:
+		event.Type = entity.SubscriptionEventOnHold
+	case !result.Valid || !result.ExpiresAt.After(now):
+		event.Type = entity.SubscriptionEventExpired
+	case dunning == entity.DunningReasonGrace:
+		event.Type = entity.SubscriptionEventBillingRetry
+	case !result.IsRenewable:
+		// Auto-renew off; access continues until expiry
+		event.Type = entity.SubscriptionEventAutoRenewOff
+	default:
+		event.Type = entity.SubscriptionEventRenewed
+	}
+	return event
+}
+
+// payPalSubscriptionEvent maps a BILLING.SUBSCRIPTION webhook. Activation
+// bills nothing by itself; PAYMENT.SALE.COMPLETED extends the subscription.
+func payPalSubscriptionEvent(eventType string) (entity.SubscriptionEvent, bool) {
+	event := entity.SubscriptionEvent{Provider: entity.TransactionProviderPayPal, ProviderType: eventType}
+	switch eventType {
+	case "BILLING.SUBSCRIPTION.ACTIVATED", "BILLING.SUBSCRIPTION.RE-ACTIVATED":
+		event.Type = entity.SubscriptionEventResumed
+	case "BILLING.SUBSCRIPTION.CANCELLED":
+		// Access continues until the paid period ends
+		event.Type = entity.SubscriptionEventAutoRenewOff
+	case "BILLING.SUBSCRIPTION.SUSPENDED":
+		event.Type = entity.SubscriptionEventOnHold
+	case "BILLING.SUBSCRIPTION.PAYMENT.FAILED":
+		event.Type = entity.SubscriptionEventBillingRetry
+	case "BILLING.SUBSCRIPTION.EXPIRED":
+		event.Type = entity.SubscriptionEventExpired
+	default:
+		return event, false
+	}
+	return event, true
+}
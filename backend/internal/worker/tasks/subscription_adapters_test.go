@@ -0,0 +1,63 @@
+package tasks
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+)
+
+func TestAppleSubscriptionEvent(t *testing.T) {
+	expiresAt := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		notifType string
+		want      entity.SubscriptionEventType
+		expiry    bool
+	}{
+		{notifType: "SUBSCRIBED", want: entity.SubscriptionEventPurchased, expiry: true},
+		{notifType: "DID_RENEW", want: entity.SubscriptionEventRenewed, expiry: true},
+		{notifType: "DID_FAIL_TO_RENEW", want: entity.SubscriptionEventBillingRetry},
+		{notifType: "CANCEL", want: entity.SubscriptionEventAutoRenewOff},
+		{notifType: "GRACE_PERIOD_EXPIRED", want: entity.SubscriptionEventExpired},
+		{notifType: "REFUND", want: entity.SubscriptionEventRevoked},
+	}
+	for _, tt := range tests {
+		t.Run(tt.notifType, func(t *testing.T) {
+			event, ok := appleSubscriptionEvent(tt.notifType, expiresAt)
+			assert.True(t, ok)
+			assert.Equal(t, tt.want, event.Type)
+			assert.Equal(t, tt.expiry, !event.ExpiresAt.IsZero(), "only purchases and renewals carry an expiry")
+		})
+	}
+
+	_, ok := appleSubscriptionEvent("PRICE_INCREASE", expiresAt)
+	assert.False(t, ok)
+}
+
+func TestGoogleSubscriptionEvent(t *testing.T) {
+	tests := []struct {
+		notificationType int
+		want             entity.SubscriptionEventType
+	}{
+		{notificationType: rtdnSubscriptionPurchased, want: entity.SubscriptionEventPurchased},
+		{notificationType: rtdnSubscriptionRecovered, want: entity.SubscriptionEventRenewed},
+		{notificationType: rtdnSubscriptionRestarted, want: entity.SubscriptionEventRenewed},
+		{notificationType: rtdnSubscriptionInGracePeriod, want: entity.SubscriptionEventBillingRetry},
+		{notificationType: rtdnSubscriptionCanceled, want: entity.SubscriptionEventAutoRenewOff},
+		{notificationType: rtdnSubscriptionOnHold, want: entity.SubscriptionEventOnHold},
+		{notificationType: rtdnSubscriptionRevoked, want: entity.SubscriptionEventRevoked},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.want), func(t *testing.T) {
+			event, ok := googleSubscriptionEvent(tt.notificationType)
+			assert.True(t, ok)
+			assert.Equal(t, tt.want, event.Type)
+			assert.True(t, event.ExpiresAt.IsZero())
+		})
+	}
+
+	_, ok := googleSubscriptionEvent(rtdnSubscriptionDeferred)
+	assert.False(t, ok, "informational notifications change nothing")
+}
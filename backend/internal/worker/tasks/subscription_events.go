@@ -0,0 +1,93 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/infrastructure/persistence/sqlc/generated"
+)
+
+// subscriptionFromRow loads the subscription aggregate from its row
+func subscriptionFromRow(row generated.Subscription) *entity.Subscription {
+	return &entity.Subscription{
+		ID:        row.ID,
+		AppID:     row.AppID,
+		UserID:    row.UserID,
+		Status:    entity.SubscriptionStatus(row.Status),
+		Source:    entity.SubscriptionSource(row.Source),
+		Platform:  row.Platform,
+		ProductID: row.ProductID,
+		PlanType:  entity.PlanType(row.PlanType),
+		ExpiresAt: row.ExpiresAt,
+		AutoRenew: row.AutoRenew,
+		CreatedAt: row.CreatedAt,
+		UpdatedAt: row.UpdatedAt,
+		DeletedAt: row.DeletedAt,
+	}
+}
+
+// applySubscriptionEvent runs a store event through the subscription
+// lifecycle and saves what it changed. An event the lifecycle rejects is
+// logged and changes nothing.
+func (h *TaskHandlers) applySubscriptionEvent(ctx context.Context, row generated.Subscription, event entity.SubscriptionEvent) (entity.SubscriptionChange, error) {
+	sub := subscriptionFromRow(row)
+	change := sub.Apply(event, time.Now())
+	fields := []zap.Field{
+		zap.String("subscription_id", row.ID.String()),
+		zap.String("notification_type", event.ProviderType),
+	}
+	if change.Rejected {
+		h.logger.Warn(event.Provider+": subscription event not allowed, ignored",
+			append(fields, zap.String("status", row.Status), zap.String("event", string(event.Type)))...,
+		)
+		return change, nil
+	}
+
+	if change.StatusChanged || change.AutoRenewDisabled {
+		var err error
+		if change.AutoRenewDisabled {
+			_, err = h.queries.CancelSubscription(ctx, row.ID)
+		} else {
+			_, err = h.queries.UpdateSubscriptionStatus(ctx, generated.UpdateSubscriptionStatusParams{
+				ID:     row.ID,
+				Status: string(sub.Status),
+			})
+		}
+		if err != nil {
+			return change, fmt.Errorf("%s: update subscription status: %w", event.Provider, err)
+		}
+		h.logger.Info(event.Provider+": subscription status updated",
+			append(fields, zap.String("old_status", row.Status), zap.String("new_status", string(sub.Status)))...,
+		)
+	}
+
+	if change.ExpiryExtended {
+		if _, err := h.queries.UpdateSubscriptionExpiry(ctx, generated.UpdateSubscriptionExpiryParams{
+			ID:        row.ID,
+			ExpiresAt: sub.ExpiresAt,
+		}); err != nil {
+			return change, fmt.Errorf("%s: update subscription expiry: %w", event.Provider, err)
+		}
+		h.logger.Info(event.Provider+": subscription expiry extended",
+			append(fields, zap.Time("new_expiry", sub.ExpiresAt))...,
+		)
+	}
+	return change, nil
+}
+
+// followDunning starts the dunning campaign of a failed renewal and closes
+// it once the subscription renews or ends
+func (h *TaskHandlers) followDunning(ctx context.Context, row generated.Subscription, event entity.SubscriptionEventType) {
+	switch event {
+	case entity.SubscriptionEventBillingRetry, entity.SubscriptionEventOnHold:
+		h.startDunning(ctx, row, event.DunningReason())
+	case entity.SubscriptionEventRenewed, entity.SubscriptionEventResumed:
+		h.closeDunning(ctx, row.ID, true)
+	case entity.SubscriptionEventExpired, entity.SubscriptionEventRevoked:
+		h.closeDunning(ctx, row.ID, false)
+	}
+}
@@ -0,0 +1,40 @@
+package tasks
+
+import (
+	"context"
+
+	"github.com/hibiken/asynq"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+)
+
+const (
+	TypeCaptureSubscriptionSnapshot = "analytics:subscription_snapshot"
+)
+
+// SubscriptionSnapshotJobHandler handles subscription snapshot jobs
+type SubscriptionSnapshotJobHandler struct {
+	snapshotService *service.SubscriptionSnapshotService
+}
+
+// NewSubscriptionSnapshotJobHandler creates a new subscription snapshot job handler
+func NewSubscriptionSnapshotJobHandler(snapshotService *service.SubscriptionSnapshotService) *SubscriptionSnapshotJobHandler {
+	return &SubscriptionSnapshotJobHandler{snapshotService: snapshotService}
+}
+
+// RegisterSubscriptionSnapshotTasks registers subscription snapshot task handlers with the server mux.
+func RegisterSubscriptionSnapshotTasks(mux *asynq.ServeMux, h *SubscriptionSnapshotJobHandler) {
+	mux.HandleFunc(TypeCaptureSubscriptionSnapshot, h.HandleCaptureSubscriptionSnapshot)
+}
+
+// RegisterSubscriptionSnapshotScheduledTasks captures the previous day just after midnight UTC
+func RegisterSubscriptionSnapshotScheduledTasks(scheduler *asynq.Scheduler) error {
+	_, err := scheduler.Register("5 0 * * *", asynq.NewTask(TypeCaptureSubscriptionSnapshot, nil))
+	return err
+}
+
+// HandleCaptureSubscriptionSnapshot records the end-of-day state of every subscription
+func (h *SubscriptionSnapshotJobHandler) HandleCaptureSubscriptionSnapshot(ctx context.Context, t *asynq.Task) error {
+	_, _, err := h.snapshotService.CaptureDaily(ctx)
+	return err
+}
@@ -0,0 +1,67 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+type taskRunRecorder interface {
+	StartTaskRun(ctx context.Context, input repository.TaskRunStartInput) (uuid.UUID, error)
+	FinishTaskRun(ctx context.Context, runID uuid.UUID, status string, errMsg string, duration time.Duration) error
+}
+
+// TaskRunMiddleware records start, finish, duration and error of every task
+// handled by the mux in the task_runs table. Recording failures are logged and
+// never fail the task itself.
+func TaskRunMiddleware(recorder taskRunRecorder, logger *zap.Logger) asynq.MiddlewareFunc {
+	return func(next asynq.Handler) asynq.Handler {
+		return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) (err error) {
+			input := repository.TaskRunStartInput{TaskType: t.Type()}
+			input.TaskID, _ = asynq.GetTaskID(ctx)
+			input.Queue, _ = asynq.GetQueueName(ctx)
+			input.RetryCount, _ = asynq.GetRetryCount(ctx)
+
+			runID, startErr := recorder.StartTaskRun(ctx, input)
+			if startErr != nil {
+				logger.Warn("Failed to record task run start", zap.String("task_type", t.Type()), zap.Error(startErr))
+				return next.ProcessTask(ctx, t)
+			}
+
+			startedAt := time.Now()
+			defer func() {
+				status := entity.TaskRunStatusSucceeded
+				errMsg := ""
+				recovered := recover()
+				if recovered != nil {
+					status = entity.TaskRunStatusFailed
+					errMsg = fmt.Sprintf("panic: %v", recovered)
+				} else if err != nil {
+					status = entity.TaskRunStatusFailed
+					errMsg = err.Error()
+				}
+
+				// The task context may already be cancelled (deadline, shutdown);
+				// the finish record must still be written.
+				finishCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+				defer cancel()
+				if finishErr := recorder.FinishTaskRun(finishCtx, runID, status, errMsg, time.Since(startedAt)); finishErr != nil {
+					logger.Warn("Failed to record task run finish", zap.String("task_type", t.Type()), zap.Error(finishErr))
+				}
+
+				if recovered != nil {
+					panic(recovered)
+				}
+			}()
+
+			return next.ProcessTask(ctx, t)
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+)
+
+type fakeTaskRunRecorder struct {
+	startErr     error
+	started      []repository.TaskRunStartInput
+	finishedID   uuid.UUID
+	finishStatus string
+	finishErrMsg string
+	finishCalls  int
+	runID        uuid.UUID
+}
+
+func (f *fakeTaskRunRecorder) StartTaskRun(_ context.Context, input repository.TaskRunStartInput) (uuid.UUID, error) {
+	f.started = append(f.started, input)
+	if f.startErr != nil {
+		return uuid.Nil, f.startErr
+	}
+	return f.runID, nil
+}
+
+func (f *fakeTaskRunRecorder) FinishTaskRun(_ context.Context, runID uuid.UUID, status string, errMsg string, _ time.Duration) error {
+	f.finishCalls++
+	f.finishedID = runID
+	f.finishStatus = status
+	f.finishErrMsg = errMsg
+	return nil
+}
+
+func TestTaskRunMiddlewareRecordsSuccess(t *testing.T) {
+	recorder := &fakeTaskRunRecorder{runID: uuid.New()}
+	handler := TaskRunMiddleware(recorder, zap.NewNop())(asynq.HandlerFunc(func(context.Context, *asynq.Task) error {
+		return nil
+	}))
+
+	err := handler.ProcessTask(context.Background(), asynq.NewTask(TypeComputeAnalytics, nil))
+
+	require.NoError(t, err)
+	require.Len(t, recorder.started, 1)
+	assert.Equal(t, TypeComputeAnalytics, recorder.started[0].TaskType)
+	assert.Equal(t, recorder.runID, recorder.finishedID)
+	assert.Equal(t, entity.TaskRunStatusSucceeded, recorder.finishStatus)
+	assert.Empty(t, recorder.finishErrMsg)
+}
+
+func TestTaskRunMiddlewareRecordsFailureAndPropagatesError(t *testing.T) {
+	recorder := &fakeTaskRunRecorder{runID: uuid.New()}
+	handler := TaskRunMiddleware(recorder, zap.NewNop())(asynq.HandlerFunc(func(context.Context, *asynq.Task) error {
+		return errors.New("boom")
+	}))
+
+	err := handler.ProcessTask(context.Background(), asynq.NewTask(TypeComputeAnalytics, nil))
+
+	require.EqualError(t, err, "boom")
+	assert.Equal(t, entity.TaskRunStatusFailed, recorder.finishStatus)
+	assert.Equal(t, "boom", recorder.finishErrMsg)
+}
+
+func TestTaskRunMiddlewareRecordsPanicAndRepanics(t *testing.T) {
+	recorder := &fakeTaskRunRecorder{runID: uuid.New()}
+	handler := TaskRunMiddleware(recorder, zap.NewNop())(asynq.HandlerFunc(func(context.Context, *asynq.Task) error {
+		panic("kaboom")
+	}))
+
+	assert.PanicsWithValue(t, "kaboom", func() {
+		_ = handler.ProcessTask(context.Background(), asynq.NewTask(TypeComputeAnalytics, nil))
+	})
+	assert.Equal(t, entity.TaskRunStatusFailed, recorder.finishStatus)
+	assert.Equal(t, "panic: kaboom", recorder.finishErrMsg)
+}
+
+func TestTaskRunMiddlewareStillRunsTaskWhenStartRecordingFails(t *testing.T) {
+	recorder := &fakeTaskRunRecorder{startErr: errors.New("db down")}
+	called := false
+	handler := TaskRunMiddleware(recorder, zap.NewNop())(asynq.HandlerFunc(func(context.Context, *asynq.Task) error {
+		called = true
+		return nil
+	}))
+
+	err := handler.ProcessTask(context.Background(), asynq.NewTask(TypeComputeAnalytics, nil))
+
+	require.NoError(t, err)
+	assert.True(t, called)
+	assert.Zero(t, recorder.finishCalls)
+}
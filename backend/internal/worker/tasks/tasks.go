@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -12,10 +13,14 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 
 	"github.com/bivex/paywall-iap/internal/appctx"
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/domain/repository"
+	"github.com/bivex/paywall-iap/internal/domain/service"
 	"github.com/bivex/paywall-iap/internal/infrastructure/logging"
 	"github.com/bivex/paywall-iap/internal/infrastructure/persistence/sqlc/generated"
 )
@@ -38,14 +43,31 @@ type TaskHandlers struct {
 	lagoAPIURL   string
 	lagoAPIKey   string
 	fcmServerKey string
+	revenueBasis service.RevenueBasis
+	feeSchedule  service.StoreFeeSchedule
+
+	pendingPurchases     pendingPurchaseResolver
+	lifetimeEntitlements lifetimeEntitlementRevoker
+	dunning              dunningTracker
+	priceIncreases       priceIncreaseConsentTracker
+	offerRedemptions     offerRedemptionRecorder
+	analyticsCache       analyticsInvalidator
+	reportingApps        reportingAppLister
+	notificationGate     service.NotificationGate
+	storeVerifiers       map[string]storePurchaseVerifier
+	payPal               payPalStore
+	payPalClient         payPalSubscriptionFetcher
+	conversions          service.ConversionRecorder
 }
 
 // NewTaskHandlers creates task handlers with database access.
 func NewTaskHandlers(queries *generated.Queries, redisClient *redis.Client) *TaskHandlers {
 	return &TaskHandlers{
-		queries: queries,
-		logger:  logging.Logger,
-		redis:   redisClient,
+		queries:      queries,
+		logger:       logging.Logger,
+		redis:        redisClient,
+		revenueBasis: service.RevenueBasisGross,
+		feeSchedule:  service.DefaultStoreFeeSchedule(),
 	}
 }
 
@@ -62,6 +84,28 @@ func (h *TaskHandlers) WithFCM(serverKey string) *TaskHandlers {
 	return h
 }
 
+// WithRevenue sets the revenue basis used for LTV and the fee schedule used to
+// break down store/Stripe payments into fee, tax and net.
+func (h *TaskHandlers) WithRevenue(basis service.RevenueBasis, schedule service.StoreFeeSchedule) *TaskHandlers {
+	h.revenueBasis = basis
+	h.feeSchedule = schedule
+	return h
+}
+
+// WithPendingPurchases resolves deferred (Ask to Buy, pending payment)
+// purchases from store notifications.
+func (h *TaskHandlers) WithPendingPurchases(resolver pendingPurchaseResolver) *TaskHandlers {
+	h.pendingPurchases = resolver
+	return h
+}
+
+// WithNotificationPreferences holds back pushes the user's notification
+// preferences do not allow.
+func (h *TaskHandlers) WithNotificationPreferences(gate service.NotificationGate) *TaskHandlers {
+	h.notificationGate = gate
+	return h
+}
+
 // RegisterHandlers registers all task handlers with the server mux.
 func RegisterHandlers(mux *asynq.ServeMux, h *TaskHandlers) {
 	mux.HandleFunc(TypeUpdateLTV, h.HandleUpdateLTV)
@@ -86,8 +130,8 @@ func RegisterScheduledTasks(scheduler *asynq.Scheduler) {
 		logging.Logger.Error("Failed to schedule LTV update", zap.Error(err))
 	}
 
-	// Compute daily analytics at midnight
-	_, err = scheduler.Register("0 0 * * *", asynq.NewTask(TypeComputeAnalytics, nil))
+	// Compute daily analytics hourly; each app is computed after its local midnight
+	_, err = scheduler.Register("0 * * * *", asynq.NewTask(TypeComputeAnalytics, []byte(`{"due_only":true}`)))
 	if err != nil {
 		logging.Logger.Error("Failed to schedule analytics computation", zap.Error(err))
 	}
@@ -123,10 +167,18 @@ func (h *TaskHandlers) HandleUpdateLTV(ctx context.Context, t *asynq.Task) error
 
 	// Sum all successful transactions for this user
 	appID, _ := appctx.AppIDFromCtx(ctx)
-	ltvRaw, err := h.queries.GetLTVByUserID(ctx, generated.GetLTVByUserIDParams{
-		AppID:  appID,
-		UserID: userUUID,
-	})
+	var ltvRaw interface{}
+	if h.revenueBasis == service.RevenueBasisNet {
+		ltvRaw, err = h.queries.GetNetLTVByUserID(ctx, generated.GetNetLTVByUserIDParams{
+			AppID:  appID,
+			UserID: userUUID,
+		})
+	} else {
+		ltvRaw, err = h.queries.GetLTVByUserID(ctx, generated.GetLTVByUserIDParams{
+			AppID:  appID,
+			UserID: userUUID,
+		})
+	}
 	if err != nil {
 		return fmt.Errorf("failed to query LTV: %w", err)
 	}
@@ -148,33 +200,81 @@ func (h *TaskHandlers) HandleUpdateLTV(ctx context.Context, t *asynq.Task) error
 	return nil
 }
 
-// HandleComputeAnalytics computes daily analytics aggregates
+// HandleComputeAnalytics computes daily analytics aggregates. Days are
+// bounded by each app's reporting time zone; with due_only set, only apps
+// whose local day just ended are computed.
 func (h *TaskHandlers) HandleComputeAnalytics(ctx context.Context, t *asynq.Task) error {
 	var payload struct {
-		Date string `json:"date"` // YYYY-MM-DD
+		Date    string `json:"date"` // YYYY-MM-DD in the app's reporting zone
+		AppID   string `json:"app_id"`
+		DueOnly bool   `json:"due_only"`
 	}
-	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+	if len(t.Payload()) > 0 {
+		if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+			return err
+		}
+	}
+
+	apps, err := h.analyticsApps(ctx, payload.AppID)
+	if err != nil {
 		return err
 	}
+	now := time.Now()
+	failed := 0
+	for _, app := range apps {
+		if payload.DueOnly && payload.Date == "" && !reportingDayEnded(now, app.loc, time.Hour) {
+			continue
+		}
+		if err := h.computeAppAnalytics(ctx, app, payload.Date, now); err != nil {
+			if len(apps) == 1 {
+				return err
+			}
+			h.logger.Error("Failed to compute analytics",
+				zap.String("app_id", app.id.String()),
+				zap.Error(err),
+			)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("analytics failed for %d of %d apps", failed, len(apps))
+	}
+	return nil
+}
 
-	// Default to yesterday if no date provided
-	targetDate := time.Now().UTC().AddDate(0, 0, -1).Truncate(24 * time.Hour)
-	if payload.Date != "" {
-		parsed, err := time.Parse("2006-01-02", payload.Date)
+// computeAppAnalytics stores one app's aggregates for date, or for its
+// previous local day when date is empty
+func (h *TaskHandlers) computeAppAnalytics(ctx context.Context, app reportingApp, date string, now time.Time) error {
+	today, _ := entity.ReportingDay(now, app.loc)
+	dayStart := today.AddDate(0, 0, -1)
+	if date != "" {
+		parsed, err := entity.ParseReportingDate(date, app.loc)
 		if err != nil {
 			return fmt.Errorf("invalid date format: %w", err)
 		}
-		targetDate = parsed
+		dayStart = parsed
 	}
+	dayEnd := dayStart.AddDate(0, 0, 1)
+	timezone := app.loc.String()
 
-	nextDay := targetDate.AddDate(0, 0, 1)
+	// Revenue days bucketed in a previous zone are recomputed first
+	replaced, err := h.queries.RebucketDailyRevenueAggregates(ctx, app.id)
+	if err != nil {
+		return fmt.Errorf("failed to rebucket daily revenue: %w", err)
+	}
+	if replaced > 0 {
+		h.logger.Info("Daily revenue rebucketed to reporting timezone",
+			zap.String("app_id", app.id.String()),
+			zap.String("timezone", timezone),
+			zap.Int32("days", replaced),
+		)
+	}
 
 	// Compute daily revenue
-	appID, _ := appctx.AppIDFromCtx(ctx)
 	revenueRaw, err := h.queries.GetDailyRevenue(ctx, generated.GetDailyRevenueParams{
-		AppID:       appID,
-		CreatedAt:   targetDate,
-		CreatedAt_2: nextDay,
+		AppID:       app.id,
+		CreatedAt:   dayStart,
+		CreatedAt_2: dayEnd,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to query daily revenue: %w", err)
@@ -183,7 +283,7 @@ func (h *TaskHandlers) HandleComputeAnalytics(ctx context.Context, t *asynq.Task
 	revenue := toFloat64(revenueRaw)
 
 	// Compute active subscription count (current snapshot)
-	activeCount, err := h.queries.GetActiveSubscriptionCount(ctx, appID)
+	activeCount, err := h.queries.GetActiveSubscriptionCount(ctx, app.id)
 	if err != nil {
 		return fmt.Errorf("failed to count active subscriptions: %w", err)
 	}
@@ -198,9 +298,11 @@ func (h *TaskHandlers) HandleComputeAnalytics(ctx context.Context, t *asynq.Task
 	}
 	for _, m := range metrics {
 		if err := h.queries.UpsertAnalyticsAggregate(ctx, generated.UpsertAnalyticsAggregateParams{
-			MetricName:  m.name,
-			MetricDate:  targetDate,
-			MetricValue: m.value,
+			AppID:             app.id,
+			MetricName:        m.name,
+			MetricDate:        aggregateDate(dayStart),
+			MetricValue:       m.value,
+			ReportingTimezone: timezone,
 		}); err != nil {
 			h.logger.Error("Failed to store metric",
 				zap.String("metric", m.name),
@@ -210,14 +312,23 @@ func (h *TaskHandlers) HandleComputeAnalytics(ctx context.Context, t *asynq.Task
 	}
 
 	h.logger.Info("Analytics computed",
-		zap.String("date", targetDate.Format("2006-01-02")),
+		zap.String("app_id", app.id.String()),
+		zap.String("date", dayStart.Format("2006-01-02")),
+		zap.String("timezone", timezone),
 		zap.Float64("daily_revenue", revenue),
 		zap.Int64("active_subscriptions", activeCount),
 	)
 	return nil
 }
 
-// HandleProcessWebhook processes incoming webhook events
+// HandleProcessWebhook processes incoming webhook events. Delivery is
+// at-least-once: the claim commits before the handler runs, and an event that
+// failed, or whose worker died before marking it processed, is handled again.
+// Handler side effects span several stores and services, so instead of one
+// transaction each is idempotent for a redelivered event: ledger rows are
+// keyed by provider transaction, subscriptions move to absolute states, and
+// provisioning, dunning, bandit conversions and price increase answers look up
+// what an earlier attempt already stored.
 func (h *TaskHandlers) HandleProcessWebhook(ctx context.Context, t *asynq.Task) error {
 	var payload struct {
 		Provider  string `json:"provider"`
@@ -234,32 +345,75 @@ func (h *TaskHandlers) HandleProcessWebhook(ctx context.Context, t *asynq.Task)
 		zap.String("event_id", payload.EventID),
 	)
 
-	// Fetch event from database to get payload
-	event, err := h.queries.GetWebhookEventByProviderAndID(ctx, generated.GetWebhookEventByProviderAndIDParams{
+	// Claim the event (received/failed → processing). A duplicate enqueue of the
+	// same (provider, event_id) either finds the row locked by the first worker or
+	// already past 'received', and is skipped. A failed event is claimed again.
+	event, err := h.queries.ClaimWebhookEvent(ctx, generated.ClaimWebhookEventParams{
 		Provider: payload.Provider,
 		EventID:  payload.EventID,
 	})
 	if err != nil {
-		return fmt.Errorf("failed to fetch webhook event: %w", err)
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("failed to claim webhook event: %w", err)
+		}
+		existing, getErr := h.queries.GetWebhookEventByProviderAndID(ctx, generated.GetWebhookEventByProviderAndIDParams{
+			Provider: payload.Provider,
+			EventID:  payload.EventID,
+		})
+		if getErr != nil {
+			return fmt.Errorf("failed to fetch webhook event: %w", getErr)
+		}
+		h.logger.Info("Skipping webhook event already processed or in progress",
+			zap.String("provider", payload.Provider),
+			zap.String("event_id", payload.EventID),
+			zap.String("status", existing.Status),
+		)
+		return nil
 	}
 
 	// Dispatch based on provider
+	var handleErr error
 	switch payload.Provider {
 	case "stripe":
-		if err := h.handleStripeEvent(ctx, event); err != nil {
-			return err
-		}
+		handleErr = h.handleStripeEvent(ctx, event)
 	case "apple":
-		if err := h.handleAppleS2SEvent(ctx, event); err != nil {
-			h.logger.Error("Apple S2S handler error", zap.Error(err), zap.String("event_id", payload.EventID))
-			// Return nil — don't retry on business logic errors; Apple expects 200.
-		}
+		handleErr = h.handleAppleS2SEvent(ctx, event)
 	case "google":
-		if err := h.handleGoogleRTDNEvent(ctx, event); err != nil {
-			h.logger.Error("Google RTDN handler error", zap.Error(err), zap.String("event_id", payload.EventID))
-			// Don't fail the task — return nil so the event is still marked processed
-			// and we don't loop on it. Real-world: send to DLQ.
+		handleErr = h.handleGoogleRTDNEvent(ctx, event)
+	case "amazon":
+		handleErr = h.handleAmazonRTNEvent(ctx, event)
+	case "huawei":
+		handleErr = h.handleHuaweiEvent(ctx, event)
+	case "paypal":
+		handleErr = h.handlePayPalEvent(ctx, event)
+	}
+
+	if handleErr != nil {
+		errMsg := handleErr.Error()
+		if err := h.queries.MarkWebhookEventFailed(ctx, generated.MarkWebhookEventFailedParams{
+			ID:        event.ID,
+			LastError: &errMsg,
+		}); err != nil {
+			h.logger.Error("Failed to mark event failed", zap.Error(err))
+		}
+
+		switch payload.Provider {
+		case "stripe", "paypal":
+			// Failed events are re-claimable, so the asynq retry picks it up again.
+			// A PayPal sale can arrive before its subscription's activation.
+			return handleErr
+		case "apple":
+			// Don't retry on business logic errors; Apple expects 200.
+			// The event stays 'failed' and can be replayed from the admin panel.
+			h.logger.Error("Apple S2S handler error", zap.Error(handleErr), zap.String("event_id", payload.EventID))
+		case "google":
+			h.logger.Error("Google RTDN handler error", zap.Error(handleErr), zap.String("event_id", payload.EventID))
+		case "amazon", "huawei":
+			// Replayable from the admin panel like Apple and Google events
+			h.logger.Error("Store notification handler error", zap.Error(handleErr),
+				zap.String("provider", payload.Provider), zap.String("event_id", payload.EventID))
 		}
+		return nil
 	}
 
 	// Mark as processed
@@ -272,11 +426,11 @@ func (h *TaskHandlers) HandleProcessWebhook(ctx context.Context, t *asynq.Task)
 
 func (h *TaskHandlers) handleStripeEvent(ctx context.Context, event generated.WebhookEvent) error {
 	var body struct {
-		Type string `json:"type"`
-		Data struct {
-			Object struct {
-				Customer string `json:"customer"` // is mapped to platform_user_id in k6
-			} `json:"object"`
+		Type     string `json:"type"`
+		Created  int64  `json:"created"`
+		Livemode *bool  `json:"livemode"`
+		Data     struct {
+			Object stripeObject `json:"object"`
 		} `json:"data"`
 	}
 
@@ -284,6 +438,11 @@ func (h *TaskHandlers) handleStripeEvent(ctx context.Context, event generated.We
 		return fmt.Errorf("failed to unmarshal stripe payload: %w", err)
 	}
 
+	environment := entity.TransactionEnvironmentProduction
+	if body.Livemode != nil && !*body.Livemode {
+		environment = entity.TransactionEnvironmentSandbox
+	}
+
 	// k6 simulation uses platform_user_id as Stripe customer ID
 	platformID := body.Data.Object.Customer
 	if platformID == "" {
@@ -292,6 +451,11 @@ func (h *TaskHandlers) handleStripeEvent(ctx context.Context, event generated.We
 
 	appID, _ := appctx.AppIDFromCtx(ctx)
 
+	// A refund only touches the ledger; it must not provision access
+	if body.Type == "charge.refunded" {
+		return h.recordStripeRefund(ctx, appID, body.Data.Object, time.Unix(body.Created, 0))
+	}
+
 	// Find the user
 	user, err := h.queries.GetUserByPlatformID(ctx, generated.GetUserByPlatformIDParams{
 		AppID:          appID,
@@ -301,8 +465,26 @@ func (h *TaskHandlers) handleStripeEvent(ctx context.Context, event generated.We
 		return fmt.Errorf("failed to find user by platform_id %s: %w", platformID, err)
 	}
 
+	// Idempotent provisioning: a redelivered event for a user that already has
+	// an active Stripe subscription must not create a second one. An active
+	// App Store / Play subscription is left alone; entitlements are merged
+	// across stores when access is checked.
+	if existing, err := h.queries.GetActiveSubscriptionByUserIDAndSource(ctx, generated.GetActiveSubscriptionByUserIDAndSourceParams{
+		AppID:  appID,
+		UserID: user.ID,
+		Source: "stripe",
+	}); err == nil {
+		h.logger.Info("Stripe subscription already active, skipping provisioning",
+			zap.String("user_id", user.ID.String()),
+			zap.String("subscription_id", existing.ID.String()),
+		)
+		return h.recordStripeInvoice(ctx, body.Type, body.Data.Object, existing, environment)
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("failed to check active subscription: %w", err)
+	}
+
 	// Provision premium access (simple create subscription)
-	_, err = h.queries.CreateSubscription(ctx, generated.CreateSubscriptionParams{
+	sub, err := h.queries.CreateSubscription(ctx, generated.CreateSubscriptionParams{
 		AppID:     appID,
 		UserID:    user.ID,
 		Status:    "active",
@@ -321,22 +503,174 @@ func (h *TaskHandlers) handleStripeEvent(ctx context.Context, event generated.We
 		zap.String("user_id", user.ID.String()),
 		zap.String("platform_id", platformID),
 	)
+	return h.recordStripeInvoice(ctx, body.Type, body.Data.Object, sub, environment)
+}
+
+// stripeObject is the subset of a Stripe event data.object the worker reads.
+// Invoice amounts are in the currency's minor unit.
+type stripeObject struct {
+	ID              string `json:"id"`
+	Customer        string `json:"customer"` // is mapped to platform_user_id in k6
+	AmountPaid      int64  `json:"amount_paid"`
+	Tax             int64  `json:"tax"`
+	Currency        string `json:"currency"`
+	Invoice         string `json:"invoice"` // set on charges
+	CustomerAddress struct {
+		Country string `json:"country"`
+	} `json:"customer_address"`
+}
+
+// recordStripeInvoice stores the gross / fee / tax / net breakdown of a paid
+// invoice. Redelivered invoices update the existing transaction instead of
+// creating a second one.
+func (h *TaskHandlers) recordStripeInvoice(ctx context.Context, eventType string, invoice stripeObject, sub generated.Subscription, environment string) error {
+	if !strings.HasPrefix(eventType, "invoice.") || invoice.ID == "" || invoice.AmountPaid <= 0 {
+		return nil
+	}
+
+	currency := strings.ToUpper(invoice.Currency)
+	divisor := 100.0
+	if stripeZeroDecimalCurrencies[currency] {
+		divisor = 1
+	}
+	breakdown := h.feeSchedule.Breakdown("stripe",
+		float64(invoice.AmountPaid)/divisor, float64(invoice.Tax)/divisor, invoice.CustomerAddress.Country)
+
+	net := breakdown.Net
+	var countryCode *string
+	if breakdown.CountryCode != "" {
+		countryCode = &breakdown.CountryCode
+	}
+	invoiceID := invoice.ID
+
+	rows, err := h.queries.ReconcileTransactionRevenue(ctx, generated.ReconcileTransactionRevenueParams{
+		AppID:        sub.AppID,
+		ProviderTxID: &invoiceID,
+		Amount:       breakdown.Gross,
+		Currency:     currency,
+		StoreFee:     breakdown.StoreFee,
+		TaxAmount:    breakdown.Tax,
+		NetAmount:    &net,
+		CountryCode:  countryCode,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to reconcile stripe invoice %s: %w", invoice.ID, err)
+	}
+	var transactionID uuid.UUID
+	if rows == 0 {
+		created, err := h.queries.CreateTransaction(ctx, generated.CreateTransactionParams{
+			AppID:          sub.AppID,
+			UserID:         sub.UserID,
+			SubscriptionID: sub.ID,
+			Amount:         breakdown.Gross,
+			Currency:       currency,
+			Status:         "success",
+			ProviderTxID:   &invoiceID,
+			StoreFee:       breakdown.StoreFee,
+			TaxAmount:      breakdown.Tax,
+			NetAmount:      &net,
+			CountryCode:    countryCode,
+			Provider:       entity.TransactionProviderStripe,
+			ProductID:      &sub.ProductID,
+			Environment:    environment,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to record stripe invoice %s: %w", invoice.ID, err)
+		}
+		transactionID = created.ID
+	}
+
+	h.logger.Info("Stripe invoice recorded",
+		zap.String("invoice_id", invoice.ID),
+		zap.Float64("gross", breakdown.Gross),
+		zap.Float64("tax", breakdown.Tax),
+		zap.Float64("net", breakdown.Net),
+	)
+	h.recordBanditConversion(ctx, "stripe", sub.AppID, invoice.ID, transactionID, sub.UserID, breakdown.Gross, currency)
+	h.markWebhookSeen(ctx, sub.ID)
+	h.invalidateAnalytics(ctx, sub.AppID)
+	return nil
+}
+
+// recordStripeRefund marks the ledger entry of the refunded charge's invoice
+// as refunded and links it to the charge.
+func (h *TaskHandlers) recordStripeRefund(ctx context.Context, appID uuid.UUID, charge stripeObject, refundedAt time.Time) error {
+	if charge.Invoice == "" {
+		h.logger.Info("Stripe refund without invoice, ledger unchanged", zap.String("charge_id", charge.ID))
+		return nil
+	}
+	invoiceID, chargeID := charge.Invoice, charge.ID
+	rows, err := h.queries.MarkTransactionRefunded(ctx, generated.MarkTransactionRefundedParams{
+		AppID:           appID,
+		ProviderTxID:    &invoiceID,
+		RefundedAt:      &refundedAt,
+		RefundReference: &chargeID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record stripe refund %s: %w", charge.ID, err)
+	}
+	h.logger.Info("Stripe refund recorded",
+		zap.String("invoice_id", invoiceID),
+		zap.String("charge_id", chargeID),
+		zap.Int64("transactions", rows),
+	)
+	h.invalidateAnalytics(ctx, appID)
 	return nil
 }
 
+// markWebhookSeen flags the subscription's ledger entries as covered by a
+// provider webhook; renewals left unflagged are reported as suspected missing
+// webhooks. Failures are logged, never returned.
+func (h *TaskHandlers) markWebhookSeen(ctx context.Context, subscriptionID uuid.UUID) {
+	if err := h.queries.MarkSubscriptionTransactionsWebhookSeen(ctx, subscriptionID); err != nil {
+		h.logger.Warn("failed to mark transactions webhook seen",
+			zap.String("subscription_id", subscriptionID.String()),
+			zap.Error(err),
+		)
+	}
+}
+
+// stripeZeroDecimalCurrencies are charged in whole units by Stripe
+var stripeZeroDecimalCurrencies = map[string]bool{
+	"BIF": true, "CLP": true, "DJF": true, "GNF": true, "JPY": true, "KMF": true, "KRW": true, "MGA": true,
+	"PYG": true, "RWF": true, "UGX": true, "VND": true, "VUV": true, "XAF": true, "XOF": true, "XPF": true,
+}
+
 // HandleSendNotification sends push notifications to users
 func (h *TaskHandlers) HandleSendNotification(ctx context.Context, t *asynq.Task) error {
 	var payload struct {
-		UserID string `json:"user_id"`
-		Type   string `json:"type"`
-		Title  string `json:"title"`
-		Body   string `json:"body"`
-		Token  string `json:"device_token"`
+		UserID   string `json:"user_id"`
+		Type     string `json:"type"`
+		Title    string `json:"title"`
+		Body     string `json:"body"`
+		Token    string `json:"device_token"`
+		Category string `json:"category"`
 	}
 	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
 		return err
 	}
 
+	if h.notificationGate != nil {
+		// Pushes enqueued without a category are account notices
+		category := entity.NotificationCategory(payload.Category)
+		if !category.IsValid() {
+			category = entity.NotificationBilling
+		}
+		if userID, err := uuid.Parse(payload.UserID); err == nil {
+			ok, err := h.notificationGate.Permit(ctx, userID, category, entity.NotificationPush)
+			if err != nil {
+				return fmt.Errorf("failed to check notification preferences: %w", err)
+			}
+			if !ok {
+				h.logger.Info("push notification suppressed by user preferences",
+					zap.String("user_id", payload.UserID),
+					zap.String("type", payload.Type),
+				)
+				return nil
+			}
+		}
+	}
+
 	if h.fcmServerKey == "" {
 		h.logger.Warn("FCM_SERVER_KEY not configured — skipping push notification",
 			zap.String("user_id", payload.UserID),
@@ -538,6 +872,13 @@ rtdnSubscriptionPaused             = 10 // paused (Play paused billing)
 rtdnSubscriptionPausedScheduleChanged = 11
 rtdnSubscriptionRevoked            = 12 // revoked (refunded)
 rtdnSubscriptionExpired            = 13 // expired
+rtdnSubscriptionPendingPurchaseCanceled = 20 // pending purchase declined or never paid
+)
+
+// One-time product RTDN types (oneTimeProductNotification.notificationType)
+const (
+	rtdnOneTimeProductPurchased = 1
+	rtdnOneTimeProductCanceled  = 2 // pending purchase canceled
 )
 
 // rtdnPayload is the DeveloperNotification JSON body stored in webhook_events.
@@ -550,17 +891,26 @@ NotificationType int    `json:"notificationType"`
 PurchaseToken    string `json:"purchaseToken"`
 SubscriptionID   string `json:"subscriptionId"`
 } `json:"subscriptionNotification"`
+VoidedPurchaseNotification *struct {
+PurchaseToken string `json:"purchaseToken"`
+OrderID       string `json:"orderId"`
+} `json:"voidedPurchaseNotification,omitempty"`
+OneTimeProductNotification *struct {
+NotificationType int    `json:"notificationType"`
+PurchaseToken    string `json:"purchaseToken"`
+SKU              string `json:"sku"`
+} `json:"oneTimeProductNotification,omitempty"`
 TestNotification *struct{} `json:"testNotification,omitempty"`
 }
 
 // handleGoogleRTDNEvent processes a Google RTDN webhook event stored in the DB.
 //
-// It maps notificationType → subscription status transition:
-//   - PURCHASED / RENEWED / RECOVERED / RESTARTED → active
-//   - CANCELED → cancelled (auto_renew=false)
-//   - EXPIRED / REVOKED → expired
-//   - ON_HOLD / PAUSED  → on_hold
-//   - IN_GRACE_PERIOD   → grace_period
+// notificationType maps to a subscription event (see googleSubscriptionEvent):
+//   - PURCHASED → purchased; RENEWED / RECOVERED / RESTARTED → renewed
+//   - CANCELED → auto-renew off (cancelled, auto_renew=false)
+//   - EXPIRED → expired; REVOKED → revoked (expired)
+//   - ON_HOLD / PAUSED  → cancelled
+//   - IN_GRACE_PERIOD   → billing retry (grace)
 //   - DEFERRED / PRICE_CHANGE_CONFIRMED / PAUSE_SCHEDULE_CHANGED → no status change (logged)
 func (h *TaskHandlers) handleGoogleRTDNEvent(ctx context.Context, event generated.WebhookEvent) error {
 var notif rtdnPayload
@@ -574,11 +924,40 @@ h.logger.Info("rtdn: test notification received")
 return nil
 }
 
+// Voided purchase (refund or chargeback) — for subscriptions only the ledger
+// changes; the entitlement follows the SUBSCRIPTION_REVOKED notification.
+// Lifetime unlocks get no other notification, so they are revoked here.
+if vp := notif.VoidedPurchaseNotification; vp != nil {
+if err := h.revokeLifetimeEntitlements(ctx, "android", vp.PurchaseToken, "refund"); err != nil {
+return fmt.Errorf("rtdn: %w", err)
+}
+return h.recordGoogleRefund(ctx, vp.PurchaseToken, vp.OrderID)
+}
+
+// One-time products only matter here while a purchase is pending
+if op := notif.OneTimeProductNotification; op != nil {
+switch op.NotificationType {
+case rtdnOneTimeProductPurchased:
+h.resolveGooglePendingPurchase(ctx, op.PurchaseToken, entity.PendingPurchaseStatusCompleted)
+case rtdnOneTimeProductCanceled:
+h.resolveGooglePendingPurchase(ctx, op.PurchaseToken, entity.PendingPurchaseStatusDeclined)
+}
+return nil
+}
+
 sn := notif.SubscriptionNotification
 if sn.PurchaseToken == "" {
 return fmt.Errorf("rtdn: missing purchaseToken in subscriptionNotification")
 }
 
+switch sn.NotificationType {
+case rtdnSubscriptionPurchased:
+h.resolveGooglePendingPurchase(ctx, sn.PurchaseToken, entity.PendingPurchaseStatusCompleted)
+case rtdnSubscriptionPendingPurchaseCanceled:
+h.resolveGooglePendingPurchase(ctx, sn.PurchaseToken, entity.PendingPurchaseStatusDeclined)
+return nil
+}
+
 h.logger.Info("rtdn: processing",
 zap.Int("notificationType", sn.NotificationType),
 zap.String("purchaseToken", sn.PurchaseToken),
@@ -598,33 +977,7 @@ zap.Error(err),
 return nil
 }
 
-newStatus := ""
-newExpiry := time.Time{}
-
 switch sn.NotificationType {
-case rtdnSubscriptionPurchased, rtdnSubscriptionRenewed,
-rtdnSubscriptionRecovered, rtdnSubscriptionRestarted:
-newStatus = "active"
-// Extend expiry by 1 month for renewal/recovered (we don't re-verify here;
-// a proper implementation would call purchases.subscriptionsv2.get).
-if sn.NotificationType == rtdnSubscriptionRenewed ||
-sn.NotificationType == rtdnSubscriptionRecovered ||
-sn.NotificationType == rtdnSubscriptionRestarted {
-newExpiry = time.Now().AddDate(0, 1, 0)
-}
-
-case rtdnSubscriptionCanceled:
-newStatus = "cancelled"
-
-case rtdnSubscriptionExpired, rtdnSubscriptionRevoked:
-newStatus = "expired"
-
-case rtdnSubscriptionOnHold, rtdnSubscriptionPaused:
-newStatus = "cancelled"
-
-case rtdnSubscriptionInGracePeriod:
-newStatus = "grace"
-
 case rtdnSubscriptionDeferred, rtdnSubscriptionPriceChangeConfirm,
 rtdnSubscriptionPausedScheduleChanged:
 // Informational — no status change needed.
@@ -633,43 +986,84 @@ zap.Int("notificationType", sn.NotificationType),
 zap.String("subscriptionId", sn.SubscriptionID),
 )
 return nil
-
-default:
+}
+subEvent, ok := googleSubscriptionEvent(sn.NotificationType)
+if !ok {
 h.logger.Warn("rtdn: unknown notificationType", zap.Int("type", sn.NotificationType))
 return nil
 }
-
-if newStatus != "" && newStatus != sub.Status {
-if _, err := h.queries.UpdateSubscriptionStatus(ctx, generated.UpdateSubscriptionStatusParams{
-ID:     sub.ID,
-Status: newStatus,
-}); err != nil {
-return fmt.Errorf("rtdn: update subscription status: %w", err)
+// Renewals extend by the plan's period: we don't re-verify here, a proper
+// implementation would call purchases.subscriptionsv2.get
+change, err := h.applySubscriptionEvent(ctx, sub, subEvent)
+if err != nil {
+return err
 }
-h.logger.Info("rtdn: subscription status updated",
-zap.String("subscription_id", sub.ID.String()),
-zap.String("old_status", sub.Status),
-zap.String("new_status", newStatus),
-)
+if !change.Rejected {
+h.followDunning(ctx, sub, subEvent.Type)
 }
-
-// Extend expiry for renewal events.
-if !newExpiry.IsZero() {
-if _, err := h.queries.UpdateSubscriptionExpiry(ctx, generated.UpdateSubscriptionExpiryParams{
-ID:        sub.ID,
-ExpiresAt: newExpiry,
-}); err != nil {
-return fmt.Errorf("rtdn: update subscription expiry: %w", err)
+if subEvent.Type == entity.SubscriptionEventRenewed {
+h.markWebhookSeen(ctx, sub.ID)
 }
-h.logger.Info("rtdn: subscription expiry extended",
-zap.String("subscription_id", sub.ID.String()),
-zap.Time("new_expiry", newExpiry),
-)
+
+if sn.NotificationType == rtdnSubscriptionRevoked {
+return h.recordGoogleRefund(ctx, sn.PurchaseToken, "")
 }
 
 return nil
 }
 
+// resolveGooglePendingPurchase finalizes the pending purchase of a purchase
+// token. Failures are logged: the subscription update must still go ahead.
+func (h *TaskHandlers) resolveGooglePendingPurchase(ctx context.Context, purchaseToken string, status entity.PendingPurchaseStatus) {
+	if h.pendingPurchases == nil || purchaseToken == "" {
+		return
+	}
+	providerTxID := ""
+	if status == entity.PendingPurchaseStatusCompleted {
+		providerTxID = purchaseToken
+	}
+	purchase, err := h.pendingPurchases.Resolve(ctx, repository.PendingPurchaseMatch{PurchaseToken: purchaseToken}, status, providerTxID)
+	if err != nil {
+		h.logger.Warn("rtdn: failed to resolve pending purchase", zap.String("purchaseToken", purchaseToken), zap.Error(err))
+		return
+	}
+	if purchase != nil {
+		h.logger.Info("rtdn: pending purchase resolved",
+			zap.String("pending_purchase_id", purchase.ID.String()),
+			zap.String("status", string(status)),
+		)
+	}
+}
+
+// recordGoogleRefund marks the ledger entry of a purchase token as refunded.
+// orderID, when known, is kept as the refund reference.
+func (h *TaskHandlers) recordGoogleRefund(ctx context.Context, purchaseToken, orderID string) error {
+	if purchaseToken == "" {
+		return fmt.Errorf("rtdn: missing purchaseToken in voidedPurchaseNotification")
+	}
+	appID, _ := appctx.AppIDFromCtx(ctx)
+	refundedAt := time.Now()
+	params := generated.MarkTransactionRefundedParams{
+		AppID:        appID,
+		ProviderTxID: &purchaseToken,
+		RefundedAt:   &refundedAt,
+	}
+	if orderID != "" {
+		params.RefundReference = &orderID
+	}
+	rows, err := h.queries.MarkTransactionRefunded(ctx, params)
+	if err != nil {
+		return fmt.Errorf("rtdn: record refund: %w", err)
+	}
+	h.logger.Info("rtdn: purchase refunded",
+		zap.String("purchaseToken", purchaseToken),
+		zap.String("orderId", orderID),
+		zap.Int64("transactions", rows),
+	)
+	h.invalidateAnalytics(ctx, appID)
+	return nil
+}
+
 // handleAppleS2SEvent processes Apple App Store Server Notifications v2.
 // The stored DB payload is the decoded JWS envelope JSON.
 // signedTransactionInfo is itself a fake-JWS whose middle part contains transaction details.
@@ -677,9 +1071,12 @@ func (h *TaskHandlers) handleAppleS2SEvent(ctx context.Context, event generated.
 // Parse outer notification envelope (stored as plain JSON in DB)
 var envelope struct {
 NotificationType string `json:"notificationType"`
+Subtype          string `json:"subtype"`
 NotificationUUID string `json:"notificationUUID"`
 Data             struct {
+Environment           string `json:"environment"`
 SignedTransactionInfo string `json:"signedTransactionInfo"`
+SignedRenewalInfo     string `json:"signedRenewalInfo"`
 } `json:"data"`
 }
 if err := json.Unmarshal(event.Payload, &envelope); err != nil {
@@ -687,10 +1084,14 @@ return fmt.Errorf("apple s2s: unmarshal envelope: %w", err)
 }
 
 notifType := strings.ToUpper(envelope.NotificationType)
+subtype := strings.ToUpper(envelope.Subtype)
 
 // Decode inner signedTransactionInfo (fake JWS: header.payload.sig)
 var originalTxID string
 var newExpiry time.Time
+var revenue appleTransactionRevenue
+var offer appleTransactionOffer
+var appAccountToken string
 
 if envelope.Data.SignedTransactionInfo != "" {
 txParts := strings.Split(envelope.Data.SignedTransactionInfo, ".")
@@ -700,9 +1101,15 @@ if err == nil {
 var txInfo struct {
 OriginalTransactionID string `json:"originalTransactionId"`
 ExpiresDate           int64  `json:"expiresDate"` // unix ms
+AppAccountToken       string `json:"appAccountToken"`
+appleTransactionRevenue
+appleTransactionOffer
 }
 if err := json.Unmarshal(txPayloadBytes, &txInfo); err == nil {
 originalTxID = txInfo.OriginalTransactionID
+appAccountToken = txInfo.AppAccountToken
+revenue = txInfo.appleTransactionRevenue
+offer = txInfo.appleTransactionOffer
 if txInfo.ExpiresDate > 0 {
 newExpiry = time.Unix(txInfo.ExpiresDate/1000, 0)
 }
@@ -711,6 +1118,12 @@ newExpiry = time.Unix(txInfo.ExpiresDate/1000, 0)
 }
 }
 
+// An approved Ask to Buy request arrives as the first purchase, often while
+// the app is closed and before any receipt is verified
+if notifType == "SUBSCRIBED" || notifType == "ONE_TIME_CHARGE" {
+h.resolveApplePendingPurchase(ctx, appAccountToken, revenue)
+}
+
 if originalTxID == "" {
 h.logger.Warn("apple s2s: no originalTransactionId in signedTransactionInfo",
 zap.String("notification_type", notifType),
@@ -719,6 +1132,12 @@ zap.String("event_id", event.EventID),
 return nil
 }
 
+if notifType == "REFUND" || notifType == "REVOKE" {
+if err := h.revokeLifetimeEntitlements(ctx, "ios", originalTxID, strings.ToLower(notifType)); err != nil {
+return fmt.Errorf("apple s2s: %w", err)
+}
+}
+
 // Look up subscription by original_transaction_id (stored as provider_tx_id on first IAP verify)
 txID := originalTxID
 sub, err := h.queries.GetSubscriptionByProviderTxID(ctx, &txID)
@@ -759,59 +1178,173 @@ time.Sleep(100 * time.Millisecond)
 }
 }
 
-// Map notificationType → subscription status
-// https://developer.apple.com/documentation/appstoreservernotifications/notificationtype
-var newStatus string
-switch notifType {
-case "SUBSCRIBED", "DID_RENEW":
-newStatus = "active"
-case "DID_FAIL_TO_RENEW":
-newStatus = "grace"
-case "EXPIRED", "GRACE_PERIOD_EXPIRED":
-newStatus = "expired"
-case "CANCEL":
-newStatus = "cancelled"
-case "REFUND", "REVOKE":
-newStatus = "cancelled"
-case "PRICE_INCREASE":
-h.logger.Info("apple s2s: price increase notification, no action",
-zap.String("subscription_id", sub.ID.String()),
-)
+if notifType == "PRICE_INCREASE" {
+// The subscription renews as before until the increase takes effect;
+// only the subscriber's consent changes
+if consent, ok := applePriceIncreaseStatus(notifType, subtype); ok {
+h.recordPriceIncrease(ctx, sub, consent, envelope.Data.SignedRenewalInfo)
+}
 return nil
-default:
+}
+subEvent, ok := appleSubscriptionEvent(notifType, newExpiry)
+if !ok {
 h.logger.Warn("apple s2s: unknown notificationType, skipping",
 zap.String("type", notifType),
 )
 return nil
 }
-
-if _, err := h.queries.UpdateSubscriptionStatus(ctx, generated.UpdateSubscriptionStatusParams{
-ID:     sub.ID,
-Status: newStatus,
-}); err != nil {
-return fmt.Errorf("apple s2s: update status to %s: %w", newStatus, err)
+change, err := h.applySubscriptionEvent(ctx, sub, subEvent)
+if err != nil {
+return err
+}
+if !change.Rejected {
+h.followDunning(ctx, sub, subEvent.Type)
 }
 
-h.logger.Info("apple s2s: subscription updated",
-zap.String("subscription_id", sub.ID.String()),
-zap.String("original_tx_id", originalTxID),
-zap.String("notification_type", notifType),
-zap.String("new_status", newStatus),
-)
+// Expired without consenting to a price increase: churn reports count it
+// apart from cancellations
+if consent, ok := applePriceIncreaseStatus(notifType, subtype); ok {
+h.recordPriceIncrease(ctx, sub, consent, envelope.Data.SignedRenewalInfo)
+}
 
-// For renewal events, also extend expiry date
-if (notifType == "DID_RENEW" || notifType == "SUBSCRIBED") && !newExpiry.IsZero() {
-if _, err := h.queries.UpdateSubscriptionExpiry(ctx, generated.UpdateSubscriptionExpiryParams{
-ID:        sub.ID,
-ExpiresAt: newExpiry,
-}); err != nil {
-return fmt.Errorf("apple s2s: update expiry: %w", err)
+if revenue.Environment == "" {
+revenue.Environment = envelope.Data.Environment
 }
-h.logger.Info("apple s2s: subscription expiry extended",
-zap.String("subscription_id", sub.ID.String()),
-zap.Time("new_expiry", newExpiry),
-)
+switch notifType {
+case "DID_RENEW", "SUBSCRIBED":
+if err := h.recordAppleRevenue(ctx, notifType, originalTxID, revenue, sub); err != nil {
+return err
+}
+h.recordAppleOfferRedemption(ctx, sub, offer, revenue)
+h.markWebhookSeen(ctx, sub.ID)
+case "REFUND", "REVOKE":
+return h.recordAppleRefund(ctx, revenue, sub, envelope.NotificationUUID)
 }
 
 return nil
 }
+
+// resolveApplePendingPurchase completes the user's pending purchase of the
+// transaction's product. Apps set appAccountToken to the user ID when starting
+// the purchase; without it the pending purchase resolves at receipt
+// verification instead. Failures are logged, never returned.
+func (h *TaskHandlers) resolveApplePendingPurchase(ctx context.Context, appAccountToken string, rev appleTransactionRevenue) {
+	if h.pendingPurchases == nil || rev.ProductID == "" {
+		return
+	}
+	userID, err := uuid.Parse(appAccountToken)
+	if err != nil {
+		return
+	}
+	purchase, err := h.pendingPurchases.Resolve(ctx, repository.PendingPurchaseMatch{
+		UserID:    userID,
+		ProductID: rev.ProductID,
+		Platform:  "ios",
+	}, entity.PendingPurchaseStatusCompleted, rev.TransactionID)
+	if err != nil {
+		h.logger.Warn("apple s2s: failed to resolve pending purchase", zap.String("user_id", userID.String()), zap.Error(err))
+		return
+	}
+	if purchase != nil {
+		h.logger.Info("apple s2s: pending purchase completed",
+			zap.String("pending_purchase_id", purchase.ID.String()),
+			zap.String("transaction_id", rev.TransactionID),
+		)
+	}
+}
+
+// appleTransactionRevenue holds the price fields of a JWSTransactionDecodedPayload.
+// price is in milliunits of currency; storefront is the ISO-3166 alpha-3 code.
+type appleTransactionRevenue struct {
+	TransactionID  string `json:"transactionId"`
+	ProductID      string `json:"productId"`
+	Price          int64  `json:"price"`
+	Currency       string `json:"currency"`
+	Storefront     string `json:"storefront"`
+	Environment    string `json:"environment"`
+	RevocationDate int64  `json:"revocationDate"` // unix ms
+}
+
+// recordAppleRevenue breaks down the price reported by an App Store notification.
+// Apple does not report tax or commission, so the fee is estimated from the
+// schedule and tax stays 0 until a financial-report import reconciles it.
+// An initial purchase updates the transaction created by receipt verification;
+// a renewal not seen before is recorded as a new transaction.
+func (h *TaskHandlers) recordAppleRevenue(ctx context.Context, notifType, originalTxID string, rev appleTransactionRevenue, sub generated.Subscription) error {
+	if rev.TransactionID == "" || rev.Price <= 0 {
+		return nil
+	}
+
+	breakdown := h.feeSchedule.Breakdown("apple", float64(rev.Price)/1000, 0, rev.Storefront)
+	currency := strings.ToUpper(rev.Currency)
+	net := breakdown.Net
+	txID := rev.TransactionID
+	var countryCode *string
+	if breakdown.CountryCode != "" {
+		countryCode = &breakdown.CountryCode
+	}
+
+	rows, err := h.queries.ReconcileTransactionRevenue(ctx, generated.ReconcileTransactionRevenueParams{
+		AppID:        sub.AppID,
+		ProviderTxID: &txID,
+		Amount:       breakdown.Gross,
+		Currency:     currency,
+		StoreFee:     breakdown.StoreFee,
+		TaxAmount:    breakdown.Tax,
+		NetAmount:    &net,
+		CountryCode:  countryCode,
+	})
+	if err != nil {
+		return fmt.Errorf("apple s2s: reconcile transaction %s: %w", rev.TransactionID, err)
+	}
+	var transactionID uuid.UUID
+	if rows == 0 && notifType == "DID_RENEW" {
+		created, err := h.queries.CreateTransaction(ctx, generated.CreateTransactionParams{
+			AppID:                 sub.AppID,
+			UserID:                sub.UserID,
+			SubscriptionID:        sub.ID,
+			Amount:                breakdown.Gross,
+			Currency:              currency,
+			Status:                "success",
+			ProviderTxID:          &txID,
+			StoreFee:              breakdown.StoreFee,
+			TaxAmount:             breakdown.Tax,
+			NetAmount:             &net,
+			CountryCode:           countryCode,
+			Provider:              entity.TransactionProviderApple,
+			OriginalTransactionID: &originalTxID,
+			ProductID:             &sub.ProductID,
+			Environment:           entity.NormalizeTransactionEnvironment(rev.Environment),
+		})
+		if err != nil {
+			return fmt.Errorf("apple s2s: record renewal transaction %s: %w", rev.TransactionID, err)
+		}
+		transactionID = created.ID
+	}
+	h.recordBanditConversion(ctx, "apple", sub.AppID, rev.TransactionID, transactionID, sub.UserID, breakdown.Gross, currency)
+	h.invalidateAnalytics(ctx, sub.AppID)
+	return nil
+}
+
+// recordAppleRefund marks the refunded or revoked transaction in the ledger,
+// linking it to the notification that reported the refund.
+func (h *TaskHandlers) recordAppleRefund(ctx context.Context, rev appleTransactionRevenue, sub generated.Subscription, notificationUUID string) error {
+	if rev.TransactionID == "" {
+		return nil
+	}
+	refundedAt := time.Now()
+	if rev.RevocationDate > 0 {
+		refundedAt = time.UnixMilli(rev.RevocationDate)
+	}
+	txID := rev.TransactionID
+	if _, err := h.queries.MarkTransactionRefunded(ctx, generated.MarkTransactionRefundedParams{
+		AppID:           sub.AppID,
+		ProviderTxID:    &txID,
+		RefundedAt:      &refundedAt,
+		RefundReference: &notificationUUID,
+	}); err != nil {
+		return fmt.Errorf("apple s2s: record refund of %s: %w", rev.TransactionID, err)
+	}
+	h.invalidateAnalytics(ctx, sub.AppID)
+	return nil
+}
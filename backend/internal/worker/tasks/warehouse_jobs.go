@@ -0,0 +1,46 @@
+package tasks
+
+import (
+	"context"
+
+	"github.com/hibiken/asynq"
+	"go.uber.org/zap"
+)
+
+const TypeWarehouseSync = "warehouse:sync"
+
+type warehouseSyncer interface {
+	Sync(ctx context.Context) error
+}
+
+// WarehouseJobHandler merges changed rows into the data warehouse
+type WarehouseJobHandler struct {
+	sync   warehouseSyncer
+	logger *zap.Logger
+}
+
+// NewWarehouseJobHandler creates a new warehouse job handler
+func NewWarehouseJobHandler(sync warehouseSyncer, logger *zap.Logger) *WarehouseJobHandler {
+	return &WarehouseJobHandler{sync: sync, logger: logger}
+}
+
+// RegisterWarehouseTasks registers warehouse task handlers with the server mux.
+func RegisterWarehouseTasks(mux *asynq.ServeMux, h *WarehouseJobHandler) {
+	mux.HandleFunc(TypeWarehouseSync, h.HandleWarehouseSync)
+}
+
+// RegisterWarehouseScheduledTasks syncs every 15 minutes
+func RegisterWarehouseScheduledTasks(scheduler *asynq.Scheduler) error {
+	_, err := scheduler.Register("*/15 * * * *", asynq.NewTask(TypeWarehouseSync, nil))
+	return err
+}
+
+// HandleWarehouseSync syncs every table; tables still syncing from an earlier
+// run are skipped
+func (h *WarehouseJobHandler) HandleWarehouseSync(ctx context.Context, t *asynq.Task) error {
+	if err := h.sync.Sync(ctx); err != nil {
+		h.logger.Error("Warehouse sync failed", zap.Error(err))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+-- Migration 040: task_runs — execution history for every asynq handler invocation
+
+CREATE TABLE IF NOT EXISTS task_runs (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    task_type    TEXT NOT NULL,
+    task_id      TEXT NOT NULL DEFAULT '',
+    queue        TEXT NOT NULL DEFAULT '',
+    retry_count  INTEGER NOT NULL DEFAULT 0,
+    status       TEXT NOT NULL CHECK (status IN ('running', 'succeeded', 'failed')),
+    error        TEXT,
+    started_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    finished_at  TIMESTAMPTZ,
+    duration_ms  BIGINT
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_runs_type_started
+    ON task_runs(task_type, started_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_task_runs_started
+    ON task_runs(started_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_task_runs_failed
+    ON task_runs(task_type, started_at DESC) WHERE status = 'failed';
+
+COMMENT ON TABLE task_runs IS 'Start/finish/duration/error record for each asynq task handler execution';
+COMMENT ON COLUMN task_runs.retry_count IS 'Number of times asynq had already retried the task when this run started';
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_webhook_events_status;
+
+ALTER TABLE webhook_events
+    DROP COLUMN IF EXISTS last_error,
+    DROP COLUMN IF EXISTS locked_at,
+    DROP COLUMN IF EXISTS attempts,
+    DROP COLUMN IF EXISTS status;
@@ -0,0 +1,20 @@
+-- Migration 041: processing state machine for webhook_events
+-- received → processing → processed | failed. Workers claim an event by moving it
+-- to 'processing' with SELECT ... FOR UPDATE SKIP LOCKED so that duplicate
+-- deliveries or duplicate enqueues of the same (provider, event_id) run once.
+
+ALTER TABLE webhook_events
+    ADD COLUMN IF NOT EXISTS status     TEXT NOT NULL DEFAULT 'received'
+        CHECK (status IN ('received', 'processing', 'processed', 'failed')),
+    ADD COLUMN IF NOT EXISTS attempts   INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS locked_at  TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS last_error TEXT;
+
+UPDATE webhook_events SET status = 'processed' WHERE processed_at IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_webhook_events_status
+    ON webhook_events(status, created_at)
+    WHERE status <> 'processed';
+
+COMMENT ON COLUMN webhook_events.status IS 'Processing state: received, processing, processed, failed';
+COMMENT ON COLUMN webhook_events.locked_at IS 'When a worker claimed the event; stale processing claims can be re-claimed';
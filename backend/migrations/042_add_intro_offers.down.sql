@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS offer_redemptions;
+
+ALTER TABLE pricing_tiers
+    DROP COLUMN IF EXISTS intro_cycles,
+    DROP COLUMN IF EXISTS intro_payment_mode,
+    DROP COLUMN IF EXISTS intro_period,
+    DROP COLUMN IF EXISTS intro_price;
@@ -0,0 +1,43 @@
+-- Migration 042: introductory offers and offer codes
+-- pricing_tiers gain an optional intro price shown on the paywall; every store
+-- verification that reports an intro / trial / offer-code price is recorded in
+-- offer_redemptions so eligibility and intro-vs-regular revenue can be derived.
+
+ALTER TABLE pricing_tiers
+    ADD COLUMN IF NOT EXISTS intro_price        NUMERIC(10,2),
+    ADD COLUMN IF NOT EXISTS intro_period       TEXT,
+    ADD COLUMN IF NOT EXISTS intro_payment_mode TEXT
+        CHECK (intro_payment_mode IN ('free_trial', 'pay_as_you_go', 'pay_up_front')),
+    ADD COLUMN IF NOT EXISTS intro_cycles       INTEGER CHECK (intro_cycles > 0);
+
+COMMENT ON COLUMN pricing_tiers.intro_period IS 'ISO-8601 duration of one intro cycle, e.g. P1W, P1M';
+COMMENT ON COLUMN pricing_tiers.intro_payment_mode IS 'free_trial, pay_as_you_go or pay_up_front (App Store / Play semantics)';
+
+CREATE TABLE IF NOT EXISTS offer_redemptions (
+    id             UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    app_id         UUID NOT NULL REFERENCES apps(id),
+    user_id        UUID NOT NULL REFERENCES users(id),
+    product_id     TEXT NOT NULL,
+    offer_type     TEXT NOT NULL
+        CHECK (offer_type IN ('introductory', 'free_trial', 'offer_code', 'promotional')),
+    offer_code     TEXT,
+    transaction_id UUID REFERENCES transactions(id),
+    provider_tx_id TEXT,
+    platform       TEXT NOT NULL CHECK (platform IN ('ios', 'android')),
+    redeemed_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Pay-as-you-go intro renewals are separate store transactions and each get a
+-- row, so intro revenue is complete; re-verifying a receipt is a no-op.
+CREATE UNIQUE INDEX IF NOT EXISTS offer_redemptions_provider_tx
+    ON offer_redemptions(app_id, provider_tx_id)
+    WHERE provider_tx_id IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_offer_redemptions_user
+    ON offer_redemptions(app_id, user_id, product_id, redeemed_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_offer_redemptions_transaction
+    ON offer_redemptions(transaction_id)
+    WHERE transaction_id IS NOT NULL;
+
+COMMENT ON TABLE offer_redemptions IS 'Intro prices, free trials, offer codes and promotional offers consumed by users';
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_transactions_app_provider_tx;
+DROP INDEX IF EXISTS idx_transactions_app_created;
+
+ALTER TABLE transactions
+    DROP COLUMN IF EXISTS reconciled_at,
+    DROP COLUMN IF EXISTS country_code,
+    DROP COLUMN IF EXISTS net_amount,
+    DROP COLUMN IF EXISTS tax_amount,
+    DROP COLUMN IF EXISTS store_fee;
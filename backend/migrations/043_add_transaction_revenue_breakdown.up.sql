@@ -0,0 +1,23 @@
+-- Migration 043: gross / store fee / tax / net breakdown per transaction
+-- amount stays the gross amount charged to the customer. net_amount is NULL
+-- until the transaction is broken down (store notification, Stripe invoice or
+-- financial-report import); reporting treats NULL as amount.
+
+ALTER TABLE transactions
+    ADD COLUMN IF NOT EXISTS store_fee     NUMERIC(10,2) NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS tax_amount    NUMERIC(10,2) NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS net_amount    NUMERIC(10,2),
+    ADD COLUMN IF NOT EXISTS country_code  TEXT,
+    ADD COLUMN IF NOT EXISTS reconciled_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_transactions_app_created
+    ON transactions(app_id, created_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_transactions_app_provider_tx
+    ON transactions(app_id, provider_tx_id)
+    WHERE provider_tx_id IS NOT NULL;
+
+COMMENT ON COLUMN transactions.store_fee IS 'Store / payment processor commission in transaction currency';
+COMMENT ON COLUMN transactions.tax_amount IS 'VAT / sales tax included in amount';
+COMMENT ON COLUMN transactions.net_amount IS 'amount - tax_amount - store_fee; NULL when not yet broken down';
+COMMENT ON COLUMN transactions.country_code IS 'Buyer country as reported by the store (ISO-3166 alpha-2 or App Store storefront)';
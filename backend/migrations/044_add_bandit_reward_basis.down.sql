@@ -0,0 +1,15 @@
+DELETE FROM bandit_arm_objective_stats WHERE reward_basis <> 'gross';
+
+ALTER TABLE bandit_arm_objective_stats
+    DROP CONSTRAINT IF EXISTS bandit_arm_objective_stats_arm_objective_basis_key;
+
+ALTER TABLE bandit_arm_objective_stats
+    ADD CONSTRAINT bandit_arm_objective_stats_arm_id_objective_type_key
+        UNIQUE (arm_id, objective_type);
+
+ALTER TABLE bandit_arm_objective_stats
+    DROP COLUMN IF EXISTS reward_basis;
+
+ALTER TABLE ab_tests
+    DROP COLUMN IF EXISTS product_costs,
+    DROP COLUMN IF EXISTS reward_basis;
@@ -0,0 +1,24 @@
+-- Migration 044: per-experiment reward basis for bandit revenue objectives
+-- reward_basis selects what RecordReward feeds the bandit: gross revenue, net of
+-- store fees, or contribution margin (net minus per-product cost). NULL falls
+-- back to the deployment-wide REVENUE_BASIS.
+
+ALTER TABLE ab_tests
+    ADD COLUMN IF NOT EXISTS reward_basis  VARCHAR(10) CHECK (reward_basis IN ('gross', 'net', 'margin')),
+    ADD COLUMN IF NOT EXISTS product_costs JSONB;
+
+-- Objective stats are kept per basis so switching an experiment's basis does
+-- not mix gross and net samples.
+ALTER TABLE bandit_arm_objective_stats
+    ADD COLUMN IF NOT EXISTS reward_basis VARCHAR(10) NOT NULL DEFAULT 'gross'
+        CHECK (reward_basis IN ('gross', 'net', 'margin'));
+
+ALTER TABLE bandit_arm_objective_stats
+    DROP CONSTRAINT IF EXISTS bandit_arm_objective_stats_arm_id_objective_type_key;
+
+ALTER TABLE bandit_arm_objective_stats
+    ADD CONSTRAINT bandit_arm_objective_stats_arm_objective_basis_key
+        UNIQUE (arm_id, objective_type, reward_basis);
+
+COMMENT ON COLUMN ab_tests.reward_basis IS 'Bandit reward basis: gross, net (after store fee) or margin (net minus product cost)';
+COMMENT ON COLUMN ab_tests.product_costs IS 'JSON map of product_id to unit cost in USD, used by the margin basis';
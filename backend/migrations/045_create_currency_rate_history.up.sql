@@ -0,0 +1,16 @@
+-- Migration 045: daily exchange-rate history for reporting-currency conversion
+-- currency_rates only keeps the latest rate per pair; admin revenue aggregates
+-- convert each day's transactions at that day's rate, so every ECB refresh is
+-- also recorded here. Global (not app-scoped), like currency_rates.
+
+CREATE TABLE IF NOT EXISTS currency_rate_history (
+    currency    VARCHAR(3)     NOT NULL,
+    rate_date   DATE           NOT NULL,
+    rate_to_usd DECIMAL(18,8)  NOT NULL CHECK (rate_to_usd > 0),
+    source      VARCHAR(50)    NOT NULL DEFAULT 'ecb',
+    created_at  TIMESTAMPTZ    NOT NULL DEFAULT now(),
+    PRIMARY KEY (currency, rate_date)
+);
+
+COMMENT ON TABLE currency_rate_history IS 'USD value of one unit of currency per day (ECB reference rates)';
+COMMENT ON COLUMN currency_rate_history.rate_to_usd IS 'amount_in_currency * rate_to_usd = amount_in_usd';
@@ -0,0 +1,14 @@
+DROP INDEX IF EXISTS idx_transactions_app_provider_created;
+DROP INDEX IF EXISTS idx_transactions_app_original_tx;
+
+ALTER TABLE transactions
+    DROP CONSTRAINT IF EXISTS chk_transactions_environment,
+    DROP CONSTRAINT IF EXISTS chk_transactions_provider;
+
+ALTER TABLE transactions
+    DROP COLUMN IF EXISTS refund_reference,
+    DROP COLUMN IF EXISTS refunded_at,
+    DROP COLUMN IF EXISTS environment,
+    DROP COLUMN IF EXISTS product_id,
+    DROP COLUMN IF EXISTS original_transaction_id,
+    DROP COLUMN IF EXISTS provider;
@@ -0,0 +1,50 @@
+-- Migration 046: normalized transaction ledger
+-- Every verification and webhook path writes one row per store charge with the
+-- provider, the store's original (first purchase) and per-charge ids, product
+-- and environment. A refund marks the charged row refunded and links the
+-- store's refund / revocation reference to it.
+
+ALTER TABLE transactions
+    ADD COLUMN IF NOT EXISTS provider                TEXT,
+    ADD COLUMN IF NOT EXISTS original_transaction_id TEXT,
+    ADD COLUMN IF NOT EXISTS product_id              TEXT,
+    ADD COLUMN IF NOT EXISTS environment             TEXT NOT NULL DEFAULT 'production',
+    ADD COLUMN IF NOT EXISTS refunded_at             TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS refund_reference        TEXT;
+
+-- Back-fill provider and product from the owning subscription
+UPDATE transactions t
+   SET provider   = CASE
+                        WHEN s.source = 'paddle' THEN 'paddle'
+                        WHEN s.platform = 'ios' THEN 'apple'
+                        WHEN s.platform = 'android' THEN 'google'
+                        ELSE 'stripe'
+                    END,
+       product_id = COALESCE(t.product_id, s.product_id)
+  FROM subscriptions s
+ WHERE s.id = t.subscription_id
+   AND t.provider IS NULL;
+
+UPDATE transactions
+   SET refunded_at = created_at
+ WHERE status = 'refunded' AND refunded_at IS NULL;
+
+ALTER TABLE transactions ALTER COLUMN provider SET NOT NULL;
+
+ALTER TABLE transactions
+    ADD CONSTRAINT chk_transactions_provider
+        CHECK (provider IN ('apple', 'google', 'stripe', 'paddle')),
+    ADD CONSTRAINT chk_transactions_environment
+        CHECK (environment IN ('production', 'sandbox'));
+
+CREATE INDEX IF NOT EXISTS idx_transactions_app_original_tx
+    ON transactions(app_id, original_transaction_id)
+    WHERE original_transaction_id IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_transactions_app_provider_created
+    ON transactions(app_id, provider, created_at DESC);
+
+COMMENT ON COLUMN transactions.provider IS 'Store that collected the payment: apple, google, stripe or paddle';
+COMMENT ON COLUMN transactions.original_transaction_id IS 'Store id of the first purchase in the subscription chain (Apple originalTransactionId, Google purchase token)';
+COMMENT ON COLUMN transactions.environment IS 'production or sandbox (store test purchases, Stripe test mode)';
+COMMENT ON COLUMN transactions.refund_reference IS 'Store refund / revocation id linked to this charge';
@@ -0,0 +1,31 @@
+-- Migration 047: paywall personalization rules
+-- Rules are evaluated in priority order (highest first) against the user's
+-- country, platform, LTV bucket, churn risk and days since install. The first
+-- matching rule picks the paywall / offer to show; when it references an
+-- experiment, the bandit chooses among the rule's arms (all arms when
+-- arm_ids is empty).
+
+CREATE TABLE IF NOT EXISTS paywall_rules (
+    id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    app_id        UUID NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+    name          TEXT NOT NULL,
+    placement     TEXT NOT NULL DEFAULT '',
+    priority      INTEGER NOT NULL DEFAULT 0,
+    enabled       BOOLEAN NOT NULL DEFAULT true,
+    conditions    JSONB NOT NULL DEFAULT '{}'::jsonb,
+    paywall_id    UUID REFERENCES app_paywalls(id) ON DELETE SET NULL,
+    offer_code    TEXT,
+    experiment_id UUID REFERENCES ab_tests(id) ON DELETE SET NULL,
+    arm_ids       UUID[] NOT NULL DEFAULT '{}',
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_paywall_rules_app_priority
+    ON paywall_rules(app_id, priority DESC, created_at)
+    WHERE enabled = true;
+
+COMMENT ON TABLE paywall_rules IS 'Personalization rules selecting the paywall, offer and experiment arms per audience';
+COMMENT ON COLUMN paywall_rules.placement IS 'Placement the rule applies to (e.g. onboarding, settings); empty matches every placement';
+COMMENT ON COLUMN paywall_rules.conditions IS 'JSON predicates: countries, platforms, ltv_buckets, min/max_churn_risk, min/max_days_since_install';
+COMMENT ON COLUMN paywall_rules.arm_ids IS 'Candidate arms of experiment_id the bandit chooses from; empty means all arms';
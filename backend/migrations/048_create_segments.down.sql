@@ -0,0 +1,3 @@
+ALTER TABLE ab_tests DROP COLUMN IF EXISTS segment_id;
+DROP TABLE IF EXISTS segment_members;
+DROP TABLE IF EXISTS segments;
@@ -0,0 +1,35 @@
+-- Migration 048: audience segments
+-- A segment is a JSON predicate over user and subscription attributes. Small
+-- segments are evaluated in real time; segments marked materialized have
+-- their membership computed by the worker into segment_members. Experiments,
+-- paywall rules and notifications target a segment by ID.
+
+CREATE TABLE IF NOT EXISTS segments (
+    id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    app_id          UUID NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+    name            TEXT NOT NULL,
+    description     TEXT NOT NULL DEFAULT '',
+    definition      JSONB NOT NULL,
+    materialized    BOOLEAN NOT NULL DEFAULT false,
+    member_count    INTEGER NOT NULL DEFAULT 0,
+    materialized_at TIMESTAMPTZ,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (app_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS segment_members (
+    segment_id UUID NOT NULL REFERENCES segments(id) ON DELETE CASCADE,
+    user_id    UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    added_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (segment_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_segment_members_user ON segment_members(user_id);
+
+ALTER TABLE ab_tests ADD COLUMN IF NOT EXISTS segment_id UUID REFERENCES segments(id) ON DELETE SET NULL;
+
+COMMENT ON TABLE segments IS 'Reusable audience definitions targeted by experiments, paywall rules and notifications';
+COMMENT ON COLUMN segments.definition IS 'Predicate tree: {"all":[...]}, {"any":[...]}, {"not":{...}} or {"field","op","value"}';
+COMMENT ON COLUMN segments.materialized IS 'Membership is read from segment_members (refreshed by the worker) instead of evaluated per request';
+COMMENT ON COLUMN ab_tests.segment_id IS 'Only members of this segment are assigned to the experiment';
@@ -0,0 +1,43 @@
+-- Migration 049: staged price rollouts
+-- A rollout schedules new prices for a pricing tier, optionally limited to one
+-- region, and ramps them up in stages (e.g. 5% on day 1, 25% on day 3, 100% on
+-- day 7). Each user falls in a stable bucket per rollout; users whose bucket is
+-- below the current stage percentage see the new price. Every rollout gets its
+-- own experiment with a holdout (control) and a ramp arm so the cohorts are
+-- compared with the regular experiment tooling.
+
+CREATE TABLE IF NOT EXISTS price_rollouts (
+    id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    app_id          UUID NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+    pricing_tier_id UUID NOT NULL REFERENCES pricing_tiers(id) ON DELETE CASCADE,
+    region          TEXT NOT NULL DEFAULT '',
+    currency        CHAR(3) NOT NULL,
+    monthly_price   NUMERIC(10,2),
+    annual_price    NUMERIC(10,2),
+    lifetime_price  NUMERIC(10,2),
+    stages          JSONB NOT NULL,
+    status          TEXT NOT NULL DEFAULT 'active'
+                    CHECK (status IN ('active', 'paused', 'completed', 'rolled_back')),
+    paused_at       TIMESTAMPTZ,
+    experiment_id   UUID NOT NULL REFERENCES ab_tests(id) ON DELETE CASCADE,
+    holdout_arm_id  UUID NOT NULL REFERENCES ab_test_arms(id) ON DELETE CASCADE,
+    ramp_arm_id     UUID NOT NULL REFERENCES ab_test_arms(id) ON DELETE CASCADE,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK (monthly_price IS NOT NULL OR annual_price IS NOT NULL OR lifetime_price IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_price_rollouts_app_status
+    ON price_rollouts(app_id, status);
+
+-- Only one running rollout per tier and region
+CREATE UNIQUE INDEX IF NOT EXISTS idx_price_rollouts_one_open
+    ON price_rollouts(pricing_tier_id, region)
+    WHERE status IN ('active', 'paused');
+
+COMMENT ON TABLE price_rollouts IS 'Scheduled price changes ramped up in percentage stages with a holdout experiment';
+COMMENT ON COLUMN price_rollouts.region IS 'ISO 3166-1 alpha-2 country the rollout is limited to; empty applies everywhere';
+COMMENT ON COLUMN price_rollouts.stages IS 'JSON array of {"at": timestamp, "percent": 1..100}, ordered by time';
+COMMENT ON COLUMN price_rollouts.paused_at IS 'Set while paused; the ramp holds the percentage reached at this time';
+COMMENT ON COLUMN price_rollouts.holdout_arm_id IS 'Control arm of experiment_id for users still on the old price';
+COMMENT ON COLUMN price_rollouts.ramp_arm_id IS 'Arm of experiment_id for users who see the new price';
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS subscription_snapshots;
+DROP TABLE IF EXISTS subscription_snapshot_runs;
@@ -0,0 +1,42 @@
+-- Migration 050: nightly subscription snapshots
+-- Shortly after midnight UTC the worker copies every subscription's status,
+-- plan and expiry into subscription_snapshots under the date that just ended,
+-- so point-in-time questions ("how many active subscriptions on March 3?")
+-- are answered from what was recorded instead of reconstructed from the
+-- current rows. Consecutive snapshots are diffed into daily new / churned /
+-- upgraded / downgraded counts.
+
+CREATE TABLE IF NOT EXISTS subscription_snapshot_runs (
+    snapshot_date DATE PRIMARY KEY,
+    captured_at   TIMESTAMPTZ NOT NULL,
+    row_count     BIGINT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS subscription_snapshots (
+    snapshot_date   DATE NOT NULL,
+    subscription_id UUID NOT NULL REFERENCES subscriptions(id) ON DELETE CASCADE,
+    app_id          UUID NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+    user_id         UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status          TEXT NOT NULL,
+    plan_type       TEXT NOT NULL,
+    product_id      TEXT NOT NULL,
+    platform        TEXT NOT NULL,
+    source          TEXT NOT NULL,
+    expires_at      TIMESTAMPTZ NOT NULL,
+    auto_renew      BOOLEAN NOT NULL,
+    active          BOOLEAN NOT NULL,
+    captured_at     TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (snapshot_date, subscription_id),
+    FOREIGN KEY (snapshot_date) REFERENCES subscription_snapshot_runs(snapshot_date) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_subscription_snapshots_app_date
+    ON subscription_snapshots(app_id, snapshot_date, user_id);
+
+CREATE INDEX IF NOT EXISTS idx_subscription_snapshots_user
+    ON subscription_snapshots(user_id, snapshot_date);
+
+COMMENT ON TABLE subscription_snapshot_runs IS 'One row per captured date, so dates without subscriptions still count as captured';
+COMMENT ON TABLE subscription_snapshots IS 'End-of-day copy of every subscription for point-in-time reporting';
+COMMENT ON COLUMN subscription_snapshots.snapshot_date IS 'UTC date whose end state the row records';
+COMMENT ON COLUMN subscription_snapshots.active IS 'Status active or grace and not yet expired at captured_at';
@@ -0,0 +1,22 @@
+-- Migration 051: OAuth2 clients for admin API automation
+-- CI jobs and dashboards exchange a client_id / client_secret for a
+-- short-lived scoped access token (client-credentials grant) instead of
+-- borrowing a human admin's JWT. Secrets are stored as bcrypt hashes and
+-- shown once at creation.
+
+CREATE TABLE IF NOT EXISTS oauth_clients (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    client_id    TEXT NOT NULL UNIQUE,
+    secret_hash  TEXT NOT NULL,
+    name         TEXT NOT NULL,
+    app_id       UUID REFERENCES apps(id) ON DELETE CASCADE,
+    scopes       TEXT[] NOT NULL,
+    created_by   UUID NOT NULL REFERENCES users(id),
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_used_at TIMESTAMPTZ,
+    revoked_at   TIMESTAMPTZ
+);
+
+COMMENT ON TABLE oauth_clients IS 'Machine clients allowed to request admin API tokens with the client-credentials grant';
+COMMENT ON COLUMN oauth_clients.app_id IS 'When set, tokens only work for this app (X-App-ID)';
+COMMENT ON COLUMN oauth_clients.created_by IS 'Admin the client acts on behalf of in the audit log';
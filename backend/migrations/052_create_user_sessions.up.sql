@@ -0,0 +1,35 @@
+-- Migration 052: end-user sessions
+-- A session is one signed-in device: it is created when tokens are first
+-- issued and keeps its ID across refresh-token rotation, so users can list
+-- the devices holding a live refresh token and sign them out. The current
+-- access and refresh token IDs are kept so revocation blocklists them at
+-- once. Expired and long-revoked rows are removed by the worker.
+
+CREATE TABLE IF NOT EXISTS user_sessions (
+    id                 UUID PRIMARY KEY,
+    user_id            UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    app_id             UUID REFERENCES apps(id) ON DELETE CASCADE,
+    refresh_jti        TEXT NOT NULL,
+    access_jti         TEXT NOT NULL,
+    device_id          TEXT NOT NULL DEFAULT '',
+    device_fingerprint TEXT NOT NULL,
+    platform           TEXT NOT NULL DEFAULT '',
+    app_version        TEXT NOT NULL DEFAULT '',
+    user_agent         TEXT NOT NULL DEFAULT '',
+    ip_address         TEXT NOT NULL DEFAULT '',
+    created_at         TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_seen_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at         TIMESTAMPTZ NOT NULL,
+    revoked_at         TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_sessions_user_active
+    ON user_sessions(user_id, last_seen_at DESC)
+    WHERE revoked_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_user_sessions_expires
+    ON user_sessions(expires_at);
+
+COMMENT ON TABLE user_sessions IS 'Signed-in devices of end users; one row per refresh-token family';
+COMMENT ON COLUMN user_sessions.device_fingerprint IS 'SHA-256 of device ID, platform and user agent captured at sign-in';
+COMMENT ON COLUMN user_sessions.refresh_jti IS 'JTI of the only refresh token of the session that may still be used';
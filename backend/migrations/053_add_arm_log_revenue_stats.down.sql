@@ -0,0 +1,3 @@
+ALTER TABLE ab_test_arm_stats
+    DROP COLUMN IF EXISTS log_revenue_sq_sum,
+    DROP COLUMN IF EXISTS log_revenue_sum;
@@ -0,0 +1,18 @@
+-- Migration 053: sufficient statistics for the revenue-per-visitor posterior
+-- Converters' revenue is modeled as lognormal, which needs the sum of
+-- log(revenue) and of its square per arm alongside the conversion counts.
+
+ALTER TABLE ab_test_arm_stats
+    ADD COLUMN IF NOT EXISTS log_revenue_sum    DOUBLE PRECISION NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS log_revenue_sq_sum DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+-- Individual amounts were never stored, so existing arms are backfilled as if
+-- every conversion earned the arm's average revenue; their revenue spread
+-- comes from the prior until new conversions arrive.
+UPDATE ab_test_arm_stats
+SET log_revenue_sum    = conversions * ln(revenue / conversions),
+    log_revenue_sq_sum = conversions * ln(revenue / conversions) ^ 2
+WHERE conversions > 0 AND revenue > 0;
+
+COMMENT ON COLUMN ab_test_arm_stats.log_revenue_sum IS 'Sum of ln(revenue) over conversions';
+COMMENT ON COLUMN ab_test_arm_stats.log_revenue_sq_sum IS 'Sum of ln(revenue)^2 over conversions';
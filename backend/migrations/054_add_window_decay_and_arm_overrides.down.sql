@@ -0,0 +1,9 @@
+ALTER TABLE ab_tests DROP COLUMN IF EXISTS window_arm_overrides;
+
+UPDATE ab_tests SET window_type = 'time' WHERE window_type = 'decay';
+
+ALTER TABLE ab_tests DROP CONSTRAINT IF EXISTS ab_tests_window_type_check;
+ALTER TABLE ab_tests
+    ADD CONSTRAINT ab_tests_window_type_check CHECK (window_type IN ('events', 'time', 'none'));
+
+COMMENT ON COLUMN ab_tests.window_type IS 'Type of windowing: events, time, or none';
@@ -0,0 +1,12 @@
+-- Migration 054: discounted windows and per-arm window overrides
+-- 'decay' weights events by 2^(-age / window_size seconds). Overrides map an
+-- arm ID to its own {"type", "size", "min_samples"} window.
+
+ALTER TABLE ab_tests DROP CONSTRAINT IF EXISTS ab_tests_window_type_check;
+ALTER TABLE ab_tests
+    ADD CONSTRAINT ab_tests_window_type_check CHECK (window_type IN ('events', 'time', 'decay', 'none'));
+
+ALTER TABLE ab_tests ADD COLUMN IF NOT EXISTS window_arm_overrides JSONB;
+
+COMMENT ON COLUMN ab_tests.window_type IS 'Type of windowing: events, time, decay or none';
+COMMENT ON COLUMN ab_tests.window_arm_overrides IS 'JSON map of arm_id to {type, size, min_samples} replacing the window for that arm';
@@ -0,0 +1,11 @@
+DELETE FROM bandit_assignment_events WHERE event_type <> 'assigned';
+ALTER TABLE bandit_assignment_events DROP CONSTRAINT IF EXISTS bandit_assignment_events_event_type_check;
+ALTER TABLE bandit_assignment_events
+    ADD CONSTRAINT bandit_assignment_events_event_type_check CHECK (event_type IN ('assigned'));
+
+DROP INDEX IF EXISTS idx_ab_test_assignments_arm_active;
+ALTER TABLE ab_test_assignments DROP COLUMN IF EXISTS excluded_at;
+
+ALTER TABLE ab_test_arms
+    DROP COLUMN IF EXISTS reassignment_policy,
+    DROP COLUMN IF EXISTS archived_at;
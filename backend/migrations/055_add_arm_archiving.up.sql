@@ -0,0 +1,23 @@
+-- Migration 055: archive arms instead of deleting them mid-experiment
+-- An archived arm is never drawn again. reassignment_policy decides what
+-- happens to users still assigned to it: keep them, reassign them to a live
+-- arm, or exclude them from the experiment for the rest of the assignment.
+
+ALTER TABLE ab_test_arms
+    ADD COLUMN IF NOT EXISTS archived_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS reassignment_policy VARCHAR(20)
+        CHECK (reassignment_policy IN ('keep', 'reassign', 'exclude'));
+
+ALTER TABLE ab_test_assignments ADD COLUMN IF NOT EXISTS excluded_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_ab_test_assignments_arm_active
+    ON ab_test_assignments(arm_id, expires_at)
+    WHERE excluded_at IS NULL;
+
+ALTER TABLE bandit_assignment_events DROP CONSTRAINT IF EXISTS bandit_assignment_events_event_type_check;
+ALTER TABLE bandit_assignment_events
+    ADD CONSTRAINT bandit_assignment_events_event_type_check CHECK (event_type IN ('assigned', 'reassigned', 'excluded'));
+
+COMMENT ON COLUMN ab_test_arms.archived_at IS 'When the arm was retired; archived arms are no longer selected';
+COMMENT ON COLUMN ab_test_arms.reassignment_policy IS 'What happens to users assigned to an archived arm: keep, reassign or exclude';
+COMMENT ON COLUMN ab_test_assignments.excluded_at IS 'Set when the user was dropped from the experiment because their arm was archived';
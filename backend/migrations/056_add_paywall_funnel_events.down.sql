@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_bandit_impression_events_funnel;
+
+DELETE FROM bandit_impression_events WHERE event_type <> 'impression';
+ALTER TABLE bandit_impression_events DROP CONSTRAINT IF EXISTS bandit_impression_events_event_type_check;
+ALTER TABLE bandit_impression_events
+    ADD CONSTRAINT bandit_impression_events_event_type_check CHECK (event_type IN ('impression'));
+
+ALTER TABLE bandit_impression_events DROP COLUMN IF EXISTS placement;
@@ -0,0 +1,15 @@
+-- Migration 056: paywall funnel events
+-- purchase_initiated sits between an impression and a conversion so the
+-- paywall_shown -> purchase_initiated -> purchase_completed funnel can be
+-- computed from ingested events. placement records where the paywall was shown.
+
+ALTER TABLE bandit_impression_events ADD COLUMN IF NOT EXISTS placement VARCHAR(100);
+
+ALTER TABLE bandit_impression_events DROP CONSTRAINT IF EXISTS bandit_impression_events_event_type_check;
+ALTER TABLE bandit_impression_events
+    ADD CONSTRAINT bandit_impression_events_event_type_check CHECK (event_type IN ('impression', 'purchase_initiated'));
+
+CREATE INDEX IF NOT EXISTS idx_bandit_impression_events_funnel
+    ON bandit_impression_events(experiment_id, arm_id, user_id, occurred_at);
+
+COMMENT ON COLUMN bandit_impression_events.placement IS 'Where the paywall was shown, e.g. onboarding or settings';
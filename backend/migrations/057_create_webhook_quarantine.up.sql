@@ -0,0 +1,28 @@
+-- Migration 057: webhook_quarantine — authenticated provider deliveries that
+-- failed to parse or did not match the provider's payload schema. The raw
+-- request body is kept so the event can be re-parsed after a code fix.
+
+CREATE TABLE IF NOT EXISTS webhook_quarantine (
+    id             UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    provider       TEXT NOT NULL CHECK (provider IN ('stripe', 'apple', 'google')),
+    raw_body       BYTEA NOT NULL,
+    error          TEXT NOT NULL,
+    status         TEXT NOT NULL DEFAULT 'quarantined' CHECK (status IN ('quarantined', 'reparsed', 'discarded')),
+    attempts       INTEGER NOT NULL DEFAULT 0,
+    last_error     TEXT,
+    event_type     TEXT,
+    event_id       TEXT,
+    received_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    resolved_at    TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_quarantine_status_received
+    ON webhook_quarantine(status, received_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_quarantine_provider_received
+    ON webhook_quarantine(provider, received_at DESC);
+
+COMMENT ON TABLE webhook_quarantine IS 'Malformed but authenticated webhook deliveries kept for inspection and re-parsing';
+COMMENT ON COLUMN webhook_quarantine.error IS 'Why the delivery was quarantined';
+COMMENT ON COLUMN webhook_quarantine.last_error IS 'Why the most recent re-parse failed';
+COMMENT ON COLUMN webhook_quarantine.event_id IS 'Provider event ID once a re-parse succeeded';
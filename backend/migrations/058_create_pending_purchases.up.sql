@@ -0,0 +1,40 @@
+-- Migration 058: pending_purchases — deferred store purchases
+-- Ask to Buy and SCA / slow payment methods leave a purchase pending until a
+-- parent approves it or the payment clears, sometimes days later. The client
+-- reports the pending state, the row is resolved when the finished purchase
+-- is verified or the store notifies us, and nothing counts as a conversion
+-- (subscription, transaction, LTV) until then.
+
+CREATE TABLE IF NOT EXISTS pending_purchases (
+    id             UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    app_id         UUID NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+    user_id        UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    platform       TEXT NOT NULL CHECK (platform IN ('ios', 'android')),
+    product_id     TEXT NOT NULL,
+    reason         TEXT NOT NULL CHECK (reason IN ('ask_to_buy', 'payment_pending')),
+    status         TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'completed', 'declined', 'expired')),
+    purchase_token TEXT,
+    device_token   TEXT,
+    provider_tx_id TEXT,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at     TIMESTAMPTZ NOT NULL,
+    resolved_at    TIMESTAMPTZ
+);
+
+-- One open request per user, product and platform
+CREATE UNIQUE INDEX IF NOT EXISTS idx_pending_purchases_open
+    ON pending_purchases(user_id, product_id, platform)
+    WHERE status = 'pending';
+
+CREATE INDEX IF NOT EXISTS idx_pending_purchases_token
+    ON pending_purchases(purchase_token)
+    WHERE purchase_token IS NOT NULL AND status = 'pending';
+
+CREATE INDEX IF NOT EXISTS idx_pending_purchases_expiry
+    ON pending_purchases(expires_at)
+    WHERE status = 'pending';
+
+COMMENT ON TABLE pending_purchases IS 'Store purchases awaiting approval or payment; not conversions until completed';
+COMMENT ON COLUMN pending_purchases.purchase_token IS 'Google Play purchase token, matched against RTDN notifications';
+COMMENT ON COLUMN pending_purchases.device_token IS 'FCM token notified when the purchase is resolved';
+COMMENT ON COLUMN pending_purchases.provider_tx_id IS 'Store transaction of the completed purchase';
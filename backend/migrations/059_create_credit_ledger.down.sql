@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS credit_ledger;
+DROP TABLE IF EXISTS credit_balances;
@@ -0,0 +1,47 @@
+-- Migration 059: credit_balances / credit_ledger — consumable IAP balances
+-- Consumable products (coins, credits) top up a per-user balance that the app
+-- spends through the API. Every change is a ledger row; the balance row is
+-- the running total and is only ever changed together with a ledger insert.
+-- idempotency_key makes crediting a store receipt and replaying a consume
+-- request safe: the second attempt returns the first entry. Receipt keys are
+-- unique per app so one store transaction is never credited twice; consume
+-- keys come from the client and are only unique per user.
+
+CREATE TABLE IF NOT EXISTS credit_balances (
+    app_id     UUID NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+    user_id    UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    balance    BIGINT NOT NULL DEFAULT 0 CHECK (balance >= 0),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (app_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS credit_ledger (
+    id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    app_id          UUID NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+    user_id         UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind            TEXT NOT NULL CHECK (kind IN ('purchase', 'consume')),
+    delta           BIGINT NOT NULL CHECK (delta <> 0),
+    balance_after   BIGINT NOT NULL CHECK (balance_after >= 0),
+    idempotency_key TEXT NOT NULL,
+    product_id      TEXT,
+    platform        TEXT,
+    provider_tx_id  TEXT,
+    amount          NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    currency        CHAR(3) NOT NULL DEFAULT 'USD',
+    description     TEXT,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_credit_ledger_purchase_key
+    ON credit_ledger(app_id, idempotency_key) WHERE kind = 'purchase';
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_credit_ledger_consume_key
+    ON credit_ledger(app_id, user_id, idempotency_key) WHERE kind = 'consume';
+
+CREATE INDEX IF NOT EXISTS idx_credit_ledger_user
+    ON credit_ledger(app_id, user_id, created_at DESC);
+
+COMMENT ON TABLE credit_balances IS 'Current consumable credit balance per app user';
+COMMENT ON TABLE credit_ledger IS 'Append-only history of credit balance changes';
+COMMENT ON COLUMN credit_ledger.idempotency_key IS 'receipt:<platform>:<store tx> for purchases, client-supplied for consumes';
+COMMENT ON COLUMN credit_ledger.amount IS 'Revenue of purchase entries in USD; 0 for other kinds';
@@ -0,0 +1,33 @@
+-- Migration 060: lifetime_entitlements — non-consumable (lifetime unlock) purchases
+-- A one-time purchase grants access permanently, so it is kept apart from
+-- subscriptions: no expiry, no renewal state machine. Access checks merge
+-- active rows with the user's subscriptions; a refund or revocation by the
+-- store flips the row to revoked.
+
+CREATE TABLE IF NOT EXISTS lifetime_entitlements (
+    id             UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    app_id         UUID NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+    user_id        UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    platform       TEXT NOT NULL CHECK (platform IN ('ios', 'android')),
+    product_id     TEXT NOT NULL,
+    provider_tx_id TEXT NOT NULL,
+    original_tx_id TEXT,
+    amount         NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    status         TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'revoked')),
+    revoke_reason  TEXT,
+    purchased_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    revoked_at     TIMESTAMPTZ,
+    -- A store transaction unlocks the product once, for one user
+    UNIQUE (platform, provider_tx_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_lifetime_entitlements_user
+    ON lifetime_entitlements(user_id)
+    WHERE status = 'active';
+
+CREATE INDEX IF NOT EXISTS idx_lifetime_entitlements_original_tx
+    ON lifetime_entitlements(platform, original_tx_id)
+    WHERE original_tx_id IS NOT NULL;
+
+COMMENT ON TABLE lifetime_entitlements IS 'Permanent access from non-consumable purchases, revoked on refund';
+COMMENT ON COLUMN lifetime_entitlements.provider_tx_id IS 'App Store transaction ID or Google Play purchase token';
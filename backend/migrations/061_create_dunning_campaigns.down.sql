@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_dunning_campaign;
+
+ALTER TABLE dunning
+    DROP COLUMN IF EXISTS payment_fix_url,
+    DROP COLUMN IF EXISTS platform,
+    DROP COLUMN IF EXISTS trigger_reason,
+    DROP COLUMN IF EXISTS variant,
+    DROP COLUMN IF EXISTS campaign_id;
+
+DROP TABLE IF EXISTS dunning_campaigns;
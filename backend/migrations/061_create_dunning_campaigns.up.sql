@@ -0,0 +1,33 @@
+-- Migration 061: dunning_campaigns — configurable reminder sequences for failed renewals
+-- A campaign is one variant of the dunning sequence: the reminder offsets
+-- after the payment failure and a weight for random assignment. Campaigns
+-- linked to a bandit arm are assigned by that experiment instead, and a
+-- recovery rewards the arm.
+
+CREATE TABLE IF NOT EXISTS dunning_campaigns (
+    id                     UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name                   TEXT NOT NULL UNIQUE,
+    variant                TEXT NOT NULL,
+    -- Follow-up reminders, in hours after the failure; the last one ends the campaign
+    reminder_offsets_hours INTEGER[] NOT NULL CHECK (cardinality(reminder_offsets_hours) BETWEEN 1 AND 10),
+    weight                 INTEGER NOT NULL DEFAULT 1 CHECK (weight > 0),
+    bandit_experiment_id   UUID,
+    bandit_arm_id          UUID,
+    is_active              BOOLEAN NOT NULL DEFAULT true,
+    created_at             TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at             TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK ((bandit_experiment_id IS NULL) = (bandit_arm_id IS NULL))
+);
+
+ALTER TABLE dunning
+    ADD COLUMN IF NOT EXISTS campaign_id     UUID REFERENCES dunning_campaigns(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS variant         TEXT,
+    ADD COLUMN IF NOT EXISTS trigger_reason  TEXT CHECK (trigger_reason IN ('grace', 'on_hold')),
+    ADD COLUMN IF NOT EXISTS platform        TEXT,
+    ADD COLUMN IF NOT EXISTS payment_fix_url TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_dunning_campaign
+    ON dunning(campaign_id, created_at);
+
+COMMENT ON TABLE dunning_campaigns IS 'Dunning reminder sequences, one row per variant';
+COMMENT ON COLUMN dunning.payment_fix_url IS 'Store deep link where the user updates the payment method';
@@ -0,0 +1,19 @@
+-- Migration 062: metering_usage — free uses spent on metered features
+-- Soft paywall meters are counted in Redis and flushed here periodically, so
+-- a lost or evicted counter is reseeded instead of handing out free uses again.
+-- Lifetime meters use 1970-01-01 as their period start.
+
+CREATE TABLE IF NOT EXISTS metering_usage (
+    app_id       UUID NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+    user_id      UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    feature      TEXT NOT NULL,
+    period_start TIMESTAMPTZ NOT NULL,
+    used         BIGINT NOT NULL DEFAULT 0 CHECK (used >= 0),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (app_id, user_id, feature, period_start)
+);
+
+CREATE INDEX IF NOT EXISTS idx_metering_usage_period
+    ON metering_usage(period_start);
+
+COMMENT ON TABLE metering_usage IS 'Free uses per user, feature and metering window, persisted from Redis counters';
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS account_merge_moves;
+DROP TABLE IF EXISTS account_merges;
@@ -0,0 +1,44 @@
+-- Migration 063: account_merges — support-driven merges of duplicate users
+-- A merge moves the source user's rows to the target and soft-deletes the
+-- source. Every moved row is journaled in account_merge_moves so the merge
+-- can be undone until undo_until; rows rewritten in place (a conflicting
+-- experiment assignment resolved in favour of the source) keep their
+-- previous values.
+
+CREATE TABLE IF NOT EXISTS account_merges (
+    id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    app_id          UUID NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+    source_user_id  UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    target_user_id  UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    conflict_policy TEXT NOT NULL CHECK (conflict_policy IN ('keep_target', 'keep_source')),
+    status          TEXT NOT NULL DEFAULT 'merged' CHECK (status IN ('merged', 'undone')),
+    reason          TEXT NOT NULL,
+    merged_by       UUID,
+    source_ltv      NUMERIC(10,2) NOT NULL DEFAULT 0,
+    moved           JSONB NOT NULL DEFAULT '{}',
+    conflicts       JSONB NOT NULL DEFAULT '[]',
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    undo_until      TIMESTAMPTZ NOT NULL,
+    undone_at       TIMESTAMPTZ,
+    CHECK (source_user_id <> target_user_id)
+);
+
+-- A user can be merged away only once until that merge is undone
+CREATE UNIQUE INDEX IF NOT EXISTS idx_account_merges_source_merged
+    ON account_merges(source_user_id)
+    WHERE status = 'merged';
+
+CREATE INDEX IF NOT EXISTS idx_account_merges_target
+    ON account_merges(target_user_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS account_merge_moves (
+    merge_id   UUID NOT NULL REFERENCES account_merges(id) ON DELETE CASCADE,
+    table_name TEXT NOT NULL,
+    row_id     UUID NOT NULL,
+    -- Set for rows rewritten in place instead of moved
+    previous   JSONB,
+    PRIMARY KEY (merge_id, table_name, row_id)
+);
+
+COMMENT ON TABLE account_merges IS 'Duplicate users merged by support, undoable until undo_until';
+COMMENT ON TABLE account_merge_moves IS 'Rows moved or rewritten by an account merge, replayed backwards on undo';
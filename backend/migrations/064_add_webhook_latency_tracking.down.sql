@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_transactions_webhook_unseen;
+DROP INDEX IF EXISTS idx_webhook_events_app_provider_created;
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS webhook_seen_at;
+ALTER TABLE webhook_events DROP COLUMN IF EXISTS provider_event_at;
@@ -0,0 +1,26 @@
+-- Migration 064: provider webhook latency and loss tracking
+-- provider_event_at is when the provider says the event happened, so receipt
+-- lag is created_at - provider_event_at and processing lag is
+-- processed_at - provider_event_at. transactions.webhook_seen_at is set once a
+-- webhook for the transaction's subscription has been processed; a renewal
+-- recorded by receipt verification that never gets one is a suspected missing
+-- webhook.
+
+ALTER TABLE webhook_events
+    ADD COLUMN IF NOT EXISTS provider_event_at TIMESTAMPTZ;
+
+ALTER TABLE transactions
+    ADD COLUMN IF NOT EXISTS webhook_seen_at TIMESTAMPTZ;
+
+-- Existing ledger entries predate tracking and must not be reported as losses
+UPDATE transactions SET webhook_seen_at = created_at WHERE webhook_seen_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_webhook_events_app_provider_created
+    ON webhook_events(app_id, provider, created_at);
+
+CREATE INDEX IF NOT EXISTS idx_transactions_webhook_unseen
+    ON transactions(app_id, created_at)
+    WHERE webhook_seen_at IS NULL;
+
+COMMENT ON COLUMN webhook_events.provider_event_at IS 'Event time reported by the provider (Stripe created, Apple signedDate, Google eventTimeMillis)';
+COMMENT ON COLUMN transactions.webhook_seen_at IS 'When a provider webhook for the subscription was processed after this transaction was recorded';
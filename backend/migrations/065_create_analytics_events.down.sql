@@ -0,0 +1,2 @@
+DROP FUNCTION IF EXISTS ensure_analytics_events_partition(TIMESTAMPTZ);
+DROP TABLE IF EXISTS analytics_events;
@@ -0,0 +1,49 @@
+-- Migration 065: analytics_events — high-volume client and webhook events
+-- Rows are bulk-loaded with COPY by the API's batching ingester. The table is
+-- range-partitioned by month on occurred_at so old months can be detached or
+-- dropped cheaply; analytics_events_default catches events outside the
+-- created partitions. app_id is NULL for provider webhooks, whose app is only
+-- known once the worker processes them.
+
+CREATE TABLE analytics_events (
+    id          UUID        NOT NULL DEFAULT gen_random_uuid(),
+    app_id      UUID        REFERENCES apps(id),
+    user_id     UUID,
+    source      TEXT        NOT NULL CHECK (source IN ('client', 'webhook')),
+    event_name  TEXT        NOT NULL,
+    properties  JSONB       NOT NULL DEFAULT '{}',
+    occurred_at TIMESTAMPTZ NOT NULL,
+    received_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (id, occurred_at)
+) PARTITION BY RANGE (occurred_at);
+
+CREATE TABLE analytics_events_default PARTITION OF analytics_events DEFAULT;
+
+CREATE INDEX idx_analytics_events_app_name_occurred
+    ON analytics_events(app_id, event_name, occurred_at);
+
+CREATE INDEX idx_analytics_events_user_occurred
+    ON analytics_events(user_id, occurred_at)
+    WHERE user_id IS NOT NULL;
+
+-- ensure_analytics_events_partition creates the monthly partition holding
+-- the given time, e.g. analytics_events_2026_10, if it does not exist yet
+CREATE OR REPLACE FUNCTION ensure_analytics_events_partition(at TIMESTAMPTZ)
+RETURNS void AS $$
+DECLARE
+    month_start DATE := date_trunc('month', at AT TIME ZONE 'UTC')::date;
+    partition   TEXT := format('analytics_events_%s', to_char(month_start, 'YYYY_MM'));
+BEGIN
+    EXECUTE format(
+        'CREATE TABLE IF NOT EXISTS %I PARTITION OF analytics_events FOR VALUES FROM (%L) TO (%L)',
+        partition,
+        month_start::timestamp AT TIME ZONE 'UTC',
+        (month_start + INTERVAL '1 month')::timestamp AT TIME ZONE 'UTC'
+    );
+END;
+$$ LANGUAGE plpgsql;
+
+SELECT ensure_analytics_events_partition(now());
+SELECT ensure_analytics_events_partition(now() + INTERVAL '1 month');
+
+COMMENT ON TABLE analytics_events IS 'Client and webhook analytics events, bulk-loaded with COPY and partitioned by month';
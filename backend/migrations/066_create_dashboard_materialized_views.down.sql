@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS dashboard_view_refreshes;
+DROP MATERIALIZED VIEW IF EXISTS mv_conversion_by_platform;
+DROP MATERIALIZED VIEW IF EXISTS mv_active_subscriptions_by_plan;
+DROP MATERIALIZED VIEW IF EXISTS mv_daily_revenue;
@@ -0,0 +1,61 @@
+-- Migration 066: materialized views behind the admin dashboard aggregates
+-- The worker refreshes them CONCURRENTLY (each has a unique index for that)
+-- and records the time in dashboard_view_refreshes, which the API reports as
+-- the data's staleness. Windows are relative to the last refresh.
+
+CREATE MATERIALIZED VIEW mv_daily_revenue AS
+SELECT app_id,
+       (created_at AT TIME ZONE 'UTC')::date                 AS day,
+       currency,
+       COALESCE(SUM(amount) FILTER (WHERE status = 'success'), 0)  AS revenue,
+       COALESCE(SUM(amount) FILTER (WHERE status = 'refunded'), 0) AS refunded,
+       COUNT(*) FILTER (WHERE status = 'success')            AS transactions
+FROM transactions
+WHERE created_at >= now() - INTERVAL '400 days'
+  AND environment = 'production'
+GROUP BY app_id, day, currency;
+
+CREATE UNIQUE INDEX idx_mv_daily_revenue_key ON mv_daily_revenue(app_id, day, currency);
+
+CREATE MATERIALIZED VIEW mv_active_subscriptions_by_plan AS
+SELECT app_id,
+       plan_type,
+       product_id,
+       COUNT(*)                                AS active,
+       COUNT(*) FILTER (WHERE status = 'grace') AS in_grace,
+       COUNT(*) FILTER (WHERE auto_renew)      AS auto_renewing
+FROM subscriptions
+WHERE status IN ('active', 'grace') AND deleted_at IS NULL
+GROUP BY app_id, plan_type, product_id;
+
+CREATE UNIQUE INDEX idx_mv_active_subscriptions_by_plan_key
+    ON mv_active_subscriptions_by_plan(app_id, plan_type, product_id);
+
+-- A user converts when they have any subscription; cohorts are signup days
+CREATE MATERIALIZED VIEW mv_conversion_by_platform AS
+SELECT u.app_id,
+       u.platform,
+       (u.created_at AT TIME ZONE 'UTC')::date AS cohort_day,
+       COUNT(*)                                AS users,
+       COUNT(*) FILTER (WHERE EXISTS (
+           SELECT 1 FROM subscriptions s WHERE s.user_id = u.id
+       ))                                      AS converted
+FROM users u
+WHERE u.created_at >= now() - INTERVAL '400 days' AND u.deleted_at IS NULL
+GROUP BY u.app_id, u.platform, cohort_day;
+
+CREATE UNIQUE INDEX idx_mv_conversion_by_platform_key
+    ON mv_conversion_by_platform(app_id, platform, cohort_day);
+
+CREATE TABLE dashboard_view_refreshes (
+    view_name    TEXT PRIMARY KEY,
+    refreshed_at TIMESTAMPTZ NOT NULL,
+    duration_ms  INT NOT NULL DEFAULT 0
+);
+
+INSERT INTO dashboard_view_refreshes (view_name, refreshed_at)
+VALUES ('mv_daily_revenue', now()),
+       ('mv_active_subscriptions_by_plan', now()),
+       ('mv_conversion_by_platform', now());
+
+COMMENT ON TABLE dashboard_view_refreshes IS 'Last refresh of each dashboard materialized view';
@@ -0,0 +1,41 @@
+DROP MATERIALIZED VIEW IF EXISTS mv_conversion_by_platform;
+DROP MATERIALIZED VIEW IF EXISTS mv_daily_revenue;
+
+CREATE MATERIALIZED VIEW mv_daily_revenue AS
+SELECT app_id,
+       (created_at AT TIME ZONE 'UTC')::date                 AS day,
+       currency,
+       COALESCE(SUM(amount) FILTER (WHERE status = 'success'), 0)  AS revenue,
+       COALESCE(SUM(amount) FILTER (WHERE status = 'refunded'), 0) AS refunded,
+       COUNT(*) FILTER (WHERE status = 'success')            AS transactions
+FROM transactions
+WHERE created_at >= now() - INTERVAL '400 days'
+  AND environment = 'production'
+GROUP BY app_id, day, currency;
+
+CREATE UNIQUE INDEX idx_mv_daily_revenue_key ON mv_daily_revenue(app_id, day, currency);
+
+CREATE MATERIALIZED VIEW mv_conversion_by_platform AS
+SELECT u.app_id,
+       u.platform,
+       (u.created_at AT TIME ZONE 'UTC')::date AS cohort_day,
+       COUNT(*)                                AS users,
+       COUNT(*) FILTER (WHERE EXISTS (
+           SELECT 1 FROM subscriptions s WHERE s.user_id = u.id
+       ))                                      AS converted
+FROM users u
+WHERE u.created_at >= now() - INTERVAL '400 days' AND u.deleted_at IS NULL
+GROUP BY u.app_id, u.platform, cohort_day;
+
+CREATE UNIQUE INDEX idx_mv_conversion_by_platform_key
+    ON mv_conversion_by_platform(app_id, platform, cohort_day);
+
+DROP FUNCTION IF EXISTS rebucket_daily_revenue_aggregates(UUID);
+ALTER TABLE analytics_aggregates DROP COLUMN IF EXISTS reporting_timezone;
+ALTER TABLE analytics_aggregates
+    ALTER COLUMN dimensions DROP NOT NULL,
+    ALTER COLUMN dimensions DROP DEFAULT;
+
+DROP FUNCTION IF EXISTS app_reporting_timezone(UUID);
+ALTER TABLE apps DROP CONSTRAINT IF EXISTS apps_settings_reporting_timezone_check;
+DROP FUNCTION IF EXISTS is_valid_timezone(TEXT);
@@ -0,0 +1,133 @@
+-- Migration 067: per-app reporting time zone
+-- settings->>'reporting_timezone' (IANA name, UTC when unset) decides where an
+-- app's business day starts. Daily aggregates, signup cohorts and the
+-- dashboard views bucket by that local day. Each aggregate row records the
+-- zone it was bucketed in, so it can be recomputed when the zone changes.
+
+CREATE OR REPLACE FUNCTION is_valid_timezone(tz TEXT) RETURNS BOOLEAN
+LANGUAGE plpgsql STABLE AS $$
+BEGIN
+    PERFORM now() AT TIME ZONE tz;
+    RETURN true;
+EXCEPTION WHEN invalid_parameter_value THEN
+    RETURN false;
+END;
+$$;
+
+-- An unknown zone would fail every refresh of the dashboard views
+ALTER TABLE apps
+    ADD CONSTRAINT apps_settings_reporting_timezone_check
+    CHECK (
+        (settings->>'reporting_timezone') IS NULL
+        OR (settings->>'reporting_timezone') = ''
+        OR is_valid_timezone(settings->>'reporting_timezone')
+    );
+
+CREATE OR REPLACE FUNCTION app_reporting_timezone(p_app_id UUID) RETURNS TEXT
+LANGUAGE sql STABLE AS $$
+    SELECT COALESCE(NULLIF(settings->>'reporting_timezone', ''), 'UTC') FROM apps WHERE id = p_app_id;
+$$;
+
+-- ── analytics_aggregates ─────────────────────────────────────────────────────
+-- NULL dimensions never conflicted, so daily upserts piled up duplicate rows.
+-- Keep the latest of each and make dimensions non-null.
+DELETE FROM analytics_aggregates a
+USING analytics_aggregates b
+WHERE a.app_id = b.app_id
+  AND a.metric_name = b.metric_name
+  AND a.metric_date = b.metric_date
+  AND COALESCE(a.dimensions, '{}'::jsonb) = COALESCE(b.dimensions, '{}'::jsonb)
+  AND (a.updated_at, a.id) < (b.updated_at, b.id);
+
+UPDATE analytics_aggregates SET dimensions = '{}'::jsonb WHERE dimensions IS NULL;
+ALTER TABLE analytics_aggregates
+    ALTER COLUMN dimensions SET DEFAULT '{}'::jsonb,
+    ALTER COLUMN dimensions SET NOT NULL;
+
+-- Existing rows were computed on UTC days
+ALTER TABLE analytics_aggregates
+    ADD COLUMN reporting_timezone TEXT NOT NULL DEFAULT 'UTC';
+
+COMMENT ON COLUMN analytics_aggregates.reporting_timezone IS 'Time zone metric_date was bucketed in';
+
+-- Recomputes an app's daily_revenue rows bucketed in another zone from the
+-- ledger, over the same span of days. Snapshot metrics such as
+-- active_subscriptions cannot be recomputed and are left as recorded.
+-- Returns the number of stale rows replaced.
+CREATE OR REPLACE FUNCTION rebucket_daily_revenue_aggregates(p_app_id UUID) RETURNS INT
+LANGUAGE plpgsql AS $$
+DECLARE
+    tz       TEXT := app_reporting_timezone(p_app_id);
+    replaced INT;
+    first_day DATE;
+    last_day  DATE;
+BEGIN
+    WITH stale AS (
+        DELETE FROM analytics_aggregates
+        WHERE app_id = p_app_id
+          AND metric_name = 'daily_revenue'
+          AND reporting_timezone <> tz
+        RETURNING metric_date
+    )
+    SELECT COUNT(*), MIN(metric_date), MAX(metric_date) INTO replaced, first_day, last_day FROM stale;
+
+    IF replaced = 0 THEN
+        RETURN 0;
+    END IF;
+
+    INSERT INTO analytics_aggregates (app_id, metric_name, metric_date, metric_value, reporting_timezone)
+    SELECT p_app_id, 'daily_revenue', d.day, COALESCE(SUM(t.amount), 0), tz
+    FROM generate_series(first_day, last_day, INTERVAL '1 day') AS d(day)
+    LEFT JOIN transactions t
+           ON t.app_id = p_app_id
+          AND t.status = 'success'
+          AND (t.created_at AT TIME ZONE tz)::date = d.day::date
+    GROUP BY d.day
+    ON CONFLICT (app_id, metric_name, metric_date, dimensions) DO UPDATE
+        SET metric_value = EXCLUDED.metric_value,
+            reporting_timezone = EXCLUDED.reporting_timezone,
+            updated_at = now();
+
+    RETURN replaced;
+END;
+$$;
+
+-- ── Dashboard views ──────────────────────────────────────────────────────────
+-- Rebuilt to bucket days in each app's zone; a zone change takes effect on
+-- the next refresh.
+DROP MATERIALIZED VIEW IF EXISTS mv_daily_revenue;
+DROP MATERIALIZED VIEW IF EXISTS mv_conversion_by_platform;
+
+CREATE MATERIALIZED VIEW mv_daily_revenue AS
+SELECT t.app_id,
+       (t.created_at AT TIME ZONE COALESCE(NULLIF(a.settings->>'reporting_timezone', ''), 'UTC'))::date AS day,
+       t.currency,
+       COALESCE(SUM(t.amount) FILTER (WHERE t.status = 'success'), 0)  AS revenue,
+       COALESCE(SUM(t.amount) FILTER (WHERE t.status = 'refunded'), 0) AS refunded,
+       COUNT(*) FILTER (WHERE t.status = 'success')                    AS transactions
+FROM transactions t
+JOIN apps a ON a.id = t.app_id
+WHERE t.created_at >= now() - INTERVAL '400 days'
+  AND t.environment = 'production'
+GROUP BY t.app_id, day, t.currency;
+
+CREATE UNIQUE INDEX idx_mv_daily_revenue_key ON mv_daily_revenue(app_id, day, currency);
+
+CREATE MATERIALIZED VIEW mv_conversion_by_platform AS
+SELECT u.app_id,
+       u.platform,
+       (u.created_at AT TIME ZONE COALESCE(NULLIF(a.settings->>'reporting_timezone', ''), 'UTC'))::date AS cohort_day,
+       COUNT(*)                                AS users,
+       COUNT(*) FILTER (WHERE EXISTS (
+           SELECT 1 FROM subscriptions s WHERE s.user_id = u.id
+       ))                                      AS converted
+FROM users u
+JOIN apps a ON a.id = u.app_id
+WHERE u.created_at >= now() - INTERVAL '400 days' AND u.deleted_at IS NULL
+GROUP BY u.app_id, u.platform, cohort_day;
+
+CREATE UNIQUE INDEX idx_mv_conversion_by_platform_key
+    ON mv_conversion_by_platform(app_id, platform, cohort_day);
+
+UPDATE dashboard_view_refreshes SET refreshed_at = now()
+WHERE view_name IN ('mv_daily_revenue', 'mv_conversion_by_platform');
@@ -0,0 +1,29 @@
+-- Migration 068: analytics_backfill_runs — progress of historical recomputes
+-- cmd/backfill recomputes aggregates, signup cohorts and user LTV over a date
+-- range one chunk of days at a time. Each chunk commits together with the
+-- run's cursor, so an interrupted run resumes after its last finished chunk
+-- and re-running a chunk only overwrites the same rows.
+
+CREATE TABLE IF NOT EXISTS analytics_backfill_runs (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    -- NULL covers every active app
+    app_id       UUID REFERENCES apps(id) ON DELETE CASCADE,
+    from_date    DATE NOT NULL,
+    to_date      DATE NOT NULL,
+    chunk_days   INT NOT NULL CHECK (chunk_days BETWEEN 1 AND 366),
+    steps        TEXT[] NOT NULL,
+    status       TEXT NOT NULL DEFAULT 'running' CHECK (status IN ('running', 'completed', 'failed')),
+    -- Last local day fully recomputed; NULL before the first chunk
+    cursor_date  DATE,
+    chunks_done  INT NOT NULL DEFAULT 0,
+    chunks_total INT NOT NULL,
+    last_error   TEXT,
+    started_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    completed_at TIMESTAMPTZ,
+    CHECK (from_date <= to_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_analytics_backfill_runs_started ON analytics_backfill_runs(started_at DESC);
+
+COMMENT ON TABLE analytics_backfill_runs IS 'Chunked recomputes of historical analytics with resumable progress';
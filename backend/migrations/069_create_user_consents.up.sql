@@ -0,0 +1,18 @@
+-- Migration 069: user_consents — what each user allows their data to be used for
+-- Users without a row have never chosen and are treated as consenting to
+-- every purpose; consent is enforced as an opt-out. Without analytics consent
+-- events are ingested and forwarded to Matomo anonymously; without
+-- personalization consent bandits ignore the user's context features.
+
+CREATE TABLE IF NOT EXISTS user_consents (
+    user_id         UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    app_id          UUID NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+    analytics       BOOLEAN NOT NULL,
+    personalization BOOLEAN NOT NULL,
+    marketing       BOOLEAN NOT NULL,
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_consents_app ON user_consents(app_id);
+
+COMMENT ON TABLE user_consents IS 'Per-user analytics, personalization and marketing consent';
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS install_attributions;
+DROP TABLE IF EXISTS skan_postbacks;
+DROP TABLE IF EXISTS skan_conversion_schemas;
@@ -0,0 +1,65 @@
+-- Migration 070: SKAdNetwork postbacks and IDFA-less install attribution
+-- Apps that cannot read the IDFA report installs through SKAdNetwork: Apple
+-- posts an anonymous, delayed postback per install carrying the ad network,
+-- campaign and the conversion value the app last set. Conversion values are
+-- decoded with the schema version the app used. Postbacks are then matched to
+-- signups probabilistically (same app and conversion value, sign-up inside the
+-- postback's timer window), and each match records its confidence.
+
+CREATE TABLE IF NOT EXISTS skan_conversion_schemas (
+    id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    app_id     UUID NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+    version    INT NOT NULL,
+    -- [{value, min_revenue, coarse}] ordered by value; revenue is what the
+    -- user paid in their first 24 hours
+    buckets    JSONB NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (app_id, version)
+);
+
+CREATE TABLE IF NOT EXISTS skan_postbacks (
+    id                      UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    app_id                  UUID NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+    transaction_id          TEXT NOT NULL UNIQUE,
+    skan_version            TEXT NOT NULL,
+    ad_network_id           TEXT NOT NULL,
+    -- campaign-id before SKAdNetwork 4, source-identifier from 4 on
+    source_identifier       TEXT,
+    source_app_id           TEXT,
+    source_domain           TEXT,
+    fidelity_type           INT,
+    redownload              BOOLEAN NOT NULL DEFAULT false,
+    did_win                 BOOLEAN NOT NULL DEFAULT true,
+    postback_sequence_index INT NOT NULL DEFAULT 0,
+    conversion_value        INT CHECK (conversion_value BETWEEN 0 AND 63),
+    coarse_conversion_value TEXT CHECK (coarse_conversion_value IN ('low', 'medium', 'high')),
+    signature_verified      BOOLEAN NOT NULL DEFAULT false,
+    payload                 JSONB NOT NULL,
+    received_at             TIMESTAMPTZ NOT NULL DEFAULT now(),
+    -- Set once matching ran, whether or not a signup matched
+    processed_at            TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_skan_postbacks_unattributed
+    ON skan_postbacks(received_at) WHERE processed_at IS NULL AND postback_sequence_index = 0 AND did_win;
+CREATE INDEX IF NOT EXISTS idx_skan_postbacks_app_received ON skan_postbacks(app_id, received_at);
+
+CREATE TABLE IF NOT EXISTS install_attributions (
+    user_id           UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    app_id            UUID NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+    postback_id       UUID NOT NULL UNIQUE REFERENCES skan_postbacks(id) ON DELETE CASCADE,
+    ad_network_id     TEXT NOT NULL,
+    source_identifier TEXT,
+    method            TEXT NOT NULL DEFAULT 'skan_probabilistic' CHECK (method IN ('skan_probabilistic')),
+    -- 1 / number of signups the postback could have come from
+    confidence        DOUBLE PRECISION NOT NULL CHECK (confidence > 0 AND confidence <= 1),
+    attributed_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_install_attributions_campaign
+    ON install_attributions(app_id, ad_network_id, source_identifier);
+
+COMMENT ON TABLE skan_conversion_schemas IS 'Versioned mapping of SKAdNetwork conversion values to first-day revenue';
+COMMENT ON TABLE skan_postbacks IS 'SKAdNetwork install postbacks, deduplicated by transaction ID';
+COMMENT ON TABLE install_attributions IS 'Probabilistic match of a signup to the campaign of a SKAdNetwork postback';
@@ -0,0 +1,31 @@
+-- Migration 071: user_acquisitions — campaign attribution reported by MMPs
+-- Adjust and AppsFlyer call back on installs and reattributions with the
+-- media source, campaign and ad set that won. Callbacks are keyed by the
+-- customer user ID the app set in the MMP SDK (our user ID or platform user
+-- ID); callbacks that arrive before the user signs up are kept with user_id
+-- NULL and linked by the worker once the user exists. A reattribution
+-- replaces the campaign but keeps the original install time.
+
+CREATE TABLE IF NOT EXISTS user_acquisitions (
+    app_id           UUID NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+    customer_user_id TEXT NOT NULL,
+    user_id          UUID REFERENCES users(id) ON DELETE CASCADE,
+    provider         TEXT NOT NULL CHECK (provider IN ('adjust', 'appsflyer')),
+    event            TEXT NOT NULL CHECK (event IN ('install', 'reattribution')),
+    channel          TEXT NOT NULL CHECK (channel IN ('organic', 'paid_social', 'paid_search', 'paid_other')),
+    media_source     TEXT NOT NULL DEFAULT '',
+    campaign         TEXT NOT NULL DEFAULT '',
+    campaign_id      TEXT NOT NULL DEFAULT '',
+    adset            TEXT NOT NULL DEFAULT '',
+    ad               TEXT NOT NULL DEFAULT '',
+    installed_at     TIMESTAMPTZ,
+    -- When the MMP attributed the current campaign
+    attributed_at    TIMESTAMPTZ NOT NULL,
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (app_id, customer_user_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_acquisitions_user ON user_acquisitions(user_id) WHERE user_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_user_acquisitions_unresolved ON user_acquisitions(app_id) WHERE user_id IS NULL;
+
+COMMENT ON TABLE user_acquisitions IS 'Latest MMP (Adjust/AppsFlyer) campaign attribution per user';
@@ -0,0 +1,17 @@
+DROP TABLE IF EXISTS warehouse_sync_state;
+
+DROP INDEX IF EXISTS idx_bandit_conversion_events_created_id;
+DROP INDEX IF EXISTS idx_ab_test_assignments_updated_id;
+DROP INDEX IF EXISTS idx_transactions_updated_id;
+DROP INDEX IF EXISTS idx_subscriptions_updated_id;
+DROP INDEX IF EXISTS idx_users_updated_id;
+
+DROP TRIGGER IF EXISTS trg_ab_test_assignments_updated_at ON ab_test_assignments;
+DROP TRIGGER IF EXISTS trg_transactions_updated_at ON transactions;
+DROP TRIGGER IF EXISTS trg_subscriptions_updated_at ON subscriptions;
+DROP TRIGGER IF EXISTS trg_users_updated_at ON users;
+DROP FUNCTION IF EXISTS touch_updated_at();
+
+ALTER TABLE ab_test_assignments DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE transactions        DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE users               DROP COLUMN IF EXISTS updated_at;
@@ -0,0 +1,62 @@
+-- Migration 072: incremental data warehouse sync
+-- The warehouse sync job copies core tables to BigQuery or Snowflake with
+-- MERGE, reading rows changed after each table's watermark in
+-- (watermark column, id) order. Tables that are updated in place get an
+-- updated_at maintained by trigger so every change moves the row past the
+-- watermark. Hard deletes are not propagated.
+
+ALTER TABLE users               ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE transactions        ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE ab_test_assignments ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+CREATE OR REPLACE FUNCTION touch_updated_at()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = now();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_users_updated_at
+    BEFORE UPDATE ON users
+    FOR EACH ROW
+    EXECUTE FUNCTION touch_updated_at();
+
+CREATE TRIGGER trg_subscriptions_updated_at
+    BEFORE UPDATE ON subscriptions
+    FOR EACH ROW
+    EXECUTE FUNCTION touch_updated_at();
+
+CREATE TRIGGER trg_transactions_updated_at
+    BEFORE UPDATE ON transactions
+    FOR EACH ROW
+    EXECUTE FUNCTION touch_updated_at();
+
+CREATE TRIGGER trg_ab_test_assignments_updated_at
+    BEFORE UPDATE ON ab_test_assignments
+    FOR EACH ROW
+    EXECUTE FUNCTION touch_updated_at();
+
+CREATE INDEX IF NOT EXISTS idx_users_updated_id               ON users(updated_at, id);
+CREATE INDEX IF NOT EXISTS idx_subscriptions_updated_id       ON subscriptions(updated_at, id);
+CREATE INDEX IF NOT EXISTS idx_transactions_updated_id        ON transactions(updated_at, id);
+CREATE INDEX IF NOT EXISTS idx_ab_test_assignments_updated_id ON ab_test_assignments(updated_at, id);
+CREATE INDEX IF NOT EXISTS idx_bandit_conversion_events_created_id ON bandit_conversion_events(created_at, id);
+
+CREATE TABLE IF NOT EXISTS warehouse_sync_state (
+    source_table    TEXT PRIMARY KEY,
+    destination     TEXT NOT NULL,
+    -- Last (watermark column, id) merged; NULL before the first batch
+    watermark_at    TIMESTAMPTZ,
+    watermark_id    UUID,
+    -- [{name, type}] as created in the warehouse; source type changes keep
+    -- the warehouse type and new source columns are appended
+    columns         JSONB NOT NULL DEFAULT '[]',
+    rows_synced     BIGINT NOT NULL DEFAULT 0,
+    status          TEXT NOT NULL DEFAULT 'idle' CHECK (status IN ('idle', 'running', 'failed')),
+    last_error      TEXT,
+    last_run_at     TIMESTAMPTZ,
+    last_success_at TIMESTAMPTZ
+);
+
+COMMENT ON TABLE warehouse_sync_state IS 'Per-table watermark and schema of the incremental warehouse sync';
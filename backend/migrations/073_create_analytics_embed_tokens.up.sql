@@ -0,0 +1,23 @@
+-- Migration 073: analytics_embed_tokens — scoped tokens for embedded dashboards
+-- Admins mint short-lived signed tokens that let a customer-facing front-end
+-- query a fixed set of metrics and dimensions for one app without an admin
+-- JWT. The signed token carries only the row ID; scope, range limit and rate
+-- limit are read from the row so a token can be revoked before it expires.
+
+CREATE TABLE IF NOT EXISTS analytics_embed_tokens (
+    id                    UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    app_id                UUID NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+    label                 TEXT NOT NULL DEFAULT '',
+    metrics               TEXT[] NOT NULL,
+    dimensions            TEXT[] NOT NULL,
+    max_range_days        INT NOT NULL CHECK (max_range_days BETWEEN 1 AND 366),
+    rate_limit_per_minute INT NOT NULL CHECK (rate_limit_per_minute BETWEEN 1 AND 600),
+    created_by            UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at            TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at            TIMESTAMPTZ NOT NULL,
+    revoked_at            TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_analytics_embed_tokens_app ON analytics_embed_tokens(app_id, created_at DESC);
+
+COMMENT ON TABLE analytics_embed_tokens IS 'Scoped, short-lived tokens for the embedded analytics API';
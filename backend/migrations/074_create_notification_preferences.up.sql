@@ -0,0 +1,33 @@
+-- Migration 074: notification preferences, quiet hours and suppression counts
+-- Users without a preferences row receive every notification. Quiet hours are
+-- minutes of the day in the user's timezone and may wrap past midnight;
+-- notifications held back by a preference are dropped, not deferred, and
+-- counted per app, day, category, channel and reason.
+
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id             UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    app_id              UUID NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+    push_enabled        BOOLEAN NOT NULL DEFAULT true,
+    email_enabled       BOOLEAN NOT NULL DEFAULT true,
+    disabled_categories TEXT[] NOT NULL DEFAULT '{}',
+    quiet_start_minute  SMALLINT CHECK (quiet_start_minute BETWEEN 0 AND 1439),
+    quiet_end_minute    SMALLINT CHECK (quiet_end_minute BETWEEN 0 AND 1439),
+    timezone            TEXT NOT NULL DEFAULT 'UTC',
+    updated_at          TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK ((quiet_start_minute IS NULL) = (quiet_end_minute IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_preferences_app ON notification_preferences(app_id);
+
+CREATE TABLE IF NOT EXISTS notification_suppressions (
+    app_id   UUID NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+    day      DATE NOT NULL,
+    category TEXT NOT NULL,
+    channel  TEXT NOT NULL CHECK (channel IN ('push', 'email')),
+    reason   TEXT NOT NULL CHECK (reason IN ('channel_disabled', 'category_disabled', 'quiet_hours', 'no_consent')),
+    count    BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (app_id, day, category, channel, reason)
+);
+
+COMMENT ON TABLE notification_preferences IS 'Per-user notification channels, categories and quiet hours';
+COMMENT ON TABLE notification_suppressions IS 'Daily counts of notifications suppressed by user preferences';
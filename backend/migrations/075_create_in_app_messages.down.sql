@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS in_app_message_events;
+DROP TABLE IF EXISTS in_app_messages;
@@ -0,0 +1,52 @@
+-- Migration 075: scheduled in-app messages (banners, modals)
+-- A message is live between starts_at and ends_at while enabled. Its trigger
+-- ties it to the user's subscription state (trial ending, lapsed), conditions
+-- reuse the paywall rule predicates and, when experiment_id is set, the
+-- bandit picks one of the variants (keyed by arm). Events cap how often a
+-- user sees a message and score the chosen arm: a click is a success and a
+-- dismissal a failure.
+
+CREATE TABLE IF NOT EXISTS in_app_messages (
+    id                   UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    app_id               UUID NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+    name                 TEXT NOT NULL,
+    placement            TEXT NOT NULL DEFAULT '',
+    format               TEXT NOT NULL CHECK (format IN ('banner', 'modal')),
+    trigger              TEXT NOT NULL DEFAULT 'always' CHECK (trigger IN ('always', 'trial_ending', 'lapsed')),
+    trigger_window_hours INTEGER NOT NULL DEFAULT 0 CHECK (trigger_window_hours >= 0),
+    priority             INTEGER NOT NULL DEFAULT 0,
+    enabled              BOOLEAN NOT NULL DEFAULT true,
+    starts_at            TIMESTAMPTZ,
+    ends_at              TIMESTAMPTZ,
+    conditions           JSONB NOT NULL DEFAULT '{}'::jsonb,
+    content              JSONB NOT NULL DEFAULT '{}'::jsonb,
+    experiment_id        UUID REFERENCES ab_tests(id) ON DELETE SET NULL,
+    variants             JSONB NOT NULL DEFAULT '[]'::jsonb,
+    max_impressions      INTEGER NOT NULL DEFAULT 0 CHECK (max_impressions >= 0),
+    min_interval_hours   INTEGER NOT NULL DEFAULT 0 CHECK (min_interval_hours >= 0),
+    created_at           TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at           TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK (ends_at IS NULL OR starts_at IS NULL OR ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_in_app_messages_app_priority
+    ON in_app_messages(app_id, priority DESC, created_at)
+    WHERE enabled = true;
+
+CREATE TABLE IF NOT EXISTS in_app_message_events (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    message_id  UUID NOT NULL REFERENCES in_app_messages(id) ON DELETE CASCADE,
+    user_id     UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    arm_id      UUID,
+    event       TEXT NOT NULL CHECK (event IN ('impression', 'click', 'dismiss')),
+    occurred_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_in_app_message_events_user ON in_app_message_events(user_id, message_id);
+CREATE INDEX IF NOT EXISTS idx_in_app_message_events_message ON in_app_message_events(message_id, event);
+
+COMMENT ON TABLE in_app_messages IS 'Scheduled in-app prompts returned by GET /v1/messages';
+COMMENT ON COLUMN in_app_messages.trigger_window_hours IS 'trial_ending: hours before the trial ends; lapsed: hours since the subscription ended (0 = any time)';
+COMMENT ON COLUMN in_app_messages.variants IS 'JSON [{arm_id, content}] the bandit chooses from when experiment_id is set';
+COMMENT ON COLUMN in_app_messages.max_impressions IS 'Impressions per user before the message stops showing; 0 = unlimited';
+COMMENT ON TABLE in_app_message_events IS 'Per-user impressions, clicks and dismissals of in-app messages';
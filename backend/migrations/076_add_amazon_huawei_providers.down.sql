@@ -0,0 +1,16 @@
+ALTER TABLE webhook_quarantine
+    DROP CONSTRAINT IF EXISTS webhook_quarantine_provider_check,
+    ADD CONSTRAINT webhook_quarantine_provider_check
+        CHECK (provider IN ('stripe', 'apple', 'google'));
+
+ALTER TABLE webhook_events
+    DROP CONSTRAINT IF EXISTS webhook_events_provider_check,
+    ADD CONSTRAINT webhook_events_provider_check
+        CHECK (provider IN ('stripe', 'apple', 'google', 'paddle'));
+
+ALTER TABLE transactions
+    DROP CONSTRAINT IF EXISTS chk_transactions_provider,
+    ADD CONSTRAINT chk_transactions_provider
+        CHECK (provider IN ('apple', 'google', 'stripe', 'paddle'));
+
+COMMENT ON COLUMN transactions.provider IS 'Store that collected the payment: apple, google, stripe or paddle';
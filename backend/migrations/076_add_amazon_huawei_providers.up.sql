@@ -0,0 +1,20 @@
+-- Migration 076: Amazon Appstore and Huawei AppGallery
+-- Purchases from both stores arrive as android subscriptions; the provider on
+-- the ledger, webhook events and quarantine tells them apart from Google Play.
+
+ALTER TABLE transactions
+    DROP CONSTRAINT IF EXISTS chk_transactions_provider,
+    ADD CONSTRAINT chk_transactions_provider
+        CHECK (provider IN ('apple', 'google', 'stripe', 'paddle', 'amazon', 'huawei'));
+
+ALTER TABLE webhook_events
+    DROP CONSTRAINT IF EXISTS webhook_events_provider_check,
+    ADD CONSTRAINT webhook_events_provider_check
+        CHECK (provider IN ('stripe', 'apple', 'google', 'paddle', 'amazon', 'huawei'));
+
+ALTER TABLE webhook_quarantine
+    DROP CONSTRAINT IF EXISTS webhook_quarantine_provider_check,
+    ADD CONSTRAINT webhook_quarantine_provider_check
+        CHECK (provider IN ('stripe', 'apple', 'google', 'amazon', 'huawei'));
+
+COMMENT ON COLUMN transactions.provider IS 'Store that collected the payment: apple, google, amazon, huawei, stripe or paddle';
@@ -0,0 +1,24 @@
+DROP TABLE IF EXISTS paypal_subscriptions;
+DROP TABLE IF EXISTS paypal_plans;
+
+ALTER TABLE webhook_quarantine
+    DROP CONSTRAINT IF EXISTS webhook_quarantine_provider_check,
+    ADD CONSTRAINT webhook_quarantine_provider_check
+        CHECK (provider IN ('stripe', 'apple', 'google', 'amazon', 'huawei'));
+
+ALTER TABLE webhook_events
+    DROP CONSTRAINT IF EXISTS webhook_events_provider_check,
+    ADD CONSTRAINT webhook_events_provider_check
+        CHECK (provider IN ('stripe', 'apple', 'google', 'paddle', 'amazon', 'huawei'));
+
+ALTER TABLE transactions
+    DROP CONSTRAINT IF EXISTS chk_transactions_provider,
+    ADD CONSTRAINT chk_transactions_provider
+        CHECK (provider IN ('apple', 'google', 'stripe', 'paddle', 'amazon', 'huawei'));
+
+ALTER TABLE subscriptions
+    DROP CONSTRAINT IF EXISTS subscriptions_source_check,
+    ADD CONSTRAINT subscriptions_source_check
+        CHECK (source IN ('iap', 'stripe', 'paddle'));
+
+COMMENT ON COLUMN transactions.provider IS 'Store that collected the payment: apple, google, amazon, huawei, stripe or paddle';
@@ -0,0 +1,50 @@
+-- Migration 077: PayPal Billing subscriptions for web paywalls
+-- PayPal plans are mapped to products per app; each PayPal subscription is
+-- linked to the subscription it provisioned so webhooks can find it.
+
+ALTER TABLE subscriptions
+    DROP CONSTRAINT IF EXISTS subscriptions_source_check,
+    ADD CONSTRAINT subscriptions_source_check
+        CHECK (source IN ('iap', 'stripe', 'paddle', 'paypal'));
+
+ALTER TABLE transactions
+    DROP CONSTRAINT IF EXISTS chk_transactions_provider,
+    ADD CONSTRAINT chk_transactions_provider
+        CHECK (provider IN ('apple', 'google', 'stripe', 'paddle', 'amazon', 'huawei', 'paypal'));
+
+ALTER TABLE webhook_events
+    DROP CONSTRAINT IF EXISTS webhook_events_provider_check,
+    ADD CONSTRAINT webhook_events_provider_check
+        CHECK (provider IN ('stripe', 'apple', 'google', 'paddle', 'amazon', 'huawei', 'paypal'));
+
+ALTER TABLE webhook_quarantine
+    DROP CONSTRAINT IF EXISTS webhook_quarantine_provider_check,
+    ADD CONSTRAINT webhook_quarantine_provider_check
+        CHECK (provider IN ('stripe', 'apple', 'google', 'amazon', 'huawei', 'paypal'));
+
+CREATE TABLE paypal_plans (
+    plan_id     TEXT PRIMARY KEY,
+    app_id      UUID NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+    product_id  TEXT NOT NULL,
+    plan_type   TEXT NOT NULL CHECK (plan_type IN ('monthly', 'annual')),
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_paypal_plans_app ON paypal_plans(app_id);
+
+CREATE TABLE paypal_subscriptions (
+    paypal_subscription_id  TEXT PRIMARY KEY,
+    app_id                  UUID NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+    user_id                 UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    subscription_id         UUID NOT NULL REFERENCES subscriptions(id) ON DELETE CASCADE,
+    plan_id                 TEXT NOT NULL,
+    status                  TEXT NOT NULL,
+    created_at              TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at              TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_paypal_subscriptions_subscription ON paypal_subscriptions(subscription_id);
+
+COMMENT ON COLUMN transactions.provider IS 'Store that collected the payment: apple, google, amazon, huawei, stripe, paddle or paypal';
+COMMENT ON COLUMN paypal_subscriptions.status IS 'Last PayPal subscription status seen: APPROVAL_PENDING, APPROVED, ACTIVE, SUSPENDED, CANCELLED or EXPIRED';
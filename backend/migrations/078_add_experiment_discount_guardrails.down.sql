@@ -0,0 +1,12 @@
+DELETE FROM experiment_automation_decision_log WHERE decision_type = 'arm_guardrail_stop';
+
+ALTER TABLE experiment_automation_decision_log
+    DROP CONSTRAINT IF EXISTS experiment_automation_decision_log_decision_type_check,
+    ADD CONSTRAINT experiment_automation_decision_log_decision_type_check
+        CHECK (decision_type IN ('status_transition'));
+
+ALTER TABLE ab_tests DROP COLUMN IF EXISTS revenue_guardrail;
+
+ALTER TABLE ab_test_arms
+    DROP COLUMN IF EXISTS archive_reason,
+    DROP COLUMN IF EXISTS discount;
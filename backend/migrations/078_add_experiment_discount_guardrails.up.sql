@@ -0,0 +1,21 @@
+-- Migration 078: discount experiment arms with revenue guardrails
+-- An arm may carry the discount it offers (percent or fixed, optional coupon
+-- code). The experiment's revenue_guardrail caps the revenue-per-user loss a
+-- discount arm may project against control; the automation reconciler
+-- archives an arm that breaches it and records why.
+
+ALTER TABLE ab_test_arms
+    ADD COLUMN IF NOT EXISTS discount JSONB,
+    ADD COLUMN IF NOT EXISTS archive_reason VARCHAR(32)
+        CHECK (archive_reason IN ('manual', 'revenue_guardrail'));
+
+ALTER TABLE ab_tests ADD COLUMN IF NOT EXISTS revenue_guardrail JSONB;
+
+ALTER TABLE experiment_automation_decision_log
+    DROP CONSTRAINT IF EXISTS experiment_automation_decision_log_decision_type_check,
+    ADD CONSTRAINT experiment_automation_decision_log_decision_type_check
+        CHECK (decision_type IN ('status_transition', 'arm_guardrail_stop'));
+
+COMMENT ON COLUMN ab_test_arms.discount IS 'Discount the arm offers: {"type":"percent"|"fixed","value":..,"coupon_code":..,"duration_periods":..}';
+COMMENT ON COLUMN ab_test_arms.archive_reason IS 'Why the arm was archived: manual or revenue_guardrail';
+COMMENT ON COLUMN ab_tests.revenue_guardrail IS 'Revenue guardrail for discount arms: {"enabled":..,"max_revenue_loss_percent":..,"min_samples":..}';
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS experiment_meta_snapshots;
+DROP TABLE IF EXISTS experiment_outcomes;
+DROP INDEX IF EXISTS idx_ab_tests_completed_category;
+ALTER TABLE ab_tests DROP COLUMN IF EXISTS category;
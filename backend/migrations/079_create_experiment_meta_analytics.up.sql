@@ -0,0 +1,51 @@
+-- Migration 079: cross-experiment meta-analytics
+-- Experiments carry an optional category (templates set their key). A daily
+-- job records the outcome of every completed experiment and snapshots the
+-- per-category aggregates, so the experimentation program's win rate, lift
+-- and revenue gained can be followed over time.
+
+ALTER TABLE ab_tests ADD COLUMN IF NOT EXISTS category VARCHAR(32);
+
+CREATE INDEX IF NOT EXISTS idx_ab_tests_completed_category
+    ON ab_tests(app_id, category) WHERE status = 'completed';
+
+CREATE TABLE IF NOT EXISTS experiment_outcomes (
+    experiment_id           UUID PRIMARY KEY REFERENCES ab_tests(id) ON DELETE CASCADE,
+    app_id                  UUID NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+    category                VARCHAR(32) NOT NULL,
+    objective_type          VARCHAR(20) NOT NULL,
+    metric                  VARCHAR(20) NOT NULL CHECK (metric IN ('conversion_rate', 'revenue_per_user')),
+    completed_at            TIMESTAMPTZ NOT NULL,
+    winner_arm_id           UUID,
+    winner_is_control       BOOLEAN NOT NULL DEFAULT false,
+    launched                BOOLEAN NOT NULL DEFAULT false,
+    control_metric          DOUBLE PRECISION NOT NULL,
+    variant_metric          DOUBLE PRECISION,
+    lift_percent            DOUBLE PRECISION,
+    revenue_per_user_uplift DOUBLE PRECISION NOT NULL DEFAULT 0,
+    users_since_completion  BIGINT NOT NULL DEFAULT 0,
+    revenue_gained          DOUBLE PRECISION NOT NULL DEFAULT 0,
+    recorded_at             TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_experiment_outcomes_app
+    ON experiment_outcomes(app_id, completed_at DESC);
+
+CREATE TABLE IF NOT EXISTS experiment_meta_snapshots (
+    app_id           UUID NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+    snapshot_date    DATE NOT NULL,
+    category         VARCHAR(32) NOT NULL,
+    experiments      INT NOT NULL,
+    conclusive       INT NOT NULL,
+    wins             INT NOT NULL,
+    launched         INT NOT NULL,
+    win_rate         DOUBLE PRECISION NOT NULL,
+    avg_lift_percent DOUBLE PRECISION,
+    revenue_gained   DOUBLE PRECISION NOT NULL,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (app_id, snapshot_date, category)
+);
+
+COMMENT ON COLUMN ab_tests.category IS 'Experiment category used by meta-analytics, e.g. price_test; NULL reports as uncategorized';
+COMMENT ON TABLE experiment_outcomes IS 'Latest recorded outcome of each completed experiment: winner, lift against control and revenue gained since launch';
+COMMENT ON TABLE experiment_meta_snapshots IS 'Daily per-category aggregates of experiment outcomes; category all holds the app-wide totals';
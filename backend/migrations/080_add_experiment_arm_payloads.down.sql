@@ -0,0 +1 @@
+ALTER TABLE ab_test_arms DROP COLUMN IF EXISTS payload;
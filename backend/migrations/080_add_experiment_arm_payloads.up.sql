@@ -0,0 +1,10 @@
+-- Migration 080: variant content per experiment arm
+-- The payload holds what a client renders for the arm (paywall copy, price
+-- ID, image URL, layout key and flat app-specific properties). It is
+-- validated by the API and returned with every assignment.
+
+ALTER TABLE ab_test_arms
+    ADD COLUMN IF NOT EXISTS payload JSONB
+        CONSTRAINT ab_test_arms_payload_is_object CHECK (payload IS NULL OR jsonb_typeof(payload) = 'object');
+
+COMMENT ON COLUMN ab_test_arms.payload IS 'Variant content: {"copy":{..},"price_id":..,"image_url":..,"layout_key":..,"properties":{..}}';
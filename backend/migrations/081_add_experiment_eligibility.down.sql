@@ -0,0 +1 @@
+ALTER TABLE ab_tests DROP COLUMN IF EXISTS eligibility;
@@ -0,0 +1,10 @@
+-- Migration 081: experiment eligibility and geo-fencing
+-- Restricts an experiment to countries, platforms, app versions and minimum
+-- OS versions. Users outside it are shown the control arm without being
+-- assigned.
+
+ALTER TABLE ab_tests
+    ADD COLUMN IF NOT EXISTS eligibility JSONB
+        CONSTRAINT ab_tests_eligibility_is_object CHECK (eligibility IS NULL OR jsonb_typeof(eligibility) = 'object');
+
+COMMENT ON COLUMN ab_tests.eligibility IS 'Eligibility: {"countries":[..],"platforms":[..],"app_versions":[..],"min_os_versions":{platform: version}}';
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_ab_test_assignments_app_version;
+ALTER TABLE ab_test_assignments DROP COLUMN IF EXISTS app_version;
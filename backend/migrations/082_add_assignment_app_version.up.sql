@@ -0,0 +1,18 @@
+-- Migration 082: app version at assignment time
+-- Lets experiment results be segmented by the app version users were on when
+-- they were assigned. Existing assignments are backfilled with the user's
+-- current version.
+
+ALTER TABLE ab_test_assignments
+    ADD COLUMN IF NOT EXISTS app_version TEXT;
+
+UPDATE ab_test_assignments s
+SET app_version = u.app_version
+FROM users u
+WHERE u.id = s.user_id
+  AND s.app_version IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_ab_test_assignments_app_version
+    ON ab_test_assignments(experiment_id, app_version);
+
+COMMENT ON COLUMN ab_test_assignments.app_version IS 'User app version when the assignment was made';
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS bandit_conversion_transactions;
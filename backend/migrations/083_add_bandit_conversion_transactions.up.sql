@@ -0,0 +1,22 @@
+-- Migration 083: one bandit conversion per transaction
+-- A webhook retry and a client report can both carry the same purchase. Each
+-- transaction is claimed here before its reward is applied, so a replay on any
+-- path is recognised and leaves the arm stats untouched.
+
+CREATE TABLE IF NOT EXISTS bandit_conversion_transactions (
+    transaction_id UUID PRIMARY KEY,
+    experiment_id UUID NOT NULL REFERENCES ab_tests(id) ON DELETE CASCADE,
+    arm_id UUID NOT NULL REFERENCES ab_test_arms(id) ON DELETE CASCADE,
+    source TEXT NOT NULL CHECK (source IN ('direct_reward', 'delayed_conversion')),
+    recorded_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+INSERT INTO bandit_conversion_transactions (transaction_id, experiment_id, arm_id, source, recorded_at)
+SELECT DISTINCT ON (transaction_id) transaction_id, experiment_id, arm_id, event_type, occurred_at
+FROM bandit_conversion_events
+WHERE transaction_id IS NOT NULL
+  AND event_type IN ('direct_reward', 'delayed_conversion')
+ORDER BY transaction_id, occurred_at ASC
+ON CONFLICT (transaction_id) DO NOTHING;
+
+COMMENT ON TABLE bandit_conversion_transactions IS 'Transactions already counted as a bandit conversion, used to deduplicate replays';
@@ -0,0 +1,10 @@
+-- Migration 084: per-experiment conversion window
+-- Pending bandit rewards expired after a global 7 days. Price tests convert
+-- within hours while onboarding tests need weeks, so each experiment may set
+-- its own window. NULL keeps the engine default.
+
+ALTER TABLE ab_tests
+    ADD COLUMN IF NOT EXISTS conversion_window_hours INTEGER
+    CHECK (conversion_window_hours BETWEEN 1 AND 720);
+
+COMMENT ON COLUMN ab_tests.conversion_window_hours IS 'Hours a pending reward waits for a conversion; NULL uses the default';
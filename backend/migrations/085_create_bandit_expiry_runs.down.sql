@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_bandit_pending_rewards_expiry_page;
+DROP TABLE IF EXISTS bandit_expiry_runs;
@@ -0,0 +1,36 @@
+-- Migration 085: bandit_expiry_runs — paced sweeps of expired pending rewards
+-- Expired pending rewards are recorded as non-conversions by a worker task
+-- that pages through them in (expires_at, id) order. Each page checkpoints the
+-- cursor and counters here, so a run interrupted by a deploy resumes after its
+-- last page and an admin can watch it progress.
+
+CREATE TABLE IF NOT EXISTS bandit_expiry_runs (
+    id                UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    status            TEXT NOT NULL DEFAULT 'running' CHECK (status IN ('running', 'completed', 'failed')),
+    trigger           TEXT NOT NULL CHECK (trigger IN ('scheduled', 'admin')),
+    batch_size        INT NOT NULL CHECK (batch_size BETWEEN 1 AND 5000),
+    -- Rewards recorded per second; 0 is unpaced
+    rate_per_second   INT NOT NULL CHECK (rate_per_second BETWEEN 0 AND 10000),
+    max_batches       INT NOT NULL DEFAULT 0 CHECK (max_batches >= 0),
+    -- Only rewards that expired by this time are swept
+    expired_before    TIMESTAMPTZ NOT NULL,
+    -- Last reward a finished page reached; NULL before the first page
+    cursor_expires_at TIMESTAMPTZ,
+    cursor_id         UUID,
+    batches_done      INT NOT NULL DEFAULT 0,
+    processed         INT NOT NULL DEFAULT 0,
+    skipped           INT NOT NULL DEFAULT 0,
+    failed            INT NOT NULL DEFAULT 0,
+    last_error        TEXT,
+    started_at        TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at        TIMESTAMPTZ NOT NULL DEFAULT now(),
+    completed_at      TIMESTAMPTZ
+);
+
+-- At most one sweep runs at a time
+CREATE UNIQUE INDEX IF NOT EXISTS idx_bandit_expiry_runs_running ON bandit_expiry_runs((true)) WHERE status = 'running';
+CREATE INDEX IF NOT EXISTS idx_bandit_expiry_runs_started ON bandit_expiry_runs(started_at DESC);
+CREATE INDEX IF NOT EXISTS idx_bandit_pending_rewards_expiry_page ON bandit_pending_rewards(expires_at, id)
+    WHERE converted = FALSE AND processed_at IS NULL;
+
+COMMENT ON TABLE bandit_expiry_runs IS 'Paged, rate-limited sweeps of expired bandit pending rewards with resumable progress';
@@ -0,0 +1,9 @@
+DELETE FROM bandit_conversion_events WHERE event_type = 'notification_open';
+ALTER TABLE bandit_conversion_events DROP CONSTRAINT IF EXISTS bandit_conversion_events_event_type_check;
+ALTER TABLE bandit_conversion_events
+    ADD CONSTRAINT bandit_conversion_events_event_type_check
+    CHECK (event_type IN ('direct_reward', 'delayed_conversion', 'expired_pending_reward'));
+
+DROP TABLE IF EXISTS notification_send_time_deliveries;
+DROP TABLE IF EXISTS notification_send_time_arms;
+DROP TABLE IF EXISTS notification_send_time_experiments;
@@ -0,0 +1,44 @@
+-- Migration 086: notification send-time bandits
+-- A send-time experiment is a bandit in ab_tests whose arms are hours of the
+-- day. Each push scheduled at an arm's hour records a delivery and a pending
+-- reward that expires reward_window_hours after the send. An open, or for
+-- conversion-rewarded experiments a purchase, credits the hour; the pending
+-- reward expiry sweep counts the rest as misses.
+
+CREATE TABLE IF NOT EXISTS notification_send_time_experiments (
+    experiment_id UUID PRIMARY KEY REFERENCES ab_tests(id) ON DELETE CASCADE,
+    reward_event  TEXT NOT NULL CHECK (reward_event IN ('open', 'conversion')),
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS notification_send_time_arms (
+    arm_id        UUID PRIMARY KEY REFERENCES ab_test_arms(id) ON DELETE CASCADE,
+    experiment_id UUID NOT NULL REFERENCES notification_send_time_experiments(experiment_id) ON DELETE CASCADE,
+    -- Hour of the day, local to the recipient
+    send_hour     SMALLINT NOT NULL CHECK (send_hour BETWEEN 0 AND 23),
+    UNIQUE (experiment_id, send_hour)
+);
+
+CREATE TABLE IF NOT EXISTS notification_send_time_deliveries (
+    id                UUID PRIMARY KEY,
+    experiment_id     UUID NOT NULL REFERENCES notification_send_time_experiments(experiment_id) ON DELETE CASCADE,
+    arm_id            UUID NOT NULL REFERENCES ab_test_arms(id) ON DELETE CASCADE,
+    user_id           UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    pending_reward_id UUID NOT NULL UNIQUE REFERENCES bandit_pending_rewards(id) ON DELETE CASCADE,
+    reward_event      TEXT NOT NULL CHECK (reward_event IN ('open', 'conversion')),
+    send_hour         SMALLINT NOT NULL CHECK (send_hour BETWEEN 0 AND 23),
+    timezone          TEXT NOT NULL DEFAULT 'UTC',
+    scheduled_for     TIMESTAMPTZ NOT NULL,
+    sent_at           TIMESTAMPTZ,
+    opened_at         TIMESTAMPTZ,
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_send_time_deliveries_arm
+    ON notification_send_time_deliveries(experiment_id, arm_id);
+
+-- Opens are credited with their own event type
+ALTER TABLE bandit_conversion_events DROP CONSTRAINT IF EXISTS bandit_conversion_events_event_type_check;
+ALTER TABLE bandit_conversion_events
+    ADD CONSTRAINT bandit_conversion_events_event_type_check
+    CHECK (event_type IN ('direct_reward', 'delayed_conversion', 'expired_pending_reward', 'notification_open'));
@@ -0,0 +1,18 @@
+-- Migration 087: user lifecycle stages
+-- Every user's lifecycle stage is recomputed nightly from subscriptions,
+-- trials and session activity. entered_at is kept while the stage is
+-- unchanged, so it records when the user moved into it.
+
+CREATE TABLE IF NOT EXISTS user_lifecycle_stages (
+    user_id     UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    app_id      UUID NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+    stage       TEXT NOT NULL
+        CHECK (stage IN ('new', 'trialing', 'engaged_payer', 'at_risk', 'churned', 'reactivated')),
+    entered_at  TIMESTAMPTZ NOT NULL,
+    computed_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_lifecycle_stages_app_stage ON user_lifecycle_stages(app_id, stage);
+
+COMMENT ON TABLE user_lifecycle_stages IS 'Nightly lifecycle stage of each user';
+COMMENT ON COLUMN user_lifecycle_stages.entered_at IS 'When the user first got the current stage';
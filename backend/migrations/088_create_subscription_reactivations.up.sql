@@ -0,0 +1,33 @@
+-- Migration 088: subscription reactivations
+-- A subscription started after the user's previous one lapsed is a
+-- reactivation. Each is attributed to the win-back offer or offer code that
+-- drove it, and to the experiment arm the user was assigned after lapsing.
+-- mrr is the list-price monthly value in USD, as in the analytics report.
+
+CREATE TABLE IF NOT EXISTS subscription_reactivations (
+    subscription_id     UUID PRIMARY KEY REFERENCES subscriptions(id) ON DELETE CASCADE,
+    app_id              UUID NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+    user_id             UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    plan_type           TEXT NOT NULL CHECK (plan_type IN ('monthly', 'annual', 'lifetime')),
+    mrr                 NUMERIC(10,2) NOT NULL,
+    lapsed_at           TIMESTAMPTZ NOT NULL,
+    reactivated_at      TIMESTAMPTZ NOT NULL,
+    source              TEXT NOT NULL CHECK (source IN ('winback_offer', 'offer_code', 'organic')),
+    winback_offer_id    UUID REFERENCES winback_offers(id) ON DELETE SET NULL,
+    campaign_id         TEXT,
+    offer_redemption_id UUID REFERENCES offer_redemptions(id) ON DELETE SET NULL,
+    offer_code          TEXT,
+    experiment_id       UUID REFERENCES ab_tests(id) ON DELETE SET NULL,
+    arm_id              UUID REFERENCES ab_test_arms(id) ON DELETE SET NULL,
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_subscription_reactivations_app_time
+    ON subscription_reactivations(app_id, reactivated_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_subscription_reactivations_experiment
+    ON subscription_reactivations(experiment_id, arm_id)
+    WHERE experiment_id IS NOT NULL;
+
+COMMENT ON TABLE subscription_reactivations IS 'Churned subscribers who resubscribed, with the campaign or offer credited';
+COMMENT ON COLUMN subscription_reactivations.mrr IS 'List-price monthly recurring revenue in USD; 0 for lifetime';
@@ -0,0 +1,22 @@
+-- Migration 089: data retention cleanup state
+-- The nightly retention job deletes rows older than each policy's retention
+-- in bounded batches and records its last result per policy here.
+
+CREATE TABLE IF NOT EXISTS retention_state (
+    policy           TEXT PRIMARY KEY,
+    table_name       TEXT NOT NULL,
+    status           TEXT NOT NULL DEFAULT 'idle' CHECK (status IN ('idle', 'failed')),
+    -- Rows older than this were eligible in the last run
+    cutoff           TIMESTAMPTZ,
+    last_deleted     BIGINT NOT NULL DEFAULT 0,
+    last_batches     INTEGER NOT NULL DEFAULT 0,
+    -- The last run hit the batch limit and left eligible rows for the next one
+    truncated        BOOLEAN NOT NULL DEFAULT false,
+    total_deleted    BIGINT NOT NULL DEFAULT 0,
+    last_duration_ms BIGINT NOT NULL DEFAULT 0,
+    last_error       TEXT,
+    last_run_at      TIMESTAMPTZ,
+    last_success_at  TIMESTAMPTZ
+);
+
+COMMENT ON TABLE retention_state IS 'Last result of each data retention policy cleanup';
@@ -0,0 +1,18 @@
+INSERT INTO ab_test_arm_stats
+SELECT (jsonb_populate_record(NULL::ab_test_arm_stats, r.stats)).*
+FROM ab_test_arm_stats_archive r
+WHERE r.stats IS NOT NULL
+ON CONFLICT DO NOTHING;
+
+INSERT INTO bandit_arm_objective_stats
+SELECT (jsonb_populate_record(NULL::bandit_arm_objective_stats, o.value)).*
+FROM ab_test_arm_stats_archive r, jsonb_array_elements(r.objective_stats) o
+ON CONFLICT DO NOTHING;
+
+DROP TABLE IF EXISTS ab_test_arm_stats_archive;
+
+UPDATE ab_tests SET status = 'completed' WHERE status = 'archived';
+ALTER TABLE ab_tests DROP COLUMN IF EXISTS archived_at;
+ALTER TABLE ab_tests DROP CONSTRAINT IF EXISTS ab_tests_status_check;
+ALTER TABLE ab_tests ADD CONSTRAINT ab_tests_status_check
+    CHECK (status IN ('draft', 'running', 'paused', 'completed'));
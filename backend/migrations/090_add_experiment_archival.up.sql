@@ -0,0 +1,26 @@
+-- Migration 090: experiment archival
+-- Archiving a completed experiment moves its arm stats out of the hot stats
+-- tables into ab_test_arm_stats_archive, keeping each row as JSON so
+-- unarchiving restores them exactly. Archived experiments are skipped by arm
+-- selection and hidden from the admin list unless asked for.
+
+ALTER TABLE ab_tests DROP CONSTRAINT IF EXISTS ab_tests_status_check;
+ALTER TABLE ab_tests ADD CONSTRAINT ab_tests_status_check
+    CHECK (status IN ('draft', 'running', 'paused', 'completed', 'archived'));
+ALTER TABLE ab_tests ADD COLUMN IF NOT EXISTS archived_at TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS ab_test_arm_stats_archive (
+    arm_id          UUID PRIMARY KEY REFERENCES ab_test_arms(id) ON DELETE CASCADE,
+    experiment_id   UUID NOT NULL REFERENCES ab_tests(id) ON DELETE CASCADE,
+    -- The arm's ab_test_arm_stats row; NULL when it had none
+    stats           JSONB,
+    -- The arm's bandit_arm_objective_stats rows
+    objective_stats JSONB NOT NULL DEFAULT '[]',
+    archived_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ab_test_arm_stats_archive_experiment
+    ON ab_test_arm_stats_archive(experiment_id);
+
+COMMENT ON TABLE ab_test_arm_stats_archive IS 'Arm stats of archived experiments, restored on unarchive';
+COMMENT ON COLUMN ab_tests.archived_at IS 'When the completed experiment was archived; NULL unless status is archived';
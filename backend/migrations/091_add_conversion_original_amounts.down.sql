@@ -0,0 +1,14 @@
+ALTER TABLE bandit_arm_objective_stats DROP COLUMN IF EXISTS original_revenue;
+
+DELETE FROM bandit_conversion_events WHERE event_type = 'currency_correction';
+ALTER TABLE bandit_conversion_events DROP CONSTRAINT IF EXISTS bandit_conversion_events_event_type_check;
+ALTER TABLE bandit_conversion_events
+    ADD CONSTRAINT bandit_conversion_events_event_type_check
+    CHECK (event_type IN ('direct_reward', 'delayed_conversion', 'expired_pending_reward', 'notification_open'));
+
+DROP INDEX IF EXISTS idx_bandit_conversion_events_currency;
+DROP INDEX IF EXISTS idx_bandit_conversion_events_corrects;
+ALTER TABLE bandit_conversion_events
+    DROP COLUMN IF EXISTS corrects_event_id,
+    DROP COLUMN IF EXISTS rate_source,
+    DROP COLUMN IF EXISTS conversion_rate;
@@ -0,0 +1,33 @@
+-- Migration 091: keep original reward amounts auditable
+-- Conversion events already store the original amount and currency; they now
+-- also record the rate used to normalize them, so a bad rate can be found and
+-- corrected later. A correction is appended as a currency_correction event
+-- whose normalized value is the delta applied to the arm, pointing at the
+-- event it corrects. Objective stats keep the summed original amounts per
+-- currency next to the normalized total.
+
+ALTER TABLE bandit_conversion_events
+    ADD COLUMN IF NOT EXISTS conversion_rate DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS rate_source TEXT,
+    ADD COLUMN IF NOT EXISTS corrects_event_id UUID REFERENCES bandit_conversion_events(id) ON DELETE CASCADE;
+
+ALTER TABLE bandit_conversion_events DROP CONSTRAINT IF EXISTS bandit_conversion_events_event_type_check;
+ALTER TABLE bandit_conversion_events
+    ADD CONSTRAINT bandit_conversion_events_event_type_check
+    CHECK (event_type IN ('direct_reward', 'delayed_conversion', 'expired_pending_reward', 'notification_open', 'currency_correction'));
+
+CREATE INDEX IF NOT EXISTS idx_bandit_conversion_events_corrects
+    ON bandit_conversion_events(corrects_event_id, created_at DESC)
+    WHERE corrects_event_id IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_bandit_conversion_events_currency
+    ON bandit_conversion_events(experiment_id, original_currency, occurred_at)
+    WHERE conversion_rate IS NOT NULL;
+
+-- Nullable so archived objective stats rows without it still restore
+ALTER TABLE bandit_arm_objective_stats
+    ADD COLUMN IF NOT EXISTS original_revenue JSONB;
+
+COMMENT ON COLUMN bandit_conversion_events.conversion_rate IS 'Rate applied to original_reward_value to reach USD; NULL when no conversion happened';
+COMMENT ON COLUMN bandit_conversion_events.corrects_event_id IS 'For currency_correction events, the conversion event whose rate was corrected';
+COMMENT ON COLUMN bandit_arm_objective_stats.original_revenue IS 'JSON map of original currency to summed original reward amount';
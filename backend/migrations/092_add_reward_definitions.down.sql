@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_ab_tests_reward_definitions;
+
+ALTER TABLE ab_tests
+    DROP COLUMN IF EXISTS reward_definitions;
@@ -0,0 +1,13 @@
+-- Migration 092: reward shaping for non-monetary conversions
+-- Experiments that optimize trial starts or onboarding completion define,
+-- per event type, the reward an assigned user's event is worth, optionally
+-- halving per half_life_seconds between assignment and event.
+
+ALTER TABLE ab_tests
+    ADD COLUMN IF NOT EXISTS reward_definitions JSONB;
+
+CREATE INDEX IF NOT EXISTS idx_ab_tests_reward_definitions
+    ON ab_tests USING GIN (reward_definitions jsonb_path_ops)
+    WHERE reward_definitions IS NOT NULL;
+
+COMMENT ON COLUMN ab_tests.reward_definitions IS 'JSON array of {name, event_type, reward, half_life_seconds} turning analytics events into arm rewards';
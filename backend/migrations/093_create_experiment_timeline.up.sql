@@ -0,0 +1,21 @@
+-- Migration 093: materialized experiment timelines
+-- A daily job totals each arm's exposures (impressions), conversions and
+-- revenue for the previous UTC day and stores the arm's win probability at
+-- the time of the run, so admin timeline charts read rows instead of
+-- re-running the Monte Carlo estimate per request.
+
+CREATE TABLE IF NOT EXISTS experiment_timeline_daily (
+    experiment_id   UUID NOT NULL REFERENCES ab_tests(id) ON DELETE CASCADE,
+    arm_id          UUID NOT NULL REFERENCES ab_test_arms(id) ON DELETE CASCADE,
+    app_id          UUID NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+    timeline_date   DATE NOT NULL,
+    exposures       BIGINT NOT NULL DEFAULT 0,
+    conversions     BIGINT NOT NULL DEFAULT 0,
+    revenue         DOUBLE PRECISION NOT NULL DEFAULT 0,
+    win_probability DOUBLE PRECISION CHECK (win_probability BETWEEN 0 AND 1),
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (experiment_id, timeline_date, arm_id)
+);
+
+COMMENT ON TABLE experiment_timeline_daily IS 'Daily per-arm exposures, conversions, revenue and win probability of running experiments';
+COMMENT ON COLUMN experiment_timeline_daily.win_probability IS 'Probability the arm is best as estimated when the day was recorded; NULL when it could not be computed';
@@ -0,0 +1,42 @@
+-- Migration 094: price_increase_consents — subscriber consent to store price increases
+-- When an App Store price increase needs consent, Apple sends PRICE_INCREASE
+-- (subtype PENDING) and renews at the new price only once the subscriber
+-- agrees (subtype ACCEPTED). Subscribers who never agree expire at the end of
+-- the period with EXPIRED / PRICE_INCREASE, which churn reports count apart
+-- from other expirations. Pending subscribers are prompted to consent.
+
+CREATE TABLE IF NOT EXISTS price_increase_consents (
+    id               UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    app_id           UUID NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+    subscription_id  UUID NOT NULL REFERENCES subscriptions(id) ON DELETE CASCADE,
+    user_id          UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    platform         TEXT NOT NULL,
+    product_id       TEXT NOT NULL,
+    status           TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'declined')),
+    new_price        NUMERIC(12, 2),
+    currency         TEXT,
+    notified_at      TIMESTAMPTZ NOT NULL,
+    responded_at     TIMESTAMPTZ,
+    prompt_count     INT NOT NULL DEFAULT 0,
+    last_prompted_at TIMESTAMPTZ,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- One open price increase per subscription
+CREATE UNIQUE INDEX IF NOT EXISTS idx_price_increase_consents_pending
+    ON price_increase_consents(subscription_id)
+    WHERE status = 'pending';
+
+CREATE INDEX IF NOT EXISTS idx_price_increase_consents_app_pending
+    ON price_increase_consents(app_id, notified_at)
+    WHERE status = 'pending';
+
+CREATE INDEX IF NOT EXISTS idx_price_increase_consents_declined
+    ON price_increase_consents(app_id, responded_at)
+    WHERE status = 'declined';
+
+COMMENT ON TABLE price_increase_consents IS 'Store price increases awaiting, given or refused subscriber consent';
+COMMENT ON COLUMN price_increase_consents.notified_at IS 'When the store first reported the price increase';
+COMMENT ON COLUMN price_increase_consents.responded_at IS 'When the subscriber consented, or expired without consenting';
+COMMENT ON COLUMN price_increase_consents.new_price IS 'Renewal price the subscriber is asked to accept, when the store reports it';
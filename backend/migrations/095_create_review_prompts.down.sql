@@ -0,0 +1,8 @@
+DELETE FROM bandit_conversion_events WHERE event_type = 'review_prompt_accepted';
+ALTER TABLE bandit_conversion_events DROP CONSTRAINT IF EXISTS bandit_conversion_events_event_type_check;
+ALTER TABLE bandit_conversion_events
+    ADD CONSTRAINT bandit_conversion_events_event_type_check
+    CHECK (event_type IN ('direct_reward', 'delayed_conversion', 'expired_pending_reward', 'notification_open', 'currency_correction'));
+
+DROP TABLE IF EXISTS review_prompt_decisions;
+DROP TABLE IF EXISTS review_prompt_arms;
@@ -0,0 +1,42 @@
+-- Migration 095: store review prompt decisions
+-- The app asks whether to prompt a user for a store review. Rules gate on
+-- engagement, a recent purchase and no recent refund or support ticket; when
+-- the app runs a review prompt bandit (an ab_tests row in category
+-- review_prompt) its arms pick how long to wait before asking. Each decision
+-- records a pending reward that a review accepted within the window credits;
+-- the pending reward expiry sweep counts the rest as misses.
+
+CREATE TABLE IF NOT EXISTS review_prompt_arms (
+    arm_id        UUID PRIMARY KEY REFERENCES ab_test_arms(id) ON DELETE CASCADE,
+    experiment_id UUID NOT NULL REFERENCES ab_tests(id) ON DELETE CASCADE,
+    delay_hours   INT NOT NULL CHECK (delay_hours BETWEEN 0 AND 336),
+    UNIQUE (experiment_id, delay_hours)
+);
+
+CREATE TABLE IF NOT EXISTS review_prompt_decisions (
+    id                UUID PRIMARY KEY,
+    app_id            UUID NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+    user_id           UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    experiment_id     UUID REFERENCES ab_tests(id) ON DELETE SET NULL,
+    arm_id            UUID REFERENCES ab_test_arms(id) ON DELETE SET NULL,
+    pending_reward_id UUID UNIQUE REFERENCES bandit_pending_rewards(id) ON DELETE SET NULL,
+    delay_hours       INT NOT NULL DEFAULT 0,
+    ask_at            TIMESTAMPTZ NOT NULL,
+    expires_at        TIMESTAMPTZ NOT NULL,
+    shown_at          TIMESTAMPTZ,
+    outcome           TEXT CHECK (outcome IN ('accepted', 'dismissed')),
+    outcome_at        TIMESTAMPTZ,
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_review_prompt_decisions_user
+    ON review_prompt_decisions(user_id, created_at DESC);
+
+-- Accepted reviews are credited with their own event type
+ALTER TABLE bandit_conversion_events DROP CONSTRAINT IF EXISTS bandit_conversion_events_event_type_check;
+ALTER TABLE bandit_conversion_events
+    ADD CONSTRAINT bandit_conversion_events_event_type_check
+    CHECK (event_type IN ('direct_reward', 'delayed_conversion', 'expired_pending_reward', 'notification_open', 'currency_correction', 'review_prompt_accepted'));
+
+COMMENT ON TABLE review_prompt_decisions IS 'Store review prompts offered to users, when to ask, and how the user answered';
+COMMENT ON COLUMN review_prompt_decisions.expires_at IS 'After this an unshown decision is stale and a new one is made';
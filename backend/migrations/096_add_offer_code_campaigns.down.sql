@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_offer_redemptions_campaign;
+DROP TABLE IF EXISTS offer_code_campaigns;
+
+DELETE FROM offer_redemptions WHERE offer_type = 'win_back';
+ALTER TABLE offer_redemptions DROP CONSTRAINT IF EXISTS offer_redemptions_offer_type_check;
+ALTER TABLE offer_redemptions
+    ADD CONSTRAINT offer_redemptions_offer_type_check
+    CHECK (offer_type IN ('introductory', 'free_trial', 'offer_code', 'promotional'));
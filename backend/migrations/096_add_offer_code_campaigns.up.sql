@@ -0,0 +1,25 @@
+-- Migration 096: offer code and win-back offer campaigns
+-- App Store signed transactions report the offer a purchase redeemed,
+-- including win-back offers. Offer code batches (by reference name) and
+-- win-back offer IDs are mapped to marketing campaigns here; redemptions are
+-- attributed at report time, so remapping a batch re-attributes its history.
+
+ALTER TABLE offer_redemptions DROP CONSTRAINT IF EXISTS offer_redemptions_offer_type_check;
+ALTER TABLE offer_redemptions
+    ADD CONSTRAINT offer_redemptions_offer_type_check
+    CHECK (offer_type IN ('introductory', 'free_trial', 'offer_code', 'promotional', 'win_back'));
+
+CREATE TABLE IF NOT EXISTS offer_code_campaigns (
+    app_id     UUID NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+    offer_code TEXT NOT NULL,
+    campaign   TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (app_id, offer_code)
+);
+
+CREATE INDEX IF NOT EXISTS idx_offer_redemptions_campaign
+    ON offer_redemptions(app_id, redeemed_at)
+    WHERE offer_type IN ('offer_code', 'win_back');
+
+COMMENT ON TABLE offer_code_campaigns IS 'Campaign each offer code batch or win-back offer is attributed to';
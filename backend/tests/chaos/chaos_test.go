@@ -0,0 +1,178 @@
+//go:build chaos
+
+// Package chaos runs fault injection scenarios against the external clients
+// to check that retries and fallbacks hold up. No dependency is contacted:
+// injected faults answer before the network, and the rest reaches local test
+// servers. Run with make test-chaos.
+package chaos
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	iapext "github.com/bivex/paywall-iap/internal/infrastructure/external/iap"
+	"github.com/bivex/paywall-iap/internal/infrastructure/external/matomo"
+	"github.com/bivex/paywall-iap/internal/infrastructure/faultinject"
+)
+
+func mustScenario(t *testing.T, spec string) *faultinject.Injector {
+	t.Helper()
+	scenario, err := faultinject.ParseScenario(spec)
+	require.NoError(t, err)
+	return faultinject.New(scenario, 42, zap.NewNop())
+}
+
+// stagedEvents is an in-memory Matomo staging queue
+type stagedEvents struct {
+	mu       sync.Mutex
+	events   map[uuid.UUID]*service.MatomoStagedEvent
+	statuses map[uuid.UUID]error
+}
+
+func newStagedEvents() *stagedEvents {
+	return &stagedEvents{events: map[uuid.UUID]*service.MatomoStagedEvent{}, statuses: map[uuid.UUID]error{}}
+}
+
+func (s *stagedEvents) EnqueueEvent(ctx context.Context, event *service.MatomoStagedEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events[event.ID] = event
+	return nil
+}
+func (s *stagedEvents) GetPendingEvents(ctx context.Context, limit int) ([]*service.MatomoStagedEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pending := make([]*service.MatomoStagedEvent, 0, len(s.events))
+	for _, event := range s.events {
+		pending = append(pending, event)
+	}
+	return pending, nil
+}
+func (s *stagedEvents) UpdateEventStatus(ctx context.Context, eventID uuid.UUID, status string, err error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statuses[eventID] = err
+	return nil
+}
+func (s *stagedEvents) GetFailedEvents(ctx context.Context, limit int) ([]*service.MatomoStagedEvent, error) {
+	return nil, nil
+}
+func (s *stagedEvents) DeleteEvent(ctx context.Context, eventID uuid.UUID) error {
+	return nil
+}
+
+func TestMatomoOutageKeepsEventsForRetry(t *testing.T) {
+	var hits atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer upstream.Close()
+
+	for _, spec := range []string{"matomo:error=1", "matomo:status=503"} {
+		t.Run(spec, func(t *testing.T) {
+			hits.Store(0)
+			client := matomo.NewClient(matomo.Config{BaseURL: upstream.URL, SiteID: "1"}, zap.NewNop()).
+				WithTransport(mustScenario(t, spec).Transport(faultinject.TargetMatomo, nil))
+			queue := newStagedEvents()
+			forwarder := service.NewMatomoForwarder(client, queue, zap.NewNop())
+
+			require.NoError(t, forwarder.TrackEvent(context.Background(), nil, "paywall", "viewed", "", 0, nil))
+			processed, succeeded, failed, err := forwarder.ProcessBatch(context.Background())
+			require.NoError(t, err)
+
+			assert.Equal(t, 1, processed)
+			assert.Zero(t, succeeded)
+			assert.Equal(t, 1, failed)
+			assert.Zero(t, hits.Load())
+			for _, sendErr := range queue.statuses {
+				assert.ErrorContains(t, sendErr, "max retries exceeded", "the failure is recorded so the event is retried")
+			}
+		})
+	}
+}
+
+func TestMatomoRetriesThroughIntermittentErrors(t *testing.T) {
+	var hits atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer upstream.Close()
+
+	client := matomo.NewClient(matomo.Config{BaseURL: upstream.URL, SiteID: "1"}, zap.NewNop()).
+		WithTransport(mustScenario(t, "matomo:error=0.5").Transport(faultinject.TargetMatomo, nil))
+	queue := newStagedEvents()
+	forwarder := service.NewMatomoForwarder(client, queue, zap.NewNop())
+	for i := 0; i < 8; i++ {
+		require.NoError(t, forwarder.TrackEvent(context.Background(), nil, "paywall", "viewed", "", 0, nil))
+	}
+
+	processed, succeeded, failed, err := forwarder.ProcessBatch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 8, processed)
+	assert.Equal(t, processed, succeeded+failed, "every event is either sent or kept for retry")
+	assert.Equal(t, int32(succeeded), hits.Load())
+	assert.Greater(t, succeeded, 4, "retries recover more events than a single attempt would")
+}
+
+func TestCurrencyFaultsFallBackToDefaultRates(t *testing.T) {
+	// Unreachable Redis: no cached rate can hide the fault
+	redisClient := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", DialTimeout: 50 * time.Millisecond, MaxRetries: -1})
+	defer redisClient.Close()
+
+	for _, spec := range []string{"currency:error=1", "currency:status=503", "currency:malformed=1"} {
+		t.Run(spec, func(t *testing.T) {
+			currencies := service.NewCurrencyRateService(redisClient, zap.NewNop()).
+				WithTransport(mustScenario(t, spec).Transport(faultinject.TargetCurrency, nil))
+
+			assert.Error(t, currencies.UpdateRates(context.Background()))
+			quote, err := currencies.GetRateQuote(context.Background(), "EUR")
+			require.NoError(t, err)
+			assert.Equal(t, "fallback", quote.Source)
+			assert.Greater(t, quote.Rate, 0.0)
+		})
+	}
+}
+
+func TestStoreFaultsNeverGrantAccess(t *testing.T) {
+	for _, spec := range []string{"apple:error=1", "apple:status=503", "apple:malformed=1", "apple:latency=1s"} {
+		t.Run(spec, func(t *testing.T) {
+			verifier := iapext.NewAppleVerifier("shared-secret", false, "").
+				WithTransport(mustScenario(t, spec).Transport(faultinject.TargetApple, nil))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+			result, err := verifier.VerifyReceipt(ctx, "receipt-data")
+			require.Error(t, err)
+			assert.Nil(t, result)
+		})
+	}
+
+	for _, spec := range []string{"google:error=1", "google:status=503", "google:malformed=1"} {
+		t.Run(spec, func(t *testing.T) {
+			verifier := iapext.NewGoogleVerifier("", true, "http://play.invalid").
+				WithTransport(mustScenario(t, spec).Transport(faultinject.TargetGoogle, nil))
+
+			_, err := verifier.VerifyReceipt(context.Background(),
+				`{"packageName":"com.example","productId":"premium","purchaseToken":"token"}`)
+			require.Error(t, err)
+			if spec == "google:error=1" {
+				assert.True(t, errors.Is(err, faultinject.ErrInjected))
+			}
+		})
+	}
+}
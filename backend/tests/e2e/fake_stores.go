@@ -0,0 +1,249 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FakeAppleStore serves the legacy verifyReceipt endpoint for receipts it
+// issued and builds App Store Server Notifications V2 for them. The API must
+// run with APPLE_MOCK_URL pointing here and JWS verification disabled.
+type FakeAppleStore struct {
+	server *httptest.Server
+
+	mu       sync.Mutex
+	nextTxID int64
+	receipts map[string]appleReceipt // receipt data -> purchase
+}
+
+type appleReceipt struct {
+	ProductID     string
+	TransactionID string
+	ExpiresAt     time.Time
+}
+
+func NewFakeAppleStore() *FakeAppleStore {
+	s := &FakeAppleStore{
+		nextTxID: 2000000000000000,
+		receipts: make(map[string]appleReceipt),
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.handleVerifyReceipt))
+	return s
+}
+
+func (s *FakeAppleStore) URL() string { return s.server.URL }
+
+func (s *FakeAppleStore) Close() { s.server.Close() }
+
+// Purchase records a subscription purchase and returns the receipt data the
+// client would send along with its original transaction ID
+func (s *FakeAppleStore) Purchase(productID string, expiresAt time.Time) (receiptData, originalTxID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextTxID++
+	txID := strconv.FormatInt(s.nextTxID, 10)
+	receiptData = base64.StdEncoding.EncodeToString([]byte("fake-apple-receipt-" + txID))
+	s.receipts[receiptData] = appleReceipt{ProductID: productID, TransactionID: txID, ExpiresAt: expiresAt}
+	return receiptData, txID
+}
+
+func (s *FakeAppleStore) handleVerifyReceipt(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ReceiptData string `json:"receipt-data"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, map[string]any{"status": 21002})
+		return
+	}
+
+	s.mu.Lock()
+	receipt, ok := s.receipts[req.ReceiptData]
+	s.mu.Unlock()
+	if !ok {
+		// 21002: the receipt data was malformed or is unknown
+		writeJSON(w, map[string]any{"status": 21002})
+		return
+	}
+
+	writeJSON(w, map[string]any{
+		"status":      0,
+		"environment": "Sandbox",
+		"latest_receipt_info": []map[string]string{{
+			"product_id":               receipt.ProductID,
+			"transaction_id":           receipt.TransactionID,
+			"original_transaction_id":  receipt.TransactionID,
+			"expires_date_ms":          strconv.FormatInt(receipt.ExpiresAt.UnixMilli(), 10),
+			"is_in_intro_offer_period": "false",
+			"is_trial_period":          "false",
+		}},
+	})
+}
+
+// RenewalNotification returns a DID_RENEW notification body moving the
+// subscription's expiry to expiresAt
+func (s *FakeAppleStore) RenewalNotification(originalTxID, productID string, expiresAt time.Time) []byte {
+	renewalTxID := originalTxID + "-" + strconv.FormatInt(expiresAt.Unix(), 10)
+	transaction := fakeJWS(map[string]any{
+		"originalTransactionId": originalTxID,
+		"transactionId":         renewalTxID,
+		"productId":             productID,
+		"expiresDate":           expiresAt.UnixMilli(),
+		"price":                 9990,
+		"currency":              "USD",
+		"storefront":            "USA",
+		"environment":           "Sandbox",
+	})
+	notification := fakeJWS(map[string]any{
+		"notificationType": "DID_RENEW",
+		"notificationUUID": uuid.NewString(),
+		"data": map[string]any{
+			"environment":           "Sandbox",
+			"signedTransactionInfo": transaction,
+		},
+	})
+	body, _ := json.Marshal(map[string]string{"signedPayload": notification})
+	return body
+}
+
+// fakeJWS encodes claims as an unsigned compact JWS, accepted only while
+// APPLE_JWS_VERIFICATION_DISABLED is set
+func fakeJWS(claims any) string {
+	header, _ := json.Marshal(map[string]string{"alg": "ES256", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	return base64.RawURLEncoding.EncodeToString(header) + "." +
+		base64.RawURLEncoding.EncodeToString(payload) + ".fake-signature"
+}
+
+// FakeGoogleStore serves purchases.subscriptions.get from the Android
+// Publisher API for purchase tokens it issued. The API must run with
+// GOOGLE_IAP_BASE_URL pointing here.
+type FakeGoogleStore struct {
+	server *httptest.Server
+
+	mu     sync.Mutex
+	tokens map[string]time.Time // purchase token -> expiry
+}
+
+func NewFakeGoogleStore() *FakeGoogleStore {
+	s := &FakeGoogleStore{tokens: make(map[string]time.Time)}
+	s.server = httptest.NewServer(http.HandlerFunc(s.handleSubscriptionGet))
+	return s
+}
+
+func (s *FakeGoogleStore) URL() string { return s.server.URL }
+
+func (s *FakeGoogleStore) Close() { s.server.Close() }
+
+// Purchase records a subscription purchase and returns the receipt data the
+// client would send along with its purchase token
+func (s *FakeGoogleStore) Purchase(packageName, productID string, expiresAt time.Time) (receiptData, purchaseToken string) {
+	purchaseToken = "fake-google-token-" + uuid.NewString()
+
+	s.mu.Lock()
+	s.tokens[purchaseToken] = expiresAt
+	s.mu.Unlock()
+
+	data, _ := json.Marshal(map[string]string{
+		"packageName":   packageName,
+		"productId":     productID,
+		"purchaseToken": purchaseToken,
+	})
+	return string(data), purchaseToken
+}
+
+// handleSubscriptionGet answers
+// GET /androidpublisher/v3/applications/{pkg}/purchases/subscriptions/{id}/tokens/{token}
+func (s *FakeGoogleStore) handleSubscriptionGet(w http.ResponseWriter, r *http.Request) {
+	_, token, ok := strings.Cut(r.URL.Path, "/tokens/")
+	if !ok || !strings.Contains(r.URL.Path, "/purchases/subscriptions/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	s.mu.Lock()
+	expiresAt, ok := s.tokens[token]
+	s.mu.Unlock()
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"code": 404, "message": "purchase token not found"}})
+		return
+	}
+
+	writeJSON(w, map[string]any{
+		"kind":             "androidpublisher#subscriptionPurchase",
+		"expiryTimeMillis": strconv.FormatInt(expiresAt.UnixMilli(), 10),
+		"paymentState":     1,
+		"autoRenewing":     true,
+	})
+}
+
+// FakeStripe sends Stripe webhook events, signed like Stripe does when the
+// API runs with a webhook secret
+type FakeStripe struct {
+	webhookSecret string
+}
+
+func NewFakeStripe(webhookSecret string) *FakeStripe {
+	return &FakeStripe{webhookSecret: webhookSecret}
+}
+
+// InvoicePaid returns a request delivering an invoice.paid event for the
+// customer to the API's Stripe webhook endpoint
+func (s *FakeStripe) InvoicePaid(baseURL, customerID string, amountCents int64) (*http.Request, error) {
+	body, err := json.Marshal(map[string]any{
+		"id":       "evt_" + strings.ReplaceAll(uuid.NewString(), "-", ""),
+		"type":     "invoice.paid",
+		"created":  time.Now().Unix(),
+		"livemode": false,
+		"data": map[string]any{
+			"object": map[string]any{
+				"id":          "in_" + strings.ReplaceAll(uuid.NewString(), "-", ""),
+				"customer":    customerID,
+				"amount_paid": amountCents,
+				"tax":         0,
+				"currency":    "usd",
+				"customer_address": map[string]string{
+					"country": "US",
+				},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, baseURL+"/webhook/stripe", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.webhookSecret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		mac := hmac.New(sha256.New, []byte(s.webhookSecret))
+		mac.Write([]byte(timestamp + "." + string(body)))
+		req.Header.Set("Stripe-Signature", fmt.Sprintf("t=%s,v1=%s", timestamp, hex.EncodeToString(mac.Sum(nil))))
+	}
+	return req, nil
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,227 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bivex/paywall-iap/internal/application/dto"
+	"github.com/bivex/paywall-iap/tests/testutil"
+)
+
+// The TestPurchaseFlow tests are the acceptance gate for the payment
+// pipeline (make test-payment-gate): each drives the real API and worker
+// through a purchase against fake stores.
+
+// envelope is the {data, meta} shape every API response uses
+type envelope[T any] struct {
+	Data T `json:"data"`
+}
+
+func TestPurchaseFlow_AppleSubscriptionLifecycle(t *testing.T) {
+	ctx := context.Background()
+	stack := SetupPaymentStack(ctx, t)
+	defer stack.Teardown(ctx, t)
+
+	app := stack.CreateApp(ctx, t, "com.e2e.apple")
+	experimentID := stack.CreateBanditExperiment(ctx, t, app.ID, "apple paywall")
+	const productID = "com.e2e.apple.premium.monthly"
+
+	var userID, accessToken, originalTxID string
+	var firstExpiry time.Time
+
+	t.Run("register", func(t *testing.T) {
+		auth := stack.register(t, "apple-user-"+uuid.NewString(), "ios", app.ID.String())
+		userID, accessToken = auth.UserID, auth.AccessToken
+	})
+
+	t.Run("assign experiment", func(t *testing.T) {
+		require.NotEmpty(t, userID)
+		var assigned envelope[struct {
+			ArmID string `json:"arm_id"`
+		}]
+		stack.doJSON(t, http.MethodPost, "/v1/bandit/assign", "", map[string]string{
+			"experiment_id": experimentID.String(),
+			"user_id":       userID,
+		}, http.StatusOK, &assigned)
+		assert.NotEmpty(t, assigned.Data.ArmID)
+	})
+
+	t.Run("verify purchase", func(t *testing.T) {
+		require.NotEmpty(t, accessToken)
+		var receipt string
+		firstExpiry = time.Now().Add(30 * 24 * time.Hour).Truncate(time.Second)
+		receipt, originalTxID = stack.Apple.Purchase(productID, firstExpiry)
+
+		var verified envelope[dto.VerifyIAPResponse]
+		stack.doJSON(t, http.MethodPost, "/v1/verify/iap", accessToken, dto.VerifyIAPRequest{
+			Platform:    "ios",
+			ReceiptData: receipt,
+			ProductID:   productID,
+		}, http.StatusOK, &verified)
+		assert.Equal(t, "active", verified.Data.Status)
+		assert.True(t, verified.Data.IsNew)
+
+		assert.True(t, stack.hasAccess(t, accessToken))
+	})
+
+	t.Run("webhook renewal", func(t *testing.T) {
+		require.NotEmpty(t, originalTxID)
+		renewedExpiry := firstExpiry.Add(30 * 24 * time.Hour)
+		body := stack.Apple.RenewalNotification(originalTxID, productID, renewedExpiry)
+		stack.doRaw(t, http.MethodPost, "/webhook/apple", body, http.StatusOK)
+
+		// The worker applies the renewal asynchronously
+		require.Eventually(t, func() bool {
+			var sub envelope[dto.SubscriptionResponse]
+			if stack.get("/v1/subscription", accessToken, &sub) != nil {
+				return false
+			}
+			expiresAt, err := time.Parse(time.RFC3339, sub.Data.ExpiresAt)
+			return err == nil && !expiresAt.Before(renewedExpiry)
+		}, 30*time.Second, 500*time.Millisecond, "renewal was not applied by the worker")
+	})
+
+	t.Run("access check", func(t *testing.T) {
+		assert.True(t, stack.hasAccess(t, accessToken))
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		stack.doJSON(t, http.MethodDelete, "/v1/subscription", accessToken, nil, http.StatusNoContent, nil)
+		assert.False(t, stack.hasAccess(t, accessToken))
+	})
+}
+
+func TestPurchaseFlow_GoogleSubscription(t *testing.T) {
+	ctx := context.Background()
+	stack := SetupPaymentStack(ctx, t)
+	defer stack.Teardown(ctx, t)
+
+	app := stack.CreateApp(ctx, t, "com.e2e.google")
+	const productID = "com.e2e.google.premium.monthly"
+
+	auth := stack.register(t, "google-user-"+uuid.NewString(), "android", app.ID.String())
+	assert.False(t, stack.hasAccess(t, auth.AccessToken))
+
+	receipt, _ := stack.Google.Purchase("com.e2e.google", productID, time.Now().Add(30*24*time.Hour))
+	var verified envelope[dto.VerifyIAPResponse]
+	stack.doJSON(t, http.MethodPost, "/v1/verify/iap", auth.AccessToken, dto.VerifyIAPRequest{
+		Platform:    "android",
+		ReceiptData: receipt,
+		ProductID:   productID,
+	}, http.StatusOK, &verified)
+	assert.Equal(t, "active", verified.Data.Status)
+	assert.True(t, stack.hasAccess(t, auth.AccessToken))
+
+	// A receipt the store does not know must not grant anything
+	unknown, _ := json.Marshal(map[string]string{
+		"packageName":   "com.e2e.google",
+		"productId":     productID,
+		"purchaseToken": "unknown-token",
+	})
+	other := stack.register(t, "google-user-"+uuid.NewString(), "android", app.ID.String())
+	stack.doJSON(t, http.MethodPost, "/v1/verify/iap", other.AccessToken, dto.VerifyIAPRequest{
+		Platform:    "android",
+		ReceiptData: string(unknown),
+		ProductID:   productID,
+	}, http.StatusUnprocessableEntity, nil)
+	assert.False(t, stack.hasAccess(t, other.AccessToken))
+}
+
+func TestPurchaseFlow_StripeInvoiceProvisionsAccess(t *testing.T) {
+	ctx := context.Background()
+	stack := SetupPaymentStack(ctx, t)
+	defer stack.Teardown(ctx, t)
+
+	stack.CreateUnscopedApp(ctx, t)
+	customerID := "cus_" + uuid.NewString()
+	auth := stack.register(t, customerID, "ios", "")
+	assert.False(t, stack.hasAccess(t, auth.AccessToken))
+
+	req, err := stack.Stripe.InvoicePaid(stack.BaseURL, customerID, 999)
+	require.NoError(t, err)
+	resp, body, err := testutil.DoRequest(stack.HTTPClient, req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+
+	require.Eventually(t, func() bool {
+		var access envelope[dto.AccessCheckResponse]
+		return stack.get("/v1/subscription/access", auth.AccessToken, &access) == nil && access.Data.HasAccess
+	}, 30*time.Second, 500*time.Millisecond, "invoice.paid did not provision access")
+}
+
+func (s *PaymentStack) register(t *testing.T, platformUserID, platform, appID string) testutil.AuthData {
+	t.Helper()
+
+	var auth envelope[testutil.AuthData]
+	s.doJSON(t, http.MethodPost, "/v1/auth/register", "", dto.RegisterRequest{
+		PlatformUserID: platformUserID,
+		DeviceID:       "device-" + platformUserID,
+		Platform:       platform,
+		AppVersion:     "1.0.0",
+		AppID:          appID,
+	}, http.StatusCreated, &auth)
+	require.NotEmpty(t, auth.Data.AccessToken)
+	return auth.Data
+}
+
+func (s *PaymentStack) hasAccess(t *testing.T, accessToken string) bool {
+	t.Helper()
+
+	var access envelope[dto.AccessCheckResponse]
+	s.doJSON(t, http.MethodGet, "/v1/subscription/access", accessToken, nil, http.StatusOK, &access)
+	return access.Data.HasAccess
+}
+
+// get decodes a 200 response into out. It returns an error instead of
+// failing the test so it can be polled from require.Eventually.
+func (s *PaymentStack) get(path, accessToken string, out any) error {
+	req, err := testutil.NewTestRequest(http.MethodGet, s.BaseURL+path, nil, accessToken)
+	if err != nil {
+		return err
+	}
+	resp, body, err := testutil.DoRequest(s.HTTPClient, req)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status %d: %s", path, resp.StatusCode, body)
+	}
+	return json.Unmarshal(body, out)
+}
+
+// doJSON sends body as JSON, requires the expected status and decodes the
+// response into out when it is non-nil
+func (s *PaymentStack) doJSON(t *testing.T, method, path, accessToken string, body any, wantStatus int, out any) {
+	t.Helper()
+
+	req, err := testutil.NewTestRequest(method, s.BaseURL+path, body, accessToken)
+	require.NoError(t, err)
+	resp, respBody, err := testutil.DoRequest(s.HTTPClient, req)
+	require.NoError(t, err)
+	require.Equal(t, wantStatus, resp.StatusCode, "%s %s: %s", method, path, respBody)
+	if out != nil {
+		require.NoError(t, json.Unmarshal(respBody, out))
+	}
+}
+
+func (s *PaymentStack) doRaw(t *testing.T, method, path string, body []byte, wantStatus int) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, s.BaseURL+path, bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	resp, respBody, err := testutil.DoRequest(s.HTTPClient, req)
+	require.NoError(t, err)
+	require.Equal(t, wantStatus, resp.StatusCode, "%s %s: %s", method, path, respBody)
+}
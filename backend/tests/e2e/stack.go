@@ -0,0 +1,360 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/google/uuid"
+	"github.com/testcontainers/testcontainers-go"
+	tcwait "github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/bivex/paywall-iap/internal/domain/entity"
+	"github.com/bivex/paywall-iap/internal/infrastructure/persistence/repository"
+	"github.com/bivex/paywall-iap/tests/testutil"
+)
+
+// moduleRoot is the backend module relative to this package
+const moduleRoot = "../.."
+
+// stackJWTSecret signs tokens for the API under test
+const stackJWTSecret = "e2e-payment-stack-jwt-secret-0123456789"
+
+// PaymentStack runs the real API and worker binaries against Postgres and
+// Redis containers, with the App Store, Play and Stripe replaced by fakes.
+// Unlike E2ETestSuite it exercises the production router, the asynq worker
+// and the full migration set.
+type PaymentStack struct {
+	DB             *testutil.TestDBContainer
+	RedisContainer testcontainers.Container
+	Apple          *FakeAppleStore
+	Google         *FakeGoogleStore
+	Stripe         *FakeStripe
+	BaseURL        string
+	HTTPClient     *http.Client
+
+	processes []*stackProcess
+}
+
+// stackProcess is a running binary and its combined output
+type stackProcess struct {
+	name   string
+	cmd    *exec.Cmd
+	output *syncBuffer
+	done   chan struct{}
+}
+
+// SetupPaymentStack builds cmd/api and cmd/worker, starts them with the
+// containers and fakes wired in and waits until the API is healthy
+func SetupPaymentStack(ctx context.Context, t *testing.T) *PaymentStack {
+	t.Helper()
+
+	stack := &PaymentStack{
+		Apple:      NewFakeAppleStore(),
+		Google:     NewFakeGoogleStore(),
+		Stripe:     NewFakeStripe(""),
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+
+	db, err := testutil.SetupTestDBContainer(ctx, t)
+	if err != nil {
+		t.Fatalf("failed to start db container: %v", err)
+	}
+	stack.DB = db
+
+	redisContainer, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        "redis:7-alpine",
+			ExposedPorts: []string{"6379/tcp"},
+			WaitingFor:   tcwait.ForLog("Ready to accept connections").WithOccurrence(1),
+		},
+		Started: true,
+	})
+	if err != nil {
+		stack.Teardown(ctx, t)
+		t.Fatalf("failed to start redis container: %v", err)
+	}
+	stack.RedisContainer = redisContainer
+
+	redisAddr, err := redisContainer.Endpoint(ctx, "")
+	if err != nil {
+		stack.Teardown(ctx, t)
+		t.Fatalf("failed to get redis endpoint: %v", err)
+	}
+
+	if err := migrateUp(db.ConnString); err != nil {
+		stack.Teardown(ctx, t)
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	binDir := t.TempDir()
+	for _, name := range []string{"api", "worker"} {
+		if err := buildBinary(ctx, name, filepath.Join(binDir, name)); err != nil {
+			stack.Teardown(ctx, t)
+			t.Fatalf("failed to build %s: %v", name, err)
+		}
+	}
+
+	port, err := freePort()
+	if err != nil {
+		stack.Teardown(ctx, t)
+		t.Fatalf("failed to allocate api port: %v", err)
+	}
+	stack.BaseURL = fmt.Sprintf("http://127.0.0.1:%d", port)
+
+	env := []string{
+		"PATH=" + os.Getenv("PATH"),
+		"HOME=" + os.Getenv("HOME"),
+		"SERVER_PORT=" + strconv.Itoa(port),
+		"DATABASE_URL=" + db.ConnString,
+		"REDIS_URL=redis://" + redisAddr,
+		"JWT_SECRET=" + stackJWTSecret,
+		"SENTRY_ENVIRONMENT=development",
+		"APPLE_JWS_VERIFICATION_DISABLED=true",
+		"GOOGLE_PUBSUB_AUTH_DISABLED=true",
+		"APPLE_MOCK_URL=" + stack.Apple.URL(),
+		"GOOGLE_IAP_BASE_URL=" + stack.Google.URL() + "/",
+	}
+	for _, name := range []string{"api", "worker"} {
+		process, err := startProcess(name, filepath.Join(binDir, name), binDir, env)
+		if err != nil {
+			stack.Teardown(ctx, t)
+			t.Fatalf("failed to start %s: %v", name, err)
+		}
+		stack.processes = append(stack.processes, process)
+	}
+
+	if err := stack.waitHealthy(ctx, 60*time.Second); err != nil {
+		stack.dumpOutput(t)
+		stack.Teardown(ctx, t)
+		t.Fatalf("api did not become healthy: %v", err)
+	}
+
+	return stack
+}
+
+// Teardown stops the binaries, fakes and containers. Process output is
+// logged when the test failed.
+func (s *PaymentStack) Teardown(ctx context.Context, t *testing.T) {
+	t.Helper()
+
+	if t.Failed() {
+		s.dumpOutput(t)
+	}
+	for _, process := range s.processes {
+		process.stop()
+	}
+	s.processes = nil
+
+	if s.Apple != nil {
+		s.Apple.Close()
+	}
+	if s.Google != nil {
+		s.Google.Close()
+	}
+	if s.RedisContainer != nil {
+		if err := s.RedisContainer.Terminate(ctx); err != nil {
+			t.Logf("Failed to terminate redis container: %v", err)
+		}
+	}
+	if s.DB != nil {
+		s.DB.Teardown(ctx, t)
+	}
+}
+
+// CreateApp registers an app with sandbox store credentials for both
+// platforms, so receipts for it are verified against the fakes
+func (s *PaymentStack) CreateApp(ctx context.Context, t *testing.T, bundleID string) *entity.App {
+	t.Helper()
+
+	repo := repository.NewAppRepository(s.DB.Pool)
+	app, err := repo.Create(ctx, bundleID, bundleID, "both")
+	if err != nil {
+		t.Fatalf("failed to create app: %v", err)
+	}
+	for _, creds := range []*entity.AppCredentials{
+		{AppID: app.ID, Provider: "apple", AppleSharedSecret: "e2e-shared-secret", AppleBundleID: bundleID, AppleEnvironment: "sandbox"},
+		{AppID: app.ID, Provider: "google", GooglePackageName: bundleID},
+	} {
+		if err := repo.UpsertCredentials(ctx, creds); err != nil {
+			t.Fatalf("failed to store %s credentials: %v", creds.Provider, err)
+		}
+	}
+	return app
+}
+
+// CreateUnscopedApp creates the app with the nil ID. Stripe webhooks are
+// processed outside any app scope, so Stripe customers live there.
+func (s *PaymentStack) CreateUnscopedApp(ctx context.Context, t *testing.T) {
+	t.Helper()
+
+	if _, err := s.DB.Pool.Exec(ctx, `
+		INSERT INTO apps (id, name, display_name, bundle_id, platform)
+		VALUES ($1, 'e2e-unscoped', 'E2E unscoped', 'e2e.unscoped', 'both')
+		ON CONFLICT (id) DO NOTHING`, uuid.Nil); err != nil {
+		t.Fatalf("failed to create unscoped app: %v", err)
+	}
+}
+
+// CreateBanditExperiment creates a running Thompson sampling experiment with
+// a control and a treatment arm
+func (s *PaymentStack) CreateBanditExperiment(ctx context.Context, t *testing.T, appID uuid.UUID, name string) uuid.UUID {
+	t.Helper()
+
+	var experimentID uuid.UUID
+	if err := s.DB.Pool.QueryRow(ctx, `
+		INSERT INTO ab_tests (app_id, name, status, is_bandit, algorithm_type, start_at)
+		VALUES ($1, $2, 'running', true, 'thompson_sampling', now())
+		RETURNING id`, appID, name).Scan(&experimentID); err != nil {
+		t.Fatalf("failed to create experiment: %v", err)
+	}
+	if _, err := s.DB.Pool.Exec(ctx, `
+		INSERT INTO ab_test_arms (experiment_id, name, is_control, traffic_weight)
+		VALUES ($1, 'control', true, 0.5), ($1, 'treatment', false, 0.5)`, experimentID); err != nil {
+		t.Fatalf("failed to create experiment arms: %v", err)
+	}
+	return experimentID
+}
+
+func (s *PaymentStack) waitHealthy(ctx context.Context, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		for _, process := range s.processes {
+			if process.exited() {
+				return fmt.Errorf("%s exited early", process.name)
+			}
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.BaseURL+"/health", nil)
+		if err != nil {
+			return err
+		}
+		if resp, err := s.HTTPClient.Do(req); err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return errors.New("timed out waiting for /health")
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+}
+
+func (s *PaymentStack) dumpOutput(t *testing.T) {
+	t.Helper()
+	for _, process := range s.processes {
+		t.Logf("---- %s output ----\n%s", process.name, process.output.String())
+	}
+}
+
+func migrateUp(connString string) error {
+	path, err := filepath.Abs(filepath.Join(moduleRoot, "migrations"))
+	if err != nil {
+		return err
+	}
+	m, err := migrate.New("file://"+path, connString)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+	return nil
+}
+
+func buildBinary(ctx context.Context, name, output string) error {
+	cmd := exec.CommandContext(ctx, "go", "build", "-o", output, "./cmd/"+name)
+	cmd.Dir = moduleRoot
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, out)
+	}
+	return nil
+}
+
+func startProcess(name, binary, dir string, env []string) (*stackProcess, error) {
+	output := &syncBuffer{}
+	cmd := exec.Command(binary)
+	cmd.Dir = dir
+	cmd.Env = env
+	cmd.Stdout = output
+	cmd.Stderr = output
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	process := &stackProcess{name: name, cmd: cmd, output: output, done: make(chan struct{})}
+	go func() {
+		_ = cmd.Wait()
+		close(process.done)
+	}()
+	return process, nil
+}
+
+func (p *stackProcess) exited() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *stackProcess) stop() {
+	if p.exited() {
+		return
+	}
+	_ = p.cmd.Process.Signal(os.Interrupt)
+	select {
+	case <-p.done:
+	case <-time.After(10 * time.Second):
+		_ = p.cmd.Process.Kill()
+		<-p.done
+	}
+}
+
+func freePort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+// syncBuffer collects process output written from several goroutines
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
@@ -0,0 +1,243 @@
+package integration
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/internal/infrastructure/cache"
+	"github.com/bivex/paywall-iap/internal/infrastructure/persistence/repository"
+	"github.com/bivex/paywall-iap/internal/interfaces/http/handlers"
+	"github.com/bivex/paywall-iap/tests/testutil"
+)
+
+// TestBanditAdvancedHandler_WindowAndPendingEndpoints drives the window and
+// delayed feedback endpoints against Postgres and Redis
+func TestBanditAdvancedHandler_WindowAndPendingEndpoints(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration tests in short mode")
+	}
+
+	ctx := context.Background()
+	logger := zap.NewNop()
+	db := testutil.SetupTestDBWithT(t)
+	redisClient := testutil.SetupTestRedis(t)
+
+	_, err := db.Exec(ctx, `
+		CREATE TABLE ab_tests (
+			id UUID PRIMARY KEY,
+			name TEXT NOT NULL,
+			status TEXT NOT NULL DEFAULT 'running',
+			objective_type VARCHAR(20) NOT NULL DEFAULT 'conversion',
+			objective_weights JSONB,
+			window_type VARCHAR(10),
+			window_size INT DEFAULT 1000,
+			window_min_samples INT DEFAULT 100,
+			enable_contextual BOOLEAN DEFAULT FALSE,
+			enable_delayed BOOLEAN DEFAULT FALSE,
+			enable_currency BOOLEAN DEFAULT FALSE,
+			exploration_alpha DECIMAL(4,2) DEFAULT 0.30,
+			reward_basis VARCHAR(10) NOT NULL DEFAULT 'gross',
+			product_costs JSONB,
+			end_at TIMESTAMPTZ,
+			window_arm_overrides JSONB,
+			conversion_window_hours INTEGER
+		);
+		CREATE TABLE ab_test_arms (
+			id UUID PRIMARY KEY,
+			experiment_id UUID NOT NULL REFERENCES ab_tests(id) ON DELETE CASCADE,
+			name TEXT NOT NULL,
+			description TEXT,
+			is_control BOOLEAN NOT NULL DEFAULT FALSE,
+			traffic_weight DOUBLE PRECISION NOT NULL DEFAULT 1,
+			archived_at TIMESTAMPTZ,
+			reassignment_policy VARCHAR(20)
+		);
+		CREATE TABLE bandit_pending_rewards (
+			id UUID PRIMARY KEY,
+			experiment_id UUID NOT NULL REFERENCES ab_tests(id) ON DELETE CASCADE,
+			arm_id UUID NOT NULL REFERENCES ab_test_arms(id) ON DELETE CASCADE,
+			user_id UUID NOT NULL,
+			assigned_at TIMESTAMPTZ NOT NULL,
+			expires_at TIMESTAMPTZ NOT NULL,
+			converted BOOLEAN NOT NULL DEFAULT FALSE,
+			conversion_value DOUBLE PRECISION,
+			conversion_currency TEXT,
+			converted_at TIMESTAMPTZ,
+			processed_at TIMESTAMPTZ
+		);
+	`)
+	require.NoError(t, err)
+
+	experimentID, controlID, variantID := uuid.New(), uuid.New(), uuid.New()
+	_, err = db.Exec(ctx, `INSERT INTO ab_tests (id, name, window_type, window_size, window_min_samples, enable_delayed) VALUES ($1, 'Advanced handler test', 'events', 100, 1, true)`, experimentID)
+	require.NoError(t, err)
+	_, err = db.Exec(ctx, `INSERT INTO ab_test_arms (id, experiment_id, name, is_control) VALUES ($1, $3, 'Control', true), ($2, $3, 'Variant', false)`, controlID, variantID, experimentID)
+	require.NoError(t, err)
+
+	userID, pendingID := uuid.New(), uuid.New()
+	assignedAt := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	_, err = db.Exec(ctx, `INSERT INTO bandit_pending_rewards (id, experiment_id, arm_id, user_id, assigned_at, expires_at) VALUES ($1, $2, $3, $4, $5, $6)`,
+		pendingID, experimentID, variantID, userID, assignedAt, assignedAt.Add(24*time.Hour))
+	require.NoError(t, err)
+
+	repo := repository.NewPostgresBanditRepository(db, logger)
+	banditCache := cache.NewRedisBanditCache(redisClient, logger)
+	engine := service.NewAdvancedBanditEngine(service.NewThompsonSamplingBandit(repo, banditCache, logger), repo, banditCache, redisClient, nil, logger,
+		&service.EngineConfig{EnableWindow: true, EnableDelayed: true})
+	handler := handlers.NewBanditAdvancedHandler(engine, nil, logger)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	bandit := router.Group("/v1/bandit")
+	bandit.GET("/experiments/:id/window/info", handler.GetWindowInfo)
+	bandit.POST("/experiments/:id/window/trim", handler.TrimWindow)
+	bandit.GET("/experiments/:id/window/events", handler.ExportWindowEvents)
+	bandit.GET("/pending/:id", handler.GetPendingReward)
+	bandit.GET("/users/:id/pending", handler.GetUserPendingRewards)
+
+	window := service.NewSlidingWindowStrategy(repo, redisClient, logger, experimentID, &service.WindowConfig{Type: service.WindowTypeEvents, Size: 100})
+	start := time.Now().Add(-time.Minute)
+	for i := 0; i < 3; i++ {
+		event := service.RewardEvent{UserID: uuid.New(), RewardValue: float64(i), Currency: "USD", Timestamp: start.Add(time.Duration(i) * time.Second)}
+		require.NoError(t, window.RecordEvent(ctx, variantID, event))
+	}
+
+	get := func(t *testing.T, path string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, httptest.NewRequest(http.MethodGet, path, nil))
+		return res
+	}
+	experimentPath := "/v1/bandit/experiments/" + experimentID.String()
+
+	t.Run("window info", func(t *testing.T) {
+		res := get(t, experimentPath+"/window/info")
+		require.Equal(t, http.StatusOK, res.Code, res.Body.String())
+
+		var body struct {
+			Windows map[uuid.UUID]service.WindowStats `json:"windows"`
+		}
+		require.NoError(t, json.Unmarshal(res.Body.Bytes(), &body))
+		require.Len(t, body.Windows, 2)
+		assert.Equal(t, 3, body.Windows[variantID].Samples)
+		assert.Equal(t, 2, body.Windows[variantID].Conversions)
+		assert.Zero(t, body.Windows[controlID].Samples)
+	})
+
+	t.Run("window trim", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, httptest.NewRequest(http.MethodPost, experimentPath+"/window/trim", nil))
+		require.Equal(t, http.StatusOK, res.Code, res.Body.String())
+	})
+
+	t.Run("window events pages by cursor", func(t *testing.T) {
+		var rewards []float64
+		cursor := ""
+		for page := 0; page < 5; page++ {
+			query := url.Values{"arm_id": {variantID.String()}, "limit": {"2"}}
+			if cursor != "" {
+				query.Set("cursor", cursor)
+			}
+			res := get(t, experimentPath+"/window/events?"+query.Encode())
+			require.Equal(t, http.StatusOK, res.Code, res.Body.String())
+
+			var body struct {
+				Events     map[uuid.UUID][]service.RewardEvent `json:"events"`
+				NextCursor *string                             `json:"next_cursor"`
+			}
+			require.NoError(t, json.Unmarshal(res.Body.Bytes(), &body))
+			assert.NotContains(t, body.Events, controlID)
+			for _, event := range body.Events[variantID] {
+				assert.Equal(t, variantID, event.ArmID)
+				rewards = append(rewards, event.RewardValue)
+			}
+			if body.NextCursor == nil {
+				break
+			}
+			cursor = *body.NextCursor
+		}
+		assert.Equal(t, []float64{2, 1, 0}, rewards, "newest first, each event once")
+	})
+
+	t.Run("window events time range", func(t *testing.T) {
+		query := url.Values{
+			"since": {start.Add(time.Second).Format(time.RFC3339Nano)},
+			"until": {start.Add(2 * time.Second).Format(time.RFC3339Nano)},
+		}
+		res := get(t, experimentPath+"/window/events?"+query.Encode())
+		require.Equal(t, http.StatusOK, res.Code, res.Body.String())
+
+		var body struct {
+			Events map[uuid.UUID][]service.RewardEvent `json:"events"`
+		}
+		require.NoError(t, json.Unmarshal(res.Body.Bytes(), &body))
+		require.Len(t, body.Events[variantID], 2)
+		assert.Equal(t, 2.0, body.Events[variantID][0].RewardValue)
+		assert.Equal(t, 1.0, body.Events[variantID][1].RewardValue)
+	})
+
+	t.Run("window events stream as ndjson", func(t *testing.T) {
+		res := get(t, experimentPath+"/window/events?format=ndjson")
+		require.Equal(t, http.StatusOK, res.Code, res.Body.String())
+		assert.Equal(t, "application/x-ndjson", res.Header().Get("Content-Type"))
+
+		lines := 0
+		scanner := bufio.NewScanner(res.Body)
+		for scanner.Scan() {
+			var event service.RewardEvent
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+			assert.Equal(t, variantID, event.ArmID)
+			lines++
+		}
+		assert.Equal(t, 3, lines)
+	})
+
+	t.Run("window events reject foreign arm", func(t *testing.T) {
+		res := get(t, experimentPath+"/window/events?arm_id="+uuid.NewString())
+		assert.Equal(t, http.StatusNotFound, res.Code, res.Body.String())
+	})
+
+	t.Run("pending reward", func(t *testing.T) {
+		res := get(t, "/v1/bandit/pending/"+pendingID.String())
+		require.Equal(t, http.StatusOK, res.Code, res.Body.String())
+
+		var reward service.PendingReward
+		require.NoError(t, json.Unmarshal(res.Body.Bytes(), &reward))
+		assert.Equal(t, pendingID, reward.ID)
+		assert.Equal(t, variantID, reward.ArmID)
+		assert.False(t, reward.Converted)
+
+		res = get(t, "/v1/bandit/pending/"+uuid.NewString())
+		assert.Equal(t, http.StatusNotFound, res.Code, res.Body.String())
+	})
+
+	t.Run("user pending rewards", func(t *testing.T) {
+		res := get(t, "/v1/bandit/users/"+userID.String()+"/pending?experiment_id="+experimentID.String())
+		require.Equal(t, http.StatusOK, res.Code, res.Body.String())
+
+		var body struct {
+			Rewards []service.PendingReward `json:"rewards"`
+		}
+		require.NoError(t, json.Unmarshal(res.Body.Bytes(), &body))
+		require.Len(t, body.Rewards, 1)
+		assert.Equal(t, pendingID, body.Rewards[0].ID)
+
+		res = get(t, "/v1/bandit/users/"+uuid.NewString()+"/pending")
+		require.Equal(t, http.StatusOK, res.Code, res.Body.String())
+		body.Rewards = nil
+		require.NoError(t, json.Unmarshal(res.Body.Bytes(), &body))
+		assert.NotNil(t, body.Rewards, "an empty list, not null")
+		assert.Empty(t, body.Rewards)
+	})
+}
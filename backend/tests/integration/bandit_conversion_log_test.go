@@ -102,6 +102,7 @@ func TestPostgresBanditRepository_AppendImpressionEventPersistsImmutableEvent(t
 			arm_id UUID NOT NULL REFERENCES ab_test_arms(id) ON DELETE CASCADE,
 			user_id UUID NOT NULL,
 			event_type TEXT NOT NULL,
+			placement VARCHAR(100),
 			metadata JSONB,
 			occurred_at TIMESTAMPTZ NOT NULL,
 			created_at TIMESTAMPTZ NOT NULL DEFAULT now()
@@ -269,6 +270,13 @@ func TestPostgresBanditRepository_ProcessPendingConversionPersistsImmutableEvent
 		);
 		CREATE UNIQUE INDEX idx_bandit_conversion_events_pending_event ON bandit_conversion_events(pending_reward_id, event_type) WHERE pending_reward_id IS NOT NULL;
 		CREATE UNIQUE INDEX idx_bandit_conversion_events_transaction_delayed ON bandit_conversion_events(transaction_id) WHERE transaction_id IS NOT NULL AND event_type = 'delayed_conversion';
+		CREATE TABLE bandit_conversion_transactions (
+			transaction_id UUID PRIMARY KEY,
+			experiment_id UUID NOT NULL REFERENCES ab_tests(id) ON DELETE CASCADE,
+			arm_id UUID NOT NULL REFERENCES ab_test_arms(id) ON DELETE CASCADE,
+			source TEXT NOT NULL,
+			recorded_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		);
 	`)
 	require.NoError(t, err)
 
@@ -289,7 +297,7 @@ func TestPostgresBanditRepository_ProcessPendingConversionPersistsImmutableEvent
 	require.NoError(t, err)
 
 	repo := repository.NewPostgresBanditRepository(db, zap.NewNop())
-	matched, processed, err := repo.ProcessPendingConversion(ctx, transactionID, userID, 19.99, "USD", processedAt)
+	matched, processed, err := repo.ProcessPendingConversion(ctx, transactionID, userID, 19.99, "USD", nil, processedAt)
 	require.NoError(t, err)
 	require.True(t, processed)
 	require.NotNil(t, matched)
@@ -326,6 +334,14 @@ func TestPostgresBanditRepository_ProcessPendingConversionPersistsImmutableEvent
 	assert.InDelta(t, 19.99, normalizedValue, 0.0001)
 	assert.Equal(t, "USD", originalCurrency)
 	assert.Equal(t, "USD", normalizedCurrency)
+
+	// A replay of the transaction is rejected without touching the arm
+	_, err = db.Exec(ctx, `INSERT INTO bandit_pending_rewards (id, experiment_id, arm_id, user_id, assigned_at, expires_at) VALUES ($1, $2, $3, $4, $5, $6)`, uuid.New(), experimentID, armID, userID, processedAt.Add(-time.Minute), processedAt.Add(time.Hour))
+	require.NoError(t, err)
+	_, _, err = repo.ProcessPendingConversion(ctx, transactionID, userID, 19.99, "USD", nil, processedAt)
+	require.ErrorIs(t, err, service.ErrDuplicateConversion)
+	require.NoError(t, db.QueryRow(ctx, `SELECT conversions FROM ab_test_arm_stats WHERE arm_id = $1`, armID).Scan(&conversions))
+	assert.Equal(t, 5, conversions)
 }
 
 func TestPostgresBanditRepository_ProcessExpiredPendingRewardPersistsImmutableEvent(t *testing.T) {
@@ -418,3 +434,60 @@ func TestPostgresBanditRepository_ProcessExpiredPendingRewardPersistsImmutableEv
 	require.NoError(t, db.QueryRow(ctx, `SELECT event_type FROM bandit_conversion_events WHERE pending_reward_id = $1`, pendingID).Scan(&eventType))
 	assert.Equal(t, "expired_pending_reward", eventType)
 }
+
+func TestPostgresBanditRepository_GetPendingRewardsByUserFiltersByExperiment(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup, err := testutil.SetupTestDB(ctx)
+	require.NoError(t, err)
+	defer cleanup()
+
+	_, err = db.Exec(ctx, `
+		CREATE TABLE ab_tests (id UUID PRIMARY KEY, name TEXT NOT NULL);
+		CREATE TABLE ab_test_arms (id UUID PRIMARY KEY, experiment_id UUID NOT NULL REFERENCES ab_tests(id) ON DELETE CASCADE, name TEXT NOT NULL);
+		CREATE TABLE bandit_pending_rewards (
+			id UUID PRIMARY KEY,
+			experiment_id UUID NOT NULL REFERENCES ab_tests(id) ON DELETE CASCADE,
+			arm_id UUID NOT NULL REFERENCES ab_test_arms(id) ON DELETE CASCADE,
+			user_id UUID NOT NULL,
+			assigned_at TIMESTAMPTZ NOT NULL,
+			expires_at TIMESTAMPTZ NOT NULL,
+			converted BOOLEAN NOT NULL DEFAULT FALSE,
+			conversion_value DOUBLE PRECISION,
+			conversion_currency TEXT,
+			converted_at TIMESTAMPTZ,
+			processed_at TIMESTAMPTZ
+		);
+	`)
+	require.NoError(t, err)
+
+	userID := uuid.New()
+	assignedAt := time.Date(2026, 3, 8, 12, 0, 0, 0, time.UTC)
+	experimentIDs := []uuid.UUID{uuid.New(), uuid.New()}
+	for i, experimentID := range experimentIDs {
+		armID := uuid.New()
+		_, err = db.Exec(ctx, `INSERT INTO ab_tests (id, name) VALUES ($1, 'Pending filter test')`, experimentID)
+		require.NoError(t, err)
+		_, err = db.Exec(ctx, `INSERT INTO ab_test_arms (id, experiment_id, name) VALUES ($1, $2, 'Variant A')`, armID, experimentID)
+		require.NoError(t, err)
+		_, err = db.Exec(ctx, `INSERT INTO bandit_pending_rewards (id, experiment_id, arm_id, user_id, assigned_at, expires_at) VALUES ($1, $2, $3, $4, $5, $6)`,
+			uuid.New(), experimentID, armID, userID, assignedAt.Add(time.Duration(i)*time.Hour), assignedAt.Add(24*time.Hour))
+		require.NoError(t, err)
+	}
+
+	repo := repository.NewPostgresBanditRepository(db, zap.NewNop())
+
+	all, err := repo.GetPendingRewardsByUser(ctx, userID, nil)
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	assert.Equal(t, experimentIDs[1], all[0].ExperimentID, "newest assignment first")
+
+	filtered, err := repo.GetPendingRewardsByUser(ctx, userID, &experimentIDs[0])
+	require.NoError(t, err)
+	require.Len(t, filtered, 1)
+	assert.Equal(t, experimentIDs[0], filtered[0].ExperimentID)
+
+	unknown := uuid.New()
+	none, err := repo.GetPendingRewardsByUser(ctx, userID, &unknown)
+	require.NoError(t, err)
+	assert.Empty(t, none)
+}
@@ -0,0 +1,87 @@
+package integration
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bivex/paywall-iap/internal/domain/service"
+	"github.com/bivex/paywall-iap/tests/testutil"
+)
+
+func TestSlidingWindowStrategy_ConcurrentRecordEvent(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration tests in short mode")
+	}
+
+	ctx := context.Background()
+	redisClient := testutil.SetupTestRedis(t)
+	strategy := service.NewSlidingWindowStrategy(nil, redisClient, zap.NewNop(), uuid.New(),
+		&service.WindowConfig{Type: service.WindowTypeEvents, Size: 50, MinSamples: 1})
+	armID := uuid.New()
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 25; i++ {
+				event := service.RewardEvent{UserID: uuid.New(), RewardValue: 1, Currency: "USD", Timestamp: time.Now()}
+				assert.NoError(t, strategy.RecordEvent(ctx, armID, event))
+				_, err := strategy.GetArmStats(ctx, armID)
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Stats read after the last write reflect the trimmed window, not a
+	// snapshot cached while writes were in flight
+	stats, err := strategy.GetArmStats(ctx, armID)
+	require.NoError(t, err)
+	assert.Equal(t, 50, stats.Samples)
+	assert.Equal(t, 50, stats.Conversions)
+}
+
+func TestSlidingWindowStrategy_RecordEventAfterScriptFlush(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration tests in short mode")
+	}
+
+	ctx := context.Background()
+	redisClient := testutil.SetupTestRedis(t)
+	strategy := service.NewSlidingWindowStrategy(nil, redisClient, zap.NewNop(), uuid.New(), nil)
+	armID := uuid.New()
+
+	require.NoError(t, strategy.RecordEvent(ctx, armID, service.RewardEvent{UserID: uuid.New(), Timestamp: time.Now()}))
+	require.NoError(t, redisClient.ScriptFlush(ctx).Err())
+	require.NoError(t, strategy.RecordEvent(ctx, armID, service.RewardEvent{UserID: uuid.New(), Timestamp: time.Now()}))
+
+	info, err := strategy.GetWindowInfo(ctx, armID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, info.Samples)
+}
+
+func BenchmarkSlidingWindowStrategy_RecordEvent(b *testing.B) {
+	ctx := context.Background()
+	redisClient := testutil.SetupTestRedis(b)
+	strategy := service.NewSlidingWindowStrategy(nil, redisClient, zap.NewNop(), uuid.New(),
+		&service.WindowConfig{Type: service.WindowTypeEvents, Size: 1000})
+	armID := uuid.New()
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			event := service.RewardEvent{UserID: uuid.New(), RewardValue: 9.99, Currency: "USD", Timestamp: time.Now()}
+			if err := strategy.RecordEvent(ctx, armID, event); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -32,6 +32,11 @@ func (m *AnalyticsRepositoryMock) GetChurnedCountBetween(ctx context.Context, st
 	return args.Int(0), args.Error(1)
 }
 
+func (m *AnalyticsRepositoryMock) GetPriceIncreaseChurnedCountBetween(ctx context.Context, start, end time.Time) (int, error) {
+	args := m.Called(ctx, start, end)
+	return args.Int(0), args.Error(1)
+}
+
 func (m *AnalyticsRepositoryMock) GetSubscriptionStatusCounts(ctx context.Context) (*repository.SubscriptionStatusCounts, error) {
 	args := m.Called(ctx)
 	if args.Get(0) == nil {
@@ -78,6 +78,11 @@ func (m *MockUserRepository) UpdateEmail(ctx context.Context, id uuid.UUID, emai
 	return args.Error(0)
 }
 
+func (m *MockUserRepository) IncrementLTV(ctx context.Context, id uuid.UUID, amount float64) error {
+	args := m.Called(ctx, id, amount)
+	return args.Error(0)
+}
+
 func (m *MockUserRepository) IncrementSessionCount(ctx context.Context, id uuid.UUID) (int, error) {
 	args := m.Called(ctx, id)
 	return args.Int(0), args.Error(1)
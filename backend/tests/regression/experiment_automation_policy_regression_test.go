@@ -53,6 +53,10 @@ func (s *automationPolicyRepoStub) UpdateExperimentStatusAndAutomationPolicyWith
 	return nil
 }
 
+func (s *automationPolicyRepoStub) ArchiveExperimentArm(context.Context, uuid.UUID, uuid.UUID, service.ArmReassignmentPolicy, time.Time) error {
+	return nil
+}
+
 func TestExperimentAdminServiceUpdateExperimentAutomationPolicyPreservesLocks(t *testing.T) {
 	lockedUntil := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
 	lockedBy := uuid.New()
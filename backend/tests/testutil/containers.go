@@ -3,89 +3,275 @@ package testutil
 import (
 	"context"
 	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
 	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
 	tcwait "github.com/testcontainers/testcontainers-go/wait"
 )
 
-// TestDBContainer holds the PostgreSQL test container
-type TestDBContainer struct {
-	Container  testcontainers.Container
-	ConnString string
-	Pool       *pgxpool.Pool
-}
+const (
+	postgresImage = "postgres:15-alpine"
+	redisImage    = "redis:7-alpine"
 
-// SetupTestDBContainer starts a PostgreSQL test container
-func SetupTestDBContainer(ctx context.Context, t *testing.T) (*TestDBContainer, error) {
-	t.Helper()
+	// reuseEnv keeps the shared containers running between test runs, under
+	// fixed names, when set to true. Combine with TESTCONTAINERS_RYUK_DISABLED=true
+	// so the reaper does not remove them when the run ends.
+	reuseEnv = "TESTUTIL_REUSE_CONTAINERS"
+
+	// testPoolMaxConns keeps parallel tests within the server's connection limit
+	testPoolMaxConns = 4
+)
+
+// sharedContainer starts a container once per test binary and hands the same
+// instance to every caller. A failed start is remembered so later tests fail
+// fast instead of retrying.
+type sharedContainer[T any] struct {
+	once    sync.Once
+	value   T
+	err     error
+	startFn func(ctx context.Context) (T, error)
+}
 
-	req := testcontainers.ContainerRequest{
-		Image:        "postgres:15-alpine",
-		ExposedPorts: []string{"5432/tcp"},
-		Env: map[string]string{
-			"POSTGRES_USER":     "test",
-			"POSTGRES_PASSWORD": "test",
-			"POSTGRES_DB":       "iap_test",
-		},
-		WaitingFor: tcwait.ForAll(
-			tcwait.ForLog("database system is ready to accept connections").WithOccurrence(2),
-			tcwait.ForListeningPort("5432/tcp"),
-		).WithDeadline(60 * time.Second),
-	}
-
-	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
-		ContainerRequest: req,
-		Started:          true,
+func (s *sharedContainer[T]) get(ctx context.Context) (T, error) {
+	s.once.Do(func() {
+		s.value, s.err = s.startFn(ctx)
 	})
+	return s.value, s.err
+}
+
+// postgresServer is the shared PostgreSQL container. Tests never use its
+// default database; each gets its own, created through admin.
+type postgresServer struct {
+	container *postgres.PostgresContainer
+	admin     *pgxpool.Pool
+
+	// cloneMu serialises CREATE DATABASE ... TEMPLATE, which fails when the
+	// template is being copied by another session
+	cloneMu sync.Mutex
+}
+
+var sharedPostgres = &sharedContainer[*postgresServer]{startFn: startPostgres}
+
+func reuseContainers() bool {
+	return os.Getenv(reuseEnv) == "true"
+}
+
+func startPostgres(ctx context.Context) (*postgresServer, error) {
+	opts := []testcontainers.ContainerCustomizer{
+		postgres.WithDatabase("postgres"),
+		postgres.WithUsername("test"),
+		postgres.WithPassword("test"),
+		testcontainers.WithCmdArgs("-c", "max_connections=500"),
+		testcontainers.WithWaitStrategy(
+			tcwait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(60 * time.Second),
+		),
+	}
+	if reuseContainers() {
+		opts = append(opts, testcontainers.WithReuseByName("paywall-iap-test-postgres"))
+	}
+
+	container, err := postgres.Run(ctx, postgresImage, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to start container: %w", err)
 	}
 
-	host, err := container.Host(ctx)
+	connStr, err := container.ConnectionString(ctx, "sslmode=disable")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection string: %w", err)
+	}
+	admin, err := pgxpool.New(ctx, connStr)
 	if err != nil {
-		container.Terminate(ctx)
-		return nil, fmt.Errorf("failed to get host: %w", err)
+		return nil, fmt.Errorf("failed to create admin pool: %w", err)
+	}
+	if err := admin.Ping(ctx); err != nil {
+		admin.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	mappedPort, err := container.MappedPort(ctx, "5432")
+	return &postgresServer{container: container, admin: admin}, nil
+}
+
+// connString returns the connection string of database on the shared server
+func (s *postgresServer) connString(ctx context.Context, database string) (string, error) {
+	connStr, err := s.container.ConnectionString(ctx, "sslmode=disable")
 	if err != nil {
-		container.Terminate(ctx)
-		return nil, fmt.Errorf("failed to get mapped port: %w", err)
+		return "", fmt.Errorf("failed to get connection string: %w", err)
+	}
+	u, err := url.Parse(connStr)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse connection string: %w", err)
+	}
+	u.Path = "/" + database
+	return u.String(), nil
+}
+
+// createDatabase creates a uniquely named database, cloned from template when
+// it is not empty, and returns its name
+func (s *postgresServer) createDatabase(ctx context.Context, template string) (string, error) {
+	name := "test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	stmt := "CREATE DATABASE " + pgx.Identifier{name}.Sanitize()
+	if template != "" {
+		stmt += " TEMPLATE " + pgx.Identifier{template}.Sanitize()
+		s.cloneMu.Lock()
+		defer s.cloneMu.Unlock()
+	}
+	if _, err := s.admin.Exec(ctx, stmt); err != nil {
+		return "", fmt.Errorf("failed to create database: %w", err)
 	}
+	return name, nil
+}
+
+// dropDatabase drops a database, disconnecting anything still using it
+func (s *postgresServer) dropDatabase(ctx context.Context, name string) error {
+	_, err := s.admin.Exec(ctx, "DROP DATABASE IF EXISTS "+pgx.Identifier{name}.Sanitize()+" WITH (FORCE)")
+	return err
+}
 
-	connString := fmt.Sprintf("postgres://test:test@%s:%s/iap_test?sslmode=disable", host, mappedPort.Port())
+// newTestDatabase creates a database on the shared server, cloned from
+// template when it is not empty, and connects to it. The cleanup closes the
+// pool and drops the database.
+func newTestDatabase(ctx context.Context, template string) (*pgxpool.Pool, string, func(), error) {
+	server, err := sharedPostgres.get(ctx)
+	if err != nil {
+		return nil, "", func() {}, err
+	}
 
-	pool, err := pgxpool.New(ctx, connString)
+	name, err := server.createDatabase(ctx, template)
 	if err != nil {
-		container.Terminate(ctx)
-		return nil, fmt.Errorf("failed to create pool: %w", err)
+		return nil, "", func() {}, err
 	}
+	drop := func() { _ = server.dropDatabase(context.Background(), name) }
 
-	// Verify connection
-	if err := pool.Ping(ctx); err != nil {
-		container.Terminate(ctx)
-		return nil, fmt.Errorf("failed to ping database: %w", err)
+	connStr, err := server.connString(ctx, name)
+	if err != nil {
+		return nil, "", drop, err
 	}
+	poolConfig, err := pgxpool.ParseConfig(connStr)
+	if err != nil {
+		return nil, "", drop, fmt.Errorf("failed to parse config: %w", err)
+	}
+	poolConfig.MaxConns = testPoolMaxConns
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, "", drop, fmt.Errorf("failed to create pool: %w", err)
+	}
+
+	cleanup := func() {
+		pool.Close()
+		drop()
+	}
+	return pool, connStr, cleanup, nil
+}
+
+// TestDBContainer holds a test database on the shared PostgreSQL container
+type TestDBContainer struct {
+	Container  testcontainers.Container
+	ConnString string
+	Pool       *pgxpool.Pool
+
+	cleanup func()
+}
+
+// SetupTestDBContainer creates an empty database of its own on the shared
+// PostgreSQL container, starting the container on first use
+func SetupTestDBContainer(ctx context.Context, t *testing.T) (*TestDBContainer, error) {
+	t.Helper()
+
+	pool, connStr, cleanup, err := newTestDatabase(ctx, "")
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+	server, _ := sharedPostgres.get(ctx)
 
 	return &TestDBContainer{
-		Container:  container,
-		ConnString: connString,
+		Container:  server.container,
+		ConnString: connStr,
 		Pool:       pool,
+		cleanup:    cleanup,
 	}, nil
 }
 
-// Teardown cleans up the test container
+// Teardown closes the pool and drops the test database. The shared container
+// keeps running for the remaining tests.
 func (tc *TestDBContainer) Teardown(ctx context.Context, t *testing.T) {
 	t.Helper()
-	if tc.Pool != nil {
-		tc.Pool.Close()
+	if tc.cleanup != nil {
+		tc.cleanup()
+		tc.cleanup = nil
 	}
-	if tc.Container != nil {
-		if err := tc.Container.Terminate(ctx); err != nil {
-			t.Logf("Failed to terminate container: %v", err)
+}
+
+// redisDatabases is how many logical databases the shared Redis serves. Tests
+// each claim one; database 0 only holds the claims.
+const redisDatabases = 256
+
+// redisClaimTTL frees a claimed database whose test died without cleaning up
+const redisClaimTTL = 30 * time.Minute
+
+var sharedRedis = &sharedContainer[string]{startFn: startRedis}
+
+// startRedis starts the shared Redis container and returns its address
+func startRedis(ctx context.Context) (string, error) {
+	opts := []testcontainers.ContainerCustomizer{
+		testcontainers.WithExposedPorts("6379/tcp"),
+		testcontainers.WithCmd("redis-server", "--databases", strconv.Itoa(redisDatabases), "--save", "", "--appendonly", "no"),
+		testcontainers.WithWaitStrategy(tcwait.ForLog("Ready to accept connections")),
+	}
+	if reuseContainers() {
+		opts = append(opts, testcontainers.WithReuseByName("paywall-iap-test-redis"))
+	}
+
+	container, err := testcontainers.Run(ctx, redisImage, opts...)
+	if err != nil {
+		return "", fmt.Errorf("failed to start Redis container: %w", err)
+	}
+	addr, err := container.Endpoint(ctx, "")
+	if err != nil {
+		return "", fmt.Errorf("failed to get Redis endpoint: %w", err)
+	}
+	return addr, nil
+}
+
+// claimRedisDB claims a free logical database on the shared Redis. Claims
+// live in Redis itself, so test binaries sharing a reused container do not
+// collide.
+func claimRedisDB(ctx context.Context, addr, owner string) (int, error) {
+	control := redis.NewClient(&redis.Options{Addr: addr})
+	defer control.Close()
+
+	for db := 1; db < redisDatabases; db++ {
+		claimed, err := control.SetNX(ctx, redisClaimKey(db), owner, redisClaimTTL).Result()
+		if err != nil {
+			return 0, fmt.Errorf("failed to claim Redis database: %w", err)
+		}
+		if claimed {
+			return db, nil
 		}
 	}
+	return 0, fmt.Errorf("all %d Redis test databases are claimed", redisDatabases-1)
+}
+
+func releaseRedisDB(ctx context.Context, addr string, db int) {
+	control := redis.NewClient(&redis.Options{Addr: addr})
+	defer control.Close()
+	_ = control.Del(ctx, redisClaimKey(db)).Err()
+}
+
+func redisClaimKey(db int) string {
+	return fmt.Sprintf("testutil:claim:%d", db)
 }
@@ -2,9 +2,145 @@ package testutil
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"testing"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// SetupMigratedTestDB creates a database of its own, with every migration in
+// backend/migrations applied, on the shared PostgreSQL container. The
+// migrations run once per migration set into a template database that each
+// test clones, so tests can run in parallel. The database is dropped when
+// the test ends.
+func SetupMigratedTestDB(t testing.TB) *pgxpool.Pool {
+	t.Helper()
+	ctx := context.Background()
+
+	template, err := migratedTemplate.get(ctx)
+	if err != nil {
+		t.Fatalf("SetupMigratedTestDB: %v", err)
+	}
+	pool, _, cleanup, err := newTestDatabase(ctx, template)
+	if err != nil {
+		cleanup()
+		t.Fatalf("SetupMigratedTestDB: %v", err)
+	}
+	t.Cleanup(cleanup)
+	return pool
+}
+
+var migratedTemplate = &sharedContainer[string]{startFn: buildMigratedTemplate}
+
+// migrationsDir is backend/migrations, located from this file so it does not
+// depend on the package running the tests
+func migrationsDir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "migrations")
+}
+
+// migrationsDigest identifies the migration set, so a reused container
+// rebuilds the template when a migration is added or changed
+func migrationsDigest(dir string) (string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.up.sql"))
+	if err != nil {
+		return "", err
+	}
+	if len(paths) == 0 {
+		return "", fmt.Errorf("no migrations in %s", dir)
+	}
+	sort.Strings(paths)
+	hash := sha256.New()
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		hash.Write([]byte(filepath.Base(path)))
+		hash.Write(content)
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:12], nil
+}
+
+// buildMigratedTemplate returns the template database for the current
+// migration set, creating it when the shared server does not have it yet. An
+// advisory lock keeps test binaries sharing a reused container from building
+// it twice, and the template only gets its final name once fully migrated.
+func buildMigratedTemplate(ctx context.Context) (string, error) {
+	server, err := sharedPostgres.get(ctx)
+	if err != nil {
+		return "", err
+	}
+	dir := migrationsDir()
+	digest, err := migrationsDigest(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read migrations: %w", err)
+	}
+	name := "iap_template_" + digest
+
+	conn, err := server.admin.Acquire(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to acquire admin connection: %w", err)
+	}
+	defer conn.Release()
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock(hashtext($1))", name); err != nil {
+		return "", fmt.Errorf("failed to lock template: %w", err)
+	}
+	defer conn.Exec(context.Background(), "SELECT pg_advisory_unlock(hashtext($1))", name)
+
+	var exists bool
+	if err := conn.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM pg_database WHERE datname = $1)", name).Scan(&exists); err != nil {
+		return "", fmt.Errorf("failed to look up template: %w", err)
+	}
+	if exists {
+		return name, nil
+	}
+
+	building := name + "_building"
+	if err := server.dropDatabase(ctx, building); err != nil {
+		return "", fmt.Errorf("failed to drop stale template: %w", err)
+	}
+	if _, err := conn.Exec(ctx, "CREATE DATABASE "+pgx.Identifier{building}.Sanitize()); err != nil {
+		return "", fmt.Errorf("failed to create template: %w", err)
+	}
+	connStr, err := server.connString(ctx, building)
+	if err != nil {
+		return "", err
+	}
+	if err := migrateUp(dir, connStr); err != nil {
+		return "", fmt.Errorf("failed to migrate template: %w", err)
+	}
+	if _, err := conn.Exec(ctx, "ALTER DATABASE "+pgx.Identifier{building}.Sanitize()+" RENAME TO "+pgx.Identifier{name}.Sanitize()); err != nil {
+		return "", fmt.Errorf("failed to rename template: %w", err)
+	}
+	return name, nil
+}
+
+// migrateUp applies every migration in dir to the database at connStr
+func migrateUp(dir, connStr string) error {
+	m, err := migrate.New("file://"+dir, connStr)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+	return nil
+}
+
 // RunMigrationsOnContainer runs all schema migrations on the test database pool
 // It re-uses the existing inline schema approach from testutil.go but via the container pool
 func RunMigrationsOnContainer(ctx context.Context, tc *TestDBContainer) error {
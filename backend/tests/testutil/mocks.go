@@ -285,3 +285,12 @@ func (r *mockSubscriptionRepo) GetTotalRevenue(ctx context.Context, userID uuid.
 	).Scan(&total)
 	return total, err
 }
+
+func (r *mockSubscriptionRepo) GetNetRevenue(ctx context.Context, userID uuid.UUID) (float64, error) {
+	var total float64
+	err := r.pool.QueryRow(ctx,
+		"SELECT COALESCE(SUM(COALESCE(t.net_amount, t.amount)), 0) FROM transactions t WHERE t.user_id = $1 AND t.status = 'success'",
+		userID,
+	).Scan(&total)
+	return total, err
+}
@@ -4,20 +4,16 @@ import (
 	"context"
 	"fmt"
 	"testing"
-	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
-	"github.com/testcontainers/testcontainers-go"
-	"github.com/testcontainers/testcontainers-go/modules/postgres"
-	"github.com/testcontainers/testcontainers-go/wait"
 )
 
 
 
 
-// SetupTestDBWithT creates a test PostgreSQL container and returns a pool.
-// The container is automatically terminated when the test ends via t.Cleanup.
+// SetupTestDBWithT creates an empty test database and returns a pool.
+// The database is automatically dropped when the test ends via t.Cleanup.
 func SetupTestDBWithT(t *testing.T) *pgxpool.Pool {
 	t.Helper()
 	ctx := context.Background()
@@ -38,54 +34,21 @@ func TeardownTestDB(t *testing.T, pool *pgxpool.Pool) {
 	}
 }
 
-// SetupTestDB creates a test database container
+// SetupTestDB creates an empty database of its own on the shared PostgreSQL
+// container, starting the container on first use. Tests calling it can run
+// in parallel; cleanup drops the database. Use SetupMigratedTestDB for a
+// database with the migrations applied.
 func SetupTestDB(ctx context.Context) (*pgxpool.Pool, func(), error) {
-	// Create PostgreSQL container
-	pgContainer, err := postgres.RunContainer(ctx,
-		testcontainers.WithImage("postgres:15-alpine"),
-		postgres.WithDatabase("testdb"),
-		postgres.WithUsername("test"),
-		postgres.WithPassword("test"),
-		testcontainers.WithWaitStrategy(
-			wait.ForLog("database system is ready to accept connections").
-				WithOccurrence(2).
-				WithStartupTimeout(5*time.Second),
-		),
-	)
+	pool, _, cleanup, err := newTestDatabase(ctx, "")
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to start container: %w", err)
+		return nil, cleanup, err
 	}
-
-	// Get connection string
-	connStr, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
-	if err != nil {
-		return nil, func() { pgContainer.Terminate(ctx) }, fmt.Errorf("failed to get connection string: %w", err)
-	}
-
-	// Create connection pool
-	poolConfig, err := pgxpool.ParseConfig(connStr)
-	if err != nil {
-		return nil, func() { pgContainer.Terminate(ctx) }, fmt.Errorf("failed to parse config: %w", err)
-	}
-
-	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
-	if err != nil {
-		return nil, func() { pgContainer.Terminate(ctx) }, fmt.Errorf("failed to create pool: %w", err)
-	}
-
-	// Cleanup function
-	cleanup := func() {
-		pool.Close()
-		pgContainer.Terminate(ctx)
-	}
-
 	return pool, cleanup, nil
 }
 
-// RunMigrations runs migrations on the test database
+// RunMigrations creates a minimal inline schema of the core tables. Prefer
+// SetupMigratedTestDB, which applies the real migrations.
 func RunMigrations(ctx context.Context, pool *pgxpool.Pool) error {
-	// For now, just create the core tables inline
-	// In production, this would run golang-migrate
 	schema := `
 	-- Users table
 	CREATE TABLE IF NOT EXISTS users (
@@ -210,45 +173,33 @@ func AssertDBCount(t *testing.T, ctx context.Context, pool *pgxpool.Pool, table
 	}
 }
 
-// SetupTestRedis starts a Redis testcontainer and returns a connected client.
-// The container is automatically terminated when the test ends.
-func SetupTestRedis(t *testing.T) *redis.Client {
+// SetupTestRedis returns a client for a logical database of its own on the
+// shared Redis container, starting the container on first use. The database
+// is flushed and released when the test ends, so tests can run in parallel.
+func SetupTestRedis(t testing.TB) *redis.Client {
 	t.Helper()
 	ctx := context.Background()
 
-	redisContainer, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
-		ContainerRequest: testcontainers.ContainerRequest{
-			Image:        "redis:7-alpine",
-			ExposedPorts: []string{"6379/tcp"},
-			WaitingFor:   wait.ForLog("Ready to accept connections"),
-		},
-		Started: true,
-	})
+	addr, err := sharedRedis.get(ctx)
 	if err != nil {
 		t.Fatalf("Failed to start Redis container: %v", err)
 	}
-
-	t.Cleanup(func() {
-		if err := redisContainer.Terminate(ctx); err != nil {
-			t.Logf("Failed to terminate Redis container: %v", err)
-		}
-	})
-
-	host, err := redisContainer.Host(ctx)
+	db, err := claimRedisDB(ctx, addr, t.Name())
 	if err != nil {
-		t.Fatalf("Failed to get Redis container host: %v", err)
+		t.Fatalf("Failed to claim Redis database: %v", err)
 	}
-	port, err := redisContainer.MappedPort(ctx, "6379")
-	if err != nil {
-		t.Fatalf("Failed to get Redis container port: %v", err)
+
+	client := redis.NewClient(&redis.Options{Addr: addr, DB: db})
+	if err := client.FlushDB(ctx).Err(); err != nil {
+		t.Fatalf("Failed to flush Redis database: %v", err)
 	}
 
-	client := redis.NewClient(&redis.Options{
-		Addr: fmt.Sprintf("%s:%s", host, port.Port()),
+	t.Cleanup(func() {
+		_ = client.FlushDB(context.Background()).Err()
+		_ = client.Close()
+		releaseRedisDB(context.Background(), addr, db)
 	})
 
-	t.Cleanup(func() { _ = client.Close() })
-
 	return client
 }
 
@@ -21,6 +21,13 @@ export interface PlanRow {
   mrr: number;
 }
 
+export interface ReactivationRevenueRow {
+  source: "winback_offer" | "offer_code" | "organic";
+  campaign?: string;
+  reactivations: number;
+  mrr: number;
+}
+
 export interface AnalyticsReport {
   mrr: number;
   arr: number;
@@ -28,6 +35,9 @@ export interface AnalyticsReport {
   total_revenue: number;
   churn_rate: number;
   new_subs_month: number;
+  resurrected_mrr: number;
+  reactivations_month: number;
+  by_reactivation: ReactivationRevenueRow[];
   trend: TrendPoint[];
   by_platform: PlatformRow[];
   by_plan: PlanRow[];
@@ -15,6 +15,7 @@ export interface UserRow {
   created_at: string;
   sub_status: string;
   sub_expires_at: string;
+  lifecycle_stage: string;
 }
 
 export interface UsersResponse {
@@ -33,6 +34,7 @@ export async function getUsers(params: {
   search?: string;
   platform?: string;
   role?: string;
+  lifecycle_stage?: string;
 } = {}): Promise<UsersResponse> {
   const cookieStore = await cookies();
   const token = cookieStore.get("admin_access_token")?.value;
@@ -45,6 +47,7 @@ export async function getUsers(params: {
   if (params.search)   qs.set("search", params.search);
   if (params.platform) qs.set("platform", params.platform);
   if (params.role)     qs.set("role", params.role);
+  if (params.lifecycle_stage) qs.set("lifecycle_stage", params.lifecycle_stage);
 
   try {
     const res = await fetch(`${BACKEND_URL}/v1/admin/users/search?${qs}`, {
@@ -17,6 +17,7 @@ export default async function AnalyticsPage() {
   }
 
   const { mrr, arr, ltv, total_revenue, churn_rate, new_subs_month, trend, by_platform, by_plan, status_counts } = report;
+  const resurrectedMrr = report.resurrected_mrr ?? 0;
 
   const statusRows = [
     { label: "Active",    value: status_counts.active,    icon: CheckCircle2, color: "text-emerald-500", bg: "bg-emerald-500/10" },
@@ -32,6 +33,7 @@ export default async function AnalyticsPage() {
     { metric: "total_revenue",  formula: "Σ successful transactions",             value: `$${total_revenue.toLocaleString("en-US", { minimumFractionDigits: 2 })}`, up: true             },
     { metric: "churn_rate",     formula: "churned_mo / (active + churned) × 100",value: `${churn_rate}%`,                                                           up: churn_rate < 5   },
     { metric: "new_subs_month", formula: "COUNT new subs created this month",     value: String(new_subs_month),                                                     up: new_subs_month > 0 },
+    { metric: "resurrected_mrr", formula: "Σ MRR of subs reactivated this month", value: `$${resurrectedMrr.toLocaleString("en-US", { minimumFractionDigits: 2 })}`, up: resurrectedMrr > 0 },
   ];
 
   return (
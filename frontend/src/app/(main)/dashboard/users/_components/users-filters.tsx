@@ -25,7 +25,7 @@ export function UsersFilters() {
     [router, pathname, sp]
   );
 
-  const hasFilters = sp.get("search") || sp.get("platform") || sp.get("role");
+  const hasFilters = sp.get("search") || sp.get("platform") || sp.get("role") || sp.get("lifecycle_stage");
 
   return (
     <div className="flex flex-wrap gap-2">
@@ -60,6 +60,20 @@ export function UsersFilters() {
           <SelectItem value="superadmin">Superadmin</SelectItem>
         </SelectContent>
       </Select>
+      <Select value={sp.get("lifecycle_stage") ?? "all"} onValueChange={(v) => update("lifecycle_stage", v)}>
+        <SelectTrigger className="w-40">
+          <SelectValue placeholder="Lifecycle" />
+        </SelectTrigger>
+        <SelectContent>
+          <SelectItem value="all">All Stages</SelectItem>
+          <SelectItem value="new">New</SelectItem>
+          <SelectItem value="trialing">Trialing</SelectItem>
+          <SelectItem value="engaged_payer">Engaged Payer</SelectItem>
+          <SelectItem value="at_risk">At Risk</SelectItem>
+          <SelectItem value="churned">Churned</SelectItem>
+          <SelectItem value="reactivated">Reactivated</SelectItem>
+        </SelectContent>
+      </Select>
       {hasFilters && (
         <Button variant="ghost" size="sm" onClick={() => router.push(pathname)}>
           Clear
@@ -16,13 +16,22 @@ const subStatusMeta: Record<string, { label: string; className: string }> = {
   none:         { label: "No Sub",       className: "bg-slate-100 text-slate-500 dark:bg-slate-800 dark:text-slate-400" },
 };
 
+const lifecycleMeta: Record<string, { label: string; className: string }> = {
+  new:           { label: "New",           className: "bg-sky-100 text-sky-700 dark:bg-sky-900/30 dark:text-sky-400" },
+  trialing:      { label: "Trialing",      className: "bg-indigo-100 text-indigo-700 dark:bg-indigo-900/30 dark:text-indigo-400" },
+  engaged_payer: { label: "Engaged Payer", className: "bg-emerald-100 text-emerald-800 dark:bg-emerald-900/30 dark:text-emerald-400" },
+  at_risk:       { label: "At Risk",       className: "bg-orange-100 text-orange-800 dark:bg-orange-900/30 dark:text-orange-400" },
+  churned:       { label: "Churned",       className: "bg-red-100 text-red-800 dark:bg-red-900/30 dark:text-red-400" },
+  reactivated:   { label: "Reactivated",   className: "bg-teal-100 text-teal-700 dark:bg-teal-900/30 dark:text-teal-400" },
+};
+
 const platformMeta: Record<string, { label: string; className: string }> = {
   ios:     { label: "iOS",     className: "bg-blue-100 text-blue-700" },
   android: { label: "Android", className: "bg-green-100 text-green-700" },
   web:     { label: "Web",     className: "bg-violet-100 text-violet-700" },
 };
 
-interface SearchParams { page?: string; search?: string; platform?: string; role?: string; }
+interface SearchParams { page?: string; search?: string; platform?: string; role?: string; lifecycle_stage?: string; }
 
 export default async function UsersPage({ searchParams }: { searchParams: Promise<SearchParams> }) {
   const sp = await searchParams;
@@ -34,6 +43,7 @@ export default async function UsersPage({ searchParams }: { searchParams: Promis
     search:   sp.search,
     platform: sp.platform,
     role:     sp.role,
+    lifecycle_stage: sp.lifecycle_stage,
   });
 
   const buildPageUrl = (p: number) => {
@@ -41,6 +51,7 @@ export default async function UsersPage({ searchParams }: { searchParams: Promis
     if (sp.search)   params.set("search",   sp.search);
     if (sp.platform) params.set("platform", sp.platform);
     if (sp.role)     params.set("role",     sp.role);
+    if (sp.lifecycle_stage) params.set("lifecycle_stage", sp.lifecycle_stage);
     params.set("page", String(p));
     return `/dashboard/users?${params.toString()}`;
   };
@@ -66,6 +77,7 @@ export default async function UsersPage({ searchParams }: { searchParams: Promis
                 <TableHead>Email</TableHead>
                 <TableHead className="w-24">Platform</TableHead>
                 <TableHead className="w-28">Sub Status</TableHead>
+                <TableHead className="w-32">Lifecycle</TableHead>
                 <TableHead className="w-24 text-right">LTV</TableHead>
                 <TableHead className="w-24">Role</TableHead>
                 <TableHead className="w-36">Joined</TableHead>
@@ -75,7 +87,7 @@ export default async function UsersPage({ searchParams }: { searchParams: Promis
             <TableBody>
               {data.users.length === 0 ? (
                 <TableRow>
-                  <TableCell colSpan={7} className="text-center text-muted-foreground py-10">
+                  <TableCell colSpan={8} className="text-center text-muted-foreground py-10">
                     No users found.
                   </TableCell>
                 </TableRow>
@@ -83,6 +95,7 @@ export default async function UsersPage({ searchParams }: { searchParams: Promis
                 data.users.map((u) => {
                   const sub = subStatusMeta[u.sub_status] ?? subStatusMeta.none;
                   const plat = platformMeta[u.platform] ?? { label: u.platform, className: "" };
+                  const stage = lifecycleMeta[u.lifecycle_stage];
                   return (
                     <TableRow key={u.id}>
                       <TableCell className="font-medium">{u.email}</TableCell>
@@ -92,6 +105,13 @@ export default async function UsersPage({ searchParams }: { searchParams: Promis
                       <TableCell>
                         <Badge className={sub.className}>{sub.label}</Badge>
                       </TableCell>
+                      <TableCell>
+                        {stage ? (
+                          <Badge className={stage.className}>{stage.label}</Badge>
+                        ) : (
+                          <span className="text-xs text-muted-foreground">—</span>
+                        )}
+                      </TableCell>
                       <TableCell className="text-right font-mono text-sm">
                         ${u.ltv.toFixed(2)}
                       </TableCell>
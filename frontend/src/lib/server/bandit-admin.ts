@@ -18,7 +18,7 @@ async function getAppId(explicitAppId: string | null): Promise<string | null> {
   return cookieStore.get("admin_app_id")?.value ?? null;
 }
 
-export { getAppId };
+export { getAdminToken, getAppId };
 
 async function parseResponse<T>(res: Response): Promise<{ ok: true; data: T } | { ok: false; error: string }> {
   const body = await res.json().catch(() => ({}));
@@ -94,7 +94,7 @@ export async function getBanditSnapshotFromCookies(experimentId: string, appId:
     }),
     fetch(`${BACKEND_URL}/v1/bandit/experiments/${experimentId}/metrics`, {
       cache: "no-store",
-      headers: { ...extraHeaders },
+      headers: { Authorization: `Bearer ${token}`, ...extraHeaders },
     }),
     fetch(`${BACKEND_URL}/v1/admin/experiments/${experimentId}/winner-recommendation-audit`, {
       headers: { Authorization: `Bearer ${token}`, ...extraHeaders },
@@ -6,7 +6,7 @@ import type {
   DelayedFeedbackServiceHealth,
   DelayedFeedbackSnapshot,
 } from "@/lib/delayed-feedback";
-import { getBanditExperimentsFromCookies, getBanditSnapshotFromCookies, getAppId } from "@/lib/server/bandit-admin";
+import { getBanditExperimentsFromCookies, getBanditSnapshotFromCookies, getAdminToken, getAppId } from "@/lib/server/bandit-admin";
 
 const BACKEND_URL = process.env.BACKEND_URL ?? "http://api:8080";
 const PROBE_UUID = "11111111-1111-1111-1111-111111111111";
@@ -43,13 +43,18 @@ function appIdHeaders(appId: string | null): Record<string, string> {
   return appId ? { "X-App-ID": appId } : {};
 }
 
+async function probeHeaders(appId: string | null): Promise<Record<string, string>> {
+  const token = await getAdminToken();
+  return { ...(token ? { Authorization: `Bearer ${token}` } : {}), ...appIdHeaders(appId) };
+}
+
 async function fetchProbe<T>(
   url: string,
   appId: string | null = null,
   options: ProbeOptions = {},
 ): Promise<{ probe: DelayedEndpointProbe; data: T | null }> {
   try {
-    const res = await fetch(url, { cache: "no-store", headers: { ...appIdHeaders(appId) } });
+    const res = await fetch(url, { cache: "no-store", headers: await probeHeaders(appId) });
     const parsed = await parseResponse<T>(res);
     if (!parsed.ok) {
       if (options.acceptedErrorStatuses?.includes(res.status)) {
@@ -9,7 +9,7 @@ import type {
   ObjectiveScoresByArm,
   ObjectiveServiceHealth,
 } from "@/lib/multi-objective";
-import { getBanditExperimentsFromCookies, getBanditSnapshotFromCookies, getAppId } from "@/lib/server/bandit-admin";
+import { getBanditExperimentsFromCookies, getBanditSnapshotFromCookies, getAdminToken, getAppId } from "@/lib/server/bandit-admin";
 
 const BACKEND_URL = process.env.BACKEND_URL ?? "http://api:8080";
 
@@ -95,9 +95,14 @@ function appIdHeaders(appId: string | null): Record<string, string> {
   return appId ? { "X-App-ID": appId } : {};
 }
 
+async function probeHeaders(appId: string | null): Promise<Record<string, string>> {
+  const token = await getAdminToken();
+  return { ...(token ? { Authorization: `Bearer ${token}` } : {}), ...appIdHeaders(appId) };
+}
+
 async function fetchProbe<T>(url: string, appId: string | null = null): Promise<{ probe: ObjectiveEndpointProbe; data: T | null }> {
   try {
-    const res = await fetch(url, { cache: "no-store", headers: { ...appIdHeaders(appId) } });
+    const res = await fetch(url, { cache: "no-store", headers: await probeHeaders(appId) });
     const parsed = await parseResponse<T>(res);
     if (!parsed.ok) {
       return {
@@ -1,6 +1,6 @@
 import "server-only";
 
-import { getBanditExperimentsFromCookies, getBanditSnapshotFromCookies, getAppId } from "@/lib/server/bandit-admin";
+import { getBanditExperimentsFromCookies, getBanditSnapshotFromCookies, getAdminToken, getAppId } from "@/lib/server/bandit-admin";
 import type {
   SlidingWindowDashboardData,
   SlidingWindowEndpointProbe,
@@ -37,9 +37,14 @@ function appIdHeaders(appId: string | null): Record<string, string> {
   return appId ? { "X-App-ID": appId } : {};
 }
 
+async function probeHeaders(appId: string | null): Promise<Record<string, string>> {
+  const token = await getAdminToken();
+  return { ...(token ? { Authorization: `Bearer ${token}` } : {}), ...appIdHeaders(appId) };
+}
+
 async function fetchProbe<T>(url: string, appId: string | null = null): Promise<{ probe: SlidingWindowEndpointProbe; data: T | null }> {
   try {
-    const res = await fetch(url, { cache: "no-store", headers: { ...appIdHeaders(appId) } });
+    const res = await fetch(url, { cache: "no-store", headers: await probeHeaders(appId) });
     const parsed = await parseResponse<T>(res);
     if (!parsed.ok) {
       return {
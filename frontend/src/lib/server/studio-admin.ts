@@ -160,21 +160,19 @@ export async function getStudioSnapshotFromCookies(experimentId: string, appId:
     };
   }
 
-  const extraHeaders = appIdHeaders(resolvedAppId);
-
   const [statisticsRes, metricsRes, objectivesRes, windowInfoRes] = await Promise.all([
     fetchProbe<BanditStatisticsResponse>(
       `${BACKEND_URL}/v1/bandit/statistics?experiment_id=${experimentId}&win_probs=true`,
-      { headers: extraHeaders },
+      { headers: authAndAppIdHeaders },
     ),
     fetchProbe<unknown>(`${BACKEND_URL}/v1/bandit/experiments/${experimentId}/metrics`, {
-      headers: extraHeaders,
+      headers: authAndAppIdHeaders,
     }),
     fetchProbe<Record<string, unknown>>(`${BACKEND_URL}/v1/bandit/experiments/${experimentId}/objectives`, {
-      headers: extraHeaders,
+      headers: authAndAppIdHeaders,
     }),
     fetchProbe<Record<string, unknown>>(`${BACKEND_URL}/v1/bandit/experiments/${experimentId}/window/info`, {
-      headers: extraHeaders,
+      headers: authAndAppIdHeaders,
     }),
   ]);
 
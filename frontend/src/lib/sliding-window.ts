@@ -51,4 +51,5 @@ export interface SlidingWindowEventsExport {
   experiment_id: string;
   events: Record<string, SlidingWindowRewardEvent[]>;
   limit: number;
+  next_cursor?: string | null;
 }